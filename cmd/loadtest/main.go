@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/loadtest"
+)
+
+func main() {
+	defaults := loadtest.DefaultConfig()
+
+	targetURL := flag.String("target", defaults.TargetURL, "base URL of the server under test")
+	concurrency := flag.Int("concurrency", defaults.Concurrency, "number of concurrent workers")
+	duration := flag.Duration("duration", defaults.Duration, "how long to run after seeding")
+	rawMix := flag.String("mix", "create-patient=1,post-vitals=3,search=6", "relative weights of the workload steps")
+	vitalsBatchSize := flag.Int("vitals-batch", defaults.VitalsBatchSize, "observations posted per post-vitals step")
+	seedPatients := flag.Int("seed-patients", defaults.SeedPatients, "patients created before the run starts")
+	apiKey := flag.String("api-key", "", "API key sent with every request")
+	seed := flag.Uint64("seed", defaults.Seed, "random seed for generated data and step order")
+	requestTimeout := flag.Duration("timeout", defaults.RequestTimeout, "per-request timeout")
+	jsonOutput := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	mix, mixError := loadtest.ParseMix(*rawMix)
+	if mixError != nil {
+		fmt.Fprintln(os.Stderr, "invalid -mix:", mixError)
+		os.Exit(2)
+	}
+	if *concurrency < 1 || *duration <= 0 || *vitalsBatchSize < 1 {
+		fmt.Fprintln(os.Stderr, "-concurrency, -duration, and -vitals-batch must be positive")
+		os.Exit(2)
+	}
+
+	config := loadtest.Config{
+		TargetURL:       *targetURL,
+		Concurrency:     *concurrency,
+		Duration:        *duration,
+		Mix:             mix,
+		VitalsBatchSize: *vitalsBatchSize,
+		SeedPatients:    *seedPatients,
+		APIKey:          *apiKey,
+		Seed:            *seed,
+		RequestTimeout:  *requestTimeout,
+	}
+
+	// Ctrl+C stops the run early and still prints what was measured
+	signalContext, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Running %s against %s with %d workers\n", config.Duration, config.TargetURL, config.Concurrency)
+	report, runError := loadtest.NewRunner(config).Run(signalContext)
+	if runError != nil {
+		fmt.Fprintln(os.Stderr, "load test failed:", runError)
+		os.Exit(1)
+	}
+
+	if *jsonOutput {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(report)
+		return
+	}
+	report.WriteText(os.Stdout)
+}
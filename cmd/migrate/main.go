@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/config"
+	"github.com/nathannewyen/fhir-health-interop/internal/database"
+	"github.com/nathannewyen/fhir-health-interop/internal/migrations"
+)
+
+// usage describes the subcommands
+const usage = `usage: migrate <command> [flags]
+
+commands:
+  up                  apply every pending migration
+  down [-steps N]     revert the latest N applied migrations (default 1)
+  status              list migrations and when each was applied
+  baseline -version N record migrations up to N as applied without running them,
+                      for databases created by running the SQL files by hand
+
+The database is read from CONFIG_FILE and the POSTGRES_* environment variables, as for the server.`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command := os.Args[1]
+	if !slices.Contains([]string{"up", "down", "status", "baseline"}, command) {
+		fmt.Fprintln(os.Stderr, "unknown command "+strconv.Quote(command))
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	flags := flag.NewFlagSet(command, flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert")
+	baselineVersion := flags.Int("version", 0, "latest migration version already applied by hand")
+	flags.Parse(os.Args[2:])
+	if command == "down" && *steps < 1 {
+		fmt.Fprintln(os.Stderr, "-steps must be positive")
+		os.Exit(2)
+	}
+	if command == "baseline" && *baselineVersion < 1 {
+		fmt.Fprintln(os.Stderr, "-version must be a positive migration version")
+		os.Exit(2)
+	}
+
+	embeddedMigrations, loadError := migrations.Embedded()
+	if loadError != nil {
+		fmt.Fprintln(os.Stderr, "invalid migrations:", loadError)
+		os.Exit(1)
+	}
+
+	serverConfig, configError := config.Load(os.Getenv("CONFIG_FILE"))
+	if configError != nil {
+		fmt.Fprintln(os.Stderr, "invalid configuration:", configError)
+		os.Exit(2)
+	}
+	databaseConnection, connectError := database.NewPostgresConnection(serverConfig.Postgres)
+	if connectError != nil {
+		fmt.Fprintln(os.Stderr, "failed to connect to database:", connectError)
+		os.Exit(1)
+	}
+	defer databaseConnection.Close()
+
+	// Ctrl+C cancels the migration in progress, whose transaction then rolls back
+	signalContext, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	migrator := migrations.NewMigrator(databaseConnection, embeddedMigrations)
+	var commandError error
+	switch command {
+	case "up":
+		var applied []migrations.Migration
+		applied, commandError = migrator.Up(signalContext)
+		printMigrations("Applied", applied)
+	case "down":
+		var reverted []migrations.Migration
+		reverted, commandError = migrator.Down(signalContext, *steps)
+		printMigrations("Reverted", reverted)
+	case "status":
+		var statuses []migrations.Status
+		statuses, commandError = migrator.Status(signalContext)
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05 MST")
+			}
+			fmt.Printf("%03d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	case "baseline":
+		var recorded []migrations.Migration
+		recorded, commandError = migrator.Baseline(signalContext, *baselineVersion)
+		printMigrations("Recorded", recorded)
+	}
+
+	if commandError != nil {
+		fmt.Fprintln(os.Stderr, "migrate "+command+" failed:", commandError)
+		os.Exit(1)
+	}
+}
+
+// printMigrations prints one line per migration, or a note when there were none
+func printMigrations(verb string, affected []migrations.Migration) {
+	if len(affected) == 0 {
+		fmt.Println("Nothing " + strings.ToLower(verb))
+		return
+	}
+	for _, migration := range affected {
+		fmt.Printf("%s %03d_%s\n", verb, migration.Version, migration.Name)
+	}
+}
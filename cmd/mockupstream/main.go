@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/mockupstream"
+)
+
+func main() {
+	defaults := mockupstream.DefaultConfig()
+
+	address := flag.String("addr", ":8090", "address to listen on")
+	patients := flag.Int("patients", defaults.Patients, "canned patients to serve, each with one observation")
+	latency := flag.Duration("latency", defaults.Latency, "delay added to every response")
+	jitter := flag.Duration("jitter", defaults.Jitter, "up to this much extra random delay per response")
+	errorRate := flag.Float64("error-rate", defaults.ErrorRate, "fraction of requests answered with a 500 (0 to 1)")
+	seed := flag.Uint64("seed", defaults.Seed, "random seed for delays and failures")
+	flag.Parse()
+
+	if *patients < 0 || *latency < 0 || *jitter < 0 || *errorRate < 0 || *errorRate > 1 {
+		fmt.Fprintln(os.Stderr, "-patients, -latency, and -jitter must not be negative and -error-rate must be between 0 and 1")
+		os.Exit(2)
+	}
+
+	config := mockupstream.Config{
+		Patients:  *patients,
+		Latency:   *latency,
+		Jitter:    *jitter,
+		ErrorRate: *errorRate,
+		Seed:      *seed,
+	}
+	server := &http.Server{
+		Addr:              *address,
+		Handler:           mockupstream.NewServer(config).Handler(),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	// Ctrl+C stops the server once in-flight requests finish
+	signalContext, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-signalContext.Done()
+		shutdownContext, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownContext)
+	}()
+
+	fmt.Fprintf(os.Stderr, "Mock upstream FHIR server on %s: %d patients, %s+%s latency, %.0f%% errors\n",
+		*address, config.Patients, config.Latency, config.Jitter, config.ErrorRate*100)
+	if serveError := server.ListenAndServe(); serveError != nil && !errors.Is(serveError, http.ErrServerClosed) {
+		fmt.Fprintln(os.Stderr, "mock upstream failed:", serveError)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/capability"
+	"github.com/nathannewyen/fhir-health-interop/internal/sdkgen"
+)
+
+func main() {
+	language := flag.String("lang", "go", "client language: go or typescript")
+	outputPath := flag.String("out", "", "file to write; standard output when empty")
+	packageName := flag.String("package", "fhirclient", "package name of the Go client")
+	flag.Parse()
+
+	var generated []byte
+	var generateError error
+	switch *language {
+	case "go":
+		generated, generateError = sdkgen.GenerateGo(capability.Resources(), *packageName)
+	case "typescript":
+		generated, generateError = sdkgen.GenerateTypeScript(capability.Resources())
+	default:
+		fmt.Fprintln(os.Stderr, "-lang must be go or typescript")
+		os.Exit(2)
+	}
+	if generateError != nil {
+		fmt.Fprintln(os.Stderr, "generation failed:", generateError)
+		os.Exit(1)
+	}
+
+	if *outputPath == "" {
+		os.Stdout.Write(generated)
+		return
+	}
+	if writeError := os.WriteFile(*outputPath, generated, 0o644); writeError != nil {
+		fmt.Fprintln(os.Stderr, "failed to write client:", writeError)
+		os.Exit(1)
+	}
+}
@@ -1,35 +1,115 @@
 package main
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/adt"
+	"github.com/nathannewyen/fhir-health-interop/internal/archival"
+	"github.com/nathannewyen/fhir-health-interop/internal/async"
+	"github.com/nathannewyen/fhir-health-interop/internal/audit"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/authz"
+	"github.com/nathannewyen/fhir-health-interop/internal/cache"
+	"github.com/nathannewyen/fhir-health-interop/internal/capability"
+	"github.com/nathannewyen/fhir-health-interop/internal/ccda"
+	"github.com/nathannewyen/fhir-health-interop/internal/config"
 	"github.com/nathannewyen/fhir-health-interop/internal/database"
+	"github.com/nathannewyen/fhir-health-interop/internal/dedup"
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/demo"
+	"github.com/nathannewyen/fhir-health-interop/internal/deprecation"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
 	"github.com/nathannewyen/fhir-health-interop/internal/handlers"
+	"github.com/nathannewyen/fhir-health-interop/internal/health"
+	"github.com/nathannewyen/fhir-health-interop/internal/hl7"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/idempotency"
+	"github.com/nathannewyen/fhir-health-interop/internal/jobs"
+	"github.com/nathannewyen/fhir-health-interop/internal/journal"
+	"github.com/nathannewyen/fhir-health-interop/internal/locking"
+	"github.com/nathannewyen/fhir-health-interop/internal/mapping"
+	"github.com/nathannewyen/fhir-health-interop/internal/matching"
+	"github.com/nathannewyen/fhir-health-interop/internal/metrics"
 	custommiddleware "github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/migrations"
+	"github.com/nathannewyen/fhir-health-interop/internal/plausibility"
+	"github.com/nathannewyen/fhir-health-interop/internal/quota"
+	"github.com/nathannewyen/fhir-health-interop/internal/ratelimit"
+	"github.com/nathannewyen/fhir-health-interop/internal/reconcile"
+	"github.com/nathannewyen/fhir-health-interop/internal/redact"
+	"github.com/nathannewyen/fhir-health-interop/internal/referencerange"
 	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/rollup"
+	"github.com/nathannewyen/fhir-health-interop/internal/searchcost"
 	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/streaming"
+	"github.com/nathannewyen/fhir-health-interop/internal/subscription"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/nathannewyen/fhir-health-interop/internal/terminology"
+	"github.com/nathannewyen/fhir-health-interop/internal/tracing"
+	"github.com/nathannewyen/fhir-health-interop/internal/units"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/nathannewyen/fhir-health-interop/internal/warmup"
+	"github.com/redis/go-redis/v9"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
 func main() {
 	// Configure zerolog for console output with human-readable format
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stdout})
 
+	// Read the port, log level, and database settings from CONFIG_FILE and the environment
+	serverConfig, configError := config.Load(os.Getenv("CONFIG_FILE"))
+	if configError != nil {
+		log.Fatal().Err(configError).Msg("Invalid server configuration")
+	}
+	zerolog.SetGlobalLevel(serverConfig.Level())
+
+	// Keep each tenant's data in its home region when RESIDENCY_FILE defines regions
+	residencyPolicy := loadResidencyPolicy()
+
 	// Initialize database connection
-	dbConfig := database.PostgresConfig{
-		Host:     "localhost",
-		Port:     "5432",
-		User:     "fhir_user",
-		Password: "fhir_password",
-		DBName:   "fhir_health_db",
+	dbConfig := serverConfig.Postgres
+	if residencyPolicy != nil {
+		// A regional deployment stores data only in its own region's databases
+		_, localRegion := residencyPolicy.LocalRegion()
+		dbConfig = localRegion.Postgres
 	}
 
-	databaseConnection, dbError := database.NewPostgresConnection(dbConfig)
+	// Continue incoming W3C trace context and export spans when an OTLP endpoint is configured
+	shutdownTracing, tracingError := tracing.Setup(context.Background(), tracing.ConfigFromEnvironment())
+	if tracingError != nil {
+		log.Fatal().Err(tracingError).Msg("Invalid OpenTelemetry configuration")
+	}
+	defer func() {
+		flushContext, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelFlush()
+		if flushError := shutdownTracing(flushContext); flushError != nil {
+			log.Error().Err(flushError).Msg("Failed to flush trace spans")
+		}
+	}()
+
+	// Export request and database query metrics for Prometheus; both databases report their query times to it
+	metricsExporter := metrics.NewExporter()
+
+	databaseConnection, dbError := database.NewObservedPostgresConnection(dbConfig, metricsExporter)
 	if dbError != nil {
 		log.Fatal().Err(dbError).Msg("Failed to connect to database")
 	}
@@ -37,85 +117,1687 @@ func main() {
 
 	log.Info().Msg("PostgreSQL connection established")
 
+	// Apply pending schema migrations before anything reads the database when AUTO_MIGRATE is set
+	if parseBoolEnv("AUTO_MIGRATE") {
+		migrateSchema(databaseConnection)
+	}
+
 	// Initialize MongoDB connection
-	mongoConfig := database.MongoConfig{
-		Host:     "localhost",
-		Port:     "27017",
-		User:     "fhir_user",
-		Password: "fhir_password",
-		Database: "admin",
+	mongoConfig := serverConfig.MongoDB
+	if residencyPolicy != nil {
+		_, localRegion := residencyPolicy.LocalRegion()
+		mongoConfig = localRegion.MongoDB
 	}
 
-	mongoDatabase, mongoError := database.NewMongoConnection(mongoConfig)
+	// One client, and so one connection pool, is shared by every MongoDB repository
+	mongoClient, mongoError := database.NewMongoClient(mongoConfig, metricsExporter)
 	if mongoError != nil {
 		log.Fatal().Err(mongoError).Msg("Failed to connect to MongoDB")
 	}
+	defer func() {
+		closeContext, cancelClose := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelClose()
+		if closeError := mongoClient.Close(closeContext); closeError != nil {
+			log.Error().Err(closeError).Msg("Failed to close the MongoDB connection pool")
+		}
+	}()
+	mongoDatabase := mongoClient.Database()
 
 	log.Info().Msg("MongoDB connection established")
 
 	// Initialize repository and service layers
 	patientRepository := repository.NewPostgresPatientRepository(databaseConnection)
-	patientService := service.NewPatientService(patientRepository)
-
+	patientRepository.SetNameSimilarity(nameSimilarity())
 	observationRepository := repository.NewMongoObservationRepository(mongoDatabase)
-	observationService := service.NewObservationService(observationRepository)
+
+	// Serve repeated patient and observation reads from Redis when REPOSITORY_CACHE_REDIS_URL is set; the
+	// services write through the cache, and the jobs that change stored data directly (erasure, identifier
+	// re-keys, reconciliation quarantine) are given cache wrappers that invalidate what they change
+	var patientStore repository.PatientRepository = patientRepository
+	var observationStore repository.ObservationRepository = observationRepository
+	repositoryCache := loadRepositoryCache()
+	if repositoryCache != nil {
+		patientStore = cache.NewPatientRepository(patientRepository, repositoryCache)
+		observationStore = cache.NewObservationRepository(observationRepository, repositoryCache)
+	}
+	patientService := service.NewPatientService(patientStore)
+
+	// Record writes to the change log that backs the differential sync feed
+	changeRepository := repository.NewPostgresChangeRepository(databaseConnection)
+	patientService.SetChangeRepository(changeRepository)
+	syncService := service.NewSyncService(changeRepository)
+
+	clientRepository := repository.NewPostgresClientRepository(databaseConnection)
+	clientRegistrationService := service.NewClientRegistrationService(clientRepository)
+
+	observationService := service.NewObservationService(observationStore)
+	observationService.SetChangeRepository(changeRepository)
+
+	// Practitioners are stored alongside patients so observations can name their performer
+	practitionerService := service.NewPractitionerService(repository.NewPostgresPractitionerRepository(databaseConnection))
+	practitionerService.SetChangeRepository(changeRepository)
+
+	// Encounters group a patient's observations per visit and name the practitioners taking part
+	encounterService := service.NewEncounterService(repository.NewPostgresEncounterRepository(databaseConnection))
+	encounterService.SetChangeRepository(changeRepository)
+
+	// Conditions keep each patient's problem list next to their observations in MongoDB
+	conditionService := service.NewConditionService(repository.NewMongoConditionRepository(mongoDatabase))
+	conditionService.SetChangeRepository(changeRepository)
+
+	// Medication requests carry prescriptions exchanged with e-prescribing systems
+	medicationRequestService := service.NewMedicationRequestService(repository.NewPostgresMedicationRequestRepository(databaseConnection))
+	medicationRequestService.SetChangeRepository(changeRepository)
+
+	// Allergy intolerances sit beside conditions in MongoDB so decision support can pull a patient's allergy list
+	allergyIntoleranceService := service.NewAllergyIntoleranceService(repository.NewMongoAllergyIntoleranceRepository(mongoDatabase))
+	allergyIntoleranceService.SetChangeRepository(changeRepository)
+
+	// Diagnostic reports group stored observations into lab and imaging results, so they live beside them in MongoDB
+	diagnosticReportService := service.NewDiagnosticReportService(repository.NewMongoDiagnosticReportRepository(mongoDatabase), observationService)
+	diagnosticReportService.SetChangeRepository(changeRepository)
+
+	// Immunizations are exchanged with immunization registries, so they sit beside the other relational records in Postgres
+	immunizationService := service.NewImmunizationService(repository.NewPostgresImmunizationRepository(databaseConnection))
+	immunizationService.SetChangeRepository(changeRepository)
+
+	// Record every access to clinical data, and every access the role policy refuses, as an AuditEvent
+	auditEventService := service.NewAuditEventService(repository.NewPostgresAuditEventRepository(databaseConnection))
+	auditor := audit.NewAuditor(auditEventService)
+
+	// Journal observation writes before they reach MongoDB, so writes interrupted by a crash are recovered
+	observationService.SetWriteJournal(repository.NewPostgresWriteJournalRepository(databaseConnection))
+	journalRecoveryJob := journal.NewJob(observationService, writeJournalStaleAfter(2*time.Minute))
+
+	// Keep the expected per-patient observation counts that nightly reconciliation verifies
+	ledgerRepository := repository.NewPostgresLedgerRepository(databaseConnection)
+	observationService.SetLedgerRepository(ledgerRepository)
+	var quarantineStore repository.ObservationQuarantineStore = observationRepository
+	if repositoryCache != nil {
+		quarantineStore = cache.NewObservationQuarantineStore(observationRepository, repositoryCache)
+	}
+	reconciler := reconcile.NewReconciler(patientRepository, quarantineStore, ledgerRepository, parseBoolEnv("RECONCILE_QUARANTINE"))
+
+	// Create the indexes behind observation searches unless the deployment may not change the database
+	if mongoConfig.SkipIndexCreation {
+		log.Info().Msg("Skipping observation index creation")
+	} else {
+		indexContext, cancelIndexes := context.WithTimeout(context.Background(), time.Minute)
+		createdIndexes, indexError := observationRepository.EnsureIndexes(indexContext)
+		cancelIndexes()
+		if indexError != nil {
+			log.Fatal().Err(indexError).Msg("Failed to create observation indexes")
+		}
+		for _, indexName := range createdIndexes {
+			log.Info().Str("index", indexName).Msg("Created observation index")
+		}
+	}
+
+	// Pre-aggregate daily per-patient, per-code observation values for longitudinal charts
+	rollupRepository := repository.NewPostgresRollupRepository(databaseConnection)
+	rollupJob := rollup.NewJob(observationRepository, rollupRepository, positiveIntEnv("ROLLUP_LOOKBACK_DAYS", 3))
+
+	// Move observations older than OBSERVATION_ARCHIVE_AFTER_DAYS into a compressed archive collection every night
+	var archivalJob *archival.Job
+	if os.Getenv("OBSERVATION_ARCHIVE_AFTER_DAYS") != "" {
+		archiveAfter := time.Duration(positiveIntEnv("OBSERVATION_ARCHIVE_AFTER_DAYS", 0)) * 24 * time.Hour
+		archiveContext, cancelArchive := context.WithTimeout(context.Background(), 10*time.Second)
+		if ensureError := observationRepository.EnsureArchiveCollection(archiveContext); ensureError != nil {
+			log.Fatal().Err(ensureError).Msg("Failed to prepare the observation archive")
+		}
+		cancelArchive()
+		observationRepository.SetArchiveAfter(archiveAfter)
+		archivalJob = archival.NewJob(observationRepository, archiveAfter)
+	}
+
+	// Legal holds block deleting held patients and their observations
+	legalHoldService := service.NewLegalHoldService(repository.NewPostgresLegalHoldRepository(databaseConnection), patientRepository)
+	patientService.SetDeletionGuard(legalHoldService)
+	observationService.SetDeletionGuard(legalHoldService)
+
+	// Refuse deleting referenced patients, or delete their observations with them, instead of leaving dangling subjects
+	switch deletePolicy := service.PatientDeletePolicy(os.Getenv("PATIENT_DELETE_POLICY")); deletePolicy {
+	case "", service.PatientDeleteAllow:
+	case service.PatientDeleteBlock, service.PatientDeleteCascade:
+		patientService.SetDeletePolicy(deletePolicy, observationService)
+		log.Info().Str("policy", string(deletePolicy)).Msg("Patient delete policy enabled")
+	default:
+		log.Fatal().Str("policy", string(deletePolicy)).Msg("Invalid PATIENT_DELETE_POLICY; use allow, block, or cascade")
+	}
+
+	// Rewrite patient identifiers in bulk, in rate-limited batches, when MRN systems change
+	rekeyPolicy := service.DefaultRekeyPolicy()
+	rekeyPolicy.BatchSize = positiveIntEnv("REKEY_BATCH_SIZE", rekeyPolicy.BatchSize)
+	rekeyPolicy.BatchInterval = rekeyBatchInterval(rekeyPolicy.BatchInterval)
+	var rekeyRepository repository.IdentifierRekeyRepository = repository.NewPostgresIdentifierRekeyRepository(databaseConnection)
+	if repositoryCache != nil {
+		rekeyRepository = cache.NewIdentifierRekeyRepository(rekeyRepository, repositoryCache)
+	}
+	identifierRekeyService := service.NewIdentifierRekeyService(rekeyRepository, patientService, rekeyPolicy)
+
+	// Erase patients on request: withdraw every store's records, wait out the waiting period, then purge and certify
+	erasurePolicy := service.DefaultErasurePolicy()
+	erasurePolicy.WaitingPeriod = erasureWaitingPeriod(erasurePolicy.WaitingPeriod)
+	var erasureRepository repository.ErasureRepository = repository.NewPostgresErasureRepository(databaseConnection)
+	var observationErasureStore repository.ObservationErasureStore = observationRepository
+	if repositoryCache != nil {
+		erasureRepository = cache.NewErasureRepository(erasureRepository, repositoryCache)
+		observationErasureStore = cache.NewObservationErasureStore(observationRepository, repositoryCache)
+	}
+	erasureService := service.NewErasureService(erasureRepository, patientRepository, erasurePolicy,
+		service.NewObservationErasureParticipant(observationService, observationErasureStore),
+		service.NewPatientErasureParticipant(patientService, erasureRepository),
+		service.NewHistoryErasureParticipant(erasureRepository),
+	)
+	erasureService.SetDeletionGuard(legalHoldService)
+
+	// Warm connection pools and per-connection caches before reporting ready
+	warmupConnections := positiveIntEnv("WARMUP_CONNECTIONS", 2)
+	warmer := warmup.NewWarmer()
+	warmer.Add("postgres", func(ctx context.Context) error {
+		return repository.WarmPostgres(ctx, databaseConnection, warmupConnections)
+	})
+	warmer.Add("mongodb", func(ctx context.Context) error {
+		return observationRepository.Warm(ctx, warmupConnections)
+	})
+	if parseBoolEnv("WARMUP_PRELOAD") {
+		// Read the most recent records so their pages are in the database caches
+		warmer.Add("preload", func(ctx context.Context) error {
+			if _, patientsError := patientRepository.GetAll(ctx, warmupPreloadCount, 0); patientsError != nil {
+				return patientsError
+			}
+			_, observationsError := observationRepository.GetAll(ctx, warmupPreloadCount, 0)
+			return observationsError
+		})
+	}
+
+	// Publish resource events to decoupled side-effect consumers
+	eventBus := events.NewBus()
+	if subscribeError := eventBus.Subscribe("audit-log", 1024, events.AuditLogger()); subscribeError != nil {
+		log.Fatal().Err(subscribeError).Msg("Failed to subscribe audit log consumer")
+	}
+	patientService.SetEventPublisher(eventBus)
+	observationService.SetEventPublisher(eventBus)
+	practitionerService.SetEventPublisher(eventBus)
+	encounterService.SetEventPublisher(eventBus)
+	conditionService.SetEventPublisher(eventBus)
+	medicationRequestService.SetEventPublisher(eventBus)
+	allergyIntoleranceService.SetEventPublisher(eventBus)
+	diagnosticReportService.SetEventPublisher(eventBus)
+	immunizationService.SetEventPublisher(eventBus)
+
+	// Stream resource events to dashboards with bounded per-connection buffers and idle timeouts
+	streamConfig := streaming.DefaultConfig()
+	streamConfig.BufferSize = positiveIntEnv("STREAM_BUFFER_SIZE", streamConfig.BufferSize)
+	streamHub := streaming.NewHub(streamConfig)
+	eventStreamHandler := handlers.NewEventStreamHandler(streamHub)
+	if subscribeError := eventBus.Subscribe("event-stream", 1024, eventStreamHandler.Consumer()); subscribeError != nil {
+		log.Fatal().Err(subscribeError).Msg("Failed to subscribe event stream consumer")
+	}
+	observationPushHandler := handlers.NewObservationPushHandler(streamHub, observationService)
+	if subscribeError := eventBus.Subscribe("observation-push", 1024, observationPushHandler.Consumer()); subscribeError != nil {
+		log.Fatal().Err(subscribeError).Msg("Failed to subscribe observation push consumer")
+	}
+
+	// Retry failed outbound deliveries from a persistent queue shared by webhooks, subscriptions, and event publishing
+	deliveryPolicy := delivery.DefaultPolicy()
+	deliveryPolicy.MaxAttempts = positiveIntEnv("DELIVERY_MAX_ATTEMPTS", deliveryPolicy.MaxAttempts)
+	deliveryPolicy.RatePerSecond = float64(positiveIntEnv("DELIVERY_RATE_PER_SECOND", int(deliveryPolicy.RatePerSecond)))
+	deliveryQueue := delivery.NewQueue(repository.NewPostgresDeliveryRepository(databaseConnection), deliveryPolicy)
+	deliveryQueue.RegisterSender(delivery.KindWebhook, delivery.NewHTTPSender())
+	deliveryQueue.RegisterSender(adt.KindMLLP, adt.NewMLLPSender())
+
+	webhookURLs, webhooksError := delivery.ParseWebhookURLs(os.Getenv("WEBHOOK_URLS"))
+	if webhooksError != nil {
+		log.Fatal().Err(webhooksError).Msg("Invalid WEBHOOK_URLS")
+	}
+	if len(webhookURLs) > 0 {
+		if subscribeError := eventBus.Subscribe("webhooks", 1024, delivery.EventWebhooks(deliveryQueue, webhookURLs)); subscribeError != nil {
+			log.Fatal().Err(subscribeError).Msg("Failed to subscribe webhook consumer")
+		}
+	}
+
+	// Publish resource changes to Kafka through an outbox written in each change's transaction, so none are lost
+	kafkaBrokers, brokersError := events.ParseKafkaBrokers(os.Getenv("KAFKA_BROKERS"))
+	if brokersError != nil {
+		log.Fatal().Err(brokersError).Msg("Invalid KAFKA_BROKERS")
+	}
+	var kafkaProducer *events.KafkaProducer
+	var outboxRelay *events.Relay
+	if len(kafkaBrokers) > 0 {
+		outboxRepository := repository.NewPostgresOutboxRepository(databaseConnection)
+		changeRepository.AddRecordListener(events.NewOutbox(outboxRepository, os.Getenv("KAFKA_TOPIC_PREFIX")))
+		relayPolicy := events.DefaultRelayPolicy()
+		relayPolicy.BatchSize = positiveIntEnv("KAFKA_BATCH_SIZE", relayPolicy.BatchSize)
+		kafkaProducer = events.NewKafkaProducer(kafkaBrokers)
+		outboxRelay = events.NewRelay(outboxRepository, kafkaProducer, relayPolicy)
+		log.Info().Strs("brokers", kafkaBrokers).Msg("Kafka event publishing enabled")
+	}
+
+	// Notify FHIR Subscriptions of matching writes; notifications are retried and dead-lettered by the delivery queue
+	subscriptionRepository := repository.NewPostgresSubscriptionRepository(databaseConnection)
+	subscriptionService := service.NewSubscriptionService(subscriptionRepository)
+	subscriptionNotifier := subscription.NewNotifier(subscriptionRepository, deliveryQueue)
+	subscriptionService.SetChangeListener(subscriptionNotifier)
+	deliveryQueue.RegisterSender(subscription.KindSubscription, subscription.NewSender(subscriptionRepository))
+	if subscribeError := eventBus.Subscribe("subscriptions", 1024, subscriptionNotifier.Handle); subscribeError != nil {
+		log.Fatal().Err(subscribeError).Msg("Failed to subscribe subscription notifier")
+	}
+
+	// Send HL7v2 ADT messages for patient changes to systems that only consume ADT
+	if adtConfig := loadADTConfig(); len(adtConfig.Destinations) > 0 {
+		adtFeed, feedError := adt.NewFeed(adtConfig, deliveryQueue)
+		if feedError != nil {
+			log.Fatal().Err(feedError).Msg("Invalid ADT destinations")
+		}
+		// Messages are queued in the patient write's transaction so none are lost or sent for rolled-back writes
+		patientService.AddWriteListener(adtFeed)
+		log.Info().Int("destinations", len(adtConfig.Destinations)).Msg("ADT feed enabled")
+	}
+
+	// Reject writes with unmappable data instead of storing them with warnings
+	if parseBoolEnv("STRICT_MAPPING") {
+		patientService.SetStrictMapping(true)
+		observationService.SetStrictMapping(true)
+		practitionerService.SetStrictMapping(true)
+		encounterService.SetStrictMapping(true)
+		conditionService.SetStrictMapping(true)
+		medicationRequestService.SetStrictMapping(true)
+		allergyIntoleranceService.SetStrictMapping(true)
+		diagnosticReportService.SetStrictMapping(true)
+		immunizationService.SetStrictMapping(true)
+		log.Info().Msg("Strict mapping enabled: writes with unmappable data are rejected")
+	}
+
+	// Apply site-specific mapping rules without forking the mappers
+	if mappingRules := loadMappingRules(); mappingRules != nil {
+		patientService.AddMappingHook(mappingRules.PatientHook())
+		observationService.AddMappingHook(mappingRules.ObservationHook())
+		patientService.SetIdentifierSystemNormalizer(mappingRules)
+		log.Info().Msg("Site-specific mapping rules loaded")
+	}
+
+	// Keep device glitches out of clinical data by checking vital signs against plausible ranges
+	if !parseBoolEnv("DISABLE_PLAUSIBILITY_CHECKS") {
+		observationService.SetPlausibilityChecker(plausibility.NewChecker(loadPlausibilityRules()))
+	}
+
+	// Refuse observations of patients that do not exist instead of storing dangling subject references
+	if parseBoolEnv("OBSERVATION_REFERENTIAL_INTEGRITY") {
+		observationService.SetReferentialIntegrity(patientStore)
+		log.Info().Msg("Observation referential integrity enabled: subjects must be stored patients")
+	}
+
+	// Fill in code and category displays that writes leave out, for viewers that show only the display
+	if !parseBoolEnv("DISABLE_DISPLAY_BACKFILL") {
+		observationService.SetTerminology(loadTerminology())
+	}
+
+	// Catch mistyped and made-up observation codes before they reach clinical data
+	if validationAction := terminologyValidationAction(); validationAction != "" {
+		observationService.SetCodeValidation(loadCodeSystemLookup(), validationAction)
+		log.Info().Str("action", validationAction).Msg("Observation code validation enabled")
+	}
+
+	// Check observation units against UCUM and store values converted to canonical units for range searches
+	unitValidation := unitValidationAction()
+	storeCanonicalUnits := parseBoolEnv("UNIT_NORMALIZATION")
+	if unitValidation != "" || storeCanonicalUnits {
+		observationService.SetUnitNormalization(units.NewConverter(loadUnitTable()), unitValidation, storeCanonicalUnits)
+		log.Info().Str("action", unitValidation).Bool("store_canonical", storeCanonicalUnits).Msg("Observation unit normalization enabled")
+	}
+
+	// Interpret values as high, low, or normal from reference ranges when the client leaves the interpretation out
+	if !parseBoolEnv("DISABLE_AUTO_INTERPRETATION") {
+		observationService.SetInterpreter(referencerange.NewInterpreter(loadReferenceRanges()))
+	}
+
+	// Shared operational state for maintenance mode and connection draining
+	operationalState := custommiddleware.NewOperationalState(30 * time.Second)
+
+	// Resolve tenants from API keys and enforce their quotas
+	tenantAPIKeys, apiKeysError := tenant.ParseAPIKeys(os.Getenv("TENANT_API_KEYS"))
+	if apiKeysError != nil {
+		log.Fatal().Err(apiKeysError).Msg("Invalid TENANT_API_KEYS")
+	}
+	tenantResolver := tenant.NewResolver(tenantAPIKeys)
+
+	// Grant roles such as compliance to specific API keys
+	apiKeyRoles, rolesError := auth.ParseKeyRoles(os.Getenv("API_KEY_ROLES"))
+	if rolesError != nil {
+		log.Fatal().Err(rolesError).Msg("Invalid API_KEY_ROLES")
+	}
+	roleResolver := auth.NewRoleResolver(apiKeyRoles)
+	// Quota counters live in PostgreSQL, checked and updated atomically, so every instance enforces the same limits
+	quotaEnforcer := quota.NewEnforcer(loadQuotaConfig(), repository.NewPostgresQuotaUsageRepository(databaseConnection))
+
+	// Announce deprecated routes and parameters and count who still uses them
+	deprecationRegistry := deprecation.NewRegistry(loadDeprecationConfig())
+
+	// Absolute links use PUBLIC_BASE_URL when set, otherwise the proxy's X-Forwarded-Proto and X-Forwarded-Host
+	var publicBaseURL *url.URL
+	if rawPublicBaseURL := os.Getenv("PUBLIC_BASE_URL"); rawPublicBaseURL != "" {
+		parsedBaseURL, parseError := custommiddleware.ParsePublicBaseURL(rawPublicBaseURL)
+		if parseError != nil {
+			log.Fatal().Err(parseError).Msg("Invalid PUBLIC_BASE_URL")
+		}
+		publicBaseURL = parsedBaseURL
+	}
 
 	// Create a new Chi router instance
 	router := chi.NewRouter()
 
-	// Add middleware in order: RequestID -> Logger -> ErrorHandler -> Recoverer -> Validator
+	// Add middleware in order: RequestID -> PublicOrigin -> Tracing -> Metrics -> Logger -> BodyLogger -> ErrorHandler -> Recoverer -> Maintenance -> Tenant -> Roles -> Tokens -> RateLimit -> RoutePolicy -> RolePolicy -> Residency -> Idempotency -> Deprecation -> Validator -> Quota -> Async
 	router.Use(custommiddleware.RequestID)
+	router.Use(custommiddleware.PublicOrigin(publicBaseURL))
+	router.Use(tracing.Middleware)
+	router.Use(metricsExporter.Middleware)
 	router.Use(custommiddleware.Logger(log.Logger))
+	if bodyLogger := loadBodyLogger(); bodyLogger != nil {
+		router.Use(bodyLogger)
+	}
 	router.Use(custommiddleware.ErrorHandler)
 	router.Use(middleware.Recoverer)
-	router.Use(custommiddleware.FHIRValidator)
+	router.Use(custommiddleware.Maintenance(operationalState))
+	router.Use(tenantResolver.Middleware)
+	router.Use(roleResolver.Middleware)
+	if tokenAuthenticator := loadTokenAuthenticator(); tokenAuthenticator != nil {
+		router.Use(tokenAuthenticator.Middleware)
+	}
+	if rateLimiter := loadRateLimiter(); rateLimiter != nil {
+		router.Use(rateLimiter.Middleware)
+	}
+	if routePolicy := loadRoutePolicy(); routePolicy != nil {
+		router.Use(auth.PolicyMiddleware(routePolicy))
+	}
+	if rolePolicy := loadRolePolicy(); rolePolicy != nil {
+		router.Use(authz.Middleware(rolePolicy, auditor))
+	}
+	router.Use(auditor.Middleware)
+	if residencyPolicy != nil {
+		router.Use(residency.Middleware(residencyPolicy))
+	}
+	// Replay responses to POSTs retried with an Idempotency-Key before quotas or handlers see them again
+	idempotencyPolicy := idempotency.DefaultPolicy()
+	idempotencyPolicy.TTL = idempotencyKeyTTL()
+	idempotencyKeeper := idempotency.NewKeeper(repository.NewPostgresIdempotencyRepository(databaseConnection), idempotencyPolicy)
+	router.Use(idempotencyKeeper.Middleware)
+	router.Use(deprecation.Middleware(deprecationRegistry, router))
+	// Exhaustive validation checks observation subjects the way observation writes resolve them
+	subjectReferenceChecker := handlers.NewSubjectReferenceChecker(patientService)
+	if residencyPolicy != nil {
+		subjectReferenceChecker.SetResidencyPolicy(residencyPolicy)
+	}
+	router.Use(custommiddleware.NewFHIRValidatorWithOptions(custommiddleware.ValidatorOptions{
+		Limits:           loadResourceLimits(),
+		StrictParsing:    parseBoolEnv("STRICT_JSON_PARSING"),
+		Exhaustive:       parseBoolEnv("EXHAUSTIVE_VALIDATION"),
+		ReferenceChecker: subjectReferenceChecker.Check,
+	}))
+	router.Use(quota.Middleware(quotaEnforcer))
+	// Run searches and operations sent with Prefer: respond-async in the background, replaying them through the
+	// handlers after this point as the caller who sent them; they run as jobs on the background job queue
+	jobPolicy := jobs.DefaultPolicy()
+	jobPolicy.Workers = positiveIntEnv("JOB_WORKERS", jobPolicy.Workers)
+	jobPolicy.Retention = searchExportDurationEnv("JOB_RETENTION", jobPolicy.Retention)
+	jobQueue := jobs.NewQueue(repository.NewPostgresJobRepository(databaseConnection), jobPolicy)
+	asyncRunner := async.NewRunner(jobQueue, async.DefaultPolicy())
+	jobQueue.RegisterHandler(async.JobKind, asyncRunner)
+	router.Use(asyncRunner.Middleware)
 
 	// Initialize handlers
 	healthHandler := handlers.NewHealthHandler()
+	healthHandler.SetMongoPool(mongoClient)
+
+	// Readiness pings each database, so an instance that lost one is rotated out of the load balancer
+	dependencyChecker := health.NewChecker(healthCheckTimeout())
+	dependencyChecker.Add("postgres", databaseConnection.PingContext)
+	dependencyChecker.Add("mongodb", mongoClient.Ping)
+	healthHandler.SetDependencyChecker(dependencyChecker)
+	metadataHandler := handlers.NewMetadataHandler(capability.Statement(capability.Resources(), time.Now()))
+	readinessHandler := handlers.NewReadinessHandler(warmer)
 	patientHandler := handlers.NewPatientHandlerWithService(patientService)
-	samplePatientHandler := handlers.NewPatientHandler()
+
+	// Advisory edit locks keep clerks on different integrations from clobbering each other; they are kept in
+	// PostgreSQL so every server behind the load balancer sees the same holder
+	lockManager := locking.NewManager(repository.NewPostgresEditLockRepository(databaseConnection))
+	patientHandler.SetLockManager(lockManager)
+	lockHandler := handlers.NewLockHandler(lockManager)
 	observationHandler := handlers.NewObservationHandler(observationService)
+	practitionerHandler := handlers.NewPractitionerHandler(practitionerService)
+	subscriptionHandler := handlers.NewSubscriptionHandler(subscriptionService)
+	encounterHandler := handlers.NewEncounterHandler(encounterService)
+	conditionHandler := handlers.NewConditionHandler(conditionService)
+	medicationRequestHandler := handlers.NewMedicationRequestHandler(medicationRequestService)
+	allergyIntoleranceHandler := handlers.NewAllergyIntoleranceHandler(allergyIntoleranceService)
+	diagnosticReportHandler := handlers.NewDiagnosticReportHandler(diagnosticReportService)
+	immunizationHandler := handlers.NewImmunizationHandler(immunizationService)
+	auditEventHandler := handlers.NewAuditEventHandler(auditEventService)
+	if residencyPolicy != nil {
+		observationHandler.SetResidencyPolicy(residencyPolicy)
+		encounterHandler.SetResidencyPolicy(residencyPolicy)
+		conditionHandler.SetResidencyPolicy(residencyPolicy)
+		medicationRequestHandler.SetResidencyPolicy(residencyPolicy)
+		allergyIntoleranceHandler.SetResidencyPolicy(residencyPolicy)
+		diagnosticReportHandler.SetResidencyPolicy(residencyPolicy)
+		immunizationHandler.SetResidencyPolicy(residencyPolicy)
+	}
+
+	// Point-in-time reads are answered from the snapshots kept in the change log
+	historyService := service.NewHistoryService(changeRepository)
+	patientHandler.SetHistoryService(historyService)
+	observationHandler.SetHistoryService(historyService)
+
+	// $everything, _include, and _revinclude read Postgres and MongoDB in parallel, degrading to partial results
+	compartmentService := service.NewCompartmentService(patientService, observationService, service.DefaultCompartmentPolicy())
+	compartmentService.SetSources(service.CompartmentSources{
+		AllergyIntolerances: allergyIntoleranceService,
+		Conditions:          conditionService,
+		DiagnosticReports:   diagnosticReportService,
+		Encounters:          encounterService,
+		Immunizations:       immunizationService,
+		MedicationRequests:  medicationRequestService,
+	})
+	compartmentService.SetChangedResources(changeRepository)
+	patientHandler.SetCompartmentService(compartmentService)
+	observationHandler.SetCompartmentService(compartmentService)
+
+	// $health-export converts vital signs for the companion app to save in Apple Health or Google Fit
+	patientHandler.SetHealthExportService(service.NewHealthExportService(patientService, observationService))
+
+	// $merge folds a duplicate patient into the surviving one, moving its observations and auditing both patients
+	patientMergeService := service.NewPatientMergeService(patientService, observationService)
+	patientMergeService.SetAuditRecorder(auditEventService)
+	patientHandler.SetMergeService(patientMergeService)
+
+	// $match ranks stored patients that may be the patient a registration desk describes
+	patientHandler.SetMatchService(service.NewPatientMatchService(patientService, loadPatientMatchConfig()))
+
+	syncHandler := handlers.NewSyncHandler(syncService)
+	rollupHandler := handlers.NewRollupHandler(service.NewRollupService(rollupRepository), rollupJob)
+
+	// Search exports materialize whole result sets into encrypted files served over signed, expiring links
+	var searchExportService *service.SearchExportService
+	var searchExportHandler *handlers.SearchExportHandler
+	if rawExportKey := os.Getenv("EXPORT_KEY"); rawExportKey != "" {
+		exportKey, decodeError := base64.StdEncoding.DecodeString(rawExportKey)
+		if decodeError != nil {
+			log.Fatal().Err(decodeError).Msg("EXPORT_KEY must be base64")
+		}
+		exportPolicy := service.DefaultSearchExportPolicy()
+		exportPolicy.LinkTTL = searchExportDurationEnv("EXPORT_LINK_TTL", exportPolicy.LinkTTL)
+		exportPolicy.Retention = searchExportDurationEnv("EXPORT_RETENTION", exportPolicy.Retention)
+		var exportServiceError error
+		searchExportService, exportServiceError = service.NewSearchExportService(repository.NewPostgresSearchExportRepository(databaseConnection), patientService, observationService, exportKey, exportPolicy)
+		if exportServiceError != nil {
+			log.Fatal().Err(exportServiceError).Msg("Invalid EXPORT_KEY")
+		}
+		searchExportHandler = handlers.NewSearchExportHandler(searchExportService)
+	}
+
+	// Bulk Data $export writes every patient and their compartments to NDJSON files under BULK_EXPORT_DIR,
+	// each export as a bulk-export job on the background job queue
+	var bulkExportService *service.BulkExportService
+	var bulkExportHandler *handlers.BulkExportHandler
+	if bulkExportDirectory := os.Getenv("BULK_EXPORT_DIR"); bulkExportDirectory != "" {
+		bulkExportPolicy := service.DefaultBulkExportPolicy()
+		bulkExportPolicy.Retention = searchExportDurationEnv("BULK_EXPORT_RETENTION", bulkExportPolicy.Retention)
+		var bulkExportError error
+		bulkExportService, bulkExportError = service.NewBulkExportService(repository.NewPostgresBulkExportRepository(databaseConnection), changeRepository, service.BulkExportSources{
+			Patients:            patientService,
+			AllergyIntolerances: allergyIntoleranceService,
+			Conditions:          conditionService,
+			DiagnosticReports:   diagnosticReportService,
+			Encounters:          encounterService,
+			Immunizations:       immunizationService,
+			MedicationRequests:  medicationRequestService,
+			Observations:        observationService,
+		}, jobQueue, bulkExportDirectory, bulkExportPolicy)
+		if bulkExportError != nil {
+			log.Fatal().Err(bulkExportError).Msg("Invalid BULK_EXPORT_DIR")
+		}
+		jobQueue.RegisterHandler(service.BulkExportJobKind, bulkExportService)
+		bulkExportHandler = handlers.NewBulkExportHandler(bulkExportService)
+	}
+
+	// Expose opaque, tenant-scoped IDs when the API is opened to third parties
+	if idSecret := os.Getenv("ID_OBFUSCATION_SECRET"); idSecret != "" {
+		idCodec, codecError := idcodec.NewHMACCodec([]byte(idSecret))
+		if codecError != nil {
+			log.Fatal().Err(codecError).Msg("Invalid ID_OBFUSCATION_SECRET")
+		}
+		patientHandler.SetIDCodec(idCodec)
+		observationHandler.SetIDCodec(idCodec)
+		practitionerHandler.SetIDCodec(idCodec)
+		encounterHandler.SetIDCodec(idCodec)
+		conditionHandler.SetIDCodec(idCodec)
+		medicationRequestHandler.SetIDCodec(idCodec)
+		allergyIntoleranceHandler.SetIDCodec(idCodec)
+		diagnosticReportHandler.SetIDCodec(idCodec)
+		immunizationHandler.SetIDCodec(idCodec)
+		lockHandler.SetIDCodec(idCodec)
+		syncHandler.SetIDCodec(idCodec)
+		rollupHandler.SetIDCodec(idCodec)
+		observationPushHandler.SetIDCodec(idCodec)
+		subjectReferenceChecker.SetIDCodec(idCodec)
+		if searchExportHandler != nil {
+			searchExportHandler.SetIDCodec(idCodec)
+			searchExportService.SetExposer(handlers.ExportExposer(idCodec))
+		}
+		if bulkExportService != nil {
+			bulkExportService.SetExposer(handlers.ExportExposer(idCodec))
+		}
+	}
+
+	// Reject or downgrade searches whose estimated cost would burden shared databases
+	if !parseBoolEnv("DISABLE_SEARCH_GUARDRAILS") {
+		searchPolicy := searchcost.DefaultPolicy()
+		patientHandler.SetSearchPolicy(searchPolicy)
+		observationHandler.SetSearchPolicy(searchPolicy)
+	}
+
+	// Track search parameter usage to guide indexing and deprecation decisions
+	searchRecorder := metrics.NewSearchRecorder(map[string][]string{
+		"Patient":            utils.PatientSearchParameters,
+		"Observation":        utils.ObservationSearchParameters,
+		"Practitioner":       utils.PractitionerSearchParameters,
+		"Encounter":          utils.EncounterSearchParameters,
+		"Condition":          utils.ConditionSearchParameters,
+		"MedicationRequest":  utils.MedicationRequestSearchParameters,
+		"AllergyIntolerance": utils.AllergyIntoleranceSearchParameters,
+		"DiagnosticReport":   utils.DiagnosticReportSearchParameters,
+		"Immunization":       utils.ImmunizationSearchParameters,
+		"AuditEvent":         utils.AuditEventSearchParameters,
+		"Subscription":       utils.SubscriptionSearchParameters,
+	})
+	searchMetricsHandler := handlers.NewSearchMetricsHandler(searchRecorder)
+
+	// Collapse identical concurrent searches (e.g. dashboards refreshing together) into one repository call
+	if !parseBoolEnv("DISABLE_SEARCH_DEDUPLICATION") {
+		searchGroup := dedup.NewGroup()
+		patientService.SetSearchGroup(searchGroup)
+		observationService.SetSearchGroup(searchGroup)
+		searchMetricsHandler.SetSearchGroup(searchGroup)
+	}
+
+	// Track which elements clients ask for and receive to guide _summary defaults and payload trimming
+	elementRecorder := metrics.NewElementRecorder(map[string][]string{
+		"Patient":            metrics.ResourceElements(fhir.Patient{}),
+		"Observation":        metrics.ResourceElements(fhir.Observation{}),
+		"Practitioner":       metrics.ResourceElements(fhir.Practitioner{}),
+		"Encounter":          metrics.ResourceElements(fhir.Encounter{}),
+		"Condition":          metrics.ResourceElements(fhir.Condition{}),
+		"MedicationRequest":  metrics.ResourceElements(fhir.MedicationRequest{}),
+		"AllergyIntolerance": metrics.ResourceElements(fhir.AllergyIntolerance{}),
+		"DiagnosticReport":   metrics.ResourceElements(fhir.DiagnosticReport{}),
+		"Immunization":       metrics.ResourceElements(fhir.Immunization{}),
+	}, loadElementUsageConfig())
+	elementUsageHandler := handlers.NewElementUsageHandler(elementRecorder)
+	operationsHandler := handlers.NewOperationsHandler(operationalState)
+	clientRegistrationHandler := handlers.NewClientRegistrationHandler(clientRegistrationService)
+	quotaHandler := handlers.NewQuotaHandler(quotaEnforcer)
+	eventsHandler := handlers.NewEventsHandler(eventBus)
+	if outboxRelay != nil {
+		eventsHandler.SetRelay(outboxRelay)
+	}
+	reconciliationHandler := handlers.NewReconciliationHandler(reconciler)
+	legalHoldHandler := handlers.NewLegalHoldHandler(legalHoldService)
+	deliveryHandler := handlers.NewDeliveryHandler(deliveryQueue)
+	jobHandler := handlers.NewJobHandler(jobQueue)
+	deprecationHandler := handlers.NewDeprecationHandler(deprecationRegistry)
+	identifierRekeyHandler := handlers.NewIdentifierRekeyHandler(identifierRekeyService)
+	erasureHandler := handlers.NewErasureHandler(erasureService)
+	hl7Ingester := hl7.NewIngester(patientService)
+	hl7Ingester.SetObservationStore(observationService, changeRepository)
+	hl7Handler := handlers.NewHL7Handler(hl7Ingester)
+	ccdaImportHandler := handlers.NewCCDAImportHandler(ccda.NewImporter(patientService, conditionService, observationService, changeRepository))
 
 	// Register health check endpoint
 	router.Get("/health", healthHandler.Check)
+	router.Get("/ready", readinessHandler.Check)
+	router.Get("/health/live", healthHandler.Live)
+	router.Get("/health/ready", healthHandler.Ready)
+	router.Method(http.MethodGet, "/metrics", metricsExporter.Handler())
+
+	// Register admin endpoints
+	router.Get("/admin/search-metrics", searchMetricsHandler.Report)
+	router.Get("/admin/element-usage", elementUsageHandler.Report)
+	router.Get("/admin/operations", operationsHandler.Status)
+	router.Put("/admin/operations/maintenance", operationsHandler.SetMaintenance)
+	router.Post("/admin/operations/drain", operationsHandler.Drain)
+	router.Delete("/admin/operations/drain", operationsHandler.Resume)
+	router.Get("/admin/clients", clientRegistrationHandler.List)
+	router.Post("/admin/clients/{clientId}/approve", clientRegistrationHandler.Approve)
+	router.Post("/admin/clients/{clientId}/reject", clientRegistrationHandler.Reject)
+	router.Get("/admin/tenants/usage", quotaHandler.AllUsage)
+	router.Get("/admin/events", eventsHandler.Stats)
+	router.Get("/admin/streams", eventStreamHandler.Stats)
+	router.Get("/admin/tenants/{tenantId}/usage", quotaHandler.TenantUsage)
+	router.Get("/admin/reconciliation", reconciliationHandler.LastReport)
+	router.Post("/admin/reconciliation/run", reconciliationHandler.Run)
+	router.Get("/admin/patients/{id}/legal-hold", legalHoldHandler.Status)
+	router.Put("/admin/patients/{id}/legal-hold", legalHoldHandler.Place)
+	router.Delete("/admin/patients/{id}/legal-hold", legalHoldHandler.Release)
+	router.Post("/admin/patients/{id}/erasure", erasureHandler.Request)
+	router.Get("/admin/erasures/{id}", erasureHandler.Status)
+	router.Post("/admin/erasures/{id}/cancel", erasureHandler.Cancel)
+	router.Get("/admin/erasures/{id}/certificate", erasureHandler.Certificate)
+	router.Get("/admin/deprecations", deprecationHandler.Usage)
+	router.Get("/admin/deliveries", deliveryHandler.Stats)
+	router.Get("/admin/deliveries/dead-letter", deliveryHandler.DeadLetters)
+	router.Post("/admin/deliveries/dead-letter/{id}/retry", deliveryHandler.Retry)
+	router.Delete("/admin/deliveries/dead-letter/{id}", deliveryHandler.Discard)
+	router.Get("/admin/jobs", jobHandler.List)
+	router.Get("/admin/jobs/{id}", jobHandler.Get)
+	router.Post("/admin/jobs/{id}/cancel", jobHandler.Cancel)
+	router.Post("/admin/identifier-rekeys", identifierRekeyHandler.Start)
+	router.Get("/admin/identifier-rekeys/{id}", identifierRekeyHandler.Status)
+	router.Get("/admin/identifier-rekeys/{id}/audit", identifierRekeyHandler.Audit)
+	router.Get("/admin/identifier-rekeys/{id}/provenance", identifierRekeyHandler.Provenance)
+	router.Post("/admin/identifier-rekeys/{id}/rollback", identifierRekeyHandler.Rollback)
+	router.Get("/admin/rollups", rollupHandler.Status)
+	router.Post("/admin/rollups/backfill", rollupHandler.Backfill)
+
+	// Register self-service OAuth2 client registration
+	router.Post("/oauth/register", clientRegistrationHandler.Register)
+
+	// Register demo sample endpoints only when demo mode is enabled
+	demoMode := isDemoModeEnabled()
+	if demoMode {
+		demo.NewHandler().RegisterRoutes(router)
+		log.Warn().Msg("Demo mode enabled: serving synthetic sample resources")
+	}
+
+	// Register the NDJSON resource event stream
+	router.Get("/stream/events", eventStreamHandler.Stream)
+
+	// Register the WebSocket push of new observations for followed patients
+	router.Get("/ws", observationPushHandler.Serve)
+
+	// Register differential sync endpoint
+	router.Get("/sync/changes", syncHandler.Changes)
+
+	// Register advisory patient edit lock endpoints
+	router.Post("/locks/Patient/{id}", lockHandler.Acquire)
+	router.Get("/locks/Patient/{id}", lockHandler.Get)
+	router.Delete("/locks/Patient/{id}", lockHandler.Release)
+
+	// Register inbound HL7 v2 ADT messages, upserted as patients through the patient service
+	router.Post("/hl7/v2", hl7Handler.Receive)
+
+	// Register C-CDA document import, creating the patient, problems, and results through their services
+	router.Post("/import/ccda", ccdaImportHandler.Import)
+
+	// Register search export endpoints when an export key is configured
+	if searchExportHandler != nil {
+		router.Post("/exports", searchExportHandler.Create)
+		router.Get("/exports/{id}", searchExportHandler.Status)
+		router.Get("/exports/{id}/download", searchExportHandler.Download)
+	}
+
+	// Register the CapabilityStatement, which the generated client SDKs are kept in sync with
+	router.Get("/fhir/metadata", metadataHandler.Capabilities)
+
+	// Register transaction and batch Bundles, whose entries are served by the routes below
+	router.Post("/fhir", handlers.NewBundleHandler(router, changeRepository).Process)
+
+	// Register the status URLs of requests sent with Prefer: respond-async
+	asyncRequestHandler := handlers.NewAsyncRequestHandler(asyncRunner)
+	router.Get("/fhir/async/{id}", asyncRequestHandler.Status)
+	router.Delete("/fhir/async/{id}", asyncRequestHandler.Cancel)
+
+	// Register Bulk Data $export endpoints when a bulk export directory is configured
+	if bulkExportHandler != nil {
+		router.Get("/fhir/Patient/$export", bulkExportHandler.KickOff)
+		router.Get("/fhir/bulk-export/{id}", bulkExportHandler.Status)
+		router.Delete("/fhir/bulk-export/{id}", bulkExportHandler.Cancel)
+		router.Get("/fhir/bulk-export/{id}/file/{type}", bulkExportHandler.File)
+	}
 
 	// Register FHIR Patient endpoints
-	router.Get("/fhir/Patient/sample", samplePatientHandler.GetSamplePatient)
 	router.Post("/fhir/Patient", patientHandler.Create)
-	router.Get("/fhir/Patient/{id}", patientHandler.GetByID)
-	router.Get("/fhir/Patient", patientHandler.GetAll)
+	router.Get("/fhir/Patient/{id}", elementRecorder.Instrument("Patient", patientHandler.GetByID))
+	router.Get("/fhir/Patient", elementRecorder.Instrument("Patient", searchRecorder.Instrument("Patient", patientHandler.GetAll)))
 	router.Put("/fhir/Patient/{id}", patientHandler.Update)
 	router.Delete("/fhir/Patient/{id}", patientHandler.Delete)
+	router.Get("/fhir/Patient/{id}/$snapshot", patientHandler.Snapshot)
+	router.Get("/fhir/Patient/{id}/_history", patientHandler.History)
+	router.Get("/fhir/Patient/{id}/_history/{version}", patientHandler.VRead)
+	router.Get("/fhir/Patient/{id}/$everything", patientHandler.Everything)
+	router.Get("/fhir/Patient/{id}/$health-export", patientHandler.HealthExport)
+	router.Get("/fhir/Patient/{id}/$avatar", patientHandler.Avatar)
+	router.Get("/fhir/Patient/{id}/$meta", patientHandler.Meta)
+	router.Post("/fhir/Patient/{id}/$meta-add", patientHandler.MetaAdd)
+	router.Post("/fhir/Patient/{id}/$meta-delete", patientHandler.MetaDelete)
+	router.Post("/fhir/Patient/$merge", patientHandler.Merge)
+	router.Post("/fhir/Patient/$match", patientHandler.Match)
 
 	// Register FHIR Observation endpoints
 	router.Post("/fhir/Observation", observationHandler.Create)
-	router.Get("/fhir/Observation/{id}", observationHandler.GetByID)
-	router.Get("/fhir/Observation", observationHandler.GetAll)
+	router.Get("/fhir/Observation/$daily-rollup", rollupHandler.Query)
+	router.Get("/fhir/Observation/$lastn", observationHandler.LastN)
+	router.Get("/fhir/Observation/{id}", elementRecorder.Instrument("Observation", observationHandler.GetByID))
+	router.Get("/fhir/Observation", elementRecorder.Instrument("Observation", searchRecorder.Instrument("Observation", observationHandler.GetAll)))
 	router.Put("/fhir/Observation/{id}", observationHandler.Update)
 	router.Delete("/fhir/Observation/{id}", observationHandler.Delete)
+	router.Get("/fhir/Observation/{id}/$meta", observationHandler.Meta)
+	router.Post("/fhir/Observation/{id}/$meta-add", observationHandler.MetaAdd)
+	router.Post("/fhir/Observation/{id}/$meta-delete", observationHandler.MetaDelete)
+	router.Get("/fhir/Observation/{id}/_history", observationHandler.History)
+	router.Get("/fhir/Observation/{id}/_history/{version}", observationHandler.VRead)
+
+	// Register FHIR Practitioner endpoints
+	router.Post("/fhir/Practitioner", practitionerHandler.Create)
+	router.Get("/fhir/Practitioner/{id}", elementRecorder.Instrument("Practitioner", practitionerHandler.GetByID))
+	router.Get("/fhir/Practitioner", elementRecorder.Instrument("Practitioner", searchRecorder.Instrument("Practitioner", practitionerHandler.GetAll)))
+	router.Put("/fhir/Practitioner/{id}", practitionerHandler.Update)
+	router.Delete("/fhir/Practitioner/{id}", practitionerHandler.Delete)
+
+	// Register FHIR Encounter endpoints
+	router.Post("/fhir/Encounter", encounterHandler.Create)
+	router.Get("/fhir/Encounter/{id}", elementRecorder.Instrument("Encounter", encounterHandler.GetByID))
+	router.Get("/fhir/Encounter", elementRecorder.Instrument("Encounter", searchRecorder.Instrument("Encounter", encounterHandler.GetAll)))
+	router.Put("/fhir/Encounter/{id}", encounterHandler.Update)
+	router.Delete("/fhir/Encounter/{id}", encounterHandler.Delete)
+
+	// Register FHIR Condition endpoints
+	router.Post("/fhir/Condition", conditionHandler.Create)
+	router.Get("/fhir/Condition/{id}", elementRecorder.Instrument("Condition", conditionHandler.GetByID))
+	router.Get("/fhir/Condition", elementRecorder.Instrument("Condition", searchRecorder.Instrument("Condition", conditionHandler.GetAll)))
+	router.Put("/fhir/Condition/{id}", conditionHandler.Update)
+	router.Delete("/fhir/Condition/{id}", conditionHandler.Delete)
+
+	// Register FHIR MedicationRequest endpoints
+	router.Post("/fhir/MedicationRequest", medicationRequestHandler.Create)
+	router.Get("/fhir/MedicationRequest/{id}", elementRecorder.Instrument("MedicationRequest", medicationRequestHandler.GetByID))
+	router.Get("/fhir/MedicationRequest", elementRecorder.Instrument("MedicationRequest", searchRecorder.Instrument("MedicationRequest", medicationRequestHandler.GetAll)))
+	router.Put("/fhir/MedicationRequest/{id}", medicationRequestHandler.Update)
+	router.Delete("/fhir/MedicationRequest/{id}", medicationRequestHandler.Delete)
+
+	// Register FHIR AllergyIntolerance endpoints
+	router.Post("/fhir/AllergyIntolerance", allergyIntoleranceHandler.Create)
+	router.Get("/fhir/AllergyIntolerance/{id}", elementRecorder.Instrument("AllergyIntolerance", allergyIntoleranceHandler.GetByID))
+	router.Get("/fhir/AllergyIntolerance", elementRecorder.Instrument("AllergyIntolerance", searchRecorder.Instrument("AllergyIntolerance", allergyIntoleranceHandler.GetAll)))
+	router.Put("/fhir/AllergyIntolerance/{id}", allergyIntoleranceHandler.Update)
+	router.Delete("/fhir/AllergyIntolerance/{id}", allergyIntoleranceHandler.Delete)
+
+	// Register FHIR DiagnosticReport endpoints
+	router.Post("/fhir/DiagnosticReport", diagnosticReportHandler.Create)
+	router.Get("/fhir/DiagnosticReport/{id}", elementRecorder.Instrument("DiagnosticReport", diagnosticReportHandler.GetByID))
+	router.Get("/fhir/DiagnosticReport", elementRecorder.Instrument("DiagnosticReport", searchRecorder.Instrument("DiagnosticReport", diagnosticReportHandler.GetAll)))
+	router.Put("/fhir/DiagnosticReport/{id}", diagnosticReportHandler.Update)
+	router.Delete("/fhir/DiagnosticReport/{id}", diagnosticReportHandler.Delete)
+
+	// Register FHIR Immunization endpoints
+	router.Post("/fhir/Immunization", immunizationHandler.Create)
+	router.Get("/fhir/Immunization/{id}", elementRecorder.Instrument("Immunization", immunizationHandler.GetByID))
+	router.Get("/fhir/Immunization", elementRecorder.Instrument("Immunization", searchRecorder.Instrument("Immunization", immunizationHandler.GetAll)))
+	router.Put("/fhir/Immunization/{id}", immunizationHandler.Update)
+	router.Delete("/fhir/Immunization/{id}", immunizationHandler.Delete)
 
-	// Define server port
-	serverPort := ":8080"
+	// Register FHIR AuditEvent endpoints (read-only; compliance or admin role)
+	router.Get("/fhir/AuditEvent/{id}", auditEventHandler.GetByID)
+	router.Get("/fhir/AuditEvent", searchRecorder.Instrument("AuditEvent", auditEventHandler.GetAll))
+
+	// Register FHIR Subscription endpoints (rest-hook notifications of matching writes)
+	router.Post("/fhir/Subscription", subscriptionHandler.Create)
+	router.Get("/fhir/Subscription/{id}", subscriptionHandler.GetByID)
+	router.Get("/fhir/Subscription", searchRecorder.Instrument("Subscription", subscriptionHandler.GetAll))
+	router.Put("/fhir/Subscription/{id}", subscriptionHandler.Update)
+	router.Delete("/fhir/Subscription/{id}", subscriptionHandler.Delete)
+
+	// Advertise only the interactions and operations the router actually serves
+	metadataHandler.SetStatement(capability.RoutedStatement(capability.Resources(), registeredRoutes(router), time.Now()))
+
+	// Listen on the configured port
+	serverPort := serverConfig.Address()
 
 	// Log server startup
 	log.Info().Str("port", serverPort).Msg("FHIR Health Interop server starting")
 	fmt.Println("\nAvailable endpoints:")
 	fmt.Println("  GET    /health                     - Health check")
-	fmt.Println("  GET    /fhir/Patient/sample        - Sample patient (hardcoded)")
+	fmt.Println("  GET    /ready                      - Readiness (503 until warm-up finishes)")
+	fmt.Println("  GET    /health/live                - Liveness (no dependency checks)")
+	fmt.Println("  GET    /health/ready               - Dependency readiness (503 when a database is down)")
+	fmt.Println("  GET    /metrics                    - Prometheus request and database query metrics")
+	fmt.Println("  GET    /admin/search-metrics       - Search parameter usage and deduplication metrics")
+	fmt.Println("  GET    /admin/element-usage        - Requested and returned element usage per resource type")
+	fmt.Println("  GET    /admin/operations           - Maintenance/drain status and in-flight requests")
+	fmt.Println("  PUT    /admin/operations/maintenance - Toggle maintenance mode (read-only, operator role)")
+	fmt.Println("  POST   /admin/operations/drain     - Drain connections before a deploy (operator role)")
+	fmt.Println("  DELETE /admin/operations/drain     - Resume accepting requests (operator role)")
+	fmt.Println("  GET    /admin/clients              - Registered clients (?status=pending for review queue)")
+	fmt.Println("  POST   /admin/clients/{id}/approve - Approve a client registration (admin role)")
+	fmt.Println("  POST   /admin/clients/{id}/reject  - Reject a client registration (admin role)")
+	fmt.Println("  GET    /admin/tenants/usage        - Quota usage for all tenants")
+	fmt.Println("  GET    /admin/tenants/{id}/usage   - Quota usage for one tenant")
+	fmt.Println("  GET    /admin/events               - Internal event bus consumer metrics")
+	fmt.Println("  GET    /admin/streams              - Event stream subscribers and slow consumer disconnects")
+	fmt.Println("  GET    /admin/reconciliation       - Latest Postgres/Mongo integrity report")
+	fmt.Println("  POST   /admin/reconciliation/run   - Run integrity reconciliation now")
+	fmt.Println("  GET    /admin/patients/{id}/legal-hold - Legal hold status and audit history (compliance role)")
+	fmt.Println("  PUT    /admin/patients/{id}/legal-hold - Place a legal hold (compliance role)")
+	fmt.Println("  DELETE /admin/patients/{id}/legal-hold - Release a legal hold (compliance role)")
+	fmt.Println("  POST   /admin/patients/{id}/erasure - Request erasure of a patient's records (compliance role)")
+	fmt.Println("  GET    /admin/erasures/{id}        - Erasure progress (also /certificate once purged)")
+	fmt.Println("  POST   /admin/erasures/{id}/cancel - Cancel an erasure and restore the patient's records")
+	fmt.Println("  POST   /admin/identifier-rekeys    - Re-key patient identifiers from a CSV mapping (identity-admin role)")
+	fmt.Println("  GET    /admin/identifier-rekeys/{id} - Re-key job progress (also /audit, /provenance)")
+	fmt.Println("  POST   /admin/identifier-rekeys/{id}/rollback - Restore the identifiers a re-key job changed")
+	fmt.Println("  GET    /admin/rollups              - Observation rollup run in progress and last run")
+	fmt.Println("  POST   /admin/rollups/backfill     - Recompute rollups for a date range (operator role)")
+	fmt.Println("  GET    /admin/jobs?status=&kind=   - Background jobs with their progress and last error")
+	fmt.Println("  GET    /admin/jobs/{id}            - Background job status")
+	fmt.Println("  POST   /admin/jobs/{id}/cancel     - Cancel a queued or running background job (operator role)")
+	fmt.Println("  POST   /oauth/register             - Register a partner app (pending approval)")
+	fmt.Println("  GET    /stream/events?_type={types} - NDJSON stream of the tenant's resource events")
+	fmt.Println("  GET    /ws?patient={id}             - WebSocket push of new observations for followed patients")
+	fmt.Println("  GET    /sync/changes?since={cursor} - Changes since the last sync cursor")
+	fmt.Println("  POST   /locks/Patient/{id}         - Acquire or renew an advisory edit lock")
+	fmt.Println("  GET    /locks/Patient/{id}         - Current edit lock holder")
+	fmt.Println("  DELETE /locks/Patient/{id}         - Release an edit lock (?force=true for any owner)")
+	fmt.Println("  POST   /hl7/v2                     - Ingest an HL7 v2 ADT A01, A04, or A08 or ORU R01 and return the ACK")
+	fmt.Println("  POST   /import/ccda                - Import the patient, problems, and results of a C-CDA document")
+	if searchExportHandler != nil {
+		fmt.Println("  POST   /exports                    - Export every result of a search to NDJSON or CSV")
+		fmt.Println("  GET    /exports/{id}               - Export progress and a signed download link once ready")
+		fmt.Println("  GET    /exports/{id}/download?expires=&signature= - Download an export (audited)")
+	}
+	if bulkExportHandler != nil {
+		fmt.Println("  GET    /fhir/Patient/$export?_type=&_since= - Start a Bulk Data export (Prefer: respond-async)")
+		fmt.Println("  GET    /fhir/bulk-export/{id}      - Bulk export progress, or its manifest once complete")
+		fmt.Println("  DELETE /fhir/bulk-export/{id}      - Cancel a bulk export and remove its files")
+		fmt.Println("  GET    /fhir/bulk-export/{id}/file/{type} - Download a bulk export NDJSON file")
+	}
+	fmt.Println("  GET    /fhir/async/{id}            - Progress of a Prefer: respond-async request, or its response")
+	fmt.Println("  DELETE /fhir/async/{id}            - Cancel a Prefer: respond-async request")
+	fmt.Println("  GET    /fhir/metadata              - CapabilityStatement (resources, search parameters, operations)")
+	fmt.Println("  POST   /fhir                       - Process a transaction or batch Bundle")
 	fmt.Println("  POST   /fhir/Patient               - Create patient")
 	fmt.Println("  GET    /fhir/Patient/{id}          - Get patient by ID")
 	fmt.Println("  GET    /fhir/Patient               - Search patients (supports filters)")
 	fmt.Println("  PUT    /fhir/Patient/{id}          - Update patient")
 	fmt.Println("  DELETE /fhir/Patient/{id}          - Delete patient")
+	fmt.Println("  GET    /fhir/Patient/{id}/$snapshot?_at= - Patient compartment as of a time")
+	fmt.Println("  GET    /fhir/Patient/{id}/_history - Patient versions, deletes included (also _history/{version})")
+	fmt.Println("  GET    /fhir/Patient/{id}/$everything - Patient and its compartment as a Bundle (_type, _since)")
+	fmt.Println("  GET    /fhir/Patient/{id}/$health-export?format= - Vital signs as HealthKit or Google Fit JSON")
+	fmt.Println("  GET    /fhir/Patient/{id}/$avatar      - Generated identicon avatar (PNG)")
+	fmt.Println("  POST   /fhir/Patient/{id}/$meta-add    - Add meta.tag values (also $meta-delete, GET $meta)")
+	fmt.Println("  POST   /fhir/Patient/$merge        - Merge a duplicate patient into another (identity-admin role)")
+	fmt.Println("  POST   /fhir/Patient/$match        - Rank stored patients that may match a partial Patient")
 	fmt.Println("  POST   /fhir/Observation           - Create observation")
 	fmt.Println("  GET    /fhir/Observation/{id}      - Get observation by ID")
 	fmt.Println("  GET    /fhir/Observation           - Search observations (supports filters)")
+	fmt.Println("  GET    /fhir/Observation/$daily-rollup?patient=&code= - Daily count/min/max/avg per code")
+	fmt.Println("  GET    /fhir/Observation/$lastn?patient=&code=&max= - Most recent observations per code")
 	fmt.Println("  PUT    /fhir/Observation/{id}      - Update observation")
 	fmt.Println("  DELETE /fhir/Observation/{id}      - Delete observation")
+	fmt.Println("  POST   /fhir/Observation/{id}/$meta-add - Add meta.tag values (also $meta-delete, GET $meta)")
+	fmt.Println("  GET    /fhir/Observation/{id}/_history - Observation versions, deletes included (also _history/{version})")
+	fmt.Println("  POST   /fhir/Practitioner          - Create practitioner")
+	fmt.Println("  GET    /fhir/Practitioner/{id}     - Get practitioner by ID")
+	fmt.Println("  GET    /fhir/Practitioner          - Search practitioners (name, identifier, specialty)")
+	fmt.Println("  PUT    /fhir/Practitioner/{id}     - Update practitioner")
+	fmt.Println("  DELETE /fhir/Practitioner/{id}     - Delete practitioner")
+	fmt.Println("  POST   /fhir/Encounter             - Create encounter")
+	fmt.Println("  GET    /fhir/Encounter/{id}        - Get encounter by ID")
+	fmt.Println("  GET    /fhir/Encounter             - Search encounters (patient, status, date)")
+	fmt.Println("  PUT    /fhir/Encounter/{id}        - Update encounter")
+	fmt.Println("  DELETE /fhir/Encounter/{id}        - Delete encounter")
+	fmt.Println("  POST   /fhir/Condition             - Create condition")
+	fmt.Println("  GET    /fhir/Condition/{id}        - Get condition by ID")
+	fmt.Println("  GET    /fhir/Condition             - Search conditions (patient, code, clinical-status, onset-date)")
+	fmt.Println("  PUT    /fhir/Condition/{id}        - Update condition")
+	fmt.Println("  DELETE /fhir/Condition/{id}        - Delete condition")
+	fmt.Println("  POST   /fhir/MedicationRequest     - Create medication request")
+	fmt.Println("  GET    /fhir/MedicationRequest/{id} - Get medication request by ID")
+	fmt.Println("  GET    /fhir/MedicationRequest     - Search medication requests (patient, status, intent, authoredon)")
+	fmt.Println("  PUT    /fhir/MedicationRequest/{id} - Update medication request")
+	fmt.Println("  DELETE /fhir/MedicationRequest/{id} - Delete medication request")
+	fmt.Println("  POST   /fhir/AllergyIntolerance    - Create allergy intolerance")
+	fmt.Println("  GET    /fhir/AllergyIntolerance/{id} - Get allergy intolerance by ID")
+	fmt.Println("  GET    /fhir/AllergyIntolerance    - Search allergy intolerances (patient, clinical-status, category, criticality)")
+	fmt.Println("  PUT    /fhir/AllergyIntolerance/{id} - Update allergy intolerance")
+	fmt.Println("  DELETE /fhir/AllergyIntolerance/{id} - Delete allergy intolerance")
+	fmt.Println("  POST   /fhir/DiagnosticReport      - Create diagnostic report")
+	fmt.Println("  GET    /fhir/DiagnosticReport/{id} - Get diagnostic report by ID")
+	fmt.Println("  GET    /fhir/DiagnosticReport      - Search diagnostic reports (patient, category, code, date)")
+	fmt.Println("  PUT    /fhir/DiagnosticReport/{id} - Update diagnostic report")
+	fmt.Println("  DELETE /fhir/DiagnosticReport/{id} - Delete diagnostic report")
+	fmt.Println("  POST   /fhir/Immunization          - Create immunization")
+	fmt.Println("  GET    /fhir/Immunization/{id}     - Get immunization by ID")
+	fmt.Println("  GET    /fhir/Immunization          - Search immunizations (patient, vaccine-code, status, lot-number, date)")
+	fmt.Println("  PUT    /fhir/Immunization/{id}     - Update immunization")
+	fmt.Println("  DELETE /fhir/Immunization/{id}     - Delete immunization")
+	fmt.Println("  GET    /fhir/AuditEvent/{id}       - Get audit event by ID (compliance or admin role)")
+	fmt.Println("  GET    /fhir/AuditEvent            - Search audit events (agent, entity, entity-type, action, outcome, date)")
+	fmt.Println("  POST   /fhir/Subscription          - Create rest-hook subscription")
+	fmt.Println("  GET    /fhir/Subscription/{id}     - Get subscription by ID (shows the error that stopped it)")
+	fmt.Println("  GET    /fhir/Subscription          - Search subscriptions (status)")
+	fmt.Println("  PUT    /fhir/Subscription/{id}     - Update, turn off, or restart subscription")
+	fmt.Println("  DELETE /fhir/Subscription/{id}     - Delete subscription")
+	if demoMode {
+		fmt.Println("  GET    /fhir/{type}/sample         - Synthetic sample resource (demo mode)")
+	}
 	fmt.Println()
 
-	serverError := http.ListenAndServe(serverPort, router)
-	if serverError != nil {
+	httpServer := &http.Server{
+		Addr:    serverPort,
+		Handler: router,
+	}
+
+	// Drain in-flight requests on SIGINT/SIGTERM before exiting
+	shutdownContext, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	// Accept connections immediately but report ready only once warm-up has finished
+	go warmer.Run(shutdownContext, warmupTimeout())
+
+	// Complete or roll back writes a crashed process left journaled, then keep checking until shutdown
+	go journalRecoveryJob.RunPeriodically(shutdownContext)
+
+	// Cross-check observations against patients every night until shutdown
+	go reconciler.RunDaily(shutdownContext, hourUTCEnv("RECONCILE_HOUR_UTC", 2))
+
+	// Recompute recent observation rollups every night and run requested backfills
+	go rollupJob.Run(shutdownContext, hourUTCEnv("ROLLUP_HOUR_UTC", 3))
+
+	// Archive old observations every night when archival tiering is on
+	if archivalJob != nil {
+		go archivalJob.RunDaily(shutdownContext, hourUTCEnv("ARCHIVE_HOUR_UTC", 4))
+	}
+
+	// Send queued deliveries until shutdown; unsent ones stay in the queue for the next start
+	go deliveryQueue.Run(shutdownContext)
+
+	// Relay outbox events to Kafka until shutdown; unpublished ones stay in the outbox for the next start
+	if outboxRelay != nil {
+		go outboxRelay.Run(shutdownContext)
+	}
+
+	// Close event stream subscribers that stop reading
+	go streamHub.Run(shutdownContext)
+
+	// Apply queued identifier re-keys until shutdown; interrupted jobs resume on the next start
+	go identifierRekeyService.Run(shutdownContext)
+
+	// Advance patient erasures until shutdown; interrupted sagas resume on the next start
+	go erasureService.Run(shutdownContext)
+
+	// Materialize queued search exports and delete expired files until shutdown
+	if searchExportService != nil {
+		go searchExportService.Run(shutdownContext)
+	}
+
+	// Remove expired bulk export files until shutdown; the exports themselves are written by the job queue
+	if bulkExportService != nil {
+		go bulkExportService.RunExpiry(shutdownContext)
+	}
+
+	// Delete expired idempotency keys until shutdown
+	go idempotencyKeeper.Run(shutdownContext)
+
+	// Run background jobs, including queued Prefer: respond-async requests and bulk exports, and delete finished
+	// jobs once they expire until shutdown
+	go jobQueue.Run(shutdownContext)
+	go func() {
+		<-shutdownContext.Done()
+		log.Info().Msg("Shutdown signal received, draining connections")
+		operationalState.SetDraining(true)
+
+		drainContext, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if shutdownError := httpServer.Shutdown(drainContext); shutdownError != nil {
+			log.Error().Err(shutdownError).Msg("Graceful shutdown did not complete")
+		}
+	}()
+
+	serverError := httpServer.ListenAndServe()
+	if serverError != nil && !errors.Is(serverError, http.ErrServerClosed) {
 		log.Fatal().Err(serverError).Msg("Failed to start server")
 	}
+
+	// Let event consumers finish queued side effects before exiting
+	eventsContext, cancelEvents := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancelEvents()
+	if eventsError := eventBus.Shutdown(eventsContext); eventsError != nil {
+		log.Warn().Err(eventsError).Msg("Event consumers did not drain before shutdown")
+	}
+	if kafkaProducer != nil {
+		if closeError := kafkaProducer.Close(); closeError != nil {
+			log.Warn().Err(closeError).Msg("Failed to close Kafka producer")
+		}
+	}
+	log.Info().Msg("Server stopped")
+}
+
+// isDemoModeEnabled reports whether the DEMO_MODE environment variable turns on sample endpoints
+func isDemoModeEnabled() bool {
+	return parseBoolEnv("DEMO_MODE")
+}
+
+// migrateSchema applies the embedded migrations not yet recorded in schema_migrations, exiting on failure
+// The migrator holds a PostgreSQL advisory lock, so replicas starting together apply each migration once
+func migrateSchema(databaseConnection *sql.DB) {
+	embeddedMigrations, loadError := migrations.Embedded()
+	if loadError != nil {
+		log.Fatal().Err(loadError).Msg("Failed to load schema migrations")
+	}
+
+	appliedMigrations, migrateError := migrations.NewMigrator(databaseConnection, embeddedMigrations).Up(context.Background())
+	for _, migration := range appliedMigrations {
+		log.Info().Int("version", migration.Version).Str("name", migration.Name).Msg("Applied schema migration")
+	}
+	if migrateError != nil {
+		log.Fatal().Err(migrateError).Msg("Failed to migrate the database schema")
+	}
+}
+
+// parseBoolEnv reads a boolean environment variable; unset or invalid values are false
+func parseBoolEnv(name string) bool {
+	rawValue := os.Getenv(name)
+	if rawValue == "" {
+		return false
+	}
+
+	enabled, parseError := strconv.ParseBool(rawValue)
+	if parseError != nil {
+		log.Warn().Str(name, rawValue).Msgf("Ignoring invalid %s value", name)
+		return false
+	}
+
+	return enabled
+}
+
+// hourUTCEnv reads an hour of the day (0-23, UTC) from the named environment variable, using defaultHour when unset
+func hourUTCEnv(name string, defaultHour int) int {
+	hourValue := os.Getenv(name)
+	if hourValue == "" {
+		return defaultHour
+	}
+
+	hour, parseError := strconv.Atoi(hourValue)
+	if parseError != nil || hour < 0 || hour > 23 {
+		log.Fatal().Str(name, hourValue).Msgf("%s must be an hour between 0 and 23", name)
+	}
+
+	return hour
+}
+
+// warmupPreloadCount is how many recent patients and observations WARMUP_PRELOAD reads
+const warmupPreloadCount = 500
+
+// positiveIntEnv reads a positive integer environment variable, using defaultValue when unset
+func positiveIntEnv(name string, defaultValue int) int {
+	rawValue := os.Getenv(name)
+	if rawValue == "" {
+		return defaultValue
+	}
+
+	value, parseError := strconv.Atoi(rawValue)
+	if parseError != nil || value <= 0 {
+		log.Fatal().Str(name, rawValue).Msgf("%s must be a positive integer", name)
+	}
+
+	return value
+}
+
+// warmupTimeout reads WARMUP_TIMEOUT (a Go duration), defaulting to 30 seconds
+func warmupTimeout() time.Duration {
+	rawTimeout := os.Getenv("WARMUP_TIMEOUT")
+	if rawTimeout == "" {
+		return 30 * time.Second
+	}
+
+	timeout, parseError := time.ParseDuration(rawTimeout)
+	if parseError != nil || timeout <= 0 {
+		log.Fatal().Str("WARMUP_TIMEOUT", rawTimeout).Msg("WARMUP_TIMEOUT must be a positive duration such as 30s")
+	}
+
+	return timeout
+}
+
+// idempotencyKeyTTL reads IDEMPOTENCY_KEY_TTL (a Go duration), how long responses are replayed to retries
+// with the same Idempotency-Key, defaulting to 24h
+func idempotencyKeyTTL() time.Duration {
+	rawTTL := os.Getenv("IDEMPOTENCY_KEY_TTL")
+	if rawTTL == "" {
+		return idempotency.DefaultPolicy().TTL
+	}
+
+	ttl, parseError := time.ParseDuration(rawTTL)
+	if parseError != nil || ttl <= 0 {
+		log.Fatal().Str("IDEMPOTENCY_KEY_TTL", rawTTL).Msg("IDEMPOTENCY_KEY_TTL must be a positive duration such as 24h")
+	}
+
+	return ttl
+}
+
+// healthCheckTimeout reads HEALTH_CHECK_TIMEOUT (a Go duration) bounding each readiness ping, defaulting to 2s
+func healthCheckTimeout() time.Duration {
+	rawTimeout := os.Getenv("HEALTH_CHECK_TIMEOUT")
+	if rawTimeout == "" {
+		return 2 * time.Second
+	}
+
+	timeout, parseError := time.ParseDuration(rawTimeout)
+	if parseError != nil || timeout <= 0 {
+		log.Fatal().Str("HEALTH_CHECK_TIMEOUT", rawTimeout).Msg("HEALTH_CHECK_TIMEOUT must be a positive duration such as 2s")
+	}
+
+	return timeout
+}
+
+// rekeyBatchInterval reads REKEY_BATCH_INTERVAL (a Go duration), using defaultInterval when unset
+func rekeyBatchInterval(defaultInterval time.Duration) time.Duration {
+	rawInterval := os.Getenv("REKEY_BATCH_INTERVAL")
+	if rawInterval == "" {
+		return defaultInterval
+	}
+
+	interval, parseError := time.ParseDuration(rawInterval)
+	if parseError != nil || interval <= 0 {
+		log.Fatal().Str("REKEY_BATCH_INTERVAL", rawInterval).Msg("REKEY_BATCH_INTERVAL must be a positive duration such as 1s")
+	}
+
+	return interval
+}
+
+// writeJournalStaleAfter reads WRITE_JOURNAL_STALE_AFTER (a Go duration), using defaultStaleAfter when unset
+func writeJournalStaleAfter(defaultStaleAfter time.Duration) time.Duration {
+	rawStaleAfter := os.Getenv("WRITE_JOURNAL_STALE_AFTER")
+	if rawStaleAfter == "" {
+		return defaultStaleAfter
+	}
+
+	staleAfter, parseError := time.ParseDuration(rawStaleAfter)
+	if parseError != nil || staleAfter <= 0 {
+		log.Fatal().Str("WRITE_JOURNAL_STALE_AFTER", rawStaleAfter).Msg("WRITE_JOURNAL_STALE_AFTER must be a positive duration such as 2m")
+	}
+
+	return staleAfter
+}
+
+// searchExportDurationEnv reads a positive Go duration for exports or background jobs from the named variable, using defaultDuration when unset
+func searchExportDurationEnv(name string, defaultDuration time.Duration) time.Duration {
+	rawDuration := os.Getenv(name)
+	if rawDuration == "" {
+		return defaultDuration
+	}
+
+	duration, parseError := time.ParseDuration(rawDuration)
+	if parseError != nil || duration <= 0 {
+		log.Fatal().Str(name, rawDuration).Msgf("%s must be a positive duration such as %s", name, defaultDuration)
+	}
+
+	return duration
+}
+
+// erasureWaitingPeriod reads ERASURE_WAITING_PERIOD (a Go duration), using defaultPeriod when unset
+func erasureWaitingPeriod(defaultPeriod time.Duration) time.Duration {
+	rawPeriod := os.Getenv("ERASURE_WAITING_PERIOD")
+	if rawPeriod == "" {
+		return defaultPeriod
+	}
+
+	period, parseError := time.ParseDuration(rawPeriod)
+	if parseError != nil || period < 0 {
+		log.Fatal().Str("ERASURE_WAITING_PERIOD", rawPeriod).Msg("ERASURE_WAITING_PERIOD must be a duration such as 720h")
+	}
+
+	return period
+}
+
+// loadQuotaConfig reads tenant quotas from TENANT_QUOTAS_FILE; without it no limits are enforced
+func loadQuotaConfig() quota.Config {
+	quotaConfigPath := os.Getenv("TENANT_QUOTAS_FILE")
+	if quotaConfigPath == "" {
+		return quota.Config{}
+	}
+
+	quotaConfig, loadError := quota.LoadConfig(quotaConfigPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("TENANT_QUOTAS_FILE", quotaConfigPath).Msg("Failed to load tenant quotas")
+	}
+
+	return quotaConfig
+}
+
+// loadDeprecationConfig reads deprecated features from DEPRECATIONS_FILE; none when unset
+func loadDeprecationConfig() deprecation.Config {
+	deprecationConfigPath := os.Getenv("DEPRECATIONS_FILE")
+	if deprecationConfigPath == "" {
+		return deprecation.Config{}
+	}
+
+	deprecationConfig, loadError := deprecation.LoadConfig(deprecationConfigPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("DEPRECATIONS_FILE", deprecationConfigPath).Msg("Failed to load deprecations")
+	}
+
+	log.Info().Int("features", len(deprecationConfig.Features)).Msg("Deprecated features loaded")
+	return deprecationConfig
+}
+
+// loadElementUsageConfig reads response sampling from ELEMENT_SAMPLE_RATE (0 to 1, default 0)
+// and ELEMENT_SAMPLING_TENANTS (tenants that consented to response inspection)
+func loadElementUsageConfig() metrics.ElementConfig {
+	elementConfig := metrics.ElementConfig{}
+	for _, tenantID := range strings.Split(os.Getenv("ELEMENT_SAMPLING_TENANTS"), ",") {
+		if tenantID = strings.TrimSpace(tenantID); tenantID != "" {
+			elementConfig.ConsentTenants = append(elementConfig.ConsentTenants, tenantID)
+		}
+	}
+
+	rawSampleRate := os.Getenv("ELEMENT_SAMPLE_RATE")
+	if rawSampleRate == "" {
+		return elementConfig
+	}
+
+	sampleRate, parseError := strconv.ParseFloat(rawSampleRate, 64)
+	if parseError != nil || sampleRate < 0 || sampleRate > 1 {
+		log.Fatal().Str("ELEMENT_SAMPLE_RATE", rawSampleRate).Msg("ELEMENT_SAMPLE_RATE must be a fraction between 0 and 1")
+	}
+	elementConfig.SampleRate = sampleRate
+
+	if sampleRate > 0 {
+		log.Info().Float64("rate", sampleRate).Int("tenants", len(elementConfig.ConsentTenants)).Msg("Response element sampling enabled")
+	}
+	return elementConfig
+}
+
+// nameSimilarity reads the fuzzy patient name search threshold from PATIENT_NAME_SIMILARITY; zero (fuzzy
+// matching off) when unset
+func nameSimilarity() float64 {
+	rawSimilarity := os.Getenv("PATIENT_NAME_SIMILARITY")
+	if rawSimilarity == "" {
+		return 0
+	}
+
+	similarity, parseError := strconv.ParseFloat(rawSimilarity, 64)
+	if parseError != nil || similarity < 0 || similarity > 1 {
+		log.Fatal().Str("PATIENT_NAME_SIMILARITY", rawSimilarity).Msg("PATIENT_NAME_SIMILARITY must be a fraction between 0 and 1")
+	}
+	if similarity > 0 {
+		log.Info().Float64("similarity", similarity).Msg("Fuzzy patient name search enabled")
+	}
+	return similarity
+}
+
+// loadADTConfig reads ADT feed destinations from ADT_DESTINATIONS_FILE; none when unset
+func loadADTConfig() adt.Config {
+	adtConfigPath := os.Getenv("ADT_DESTINATIONS_FILE")
+	if adtConfigPath == "" {
+		return adt.Config{}
+	}
+
+	adtConfig, loadError := adt.LoadConfig(adtConfigPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("ADT_DESTINATIONS_FILE", adtConfigPath).Msg("Failed to load ADT destinations")
+	}
+
+	return adtConfig
+}
+
+// loadResidencyPolicy reads data residency regions from RESIDENCY_FILE for the deployment's REGION; nil when unset
+func loadResidencyPolicy() *residency.Policy {
+	residencyConfigPath := os.Getenv("RESIDENCY_FILE")
+	if residencyConfigPath == "" {
+		return nil
+	}
+
+	residencyConfig, loadError := residency.LoadConfig(residencyConfigPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("RESIDENCY_FILE", residencyConfigPath).Msg("Failed to load residency regions")
+	}
+
+	residencyPolicy, policyError := residency.NewPolicy(residencyConfig, os.Getenv("REGION"))
+	if policyError != nil {
+		log.Fatal().Err(policyError).Msg("REGION must name a region defined in RESIDENCY_FILE")
+	}
+
+	regionName, _ := residencyPolicy.LocalRegion()
+	log.Info().Str("region", regionName).Int("tenants", len(residencyConfig.Tenants)).Msg("Data residency enabled")
+	return residencyPolicy
+}
+
+// loadRoutePolicy reads the roles required per route from ROUTE_POLICY_FILE; nil, enforcing nothing, when unset
+func loadRoutePolicy() *auth.RoutePolicy {
+	routePolicyPath := os.Getenv("ROUTE_POLICY_FILE")
+	if routePolicyPath == "" {
+		return nil
+	}
+
+	routePolicy, loadError := auth.LoadRoutePolicy(routePolicyPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("ROUTE_POLICY_FILE", routePolicyPath).Msg("Failed to load route policy")
+	}
+
+	log.Info().Int("rules", len(routePolicy.Rules)).Str("default", routePolicy.Default).Msg("Route policy loaded")
+	return routePolicy
+}
+
+// loadRolePolicy reads the resource operations each role may perform from ROLE_POLICY_FILE; nil when unset
+func loadRolePolicy() *authz.Policy {
+	rolePolicyPath := os.Getenv("ROLE_POLICY_FILE")
+	if rolePolicyPath == "" {
+		return nil
+	}
+
+	rolePolicy, loadError := authz.LoadPolicy(rolePolicyPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("ROLE_POLICY_FILE", rolePolicyPath).Msg("Failed to load role policy")
+	}
+
+	log.Info().Int("roles", len(rolePolicy.Roles)).Msg("Role policy loaded")
+	return rolePolicy
+}
+
+// loadTokenAuthenticator builds SMART on FHIR bearer token validation from SMART_JWKS_URL, SMART_ISSUER,
+// and SMART_AUDIENCE; nil when SMART_JWKS_URL is unset or SMART_AUTH_DISABLED is set for local development
+func loadTokenAuthenticator() *auth.TokenAuthenticator {
+	jwksURL := os.Getenv("SMART_JWKS_URL")
+	if jwksURL == "" {
+		return nil
+	}
+	if parseBoolEnv("SMART_AUTH_DISABLED") {
+		log.Warn().Msg("SMART_AUTH_DISABLED is set: bearer tokens are not checked; never use this outside local development")
+		return nil
+	}
+
+	tokenConfig := auth.DefaultTokenConfig()
+	tokenConfig.JWKSURL = jwksURL
+	tokenConfig.Issuer = os.Getenv("SMART_ISSUER")
+	tokenConfig.Audience = os.Getenv("SMART_AUDIENCE")
+	validator, validatorError := auth.NewTokenValidator(tokenConfig)
+	if validatorError != nil {
+		log.Fatal().Err(validatorError).Msg("Invalid SMART token validation settings")
+	}
+
+	log.Info().Str("issuer", tokenConfig.Issuer).Str("audience", tokenConfig.Audience).Msg("SMART bearer token validation enabled")
+	return auth.NewTokenAuthenticator(validator)
+}
+
+// loadBodyLogger logs redacted FHIR request and response bodies at debug level when LOG_BODIES is true, using
+// the redaction rules in REDACTION_RULES_FILE (the built-in rules when unset); nil when LOG_BODIES is not set
+func loadBodyLogger() func(http.Handler) http.Handler {
+	if !parseBoolEnv("LOG_BODIES") {
+		return nil
+	}
+
+	redactionConfig := redact.Config{}
+	if redactionConfigPath := os.Getenv("REDACTION_RULES_FILE"); redactionConfigPath != "" {
+		loadedConfig, loadError := redact.LoadConfig(redactionConfigPath)
+		if loadError != nil {
+			log.Fatal().Err(loadError).Str("REDACTION_RULES_FILE", redactionConfigPath).Msg("Failed to load redaction rules")
+		}
+		redactionConfig = loadedConfig
+	}
+
+	maxBytes := positiveIntEnv("LOG_BODY_MAX_BYTES", custommiddleware.DefaultBodyLogMaxBytes)
+	if zerolog.GlobalLevel() > zerolog.DebugLevel {
+		log.Warn().Msg("LOG_BODIES is set but LOG_LEVEL is above debug, so no bodies are logged")
+	}
+	log.Info().Int("max_bytes", maxBytes).Msg("Redacted body logging enabled")
+	return custommiddleware.BodyLogger(log.Logger, redact.NewRedactor(redactionConfig), maxBytes)
+}
+
+// loadRateLimiter reads per-client read and write rates from RATE_LIMITS_FILE, keeping the buckets in the Redis
+// at RATE_LIMIT_REDIS_URL so servers share them, or in memory when unset; nil when RATE_LIMITS_FILE is unset
+func loadRateLimiter() *ratelimit.Limiter {
+	rateLimitConfigPath := os.Getenv("RATE_LIMITS_FILE")
+	if rateLimitConfigPath == "" {
+		return nil
+	}
+
+	rateLimitConfig, loadError := ratelimit.LoadConfig(rateLimitConfigPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("RATE_LIMITS_FILE", rateLimitConfigPath).Msg("Failed to load rate limits")
+	}
+
+	redisURL := os.Getenv("RATE_LIMIT_REDIS_URL")
+	if redisURL == "" {
+		log.Info().Int("clients", len(rateLimitConfig.Clients)).Msg("Rate limiting enabled with in-memory buckets")
+		return ratelimit.NewLimiter(rateLimitConfig, ratelimit.NewMemoryStore())
+	}
+
+	redisOptions, parseError := redis.ParseURL(redisURL)
+	if parseError != nil {
+		log.Fatal().Err(parseError).Msg("RATE_LIMIT_REDIS_URL must be a redis:// or rediss:// URL")
+	}
+	redisClient := redis.NewClient(redisOptions)
+	// Requests are let through while Redis is unreachable, so a failed ping only warns
+	pingContext, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if pingError := redisClient.Ping(pingContext).Err(); pingError != nil {
+		log.Warn().Err(pingError).Msg("Rate limit Redis unreachable; requests are not limited until it answers")
+	}
+
+	log.Info().Int("clients", len(rateLimitConfig.Clients)).Msg("Rate limiting enabled with Redis buckets")
+	return ratelimit.NewLimiter(rateLimitConfig, ratelimit.NewRedisStore(redisClient))
+}
+
+// loadRepositoryCache connects to the Redis at REPOSITORY_CACHE_REDIS_URL for caching patient and observation
+// reads, with REPOSITORY_CACHE_TTL, REPOSITORY_CACHE_SEARCH_TTL, and REPOSITORY_CACHE_WRITE_HOLD over the
+// defaults; nil when REPOSITORY_CACHE_REDIS_URL is unset
+func loadRepositoryCache() *cache.Cache {
+	redisURL := os.Getenv("REPOSITORY_CACHE_REDIS_URL")
+	if redisURL == "" {
+		return nil
+	}
+
+	redisOptions, parseError := redis.ParseURL(redisURL)
+	if parseError != nil {
+		log.Fatal().Err(parseError).Msg("REPOSITORY_CACHE_REDIS_URL must be a redis:// or rediss:// URL")
+	}
+	redisClient := redis.NewClient(redisOptions)
+	// Reads go to the databases while Redis is unreachable, so a failed ping only warns
+	pingContext, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if pingError := redisClient.Ping(pingContext).Err(); pingError != nil {
+		log.Warn().Err(pingError).Msg("Repository cache Redis unreachable; reads are not cached until it answers")
+	}
+
+	defaults := cache.DefaultPolicy()
+	policy := cache.Policy{
+		TTL:       repositoryCacheDurationEnv("REPOSITORY_CACHE_TTL", defaults.TTL),
+		SearchTTL: repositoryCacheDurationEnv("REPOSITORY_CACHE_SEARCH_TTL", defaults.SearchTTL),
+		WriteHold: repositoryCacheDurationEnv("REPOSITORY_CACHE_WRITE_HOLD", defaults.WriteHold),
+	}
+	log.Info().Dur("ttl", policy.TTL).Dur("search_ttl", policy.SearchTTL).Msg("Repository cache enabled for patients and observations")
+	return cache.New(redisClient, policy)
+}
+
+// repositoryCacheDurationEnv reads a positive Go duration for the repository cache from the named variable, using defaultDuration when unset
+func repositoryCacheDurationEnv(name string, defaultDuration time.Duration) time.Duration {
+	rawDuration := os.Getenv(name)
+	if rawDuration == "" {
+		return defaultDuration
+	}
+
+	duration, parseError := time.ParseDuration(rawDuration)
+	if parseError != nil || duration <= 0 {
+		log.Fatal().Str(name, rawDuration).Msgf("%s must be a positive duration such as %s", name, defaultDuration)
+	}
+
+	return duration
+}
+
+// loadTerminology reads site displays from TERMINOLOGY_FILE over the built-in ones; the built-in displays when unset
+func loadTerminology() *terminology.Terminology {
+	terminologyPath := os.Getenv("TERMINOLOGY_FILE")
+	if terminologyPath == "" {
+		return terminology.Default()
+	}
+
+	siteTerminology, loadError := terminology.Load(terminologyPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("TERMINOLOGY_FILE", terminologyPath).Msg("Failed to load terminology")
+	}
+
+	log.Info().Int("codes", siteTerminology.Len()).Msg("Site-specific terminology loaded")
+	return siteTerminology
+}
+
+// terminologyValidationAction reads TERMINOLOGY_VALIDATION: reject or warn enables observation code validation,
+// and unset or off leaves it disabled
+func terminologyValidationAction() string {
+	validationAction := os.Getenv("TERMINOLOGY_VALIDATION")
+	switch validationAction {
+	case "", "off":
+		return ""
+	case terminology.ValidationReject, terminology.ValidationWarn:
+		return validationAction
+	default:
+		log.Fatal().Str("TERMINOLOGY_VALIDATION", validationAction).Msg("TERMINOLOGY_VALIDATION must be reject, warn, or off")
+		return ""
+	}
+}
+
+// loadCodeSystemLookup checks codes against the built-in and TERMINOLOGY_FILE concepts and, with
+// TERMINOLOGY_SERVER_URL set, asks that FHIR terminology server about the codes they cannot check
+// TERMINOLOGY_SERVER_TIMEOUT (a Go duration, default 2s) bounds each server request
+func loadCodeSystemLookup() terminology.CodeSystemLookup {
+	localTerminology := loadTerminology()
+	serverURL := os.Getenv("TERMINOLOGY_SERVER_URL")
+	if serverURL == "" {
+		return localTerminology
+	}
+
+	timeout := 2 * time.Second
+	if rawTimeout := os.Getenv("TERMINOLOGY_SERVER_TIMEOUT"); rawTimeout != "" {
+		parsedTimeout, parseError := time.ParseDuration(rawTimeout)
+		if parseError != nil || parsedTimeout <= 0 {
+			log.Fatal().Str("TERMINOLOGY_SERVER_TIMEOUT", rawTimeout).Msg("TERMINOLOGY_SERVER_TIMEOUT must be a positive duration such as 2s")
+		}
+		timeout = parsedTimeout
+	}
+	serverLookup, serverError := terminology.NewServerLookup(serverURL, timeout)
+	if serverError != nil {
+		log.Fatal().Err(serverError).Msg("Invalid TERMINOLOGY_SERVER_URL")
+	}
+
+	log.Info().Str("url", serverURL).Msg("Terminology server lookups enabled")
+	return terminology.Fallback(localTerminology, serverLookup)
+}
+
+// unitValidationAction reads UNIT_VALIDATION: reject or warn enables observation unit validation, and unset or
+// off leaves it disabled
+func unitValidationAction() string {
+	validationAction := os.Getenv("UNIT_VALIDATION")
+	switch validationAction {
+	case "", "off":
+		return ""
+	case units.ValidationReject, units.ValidationWarn:
+		return validationAction
+	default:
+		log.Fatal().Str("UNIT_VALIDATION", validationAction).Msg("UNIT_VALIDATION must be reject, warn, or off")
+		return ""
+	}
+}
+
+// loadUnitTable reads site units and analytes from UNITS_FILE over the built-in ones; the built-in table when unset
+func loadUnitTable() *units.Table {
+	unitTablePath := os.Getenv("UNITS_FILE")
+	if unitTablePath == "" {
+		return units.DefaultTable()
+	}
+
+	unitTable, loadError := units.LoadTable(unitTablePath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("UNITS_FILE", unitTablePath).Msg("Failed to load unit table")
+	}
+
+	log.Info().Int("dimensions", len(unitTable.Dimensions)).Int("analytes", len(unitTable.Analytes)).Msg("Site-specific unit table loaded")
+	return unitTable
+}
+
+// loadReferenceRanges reads site ranges from REFERENCE_RANGES_FILE over the built-in ones; the built-in ranges when unset
+func loadReferenceRanges() *referencerange.Ranges {
+	referenceRangesPath := os.Getenv("REFERENCE_RANGES_FILE")
+	if referenceRangesPath == "" {
+		return referencerange.DefaultRanges()
+	}
+
+	referenceRanges, loadError := referencerange.LoadRanges(referenceRangesPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("REFERENCE_RANGES_FILE", referenceRangesPath).Msg("Failed to load reference ranges")
+	}
+
+	log.Info().Int("ranges", len(referenceRanges.Ranges)).Msg("Site-specific reference ranges loaded")
+	return referenceRanges
+}
+
+// loadPlausibilityRules reads site ranges from PLAUSIBILITY_RULES_FILE over the built-in ones; the built-in ranges when unset
+func loadPlausibilityRules() *plausibility.Rules {
+	plausibilityRulesPath := os.Getenv("PLAUSIBILITY_RULES_FILE")
+	if plausibilityRulesPath == "" {
+		return plausibility.DefaultRules()
+	}
+
+	plausibilityRules, loadError := plausibility.LoadRules(plausibilityRulesPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("PLAUSIBILITY_RULES_FILE", plausibilityRulesPath).Msg("Failed to load plausibility rules")
+	}
+
+	log.Info().Int("rules", len(plausibilityRules.Rules)).Msg("Site-specific plausibility rules loaded")
+	return plausibilityRules
+}
+
+// loadPatientMatchConfig reads $match weights and grade thresholds from PATIENT_MATCH_CONFIG_FILE over the
+// built-in ones; the built-in scoring when unset
+func loadPatientMatchConfig() matching.Config {
+	matchConfigPath := os.Getenv("PATIENT_MATCH_CONFIG_FILE")
+	if matchConfigPath == "" {
+		return matching.DefaultConfig()
+	}
+
+	matchConfig, loadError := matching.LoadConfig(matchConfigPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("PATIENT_MATCH_CONFIG_FILE", matchConfigPath).Msg("Failed to load patient match config")
+	}
+
+	log.Info().Interface("weights", matchConfig.Weights).Msg("Site-specific patient match scoring loaded")
+	return matchConfig
+}
+
+// loadMappingRules reads site-specific mapping rules from MAPPING_RULES_FILE; nil when unset
+func loadMappingRules() *mapping.Rules {
+	mappingRulesPath := os.Getenv("MAPPING_RULES_FILE")
+	if mappingRulesPath == "" {
+		return nil
+	}
+
+	mappingRules, loadError := mapping.LoadRules(mappingRulesPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("MAPPING_RULES_FILE", mappingRulesPath).Msg("Failed to load mapping rules")
+	}
+
+	return mappingRules
+}
+
+// loadResourceLimits reads element-count limits from RESOURCE_LIMITS_FILE; defaults apply when unset
+func loadResourceLimits() custommiddleware.ResourceLimits {
+	resourceLimitsPath := os.Getenv("RESOURCE_LIMITS_FILE")
+	if resourceLimitsPath == "" {
+		return custommiddleware.DefaultResourceLimits()
+	}
+
+	resourceLimits, loadError := custommiddleware.LoadResourceLimits(resourceLimitsPath)
+	if loadError != nil {
+		log.Fatal().Err(loadError).Str("RESOURCE_LIMITS_FILE", resourceLimitsPath).Msg("Failed to load resource limits")
+	}
+
+	return resourceLimits
+}
+
+// registeredRoutes lists the method and pattern of every route on router, for the CapabilityStatement
+func registeredRoutes(router chi.Routes) []capability.Route {
+	var routes []capability.Route
+	chi.Walk(router, func(method string, pattern string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		routes = append(routes, capability.Route{Method: method, Pattern: pattern})
+		return nil
+	})
+	return routes
 }
@@ -0,0 +1,242 @@
+package adt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Transports a destination can receive messages over
+const (
+	TransportMLLP = "mllp"
+	TransportHTTP = "http"
+)
+
+// Destination is a downstream system receiving the ADT feed
+type Destination struct {
+	// Name identifies the destination in logs
+	Name string `json:"name"`
+
+	// Transport is mllp or http
+	Transport string `json:"transport"`
+
+	// Address is host:port for MLLP or a URL for HTTP
+	Address string `json:"address"`
+
+	// Events lists the trigger events to send; empty sends A28, A31, and A40
+	Events []string `json:"events,omitempty"`
+
+	SendingApplication   string `json:"sending_application"`
+	SendingFacility      string `json:"sending_facility"`
+	ReceivingApplication string `json:"receiving_application"`
+	ReceivingFacility    string `json:"receiving_facility"`
+
+	// ProcessingID is MSH-11: P (production), T (training), or D (debugging); defaults to P
+	ProcessingID string `json:"processing_id,omitempty"`
+
+	AssigningAuthority string `json:"assigning_authority,omitempty"`
+
+	// Template overrides DefaultTemplate inline; TemplateFile reads it from a file
+	Template     string `json:"template,omitempty"`
+	TemplateFile string `json:"template_file,omitempty"`
+}
+
+// Config lists the ADT destinations
+type Config struct {
+	Destinations []Destination `json:"destinations"`
+}
+
+// LoadConfig reads ADT destinations from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return config, fmt.Errorf("failed to read ADT config: %w", readError)
+	}
+
+	if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+		return config, fmt.Errorf("failed to parse ADT config: %w", decodeError)
+	}
+
+	return config, nil
+}
+
+// Enqueuer queues deliveries all together; *delivery.Queue satisfies it
+type Enqueuer interface {
+	EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error)
+}
+
+// compiledDestination is a destination with its template parsed and event filter resolved
+type compiledDestination struct {
+	Destination
+	deliveryKind    string
+	messageTemplate *template.Template
+	events          map[string]bool
+}
+
+// Feed turns patient writes into ADT messages and queues them for each destination
+type Feed struct {
+	destinations []compiledDestination
+	enqueuer     Enqueuer
+
+	// now and newControlID are replaceable for tests
+	now          func() time.Time
+	newControlID func() string
+}
+
+// NewFeed validates the destinations and compiles their templates
+func NewFeed(config Config, enqueuer Enqueuer) (*Feed, error) {
+	feed := &Feed{
+		enqueuer:     enqueuer,
+		now:          time.Now,
+		newControlID: newControlID,
+	}
+
+	for _, destination := range config.Destinations {
+		compiled, compileError := compileDestination(destination)
+		if compileError != nil {
+			return nil, compileError
+		}
+		feed.destinations = append(feed.destinations, compiled)
+	}
+
+	return feed, nil
+}
+
+// compileDestination checks a destination and parses its template
+func compileDestination(destination Destination) (compiledDestination, error) {
+	compiled := compiledDestination{Destination: destination, events: make(map[string]bool)}
+
+	if destination.Name == "" || destination.Address == "" {
+		return compiled, fmt.Errorf("ADT destination needs a name and an address")
+	}
+	switch destination.Transport {
+	case TransportMLLP:
+		compiled.deliveryKind = KindMLLP
+	case TransportHTTP:
+		compiled.deliveryKind = delivery.KindWebhook
+	default:
+		return compiled, fmt.Errorf("ADT destination %q has invalid transport %q: must be mllp or http", destination.Name, destination.Transport)
+	}
+	if compiled.ProcessingID == "" {
+		compiled.ProcessingID = "P"
+	}
+
+	eventNames := destination.Events
+	if len(eventNames) == 0 {
+		eventNames = []string{EventRegister, EventUpdate, EventMerge}
+	}
+	for _, eventName := range eventNames {
+		if _, supported := messageStructures[eventName]; !supported {
+			return compiled, fmt.Errorf("ADT destination %q lists unsupported event %q", destination.Name, eventName)
+		}
+		compiled.events[eventName] = true
+	}
+
+	templateText := DefaultTemplate
+	switch {
+	case destination.Template != "" && destination.TemplateFile != "":
+		return compiled, fmt.Errorf("ADT destination %q sets both template and template_file", destination.Name)
+	case destination.Template != "":
+		templateText = destination.Template
+	case destination.TemplateFile != "":
+		templateBytes, readError := os.ReadFile(destination.TemplateFile)
+		if readError != nil {
+			return compiled, fmt.Errorf("failed to read template for ADT destination %q: %w", destination.Name, readError)
+		}
+		templateText = string(templateBytes)
+	}
+
+	messageTemplate, parseError := ParseTemplate(destination.Name, templateText)
+	if parseError != nil {
+		return compiled, fmt.Errorf("invalid template for ADT destination %q: %w", destination.Name, parseError)
+	}
+	compiled.messageTemplate = messageTemplate
+
+	return compiled, nil
+}
+
+// PatientWritten queues A28 for a created patient and A31 for an updated one
+// It runs inside the patient write's transaction, so the messages are queued exactly when the write commits
+func (feed *Feed) PatientWritten(ctx context.Context, operation models.ChangeOperation, patient *models.Patient) error {
+	switch operation {
+	case models.ChangeOperationCreate:
+		return feed.Emit(ctx, EventRegister, patient)
+	case models.ChangeOperationUpdate:
+		return feed.Emit(ctx, EventUpdate, patient)
+	default:
+		return nil
+	}
+}
+
+// PatientMerged queues A40 for a duplicate merged into the target patient
+// Like PatientWritten it runs inside the merged patient's update, so the message is queued exactly when the merge commits
+func (feed *Feed) PatientMerged(ctx context.Context, source *models.Patient, target *models.Patient) error {
+	return feed.emit(ctx, EventMerge, target, source)
+}
+
+// Emit renders the trigger event for every subscribed destination and queues the messages together
+// Either every destination's message is queued or none is: a template that fails to render, or a failed
+// enqueue, returns an error so the caller's write is rolled back rather than reaching only some systems
+func (feed *Feed) Emit(ctx context.Context, eventName string, patient *models.Patient) error {
+	return feed.emit(ctx, eventName, patient, nil)
+}
+
+// emit is Emit with the prior patient of a merge, which is nil for other events
+func (feed *Feed) emit(ctx context.Context, eventName string, patient *models.Patient, priorPatient *models.Patient) error {
+	deliveries := []*models.Delivery{}
+	for _, destination := range feed.destinations {
+		if !destination.events[eventName] {
+			continue
+		}
+
+		message, renderError := Render(destination.messageTemplate, MessageData{
+			Event:                eventName,
+			Structure:            messageStructures[eventName],
+			ControlID:            feed.newControlID(),
+			Timestamp:            feed.now(),
+			SendingApplication:   destination.SendingApplication,
+			SendingFacility:      destination.SendingFacility,
+			ReceivingApplication: destination.ReceivingApplication,
+			ReceivingFacility:    destination.ReceivingFacility,
+			ProcessingID:         destination.ProcessingID,
+			AssigningAuthority:   destination.AssigningAuthority,
+			Patient:              patient,
+			PriorPatient:         priorPatient,
+		})
+		if renderError != nil {
+			log.Error().Err(renderError).Str("destination", destination.Name).Str("patient_id", patient.ID).Msg("Failed to render ADT message")
+			return fmt.Errorf("failed to render %s for %s: %w", eventName, destination.Name, renderError)
+		}
+
+		deliveries = append(deliveries, &models.Delivery{
+			Kind:        destination.deliveryKind,
+			Destination: destination.Address,
+			ContentType: ContentType,
+			Payload:     message,
+		})
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	if _, enqueueError := feed.enqueuer.EnqueueAll(ctx, deliveries); enqueueError != nil {
+		return fmt.Errorf("failed to queue %s: %w", eventName, enqueueError)
+	}
+	return nil
+}
+
+// newControlID returns a unique MSH-10 value within the 20-character limit
+func newControlID() string {
+	return strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:20])
+}
@@ -0,0 +1,161 @@
+package adt
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// queuedMessage is one payload passed to the recording enqueuer
+type queuedMessage struct {
+	kind        string
+	destination string
+	contentType string
+	payload     string
+}
+
+// recordingEnqueuer captures queued messages and counts the batches they arrived in
+type recordingEnqueuer struct {
+	messages []queuedMessage
+	batches  int
+}
+
+// EnqueueAll records the messages
+func (enqueuer *recordingEnqueuer) EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error) {
+	enqueuer.batches++
+	for _, queued := range deliveries {
+		enqueuer.messages = append(enqueuer.messages, queuedMessage{queued.Kind, queued.Destination, queued.ContentType, string(queued.Payload)})
+	}
+	return deliveries, nil
+}
+
+// newTestFeed creates a feed with an MLLP destination for all events and an HTTP destination for A28 only
+func newTestFeed(t *testing.T) (*Feed, *recordingEnqueuer) {
+	enqueuer := &recordingEnqueuer{}
+	feed, feedError := NewFeed(Config{Destinations: []Destination{
+		{Name: "lab", Transport: TransportMLLP, Address: "lab:2575", SendingApplication: "FHIRHUB", AssigningAuthority: "MRN"},
+		{Name: "archive", Transport: TransportHTTP, Address: "https://archive.example/hl7", Events: []string{EventRegister},
+			Template: "MSH|^~\\&|{{.SendingApplication}}||||{{timestamp .Timestamp}}||ADT^{{.Event}}|{{.ControlID}}|{{.ProcessingID}}|2.3\nPID|1||{{upper .Patient.FamilyName}}"},
+	}}, enqueuer)
+	if feedError != nil {
+		t.Fatalf("Expected valid feed, got %v", feedError)
+	}
+	feed.now = func() time.Time { return time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC) }
+	feed.newControlID = func() string { return "CTRL" }
+	return feed, enqueuer
+}
+
+// TestFeed_CreatedPatientSendsA28ToEachDestination verifies routing, transports, and per-destination templates
+func TestFeed_CreatedPatientSendsA28ToEachDestination(t *testing.T) {
+	feed, enqueuer := newTestFeed(t)
+
+	if writtenError := feed.PatientWritten(context.Background(), models.ChangeOperationCreate, testPatient()); writtenError != nil {
+		t.Fatalf("Expected no error, got %v", writtenError)
+	}
+
+	if len(enqueuer.messages) != 2 || enqueuer.batches != 1 {
+		t.Fatalf("Expected two messages queued together, got %d batches: %+v", enqueuer.batches, enqueuer.messages)
+	}
+	labMessage, archiveMessage := enqueuer.messages[0], enqueuer.messages[1]
+	if labMessage.kind != KindMLLP || labMessage.destination != "lab:2575" || labMessage.contentType != ContentType {
+		t.Errorf("Unexpected lab delivery: %+v", labMessage)
+	}
+	if !strings.Contains(labMessage.payload, "ADT^A28^ADT_A05|CTRL|P|2.5.1") {
+		t.Errorf("Unexpected lab payload: %q", labMessage.payload)
+	}
+	if archiveMessage.kind != delivery.KindWebhook || !strings.Contains(archiveMessage.payload, `PID|1||O'BRIEN|SMITH`) {
+		t.Errorf("Unexpected archive delivery: %+v", archiveMessage)
+	}
+}
+
+// TestFeed_UpdateRespectsEventFilters verifies A31 goes only to subscribed destinations and deletes send nothing
+func TestFeed_UpdateRespectsEventFilters(t *testing.T) {
+	feed, enqueuer := newTestFeed(t)
+
+	feed.PatientWritten(context.Background(), models.ChangeOperationUpdate, testPatient())
+	feed.PatientWritten(context.Background(), models.ChangeOperationDelete, testPatient())
+
+	if len(enqueuer.messages) != 1 || !strings.Contains(enqueuer.messages[0].payload, "ADT^A31") {
+		t.Fatalf("Expected A31 to the lab only, got %+v", enqueuer.messages)
+	}
+}
+
+// TestFeed_MergeSendsA40 verifies a merge names the surviving patient in PID and the duplicate in MRG
+func TestFeed_MergeSendsA40(t *testing.T) {
+	feed, enqueuer := newTestFeed(t)
+	duplicate := testPatient()
+	duplicate.ID = "duplicate-id"
+	duplicate.IdentifierValue = "MRN-9"
+
+	if mergedError := feed.PatientMerged(context.Background(), duplicate, testPatient()); mergedError != nil {
+		t.Fatalf("Expected no error, got %v", mergedError)
+	}
+
+	if len(enqueuer.messages) != 1 {
+		t.Fatalf("Expected A40 to the lab only, got %+v", enqueuer.messages)
+	}
+	payload := enqueuer.messages[0].payload
+	if !strings.Contains(payload, "ADT^A40^ADT_A39") || !strings.Contains(payload, "\rMRG|MRN-9^^^MRN^MR\rPV1|1|N\r") {
+		t.Errorf("Expected an A40 with the duplicate in MRG before PV1, got %q", payload)
+	}
+}
+
+// TestFeed_RenderFailureQueuesNothing verifies one failing template keeps every destination's message out of the queue
+func TestFeed_RenderFailureQueuesNothing(t *testing.T) {
+	enqueuer := &recordingEnqueuer{}
+	feed, feedError := NewFeed(Config{Destinations: []Destination{
+		{Name: "lab", Transport: TransportMLLP, Address: "lab:2575"},
+		{Name: "broken", Transport: TransportMLLP, Address: "broken:2575", Template: "PID|1"},
+	}}, enqueuer)
+	if feedError != nil {
+		t.Fatalf("Expected valid feed, got %v", feedError)
+	}
+
+	if emitError := feed.Emit(context.Background(), EventRegister, testPatient()); emitError == nil {
+		t.Error("Expected error when a destination's template fails to render")
+	}
+	if enqueuer.batches != 0 {
+		t.Errorf("Expected nothing queued, got %+v", enqueuer.messages)
+	}
+}
+
+// TestNewFeed_RejectsInvalidDestinations verifies configuration errors are caught at startup
+func TestNewFeed_RejectsInvalidDestinations(t *testing.T) {
+	testCases := map[string]Destination{
+		"missing address":   {Name: "lab", Transport: TransportMLLP},
+		"unknown transport": {Name: "lab", Transport: "ftp", Address: "lab"},
+		"unknown event":     {Name: "lab", Transport: TransportMLLP, Address: "lab", Events: []string{"A08"}},
+		"bad template":      {Name: "lab", Transport: TransportMLLP, Address: "lab", Template: "{{.Patient"},
+		"missing template":  {Name: "lab", Transport: TransportMLLP, Address: "lab", TemplateFile: "/nonexistent.tmpl"},
+	}
+
+	for name, destination := range testCases {
+		if _, feedError := NewFeed(Config{Destinations: []Destination{destination}}, &recordingEnqueuer{}); feedError == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+// TestLoadConfig_ExampleFiles verifies the shipped example configuration and template compile
+func TestLoadConfig_ExampleFiles(t *testing.T) {
+	repositoryRoot := filepath.Join("..", "..")
+	config, loadError := LoadConfig(filepath.Join(repositoryRoot, "config", "adt.example.json"))
+	if loadError != nil {
+		t.Fatalf("Expected example config to load, got %v", loadError)
+	}
+
+	// Template paths in the example are relative to the repository root
+	workingDirectory, _ := os.Getwd()
+	os.Chdir(repositoryRoot)
+	defer os.Chdir(workingDirectory)
+
+	if _, feedError := NewFeed(config, &recordingEnqueuer{}); feedError != nil {
+		t.Errorf("Expected example destinations to compile, got %v", feedError)
+	}
+}
@@ -0,0 +1,151 @@
+package adt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// Trigger events emitted by the feed
+const (
+	// EventRegister (A28) adds person information for a new patient
+	EventRegister = "A28"
+
+	// EventUpdate (A31) updates person information
+	EventUpdate = "A31"
+
+	// EventMerge (A40) merges a duplicate patient, named in MRG, into the patient named in PID
+	EventMerge = "A40"
+)
+
+// ContentType is the media type of an ER7-encoded HL7v2 message
+const ContentType = "x-application/hl7-v2+er7"
+
+// segmentSeparator ends every HL7v2 segment
+const segmentSeparator = "\r"
+
+// messageStructures maps trigger events to their HL7 v2.5.1 message structure
+var messageStructures = map[string]string{
+	EventRegister: "ADT_A05",
+	EventUpdate:   "ADT_A05",
+	EventMerge:    "ADT_A39",
+}
+
+// hl7TimestampLayout formats DTM values to the second
+const hl7TimestampLayout = "20060102150405"
+
+// DefaultTemplate renders A28, A31, and A40 messages with the segments most receivers require
+// Templates are written one segment per line; blank lines are dropped and lines are joined with carriage returns
+const DefaultTemplate = `MSH|^~\&|{{esc .SendingApplication}}|{{esc .SendingFacility}}|{{esc .ReceivingApplication}}|{{esc .ReceivingFacility}}|{{timestamp .Timestamp}}||ADT^{{.Event}}^{{.Structure}}|{{.ControlID}}|{{.ProcessingID}}|2.5.1
+EVN|{{.Event}}|{{timestamp .Timestamp}}
+PID|1||{{esc .Patient.IdentifierValue}}^^^{{esc .AssigningAuthority}}^MR||{{esc .Patient.FamilyName}}^{{esc .Patient.GivenName}}||{{date .Patient.BirthDate}}|{{gender .Patient.Gender}}
+{{if .PriorPatient}}MRG|{{esc .PriorPatient.IdentifierValue}}^^^{{esc .AssigningAuthority}}^MR{{end}}
+PV1|1|N
+`
+
+// MessageData is the input to a message template
+type MessageData struct {
+	Event     string
+	Structure string
+	ControlID string
+	Timestamp time.Time
+
+	SendingApplication   string
+	SendingFacility      string
+	ReceivingApplication string
+	ReceivingFacility    string
+	ProcessingID         string
+
+	// AssigningAuthority qualifies patient identifiers in PID-3
+	AssigningAuthority string
+
+	Patient *models.Patient
+
+	// PriorPatient is the duplicate merged into Patient, set only for A40
+	PriorPatient *models.Patient
+}
+
+// templateFunctions are available to every message template
+var templateFunctions = template.FuncMap{
+	"esc":       Escape,
+	"timestamp": formatTimestamp,
+	"date":      formatDate,
+	"gender":    formatGender,
+	"upper":     strings.ToUpper,
+}
+
+// ParseTemplate compiles a message template with the HL7 helper functions
+func ParseTemplate(name string, text string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFunctions).Option("missingkey=error").Parse(text)
+}
+
+// Render executes a message template and returns the ER7-encoded message
+func Render(messageTemplate *template.Template, data MessageData) ([]byte, error) {
+	var rendered bytes.Buffer
+	if executeError := messageTemplate.Execute(&rendered, data); executeError != nil {
+		return nil, fmt.Errorf("failed to render %s message: %w", data.Event, executeError)
+	}
+
+	segments := []string{}
+	for _, line := range strings.Split(rendered.String(), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		segments = append(segments, line)
+	}
+	if len(segments) == 0 || !strings.HasPrefix(segments[0], "MSH|") {
+		return nil, fmt.Errorf("rendered %s message must start with an MSH segment", data.Event)
+	}
+
+	return []byte(strings.Join(segments, segmentSeparator) + segmentSeparator), nil
+}
+
+// escapeReplacer replaces HL7 delimiters with their escape sequences; the escape character goes first
+var escapeReplacer = strings.NewReplacer(
+	`\`, `\E\`,
+	`|`, `\F\`,
+	`^`, `\S\`,
+	`&`, `\T\`,
+	`~`, `\R\`,
+	"\r", `\X0D\`,
+	"\n", `\X0A\`,
+)
+
+// Escape encodes HL7 delimiters in a field value
+func Escape(value string) string {
+	return escapeReplacer.Replace(value)
+}
+
+// formatTimestamp formats a DTM value in UTC
+func formatTimestamp(timestamp time.Time) string {
+	return timestamp.UTC().Format(hl7TimestampLayout)
+}
+
+// formatDate formats a DT value, or returns empty when unknown
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format("20060102")
+}
+
+// formatGender maps FHIR administrative gender to HL7 table 0001
+func formatGender(gender string) string {
+	switch gender {
+	case "male":
+		return "M"
+	case "female":
+		return "F"
+	case "other":
+		return "O"
+	case "unknown":
+		return "U"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,81 @@
+package adt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// testPatient returns a patient with every demographic field set
+func testPatient() *models.Patient {
+	birthDate := time.Date(1980, 2, 29, 0, 0, 0, 0, time.UTC)
+	return &models.Patient{
+		ID:              "11111111-1111-1111-1111-111111111111",
+		IdentifierValue: "MRN001",
+		FamilyName:      "O'Brien|Smith",
+		GivenName:       "Ann",
+		Gender:          "female",
+		BirthDate:       &birthDate,
+	}
+}
+
+// testMessageData returns template input for an A28
+func testMessageData() MessageData {
+	return MessageData{
+		Event:                EventRegister,
+		Structure:            messageStructures[EventRegister],
+		ControlID:            "CTRL1",
+		Timestamp:            time.Date(2026, 10, 1, 8, 30, 15, 0, time.UTC),
+		SendingApplication:   "FHIRHUB",
+		SendingFacility:      "MAIN",
+		ReceivingApplication: "LIS",
+		ReceivingFacility:    "LAB",
+		ProcessingID:         "P",
+		AssigningAuthority:   "MRN",
+		Patient:              testPatient(),
+	}
+}
+
+// TestRender_DefaultTemplateA28 verifies the default segments, escaping, and carriage-return separators
+func TestRender_DefaultTemplateA28(t *testing.T) {
+	messageTemplate, _ := ParseTemplate("default", DefaultTemplate)
+
+	message, renderError := Render(messageTemplate, testMessageData())
+	if renderError != nil {
+		t.Fatalf("Expected no error, got %v", renderError)
+	}
+
+	segments := strings.Split(strings.TrimSuffix(string(message), "\r"), "\r")
+	expectedSegments := []string{
+		`MSH|^~\&|FHIRHUB|MAIN|LIS|LAB|20261001083015||ADT^A28^ADT_A05|CTRL1|P|2.5.1`,
+		`EVN|A28|20261001083015`,
+		`PID|1||MRN001^^^MRN^MR||O'Brien\F\Smith^Ann||19800229|F`,
+		`PV1|1|N`,
+	}
+	if len(segments) != len(expectedSegments) {
+		t.Fatalf("Expected %d segments, got %q", len(expectedSegments), segments)
+	}
+	for index, segment := range segments {
+		if segment != expectedSegments[index] {
+			t.Errorf("Segment %d:\n got %q\nwant %q", index, segment, expectedSegments[index])
+		}
+	}
+}
+
+// TestRender_RequiresMSH verifies templates that do not start with MSH are refused
+func TestRender_RequiresMSH(t *testing.T) {
+	messageTemplate, _ := ParseTemplate("broken", "PID|1||{{.Patient.IdentifierValue}}")
+
+	if _, renderError := Render(messageTemplate, testMessageData()); renderError == nil {
+		t.Error("Expected error for a message without MSH")
+	}
+}
+
+// TestEscape verifies every delimiter is escaped, the escape character first
+func TestEscape(t *testing.T) {
+	if escaped := Escape(`a\b|c^d&e~f`); escaped != `a\E\b\F\c\S\d\T\e\R\f` {
+		t.Errorf("Unexpected escaping: %s", escaped)
+	}
+}
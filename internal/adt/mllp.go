@@ -0,0 +1,102 @@
+package adt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// KindMLLP is the delivery kind for messages sent over MLLP
+const KindMLLP = "mllp"
+
+// MLLP framing bytes
+const (
+	mllpStartBlock     = 0x0b
+	mllpEndBlock       = 0x1c
+	mllpCarriageReturn = 0x0d
+)
+
+// MLLPSender sends HL7v2 messages over MLLP and waits for the receiver's acknowledgement
+type MLLPSender struct {
+	dialer net.Dialer
+}
+
+// NewMLLPSender creates an MLLP sender; each send is bounded by the delivery queue's send timeout
+func NewMLLPSender() *MLLPSender {
+	return &MLLPSender{}
+}
+
+// Send writes one framed message and reads the ACK
+// AA/CA acknowledgements succeed, AE/CE are retried, and AR/CR fail permanently
+func (sender *MLLPSender) Send(ctx context.Context, queued *models.Delivery) error {
+	connection, dialError := sender.dialer.DialContext(ctx, "tcp", queued.Destination)
+	if dialError != nil {
+		return dialError
+	}
+	defer connection.Close()
+
+	if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+		connection.SetDeadline(deadline)
+	}
+
+	frame := make([]byte, 0, len(queued.Payload)+3)
+	frame = append(frame, mllpStartBlock)
+	frame = append(frame, queued.Payload...)
+	frame = append(frame, mllpEndBlock, mllpCarriageReturn)
+	if _, writeError := connection.Write(frame); writeError != nil {
+		return writeError
+	}
+
+	acknowledgement, readError := readFrame(bufio.NewReader(connection))
+	if readError != nil {
+		return fmt.Errorf("failed to read MLLP acknowledgement: %w", readError)
+	}
+
+	return checkAcknowledgement(acknowledgement)
+}
+
+// readFrame reads one MLLP frame and returns its content
+func readFrame(reader *bufio.Reader) ([]byte, error) {
+	if _, skipError := reader.ReadBytes(mllpStartBlock); skipError != nil {
+		return nil, skipError
+	}
+
+	content, readError := reader.ReadBytes(mllpEndBlock)
+	if readError != nil {
+		return nil, readError
+	}
+	return bytes.TrimSuffix(content, []byte{mllpEndBlock}), nil
+}
+
+// checkAcknowledgement reads MSA-1 from an ACK message
+func checkAcknowledgement(acknowledgement []byte) error {
+	for _, segment := range strings.Split(string(acknowledgement), segmentSeparator) {
+		if !strings.HasPrefix(segment, "MSA|") {
+			continue
+		}
+
+		fields := strings.Split(segment, "|")
+		acknowledgementCode := fields[1]
+		var errorText string
+		if len(fields) > 3 {
+			errorText = fields[3]
+		}
+
+		switch acknowledgementCode {
+		case "AA", "CA":
+			return nil
+		case "AR", "CR":
+			return delivery.Permanent(fmt.Errorf("receiver rejected message (%s): %s", acknowledgementCode, errorText))
+		default:
+			return fmt.Errorf("receiver reported an error (%s): %s", acknowledgementCode, errorText)
+		}
+	}
+
+	return fmt.Errorf("acknowledgement has no MSA segment")
+}
@@ -0,0 +1,78 @@
+package adt
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// startMLLPReceiver accepts one connection, records the framed message, and replies with the given MSA code
+func startMLLPReceiver(t *testing.T, acknowledgementCode string) (string, chan string) {
+	listener, listenError := net.Listen("tcp", "127.0.0.1:0")
+	if listenError != nil {
+		t.Fatalf("Failed to listen: %v", listenError)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan string, 1)
+	go func() {
+		connection, acceptError := listener.Accept()
+		if acceptError != nil {
+			return
+		}
+		defer connection.Close()
+
+		message, _ := readFrame(bufio.NewReader(connection))
+		received <- string(message)
+
+		acknowledgement := "MSH|^~\\&|LIS|LAB|||20261001||ACK|1|P|2.5.1\rMSA|" + acknowledgementCode + "|CTRL|reason\r"
+		connection.Write(append(append([]byte{mllpStartBlock}, acknowledgement...), mllpEndBlock, mllpCarriageReturn))
+	}()
+
+	return listener.Addr().String(), received
+}
+
+// TestMLLPSender_Acknowledgements verifies framing and how each acknowledgement code is treated
+func TestMLLPSender_Acknowledgements(t *testing.T) {
+	testCases := []struct {
+		code            string
+		expectError     bool
+		expectPermanent bool
+	}{
+		{"AA", false, false},
+		{"AE", true, false},
+		{"AR", true, true},
+	}
+
+	for _, testCase := range testCases {
+		address, received := startMLLPReceiver(t, testCase.code)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+
+		sendError := NewMLLPSender().Send(ctx, &models.Delivery{Destination: address, Payload: []byte("MSH|^~\\&|FHIRHUB\rPID|1\r")})
+		cancel()
+
+		if (sendError != nil) != testCase.expectError || delivery.IsPermanent(sendError) != testCase.expectPermanent {
+			t.Errorf("%s: unexpected result %v", testCase.code, sendError)
+		}
+		if message := <-received; message != "MSH|^~\\&|FHIRHUB\rPID|1\r" {
+			t.Errorf("%s: unexpected framed message %q", testCase.code, message)
+		}
+	}
+}
+
+// TestMLLPSender_ConnectionRefused verifies unreachable receivers are retried
+func TestMLLPSender_ConnectionRefused(t *testing.T) {
+	listener, _ := net.Listen("tcp", "127.0.0.1:0")
+	address := listener.Addr().String()
+	listener.Close()
+
+	sendError := NewMLLPSender().Send(context.Background(), &models.Delivery{Destination: address, Payload: []byte("MSH|")})
+	if sendError == nil || delivery.IsPermanent(sendError) {
+		t.Errorf("Expected a retryable error, got %v", sendError)
+	}
+}
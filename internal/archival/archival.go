@@ -0,0 +1,106 @@
+package archival
+
+import (
+	"context"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/reconcile"
+	"github.com/rs/zerolog/log"
+)
+
+// Store moves observations between the primary collection and the archive
+type Store interface {
+	// ArchiveBefore moves observations effective before cutoff into the archive
+	ArchiveBefore(ctx context.Context, cutoff time.Time) (int64, error)
+
+	// RestoreSince moves archived observations that are not effective before cutoff back out of the archive
+	RestoreSince(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// Report describes one archival run
+type Report struct {
+	// Cutoff is the effective date before which observations were archived
+	Cutoff     time.Time `json:"cutoff"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Restored counts archived observations moved back because they are no longer older than Cutoff
+	Restored int64 `json:"restored"`
+
+	// Archived counts observations moved into the archive
+	Archived int64 `json:"archived"`
+
+	// Error is set when the run stopped early
+	Error string `json:"error,omitempty"`
+}
+
+// Job moves observations older than the archival age into the archive every night
+// Recent observations stay in the primary collection, where the hottest reads never touch the archive
+type Job struct {
+	store Store
+
+	// archiveAfter is how long after their effective date observations are archived
+	archiveAfter time.Duration
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewJob creates an archival job for observations effective more than archiveAfter ago
+func NewJob(store Store, archiveAfter time.Duration) *Job {
+	return &Job{
+		store:        store,
+		archiveAfter: archiveAfter,
+		now:          time.Now,
+	}
+}
+
+// Run restores archived observations that are now too recent for the archive, then archives the old ones
+// Restoring first means a lengthened archival age takes effect in one run
+func (job *Job) Run(ctx context.Context) *Report {
+	report := &Report{StartedAt: job.now()}
+	report.Cutoff = report.StartedAt.Add(-job.archiveAfter)
+
+	var runError error
+	report.Restored, runError = job.store.RestoreSince(ctx, report.Cutoff)
+	if runError == nil {
+		report.Archived, runError = job.store.ArchiveBefore(ctx, report.Cutoff)
+	}
+	if runError != nil {
+		report.Error = runError.Error()
+	}
+	report.FinishedAt = job.now()
+
+	logReport(report)
+	return report
+}
+
+// RunDaily runs the job every day at the given UTC hour until ctx is cancelled
+func (job *Job) RunDaily(ctx context.Context, hourUTC int) {
+	for {
+		timer := time.NewTimer(reconcile.NextRun(job.now(), hourUTC).Sub(job.now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			// Errors are captured in the report and logged by Run
+			job.Run(ctx)
+		}
+	}
+}
+
+// logReport writes a run summary, at error level when it failed
+func logReport(report *Report) {
+	logEvent := log.Info()
+	if report.Error != "" {
+		logEvent = log.Error().Str("error", report.Error)
+	}
+	logEvent.
+		Time("cutoff", report.Cutoff).
+		Int64("restored", report.Restored).
+		Int64("archived", report.Archived).
+		Dur("duration", report.FinishedAt.Sub(report.StartedAt)).
+		Msg("Observation archival finished")
+}
@@ -0,0 +1,92 @@
+package archival
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeStore records the cutoffs it was given and returns fixed counts
+type fakeStore struct {
+	calls        []string
+	cutoffs      []time.Time
+	restoreError error
+}
+
+// ArchiveBefore records the call and reports five archived observations
+func (store *fakeStore) ArchiveBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	store.calls = append(store.calls, "archive")
+	store.cutoffs = append(store.cutoffs, cutoff)
+	return 5, nil
+}
+
+// RestoreSince records the call and reports two restored observations, or fails with restoreError
+func (store *fakeStore) RestoreSince(ctx context.Context, cutoff time.Time) (int64, error) {
+	store.calls = append(store.calls, "restore")
+	store.cutoffs = append(store.cutoffs, cutoff)
+	if store.restoreError != nil {
+		return 0, store.restoreError
+	}
+	return 2, nil
+}
+
+// TestJob_RunRestoresThenArchives verifies both steps use the cutoff archiveAfter before now, restoring first
+func TestJob_RunRestoresThenArchives(t *testing.T) {
+	store := &fakeStore{}
+	job := NewJob(store, 90*24*time.Hour)
+	now := time.Date(2024, 6, 1, 4, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	report := job.Run(context.Background())
+
+	expectedCutoff := time.Date(2024, 3, 3, 4, 0, 0, 0, time.UTC)
+	if !report.Cutoff.Equal(expectedCutoff) {
+		t.Errorf("Expected cutoff %v, got %v", expectedCutoff, report.Cutoff)
+	}
+	if len(store.calls) != 2 || store.calls[0] != "restore" || store.calls[1] != "archive" {
+		t.Fatalf("Expected restore then archive, got %v", store.calls)
+	}
+	for _, cutoff := range store.cutoffs {
+		if !cutoff.Equal(expectedCutoff) {
+			t.Errorf("Expected every step to use cutoff %v, got %v", expectedCutoff, cutoff)
+		}
+	}
+	if report.Restored != 2 || report.Archived != 5 || report.Error != "" {
+		t.Errorf("Expected 2 restored and 5 archived, got %+v", report)
+	}
+}
+
+// TestJob_RunStopsOnRestoreFailure verifies a failed restore skips archiving and is reported
+func TestJob_RunStopsOnRestoreFailure(t *testing.T) {
+	store := &fakeStore{restoreError: errors.New("archive unavailable")}
+	job := NewJob(store, 24*time.Hour)
+
+	report := job.Run(context.Background())
+
+	if len(store.calls) != 1 {
+		t.Errorf("Expected archiving to be skipped, got %v", store.calls)
+	}
+	if report.Error != "archive unavailable" || report.Archived != 0 {
+		t.Errorf("Expected the restore error in the report, got %+v", report)
+	}
+}
+
+// TestJob_RunDailyStopsOnCancel verifies the schedule loop returns once its context is cancelled
+func TestJob_RunDailyStopsOnCancel(t *testing.T) {
+	job := NewJob(&fakeStore{}, 24*time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	finished := make(chan struct{})
+	go func() {
+		job.RunDaily(ctx, 4)
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("Expected RunDaily to return after cancellation")
+	}
+}
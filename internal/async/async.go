@@ -0,0 +1,349 @@
+package async
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/jobs"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// JobKind is the job kind async requests run as
+const JobKind = "async-request"
+
+// StatusPath is the path under which each async request's status URL is served, followed by its job ID
+const StatusPath = "/fhir/async/"
+
+// respondAsync is the Prefer token asking for the asynchronous pattern
+const respondAsync = "respond-async"
+
+// unreplayedHeaders are never stored: credentials were checked when the request was accepted and the replay
+// runs as the principal recorded then, and the others describe the original connection
+var unreplayedHeaders = []string{"Authorization", tenant.HeaderAPIKey, "Cookie", "Proxy-Authorization", "Content-Length", "Connection", "Idempotency-Key"}
+
+// errResponseTooLarge stops a handler whose response would not fit the stored response limit
+var errResponseTooLarge = errors.New("async response exceeds the size limit")
+
+// Queue runs async requests as background jobs
+type Queue interface {
+	Enqueue(ctx context.Context, kind string, payload []byte, maxAttempts int) (*models.Job, error)
+	Get(ctx context.Context, jobID string) (*models.Job, error)
+	Cancel(ctx context.Context, jobID string) error
+	Discard(ctx context.Context, jobID string) error
+}
+
+// Policy bounds what async requests store and how often they are retried
+type Policy struct {
+	// SearchAttempts is how many times a search is started before it fails; a search whose worker stopped
+	// is run again, while operations run at most once since they may have changed data
+	SearchAttempts int
+
+	// MaxRequestBytes and MaxResponseBytes bound the stored request and response bodies
+	MaxRequestBytes  int64
+	MaxResponseBytes int
+}
+
+// DefaultPolicy returns the async settings used unless configured otherwise
+func DefaultPolicy() Policy {
+	return Policy{
+		SearchAttempts:   3,
+		MaxRequestBytes:  10 << 20,
+		MaxResponseBytes: 32 << 20,
+	}
+}
+
+// storedRequest is the job payload: the request as received, less credentials, and the caller's roles
+// The principal and tenant are the job's owner
+type storedRequest struct {
+	Method         string            `json:"method"`
+	URL            string            `json:"url"`
+	Headers        map[string]string `json:"headers"`
+	Body           []byte            `json:"body,omitempty"`
+	Roles          []string          `json:"roles,omitempty"`
+	TenantVerified bool              `json:"tenant_verified"`
+}
+
+// Response is the job result: the response the request was served with
+type Response struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    []byte            `json:"body"`
+}
+
+// Runner implements the FHIR asynchronous request pattern for expensive searches and operations
+// Its middleware queues a request sent with Prefer: respond-async as a job and answers 202 with a status URL;
+// the job then serves the request through the handlers after the middleware, as the caller who sent it, and
+// keeps the response as its result for the status endpoint
+type Runner struct {
+	queue  Queue
+	policy Policy
+
+	// handler serves replayed requests; it is the handler the middleware wraps
+	handler http.Handler
+}
+
+// NewRunner creates a runner queuing requests on queue; register it as the handler of JobKind
+func NewRunner(queue Queue, policy Policy) *Runner {
+	return &Runner{
+		queue:  queue,
+		policy: policy,
+	}
+}
+
+// Eligible reports whether a request may run asynchronously: type-level searches and $ operations under /fhir
+// $export keeps its own Bulk Data flow, and writes always run while the client waits
+func Eligible(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, "/fhir/") || strings.HasPrefix(r.URL.Path, StatusPath) {
+		return false
+	}
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/fhir/"), "/"), "/")
+	lastSegment := segments[len(segments)-1]
+	if strings.HasPrefix(lastSegment, "$") {
+		return lastSegment != "$export"
+	}
+
+	isResourceType := segments[0] != "" && segments[0][0] >= 'A' && segments[0][0] <= 'Z'
+	return r.Method == http.MethodGet && len(segments) == 1 && isResourceType
+}
+
+// wantsAsync reports whether the request's Prefer header asks for respond-async
+func wantsAsync(r *http.Request) bool {
+	for _, preference := range strings.Split(r.Header.Get("Prefer"), ",") {
+		if strings.EqualFold(strings.TrimSpace(preference), respondAsync) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware accepts eligible requests sent with Prefer: respond-async, answering 202 with the status URL in
+// Content-Location, and passes every other request on unchanged
+// It must run after the middleware that authenticates, authorizes, and validates requests, since replays start
+// at the handler it wraps; register it once
+func (runner *Runner) Middleware(next http.Handler) http.Handler {
+	runner.handler = next
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !wantsAsync(r) || !Eligible(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestBody, readError := io.ReadAll(io.LimitReader(r.Body, runner.policy.MaxRequestBytes+1))
+		if readError != nil {
+			outcome.WriteForRequest(w, r, http.StatusBadRequest, []outcome.Issue{outcome.Error(fhir.IssueTypeStructure, "Failed to read request body")})
+			return
+		}
+		if int64(len(requestBody)) > runner.policy.MaxRequestBytes {
+			outcome.WriteForRequest(w, r, http.StatusRequestEntityTooLarge, []outcome.Issue{
+				outcome.Error(fhir.IssueTypeTooLong, fmt.Sprintf("Async request bodies are limited to %d bytes", runner.policy.MaxRequestBytes)),
+			})
+			return
+		}
+
+		tenantID := tenant.FromContext(r.Context())
+		payload, encodeError := json.Marshal(storedRequest{
+			Method:         r.Method,
+			URL:            middleware.RequestScheme(r) + "://" + r.Host + r.URL.RequestURI(),
+			Headers:        replayHeaders(r.Header),
+			Body:           requestBody,
+			Roles:          auth.FromContext(r.Context()).Roles,
+			TenantVerified: tenantID != tenant.DefaultTenantID && tenant.VerifiedFromContext(r.Context()) == tenantID,
+		})
+		if encodeError != nil {
+			middleware.WriteError(w, r, apperrors.Internal("Failed to queue async request", encodeError))
+			return
+		}
+
+		// An operation may have changed data before its worker stopped; running it again could apply it twice
+		maxAttempts := 1
+		if r.Method == http.MethodGet {
+			maxAttempts = runner.policy.SearchAttempts
+		}
+		job, enqueueError := runner.queue.Enqueue(r.Context(), JobKind, payload, maxAttempts)
+		if enqueueError != nil {
+			middleware.WriteError(w, r, apperrors.Internal("Failed to queue async request", enqueueError))
+			return
+		}
+
+		log.Info().Str("job_id", job.ID).Str("principal", job.PrincipalID).Str("method", r.Method).Str("path", r.URL.Path).Msg("Async request accepted")
+		w.Header().Set("Content-Location", middleware.RequestScheme(r)+"://"+r.Host+StatusPath+job.ID)
+		outcome.WriteForRequest(w, r, http.StatusAccepted, []outcome.Issue{
+			outcome.Information(fhir.IssueTypeInformational, "Request accepted; poll the Content-Location URL for its result"),
+		})
+	})
+}
+
+// Get returns an async request's job to the caller who sent it, with its response once it succeeded
+// Cancelled requests, expired ones, and those of other callers or tenants are not found
+func (runner *Runner) Get(ctx context.Context, jobID string) (*models.Job, *Response, error) {
+	job, getError := runner.queue.Get(ctx, jobID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, nil, apperrors.NotFound("AsyncRequest", jobID)
+	}
+	if getError != nil {
+		return nil, nil, apperrors.Internal("Failed to read async request", getError)
+	}
+	if job.Kind != JobKind || job.Status == models.JobStatusCancelled || job.PrincipalID != auth.FromContext(ctx).ID || job.TenantID != tenant.FromContext(ctx) {
+		return nil, nil, apperrors.NotFound("AsyncRequest", jobID)
+	}
+	if job.Status != models.JobStatusSucceeded {
+		return job, nil, nil
+	}
+
+	var response Response
+	if decodeError := json.Unmarshal(job.Result, &response); decodeError != nil {
+		return nil, nil, apperrors.Internal("Failed to read async response", decodeError)
+	}
+	return job, &response, nil
+}
+
+// Cancel stops a queued or running request, or discards a finished one's response
+func (runner *Runner) Cancel(ctx context.Context, jobID string) error {
+	job, _, getError := runner.Get(ctx, jobID)
+	if getError != nil {
+		return getError
+	}
+
+	cancel := runner.queue.Cancel
+	if job.Status.Finished() {
+		cancel = runner.queue.Discard
+	}
+	cancelError := cancel(ctx, jobID)
+	if errors.Is(cancelError, sql.ErrNoRows) {
+		// The request finished or was cancelled meanwhile
+		return apperrors.Conflict("AsyncRequest", "the request changed state; check its status and try again")
+	}
+	if cancelError != nil {
+		return apperrors.Internal("Failed to cancel async request", cancelError)
+	}
+	return nil
+}
+
+// Run serves the stored request through the wrapped handler as the caller who sent it and returns its response
+func (runner *Runner) Run(ctx context.Context, job *models.Job, progress jobs.Progress) ([]byte, error) {
+	var request storedRequest
+	if decodeError := json.Unmarshal(job.Payload, &request); decodeError != nil {
+		return nil, jobs.Permanent(fmt.Errorf("invalid stored request: %w", decodeError))
+	}
+	requestURL, parseError := url.Parse(request.URL)
+	if parseError != nil {
+		return nil, jobs.Permanent(fmt.Errorf("invalid stored request URL: %w", parseError))
+	}
+
+	replayContext := auth.WithPrincipal(ctx, auth.Principal{ID: job.PrincipalID, Roles: request.Roles})
+	if request.TenantVerified {
+		replayContext = tenant.WithVerifiedTenant(replayContext, job.TenantID)
+	} else {
+		replayContext = tenant.WithTenant(replayContext, job.TenantID)
+	}
+	replayContext = context.WithValue(replayContext, middleware.RequestIDKey, "async-"+job.ID)
+	// The wrapped handler routes the request itself, starting from a fresh routing context
+	replayContext = context.WithValue(replayContext, chi.RouteCtxKey, chi.NewRouteContext())
+
+	replay, requestError := http.NewRequestWithContext(replayContext, request.Method, requestURL.RequestURI(), bytes.NewReader(request.Body))
+	if requestError != nil {
+		return nil, jobs.Permanent(fmt.Errorf("invalid stored request: %w", requestError))
+	}
+	for headerName, value := range request.Headers {
+		replay.Header.Set(headerName, value)
+	}
+	replay.Host = requestURL.Host
+	if requestURL.Scheme == "https" {
+		// Handlers build absolute links from the request's scheme, so keep the one the client used
+		replay.TLS = &tls.ConnectionState{}
+	}
+
+	recorder := newResponseRecorder(runner.policy.MaxResponseBytes)
+	runner.handler.ServeHTTP(recorder, replay)
+	if errors.Is(recorder.writeError, errResponseTooLarge) {
+		return nil, jobs.Permanent(fmt.Errorf("the response exceeds %d bytes; narrow the request or page through it", runner.policy.MaxResponseBytes))
+	}
+	if recorder.statusCode == 0 {
+		recorder.statusCode = http.StatusOK
+	}
+
+	response := Response{Status: recorder.statusCode, Headers: map[string]string{}, Body: recorder.body.Bytes()}
+	for headerName := range recorder.header {
+		response.Headers[headerName] = recorder.header.Get(headerName)
+	}
+	return json.Marshal(response)
+}
+
+// replayHeaders returns the request headers a replay is sent with, without credentials or respond-async
+func replayHeaders(header http.Header) map[string]string {
+	stored := map[string]string{}
+	for headerName := range header {
+		stored[http.CanonicalHeaderKey(headerName)] = header.Get(headerName)
+	}
+	for _, headerName := range unreplayedHeaders {
+		delete(stored, http.CanonicalHeaderKey(headerName))
+	}
+
+	var preferences []string
+	for _, preference := range strings.Split(header.Get("Prefer"), ",") {
+		if preference = strings.TrimSpace(preference); preference != "" && !strings.EqualFold(preference, respondAsync) {
+			preferences = append(preferences, preference)
+		}
+	}
+	delete(stored, "Prefer")
+	if len(preferences) > 0 {
+		stored["Prefer"] = strings.Join(preferences, ", ")
+	}
+	return stored
+}
+
+// responseRecorder keeps the response to a replayed request, refusing bodies over its limit
+type responseRecorder struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+	limit      int
+	writeError error
+}
+
+// newResponseRecorder creates an empty recorder keeping at most limit body bytes
+func newResponseRecorder(limit int) *responseRecorder {
+	return &responseRecorder{header: make(http.Header), limit: limit}
+}
+
+// Header returns the response headers
+func (recorder *responseRecorder) Header() http.Header {
+	return recorder.header
+}
+
+// WriteHeader records the first status written
+func (recorder *responseRecorder) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+}
+
+// Write buffers the body, failing once it would pass the limit so the handler stops early
+func (recorder *responseRecorder) Write(bodyBytes []byte) (int, error) {
+	recorder.WriteHeader(http.StatusOK)
+	if recorder.body.Len()+len(bodyBytes) > recorder.limit {
+		recorder.writeError = errResponseTooLarge
+		return 0, errResponseTooLarge
+	}
+	return recorder.body.Write(bodyBytes)
+}
+
+// Flush does nothing; streamed responses are kept whole
+func (recorder *responseRecorder) Flush() {}
@@ -0,0 +1,330 @@
+package async
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/jobs"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// MockQueue implements Queue in memory for testing
+type MockQueue struct {
+	mutex sync.Mutex
+	jobs  map[string]*models.Job
+}
+
+// newMockQueue creates an empty queue
+func newMockQueue() *MockQueue {
+	return &MockQueue{jobs: make(map[string]*models.Job)}
+}
+
+// Enqueue stores a queued job owned by the caller
+func (mock *MockQueue) Enqueue(ctx context.Context, kind string, payload []byte, maxAttempts int) (*models.Job, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	job := &models.Job{
+		ID:          uuid.NewString(),
+		Kind:        kind,
+		Payload:     payload,
+		Status:      models.JobStatusQueued,
+		MaxAttempts: maxAttempts,
+		PrincipalID: auth.FromContext(ctx).ID,
+		TenantID:    tenant.FromContext(ctx),
+	}
+	mock.jobs[job.ID] = job
+	copied := *job
+	return &copied, nil
+}
+
+// Get returns a copy of the job
+func (mock *MockQueue) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	job, exists := mock.jobs[jobID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// Cancel marks an unfinished job cancelled
+func (mock *MockQueue) Cancel(ctx context.Context, jobID string) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	job, exists := mock.jobs[jobID]
+	if !exists || job.Status.Finished() {
+		return sql.ErrNoRows
+	}
+	job.Status = models.JobStatusCancelled
+	return nil
+}
+
+// Discard deletes a finished job
+func (mock *MockQueue) Discard(ctx context.Context, jobID string) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	job, exists := mock.jobs[jobID]
+	if !exists || !job.Status.Finished() {
+		return sql.ErrNoRows
+	}
+	delete(mock.jobs, jobID)
+	return nil
+}
+
+// run runs the job through the runner and records its outcome as the job queue would
+func (mock *MockQueue) run(t *testing.T, runner *Runner, jobID string) error {
+	t.Helper()
+	job, _ := mock.Get(context.Background(), jobID)
+	result, runError := runner.Run(context.Background(), job, func(string) {})
+
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	if runError != nil {
+		mock.jobs[jobID].Status = models.JobStatusFailed
+		mock.jobs[jobID].LastError = runError.Error()
+		return runError
+	}
+	mock.jobs[jobID].Status = models.JobStatusSucceeded
+	mock.jobs[jobID].Result = result
+	return nil
+}
+
+// newTestRunner creates a runner wrapping a router that echoes what each replay received
+func newTestRunner(queue Queue) (*Runner, http.Handler) {
+	runner := NewRunner(queue, DefaultPolicy())
+	router := chi.NewRouter()
+	router.Use(runner.Middleware)
+	router.Get("/fhir/Observation", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/fhir+json")
+		fmt.Fprintf(w, "principal=%s roles=%s tenant=%s verified=%s prefer=%s auth=%s url=%s",
+			auth.FromContext(r.Context()).ID, strings.Join(auth.FromContext(r.Context()).Roles, ","), tenant.FromContext(r.Context()),
+			tenant.VerifiedFromContext(r.Context()), r.Header.Get("Prefer"), r.Header.Get("Authorization"), fhirURL(r))
+	})
+	router.Post("/fhir/Patient/$match", func(w http.ResponseWriter, r *http.Request) {
+		requestBody, _ := io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write(requestBody)
+	})
+	router.Get("/fhir/Patient/$everything", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 64)))
+	})
+	return runner, router
+}
+
+// fhirURL rebuilds the absolute URL the handler saw
+func fhirURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// asyncRequest builds a request sent with Prefer: respond-async by alice in tenant-a
+func asyncRequest(method string, target string, body string) *http.Request {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Prefer", "handling=lenient, respond-async")
+	request.Header.Set("Authorization", "Bearer secret")
+	return request.WithContext(aliceContext(request.Context()))
+}
+
+// aliceContext returns ctx carrying alice as a clinician of the verified tenant-a
+func aliceContext(ctx context.Context) context.Context {
+	ctx = auth.WithPrincipal(ctx, auth.Principal{ID: "alice", Roles: []string{"clinician"}})
+	return tenant.WithVerifiedTenant(ctx, "tenant-a")
+}
+
+// accept sends the request through the router and returns the queued job's ID
+func accept(t *testing.T, router http.Handler, request *http.Request) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	contentLocation := recorder.Header().Get("Content-Location")
+	if !strings.HasPrefix(contentLocation, "http://example.com"+StatusPath) {
+		t.Fatalf("Expected Content-Location under %s, got %q", StatusPath, contentLocation)
+	}
+	return strings.TrimPrefix(contentLocation, "http://example.com"+StatusPath)
+}
+
+func TestEligible(t *testing.T) {
+	testCases := []struct {
+		method   string
+		path     string
+		expected bool
+	}{
+		{http.MethodGet, "/fhir/Observation", true},
+		{http.MethodGet, "/fhir/Patient/$everything", true},
+		{http.MethodPost, "/fhir/Patient/$match", true},
+		{http.MethodGet, "/fhir/Patient/123/$everything", true},
+		{http.MethodGet, "/fhir/Patient/$export", false},
+		{http.MethodGet, "/fhir/Patient/123", false},
+		{http.MethodPost, "/fhir/Observation", false},
+		{http.MethodGet, "/fhir/metadata", false},
+		{http.MethodGet, "/fhir/async/abc", false},
+		{http.MethodGet, "/admin/observations", false},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(testCase.method, testCase.path, nil)
+		if Eligible(request) != testCase.expected {
+			t.Errorf("Eligible(%s %s): expected %v", testCase.method, testCase.path, testCase.expected)
+		}
+	}
+}
+
+func TestMiddleware_PassesThroughWithoutPreference(t *testing.T) {
+	queue := newMockQueue()
+	_, router := newTestRunner(queue)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Observation", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+	if len(queue.jobs) != 0 {
+		t.Errorf("Expected nothing queued, got %d jobs", len(queue.jobs))
+	}
+}
+
+func TestMiddleware_OperationsRunAtMostOnce(t *testing.T) {
+	queue := newMockQueue()
+	_, router := newTestRunner(queue)
+
+	searchID := accept(t, router, asyncRequest(http.MethodGet, "/fhir/Observation", ""))
+	operationID := accept(t, router, asyncRequest(http.MethodPost, "/fhir/Patient/$match", "{}"))
+
+	if queue.jobs[searchID].MaxAttempts != DefaultPolicy().SearchAttempts {
+		t.Errorf("Expected a search to be retried, got %d attempts", queue.jobs[searchID].MaxAttempts)
+	}
+	if queue.jobs[operationID].MaxAttempts != 1 {
+		t.Errorf("Expected an operation to run once, got %d attempts", queue.jobs[operationID].MaxAttempts)
+	}
+}
+
+func TestRunner_ReplaysAsTheOriginalCaller(t *testing.T) {
+	queue := newMockQueue()
+	runner, router := newTestRunner(queue)
+	jobID := accept(t, router, asyncRequest(http.MethodGet, "/fhir/Observation?code=8480-6", ""))
+
+	if runError := queue.run(t, runner, jobID); runError != nil {
+		t.Fatalf("Unexpected run error: %v", runError)
+	}
+
+	job, response, getError := runner.Get(aliceContext(context.Background()), jobID)
+	if getError != nil || job.Status != models.JobStatusSucceeded || response == nil || response.Status != http.StatusOK {
+		t.Fatalf("Expected a 200 response, got %+v %+v (%v)", job, response, getError)
+	}
+	expectedBody := "principal=alice roles=clinician tenant=tenant-a verified=tenant-a prefer=handling=lenient auth= url=http://example.com/fhir/Observation?code=8480-6"
+	if string(response.Body) != expectedBody {
+		t.Errorf("Expected body %q, got %q", expectedBody, response.Body)
+	}
+	if response.Headers["Content-Type"] != "application/fhir+json" {
+		t.Errorf("Expected the Content-Type to be kept, got %v", response.Headers)
+	}
+}
+
+func TestRunner_ReplaysTheRequestBody(t *testing.T) {
+	queue := newMockQueue()
+	runner, router := newTestRunner(queue)
+	jobID := accept(t, router, asyncRequest(http.MethodPost, "/fhir/Patient/$match", `{"resourceType":"Parameters"}`))
+
+	queue.run(t, runner, jobID)
+
+	var response Response
+	json.Unmarshal(queue.jobs[jobID].Result, &response)
+	if string(response.Body) != `{"resourceType":"Parameters"}` {
+		t.Errorf("Expected the body to be replayed, got %q", response.Body)
+	}
+}
+
+func TestRunner_FailsOversizedResponses(t *testing.T) {
+	queue := newMockQueue()
+	runner, router := newTestRunner(queue)
+	runner.policy.MaxResponseBytes = 16
+	jobID := accept(t, router, asyncRequest(http.MethodGet, "/fhir/Patient/$everything", ""))
+
+	runError := queue.run(t, runner, jobID)
+
+	if !jobs.IsPermanent(runError) || !strings.Contains(runError.Error(), "exceeds 16 bytes") {
+		t.Errorf("Expected a permanent size failure, got %v", runError)
+	}
+}
+
+func TestRunner_GetHidesOtherCallersRequests(t *testing.T) {
+	queue := newMockQueue()
+	runner, router := newTestRunner(queue)
+	jobID := accept(t, router, asyncRequest(http.MethodGet, "/fhir/Observation", ""))
+
+	if _, _, getError := runner.Get(aliceContext(context.Background()), jobID); getError != nil {
+		t.Errorf("Expected the owner to see the request, got %v", getError)
+	}
+
+	otherContexts := []context.Context{
+		tenant.WithTenant(auth.WithPrincipal(context.Background(), auth.Principal{ID: "bob"}), "tenant-a"),
+		tenant.WithTenant(auth.WithPrincipal(context.Background(), auth.Principal{ID: "alice"}), "tenant-b"),
+	}
+	for _, otherContext := range otherContexts {
+		_, _, getError := runner.Get(otherContext, jobID)
+		if appError, ok := getError.(*apperrors.AppError); !ok || appError.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected not found for another caller, got %v", getError)
+		}
+	}
+
+	// Jobs of other kinds are never served as async requests
+	otherJob, _ := queue.Enqueue(aliceContext(context.Background()), "reindex", nil, 1)
+	_, _, getError := runner.Get(aliceContext(context.Background()), otherJob.ID)
+	if appError, ok := getError.(*apperrors.AppError); !ok || appError.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected not found for another kind of job, got %v", getError)
+	}
+}
+
+func TestRunner_CancelOrDiscard(t *testing.T) {
+	queue := newMockQueue()
+	runner, router := newTestRunner(queue)
+	queuedID := accept(t, router, asyncRequest(http.MethodGet, "/fhir/Observation", ""))
+	finishedID := accept(t, router, asyncRequest(http.MethodGet, "/fhir/Observation", ""))
+	queue.run(t, runner, finishedID)
+
+	for _, jobID := range []string{queuedID, finishedID} {
+		if cancelError := runner.Cancel(aliceContext(context.Background()), jobID); cancelError != nil {
+			t.Fatalf("Unexpected cancel error: %v", cancelError)
+		}
+		_, _, getError := runner.Get(aliceContext(context.Background()), jobID)
+		if appError, ok := getError.(*apperrors.AppError); !ok || appError.StatusCode != http.StatusNotFound {
+			t.Errorf("Expected the request to be gone after cancelling, got %v", getError)
+		}
+	}
+}
+
+func TestReplayHeaders_DropsCredentialsAndRespondAsync(t *testing.T) {
+	header := http.Header{}
+	header.Set("Authorization", "Bearer secret")
+	header.Set(tenant.HeaderAPIKey, "key")
+	header.Set("Accept", "application/fhir+json")
+	header.Set("Prefer", "respond-async")
+
+	stored := replayHeaders(header)
+
+	if len(stored) != 1 || stored["Accept"] != "application/fhir+json" {
+		t.Errorf("Expected only Accept to be kept, got %v", stored)
+	}
+}
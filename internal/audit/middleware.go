@@ -0,0 +1,222 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/authz"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// recordTimeout bounds how long a request waits for its audit event to be stored
+const recordTimeout = 5 * time.Second
+
+// Store persists audit events
+type Store interface {
+	Record(ctx context.Context, event *models.AuditEvent) error
+}
+
+// Auditor records an AuditEvent for every FHIR resource request and every request the role policy refuses
+type Auditor struct {
+	store Store
+}
+
+// NewAuditor creates an auditor writing to the store
+func NewAuditor(store Store) *Auditor {
+	return &Auditor{store: store}
+}
+
+// statusRecorder captures the status code written by the wrapped handler, and the body when capturing is on
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	captureBody bool
+	body        bytes.Buffer
+}
+
+// WriteHeader captures the status code before writing
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	recorder.statusCode = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write copies the body when capturing before writing it through
+func (recorder *statusRecorder) Write(body []byte) (int, error) {
+	if recorder.captureBody {
+		recorder.body.Write(body)
+	}
+	return recorder.ResponseWriter.Write(body)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// Middleware records who made each FHIR resource request, what it touched, and how it ended
+// It runs after the principal and tenant are resolved; requests outside the resource endpoints are not audited.
+// Transaction and batch bundle entries are re-dispatched through the router, so each entry is audited on its own
+func (auditor *Auditor) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		event, audited := newEvent(r)
+		if !audited {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Creates only learn their resource's ID from the response body
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK, captureBody: event.Action == models.AuditActionCreate}
+		next.ServeHTTP(recorder, r)
+
+		event.StatusCode = recorder.statusCode
+		event.Outcome = models.AuditOutcomeForStatus(recorder.statusCode)
+		event.RequestID = w.Header().Get("X-Request-ID")
+		if event.Action == models.AuditActionCreate {
+			event.EntityID = createdResourceID(recorder.body.Bytes())
+		}
+		auditor.record(r.Context(), event)
+	})
+}
+
+// RecordDenial records a request the role policy refused as a failed AuditEvent, and logs it
+// Auditor implements authz.DenialRecorder
+func (auditor *Auditor) RecordDenial(ctx context.Context, denial authz.Denial) {
+	authz.LogDenialRecorder{}.RecordDenial(ctx, denial)
+
+	resourceType, interaction, _ := auth.RequiredAccess(denial.Method, denial.Path)
+	event := &models.AuditEvent{
+		StatusCode:    http.StatusForbidden,
+		Outcome:       models.AuditOutcomeMinorFailure,
+		AgentID:       denial.PrincipalID,
+		AgentRoles:    denial.Roles,
+		TenantID:      denial.TenantID,
+		ClientAddress: clientAddress(denial.ClientAddress),
+		EntityType:    resourceType,
+		EntityID:      entityID(denial.Path),
+		Method:        denial.Method,
+		Path:          denial.Path,
+		RequestID:     denial.RequestID,
+	}
+	if denial.PrincipalID == auth.AnonymousPrincipalID {
+		event.StatusCode = http.StatusUnauthorized
+	}
+	event.Action, event.Interaction = classify(denial.Method, denial.Path, interaction)
+	auditor.record(ctx, event)
+}
+
+// record stores the event even when the request was canceled, logging events that could not be stored
+func (auditor *Auditor) record(ctx context.Context, event *models.AuditEvent) {
+	recordContext, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	if recordError := auditor.store.Record(recordContext, event); recordError != nil {
+		log.Error().
+			Err(recordError).
+			Str("audit", "audit_event_lost").
+			Str("principal", event.AgentID).
+			Str("method", event.Method).
+			Str("path", event.Path).
+			Str("request_id", event.RequestID).
+			Msg("Failed to record audit event")
+	}
+}
+
+// newEvent starts the audit event for a FHIR resource request, reporting false for requests that are not audited
+func newEvent(r *http.Request) (*models.AuditEvent, bool) {
+	resourceType, interaction, scoped := auth.RequiredAccess(r.Method, r.URL.Path)
+	if !scoped {
+		return nil, false
+	}
+
+	principal := auth.FromContext(r.Context())
+	event := &models.AuditEvent{
+		AgentID:       principal.ID,
+		AgentRoles:    principal.Roles,
+		TenantID:      tenant.FromContext(r.Context()),
+		ClientAddress: clientAddress(r.RemoteAddr),
+		EntityType:    resourceType,
+		EntityID:      entityID(r.URL.Path),
+		Method:        r.Method,
+		Path:          r.URL.Path,
+	}
+	event.Action, event.Interaction = classify(r.Method, r.URL.Path, interaction)
+	if event.Action == models.AuditActionExecute {
+		event.Query = r.URL.RawQuery
+	}
+	return event, true
+}
+
+// classify maps a request and the SMART interaction it needs to the AuditEvent action and the FHIR
+// restful-interaction code
+func classify(method string, path string, interaction string) (string, string) {
+	operation := strings.Contains(path, "/$")
+	switch interaction {
+	case auth.InteractionCreate:
+		return models.AuditActionCreate, "create"
+	case auth.InteractionUpdate:
+		if operation {
+			return models.AuditActionUpdate, "operation"
+		}
+		if method == http.MethodPatch {
+			return models.AuditActionUpdate, "patch"
+		}
+		return models.AuditActionUpdate, "update"
+	case auth.InteractionDelete:
+		return models.AuditActionDelete, "delete"
+	case auth.InteractionRead:
+		if operation {
+			return models.AuditActionExecute, "operation"
+		}
+		if strings.Contains(path, "/_history/") {
+			return models.AuditActionRead, "vread"
+		}
+		if strings.HasSuffix(path, "/_history") {
+			return models.AuditActionRead, "history-instance"
+		}
+		return models.AuditActionRead, "read"
+	}
+
+	// Searches, type-level history, and type-level operations
+	if operation {
+		return models.AuditActionExecute, "operation"
+	}
+	if strings.HasSuffix(path, "/_history") {
+		return models.AuditActionExecute, "history-type"
+	}
+	return models.AuditActionExecute, "search-type"
+}
+
+// entityID returns the resource ID from /fhir/{type}/{id}/..., or "" for type-level requests
+func entityID(path string) string {
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/fhir/"), "/"), "/")
+	if len(segments) < 2 || strings.HasPrefix(segments[1], "$") || strings.HasPrefix(segments[1], "_") {
+		return ""
+	}
+	return segments[1]
+}
+
+// createdResourceID reads the id of the resource returned by a create, or "" when there is none
+func createdResourceID(responseBody []byte) string {
+	var createdResource struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(responseBody, &createdResource)
+	return createdResource.ID
+}
+
+// clientAddress strips the port from a remote address
+func clientAddress(remoteAddress string) string {
+	host, _, splitError := net.SplitHostPort(remoteAddress)
+	if splitError != nil {
+		return remoteAddress
+	}
+	return host
+}
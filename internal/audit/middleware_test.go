@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/authz"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// memoryStore keeps recorded events in memory for testing
+type memoryStore struct {
+	events []*models.AuditEvent
+}
+
+// Record appends the event
+func (store *memoryStore) Record(ctx context.Context, event *models.AuditEvent) error {
+	store.events = append(store.events, event)
+	return nil
+}
+
+// TestAuditor_Middleware verifies each resource request is recorded with its agent, entity, and outcome
+func TestAuditor_Middleware(t *testing.T) {
+	store := &memoryStore{}
+	handler := NewAuditor(store).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost:
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"resourceType":"Observation","id":"obs-9"}`))
+		case r.URL.Path == "/fhir/Observation/missing":
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	testCases := []struct {
+		method              string
+		target              string
+		expectedAction      string
+		expectedInteraction string
+		expectedEntityID    string
+		expectedOutcome     string
+		expectedQuery       string
+	}{
+		{http.MethodPost, "/fhir/Observation", models.AuditActionCreate, "create", "obs-9", models.AuditOutcomeSuccess, ""},
+		{http.MethodGet, "/fhir/Observation/obs-1", models.AuditActionRead, "read", "obs-1", models.AuditOutcomeSuccess, ""},
+		{http.MethodGet, "/fhir/Observation/missing", models.AuditActionRead, "read", "missing", models.AuditOutcomeMinorFailure, ""},
+		{http.MethodGet, "/fhir/Observation/obs-1/_history/2", models.AuditActionRead, "vread", "obs-1", models.AuditOutcomeSuccess, ""},
+		{http.MethodGet, "/fhir/Observation?code=1234-5", models.AuditActionExecute, "search-type", "", models.AuditOutcomeSuccess, "code=1234-5"},
+		{http.MethodGet, "/fhir/Patient/p-1/$everything", models.AuditActionExecute, "operation", "p-1", models.AuditOutcomeSuccess, ""},
+		{http.MethodPut, "/fhir/Observation/obs-1", models.AuditActionUpdate, "update", "obs-1", models.AuditOutcomeSuccess, ""},
+		{http.MethodDelete, "/fhir/Observation/obs-1", models.AuditActionDelete, "delete", "obs-1", models.AuditOutcomeSuccess, ""},
+	}
+
+	for _, testCase := range testCases {
+		store.events = nil
+		request := httptest.NewRequest(testCase.method, testCase.target, nil)
+		request.RemoteAddr = "10.0.0.7:51234"
+		principal := auth.Principal{ID: "api-key:clinic", Roles: []string{auth.RoleClinician}}
+		request = request.WithContext(tenant.WithTenant(auth.WithPrincipal(request.Context(), principal), "clinic-a"))
+		handler.ServeHTTP(httptest.NewRecorder(), request)
+
+		if len(store.events) != 1 {
+			t.Fatalf("%s %s: expected one audit event, got %d", testCase.method, testCase.target, len(store.events))
+		}
+		event := store.events[0]
+		if event.Action != testCase.expectedAction || event.Interaction != testCase.expectedInteraction ||
+			event.EntityID != testCase.expectedEntityID || event.Outcome != testCase.expectedOutcome || event.Query != testCase.expectedQuery {
+			t.Errorf("%s %s: unexpected event %+v", testCase.method, testCase.target, event)
+		}
+		if event.AgentID != "api-key:clinic" || event.TenantID != "clinic-a" || event.ClientAddress != "10.0.0.7" {
+			t.Errorf("%s %s: expected the agent, tenant, and address, got %+v", testCase.method, testCase.target, event)
+		}
+	}
+
+	store.events = nil
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+	if len(store.events) != 0 {
+		t.Errorf("Expected non-resource routes not to be audited, got %+v", store.events)
+	}
+}
+
+// TestAuditor_RecordDenial verifies refused requests are recorded as failures
+func TestAuditor_RecordDenial(t *testing.T) {
+	store := &memoryStore{}
+	NewAuditor(store).RecordDenial(context.Background(), authz.Denial{
+		PrincipalID:   "api-key:patient",
+		Method:        http.MethodDelete,
+		Path:          "/fhir/Observation/obs-1",
+		ClientAddress: "10.0.0.7:51234",
+		Reason:        "requires a role granting d on Observation",
+	})
+
+	if len(store.events) != 1 {
+		t.Fatalf("Expected one audit event, got %d", len(store.events))
+	}
+	event := store.events[0]
+	if event.Action != models.AuditActionDelete || event.Outcome != models.AuditOutcomeMinorFailure || event.StatusCode != http.StatusForbidden ||
+		event.EntityType != "Observation" || event.EntityID != "obs-1" || event.ClientAddress != "10.0.0.7" {
+		t.Errorf("Expected a failed delete of the observation, got %+v", event)
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// RoleCompliance may place and release legal holds
+const RoleCompliance = "compliance"
+
+// RoleIdentityAdmin may re-key patient identifiers in bulk and merge duplicate patients
+const RoleIdentityAdmin = "identity-admin"
+
+// RoleOperator may toggle maintenance mode and drain connections
+const RoleOperator = "operator"
+
+// RoleAdmin may perform any administrative operation
+const RoleAdmin = "admin"
+
+// RoleClinician may work with the clinical records of the patients in their care
+const RoleClinician = "clinician"
+
+// RolePatient may work with their own records
+const RolePatient = "patient"
+
+// AnonymousPrincipalID identifies requests made without an API key
+const AnonymousPrincipalID = "anonymous"
+
+// Principal is the caller a request is attributed to
+type Principal struct {
+	// ID identifies the caller in audit logs without revealing credentials
+	ID string
+
+	// Roles granted to the caller
+	Roles []string
+}
+
+// HasRole reports whether the principal was granted role
+func (principal Principal) HasRole(role string) bool {
+	return slices.Contains(principal.Roles, role)
+}
+
+// HasAnyRole reports whether the principal was granted at least one of roles
+func (principal Principal) HasAnyRole(roles ...string) bool {
+	for _, role := range roles {
+		if principal.HasRole(role) {
+			return true
+		}
+	}
+	return false
+}
+
+// contextKey is a custom type for auth context keys to avoid collisions
+type contextKey string
+
+// principalKey is the context key for the request's principal
+const principalKey contextKey = "principal"
+
+// WithPrincipal returns a context carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+// FromContext returns the request's principal, or an anonymous principal with no roles
+func FromContext(ctx context.Context) Principal {
+	if principal, ok := ctx.Value(principalKey).(Principal); ok {
+		return principal
+	}
+	return Principal{ID: AnonymousPrincipalID}
+}
+
+// RoleResolver attaches a principal and its roles to each request based on its API key
+type RoleResolver struct {
+	// keyRoles maps API keys to granted roles
+	keyRoles map[string][]string
+}
+
+// NewRoleResolver creates a resolver with the given API key to roles mapping
+func NewRoleResolver(keyRoles map[string][]string) *RoleResolver {
+	return &RoleResolver{
+		keyRoles: keyRoles,
+	}
+}
+
+// Middleware stores the principal in the request context
+// It runs after tenant resolution, which has already rejected unknown API keys
+func (resolver *RoleResolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := Principal{ID: AnonymousPrincipalID}
+		if apiKey := r.Header.Get(tenant.HeaderAPIKey); apiKey != "" {
+			principal = Principal{ID: KeyFingerprint(apiKey), Roles: resolver.keyRoles[apiKey]}
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// KeyFingerprint returns a short, non-reversible identifier for an API key
+func KeyFingerprint(apiKey string) string {
+	digest := sha256.Sum256([]byte(apiKey))
+	return "api-key:" + hex.EncodeToString(digest[:4])
+}
+
+// ParseKeyRoles parses a "key:role|role,key:role" list into an API key to roles mapping
+func ParseKeyRoles(rawKeyRoles string) (map[string][]string, error) {
+	keyRoles := make(map[string][]string)
+	for _, pair := range strings.Split(rawKeyRoles, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		apiKey, rawRoles, found := strings.Cut(pair, ":")
+		apiKey = strings.TrimSpace(apiKey)
+		if !found || apiKey == "" || strings.TrimSpace(rawRoles) == "" {
+			return nil, fmt.Errorf("invalid API key role entry %q: expected key:role|role", pair)
+		}
+
+		for _, role := range strings.Split(rawRoles, "|") {
+			if role = strings.TrimSpace(role); role != "" {
+				keyRoles[apiKey] = append(keyRoles[apiKey], role)
+			}
+		}
+	}
+	return keyRoles, nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestParseKeyRoles verifies role lists are parsed per key
+func TestParseKeyRoles(t *testing.T) {
+	keyRoles, parseError := ParseKeyRoles("key-1:compliance|admin, key-2:admin")
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+	if len(keyRoles["key-1"]) != 2 || keyRoles["key-1"][0] != RoleCompliance || keyRoles["key-2"][0] != "admin" {
+		t.Errorf("Unexpected roles: %v", keyRoles)
+	}
+
+	if _, parseError := ParseKeyRoles("key-1"); parseError == nil {
+		t.Error("Expected error for entry without roles")
+	}
+}
+
+// TestRoleResolver_Middleware verifies principals are attached from API keys
+func TestRoleResolver_Middleware(t *testing.T) {
+	resolver := NewRoleResolver(map[string][]string{"secret-key": {RoleCompliance}})
+
+	var principal Principal
+	handler := resolver.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal = FromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("X-API-Key", "secret-key")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	if !principal.HasRole(RoleCompliance) || !strings.HasPrefix(principal.ID, "api-key:") || strings.Contains(principal.ID, "secret-key") {
+		t.Errorf("Unexpected principal: %+v", principal)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if principal.ID != AnonymousPrincipalID || principal.HasRole(RoleCompliance) {
+		t.Errorf("Expected anonymous principal, got %+v", principal)
+	}
+}
+
+// TestFromContext_Default verifies contexts without a principal are anonymous
+func TestFromContext_Default(t *testing.T) {
+	if FromContext(context.Background()).ID != AnonymousPrincipalID {
+		t.Error("Expected anonymous principal")
+	}
+}
@@ -0,0 +1,175 @@
+package auth
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/rs/zerolog/log"
+)
+
+// Route policy defaults for requests that match no rule
+const (
+	// PolicyAllow lets unmatched requests through to the checks built into the handlers
+	PolicyAllow = "allow"
+
+	// PolicyDeny refuses unmatched requests, so every route must be listed
+	PolicyDeny = "deny"
+)
+
+// RouteRule grants access to the requests matching a method and path pattern
+type RouteRule struct {
+	// Methods the rule applies to; empty applies it to every method
+	Methods []string `json:"methods"`
+
+	// Path is matched segment by segment: "{name}" matches any one segment and a final "*" matches
+	// the rest of the path, so "/admin/*" covers every admin route
+	Path string `json:"path"`
+
+	// Roles grants access to callers with at least one of them
+	Roles []string `json:"roles"`
+
+	// Authenticated grants access to any caller with an API key when Roles is empty
+	// A rule with neither Roles nor Authenticated is public
+	Authenticated bool `json:"authenticated"`
+}
+
+// RoutePolicy maps routes to the roles allowed to call them
+// Rules are checked in order and the first one matching a request decides it
+// The policy adds to the role checks built into handlers; it cannot grant what they refuse
+type RoutePolicy struct {
+	// Default decides requests no rule matches: allow (the default) or deny
+	Default string `json:"default"`
+
+	Rules []RouteRule `json:"rules"`
+}
+
+// LoadRoutePolicy reads and validates a route policy from a JSON file
+func LoadRoutePolicy(path string) (*RoutePolicy, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read route policy: %w", readError)
+	}
+
+	policy := &RoutePolicy{}
+	if decodeError := json.Unmarshal(fileBytes, policy); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse route policy: %w", decodeError)
+	}
+
+	return policy, policy.Validate()
+}
+
+// Validate checks the default and that every rule has a rooted path and known methods
+func (policy *RoutePolicy) Validate() error {
+	if policy.Default != "" && policy.Default != PolicyAllow && policy.Default != PolicyDeny {
+		return fmt.Errorf("route policy default %q must be %s or %s", policy.Default, PolicyAllow, PolicyDeny)
+	}
+
+	knownMethods := []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+	var ruleErrors []error
+	for ruleIndex, rule := range policy.Rules {
+		if !strings.HasPrefix(rule.Path, "/") {
+			ruleErrors = append(ruleErrors, fmt.Errorf("route rule %d: path %q must start with /", ruleIndex, rule.Path))
+		}
+		if wildcardIndex := strings.Index(rule.Path, "*"); wildcardIndex >= 0 && wildcardIndex != len(rule.Path)-1 {
+			ruleErrors = append(ruleErrors, fmt.Errorf("route rule %d: * may only end path %q", ruleIndex, rule.Path))
+		}
+		for _, method := range rule.Methods {
+			if !slices.Contains(knownMethods, method) {
+				ruleErrors = append(ruleErrors, fmt.Errorf("route rule %d: unknown method %q", ruleIndex, method))
+			}
+		}
+	}
+	return errors.Join(ruleErrors...)
+}
+
+// Check decides whether principal may make the request, returning a 401 for anonymous callers and a 403
+// for callers without a granted role; a nil policy allows everything
+func (policy *RoutePolicy) Check(principal Principal, method string, path string) error {
+	if policy == nil {
+		return nil
+	}
+
+	ruleIndex := policy.match(method, path)
+	if ruleIndex < 0 {
+		if policy.Default == PolicyDeny {
+			return policyRefusal(principal, "No route policy rule allows "+method+" "+path)
+		}
+		return nil
+	}
+
+	rule := policy.Rules[ruleIndex]
+	switch {
+	case len(rule.Roles) > 0 && !principal.HasAnyRole(rule.Roles...):
+		return policyRefusal(principal, method+" "+path+" requires one of the roles "+strings.Join(rule.Roles, ", "))
+	case len(rule.Roles) == 0 && rule.Authenticated && principal.ID == AnonymousPrincipalID:
+		return policyRefusal(principal, method+" "+path+" requires an API key")
+	}
+	return nil
+}
+
+// match returns the index of the first rule matching the request, or -1
+func (policy *RoutePolicy) match(method string, path string) int {
+	for ruleIndex, rule := range policy.Rules {
+		if len(rule.Methods) > 0 && !slices.Contains(rule.Methods, method) {
+			continue
+		}
+		if pathMatches(rule.Path, path) {
+			return ruleIndex
+		}
+	}
+	return -1
+}
+
+// pathMatches reports whether a request path matches a rule's path pattern
+func pathMatches(pattern string, path string) bool {
+	patternSegments := strings.Split(strings.TrimSuffix(pattern, "/"), "/")
+	pathSegments := strings.Split(strings.TrimSuffix(path, "/"), "/")
+
+	for segmentIndex, patternSegment := range patternSegments {
+		if patternSegment == "*" && segmentIndex == len(patternSegments)-1 {
+			return len(pathSegments) > segmentIndex
+		}
+		if segmentIndex >= len(pathSegments) {
+			return false
+		}
+		isParameter := strings.HasPrefix(patternSegment, "{") && strings.HasSuffix(patternSegment, "}")
+		if isParameter && pathSegments[segmentIndex] != "" {
+			continue
+		}
+		if patternSegment != pathSegments[segmentIndex] {
+			return false
+		}
+	}
+	return len(pathSegments) == len(patternSegments)
+}
+
+// policyRefusal is a 401 for anonymous callers, who may succeed with an API key, and a 403 otherwise
+func policyRefusal(principal Principal, message string) error {
+	if principal.ID == AnonymousPrincipalID {
+		return apperrors.Unauthorized(message)
+	}
+	return apperrors.Forbidden(message)
+}
+
+// PolicyMiddleware enforces the route policy on every request
+// It runs after the role resolver, which puts the principal in the request context
+func PolicyMiddleware(policy *RoutePolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := FromContext(r.Context())
+			if checkError := policy.Check(principal, r.Method, r.URL.Path); checkError != nil {
+				log.Info().Str("principal", principal.ID).Str("method", r.Method).Str("path", r.URL.Path).Msg("Request refused by route policy")
+				middleware.WriteError(w, r, checkError)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+)
+
+// testRoutePolicy guards admin writes by role, requires a key for health exports, and keeps health public
+func testRoutePolicy() *RoutePolicy {
+	return &RoutePolicy{
+		Default: PolicyAllow,
+		Rules: []RouteRule{
+			{Path: "/admin/operations/*", Roles: []string{RoleOperator, RoleAdmin}},
+			{Methods: []string{http.MethodGet}, Path: "/admin/*", Roles: []string{"auditor", RoleAdmin}},
+			{Path: "/fhir/Patient/{id}/$health-export", Authenticated: true},
+			{Path: "/health"},
+		},
+	}
+}
+
+// statusOf returns the HTTP status of a policy decision, 200 when it allows the request
+func statusOf(checkError error) int {
+	var appError *apperrors.AppError
+	if errors.As(checkError, &appError) {
+		return appError.StatusCode
+	}
+	return http.StatusOK
+}
+
+// TestRoutePolicy_Check verifies the first matching rule decides each request
+func TestRoutePolicy_Check(t *testing.T) {
+	operator := Principal{ID: "key-1", Roles: []string{RoleOperator}}
+	auditor := Principal{ID: "key-2", Roles: []string{"auditor"}}
+	anonymous := Principal{ID: AnonymousPrincipalID}
+
+	testCases := []struct {
+		name           string
+		principal      Principal
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"role granted", operator, http.MethodPut, "/admin/operations/maintenance", http.StatusOK},
+		{"earlier rule wins", auditor, http.MethodGet, "/admin/operations", http.StatusOK},
+		{"earlier rule refuses", auditor, http.MethodPut, "/admin/operations/maintenance", http.StatusForbidden},
+		{"method filtered", auditor, http.MethodGet, "/admin/rollups", http.StatusOK},
+		{"role missing", operator, http.MethodGet, "/admin/rollups", http.StatusForbidden},
+		{"anonymous gets 401", anonymous, http.MethodGet, "/admin/rollups", http.StatusUnauthorized},
+		{"parameter segment", anonymous, http.MethodGet, "/fhir/Patient/p-1/$health-export", http.StatusUnauthorized},
+		{"authenticated", auditor, http.MethodGet, "/fhir/Patient/p-1/$health-export", http.StatusOK},
+		{"public rule", anonymous, http.MethodGet, "/health", http.StatusOK},
+		{"unmatched allowed", anonymous, http.MethodGet, "/fhir/Patient", http.StatusOK},
+	}
+
+	policy := testRoutePolicy()
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			status := statusOf(policy.Check(testCase.principal, testCase.method, testCase.path))
+			if status != testCase.expectedStatus {
+				t.Errorf("Expected %d for %s %s, got %d", testCase.expectedStatus, testCase.method, testCase.path, status)
+			}
+		})
+	}
+}
+
+// TestRoutePolicy_DefaultDeny verifies unmatched requests are refused when the default is deny
+func TestRoutePolicy_DefaultDeny(t *testing.T) {
+	policy := testRoutePolicy()
+	policy.Default = PolicyDeny
+
+	if status := statusOf(policy.Check(Principal{ID: "key-1"}, http.MethodGet, "/fhir/Patient")); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for an unlisted route, got %d", status)
+	}
+	if status := statusOf(policy.Check(Principal{ID: AnonymousPrincipalID}, http.MethodGet, "/health")); status != http.StatusOK {
+		t.Errorf("Expected listed public routes to stay open, got %d", status)
+	}
+}
+
+// TestPathMatches verifies parameter and trailing wildcard segments
+func TestPathMatches(t *testing.T) {
+	testCases := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"/fhir/Patient/{id}", "/fhir/Patient/123", true},
+		{"/fhir/Patient/{id}", "/fhir/Patient/123/$everything", false},
+		{"/fhir/Patient/{id}", "/fhir/Patient/", false},
+		{"/admin/*", "/admin/erasures/1/cancel", true},
+		{"/admin/*", "/admin", false},
+		{"/health", "/health/", true},
+		{"/health", "/healthz", false},
+	}
+
+	for _, testCase := range testCases {
+		if matched := pathMatches(testCase.pattern, testCase.path); matched != testCase.expected {
+			t.Errorf("Expected %s matching %s to be %v", testCase.pattern, testCase.path, testCase.expected)
+		}
+	}
+}
+
+// TestLoadRoutePolicy verifies a policy file is parsed and invalid rules are rejected
+func TestLoadRoutePolicy(t *testing.T) {
+	directory := t.TempDir()
+	validPath := filepath.Join(directory, "valid.json")
+	os.WriteFile(validPath, []byte(`{"default": "deny", "rules": [{"methods": ["GET"], "path": "/fhir/*", "authenticated": true}]}`), 0o600)
+
+	policy, loadError := LoadRoutePolicy(validPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if policy.Default != PolicyDeny || len(policy.Rules) != 1 || !policy.Rules[0].Authenticated {
+		t.Errorf("Expected the rule to be loaded, got %+v", policy)
+	}
+
+	invalidPath := filepath.Join(directory, "invalid.json")
+	os.WriteFile(invalidPath, []byte(`{"default": "maybe", "rules": [{"methods": ["FETCH"], "path": "admin/*/x"}]}`), 0o600)
+	if _, invalidError := LoadRoutePolicy(invalidPath); invalidError == nil {
+		t.Error("Expected an invalid policy to be rejected")
+	}
+}
+
+// TestPolicyMiddleware verifies refused requests never reach the handler
+func TestPolicyMiddleware(t *testing.T) {
+	reached := false
+	handler := PolicyMiddleware(testRoutePolicy())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/admin/rollups", nil)
+	request = request.WithContext(WithPrincipal(request.Context(), Principal{ID: "key-1"}))
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusForbidden || reached {
+		t.Errorf("Expected 403 without reaching the handler, got %d (reached %v)", recorder.Code, reached)
+	}
+}
@@ -0,0 +1,282 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"unicode"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// SMART scope contexts
+const (
+	// ScopeContextPatient limits a scope to the launch patient's compartment
+	ScopeContextPatient = "patient"
+
+	// ScopeContextUser grants whatever the signed-in user may access
+	ScopeContextUser = "user"
+
+	// ScopeContextSystem grants a backend service access without a user
+	ScopeContextSystem = "system"
+)
+
+// SMART v2 interactions; v1 read maps to read and search, v1 write to create, update, and delete
+const (
+	InteractionCreate = "c"
+	InteractionRead   = "r"
+	InteractionUpdate = "u"
+	InteractionDelete = "d"
+	InteractionSearch = "s"
+)
+
+// allInteractions lists the v2 interactions in the order a scope must name them
+const allInteractions = "cruds"
+
+// tokenPublicPaths are served without a token: probes, metrics scrapes, the capability statement, and client registration
+var tokenPublicPaths = []string{"/health", "/health/live", "/health/ready", "/ready", "/metrics", "/fhir/metadata", "/oauth/register"}
+
+// Scope is one parsed SMART clinical scope such as patient/Observation.read or user/*.cruds
+type Scope struct {
+	// Context is patient, user, or system
+	Context string
+
+	// ResourceType is the FHIR resource type, or * for every type
+	ResourceType string
+
+	// Interactions are the granted v2 interaction letters, a subset of "cruds"
+	Interactions string
+}
+
+// Grants reports whether the scope allows the interaction on the resource type
+func (scope Scope) Grants(resourceType string, interaction string) bool {
+	return (scope.ResourceType == "*" || scope.ResourceType == resourceType) && strings.Contains(scope.Interactions, interaction)
+}
+
+// ParseScopes parses a space-separated scope claim, keeping SMART clinical scopes and skipping others
+// such as openid, fhirUser, and launch/patient
+func ParseScopes(rawScopes string) []Scope {
+	scopes := []Scope{}
+	for _, rawScope := range strings.Fields(rawScopes) {
+		if scope, parsed := parseScope(rawScope); parsed {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// parseScope parses one context/Type.permissions scope in SMART v1 (read, write, *) or v2 (cruds) syntax,
+// ignoring any ?param=value constraint suffix of v2 scopes
+func parseScope(rawScope string) (Scope, bool) {
+	scopeContext, resourceAndPermissions, found := strings.Cut(rawScope, "/")
+	if !found || !slices.Contains([]string{ScopeContextPatient, ScopeContextUser, ScopeContextSystem}, scopeContext) {
+		return Scope{}, false
+	}
+	resourceAndPermissions, _, _ = strings.Cut(resourceAndPermissions, "?")
+	resourceType, permissions, found := strings.Cut(resourceAndPermissions, ".")
+	if !found || resourceType == "" {
+		return Scope{}, false
+	}
+
+	interactions, parsed := ParseInteractions(permissions)
+	if !parsed {
+		return Scope{}, false
+	}
+
+	return Scope{Context: scopeContext, ResourceType: resourceType, Interactions: interactions}, true
+}
+
+// ParseInteractions converts SMART permissions, v1 read, write, or * or v2 letters such as rs, into the
+// granted v2 interaction letters
+func ParseInteractions(permissions string) (string, bool) {
+	switch permissions {
+	case "read":
+		return InteractionRead + InteractionSearch, true
+	case "write":
+		return InteractionCreate + InteractionUpdate + InteractionDelete, true
+	case "*":
+		return allInteractions, true
+	case "":
+		return "", false
+	}
+
+	// v2 letters must appear at most once each and in c, r, u, d, s order
+	remaining := allInteractions
+	for _, letter := range permissions {
+		letterIndex := strings.IndexRune(remaining, letter)
+		if letterIndex < 0 {
+			return "", false
+		}
+		remaining = remaining[letterIndex+1:]
+	}
+	return permissions, true
+}
+
+// RequiredAccess maps a request to the resource type and interaction a scope must grant
+// It reports false for requests outside the FHIR resource endpoints, such as /fhir/bulk-export/{id} and
+// transaction bundles, whose entries are checked as they are dispatched
+func RequiredAccess(method string, path string) (string, string, bool) {
+	resourcePath, found := strings.CutPrefix(path, "/fhir/")
+	if !found {
+		return "", "", false
+	}
+	segments := strings.Split(strings.TrimSuffix(resourcePath, "/"), "/")
+	resourceType := segments[0]
+	if resourceType == "" || !unicode.IsUpper(rune(resourceType[0])) {
+		return "", "", false
+	}
+	typeLevel := len(segments) == 1 || strings.HasPrefix(segments[1], "$") || strings.HasPrefix(segments[1], "_")
+
+	switch method {
+	case http.MethodGet, http.MethodHead:
+		if typeLevel {
+			return resourceType, InteractionSearch, true
+		}
+		return resourceType, InteractionRead, true
+	case http.MethodPost:
+		switch {
+		case len(segments) == 1:
+			return resourceType, InteractionCreate, true
+		case segments[1] == "_search":
+			return resourceType, InteractionSearch, true
+		}
+		// Operations such as $meta-add change the resource they are invoked on
+		return resourceType, InteractionUpdate, true
+	case http.MethodPut, http.MethodPatch:
+		return resourceType, InteractionUpdate, true
+	case http.MethodDelete:
+		return resourceType, InteractionDelete, true
+	}
+	return "", "", false
+}
+
+// CheckScopes decides whether a token's scopes allow the request, returning a 403 when they do not
+// Patient-level scopes only allow requests the path or query limits to the launch patient: the patient's
+// own record, or searches whose patient or subject parameter names it
+func CheckScopes(claims *TokenClaims, r *http.Request) error {
+	resourceType, interaction, scoped := RequiredAccess(r.Method, r.URL.Path)
+	if !scoped {
+		return nil
+	}
+
+	patientScopeGranted := false
+	for _, scope := range claims.Scopes {
+		if !scope.Grants(resourceType, interaction) {
+			continue
+		}
+		if scope.Context != ScopeContextPatient {
+			return nil
+		}
+		patientScopeGranted = true
+	}
+
+	if !patientScopeGranted {
+		return apperrors.Forbidden(r.Method + " " + r.URL.Path + " requires a scope granting " + interaction + " on " + resourceType)
+	}
+	if claims.PatientID == "" || !limitedToPatient(r, resourceType, claims.PatientID) {
+		return apperrors.Forbidden("Patient-level scopes only allow requests limited to the launch patient")
+	}
+	return nil
+}
+
+// limitedToPatient reports whether the request's path or query confines it to the patient's compartment
+func limitedToPatient(r *http.Request, resourceType string, patientID string) bool {
+	segments := strings.Split(strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/fhir/"), "/"), "/")
+	queryValues := r.URL.Query()
+	isSearch := (len(segments) == 1 && r.Method != http.MethodPost) || (len(segments) == 2 && segments[1] == "_search")
+
+	if resourceType == "Patient" {
+		if len(segments) > 1 && segments[1] == patientID {
+			return true
+		}
+		return isSearch && slices.Equal(queryValues["_id"], []string{patientID})
+	}
+
+	if !isSearch {
+		return false
+	}
+	for _, parameter := range []string{"patient", "subject"} {
+		values := queryValues[parameter]
+		if len(values) == 1 && (values[0] == patientID || values[0] == "Patient/"+patientID) {
+			return true
+		}
+	}
+	return false
+}
+
+// fhirUserRoles grants the clinician role to practitioners and the patient role to patients signing in
+// fhirUser is a relative or absolute reference, so its type is the second-to-last path segment
+func fhirUserRoles(fhirUser string) []string {
+	segments := strings.Split(strings.TrimSuffix(fhirUser, "/"), "/")
+	if len(segments) < 2 {
+		return nil
+	}
+
+	switch segments[len(segments)-2] {
+	case "Practitioner", "PractitionerRole":
+		return []string{RoleClinician}
+	case "Patient":
+		return []string{RolePatient}
+	}
+	return nil
+}
+
+// TokenAuthenticator requires OAuth2 bearer tokens and enforces their SMART scopes
+type TokenAuthenticator struct {
+	validator *TokenValidator
+}
+
+// NewTokenAuthenticator creates an authenticator validating tokens with validator
+func NewTokenAuthenticator(validator *TokenValidator) *TokenAuthenticator {
+	return &TokenAuthenticator{
+		validator: validator,
+	}
+}
+
+// Middleware validates the bearer token, attributes the request to the token's client, and refuses
+// requests its scopes do not allow
+// It runs after tenant resolution and role resolution: requests carrying a known API key keep the
+// principal and roles of their key, and a token replaces them when both are sent
+func (authenticator *TokenAuthenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slices.Contains(tokenPublicPaths, r.URL.Path) || strings.HasPrefix(r.URL.Path, "/.well-known/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scheme, rawToken, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") {
+			if r.Header.Get(tenant.HeaderAPIKey) != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer`)
+			middleware.WriteError(w, r, apperrors.Unauthorized("A bearer token is required"))
+			return
+		}
+
+		claims, validateError := authenticator.validator.Validate(r.Context(), strings.TrimSpace(rawToken))
+		if validateError != nil {
+			log.Info().Err(validateError).Str("path", r.URL.Path).Msg("Bearer token refused")
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			middleware.WriteError(w, r, apperrors.Unauthorized("The bearer token is invalid or expired"))
+			return
+		}
+
+		principal := Principal{ID: "oauth:" + claims.Subject, Roles: fhirUserRoles(claims.FHIRUser)}
+		if claims.ClientID != "" {
+			principal.ID = "oauth:" + claims.ClientID
+		}
+		if scopeError := CheckScopes(claims, r); scopeError != nil {
+			log.Info().Str("principal", principal.ID).Str("method", r.Method).Str("path", r.URL.Path).Msg("Request refused by token scopes")
+			w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope"`)
+			middleware.WriteError(w, r, scopeError)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
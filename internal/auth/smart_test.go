@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestParseScopes verifies SMART v1 and v2 scopes are parsed and other scopes skipped
+func TestParseScopes(t *testing.T) {
+	scopes := ParseScopes("openid fhirUser launch/patient patient/Observation.read user/*.write system/Patient.rs?identifier=x patient/Condition.sr user/Encounter.* bogus/Patient.read")
+
+	expected := []Scope{
+		{Context: ScopeContextPatient, ResourceType: "Observation", Interactions: "rs"},
+		{Context: ScopeContextUser, ResourceType: "*", Interactions: "cud"},
+		{Context: ScopeContextSystem, ResourceType: "Patient", Interactions: "rs"},
+		{Context: ScopeContextUser, ResourceType: "Encounter", Interactions: "cruds"},
+	}
+	if len(scopes) != len(expected) {
+		t.Fatalf("Expected %d scopes, got %+v", len(expected), scopes)
+	}
+	for scopeIndex, scope := range scopes {
+		if scope != expected[scopeIndex] {
+			t.Errorf("Expected %+v, got %+v", expected[scopeIndex], scope)
+		}
+	}
+}
+
+// TestRequiredAccess verifies requests map to the interaction a scope must grant
+func TestRequiredAccess(t *testing.T) {
+	testCases := []struct {
+		method              string
+		path                string
+		expectedType        string
+		expectedInteraction string
+	}{
+		{http.MethodGet, "/fhir/Observation", "Observation", InteractionSearch},
+		{http.MethodGet, "/fhir/Observation/$daily-rollup", "Observation", InteractionSearch},
+		{http.MethodPost, "/fhir/Observation/_search", "Observation", InteractionSearch},
+		{http.MethodGet, "/fhir/Patient/p-1", "Patient", InteractionRead},
+		{http.MethodGet, "/fhir/Patient/p-1/$everything", "Patient", InteractionRead},
+		{http.MethodPost, "/fhir/Patient", "Patient", InteractionCreate},
+		{http.MethodPost, "/fhir/Patient/p-1/$meta-add", "Patient", InteractionUpdate},
+		{http.MethodPut, "/fhir/Patient/p-1", "Patient", InteractionUpdate},
+		{http.MethodDelete, "/fhir/Patient/p-1", "Patient", InteractionDelete},
+		{http.MethodGet, "/fhir/bulk-export/1", "", ""},
+		{http.MethodPost, "/fhir", "", ""},
+		{http.MethodGet, "/admin/rollups", "", ""},
+	}
+
+	for _, testCase := range testCases {
+		resourceType, interaction, _ := RequiredAccess(testCase.method, testCase.path)
+		if resourceType != testCase.expectedType || interaction != testCase.expectedInteraction {
+			t.Errorf("%s %s: expected %s %q, got %s %q", testCase.method, testCase.path, testCase.expectedType, testCase.expectedInteraction, resourceType, interaction)
+		}
+	}
+}
+
+// TestCheckScopes verifies resource scopes are enforced and patient scopes limited to the launch patient
+func TestCheckScopes(t *testing.T) {
+	patientClaims := &TokenClaims{Scopes: ParseScopes("patient/Observation.read patient/Patient.read"), PatientID: "p-1"}
+	userClaims := &TokenClaims{Scopes: ParseScopes("user/Observation.rs")}
+
+	testCases := []struct {
+		name           string
+		claims         *TokenClaims
+		method         string
+		target         string
+		expectedStatus int
+	}{
+		{"user search", userClaims, http.MethodGet, "/fhir/Observation?code=1234-5", http.StatusOK},
+		{"user without write", userClaims, http.MethodPost, "/fhir/Observation", http.StatusForbidden},
+		{"user without type", userClaims, http.MethodGet, "/fhir/Condition", http.StatusForbidden},
+		{"launch patient record", patientClaims, http.MethodGet, "/fhir/Patient/p-1", http.StatusOK},
+		{"launch patient by _id", patientClaims, http.MethodGet, "/fhir/Patient?_id=p-1", http.StatusOK},
+		{"other patient record", patientClaims, http.MethodGet, "/fhir/Patient/p-2", http.StatusForbidden},
+		{"patient search", patientClaims, http.MethodGet, "/fhir/Observation?patient=p-1", http.StatusOK},
+		{"subject reference search", patientClaims, http.MethodGet, "/fhir/Observation?subject=Patient/p-1", http.StatusOK},
+		{"unlimited search", patientClaims, http.MethodGet, "/fhir/Observation", http.StatusForbidden},
+		{"several patients", patientClaims, http.MethodGet, "/fhir/Observation?patient=p-1&patient=p-2", http.StatusForbidden},
+		{"instance outside the path", patientClaims, http.MethodGet, "/fhir/Observation/obs-1", http.StatusForbidden},
+		{"non-resource route", patientClaims, http.MethodGet, "/fhir/bulk-export/1", http.StatusOK},
+		{"without launch patient", &TokenClaims{Scopes: patientClaims.Scopes}, http.MethodGet, "/fhir/Patient/p-1", http.StatusForbidden},
+	}
+
+	for _, testCase := range testCases {
+		status := statusOf(CheckScopes(testCase.claims, httptest.NewRequest(testCase.method, testCase.target, nil)))
+		if status != testCase.expectedStatus {
+			t.Errorf("%s: expected %d, got %d", testCase.name, testCase.expectedStatus, status)
+		}
+	}
+}
+
+// TestTokenAuthenticator_Middleware verifies tokens are required, validated, and attributed, and that
+// public routes and API key requests pass through
+func TestTokenAuthenticator_Middleware(t *testing.T) {
+	signer := newTestSigner(t)
+	var reachedPrincipal *Principal
+	handler := NewTokenAuthenticator(signer.serveKeys(t)).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal := FromContext(r.Context())
+		reachedPrincipal = &principal
+	}))
+	serve := func(target string, authorization string, apiKey string) *httptest.ResponseRecorder {
+		reachedPrincipal = nil
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		if apiKey != "" {
+			request.Header.Set("X-API-Key", apiKey)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if recorder := serve("/fhir/Observation", "", ""); recorder.Code != http.StatusUnauthorized || recorder.Header().Get("WWW-Authenticate") != "Bearer" || reachedPrincipal != nil {
+		t.Errorf("Expected 401 asking for a bearer token, got %d %v", recorder.Code, recorder.Header())
+	}
+	if recorder := serve("/fhir/Observation", "Bearer not-a-token", ""); recorder.Code != http.StatusUnauthorized || reachedPrincipal != nil {
+		t.Errorf("Expected 401 for an invalid token, got %d", recorder.Code)
+	}
+	if recorder := serve("/fhir/metadata", "", ""); recorder.Code != http.StatusOK || reachedPrincipal == nil {
+		t.Errorf("Expected the capability statement to stay public, got %d", recorder.Code)
+	}
+	if recorder := serve("/fhir/Observation", "", "key-1"); recorder.Code != http.StatusOK || reachedPrincipal == nil {
+		t.Errorf("Expected API key requests to keep working, got %d", recorder.Code)
+	}
+
+	readToken := signer.sign(t, "RS256", "rsa-1", validClaims("user/Observation.read"))
+	if recorder := serve("/fhir/Condition", "Bearer "+readToken, ""); recorder.Code != http.StatusForbidden || reachedPrincipal != nil {
+		t.Errorf("Expected 403 for a type outside the scopes, got %d", recorder.Code)
+	}
+	if recorder := serve("/fhir/Observation", "bearer "+readToken, ""); recorder.Code != http.StatusOK || reachedPrincipal == nil || reachedPrincipal.ID != "oauth:growth-chart" {
+		t.Errorf("Expected the request attributed to the token's client, got %d %+v", recorder.Code, reachedPrincipal)
+	}
+
+	practitionerClaims := validClaims("user/Observation.read")
+	practitionerClaims["fhirUser"] = "https://fhir.example.com/fhir/Practitioner/pr-1"
+	serve("/fhir/Observation", "Bearer "+signer.sign(t, "RS256", "rsa-1", practitionerClaims), "")
+	if reachedPrincipal == nil || !reachedPrincipal.HasRole(RoleClinician) {
+		t.Errorf("Expected practitioners to get the clinician role, got %+v", reachedPrincipal)
+	}
+}
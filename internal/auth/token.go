@@ -0,0 +1,395 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/singleflight"
+)
+
+// TokenConfig configures how OAuth2 bearer tokens are validated
+type TokenConfig struct {
+	// JWKSURL is where the authorization server publishes its signing keys
+	JWKSURL string
+
+	// Issuer must equal the token's iss claim
+	Issuer string
+
+	// Audience must be one of the token's aud claim values, usually this server's FHIR base URL
+	Audience string
+
+	// KeysTTL is how long fetched signing keys are trusted before they are fetched again
+	KeysTTL time.Duration
+
+	// ClockSkew is tolerated when checking exp and nbf against the server's clock
+	ClockSkew time.Duration
+}
+
+// DefaultTokenConfig returns a configuration refreshing keys hourly and tolerating a minute of clock skew
+func DefaultTokenConfig() TokenConfig {
+	return TokenConfig{
+		KeysTTL:   time.Hour,
+		ClockSkew: time.Minute,
+	}
+}
+
+// jwksRefetchInterval limits fetches of the key set, so forged key IDs or an unreachable authorization
+// server cannot make every request wait on a fetch
+const jwksRefetchInterval = time.Minute
+
+// TokenClaims are the claims of a validated access token this server uses
+type TokenClaims struct {
+	// Subject is the sub claim, the user or system the token was issued to
+	Subject string
+
+	// ClientID is the client_id claim, the application holding the token
+	ClientID string
+
+	// Scopes are the granted SMART scopes, parsed from the scope claim
+	Scopes []Scope
+
+	// PatientID is the patient claim: the launch patient that patient-level scopes are limited to
+	PatientID string
+
+	// FHIRUser is the fhirUser claim, a reference to the signed-in user such as Practitioner/123
+	FHIRUser string
+}
+
+// tokenHeader is the JOSE header of a signed JWT
+type tokenHeader struct {
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+}
+
+// tokenPayload is the JWT claims set as it is encoded
+type tokenPayload struct {
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *int64          `json:"exp"`
+	NotBefore *int64          `json:"nbf"`
+	ClientID  string          `json:"client_id"`
+	Scope     string          `json:"scope"`
+	Patient   string          `json:"patient"`
+	FHIRUser  string          `json:"fhirUser"`
+}
+
+// jsonWebKey is one key of a JSON Web Key Set; only the members of RSA and EC signing keys are read
+type jsonWebKey struct {
+	KeyType  string `json:"kty"`
+	KeyID    string `json:"kid"`
+	Use      string `json:"use"`
+	Modulus  string `json:"n"`
+	Exponent string `json:"e"`
+	Curve    string `json:"crv"`
+	X        string `json:"x"`
+	Y        string `json:"y"`
+}
+
+// signatureAlgorithm describes how a JWS alg value is verified
+type signatureAlgorithm struct {
+	hash crypto.Hash
+
+	// curve is the curve of ECDSA algorithms, nil for RSA
+	curve elliptic.Curve
+}
+
+// signatureAlgorithms are the asymmetric algorithms tokens may be signed with; "none" and shared-secret
+// algorithms are refused because the keys come from a public key set
+var signatureAlgorithms = map[string]signatureAlgorithm{
+	"RS256": {hash: crypto.SHA256},
+	"RS384": {hash: crypto.SHA384},
+	"RS512": {hash: crypto.SHA512},
+	"ES256": {hash: crypto.SHA256, curve: elliptic.P256()},
+	"ES384": {hash: crypto.SHA384, curve: elliptic.P384()},
+	"ES512": {hash: crypto.SHA512, curve: elliptic.P521()},
+}
+
+// TokenValidator verifies JWT access tokens against the signing keys published at a JWKS endpoint
+type TokenValidator struct {
+	config     TokenConfig
+	httpClient *http.Client
+
+	// refresh collapses concurrent key set fetches into one
+	refresh singleflight.Group
+
+	// mutex guards the cached keys and fetch times; it is never held while fetching
+	mutex       sync.Mutex
+	keys        map[string]crypto.PublicKey
+	fetchedAt   time.Time
+	attemptedAt time.Time
+
+	// now returns the current time; replaced in tests
+	now func() time.Time
+}
+
+// NewTokenValidator creates a validator for tokens issued by config.Issuer for config.Audience
+// The issuer and audience are required so tokens minted for other servers are refused
+func NewTokenValidator(config TokenConfig) (*TokenValidator, error) {
+	if !strings.HasPrefix(config.JWKSURL, "https://") && !strings.HasPrefix(config.JWKSURL, "http://") {
+		return nil, fmt.Errorf("JWKS URL %q must be an http or https URL", config.JWKSURL)
+	}
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, errors.New("token validation requires an issuer and an audience")
+	}
+
+	return &TokenValidator{
+		config:     config,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		keys:       make(map[string]crypto.PublicKey),
+		now:        time.Now,
+	}, nil
+}
+
+// Validate verifies the token's signature, issuer, audience, and lifetime and returns its claims
+func (validator *TokenValidator) Validate(ctx context.Context, rawToken string) (*TokenClaims, error) {
+	encodedParts := strings.Split(rawToken, ".")
+	if len(encodedParts) != 3 {
+		return nil, errors.New("token is not a signed JWT")
+	}
+
+	header := tokenHeader{}
+	if decodeError := decodeTokenPart(encodedParts[0], &header); decodeError != nil {
+		return nil, fmt.Errorf("invalid token header: %w", decodeError)
+	}
+	algorithm, supported := signatureAlgorithms[header.Algorithm]
+	if !supported {
+		return nil, fmt.Errorf("token algorithm %q is not supported", header.Algorithm)
+	}
+
+	signature, signatureError := base64.RawURLEncoding.DecodeString(encodedParts[2])
+	if signatureError != nil {
+		return nil, fmt.Errorf("invalid token signature encoding: %w", signatureError)
+	}
+	publicKey, keyError := validator.signingKey(ctx, header.KeyID)
+	if keyError != nil {
+		return nil, keyError
+	}
+	if verifyError := verifySignature(algorithm, publicKey, encodedParts[0]+"."+encodedParts[1], signature); verifyError != nil {
+		return nil, verifyError
+	}
+
+	payload := tokenPayload{}
+	if decodeError := decodeTokenPart(encodedParts[1], &payload); decodeError != nil {
+		return nil, fmt.Errorf("invalid token claims: %w", decodeError)
+	}
+	if claimsError := validator.checkClaims(payload); claimsError != nil {
+		return nil, claimsError
+	}
+
+	return &TokenClaims{
+		Subject:   payload.Subject,
+		ClientID:  payload.ClientID,
+		Scopes:    ParseScopes(payload.Scope),
+		PatientID: payload.Patient,
+		FHIRUser:  payload.FHIRUser,
+	}, nil
+}
+
+// checkClaims checks the issuer, audience, and lifetime of a token whose signature is valid
+func (validator *TokenValidator) checkClaims(payload tokenPayload) error {
+	if payload.Issuer != validator.config.Issuer {
+		return fmt.Errorf("token issuer %q is not trusted", payload.Issuer)
+	}
+
+	var audiences []string
+	var singleAudience string
+	if json.Unmarshal(payload.Audience, &singleAudience) == nil {
+		audiences = []string{singleAudience}
+	} else if json.Unmarshal(payload.Audience, &audiences) != nil {
+		return errors.New("token audience must be a string or an array of strings")
+	}
+	if !slices.Contains(audiences, validator.config.Audience) {
+		return errors.New("token was not issued for this server")
+	}
+
+	now := validator.now()
+	if payload.ExpiresAt == nil {
+		return errors.New("token has no expiry")
+	}
+	if now.After(time.Unix(*payload.ExpiresAt, 0).Add(validator.config.ClockSkew)) {
+		return errors.New("token has expired")
+	}
+	if payload.NotBefore != nil && now.Add(validator.config.ClockSkew).Before(time.Unix(*payload.NotBefore, 0)) {
+		return errors.New("token is not valid yet")
+	}
+	return nil
+}
+
+// signingKey returns the key with the given ID, fetching the key set when the cached one is stale or
+// lacks the key because the authorization server rotated its keys
+func (validator *TokenValidator) signingKey(ctx context.Context, keyID string) (crypto.PublicKey, error) {
+	validator.mutex.Lock()
+	now := validator.now()
+	publicKey, cached := validator.keys[keyID]
+	stale := now.Sub(validator.fetchedAt) >= validator.config.KeysTTL
+	refetchAllowed := now.Sub(validator.attemptedAt) >= jwksRefetchInterval
+	validator.mutex.Unlock()
+
+	if (stale || !cached) && refetchAllowed {
+		// Concurrent callers share one fetch, made without holding the mutex so other tokens keep validating
+		validator.refresh.Do("jwks", func() (interface{}, error) {
+			validator.refreshKeys(context.WithoutCancel(ctx))
+			return nil, nil
+		})
+		validator.mutex.Lock()
+		publicKey, cached = validator.keys[keyID]
+		validator.mutex.Unlock()
+	}
+
+	if !cached {
+		return nil, fmt.Errorf("token signing key %q is unknown", keyID)
+	}
+	return publicKey, nil
+}
+
+// refreshKeys fetches the key set and swaps it in, at most once per jwksRefetchInterval
+func (validator *TokenValidator) refreshKeys(ctx context.Context) {
+	validator.mutex.Lock()
+	attemptedAt := validator.now()
+	if attemptedAt.Sub(validator.attemptedAt) < jwksRefetchInterval {
+		// Another fetch finished between the caller's check and this one
+		validator.mutex.Unlock()
+		return
+	}
+	validator.attemptedAt = attemptedAt
+	validator.mutex.Unlock()
+
+	keys, fetchError := validator.fetchKeys(ctx)
+	if fetchError != nil {
+		// Keep trusting the cached keys; an unreachable authorization server should not lock every client out
+		log.Warn().Err(fetchError).Str("jwks_url", validator.config.JWKSURL).Msg("Failed to refresh token signing keys")
+		return
+	}
+
+	validator.mutex.Lock()
+	validator.keys = keys
+	validator.fetchedAt = attemptedAt
+	validator.mutex.Unlock()
+}
+
+// fetchKeys downloads and parses the key set, skipping keys that are not RSA or EC signing keys
+func (validator *TokenValidator) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	request, requestError := http.NewRequestWithContext(ctx, http.MethodGet, validator.config.JWKSURL, nil)
+	if requestError != nil {
+		return nil, requestError
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, fetchError := validator.httpClient.Do(request)
+	if fetchError != nil {
+		return nil, fetchError
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint answered %d", response.StatusCode)
+	}
+
+	keySet := struct {
+		Keys []jsonWebKey `json:"keys"`
+	}{}
+	if decodeError := json.NewDecoder(response.Body).Decode(&keySet); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse key set: %w", decodeError)
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, key := range keySet.Keys {
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		publicKey, parseError := key.publicKey()
+		if parseError != nil {
+			log.Warn().Err(parseError).Str("kid", key.KeyID).Msg("Skipping unusable token signing key")
+			continue
+		}
+		keys[key.KeyID] = publicKey
+	}
+	return keys, nil
+}
+
+// publicKey converts the JWK into an RSA or ECDSA public key
+func (key jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch key.KeyType {
+	case "RSA":
+		modulus, modulusError := decodeBigInt(key.Modulus)
+		exponent, exponentError := decodeBigInt(key.Exponent)
+		if modulusError != nil || exponentError != nil || !exponent.IsInt64() {
+			return nil, errors.New("invalid RSA key parameters")
+		}
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+	case "EC":
+		curves := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}
+		curve, known := curves[key.Curve]
+		if !known {
+			return nil, fmt.Errorf("unsupported curve %q", key.Curve)
+		}
+		x, xError := decodeBigInt(key.X)
+		y, yError := decodeBigInt(key.Y)
+		if xError != nil || yError != nil || !curve.IsOnCurve(x, y) {
+			return nil, errors.New("invalid EC key parameters")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", key.KeyType)
+	}
+}
+
+// verifySignature checks a JWS signature over signingInput with the key the algorithm calls for
+func verifySignature(algorithm signatureAlgorithm, publicKey crypto.PublicKey, signingInput string, signature []byte) error {
+	hasher := algorithm.hash.New()
+	hasher.Write([]byte(signingInput))
+	digest := hasher.Sum(nil)
+
+	switch typedKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		if algorithm.curve != nil || rsa.VerifyPKCS1v15(typedKey, algorithm.hash, digest, signature) != nil {
+			return errors.New("token signature is invalid")
+		}
+	case *ecdsa.PublicKey:
+		// ECDSA signatures are the two integers r and s, each padded to the curve's size
+		integerSize := (typedKey.Curve.Params().BitSize + 7) / 8
+		if algorithm.curve != typedKey.Curve || len(signature) != 2*integerSize {
+			return errors.New("token signature is invalid")
+		}
+		r := new(big.Int).SetBytes(signature[:integerSize])
+		s := new(big.Int).SetBytes(signature[integerSize:])
+		if !ecdsa.Verify(typedKey, digest, r, s) {
+			return errors.New("token signature is invalid")
+		}
+	default:
+		return errors.New("token signing key type is not supported")
+	}
+	return nil
+}
+
+// decodeTokenPart decodes a base64url-encoded JSON part of a JWT
+func decodeTokenPart(encodedPart string, target interface{}) error {
+	decoded, decodeError := base64.RawURLEncoding.DecodeString(encodedPart)
+	if decodeError != nil {
+		return decodeError
+	}
+	return json.Unmarshal(decoded, target)
+}
+
+// decodeBigInt decodes a base64url-encoded unsigned big-endian integer
+func decodeBigInt(encoded string) (*big.Int, error) {
+	decoded, decodeError := base64.RawURLEncoding.DecodeString(encoded)
+	if decodeError != nil || len(decoded) == 0 {
+		return nil, errors.New("invalid integer encoding")
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
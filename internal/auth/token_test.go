@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testIssuer and testAudience are the issuer and audience test tokens are validated against
+const (
+	testIssuer   = "https://auth.example.com"
+	testAudience = "https://fhir.example.com/fhir"
+)
+
+// testSigner signs tokens with an RSA key published under kid "rsa-1" and an EC key under "ec-1"
+type testSigner struct {
+	rsaKey *rsa.PrivateKey
+	ecKey  *ecdsa.PrivateKey
+
+	// fetches counts requests for the key set
+	fetches int
+}
+
+// newTestSigner generates the signing keys
+func newTestSigner(t *testing.T) *testSigner {
+	t.Helper()
+	rsaKey, rsaError := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, ecError := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if rsaError != nil || ecError != nil {
+		t.Fatalf("Expected keys to be generated, got %v %v", rsaError, ecError)
+	}
+	return &testSigner{rsaKey: rsaKey, ecKey: ecKey}
+}
+
+// serveKeys publishes the public keys as a JWKS document and returns a validator fetching them
+func (signer *testSigner) serveKeys(t *testing.T) *TokenValidator {
+	t.Helper()
+	encode := func(value *big.Int) string { return base64.RawURLEncoding.EncodeToString(value.Bytes()) }
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signer.fetches++
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa-1", "use": "sig", "n": encode(signer.rsaKey.N), "e": encode(big.NewInt(int64(signer.rsaKey.E)))},
+			{"kty": "EC", "kid": "ec-1", "crv": "P-256", "x": encode(signer.ecKey.X), "y": encode(signer.ecKey.Y)},
+			{"kty": "oct", "kid": "secret", "k": "c2VjcmV0"},
+		}})
+	}))
+	t.Cleanup(server.Close)
+
+	config := DefaultTokenConfig()
+	config.JWKSURL = server.URL
+	config.Issuer = testIssuer
+	config.Audience = testAudience
+	validator, validatorError := NewTokenValidator(config)
+	if validatorError != nil {
+		t.Fatalf("Expected the validator to be created, got %v", validatorError)
+	}
+	return validator
+}
+
+// sign returns a JWT with the given header values and claims, signed with the key the algorithm uses
+func (signer *testSigner) sign(t *testing.T, algorithm string, keyID string, claims map[string]interface{}) string {
+	t.Helper()
+	encodePart := func(value interface{}) string {
+		encoded, _ := json.Marshal(value)
+		return base64.RawURLEncoding.EncodeToString(encoded)
+	}
+	signingInput := encodePart(map[string]string{"alg": algorithm, "kid": keyID}) + "." + encodePart(claims)
+	digest := crypto.SHA256.New()
+	digest.Write([]byte(signingInput))
+
+	var signature []byte
+	switch algorithm {
+	case "RS256":
+		signature, _ = rsa.SignPKCS1v15(rand.Reader, signer.rsaKey, crypto.SHA256, digest.Sum(nil))
+	case "ES256":
+		r, s, _ := ecdsa.Sign(rand.Reader, signer.ecKey, digest.Sum(nil))
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// validClaims returns claims accepted by the test validator, expiring in an hour
+func validClaims(scope string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":       testIssuer,
+		"aud":       []string{testAudience},
+		"sub":       "user-1",
+		"client_id": "growth-chart",
+		"exp":       time.Now().Add(time.Hour).Unix(),
+		"scope":     scope,
+	}
+}
+
+// TestTokenValidator_Validate verifies signatures, claims, and the algorithms tokens may use
+func TestTokenValidator_Validate(t *testing.T) {
+	signer := newTestSigner(t)
+	validator := signer.serveKeys(t)
+
+	claims, validateError := validator.Validate(context.Background(), signer.sign(t, "RS256", "rsa-1", validClaims("openid patient/Observation.read")))
+	if validateError != nil {
+		t.Fatalf("Expected the RSA token to be valid, got %v", validateError)
+	}
+	if claims.ClientID != "growth-chart" || claims.Subject != "user-1" || len(claims.Scopes) != 1 {
+		t.Errorf("Expected the token's claims, got %+v", claims)
+	}
+	if _, ecError := validator.Validate(context.Background(), signer.sign(t, "ES256", "ec-1", validClaims(""))); ecError != nil {
+		t.Errorf("Expected the EC token to be valid, got %v", ecError)
+	}
+
+	withClaim := func(name string, value interface{}) map[string]interface{} {
+		claims := validClaims("")
+		claims[name] = value
+		return claims
+	}
+	tampered := signer.sign(t, "RS256", "rsa-1", validClaims("user/*.read"))
+	tamperedParts := strings.Split(tampered, ".")
+	tamperedParts[1] = strings.Split(signer.sign(t, "RS256", "rsa-1", validClaims("user/*.*")), ".")[1]
+
+	invalidTokens := map[string]string{
+		"not a JWT":         "opaque-token",
+		"unsigned":          strings.Join(strings.Split(signer.sign(t, "none", "rsa-1", validClaims("")), ".")[:2], ".") + ".",
+		"shared secret":     signer.sign(t, "HS256", "secret", validClaims("")),
+		"unknown key":       signer.sign(t, "RS256", "rsa-2", validClaims("")),
+		"wrong key type":    signer.sign(t, "ES256", "rsa-1", validClaims("")),
+		"tampered claims":   strings.Join(tamperedParts, "."),
+		"other issuer":      signer.sign(t, "RS256", "rsa-1", withClaim("iss", "https://other.example.com")),
+		"other audience":    signer.sign(t, "RS256", "rsa-1", withClaim("aud", "https://other.example.com/fhir")),
+		"expired":           signer.sign(t, "RS256", "rsa-1", withClaim("exp", time.Now().Add(-time.Hour).Unix())),
+		"not yet valid":     signer.sign(t, "RS256", "rsa-1", withClaim("nbf", time.Now().Add(time.Hour).Unix())),
+		"without an expiry": signer.sign(t, "RS256", "rsa-1", withClaim("exp", nil)),
+	}
+	for name, invalidToken := range invalidTokens {
+		if _, invalidError := validator.Validate(context.Background(), invalidToken); invalidError == nil {
+			t.Errorf("%s: expected the token to be refused", name)
+		}
+	}
+}
+
+// TestTokenValidator_KeyRefresh verifies keys are cached, refetched for unknown key IDs at most once a
+// minute, and refetched once they go stale
+func TestTokenValidator_KeyRefresh(t *testing.T) {
+	signer := newTestSigner(t)
+	validator := signer.serveKeys(t)
+	now := time.Now()
+	validator.now = func() time.Time { return now }
+
+	validToken := signer.sign(t, "RS256", "rsa-1", validClaims(""))
+	unknownKeyToken := signer.sign(t, "RS256", "rsa-2", validClaims(""))
+	validator.Validate(context.Background(), validToken)
+	validator.Validate(context.Background(), validToken)
+	validator.Validate(context.Background(), unknownKeyToken)
+	if signer.fetches != 1 {
+		t.Errorf("Expected one fetch within a minute, got %d", signer.fetches)
+	}
+
+	now = now.Add(2 * time.Minute)
+	validator.Validate(context.Background(), unknownKeyToken)
+	validator.Validate(context.Background(), validToken)
+	if signer.fetches != 2 {
+		t.Errorf("Expected an unknown key to trigger one refetch after a minute, got %d fetches", signer.fetches)
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, staleError := validator.Validate(context.Background(), validToken); staleError == nil {
+		t.Error("Expected the token to have expired by now")
+	}
+	if signer.fetches != 3 {
+		t.Errorf("Expected stale keys to be refetched, got %d fetches", signer.fetches)
+	}
+}
+
+// TestTokenValidator_KeyRefreshDoesNotBlock verifies tokens signed with cached keys keep validating while
+// a key set fetch is in flight
+func TestTokenValidator_KeyRefreshDoesNotBlock(t *testing.T) {
+	signer := newTestSigner(t)
+	validator := signer.serveKeys(t)
+	now := time.Now()
+	validator.now = func() time.Time { return now }
+	validToken := signer.sign(t, "RS256", "rsa-1", validClaims(""))
+	if _, validateError := validator.Validate(context.Background(), validToken); validateError != nil {
+		t.Fatalf("Expected the token to validate, got %v", validateError)
+	}
+
+	fetchStarted := make(chan struct{})
+	releaseFetch := make(chan struct{})
+	slowServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fetchStarted)
+		<-releaseFetch
+		w.Write([]byte(`{"keys":[]}`))
+	}))
+	t.Cleanup(slowServer.Close)
+	validator.config.JWKSURL = slowServer.URL
+	now = now.Add(2 * time.Minute)
+
+	refreshDone := make(chan struct{})
+	go func() {
+		defer close(refreshDone)
+		validator.Validate(context.Background(), signer.sign(t, "RS256", "rsa-2", validClaims("")))
+	}()
+	<-fetchStarted
+
+	if _, validateError := validator.Validate(context.Background(), validToken); validateError != nil {
+		t.Errorf("Expected the cached key to validate during the fetch, got %v", validateError)
+	}
+	close(releaseFetch)
+	<-refreshDone
+}
+
+// TestNewTokenValidator_RequiresIssuerAndAudience verifies misconfiguration is reported at startup
+func TestNewTokenValidator_RequiresIssuerAndAudience(t *testing.T) {
+	configs := map[string]TokenConfig{
+		"missing URL":      {Issuer: testIssuer, Audience: testAudience},
+		"missing issuer":   {JWKSURL: "https://auth.example.com/jwks", Audience: testAudience},
+		"missing audience": {JWKSURL: "https://auth.example.com/jwks", Issuer: testIssuer},
+	}
+	for name, config := range configs {
+		if _, configError := NewTokenValidator(config); configError == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package authz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+)
+
+// Permission grants a role interactions on one resource type
+type Permission struct {
+	// ResourceType is the FHIR resource type, or * for every type
+	ResourceType string
+
+	// Interactions are the granted SMART v2 interaction letters, a subset of "cruds"
+	Interactions string
+}
+
+// Policy maps roles to the resource operations they may perform
+// Permissions are written like SMART scopes without the context: "Observation.cruds", "Patient.rs",
+// "*.read", or "Condition.write"
+type Policy struct {
+	Roles map[string][]string `json:"roles"`
+
+	// permissions holds the parsed Roles
+	permissions map[string][]Permission
+}
+
+// LoadPolicy reads and validates a role policy from a JSON file
+func LoadPolicy(path string) (*Policy, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read role policy: %w", readError)
+	}
+
+	policy := &Policy{}
+	if decodeError := json.Unmarshal(fileBytes, policy); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse role policy: %w", decodeError)
+	}
+
+	return policy, policy.Validate()
+}
+
+// Validate parses every permission, reporting the ones that are malformed
+func (policy *Policy) Validate() error {
+	policy.permissions = make(map[string][]Permission)
+	var permissionErrors []error
+	for role, rawPermissions := range policy.Roles {
+		for _, rawPermission := range rawPermissions {
+			resourceType, rawInteractions, found := strings.Cut(rawPermission, ".")
+			interactions, parsed := auth.ParseInteractions(rawInteractions)
+			if !found || resourceType == "" || !parsed {
+				permissionErrors = append(permissionErrors, fmt.Errorf("role %s: permission %q must be Type.cruds, Type.read, or Type.write", role, rawPermission))
+				continue
+			}
+			policy.permissions[role] = append(policy.permissions[role], Permission{ResourceType: resourceType, Interactions: interactions})
+		}
+	}
+	return errors.Join(permissionErrors...)
+}
+
+// Allows reports whether any of the roles grants the interaction on the resource type
+func (policy *Policy) Allows(roles []string, resourceType string, interaction string) bool {
+	for _, role := range roles {
+		for _, permission := range policy.permissions[role] {
+			if (permission.ResourceType == "*" || permission.ResourceType == resourceType) && strings.Contains(permission.Interactions, interaction) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Check decides whether principal may make a FHIR resource request, returning a 401 for anonymous callers
+// and a 403 for callers without a granting role
+// Requests outside the FHIR resource endpoints are left to the route policy and handlers
+func (policy *Policy) Check(principal auth.Principal, method string, path string) error {
+	resourceType, interaction, scoped := auth.RequiredAccess(method, path)
+	if !scoped || policy.Allows(principal.Roles, resourceType, interaction) {
+		return nil
+	}
+
+	message := method + " " + path + " requires a role granting " + interaction + " on " + resourceType
+	if principal.ID == auth.AnonymousPrincipalID {
+		return apperrors.Unauthorized(message)
+	}
+	return apperrors.Forbidden(message)
+}
@@ -0,0 +1,89 @@
+package authz
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+)
+
+// testPolicy lets admins do anything, clinicians write observations, and patients read them
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	policy := &Policy{Roles: map[string][]string{
+		auth.RoleAdmin:     {"*.cruds"},
+		auth.RoleClinician: {"Observation.cruds", "Patient.read"},
+		auth.RolePatient:   {"Observation.rs"},
+	}}
+	if validateError := policy.Validate(); validateError != nil {
+		t.Fatalf("Expected a valid policy, got %v", validateError)
+	}
+	return policy
+}
+
+// statusOf returns the HTTP status of a policy decision, 200 when it allows the request
+func statusOf(checkError error) int {
+	var appError *apperrors.AppError
+	if errors.As(checkError, &appError) {
+		return appError.StatusCode
+	}
+	return http.StatusOK
+}
+
+// TestPolicy_Check verifies each role is limited to its resource operations
+func TestPolicy_Check(t *testing.T) {
+	admin := auth.Principal{ID: "key-1", Roles: []string{auth.RoleAdmin}}
+	clinician := auth.Principal{ID: "key-2", Roles: []string{auth.RoleClinician}}
+	patient := auth.Principal{ID: "oauth:app", Roles: []string{auth.RolePatient}}
+	anonymous := auth.Principal{ID: auth.AnonymousPrincipalID}
+
+	testCases := []struct {
+		name           string
+		principal      auth.Principal
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{"admin deletes anything", admin, http.MethodDelete, "/fhir/Condition/c-1", http.StatusOK},
+		{"clinician writes observations", clinician, http.MethodPost, "/fhir/Observation", http.StatusOK},
+		{"clinician reads patients", clinician, http.MethodGet, "/fhir/Patient/p-1", http.StatusOK},
+		{"clinician cannot delete patients", clinician, http.MethodDelete, "/fhir/Patient/p-1", http.StatusForbidden},
+		{"patient searches observations", patient, http.MethodGet, "/fhir/Observation", http.StatusOK},
+		{"patient cannot write", patient, http.MethodPut, "/fhir/Observation/o-1", http.StatusForbidden},
+		{"no role", auth.Principal{ID: "key-3"}, http.MethodGet, "/fhir/Observation", http.StatusForbidden},
+		{"anonymous gets 401", anonymous, http.MethodGet, "/fhir/Observation", http.StatusUnauthorized},
+		{"non-resource routes pass", anonymous, http.MethodGet, "/admin/rollups", http.StatusOK},
+	}
+
+	policy := testPolicy(t)
+	for _, testCase := range testCases {
+		if status := statusOf(policy.Check(testCase.principal, testCase.method, testCase.path)); status != testCase.expectedStatus {
+			t.Errorf("%s: expected %d, got %d", testCase.name, testCase.expectedStatus, status)
+		}
+	}
+}
+
+// TestLoadPolicy verifies a policy file is parsed and malformed permissions are rejected
+func TestLoadPolicy(t *testing.T) {
+	directory := t.TempDir()
+	validPath := filepath.Join(directory, "valid.json")
+	os.WriteFile(validPath, []byte(`{"roles": {"clinician": ["Observation.write", "*.rs"]}}`), 0o600)
+
+	policy, loadError := LoadPolicy(validPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if !policy.Allows([]string{auth.RoleClinician}, "Condition", auth.InteractionSearch) || policy.Allows([]string{auth.RoleClinician}, "Condition", auth.InteractionCreate) {
+		t.Errorf("Expected the permissions to be loaded, got %+v", policy.permissions)
+	}
+
+	invalidPath := filepath.Join(directory, "invalid.json")
+	os.WriteFile(invalidPath, []byte(`{"roles": {"clinician": ["Observation", "Patient.sr", ".rs"]}}`), 0o600)
+	if _, invalidError := LoadPolicy(invalidPath); invalidError == nil {
+		t.Error("Expected malformed permissions to be rejected")
+	}
+}
@@ -0,0 +1,77 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// Denial is an access attempt the role policy refused
+type Denial struct {
+	PrincipalID   string
+	Roles         []string
+	TenantID      string
+	ClientAddress string
+	Method        string
+	Path          string
+	RequestID     string
+	Reason        string
+	DeniedAt      time.Time
+}
+
+// DenialRecorder keeps an audit trail of refused access attempts
+type DenialRecorder interface {
+	RecordDenial(ctx context.Context, denial Denial)
+}
+
+// LogDenialRecorder writes each denial to the application log as an audit entry
+type LogDenialRecorder struct{}
+
+// RecordDenial logs the denial at warn level so it stands out from routine request logs
+func (LogDenialRecorder) RecordDenial(ctx context.Context, denial Denial) {
+	log.Warn().
+		Str("audit", "access_denied").
+		Str("principal", denial.PrincipalID).
+		Strs("roles", denial.Roles).
+		Str("tenant_id", denial.TenantID).
+		Str("client_address", denial.ClientAddress).
+		Str("method", denial.Method).
+		Str("path", denial.Path).
+		Str("request_id", denial.RequestID).
+		Str("reason", denial.Reason).
+		Time("denied_at", denial.DeniedAt).
+		Msg("Access denied by role policy")
+}
+
+// Middleware refuses FHIR resource requests the principal's roles do not allow, recording each refusal
+// It runs after the principal is resolved from the API key or bearer token
+func Middleware(policy *Policy, recorder DenialRecorder) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal := auth.FromContext(r.Context())
+			checkError := policy.Check(principal, r.Method, r.URL.Path)
+			if checkError == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			recorder.RecordDenial(r.Context(), Denial{
+				PrincipalID:   principal.ID,
+				Roles:         principal.Roles,
+				TenantID:      tenant.FromContext(r.Context()),
+				ClientAddress: r.RemoteAddr,
+				Method:        r.Method,
+				Path:          r.URL.Path,
+				RequestID:     w.Header().Get("X-Request-ID"),
+				Reason:        checkError.Error(),
+				DeniedAt:      time.Now().UTC(),
+			})
+			middleware.WriteError(w, r, checkError)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package authz
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+)
+
+// recordingDenials keeps the denials it is given
+type recordingDenials struct {
+	denials []Denial
+}
+
+// RecordDenial stores the denial
+func (recorder *recordingDenials) RecordDenial(ctx context.Context, denial Denial) {
+	recorder.denials = append(recorder.denials, denial)
+}
+
+// TestMiddleware verifies refused requests never reach the handler and are recorded, and allowed ones are not
+func TestMiddleware(t *testing.T) {
+	recorder := &recordingDenials{}
+	reached := false
+	handler := Middleware(testPolicy(t), recorder)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	serve := func(method string, target string) int {
+		reached = false
+		request := httptest.NewRequest(method, target, nil)
+		request = request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{ID: "oauth:app", Roles: []string{auth.RolePatient}}))
+		responseRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(responseRecorder, request)
+		return responseRecorder.Code
+	}
+
+	if status := serve(http.MethodDelete, "/fhir/Observation/o-1"); status != http.StatusForbidden || reached {
+		t.Errorf("Expected 403 without reaching the handler, got %d (reached %v)", status, reached)
+	}
+	if len(recorder.denials) != 1 || recorder.denials[0].PrincipalID != "oauth:app" || recorder.denials[0].Method != http.MethodDelete || recorder.denials[0].Reason == "" {
+		t.Errorf("Expected the denial to be recorded, got %+v", recorder.denials)
+	}
+
+	if status := serve(http.MethodGet, "/fhir/Observation"); status != http.StatusOK || !reached || len(recorder.denials) != 1 {
+		t.Errorf("Expected the allowed request through without a denial, got %d (reached %v, %d denials)", status, reached, len(recorder.denials))
+	}
+}
@@ -0,0 +1,116 @@
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strconv"
+)
+
+// Avatar sizes in pixels
+const (
+	DefaultSize = 128
+	MinSize     = 16
+	MaxSize     = 512
+)
+
+// gridSize is the number of cells per side; the right columns mirror the left ones
+const gridSize = 5
+
+// version is part of every entity tag, so changing the design invalidates cached avatars
+const version = "1"
+
+// backgroundColor is the light gray behind the pattern
+var backgroundColor = color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+// Identicon renders the avatar for seed as a size by size PNG
+// The same seed always gives the same image: a symmetric 5x5 pattern in a color derived from the seed's
+// SHA-256 digest. Only the digest is used, so the image reveals nothing about the seed
+func Identicon(seed string, size int) ([]byte, error) {
+	if size < MinSize || size > MaxSize {
+		return nil, fmt.Errorf("avatar size must be between %d and %d pixels", MinSize, MaxSize)
+	}
+
+	digest := sha256.Sum256([]byte(seed))
+	cells := filledCells(digest)
+	palette := color.Palette{backgroundColor, foregroundColor(digest)}
+	canvas := image.NewPaletted(image.Rect(0, 0, size, size), palette)
+
+	// Half a cell of margin on each side
+	cellSize := float64(size) / float64(gridSize+1)
+	margin := cellSize / 2
+	for y := 0; y < size; y++ {
+		row := int(math.Floor((float64(y) - margin) / cellSize))
+		for x := 0; x < size; x++ {
+			column := int(math.Floor((float64(x) - margin) / cellSize))
+			if row >= 0 && row < gridSize && column >= 0 && column < gridSize && cells[row][column] {
+				canvas.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	var encoded bytes.Buffer
+	if encodeError := png.Encode(&encoded, canvas); encodeError != nil {
+		return nil, fmt.Errorf("failed to encode avatar: %w", encodeError)
+	}
+	return encoded.Bytes(), nil
+}
+
+// ETag returns the strong entity tag of the avatar for seed at size
+func ETag(seed string, size int) string {
+	digest := sha256.Sum256([]byte(version + "\x00" + strconv.Itoa(size) + "\x00" + seed))
+	return `"` + hex.EncodeToString(digest[:12]) + `"`
+}
+
+// filledCells picks the filled cells from the digest, mirroring the left columns onto the right
+func filledCells(digest [sha256.Size]byte) [gridSize][gridSize]bool {
+	var cells [gridSize][gridSize]bool
+	halfWidth := (gridSize + 1) / 2
+	for row := 0; row < gridSize; row++ {
+		for column := 0; column < halfWidth; column++ {
+			// The first two bytes choose the color, so the pattern reads from the third onwards
+			filled := digest[2+row*halfWidth+column]&1 == 1
+			cells[row][column] = filled
+			cells[row][gridSize-1-column] = filled
+		}
+	}
+	return cells
+}
+
+// foregroundColor turns the digest's first two bytes into a hue at fixed saturation and lightness,
+// so every avatar is equally readable on the background
+func foregroundColor(digest [sha256.Size]byte) color.RGBA {
+	hue := float64(int(digest[0])<<8|int(digest[1])) / 65536 * 360
+	const saturation = 0.55
+	const lightness = 0.5
+
+	chroma := (1 - math.Abs(2*lightness-1)) * saturation
+	secondary := chroma * (1 - math.Abs(math.Mod(hue/60, 2)-1))
+	var red, green, blue float64
+	switch {
+	case hue < 60:
+		red, green = chroma, secondary
+	case hue < 120:
+		red, green = secondary, chroma
+	case hue < 180:
+		green, blue = chroma, secondary
+	case hue < 240:
+		green, blue = secondary, chroma
+	case hue < 300:
+		red, blue = secondary, chroma
+	default:
+		red, blue = chroma, secondary
+	}
+	offset := lightness - chroma/2
+	return color.RGBA{
+		R: uint8(math.Round((red + offset) * 255)),
+		G: uint8(math.Round((green + offset) * 255)),
+		B: uint8(math.Round((blue + offset) * 255)),
+		A: 255,
+	}
+}
@@ -0,0 +1,60 @@
+package avatar
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+// TestIdenticon_Deterministic verifies a seed always renders the same image and other seeds differ
+func TestIdenticon_Deterministic(t *testing.T) {
+	first, firstError := Identicon("patient-1", DefaultSize)
+	if firstError != nil {
+		t.Fatalf("Expected no error, got %v", firstError)
+	}
+	again, _ := Identicon("patient-1", DefaultSize)
+	other, _ := Identicon("patient-2", DefaultSize)
+
+	if !bytes.Equal(first, again) {
+		t.Error("Expected the same seed to render the same avatar")
+	}
+	if bytes.Equal(first, other) {
+		t.Error("Expected different seeds to render different avatars")
+	}
+	if ETag("patient-1", DefaultSize) == ETag("patient-1", 64) || ETag("patient-1", DefaultSize) != ETag("patient-1", DefaultSize) {
+		t.Error("Expected the entity tag to follow the seed and size")
+	}
+}
+
+// TestIdenticon_SizeAndSymmetry verifies the image has the requested size and mirrors left to right
+func TestIdenticon_SizeAndSymmetry(t *testing.T) {
+	encoded, renderError := Identicon("patient-1", 60)
+	if renderError != nil {
+		t.Fatalf("Expected no error, got %v", renderError)
+	}
+	decoded, decodeError := png.Decode(bytes.NewReader(encoded))
+	if decodeError != nil {
+		t.Fatalf("Expected a PNG, got %v", decodeError)
+	}
+
+	bounds := decoded.Bounds()
+	if bounds.Dx() != 60 || bounds.Dy() != 60 {
+		t.Fatalf("Expected 60x60, got %dx%d", bounds.Dx(), bounds.Dy())
+	}
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 30; x++ {
+			if decoded.At(x, y) != decoded.At(59-x, y) {
+				t.Fatalf("Expected pixel (%d,%d) to mirror (%d,%d)", x, y, 59-x, y)
+			}
+		}
+	}
+}
+
+// TestIdenticon_RejectsSize verifies sizes outside the allowed range are refused
+func TestIdenticon_RejectsSize(t *testing.T) {
+	for _, size := range []int{MinSize - 1, MaxSize + 1} {
+		if _, renderError := Identicon("patient-1", size); renderError == nil {
+			t.Errorf("Expected size %d to be rejected", size)
+		}
+	}
+}
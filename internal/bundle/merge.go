@@ -0,0 +1,125 @@
+package bundle
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// resourceIdentity holds the fields used to recognize the same resource across sources
+type resourceIdentity struct {
+	ResourceType string `json:"resourceType"`
+	ID           string `json:"id"`
+}
+
+// key returns the "Type/id" key, or an empty string when the resource has no identity
+func (identity resourceIdentity) key() string {
+	if identity.ResourceType == "" || identity.ID == "" {
+		return ""
+	}
+	return identity.ResourceType + "/" + identity.ID
+}
+
+// entryIdentity extracts the resource type and ID from a bundle entry's raw resource
+func entryIdentity(entry fhir.BundleEntry) resourceIdentity {
+	var identity resourceIdentity
+	if len(entry.Resource) == 0 {
+		return identity
+	}
+	if unmarshalError := json.Unmarshal(entry.Resource, &identity); unmarshalError != nil {
+		return resourceIdentity{}
+	}
+	return identity
+}
+
+// entryMode returns the search mode of an entry, treating entries without one as matches
+func entryMode(entry fhir.BundleEntry) fhir.SearchEntryMode {
+	if entry.Search == nil || entry.Search.Mode == nil {
+		return fhir.SearchEntryModeMatch
+	}
+	return *entry.Search.Mode
+}
+
+// MergeEntries combines entry groups gathered from several stores or include queries
+// into one canonical list:
+//   - entries are de-duplicated by resource type and ID; a resource that is both a
+//     match and an include is kept once, as a match
+//   - match entries come first, in group order then source order, so the primary
+//     query's sort order is preserved
+//   - include entries follow, ordered by resource type then ID, so concurrent fetches
+//     that complete in different orders still produce identical pages
+//   - outcome entries (e.g. partial-result warnings) come last
+//
+// Callers must pass groups in a fixed order (not in completion order) for the result to be stable.
+func MergeEntries(groups ...[]fhir.BundleEntry) []fhir.BundleEntry {
+	matches := make([]fhir.BundleEntry, 0)
+	includes := make([]fhir.BundleEntry, 0)
+	outcomes := make([]fhir.BundleEntry, 0)
+
+	// First pass: record which resources appear as matches anywhere
+	matchedKeys := make(map[string]bool)
+	for _, group := range groups {
+		for _, entry := range group {
+			if entryMode(entry) == fhir.SearchEntryModeMatch {
+				if entryKey := entryIdentity(entry).key(); entryKey != "" {
+					matchedKeys[entryKey] = true
+				}
+			}
+		}
+	}
+
+	// Second pass: bucket entries by mode, skipping duplicates
+	seenKeys := make(map[string]bool)
+	for _, group := range groups {
+		for _, entry := range group {
+			entryKey := entryIdentity(entry).key()
+			mode := entryMode(entry)
+
+			// Outcome entries and anonymous resources are never de-duplicated
+			if mode == fhir.SearchEntryModeOutcome {
+				outcomes = append(outcomes, entry)
+				continue
+			}
+			if entryKey == "" {
+				if mode == fhir.SearchEntryModeMatch {
+					matches = append(matches, entry)
+				} else {
+					includes = append(includes, entry)
+				}
+				continue
+			}
+
+			// An include of a resource that also matched is dropped in favour of the match
+			if mode == fhir.SearchEntryModeInclude && matchedKeys[entryKey] {
+				continue
+			}
+			if seenKeys[entryKey] {
+				continue
+			}
+			seenKeys[entryKey] = true
+
+			if mode == fhir.SearchEntryModeMatch {
+				matches = append(matches, entry)
+			} else {
+				includes = append(includes, entry)
+			}
+		}
+	}
+
+	// Includes have no query-defined order, so order them canonically
+	sort.SliceStable(includes, func(left int, right int) bool {
+		leftIdentity := entryIdentity(includes[left])
+		rightIdentity := entryIdentity(includes[right])
+		if leftIdentity.ResourceType != rightIdentity.ResourceType {
+			return leftIdentity.ResourceType < rightIdentity.ResourceType
+		}
+		return leftIdentity.ID < rightIdentity.ID
+	})
+
+	merged := make([]fhir.BundleEntry, 0, len(matches)+len(includes)+len(outcomes))
+	merged = append(merged, matches...)
+	merged = append(merged, includes...)
+	merged = append(merged, outcomes...)
+	return merged
+}
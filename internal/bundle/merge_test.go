@@ -0,0 +1,131 @@
+package bundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// testEntry builds a bundle entry for a resource type, ID, and search mode
+func testEntry(resourceType string, resourceID string, mode fhir.SearchEntryMode) fhir.BundleEntry {
+	resourceJSON, _ := json.Marshal(map[string]string{"resourceType": resourceType, "id": resourceID})
+	return fhir.BundleEntry{
+		Resource: resourceJSON,
+		Search:   &fhir.BundleEntrySearch{Mode: &mode},
+	}
+}
+
+// entryKeys lists the Type/id keys of entries in order
+func entryKeys(entries []fhir.BundleEntry) []string {
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		keys = append(keys, entryIdentity(entry).key())
+	}
+	return keys
+}
+
+// assertKeys compares entry keys against the expected order
+func assertKeys(t *testing.T, entries []fhir.BundleEntry, expected []string) {
+	t.Helper()
+	actual := entryKeys(entries)
+	if len(actual) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, actual)
+	}
+	for index := range expected {
+		if actual[index] != expected[index] {
+			t.Fatalf("Expected %v, got %v", expected, actual)
+		}
+	}
+}
+
+// TestMergeEntries_Deduplicates verifies the same resource from two sources appears once
+func TestMergeEntries_Deduplicates(t *testing.T) {
+	merged := MergeEntries(
+		[]fhir.BundleEntry{testEntry("Observation", "o1", fhir.SearchEntryModeInclude)},
+		[]fhir.BundleEntry{testEntry("Observation", "o1", fhir.SearchEntryModeInclude)},
+	)
+
+	assertKeys(t, merged, []string{"Observation/o1"})
+}
+
+// TestMergeEntries_MatchesBeforeIncludes verifies matches lead and keep their source order
+func TestMergeEntries_MatchesBeforeIncludes(t *testing.T) {
+	merged := MergeEntries(
+		[]fhir.BundleEntry{
+			testEntry("Patient", "p2", fhir.SearchEntryModeMatch),
+			testEntry("Patient", "p1", fhir.SearchEntryModeMatch),
+		},
+		[]fhir.BundleEntry{
+			testEntry("Observation", "o2", fhir.SearchEntryModeInclude),
+			testEntry("Observation", "o1", fhir.SearchEntryModeInclude),
+		},
+	)
+
+	assertKeys(t, merged, []string{"Patient/p2", "Patient/p1", "Observation/o1", "Observation/o2"})
+}
+
+// TestMergeEntries_MatchWinsOverInclude verifies a resource both matched and included stays a match
+func TestMergeEntries_MatchWinsOverInclude(t *testing.T) {
+	merged := MergeEntries(
+		[]fhir.BundleEntry{testEntry("Patient", "p1", fhir.SearchEntryModeInclude)},
+		[]fhir.BundleEntry{testEntry("Patient", "p1", fhir.SearchEntryModeMatch)},
+	)
+
+	assertKeys(t, merged, []string{"Patient/p1"})
+	if entryMode(merged[0]) != fhir.SearchEntryModeMatch {
+		t.Error("Expected the surviving entry to be the match")
+	}
+}
+
+// TestMergeEntries_StableIncludeOrder verifies include order does not depend on arrival order
+func TestMergeEntries_StableIncludeOrder(t *testing.T) {
+	firstOrder := MergeEntries([]fhir.BundleEntry{
+		testEntry("Observation", "b", fhir.SearchEntryModeInclude),
+		testEntry("Encounter", "z", fhir.SearchEntryModeInclude),
+		testEntry("Observation", "a", fhir.SearchEntryModeInclude),
+	})
+	secondOrder := MergeEntries([]fhir.BundleEntry{
+		testEntry("Observation", "a", fhir.SearchEntryModeInclude),
+		testEntry("Observation", "b", fhir.SearchEntryModeInclude),
+		testEntry("Encounter", "z", fhir.SearchEntryModeInclude),
+	})
+
+	expected := []string{"Encounter/z", "Observation/a", "Observation/b"}
+	assertKeys(t, firstOrder, expected)
+	assertKeys(t, secondOrder, expected)
+}
+
+// TestMergeEntries_OutcomesLast verifies outcome entries are kept and placed last
+func TestMergeEntries_OutcomesLast(t *testing.T) {
+	outcomeMode := fhir.SearchEntryModeOutcome
+	outcomeEntry := fhir.BundleEntry{
+		Resource: json.RawMessage(`{"resourceType":"OperationOutcome"}`),
+		Search:   &fhir.BundleEntrySearch{Mode: &outcomeMode},
+	}
+
+	merged := MergeEntries(
+		[]fhir.BundleEntry{outcomeEntry},
+		[]fhir.BundleEntry{testEntry("Patient", "p1", fhir.SearchEntryModeMatch)},
+	)
+
+	if len(merged) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(merged))
+	}
+	if entryMode(merged[1]) != fhir.SearchEntryModeOutcome {
+		t.Error("Expected outcome entry last")
+	}
+}
+
+// TestMergeEntries_NoSearchModeIsMatch verifies entries without search mode count as matches
+func TestMergeEntries_NoSearchModeIsMatch(t *testing.T) {
+	entry := testEntry("Patient", "p1", fhir.SearchEntryModeMatch)
+	entry.Search = nil
+
+	merged := MergeEntries(
+		[]fhir.BundleEntry{testEntry("Observation", "o1", fhir.SearchEntryModeInclude)},
+		[]fhir.BundleEntry{entry},
+	)
+
+	assertKeys(t, merged, []string{"Patient/p1", "Observation/o1"})
+}
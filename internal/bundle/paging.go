@@ -0,0 +1,91 @@
+package bundle
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Page describes which slice of a search's matches a searchset carries
+type Page struct {
+	// Total is the number of resources matching the search across all pages
+	Total int
+
+	// Count is the page size and Offset the number of matches skipped before the page
+	Count  int
+	Offset int
+
+	// CursorPaging pages with _cursor in place of _offset: Cursor is the _cursor the page was read with, ""
+	// for the first page, and NextCursor continues the search after it, "" on the last page
+	CursorPaging bool
+	Cursor       string
+	NextCursor   string
+}
+
+// Links returns the self link of the page at searchURL, plus next and previous when those pages exist
+// Every query parameter but _count, _offset, and _cursor is kept, so following a link repeats the same search
+func (page Page) Links(searchURL *url.URL) []fhir.BundleLink {
+	if page.CursorPaging {
+		return page.cursorLinks(searchURL)
+	}
+
+	links := []fhir.BundleLink{{Relation: "self", Url: page.pageURL(searchURL, page.Offset)}}
+	if page.Count <= 0 {
+		return links
+	}
+	if page.Offset+page.Count < page.Total {
+		links = append(links, fhir.BundleLink{Relation: "next", Url: page.pageURL(searchURL, page.Offset+page.Count)})
+	}
+	if page.Offset > 0 {
+		previousOffset := max(page.Offset-page.Count, 0)
+		links = append(links, fhir.BundleLink{Relation: "previous", Url: page.pageURL(searchURL, previousOffset)})
+	}
+	return links
+}
+
+// pageURL returns searchURL with _count set to the page size and _offset set to offset
+func (page Page) pageURL(searchURL *url.URL, offset int) string {
+	query := searchURL.Query()
+	query.Set("_count", strconv.Itoa(page.Count))
+	query.Set("_offset", strconv.Itoa(offset))
+
+	linkURL := *searchURL
+	linkURL.RawQuery = query.Encode()
+	return linkURL.String()
+}
+
+// cursorLinks returns the self link of a cursor-paged page, plus next when more matches follow
+// Cursors only lead forward, so there is no previous link
+func (page Page) cursorLinks(searchURL *url.URL) []fhir.BundleLink {
+	links := []fhir.BundleLink{{Relation: "self", Url: page.cursorURL(searchURL, page.Cursor)}}
+	if page.NextCursor != "" {
+		links = append(links, fhir.BundleLink{Relation: "next", Url: page.cursorURL(searchURL, page.NextCursor)})
+	}
+	return links
+}
+
+// cursorURL returns searchURL with _count set to the page size and _cursor set to cursor, or removed when
+// cursor is ""
+func (page Page) cursorURL(searchURL *url.URL, cursor string) string {
+	query := searchURL.Query()
+	query.Set("_count", strconv.Itoa(page.Count))
+	query.Del("_offset")
+	query.Del("_cursor")
+	if cursor != "" {
+		query.Set("_cursor", cursor)
+	}
+
+	linkURL := *searchURL
+	linkURL.RawQuery = query.Encode()
+	return linkURL.String()
+}
+
+// PagedSearchset builds a searchset Bundle for one page of a search: its total counts every match of the
+// search rather than the match entries on the page, and its links lead to the neighboring pages
+func PagedSearchset(entries []fhir.BundleEntry, searchURL *url.URL, page Page) fhir.Bundle {
+	searchset := Searchset(entries)
+	searchset.Total = &page.Total
+	searchset.Link = page.Links(searchURL)
+	return searchset
+}
@@ -0,0 +1,95 @@
+package bundle
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// pageLinks indexes a Bundle's links by relation
+func pageLinks(links []fhir.BundleLink) map[string]string {
+	byRelation := make(map[string]string, len(links))
+	for _, link := range links {
+		byRelation[link.Relation] = link.Url
+	}
+	return byRelation
+}
+
+// TestPage_Links verifies next and previous appear only when those pages exist and keep the search's other parameters
+func TestPage_Links(t *testing.T) {
+	searchURL, _ := url.Parse("https://fhir.example.com/fhir/Patient?family=Smith&_count=10&_offset=10")
+
+	middleLinks := pageLinks(Page{Total: 25, Count: 10, Offset: 10}.Links(searchURL))
+	expectedLinks := map[string]string{
+		"self":     "https://fhir.example.com/fhir/Patient?_count=10&_offset=10&family=Smith",
+		"next":     "https://fhir.example.com/fhir/Patient?_count=10&_offset=20&family=Smith",
+		"previous": "https://fhir.example.com/fhir/Patient?_count=10&_offset=0&family=Smith",
+	}
+	for relation, expectedURL := range expectedLinks {
+		if middleLinks[relation] != expectedURL {
+			t.Errorf("Expected %s link %s, got %s", relation, expectedURL, middleLinks[relation])
+		}
+	}
+
+	firstLinks := pageLinks(Page{Total: 25, Count: 10, Offset: 0}.Links(searchURL))
+	if _, hasPrevious := firstLinks["previous"]; hasPrevious {
+		t.Errorf("Expected no previous link on the first page, got %s", firstLinks["previous"])
+	}
+
+	lastLinks := pageLinks(Page{Total: 25, Count: 10, Offset: 20}.Links(searchURL))
+	if _, hasNext := lastLinks["next"]; hasNext {
+		t.Errorf("Expected no next link on the last page, got %s", lastLinks["next"])
+	}
+
+	// An offset that is not a multiple of the page size never pages back past the first match
+	unalignedLinks := pageLinks(Page{Total: 25, Count: 10, Offset: 4}.Links(searchURL))
+	if unalignedLinks["previous"] != "https://fhir.example.com/fhir/Patient?_count=10&_offset=0&family=Smith" {
+		t.Errorf("Expected the previous link to clamp to offset 0, got %s", unalignedLinks["previous"])
+	}
+}
+
+// TestPage_CursorLinks verifies cursor-paged links carry _cursor in place of _offset and only lead forward
+func TestPage_CursorLinks(t *testing.T) {
+	searchURL, _ := url.Parse("https://fhir.example.com/fhir/Patient?family=Smith&_count=10&_cursor=first")
+
+	middleLinks := pageLinks(Page{Total: 25, Count: 10, CursorPaging: true, Cursor: "first", NextCursor: "second"}.Links(searchURL))
+	expectedLinks := map[string]string{
+		"self": "https://fhir.example.com/fhir/Patient?_count=10&_cursor=first&family=Smith",
+		"next": "https://fhir.example.com/fhir/Patient?_count=10&_cursor=second&family=Smith",
+	}
+	for relation, expectedURL := range expectedLinks {
+		if middleLinks[relation] != expectedURL {
+			t.Errorf("Expected %s link %s, got %s", relation, expectedURL, middleLinks[relation])
+		}
+	}
+	if _, hasPrevious := middleLinks["previous"]; hasPrevious {
+		t.Errorf("Expected no previous link with cursors, got %s", middleLinks["previous"])
+	}
+
+	firstURL, _ := url.Parse("https://fhir.example.com/fhir/Patient?family=Smith")
+	firstLinks := pageLinks(Page{Total: 25, Count: 10, CursorPaging: true, NextCursor: "second"}.Links(firstURL))
+	if firstLinks["self"] != "https://fhir.example.com/fhir/Patient?_count=10&family=Smith" {
+		t.Errorf("Expected the first page's self link without _cursor, got %s", firstLinks["self"])
+	}
+
+	lastLinks := pageLinks(Page{Total: 25, Count: 10, CursorPaging: true, Cursor: "third"}.Links(searchURL))
+	if _, hasNext := lastLinks["next"]; hasNext {
+		t.Errorf("Expected no next link on the last page, got %s", lastLinks["next"])
+	}
+}
+
+// TestPagedSearchset_Total verifies the total counts every match of the search, not only the page's entries
+func TestPagedSearchset_Total(t *testing.T) {
+	searchURL, _ := url.Parse("https://fhir.example.com/fhir/Observation")
+	searchset := PagedSearchset([]fhir.BundleEntry{
+		testEntry("Observation", "o-1", fhir.SearchEntryModeMatch),
+	}, searchURL, Page{Total: 42, Count: 1, Offset: 0})
+
+	if searchset.Total == nil || *searchset.Total != 42 {
+		t.Errorf("Expected total 42, got %v", searchset.Total)
+	}
+	if len(searchset.Link) != 2 {
+		t.Errorf("Expected self and next links, got %+v", searchset.Link)
+	}
+}
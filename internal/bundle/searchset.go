@@ -0,0 +1,49 @@
+package bundle
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SearchEntry wraps a resource as a searchset entry with the given search mode
+// The entry's fullUrl is baseURL/Type/id when the resource carries both a type and an ID;
+// anonymous resources such as outcome entries have none
+func SearchEntry(baseURL string, resource interface{}, mode fhir.SearchEntryMode) fhir.BundleEntry {
+	encodedResource, _ := json.Marshal(resource)
+	entry := fhir.BundleEntry{
+		Resource: encodedResource,
+		Search:   &fhir.BundleEntrySearch{Mode: &mode},
+	}
+	if entryKey := entryIdentity(entry).key(); entryKey != "" {
+		fullURL := strings.TrimSuffix(baseURL, "/") + "/" + entryKey
+		entry.FullUrl = &fullURL
+	}
+	return entry
+}
+
+// SearchEntries wraps each resource of a search result as a searchset entry with the given search mode
+func SearchEntries[Resource any](baseURL string, resources []Resource, mode fhir.SearchEntryMode) []fhir.BundleEntry {
+	entries := make([]fhir.BundleEntry, 0, len(resources))
+	for _, resource := range resources {
+		entries = append(entries, SearchEntry(baseURL, resource, mode))
+	}
+	return entries
+}
+
+// Searchset builds a searchset Bundle whose total counts the match entries
+// Include and outcome entries are carried but not counted, as FHIR defines total
+func Searchset(entries []fhir.BundleEntry) fhir.Bundle {
+	total := 0
+	for _, entry := range entries {
+		if entryMode(entry) == fhir.SearchEntryModeMatch {
+			total++
+		}
+	}
+	return fhir.Bundle{
+		Type:  fhir.BundleTypeSearchset,
+		Total: &total,
+		Entry: entries,
+	}
+}
@@ -0,0 +1,34 @@
+package bundle
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestSearchEntry_FullURL verifies identified resources get an absolute fullUrl and anonymous ones none
+func TestSearchEntry_FullURL(t *testing.T) {
+	patientEntry := SearchEntry("https://fhir.example.com/fhir/", map[string]string{"resourceType": "Patient", "id": "p-1"}, fhir.SearchEntryModeMatch)
+	if patientEntry.FullUrl == nil || *patientEntry.FullUrl != "https://fhir.example.com/fhir/Patient/p-1" {
+		t.Errorf("Expected the patient's fullUrl, got %v", patientEntry.FullUrl)
+	}
+
+	outcomeEntry := SearchEntry("https://fhir.example.com/fhir", map[string]string{"resourceType": "OperationOutcome"}, fhir.SearchEntryModeOutcome)
+	if outcomeEntry.FullUrl != nil {
+		t.Errorf("Expected no fullUrl for a resource without an ID, got %s", *outcomeEntry.FullUrl)
+	}
+}
+
+// TestSearchset_Total verifies total counts only match entries
+func TestSearchset_Total(t *testing.T) {
+	searchset := Searchset([]fhir.BundleEntry{
+		testEntry("Patient", "p-1", fhir.SearchEntryModeMatch),
+		testEntry("Patient", "p-2", fhir.SearchEntryModeMatch),
+		testEntry("Observation", "o-1", fhir.SearchEntryModeInclude),
+		testEntry("OperationOutcome", "", fhir.SearchEntryModeOutcome),
+	})
+
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 2 || len(searchset.Entry) != 4 {
+		t.Errorf("Expected a searchset with total 2 and 4 entries, got %+v", searchset)
+	}
+}
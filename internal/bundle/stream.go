@@ -0,0 +1,61 @@
+package bundle
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// RecordWriter sends a response in pieces, as streaming.Writer does
+type RecordWriter interface {
+	// WriteRecord writes one piece followed by a newline, flushing when enough bytes are buffered
+	WriteRecord(record []byte) error
+
+	// Flush pushes the buffered pieces to the client
+	Flush() error
+}
+
+// SearchsetWriter writes a searchset Bundle as FHIR JSON one entry at a time, so a search's matches are sent
+// as they are read instead of built into a Bundle first
+// Each entry is written on its own line; the newlines are whitespace between array elements
+type SearchsetWriter struct {
+	records    RecordWriter
+	entryCount int
+}
+
+// NewSearchsetWriter starts a searchset Bundle, writing its total and links before any entry
+// total must be known up front, since it precedes the entries
+func NewSearchsetWriter(records RecordWriter, total int, links []fhir.BundleLink) (*SearchsetWriter, error) {
+	encodedLinks, encodeError := json.Marshal(links)
+	if encodeError != nil {
+		return nil, encodeError
+	}
+
+	opening := `{"resourceType":"Bundle","type":"searchset","total":` + strconv.Itoa(total) + `,"link":` + string(encodedLinks) + `,"entry":[`
+	if writeError := records.WriteRecord([]byte(opening)); writeError != nil {
+		return nil, writeError
+	}
+	return &SearchsetWriter{records: records}, nil
+}
+
+// WriteEntry appends an entry to the Bundle
+func (writer *SearchsetWriter) WriteEntry(entry fhir.BundleEntry) error {
+	encodedEntry, encodeError := json.Marshal(entry)
+	if encodeError != nil {
+		return encodeError
+	}
+	if writer.entryCount > 0 {
+		encodedEntry = append([]byte(","), encodedEntry...)
+	}
+	writer.entryCount++
+	return writer.records.WriteRecord(encodedEntry)
+}
+
+// Close ends the Bundle and flushes what remains to the client
+func (writer *SearchsetWriter) Close() error {
+	if writeError := writer.records.WriteRecord([]byte("]}")); writeError != nil {
+		return writeError
+	}
+	return writer.records.Flush()
+}
@@ -0,0 +1,75 @@
+package bundle
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// bufferedRecords collects written records in memory and counts flushes
+type bufferedRecords struct {
+	body    bytes.Buffer
+	flushes int
+}
+
+// WriteRecord appends the record and a newline
+func (records *bufferedRecords) WriteRecord(record []byte) error {
+	records.body.Write(record)
+	records.body.WriteByte('\n')
+	return nil
+}
+
+// Flush counts the flush
+func (records *bufferedRecords) Flush() error {
+	records.flushes++
+	return nil
+}
+
+// TestSearchsetWriter verifies streamed entries make up a searchset Bundle that decodes like a built one
+func TestSearchsetWriter(t *testing.T) {
+	records := &bufferedRecords{}
+	links := []fhir.BundleLink{{Relation: "self", Url: "https://fhir.example.com/fhir/Patient?_stream=true"}}
+	searchset, startError := NewSearchsetWriter(records, 2, links)
+	if startError != nil {
+		t.Fatalf("Expected the Bundle to start, got %v", startError)
+	}
+	searchset.WriteEntry(testEntry("Patient", "p-1", fhir.SearchEntryModeMatch))
+	searchset.WriteEntry(testEntry("Patient", "p-2", fhir.SearchEntryModeMatch))
+	if closeError := searchset.Close(); closeError != nil {
+		t.Fatalf("Expected the Bundle to close, got %v", closeError)
+	}
+
+	var streamed fhir.Bundle
+	if decodeError := json.Unmarshal(records.body.Bytes(), &streamed); decodeError != nil {
+		t.Fatalf("Expected valid Bundle JSON, got %v in %s", decodeError, records.body.String())
+	}
+	if streamed.Type != fhir.BundleTypeSearchset || streamed.Total == nil || *streamed.Total != 2 {
+		t.Errorf("Expected a searchset with total 2, got %+v", streamed)
+	}
+	if keys := entryKeys(streamed.Entry); len(keys) != 2 || keys[0] != "Patient/p-1" || keys[1] != "Patient/p-2" {
+		t.Errorf("Expected both entries in order, got %v", keys)
+	}
+	if len(streamed.Link) != 1 || streamed.Link[0].Url != links[0].Url {
+		t.Errorf("Expected the self link, got %+v", streamed.Link)
+	}
+	if records.flushes == 0 {
+		t.Error("Expected the end of the Bundle to be flushed")
+	}
+}
+
+// TestSearchsetWriter_Empty verifies a stream without matches is still a valid Bundle
+func TestSearchsetWriter_Empty(t *testing.T) {
+	records := &bufferedRecords{}
+	searchset, _ := NewSearchsetWriter(records, 0, []fhir.BundleLink{})
+	searchset.Close()
+
+	var streamed fhir.Bundle
+	if decodeError := json.Unmarshal(records.body.Bytes(), &streamed); decodeError != nil {
+		t.Fatalf("Expected valid Bundle JSON, got %v in %s", decodeError, records.body.String())
+	}
+	if len(streamed.Entry) != 0 {
+		t.Errorf("Expected no entries, got %d", len(streamed.Entry))
+	}
+}
@@ -0,0 +1,163 @@
+package bundle
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// transactionMethodOrder is the order FHIR processes transaction entries in, whatever their order in the Bundle
+var transactionMethodOrder = map[fhir.HTTPVerb]int{
+	fhir.HTTPVerbDELETE: 0,
+	fhir.HTTPVerbPOST:   1,
+	fhir.HTTPVerbPUT:    2,
+	fhir.HTTPVerbPATCH:  2,
+	fhir.HTTPVerbGET:    3,
+	fhir.HTTPVerbHEAD:   3,
+}
+
+// TransactionOrder returns the indexes of entries in processing order: deletes, then creates, then updates,
+// then reads, each group keeping its order in the Bundle, except that a create referencing another create's
+// urn:uuid fullUrl is moved after it so the reference can be resolved
+// Entries must all carry a request
+func TransactionOrder(entries []fhir.BundleEntry) []int {
+	order := make([]int, len(entries))
+	for entryIndex := range entries {
+		order[entryIndex] = entryIndex
+	}
+	sort.SliceStable(order, func(left int, right int) bool {
+		return transactionMethodOrder[entries[order[left]].Request.Method] < transactionMethodOrder[entries[order[right]].Request.Method]
+	})
+
+	createsStart := sort.Search(len(order), func(position int) bool {
+		return transactionMethodOrder[entries[order[position]].Request.Method] >= transactionMethodOrder[fhir.HTTPVerbPOST]
+	})
+	createsEnd := sort.Search(len(order), func(position int) bool {
+		return transactionMethodOrder[entries[order[position]].Request.Method] > transactionMethodOrder[fhir.HTTPVerbPOST]
+	})
+	orderCreatesByReference(entries, order[createsStart:createsEnd])
+	return order
+}
+
+// orderCreatesByReference reorders creates in place so each comes after the creates whose urn:uuid fullUrl
+// it references; creates caught in a reference cycle keep their Bundle order
+func orderCreatesByReference(entries []fhir.BundleEntry, creates []int) {
+	fullURLs := make(map[string]bool, len(creates))
+	for _, entryIndex := range creates {
+		if fullURL := entries[entryIndex].FullUrl; fullURL != nil && strings.HasPrefix(*fullURL, "urn:uuid:") {
+			fullURLs[*fullURL] = true
+		}
+	}
+	if len(fullURLs) == 0 {
+		return
+	}
+
+	pending := append([]int(nil), creates...)
+	ordered := make([]int, 0, len(creates))
+	for len(pending) > 0 {
+		waiting := make(map[string]bool)
+		for _, entryIndex := range pending {
+			if fullURL := entries[entryIndex].FullUrl; fullURL != nil {
+				waiting[*fullURL] = true
+			}
+		}
+
+		var stillPending []int
+		for _, entryIndex := range pending {
+			if referencesAny(entries[entryIndex].Resource, fullURLs, waiting, entries[entryIndex].FullUrl) {
+				stillPending = append(stillPending, entryIndex)
+				continue
+			}
+			ordered = append(ordered, entryIndex)
+		}
+		if len(stillPending) == len(pending) {
+			ordered = append(ordered, pending...)
+			break
+		}
+		pending = stillPending
+	}
+	copy(creates, ordered)
+}
+
+// referencesAny reports whether resource references a create's fullUrl that is still waiting to be processed,
+// ignoring references to the entry's own fullUrl
+func referencesAny(resource json.RawMessage, fullURLs map[string]bool, waiting map[string]bool, ownFullURL *string) bool {
+	for fullURL := range waiting {
+		if !fullURLs[fullURL] || ownFullURL != nil && *ownFullURL == fullURL {
+			continue
+		}
+		if strings.Contains(string(resource), strconv.Quote(fullURL)) {
+			return true
+		}
+	}
+	return false
+}
+
+// RewriteReferences replaces every reference in resource that names a key of replacements, such as the
+// urn:uuid fullUrl of an entry created earlier in the transaction, with its value, such as "Patient/123"
+// The resource is returned unchanged when nothing matched
+func RewriteReferences(resource json.RawMessage, replacements map[string]string) json.RawMessage {
+	if len(resource) == 0 || len(replacements) == 0 {
+		return resource
+	}
+	var decodedResource interface{}
+	if decodeError := json.Unmarshal(resource, &decodedResource); decodeError != nil {
+		return resource
+	}
+	if !rewriteReferenceValues(decodedResource, replacements) {
+		return resource
+	}
+	rewrittenResource, encodeError := json.Marshal(decodedResource)
+	if encodeError != nil {
+		return resource
+	}
+	return rewrittenResource
+}
+
+// rewriteReferenceValues rewrites the reference elements found anywhere under value and reports whether any changed
+func rewriteReferenceValues(value interface{}, replacements map[string]string) bool {
+	rewritten := false
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		for key, element := range typedValue {
+			if reference, isString := element.(string); isString && key == "reference" {
+				if replacement, found := replacements[reference]; found {
+					typedValue[key] = replacement
+					rewritten = true
+				}
+				continue
+			}
+			if rewriteReferenceValues(element, replacements) {
+				rewritten = true
+			}
+		}
+	case []interface{}:
+		for _, element := range typedValue {
+			if rewriteReferenceValues(element, replacements) {
+				rewritten = true
+			}
+		}
+	}
+	return rewritten
+}
+
+// ResponseStatus formats an HTTP status code the way Bundle.entry.response.status carries it, e.g. "201 Created"
+func ResponseStatus(statusCode int) string {
+	return strings.TrimSpace(strconv.Itoa(statusCode) + " " + http.StatusText(statusCode))
+}
+
+// TransactionResponse builds the transaction-response or batch-response Bundle answering a Bundle of bundleType
+func TransactionResponse(bundleType fhir.BundleType, entries []fhir.BundleEntry) fhir.Bundle {
+	responseType := fhir.BundleTypeTransactionResponse
+	if bundleType == fhir.BundleTypeBatch {
+		responseType = fhir.BundleTypeBatchResponse
+	}
+	return fhir.Bundle{
+		Type:  responseType,
+		Entry: entries,
+	}
+}
@@ -0,0 +1,90 @@
+package bundle
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// requestEntry builds an entry carrying only a request with the given method
+func requestEntry(method fhir.HTTPVerb) fhir.BundleEntry {
+	return fhir.BundleEntry{Request: &fhir.BundleEntryRequest{Method: method}}
+}
+
+// TestTransactionOrder verifies deletes run before creates, creates before updates, and reads last, keeping Bundle order within each group
+func TestTransactionOrder(t *testing.T) {
+	entries := []fhir.BundleEntry{
+		requestEntry(fhir.HTTPVerbGET),
+		requestEntry(fhir.HTTPVerbPUT),
+		requestEntry(fhir.HTTPVerbPOST),
+		requestEntry(fhir.HTTPVerbDELETE),
+		requestEntry(fhir.HTTPVerbPOST),
+	}
+
+	order := TransactionOrder(entries)
+
+	expectedOrder := []int{3, 2, 4, 1, 0}
+	for position, entryIndex := range expectedOrder {
+		if order[position] != entryIndex {
+			t.Fatalf("Expected processing order %v, got %v", expectedOrder, order)
+		}
+	}
+}
+
+// TestRewriteReferences verifies urn:uuid references are replaced wherever they appear and other references are kept
+func TestRewriteReferences(t *testing.T) {
+	resource := json.RawMessage(`{"resourceType":"Observation","subject":{"reference":"urn:uuid:patient-1"},"performer":[{"reference":"Practitioner/7"},{"reference":"urn:uuid:patient-1"}]}`)
+
+	rewrittenResource := RewriteReferences(resource, map[string]string{"urn:uuid:patient-1": "Patient/42"})
+
+	var observation fhir.Observation
+	if decodeError := json.Unmarshal(rewrittenResource, &observation); decodeError != nil {
+		t.Fatalf("Expected the rewritten resource to decode, got %v", decodeError)
+	}
+	if *observation.Subject.Reference != "Patient/42" {
+		t.Errorf("Expected the subject to reference Patient/42, got %s", *observation.Subject.Reference)
+	}
+	if *observation.Performer[0].Reference != "Practitioner/7" || *observation.Performer[1].Reference != "Patient/42" {
+		t.Errorf("Expected only the urn:uuid performer to be rewritten, got %s and %s", *observation.Performer[0].Reference, *observation.Performer[1].Reference)
+	}
+
+	unchangedResource := json.RawMessage(`{"resourceType":"Patient","id":"1"}`)
+	if string(RewriteReferences(unchangedResource, map[string]string{"urn:uuid:patient-1": "Patient/42"})) != string(unchangedResource) {
+		t.Error("Expected a resource without matching references to be returned unchanged")
+	}
+}
+
+// TestTransactionResponse verifies the response Bundle type follows the request Bundle type
+func TestTransactionResponse(t *testing.T) {
+	if responseType := TransactionResponse(fhir.BundleTypeTransaction, nil).Type; responseType != fhir.BundleTypeTransactionResponse {
+		t.Errorf("Expected transaction-response, got %s", responseType.Code())
+	}
+	if responseType := TransactionResponse(fhir.BundleTypeBatch, nil).Type; responseType != fhir.BundleTypeBatchResponse {
+		t.Errorf("Expected batch-response, got %s", responseType.Code())
+	}
+	if status := ResponseStatus(201); status != "201 Created" {
+		t.Errorf("Expected \"201 Created\", got %q", status)
+	}
+}
+
+// TestTransactionOrder_CreatesFollowReferences verifies a create runs after the create whose urn:uuid it references
+func TestTransactionOrder_CreatesFollowReferences(t *testing.T) {
+	patientURL := "urn:uuid:patient-1"
+	observationURL := "urn:uuid:observation-1"
+	entries := []fhir.BundleEntry{
+		{FullUrl: &observationURL, Resource: json.RawMessage(`{"subject":{"reference":"urn:uuid:patient-1"}}`), Request: &fhir.BundleEntryRequest{Method: fhir.HTTPVerbPOST}},
+		requestEntry(fhir.HTTPVerbGET),
+		{FullUrl: &patientURL, Resource: json.RawMessage(`{"resourceType":"Patient"}`), Request: &fhir.BundleEntryRequest{Method: fhir.HTTPVerbPOST}},
+		requestEntry(fhir.HTTPVerbDELETE),
+	}
+
+	order := TransactionOrder(entries)
+
+	expectedOrder := []int{3, 2, 0, 1}
+	for position, entryIndex := range expectedOrder {
+		if order[position] != entryIndex {
+			t.Fatalf("Expected processing order %v, got %v", expectedOrder, order)
+		}
+	}
+}
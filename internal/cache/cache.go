@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog/log"
+)
+
+// redisKeyPrefix namespaces the cache keys in a shared Redis
+const redisKeyPrefix = "fhir:cache:"
+
+// invalidatedMarker is stored under a resource's key while a write to it may still be uncommitted
+// Cached resources are JSON objects, so they never equal it
+const invalidatedMarker = "invalidated"
+
+// searchKind and countKind separate cached result pages from cached counts of the same criteria
+const (
+	searchKind = "search"
+	countKind  = "count"
+)
+
+// Policy controls how long reads are cached
+type Policy struct {
+	// TTL is how long a resource read by ID is cached
+	TTL time.Duration
+
+	// SearchTTL is how long a page of search results, or a search count, is cached
+	SearchTTL time.Duration
+
+	// WriteHold is how long after a write starts that its resource, and searches of its type, are not cached
+	// It must outlast the write's transaction, so a read made before the commit cannot cache the old value
+	WriteHold time.Duration
+}
+
+// DefaultPolicy caches reads by ID for a minute and searches for 15 seconds, holding off caching for
+// 10 seconds after each write
+func DefaultPolicy() Policy {
+	return Policy{
+		TTL:       time.Minute,
+		SearchTTL: 15 * time.Second,
+		WriteHold: 10 * time.Second,
+	}
+}
+
+// Cache keeps repository reads in Redis, shared by every server behind a load balancer
+// When Redis fails, reads go to the repository and are not cached, rather than turning a Redis outage into
+// a FHIR outage
+type Cache struct {
+	client redis.Cmdable
+	policy Policy
+}
+
+// New creates a cache over a Redis client
+func New(client redis.Cmdable, policy Policy) *Cache {
+	return &Cache{
+		client: client,
+		policy: policy,
+	}
+}
+
+// resourceKey is the key of one resource read by ID
+func resourceKey(resourceType string, resourceID string) string {
+	return redisKeyPrefix + resourceType + ":id:" + resourceID
+}
+
+// generationKey holds a counter bumped by every write of the resource type; search keys include it, so a
+// write leaves every earlier search entry unreachable
+func generationKey(resourceType string) string {
+	return redisKeyPrefix + resourceType + ":generation"
+}
+
+// writingKey exists while a write of the resource type may still be uncommitted
+func writingKey(resourceType string) string {
+	return redisKeyPrefix + resourceType + ":writing"
+}
+
+// searchKey returns the key of a search of the resource type, or "" when searches must not be cached now
+// kind separates result pages from counts of the same criteria
+func (cache *Cache) searchKey(ctx context.Context, resourceType string, kind string, searchParams interface{}) string {
+	encodedParams, encodeError := json.Marshal(searchParams)
+	if encodeError != nil {
+		return ""
+	}
+
+	values, readError := cache.client.MGet(ctx, generationKey(resourceType), writingKey(resourceType)).Result()
+	if readError != nil {
+		log.Warn().Err(readError).Str("resource_type", resourceType).Msg("Repository cache unavailable; search not cached")
+		return ""
+	}
+	if values[1] != nil {
+		return ""
+	}
+
+	generation := "0"
+	if rawGeneration, isString := values[0].(string); isString {
+		generation = rawGeneration
+	}
+	paramsHash := sha256.Sum256(encodedParams)
+	return redisKeyPrefix + resourceType + ":" + kind + ":" + generation + ":" + hex.EncodeToString(paramsHash[:])
+}
+
+// invalidate is called before a write: it marks the written resources, and searches of their type, as not
+// to be cached until WriteHold has passed, and makes cached searches of the type unreachable
+// A failure is logged, since the write must go ahead; cached reads then stay stale until they expire
+func (cache *Cache) invalidate(ctx context.Context, resourceType string, resourceIDs ...string) {
+	pipeline := cache.client.TxPipeline()
+	for _, resourceID := range resourceIDs {
+		pipeline.Set(ctx, resourceKey(resourceType, resourceID), invalidatedMarker, cache.policy.WriteHold)
+	}
+	pipeline.Set(ctx, writingKey(resourceType), "1", cache.policy.WriteHold)
+	pipeline.Incr(ctx, generationKey(resourceType))
+	if _, execError := pipeline.Exec(ctx); execError != nil {
+		log.Error().Err(execError).Str("resource_type", resourceType).Strs("ids", resourceIDs).Msg("Failed to invalidate repository cache")
+	}
+}
+
+// readThrough returns the value cached under key, or loads it and caches it for ttl
+// Nothing is cached when key is "", while the key holds the invalidated marker, or when load fails.
+// Values are stored only if the key is still empty, so a write's marker is never overwritten
+func readThrough[Value any](ctx context.Context, cache *Cache, key string, ttl time.Duration, load func() (Value, error)) (Value, error) {
+	if key == "" {
+		return load()
+	}
+
+	cachedValue, getError := cache.client.Get(ctx, key).Result()
+	switch {
+	case errors.Is(getError, redis.Nil):
+	case getError != nil:
+		log.Warn().Err(getError).Str("key", key).Msg("Repository cache unavailable; read not cached")
+		return load()
+	case cachedValue == invalidatedMarker:
+		return load()
+	default:
+		var decoded Value
+		if decodeError := json.Unmarshal([]byte(cachedValue), &decoded); decodeError == nil {
+			return decoded, nil
+		}
+		// An entry written by another version of the server is replaced when it expires
+		return load()
+	}
+
+	loaded, loadError := load()
+	if loadError != nil {
+		return loaded, loadError
+	}
+	encoded, encodeError := json.Marshal(loaded)
+	if encodeError != nil {
+		return loaded, nil
+	}
+	if setError := cache.client.SetNX(ctx, key, encoded, ttl).Err(); setError != nil {
+		log.Warn().Err(setError).Str("key", key).Msg("Failed to cache repository read")
+	}
+	return loaded, nil
+}
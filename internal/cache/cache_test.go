@@ -0,0 +1,43 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/redis/go-redis/v9"
+)
+
+// newTestCache returns a cache over a fresh in-memory Redis
+func newTestCache(t *testing.T) (*Cache, *miniredis.Miniredis) {
+	t.Helper()
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return New(client, DefaultPolicy()), redisServer
+}
+
+// TestPatientRepository_RedisDown verifies reads go to the repository when Redis is unreachable
+func TestPatientRepository_RedisDown(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr(), MaxRetries: -1, DialTimeout: 100 * time.Millisecond})
+	defer client.Close()
+	redisServer.Close()
+
+	inner := &countingPatientRepository{patients: map[string]*models.Patient{"p1": {ID: "p1", FamilyName: "Smith"}}}
+	cachedRepository := NewPatientRepository(inner, New(client, DefaultPolicy()))
+	ctx := context.Background()
+
+	if _, updateError := cachedRepository.Update(ctx, &models.Patient{ID: "p1", FamilyName: "Jones"}); updateError != nil {
+		t.Errorf("Expected the write to go ahead without Redis, got %v", updateError)
+	}
+	patient, getError := cachedRepository.GetByID(ctx, "p1")
+	if getError != nil || patient.FamilyName != "Jones" {
+		t.Errorf("Expected the patient read from the repository, got %+v (%v)", patient, getError)
+	}
+	if _, searchError := cachedRepository.Search(ctx, &models.PatientSearchParams{}); searchError != nil {
+		t.Errorf("Expected the search read from the repository, got %v", searchError)
+	}
+}
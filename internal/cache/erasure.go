@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// ObservationErasureStore invalidates cached observations, and observation searches, that an erasure moves
+// aside or back, so reads stop serving withdrawn observations and find restored ones
+type ObservationErasureStore struct {
+	repository.ObservationErasureStore
+	cache *Cache
+}
+
+// NewObservationErasureStore wraps erasureStore with the cache
+func NewObservationErasureStore(erasureStore repository.ObservationErasureStore, cache *Cache) *ObservationErasureStore {
+	return &ObservationErasureStore{
+		ObservationErasureStore: erasureStore,
+		cache:                   cache,
+	}
+}
+
+// HoldForErasure invalidates observation searches, moves the observations aside, then invalidates each one moved
+// The moved observations are only known afterwards; the move has completed by then, so a read that raced it
+// cannot cache the withdrawn observation over the invalidation
+func (cachedStore *ObservationErasureStore) HoldForErasure(ctx context.Context, erasureID int64, patientID string) ([]*models.Observation, error) {
+	cachedStore.cache.invalidate(ctx, observationResourceType)
+	heldObservations, holdError := cachedStore.ObservationErasureStore.HoldForErasure(ctx, erasureID, patientID)
+	cachedStore.cache.invalidate(ctx, observationResourceType, observationIDs(heldObservations)...)
+	return heldObservations, holdError
+}
+
+// RestoreErasure invalidates observation searches, moves the observations back, then invalidates each one moved
+func (cachedStore *ObservationErasureStore) RestoreErasure(ctx context.Context, erasureID int64) ([]*models.Observation, error) {
+	cachedStore.cache.invalidate(ctx, observationResourceType)
+	restoredObservations, restoreError := cachedStore.ObservationErasureStore.RestoreErasure(ctx, erasureID)
+	cachedStore.cache.invalidate(ctx, observationResourceType, observationIDs(restoredObservations)...)
+	return restoredObservations, restoreError
+}
+
+// observationIDs returns the IDs of observations
+func observationIDs(observations []*models.Observation) []string {
+	identifiers := make([]string, 0, len(observations))
+	for _, observation := range observations {
+		identifiers = append(identifiers, observation.ID)
+	}
+	return identifiers
+}
+
+// ErasureRepository invalidates the cached patient, and patient searches, when an erasure removes the patient
+// row or puts it back
+type ErasureRepository struct {
+	repository.ErasureRepository
+	cache *Cache
+}
+
+// NewErasureRepository wraps erasureRepository with the cache
+func NewErasureRepository(erasureRepository repository.ErasureRepository, cache *Cache) *ErasureRepository {
+	return &ErasureRepository{
+		ErasureRepository: erasureRepository,
+		cache:             cache,
+	}
+}
+
+// HoldPatientRecords invalidates the patient and patient searches and removes the patient row
+// The hold joins the withdrawal's transaction, so invalidating first keeps reads made before the commit from being cached
+func (cachedRepository *ErasureRepository) HoldPatientRecords(ctx context.Context, erasureID int64, patientID string) (int64, error) {
+	cachedRepository.cache.invalidate(ctx, patientResourceType, patientID)
+	return cachedRepository.ErasureRepository.HoldPatientRecords(ctx, erasureID, patientID)
+}
+
+// RestorePatientRecords invalidates the erasure's patient and patient searches and puts the patient back
+// The restore runs in a transaction, so invalidating first keeps reads made before the commit from being cached
+func (cachedRepository *ErasureRepository) RestorePatientRecords(ctx context.Context, erasureID int64) error {
+	erasure, getError := cachedRepository.ErasureRepository.Get(ctx, erasureID)
+	if getError != nil {
+		return getError
+	}
+	cachedRepository.cache.invalidate(ctx, patientResourceType, erasure.PatientID)
+	return cachedRepository.ErasureRepository.RestorePatientRecords(ctx, erasureID)
+}
@@ -0,0 +1,143 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// movingErasureStore moves observations between the counting repository and a hold kept in memory
+type movingErasureStore struct {
+	repository.ObservationErasureStore
+	observations *countingObservationRepository
+	held         []*models.Observation
+}
+
+// HoldForErasure moves the patient's observations into the hold
+func (store *movingErasureStore) HoldForErasure(ctx context.Context, erasureID int64, patientID string) ([]*models.Observation, error) {
+	moved := []*models.Observation{}
+	for observationID, observation := range store.observations.observations {
+		if observation.PatientID == patientID {
+			moved = append(moved, observation)
+			delete(store.observations.observations, observationID)
+		}
+	}
+	store.held = append(store.held, moved...)
+	return moved, nil
+}
+
+// RestoreErasure moves the held observations back
+func (store *movingErasureStore) RestoreErasure(ctx context.Context, erasureID int64) ([]*models.Observation, error) {
+	restored := store.held
+	for _, observation := range restored {
+		store.observations.observations[observation.ID] = observation
+	}
+	store.held = nil
+	return restored, nil
+}
+
+// TestObservationErasureStore_HoldAndRestore verifies held observations and counts stop being served from the
+// cache, and restored ones are read again
+func TestObservationErasureStore_HoldAndRestore(t *testing.T) {
+	repositoryCache, redisServer := newTestCache(t)
+	value := 72.0
+	inner := &countingObservationRepository{observations: map[string]*models.Observation{
+		"o1": {ID: "o1", PatientID: "p1", Code: "8867-4", ValueQuantity: &value},
+	}}
+	cachedRepository := NewObservationRepository(inner, repositoryCache)
+	erasureStore := NewObservationErasureStore(&movingErasureStore{observations: inner}, repositoryCache)
+	ctx := context.Background()
+	patientSearch := &models.ObservationSearchParams{PatientID: "p1"}
+
+	cachedRepository.GetByID(ctx, "o1")
+	cachedRepository.Count(ctx, patientSearch)
+	if held, _ := erasureStore.HoldForErasure(ctx, 1, "p1"); len(held) != 1 {
+		t.Fatalf("Expected one held observation, got %d", len(held))
+	}
+	if _, getError := cachedRepository.GetByID(ctx, "o1"); getError != repository.ErrObservationNotFound {
+		t.Errorf("Expected the held observation not found, got %v", getError)
+	}
+	if count, _ := cachedRepository.Count(ctx, patientSearch); count != 0 {
+		t.Errorf("Expected the count to drop after the hold, got %d", count)
+	}
+
+	// A count cached after the write hold must still be replaced by the restore
+	redisServer.FastForward(DefaultPolicy().WriteHold)
+	cachedRepository.Count(ctx, patientSearch)
+	erasureStore.RestoreErasure(ctx, 1)
+	if observation, getError := cachedRepository.GetByID(ctx, "o1"); getError != nil || observation.ID != "o1" {
+		t.Errorf("Expected the restored observation, got %+v (%v)", observation, getError)
+	}
+	if count, _ := cachedRepository.Count(ctx, patientSearch); count != 1 {
+		t.Errorf("Expected the count to return after the restore, got %d", count)
+	}
+}
+
+// restoringErasureRepository puts an erasure's patient back into the counting repository
+type restoringErasureRepository struct {
+	repository.ErasureRepository
+	patients *countingPatientRepository
+	held     *models.Patient
+}
+
+// Get returns an erasure of the held patient
+func (erasureRepository *restoringErasureRepository) Get(ctx context.Context, erasureID int64) (*models.PatientErasure, error) {
+	return &models.PatientErasure{ID: erasureID, PatientID: erasureRepository.held.ID}, nil
+}
+
+// RestorePatientRecords puts the held patient back
+func (erasureRepository *restoringErasureRepository) RestorePatientRecords(ctx context.Context, erasureID int64) error {
+	erasureRepository.patients.patients[erasureRepository.held.ID] = erasureRepository.held
+	return nil
+}
+
+// HoldPatientRecords removes the patient, keeping it as the held patient
+func (erasureRepository *restoringErasureRepository) HoldPatientRecords(ctx context.Context, erasureID int64, patientID string) (int64, error) {
+	erasureRepository.held = erasureRepository.patients.patients[patientID]
+	delete(erasureRepository.patients.patients, patientID)
+	return 1, nil
+}
+
+// TestErasureRepository_HoldPatientRecords verifies a held patient stops being served from the cache
+func TestErasureRepository_HoldPatientRecords(t *testing.T) {
+	repositoryCache, _ := newTestCache(t)
+	inner := &countingPatientRepository{patients: map[string]*models.Patient{"p1": {ID: "p1", FamilyName: "Smith"}}}
+	cachedRepository := NewPatientRepository(inner, repositoryCache)
+	erasureRepository := NewErasureRepository(&restoringErasureRepository{patients: inner}, repositoryCache)
+	ctx := context.Background()
+
+	cachedRepository.GetByID(ctx, "p1")
+	if _, holdError := erasureRepository.HoldPatientRecords(ctx, 1, "p1"); holdError != nil {
+		t.Fatalf("Unexpected hold error: %v", holdError)
+	}
+	if _, getError := cachedRepository.GetByID(ctx, "p1"); getError != sql.ErrNoRows {
+		t.Errorf("Expected the held patient not found, got %v", getError)
+	}
+}
+
+// TestErasureRepository_RestorePatientRecords verifies a restored patient is found by searches cached while it was held
+func TestErasureRepository_RestorePatientRecords(t *testing.T) {
+	repositoryCache, _ := newTestCache(t)
+	inner := &countingPatientRepository{patients: map[string]*models.Patient{}}
+	cachedRepository := NewPatientRepository(inner, repositoryCache)
+	erasureRepository := NewErasureRepository(&restoringErasureRepository{patients: inner, held: &models.Patient{ID: "p1", FamilyName: "Smith"}}, repositoryCache)
+	ctx := context.Background()
+	allPatients := &models.PatientSearchParams{}
+
+	if _, getError := cachedRepository.GetByID(ctx, "p1"); getError != sql.ErrNoRows {
+		t.Fatalf("Expected the held patient not found, got %v", getError)
+	}
+	if patients, _ := cachedRepository.Search(ctx, allPatients); len(patients) != 0 {
+		t.Fatalf("Expected no patients while held, got %d", len(patients))
+	}
+
+	if restoreError := erasureRepository.RestorePatientRecords(ctx, 1); restoreError != nil {
+		t.Fatalf("Unexpected restore error: %v", restoreError)
+	}
+	if patients, _ := cachedRepository.Search(ctx, allPatients); len(patients) != 1 || inner.searches != 2 {
+		t.Errorf("Expected the restored patient found by a new search, got %d after %d searches", len(patients), inner.searches)
+	}
+}
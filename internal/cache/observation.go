@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// observationResourceType names observations in cache keys
+const observationResourceType = "observation"
+
+// ObservationRepository caches observation reads by ID, searches, and search counts in front of another repository,
+// invalidating them on every write made through it
+// Listings and other reads go straight to the wrapped repository
+type ObservationRepository struct {
+	repository.ObservationRepository
+	cache *Cache
+}
+
+// NewObservationRepository wraps observationRepository with the cache
+func NewObservationRepository(observationRepository repository.ObservationRepository, cache *Cache) *ObservationRepository {
+	return &ObservationRepository{
+		ObservationRepository: observationRepository,
+		cache:                 cache,
+	}
+}
+
+// GetByID returns the cached observation, or reads and caches it; missing observations are not cached
+func (cachedRepository *ObservationRepository) GetByID(ctx context.Context, observationID string) (*models.Observation, error) {
+	return readThrough(ctx, cachedRepository.cache, resourceKey(observationResourceType, observationID), cachedRepository.cache.policy.TTL, func() (*models.Observation, error) {
+		return cachedRepository.ObservationRepository.GetByID(ctx, observationID)
+	})
+}
+
+// Search returns the cached page of matching observations, or searches and caches it
+func (cachedRepository *ObservationRepository) Search(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*models.Observation, error) {
+	searchKey := cachedRepository.cache.searchKey(ctx, observationResourceType, searchKind, searchParams)
+	return readThrough(ctx, cachedRepository.cache, searchKey, cachedRepository.cache.policy.SearchTTL, func() ([]*models.Observation, error) {
+		return cachedRepository.ObservationRepository.Search(ctx, searchParams)
+	})
+}
+
+// Count returns the cached number of matching observations, or counts and caches it
+func (cachedRepository *ObservationRepository) Count(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error) {
+	countKey := cachedRepository.cache.searchKey(ctx, observationResourceType, countKind, searchParams)
+	return readThrough(ctx, cachedRepository.cache, countKey, cachedRepository.cache.policy.SearchTTL, func() (int, error) {
+		return cachedRepository.ObservationRepository.Count(ctx, searchParams)
+	})
+}
+
+// Create invalidates cached observation searches and creates the observation
+func (cachedRepository *ObservationRepository) Create(ctx context.Context, observation *models.Observation) (*models.Observation, error) {
+	cachedRepository.cache.invalidate(ctx, observationResourceType)
+	return cachedRepository.ObservationRepository.Create(ctx, observation)
+}
+
+// Update invalidates the cached observation and observation searches and updates the observation
+func (cachedRepository *ObservationRepository) Update(ctx context.Context, observation *models.Observation) (*models.Observation, error) {
+	cachedRepository.cache.invalidate(ctx, observationResourceType, observation.ID)
+	return cachedRepository.ObservationRepository.Update(ctx, observation)
+}
+
+// Delete invalidates the cached observation and observation searches and deletes the observation
+func (cachedRepository *ObservationRepository) Delete(ctx context.Context, observationID string) error {
+	cachedRepository.cache.invalidate(ctx, observationResourceType, observationID)
+	return cachedRepository.ObservationRepository.Delete(ctx, observationID)
+}
+
+// UpdateTags invalidates the cached observation and observation searches, since both carry tags, and changes the tags
+func (cachedRepository *ObservationRepository) UpdateTags(ctx context.Context, observationID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	cachedRepository.cache.invalidate(ctx, observationResourceType, observationID)
+	return cachedRepository.ObservationRepository.UpdateTags(ctx, observationID, change)
+}
+
+// ObservationQuarantineStore invalidates cached observations, and observation searches, that reconciliation
+// quarantines
+type ObservationQuarantineStore struct {
+	repository.ObservationQuarantineStore
+	cache *Cache
+}
+
+// NewObservationQuarantineStore wraps quarantineStore with the cache
+func NewObservationQuarantineStore(quarantineStore repository.ObservationQuarantineStore, cache *Cache) *ObservationQuarantineStore {
+	return &ObservationQuarantineStore{
+		ObservationQuarantineStore: quarantineStore,
+		cache:                      cache,
+	}
+}
+
+// QuarantineByPatient invalidates observation searches, quarantines the observations, then invalidates each one moved
+func (cachedStore *ObservationQuarantineStore) QuarantineByPatient(ctx context.Context, patientID string) ([]string, error) {
+	cachedStore.cache.invalidate(ctx, observationResourceType)
+	quarantinedIDs, quarantineError := cachedStore.ObservationQuarantineStore.QuarantineByPatient(ctx, patientID)
+	cachedStore.cache.invalidate(ctx, observationResourceType, quarantinedIDs...)
+	return quarantinedIDs, quarantineError
+}
@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// countingObservationRepository keeps observations in memory and counts the reads and counts that reach it
+type countingObservationRepository struct {
+	repository.ObservationRepository
+	observations map[string]*models.Observation
+	reads        int
+	counts       int
+}
+
+// GetByID returns the stored observation, or ErrObservationNotFound
+func (inner *countingObservationRepository) GetByID(ctx context.Context, observationID string) (*models.Observation, error) {
+	inner.reads++
+	observation, exists := inner.observations[observationID]
+	if !exists {
+		return nil, repository.ErrObservationNotFound
+	}
+	return observation, nil
+}
+
+// Count returns how many observations are stored
+func (inner *countingObservationRepository) Count(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error) {
+	inner.counts++
+	return len(inner.observations), nil
+}
+
+// Delete removes the stored observation
+func (inner *countingObservationRepository) Delete(ctx context.Context, observationID string) error {
+	delete(inner.observations, observationID)
+	return nil
+}
+
+// TestObservationRepository_Delete verifies a delete invalidates the cached observation and search counts
+func TestObservationRepository_Delete(t *testing.T) {
+	repositoryCache, _ := newTestCache(t)
+	value := 72.0
+	inner := &countingObservationRepository{observations: map[string]*models.Observation{
+		"o1": {ID: "o1", PatientID: "p1", Code: "8867-4", ValueQuantity: &value},
+	}}
+	cachedRepository := NewObservationRepository(inner, repositoryCache)
+	ctx := context.Background()
+	patientSearch := &models.ObservationSearchParams{PatientID: "p1"}
+
+	cachedRepository.GetByID(ctx, "o1")
+	observation, _ := cachedRepository.GetByID(ctx, "o1")
+	if inner.reads != 1 || observation.ValueQuantity == nil || *observation.ValueQuantity != 72 {
+		t.Errorf("Expected the cached observation with its value, got %+v after %d reads", observation, inner.reads)
+	}
+	cachedRepository.Count(ctx, patientSearch)
+	if count, _ := cachedRepository.Count(ctx, patientSearch); count != 1 || inner.counts != 1 {
+		t.Errorf("Expected a cached count of 1, got %d after %d counts", count, inner.counts)
+	}
+
+	cachedRepository.Delete(ctx, "o1")
+	if _, getError := cachedRepository.GetByID(ctx, "o1"); getError != repository.ErrObservationNotFound {
+		t.Errorf("Expected the deleted observation not found, got %v", getError)
+	}
+	if count, _ := cachedRepository.Count(ctx, patientSearch); count != 0 {
+		t.Errorf("Expected the count to drop after the delete, got %d", count)
+	}
+}
+
+// quarantiningStore removes a patient's observations from the counting repository
+type quarantiningStore struct {
+	repository.ObservationQuarantineStore
+	observations *countingObservationRepository
+}
+
+// QuarantineByPatient removes the patient's observations and returns their IDs
+func (store *quarantiningStore) QuarantineByPatient(ctx context.Context, patientID string) ([]string, error) {
+	quarantinedIDs := []string{}
+	for observationID, observation := range store.observations.observations {
+		if observation.PatientID == patientID {
+			quarantinedIDs = append(quarantinedIDs, observationID)
+			delete(store.observations.observations, observationID)
+		}
+	}
+	return quarantinedIDs, nil
+}
+
+// TestObservationQuarantineStore_QuarantineByPatient verifies quarantined observations and counts stop being
+// served from the cache
+func TestObservationQuarantineStore_QuarantineByPatient(t *testing.T) {
+	repositoryCache, _ := newTestCache(t)
+	inner := &countingObservationRepository{observations: map[string]*models.Observation{
+		"o1": {ID: "o1", PatientID: "orphan", Code: "8867-4"},
+	}}
+	cachedRepository := NewObservationRepository(inner, repositoryCache)
+	quarantineStore := NewObservationQuarantineStore(&quarantiningStore{observations: inner}, repositoryCache)
+	ctx := context.Background()
+	orphanSearch := &models.ObservationSearchParams{PatientID: "orphan"}
+
+	cachedRepository.GetByID(ctx, "o1")
+	cachedRepository.Count(ctx, orphanSearch)
+	if quarantinedIDs, _ := quarantineStore.QuarantineByPatient(ctx, "orphan"); len(quarantinedIDs) != 1 {
+		t.Fatalf("Expected one quarantined observation, got %v", quarantinedIDs)
+	}
+	if _, getError := cachedRepository.GetByID(ctx, "o1"); getError != repository.ErrObservationNotFound {
+		t.Errorf("Expected the quarantined observation not found, got %v", getError)
+	}
+	if count, _ := cachedRepository.Count(ctx, orphanSearch); count != 0 {
+		t.Errorf("Expected the count to drop after the quarantine, got %d", count)
+	}
+}
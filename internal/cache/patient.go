@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// patientResourceType names patients in cache keys
+const patientResourceType = "patient"
+
+// PatientRepository caches patient reads by ID, searches, and search counts in front of another repository,
+// invalidating them on every write made through it
+// Listings and other reads go straight to the wrapped repository
+type PatientRepository struct {
+	repository.PatientRepository
+	cache *Cache
+}
+
+// NewPatientRepository wraps patientRepository with the cache
+func NewPatientRepository(patientRepository repository.PatientRepository, cache *Cache) *PatientRepository {
+	return &PatientRepository{
+		PatientRepository: patientRepository,
+		cache:             cache,
+	}
+}
+
+// GetByID returns the cached patient, or reads and caches it; missing patients are not cached
+func (cachedRepository *PatientRepository) GetByID(ctx context.Context, patientID string) (*models.Patient, error) {
+	return readThrough(ctx, cachedRepository.cache, resourceKey(patientResourceType, patientID), cachedRepository.cache.policy.TTL, func() (*models.Patient, error) {
+		return cachedRepository.PatientRepository.GetByID(ctx, patientID)
+	})
+}
+
+// Search returns the cached page of matching patients, or searches and caches it
+func (cachedRepository *PatientRepository) Search(ctx context.Context, searchParams *models.PatientSearchParams) ([]*models.Patient, error) {
+	searchKey := cachedRepository.cache.searchKey(ctx, patientResourceType, searchKind, searchParams)
+	return readThrough(ctx, cachedRepository.cache, searchKey, cachedRepository.cache.policy.SearchTTL, func() ([]*models.Patient, error) {
+		return cachedRepository.PatientRepository.Search(ctx, searchParams)
+	})
+}
+
+// Count returns the cached number of matching patients, or counts and caches it
+func (cachedRepository *PatientRepository) Count(ctx context.Context, searchParams *models.PatientSearchParams) (int, error) {
+	countKey := cachedRepository.cache.searchKey(ctx, patientResourceType, countKind, searchParams)
+	return readThrough(ctx, cachedRepository.cache, countKey, cachedRepository.cache.policy.SearchTTL, func() (int, error) {
+		return cachedRepository.PatientRepository.Count(ctx, searchParams)
+	})
+}
+
+// Create invalidates cached patient searches and creates the patient
+func (cachedRepository *PatientRepository) Create(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
+	cachedRepository.cache.invalidate(ctx, patientResourceType)
+	return cachedRepository.PatientRepository.Create(ctx, patient)
+}
+
+// Update invalidates the cached patient and patient searches and updates the patient
+func (cachedRepository *PatientRepository) Update(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
+	cachedRepository.cache.invalidate(ctx, patientResourceType, patient.ID)
+	return cachedRepository.PatientRepository.Update(ctx, patient)
+}
+
+// Delete invalidates the cached patient and patient searches and deletes the patient
+func (cachedRepository *PatientRepository) Delete(ctx context.Context, patientID string) error {
+	cachedRepository.cache.invalidate(ctx, patientResourceType, patientID)
+	return cachedRepository.PatientRepository.Delete(ctx, patientID)
+}
+
+// UpdateTags invalidates the cached patient and patient searches, since both carry tags, and changes the tags
+func (cachedRepository *PatientRepository) UpdateTags(ctx context.Context, patientID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	cachedRepository.cache.invalidate(ctx, patientResourceType, patientID)
+	return cachedRepository.PatientRepository.UpdateTags(ctx, patientID, change)
+}
+
+// IdentifierRekeyRepository invalidates cached patients, and patient searches, whose identifiers a re-key job
+// rewrites or restores
+type IdentifierRekeyRepository struct {
+	repository.IdentifierRekeyRepository
+	cache *Cache
+}
+
+// NewIdentifierRekeyRepository wraps rekeyRepository with the cache
+func NewIdentifierRekeyRepository(rekeyRepository repository.IdentifierRekeyRepository, cache *Cache) *IdentifierRekeyRepository {
+	return &IdentifierRekeyRepository{
+		IdentifierRekeyRepository: rekeyRepository,
+		cache:                     cache,
+	}
+}
+
+// ApplyBatch invalidates patient searches, applies the batch, then invalidates each patient it rewrote
+// The rewritten patients are only known once the batch's transaction has committed, so a read that raced it
+// cannot cache the old identifiers over the invalidation
+func (cachedRepository *IdentifierRekeyRepository) ApplyBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	cachedRepository.cache.invalidate(ctx, patientResourceType)
+	records, batchError := cachedRepository.IdentifierRekeyRepository.ApplyBatch(ctx, jobID, limit)
+	cachedRepository.cache.invalidate(ctx, patientResourceType, rekeyedPatientIDs(records)...)
+	return records, batchError
+}
+
+// RollbackBatch invalidates patient searches, reverts the batch, then invalidates each patient it restored
+func (cachedRepository *IdentifierRekeyRepository) RollbackBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	cachedRepository.cache.invalidate(ctx, patientResourceType)
+	records, batchError := cachedRepository.IdentifierRekeyRepository.RollbackBatch(ctx, jobID, limit)
+	cachedRepository.cache.invalidate(ctx, patientResourceType, rekeyedPatientIDs(records)...)
+	return records, batchError
+}
+
+// rekeyedPatientIDs returns the patients named by re-key records; skipped mappings name none
+func rekeyedPatientIDs(records []*models.RekeyRecord) []string {
+	patientIDs := []string{}
+	for _, record := range records {
+		if record.PatientID != "" {
+			patientIDs = append(patientIDs, record.PatientID)
+		}
+	}
+	return patientIDs
+}
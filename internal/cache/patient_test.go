@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// countingPatientRepository keeps patients in memory and counts the reads that reach it
+type countingPatientRepository struct {
+	repository.PatientRepository
+	patients map[string]*models.Patient
+	reads    int
+	searches int
+}
+
+// GetByID returns the stored patient, or sql.ErrNoRows
+func (inner *countingPatientRepository) GetByID(ctx context.Context, patientID string) (*models.Patient, error) {
+	inner.reads++
+	patient, exists := inner.patients[patientID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	copied := *patient
+	return &copied, nil
+}
+
+// Search returns every stored patient with the searched gender
+func (inner *countingPatientRepository) Search(ctx context.Context, searchParams *models.PatientSearchParams) ([]*models.Patient, error) {
+	inner.searches++
+	patients := []*models.Patient{}
+	for _, patient := range inner.patients {
+		if len(searchParams.Gender) == 0 || patient.Gender == searchParams.Gender[0][0] {
+			copied := *patient
+			patients = append(patients, &copied)
+		}
+	}
+	return patients, nil
+}
+
+// Create stores the patient under its family name
+func (inner *countingPatientRepository) Create(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
+	patient.ID = patient.FamilyName
+	inner.patients[patient.ID] = patient
+	return patient, nil
+}
+
+// Update replaces the stored patient
+func (inner *countingPatientRepository) Update(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
+	inner.patients[patient.ID] = patient
+	return patient, nil
+}
+
+// TestPatientRepository_GetByID verifies reads are cached, writes invalidate them, and caching resumes after the write hold
+func TestPatientRepository_GetByID(t *testing.T) {
+	repositoryCache, redisServer := newTestCache(t)
+	inner := &countingPatientRepository{patients: map[string]*models.Patient{"p1": {ID: "p1", FamilyName: "Smith"}}}
+	cachedRepository := NewPatientRepository(inner, repositoryCache)
+	ctx := context.Background()
+
+	for range 2 {
+		patient, getError := cachedRepository.GetByID(ctx, "p1")
+		if getError != nil || patient.FamilyName != "Smith" {
+			t.Fatalf("Expected Smith, got %+v (%v)", patient, getError)
+		}
+	}
+	if inner.reads != 1 {
+		t.Errorf("Expected the second read served from the cache, got %d repository reads", inner.reads)
+	}
+
+	cachedRepository.Update(ctx, &models.Patient{ID: "p1", FamilyName: "Jones"})
+	for range 2 {
+		patient, _ := cachedRepository.GetByID(ctx, "p1")
+		if patient.FamilyName != "Jones" {
+			t.Errorf("Expected the updated name, got %q", patient.FamilyName)
+		}
+	}
+	if inner.reads != 3 {
+		t.Errorf("Expected reads during the write hold to skip the cache, got %d repository reads", inner.reads)
+	}
+
+	redisServer.FastForward(DefaultPolicy().WriteHold)
+	cachedRepository.GetByID(ctx, "p1")
+	cachedRepository.GetByID(ctx, "p1")
+	if inner.reads != 4 {
+		t.Errorf("Expected caching to resume after the write hold, got %d repository reads", inner.reads)
+	}
+}
+
+// TestPatientRepository_GetByID_NotFound verifies missing patients are not cached
+func TestPatientRepository_GetByID_NotFound(t *testing.T) {
+	repositoryCache, _ := newTestCache(t)
+	inner := &countingPatientRepository{patients: map[string]*models.Patient{}}
+	cachedRepository := NewPatientRepository(inner, repositoryCache)
+
+	cachedRepository.GetByID(context.Background(), "missing")
+	if _, getError := cachedRepository.GetByID(context.Background(), "missing"); getError != sql.ErrNoRows {
+		t.Errorf("Expected sql.ErrNoRows, got %v", getError)
+	}
+	if inner.reads != 2 {
+		t.Errorf("Expected both reads to reach the repository, got %d", inner.reads)
+	}
+}
+
+// TestPatientRepository_Search verifies searches are cached per criteria and a create makes them stale
+func TestPatientRepository_Search(t *testing.T) {
+	repositoryCache, redisServer := newTestCache(t)
+	inner := &countingPatientRepository{patients: map[string]*models.Patient{"p1": {ID: "p1", Gender: "female"}}}
+	cachedRepository := NewPatientRepository(inner, repositoryCache)
+	ctx := context.Background()
+	femaleSearch := &models.PatientSearchParams{Gender: models.ValueCriteria{{"female"}}, Limit: 10}
+
+	cachedRepository.Search(ctx, femaleSearch)
+	cachedRepository.Search(ctx, femaleSearch)
+	cachedRepository.Search(ctx, &models.PatientSearchParams{Gender: models.ValueCriteria{{"male"}}, Limit: 10})
+	if inner.searches != 2 {
+		t.Errorf("Expected one search per distinct criteria, got %d", inner.searches)
+	}
+
+	cachedRepository.Create(ctx, &models.Patient{FamilyName: "p2", Gender: "female"})
+	redisServer.FastForward(DefaultPolicy().WriteHold)
+	patients, _ := cachedRepository.Search(ctx, femaleSearch)
+	if len(patients) != 2 {
+		t.Errorf("Expected the created patient found after the create, got %d patients", len(patients))
+	}
+}
+
+// rewritingRekeyRepository changes the family name of the patients a batch names, standing in for identifier rewrites
+type rewritingRekeyRepository struct {
+	repository.IdentifierRekeyRepository
+	patients *countingPatientRepository
+	batch    []*models.RekeyRecord
+}
+
+// ApplyBatch renames the batch's patients
+func (rekeyRepository *rewritingRekeyRepository) ApplyBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	for _, record := range rekeyRepository.batch {
+		if patient, exists := rekeyRepository.patients.patients[record.PatientID]; exists {
+			patient.FamilyName = "Rekeyed"
+		}
+	}
+	return rekeyRepository.batch, nil
+}
+
+// TestIdentifierRekeyRepository_ApplyBatch verifies the patients a batch rewrites, and patient searches, are
+// read again afterwards
+func TestIdentifierRekeyRepository_ApplyBatch(t *testing.T) {
+	repositoryCache, redisServer := newTestCache(t)
+	inner := &countingPatientRepository{patients: map[string]*models.Patient{"p1": {ID: "p1", FamilyName: "Smith", Gender: "female"}}}
+	cachedRepository := NewPatientRepository(inner, repositoryCache)
+	rekeyRepository := NewIdentifierRekeyRepository(&rewritingRekeyRepository{patients: inner, batch: []*models.RekeyRecord{
+		{PatientID: "p1", Action: models.RekeyActionRekeyed},
+		{Action: models.RekeyActionSkipped},
+	}}, repositoryCache)
+	ctx := context.Background()
+	femaleSearch := &models.PatientSearchParams{Gender: models.ValueCriteria{{"female"}}}
+
+	cachedRepository.GetByID(ctx, "p1")
+	cachedRepository.Search(ctx, femaleSearch)
+	rekeyRepository.ApplyBatch(ctx, 1, 100)
+
+	if patient, _ := cachedRepository.GetByID(ctx, "p1"); patient.FamilyName != "Rekeyed" {
+		t.Errorf("Expected the rewritten patient, got %q", patient.FamilyName)
+	}
+	redisServer.FastForward(DefaultPolicy().WriteHold)
+	if patients, _ := cachedRepository.Search(ctx, femaleSearch); len(patients) != 1 || patients[0].FamilyName != "Rekeyed" || inner.searches != 2 {
+		t.Errorf("Expected a new search to find the rewritten patient, got %+v after %d searches", patients, inner.searches)
+	}
+}
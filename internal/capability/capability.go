@@ -0,0 +1,265 @@
+package capability
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// operationDefinitionBase is the canonical base of the operations this server defines itself
+const operationDefinitionBase = "https://github.com/nathannewyen/fhir-health-interop/OperationDefinition/"
+
+// SearchParameter describes one query parameter a resource search accepts
+type SearchParameter struct {
+	Name string
+	Type fhir.SearchParamType
+
+	// Repeatable parameters may be given more than once
+	Repeatable bool
+
+	Documentation string
+}
+
+// Operation describes one $operation exposed on a resource type or instance
+type Operation struct {
+	// Name is the operation name without the leading $
+	Name       string
+	Definition string
+
+	// Method is the HTTP method the operation is invoked with
+	Method string
+
+	// Instance operations are invoked on /{type}/{id}/$name, others on /{type}/$name
+	Instance bool
+
+	Documentation string
+}
+
+// Resource describes what the server supports for one resource type
+type Resource struct {
+	Type             fhir.ResourceType
+	Interactions     []fhir.TypeRestfulInteraction
+	SearchParameters []SearchParameter
+	Includes         []string
+	RevIncludes      []string
+	Operations       []Operation
+}
+
+// Name returns the resource type name, e.g. "Patient"
+func (resource Resource) Name() string {
+	return resource.Type.Code()
+}
+
+// Supports reports whether the resource type supports the interaction
+func (resource Resource) Supports(interaction fhir.TypeRestfulInteraction) bool {
+	return slices.Contains(resource.Interactions, interaction)
+}
+
+// crudInteractions are the interactions every stored resource type supports
+var crudInteractions = []fhir.TypeRestfulInteraction{
+	fhir.TypeRestfulInteractionCreate,
+	fhir.TypeRestfulInteractionRead,
+	fhir.TypeRestfulInteractionUpdate,
+	fhir.TypeRestfulInteractionDelete,
+	fhir.TypeRestfulInteractionSearchType,
+}
+
+// versionedInteractions add version reads and instance history to the CRUD interactions, for the resource
+// types whose versions the change log keeps
+var versionedInteractions = append(slices.Clone(crudInteractions),
+	fhir.TypeRestfulInteractionVread,
+	fhir.TypeRestfulInteractionHistoryInstance,
+)
+
+// searchParameterDefinitions gives the type and meaning of every parameter the search parsers accept
+// _include and _revinclude are not listed: they are advertised through Resource.Includes and Resource.RevIncludes instead
+var searchParameterDefinitions = map[string]SearchParameter{
+	"name":            {Type: fhir.SearchParamTypeString, Documentation: "Any part of the given or family name; Patient also accepts :contains and :exact"},
+	"family":          {Type: fhir.SearchParamTypeString, Documentation: "Family name; Patient also accepts :contains and :exact"},
+	"given":           {Type: fhir.SearchParamTypeString, Documentation: "Given name; Patient also accepts :contains and :exact"},
+	"gender":          {Type: fhir.SearchParamTypeToken, Documentation: "Administrative gender"},
+	"birthdate":       {Type: fhir.SearchParamTypeDate, Documentation: "Birth date, optionally prefixed with ge, gt, le, or lt"},
+	"active":          {Type: fhir.SearchParamTypeToken, Documentation: "Whether the record is active (true or false)"},
+	"identifier":      {Type: fhir.SearchParamTypeToken, Documentation: "Identifier as system|value, value, |value, or system|; Patient searches also match site aliases of the system"},
+	"patient":         {Type: fhir.SearchParamTypeReference, Documentation: "Subject patient ID or Patient/{id} reference"},
+	"code":            {Type: fhir.SearchParamTypeToken, Documentation: "Observation code; Condition or DiagnosticReport code as code, system|code, |code, or system|"},
+	"category":        {Type: fhir.SearchParamTypeToken, Documentation: "Observation, AllergyIntolerance, or DiagnosticReport category"},
+	"encounter":       {Type: fhir.SearchParamTypeReference, Documentation: "Encounter ID or Encounter/{id} reference"},
+	"status":          {Type: fhir.SearchParamTypeToken, Documentation: "Observation, Encounter, MedicationRequest, Immunization, or Subscription status"},
+	"date":            {Type: fhir.SearchParamTypeDate, Documentation: "Observation or DiagnosticReport effective date, Encounter period, Immunization occurrence, or AuditEvent recording time, prefixed with ge, gt, le, or lt"},
+	"value-quantity":  {Type: fhir.SearchParamTypeQuantity, Documentation: "Observation value as [prefix]number or [prefix]number|system|unit with prefix eq, ne, gt, lt, ge, le, or ap; the unit is matched but not the system"},
+	"clinical-status": {Type: fhir.SearchParamTypeToken, Documentation: "Condition or AllergyIntolerance clinical status (active, inactive, resolved; Condition also recurrence, relapse, remission)"},
+	"onset-date":      {Type: fhir.SearchParamTypeDate, Documentation: "Condition onset date prefixed with ge, gt, le, or lt"},
+	"intent":          {Type: fhir.SearchParamTypeToken, Documentation: "MedicationRequest intent (proposal, plan, order, ...)"},
+	"criticality":     {Type: fhir.SearchParamTypeToken, Documentation: "AllergyIntolerance criticality (low, high, unable-to-assess)"},
+	"vaccine-code":    {Type: fhir.SearchParamTypeToken, Documentation: "Immunization vaccine code as code, system|code, |code, or system|"},
+	"lot-number":      {Type: fhir.SearchParamTypeString, Documentation: "Immunization vaccine lot number, matched exactly"},
+	"authoredon":      {Type: fhir.SearchParamTypeDate, Documentation: "MedicationRequest authored date prefixed with ge, gt, le, or lt"},
+	"specialty":       {Type: fhir.SearchParamTypeToken, Documentation: "Practitioner qualification code as code, system|code, |code, or system|"},
+	"agent":           {Type: fhir.SearchParamTypeToken, Documentation: "AuditEvent requesting principal, such as oauth:{client_id}"},
+	"entity":          {Type: fhir.SearchParamTypeReference, Documentation: "AuditEvent accessed resource as {type}/{id} or a bare ID"},
+	"entity-type":     {Type: fhir.SearchParamTypeToken, Documentation: "AuditEvent accessed resource type"},
+	"action":          {Type: fhir.SearchParamTypeToken, Documentation: "AuditEvent action (C, R, U, D, or E)"},
+	"outcome":         {Type: fhir.SearchParamTypeToken, Documentation: "AuditEvent outcome (0 success, 4 refused or rejected, 8 server failure)"},
+	"_tag":            {Type: fhir.SearchParamTypeToken, Repeatable: true, Documentation: "meta.tag as code, system|code, |code, or system|; repeats must all match"},
+	"_elements":       {Type: fhir.SearchParamTypeSpecial, Repeatable: true, Documentation: "Elements to return; the rest are left out"},
+	"_sort":           {Type: fhir.SearchParamTypeSpecial, Documentation: "Sort field, prefixed with - for descending"},
+	"_count":          {Type: fhir.SearchParamTypeNumber, Documentation: "Page size (at most 100)"},
+	"_offset":         {Type: fhir.SearchParamTypeNumber, Documentation: "Number of matches to skip"},
+	"_cursor":         {Type: fhir.SearchParamTypeSpecial, Documentation: "Continuation token from a previous page's next link; cannot be combined with _offset"},
+	"_stream":         {Type: fhir.SearchParamTypeSpecial, Documentation: "true to receive every match, up to 100000, in one JSON Bundle written as the matches are read"},
+}
+
+// metaOperations are the meta.tag operations every stored resource type supports
+var metaOperations = []Operation{
+	{Name: "meta", Definition: "http://hl7.org/fhir/OperationDefinition/Resource-meta", Method: http.MethodGet, Instance: true, Documentation: "The resource's meta (tags)"},
+	{Name: "meta-add", Definition: "http://hl7.org/fhir/OperationDefinition/Resource-meta-add", Method: http.MethodPost, Instance: true, Documentation: "Add meta.tag values without creating a new version"},
+	{Name: "meta-delete", Definition: "http://hl7.org/fhir/OperationDefinition/Resource-meta-delete", Method: http.MethodPost, Instance: true, Documentation: "Remove meta.tag values without creating a new version"},
+}
+
+// Resources returns the resource types the server supports, the single definition that both the
+// CapabilityStatement and the generated client SDKs are built from
+func Resources() []Resource {
+	return []Resource{
+		{
+			Type:             fhir.ResourceTypePatient,
+			Interactions:     versionedInteractions,
+			SearchParameters: searchParameters(utils.PatientSearchParameters),
+			RevIncludes:      utils.PatientRevIncludes,
+			Operations: append([]Operation{
+				{Name: "everything", Definition: "http://hl7.org/fhir/OperationDefinition/Patient-everything", Method: http.MethodGet, Instance: true, Documentation: "The patient and its compartment as a searchset Bundle, filtered by _type and _since"},
+				{Name: "snapshot", Definition: operationDefinitionBase + "Patient-snapshot", Method: http.MethodGet, Instance: true, Documentation: "The patient compartment as it was at _at"},
+				{Name: "health-export", Definition: operationDefinitionBase + "Patient-health-export", Method: http.MethodGet, Instance: true, Documentation: "The patient's vital signs as Apple HealthKit or Google Fit JSON"},
+				{Name: "avatar", Definition: operationDefinitionBase + "Patient-avatar", Method: http.MethodGet, Instance: true, Documentation: "A generated identicon PNG for the patient"},
+				{Name: "export", Definition: "http://hl7.org/fhir/uv/bulkdata/OperationDefinition/patient-export", Method: http.MethodGet, Documentation: "Asynchronous Bulk Data export of every patient and their compartments as NDJSON files"},
+				{Name: "merge", Definition: "http://hl7.org/fhir/OperationDefinition/Patient-merge", Method: http.MethodPost, Documentation: "Merge a duplicate source-patient into a target-patient: its observations move to the target and it becomes inactive, replaced by the target"},
+				{Name: "match", Definition: "http://hl7.org/fhir/OperationDefinition/Patient-match", Method: http.MethodPost, Documentation: "Stored patients that may be the same person as a partial Patient, ranked by score with a match grade"},
+			}, metaOperations...),
+		},
+		{
+			Type:             fhir.ResourceTypeObservation,
+			Interactions:     versionedInteractions,
+			SearchParameters: searchParameters(utils.ObservationSearchParameters),
+			Includes:         utils.ObservationIncludes,
+			Operations: append([]Operation{
+				{Name: "daily-rollup", Definition: operationDefinitionBase + "Observation-daily-rollup", Method: http.MethodGet, Documentation: "Daily count, min, max, and average for a patient and code"},
+				{Name: "lastn", Definition: "http://hl7.org/fhir/OperationDefinition/Observation-lastn", Method: http.MethodGet, Documentation: "The patient's max most recent observations of each code, filtered by code, category, and date"},
+			}, metaOperations...),
+		},
+		{
+			Type:             fhir.ResourceTypePractitioner,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.PractitionerSearchParameters),
+		},
+		{
+			Type:             fhir.ResourceTypeEncounter,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.EncounterSearchParameters),
+		},
+		{
+			Type:             fhir.ResourceTypeCondition,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.ConditionSearchParameters),
+		},
+		{
+			Type:             fhir.ResourceTypeMedicationRequest,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.MedicationRequestSearchParameters),
+		},
+		{
+			Type:             fhir.ResourceTypeAllergyIntolerance,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.AllergyIntoleranceSearchParameters),
+		},
+		{
+			Type:             fhir.ResourceTypeDiagnosticReport,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.DiagnosticReportSearchParameters),
+		},
+		{
+			Type:             fhir.ResourceTypeImmunization,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.ImmunizationSearchParameters),
+		},
+		{
+			// Audit events are written by the server as requests are made, never through the API
+			Type:             fhir.ResourceTypeAuditEvent,
+			Interactions:     []fhir.TypeRestfulInteraction{fhir.TypeRestfulInteractionRead, fhir.TypeRestfulInteractionSearchType},
+			SearchParameters: searchParameters(utils.AuditEventSearchParameters),
+		},
+		{
+			Type:             fhir.ResourceTypeSubscription,
+			Interactions:     crudInteractions,
+			SearchParameters: searchParameters(utils.SubscriptionSearchParameters),
+		},
+	}
+}
+
+// searchParameters describes the named parameters, skipping _include, _revinclude, and any without a definition
+func searchParameters(names []string) []SearchParameter {
+	parameters := make([]SearchParameter, 0, len(names))
+	for _, name := range names {
+		definition, defined := searchParameterDefinitions[name]
+		if !defined {
+			continue
+		}
+		definition.Name = name
+		parameters = append(parameters, definition)
+	}
+	return parameters
+}
+
+// Statement builds the server's CapabilityStatement, dated publishedAt
+func Statement(resources []Resource, publishedAt time.Time) fhir.CapabilityStatement {
+	softwareName := "fhir-health-interop"
+	restResources := make([]fhir.CapabilityStatementRestResource, 0, len(resources))
+	for _, resource := range resources {
+		restResources = append(restResources, restResource(resource))
+	}
+
+	return fhir.CapabilityStatement{
+		Name:        &softwareName,
+		Status:      fhir.PublicationStatusActive,
+		Date:        publishedAt.UTC().Format(time.RFC3339),
+		Kind:        fhir.CapabilityStatementKindInstance,
+		Software:    &fhir.CapabilityStatementSoftware{Name: softwareName},
+		FhirVersion: fhir.FHIRVersion4_0_1,
+		Format:      []string{"application/fhir+json", "application/fhir+xml"},
+		Rest: []fhir.CapabilityStatementRest{{
+			Mode:     fhir.RestfulCapabilityModeServer,
+			Resource: restResources,
+		}},
+	}
+}
+
+// restResource converts one resource description to its CapabilityStatement entry
+func restResource(resource Resource) fhir.CapabilityStatementRestResource {
+	restEntry := fhir.CapabilityStatementRestResource{
+		Type:             resource.Type,
+		SearchInclude:    resource.Includes,
+		SearchRevInclude: resource.RevIncludes,
+	}
+	for _, interaction := range resource.Interactions {
+		restEntry.Interaction = append(restEntry.Interaction, fhir.CapabilityStatementRestResourceInteraction{Code: interaction})
+	}
+	for _, parameter := range resource.SearchParameters {
+		documentation := parameter.Documentation
+		restEntry.SearchParam = append(restEntry.SearchParam, fhir.CapabilityStatementRestResourceSearchParam{
+			Name:          parameter.Name,
+			Type:          parameter.Type,
+			Documentation: &documentation,
+		})
+	}
+	for _, operation := range resource.Operations {
+		documentation := operation.Documentation
+		restEntry.Operation = append(restEntry.Operation, fhir.CapabilityStatementRestResourceOperation{
+			Name:          operation.Name,
+			Definition:    operation.Definition,
+			Documentation: &documentation,
+		})
+	}
+	return restEntry
+}
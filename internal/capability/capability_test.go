@@ -0,0 +1,59 @@
+package capability
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestResources_DescribesEverySearchParameter verifies a parameter added to a search parser is not left out of the
+// CapabilityStatement and the generated clients
+func TestResources_DescribesEverySearchParameter(t *testing.T) {
+	parsedParameters := map[string][]string{
+		"Patient":            utils.PatientSearchParameters,
+		"Observation":        utils.ObservationSearchParameters,
+		"Practitioner":       utils.PractitionerSearchParameters,
+		"Encounter":          utils.EncounterSearchParameters,
+		"Condition":          utils.ConditionSearchParameters,
+		"MedicationRequest":  utils.MedicationRequestSearchParameters,
+		"AllergyIntolerance": utils.AllergyIntoleranceSearchParameters,
+		"DiagnosticReport":   utils.DiagnosticReportSearchParameters,
+		"Immunization":       utils.ImmunizationSearchParameters,
+		"AuditEvent":         utils.AuditEventSearchParameters,
+		"Subscription":       utils.SubscriptionSearchParameters,
+	}
+
+	for _, resource := range Resources() {
+		described := map[string]bool{}
+		for _, parameter := range resource.SearchParameters {
+			described[parameter.Name] = true
+		}
+		for _, parameterName := range parsedParameters[resource.Name()] {
+			if parameterName == "_include" || parameterName == "_revinclude" {
+				continue
+			}
+			if !described[parameterName] {
+				t.Errorf("%s search parameter %s has no entry in searchParameterDefinitions", resource.Name(), parameterName)
+			}
+		}
+	}
+}
+
+// TestStatement verifies the statement carries each resource's interactions and operations
+func TestStatement(t *testing.T) {
+	statement := Statement(Resources(), time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+
+	if statement.Date != "2026-03-01T12:00:00Z" || statement.FhirVersion != fhir.FHIRVersion4_0_1 {
+		t.Errorf("Unexpected statement header: %s %s", statement.Date, statement.FhirVersion.Code())
+	}
+
+	observationEntry := statement.Rest[0].Resource[1]
+	if observationEntry.Type != fhir.ResourceTypeObservation || len(observationEntry.Interaction) != 7 {
+		t.Fatalf("Expected Observation with 7 interactions, got %+v", observationEntry)
+	}
+	if observationEntry.Operation[0].Name != "daily-rollup" || observationEntry.Operation[0].Definition == "" {
+		t.Errorf("Expected $daily-rollup with a definition, got %+v", observationEntry.Operation[0])
+	}
+}
@@ -0,0 +1,109 @@
+package capability
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Route is one method and path pattern registered on the server's router, e.g. GET /fhir/Patient/{id}
+type Route struct {
+	Method  string
+	Pattern string
+}
+
+// interactionRoutes gives the route serving each type-level interaction; {type} stands for the resource type
+var interactionRoutes = map[fhir.TypeRestfulInteraction]Route{
+	fhir.TypeRestfulInteractionCreate:          {Method: http.MethodPost, Pattern: "/fhir/{type}"},
+	fhir.TypeRestfulInteractionRead:            {Method: http.MethodGet, Pattern: "/fhir/{type}/{id}"},
+	fhir.TypeRestfulInteractionUpdate:          {Method: http.MethodPut, Pattern: "/fhir/{type}/{id}"},
+	fhir.TypeRestfulInteractionDelete:          {Method: http.MethodDelete, Pattern: "/fhir/{type}/{id}"},
+	fhir.TypeRestfulInteractionSearchType:      {Method: http.MethodGet, Pattern: "/fhir/{type}"},
+	fhir.TypeRestfulInteractionVread:           {Method: http.MethodGet, Pattern: "/fhir/{type}/{id}/_history/{version}"},
+	fhir.TypeRestfulInteractionHistoryInstance: {Method: http.MethodGet, Pattern: "/fhir/{type}/{id}/_history"},
+}
+
+// InteractionRoute returns the route that serves interaction on the resource type
+func (resource Resource) InteractionRoute(interaction fhir.TypeRestfulInteraction) Route {
+	route := interactionRoutes[interaction]
+	route.Pattern = strings.Replace(route.Pattern, "{type}", resource.Name(), 1)
+	return route
+}
+
+// OperationRoute returns the route that serves operation on the resource type
+func (resource Resource) OperationRoute(operation Operation) Route {
+	if operation.Instance {
+		return Route{Method: operation.Method, Pattern: "/fhir/" + resource.Name() + "/{id}/$" + operation.Name}
+	}
+	return Route{Method: operation.Method, Pattern: "/fhir/" + resource.Name() + "/$" + operation.Name}
+}
+
+// routeSet indexes routes by method and pattern, ignoring method case and trailing slashes
+func routeSet(routes []Route) map[Route]bool {
+	registered := make(map[Route]bool, len(routes))
+	for _, route := range routes {
+		route.Method = strings.ToUpper(route.Method)
+		if route.Pattern != "/" {
+			route.Pattern = strings.TrimSuffix(route.Pattern, "/")
+		}
+		registered[route] = true
+	}
+	return registered
+}
+
+// Routed trims resources to what the router actually serves, so the CapabilityStatement cannot advertise
+// an interaction or operation whose route was never registered
+// Interactions and operations without a route are dropped, and so are resource types left with neither
+func Routed(resources []Resource, routes []Route) []Resource {
+	registered := routeSet(routes)
+
+	routedResources := make([]Resource, 0, len(resources))
+	for _, resource := range resources {
+		routedResource := resource
+		routedResource.Interactions = nil
+		routedResource.Operations = nil
+		for _, interaction := range resource.Interactions {
+			if registered[resource.InteractionRoute(interaction)] {
+				routedResource.Interactions = append(routedResource.Interactions, interaction)
+			}
+		}
+		for _, operation := range resource.Operations {
+			if registered[resource.OperationRoute(operation)] {
+				routedResource.Operations = append(routedResource.Operations, operation)
+			}
+		}
+
+		if len(routedResource.Interactions) == 0 && len(routedResource.Operations) == 0 {
+			continue
+		}
+		if !routedResource.Supports(fhir.TypeRestfulInteractionSearchType) {
+			routedResource.SearchParameters = nil
+			routedResource.Includes = nil
+			routedResource.RevIncludes = nil
+		}
+		routedResources = append(routedResources, routedResource)
+	}
+	return routedResources
+}
+
+// systemInteractionRoutes gives the route serving each system-level interaction
+var systemInteractionRoutes = map[fhir.SystemRestfulInteraction]Route{
+	fhir.SystemRestfulInteractionTransaction: {Method: http.MethodPost, Pattern: "/fhir"},
+	fhir.SystemRestfulInteractionBatch:       {Method: http.MethodPost, Pattern: "/fhir"},
+}
+
+// RoutedStatement builds the CapabilityStatement for what the router actually serves: the routed resources
+// and the system-level interactions, such as transaction and batch, whose route was registered
+func RoutedStatement(resources []Resource, routes []Route, publishedAt time.Time) fhir.CapabilityStatement {
+	statement := Statement(Routed(resources, routes), publishedAt)
+
+	registered := routeSet(routes)
+	for _, interaction := range []fhir.SystemRestfulInteraction{fhir.SystemRestfulInteractionTransaction, fhir.SystemRestfulInteractionBatch} {
+		if registered[systemInteractionRoutes[interaction]] {
+			statement.Rest[0].Interaction = append(statement.Rest[0].Interaction, fhir.CapabilityStatementRestInteraction{Code: interaction})
+		}
+	}
+	return statement
+}
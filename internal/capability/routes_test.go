@@ -0,0 +1,69 @@
+package capability
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestRouted verifies interactions, operations, and resource types without a registered route are not advertised
+func TestRouted(t *testing.T) {
+	routes := []Route{
+		{Method: http.MethodPost, Pattern: "/fhir/Patient"},
+		{Method: http.MethodGet, Pattern: "/fhir/Patient/{id}"},
+		{Method: http.MethodGet, Pattern: "/fhir/Patient/"},
+		{Method: http.MethodGet, Pattern: "/fhir/Patient/{id}/$everything"},
+		{Method: http.MethodPost, Pattern: "/fhir/Patient/{id}/$everything"},
+		{Method: http.MethodGet, Pattern: "/fhir/Observation/{id}"},
+	}
+
+	routedResources := Routed(Resources(), routes)
+	if len(routedResources) != 2 {
+		t.Fatalf("Expected Patient and Observation, got %d resource types", len(routedResources))
+	}
+
+	patient := routedResources[0]
+	expectedInteractions := []fhir.TypeRestfulInteraction{fhir.TypeRestfulInteractionCreate, fhir.TypeRestfulInteractionRead, fhir.TypeRestfulInteractionSearchType}
+	if len(patient.Interactions) != len(expectedInteractions) {
+		t.Fatalf("Expected create, read, and search, got %v", patient.Interactions)
+	}
+	for _, interaction := range expectedInteractions {
+		if !patient.Supports(interaction) {
+			t.Errorf("Expected Patient to support %s", interaction.Code())
+		}
+	}
+	if len(patient.Operations) != 1 || patient.Operations[0].Name != "everything" {
+		t.Errorf("Expected only $everything, got %+v", patient.Operations)
+	}
+	if len(patient.SearchParameters) == 0 || len(patient.RevIncludes) == 0 {
+		t.Errorf("Expected searchable Patient to keep its search parameters")
+	}
+
+	observation := routedResources[1]
+	if !observation.Supports(fhir.TypeRestfulInteractionRead) || len(observation.Interactions) != 1 || len(observation.Operations) != 0 {
+		t.Errorf("Expected Observation with read only, got %+v", observation)
+	}
+	if len(observation.SearchParameters) != 0 {
+		t.Errorf("Expected no search parameters without a search route, got %+v", observation.SearchParameters)
+	}
+
+	if unroutedResources := Routed(Resources(), nil); len(unroutedResources) != 0 {
+		t.Errorf("Expected no resource types without routes, got %+v", unroutedResources)
+	}
+}
+
+// TestRoutedStatement verifies transaction and batch are advertised only when POST /fhir is routed
+func TestRoutedStatement(t *testing.T) {
+	routes := []Route{{Method: http.MethodGet, Pattern: "/fhir/Patient/{id}"}}
+	if interactions := RoutedStatement(Resources(), routes, time.Now()).Rest[0].Interaction; len(interactions) != 0 {
+		t.Errorf("Expected no system interactions without a POST /fhir route, got %v", interactions)
+	}
+
+	routes = append(routes, Route{Method: http.MethodPost, Pattern: "/fhir"})
+	interactions := RoutedStatement(Resources(), routes, time.Now()).Rest[0].Interaction
+	if len(interactions) != 2 || interactions[0].Code != fhir.SystemRestfulInteractionTransaction || interactions[1].Code != fhir.SystemRestfulInteractionBatch {
+		t.Errorf("Expected transaction and batch, got %v", interactions)
+	}
+}
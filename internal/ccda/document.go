@@ -0,0 +1,157 @@
+package ccda
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+// cdaNamespace is the XML namespace of CDA documents
+const cdaNamespace = "urn:hl7-org:v3"
+
+// Section kinds the importer maps to FHIR resources
+const (
+	// SectionProblems is the Problem Section, whose problem observations become Conditions
+	SectionProblems = "problems"
+
+	// SectionResults is the Results Section, whose result observations become Observations
+	SectionResults = "results"
+)
+
+// sectionTemplates maps the C-CDA section template IDs, with and without required entries, to the section kinds
+var sectionTemplates = map[string]string{
+	"2.16.840.1.113883.10.20.22.2.5.1": SectionProblems,
+	"2.16.840.1.113883.10.20.22.2.5":   SectionProblems,
+	"2.16.840.1.113883.10.20.22.2.3.1": SectionResults,
+	"2.16.840.1.113883.10.20.22.2.3":   SectionResults,
+}
+
+// sectionCodes maps the LOINC section codes to the section kinds, for documents that omit the template IDs
+var sectionCodes = map[string]string{
+	"11450-4": SectionProblems,
+	"30954-2": SectionResults,
+}
+
+// Document is a parsed C-CDA document, keeping only the parts the importer reads
+type Document struct {
+	XMLName      xml.Name       `xml:"ClinicalDocument"`
+	Title        string         `xml:"title"`
+	RecordTarget []recordTarget `xml:"recordTarget"`
+	Sections     []Section      `xml:"component>structuredBody>component>section"`
+}
+
+// recordTarget is the patient the document is about
+type recordTarget struct {
+	IDs        []instanceIdentifier `xml:"patientRole>id"`
+	Names      []personName         `xml:"patientRole>patient>name"`
+	GenderCode conceptDescriptor    `xml:"patientRole>patient>administrativeGenderCode"`
+	BirthTime  timestamp            `xml:"patientRole>patient>birthTime"`
+}
+
+// instanceIdentifier is an II: an OID root with an optional extension
+type instanceIdentifier struct {
+	Root       string `xml:"root,attr"`
+	Extension  string `xml:"extension,attr"`
+	NullFlavor string `xml:"nullFlavor,attr"`
+}
+
+// personName is a PN with its given and family parts
+type personName struct {
+	Use    string   `xml:"use,attr"`
+	Given  []string `xml:"given"`
+	Family string   `xml:"family"`
+}
+
+// conceptDescriptor is a CD with its translations
+type conceptDescriptor struct {
+	Code         string              `xml:"code,attr"`
+	CodeSystem   string              `xml:"codeSystem,attr"`
+	DisplayName  string              `xml:"displayName,attr"`
+	NullFlavor   string              `xml:"nullFlavor,attr"`
+	Translations []conceptDescriptor `xml:"translation"`
+}
+
+// timestamp is a TS, or an IVL_TS whose low and high bounds are read
+type timestamp struct {
+	Value string `xml:"value,attr"`
+	Low   *struct {
+		Value string `xml:"value,attr"`
+	} `xml:"low"`
+	High *struct {
+		Value string `xml:"value,attr"`
+	} `xml:"high"`
+}
+
+// observationValue is an ANY-typed observation value, told apart by its xsi:type
+type observationValue struct {
+	Type         string              `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+	Value        string              `xml:"value,attr"`
+	Unit         string              `xml:"unit,attr"`
+	Code         string              `xml:"code,attr"`
+	CodeSystem   string              `xml:"codeSystem,attr"`
+	DisplayName  string              `xml:"displayName,attr"`
+	NullFlavor   string              `xml:"nullFlavor,attr"`
+	Text         string              `xml:",chardata"`
+	Translations []conceptDescriptor `xml:"translation"`
+}
+
+// clinicalObservation is a CDA observation entry, such as a Problem Observation or a Result Observation
+type clinicalObservation struct {
+	NegationInd   string             `xml:"negationInd,attr"`
+	Code          conceptDescriptor  `xml:"code"`
+	StatusCode    conceptDescriptor  `xml:"statusCode"`
+	EffectiveTime timestamp          `xml:"effectiveTime"`
+	Values        []observationValue `xml:"value"`
+}
+
+// entry is one section entry: a Problem Concern Act or a Result Organizer, with the observations they hold
+type entry struct {
+	ConcernObservations   []clinicalObservation `xml:"act>entryRelationship>observation"`
+	OrganizerTime         timestamp             `xml:"organizer>effectiveTime"`
+	OrganizerObservations []clinicalObservation `xml:"organizer>component>observation"`
+}
+
+// Section is one section of the document's structured body
+type Section struct {
+	TemplateIDs []instanceIdentifier `xml:"templateId"`
+	Code        conceptDescriptor    `xml:"code"`
+	Title       string               `xml:"title"`
+	NullFlavor  string               `xml:"nullFlavor,attr"`
+	Entries     []entry              `xml:"entry"`
+}
+
+// Kind returns the section kind the importer maps, or empty for sections it does not read
+func (section Section) Kind() string {
+	for _, templateID := range section.TemplateIDs {
+		if kind, known := sectionTemplates[templateID.Root]; known {
+			return kind
+		}
+	}
+	return sectionCodes[section.Code.Code]
+}
+
+// Name returns the section's title, or its code when it has none
+func (section Section) Name() string {
+	if title := strings.TrimSpace(section.Title); title != "" {
+		return title
+	}
+	if section.Code.DisplayName != "" {
+		return section.Code.DisplayName
+	}
+	return section.Code.Code
+}
+
+// Parse reads a C-CDA document about a single patient
+func Parse(raw []byte) (*Document, error) {
+	var document Document
+	if unmarshalError := xml.Unmarshal(raw, &document); unmarshalError != nil {
+		return nil, fmt.Errorf("document is not well-formed XML: %w", unmarshalError)
+	}
+	if document.XMLName.Space != cdaNamespace || document.XMLName.Local != "ClinicalDocument" {
+		return nil, fmt.Errorf("root element must be ClinicalDocument in the %s namespace", cdaNamespace)
+	}
+	if len(document.RecordTarget) != 1 {
+		return nil, fmt.Errorf("document must have exactly one recordTarget, got %d", len(document.RecordTarget))
+	}
+	return &document, nil
+}
@@ -0,0 +1,112 @@
+package ccda
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// testDocument has two problems (one resolved, one negated), a results organizer with a translated LOINC code,
+// a coded value, and a cancelled result, an empty results section, and a section the importer does not read
+const testDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<ClinicalDocument xmlns="urn:hl7-org:v3" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:cda="urn:hl7-org:v3">
+  <title>Summary of Care</title>
+  <recordTarget><patientRole>
+    <id root="2.16.840.1.113883.4.1" nullFlavor="MSK"/>
+    <id root="1.2.3.4" extension="MRN001"/>
+    <patient>
+      <name use="L"><given>Ann</given><given>Marie</given><family>Smith</family></name>
+      <administrativeGenderCode code="UN" codeSystem="2.16.840.1.113883.5.1"/>
+      <birthTime value="198002291030-0500"/>
+    </patient>
+  </patientRole></recordTarget>
+  <component><structuredBody>
+    <component><section>
+      <code code="11450-4" codeSystem="2.16.840.1.113883.6.1" displayName="Problem list"/>
+      <entry><act><entryRelationship><observation>
+        <effectiveTime><low value="20190105"/><high value="20200105"/></effectiveTime>
+        <value xsi:type="CD" nullFlavor="OTH"><translation code="J45.909" codeSystem="2.16.840.1.113883.6.90" displayName="Asthma"/></value>
+      </observation></entryRelationship></act></entry>
+      <entry><act><entryRelationship><observation negationInd="true">
+        <value xsi:type="CD" code="55607006" codeSystem="2.16.840.1.113883.6.96"/>
+      </observation></entryRelationship></act></entry>
+    </section></component>
+    <component><section>
+      <templateId root="2.16.840.1.113883.10.20.22.2.3"/>
+      <title>Lab Results</title>
+      <entry><organizer><effectiveTime><low value="20261001083000+0200"/></effectiveTime>
+        <component><observation>
+          <code code="GLU" codeSystem="2.16.840.1.113883.19.2"><translation code="2345-7" codeSystem="2.16.840.1.113883.6.1" displayName="Glucose"/></code>
+          <statusCode code="completed"/>
+          <value xsi:type="cda:PQ" value="5.40" unit="mmol/L"/>
+        </observation></component>
+        <component><observation>
+          <code code="5778-6" codeSystem="2.16.840.1.113883.6.1"/><statusCode code="active"/>
+          <effectiveTime value="20261001090000"/>
+          <value xsi:type="CD" code="371244009" codeSystem="2.16.840.1.113883.6.96" displayName="Yellow"/>
+        </observation></component>
+        <component><observation>
+          <code code="2093-3" codeSystem="2.16.840.1.113883.6.1"/><statusCode code="cancelled"/>
+          <value xsi:type="PQ" value="180" unit="mg/dL"/>
+        </observation></component>
+      </organizer></entry>
+    </section></component>
+    <component><section nullFlavor="NI">
+      <templateId root="2.16.840.1.113883.10.20.22.2.3.1"/><title>Pending Results</title>
+    </section></component>
+    <component><section><title>Social History</title></section></component>
+  </structuredBody></component>
+</ClinicalDocument>`
+
+// TestParse_RejectsOtherDocuments verifies malformed XML, other root elements, and documents about several patients
+func TestParse_RejectsOtherDocuments(t *testing.T) {
+	testCases := map[string]string{
+		"not xml":         `{"resourceType":"Patient"}`,
+		"wrong root":      `<Bundle xmlns="http://hl7.org/fhir"/>`,
+		"no namespace":    `<ClinicalDocument><recordTarget/></ClinicalDocument>`,
+		"two patients":    `<ClinicalDocument xmlns="urn:hl7-org:v3"><recordTarget/><recordTarget/></ClinicalDocument>`,
+		"no recordTarget": `<ClinicalDocument xmlns="urn:hl7-org:v3"/>`,
+	}
+	for name, raw := range testCases {
+		if _, parseError := Parse([]byte(raw)); parseError == nil {
+			t.Errorf("%s: expected the document to be rejected", name)
+		}
+	}
+}
+
+// TestDocument_Patient verifies the identifier with an extension, names, gender, and local birth date are read
+func TestDocument_Patient(t *testing.T) {
+	document, parseError := Parse([]byte(testDocument))
+	if parseError != nil {
+		t.Fatalf("Expected the document to parse, got %v", parseError)
+	}
+
+	fhirPatient, patientError := document.Patient()
+	if patientError != nil {
+		t.Fatalf("Expected the patient to map, got %v", patientError)
+	}
+	if *fhirPatient.Identifier[0].System != "urn:oid:1.2.3.4" || *fhirPatient.Identifier[0].Value != "MRN001" {
+		t.Errorf("Expected the identifier with an extension, got %+v", fhirPatient.Identifier)
+	}
+	if *fhirPatient.Name[0].Family != "Smith" || strings.Join(fhirPatient.Name[0].Given, " ") != "Ann Marie" {
+		t.Errorf("Expected the legal name, got %+v", fhirPatient.Name)
+	}
+	if *fhirPatient.Gender != fhir.AdministrativeGenderOther || *fhirPatient.BirthDate != "1980-02-29" {
+		t.Errorf("Expected gender other and the birth date in the sender's time zone, got %v and %s", *fhirPatient.Gender, *fhirPatient.BirthDate)
+	}
+}
+
+// TestSection_Kind verifies sections are recognized by template ID or LOINC code
+func TestSection_Kind(t *testing.T) {
+	document, _ := Parse([]byte(testDocument))
+	expectedKinds := []string{SectionProblems, SectionResults, SectionResults, ""}
+	for index, section := range document.Sections {
+		if kind := section.Kind(); kind != expectedKinds[index] {
+			t.Errorf("Section %d (%s): expected kind %q, got %q", index, section.Name(), expectedKinds[index], kind)
+		}
+	}
+	if name := document.Sections[0].Name(); name != "Problem list" {
+		t.Errorf("Expected an untitled section to be named by its code, got %q", name)
+	}
+}
@@ -0,0 +1,206 @@
+package ccda
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// PatientStore finds and creates patients; *service.PatientService satisfies it
+type PatientStore interface {
+	SearchPatients(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, error)
+	CreatePatient(ctx context.Context, fhirPatient *fhir.Patient) (*fhir.Patient, error)
+}
+
+// ConditionStore creates conditions; *service.ConditionService satisfies it
+type ConditionStore interface {
+	CreateCondition(ctx context.Context, fhirCondition *fhir.Condition) (*fhir.Condition, error)
+}
+
+// ObservationStore creates observations; *service.ObservationService satisfies it
+type ObservationStore interface {
+	CreateObservation(ctx context.Context, fhirObservation *fhir.Observation) (*fhir.Observation, error)
+}
+
+// Transactor runs work in a single database transaction that commits when work succeeds and rolls back when it fails
+type Transactor interface {
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+}
+
+// Summary reports what an import created and what it left out
+type Summary struct {
+	// Title is the document's title
+	Title string `json:"title,omitempty"`
+
+	// PatientID is the patient the document was imported for, and PatientCreated reports whether the import created it
+	PatientID      string `json:"patient_id"`
+	PatientCreated bool   `json:"patient_created"`
+
+	Created         []CreatedResource `json:"created"`
+	SkippedSections []SkippedSection  `json:"skipped_sections"`
+	SkippedEntries  []SkippedEntry    `json:"skipped_entries"`
+}
+
+// CreatedResource is one resource the import created
+type CreatedResource struct {
+	ResourceType string `json:"resource_type"`
+	ID           string `json:"id"`
+
+	// Section is the title of the section the resource came from, empty for the patient
+	Section string `json:"section,omitempty"`
+}
+
+// SkippedSection is a section the import did not read
+type SkippedSection struct {
+	Title  string `json:"title"`
+	Code   string `json:"code,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// SkippedEntry is an entry of an imported section that could not be converted
+type SkippedEntry struct {
+	Section string `json:"section"`
+
+	// Entry is the entry's position in its section, from 1
+	Entry  int    `json:"entry"`
+	Reason string `json:"reason"`
+}
+
+// Importer creates the FHIR resources a C-CDA document describes
+type Importer struct {
+	patients     PatientStore
+	conditions   ConditionStore
+	observations ObservationStore
+	transactor   Transactor
+}
+
+// NewImporter creates an importer writing through the stores in transactions run by transactor
+func NewImporter(patients PatientStore, conditions ConditionStore, observations ObservationStore, transactor Transactor) *Importer {
+	return &Importer{
+		patients:     patients,
+		conditions:   conditions,
+		observations: observations,
+		transactor:   transactor,
+	}
+}
+
+// Import creates the document's patient unless one with its identifier is stored, then a Condition for each
+// problem and an Observation for each result, all in one transaction
+// Entries that cannot be converted are skipped and listed in the summary; a document whose patient cannot be
+// read or matches several stored patients, or whose resources the services refuse, fails with a RejectionError
+func (importer *Importer) Import(ctx context.Context, document *Document) (*Summary, error) {
+	fhirPatient, patientError := document.Patient()
+	if patientError != nil {
+		return nil, rejection(patientError.Error(), "ClinicalDocument.recordTarget")
+	}
+	identifier := fhirPatient.Identifier[0]
+
+	// Conditions and observations are stored in MongoDB, so the scope removes them again if a later write fails
+	scopeContext, scope := service.WithTransactionScope(ctx)
+	var summary *Summary
+	transactionError := importer.transactor.InTransaction(scopeContext, func(transactionContext context.Context) error {
+		summary = &Summary{Title: document.Title, Created: []CreatedResource{}, SkippedSections: []SkippedSection{}, SkippedEntries: []SkippedEntry{}}
+
+		matchingPatients, searchError := importer.patients.SearchPatients(transactionContext, &models.PatientSearchParams{
+			Identifier: &models.IdentifierCriterion{Systems: []string{*identifier.System}, Value: *identifier.Value},
+			Limit:      2,
+		})
+		if searchError != nil {
+			return fmt.Errorf("failed to find patient: %w", searchError)
+		}
+		switch len(matchingPatients) {
+		case 0:
+			createdPatient, createError := importer.patients.CreatePatient(transactionContext, fhirPatient)
+			if createError != nil {
+				return fmt.Errorf("failed to create patient: %w", createError)
+			}
+			summary.PatientID, summary.PatientCreated = *createdPatient.Id, true
+			summary.Created = append(summary.Created, CreatedResource{ResourceType: "Patient", ID: *createdPatient.Id})
+		case 1:
+			summary.PatientID = *matchingPatients[0].Id
+		default:
+			return rejection(fmt.Sprintf("Identifier %s|%s matches several patients", *identifier.System, *identifier.Value), "ClinicalDocument.recordTarget")
+		}
+
+		for _, section := range document.Sections {
+			if sectionError := importer.importSection(transactionContext, section, summary); sectionError != nil {
+				return sectionError
+			}
+		}
+		return nil
+	})
+	if transactionError != nil {
+		scope.Rollback(ctx)
+		return nil, transactionError
+	}
+	scope.Commit(ctx)
+
+	log.Info().Str("patient_id", summary.PatientID).Int("created", len(summary.Created)).
+		Int("skipped_sections", len(summary.SkippedSections)).Int("skipped_entries", len(summary.SkippedEntries)).Msg("Imported C-CDA document")
+	return summary, nil
+}
+
+// importSection creates the resources of one problems or results section, and records any other section as skipped
+func (importer *Importer) importSection(ctx context.Context, section Section, summary *Summary) error {
+	kind := section.Kind()
+	switch {
+	case kind == "":
+		summary.SkippedSections = append(summary.SkippedSections, SkippedSection{Title: section.Name(), Code: section.Code.Code, Reason: "Section type is not imported"})
+		return nil
+	case section.NullFlavor != "" || len(section.Entries) == 0:
+		summary.SkippedSections = append(summary.SkippedSections, SkippedSection{Title: section.Name(), Code: section.Code.Code, Reason: "Section has no entries"})
+		return nil
+	}
+
+	for entryIndex, sectionEntry := range section.Entries {
+		skip := func(reason error) {
+			summary.SkippedEntries = append(summary.SkippedEntries, SkippedEntry{Section: section.Name(), Entry: entryIndex + 1, Reason: reason.Error()})
+		}
+
+		if kind == SectionProblems {
+			if len(sectionEntry.ConcernObservations) == 0 {
+				skip(fmt.Errorf("entry has no problem concern act with a problem observation"))
+			}
+			for _, problem := range sectionEntry.ConcernObservations {
+				fhirCondition, mapError := condition(problem, summary.PatientID)
+				if mapError != nil {
+					skip(mapError)
+					continue
+				}
+				createdCondition, createError := importer.conditions.CreateCondition(ctx, fhirCondition)
+				if createError != nil {
+					return fmt.Errorf("failed to create condition from %s entry %d: %w", section.Name(), entryIndex+1, createError)
+				}
+				summary.Created = append(summary.Created, CreatedResource{ResourceType: "Condition", ID: *createdCondition.Id, Section: section.Name()})
+			}
+			continue
+		}
+
+		if len(sectionEntry.OrganizerObservations) == 0 {
+			skip(fmt.Errorf("entry has no result organizer with a result observation"))
+		}
+		for _, result := range sectionEntry.OrganizerObservations {
+			fhirObservation, mapError := observation(result, sectionEntry.OrganizerTime, summary.PatientID)
+			if mapError != nil {
+				skip(mapError)
+				continue
+			}
+			createdObservation, createError := importer.observations.CreateObservation(ctx, fhirObservation)
+			if createError != nil {
+				return fmt.Errorf("failed to create observation from %s entry %d: %w", section.Name(), entryIndex+1, createError)
+			}
+			summary.Created = append(summary.Created, CreatedResource{ResourceType: "Observation", ID: *createdObservation.Id, Section: section.Name()})
+		}
+	}
+	return nil
+}
+
+// rejection returns the RejectionError reporting a document that cannot be imported
+func rejection(diagnostics string, expression string) error {
+	return &outcome.RejectionError{Issues: []outcome.Issue{outcome.Error(fhir.IssueTypeInvalid, diagnostics, expression)}}
+}
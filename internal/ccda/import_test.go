@@ -0,0 +1,138 @@
+package ccda
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// recordingStore keeps created resources in memory, matching patient searches on identifier value
+type recordingStore struct {
+	patients     []*fhir.Patient
+	conditions   []*fhir.Condition
+	observations []*fhir.Observation
+	createError  error
+}
+
+// SearchPatients returns the patients with the searched identifier value
+func (store *recordingStore) SearchPatients(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, error) {
+	matches := []*fhir.Patient{}
+	for _, storedPatient := range store.patients {
+		if *storedPatient.Identifier[0].Value == searchParams.Identifier.Value {
+			matches = append(matches, storedPatient)
+		}
+	}
+	return matches, nil
+}
+
+// CreatePatient stores the patient under a sequential ID
+func (store *recordingStore) CreatePatient(ctx context.Context, fhirPatient *fhir.Patient) (*fhir.Patient, error) {
+	patientID := fmt.Sprintf("patient-%d", len(store.patients))
+	fhirPatient.Id = &patientID
+	store.patients = append(store.patients, fhirPatient)
+	return fhirPatient, nil
+}
+
+// CreateCondition stores the condition under a sequential ID
+func (store *recordingStore) CreateCondition(ctx context.Context, fhirCondition *fhir.Condition) (*fhir.Condition, error) {
+	conditionID := fmt.Sprintf("condition-%d", len(store.conditions))
+	fhirCondition.Id = &conditionID
+	store.conditions = append(store.conditions, fhirCondition)
+	return fhirCondition, nil
+}
+
+// CreateObservation stores the observation under a sequential ID unless a create error is set
+func (store *recordingStore) CreateObservation(ctx context.Context, fhirObservation *fhir.Observation) (*fhir.Observation, error) {
+	if store.createError != nil {
+		return nil, store.createError
+	}
+	observationID := fmt.Sprintf("observation-%d", len(store.observations))
+	fhirObservation.Id = &observationID
+	store.observations = append(store.observations, fhirObservation)
+	return fhirObservation, nil
+}
+
+// InTransaction runs work directly
+func (store *recordingStore) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return work(ctx)
+}
+
+// importTestDocument parses testDocument and imports it into the store
+func importTestDocument(t *testing.T, store *recordingStore) (*Summary, error) {
+	document, parseError := Parse([]byte(testDocument))
+	if parseError != nil {
+		t.Fatalf("Expected the document to parse, got %v", parseError)
+	}
+	return NewImporter(store, store, store, store).Import(context.Background(), document)
+}
+
+// TestImport_CreatesProblemsAndResults verifies the created resources and what the summary reports as skipped
+func TestImport_CreatesProblemsAndResults(t *testing.T) {
+	store := &recordingStore{}
+	summary, importError := importTestDocument(t, store)
+	if importError != nil {
+		t.Fatalf("Expected the import to succeed, got %v", importError)
+	}
+	if summary.PatientID != "patient-0" || !summary.PatientCreated || summary.Title != "Summary of Care" || len(summary.Created) != 4 {
+		t.Fatalf("Expected the patient, one condition, and two observations, got %+v", summary)
+	}
+
+	asthma := store.conditions[0]
+	if *asthma.Code.Coding[0].System != "http://hl7.org/fhir/sid/icd-10-cm" || *asthma.Code.Coding[0].Code != "J45.909" {
+		t.Errorf("Expected the translated ICD-10-CM code, got %+v", asthma.Code)
+	}
+	if *asthma.ClinicalStatus.Coding[0].Code != "resolved" || *asthma.OnsetDateTime != "2019-01-05" || *asthma.Subject.Reference != "Patient/patient-0" {
+		t.Errorf("Expected a resolved condition of the patient with its onset date, got %+v", asthma)
+	}
+
+	glucose := store.observations[0]
+	if *glucose.Code.Coding[0].Code != "2345-7" || *glucose.Code.Coding[0].System != "http://loinc.org" {
+		t.Errorf("Expected the LOINC translation to be preferred, got %+v", glucose.Code)
+	}
+	if glucose.ValueQuantity.Value.String() != "5.4" || *glucose.ValueQuantity.Unit != "mmol/L" || *glucose.EffectiveDateTime != "2026-10-01T06:30:00Z" {
+		t.Errorf("Expected 5.4 mmol/L at the organizer time in UTC, got %+v", glucose)
+	}
+	urineColor := store.observations[1]
+	if *urineColor.ValueString != "Yellow" || urineColor.Status != fhir.ObservationStatusPreliminary || *urineColor.EffectiveDateTime != "2026-10-01T09:00:00Z" {
+		t.Errorf("Expected a preliminary coded result at its own time, got %+v", urineColor)
+	}
+
+	if len(summary.SkippedEntries) != 2 || summary.SkippedEntries[0].Section != "Problem list" || summary.SkippedEntries[0].Entry != 2 || summary.SkippedEntries[1].Section != "Lab Results" {
+		t.Errorf("Expected the negated problem and the cancelled result to be skipped, got %+v", summary.SkippedEntries)
+	}
+	if len(summary.SkippedSections) != 2 || summary.SkippedSections[0].Title != "Pending Results" || summary.SkippedSections[1].Title != "Social History" {
+		t.Errorf("Expected the empty and the unsupported sections to be skipped, got %+v", summary.SkippedSections)
+	}
+}
+
+// TestImport_MatchesStoredPatient verifies a known identifier reuses the patient and a shared one is rejected
+func TestImport_MatchesStoredPatient(t *testing.T) {
+	patientID, identifierValue := "existing", "MRN001"
+	store := &recordingStore{patients: []*fhir.Patient{{Id: &patientID, Identifier: []fhir.Identifier{{Value: &identifierValue}}}}}
+	summary, importError := importTestDocument(t, store)
+	if importError != nil || summary.PatientID != "existing" || summary.PatientCreated || len(store.patients) != 1 {
+		t.Fatalf("Expected the stored patient to be reused, got %+v (%v)", summary, importError)
+	}
+	if *store.conditions[0].Subject.Reference != "Patient/existing" {
+		t.Errorf("Expected the condition to reference the stored patient, got %s", *store.conditions[0].Subject.Reference)
+	}
+
+	store.patients = append(store.patients, store.patients[0])
+	var rejection *outcome.RejectionError
+	if _, importError := importTestDocument(t, store); !errors.As(importError, &rejection) {
+		t.Errorf("Expected a rejection for an identifier shared by several patients, got %v", importError)
+	}
+}
+
+// TestImport_FailsWhenAWriteFails verifies a failed write fails the whole import
+func TestImport_FailsWhenAWriteFails(t *testing.T) {
+	store := &recordingStore{createError: errors.New("connection reset")}
+	if summary, importError := importTestDocument(t, store); importError == nil || summary != nil {
+		t.Errorf("Expected the import to fail, got %+v", summary)
+	}
+}
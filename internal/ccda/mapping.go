@@ -0,0 +1,301 @@
+package ccda
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/hl7"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// loincOID is the code system OID of LOINC
+const loincOID = "2.16.840.1.113883.6.1"
+
+// codeSystemURIs maps the code system OIDs common in C-CDA documents to their FHIR system URIs
+// Other OIDs are kept as urn:oid: URIs
+var codeSystemURIs = map[string]string{
+	loincOID:                  "http://loinc.org",
+	"2.16.840.1.113883.6.96":  "http://snomed.info/sct",
+	"2.16.840.1.113883.6.90":  "http://hl7.org/fhir/sid/icd-10-cm",
+	"2.16.840.1.113883.6.103": "http://hl7.org/fhir/sid/icd-9-cm",
+	"2.16.840.1.113883.6.88":  "http://www.nlm.nih.gov/research/umls/rxnorm",
+}
+
+// genders maps HL7 v3 AdministrativeGender codes to FHIR administrative gender
+var genders = map[string]fhir.AdministrativeGender{
+	"M":  fhir.AdministrativeGenderMale,
+	"F":  fhir.AdministrativeGenderFemale,
+	"UN": fhir.AdministrativeGenderOther,
+}
+
+// resultStatuses maps result observation status codes to FHIR observation statuses; other statuses, such as
+// aborted or cancelled, have no result to store
+var resultStatuses = map[string]fhir.ObservationStatus{
+	"":          fhir.ObservationStatusFinal,
+	"completed": fhir.ObservationStatusFinal,
+	"active":    fhir.ObservationStatusPreliminary,
+}
+
+// Systems of the codes the importer sets itself
+const (
+	conditionClinicalSystem     = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	conditionVerificationSystem = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+	observationCategorySystem   = "http://terminology.hl7.org/CodeSystem/observation-category"
+)
+
+// codeSystemURI returns the FHIR system URI of a code system OID
+func codeSystemURI(oid string) string {
+	if uri, known := codeSystemURIs[oid]; known {
+		return uri
+	}
+	return "urn:oid:" + oid
+}
+
+// coding returns the coding of a concept, preferring the one in preferredSystem (an OID) among the concept and its
+// translations and otherwise taking the first with a code; it returns false when none has a code
+func coding(concept conceptDescriptor, preferredSystem string) (fhir.Coding, bool) {
+	candidates := append([]conceptDescriptor{concept}, concept.Translations...)
+	chosen := -1
+	for index, candidate := range candidates {
+		if candidate.Code == "" || candidate.NullFlavor != "" {
+			continue
+		}
+		if chosen < 0 || (candidate.CodeSystem == preferredSystem && candidates[chosen].CodeSystem != preferredSystem) {
+			chosen = index
+		}
+	}
+	if chosen < 0 {
+		return fhir.Coding{}, false
+	}
+
+	code, system := candidates[chosen].Code, codeSystemURI(candidates[chosen].CodeSystem)
+	fhirCoding := fhir.Coding{System: &system, Code: &code}
+	if display := candidates[chosen].DisplayName; display != "" {
+		fhirCoding.Display = &display
+	}
+	return fhirCoding, true
+}
+
+// PatientIdentifier returns the first recordTarget identifier with both a root and an extension, which the
+// importer matches stored patients on
+func (document *Document) PatientIdentifier() (fhir.Identifier, error) {
+	for _, identifier := range document.RecordTarget[0].IDs {
+		if identifier.NullFlavor != "" || identifier.Root == "" || identifier.Extension == "" {
+			continue
+		}
+		system, value := "urn:oid:"+identifier.Root, identifier.Extension
+		return fhir.Identifier{System: &system, Value: &value}, nil
+	}
+	return fhir.Identifier{}, fmt.Errorf("recordTarget has no patient identifier with a root and an extension")
+}
+
+// Patient returns an active patient with the recordTarget identifier, names, gender, and birth date
+func (document *Document) Patient() (*fhir.Patient, error) {
+	identifier, identifierError := document.PatientIdentifier()
+	if identifierError != nil {
+		return nil, identifierError
+	}
+	target := document.RecordTarget[0]
+	active := true
+	fhirPatient := &fhir.Patient{Active: &active, Identifier: []fhir.Identifier{identifier}}
+
+	for _, name := range target.Names {
+		humanName := fhir.HumanName{}
+		if family := strings.TrimSpace(name.Family); family != "" {
+			humanName.Family = &family
+		}
+		for _, given := range name.Given {
+			if given = strings.TrimSpace(given); given != "" {
+				humanName.Given = append(humanName.Given, given)
+			}
+		}
+		if humanName.Family != nil || len(humanName.Given) > 0 {
+			fhirPatient.Name = append(fhirPatient.Name, humanName)
+		}
+	}
+
+	if target.GenderCode.NullFlavor != "" {
+		unknownGender := fhir.AdministrativeGenderUnknown
+		fhirPatient.Gender = &unknownGender
+	} else if target.GenderCode.Code != "" {
+		fhirGender, known := genders[target.GenderCode.Code]
+		if !known {
+			return nil, fmt.Errorf("administrativeGenderCode %q is not an HL7 AdministrativeGender code", target.GenderCode.Code)
+		}
+		fhirPatient.Gender = &fhirGender
+	}
+
+	if birthTime := target.BirthTime.Value; birthTime != "" {
+		parsedTime, parseError := hl7.ParseDateTime(birthTime)
+		if parseError != nil {
+			return nil, fmt.Errorf("birthTime is invalid: %w", parseError)
+		}
+		birthDate := parsedTime.Format("2006-01-02")
+		fhirPatient.BirthDate = &birthDate
+	}
+
+	return fhirPatient, nil
+}
+
+// condition converts a Problem Observation into a confirmed Condition of the patient
+// A problem whose effective time has a high bound has ended, so it is resolved; otherwise it is active
+func condition(problem clinicalObservation, patientID string) (*fhir.Condition, error) {
+	if problem.NegationInd == "true" {
+		return nil, fmt.Errorf("negated problem observation records the absence of a problem")
+	}
+	if len(problem.Values) == 0 {
+		return nil, fmt.Errorf("problem observation has no value")
+	}
+	problemCoding, coded := coding(problem.Values[0].concept(), "")
+	if !coded {
+		return nil, fmt.Errorf("problem observation value has no code")
+	}
+
+	patientReference := "Patient/" + patientID
+	clinicalStatus := "active"
+	if problem.EffectiveTime.High != nil && problem.EffectiveTime.High.Value != "" {
+		clinicalStatus = "resolved"
+	}
+	clinicalSystem, verificationSystem, verificationStatus := conditionClinicalSystem, conditionVerificationSystem, "confirmed"
+	fhirCondition := &fhir.Condition{
+		Subject:            fhir.Reference{Reference: &patientReference},
+		Code:               &fhir.CodeableConcept{Coding: []fhir.Coding{problemCoding}},
+		ClinicalStatus:     &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &clinicalSystem, Code: &clinicalStatus}}},
+		VerificationStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &verificationSystem, Code: &verificationStatus}}},
+	}
+
+	if onset := problem.EffectiveTime.start(); onset != "" {
+		onsetDateTime, timeError := fhirDateTime(onset)
+		if timeError != nil {
+			return nil, fmt.Errorf("problem onset is invalid: %w", timeError)
+		}
+		fhirCondition.OnsetDateTime = &onsetDateTime
+	}
+	return fhirCondition, nil
+}
+
+// observation converts a Result Observation into a laboratory Observation of the patient
+// A result without its own effective time takes the time of the organizer that holds it
+func observation(result clinicalObservation, organizerTime timestamp, patientID string) (*fhir.Observation, error) {
+	fhirStatus, storable := resultStatuses[result.StatusCode.Code]
+	if !storable {
+		return nil, fmt.Errorf("result status %q has no result to store", result.StatusCode.Code)
+	}
+	resultCoding, coded := coding(result.Code, loincOID)
+	if !coded {
+		return nil, fmt.Errorf("result observation has no code")
+	}
+	if len(result.Values) == 0 {
+		return nil, fmt.Errorf("result observation has no value")
+	}
+
+	patientReference := "Patient/" + patientID
+	categoryCode, categorySystem := "laboratory", observationCategorySystem
+	fhirObservation := &fhir.Observation{
+		Status:  fhirStatus,
+		Subject: &fhir.Reference{Reference: &patientReference},
+		Code:    fhir.CodeableConcept{Coding: []fhir.Coding{resultCoding}},
+		Category: []fhir.CodeableConcept{{
+			Coding: []fhir.Coding{{System: &categorySystem, Code: &categoryCode}},
+		}},
+	}
+	if valueError := result.Values[0].applyTo(fhirObservation); valueError != nil {
+		return nil, valueError
+	}
+
+	effectiveTime := result.EffectiveTime.start()
+	if effectiveTime == "" {
+		effectiveTime = organizerTime.start()
+	}
+	if effectiveTime != "" {
+		parsedTime, parseError := hl7.ParseDateTime(effectiveTime)
+		if parseError != nil {
+			return nil, fmt.Errorf("result effective time is invalid: %w", parseError)
+		}
+		effectiveDateTime := parsedTime.UTC().Format("2006-01-02T15:04:05Z")
+		fhirObservation.EffectiveDateTime = &effectiveDateTime
+	}
+	return fhirObservation, nil
+}
+
+// start returns the time point, or the low bound of an interval
+func (effectiveTime timestamp) start() string {
+	if effectiveTime.Value != "" {
+		return effectiveTime.Value
+	}
+	if effectiveTime.Low != nil {
+		return effectiveTime.Low.Value
+	}
+	return ""
+}
+
+// fhirDateTime converts a TS value to a FHIR date when it has day precision, and to a UTC date-time otherwise
+func fhirDateTime(value string) (string, error) {
+	parsedTime, parseError := hl7.ParseDateTime(value)
+	if parseError != nil {
+		return "", parseError
+	}
+	if len(value) == len("20060102") {
+		return parsedTime.Format("2006-01-02"), nil
+	}
+	return parsedTime.UTC().Format("2006-01-02T15:04:05Z"), nil
+}
+
+// concept reads a coded observation value as a concept descriptor
+func (value observationValue) concept() conceptDescriptor {
+	return conceptDescriptor{
+		Code:         value.Code,
+		CodeSystem:   value.CodeSystem,
+		DisplayName:  value.DisplayName,
+		NullFlavor:   value.NullFlavor,
+		Translations: value.Translations,
+	}
+}
+
+// applyTo sets the observation's value: physical quantities become quantities, and strings and coded values
+// become strings
+func (value observationValue) applyTo(fhirObservation *fhir.Observation) error {
+	if value.NullFlavor != "" {
+		return fmt.Errorf("result value is unknown (nullFlavor %s)", value.NullFlavor)
+	}
+
+	// xsi:type may be prefixed with the namespace prefix the document binds to the CDA namespace
+	valueType := value.Type[strings.LastIndex(value.Type, ":")+1:]
+	switch valueType {
+	case "PQ":
+		number, parseError := strconv.ParseFloat(strings.TrimSpace(value.Value), 64)
+		if parseError != nil {
+			return fmt.Errorf("result quantity %q is not a number", value.Value)
+		}
+		quantityValue := json.Number(strconv.FormatFloat(number, 'f', -1, 64))
+		quantity := &fhir.Quantity{Value: &quantityValue}
+		// Unit "1" is the UCUM unity, which is what a dimensionless result carries
+		if value.Unit != "" && value.Unit != "1" {
+			unit := value.Unit
+			quantity.Unit = &unit
+		}
+		fhirObservation.ValueQuantity = quantity
+	case "ST", "ED":
+		text := strings.TrimSpace(value.Text)
+		if text == "" {
+			return fmt.Errorf("result value is empty")
+		}
+		fhirObservation.ValueString = &text
+	case "CD", "CE", "CO", "CV":
+		// The coded value is stored as its display name, or as its code when it has none
+		valueCoding, coded := coding(value.concept(), "")
+		if !coded {
+			return fmt.Errorf("result value has no code")
+		}
+		text := *valueCoding.Code
+		if valueCoding.Display != nil {
+			text = *valueCoding.Display
+		}
+		fhirObservation.ValueString = &text
+	default:
+		return fmt.Errorf("result value type %q is not supported: send PQ, ST, ED, or a coded value", value.Type)
+	}
+	return nil
+}
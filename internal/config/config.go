@@ -0,0 +1,176 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/database"
+	"github.com/rs/zerolog"
+)
+
+// Config holds the settings the server needs before it can start: where it listens, how much it logs,
+// and the databases it connects to
+type Config struct {
+	// Port is the TCP port the HTTP server listens on
+	Port int `json:"port"`
+
+	// LogLevel is a zerolog level name such as debug, info, warn, or error
+	LogLevel string `json:"log_level"`
+
+	Postgres database.PostgresConfig `json:"postgres"`
+	MongoDB  database.MongoConfig    `json:"mongodb"`
+}
+
+// environmentOverrides maps each environment variable to the setting it overrides
+var environmentOverrides = map[string]func(config *Config, value string){
+	"LOG_LEVEL":         func(config *Config, value string) { config.LogLevel = value },
+	"POSTGRES_HOST":     func(config *Config, value string) { config.Postgres.Host = value },
+	"POSTGRES_PORT":     func(config *Config, value string) { config.Postgres.Port = value },
+	"POSTGRES_USER":     func(config *Config, value string) { config.Postgres.User = value },
+	"POSTGRES_PASSWORD": func(config *Config, value string) { config.Postgres.Password = value },
+	"POSTGRES_DB":       func(config *Config, value string) { config.Postgres.DBName = value },
+	"MONGO_HOST":        func(config *Config, value string) { config.MongoDB.Host = value },
+	"MONGO_PORT":        func(config *Config, value string) { config.MongoDB.Port = value },
+	"MONGO_USER":        func(config *Config, value string) { config.MongoDB.User = value },
+	"MONGO_PASSWORD":    func(config *Config, value string) { config.MongoDB.Password = value },
+	"MONGO_DATABASE":    func(config *Config, value string) { config.MongoDB.Database = value },
+}
+
+// mongoSettingOverrides maps each environment variable to the MongoDB client setting it overrides,
+// failing when the value does not parse
+var mongoSettingOverrides = map[string]func(config *Config, value string) error{
+	"MONGO_MAX_POOL_SIZE": func(config *Config, value string) error {
+		poolSize, parseError := strconv.ParseUint(value, 10, 64)
+		config.MongoDB.MaxPoolSize = poolSize
+		return parseError
+	},
+	"MONGO_MIN_POOL_SIZE": func(config *Config, value string) error {
+		poolSize, parseError := strconv.ParseUint(value, 10, 64)
+		config.MongoDB.MinPoolSize = poolSize
+		return parseError
+	},
+	"MONGO_MAX_CONN_IDLE_TIME_MS": func(config *Config, value string) error {
+		milliseconds, parseError := strconv.Atoi(value)
+		config.MongoDB.MaxConnIdleTimeMS = milliseconds
+		return parseError
+	},
+	"MONGO_CONNECT_TIMEOUT_MS": func(config *Config, value string) error {
+		milliseconds, parseError := strconv.Atoi(value)
+		config.MongoDB.ConnectTimeoutMS = milliseconds
+		return parseError
+	},
+	"MONGO_SERVER_SELECTION_TIMEOUT_MS": func(config *Config, value string) error {
+		milliseconds, parseError := strconv.Atoi(value)
+		config.MongoDB.ServerSelectionTimeoutMS = milliseconds
+		return parseError
+	},
+	"MONGO_RETRY_WRITES": func(config *Config, value string) error {
+		retryWrites, parseError := strconv.ParseBool(value)
+		config.MongoDB.RetryWrites = &retryWrites
+		return parseError
+	},
+	"MONGO_SKIP_INDEX_CREATION": func(config *Config, value string) error {
+		skipIndexCreation, parseError := strconv.ParseBool(value)
+		config.MongoDB.SkipIndexCreation = skipIndexCreation
+		return parseError
+	},
+}
+
+// Default returns the settings of the local docker-compose setup
+func Default() Config {
+	return Config{
+		Port:     8080,
+		LogLevel: "info",
+		Postgres: database.PostgresConfig{
+			Host:     "localhost",
+			Port:     "5432",
+			User:     "fhir_user",
+			Password: "fhir_password",
+			DBName:   "fhir_health_db",
+		},
+		MongoDB: database.MongoConfig{
+			Host:     "localhost",
+			Port:     "27017",
+			User:     "fhir_user",
+			Password: "fhir_password",
+			Database: "admin",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, then the JSON file at path when path is not empty,
+// then any environment variables that are set, and validates the result
+// A setting left out of the file keeps its default; an environment variable always wins over the file
+func Load(path string) (Config, error) {
+	config := Default()
+
+	if path != "" {
+		fileBytes, readError := os.ReadFile(path)
+		if readError != nil {
+			return config, fmt.Errorf("failed to read config file: %w", readError)
+		}
+		if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+			return config, fmt.Errorf("failed to parse config file: %w", decodeError)
+		}
+	}
+
+	for variableName, override := range environmentOverrides {
+		if value := os.Getenv(variableName); value != "" {
+			override(&config, value)
+		}
+	}
+	for variableName, override := range mongoSettingOverrides {
+		if value := os.Getenv(variableName); value != "" {
+			if overrideError := override(&config, value); overrideError != nil {
+				return config, fmt.Errorf("%s must be a number or boolean, got %q", variableName, value)
+			}
+		}
+	}
+	if rawPort := os.Getenv("SERVER_PORT"); rawPort != "" {
+		port, parseError := strconv.Atoi(rawPort)
+		if parseError != nil {
+			return config, fmt.Errorf("SERVER_PORT must be a number, got %q", rawPort)
+		}
+		config.Port = port
+	}
+
+	return config, config.Validate()
+}
+
+// Validate checks that the port is usable, the log level is known, both databases are fully addressed,
+// and the MongoDB pool settings are consistent
+func (config Config) Validate() error {
+	if config.Port < 1 || config.Port > 65535 {
+		return fmt.Errorf("port must be between 1 and 65535, got %d", config.Port)
+	}
+	if _, parseError := zerolog.ParseLevel(config.LogLevel); parseError != nil || config.LogLevel == "" {
+		return fmt.Errorf("unknown log_level %q", config.LogLevel)
+	}
+	if config.Postgres.Host == "" || config.Postgres.Port == "" || config.Postgres.User == "" || config.Postgres.DBName == "" {
+		return errors.New("postgres needs a host, port, user, and dbname")
+	}
+	if config.MongoDB.Host == "" || config.MongoDB.Port == "" || config.MongoDB.User == "" || config.MongoDB.Database == "" {
+		return errors.New("mongodb needs a host, port, user, and database")
+	}
+	if config.MongoDB.MaxPoolSize > 0 && config.MongoDB.MinPoolSize > config.MongoDB.MaxPoolSize {
+		return fmt.Errorf("mongodb min_pool_size %d is above max_pool_size %d", config.MongoDB.MinPoolSize, config.MongoDB.MaxPoolSize)
+	}
+	if config.MongoDB.MaxConnIdleTimeMS < 0 || config.MongoDB.ConnectTimeoutMS < 0 || config.MongoDB.ServerSelectionTimeoutMS < 0 {
+		return errors.New("mongodb timeouts cannot be negative")
+	}
+	return nil
+}
+
+// Address returns the listen address for the HTTP server
+func (config Config) Address() string {
+	return ":" + strconv.Itoa(config.Port)
+}
+
+// Level returns the zerolog level named by LogLevel; Validate has already rejected unknown names
+func (config Config) Level() zerolog.Level {
+	level, _ := zerolog.ParseLevel(config.LogLevel)
+	return level
+}
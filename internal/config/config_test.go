@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// clearEnvironment blanks every variable Load reads, so the developer's shell cannot change test results
+func clearEnvironment(t *testing.T) {
+	t.Helper()
+	for variableName := range environmentOverrides {
+		t.Setenv(variableName, "")
+	}
+	for variableName := range mongoSettingOverrides {
+		t.Setenv(variableName, "")
+	}
+	t.Setenv("SERVER_PORT", "")
+}
+
+// TestLoad_Defaults verifies the local docker-compose settings are used when nothing is configured
+func TestLoad_Defaults(t *testing.T) {
+	clearEnvironment(t)
+	loadedConfig, loadError := Load("")
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if loadedConfig != Default() || loadedConfig.Address() != ":8080" || loadedConfig.Level() != zerolog.InfoLevel {
+		t.Errorf("Expected the defaults, got %+v", loadedConfig)
+	}
+}
+
+// TestLoad_FileAndEnvironment verifies the file overrides the defaults and the environment overrides the file
+func TestLoad_FileAndEnvironment(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "server.json")
+	clearEnvironment(t)
+	os.WriteFile(configPath, []byte(`{"port": 9090, "log_level": "debug", "postgres": {"host": "db.internal", "password": "file-secret"}}`), 0o600)
+	t.Setenv("POSTGRES_PASSWORD", "env-secret")
+	t.Setenv("MONGO_HOST", "mongo.internal")
+
+	loadedConfig, loadError := Load(configPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if loadedConfig.Port != 9090 || loadedConfig.Level() != zerolog.DebugLevel {
+		t.Errorf("Expected the file's port and log level, got %+v", loadedConfig)
+	}
+	if loadedConfig.Postgres.Host != "db.internal" || loadedConfig.Postgres.Password != "env-secret" || loadedConfig.Postgres.DBName != "fhir_health_db" {
+		t.Errorf("Expected the file's host, the environment's password, and the default dbname, got %+v", loadedConfig.Postgres)
+	}
+	if loadedConfig.MongoDB.Host != "mongo.internal" {
+		t.Errorf("Expected the environment's MongoDB host, got %q", loadedConfig.MongoDB.Host)
+	}
+}
+
+// TestLoad_MongoPool verifies the pool settings are read from the file and overridden by the environment
+func TestLoad_MongoPool(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "server.json")
+	clearEnvironment(t)
+	os.WriteFile(configPath, []byte(`{"mongodb": {"max_pool_size": 40, "min_pool_size": 4, "connect_timeout_ms": 2000}}`), 0o600)
+	t.Setenv("MONGO_MAX_POOL_SIZE", "80")
+	t.Setenv("MONGO_RETRY_WRITES", "false")
+	t.Setenv("MONGO_SKIP_INDEX_CREATION", "true")
+
+	loadedConfig, loadError := Load(configPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	mongoConfig := loadedConfig.MongoDB
+	if mongoConfig.MaxPoolSize != 80 || mongoConfig.MinPoolSize != 4 || mongoConfig.ConnectTimeoutMS != 2000 {
+		t.Errorf("Expected the environment's max and the file's min and timeout, got %+v", mongoConfig)
+	}
+	if mongoConfig.RetryWrites == nil || *mongoConfig.RetryWrites {
+		t.Errorf("Expected retry writes to be turned off, got %v", mongoConfig.RetryWrites)
+	}
+	if !mongoConfig.SkipIndexCreation {
+		t.Error("Expected index creation to be skipped")
+	}
+}
+
+// TestLoad_Invalid verifies unusable settings are rejected
+func TestLoad_Invalid(t *testing.T) {
+	testCases := []struct {
+		name          string
+		fileContents  string
+		variableName  string
+		variableValue string
+		expectedError string
+	}{
+		{"port out of range", `{"port": 70000}`, "", "", "port must be between"},
+		{"unknown log level", `{"log_level": "loud"}`, "", "", "unknown log_level"},
+		{"empty database host", `{"postgres": {"host": ""}}`, "", "", "postgres needs"},
+		{"malformed file", `{"port":`, "", "", "failed to parse"},
+		{"non-numeric port variable", `{}`, "SERVER_PORT", "http", "SERVER_PORT must be a number"},
+		{"non-numeric pool size variable", `{}`, "MONGO_MAX_POOL_SIZE", "lots", "MONGO_MAX_POOL_SIZE must be"},
+		{"min pool above max", `{"mongodb": {"max_pool_size": 5, "min_pool_size": 10}}`, "", "", "min_pool_size 10 is above"},
+		{"negative timeout", `{"mongodb": {"connect_timeout_ms": -1}}`, "", "", "cannot be negative"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			clearEnvironment(t)
+			configPath := filepath.Join(t.TempDir(), "server.json")
+			os.WriteFile(configPath, []byte(testCase.fileContents), 0o600)
+			if testCase.variableName != "" {
+				t.Setenv(testCase.variableName, testCase.variableValue)
+			}
+
+			_, loadError := Load(configPath)
+			if loadError == nil || !strings.Contains(loadError.Error(), testCase.expectedError) {
+				t.Errorf("Expected an error containing %q, got %v", testCase.expectedError, loadError)
+			}
+		})
+	}
+
+	if _, loadError := Load(filepath.Join(t.TempDir(), "missing.json")); loadError == nil {
+		t.Error("Expected an error for a missing config file")
+	}
+}
@@ -0,0 +1,220 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the tracer of the database client spans
+const tracerName = "github.com/nathannewyen/fhir-health-interop/internal/database"
+
+// Stores named to query observers
+const (
+	StorePostgres = "postgres"
+	StoreMongoDB  = "mongodb"
+)
+
+// QueryObserver is told how long each database query took, for example to export query latency metrics
+type QueryObserver interface {
+	ObserveQuery(store string, operation string, duration time.Duration, failed bool)
+}
+
+// sqlOperations are the statement keywords reported as operations; any other statement is reported as "other"
+var sqlOperations = []string{"select", "insert", "update", "delete", "with"}
+
+// sqlOperation returns the statement's leading keyword, lowercased, so the operation label stays bounded
+func sqlOperation(query string) string {
+	keyword, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	keyword = strings.ToLower(strings.TrimSpace(keyword))
+	for _, operation := range sqlOperations {
+		if keyword == operation {
+			return operation
+		}
+	}
+	return "other"
+}
+
+// startClientSpan starts a database client span as a child of the span in ctx
+func startClientSpan(ctx context.Context, spanName string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, spanName, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+// endClientSpan marks a failed query's span as an error and ends it
+func endClientSpan(span trace.Span, failure string) {
+	if failure != "" {
+		span.SetStatus(codes.Error, failure)
+	}
+	span.End()
+}
+
+// observedConnector opens connections whose queries are timed and traced
+type observedConnector struct {
+	connector driver.Connector
+	observer  QueryObserver
+}
+
+// Connect opens an underlying connection and wraps it
+func (connector observedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, connectError := connector.connector.Connect(ctx)
+	if connectError != nil {
+		return nil, connectError
+	}
+	return &observedConn{Conn: conn, observer: connector.observer}, nil
+}
+
+// Driver returns the underlying driver
+func (connector observedConnector) Driver() driver.Driver {
+	return connector.connector.Driver()
+}
+
+// observedConn times and traces the queries and statements run directly on a connection, including inside
+// transactions; a nil observer only traces
+// Explicitly prepared statements are neither timed nor traced; the repositories run their queries directly
+// The span carries the statement text, which holds placeholders rather than values
+type observedConn struct {
+	driver.Conn
+	observer QueryObserver
+}
+
+// QueryContext times and traces a query
+func (conn *observedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, supported := conn.Conn.(driver.QueryerContext)
+	if !supported {
+		return nil, driver.ErrSkip
+	}
+	startedAt := time.Now()
+	spanContext, span := conn.startSpan(ctx, query)
+	rows, queryError := queryer.QueryContext(spanContext, query, args)
+	conn.observe(span, query, startedAt, queryError)
+	return rows, queryError
+}
+
+// ExecContext times and traces a statement
+func (conn *observedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, supported := conn.Conn.(driver.ExecerContext)
+	if !supported {
+		return nil, driver.ErrSkip
+	}
+	startedAt := time.Now()
+	spanContext, span := conn.startSpan(ctx, query)
+	result, execError := execer.ExecContext(spanContext, query, args)
+	conn.observe(span, query, startedAt, execError)
+	return result, execError
+}
+
+// startSpan starts the span of one statement, named by its operation
+func (conn *observedConn) startSpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := sqlOperation(query)
+	return startClientSpan(ctx, "postgres "+operation,
+		semconv.DBSystemNamePostgreSQL,
+		semconv.DBOperationName(operation),
+		semconv.DBQueryText(query),
+	)
+}
+
+// observe ends the query's span and reports its duration
+// A driver asking database/sql to fall back to a prepared statement ran nothing, so that is not reported
+func (conn *observedConn) observe(span trace.Span, query string, startedAt time.Time, queryError error) {
+	if errors.Is(queryError, driver.ErrSkip) {
+		span.End()
+		return
+	}
+	failure := ""
+	if queryError != nil {
+		failure = queryError.Error()
+	}
+	endClientSpan(span, failure)
+	if conn.observer != nil {
+		conn.observer.ObserveQuery(StorePostgres, sqlOperation(query), time.Since(startedAt), queryError != nil)
+	}
+}
+
+// PrepareContext prepares a statement on the underlying connection
+func (conn *observedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, supported := conn.Conn.(driver.ConnPrepareContext); supported {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return conn.Conn.Prepare(query)
+}
+
+// BeginTx starts a transaction on the underlying connection
+func (conn *observedConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, supported := conn.Conn.(driver.ConnBeginTx); supported {
+		return beginner.BeginTx(ctx, options)
+	}
+	return nil, errors.New("database driver does not support transaction options")
+}
+
+// Ping checks the underlying connection
+func (conn *observedConn) Ping(ctx context.Context) error {
+	if pinger, supported := conn.Conn.(driver.Pinger); supported {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession lets the underlying connection refuse reuse after a broken session
+func (conn *observedConn) ResetSession(ctx context.Context) error {
+	if resetter, supported := conn.Conn.(driver.SessionResetter); supported {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid reports whether the underlying connection can be returned to the pool
+func (conn *observedConn) IsValid() bool {
+	if validator, supported := conn.Conn.(driver.Validator); supported {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// mongoCommandMonitor traces every MongoDB command and reports its duration, named by the command such as
+// find or insert; a nil observer only traces
+// Spans name the collection but not the command document, which holds patient data
+func mongoCommandMonitor(observer QueryObserver) *event.CommandMonitor {
+	var openSpans sync.Map
+	finish := func(finishedEvent event.CommandFinishedEvent, failure string) {
+		if openSpan, found := openSpans.LoadAndDelete(finishedEvent.RequestID); found {
+			endClientSpan(openSpan.(trace.Span), failure)
+		}
+		if observer != nil {
+			observer.ObserveQuery(StoreMongoDB, finishedEvent.CommandName, finishedEvent.Duration, failure != "")
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, startedEvent *event.CommandStartedEvent) {
+			attributes := []attribute.KeyValue{
+				semconv.DBSystemNameMongoDB,
+				semconv.DBNamespace(startedEvent.DatabaseName),
+				semconv.DBOperationName(startedEvent.CommandName),
+			}
+			// The command's first element names the collection, as in {find: "observations"}
+			if firstElement, elementError := startedEvent.Command.IndexErr(0); elementError == nil {
+				if collectionName, isString := firstElement.Value().StringValueOK(); isString {
+					attributes = append(attributes, semconv.DBCollectionName(collectionName))
+				}
+			}
+			_, span := startClientSpan(ctx, "mongodb "+startedEvent.CommandName, attributes...)
+			openSpans.Store(startedEvent.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, succeededEvent *event.CommandSucceededEvent) {
+			finish(succeededEvent.CommandFinishedEvent, "")
+		},
+		Failed: func(ctx context.Context, failedEvent *event.CommandFailedEvent) {
+			finish(failedEvent.CommandFinishedEvent, failedEvent.Failure)
+		},
+	}
+}
@@ -0,0 +1,156 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// observedQuery is one query reported to recordingObserver
+type observedQuery struct {
+	store     string
+	operation string
+	failed    bool
+}
+
+// recordingObserver keeps every observed query for testing
+type recordingObserver struct {
+	queries []observedQuery
+}
+
+// ObserveQuery records the query
+func (observer *recordingObserver) ObserveQuery(store string, operation string, duration time.Duration, failed bool) {
+	observer.queries = append(observer.queries, observedQuery{store: store, operation: operation, failed: failed})
+}
+
+// fakeConnector opens fakeConns
+type fakeConnector struct{}
+
+// Connect opens a fakeConn
+func (fakeConnector) Connect(ctx context.Context) (driver.Conn, error) { return &fakeConn{}, nil }
+
+// Driver is not used by the tests
+func (fakeConnector) Driver() driver.Driver { return nil }
+
+// fakeConn answers queries with no rows and fails statements against the missing table
+type fakeConn struct{}
+
+// Prepare is not used by the tests
+func (*fakeConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+// Close does nothing
+func (*fakeConn) Close() error { return nil }
+
+// Begin starts a no-op transaction
+func (*fakeConn) Begin() (driver.Tx, error) { return fakeTx{}, nil }
+
+// BeginTx starts a no-op transaction
+func (*fakeConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+// QueryContext returns no rows
+func (*fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return fakeRows{}, nil
+}
+
+// ExecContext fails statements on the missing table
+func (*fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "DELETE FROM missing" {
+		return nil, errors.New(`relation "missing" does not exist`)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+// fakeTx commits and rolls back nothing
+type fakeTx struct{}
+
+// Commit does nothing
+func (fakeTx) Commit() error { return nil }
+
+// Rollback does nothing
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows has one column and no rows
+type fakeRows struct{}
+
+// Columns names the one column
+func (fakeRows) Columns() []string { return []string{"id"} }
+
+// Close does nothing
+func (fakeRows) Close() error { return nil }
+
+// Next reports there are no rows
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+// TestObservedConnector verifies queries and statements are reported with their operation and outcome,
+// including inside transactions
+func TestObservedConnector(t *testing.T) {
+	observer := &recordingObserver{}
+	databaseConnection := sql.OpenDB(observedConnector{connector: fakeConnector{}, observer: observer})
+	defer databaseConnection.Close()
+	ctx := context.Background()
+
+	rows, _ := databaseConnection.QueryContext(ctx, "SELECT id FROM patients WHERE id = $1", "p-1")
+	rows.Close()
+	databaseConnection.ExecContext(ctx, "  insert INTO patients (id) VALUES ($1)", "p-1")
+	databaseConnection.ExecContext(ctx, "DELETE FROM missing")
+	transaction, _ := databaseConnection.BeginTx(ctx, nil)
+	transaction.ExecContext(ctx, "UPDATE patients SET active = true")
+	transaction.Commit()
+	databaseConnection.ExecContext(ctx, "LOCK TABLE patients")
+
+	expectedQueries := []observedQuery{
+		{StorePostgres, "select", false},
+		{StorePostgres, "insert", false},
+		{StorePostgres, "delete", true},
+		{StorePostgres, "update", false},
+		{StorePostgres, "other", false},
+	}
+	if len(observer.queries) != len(expectedQueries) {
+		t.Fatalf("Expected %d observed queries, got %+v", len(expectedQueries), observer.queries)
+	}
+	for queryIndex, expectedQuery := range expectedQueries {
+		if observer.queries[queryIndex] != expectedQuery {
+			t.Errorf("Query %d: expected %+v, got %+v", queryIndex, expectedQuery, observer.queries[queryIndex])
+		}
+	}
+}
+
+// TestObservedConnector_Spans verifies each statement gets a client span under the caller's span, even without
+// an observer
+func TestObservedConnector_Spans(t *testing.T) {
+	spanRecorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	defer otel.SetTracerProvider(previousProvider)
+
+	databaseConnection := sql.OpenDB(observedConnector{connector: fakeConnector{}})
+	defer databaseConnection.Close()
+	requestContext, requestSpan := otel.Tracer("test").Start(context.Background(), "GET /fhir/Patient/{id}")
+	rows, _ := databaseConnection.QueryContext(requestContext, "SELECT id FROM patients WHERE id = $1", "p-1")
+	rows.Close()
+	databaseConnection.ExecContext(requestContext, "DELETE FROM missing")
+	requestSpan.End()
+
+	spans := spanRecorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected two statement spans and the request span, got %d", len(spans))
+	}
+	selectSpan, deleteSpan := spans[0], spans[1]
+	if selectSpan.Name() != "postgres select" || selectSpan.SpanKind() != trace.SpanKindClient || selectSpan.Parent().SpanID() != requestSpan.SpanContext().SpanID() {
+		t.Errorf("Expected a client span under the request span, got %q", selectSpan.Name())
+	}
+	if deleteSpan.Name() != "postgres delete" || deleteSpan.Status().Description != `relation "missing" does not exist` {
+		t.Errorf("Expected the failed delete's span to carry its error, got %q %v", deleteSpan.Name(), deleteSpan.Status())
+	}
+}
@@ -3,24 +3,95 @@ package database
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// mongoConnectTimeout bounds connecting and the first ping when ConnectTimeoutMS is not set
+const mongoConnectTimeout = 10 * time.Second
+
+// mongoDefaultMaxPoolSize is the driver's connection cap per server when MaxPoolSize is not set
+const mongoDefaultMaxPoolSize = 100
+
 // MongoConfig holds MongoDB connection configuration
+// Pool settings left at zero (or RetryWrites left nil) keep the driver defaults
 type MongoConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	Database string
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Database string `json:"database"`
+
+	// MaxPoolSize caps the connections open per server; MinPoolSize keeps that many open while idle
+	MaxPoolSize uint64 `json:"max_pool_size"`
+	MinPoolSize uint64 `json:"min_pool_size"`
+
+	// MaxConnIdleTimeMS closes pooled connections idle for longer than this
+	MaxConnIdleTimeMS int `json:"max_conn_idle_time_ms"`
+
+	// ConnectTimeoutMS bounds opening one connection; ServerSelectionTimeoutMS bounds waiting for a usable server
+	ConnectTimeoutMS         int `json:"connect_timeout_ms"`
+	ServerSelectionTimeoutMS int `json:"server_selection_timeout_ms"`
+
+	// RetryWrites retries a write once after a network error or failover
+	RetryWrites *bool `json:"retry_writes"`
+
+	// SkipIndexCreation leaves the collections' indexes alone at startup, for read-only deployments whose
+	// user may not create indexes
+	SkipIndexCreation bool `json:"skip_index_creation"`
 }
 
-// NewMongoConnection creates a new MongoDB connection
-func NewMongoConnection(config MongoConfig) (*mongo.Database, error) {
-	// Build connection URI
+// MongoPoolStats reports the state of the MongoDB connection pool
+type MongoPoolStats struct {
+	MaxPoolSize       uint64 `json:"maxPoolSize"`
+	OpenConnections   int64  `json:"openConnections"`
+	InUseConnections  int64  `json:"inUseConnections"`
+	IdleConnections   int64  `json:"idleConnections"`
+	CheckoutFailures  int64  `json:"checkoutFailures"`
+	PoolClearedEvents int64  `json:"poolClearedEvents"`
+}
+
+// poolCounters follows the driver's connection pool events
+type poolCounters struct {
+	open             atomic.Int64
+	inUse            atomic.Int64
+	checkoutFailures atomic.Int64
+	cleared          atomic.Int64
+}
+
+// handle updates the counters for one pool event
+func (counters *poolCounters) handle(poolEvent *event.PoolEvent) {
+	switch poolEvent.Type {
+	case event.ConnectionCreated:
+		counters.open.Add(1)
+	case event.ConnectionClosed:
+		counters.open.Add(-1)
+	case event.GetSucceeded:
+		counters.inUse.Add(1)
+	case event.ConnectionReturned:
+		counters.inUse.Add(-1)
+	case event.GetFailed:
+		counters.checkoutFailures.Add(1)
+	case event.PoolCleared:
+		counters.cleared.Add(1)
+	}
+}
+
+// MongoClient owns the one MongoDB client the server shares across repositories
+type MongoClient struct {
+	client      *mongo.Client
+	database    *mongo.Database
+	maxPoolSize uint64
+	counters    *poolCounters
+}
+
+// NewMongoClient connects to MongoDB with the configured pool settings and verifies the connection
+// Every command is traced and its duration reported to queryObserver; a nil observer only traces
+func NewMongoClient(config MongoConfig, queryObserver QueryObserver) (*MongoClient, error) {
 	connectionURI := fmt.Sprintf("mongodb://%s:%s@%s:%s",
 		config.User,
 		config.Password,
@@ -28,29 +99,101 @@ func NewMongoConnection(config MongoConfig) (*mongo.Database, error) {
 		config.Port,
 	)
 
-	// Set client options
-	clientOptions := options.Client().ApplyURI(connectionURI)
+	counters := &poolCounters{}
+	clientOptions := mongoClientOptions(config).
+		ApplyURI(connectionURI).
+		SetPoolMonitor(&event.PoolMonitor{Event: counters.handle}).
+		SetMonitor(mongoCommandMonitor(queryObserver))
 
-	// Create context with timeout for connection
-	connectionContext, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	connectTimeout := mongoConnectTimeout
+	if config.ConnectTimeoutMS > 0 {
+		connectTimeout = time.Duration(config.ConnectTimeoutMS) * time.Millisecond
+	}
+	connectionContext, cancel := context.WithTimeout(context.Background(), connectTimeout)
 	defer cancel()
 
-	// Connect to MongoDB
 	client, connectionError := mongo.Connect(connectionContext, clientOptions)
 	if connectionError != nil {
 		return nil, fmt.Errorf("failed to connect to MongoDB: %w", connectionError)
 	}
 
-	// Ping the database to verify connection
-	pingContext, pingCancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer pingCancel()
-
-	pingError := client.Ping(pingContext, nil)
-	if pingError != nil {
+	// Ping the database to verify connection, releasing the pool when it fails
+	if pingError := client.Ping(connectionContext, nil); pingError != nil {
+		client.Disconnect(context.Background())
 		return nil, fmt.Errorf("failed to ping MongoDB: %w", pingError)
 	}
 
-	// Return database instance
-	database := client.Database(config.Database)
-	return database, nil
+	maxPoolSize := uint64(mongoDefaultMaxPoolSize)
+	if config.MaxPoolSize > 0 {
+		maxPoolSize = config.MaxPoolSize
+	}
+	return &MongoClient{
+		client:      client,
+		database:    client.Database(config.Database),
+		maxPoolSize: maxPoolSize,
+		counters:    counters,
+	}, nil
+}
+
+// mongoClientOptions turns the pool settings that are set into driver options
+func mongoClientOptions(config MongoConfig) *options.ClientOptions {
+	clientOptions := options.Client()
+	if config.MaxPoolSize > 0 {
+		clientOptions.SetMaxPoolSize(config.MaxPoolSize)
+	}
+	if config.MinPoolSize > 0 {
+		clientOptions.SetMinPoolSize(config.MinPoolSize)
+	}
+	if config.MaxConnIdleTimeMS > 0 {
+		clientOptions.SetMaxConnIdleTime(time.Duration(config.MaxConnIdleTimeMS) * time.Millisecond)
+	}
+	if config.ConnectTimeoutMS > 0 {
+		clientOptions.SetConnectTimeout(time.Duration(config.ConnectTimeoutMS) * time.Millisecond)
+	}
+	if config.ServerSelectionTimeoutMS > 0 {
+		clientOptions.SetServerSelectionTimeout(time.Duration(config.ServerSelectionTimeoutMS) * time.Millisecond)
+	}
+	if config.RetryWrites != nil {
+		clientOptions.SetRetryWrites(*config.RetryWrites)
+	}
+	return clientOptions
+}
+
+// Database returns the configured database; every repository shares the client's pool through it
+func (mongoClient *MongoClient) Database() *mongo.Database {
+	return mongoClient.database
+}
+
+// PoolStats reports the open, in-use, and idle connections and the checkout failures since connecting
+func (mongoClient *MongoClient) PoolStats() MongoPoolStats {
+	openConnections := mongoClient.counters.open.Load()
+	inUseConnections := mongoClient.counters.inUse.Load()
+	return MongoPoolStats{
+		MaxPoolSize:       mongoClient.maxPoolSize,
+		OpenConnections:   openConnections,
+		InUseConnections:  inUseConnections,
+		IdleConnections:   max(openConnections-inUseConnections, 0),
+		CheckoutFailures:  mongoClient.counters.checkoutFailures.Load(),
+		PoolClearedEvents: mongoClient.counters.cleared.Load(),
+	}
+}
+
+// Ping verifies the primary answers, for readiness checks
+func (mongoClient *MongoClient) Ping(ctx context.Context) error {
+	return mongoClient.client.Ping(ctx, nil)
+}
+
+// Close waits for in-use connections to be returned, until ctx ends, then closes the pool
+func (mongoClient *MongoClient) Close(ctx context.Context) error {
+	return mongoClient.client.Disconnect(ctx)
+}
+
+// NewMongoConnection creates a new MongoDB connection
+// The client cannot be closed through the returned database; servers use NewMongoClient
+func NewMongoConnection(config MongoConfig) (*mongo.Database, error) {
+	mongoClient, clientError := NewMongoClient(config, nil)
+	if clientError != nil {
+		return nil, clientError
+	}
+	return mongoClient.Database(), nil
 }
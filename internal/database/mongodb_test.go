@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/event"
 )
 
 // TestNewMongoConnection_Success tests successful MongoDB connection
@@ -352,3 +353,50 @@ func containsURIError(errorMessage string) bool {
 
 	return false
 }
+
+// TestMongoClientOptions verifies only the pool settings that are set override the driver defaults
+func TestMongoClientOptions(t *testing.T) {
+	retryWrites := false
+	clientOptions := mongoClientOptions(MongoConfig{
+		MaxPoolSize:       50,
+		MinPoolSize:       5,
+		MaxConnIdleTimeMS: 60000,
+		ConnectTimeoutMS:  3000,
+		RetryWrites:       &retryWrites,
+	})
+
+	if *clientOptions.MaxPoolSize != 50 || *clientOptions.MinPoolSize != 5 {
+		t.Errorf("Expected pool sizes 5 to 50, got %d to %d", *clientOptions.MinPoolSize, *clientOptions.MaxPoolSize)
+	}
+	if *clientOptions.MaxConnIdleTime != time.Minute || *clientOptions.ConnectTimeout != 3*time.Second {
+		t.Errorf("Expected a 1m idle time and 3s connect timeout, got %v and %v", *clientOptions.MaxConnIdleTime, *clientOptions.ConnectTimeout)
+	}
+	if *clientOptions.RetryWrites {
+		t.Error("Expected retry writes to be turned off")
+	}
+	if clientOptions.ServerSelectionTimeout != nil {
+		t.Errorf("Expected the driver's server selection timeout, got %v", *clientOptions.ServerSelectionTimeout)
+	}
+
+	defaultOptions := mongoClientOptions(MongoConfig{})
+	if defaultOptions.MaxPoolSize != nil || defaultOptions.RetryWrites != nil {
+		t.Error("Expected an empty config to keep every driver default")
+	}
+}
+
+// TestMongoClient_PoolStats verifies pool events are counted into open, in-use, and idle connections
+func TestMongoClient_PoolStats(t *testing.T) {
+	counters := &poolCounters{}
+	for _, eventType := range []string{
+		event.ConnectionCreated, event.ConnectionCreated, event.ConnectionCreated, event.ConnectionClosed,
+		event.GetSucceeded, event.GetSucceeded, event.ConnectionReturned, event.GetFailed, event.PoolCleared,
+	} {
+		counters.handle(&event.PoolEvent{Type: eventType})
+	}
+
+	stats := (&MongoClient{maxPoolSize: 20, counters: counters}).PoolStats()
+	expectedStats := MongoPoolStats{MaxPoolSize: 20, OpenConnections: 2, InUseConnections: 1, IdleConnections: 1, CheckoutFailures: 1, PoolClearedEvents: 1}
+	if stats != expectedStats {
+		t.Errorf("Expected %+v, got %+v", expectedStats, stats)
+	}
+}
@@ -4,21 +4,26 @@ import (
 	"database/sql"
 	"fmt"
 
-	// PostgreSQL driver import for side effects (registers the driver)
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgresConfig holds the configuration for PostgreSQL connection
 type PostgresConfig struct {
-	Host     string
-	Port     string
-	User     string
-	Password string
-	DBName   string
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	DBName   string `json:"dbname"`
 }
 
 // NewPostgresConnection creates and returns a new PostgreSQL database connection
 func NewPostgresConnection(config PostgresConfig) (*sql.DB, error) {
+	return NewObservedPostgresConnection(config, nil)
+}
+
+// NewObservedPostgresConnection creates a PostgreSQL database connection that traces every query and
+// reports its duration to queryObserver; a nil observer only traces
+func NewObservedPostgresConnection(config PostgresConfig, queryObserver QueryObserver) (*sql.DB, error) {
 	// Build the connection string using the provided configuration
 	connectionString := fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
@@ -30,14 +35,16 @@ func NewPostgresConnection(config PostgresConfig) (*sql.DB, error) {
 	)
 
 	// Open a connection to the database
-	databaseConnection, openError := sql.Open("postgres", connectionString)
+	connector, openError := pq.NewConnector(connectionString)
 	if openError != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", openError)
 	}
+	databaseConnection := sql.OpenDB(observedConnector{connector: connector, observer: queryObserver})
 
 	// Verify the connection is working by pinging the database
 	pingError := databaseConnection.Ping()
 	if pingError != nil {
+		databaseConnection.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", pingError)
 	}
 
@@ -0,0 +1,138 @@
+package dedup
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"golang.org/x/sync/singleflight"
+)
+
+// Group collapses identical concurrent searches into a single repository call whose results every caller shares
+// Dashboards that refresh the same searches on many screens at once then cost one query per refresh
+// It is safe for concurrent use by multiple request goroutines
+type Group struct {
+	flight singleflight.Group
+
+	mutex sync.Mutex
+
+	// counts holds request and execution counts keyed by resource type
+	counts map[string]*counts
+}
+
+// counts tracks how many searches were requested and how many reached the repository
+type counts struct {
+	requests   int64
+	executions int64
+}
+
+// Report summarizes how often searches for a resource type were collapsed
+type Report struct {
+	ResourceType string `json:"resource_type"`
+
+	// Requests is the number of searches callers made
+	Requests int64 `json:"requests"`
+
+	// Executions is the number of searches that reached the repository
+	Executions int64 `json:"executions"`
+
+	// Collapsed is the number of searches answered by another caller's in-flight search
+	Collapsed int64 `json:"collapsed"`
+
+	// CollapseRate is Collapsed as a fraction of Requests
+	CollapseRate float64 `json:"collapse_rate"`
+}
+
+// NewGroup creates a group with no searches in flight
+func NewGroup() *Group {
+	return &Group{counts: make(map[string]*counts)}
+}
+
+// Do runs search once for all concurrent callers asking for the same resource type and parameters in the same tenant
+// shared reports whether the result was also handed to other callers; they must copy it before modifying it
+// The search runs detached from any one caller's cancellation so a caller giving up does not fail the others;
+// a cancelled caller stops waiting and gets its context's error
+// A nil group runs every search directly
+func Do[Result any](ctx context.Context, group *Group, resourceType string, parameters interface{}, search func(ctx context.Context) (Result, error)) (Result, bool, error) {
+	if group == nil {
+		result, searchError := search(ctx)
+		return result, false, searchError
+	}
+
+	// Parameters that cannot be keyed are searched directly rather than risk sharing the wrong result
+	parameterBytes, keyError := json.Marshal(parameters)
+	if keyError != nil {
+		result, searchError := search(ctx)
+		return result, false, searchError
+	}
+	searchKey := resourceType + "\x00" + tenant.FromContext(ctx) + "\x00" + string(parameterBytes)
+
+	group.countRequest(resourceType)
+	resultChannel := group.flight.DoChan(searchKey, func() (interface{}, error) {
+		group.countExecution(resourceType)
+		return search(context.WithoutCancel(ctx))
+	})
+
+	select {
+	case <-ctx.Done():
+		var zero Result
+		return zero, false, ctx.Err()
+	case flightResult := <-resultChannel:
+		if flightResult.Err != nil {
+			var zero Result
+			return zero, flightResult.Shared, flightResult.Err
+		}
+		return flightResult.Val.(Result), flightResult.Shared, nil
+	}
+}
+
+// countRequest counts a search asked for by a caller
+func (group *Group) countRequest(resourceType string) {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	group.countsFor(resourceType).requests++
+}
+
+// countExecution counts a search that reached the repository
+func (group *Group) countExecution(resourceType string) {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	group.countsFor(resourceType).executions++
+}
+
+// countsFor returns the counts for a resource type, creating them on first use; the caller holds the mutex
+func (group *Group) countsFor(resourceType string) *counts {
+	resourceCounts, exists := group.counts[resourceType]
+	if !exists {
+		resourceCounts = &counts{}
+		group.counts[resourceType] = resourceCounts
+	}
+	return resourceCounts
+}
+
+// Report returns point-in-time collapse figures for every resource type searched so far
+func (group *Group) Report() []Report {
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+
+	reports := make([]Report, 0, len(group.counts))
+	for resourceType, resourceCounts := range group.counts {
+		report := Report{
+			ResourceType: resourceType,
+			Requests:     resourceCounts.requests,
+			Executions:   resourceCounts.executions,
+			Collapsed:    resourceCounts.requests - resourceCounts.executions,
+		}
+		if report.Requests > 0 {
+			report.CollapseRate = float64(report.Collapsed) / float64(report.Requests)
+		}
+		reports = append(reports, report)
+	}
+	sort.Slice(reports, func(left int, right int) bool {
+		return reports[left].ResourceType < reports[right].ResourceType
+	})
+
+	return reports
+}
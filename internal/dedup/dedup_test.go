@@ -0,0 +1,119 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// requestCount returns the searches requested across all resource types
+func requestCount(group *Group) int64 {
+	var requests int64
+	for _, report := range group.Report() {
+		requests += report.Requests
+	}
+	return requests
+}
+
+// TestDo_CollapsesIdenticalSearches verifies concurrent identical searches share one call and are counted
+func TestDo_CollapsesIdenticalSearches(t *testing.T) {
+	group := NewGroup()
+	release := make(chan struct{})
+	var executions int64
+	search := func(ctx context.Context) ([]string, error) {
+		atomic.AddInt64(&executions, 1)
+		<-release
+		return []string{"patient-1"}, nil
+	}
+	parameters := &models.PatientSearchParams{Name: models.NameCriteria{{Values: []string{"smith"}}}, Limit: 20}
+
+	const callers = 6
+	var waitGroup sync.WaitGroup
+	results := make([][]string, callers)
+	for index := 0; index < callers; index++ {
+		waitGroup.Add(1)
+		go func(index int) {
+			defer waitGroup.Done()
+			results[index], _, _ = Do(context.Background(), group, "Patient", parameters, search)
+		}(index)
+	}
+
+	// Let every caller join the in-flight search before it completes
+	for requestCount(group) < callers {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	waitGroup.Wait()
+
+	if executions != 1 {
+		t.Errorf("Expected one repository call, got %d", executions)
+	}
+	for index, result := range results {
+		if len(result) != 1 || result[0] != "patient-1" {
+			t.Errorf("Expected caller %d to get the shared result, got %v", index, result)
+		}
+	}
+	report := group.Report()[0]
+	if report.Executions != 1 || report.Collapsed != callers-1 || report.CollapseRate != float64(callers-1)/callers {
+		t.Errorf("Unexpected collapse figures %+v", report)
+	}
+}
+
+// TestDo_KeysByParametersAndTenant verifies different searches are not shared and nil groups search directly
+func TestDo_KeysByParametersAndTenant(t *testing.T) {
+	group := NewGroup()
+	var executions int64
+	search := func(ctx context.Context) (int64, error) {
+		return atomic.AddInt64(&executions, 1), nil
+	}
+
+	Do(context.Background(), group, "Observation", &models.ObservationSearchParams{Code: models.ValueCriteria{{"8867-4"}}}, search)
+	Do(context.Background(), group, "Observation", &models.ObservationSearchParams{Code: models.ValueCriteria{{"8310-5"}}}, search)
+	if executions != 2 {
+		t.Errorf("Expected different parameters to search separately, got %d calls", executions)
+	}
+
+	result, shared, _ := Do(context.Background(), nil, "Observation", nil, search)
+	if result != 3 || shared {
+		t.Errorf("Expected a nil group to search directly, got %d shared=%v", result, shared)
+	}
+}
+
+// TestDo_CancelledCallerStopsWaiting verifies a cancelled caller returns while the shared search completes for others
+func TestDo_CancelledCallerStopsWaiting(t *testing.T) {
+	group := NewGroup()
+	release := make(chan struct{})
+	search := func(ctx context.Context) (string, error) {
+		<-release
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "done", nil
+	}
+
+	waitingResult := make(chan string)
+	go func() {
+		result, _, _ := Do(context.Background(), group, "Patient", "same", search)
+		waitingResult <- result
+	}()
+	for requestCount(group) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	cancelledContext, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, cancelError := Do(cancelledContext, group, "Patient", "same", search); !errors.Is(cancelError, context.Canceled) {
+		t.Errorf("Expected the cancelled caller to get context.Canceled, got %v", cancelError)
+	}
+
+	close(release)
+	if result := <-waitingResult; result != "done" {
+		t.Errorf("Expected the other caller to get the result, got %q", result)
+	}
+}
@@ -0,0 +1,97 @@
+package delivery
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// CircuitStatus describes a destination whose circuit is open
+type CircuitStatus struct {
+	Destination         string    `json:"destination"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	OpenUntil           time.Time `json:"open_until"`
+}
+
+// circuit tracks failures for one destination
+type circuit struct {
+	consecutiveFailures int
+	openUntil           time.Time
+}
+
+// Breakers keeps a circuit per destination so one failing endpoint does not consume every attempt
+// After the cooldown one trial send is allowed; a failure reopens the circuit and a success closes it
+type Breakers struct {
+	threshold int
+	cooldown  time.Duration
+
+	mutex    sync.Mutex
+	circuits map[string]*circuit
+}
+
+// NewBreakers creates breakers that open after threshold consecutive failures; zero disables them
+func NewBreakers(threshold int, cooldown time.Duration) *Breakers {
+	return &Breakers{
+		threshold: threshold,
+		cooldown:  cooldown,
+		circuits:  make(map[string]*circuit),
+	}
+}
+
+// Allow reports whether a send to destination may proceed, and until when the circuit is open otherwise
+func (breakers *Breakers) Allow(destination string, now time.Time) (bool, time.Time) {
+	breakers.mutex.Lock()
+	defer breakers.mutex.Unlock()
+
+	destinationCircuit, exists := breakers.circuits[destination]
+	if !exists || !now.Before(destinationCircuit.openUntil) {
+		return true, time.Time{}
+	}
+	return false, destinationCircuit.openUntil
+}
+
+// RecordSuccess closes the destination's circuit
+func (breakers *Breakers) RecordSuccess(destination string) {
+	breakers.mutex.Lock()
+	defer breakers.mutex.Unlock()
+	delete(breakers.circuits, destination)
+}
+
+// RecordFailure counts a failure and opens the circuit once the threshold is reached
+func (breakers *Breakers) RecordFailure(destination string, now time.Time) {
+	breakers.mutex.Lock()
+	defer breakers.mutex.Unlock()
+
+	destinationCircuit, exists := breakers.circuits[destination]
+	if !exists {
+		destinationCircuit = &circuit{}
+		breakers.circuits[destination] = destinationCircuit
+	}
+
+	destinationCircuit.consecutiveFailures++
+	if breakers.threshold > 0 && destinationCircuit.consecutiveFailures >= breakers.threshold {
+		destinationCircuit.openUntil = now.Add(breakers.cooldown)
+	}
+}
+
+// Open lists circuits that are open at now, sorted by destination
+func (breakers *Breakers) Open(now time.Time) []CircuitStatus {
+	breakers.mutex.Lock()
+	defer breakers.mutex.Unlock()
+
+	openCircuits := []CircuitStatus{}
+	for destination, destinationCircuit := range breakers.circuits {
+		if now.Before(destinationCircuit.openUntil) {
+			openCircuits = append(openCircuits, CircuitStatus{
+				Destination:         destination,
+				ConsecutiveFailures: destinationCircuit.consecutiveFailures,
+				OpenUntil:           destinationCircuit.openUntil,
+			})
+		}
+	}
+
+	sort.Slice(openCircuits, func(left, right int) bool {
+		return openCircuits[left].Destination < openCircuits[right].Destination
+	})
+	return openCircuits
+}
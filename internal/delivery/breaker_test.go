@@ -0,0 +1,52 @@
+package delivery
+
+import (
+	"testing"
+	"time"
+)
+
+// TestBreakers_OpenAndRecover verifies the circuit opens at the threshold, allows a trial after the cooldown,
+// reopens on a failed trial, and closes on success
+func TestBreakers_OpenAndRecover(t *testing.T) {
+	breakers := NewBreakers(2, time.Minute)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	destination := "https://partner.example/hook"
+
+	breakers.RecordFailure(destination, now)
+	if allowed, _ := breakers.Allow(destination, now); !allowed {
+		t.Fatal("Expected circuit closed below the threshold")
+	}
+
+	breakers.RecordFailure(destination, now)
+	if allowed, openUntil := breakers.Allow(destination, now); allowed || !openUntil.Equal(now.Add(time.Minute)) {
+		t.Fatalf("Expected circuit open until %s, got %v %s", now.Add(time.Minute), allowed, openUntil)
+	}
+
+	trialTime := now.Add(time.Minute)
+	if allowed, _ := breakers.Allow(destination, trialTime); !allowed {
+		t.Fatal("Expected a trial send after the cooldown")
+	}
+	breakers.RecordFailure(destination, trialTime)
+	if allowed, _ := breakers.Allow(destination, trialTime); allowed {
+		t.Fatal("Expected a failed trial to reopen the circuit")
+	}
+
+	breakers.RecordSuccess(destination)
+	if allowed, _ := breakers.Allow(destination, trialTime); !allowed || len(breakers.Open(trialTime)) != 0 {
+		t.Error("Expected success to close the circuit")
+	}
+}
+
+// TestBreakers_Disabled verifies a zero threshold never opens circuits
+func TestBreakers_Disabled(t *testing.T) {
+	breakers := NewBreakers(0, time.Minute)
+	now := time.Now()
+
+	for attempt := 0; attempt < 10; attempt++ {
+		breakers.RecordFailure("https://partner.example/hook", now)
+	}
+
+	if allowed, _ := breakers.Allow("https://partner.example/hook", now); !allowed {
+		t.Error("Expected disabled breakers to allow every send")
+	}
+}
@@ -0,0 +1,392 @@
+package delivery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// KindWebhook is the delivery kind for HTTP webhooks; other packages register their own kinds, such as ADT over MLLP
+const KindWebhook = "webhook"
+
+// ErrUnknownKind is returned when enqueuing a kind without a registered sender
+var ErrUnknownKind = errors.New("no sender registered for delivery kind")
+
+// Store persists queued deliveries and dead letters
+type Store interface {
+	Enqueue(ctx context.Context, delivery *models.Delivery) (*models.Delivery, error)
+	EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error)
+	ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*models.Delivery, error)
+	Complete(ctx context.Context, id int64) error
+	Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string) error
+	MarkDead(ctx context.Context, id int64, attempts int, lastError string) error
+	ListDead(ctx context.Context, limit int, offset int) ([]*models.Delivery, error)
+	Requeue(ctx context.Context, id int64) error
+	DeleteDead(ctx context.Context, id int64) error
+	CountByStatus(ctx context.Context) (map[models.DeliveryStatus]int64, error)
+}
+
+// Sender delivers one payload to its destination
+type Sender interface {
+	Send(ctx context.Context, delivery *models.Delivery) error
+}
+
+// SenderFunc adapts a function to the Sender interface
+type SenderFunc func(ctx context.Context, delivery *models.Delivery) error
+
+// Send calls the function
+func (senderFunc SenderFunc) Send(ctx context.Context, delivery *models.Delivery) error {
+	return senderFunc(ctx, delivery)
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+// Error implements the error interface
+func (permanent *permanentError) Error() string {
+	return permanent.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (permanent *permanentError) Unwrap() error {
+	return permanent.err
+}
+
+// Permanent wraps an error so the delivery is dead-lettered without further attempts
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether an error was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Policy controls retry timing, circuit breaking, and send rate
+type Policy struct {
+	// MaxAttempts is the number of failed attempts after which a delivery is dead-lettered
+	MaxAttempts int
+
+	// BaseDelay is the wait after the first failure; each further failure doubles it up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Jitter spreads each delay by up to this fraction either way so retries do not arrive in waves
+	Jitter float64
+
+	// BreakerThreshold consecutive failures open a destination's circuit for BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+
+	// RatePerSecond caps sends across all destinations; zero means unlimited
+	RatePerSecond float64
+
+	// BatchSize is the number of due deliveries claimed per poll
+	BatchSize int
+
+	// PollInterval is the wait between polls when the queue is drained
+	PollInterval time.Duration
+
+	// SendTimeout bounds a single send; a claimed batch is leased long enough for every send in it to time out
+	SendTimeout time.Duration
+}
+
+// DefaultPolicy returns settings suited to partner webhooks: retries over roughly a day before dead-lettering
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:      12,
+		BaseDelay:        30 * time.Second,
+		MaxDelay:         4 * time.Hour,
+		Jitter:           0.2,
+		BreakerThreshold: 5,
+		BreakerCooldown:  time.Minute,
+		RatePerSecond:    20,
+		BatchSize:        50,
+		PollInterval:     5 * time.Second,
+		SendTimeout:      10 * time.Second,
+	}
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts
+// random is a value in [0, 1) used to apply jitter
+func (policy Policy) Backoff(failedAttempts int, random float64) time.Duration {
+	if failedAttempts < 1 {
+		failedAttempts = 1
+	}
+
+	delay := float64(policy.BaseDelay) * math.Pow(2, float64(failedAttempts-1))
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		delay = float64(policy.MaxDelay)
+	}
+	delay *= 1 + policy.Jitter*(2*random-1)
+
+	return time.Duration(delay)
+}
+
+// sendInterval returns the pause between sends that keeps to RatePerSecond, or zero when unlimited
+func (policy Policy) sendInterval() time.Duration {
+	if policy.RatePerSecond <= 0 {
+		return 0
+	}
+	return time.Duration(float64(time.Second) / policy.RatePerSecond)
+}
+
+// batchLease returns how long a claimed batch is leased: long enough for every delivery in it to wait
+// its turn and time out, plus one more send timeout, so no other worker claims a delivery still in the batch
+func (policy Policy) batchLease() time.Duration {
+	return time.Duration(policy.BatchSize)*(policy.SendTimeout+policy.sendInterval()) + policy.SendTimeout
+}
+
+// Stats reports queue depth and delivery counters since startup
+type Stats struct {
+	Pending int64 `json:"pending"`
+	Dead    int64 `json:"dead"`
+
+	Delivered    int64 `json:"delivered"`
+	Retried      int64 `json:"retried"`
+	DeadLettered int64 `json:"dead_lettered"`
+
+	// Deferred counts deliveries postponed because their destination's circuit was open
+	Deferred int64 `json:"deferred"`
+
+	OpenCircuits []CircuitStatus `json:"open_circuits"`
+}
+
+// Queue retries outbound deliveries with backoff and per-destination circuit breaking
+// Webhooks and the ADT feed enqueue deliveries here; each kind registers a sender
+type Queue struct {
+	store    Store
+	policy   Policy
+	breakers *Breakers
+
+	sendersMutex sync.RWMutex
+	senders      map[string]Sender
+
+	countersMutex sync.Mutex
+	counters      Stats
+
+	// now, random, and wait are replaceable for tests
+	now    func() time.Time
+	random func() float64
+	wait   func(ctx context.Context, duration time.Duration) error
+}
+
+// NewQueue creates a queue backed by store
+func NewQueue(store Store, policy Policy) *Queue {
+	return &Queue{
+		store:    store,
+		policy:   policy,
+		breakers: NewBreakers(policy.BreakerThreshold, policy.BreakerCooldown),
+		senders:  make(map[string]Sender),
+		now:      time.Now,
+		random:   rand.Float64,
+		wait:     waitFor,
+	}
+}
+
+// RegisterSender sets the sender used for a delivery kind
+func (queue *Queue) RegisterSender(kind string, sender Sender) {
+	queue.sendersMutex.Lock()
+	defer queue.sendersMutex.Unlock()
+	queue.senders[kind] = sender
+}
+
+// sender returns the sender for a kind, or nil when none is registered
+func (queue *Queue) sender(kind string) Sender {
+	queue.sendersMutex.RLock()
+	defer queue.sendersMutex.RUnlock()
+	return queue.senders[kind]
+}
+
+// Enqueue stores a delivery for sending on the next poll
+func (queue *Queue) Enqueue(ctx context.Context, kind string, destination string, contentType string, payload []byte) (*models.Delivery, error) {
+	if queue.sender(kind) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+
+	return queue.store.Enqueue(ctx, &models.Delivery{
+		Kind:        kind,
+		Destination: destination,
+		ContentType: contentType,
+		Payload:     payload,
+	})
+}
+
+// EnqueueAll stores several deliveries so that either all of them are queued or none are
+// When ctx carries a database transaction they are queued in it and commit with the caller's write
+func (queue *Queue) EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error) {
+	for _, queued := range deliveries {
+		if queue.sender(queued.Kind) == nil {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownKind, queued.Kind)
+		}
+	}
+
+	return queue.store.EnqueueAll(ctx, deliveries)
+}
+
+// Run processes due deliveries until ctx is cancelled
+// Full batches are followed immediately by the next poll so a backlog drains without waiting
+func (queue *Queue) Run(ctx context.Context) {
+	for {
+		processed, processError := queue.ProcessDue(ctx)
+		if processError != nil && ctx.Err() == nil {
+			log.Error().Err(processError).Msg("Failed to process delivery queue")
+		}
+
+		if processed < queue.policy.BatchSize || processError != nil {
+			if queue.wait(ctx, queue.policy.PollInterval) != nil {
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// ProcessDue claims one batch of due deliveries and attempts each, returning how many were claimed
+func (queue *Queue) ProcessDue(ctx context.Context) (int, error) {
+	sendInterval := queue.policy.sendInterval()
+	now := queue.now()
+	leaseUntil := now.Add(queue.policy.batchLease())
+	deliveries, claimError := queue.store.ClaimDue(ctx, now, leaseUntil, queue.policy.BatchSize)
+	if claimError != nil {
+		return 0, claimError
+	}
+
+	for index, claimed := range deliveries {
+		if index > 0 && sendInterval > 0 {
+			if waitError := queue.wait(ctx, sendInterval); waitError != nil {
+				// Unprocessed deliveries are retried once their lease expires
+				return len(deliveries), waitError
+			}
+		}
+		if attemptError := queue.attempt(ctx, claimed); attemptError != nil {
+			return len(deliveries), attemptError
+		}
+	}
+
+	return len(deliveries), nil
+}
+
+// attempt sends one delivery and records the result; only store failures are returned
+func (queue *Queue) attempt(ctx context.Context, claimed *models.Delivery) error {
+	now := queue.now()
+
+	if allowed, openUntil := queue.breakers.Allow(claimed.Destination, now); !allowed {
+		queue.count(func(counters *Stats) { counters.Deferred++ })
+		return queue.store.Reschedule(ctx, claimed.ID, claimed.Attempts, openUntil, "circuit open for destination")
+	}
+
+	sender := queue.sender(claimed.Kind)
+	if sender == nil {
+		return queue.deadLetter(ctx, claimed, claimed.Attempts, fmt.Errorf("%w: %s", ErrUnknownKind, claimed.Kind))
+	}
+
+	sendContext, cancel := context.WithTimeout(ctx, queue.policy.SendTimeout)
+	sendError := sender.Send(sendContext, claimed)
+	cancel()
+
+	if sendError == nil {
+		queue.breakers.RecordSuccess(claimed.Destination)
+		queue.count(func(counters *Stats) { counters.Delivered++ })
+		return queue.store.Complete(ctx, claimed.ID)
+	}
+
+	failedAttempts := claimed.Attempts + 1
+	if IsPermanent(sendError) {
+		return queue.deadLetter(ctx, claimed, failedAttempts, sendError)
+	}
+
+	queue.breakers.RecordFailure(claimed.Destination, now)
+	if failedAttempts >= queue.policy.MaxAttempts {
+		return queue.deadLetter(ctx, claimed, failedAttempts, sendError)
+	}
+
+	nextAttemptAt := now.Add(queue.policy.Backoff(failedAttempts, queue.random()))
+	queue.count(func(counters *Stats) { counters.Retried++ })
+	log.Debug().
+		Err(sendError).
+		Int64("delivery_id", claimed.ID).
+		Str("kind", claimed.Kind).
+		Str("destination", claimed.Destination).
+		Int("attempts", failedAttempts).
+		Time("next_attempt_at", nextAttemptAt).
+		Msg("Delivery failed; retry scheduled")
+	return queue.store.Reschedule(ctx, claimed.ID, failedAttempts, nextAttemptAt, sendError.Error())
+}
+
+// deadLetter moves a delivery to the dead letters and logs why
+func (queue *Queue) deadLetter(ctx context.Context, claimed *models.Delivery, attempts int, cause error) error {
+	queue.count(func(counters *Stats) { counters.DeadLettered++ })
+	log.Warn().
+		Err(cause).
+		Int64("delivery_id", claimed.ID).
+		Str("kind", claimed.Kind).
+		Str("destination", claimed.Destination).
+		Int("attempts", attempts).
+		Msg("Delivery moved to dead letters")
+	return queue.store.MarkDead(ctx, claimed.ID, attempts, cause.Error())
+}
+
+// count updates the in-memory counters
+func (queue *Queue) count(update func(counters *Stats)) {
+	queue.countersMutex.Lock()
+	defer queue.countersMutex.Unlock()
+	update(&queue.counters)
+}
+
+// DeadLetters lists deliveries that will not be retried automatically
+func (queue *Queue) DeadLetters(ctx context.Context, limit int, offset int) ([]*models.Delivery, error) {
+	return queue.store.ListDead(ctx, limit, offset)
+}
+
+// Retry returns a dead letter to the queue with its attempts reset; sql.ErrNoRows when it is not dead
+func (queue *Queue) Retry(ctx context.Context, id int64) error {
+	return queue.store.Requeue(ctx, id)
+}
+
+// Discard deletes a dead letter; sql.ErrNoRows when it is not dead
+func (queue *Queue) Discard(ctx context.Context, id int64) error {
+	return queue.store.DeleteDead(ctx, id)
+}
+
+// Stats returns queue depth from the store together with counters and open circuits
+func (queue *Queue) Stats(ctx context.Context) (Stats, error) {
+	statusCounts, countError := queue.store.CountByStatus(ctx)
+	if countError != nil {
+		return Stats{}, countError
+	}
+
+	queue.countersMutex.Lock()
+	stats := queue.counters
+	queue.countersMutex.Unlock()
+
+	stats.Pending = statusCounts[models.DeliveryStatusPending]
+	stats.Dead = statusCounts[models.DeliveryStatusDead]
+	stats.OpenCircuits = queue.breakers.Open(queue.now())
+	return stats, nil
+}
+
+// waitFor sleeps for duration or until ctx is cancelled
+func waitFor(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
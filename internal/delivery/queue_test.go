@@ -0,0 +1,420 @@
+package delivery
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// memoryStore is an in-memory Store for tests
+type memoryStore struct {
+	mutex      sync.Mutex
+	nextID     int64
+	deliveries map[int64]*models.Delivery
+}
+
+// newMemoryStore creates an empty store
+func newMemoryStore() *memoryStore {
+	return &memoryStore{deliveries: make(map[int64]*models.Delivery)}
+}
+
+// Enqueue stores a pending delivery
+func (store *memoryStore) Enqueue(ctx context.Context, delivery *models.Delivery) (*models.Delivery, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.nextID++
+	stored := *delivery
+	stored.ID = store.nextID
+	stored.Status = models.DeliveryStatusPending
+	store.deliveries[stored.ID] = &stored
+	result := stored
+	return &result, nil
+}
+
+// EnqueueAll stores each delivery
+func (store *memoryStore) EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error) {
+	queued := make([]*models.Delivery, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		stored, _ := store.Enqueue(ctx, delivery)
+		queued = append(queued, stored)
+	}
+	return queued, nil
+}
+
+// ClaimDue returns due pending deliveries in ID order and leases them
+func (store *memoryStore) ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*models.Delivery, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	claimed := []*models.Delivery{}
+	for _, stored := range store.deliveries {
+		if stored.Status == models.DeliveryStatusPending && !stored.NextAttemptAt.After(now) {
+			stored.NextAttemptAt = leaseUntil
+			copied := *stored
+			claimed = append(claimed, &copied)
+		}
+	}
+	sort.Slice(claimed, func(left, right int) bool { return claimed[left].ID < claimed[right].ID })
+	if len(claimed) > limit {
+		claimed = claimed[:limit]
+	}
+	return claimed, nil
+}
+
+// Complete deletes a delivery
+func (store *memoryStore) Complete(ctx context.Context, id int64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	delete(store.deliveries, id)
+	return nil
+}
+
+// Reschedule records an attempt
+func (store *memoryStore) Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored := store.deliveries[id]
+	stored.Attempts, stored.NextAttemptAt, stored.LastError = attempts, nextAttemptAt, lastError
+	return nil
+}
+
+// MarkDead dead-letters a delivery
+func (store *memoryStore) MarkDead(ctx context.Context, id int64, attempts int, lastError string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored := store.deliveries[id]
+	stored.Status, stored.Attempts, stored.LastError = models.DeliveryStatusDead, attempts, lastError
+	return nil
+}
+
+// ListDead returns every dead letter
+func (store *memoryStore) ListDead(ctx context.Context, limit int, offset int) ([]*models.Delivery, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	dead := []*models.Delivery{}
+	for _, stored := range store.deliveries {
+		if stored.Status == models.DeliveryStatusDead {
+			copied := *stored
+			dead = append(dead, &copied)
+		}
+	}
+	return dead, nil
+}
+
+// Requeue resets a dead letter
+func (store *memoryStore) Requeue(ctx context.Context, id int64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.deliveries[id]
+	if !exists || stored.Status != models.DeliveryStatusDead {
+		return sql.ErrNoRows
+	}
+	stored.Status, stored.Attempts, stored.NextAttemptAt = models.DeliveryStatusPending, 0, time.Time{}
+	return nil
+}
+
+// DeleteDead removes a dead letter
+func (store *memoryStore) DeleteDead(ctx context.Context, id int64) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.deliveries[id]
+	if !exists || stored.Status != models.DeliveryStatusDead {
+		return sql.ErrNoRows
+	}
+	delete(store.deliveries, id)
+	return nil
+}
+
+// CountByStatus counts deliveries per status
+func (store *memoryStore) CountByStatus(ctx context.Context) (map[models.DeliveryStatus]int64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	counts := map[models.DeliveryStatus]int64{}
+	for _, stored := range store.deliveries {
+		counts[stored.Status]++
+	}
+	return counts, nil
+}
+
+// get returns a copy of a stored delivery
+func (store *memoryStore) get(id int64) (models.Delivery, bool) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.deliveries[id]
+	if !exists {
+		return models.Delivery{}, false
+	}
+	return *stored, true
+}
+
+// testPolicy returns a policy without jitter or pacing
+func testPolicy() Policy {
+	policy := DefaultPolicy()
+	policy.Jitter = 0
+	policy.RatePerSecond = 0
+	policy.MaxAttempts = 3
+	policy.BreakerThreshold = 2
+	return policy
+}
+
+// newTestQueue creates a queue on a memory store with a fixed clock
+func newTestQueue(policy Policy, sender Sender) (*Queue, *memoryStore, *time.Time) {
+	store := newMemoryStore()
+	queue := NewQueue(store, policy)
+	queue.RegisterSender(KindWebhook, sender)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	queue.now = func() time.Time { return now }
+	queue.random = func() float64 { return 0.5 }
+	return queue, store, &now
+}
+
+// TestPolicy_Backoff verifies exponential growth, the cap, and jitter bounds
+func TestPolicy_Backoff(t *testing.T) {
+	policy := Policy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Jitter: 0.2}
+
+	testCases := []struct {
+		failedAttempts int
+		random         float64
+		expected       time.Duration
+	}{
+		{1, 0.5, time.Second},
+		{3, 0.5, 4 * time.Second},
+		{10, 0.5, 10 * time.Second},
+		{1, 0, 800 * time.Millisecond},
+		{1, 1, 1200 * time.Millisecond},
+	}
+
+	for _, testCase := range testCases {
+		if delay := policy.Backoff(testCase.failedAttempts, testCase.random); delay != testCase.expected {
+			t.Errorf("Backoff(%d, %v) = %s, expected %s", testCase.failedAttempts, testCase.random, delay, testCase.expected)
+		}
+	}
+}
+
+// TestQueue_DeliversAndCompletes verifies successful sends are removed from the queue
+func TestQueue_DeliversAndCompletes(t *testing.T) {
+	var sentPayloads []string
+	queue, store, _ := newTestQueue(testPolicy(), SenderFunc(func(ctx context.Context, delivery *models.Delivery) error {
+		sentPayloads = append(sentPayloads, string(delivery.Payload))
+		return nil
+	}))
+
+	queued, _ := queue.Enqueue(context.Background(), KindWebhook, "https://partner.example/hook", "application/json", []byte(`{"n":1}`))
+	processed, processError := queue.ProcessDue(context.Background())
+
+	if processError != nil || processed != 1 || len(sentPayloads) != 1 || sentPayloads[0] != `{"n":1}` {
+		t.Fatalf("Expected one delivery sent, got %d %v %v", processed, sentPayloads, processError)
+	}
+	if _, exists := store.get(queued.ID); exists {
+		t.Error("Expected delivered entry to be removed")
+	}
+
+	stats, _ := queue.Stats(context.Background())
+	if stats.Delivered != 1 || stats.Pending != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestQueue_RetriesThenDeadLetters verifies backoff scheduling and dead-lettering after max attempts
+func TestQueue_RetriesThenDeadLetters(t *testing.T) {
+	policy := testPolicy()
+	policy.BreakerThreshold = 0
+	queue, store, now := newTestQueue(policy, SenderFunc(func(ctx context.Context, delivery *models.Delivery) error {
+		return errors.New("connection refused")
+	}))
+
+	queued, _ := queue.Enqueue(context.Background(), KindWebhook, "https://partner.example/hook", "application/json", []byte(`{}`))
+	queue.ProcessDue(context.Background())
+
+	stored, _ := store.get(queued.ID)
+	if stored.Attempts != 1 || !stored.NextAttemptAt.Equal(now.Add(policy.BaseDelay)) || stored.LastError != "connection refused" {
+		t.Fatalf("Expected first retry scheduled after the base delay, got %+v", stored)
+	}
+
+	// Nothing is due until the backoff has elapsed
+	if processed, _ := queue.ProcessDue(context.Background()); processed != 0 {
+		t.Fatalf("Expected no due deliveries, got %d", processed)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		*now = now.Add(time.Hour)
+		queue.ProcessDue(context.Background())
+	}
+
+	stored, _ = store.get(queued.ID)
+	if stored.Status != models.DeliveryStatusDead || stored.Attempts != 3 {
+		t.Fatalf("Expected dead letter after 3 attempts, got %+v", stored)
+	}
+
+	stats, _ := queue.Stats(context.Background())
+	if stats.Retried != 2 || stats.DeadLettered != 1 || stats.Dead != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestQueue_PermanentFailureDeadLettersImmediately verifies non-retryable errors skip the retries
+func TestQueue_PermanentFailureDeadLettersImmediately(t *testing.T) {
+	queue, store, _ := newTestQueue(testPolicy(), SenderFunc(func(ctx context.Context, delivery *models.Delivery) error {
+		return Permanent(errors.New("webhook responded 410"))
+	}))
+
+	queued, _ := queue.Enqueue(context.Background(), KindWebhook, "https://gone.example/hook", "application/json", []byte(`{}`))
+	queue.ProcessDue(context.Background())
+
+	if stored, _ := store.get(queued.ID); stored.Status != models.DeliveryStatusDead || stored.Attempts != 1 {
+		t.Errorf("Expected immediate dead letter, got %+v", stored)
+	}
+}
+
+// TestQueue_CircuitBreakerDefersDestination verifies an open circuit postpones sends without using attempts
+func TestQueue_CircuitBreakerDefersDestination(t *testing.T) {
+	sendsByDestination := map[string]int{}
+	queue, store, now := newTestQueue(testPolicy(), SenderFunc(func(ctx context.Context, delivery *models.Delivery) error {
+		sendsByDestination[delivery.Destination]++
+		if delivery.Destination == "https://down.example/hook" {
+			return errors.New("503")
+		}
+		return nil
+	}))
+
+	var downIDs []int64
+	for index := 0; index < 3; index++ {
+		queued, _ := queue.Enqueue(context.Background(), KindWebhook, "https://down.example/hook", "application/json", []byte(`{}`))
+		downIDs = append(downIDs, queued.ID)
+	}
+	queue.Enqueue(context.Background(), KindWebhook, "https://up.example/hook", "application/json", []byte(`{}`))
+
+	queue.ProcessDue(context.Background())
+
+	if sendsByDestination["https://down.example/hook"] != 2 || sendsByDestination["https://up.example/hook"] != 1 {
+		t.Fatalf("Expected the circuit to open after 2 failures, got %v", sendsByDestination)
+	}
+	deferred, _ := store.get(downIDs[2])
+	if deferred.Attempts != 0 || !deferred.NextAttemptAt.Equal(now.Add(testPolicy().BreakerCooldown)) {
+		t.Errorf("Expected deferral until the circuit closes without an attempt, got %+v", deferred)
+	}
+
+	stats, _ := queue.Stats(context.Background())
+	if stats.Deferred != 1 || len(stats.OpenCircuits) != 1 || stats.OpenCircuits[0].Destination != "https://down.example/hook" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestQueue_PacesSends verifies the rate limit waits between sends
+func TestQueue_PacesSends(t *testing.T) {
+	policy := testPolicy()
+	policy.RatePerSecond = 4
+	queue, _, _ := newTestQueue(policy, SenderFunc(func(ctx context.Context, delivery *models.Delivery) error { return nil }))
+	var waits []time.Duration
+	queue.wait = func(ctx context.Context, duration time.Duration) error {
+		waits = append(waits, duration)
+		return nil
+	}
+
+	for index := 0; index < 3; index++ {
+		queue.Enqueue(context.Background(), KindWebhook, "https://partner.example/hook", "application/json", []byte(`{}`))
+	}
+	queue.ProcessDue(context.Background())
+
+	if len(waits) != 2 || waits[0] != 250*time.Millisecond {
+		t.Errorf("Expected two 250ms waits between three sends, got %v", waits)
+	}
+}
+
+// TestQueue_LeaseCoversBatch verifies a claimed batch stays leased until its last send has timed out
+func TestQueue_LeaseCoversBatch(t *testing.T) {
+	policy := testPolicy()
+	policy.BatchSize = 3
+	policy.RatePerSecond = 4
+	var now *time.Time
+	leaseExpiredDuringSend := false
+	queue, _, clock := newTestQueue(policy, SenderFunc(func(ctx context.Context, delivery *models.Delivery) error {
+		*now = now.Add(policy.SendTimeout)
+		if delivery.NextAttemptAt.Before(*now) {
+			leaseExpiredDuringSend = true
+		}
+		return errors.New("timed out")
+	}))
+	now = clock
+	queue.wait = func(ctx context.Context, duration time.Duration) error {
+		*now = now.Add(duration)
+		return nil
+	}
+
+	// Separate destinations keep the circuit breaker from deferring the later sends
+	for _, destination := range []string{"https://one.example/hook", "https://two.example/hook", "https://three.example/hook"} {
+		queue.Enqueue(context.Background(), KindWebhook, destination, "application/json", []byte(`{}`))
+	}
+	queue.ProcessDue(context.Background())
+
+	if leaseExpiredDuringSend {
+		t.Error("Expected the lease to outlast every send in the batch")
+	}
+}
+
+// TestQueue_EnqueueUnknownKind verifies kinds without a sender are refused
+func TestQueue_EnqueueUnknownKind(t *testing.T) {
+	queue, _, _ := newTestQueue(testPolicy(), SenderFunc(func(ctx context.Context, delivery *models.Delivery) error { return nil }))
+
+	if _, enqueueError := queue.Enqueue(context.Background(), "kafka", "resource-events", "application/json", nil); !errors.Is(enqueueError, ErrUnknownKind) {
+		t.Errorf("Expected ErrUnknownKind, got %v", enqueueError)
+	}
+}
+
+// TestQueue_EnqueueAllIsAllOrNothing verifies one unknown kind keeps the whole batch out of the queue
+func TestQueue_EnqueueAllIsAllOrNothing(t *testing.T) {
+	queue, store, _ := newTestQueue(testPolicy(), SenderFunc(func(ctx context.Context, delivery *models.Delivery) error { return nil }))
+
+	_, enqueueError := queue.EnqueueAll(context.Background(), []*models.Delivery{
+		{Kind: KindWebhook, Destination: "https://partner.example/hook"},
+		{Kind: "ftp", Destination: "ftp://partner.example"},
+	})
+	if !errors.Is(enqueueError, ErrUnknownKind) || len(store.deliveries) != 0 {
+		t.Errorf("Expected ErrUnknownKind and nothing queued, got %v with %d queued", enqueueError, len(store.deliveries))
+	}
+
+	queued, enqueueError := queue.EnqueueAll(context.Background(), []*models.Delivery{
+		{Kind: KindWebhook, Destination: "https://one.example/hook"},
+		{Kind: KindWebhook, Destination: "https://two.example/hook"},
+	})
+	if enqueueError != nil || len(queued) != 2 || len(store.deliveries) != 2 {
+		t.Errorf("Expected both deliveries queued, got %v with %d queued", enqueueError, len(store.deliveries))
+	}
+}
+
+// TestQueue_RetryAndDiscardDeadLetters verifies dead letters can be requeued or dropped
+func TestQueue_RetryAndDiscardDeadLetters(t *testing.T) {
+	queue, store, _ := newTestQueue(testPolicy(), SenderFunc(func(ctx context.Context, delivery *models.Delivery) error {
+		return Permanent(errors.New("rejected"))
+	}))
+
+	first, _ := queue.Enqueue(context.Background(), KindWebhook, "https://partner.example/hook", "application/json", []byte(`{}`))
+	second, _ := queue.Enqueue(context.Background(), KindWebhook, "https://partner.example/hook", "application/json", []byte(`{}`))
+	queue.ProcessDue(context.Background())
+
+	if deadLetters, _ := queue.DeadLetters(context.Background(), 10, 0); len(deadLetters) != 2 {
+		t.Fatalf("Expected two dead letters, got %d", len(deadLetters))
+	}
+
+	if retryError := queue.Retry(context.Background(), first.ID); retryError != nil {
+		t.Fatalf("Expected no error retrying, got %v", retryError)
+	}
+	if stored, _ := store.get(first.ID); stored.Status != models.DeliveryStatusPending || stored.Attempts != 0 {
+		t.Errorf("Expected requeued delivery, got %+v", stored)
+	}
+	if retryError := queue.Retry(context.Background(), first.ID); !errors.Is(retryError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows retrying a pending delivery, got %v", retryError)
+	}
+
+	if discardError := queue.Discard(context.Background(), second.ID); discardError != nil {
+		t.Fatalf("Expected no error discarding, got %v", discardError)
+	}
+	if _, exists := store.get(second.ID); exists {
+		t.Error("Expected discarded dead letter to be removed")
+	}
+}
@@ -0,0 +1,130 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Headers sent with every webhook delivery so receivers can deduplicate retries
+const (
+	HeaderDeliveryID      = "X-Delivery-ID"
+	HeaderDeliveryAttempt = "X-Delivery-Attempt"
+)
+
+// HTTPSender posts webhook payloads to their destination URL
+type HTTPSender struct {
+	client *http.Client
+}
+
+// NewHTTPSender creates a webhook sender; each request is bounded by the queue's send timeout
+func NewHTTPSender() *HTTPSender {
+	return &HTTPSender{client: &http.Client{}}
+}
+
+// Send posts the payload to the delivery's destination URL
+func (sender *HTTPSender) Send(ctx context.Context, delivery *models.Delivery) error {
+	return sender.Post(ctx, delivery, delivery.Destination, nil)
+}
+
+// Post posts the payload to endpoint with the extra headers; 2xx succeeds, 408, 429 and 5xx are retried, and
+// other statuses fail permanently
+// The request carries a traceparent header, so the receiver can continue the delivery's trace
+func (sender *HTTPSender) Post(ctx context.Context, delivery *models.Delivery, endpoint string, header http.Header) error {
+	ctx, span := tracing.Start(ctx, "delivery.Send", attribute.Int64("delivery.id", delivery.ID), attribute.Int("delivery.attempt", delivery.Attempts+1))
+	defer span.End()
+
+	request, requestError := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(delivery.Payload))
+	if requestError != nil {
+		return Permanent(requestError)
+	}
+	for name, values := range header {
+		request.Header[name] = values
+	}
+	if delivery.ContentType != "" {
+		request.Header.Set("Content-Type", delivery.ContentType)
+	}
+	request.Header.Set(HeaderDeliveryID, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(HeaderDeliveryAttempt, strconv.Itoa(delivery.Attempts+1))
+	tracing.Inject(ctx, request.Header)
+
+	response, sendError := sender.client.Do(request)
+	if sendError != nil {
+		return sendError
+	}
+	response.Body.Close()
+
+	statusError := fmt.Errorf("webhook responded %d", response.StatusCode)
+	switch {
+	case response.StatusCode >= 200 && response.StatusCode < 300:
+		return nil
+	case response.StatusCode == http.StatusRequestTimeout, response.StatusCode == http.StatusTooManyRequests, response.StatusCode >= 500:
+		return statusError
+	default:
+		return Permanent(statusError)
+	}
+}
+
+// webhookEvent is the JSON payload sent to webhook destinations for a resource event
+type webhookEvent struct {
+	Type         events.EventType `json:"type"`
+	ResourceType string           `json:"resource_type"`
+	ResourceID   string           `json:"resource_id"`
+	Version      int              `json:"version,omitempty"`
+	TenantID     string           `json:"tenant_id"`
+	OccurredAt   time.Time        `json:"occurred_at"`
+}
+
+// EventWebhooks returns an event bus handler that queues every resource event for each webhook URL
+// Sends happen from the queue, so a slow or failing receiver never blocks the bus
+func EventWebhooks(queue *Queue, webhookURLs []string) events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		payload, encodeError := json.Marshal(webhookEvent{
+			Type:         event.Type,
+			ResourceType: event.ResourceType,
+			ResourceID:   event.ResourceID,
+			Version:      event.Version,
+			TenantID:     event.TenantID,
+			OccurredAt:   event.OccurredAt,
+		})
+		if encodeError != nil {
+			return encodeError
+		}
+
+		for _, webhookURL := range webhookURLs {
+			if _, enqueueError := queue.Enqueue(ctx, KindWebhook, webhookURL, "application/json", payload); enqueueError != nil {
+				return enqueueError
+			}
+		}
+		return nil
+	}
+}
+
+// ParseWebhookURLs parses a comma-separated list of absolute http(s) URLs
+func ParseWebhookURLs(rawURLs string) ([]string, error) {
+	webhookURLs := []string{}
+	for _, rawURL := range strings.Split(rawURLs, ",") {
+		rawURL = strings.TrimSpace(rawURL)
+		if rawURL == "" {
+			continue
+		}
+
+		parsedURL, parseError := url.Parse(rawURL)
+		if parseError != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+			return nil, fmt.Errorf("invalid webhook URL %q: expected an absolute http or https URL", rawURL)
+		}
+		webhookURLs = append(webhookURLs, rawURL)
+	}
+	return webhookURLs, nil
+}
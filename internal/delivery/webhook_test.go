@@ -0,0 +1,87 @@
+package delivery
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestHTTPSender_ClassifiesResponses verifies which statuses succeed, retry, or fail permanently
+func TestHTTPSender_ClassifiesResponses(t *testing.T) {
+	testCases := []struct {
+		statusCode      int
+		expectError     bool
+		expectPermanent bool
+	}{
+		{http.StatusNoContent, false, false},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusServiceUnavailable, true, false},
+		{http.StatusGone, true, true},
+	}
+
+	for _, testCase := range testCases {
+		var receivedHeaders http.Header
+		var receivedBody string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			receivedHeaders = r.Header
+			bodyBytes, _ := io.ReadAll(r.Body)
+			receivedBody = string(bodyBytes)
+			w.WriteHeader(testCase.statusCode)
+		}))
+
+		sendError := NewHTTPSender().Send(context.Background(), &models.Delivery{
+			ID: 7, Destination: server.URL, ContentType: "application/json", Payload: []byte(`{"a":1}`), Attempts: 2,
+		})
+		server.Close()
+
+		if (sendError != nil) != testCase.expectError || IsPermanent(sendError) != testCase.expectPermanent {
+			t.Errorf("Status %d: unexpected result %v", testCase.statusCode, sendError)
+		}
+		if receivedBody != `{"a":1}` || receivedHeaders.Get(HeaderDeliveryID) != "7" || receivedHeaders.Get(HeaderDeliveryAttempt) != "3" {
+			t.Errorf("Status %d: unexpected request %q %v", testCase.statusCode, receivedBody, receivedHeaders)
+		}
+	}
+}
+
+// TestEventWebhooks_QueuesEachDestination verifies an event is queued once per webhook URL
+func TestEventWebhooks_QueuesEachDestination(t *testing.T) {
+	queue, store, _ := newTestQueue(testPolicy(), NewHTTPSender())
+	handler := EventWebhooks(queue, []string{"https://a.example/hook", "https://b.example/hook"})
+
+	handlerError := handler(context.Background(), events.Event{
+		Type: events.EventResourceCreated, ResourceType: "Patient", ResourceID: "123", TenantID: "acme", OccurredAt: time.Now(),
+	})
+	if handlerError != nil {
+		t.Fatalf("Expected no error, got %v", handlerError)
+	}
+
+	if len(store.deliveries) != 2 {
+		t.Fatalf("Expected two queued deliveries, got %d", len(store.deliveries))
+	}
+	var payload map[string]interface{}
+	json.Unmarshal(store.deliveries[1].Payload, &payload)
+	if payload["resource_id"] != "123" || payload["type"] != "resource.created" || store.deliveries[1].Kind != KindWebhook {
+		t.Errorf("Unexpected payload: %v", payload)
+	}
+}
+
+// TestParseWebhookURLs verifies parsing and rejection of relative or non-HTTP URLs
+func TestParseWebhookURLs(t *testing.T) {
+	webhookURLs, parseError := ParseWebhookURLs(" https://a.example/hook, ,http://b.example ")
+	if parseError != nil || len(webhookURLs) != 2 || webhookURLs[0] != "https://a.example/hook" {
+		t.Errorf("Unexpected parse result: %v %v", webhookURLs, parseError)
+	}
+
+	for _, invalid := range []string{"/relative", "ftp://a.example", "https://"} {
+		if _, parseError := ParseWebhookURLs(invalid); parseError == nil {
+			t.Errorf("Expected error for %q", invalid)
+		}
+	}
+}
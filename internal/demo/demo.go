@@ -0,0 +1,364 @@
+package demo
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SampleGenerator produces a synthetic FHIR resource for a given seed
+// Generated data is entirely fictional so it is safe to expose in docs and client tests
+type SampleGenerator func(seed int) interface{}
+
+// syntheticPerson is a fictional identity used when generating sample resources
+type syntheticPerson struct {
+	FamilyName string
+	GivenName  string
+	Gender     fhir.AdministrativeGender
+	BirthDate  string
+}
+
+// syntheticPeople is the fixed pool of fictional identities used by the generators
+var syntheticPeople = []syntheticPerson{
+	{FamilyName: "Smith", GivenName: "John", Gender: fhir.AdministrativeGenderMale, BirthDate: "1990-01-15"},
+	{FamilyName: "Example", GivenName: "Jane", Gender: fhir.AdministrativeGenderFemale, BirthDate: "1985-06-30"},
+	{FamilyName: "Sample", GivenName: "Alex", Gender: fhir.AdministrativeGenderOther, BirthDate: "2001-11-02"},
+}
+
+// Handler serves generated sample resources for every supported FHIR resource type
+type Handler struct {
+	generators map[string]SampleGenerator
+}
+
+// NewHandler creates a demo handler with generators for all supported resource types
+func NewHandler() *Handler {
+	return &Handler{
+		generators: map[string]SampleGenerator{
+			"Patient":            GeneratePatient,
+			"Observation":        GenerateObservation,
+			"Practitioner":       GeneratePractitioner,
+			"Encounter":          GenerateEncounter,
+			"Condition":          GenerateCondition,
+			"MedicationRequest":  GenerateMedicationRequest,
+			"AllergyIntolerance": GenerateAllergyIntolerance,
+			"DiagnosticReport":   GenerateDiagnosticReport,
+			"Immunization":       GenerateImmunization,
+			"AuditEvent":         GenerateAuditEvent,
+			"Subscription":       GenerateSubscription,
+		},
+	}
+}
+
+// ResourceTypes returns the resource types the demo handler can generate samples for
+func (handler *Handler) ResourceTypes() []string {
+	resourceTypes := make([]string, 0, len(handler.generators))
+	for resourceType := range handler.generators {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	return resourceTypes
+}
+
+// RegisterRoutes registers GET /fhir/{type}/sample for every supported resource type
+// Routes are only registered when demo mode is enabled, so nothing is exposed otherwise
+func (handler *Handler) RegisterRoutes(router chi.Router) {
+	for resourceType, generator := range handler.generators {
+		router.Get("/fhir/"+resourceType+"/sample", handler.serveSample(generator))
+	}
+}
+
+// serveSample returns an HTTP handler that writes the generated sample resource
+func (handler *Handler) serveSample(generator SampleGenerator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Optional seed selects a different synthetic identity (defaults to 0)
+		seed := 0
+		if seedParam := r.URL.Query().Get("seed"); seedParam != "" {
+			parsedSeed, parseError := strconv.Atoi(seedParam)
+			if parseError != nil || parsedSeed < 0 {
+				middleware.WriteError(w, r, apperrors.InvalidInput("seed", "must be a non-negative integer"))
+				return
+			}
+			seed = parsedSeed
+		}
+
+		// Set FHIR-compliant response headers
+		w.Header().Set("Content-Type", "application/fhir+json")
+		w.WriteHeader(http.StatusOK)
+
+		// Encode and return the generated resource as JSON
+		json.NewEncoder(w).Encode(generator(seed))
+	}
+}
+
+// personForSeed picks a synthetic identity deterministically from the seed
+func personForSeed(seed int) syntheticPerson {
+	return syntheticPeople[seed%len(syntheticPeople)]
+}
+
+// sampleID builds a stable sample resource ID for a seed
+func sampleID(seed int) string {
+	return strconv.Itoa(12345 + seed)
+}
+
+// GeneratePatient builds a synthetic FHIR Patient resource
+func GeneratePatient(seed int) interface{} {
+	person := personForSeed(seed)
+	patientID := sampleID(seed)
+	identifierSystem := "http://hospital.example.org/patients"
+	familyName := person.FamilyName
+	gender := person.Gender
+	birthDate := person.BirthDate
+	active := true
+
+	// Build the FHIR Patient resource following R4 specification
+	return fhir.Patient{
+		Id:     &patientID,
+		Active: &active,
+		Name: []fhir.HumanName{
+			{
+				Family: &familyName,
+				Given:  []string{person.GivenName},
+			},
+		},
+		Gender:    &gender,
+		BirthDate: &birthDate,
+		Identifier: []fhir.Identifier{
+			{
+				System: &identifierSystem,
+				Value:  &patientID,
+			},
+		},
+	}
+}
+
+// GenerateObservation builds a synthetic heart rate Observation for the sample patient
+func GenerateObservation(seed int) interface{} {
+	observationID := "obs-" + sampleID(seed)
+	patientReference := "Patient/" + sampleID(seed)
+	categoryCode := "vital-signs"
+	codeSystem := "http://loinc.org"
+	code := "8867-4"
+	codeDisplay := "Heart rate"
+	effectiveDateTime := "2024-01-15T09:30:00Z"
+	unit := "beats/minute"
+	value := json.Number(strconv.Itoa(60 + (seed*7)%40))
+
+	// Build the FHIR Observation resource following R4 specification
+	return fhir.Observation{
+		Id:     &observationID,
+		Status: fhir.ObservationStatusFinal,
+		Category: []fhir.CodeableConcept{
+			{Coding: []fhir.Coding{{Code: &categoryCode}}},
+		},
+		Code: fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: &codeSystem, Code: &code, Display: &codeDisplay}},
+		},
+		Subject:           &fhir.Reference{Reference: &patientReference},
+		EffectiveDateTime: &effectiveDateTime,
+		ValueQuantity:     &fhir.Quantity{Value: &value, Unit: &unit},
+	}
+}
+
+// samplePatientReference references the sample patient generated for the same seed
+func samplePatientReference(seed int) fhir.Reference {
+	patientReference := "Patient/" + sampleID(seed)
+	return fhir.Reference{Reference: &patientReference}
+}
+
+// GeneratePractitioner builds a synthetic Practitioner with a family medicine qualification
+func GeneratePractitioner(seed int) interface{} {
+	person := personForSeed(seed + 1)
+	practitionerID := "prac-" + sampleID(seed)
+	identifierSystem := "http://hl7.org/fhir/sid/us-npi"
+	identifierValue := strconv.Itoa(1234567890 + seed)
+	familyName := person.FamilyName
+	prefix := "Dr."
+	qualificationSystem := "http://terminology.hl7.org/CodeSystem/v2-0360"
+	qualificationCode := "MD"
+	active := true
+
+	return fhir.Practitioner{
+		Id:         &practitionerID,
+		Active:     &active,
+		Identifier: []fhir.Identifier{{System: &identifierSystem, Value: &identifierValue}},
+		Name: []fhir.HumanName{
+			{Family: &familyName, Given: []string{person.GivenName}, Prefix: []string{prefix}},
+		},
+		Qualification: []fhir.PractitionerQualification{
+			{Code: fhir.CodeableConcept{Coding: []fhir.Coding{{System: &qualificationSystem, Code: &qualificationCode}}}},
+		},
+	}
+}
+
+// GenerateEncounter builds a synthetic finished ambulatory Encounter for the sample patient
+func GenerateEncounter(seed int) interface{} {
+	encounterID := "enc-" + sampleID(seed)
+	classSystem := "http://terminology.hl7.org/CodeSystem/v3-ActCode"
+	classCode := "AMB"
+	classDisplay := "ambulatory"
+	periodStart := "2024-01-15T09:00:00Z"
+	periodEnd := "2024-01-15T09:45:00Z"
+	subject := samplePatientReference(seed)
+
+	return fhir.Encounter{
+		Id:      &encounterID,
+		Status:  fhir.EncounterStatusFinished,
+		Class:   fhir.Coding{System: &classSystem, Code: &classCode, Display: &classDisplay},
+		Subject: &subject,
+		Period:  &fhir.Period{Start: &periodStart, End: &periodEnd},
+	}
+}
+
+// GenerateCondition builds a synthetic active hypertension Condition for the sample patient
+func GenerateCondition(seed int) interface{} {
+	conditionID := "cond-" + sampleID(seed)
+	clinicalStatusSystem := "http://terminology.hl7.org/CodeSystem/condition-clinical"
+	clinicalStatus := "active"
+	codeSystem := "http://snomed.info/sct"
+	code := "38341003"
+	codeDisplay := "Hypertensive disorder"
+	onsetDateTime := "2020-03-01"
+
+	return fhir.Condition{
+		Id:             &conditionID,
+		ClinicalStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &clinicalStatusSystem, Code: &clinicalStatus}}},
+		Code:           &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &codeSystem, Code: &code, Display: &codeDisplay}}},
+		Subject:        samplePatientReference(seed),
+		OnsetDateTime:  &onsetDateTime,
+	}
+}
+
+// GenerateMedicationRequest builds a synthetic active lisinopril order for the sample patient
+func GenerateMedicationRequest(seed int) interface{} {
+	medicationRequestID := "medreq-" + sampleID(seed)
+	medicationSystem := "http://www.nlm.nih.gov/research/umls/rxnorm"
+	medicationCode := "314076"
+	medicationDisplay := "lisinopril 10 MG Oral Tablet"
+	authoredOn := "2024-01-15"
+	dosageText := "Take one tablet by mouth once daily"
+
+	return fhir.MedicationRequest{
+		Id:     &medicationRequestID,
+		Status: "active",
+		Intent: "order",
+		MedicationCodeableConcept: fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: &medicationSystem, Code: &medicationCode, Display: &medicationDisplay}},
+		},
+		Subject:           samplePatientReference(seed),
+		AuthoredOn:        &authoredOn,
+		DosageInstruction: []fhir.Dosage{{Text: &dosageText}},
+	}
+}
+
+// GenerateAllergyIntolerance builds a synthetic peanut allergy for the sample patient
+func GenerateAllergyIntolerance(seed int) interface{} {
+	allergyID := "allergy-" + sampleID(seed)
+	clinicalStatusSystem := "http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical"
+	clinicalStatus := "active"
+	codeSystem := "http://snomed.info/sct"
+	code := "91935009"
+	codeDisplay := "Allergy to peanut"
+	allergyType := fhir.AllergyIntoleranceTypeAllergy
+	criticality := fhir.AllergyIntoleranceCriticalityHigh
+
+	return fhir.AllergyIntolerance{
+		Id:             &allergyID,
+		ClinicalStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &clinicalStatusSystem, Code: &clinicalStatus}}},
+		Type:           &allergyType,
+		Category:       []fhir.AllergyIntoleranceCategory{fhir.AllergyIntoleranceCategoryFood},
+		Criticality:    &criticality,
+		Code:           &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &codeSystem, Code: &code, Display: &codeDisplay}}},
+		Patient:        samplePatientReference(seed),
+	}
+}
+
+// GenerateDiagnosticReport builds a synthetic final lab report whose result is the sample observation
+func GenerateDiagnosticReport(seed int) interface{} {
+	reportID := "report-" + sampleID(seed)
+	categorySystem := "http://terminology.hl7.org/CodeSystem/v2-0074"
+	categoryCode := "LAB"
+	codeSystem := "http://loinc.org"
+	code := "58410-2"
+	codeDisplay := "CBC panel - Blood by Automated count"
+	effectiveDateTime := "2024-01-15T09:30:00Z"
+	observationReference := "Observation/obs-" + sampleID(seed)
+	subject := samplePatientReference(seed)
+
+	return fhir.DiagnosticReport{
+		Id:     &reportID,
+		Status: fhir.DiagnosticReportStatusFinal,
+		Category: []fhir.CodeableConcept{
+			{Coding: []fhir.Coding{{System: &categorySystem, Code: &categoryCode}}},
+		},
+		Code:              fhir.CodeableConcept{Coding: []fhir.Coding{{System: &codeSystem, Code: &code, Display: &codeDisplay}}},
+		Subject:           &subject,
+		EffectiveDateTime: &effectiveDateTime,
+		Result:            []fhir.Reference{{Reference: &observationReference}},
+	}
+}
+
+// GenerateImmunization builds a synthetic completed influenza vaccination for the sample patient
+func GenerateImmunization(seed int) interface{} {
+	immunizationID := "imm-" + sampleID(seed)
+	vaccineSystem := "http://hl7.org/fhir/sid/cvx"
+	vaccineCode := "140"
+	vaccineDisplay := "Influenza, seasonal, injectable, preservative free"
+	lotNumber := "LOT-" + sampleID(seed)
+
+	return fhir.Immunization{
+		Id:     &immunizationID,
+		Status: fhir.ImmunizationStatusCodesCompleted,
+		VaccineCode: fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: &vaccineSystem, Code: &vaccineCode, Display: &vaccineDisplay}},
+		},
+		Patient:            samplePatientReference(seed),
+		OccurrenceDateTime: "2023-10-01",
+		LotNumber:          &lotNumber,
+	}
+}
+
+// GenerateAuditEvent builds a synthetic AuditEvent recording a successful read of the sample patient
+func GenerateAuditEvent(seed int) interface{} {
+	auditEventID := "audit-" + sampleID(seed)
+	typeSystem := "http://terminology.hl7.org/CodeSystem/audit-event-type"
+	typeCode := "rest"
+	agentName := "api-key:demo"
+	observerDisplay := "fhir-health-interop"
+	action := fhir.AuditEventActionR
+	outcome := fhir.AuditEventOutcome0
+	patientReference := samplePatientReference(seed)
+
+	return fhir.AuditEvent{
+		Id:       &auditEventID,
+		Type:     fhir.Coding{System: &typeSystem, Code: &typeCode},
+		Action:   &action,
+		Recorded: "2024-01-15T09:30:00Z",
+		Outcome:  &outcome,
+		Agent:    []fhir.AuditEventAgent{{Name: &agentName, Requestor: true}},
+		Source:   fhir.AuditEventSource{Observer: fhir.Reference{Display: &observerDisplay}},
+		Entity:   []fhir.AuditEventEntity{{What: &patientReference}},
+	}
+}
+
+// GenerateSubscription builds a synthetic REST-hook Subscription to the sample patient's observations
+func GenerateSubscription(seed int) interface{} {
+	subscriptionID := "sub-" + sampleID(seed)
+	endpoint := "https://partner.example.org/fhir-hook"
+	payload := "application/fhir+json"
+
+	return fhir.Subscription{
+		Id:       &subscriptionID,
+		Status:   fhir.SubscriptionStatusActive,
+		Reason:   "Notify the care team of new observations",
+		Criteria: "Observation?patient=" + sampleID(seed),
+		Channel: fhir.SubscriptionChannel{
+			Type:     fhir.SubscriptionChannelTypeRestHook,
+			Endpoint: &endpoint,
+			Payload:  &payload,
+		},
+	}
+}
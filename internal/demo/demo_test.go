@@ -0,0 +1,175 @@
+package demo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/capability"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newDemoRouter creates a router with demo routes registered
+func newDemoRouter() *chi.Mux {
+	router := chi.NewRouter()
+	NewHandler().RegisterRoutes(router)
+	return router
+}
+
+// TestHandler_SamplePatient verifies the sample patient endpoint returns correct FHIR data
+func TestHandler_SamplePatient(t *testing.T) {
+	router := newDemoRouter()
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient/sample", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+
+	// Verify HTTP status code is 200 OK
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, responseRecorder.Code)
+	}
+
+	// Verify Content-Type header is application/fhir+json
+	contentType := responseRecorder.Header().Get("Content-Type")
+	if contentType != "application/fhir+json" {
+		t.Errorf("Expected Content-Type application/fhir+json, got %s", contentType)
+	}
+
+	// Parse the JSON response body into FHIR Patient struct
+	var patientResponse fhir.Patient
+	decodeError := json.NewDecoder(responseRecorder.Body).Decode(&patientResponse)
+	if decodeError != nil {
+		t.Fatalf("Failed to decode response body: %v", decodeError)
+	}
+
+	// Verify the default seed produces the documented sample patient
+	if patientResponse.Id == nil || *patientResponse.Id != "12345" {
+		t.Errorf("Expected patient ID 12345, got %v", patientResponse.Id)
+	}
+	if patientResponse.Active == nil || !*patientResponse.Active {
+		t.Error("Expected patient to be active")
+	}
+	if patientResponse.Gender == nil || *patientResponse.Gender != fhir.AdministrativeGenderMale {
+		t.Errorf("Expected gender male, got %v", patientResponse.Gender)
+	}
+	if patientResponse.BirthDate == nil || *patientResponse.BirthDate != "1990-01-15" {
+		t.Errorf("Expected birth date 1990-01-15, got %v", patientResponse.BirthDate)
+	}
+	if len(patientResponse.Name) == 0 || *patientResponse.Name[0].Family != "Smith" || patientResponse.Name[0].Given[0] != "John" {
+		t.Errorf("Expected name John Smith, got %+v", patientResponse.Name)
+	}
+	if len(patientResponse.Identifier) == 0 {
+		t.Error("Expected patient to have at least one identifier")
+	}
+}
+
+// TestHandler_SamplePatient_Seed verifies the seed parameter selects a different identity
+func TestHandler_SamplePatient_Seed(t *testing.T) {
+	router := newDemoRouter()
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient/sample?seed=1", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+
+	var patientResponse fhir.Patient
+	json.NewDecoder(responseRecorder.Body).Decode(&patientResponse)
+
+	if *patientResponse.Id != "12346" {
+		t.Errorf("Expected patient ID 12346, got %s", *patientResponse.Id)
+	}
+	if *patientResponse.Name[0].Family != "Example" {
+		t.Errorf("Expected family name Example, got %s", *patientResponse.Name[0].Family)
+	}
+}
+
+// TestHandler_InvalidSeed verifies a bad seed is rejected
+func TestHandler_InvalidSeed(t *testing.T) {
+	router := newDemoRouter()
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient/sample?seed=abc", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", responseRecorder.Code)
+	}
+	if !strings.Contains(responseRecorder.Body.String(), `"code":"INVALID_INPUT"`) {
+		t.Errorf("Expected the standard error body, got %s", responseRecorder.Body.String())
+	}
+}
+
+// TestHandler_SampleObservation verifies the sample observation references the sample patient
+func TestHandler_SampleObservation(t *testing.T) {
+	router := newDemoRouter()
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation/sample", nil)
+	responseRecorder := httptest.NewRecorder()
+	router.ServeHTTP(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", responseRecorder.Code)
+	}
+
+	var observationResponse fhir.Observation
+	decodeError := json.NewDecoder(responseRecorder.Body).Decode(&observationResponse)
+	if decodeError != nil {
+		t.Fatalf("Failed to decode response body: %v", decodeError)
+	}
+
+	if observationResponse.Subject == nil || *observationResponse.Subject.Reference != "Patient/12345" {
+		t.Errorf("Expected subject Patient/12345, got %+v", observationResponse.Subject)
+	}
+	if observationResponse.ValueQuantity == nil {
+		t.Error("Expected observation to have a value quantity")
+	}
+}
+
+// TestHandler_SampleForEverySupportedType verifies every resource type in the CapabilityStatement has a sample,
+// so a new resource type cannot be added without a demo generator
+func TestHandler_SampleForEverySupportedType(t *testing.T) {
+	router := newDemoRouter()
+	statement := capability.Statement(capability.Resources(), time.Now())
+
+	for _, resource := range statement.Rest[0].Resource {
+		resourceType := resource.Type.Code()
+		request := httptest.NewRequest(http.MethodGet, "/fhir/"+resourceType+"/sample?seed=1", nil)
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+
+		if responseRecorder.Code != http.StatusOK {
+			t.Errorf("Expected status 200 for the %s sample, got %d", resourceType, responseRecorder.Code)
+			continue
+		}
+
+		var sample map[string]interface{}
+		if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&sample); decodeError != nil {
+			t.Fatalf("Failed to decode the %s sample: %v", resourceType, decodeError)
+		}
+		if sample["resourceType"] != resourceType {
+			t.Errorf("Expected resourceType %s, got %v", resourceType, sample["resourceType"])
+		}
+		if sampleID, _ := sample["id"].(string); !strings.HasSuffix(sampleID, "12346") {
+			t.Errorf("Expected the %s sample ID to follow the seed, got %v", resourceType, sample["id"])
+		}
+	}
+}
+
+// TestHandler_RoutesNotRegistered verifies sample routes do not exist when demo mode is off
+func TestHandler_RoutesNotRegistered(t *testing.T) {
+	// A router without demo routes registered mirrors demo mode being disabled
+	router := chi.NewRouter()
+
+	for _, resourceType := range NewHandler().ResourceTypes() {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/"+resourceType+"/sample", nil)
+		responseRecorder := httptest.NewRecorder()
+		router.ServeHTTP(responseRecorder, request)
+
+		if responseRecorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %s sample, got %d", resourceType, responseRecorder.Code)
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package deprecation
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Feature is a route, or a parameter on a route, that clients should stop using
+type Feature struct {
+	// ID names the feature in usage metrics
+	ID string `json:"id"`
+
+	// Method restricts the feature to one HTTP method; empty matches every method
+	Method string `json:"method,omitempty"`
+
+	// Route is the chi route pattern, such as /fhir/Patient/{id}
+	Route string `json:"route"`
+
+	// Parameter restricts the feature to requests carrying this query parameter
+	Parameter string `json:"parameter,omitempty"`
+
+	// DeprecatedAt is when the feature was deprecated, reported in the Deprecation header
+	DeprecatedAt time.Time `json:"deprecated_at"`
+
+	// Sunset is when the feature is expected to stop working; zero when not yet scheduled
+	Sunset time.Time `json:"sunset"`
+
+	// Link points to migration documentation
+	Link string `json:"link,omitempty"`
+
+	// Message tells clients what to use instead
+	Message string `json:"message"`
+}
+
+// Config lists the deprecated features
+type Config struct {
+	Features []Feature `json:"features"`
+}
+
+// LoadConfig reads and validates a deprecation configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return config, fmt.Errorf("failed to read deprecation config: %w", readError)
+	}
+
+	if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+		return config, fmt.Errorf("failed to parse deprecation config: %w", decodeError)
+	}
+
+	if validationError := config.Validate(); validationError != nil {
+		return config, validationError
+	}
+
+	return config, nil
+}
+
+// Validate checks that every feature is identifiable, routable and dated consistently
+func (config Config) Validate() error {
+	seenIDs := make(map[string]bool, len(config.Features))
+	for index, feature := range config.Features {
+		if feature.ID == "" {
+			return fmt.Errorf("deprecated feature %d has no id", index)
+		}
+		if seenIDs[feature.ID] {
+			return fmt.Errorf("deprecated feature %q is listed more than once", feature.ID)
+		}
+		seenIDs[feature.ID] = true
+
+		if !strings.HasPrefix(feature.Route, "/") {
+			return fmt.Errorf("deprecated feature %q needs a route starting with /", feature.ID)
+		}
+		if feature.DeprecatedAt.IsZero() {
+			return fmt.Errorf("deprecated feature %q has no deprecated_at date", feature.ID)
+		}
+		if !feature.Sunset.IsZero() && feature.Sunset.Before(feature.DeprecatedAt) {
+			return fmt.Errorf("deprecated feature %q has a sunset before its deprecation", feature.ID)
+		}
+	}
+	return nil
+}
+
+// matches reports whether the feature applies to a request routed to routePattern
+func (feature Feature) matches(method string, routePattern string, r *http.Request) bool {
+	if feature.Route != routePattern {
+		return false
+	}
+	if feature.Method != "" && !strings.EqualFold(feature.Method, method) {
+		return false
+	}
+	if feature.Parameter != "" && !r.URL.Query().Has(feature.Parameter) {
+		return false
+	}
+	return true
+}
+
+// FeatureUsage reports how often a deprecated feature is still used
+type FeatureUsage struct {
+	Feature Feature `json:"feature"`
+
+	// Requests is the number of requests that used the feature since startup
+	Requests int64 `json:"requests"`
+
+	// LastUsedAt is when the feature was last used; nil when unused
+	LastUsedAt *time.Time `json:"last_used_at"`
+
+	// Tenants counts requests per tenant so owners of remaining callers can be contacted
+	Tenants map[string]int64 `json:"tenants"`
+
+	// PastSunset is set once the sunset date has passed
+	PastSunset bool `json:"past_sunset"`
+}
+
+// featureStats holds mutable usage counters for one feature
+type featureStats struct {
+	requests     int64
+	lastUsedAt   time.Time
+	tenantCounts map[string]int64
+}
+
+// Registry holds the deprecated features and counts their use
+// It is safe for concurrent use by multiple request goroutines
+type Registry struct {
+	features []Feature
+
+	mutex sync.Mutex
+	stats map[string]*featureStats
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewRegistry creates a registry for the configured features
+func NewRegistry(config Config) *Registry {
+	stats := make(map[string]*featureStats, len(config.Features))
+	for _, feature := range config.Features {
+		stats[feature.ID] = &featureStats{tenantCounts: make(map[string]int64)}
+	}
+
+	return &Registry{
+		features: config.Features,
+		stats:    stats,
+		now:      time.Now,
+	}
+}
+
+// Match returns the features that apply to a request routed to routePattern
+func (registry *Registry) Match(r *http.Request, routePattern string) []Feature {
+	var matched []Feature
+	for _, feature := range registry.features {
+		if feature.matches(r.Method, routePattern, r) {
+			matched = append(matched, feature)
+		}
+	}
+	return matched
+}
+
+// Record counts one use of a feature by a tenant
+func (registry *Registry) Record(featureID string, tenantID string) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	stats, exists := registry.stats[featureID]
+	if !exists {
+		return
+	}
+	stats.requests++
+	stats.lastUsedAt = registry.now()
+	stats.tenantCounts[tenantID]++
+}
+
+// Usage returns usage for every configured feature, sorted by feature ID
+func (registry *Registry) Usage() []FeatureUsage {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	now := registry.now()
+	usage := make([]FeatureUsage, 0, len(registry.features))
+	for _, feature := range registry.features {
+		stats := registry.stats[feature.ID]
+
+		tenantCounts := make(map[string]int64, len(stats.tenantCounts))
+		for tenantID, count := range stats.tenantCounts {
+			tenantCounts[tenantID] = count
+		}
+
+		featureUsage := FeatureUsage{
+			Feature:    feature,
+			Requests:   stats.requests,
+			Tenants:    tenantCounts,
+			PastSunset: !feature.Sunset.IsZero() && !now.Before(feature.Sunset),
+		}
+		if stats.requests > 0 {
+			lastUsedAt := stats.lastUsedAt
+			featureUsage.LastUsedAt = &lastUsedAt
+		}
+		usage = append(usage, featureUsage)
+	}
+
+	sort.Slice(usage, func(left, right int) bool {
+		return usage[left].Feature.ID < usage[right].Feature.ID
+	})
+	return usage
+}
@@ -0,0 +1,117 @@
+package deprecation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testFeature returns a valid deprecated feature on the patient search route
+func testFeature() Feature {
+	return Feature{
+		ID:           "patient-search-bare-array",
+		Method:       http.MethodGet,
+		Route:        "/fhir/Patient",
+		DeprecatedAt: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:       time.Date(2027, 5, 1, 0, 0, 0, 0, time.UTC),
+		Link:         "https://example.com/migrate",
+		Message:      "use searchset Bundles",
+	}
+}
+
+// TestLoadConfig verifies features are read from a JSON file
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "deprecations.json")
+	os.WriteFile(configPath, []byte(`{"features": [{"id": "old-param", "route": "/fhir/Observation", "parameter": "subject",
+		"deprecated_at": "2026-11-01T00:00:00Z", "message": "use patient"}]}`), 0o600)
+
+	config, loadError := LoadConfig(configPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if len(config.Features) != 1 || config.Features[0].Parameter != "subject" || !config.Features[0].Sunset.IsZero() {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+}
+
+// TestConfig_Validate verifies incomplete or inconsistent features are rejected
+func TestConfig_Validate(t *testing.T) {
+	missingID := testFeature()
+	missingID.ID = ""
+	relativeRoute := testFeature()
+	relativeRoute.Route = "fhir/Patient"
+	undated := testFeature()
+	undated.DeprecatedAt = time.Time{}
+	earlySunset := testFeature()
+	earlySunset.Sunset = earlySunset.DeprecatedAt.Add(-time.Hour)
+
+	testCases := map[string][]Feature{
+		"missing id":     {missingID},
+		"duplicate id":   {testFeature(), testFeature()},
+		"relative route": {relativeRoute},
+		"undated":        {undated},
+		"early sunset":   {earlySunset},
+	}
+
+	for name, features := range testCases {
+		if validationError := (Config{Features: features}).Validate(); validationError == nil {
+			t.Errorf("%s: expected a validation error", name)
+		}
+	}
+
+	if validationError := (Config{Features: []Feature{testFeature()}}).Validate(); validationError != nil {
+		t.Errorf("Expected valid config, got %v", validationError)
+	}
+}
+
+// TestRegistry_Match verifies method and parameter restrictions
+func TestRegistry_Match(t *testing.T) {
+	parameterFeature := testFeature()
+	parameterFeature.ID = "patient-name-param"
+	parameterFeature.Method = ""
+	parameterFeature.Parameter = "name"
+	registry := NewRegistry(Config{Features: []Feature{testFeature(), parameterFeature}})
+
+	if matched := registry.Match(httptest.NewRequest(http.MethodGet, "/fhir/Patient?name=smith", nil), "/fhir/Patient"); len(matched) != 2 {
+		t.Errorf("Expected both features, got %+v", matched)
+	}
+	if matched := registry.Match(httptest.NewRequest(http.MethodPost, "/fhir/Patient", nil), "/fhir/Patient"); len(matched) != 0 {
+		t.Errorf("Expected no match for POST without the parameter, got %+v", matched)
+	}
+	if matched := registry.Match(httptest.NewRequest(http.MethodGet, "/fhir/Patient/123", nil), "/fhir/Patient/{id}"); len(matched) != 0 {
+		t.Errorf("Expected no match on another route, got %+v", matched)
+	}
+}
+
+// TestRegistry_Usage verifies requests are counted per feature and tenant
+func TestRegistry_Usage(t *testing.T) {
+	unusedFeature := testFeature()
+	unusedFeature.ID = "a-unused"
+	registry := NewRegistry(Config{Features: []Feature{testFeature(), unusedFeature}})
+	usedAt := time.Date(2027, 6, 1, 0, 0, 0, 0, time.UTC)
+	registry.now = func() time.Time { return usedAt }
+
+	registry.Record("patient-search-bare-array", "acme")
+	registry.Record("patient-search-bare-array", "acme")
+	registry.Record("patient-search-bare-array", "globex")
+	registry.Record("unknown", "acme")
+
+	usage := registry.Usage()
+	if len(usage) != 2 || usage[0].Feature.ID != "a-unused" {
+		t.Fatalf("Expected features sorted by ID, got %+v", usage)
+	}
+	if usage[0].Requests != 0 || usage[0].LastUsedAt != nil {
+		t.Errorf("Expected unused feature, got %+v", usage[0])
+	}
+
+	used := usage[1]
+	if used.Requests != 3 || used.Tenants["acme"] != 2 || used.Tenants["globex"] != 1 {
+		t.Errorf("Unexpected counts: %+v", used)
+	}
+	if used.LastUsedAt == nil || !used.LastUsedAt.Equal(usedAt) || !used.PastSunset {
+		t.Errorf("Expected last use recorded and sunset passed, got %+v", used)
+	}
+}
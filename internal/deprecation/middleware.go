@@ -0,0 +1,94 @@
+package deprecation
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Headers used to announce deprecations (RFC 9745) and sunsets (RFC 8594)
+const (
+	HeaderDeprecation = "Deprecation"
+	HeaderSunset      = "Sunset"
+)
+
+// Middleware announces deprecated features on the responses that use them and counts their use
+// Matching features set the Deprecation, Sunset and Link headers and add a warning to the request's
+// issue collector, so responses carrying an OperationOutcome include it
+// Global middleware runs before chi routes the request, so the route pattern is looked up in routes;
+// tenant.Resolver must run first for usage to be attributed to tenants
+func Middleware(registry *Registry, routes chi.Routes) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			routePattern := routes.Find(chi.NewRouteContext(), r.Method, r.URL.Path)
+			if routePattern == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			features := registry.Match(r, routePattern)
+			if len(features) == 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tenantID := tenant.FromContext(r.Context())
+			issueContext, _ := outcome.WithCollector(r.Context())
+			for _, feature := range features {
+				registry.Record(feature.ID, tenantID)
+				announce(w, feature)
+				outcome.Collect(issueContext, warningIssue(feature))
+
+				log.Debug().
+					Str("feature", feature.ID).
+					Str("tenant", tenantID).
+					Str("path", r.URL.Path).
+					Msg("Deprecated feature used")
+			}
+
+			next.ServeHTTP(w, r.WithContext(issueContext))
+		})
+	}
+}
+
+// announce sets the deprecation headers for one feature
+// When several features match, the first configured feature's dates are reported
+func announce(w http.ResponseWriter, feature Feature) {
+	headers := w.Header()
+
+	if headers.Get(HeaderDeprecation) == "" {
+		headers.Set(HeaderDeprecation, fmt.Sprintf("@%d", feature.DeprecatedAt.Unix()))
+	}
+	if headers.Get(HeaderSunset) == "" && !feature.Sunset.IsZero() {
+		headers.Set(HeaderSunset, feature.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if feature.Link != "" {
+		headers.Add("Link", fmt.Sprintf("<%s>; rel=\"deprecation\"", feature.Link))
+	}
+}
+
+// warningIssue describes the deprecation for OperationOutcome responses
+func warningIssue(feature Feature) outcome.Issue {
+	diagnostics := []string{"Deprecated: " + feature.Message}
+	if !feature.Sunset.IsZero() {
+		diagnostics = append(diagnostics, "removal is scheduled for "+feature.Sunset.UTC().Format("2006-01-02"))
+	}
+
+	var expression []string
+	if feature.Parameter != "" {
+		expression = []string{feature.Parameter}
+	}
+
+	return outcome.Issue{
+		Severity:    fhir.IssueSeverityWarning,
+		Code:        fhir.IssueTypeBusinessRule,
+		Diagnostics: strings.Join(diagnostics, "; "),
+		Expression:  expression,
+	}
+}
@@ -0,0 +1,74 @@
+package deprecation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// newTestRouter builds a router with the deprecation middleware and routes that report collected issues
+func newTestRouter(registry *Registry) *chi.Mux {
+	router := chi.NewRouter()
+	router.Use(Middleware(registry, router))
+
+	reportIssues := func(w http.ResponseWriter, r *http.Request) {
+		outcome.WriteForRequest(w, r, http.StatusOK, nil)
+	}
+	router.Get("/fhir/Patient", reportIssues)
+	router.Get("/fhir/Patient/{id}", reportIssues)
+	return router
+}
+
+// TestMiddleware_AnnouncesDeprecation verifies headers, the OperationOutcome warning and usage counting
+func TestMiddleware_AnnouncesDeprecation(t *testing.T) {
+	registry := NewRegistry(Config{Features: []Feature{testFeature()}})
+	router := newTestRouter(registry)
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?gender=female", nil)
+	request = request.WithContext(tenant.WithTenant(request.Context(), "acme"))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if deprecationHeader := recorder.Header().Get(HeaderDeprecation); deprecationHeader != "@1793491200" {
+		t.Errorf("Unexpected Deprecation header: %q", deprecationHeader)
+	}
+	if sunsetHeader := recorder.Header().Get(HeaderSunset); sunsetHeader != "Sat, 01 May 2027 00:00:00 GMT" {
+		t.Errorf("Unexpected Sunset header: %q", sunsetHeader)
+	}
+	if linkHeader := recorder.Header().Get("Link"); linkHeader != `<https://example.com/migrate>; rel="deprecation"` {
+		t.Errorf("Unexpected Link header: %q", linkHeader)
+	}
+
+	body := recorder.Body.String()
+	if !strings.Contains(body, "Deprecated: use searchset Bundles; removal is scheduled for 2027-05-01") || !strings.Contains(body, `"business-rule"`) {
+		t.Errorf("Expected deprecation warning in OperationOutcome, got %s", body)
+	}
+
+	if usage := registry.Usage(); usage[0].Requests != 1 || usage[0].Tenants["acme"] != 1 {
+		t.Errorf("Expected usage recorded for acme, got %+v", usage)
+	}
+}
+
+// TestMiddleware_IgnoresOtherRoutes verifies requests outside deprecated features are untouched
+func TestMiddleware_IgnoresOtherRoutes(t *testing.T) {
+	registry := NewRegistry(Config{Features: []Feature{testFeature()}})
+	router := newTestRouter(registry)
+
+	for _, target := range []string{"/fhir/Patient/123", "/unknown"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+
+		if recorder.Header().Get(HeaderDeprecation) != "" || strings.Contains(recorder.Body.String(), "Deprecated") {
+			t.Errorf("%s: expected no deprecation notice", target)
+		}
+	}
+
+	if usage := registry.Usage(); usage[0].Requests != 0 {
+		t.Errorf("Expected no usage, got %+v", usage)
+	}
+}
@@ -0,0 +1,105 @@
+package encoding
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// resourceTypes gives the model of each resource type the server reads or writes, so its XML converts to JSON
+// with the right arrays, numbers, and booleans; other resource types are converted with every value a string
+var resourceTypes = map[string]reflect.Type{
+	"AllergyIntolerance":  reflect.TypeOf(fhir.AllergyIntolerance{}),
+	"Bundle":              reflect.TypeOf(fhir.Bundle{}),
+	"CapabilityStatement": reflect.TypeOf(fhir.CapabilityStatement{}),
+	"Condition":           reflect.TypeOf(fhir.Condition{}),
+	"DiagnosticReport":    reflect.TypeOf(fhir.DiagnosticReport{}),
+	"Encounter":           reflect.TypeOf(fhir.Encounter{}),
+	"Immunization":        reflect.TypeOf(fhir.Immunization{}),
+	"MedicationRequest":   reflect.TypeOf(fhir.MedicationRequest{}),
+	"Observation":         reflect.TypeOf(fhir.Observation{}),
+	"OperationOutcome":    reflect.TypeOf(fhir.OperationOutcome{}),
+	"Parameters":          reflect.TypeOf(fhir.Parameters{}),
+	"Patient":             reflect.TypeOf(fhir.Patient{}),
+	"Practitioner":        reflect.TypeOf(fhir.Practitioner{}),
+}
+
+// rawMessageType marks elements holding a whole resource, such as Bundle.entry.resource
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// extensionType is the model of extensions, the only children a primitive element may have
+var extensionType = reflect.TypeOf(fhir.Extension{})
+
+// jsonNumberType marks decimal elements, which the models keep as json.Number
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// jsonUnmarshalerType marks code elements, which the models keep as integer enums that read and write strings
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// modelFieldCache holds the fields of each model already looked at, keyed by its reflect.Type
+var modelFieldCache sync.Map
+
+// modelFields returns the types of a model's fields keyed by their JSON names, or nil for a nil model
+func modelFields(modelType reflect.Type) map[string]reflect.Type {
+	if modelType == nil {
+		return nil
+	}
+	if cachedFields, cached := modelFieldCache.Load(modelType); cached {
+		return cachedFields.(map[string]reflect.Type)
+	}
+
+	fields := make(map[string]reflect.Type, modelType.NumField())
+	for index := 0; index < modelType.NumField(); index++ {
+		field := modelType.Field(index)
+		jsonName, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if jsonName != "" && jsonName != "-" {
+			fields[jsonName] = field.Type
+		}
+	}
+	modelFieldCache.Store(modelType, fields)
+	return fields
+}
+
+// isRepeated reports whether a field holds a JSON array
+func isRepeated(fieldType reflect.Type) bool {
+	return fieldType != nil && fieldType.Kind() == reflect.Slice && fieldType != rawMessageType
+}
+
+// elementType returns the type of one value of a field, without pointers or the slice of a repeated field
+func elementType(fieldType reflect.Type) reflect.Type {
+	if fieldType == nil {
+		return nil
+	}
+	if isRepeated(fieldType) {
+		fieldType = fieldType.Elem()
+	}
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	return fieldType
+}
+
+// isPrimitive reports whether values of the type are FHIR primitives, written in XML as a value attribute
+func isPrimitive(valueType reflect.Type) bool {
+	kind := valueType.Kind()
+	return valueType == jsonNumberType || kind == reflect.Bool || kind == reflect.String || isNumeric(valueType)
+}
+
+// isNumeric reports whether values of the type are JSON numbers
+func isNumeric(valueType reflect.Type) bool {
+	switch valueType.Kind() {
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// isCode reports whether the type is a code enum, whose JSON is a string even though its kind is an integer
+func isCode(valueType reflect.Type) bool {
+	return valueType.Kind() != reflect.String && reflect.PointerTo(valueType).Implements(jsonUnmarshalerType)
+}
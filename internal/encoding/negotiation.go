@@ -0,0 +1,132 @@
+package encoding
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+// JSONMediaType and XMLMediaType are the FHIR media types the server reads and writes
+const (
+	JSONMediaType = "application/fhir+json"
+	XMLMediaType  = "application/fhir+xml"
+)
+
+// Format is the wire format of a FHIR resource
+type Format int
+
+const (
+	// FormatJSON is FHIR JSON, the default for requests and responses
+	FormatJSON Format = iota
+
+	// FormatXML is FHIR XML
+	FormatXML
+)
+
+// formatNames maps media types and the _format shorthands FHIR defines to the format they name
+var formatNames = map[string]Format{
+	"json":                  FormatJSON,
+	"application/json":      FormatJSON,
+	"application/fhir+json": FormatJSON,
+	"xml":                   FormatXML,
+	"text/xml":              FormatXML,
+	"application/xml":       FormatXML,
+	"application/fhir+xml":  FormatXML,
+}
+
+// MediaType returns the Content-Type of a response in the format
+func (format Format) MediaType() string {
+	if format == FormatXML {
+		return XMLMediaType
+	}
+	return JSONMediaType
+}
+
+// RequestFormat returns the format of the request body named by its Content-Type
+// Bodies without a Content-Type, or with one that is not XML, are read as JSON
+func RequestFormat(r *http.Request) Format {
+	mediaType, _, parseError := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if parseError != nil {
+		return FormatJSON
+	}
+	return formatNames[strings.ToLower(mediaType)]
+}
+
+// ResponseFormat returns the format the client asked to receive
+// The _format query parameter wins over Accept, as FHIR allows for clients that cannot set headers;
+// among Accept media ranges the supported one with the highest quality is chosen, and JSON otherwise
+func ResponseFormat(r *http.Request) Format {
+	if formatParameter := r.URL.Query().Get("_format"); formatParameter != "" {
+		if format, known := formatNames[strings.ToLower(formatParameter)]; known {
+			return format
+		}
+	}
+
+	chosenFormat := FormatJSON
+	chosenQuality := -1.0
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, parameters, parseError := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if parseError != nil {
+			continue
+		}
+		format, supported := formatNames[strings.ToLower(mediaType)]
+		if !supported {
+			continue
+		}
+		quality := 1.0
+		if rawQuality, hasQuality := parameters["q"]; hasQuality {
+			parsedQuality, qualityError := strconv.ParseFloat(rawQuality, 64)
+			if qualityError != nil {
+				continue
+			}
+			quality = parsedQuality
+		}
+		if quality > chosenQuality {
+			chosenFormat = format
+			chosenQuality = quality
+		}
+	}
+	return chosenFormat
+}
+
+// Decode reads the request body into target, converting FHIR XML to JSON first when the Content-Type says XML
+func Decode(r *http.Request, target interface{}) error {
+	if RequestFormat(r) != FormatXML {
+		return json.NewDecoder(r.Body).Decode(target)
+	}
+
+	xmlBody, readError := io.ReadAll(r.Body)
+	if readError != nil {
+		return readError
+	}
+	jsonBody, convertError := XMLToJSON(xmlBody)
+	if convertError != nil {
+		return convertError
+	}
+	return json.Unmarshal(jsonBody, target)
+}
+
+// Write sends resource with the status code in the format the client negotiated
+func Write(w http.ResponseWriter, r *http.Request, statusCode int, resource interface{}) {
+	if ResponseFormat(r) != FormatXML {
+		w.Header().Set("Content-Type", JSONMediaType)
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(resource)
+		return
+	}
+
+	xmlBody, marshalError := MarshalXML(resource)
+	if marshalError != nil {
+		log.Error().Err(marshalError).Msg("Failed to encode resource as FHIR XML")
+		http.Error(w, "Failed to encode resource as FHIR XML", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", XMLMediaType)
+	w.WriteHeader(statusCode)
+	w.Write(xmlBody)
+}
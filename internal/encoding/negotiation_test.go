@@ -0,0 +1,66 @@
+package encoding
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestResponseFormat verifies _format wins over Accept and Accept quality values pick between JSON and XML
+func TestResponseFormat(t *testing.T) {
+	testCases := []struct {
+		target   string
+		accept   string
+		expected Format
+	}{
+		{target: "/fhir/Patient", accept: "", expected: FormatJSON},
+		{target: "/fhir/Patient", accept: "*/*", expected: FormatJSON},
+		{target: "/fhir/Patient", accept: "application/fhir+xml", expected: FormatXML},
+		{target: "/fhir/Patient", accept: "application/fhir+json;q=0.5, application/fhir+xml", expected: FormatXML},
+		{target: "/fhir/Patient", accept: "application/fhir+xml;q=0.2, application/json;q=0.9", expected: FormatJSON},
+		{target: "/fhir/Patient?_format=xml", accept: "application/fhir+json", expected: FormatXML},
+		{target: "/fhir/Patient?_format=application/fhir%2Bjson", accept: "application/fhir+xml", expected: FormatJSON},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodGet, testCase.target, nil)
+		request.Header.Set("Accept", testCase.accept)
+		if format := ResponseFormat(request); format != testCase.expected {
+			t.Errorf("Expected format %d for %s with Accept %q, got %d", testCase.expected, testCase.target, testCase.accept, format)
+		}
+	}
+}
+
+// TestDecode_XML verifies an XML body is read into the model like a JSON one
+func TestDecode_XML(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", strings.NewReader(`<Patient xmlns="http://hl7.org/fhir"><name><family value="Smith"/></name></Patient>`))
+	request.Header.Set("Content-Type", "application/fhir+xml; charset=utf-8")
+
+	var patient fhir.Patient
+	if decodeError := Decode(request, &patient); decodeError != nil {
+		t.Fatalf("Expected the XML patient to decode, got %v", decodeError)
+	}
+	if len(patient.Name) != 1 || patient.Name[0].Family == nil || *patient.Name[0].Family != "Smith" {
+		t.Errorf("Expected the family name Smith, got %+v", patient.Name)
+	}
+}
+
+// TestWrite_XML verifies resources are sent as FHIR XML with its media type when the client accepts it
+func TestWrite_XML(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient/p-1", nil)
+	request.Header.Set("Accept", "application/fhir+xml")
+	recorder := httptest.NewRecorder()
+
+	patientID := "p-1"
+	Write(recorder, request, http.StatusOK, fhir.Patient{Id: &patientID})
+
+	if contentType := recorder.Header().Get("Content-Type"); contentType != XMLMediaType {
+		t.Errorf("Expected Content-Type %s, got %s", XMLMediaType, contentType)
+	}
+	if !strings.Contains(recorder.Body.String(), `<Patient xmlns="http://hl7.org/fhir"><id value="p-1"/></Patient>`) {
+		t.Errorf("Expected the patient as XML, got %s", recorder.Body.String())
+	}
+}
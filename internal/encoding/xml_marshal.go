@@ -0,0 +1,312 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// fhirNamespace and xhtmlNamespace are the XML namespaces of FHIR elements and of narrative divs
+const (
+	fhirNamespace  = "http://hl7.org/fhir"
+	xhtmlNamespace = "http://www.w3.org/1999/xhtml"
+)
+
+// errNotResource is returned for JSON that is not an object with a resourceType
+var errNotResource = errors.New("FHIR XML needs a JSON object with a resourceType")
+
+// orderedMember is one name and value of a JSON object, kept in document order
+type orderedMember struct {
+	name  string
+	value interface{}
+}
+
+// orderedObject is a JSON object whose members keep their order, since FHIR XML orders elements as the
+// resource definitions do and the models marshal their fields in that order
+type orderedObject []orderedMember
+
+// get returns the value of the named member, or nil when the object has none
+func (object orderedObject) get(name string) interface{} {
+	for _, member := range object {
+		if member.name == name {
+			return member.value
+		}
+	}
+	return nil
+}
+
+// MarshalXML encodes a FHIR resource, such as a model from the fhir package, as FHIR XML
+func MarshalXML(resource interface{}) ([]byte, error) {
+	jsonBody, marshalError := json.Marshal(resource)
+	if marshalError != nil {
+		return nil, marshalError
+	}
+	return JSONToXML(jsonBody)
+}
+
+// JSONToXML converts a FHIR JSON resource to FHIR XML
+// Primitives become value attributes, element IDs and extension URLs become attributes, _name members
+// add their id and extensions to the primitive they describe, and narrative divs are written as XHTML
+func JSONToXML(jsonBody []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(jsonBody))
+	decoder.UseNumber()
+	value, decodeError := decodeOrdered(decoder)
+	if decodeError != nil {
+		return nil, decodeError
+	}
+	resource, isObject := value.(orderedObject)
+	if !isObject {
+		return nil, errNotResource
+	}
+
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(xml.Header)
+	if writeError := writeResource(buffer, resource, true); writeError != nil {
+		return nil, writeError
+	}
+	return buffer.Bytes(), nil
+}
+
+// decodeOrdered reads the next JSON value, keeping object members in document order
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, tokenError := decoder.Token()
+	if tokenError != nil {
+		return nil, tokenError
+	}
+
+	switch delimiter := token.(type) {
+	case json.Delim:
+		if delimiter == '[' {
+			array := []interface{}{}
+			for decoder.More() {
+				item, itemError := decodeOrdered(decoder)
+				if itemError != nil {
+					return nil, itemError
+				}
+				array = append(array, item)
+			}
+			_, closeError := decoder.Token()
+			return array, closeError
+		}
+
+		object := orderedObject{}
+		for decoder.More() {
+			nameToken, nameError := decoder.Token()
+			if nameError != nil {
+				return nil, nameError
+			}
+			memberValue, valueError := decodeOrdered(decoder)
+			if valueError != nil {
+				return nil, valueError
+			}
+			object = append(object, orderedMember{name: nameToken.(string), value: memberValue})
+		}
+		_, closeError := decoder.Token()
+		return object, closeError
+	default:
+		return token, nil
+	}
+}
+
+// writeResource writes a resource as an element named by its resourceType; only the root declares the
+// FHIR namespace, which nested resources inherit
+func writeResource(buffer *bytes.Buffer, resource orderedObject, root bool) error {
+	resourceType, isString := resource.get("resourceType").(string)
+	if !isString || resourceType == "" {
+		return errNotResource
+	}
+
+	buffer.WriteString("<" + resourceType)
+	if root {
+		buffer.WriteString(` xmlns="` + fhirNamespace + `"`)
+	}
+	buffer.WriteString(">")
+	if writeError := writeMembers(buffer, resource, true); writeError != nil {
+		return writeError
+	}
+	buffer.WriteString("</" + resourceType + ">")
+	return nil
+}
+
+// writeMembers writes an object's members as child elements
+// A resource's id is an element, while other elements carry their id as an attribute, written by writeElement
+func writeMembers(buffer *bytes.Buffer, object orderedObject, isResource bool) error {
+	for _, member := range object {
+		if member.name == "resourceType" || (!isResource && member.name == "id") {
+			continue
+		}
+
+		// _name members are written with the primitive they describe, or alone when it has no value
+		if baseName, isPrimitiveExtra := strings.CutPrefix(member.name, "_"); isPrimitiveExtra {
+			if object.get(baseName) == nil {
+				if writeError := writeElement(buffer, baseName, nil, member.value); writeError != nil {
+					return writeError
+				}
+			}
+			continue
+		}
+
+		if writeError := writeElement(buffer, member.name, member.value, object.get("_"+member.name)); writeError != nil {
+			return writeError
+		}
+	}
+	return nil
+}
+
+// writeElement writes value as one element named name, or one per item when value is an array
+// primitiveExtra is the value of the matching _name member, if any
+func writeElement(buffer *bytes.Buffer, name string, value interface{}, primitiveExtra interface{}) error {
+	values, isArray := value.([]interface{})
+	extras, extrasAreArray := primitiveExtra.([]interface{})
+	if !isArray && extrasAreArray {
+		values = make([]interface{}, len(extras))
+		isArray = true
+	}
+	if isArray {
+		for index, item := range values {
+			var itemExtra interface{}
+			if index < len(extras) {
+				itemExtra = extras[index]
+			}
+			if writeError := writeElement(buffer, name, item, itemExtra); writeError != nil {
+				return writeError
+			}
+		}
+		return nil
+	}
+
+	if object, isObject := value.(orderedObject); isObject {
+		return writeComplexElement(buffer, name, object)
+	}
+	if name == "div" {
+		if text, isString := value.(string); isString {
+			writeNarrative(buffer, text)
+			return nil
+		}
+	}
+	return writePrimitiveElement(buffer, name, value, primitiveExtra)
+}
+
+// writeComplexElement writes a datatype or backbone element, or a contained resource such as a Bundle entry's
+func writeComplexElement(buffer *bytes.Buffer, name string, object orderedObject) error {
+	if object.get("resourceType") != nil {
+		buffer.WriteString("<" + name + ">")
+		if writeError := writeResource(buffer, object, false); writeError != nil {
+			return writeError
+		}
+		buffer.WriteString("</" + name + ">")
+		return nil
+	}
+
+	buffer.WriteString("<" + name)
+	if elementID, isString := object.get("id").(string); isString {
+		writeAttribute(buffer, "id", elementID)
+	}
+	isExtension := name == "extension" || name == "modifierExtension"
+	if extensionURL, isString := object.get("url").(string); isString && isExtension {
+		writeAttribute(buffer, "url", extensionURL)
+	}
+	buffer.WriteString(">")
+
+	children := object
+	if isExtension {
+		children = orderedObject{}
+		for _, member := range object {
+			if member.name != "url" {
+				children = append(children, member)
+			}
+		}
+	}
+	if writeError := writeMembers(buffer, children, false); writeError != nil {
+		return writeError
+	}
+	buffer.WriteString("</" + name + ">")
+	return nil
+}
+
+// writePrimitiveElement writes a primitive as a value attribute, with the id and extensions of its _name member
+func writePrimitiveElement(buffer *bytes.Buffer, name string, value interface{}, primitiveExtra interface{}) error {
+	buffer.WriteString("<" + name)
+	if value != nil {
+		writeAttribute(buffer, "value", primitiveText(value))
+	}
+
+	extra, hasExtra := primitiveExtra.(orderedObject)
+	if !hasExtra {
+		buffer.WriteString("/>")
+		return nil
+	}
+	if elementID, isString := extra.get("id").(string); isString {
+		writeAttribute(buffer, "id", elementID)
+	}
+	buffer.WriteString(">")
+	for _, member := range extra {
+		if member.name != "extension" {
+			continue
+		}
+		if writeError := writeElement(buffer, "extension", member.value, nil); writeError != nil {
+			return writeError
+		}
+	}
+	buffer.WriteString("</" + name + ">")
+	return nil
+}
+
+// primitiveText returns the XML value attribute of a JSON primitive
+func primitiveText(value interface{}) string {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue
+	case json.Number:
+		return typedValue.String()
+	case bool:
+		if typedValue {
+			return "true"
+		}
+		return "false"
+	default:
+		return fmt.Sprint(typedValue)
+	}
+}
+
+// writeAttribute writes one escaped attribute
+func writeAttribute(buffer *bytes.Buffer, name string, value string) {
+	buffer.WriteString(" " + name + `="`)
+	xml.EscapeText(buffer, []byte(value))
+	buffer.WriteString(`"`)
+}
+
+// writeNarrative writes a narrative div, which is XHTML rather than FHIR elements
+// A div that is not well-formed XML is written as escaped text inside an empty div so the document stays valid
+func writeNarrative(buffer *bytes.Buffer, div string) {
+	div = strings.TrimSpace(div)
+	if wellFormed(div) && strings.HasPrefix(div, "<div") {
+		openingTag, _, _ := strings.Cut(div, ">")
+		if !strings.Contains(openingTag, "xmlns=") {
+			div = `<div xmlns="` + xhtmlNamespace + `"` + strings.TrimPrefix(div, "<div")
+		}
+		buffer.WriteString(div)
+		return
+	}
+	buffer.WriteString(`<div xmlns="` + xhtmlNamespace + `">`)
+	xml.EscapeText(buffer, []byte(div))
+	buffer.WriteString("</div>")
+}
+
+// wellFormed reports whether text parses as a sequence of XML tokens with balanced elements
+func wellFormed(text string) bool {
+	decoder := xml.NewDecoder(strings.NewReader(text))
+	for {
+		_, tokenError := decoder.Token()
+		if errors.Is(tokenError, io.EOF) {
+			return true
+		}
+		if tokenError != nil {
+			return false
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package encoding
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestMarshalXML_Patient verifies primitives become value attributes, arrays repeat, and the root declares the FHIR namespace
+func TestMarshalXML_Patient(t *testing.T) {
+	patientID := "p-1"
+	familyName := "Smith"
+	active := true
+	gender := fhir.AdministrativeGenderFemale
+	patient := fhir.Patient{
+		Id:     &patientID,
+		Active: &active,
+		Name:   []fhir.HumanName{{Family: &familyName, Given: []string{"Jane", "Q"}}},
+		Gender: &gender,
+	}
+
+	xmlBody, marshalError := MarshalXML(patient)
+	if marshalError != nil {
+		t.Fatalf("Expected the patient to encode, got %v", marshalError)
+	}
+
+	expectedXML := `<Patient xmlns="http://hl7.org/fhir"><id value="p-1"/><active value="true"/><name><family value="Smith"/><given value="Jane"/><given value="Q"/></name><gender value="female"/></Patient>`
+	if !strings.HasSuffix(string(xmlBody), expectedXML) {
+		t.Errorf("Expected %s, got %s", expectedXML, xmlBody)
+	}
+}
+
+// TestXMLToJSON_Patient verifies the model decides arrays and booleans, so a single given name is still an array
+func TestXMLToJSON_Patient(t *testing.T) {
+	xmlBody := `<?xml version="1.0" encoding="UTF-8"?>
+<Patient xmlns="http://hl7.org/fhir">
+  <!-- registration desk -->
+  <active value="true"/>
+  <name>
+    <family value="Smith"/>
+    <given value="Jane"/>
+  </name>
+  <gender value="female"/>
+  <birthDate value="1990-01-15"/>
+</Patient>`
+
+	jsonBody, convertError := XMLToJSON([]byte(xmlBody))
+	if convertError != nil {
+		t.Fatalf("Expected the patient to convert, got %v", convertError)
+	}
+
+	var patient fhir.Patient
+	if decodeError := json.Unmarshal(jsonBody, &patient); decodeError != nil {
+		t.Fatalf("Expected JSON the model reads, got %v: %s", decodeError, jsonBody)
+	}
+	if patient.Active == nil || !*patient.Active || patient.Gender == nil || *patient.Gender != fhir.AdministrativeGenderFemale {
+		t.Errorf("Expected an active female patient, got %s", jsonBody)
+	}
+	if len(patient.Name) != 1 || !reflect.DeepEqual(patient.Name[0].Given, []string{"Jane"}) || *patient.Name[0].Family != "Smith" {
+		t.Errorf("Expected the name Jane Smith, got %s", jsonBody)
+	}
+}
+
+// TestXML_RoundTrip verifies an observation with a decimal, an extension, a narrative, and a primitive's
+// extension survives JSON to XML and back
+func TestXML_RoundTrip(t *testing.T) {
+	observationJSON := `{"resourceType":"Observation","id":"o-1",` +
+		`"text":{"status":"generated","div":"<div xmlns=\"http://www.w3.org/1999/xhtml\"><p>Heart rate <b>72</b></p></div>"},` +
+		`"extension":[{"url":"http://example.org/device","valueString":"watch"}],` +
+		`"status":"final","_status":{"id":"s-1","extension":[{"url":"http://example.org/source","valueString":"device"}]},` +
+		`"code":{"coding":[{"system":"http://loinc.org","code":"8867-4"}]},` +
+		`"valueQuantity":{"value":72.5,"unit":"beats/minute"}}`
+
+	xmlBody, toXMLError := JSONToXML([]byte(observationJSON))
+	if toXMLError != nil {
+		t.Fatalf("Expected the observation to encode, got %v", toXMLError)
+	}
+	for _, expectedFragment := range []string{
+		`<extension url="http://example.org/device"><valueString value="watch"/></extension>`,
+		`<status value="final" id="s-1"><extension url="http://example.org/source"><valueString value="device"/></extension></status>`,
+		`<div xmlns="http://www.w3.org/1999/xhtml"><p>Heart rate <b>72</b></p></div>`,
+		`<value value="72.5"/>`,
+	} {
+		if !strings.Contains(string(xmlBody), expectedFragment) {
+			t.Errorf("Expected %s in %s", expectedFragment, xmlBody)
+		}
+	}
+
+	jsonBody, toJSONError := XMLToJSON(xmlBody)
+	if toJSONError != nil {
+		t.Fatalf("Expected the XML to convert back, got %v", toJSONError)
+	}
+	var original, roundTripped map[string]interface{}
+	json.Unmarshal([]byte(observationJSON), &original)
+	json.Unmarshal(jsonBody, &roundTripped)
+	if !reflect.DeepEqual(original, roundTripped) {
+		t.Errorf("Expected the round trip to keep the observation\nwant %v\ngot  %v", original, roundTripped)
+	}
+}
+
+// TestXML_BundleResources verifies entry resources are wrapped in an element named by their type
+func TestXML_BundleResources(t *testing.T) {
+	bundleJSON := `{"resourceType":"Bundle","type":"transaction","entry":[{"fullUrl":"urn:uuid:1","resource":{"resourceType":"Patient","active":false},"request":{"method":"POST","url":"Patient"}}]}`
+
+	xmlBody, toXMLError := JSONToXML([]byte(bundleJSON))
+	if toXMLError != nil {
+		t.Fatalf("Expected the bundle to encode, got %v", toXMLError)
+	}
+	if !strings.Contains(string(xmlBody), `<resource><Patient><active value="false"/></Patient></resource>`) {
+		t.Errorf("Expected the entry's patient wrapped in resource, got %s", xmlBody)
+	}
+
+	jsonBody, toJSONError := XMLToJSON(xmlBody)
+	if toJSONError != nil {
+		t.Fatalf("Expected the XML to convert back, got %v", toJSONError)
+	}
+	var transaction fhir.Bundle
+	json.Unmarshal(jsonBody, &transaction)
+	if len(transaction.Entry) != 1 || string(transaction.Entry[0].Resource) != `{"active":false,"resourceType":"Patient"}` {
+		t.Errorf("Expected one entry with the patient, got %s", jsonBody)
+	}
+}
+
+// TestXMLToJSON_UnknownElements verifies elements the model does not define are kept for strict parsing to report
+func TestXMLToJSON_UnknownElements(t *testing.T) {
+	jsonBody, convertError := XMLToJSON([]byte(`<Patient xmlns="http://hl7.org/fhir"><birthdate value="1990-01-15"/><active value="yes"/></Patient>`))
+	if convertError != nil {
+		t.Fatalf("Expected the patient to convert, got %v", convertError)
+	}
+	if string(jsonBody) != `{"active":"yes","birthdate":"1990-01-15","resourceType":"Patient"}` {
+		t.Errorf("Expected the misspelled element and the malformed boolean kept as strings, got %s", jsonBody)
+	}
+}
+
+// TestXMLToJSON_Invalid verifies malformed documents, foreign namespaces, and deep nesting are refused
+func TestXMLToJSON_Invalid(t *testing.T) {
+	deeplyNested := `<Patient xmlns="http://hl7.org/fhir">` + strings.Repeat("<extension>", maxXMLDepth+1) + strings.Repeat("</extension>", maxXMLDepth+1) + `</Patient>`
+	for name, xmlBody := range map[string]string{
+		"unclosed":      `<Patient xmlns="http://hl7.org/fhir"><active value="true"/>`,
+		"no namespace":  `<Patient><active value="true"/></Patient>`,
+		"deep nesting":  deeplyNested,
+		"not primitive": `<Patient xmlns="http://hl7.org/fhir"><active value="true"><family value="x"/></active></Patient>`,
+	} {
+		if _, convertError := XMLToJSON([]byte(xmlBody)); !errors.Is(convertError, ErrInvalidXML) {
+			t.Errorf("Expected ErrInvalidXML for %s, got %v", name, convertError)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package encoding
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// maxXMLDepth bounds element nesting so a hostile body cannot exhaust the stack while it is converted
+const maxXMLDepth = 64
+
+// xmlNamespace is the namespace of xml: attributes such as xml:lang in narrative XHTML
+const xmlNamespace = "http://www.w3.org/XML/1998/namespace"
+
+// ErrInvalidXML wraps every failure to read a FHIR XML body, so callers can answer with a 400
+var ErrInvalidXML = errors.New("invalid FHIR XML")
+
+// xmlReader converts one FHIR XML document to JSON, tracking how deep it has descended
+type xmlReader struct {
+	decoder *xml.Decoder
+	depth   int
+}
+
+// XMLToJSON converts a FHIR XML resource to FHIR JSON
+// Models of the resource types the server knows decide which elements become arrays, numbers, or booleans;
+// value attributes become primitives, and the id and extensions of a primitive become its _name member
+func XMLToJSON(xmlBody []byte) ([]byte, error) {
+	reader := &xmlReader{decoder: xml.NewDecoder(bytes.NewReader(xmlBody))}
+
+	root, rootError := reader.nextStartElement()
+	if rootError != nil {
+		return nil, rootError
+	}
+	if root.Name.Space != fhirNamespace {
+		return nil, fmt.Errorf("%w: root element <%s> is not in the %s namespace", ErrInvalidXML, root.Name.Local, fhirNamespace)
+	}
+
+	resource, readError := reader.readResource(root)
+	if readError != nil {
+		return nil, readError
+	}
+	return json.Marshal(resource)
+}
+
+// nextStartElement skips the XML declaration, comments, and whitespace up to the next element
+// It returns an error when the enclosing element ends first
+func (reader *xmlReader) nextStartElement() (xml.StartElement, error) {
+	for {
+		token, tokenError := reader.decoder.Token()
+		if tokenError != nil {
+			return xml.StartElement{}, fmt.Errorf("%w: %v", ErrInvalidXML, tokenError)
+		}
+		switch typedToken := token.(type) {
+		case xml.StartElement:
+			return typedToken, nil
+		case xml.EndElement:
+			return xml.StartElement{}, fmt.Errorf("%w: <%s> holds no resource", ErrInvalidXML, typedToken.Name.Local)
+		}
+	}
+}
+
+// readResource reads a resource element, whose name is its resourceType
+func (reader *xmlReader) readResource(start xml.StartElement) (map[string]interface{}, error) {
+	resource := map[string]interface{}{"resourceType": start.Name.Local}
+	if readError := reader.readChildren(resource, resourceTypes[start.Name.Local]); readError != nil {
+		return nil, readError
+	}
+	return resource, nil
+}
+
+// readChildren reads child elements into object up to the end of the enclosing element
+// modelType is the model of the enclosing element, or nil when it is not known
+func (reader *xmlReader) readChildren(object map[string]interface{}, modelType reflect.Type) error {
+	fields := modelFields(modelType)
+	for {
+		token, tokenError := reader.decoder.Token()
+		if tokenError != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidXML, tokenError)
+		}
+		switch typedToken := token.(type) {
+		case xml.StartElement:
+			name := typedToken.Name.Local
+			fieldType, known := fields[name]
+			value, primitiveExtra, valueError := reader.readValue(typedToken, name, elementType(fieldType))
+			if valueError != nil {
+				return valueError
+			}
+			// Elements the model does not define are kept so strict parsing can report them
+			repeated := isRepeated(fieldType) || (!known && object[name] != nil)
+			addMember(object, name, value, primitiveExtra, repeated)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// readValue reads one element as the JSON value of its type, along with the _name member of a primitive
+func (reader *xmlReader) readValue(start xml.StartElement, name string, valueType reflect.Type) (interface{}, interface{}, error) {
+	reader.depth++
+	defer func() { reader.depth-- }()
+	if reader.depth > maxXMLDepth {
+		return nil, nil, fmt.Errorf("%w: elements are nested more than %d deep", ErrInvalidXML, maxXMLDepth)
+	}
+
+	switch {
+	case valueType == nil:
+		value, readError := reader.readUntyped(start)
+		return value, nil, readError
+	case valueType == rawMessageType:
+		value, readError := reader.readContainedResource()
+		return value, nil, readError
+	case name == "div" && valueType.Kind() == reflect.String:
+		value, readError := reader.readNarrative(start)
+		return value, nil, readError
+	case isPrimitive(valueType) || isCode(valueType):
+		return reader.readPrimitive(start, valueType)
+	default:
+		object := elementAttributes(start)
+		readError := reader.readChildren(object, valueType)
+		return object, nil, readError
+	}
+}
+
+// readPrimitive reads a primitive's value attribute as JSON of its type, and its id and extensions as the
+// primitive's _name member, which is nil when it has neither
+func (reader *xmlReader) readPrimitive(start xml.StartElement, valueType reflect.Type) (interface{}, interface{}, error) {
+	var value interface{}
+	if text, hasValue := attribute(start, "value"); hasValue {
+		value = primitiveValue(text, valueType)
+	}
+
+	primitiveExtra := map[string]interface{}{}
+	if elementID, hasID := attribute(start, "id"); hasID {
+		primitiveExtra["id"] = elementID
+	}
+	for {
+		token, tokenError := reader.decoder.Token()
+		if tokenError != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidXML, tokenError)
+		}
+		if childStart, isStart := token.(xml.StartElement); isStart {
+			if childStart.Name.Local != "extension" {
+				return nil, nil, fmt.Errorf("%w: primitive <%s> may only contain extensions, found <%s>", ErrInvalidXML, start.Name.Local, childStart.Name.Local)
+			}
+			extension, _, extensionError := reader.readValue(childStart, "extension", extensionType)
+			if extensionError != nil {
+				return nil, nil, extensionError
+			}
+			extensions, _ := primitiveExtra["extension"].([]interface{})
+			primitiveExtra["extension"] = append(extensions, extension)
+		}
+		if _, isEnd := token.(xml.EndElement); isEnd {
+			break
+		}
+	}
+
+	if len(primitiveExtra) == 0 {
+		return value, nil, nil
+	}
+	return value, primitiveExtra, nil
+}
+
+// primitiveValue returns the JSON value of a value attribute: booleans and numbers are typed, and anything
+// else, including a malformed number, stays a string for validation to report
+func primitiveValue(text string, valueType reflect.Type) interface{} {
+	if isCode(valueType) {
+		return text
+	}
+	if valueType.Kind() == reflect.Bool {
+		switch text {
+		case "true":
+			return true
+		case "false":
+			return false
+		}
+		return text
+	}
+	if (valueType == jsonNumberType || isNumeric(valueType)) && isJSONNumber(text) {
+		return json.Number(text)
+	}
+	return text
+}
+
+// isJSONNumber reports whether text is a JSON number literal
+func isJSONNumber(text string) bool {
+	if text == "" || (text[0] != '-' && (text[0] < '0' || text[0] > '9')) {
+		return false
+	}
+	return json.Valid([]byte(text))
+}
+
+// readContainedResource reads the single resource inside an element such as Bundle.entry.resource
+func (reader *xmlReader) readContainedResource() (interface{}, error) {
+	resourceStart, startError := reader.nextStartElement()
+	if startError != nil {
+		return nil, startError
+	}
+	resource, readError := reader.readResource(resourceStart)
+	if readError != nil {
+		return nil, readError
+	}
+
+	// Nothing but whitespace and comments may follow the resource inside its wrapper
+	for {
+		token, tokenError := reader.decoder.Token()
+		if tokenError != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidXML, tokenError)
+		}
+		switch typedToken := token.(type) {
+		case xml.StartElement:
+			return nil, fmt.Errorf("%w: <%s> follows the %s resource in the same element", ErrInvalidXML, typedToken.Name.Local, resourceStart.Name.Local)
+		case xml.EndElement:
+			return resource, nil
+		}
+	}
+}
+
+// readNarrative reads a narrative div back into the XHTML string FHIR JSON carries
+func (reader *xmlReader) readNarrative(start xml.StartElement) (string, error) {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(`<div xmlns="` + xhtmlNamespace + `"`)
+	writeXHTMLAttributes(buffer, start.Attr)
+	buffer.WriteString(">")
+
+	for depth := 1; depth > 0; {
+		token, tokenError := reader.decoder.Token()
+		if tokenError != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidXML, tokenError)
+		}
+		switch typedToken := token.(type) {
+		case xml.StartElement:
+			depth++
+			buffer.WriteString("<" + typedToken.Name.Local)
+			writeXHTMLAttributes(buffer, typedToken.Attr)
+			buffer.WriteString(">")
+		case xml.EndElement:
+			depth--
+			buffer.WriteString("</" + typedToken.Name.Local + ">")
+		case xml.CharData:
+			xml.EscapeText(buffer, typedToken)
+		}
+	}
+	return buffer.String(), nil
+}
+
+// writeXHTMLAttributes writes the attributes of an XHTML element, leaving out namespace declarations
+func writeXHTMLAttributes(buffer *bytes.Buffer, attributes []xml.Attr) {
+	for _, xhtmlAttribute := range attributes {
+		if xhtmlAttribute.Name.Space == "xmlns" || xhtmlAttribute.Name.Local == "xmlns" {
+			continue
+		}
+		attributeName := xhtmlAttribute.Name.Local
+		if xhtmlAttribute.Name.Space == xmlNamespace {
+			attributeName = "xml:" + attributeName
+		}
+		writeAttribute(buffer, attributeName, xhtmlAttribute.Value)
+	}
+}
+
+// readUntyped reads an element the models do not describe: a lone value attribute becomes a string and
+// anything else an object of its attributes and children
+func (reader *xmlReader) readUntyped(start xml.StartElement) (interface{}, error) {
+	object := elementAttributes(start)
+	if readError := reader.readChildren(object, nil); readError != nil {
+		return nil, readError
+	}
+
+	text, hasValue := attribute(start, "value")
+	if !hasValue {
+		return object, nil
+	}
+	if len(object) == 0 {
+		return text, nil
+	}
+	object["value"] = text
+	return object, nil
+}
+
+// elementAttributes returns the id and extension url attributes of a complex element as JSON members
+func elementAttributes(start xml.StartElement) map[string]interface{} {
+	object := map[string]interface{}{}
+	for _, name := range []string{"id", "url"} {
+		if value, present := attribute(start, name); present {
+			object[name] = value
+		}
+	}
+	return object
+}
+
+// attribute returns the value of the element's attribute without a namespace named name
+func attribute(start xml.StartElement, name string) (string, bool) {
+	for _, elementAttribute := range start.Attr {
+		if elementAttribute.Name.Space == "" && elementAttribute.Name.Local == name {
+			return elementAttribute.Value, true
+		}
+	}
+	return "", false
+}
+
+// addMember adds a converted element to object: repeated elements are appended to an array, and a
+// primitive's _name member is kept alongside, padded with nulls so its items line up with the values
+func addMember(object map[string]interface{}, name string, value interface{}, primitiveExtra interface{}, repeated bool) {
+	if !repeated {
+		if value != nil {
+			object[name] = value
+		}
+		if primitiveExtra != nil {
+			object["_"+name] = primitiveExtra
+		}
+		return
+	}
+
+	values, isArray := object[name].([]interface{})
+	if existing, present := object[name]; present && !isArray {
+		values = []interface{}{existing}
+	}
+	index := len(values)
+	object[name] = append(values, value)
+
+	if primitiveExtra != nil {
+		extras, _ := object["_"+name].([]interface{})
+		for len(extras) < index {
+			extras = append(extras, nil)
+		}
+		object["_"+name] = append(extras, primitiveExtra)
+	}
+}
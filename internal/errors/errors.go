@@ -35,6 +35,15 @@ func NotFound(resourceType string, resourceID string) *AppError {
 	}
 }
 
+// Gone creates a 410 Gone error for a resource that existed but was deleted
+func Gone(resourceType string, resourceID string) *AppError {
+	return &AppError{
+		Code:       "RESOURCE_DELETED",
+		Message:    fmt.Sprintf("%s with ID '%s' was deleted", resourceType, resourceID),
+		StatusCode: http.StatusGone,
+	}
+}
+
 // ValidationError creates a 400 Bad Request error for validation failures
 func ValidationError(message string) *AppError {
 	return &AppError{
@@ -72,6 +81,15 @@ func Conflict(resourceType string, reason string) *AppError {
 	}
 }
 
+// PreconditionFailed creates a 412 Precondition Failed error, e.g. for an If-Match naming a stale version
+func PreconditionFailed(message string) *AppError {
+	return &AppError{
+		Code:       "PRECONDITION_FAILED",
+		Message:    message,
+		StatusCode: http.StatusPreconditionFailed,
+	}
+}
+
 // Unauthorized creates a 401 Unauthorized error
 func Unauthorized(message string) *AppError {
 	return &AppError{
@@ -90,6 +108,24 @@ func Forbidden(message string) *AppError {
 	}
 }
 
+// UnavailableForLegalReasons creates a 451 Unavailable For Legal Reasons error
+func UnavailableForLegalReasons(message string) *AppError {
+	return &AppError{
+		Code:       "UNAVAILABLE_FOR_LEGAL_REASONS",
+		Message:    message,
+		StatusCode: http.StatusUnavailableForLegalReasons,
+	}
+}
+
+// ServiceUnavailable creates a 503 Service Unavailable error
+func ServiceUnavailable(message string) *AppError {
+	return &AppError{
+		Code:       "SERVICE_UNAVAILABLE",
+		Message:    message,
+		StatusCode: http.StatusServiceUnavailable,
+	}
+}
+
 // Wrap wraps an existing error with additional context
 func Wrap(err error, message string) *AppError {
 	if err == nil {
@@ -108,6 +108,18 @@ func TestForbidden(t *testing.T) {
 	}
 }
 
+// TestUnavailableForLegalReasons verifies UnavailableForLegalReasons error creation
+func TestUnavailableForLegalReasons(t *testing.T) {
+	err := UnavailableForLegalReasons("Held in another region")
+
+	if err.Code != "UNAVAILABLE_FOR_LEGAL_REASONS" {
+		t.Errorf("Expected code UNAVAILABLE_FOR_LEGAL_REASONS, got %s", err.Code)
+	}
+	if err.StatusCode != http.StatusUnavailableForLegalReasons {
+		t.Errorf("Expected status 451, got %d", err.StatusCode)
+	}
+}
+
 // TestAppError_Error verifies Error() method
 func TestAppError_Error(t *testing.T) {
 	originalErr := errors.New("original error")
@@ -180,3 +192,27 @@ func TestWrap_GenericError(t *testing.T) {
 		t.Error("Expected wrapped error to contain original")
 	}
 }
+
+// TestServiceUnavailable verifies ServiceUnavailable error creation
+func TestServiceUnavailable(t *testing.T) {
+	err := ServiceUnavailable("Server is in maintenance mode")
+
+	if err.Code != "SERVICE_UNAVAILABLE" {
+		t.Errorf("Expected code SERVICE_UNAVAILABLE, got %s", err.Code)
+	}
+	if err.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", err.StatusCode)
+	}
+}
+
+// TestGone verifies Gone error creation
+func TestGone(t *testing.T) {
+	err := Gone("Patient", "123")
+
+	if err.Code != "RESOURCE_DELETED" {
+		t.Errorf("Expected code RESOURCE_DELETED, got %s", err.Code)
+	}
+	if err.StatusCode != http.StatusGone {
+		t.Errorf("Expected status 410, got %d", err.StatusCode)
+	}
+}
@@ -0,0 +1,258 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrBusClosed is returned when subscribing to a bus that has been shut down
+var ErrBusClosed = errors.New("event bus closed")
+
+// EventType identifies what happened to a resource
+type EventType string
+
+const (
+	// EventResourceCreated is published after a resource is created
+	EventResourceCreated EventType = "resource.created"
+
+	// EventResourceUpdated is published after a resource is updated
+	EventResourceUpdated EventType = "resource.updated"
+
+	// EventResourceDeleted is published after a resource is deleted
+	EventResourceDeleted EventType = "resource.deleted"
+)
+
+// Event describes a completed write to a resource
+type Event struct {
+	Type         EventType `json:"type"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+
+	// Version from the change log, or 0 when it was not recorded
+	Version int `json:"version,omitempty"`
+
+	// Tenant that performed the write
+	TenantID string `json:"tenant_id"`
+
+	OccurredAt time.Time `json:"occurred_at"`
+
+	// Resource is the written resource (nil for deletes); consumers must not modify it
+	Resource interface{} `json:"-"`
+}
+
+// Handler processes one event for a consumer
+type Handler func(ctx context.Context, event Event) error
+
+// Publisher is the interface services use to emit events
+type Publisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// ConsumerStats reports per-consumer delivery counters
+type ConsumerStats struct {
+	Name          string `json:"name"`
+	QueueDepth    int    `json:"queue_depth"`
+	QueueCapacity int    `json:"queue_capacity"`
+	Delivered     int64  `json:"delivered"`
+	Failed        int64  `json:"failed"`
+	Dropped       int64  `json:"dropped"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// defaultQueueSize is used when a consumer does not specify a queue size
+const defaultQueueSize = 256
+
+// Bus is an in-process publish/subscribe bus for resource events
+// Each consumer has its own bounded queue and goroutine, so a slow consumer only drops its own events
+type Bus struct {
+	mutex     sync.RWMutex
+	consumers []*consumer
+	closed    bool
+	waitGroup sync.WaitGroup
+}
+
+// consumer is one subscriber with its own queue and counters
+type consumer struct {
+	name    string
+	handler Handler
+	queue   chan Event
+
+	delivered atomic.Int64
+	failed    atomic.Int64
+	dropped   atomic.Int64
+	lastError atomic.Value
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a consumer with a bounded queue (queueSize <= 0 uses the default)
+// Events published while the queue is full are dropped for that consumer and counted
+func (bus *Bus) Subscribe(name string, queueSize int, handler Handler) error {
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+
+	bus.mutex.Lock()
+	defer bus.mutex.Unlock()
+
+	if bus.closed {
+		return ErrBusClosed
+	}
+	for _, existing := range bus.consumers {
+		if existing.name == name {
+			return fmt.Errorf("event consumer %q already subscribed", name)
+		}
+	}
+
+	newConsumer := &consumer{
+		name:    name,
+		handler: handler,
+		queue:   make(chan Event, queueSize),
+	}
+	bus.consumers = append(bus.consumers, newConsumer)
+
+	bus.waitGroup.Add(1)
+	go bus.run(newConsumer)
+
+	return nil
+}
+
+// Publish enqueues an event for every consumer without blocking the caller
+func (bus *Bus) Publish(ctx context.Context, event Event) {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+
+	if bus.closed {
+		return
+	}
+
+	for _, subscribedConsumer := range bus.consumers {
+		select {
+		case subscribedConsumer.queue <- event:
+		default:
+			subscribedConsumer.dropped.Add(1)
+			log.Warn().
+				Str("consumer", subscribedConsumer.name).
+				Str("event_type", string(event.Type)).
+				Str("resource_type", event.ResourceType).
+				Str("resource_id", event.ResourceID).
+				Msg("Event consumer queue full; dropping event")
+		}
+	}
+}
+
+// Stats returns counters for every consumer, sorted by name
+func (bus *Bus) Stats() []ConsumerStats {
+	bus.mutex.RLock()
+	defer bus.mutex.RUnlock()
+
+	consumerStats := make([]ConsumerStats, 0, len(bus.consumers))
+	for _, subscribedConsumer := range bus.consumers {
+		stats := ConsumerStats{
+			Name:          subscribedConsumer.name,
+			QueueDepth:    len(subscribedConsumer.queue),
+			QueueCapacity: cap(subscribedConsumer.queue),
+			Delivered:     subscribedConsumer.delivered.Load(),
+			Failed:        subscribedConsumer.failed.Load(),
+			Dropped:       subscribedConsumer.dropped.Load(),
+		}
+		if lastError, ok := subscribedConsumer.lastError.Load().(string); ok {
+			stats.LastError = lastError
+		}
+		consumerStats = append(consumerStats, stats)
+	}
+	sort.Slice(consumerStats, func(i, j int) bool {
+		return consumerStats[i].Name < consumerStats[j].Name
+	})
+
+	return consumerStats
+}
+
+// Shutdown stops accepting events and waits for consumers to drain their queues or the context to end
+func (bus *Bus) Shutdown(ctx context.Context) error {
+	bus.mutex.Lock()
+	if !bus.closed {
+		bus.closed = true
+		for _, subscribedConsumer := range bus.consumers {
+			close(subscribedConsumer.queue)
+		}
+	}
+	bus.mutex.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		bus.waitGroup.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// run delivers queued events to one consumer until its queue is closed
+func (bus *Bus) run(subscribedConsumer *consumer) {
+	defer bus.waitGroup.Done()
+
+	for event := range subscribedConsumer.queue {
+		bus.dispatch(subscribedConsumer, event)
+	}
+}
+
+// dispatch invokes the consumer's handler, isolating failures and panics from other consumers
+func (bus *Bus) dispatch(subscribedConsumer *consumer, event Event) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			subscribedConsumer.failed.Add(1)
+			subscribedConsumer.lastError.Store(fmt.Sprintf("panic: %v", recovered))
+			log.Error().Str("consumer", subscribedConsumer.name).Interface("panic", recovered).Msg("Event consumer panicked")
+		}
+	}()
+
+	handleError := subscribedConsumer.handler(context.Background(), event)
+	if handleError != nil {
+		subscribedConsumer.failed.Add(1)
+		subscribedConsumer.lastError.Store(handleError.Error())
+		log.Warn().
+			Err(handleError).
+			Str("consumer", subscribedConsumer.name).
+			Str("event_type", string(event.Type)).
+			Str("resource_id", event.ResourceID).
+			Msg("Event consumer failed")
+		return
+	}
+
+	subscribedConsumer.delivered.Add(1)
+}
+
+// AuditLogger returns a handler that writes every event to the structured log
+func AuditLogger() Handler {
+	return func(ctx context.Context, event Event) error {
+		log.Info().
+			Str("event_type", string(event.Type)).
+			Str("resource_type", event.ResourceType).
+			Str("resource_id", event.ResourceID).
+			Int("version", event.Version).
+			Str("tenant_id", event.TenantID).
+			Time("occurred_at", event.OccurredAt).
+			Msg("Resource event")
+		return nil
+	}
+}
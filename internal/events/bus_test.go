@@ -0,0 +1,149 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestBus_DeliversToAllConsumers verifies every consumer receives every event
+func TestBus_DeliversToAllConsumers(t *testing.T) {
+	bus := NewBus()
+	var mutex sync.Mutex
+	received := map[string][]string{}
+
+	for _, name := range []string{"audit", "cache"} {
+		consumerName := name
+		bus.Subscribe(consumerName, 10, func(ctx context.Context, event Event) error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			received[consumerName] = append(received[consumerName], event.ResourceID)
+			return nil
+		})
+	}
+
+	bus.Publish(context.Background(), Event{Type: EventResourceCreated, ResourceType: "Patient", ResourceID: "1"})
+	bus.Publish(context.Background(), Event{Type: EventResourceUpdated, ResourceType: "Patient", ResourceID: "2"})
+
+	if shutdownError := bus.Shutdown(context.Background()); shutdownError != nil {
+		t.Fatalf("Expected clean shutdown, got %v", shutdownError)
+	}
+
+	for _, name := range []string{"audit", "cache"} {
+		if len(received[name]) != 2 || received[name][0] != "1" || received[name][1] != "2" {
+			t.Errorf("Consumer %s received %v", name, received[name])
+		}
+	}
+
+	for _, stats := range bus.Stats() {
+		if stats.Delivered != 2 {
+			t.Errorf("Expected 2 delivered for %s, got %d", stats.Name, stats.Delivered)
+		}
+	}
+}
+
+// TestBus_SlowConsumerDropsOnlyItsEvents verifies bounded queues isolate consumers
+func TestBus_SlowConsumerDropsOnlyItsEvents(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+
+	bus.Subscribe("slow", 1, func(ctx context.Context, event Event) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-release
+		return nil
+	})
+	bus.Subscribe("fast", 10, func(ctx context.Context, event Event) error {
+		return nil
+	})
+
+	// First event occupies the slow handler, second fills its queue, third is dropped
+	bus.Publish(context.Background(), Event{ResourceID: "1"})
+	<-started
+	bus.Publish(context.Background(), Event{ResourceID: "2"})
+	bus.Publish(context.Background(), Event{ResourceID: "3"})
+
+	close(release)
+	bus.Shutdown(context.Background())
+
+	stats := bus.Stats()
+	if stats[0].Name != "fast" || stats[0].Dropped != 0 || stats[0].Delivered != 3 {
+		t.Errorf("Unexpected fast consumer stats: %+v", stats[0])
+	}
+	if stats[1].Name != "slow" || stats[1].Dropped != 1 || stats[1].Delivered != 2 {
+		t.Errorf("Unexpected slow consumer stats: %+v", stats[1])
+	}
+}
+
+// TestBus_FailuresAndPanicsAreCounted verifies handler errors do not stop delivery
+func TestBus_FailuresAndPanicsAreCounted(t *testing.T) {
+	bus := NewBus()
+	calls := 0
+	bus.Subscribe("flaky", 10, func(ctx context.Context, event Event) error {
+		calls++
+		switch calls {
+		case 1:
+			return errors.New("cache unavailable")
+		case 2:
+			panic("boom")
+		}
+		return nil
+	})
+
+	for index := 0; index < 3; index++ {
+		bus.Publish(context.Background(), Event{ResourceID: "1"})
+	}
+	bus.Shutdown(context.Background())
+
+	stats := bus.Stats()[0]
+	if stats.Failed != 2 || stats.Delivered != 1 || stats.LastError != "panic: boom" {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+// TestBus_SubscribeRules verifies duplicate names and closed buses are rejected
+func TestBus_SubscribeRules(t *testing.T) {
+	bus := NewBus()
+	noop := func(ctx context.Context, event Event) error { return nil }
+
+	if subscribeError := bus.Subscribe("audit", 0, noop); subscribeError != nil {
+		t.Fatalf("Expected no error, got %v", subscribeError)
+	}
+	if bus.Subscribe("audit", 0, noop) == nil {
+		t.Error("Expected duplicate consumer name to be rejected")
+	}
+	if bus.Stats()[0].QueueCapacity != defaultQueueSize {
+		t.Errorf("Expected default queue size, got %d", bus.Stats()[0].QueueCapacity)
+	}
+
+	bus.Shutdown(context.Background())
+	if !errors.Is(bus.Subscribe("late", 0, noop), ErrBusClosed) {
+		t.Error("Expected ErrBusClosed after shutdown")
+	}
+
+	// Publishing after shutdown is a no-op rather than a panic
+	bus.Publish(context.Background(), Event{ResourceID: "ignored"})
+}
+
+// TestBus_ShutdownTimeout verifies shutdown respects the context deadline
+func TestBus_ShutdownTimeout(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	defer close(release)
+	bus.Subscribe("stuck", 1, func(ctx context.Context, event Event) error {
+		<-release
+		return nil
+	})
+	bus.Publish(context.Background(), Event{ResourceID: "1"})
+
+	shortContext, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if bus.Shutdown(shortContext) == nil {
+		t.Error("Expected shutdown to time out while a consumer is blocked")
+	}
+}
@@ -0,0 +1,80 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Message is one record published to Kafka
+type Message struct {
+	Topic string
+	Key   string
+	Value []byte
+}
+
+// Producer publishes messages to Kafka, returning only once the brokers acknowledged all of them
+type Producer interface {
+	Produce(ctx context.Context, messages []Message) error
+}
+
+// KafkaProducer publishes to a Kafka cluster, hashing keys to partitions so each resource's events stay in order
+type KafkaProducer struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaProducer creates a producer for the brokers; topics must already exist
+func NewKafkaProducer(brokers []string) *KafkaProducer {
+	return &KafkaProducer{
+		writer: &kafka.Writer{
+			Addr:     kafka.TCP(brokers...),
+			Balancer: &kafka.Hash{},
+			// Wait for every in-sync replica so an acknowledged event survives a broker failure
+			RequiredAcks: kafka.RequireAll,
+			// The relay already batches; do not hold messages back waiting for more
+			BatchTimeout: 10 * time.Millisecond,
+			// The relay retries failed batches itself, from the outbox
+			MaxAttempts: 1,
+		},
+	}
+}
+
+// Produce writes the messages and waits for their acknowledgement
+func (producer *KafkaProducer) Produce(ctx context.Context, messages []Message) error {
+	kafkaMessages := make([]kafka.Message, len(messages))
+	for index, message := range messages {
+		kafkaMessages[index] = kafka.Message{
+			Topic: message.Topic,
+			Key:   []byte(message.Key),
+			Value: message.Value,
+		}
+	}
+	return producer.writer.WriteMessages(ctx, kafkaMessages...)
+}
+
+// Close flushes and closes the broker connections
+func (producer *KafkaProducer) Close() error {
+	return producer.writer.Close()
+}
+
+// ParseKafkaBrokers parses a comma-separated list of host:port broker addresses
+func ParseKafkaBrokers(rawBrokers string) ([]string, error) {
+	brokers := []string{}
+	for _, broker := range strings.Split(rawBrokers, ",") {
+		broker = strings.TrimSpace(broker)
+		if broker == "" {
+			continue
+		}
+
+		host, port, splitError := net.SplitHostPort(broker)
+		if splitError != nil || host == "" || port == "" {
+			return nil, fmt.Errorf("invalid Kafka broker %q: expected host:port", broker)
+		}
+		brokers = append(brokers, broker)
+	}
+	return brokers, nil
+}
@@ -0,0 +1,16 @@
+package events
+
+import (
+	"testing"
+)
+
+// TestParseKafkaBrokers verifies broker lists are split and addresses validated
+func TestParseKafkaBrokers(t *testing.T) {
+	brokers, parseError := ParseKafkaBrokers(" kafka-1:9092, kafka-2:9092,")
+	if parseError != nil || len(brokers) != 2 || brokers[1] != "kafka-2:9092" {
+		t.Errorf("Unexpected brokers %v (%v)", brokers, parseError)
+	}
+	if _, parseError := ParseKafkaBrokers("kafka-1"); parseError == nil {
+		t.Error("Expected a broker without a port rejected")
+	}
+}
@@ -0,0 +1,268 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// DefaultTopicPrefix is prepended to the lowercase resource type to name each resource's topic, e.g. fhir.patient
+const DefaultTopicPrefix = "fhir."
+
+// eventTypeByOperation maps change log operations to event types
+var eventTypeByOperation = map[models.ChangeOperation]EventType{
+	models.ChangeOperationCreate: EventResourceCreated,
+	models.ChangeOperationUpdate: EventResourceUpdated,
+	models.ChangeOperationDelete: EventResourceDeleted,
+}
+
+// TypeForOperation returns the event type published for a change log operation
+func TypeForOperation(operation models.ChangeOperation) EventType {
+	return eventTypeByOperation[operation]
+}
+
+// OutboxStore persists events until the relay has published them
+type OutboxStore interface {
+	Append(ctx context.Context, event *models.OutboxEvent) error
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+	TryLockRelay(ctx context.Context) (bool, error)
+	ListOldest(ctx context.Context, limit int) ([]*models.OutboxEvent, error)
+	Delete(ctx context.Context, ids []int64) error
+	RecordFailure(ctx context.Context, id int64, lastError string) error
+	Count(ctx context.Context) (int64, error)
+}
+
+// Outbox writes an event for every recorded change into the change's own transaction
+// A write that commits therefore always leaves its event behind for the relay, even if the process dies next
+type Outbox struct {
+	store       OutboxStore
+	topicPrefix string
+}
+
+// NewOutbox creates an outbox naming topics with topicPrefix (empty uses DefaultTopicPrefix)
+func NewOutbox(store OutboxStore, topicPrefix string) *Outbox {
+	if topicPrefix == "" {
+		topicPrefix = DefaultTopicPrefix
+	}
+	return &Outbox{
+		store:       store,
+		topicPrefix: topicPrefix,
+	}
+}
+
+// Topic returns the topic a resource type's events are published to
+func (outbox *Outbox) Topic(resourceType string) string {
+	return outbox.topicPrefix + strings.ToLower(resourceType)
+}
+
+// ChangeRecorded appends the change's event; ctx carries the change's transaction and the writing tenant
+func (outbox *Outbox) ChangeRecorded(ctx context.Context, change *models.ResourceChange) error {
+	payload, encodeError := json.Marshal(Event{
+		Type:         TypeForOperation(change.Operation),
+		ResourceType: change.ResourceType,
+		ResourceID:   change.ResourceID,
+		Version:      change.Version,
+		TenantID:     tenant.FromContext(ctx),
+		OccurredAt:   change.ChangedAt,
+	})
+	if encodeError != nil {
+		return encodeError
+	}
+
+	return outbox.store.Append(ctx, &models.OutboxEvent{
+		Topic:   outbox.Topic(change.ResourceType),
+		Key:     change.ResourceID,
+		Payload: payload,
+	})
+}
+
+// RelayPolicy controls how often and how much the relay publishes
+type RelayPolicy struct {
+	// BatchSize is the largest number of events published at once
+	BatchSize int
+
+	// PollInterval is the wait between polls once the outbox is drained
+	PollInterval time.Duration
+
+	// BaseDelay is the wait after the first failed publish; each further failure doubles it up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// PublishTimeout bounds each batch sent to the brokers
+	PublishTimeout time.Duration
+}
+
+// DefaultRelayPolicy returns the relay settings used when nothing is configured
+func DefaultRelayPolicy() RelayPolicy {
+	return RelayPolicy{
+		BatchSize:      100,
+		PollInterval:   time.Second,
+		BaseDelay:      time.Second,
+		MaxDelay:       time.Minute,
+		PublishTimeout: 10 * time.Second,
+	}
+}
+
+// backoff returns the wait after the given number of consecutive failures
+func (policy RelayPolicy) backoff(consecutiveFailures int) time.Duration {
+	delay := policy.BaseDelay
+	for failure := 1; failure < consecutiveFailures && delay < policy.MaxDelay; failure++ {
+		delay *= 2
+	}
+	if delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
+
+// RelayStats reports outbox depth and publishing counters since startup
+type RelayStats struct {
+	Pending         int64     `json:"pending"`
+	Published       int64     `json:"published"`
+	Failures        int64     `json:"failures"`
+	LastError       string    `json:"last_error,omitempty"`
+	LastPublishedAt time.Time `json:"last_published_at,omitempty"`
+}
+
+// Relay publishes outbox events to Kafka oldest first and deletes them once the brokers acknowledge them
+// A failed batch is retried whole, so consumers may see an event more than once but never miss one,
+// and an event is never published before one written earlier
+type Relay struct {
+	store    OutboxStore
+	producer Producer
+	policy   RelayPolicy
+
+	countersMutex sync.Mutex
+	counters      RelayStats
+
+	// wait is replaceable for tests
+	wait func(ctx context.Context, duration time.Duration) error
+}
+
+// NewRelay creates a relay from the outbox to producer
+func NewRelay(store OutboxStore, producer Producer, policy RelayPolicy) *Relay {
+	return &Relay{
+		store:    store,
+		producer: producer,
+		policy:   policy,
+		wait:     waitFor,
+	}
+}
+
+// Run relays events until ctx is cancelled
+// Full batches are followed immediately by the next one so a backlog drains without waiting
+func (relay *Relay) Run(ctx context.Context) {
+	consecutiveFailures := 0
+	for {
+		published, relayError := relay.RelayOnce(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		pause := time.Duration(0)
+		switch {
+		case relayError != nil:
+			consecutiveFailures++
+			pause = relay.policy.backoff(consecutiveFailures)
+			log.Error().Err(relayError).Dur("retry_in", pause).Msg("Failed to relay event outbox")
+		case published < relay.policy.BatchSize:
+			consecutiveFailures = 0
+			pause = relay.policy.PollInterval
+		default:
+			consecutiveFailures = 0
+		}
+
+		if pause > 0 && relay.wait(ctx, pause) != nil {
+			return
+		}
+	}
+}
+
+// RelayOnce publishes one batch of the oldest events and returns how many were published
+// It publishes nothing while another instance holds the relay lock
+func (relay *Relay) RelayOnce(ctx context.Context) (int, error) {
+	published := 0
+	var publishError error
+
+	transactionError := relay.store.InTransaction(ctx, func(transactionContext context.Context) error {
+		locked, lockError := relay.store.TryLockRelay(transactionContext)
+		if lockError != nil || !locked {
+			return lockError
+		}
+
+		batch, listError := relay.store.ListOldest(transactionContext, relay.policy.BatchSize)
+		if listError != nil || len(batch) == 0 {
+			return listError
+		}
+
+		messages := make([]Message, len(batch))
+		ids := make([]int64, len(batch))
+		for index, outboxEvent := range batch {
+			messages[index] = Message{Topic: outboxEvent.Topic, Key: outboxEvent.Key, Value: outboxEvent.Payload}
+			ids[index] = outboxEvent.ID
+		}
+
+		publishContext, cancel := context.WithTimeout(transactionContext, relay.policy.PublishTimeout)
+		publishError = relay.producer.Produce(publishContext, messages)
+		cancel()
+		if publishError != nil {
+			// Part of the batch may have reached the brokers; all of it stays queued and is sent again
+			return relay.store.RecordFailure(transactionContext, batch[0].ID, publishError.Error())
+		}
+
+		published = len(batch)
+		return relay.store.Delete(transactionContext, ids)
+	})
+
+	relay.countersMutex.Lock()
+	defer relay.countersMutex.Unlock()
+	if publishError != nil {
+		relay.counters.Failures++
+		relay.counters.LastError = publishError.Error()
+		return 0, publishError
+	}
+	if transactionError != nil {
+		// The batch reached Kafka but is still in the outbox, so it will be published again
+		return 0, transactionError
+	}
+	if published > 0 {
+		relay.counters.Published += int64(published)
+		relay.counters.LastError = ""
+		relay.counters.LastPublishedAt = time.Now()
+	}
+	return published, nil
+}
+
+// Stats returns the outbox depth together with the relay's counters
+func (relay *Relay) Stats(ctx context.Context) (RelayStats, error) {
+	pending, countError := relay.store.Count(ctx)
+	if countError != nil {
+		return RelayStats{}, countError
+	}
+
+	relay.countersMutex.Lock()
+	stats := relay.counters
+	relay.countersMutex.Unlock()
+
+	stats.Pending = pending
+	return stats, nil
+}
+
+// waitFor sleeps for duration or until ctx is cancelled
+func waitFor(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
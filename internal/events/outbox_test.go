@@ -0,0 +1,162 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// memoryOutboxStore keeps outbox events in memory
+type memoryOutboxStore struct {
+	outboxEvents []*models.OutboxEvent
+	nextID       int64
+	locked       bool
+}
+
+// Append stores the event with the next ID
+func (store *memoryOutboxStore) Append(ctx context.Context, event *models.OutboxEvent) error {
+	store.nextID++
+	event.ID = store.nextID
+	store.outboxEvents = append(store.outboxEvents, event)
+	return nil
+}
+
+// InTransaction runs work directly
+func (store *memoryOutboxStore) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return work(ctx)
+}
+
+// TryLockRelay fails while another relay is simulated to hold the lock
+func (store *memoryOutboxStore) TryLockRelay(ctx context.Context) (bool, error) {
+	return !store.locked, nil
+}
+
+// ListOldest returns the first limit events
+func (store *memoryOutboxStore) ListOldest(ctx context.Context, limit int) ([]*models.OutboxEvent, error) {
+	if len(store.outboxEvents) < limit {
+		limit = len(store.outboxEvents)
+	}
+	return store.outboxEvents[:limit], nil
+}
+
+// Delete removes the events with the IDs
+func (store *memoryOutboxStore) Delete(ctx context.Context, ids []int64) error {
+	deleted := map[int64]bool{}
+	for _, id := range ids {
+		deleted[id] = true
+	}
+	remaining := []*models.OutboxEvent{}
+	for _, outboxEvent := range store.outboxEvents {
+		if !deleted[outboxEvent.ID] {
+			remaining = append(remaining, outboxEvent)
+		}
+	}
+	store.outboxEvents = remaining
+	return nil
+}
+
+// RecordFailure counts the failed attempt
+func (store *memoryOutboxStore) RecordFailure(ctx context.Context, id int64, lastError string) error {
+	for _, outboxEvent := range store.outboxEvents {
+		if outboxEvent.ID == id {
+			outboxEvent.Attempts++
+			outboxEvent.LastError = lastError
+		}
+	}
+	return nil
+}
+
+// Count returns the number of stored events
+func (store *memoryOutboxStore) Count(ctx context.Context) (int64, error) {
+	return int64(len(store.outboxEvents)), nil
+}
+
+// recordingProducer keeps produced messages and fails while failWith is set
+type recordingProducer struct {
+	produced []Message
+	failWith error
+}
+
+// Produce records the messages unless failing
+func (producer *recordingProducer) Produce(ctx context.Context, messages []Message) error {
+	if producer.failWith != nil {
+		return producer.failWith
+	}
+	producer.produced = append(producer.produced, messages...)
+	return nil
+}
+
+// TestOutbox_ChangeRecorded verifies each change is stored as an event on its resource type's topic, keyed by resource ID
+func TestOutbox_ChangeRecorded(t *testing.T) {
+	store := &memoryOutboxStore{}
+	outbox := NewOutbox(store, "")
+	changedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	ctx := tenant.WithTenant(context.Background(), "clinic-a")
+	change := &models.ResourceChange{ResourceType: "Observation", ResourceID: "obs-1", Version: 3, Operation: models.ChangeOperationUpdate, ChangedAt: changedAt}
+	if recordError := outbox.ChangeRecorded(ctx, change); recordError != nil {
+		t.Fatalf("Expected the change appended, got %v", recordError)
+	}
+
+	if len(store.outboxEvents) != 1 || store.outboxEvents[0].Topic != "fhir.observation" || store.outboxEvents[0].Key != "obs-1" {
+		t.Fatalf("Unexpected outbox events %+v", store.outboxEvents)
+	}
+	var event Event
+	json.Unmarshal(store.outboxEvents[0].Payload, &event)
+	if event.Type != EventResourceUpdated || event.Version != 3 || event.TenantID != "clinic-a" || !event.OccurredAt.Equal(changedAt) {
+		t.Errorf("Unexpected event payload %+v", event)
+	}
+}
+
+// TestRelay_PublishesInOrderAndRetriesFailedBatches verifies events leave the outbox only once published
+func TestRelay_PublishesInOrderAndRetriesFailedBatches(t *testing.T) {
+	store := &memoryOutboxStore{}
+	for _, resourceID := range []string{"1", "2", "3"} {
+		store.Append(context.Background(), &models.OutboxEvent{Topic: "fhir.patient", Key: resourceID, Payload: []byte(`{}`)})
+	}
+	producer := &recordingProducer{failWith: errors.New("leader not available")}
+	policy := DefaultRelayPolicy()
+	policy.BatchSize = 2
+	relay := NewRelay(store, producer, policy)
+	ctx := context.Background()
+
+	if _, relayError := relay.RelayOnce(ctx); relayError == nil {
+		t.Fatal("Expected the broker failure returned")
+	}
+	if len(store.outboxEvents) != 3 || store.outboxEvents[0].Attempts != 1 {
+		t.Fatalf("Expected the failed batch kept with its attempt counted, got %+v", store.outboxEvents)
+	}
+
+	producer.failWith = nil
+	if published, _ := relay.RelayOnce(ctx); published != 2 {
+		t.Errorf("Expected a full batch published, got %d", published)
+	}
+	if published, _ := relay.RelayOnce(ctx); published != 1 {
+		t.Errorf("Expected the rest published, got %d", published)
+	}
+	if len(producer.produced) != 3 || producer.produced[0].Key != "1" || producer.produced[2].Key != "3" {
+		t.Errorf("Expected events published oldest first, got %+v", producer.produced)
+	}
+
+	stats, _ := relay.Stats(ctx)
+	if stats.Pending != 0 || stats.Published != 3 || stats.Failures != 1 || stats.LastError != "" {
+		t.Errorf("Unexpected relay stats %+v", stats)
+	}
+}
+
+// TestRelay_SkipsWhileAnotherRelayHoldsTheLock verifies only one instance publishes at a time
+func TestRelay_SkipsWhileAnotherRelayHoldsTheLock(t *testing.T) {
+	store := &memoryOutboxStore{locked: true}
+	store.Append(context.Background(), &models.OutboxEvent{Topic: "fhir.patient", Key: "1", Payload: []byte(`{}`)})
+	producer := &recordingProducer{}
+
+	published, relayError := NewRelay(store, producer, DefaultRelayPolicy()).RelayOnce(context.Background())
+	if relayError != nil || published != 0 || len(producer.produced) != 0 {
+		t.Errorf("Expected nothing published without the lock, got %d (%v)", published, relayError)
+	}
+}
@@ -0,0 +1,116 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"golang.org/x/sync/errgroup"
+)
+
+// Branch is one independent read in a fan-out (e.g. "load Observations for the patient")
+// Fetch stores its results through the closure; it must respect context cancellation
+type Branch struct {
+	// Name identifies the branch in partial-result warnings (e.g. "Observation")
+	Name string
+
+	// Required branches abort the whole fan-out on failure; optional ones degrade to a warning
+	Required bool
+
+	// Timeout bounds this branch only; zero uses Options.DefaultTimeout
+	Timeout time.Duration
+
+	// Fetch performs the read
+	Fetch func(ctx context.Context) error
+}
+
+// Options configures a fan-out
+type Options struct {
+	// DefaultTimeout applies to branches without their own timeout (zero means no timeout)
+	DefaultTimeout time.Duration
+
+	// MaxConcurrency limits how many branches run at once (zero means unlimited)
+	MaxConcurrency int
+}
+
+// Failure records an optional branch that did not complete
+type Failure struct {
+	Branch string
+	Err    error
+}
+
+// Result describes the outcome of a fan-out whose required branches all succeeded
+type Result struct {
+	Failures []Failure
+}
+
+// Partial reports whether any optional branch failed, meaning the combined result is incomplete
+func (result Result) Partial() bool {
+	return len(result.Failures) > 0
+}
+
+// Issues converts optional branch failures into warning issues for a partial-result OperationOutcome
+func (result Result) Issues() []outcome.Issue {
+	issues := make([]outcome.Issue, 0, len(result.Failures))
+	for _, failure := range result.Failures {
+		issueCode := fhir.IssueTypeIncomplete
+		if errors.Is(failure.Err, context.DeadlineExceeded) {
+			issueCode = fhir.IssueTypeTimeout
+		}
+		issues = append(issues, outcome.Warning(issueCode, fmt.Sprintf("%s results are incomplete: %v", failure.Branch, failure.Err)))
+	}
+	return issues
+}
+
+// Run executes all branches in parallel and waits for them to finish
+// A failing required branch cancels the remaining branches and its error is returned
+// Failing optional branches are collected in the Result so callers can return partial data
+func Run(ctx context.Context, options Options, branches []Branch) (Result, error) {
+	group, groupContext := errgroup.WithContext(ctx)
+	if options.MaxConcurrency > 0 {
+		group.SetLimit(options.MaxConcurrency)
+	}
+
+	var failuresMutex sync.Mutex
+	failures := make([]Failure, 0)
+
+	for _, branch := range branches {
+		group.Go(func() error {
+			// Apply the per-branch timeout on top of the shared group context
+			branchTimeout := branch.Timeout
+			if branchTimeout == 0 {
+				branchTimeout = options.DefaultTimeout
+			}
+			branchContext := groupContext
+			if branchTimeout > 0 {
+				var cancel context.CancelFunc
+				branchContext, cancel = context.WithTimeout(groupContext, branchTimeout)
+				defer cancel()
+			}
+
+			fetchError := branch.Fetch(branchContext)
+			if fetchError == nil {
+				return nil
+			}
+
+			if branch.Required {
+				return fmt.Errorf("%s: %w", branch.Name, fetchError)
+			}
+
+			failuresMutex.Lock()
+			failures = append(failures, Failure{Branch: branch.Name, Err: fetchError})
+			failuresMutex.Unlock()
+			return nil
+		})
+	}
+
+	if waitError := group.Wait(); waitError != nil {
+		return Result{}, waitError
+	}
+
+	return Result{Failures: failures}, nil
+}
@@ -0,0 +1,140 @@
+package fanout
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestRun_AllBranchesSucceed verifies results from every branch are collected
+func TestRun_AllBranchesSucceed(t *testing.T) {
+	var patientLoaded, observationsLoaded bool
+
+	result, runError := Run(context.Background(), Options{}, []Branch{
+		{Name: "Patient", Required: true, Fetch: func(ctx context.Context) error { patientLoaded = true; return nil }},
+		{Name: "Observation", Fetch: func(ctx context.Context) error { observationsLoaded = true; return nil }},
+	})
+
+	if runError != nil {
+		t.Fatalf("Expected no error, got %v", runError)
+	}
+	if !patientLoaded || !observationsLoaded {
+		t.Error("Expected both branches to run")
+	}
+	if result.Partial() {
+		t.Error("Expected complete result")
+	}
+}
+
+// TestRun_BranchesRunInParallel verifies total latency is not the sum of branch latencies
+func TestRun_BranchesRunInParallel(t *testing.T) {
+	slowFetch := func(ctx context.Context) error {
+		time.Sleep(50 * time.Millisecond)
+		return nil
+	}
+
+	startTime := time.Now()
+	Run(context.Background(), Options{}, []Branch{
+		{Name: "a", Fetch: slowFetch},
+		{Name: "b", Fetch: slowFetch},
+		{Name: "c", Fetch: slowFetch},
+	})
+
+	if elapsed := time.Since(startTime); elapsed > 140*time.Millisecond {
+		t.Errorf("Expected parallel execution, took %v", elapsed)
+	}
+}
+
+// TestRun_OptionalFailureIsPartial verifies optional failures degrade to warnings
+func TestRun_OptionalFailureIsPartial(t *testing.T) {
+	result, runError := Run(context.Background(), Options{}, []Branch{
+		{Name: "Patient", Required: true, Fetch: func(ctx context.Context) error { return nil }},
+		{Name: "Observation", Fetch: func(ctx context.Context) error { return errors.New("mongo unavailable") }},
+	})
+
+	if runError != nil {
+		t.Fatalf("Expected no error, got %v", runError)
+	}
+	if !result.Partial() {
+		t.Fatal("Expected partial result")
+	}
+
+	issues := result.Issues()
+	if len(issues) != 1 || issues[0].Severity != fhir.IssueSeverityWarning || issues[0].Code != fhir.IssueTypeIncomplete {
+		t.Fatalf("Expected one incomplete warning, got %+v", issues)
+	}
+	if !strings.Contains(issues[0].Diagnostics, "Observation") {
+		t.Errorf("Expected diagnostics to name the branch, got %s", issues[0].Diagnostics)
+	}
+}
+
+// TestRun_RequiredFailureCancelsOthers verifies a required failure aborts the fan-out
+func TestRun_RequiredFailureCancelsOthers(t *testing.T) {
+	var cancelled atomic.Bool
+
+	_, runError := Run(context.Background(), Options{}, []Branch{
+		{Name: "Patient", Required: true, Fetch: func(ctx context.Context) error { return errors.New("not found") }},
+		{Name: "Observation", Fetch: func(ctx context.Context) error {
+			<-ctx.Done()
+			cancelled.Store(true)
+			return ctx.Err()
+		}},
+	})
+
+	if runError == nil || !strings.Contains(runError.Error(), "Patient: not found") {
+		t.Fatalf("Expected required branch error, got %v", runError)
+	}
+	if !cancelled.Load() {
+		t.Error("Expected remaining branches to be cancelled")
+	}
+}
+
+// TestRun_BranchTimeout verifies per-branch timeouts are reported as timeout warnings
+func TestRun_BranchTimeout(t *testing.T) {
+	result, runError := Run(context.Background(), Options{DefaultTimeout: time.Second}, []Branch{
+		{Name: "Observation", Timeout: 10 * time.Millisecond, Fetch: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	})
+
+	if runError != nil {
+		t.Fatalf("Expected no error, got %v", runError)
+	}
+	issues := result.Issues()
+	if len(issues) != 1 || issues[0].Code != fhir.IssueTypeTimeout {
+		t.Errorf("Expected timeout warning, got %+v", issues)
+	}
+}
+
+// TestRun_MaxConcurrency verifies the concurrency limit is honored
+func TestRun_MaxConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	trackingFetch := func(ctx context.Context) error {
+		current := running.Add(1)
+		for {
+			observed := maxRunning.Load()
+			if current <= observed || maxRunning.CompareAndSwap(observed, current) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		running.Add(-1)
+		return nil
+	}
+
+	branches := make([]Branch, 5)
+	for index := range branches {
+		branches[index] = Branch{Name: "branch", Fetch: trackingFetch}
+	}
+	Run(context.Background(), Options{MaxConcurrency: 2}, branches)
+
+	if maxRunning.Load() > 2 {
+		t.Errorf("Expected at most 2 concurrent branches, got %d", maxRunning.Load())
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// AllergyIntoleranceHandler handles AllergyIntolerance FHIR resource requests
+type AllergyIntoleranceHandler struct {
+	allergyIntoleranceService *service.AllergyIntoleranceService
+	idCodec                   idcodec.Codec
+	residencyPolicy           *residency.Policy
+}
+
+// NewAllergyIntoleranceHandler creates an AllergyIntoleranceHandler backed by the allergy intolerance service
+func NewAllergyIntoleranceHandler(allergyIntoleranceService *service.AllergyIntoleranceService) *AllergyIntoleranceHandler {
+	return &AllergyIntoleranceHandler{
+		allergyIntoleranceService: allergyIntoleranceService,
+	}
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *AllergyIntoleranceHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetResidencyPolicy refuses patient references to patients held in another region
+func (handler *AllergyIntoleranceHandler) SetResidencyPolicy(policy *residency.Policy) {
+	handler.residencyPolicy = policy
+}
+
+// exposeAllergyIntolerance rewrites an allergy intolerance's ID and patient reference to their exposed forms
+func exposeAllergyIntolerance(ctx context.Context, codec idcodec.Codec, fhirAllergyIntolerance *fhir.AllergyIntolerance) {
+	exposeID(ctx, codec, "AllergyIntolerance", fhirAllergyIntolerance.Id)
+	exposeReference(ctx, codec, fhirAllergyIntolerance.Patient.Reference)
+}
+
+// resolvePatient rewrites an exposed patient reference to the stored patient ID, writing a 400 when it is
+// unknown and a 403 when it points to another region
+func (handler *AllergyIntoleranceHandler) resolvePatient(w http.ResponseWriter, r *http.Request, fhirAllergyIntolerance *fhir.AllergyIntolerance) bool {
+	if handler.residencyPolicy != nil && fhirAllergyIntolerance.Patient.Reference != nil {
+		if residencyError := handler.residencyPolicy.CheckReference(*fhirAllergyIntolerance.Patient.Reference); residencyError != nil {
+			middleware.WriteError(w, r, residencyError)
+			return false
+		}
+	}
+	if resolveError := resolveReference(r.Context(), handler.idCodec, fhirAllergyIntolerance.Patient.Reference); resolveError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("patient", "Unknown patient reference"))
+		return false
+	}
+	return true
+}
+
+// Create handles POST /fhir/AllergyIntolerance - creates a new allergy intolerance
+func (handler *AllergyIntoleranceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirAllergyIntolerance fhir.AllergyIntolerance
+	if decodeError := encoding.Decode(r, &fhirAllergyIntolerance); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR AllergyIntolerance JSON"))
+		return
+	}
+
+	// Translate an exposed patient reference back to the stored ID
+	if !handler.resolvePatient(w, r, &fhirAllergyIntolerance) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdAllergyIntolerance, createError := handler.allergyIntoleranceService.CreateAllergyIntolerance(issueContext, &fhirAllergyIntolerance)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create allergy intolerance")
+		return
+	}
+	exposeAllergyIntolerance(r.Context(), handler.idCodec, createdAllergyIntolerance)
+
+	writeWriteResult(w, r, http.StatusCreated, createdAllergyIntolerance, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/AllergyIntolerance/{id} - retrieves an allergy intolerance by ID
+func (handler *AllergyIntoleranceHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	allergyIntoleranceID := chi.URLParam(r, "id")
+	internalAllergyIntoleranceID, resolved := resolveID(w, r, handler.idCodec, "AllergyIntolerance", allergyIntoleranceID)
+	if !resolved {
+		return
+	}
+
+	fhirAllergyIntolerance, getError := handler.allergyIntoleranceService.GetAllergyIntoleranceByID(r.Context(), internalAllergyIntoleranceID)
+	if errors.Is(getError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("AllergyIntolerance", allergyIntoleranceID))
+		return
+	}
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("AllergyIntolerance", allergyIntoleranceID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read allergy intolerance", getError))
+		return
+	}
+	exposeAllergyIntolerance(r.Context(), handler.idCodec, fhirAllergyIntolerance)
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("AllergyIntolerance", fhirAllergyIntolerance, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/AllergyIntolerance - searches allergy intolerances by patient, clinical status, category, or criticality
+func (handler *AllergyIntoleranceHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseAllergyIntoleranceSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	if searchParams.PatientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", searchParams.PatientID)
+		if !resolved {
+			return
+		}
+		searchParams.PatientID = internalPatientID
+	}
+
+	fhirAllergyIntolerances, searchError := handler.allergyIntoleranceService.SearchAllergyIntolerances(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search allergy intolerances", searchError))
+		return
+	}
+	total, countError := handler.allergyIntoleranceService.CountAllergyIntolerances(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count allergy intolerances", countError))
+		return
+	}
+	for _, fhirAllergyIntolerance := range fhirAllergyIntolerances {
+		exposeAllergyIntolerance(r.Context(), handler.idCodec, fhirAllergyIntolerance)
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "AllergyIntolerance", fhirAllergyIntolerances, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/AllergyIntolerance/{id} - updates an existing allergy intolerance
+func (handler *AllergyIntoleranceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	allergyIntoleranceID := chi.URLParam(r, "id")
+
+	var fhirAllergyIntolerance fhir.AllergyIntolerance
+	if decodeError := encoding.Decode(r, &fhirAllergyIntolerance); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR AllergyIntolerance JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirAllergyIntolerance.Id != nil && *fhirAllergyIntolerance.Id != allergyIntoleranceID {
+		middleware.WriteError(w, r, apperrors.ValidationError("AllergyIntolerance ID in URL does not match ID in body"))
+		return
+	}
+
+	internalAllergyIntoleranceID, resolved := resolveID(w, r, handler.idCodec, "AllergyIntolerance", allergyIntoleranceID)
+	if !resolved {
+		return
+	}
+	if fhirAllergyIntolerance.Id != nil {
+		fhirAllergyIntolerance.Id = &internalAllergyIntoleranceID
+	}
+	if !handler.resolvePatient(w, r, &fhirAllergyIntolerance) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedAllergyIntolerance, updateError := handler.allergyIntoleranceService.UpdateAllergyIntolerance(issueContext, internalAllergyIntoleranceID, &fhirAllergyIntolerance)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("AllergyIntolerance", allergyIntoleranceID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("AllergyIntolerance", allergyIntoleranceID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update allergy intolerance")
+		return
+	}
+	exposeAllergyIntolerance(r.Context(), handler.idCodec, updatedAllergyIntolerance)
+
+	writeWriteResult(w, r, http.StatusOK, updatedAllergyIntolerance, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/AllergyIntolerance/{id} - deletes an allergy intolerance
+func (handler *AllergyIntoleranceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	allergyIntoleranceID := chi.URLParam(r, "id")
+	internalAllergyIntoleranceID, resolved := resolveID(w, r, handler.idCodec, "AllergyIntolerance", allergyIntoleranceID)
+	if !resolved {
+		return
+	}
+
+	if deleteError := handler.allergyIntoleranceService.DeleteAllergyIntolerance(r.Context(), internalAllergyIntoleranceID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "AllergyIntolerance", allergyIntoleranceID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockAllergyIntoleranceRepository implements AllergyIntoleranceRepository interface for testing
+type MockAllergyIntoleranceRepository struct {
+	allergyIntolerances map[string]*models.AllergyIntolerance
+	deleted             map[string]bool
+	lastSearch          *models.AllergyIntoleranceSearchParams
+}
+
+// NewMockAllergyIntoleranceRepository creates a new mock repository for testing
+func NewMockAllergyIntoleranceRepository() *MockAllergyIntoleranceRepository {
+	return &MockAllergyIntoleranceRepository{allergyIntolerances: make(map[string]*models.AllergyIntolerance), deleted: make(map[string]bool)}
+}
+
+// Create stores an allergy intolerance under a generated ObjectID
+func (mock *MockAllergyIntoleranceRepository) Create(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error) {
+	allergyIntolerance.ID = primitive.NewObjectID().Hex()
+	mock.allergyIntolerances[allergyIntolerance.ID] = allergyIntolerance
+	return allergyIntolerance, nil
+}
+
+// GetByID retrieves a stored allergy intolerance
+func (mock *MockAllergyIntoleranceRepository) GetByID(ctx context.Context, allergyIntoleranceID string) (*models.AllergyIntolerance, error) {
+	if mock.deleted[allergyIntoleranceID] {
+		return nil, deletedError(repository.ErrAllergyIntoleranceNotFound)
+	}
+	allergyIntolerance, exists := mock.allergyIntolerances[allergyIntoleranceID]
+	if !exists {
+		return nil, repository.ErrAllergyIntoleranceNotFound
+	}
+	return allergyIntolerance, nil
+}
+
+// Search returns every stored allergy intolerance and remembers the criteria
+func (mock *MockAllergyIntoleranceRepository) Search(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) ([]*models.AllergyIntolerance, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.AllergyIntolerance, 0, len(mock.allergyIntolerances))
+	for _, allergyIntolerance := range mock.allergyIntolerances {
+		result = append(result, allergyIntolerance)
+	}
+	return result, nil
+}
+
+// Count returns how many allergy intolerances are stored, as every search matches them all
+func (mock *MockAllergyIntoleranceRepository) Count(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) (int, error) {
+	return len(mock.allergyIntolerances), nil
+}
+
+// Update replaces a stored allergy intolerance
+func (mock *MockAllergyIntoleranceRepository) Update(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error) {
+	if mock.deleted[allergyIntolerance.ID] {
+		return nil, deletedError(repository.ErrAllergyIntoleranceNotFound)
+	}
+	if _, exists := mock.allergyIntolerances[allergyIntolerance.ID]; !exists {
+		return nil, repository.ErrAllergyIntoleranceNotFound
+	}
+	mock.allergyIntolerances[allergyIntolerance.ID] = allergyIntolerance
+	return allergyIntolerance, nil
+}
+
+// Delete marks a stored allergy intolerance deleted, as the repository does
+func (mock *MockAllergyIntoleranceRepository) Delete(ctx context.Context, allergyIntoleranceID string) error {
+	if mock.deleted[allergyIntoleranceID] {
+		return deletedError(repository.ErrAllergyIntoleranceNotFound)
+	}
+	if _, exists := mock.allergyIntolerances[allergyIntoleranceID]; !exists {
+		return repository.ErrAllergyIntoleranceNotFound
+	}
+	delete(mock.allergyIntolerances, allergyIntoleranceID)
+	mock.deleted[allergyIntoleranceID] = true
+	return nil
+}
+
+// newAllergyIntoleranceRouter registers the AllergyIntolerance routes as cmd/server does
+func newAllergyIntoleranceRouter(handler *AllergyIntoleranceHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/AllergyIntolerance", handler.Create)
+	router.Get("/fhir/AllergyIntolerance/{id}", handler.GetByID)
+	router.Get("/fhir/AllergyIntolerance", handler.GetAll)
+	router.Put("/fhir/AllergyIntolerance/{id}", handler.Update)
+	router.Delete("/fhir/AllergyIntolerance/{id}", handler.Delete)
+	return router
+}
+
+// TestAllergyIntoleranceRoutes verifies create, read, search, update, and delete, that deleted allergies are 410, and that unknown ones are 404
+func TestAllergyIntoleranceRoutes(t *testing.T) {
+	allergyIntoleranceRepository := NewMockAllergyIntoleranceRepository()
+	router := newAllergyIntoleranceRouter(NewAllergyIntoleranceHandler(service.NewAllergyIntoleranceService(allergyIntoleranceRepository)))
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/AllergyIntolerance", `{"resourceType":"AllergyIntolerance",`+
+		`"clinicalStatus":{"coding":[{"system":"http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical","code":"active"}]},`+
+		`"category":["medication"],"criticality":"high",`+
+		`"code":{"coding":[{"system":"http://www.nlm.nih.gov/research/umls/rxnorm","code":"7980","display":"Penicillin G"}]},`+
+		`"patient":{"reference":"Patient/patient-1"}}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the allergy intolerance, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdAllergyIntolerance fhir.AllergyIntolerance
+	json.NewDecoder(createRecorder.Body).Decode(&createdAllergyIntolerance)
+	allergyIntoleranceID := *createdAllergyIntolerance.Id
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/AllergyIntolerance/"+allergyIntoleranceID, "")
+	var readAllergyIntolerance fhir.AllergyIntolerance
+	json.NewDecoder(readRecorder.Body).Decode(&readAllergyIntolerance)
+	if readRecorder.Code != http.StatusOK || *readAllergyIntolerance.Patient.Reference != "Patient/patient-1" || readAllergyIntolerance.Criticality == nil || *readAllergyIntolerance.Criticality != fhir.AllergyIntoleranceCriticalityHigh {
+		t.Errorf("Expected the patient's high-criticality allergy, got %d: %s", readRecorder.Code, readRecorder.Body.String())
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/AllergyIntolerance?patient=Patient/patient-1&clinical-status=active&category=medication&criticality=high", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 1 || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one allergy intolerance, got %s", searchRecorder.Body.String())
+	}
+	lastSearch := allergyIntoleranceRepository.lastSearch
+	if lastSearch.PatientID != "patient-1" || lastSearch.ClinicalStatus != "active" || lastSearch.Category != "medication" || lastSearch.Criticality != "high" {
+		t.Errorf("Expected the patient, clinical status, category, and criticality passed to the repository, got %+v", lastSearch)
+	}
+
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/AllergyIntolerance/"+allergyIntoleranceID, `{"resourceType":"AllergyIntolerance","id":"`+allergyIntoleranceID+`",`+
+		`"clinicalStatus":{"coding":[{"code":"resolved"}]},"patient":{"reference":"Patient/patient-1"}}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the allergy intolerance, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+	if mismatchRecorder := serveCRUD(router, http.MethodPut, "/fhir/AllergyIntolerance/"+allergyIntoleranceID, `{"resourceType":"AllergyIntolerance","id":"other","patient":{"reference":"Patient/patient-1"}}`); mismatchRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body ID that differs from the URL, got %d", mismatchRecorder.Code)
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/AllergyIntolerance/"+allergyIntoleranceID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the allergy intolerance, got %d", deleteRecorder.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveCRUD(router, method, "/fhir/AllergyIntolerance/"+allergyIntoleranceID, ""); recorder.Code != http.StatusGone {
+			t.Errorf("Expected status 410 for %s of a deleted allergy intolerance, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/AllergyIntolerance/"+primitive.NewObjectID().Hex(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 reading an unknown allergy intolerance, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/async"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// asyncRetryAfterSeconds is how long clients are asked to wait between async status polls
+const asyncRetryAfterSeconds = "5"
+
+// AsyncRequestHandler serves the status URLs of requests sent with Prefer: respond-async
+type AsyncRequestHandler struct {
+	runner *async.Runner
+}
+
+// NewAsyncRequestHandler creates a new instance of AsyncRequestHandler
+func NewAsyncRequestHandler(runner *async.Runner) *AsyncRequestHandler {
+	return &AsyncRequestHandler{
+		runner: runner,
+	}
+}
+
+// Status handles GET /fhir/async/{id} - answers 202 with X-Progress while the request waits or runs, and 200
+// with a batch-response Bundle holding the request's response once it finished
+func (handler *AsyncRequestHandler) Status(w http.ResponseWriter, r *http.Request) {
+	job, response, getError := handler.runner.Get(r.Context(), chi.URLParam(r, "id"))
+	if getError != nil {
+		middleware.WriteError(w, r, getError)
+		return
+	}
+
+	switch job.Status {
+	case models.JobStatusQueued, models.JobStatusRunning:
+		progress := "Queued"
+		if job.Status == models.JobStatusRunning {
+			progress = "In progress"
+		}
+		w.Header().Set("X-Progress", progress)
+		w.Header().Set("Retry-After", asyncRetryAfterSeconds)
+		w.WriteHeader(http.StatusAccepted)
+	case models.JobStatusFailed:
+		middleware.WriteError(w, r, apperrors.Internal("Async request failed: "+job.LastError, nil))
+	default:
+		// The request's own status, even an error, is reported in the entry; the poll itself succeeded
+		entry := newEntryResponseWriter()
+		for headerName, value := range response.Headers {
+			entry.header.Set(headerName, value)
+		}
+		entry.statusCode = response.Status
+		entry.body.Write(response.Body)
+
+		if job.ExpiresAt != nil {
+			w.Header().Set("Expires", job.ExpiresAt.UTC().Format(http.TimeFormat))
+		}
+		if job.FinishedAt != nil {
+			w.Header().Set("Last-Modified", job.FinishedAt.UTC().Format(http.TimeFormat))
+		}
+		writeTransactionResponse(w, r, fhir.BundleTypeBatch, []fhir.BundleEntry{entry.responseEntry()})
+	}
+}
+
+// Cancel handles DELETE /fhir/async/{id} - stops the request, or discards its response when it finished
+func (handler *AsyncRequestHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	if cancelError := handler.runner.Cancel(r.Context(), chi.URLParam(r, "id")); cancelError != nil {
+		middleware.WriteError(w, r, cancelError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
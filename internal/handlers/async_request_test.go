@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/async"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// stubAsyncRequestID is the ID of the job held by StubAsyncRequestQueue
+const stubAsyncRequestID = "6f1c2d3e-4a5b-4c6d-8e9f-0a1b2c3d4e5f"
+
+// StubAsyncRequestQueue implements async.Queue with at most one job
+type StubAsyncRequestQueue struct {
+	job *models.Job
+}
+
+// Enqueue stores the job as queued
+func (stub *StubAsyncRequestQueue) Enqueue(ctx context.Context, kind string, payload []byte, maxAttempts int) (*models.Job, error) {
+	stub.job = &models.Job{ID: stubAsyncRequestID, Kind: kind, Payload: payload, Status: models.JobStatusQueued, MaxAttempts: maxAttempts}
+	return stub.Get(ctx, stubAsyncRequestID)
+}
+
+// Get returns the stored job
+func (stub *StubAsyncRequestQueue) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	if stub.job == nil || jobID != stub.job.ID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *stub.job
+	return &copied, nil
+}
+
+// Cancel marks the job cancelled
+func (stub *StubAsyncRequestQueue) Cancel(ctx context.Context, jobID string) error {
+	stub.job.Status = models.JobStatusCancelled
+	return nil
+}
+
+// Discard drops the job
+func (stub *StubAsyncRequestQueue) Discard(ctx context.Context, jobID string) error {
+	stub.job = nil
+	return nil
+}
+
+// newAsyncRequestRouter serves the async status routes over a queue holding job, an async request owned by alice
+func newAsyncRequestRouter(job *models.Job) (*StubAsyncRequestQueue, *chi.Mux) {
+	job.ID = stubAsyncRequestID
+	job.Kind = async.JobKind
+	job.PrincipalID = "alice"
+	job.TenantID = "default"
+	queue := &StubAsyncRequestQueue{job: job}
+	handler := NewAsyncRequestHandler(async.NewRunner(queue, async.DefaultPolicy()))
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principalID := r.Header.Get("X-Test-Principal")
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{ID: principalID})))
+		})
+	})
+	router.Get("/fhir/async/{id}", handler.Status)
+	router.Delete("/fhir/async/{id}", handler.Cancel)
+	return queue, router
+}
+
+// serveAsyncRequest sends a request to the router as alice
+func serveAsyncRequest(router http.Handler, method string, path string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, path, nil)
+	request.Header.Set("X-Test-Principal", "alice")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestAsyncRequestHandler_StatusWhileRunning(t *testing.T) {
+	_, router := newAsyncRequestRouter(&models.Job{Status: models.JobStatusRunning})
+
+	recorder := serveAsyncRequest(router, http.MethodGet, "/fhir/async/"+stubAsyncRequestID)
+
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("X-Progress") != "In progress" || recorder.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected progress headers, got %v", recorder.Header())
+	}
+}
+
+func TestAsyncRequestHandler_StatusWhenComplete(t *testing.T) {
+	finishedAt := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	result, _ := json.Marshal(async.Response{
+		Status:  http.StatusOK,
+		Headers: map[string]string{"Content-Type": "application/fhir+json"},
+		Body:    []byte(`{"resourceType":"Bundle","type":"searchset","total":0}`),
+	})
+	_, router := newAsyncRequestRouter(&models.Job{Status: models.JobStatusSucceeded, Result: result, FinishedAt: &finishedAt})
+
+	recorder := serveAsyncRequest(router, http.MethodGet, "/fhir/async/"+stubAsyncRequestID)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response fhir.Bundle
+	if decodeError := json.Unmarshal(recorder.Body.Bytes(), &response); decodeError != nil {
+		t.Fatalf("Failed to decode response: %v", decodeError)
+	}
+	if response.Type != fhir.BundleTypeBatchResponse || len(response.Entry) != 1 {
+		t.Fatalf("Expected a batch-response with one entry, got %s with %d", response.Type.Code(), len(response.Entry))
+	}
+	if response.Entry[0].Response.Status != "200 OK" || len(response.Entry[0].Resource) == 0 {
+		t.Errorf("Expected the searchset in a 200 entry, got %+v", response.Entry[0].Response)
+	}
+}
+
+func TestAsyncRequestHandler_StatusWhenFailed(t *testing.T) {
+	_, router := newAsyncRequestRouter(&models.Job{Status: models.JobStatusFailed, LastError: "boom"})
+
+	recorder := serveAsyncRequest(router, http.MethodGet, "/fhir/async/"+stubAsyncRequestID)
+
+	if recorder.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status 500, got %d", recorder.Code)
+	}
+}
+
+func TestAsyncRequestHandler_StatusOfAnotherCaller(t *testing.T) {
+	_, router := newAsyncRequestRouter(&models.Job{Status: models.JobStatusRunning})
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/async/"+stubAsyncRequestID, nil)
+	request.Header.Set("X-Test-Principal", "bob")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", recorder.Code)
+	}
+}
+
+func TestAsyncRequestHandler_Cancel(t *testing.T) {
+	queue, router := newAsyncRequestRouter(&models.Job{Status: models.JobStatusQueued})
+
+	recorder := serveAsyncRequest(router, http.MethodDelete, "/fhir/async/"+stubAsyncRequestID)
+
+	if recorder.Code != http.StatusAccepted {
+		t.Fatalf("Expected status 202, got %d", recorder.Code)
+	}
+	if queue.job.Status != models.JobStatusCancelled {
+		t.Errorf("Expected the request to be cancelled, got %s", queue.job.Status)
+	}
+
+	recorder = serveAsyncRequest(router, http.MethodGet, "/fhir/async/"+stubAsyncRequestID)
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected a cancelled request to be gone, got %d", recorder.Code)
+	}
+}
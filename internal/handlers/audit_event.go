@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+)
+
+// AuditEventHandler serves the audit trail as read-only FHIR AuditEvent resources
+// Audit events are written by the audit middleware, never through the API
+type AuditEventHandler struct {
+	auditEventService *service.AuditEventService
+}
+
+// NewAuditEventHandler creates an AuditEventHandler backed by the audit event service
+func NewAuditEventHandler(auditEventService *service.AuditEventService) *AuditEventHandler {
+	return &AuditEventHandler{
+		auditEventService: auditEventService,
+	}
+}
+
+// GetByID handles GET /fhir/AuditEvent/{id} - retrieves an audit event (compliance or admin role only)
+func (handler *AuditEventHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	eventID := chi.URLParam(r, "id")
+
+	fhirEvent, getError := handler.auditEventService.GetAuditEventByID(r.Context(), eventID)
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("AuditEvent", eventID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, getError)
+		return
+	}
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("AuditEvent", fhirEvent, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/AuditEvent - searches audit events by agent, entity, action, outcome, or date
+// (compliance or admin role only)
+func (handler *AuditEventHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseAuditEventSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	fhirEvents, searchError := handler.auditEventService.SearchAuditEvents(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, searchError)
+		return
+	}
+	total, countError := handler.auditEventService.CountAuditEvents(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count audit events", countError))
+		return
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "AuditEvent", fhirEvents, utils.ParseElementsParameter(r), page)
+}
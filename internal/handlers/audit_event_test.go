@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockAuditEventRepository implements AuditEventRepository in memory for testing
+type MockAuditEventRepository struct {
+	events []*models.AuditEvent
+}
+
+// Create stores the event
+func (mock *MockAuditEventRepository) Create(ctx context.Context, event *models.AuditEvent) (*models.AuditEvent, error) {
+	mock.events = append(mock.events, event)
+	return event, nil
+}
+
+// GetByID returns the stored event
+func (mock *MockAuditEventRepository) GetByID(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	for _, event := range mock.events {
+		if event.ID == eventID {
+			return event, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// Search returns every stored event
+func (mock *MockAuditEventRepository) Search(ctx context.Context, searchParams *models.AuditEventSearchParams) ([]*models.AuditEvent, error) {
+	return mock.events, nil
+}
+
+// Count returns the number of stored events
+func (mock *MockAuditEventRepository) Count(ctx context.Context, searchParams *models.AuditEventSearchParams) (int, error) {
+	return len(mock.events), nil
+}
+
+// newTestAuditEventRouter serves the audit event routes over one stored read of a patient
+func newTestAuditEventRouter() *chi.Mux {
+	repository := &MockAuditEventRepository{events: []*models.AuditEvent{{
+		ID:          "6f1c1d3e-8a43-4c52-9f0b-3c2a4f0d9e11",
+		Action:      models.AuditActionRead,
+		Interaction: "read",
+		Outcome:     models.AuditOutcomeSuccess,
+		StatusCode:  http.StatusOK,
+		AgentID:     "api-key:clinic",
+		EntityType:  "Patient",
+		EntityID:    "p-1",
+	}}}
+	handler := NewAuditEventHandler(service.NewAuditEventService(repository))
+
+	router := chi.NewRouter()
+	router.Get("/fhir/AuditEvent", handler.GetAll)
+	router.Get("/fhir/AuditEvent/{id}", handler.GetByID)
+	return router
+}
+
+// auditEventRequest builds a request made with the given roles
+func auditEventRequest(target string, roles ...string) *http.Request {
+	request := httptest.NewRequest(http.MethodGet, target, nil)
+	return request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{ID: "api-key:auditor", Roles: roles}))
+}
+
+// TestAuditEventHandler_Search verifies the trail is served as a searchset of AuditEvents
+func TestAuditEventHandler_Search(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newTestAuditEventRouter().ServeHTTP(recorder, auditEventRequest("/fhir/AuditEvent?entity=Patient/p-1", auth.RoleCompliance))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var searchBundle struct {
+		Total int `json:"total"`
+		Entry []struct {
+			Resource fhir.AuditEvent `json:"resource"`
+		} `json:"entry"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &searchBundle)
+	if searchBundle.Total != 1 || len(searchBundle.Entry) != 1 {
+		t.Fatalf("Expected one audit event, got %s", recorder.Body.String())
+	}
+	event := searchBundle.Entry[0].Resource
+	if event.Entity[0].What == nil || *event.Entity[0].What.Reference != "Patient/p-1" || *event.Agent[0].Who.Identifier.Value != "api-key:clinic" {
+		t.Errorf("Expected the clinic's read of patient p-1, got %+v", event)
+	}
+}
+
+// TestAuditEventHandler_GetByID verifies single events are read and unknown ones are not found
+func TestAuditEventHandler_GetByID(t *testing.T) {
+	router := newTestAuditEventRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, auditEventRequest("/fhir/AuditEvent/6f1c1d3e-8a43-4c52-9f0b-3c2a4f0d9e11", auth.RoleAdmin))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, auditEventRequest("/fhir/AuditEvent/unknown", auth.RoleAdmin))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown event, got %d", recorder.Code)
+	}
+}
+
+// TestAuditEventHandler_RequiresAuditorRole verifies callers without the compliance or admin role are refused
+func TestAuditEventHandler_RequiresAuditorRole(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newTestAuditEventRouter().ServeHTTP(recorder, auditEventRequest("/fhir/AuditEvent", auth.RoleClinician))
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403, got %d", recorder.Code)
+	}
+}
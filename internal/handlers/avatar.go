@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/avatar"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+)
+
+// avatarCacheControl lets browsers keep an avatar for a day; it never changes, but a deleted patient's should go
+const avatarCacheControl = "private, max-age=86400"
+
+// Avatar handles GET /fhir/Patient/{id}/$avatar?size= - a generated identicon for the patient
+// Patients have no stored photos, so UIs use this instead of third-party avatar services that would receive
+// patient identifiers. The image depends only on the stored patient ID, and unchanged avatars are answered
+// with 304 Not Modified
+func (handler *PatientHandler) Avatar(w http.ResponseWriter, r *http.Request) {
+	size := avatar.DefaultSize
+	if rawSize := r.URL.Query().Get("size"); rawSize != "" {
+		parsedSize, parseError := strconv.Atoi(rawSize)
+		if parseError != nil || parsedSize < avatar.MinSize || parsedSize > avatar.MaxSize {
+			middleware.WriteError(w, r, apperrors.InvalidInput("size", "must be a number of pixels from "+strconv.Itoa(avatar.MinSize)+" to "+strconv.Itoa(avatar.MaxSize)))
+			return
+		}
+		size = parsedSize
+	}
+
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+	if _, getError := handler.patientService.GetPatientByID(r.Context(), internalPatientID); getError != nil {
+		writeNotFoundOrGone(w, r, getError, "Patient", patientID)
+		return
+	}
+
+	entityTag := avatar.ETag(internalPatientID, size)
+	w.Header().Set("ETag", entityTag)
+	w.Header().Set("Cache-Control", avatarCacheControl)
+	if entityTagMatches(r.Header.Get("If-None-Match"), entityTag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	image, renderError := avatar.Identicon(internalPatientID, size)
+	if renderError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to render avatar", renderError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Content-Length", strconv.Itoa(len(image)))
+	w.WriteHeader(http.StatusOK)
+	w.Write(image)
+}
+
+// entityTagMatches reports whether an If-None-Match header lists entityTag or is "*"
+// Weak tags match too, as RFC 9110 requires for If-None-Match
+func entityTagMatches(ifNoneMatch string, entityTag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == entityTag {
+			return true
+		}
+	}
+	return false
+}
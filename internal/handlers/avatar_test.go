@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"bytes"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// newAvatarTestRouter routes $avatar over one stored patient
+func newAvatarTestRouter() *chi.Mux {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", FamilyName: "Smith"}
+	handler := NewPatientHandlerWithService(service.NewPatientService(patientRepository))
+
+	router := chi.NewRouter()
+	router.Get("/fhir/Patient/{id}/$avatar", handler.Avatar)
+	return router
+}
+
+// TestPatientHandler_Avatar verifies a cacheable PNG of the requested size is served and revalidated
+func TestPatientHandler_Avatar(t *testing.T) {
+	router := newAvatarTestRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/patient-1/$avatar?size=64", nil))
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "image/png" {
+		t.Fatalf("Expected a PNG, got %d %q", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if recorder.Header().Get("Cache-Control") != avatarCacheControl || recorder.Header().Get("ETag") == "" {
+		t.Errorf("Expected caching headers, got %v", recorder.Header())
+	}
+	decoded, decodeError := png.Decode(bytes.NewReader(recorder.Body.Bytes()))
+	if decodeError != nil || decoded.Bounds().Dx() != 64 {
+		t.Fatalf("Expected a 64 pixel PNG, got %v", decodeError)
+	}
+
+	revalidation := httptest.NewRequest(http.MethodGet, "/fhir/Patient/patient-1/$avatar?size=64", nil)
+	revalidation.Header.Set("If-None-Match", recorder.Header().Get("ETag"))
+	notModified := httptest.NewRecorder()
+	router.ServeHTTP(notModified, revalidation)
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Errorf("Expected 304 without a body, got %d with %d bytes", notModified.Code, notModified.Body.Len())
+	}
+}
+
+// TestPatientHandler_AvatarRejections verifies unknown patients and invalid sizes are refused
+func TestPatientHandler_AvatarRejections(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{"unknown patient", "/fhir/Patient/patient-2/$avatar", http.StatusNotFound},
+		{"size too large", "/fhir/Patient/patient-1/$avatar?size=4096", http.StatusBadRequest},
+		{"size not a number", "/fhir/Patient/patient-1/$avatar?size=large", http.StatusBadRequest},
+	}
+
+	router := newAvatarTestRouter()
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.path, nil))
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("Expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/rs/zerolog/log"
+)
+
+// bulkExportRetryAfterSeconds is how long clients are asked to wait between status polls
+const bulkExportRetryAfterSeconds = "10"
+
+// bulkExportOutputFormats are the _outputFormat values meaning NDJSON, the only format written
+var bulkExportOutputFormats = []string{"application/fhir+ndjson", "application/ndjson", "ndjson"}
+
+// bulkExportParameters are the kick-off parameters the export honors; others are refused unless the client
+// sent Prefer: handling=lenient
+var bulkExportParameters = []string{"_outputFormat", "_type", "_since"}
+
+// BulkExportManifest is the completion manifest of a bulk export, as defined by the FHIR Bulk Data Access spec
+type BulkExportManifest struct {
+	// TransactionTime is when the export was requested; resources changed later may be left out
+	TransactionTime string `json:"transactionTime"`
+
+	// Request is the kick-off request URL
+	Request string `json:"request"`
+
+	// RequiresAccessToken is always true: files are served to the requester's API key only
+	RequiresAccessToken bool `json:"requiresAccessToken"`
+
+	Output []BulkExportOutput `json:"output"`
+	Error  []BulkExportOutput `json:"error"`
+}
+
+// BulkExportOutput is one NDJSON file listed in a completion manifest
+type BulkExportOutput struct {
+	Type  string `json:"type"`
+	URL   string `json:"url"`
+	Count int    `json:"count"`
+}
+
+// BulkExportHandler serves FHIR Bulk Data $export kick-off, status, cancellation, and file requests
+type BulkExportHandler struct {
+	exportService *service.BulkExportService
+}
+
+// NewBulkExportHandler creates a new instance of BulkExportHandler
+func NewBulkExportHandler(exportService *service.BulkExportService) *BulkExportHandler {
+	return &BulkExportHandler{
+		exportService: exportService,
+	}
+}
+
+// KickOff handles GET /fhir/Patient/$export - queues an export of every patient and their compartments
+// Requires Prefer: respond-async, and answers 202 with the status URL in Content-Location
+func (handler *BulkExportHandler) KickOff(w http.ResponseWriter, r *http.Request) {
+	if !hasPreference(r, "respond-async") {
+		middleware.WriteError(w, r, apperrors.InvalidInput("Prefer", "bulk exports are asynchronous; send Prefer: respond-async"))
+		return
+	}
+
+	queryValues := r.URL.Query()
+	if !hasPreference(r, "handling=lenient") {
+		for parameter := range queryValues {
+			if !slices.Contains(bulkExportParameters, parameter) {
+				middleware.WriteError(w, r, apperrors.InvalidInput(parameter, "is not supported by $export"))
+				return
+			}
+		}
+	}
+	if outputFormat := queryValues.Get("_outputFormat"); outputFormat != "" && !slices.Contains(bulkExportOutputFormats, outputFormat) {
+		middleware.WriteError(w, r, apperrors.InvalidInput("_outputFormat", "only application/fhir+ndjson is supported"))
+		return
+	}
+
+	var types []string
+	for _, typeList := range queryValues["_type"] {
+		for _, resourceType := range strings.Split(typeList, ",") {
+			if resourceType = strings.TrimSpace(resourceType); resourceType != "" {
+				types = append(types, resourceType)
+			}
+		}
+	}
+
+	var since *time.Time
+	if rawSince := queryValues.Get("_since"); rawSince != "" {
+		parsedSince, parseError := time.Parse(time.RFC3339, rawSince)
+		if parseError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_since", "must be a FHIR instant, e.g. 2026-01-02T15:04:05Z"))
+			return
+		}
+		since = &parsedSince
+	}
+
+	requestURL := middleware.RequestScheme(r) + "://" + r.Host + r.URL.RequestURI()
+	export, requestError := handler.exportService.Request(r.Context(), requestURL, types, since)
+	if requestError != nil {
+		middleware.WriteError(w, r, requestError)
+		return
+	}
+
+	w.Header().Set("Content-Location", bulkExportURL(r, export.ID))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// Status handles GET /fhir/bulk-export/{id} - answers 202 with X-Progress while the export runs, and 200 with
+// the completion manifest once its files are ready
+func (handler *BulkExportHandler) Status(w http.ResponseWriter, r *http.Request) {
+	exportID, parsed := parseBulkExportID(w, r)
+	if !parsed {
+		return
+	}
+
+	export, getError := handler.exportService.Get(r.Context(), exportID)
+	if getError != nil {
+		middleware.WriteError(w, r, getError)
+		return
+	}
+
+	switch export.Status {
+	case models.BulkExportStatusPending, models.BulkExportStatusRunning:
+		progress := "Queued"
+		if export.Progress != "" {
+			progress = "Exporting " + export.Progress
+		}
+		w.Header().Set("X-Progress", progress)
+		w.Header().Set("Retry-After", bulkExportRetryAfterSeconds)
+		w.WriteHeader(http.StatusAccepted)
+	case models.BulkExportStatusFailed:
+		middleware.WriteError(w, r, apperrors.Internal("Bulk export failed: "+export.Error, nil))
+	default:
+		manifest := BulkExportManifest{
+			TransactionTime:     export.CreatedAt.UTC().Format(time.RFC3339),
+			Request:             export.Request,
+			RequiresAccessToken: true,
+			Output:              []BulkExportOutput{},
+			Error:               []BulkExportOutput{},
+		}
+		for _, file := range export.Files {
+			manifest.Output = append(manifest.Output, BulkExportOutput{
+				Type:  file.ResourceType,
+				URL:   bulkExportURL(r, export.ID) + "/file/" + file.ResourceType,
+				Count: file.ResourceCount,
+			})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if export.ExpiresAt != nil {
+			w.Header().Set("Expires", export.ExpiresAt.UTC().Format(http.TimeFormat))
+		}
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(manifest)
+	}
+}
+
+// Cancel handles DELETE /fhir/bulk-export/{id} - cancels the export and removes its files
+func (handler *BulkExportHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	exportID, parsed := parseBulkExportID(w, r)
+	if !parsed {
+		return
+	}
+
+	if cancelError := handler.exportService.Cancel(r.Context(), exportID); cancelError != nil {
+		middleware.WriteError(w, r, cancelError)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// File handles GET /fhir/bulk-export/{id}/file/{type} - streams the NDJSON file of one resource type
+func (handler *BulkExportHandler) File(w http.ResponseWriter, r *http.Request) {
+	exportID, parsed := parseBulkExportID(w, r)
+	if !parsed {
+		return
+	}
+
+	file, openError := handler.exportService.OpenFile(r.Context(), exportID, chi.URLParam(r, "type"))
+	if openError != nil {
+		middleware.WriteError(w, r, openError)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", "application/fhir+ndjson")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, copyError := io.Copy(w, file); copyError != nil {
+		log.Warn().Err(copyError).Int64("export_id", exportID).Msg("Bulk export file download interrupted")
+	}
+}
+
+// bulkExportURL returns the absolute status URL of an export
+func bulkExportURL(r *http.Request, exportID int64) string {
+	return fhirBaseURL(r) + "/bulk-export/" + strconv.FormatInt(exportID, 10)
+}
+
+// parseBulkExportID reads the export ID from the URL, responding 404 when it is not a number
+func parseBulkExportID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	rawExportID := chi.URLParam(r, "id")
+	exportID, parseError := strconv.ParseInt(rawExportID, 10, 64)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound("BulkExport", rawExportID))
+		return 0, false
+	}
+	return exportID, true
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// StubBulkExportRepository implements BulkExportRepository with at most one export
+type StubBulkExportRepository struct {
+	export *models.BulkExport
+}
+
+// InTransaction runs work directly
+func (stub *StubBulkExportRepository) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return work(ctx)
+}
+
+// Create stores the export as pending
+func (stub *StubBulkExportRepository) Create(ctx context.Context, export *models.BulkExport) (*models.BulkExport, error) {
+	export.ID = 1
+	export.Status = models.BulkExportStatusPending
+	export.CreatedAt = time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	stub.export = export
+	return stub.Get(ctx, export.ID)
+}
+
+// Get returns the stored export
+func (stub *StubBulkExportRepository) Get(ctx context.Context, exportID int64) (*models.BulkExport, error) {
+	if stub.export == nil || exportID != stub.export.ID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *stub.export
+	return &copied, nil
+}
+
+// GetByJob returns the stored export when the job writes it
+func (stub *StubBulkExportRepository) GetByJob(ctx context.Context, jobID string) (*models.BulkExport, error) {
+	if stub.export == nil || jobID != stub.export.JobID {
+		return nil, sql.ErrNoRows
+	}
+	return stub.Get(ctx, stub.export.ID)
+}
+
+// Start marks the stored export running when it is pending
+func (stub *StubBulkExportRepository) Start(ctx context.Context, jobID string) (*models.BulkExport, error) {
+	if stub.export == nil || jobID != stub.export.JobID || stub.export.Status != models.BulkExportStatusPending {
+		return nil, sql.ErrNoRows
+	}
+	stub.export.Status = models.BulkExportStatusRunning
+	return stub.Get(ctx, stub.export.ID)
+}
+
+// UpdateProgress records the progress
+func (stub *StubBulkExportRepository) UpdateProgress(ctx context.Context, exportID int64, progress string) error {
+	stub.export.Progress = progress
+	return nil
+}
+
+// Complete stores the files
+func (stub *StubBulkExportRepository) Complete(ctx context.Context, exportID int64, files []*models.BulkExportFile, expiresAt time.Time) error {
+	stub.export.Status = models.BulkExportStatusComplete
+	stub.export.Files = files
+	stub.export.ExpiresAt = &expiresAt
+	return nil
+}
+
+// Fail records the failure
+func (stub *StubBulkExportRepository) Fail(ctx context.Context, exportID int64, failure string) error {
+	stub.export.Status = models.BulkExportStatusFailed
+	stub.export.Error = failure
+	return nil
+}
+
+// Cancel marks the export cancelled
+func (stub *StubBulkExportRepository) Cancel(ctx context.Context, exportID int64) error {
+	stub.export.Status = models.BulkExportStatusCancelled
+	return nil
+}
+
+// ExpireBefore expires nothing
+func (stub *StubBulkExportRepository) ExpireBefore(ctx context.Context, now time.Time) ([]int64, error) {
+	return nil, nil
+}
+
+// setupBulkExportHandler creates a bulk export handler exporting observations and the router serving it
+func setupBulkExportHandler(t *testing.T) (*chi.Mux, *service.BulkExportService, *StubBulkExportRepository) {
+	t.Helper()
+	exportRepository := &StubBulkExportRepository{}
+	exportService, serviceError := service.NewBulkExportService(exportRepository, nil, service.BulkExportSources{Observations: &StubExportObservationSearcher{}}, &StubAsyncRequestQueue{}, t.TempDir(), service.DefaultBulkExportPolicy())
+	if serviceError != nil {
+		t.Fatalf("Expected no error, got %v", serviceError)
+	}
+
+	exportHandler := NewBulkExportHandler(exportService)
+	router := chi.NewRouter()
+	router.Get("/fhir/Patient/$export", exportHandler.KickOff)
+	router.Get("/fhir/bulk-export/{id}", exportHandler.Status)
+	router.Delete("/fhir/bulk-export/{id}", exportHandler.Cancel)
+	router.Get("/fhir/bulk-export/{id}/file/{type}", exportHandler.File)
+	return router, exportService, exportRepository
+}
+
+// asyncRequest builds an authenticated request preferring an asynchronous response
+func asyncRequest(method string, target string) *http.Request {
+	request := analystRequest(method, target, "")
+	request.Header.Set("Prefer", "respond-async")
+	return request
+}
+
+// TestBulkExportHandler_KickOff verifies the kick-off requirements and the parameters passed to the export
+func TestBulkExportHandler_KickOff(t *testing.T) {
+	router, _, exportRepository := setupBulkExportHandler(t)
+
+	testCases := map[string]*http.Request{
+		"synchronous":          analystRequest(http.MethodGet, "/fhir/Patient/$export", ""),
+		"unsupported format":   asyncRequest(http.MethodGet, "/fhir/Patient/$export?_outputFormat=text/csv"),
+		"unsupported type":     asyncRequest(http.MethodGet, "/fhir/Patient/$export?_type=Practitioner"),
+		"invalid since":        asyncRequest(http.MethodGet, "/fhir/Patient/$export?_since=yesterday"),
+		"unsupported argument": asyncRequest(http.MethodGet, "/fhir/Patient/$export?_typeFilter=Observation%3Fcode%3D1"),
+	}
+	for name, request := range testCases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d: %s", name, recorder.Code, recorder.Body.String())
+		}
+	}
+
+	lenientRequest := asyncRequest(http.MethodGet, "/fhir/Patient/$export?_type=Observation&_since=2026-01-01T00:00:00Z&_typeFilter=x")
+	lenientRequest.Header.Set("Prefer", "respond-async, handling=lenient")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, lenientRequest)
+	if recorder.Code != http.StatusAccepted || recorder.Header().Get("Content-Location") != "http://example.com/fhir/bulk-export/1" {
+		t.Fatalf("Expected 202 with the status URL, got %d %q: %s", recorder.Code, recorder.Header().Get("Content-Location"), recorder.Body.String())
+	}
+	if exportRepository.export.Since == nil || len(exportRepository.export.Types) != 1 || !strings.HasSuffix(exportRepository.export.Request, "/fhir/Patient/$export?_type=Observation&_since=2026-01-01T00:00:00Z&_typeFilter=x") {
+		t.Errorf("Expected the export to keep _type, _since, and the request URL, got %+v", exportRepository.export)
+	}
+}
+
+// TestBulkExportHandler_StatusAndFile verifies progress while running, the manifest once complete, the NDJSON
+// file, and that a cancelled export is gone
+func TestBulkExportHandler_StatusAndFile(t *testing.T) {
+	router, exportService, exportRepository := setupBulkExportHandler(t)
+	router.ServeHTTP(httptest.NewRecorder(), asyncRequest(http.MethodGet, "/fhir/Patient/$export"))
+
+	pendingRecorder := httptest.NewRecorder()
+	router.ServeHTTP(pendingRecorder, analystRequest(http.MethodGet, "/fhir/bulk-export/1", ""))
+	if pendingRecorder.Code != http.StatusAccepted || pendingRecorder.Header().Get("X-Progress") != "Queued" || pendingRecorder.Header().Get("Retry-After") == "" {
+		t.Errorf("Expected 202 with progress while queued, got %d %v", pendingRecorder.Code, pendingRecorder.Header())
+	}
+
+	exportService.Run(context.Background(), &models.Job{ID: exportRepository.export.JobID, Kind: service.BulkExportJobKind}, func(string) {})
+	statusRecorder := httptest.NewRecorder()
+	router.ServeHTTP(statusRecorder, analystRequest(http.MethodGet, "/fhir/bulk-export/1", ""))
+	var manifest BulkExportManifest
+	json.NewDecoder(statusRecorder.Body).Decode(&manifest)
+	if statusRecorder.Code != http.StatusOK || manifest.TransactionTime != "2026-10-01T08:00:00Z" || !manifest.RequiresAccessToken || len(manifest.Output) != 1 {
+		t.Fatalf("Expected the completion manifest, got %d %+v", statusRecorder.Code, manifest)
+	}
+	if output := manifest.Output[0]; output.Type != "Observation" || output.Count != 1 || output.URL != "http://example.com/fhir/bulk-export/1/file/Observation" {
+		t.Errorf("Expected the Observation file, got %+v", output)
+	}
+
+	fileRecorder := httptest.NewRecorder()
+	router.ServeHTTP(fileRecorder, analystRequest(http.MethodGet, "/fhir/bulk-export/1/file/Observation", ""))
+	if fileRecorder.Code != http.StatusOK || fileRecorder.Header().Get("Content-Type") != "application/fhir+ndjson" || !strings.Contains(fileRecorder.Body.String(), `"id":"obs-1"`) {
+		t.Errorf("Expected the NDJSON file, got %d %q", fileRecorder.Code, fileRecorder.Body.String())
+	}
+	anonymousRecorder := httptest.NewRecorder()
+	router.ServeHTTP(anonymousRecorder, httptest.NewRequest(http.MethodGet, "/fhir/bulk-export/1/file/Observation", nil))
+	if anonymousRecorder.Code != http.StatusNotFound {
+		t.Errorf("Expected the file to be hidden from other callers, got %d", anonymousRecorder.Code)
+	}
+
+	cancelRecorder := httptest.NewRecorder()
+	router.ServeHTTP(cancelRecorder, analystRequest(http.MethodDelete, "/fhir/bulk-export/1", ""))
+	goneRecorder := httptest.NewRecorder()
+	router.ServeHTTP(goneRecorder, analystRequest(http.MethodGet, "/fhir/bulk-export/1", ""))
+	if cancelRecorder.Code != http.StatusAccepted || goneRecorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 202 for the cancellation and 404 afterwards, got %d and %d", cancelRecorder.Code, goneRecorder.Code)
+	}
+}
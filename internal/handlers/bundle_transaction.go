@@ -0,0 +1,388 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// maxBundleEntries caps how many entries one transaction or batch Bundle may carry
+const maxBundleEntries = 500
+
+// errTransactionEntryFailed aborts a transaction after one of its entries failed
+var errTransactionEntryFailed = errors.New("transaction entry failed")
+
+// entryRequestHeaders are the conditional headers an entry sets from its request element; the outer
+// request's own values never apply to an entry
+var entryRequestHeaders = []string{"If-Match", "If-None-Match", "If-Modified-Since", "If-None-Exist"}
+
+// mongoResourceNames names the resource types stored in MongoDB, whose updates and deletes cannot be rolled back
+// with a transaction
+var mongoResourceNames = map[string]string{"Observation": "observations", "Condition": "conditions", "AllergyIntolerance": "allergy intolerances", "DiagnosticReport": "diagnostic reports"}
+
+// Transactor runs work in a single database transaction that commits when work succeeds and rolls back when it fails
+type Transactor interface {
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+}
+
+// BundleHandler handles transaction and batch Bundles posted to the FHIR base URL
+// Each entry is served by the router exactly as if it had been sent on its own, so entries pass the same
+// middleware, validation, and access checks as individual requests
+type BundleHandler struct {
+	router     http.Handler
+	transactor Transactor
+}
+
+// NewBundleHandler creates a handler that serves entries through router and runs transactions with transactor
+func NewBundleHandler(router http.Handler, transactor Transactor) *BundleHandler {
+	return &BundleHandler{
+		router:     router,
+		transactor: transactor,
+	}
+}
+
+// Process handles POST /fhir
+// A transaction applies all of its entries or none of them and answers with a transaction-response Bundle,
+// or with the first failing entry's status and an OperationOutcome; a batch applies each entry on its own
+// and reports every entry's outcome in a batch-response Bundle
+func (handler *BundleHandler) Process(w http.ResponseWriter, r *http.Request) {
+	var requestBundle fhir.Bundle
+	bodyBytes, readError := io.ReadAll(r.Body)
+	if readError == nil && encoding.RequestFormat(r) == encoding.FormatXML {
+		bodyBytes, readError = encoding.XMLToJSON(bodyBytes)
+	}
+	if readError != nil || json.Unmarshal(bodyBytes, &requestBundle) != nil || !isBundle(bodyBytes) {
+		outcome.WriteForRequest(w, r, http.StatusBadRequest, []outcome.Issue{outcome.Error(fhir.IssueTypeStructure, "Request body must be a Bundle resource")})
+		return
+	}
+	if requestBundle.Type != fhir.BundleTypeTransaction && requestBundle.Type != fhir.BundleTypeBatch {
+		outcome.WriteForRequest(w, r, http.StatusBadRequest, []outcome.Issue{outcome.Error(fhir.IssueTypeNotSupported, "Only transaction and batch Bundles can be posted, got "+requestBundle.Type.Code(), "Bundle.type")})
+		return
+	}
+	if len(requestBundle.Entry) > maxBundleEntries {
+		outcome.WriteForRequest(w, r, http.StatusRequestEntityTooLarge, []outcome.Issue{outcome.Error(fhir.IssueTypeTooCostly, fmt.Sprintf("Bundle has %d entries, the maximum is %d", len(requestBundle.Entry), maxBundleEntries), "Bundle.entry")})
+		return
+	}
+
+	var issues []outcome.Issue
+	for entryIndex, entry := range requestBundle.Entry {
+		issues = append(issues, entryRequestIssues(entryIndex, entry, requestBundle.Type)...)
+	}
+	if len(issues) > 0 {
+		outcome.WriteForRequest(w, r, http.StatusBadRequest, issues)
+		return
+	}
+
+	if requestBundle.Type == fhir.BundleTypeBatch {
+		handler.processBatch(w, r, requestBundle.Entry)
+		return
+	}
+	handler.processTransaction(w, r, requestBundle.Entry)
+}
+
+// processBatch serves each entry independently, in Bundle order, and reports every outcome
+func (handler *BundleHandler) processBatch(w http.ResponseWriter, r *http.Request, entries []fhir.BundleEntry) {
+	responseEntries := make([]fhir.BundleEntry, 0, len(entries))
+	for _, entry := range entries {
+		responseEntries = append(responseEntries, handler.serveEntry(r.Context(), r, entry).responseEntry())
+	}
+	writeTransactionResponse(w, r, fhir.BundleTypeBatch, responseEntries)
+}
+
+// processTransaction serves every entry inside one database transaction, in FHIR processing order,
+// resolving urn:uuid references to resources created earlier in the Bundle
+// MongoDB creates are undone and events are only published once the transaction commits
+func (handler *BundleHandler) processTransaction(w http.ResponseWriter, r *http.Request, entries []fhir.BundleEntry) {
+	responseEntries := make([]fhir.BundleEntry, len(entries))
+	createdReferences := make(map[string]string)
+	failedEntryIndex := -1
+	var failedResult *entryResponseWriter
+
+	scopeContext, scope := service.WithTransactionScope(r.Context())
+	transactionError := handler.transactor.InTransaction(scopeContext, func(transactionContext context.Context) error {
+		for _, entryIndex := range bundle.TransactionOrder(entries) {
+			entry := entries[entryIndex]
+			entry.Resource = bundle.RewriteReferences(entry.Resource, createdReferences)
+
+			result := handler.serveEntry(transactionContext, r, entry)
+			if !result.succeeded() {
+				failedEntryIndex, failedResult = entryIndex, result
+				return errTransactionEntryFailed
+			}
+			responseEntries[entryIndex] = result.responseEntry()
+
+			if entry.FullUrl != nil && strings.HasPrefix(*entry.FullUrl, "urn:uuid:") && entry.Request.Method == fhir.HTTPVerbPOST {
+				if location := result.location(); location != "" {
+					createdReferences[*entry.FullUrl] = location
+				}
+			}
+		}
+		return nil
+	})
+	if transactionError != nil {
+		scope.Rollback(r.Context())
+		if failedResult == nil {
+			middleware.WriteError(w, r, apperrors.Internal("Failed to commit transaction", transactionError))
+			return
+		}
+
+		log.Warn().
+			Int("entry", failedEntryIndex).
+			Int("status", failedResult.statusCode).
+			Msg("Transaction rolled back after a failed entry")
+
+		entry := entries[failedEntryIndex]
+		entryIssue := outcome.Error(
+			fhir.IssueTypeProcessing,
+			fmt.Sprintf("Transaction rolled back: %s %s failed with %s", entry.Request.Method.Code(), entry.Request.Url, bundle.ResponseStatus(failedResult.statusCode)),
+			fmt.Sprintf("Bundle.entry[%d]", failedEntryIndex),
+		)
+		outcome.WriteForRequest(w, r, failedResult.statusCode, append([]outcome.Issue{entryIssue}, failedResult.issues()...))
+		return
+	}
+
+	scope.Commit(r.Context())
+	writeTransactionResponse(w, r, fhir.BundleTypeTransaction, responseEntries)
+}
+
+// serveEntry replays one entry through the router as a request carrying the outer request's headers,
+// so authentication, tenant, and role apply to it unchanged
+func (handler *BundleHandler) serveEntry(ctx context.Context, r *http.Request, entry fhir.BundleEntry) *entryResponseWriter {
+	recorder := newEntryResponseWriter()
+
+	var body io.Reader = http.NoBody
+	if len(entry.Resource) > 0 {
+		body = bytes.NewReader(entry.Resource)
+	}
+	// A fresh routing context lets the router match the entry's path instead of the outer POST /fhir
+	entryContext := context.WithValue(ctx, chi.RouteCtxKey, nil)
+	entryRequest, requestError := http.NewRequestWithContext(entryContext, entry.Request.Method.Code(), "/fhir/"+entry.Request.Url, body)
+	if requestError != nil {
+		outcome.Write(recorder, http.StatusBadRequest, []outcome.Issue{outcome.Error(fhir.IssueTypeInvalid, "Invalid entry request url: "+requestError.Error())})
+		return recorder
+	}
+
+	entryRequest.Header = r.Header.Clone()
+	entryRequest.Header.Del("Content-Length")
+	entryRequest.Header.Del("Prefer")
+	for _, headerName := range entryRequestHeaders {
+		entryRequest.Header.Del(headerName)
+	}
+	// Entry bodies and responses are JSON whatever format the Bundle itself was exchanged in
+	entryRequest.Header.Set("Content-Type", encoding.JSONMediaType)
+	entryRequest.Header.Set("Accept", encoding.JSONMediaType)
+	setEntryHeader(entryRequest, "If-Match", entry.Request.IfMatch)
+	setEntryHeader(entryRequest, "If-None-Match", entry.Request.IfNoneMatch)
+	setEntryHeader(entryRequest, "If-Modified-Since", entry.Request.IfModifiedSince)
+	setEntryHeader(entryRequest, "If-None-Exist", entry.Request.IfNoneExist)
+	entryRequest.Host = r.Host
+	entryRequest.RemoteAddr = r.RemoteAddr
+	entryRequest.TLS = r.TLS
+
+	handler.router.ServeHTTP(recorder, entryRequest)
+	return recorder
+}
+
+// setEntryHeader sets a conditional header from the entry's request element when it is present
+func setEntryHeader(entryRequest *http.Request, headerName string, value *string) {
+	if value != nil && *value != "" {
+		entryRequest.Header.Set(headerName, *value)
+	}
+}
+
+// isBundle reports whether a JSON body declares itself a Bundle
+func isBundle(bodyBytes []byte) bool {
+	var declaration struct {
+		ResourceType string `json:"resourceType"`
+	}
+	return json.Unmarshal(bodyBytes, &declaration) == nil && declaration.ResourceType == "Bundle"
+}
+
+// entryRequestIssues checks that an entry names a method and a relative URL on this server
+// Transactions may not update or delete observations, since MongoDB cannot roll those writes back
+func entryRequestIssues(entryIndex int, entry fhir.BundleEntry, bundleType fhir.BundleType) []outcome.Issue {
+	expression := fmt.Sprintf("Bundle.entry[%d].request", entryIndex)
+	if entry.Request == nil {
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeRequired, "Entry has no request", expression)}
+	}
+
+	requestURL := entry.Request.Url
+	switch {
+	case requestURL == "":
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeRequired, "Entry request has no url", expression+".url")}
+	case strings.HasPrefix(requestURL, "/") || strings.Contains(requestURL, "://") || strings.Contains(requestURL, ".."):
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeInvalid, "Entry request url must be relative to the FHIR base, e.g. Patient/123", expression+".url")}
+	}
+
+	method := entry.Request.Method
+	if method != fhir.HTTPVerbGET && method != fhir.HTTPVerbPOST && method != fhir.HTTPVerbPUT && method != fhir.HTTPVerbDELETE {
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeNotSupported, "Entry request method "+method.Code()+" is not supported", expression+".method")}
+	}
+	if (method == fhir.HTTPVerbPOST || method == fhir.HTTPVerbPUT) && len(entry.Resource) == 0 {
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeRequired, method.Code()+" entries must carry a resource", fmt.Sprintf("Bundle.entry[%d].resource", entryIndex))}
+	}
+
+	resourceType, resourcePath, _ := strings.Cut(strings.SplitN(requestURL, "?", 2)[0], "/")
+	if resourceName, storedInMongo := mongoResourceNames[resourceType]; storedInMongo && bundleType == fhir.BundleTypeTransaction && (method == fhir.HTTPVerbPUT || method == fhir.HTTPVerbDELETE || resourcePath != "" && method == fhir.HTTPVerbPOST) {
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeNotSupported, "Transactions can create and read "+resourceName+" but not change or delete them; use a batch instead", expression)}
+	}
+	return nil
+}
+
+// writeTransactionResponse responds with the transaction-response or batch-response Bundle for bundleType
+func writeTransactionResponse(w http.ResponseWriter, r *http.Request, bundleType fhir.BundleType, entries []fhir.BundleEntry) {
+	encoding.Write(w, r, http.StatusOK, bundle.TransactionResponse(bundleType, entries))
+}
+
+// entryResponseWriter captures the response to one Bundle entry
+type entryResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+// newEntryResponseWriter creates an empty entry response
+func newEntryResponseWriter() *entryResponseWriter {
+	return &entryResponseWriter{header: make(http.Header)}
+}
+
+// Header returns the entry response's headers
+func (recorder *entryResponseWriter) Header() http.Header {
+	return recorder.header
+}
+
+// WriteHeader records the first status written
+func (recorder *entryResponseWriter) WriteHeader(statusCode int) {
+	if recorder.statusCode == 0 {
+		recorder.statusCode = statusCode
+	}
+}
+
+// Write buffers the body, implying a 200 status when none was written
+func (recorder *entryResponseWriter) Write(bodyBytes []byte) (int, error) {
+	recorder.WriteHeader(http.StatusOK)
+	return recorder.body.Write(bodyBytes)
+}
+
+// succeeded reports whether the entry was served with a 2xx status
+func (recorder *entryResponseWriter) succeeded() bool {
+	return recorder.statusCode >= 200 && recorder.statusCode < 300
+}
+
+// location returns "Type/id" of the resource the entry returned, or its Location header when it has no body
+func (recorder *entryResponseWriter) location() string {
+	var identity struct {
+		ResourceType string `json:"resourceType"`
+		ID           string `json:"id"`
+	}
+	if json.Unmarshal(recorder.body.Bytes(), &identity) == nil && identity.ResourceType != "" && identity.ID != "" && identity.ResourceType != "Bundle" && identity.ResourceType != "OperationOutcome" {
+		return identity.ResourceType + "/" + identity.ID
+	}
+	return strings.TrimPrefix(recorder.header.Get("Location"), "/fhir/")
+}
+
+// responseEntry reports the entry's outcome as a response Bundle entry: its status, the location and version
+// of the resource it wrote, and the resource itself, or an OperationOutcome when it failed
+func (recorder *entryResponseWriter) responseEntry() fhir.BundleEntry {
+	response := &fhir.BundleEntryResponse{Status: bundle.ResponseStatus(recorder.statusCode)}
+	entry := fhir.BundleEntry{Response: response}
+
+	if !recorder.succeeded() {
+		response.Outcome, _ = json.Marshal(outcome.New(recorder.issues()))
+		return entry
+	}
+	if location := recorder.location(); location != "" {
+		response.Location = &location
+	}
+	if entityTag := recorder.header.Get("ETag"); entityTag != "" {
+		response.Etag = &entityTag
+	}
+	if lastModified := recorder.header.Get("Last-Modified"); lastModified != "" {
+		response.LastModified = &lastModified
+	}
+	if json.Valid(recorder.body.Bytes()) {
+		entry.Resource = bytes.TrimSpace(recorder.body.Bytes())
+	}
+	return entry
+}
+
+// issues explains a failed entry as OperationOutcome issues
+// Handlers that answered with an OperationOutcome keep their issues; application error bodies and plain
+// text errors become one issue classified by the status code
+func (recorder *entryResponseWriter) issues() []outcome.Issue {
+	var operationOutcome fhir.OperationOutcome
+	var declaration struct {
+		ResourceType string `json:"resourceType"`
+	}
+	if json.Unmarshal(recorder.body.Bytes(), &declaration) == nil && declaration.ResourceType == "OperationOutcome" && json.Unmarshal(recorder.body.Bytes(), &operationOutcome) == nil {
+		issues := make([]outcome.Issue, 0, len(operationOutcome.Issue))
+		for _, fhirIssue := range operationOutcome.Issue {
+			issue := outcome.Issue{Severity: fhirIssue.Severity, Code: fhirIssue.Code, Expression: fhirIssue.Expression}
+			if fhirIssue.Diagnostics != nil {
+				issue.Diagnostics = *fhirIssue.Diagnostics
+			}
+			issues = append(issues, issue)
+		}
+		return issues
+	}
+	return []outcome.Issue{outcome.Error(issueTypeForStatus(recorder.statusCode), recorder.errorMessage())}
+}
+
+// errorMessage extracts the message of an application error body, a validator error body, or a plain text error
+func (recorder *entryResponseWriter) errorMessage() string {
+	var applicationError struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(recorder.body.Bytes(), &applicationError) == nil && applicationError.Error.Message != "" {
+		return applicationError.Error.Message
+	}
+
+	var validatorError struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(recorder.body.Bytes(), &validatorError) == nil && validatorError.Message != "" {
+		return validatorError.Message
+	}
+
+	if text := strings.TrimSpace(recorder.body.String()); text != "" && !json.Valid(recorder.body.Bytes()) {
+		return text
+	}
+	return http.StatusText(recorder.statusCode)
+}
+
+// issueTypeForStatus classifies a failed entry's HTTP status as a FHIR issue type
+func issueTypeForStatus(statusCode int) fhir.IssueType {
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return fhir.IssueTypeInvalid
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return fhir.IssueTypeSecurity
+	case statusCode == http.StatusNotFound || statusCode == http.StatusGone:
+		return fhir.IssueTypeNotFound
+	case statusCode == http.StatusConflict || statusCode == http.StatusPreconditionFailed:
+		return fhir.IssueTypeConflict
+	case statusCode == http.StatusTooManyRequests:
+		return fhir.IssueTypeThrottled
+	case statusCode >= 500:
+		return fhir.IssueTypeException
+	default:
+		return fhir.IssueTypeProcessing
+	}
+}
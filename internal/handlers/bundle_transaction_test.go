@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// recordingTransactor runs work directly and records how the transaction ended
+type recordingTransactor struct {
+	transactionError error
+	transactions     int
+}
+
+// InTransaction runs work and records its error as the transaction's outcome
+func (transactor *recordingTransactor) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	transactor.transactions++
+	transactor.transactionError = work(ctx)
+	return transactor.transactionError
+}
+
+// newBundleRouter registers POST /fhir next to the CRUD routes its entries are served by
+func newBundleRouter(transactor Transactor) *chi.Mux {
+	router := newCRUDRouter(NewPatientHandlerWithService(service.NewPatientService(NewMockPatientRepository())), NewObservationHandler(NewMockObservationService()))
+	router.Post("/fhir", NewBundleHandler(router, transactor).Process)
+	return router
+}
+
+// TestBundleHandler_Transaction verifies a transaction creates its entries and resolves urn:uuid references between them
+func TestBundleHandler_Transaction(t *testing.T) {
+	transactor := &recordingTransactor{}
+	router := newBundleRouter(transactor)
+
+	recorder := serveCRUD(router, http.MethodPost, "/fhir", `{"resourceType":"Bundle","type":"transaction","entry":[
+		{"fullUrl":"urn:uuid:observation-1","resource":{"resourceType":"Observation","status":"final","code":{"text":"Heart rate"},"subject":{"reference":"urn:uuid:patient-1"}},"request":{"method":"POST","url":"Observation"}},
+		{"fullUrl":"urn:uuid:patient-1","resource":{"resourceType":"Patient","name":[{"family":"Smith"}]},"request":{"method":"POST","url":"Patient"}}
+	]}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if transactor.transactions != 1 || transactor.transactionError != nil {
+		t.Errorf("Expected one committed transaction, got %d ending with %v", transactor.transactions, transactor.transactionError)
+	}
+
+	var responseBundle fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&responseBundle)
+	if responseBundle.Type != fhir.BundleTypeTransactionResponse || len(responseBundle.Entry) != 2 {
+		t.Fatalf("Expected a transaction-response with 2 entries, got %s with %d", responseBundle.Type.Code(), len(responseBundle.Entry))
+	}
+	for entryIndex, entry := range responseBundle.Entry {
+		if entry.Response == nil || entry.Response.Status != "201 Created" || entry.Response.Location == nil {
+			t.Fatalf("Expected entry %d to report 201 Created with a location, got %+v", entryIndex, entry.Response)
+		}
+	}
+
+	patientLocation := *responseBundle.Entry[1].Response.Location
+	if !strings.HasPrefix(patientLocation, "Patient/") {
+		t.Fatalf("Expected the patient entry to be located at Patient/{id}, got %s", patientLocation)
+	}
+	var createdObservation fhir.Observation
+	json.Unmarshal(responseBundle.Entry[0].Resource, &createdObservation)
+	if createdObservation.Subject == nil || *createdObservation.Subject.Reference != patientLocation {
+		t.Errorf("Expected the observation subject to be rewritten to %s, got %+v", patientLocation, createdObservation.Subject)
+	}
+}
+
+// TestBundleHandler_TransactionRollsBack verifies a failing entry fails the whole transaction with its status
+func TestBundleHandler_TransactionRollsBack(t *testing.T) {
+	transactor := &recordingTransactor{}
+	router := newBundleRouter(transactor)
+
+	recorder := serveCRUD(router, http.MethodPost, "/fhir", `{"resourceType":"Bundle","type":"transaction","entry":[
+		{"resource":{"resourceType":"Patient","name":[{"family":"Smith"}]},"request":{"method":"POST","url":"Patient"}},
+		{"request":{"method":"DELETE","url":"Patient/missing"}}
+	]}`)
+	if recorder.Code != http.StatusNotFound {
+		t.Fatalf("Expected the failed entry's status 404, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if transactor.transactionError == nil {
+		t.Error("Expected the transaction to be rolled back")
+	}
+
+	var operationOutcome fhir.OperationOutcome
+	json.NewDecoder(recorder.Body).Decode(&operationOutcome)
+	if len(operationOutcome.Issue) == 0 || len(operationOutcome.Issue[0].Expression) == 0 || operationOutcome.Issue[0].Expression[0] != "Bundle.entry[1]" {
+		t.Errorf("Expected the outcome to point at Bundle.entry[1], got %+v", operationOutcome.Issue)
+	}
+}
+
+// TestBundleHandler_Batch verifies batch entries succeed or fail independently and outside any transaction
+func TestBundleHandler_Batch(t *testing.T) {
+	transactor := &recordingTransactor{}
+	router := newBundleRouter(transactor)
+
+	recorder := serveCRUD(router, http.MethodPost, "/fhir", `{"resourceType":"Bundle","type":"batch","entry":[
+		{"request":{"method":"GET","url":"Patient/missing"}},
+		{"resource":{"resourceType":"Patient","name":[{"family":"Smith"}]},"request":{"method":"POST","url":"Patient"}}
+	]}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if transactor.transactions != 0 {
+		t.Errorf("Expected a batch not to open a transaction, got %d", transactor.transactions)
+	}
+
+	var responseBundle fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&responseBundle)
+	if responseBundle.Type != fhir.BundleTypeBatchResponse || len(responseBundle.Entry) != 2 {
+		t.Fatalf("Expected a batch-response with 2 entries, got %s with %d", responseBundle.Type.Code(), len(responseBundle.Entry))
+	}
+	if status := responseBundle.Entry[0].Response.Status; status != "404 Not Found" || len(responseBundle.Entry[0].Response.Outcome) == 0 {
+		t.Errorf("Expected the missing patient to be reported as 404 with an outcome, got %s", status)
+	}
+	if status := responseBundle.Entry[1].Response.Status; status != "201 Created" {
+		t.Errorf("Expected the create to succeed despite the failed read, got %s", status)
+	}
+}
+
+// TestBundleHandler_RejectsInvalidBundles verifies malformed Bundles are refused before any entry runs
+func TestBundleHandler_RejectsInvalidBundles(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{"not a bundle", `{"resourceType":"Patient"}`, http.StatusBadRequest},
+		{"searchset", `{"resourceType":"Bundle","type":"searchset"}`, http.StatusBadRequest},
+		{"entry without request", `{"resourceType":"Bundle","type":"batch","entry":[{"resource":{"resourceType":"Patient"}}]}`, http.StatusBadRequest},
+		{"absolute url", `{"resourceType":"Bundle","type":"batch","entry":[{"request":{"method":"GET","url":"http://other.example/fhir/Patient/1"}}]}`, http.StatusBadRequest},
+		{"observation update in transaction", `{"resourceType":"Bundle","type":"transaction","entry":[{"resource":{"resourceType":"Observation"},"request":{"method":"PUT","url":"Observation/1"}}]}`, http.StatusBadRequest},
+		{"condition delete in transaction", `{"resourceType":"Bundle","type":"transaction","entry":[{"request":{"method":"DELETE","url":"Condition/1"}}]}`, http.StatusBadRequest},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transactor := &recordingTransactor{}
+			recorder := serveCRUD(newBundleRouter(transactor), http.MethodPost, "/fhir", testCase.body)
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if transactor.transactions != 0 {
+				t.Error("Expected no transaction to be opened")
+			}
+		})
+	}
+}
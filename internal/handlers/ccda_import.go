@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/ccda"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+)
+
+// maxCCDADocumentBytes caps the size of an imported C-CDA document
+const maxCCDADocumentBytes = 16 << 20
+
+// CCDAImportHandler imports C-CDA documents as FHIR resources
+type CCDAImportHandler struct {
+	importer *ccda.Importer
+}
+
+// NewCCDAImportHandler creates a new instance of CCDAImportHandler
+func NewCCDAImportHandler(importer *ccda.Importer) *CCDAImportHandler {
+	return &CCDAImportHandler{
+		importer: importer,
+	}
+}
+
+// Import handles POST /import/ccda - creates the patient, problems, and results of a C-CDA document
+// Responds 201 with the import summary when resources were created, and 200 when the document added nothing
+func (handler *CCDAImportHandler) Import(w http.ResponseWriter, r *http.Request) {
+	rawDocument, readError := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCCDADocumentBytes))
+	if readError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Unreadable C-CDA document"))
+		return
+	}
+	document, parseError := ccda.Parse(rawDocument)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", parseError.Error()))
+		return
+	}
+
+	summary, importError := handler.importer.Import(r.Context(), document)
+	if importError != nil {
+		writeWriteError(w, r, importError, "Failed to import C-CDA document")
+		return
+	}
+
+	statusCode := http.StatusOK
+	if len(summary.Created) > 0 {
+		statusCode = http.StatusCreated
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(summary)
+}
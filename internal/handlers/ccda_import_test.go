@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/ccda"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// testCCDADocument has a problem, a result, and a medications section the importer skips
+const testCCDADocument = `<ClinicalDocument xmlns="urn:hl7-org:v3" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <title>Continuity of Care Document</title>
+  <recordTarget><patientRole>
+    <id root="2.16.840.1.113883.19.5" extension="MRN001"/>
+    <patient><name><given>Jane</given><family>Doe</family></name><administrativeGenderCode code="F"/><birthTime value="19900115"/></patient>
+  </patientRole></recordTarget>
+  <component><structuredBody>
+    <component><section>
+      <templateId root="2.16.840.1.113883.10.20.22.2.5.1"/><title>Problems</title>
+      <entry><act><entryRelationship><observation>
+        <effectiveTime><low value="20200301"/></effectiveTime>
+        <value xsi:type="CD" code="44054006" codeSystem="2.16.840.1.113883.6.96" displayName="Diabetes mellitus type 2"/>
+      </observation></entryRelationship></act></entry>
+    </section></component>
+    <component><section>
+      <templateId root="2.16.840.1.113883.10.20.22.2.3.1"/><title>Results</title>
+      <entry><organizer><effectiveTime value="20261001083000"/><component><observation>
+        <code code="4548-4" codeSystem="2.16.840.1.113883.6.1" displayName="Hemoglobin A1c"/><statusCode code="completed"/>
+        <value xsi:type="PQ" value="7.1" unit="%"/>
+      </observation></component></organizer></entry>
+    </section></component>
+    <component><section>
+      <templateId root="2.16.840.1.113883.10.20.22.2.1.1"/><code code="10160-0"/><title>Medications</title>
+    </section></component>
+  </structuredBody></component>
+</ClinicalDocument>`
+
+// postCCDA sends a document to the import handler
+func postCCDA(handler *CCDAImportHandler, document string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/import/ccda", strings.NewReader(document))
+	request.Header.Set("Content-Type", "application/xml")
+	recorder := httptest.NewRecorder()
+	handler.Import(recorder, request)
+	return recorder
+}
+
+// TestCCDAImportHandler_ImportsDocument verifies the summary of a first import and that a second one reuses the patient
+func TestCCDAImportHandler_ImportsDocument(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	conditionRepository := NewMockConditionRepository()
+	observationService := NewMockObservationService()
+	transactor := &recordingTransactor{}
+	handler := NewCCDAImportHandler(ccda.NewImporter(service.NewPatientService(patientRepository), service.NewConditionService(conditionRepository), observationService, transactor))
+
+	recorder := postCCDA(handler, testCCDADocument)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var summary ccda.Summary
+	if decodeError := json.Unmarshal(recorder.Body.Bytes(), &summary); decodeError != nil {
+		t.Fatalf("Expected a JSON summary, got %v", decodeError)
+	}
+	if !summary.PatientCreated || len(summary.Created) != 3 || summary.Created[1].ResourceType != "Condition" || summary.Created[2].Section != "Results" {
+		t.Errorf("Expected the patient, a condition, and an observation to be created, got %+v", summary)
+	}
+	if len(summary.SkippedSections) != 1 || summary.SkippedSections[0].Title != "Medications" || len(summary.SkippedEntries) != 0 {
+		t.Errorf("Expected only the medications section to be skipped, got %+v", summary)
+	}
+	if len(patientRepository.patients) != 1 || len(conditionRepository.conditions) != 1 || len(observationService.observations) != 1 || transactor.transactions != 1 {
+		t.Errorf("Expected one patient, condition, and observation stored in one transaction")
+	}
+
+	secondRecorder := postCCDA(handler, testCCDADocument)
+	if secondRecorder.Code != http.StatusCreated || strings.Contains(secondRecorder.Body.String(), `"patient_created":true`) || len(patientRepository.patients) != 1 {
+		t.Errorf("Expected the second import to reuse the patient, got %d: %s", secondRecorder.Code, secondRecorder.Body.String())
+	}
+}
+
+// TestCCDAImportHandler_RejectsDocuments verifies unreadable documents are 400 and unimportable patients are 422
+func TestCCDAImportHandler_RejectsDocuments(t *testing.T) {
+	handler := NewCCDAImportHandler(ccda.NewImporter(service.NewPatientService(NewMockPatientRepository()), service.NewConditionService(NewMockConditionRepository()), NewMockObservationService(), &recordingTransactor{}))
+
+	if recorder := postCCDA(handler, `{"resourceType":"Patient"}`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body that is not XML, got %d", recorder.Code)
+	}
+	noIdentifier := strings.Replace(testCCDADocument, `extension="MRN001"`, "", 1)
+	if recorder := postCCDA(handler, noIdentifier); recorder.Code != http.StatusUnprocessableEntity || !strings.Contains(recorder.Body.String(), "recordTarget") {
+		t.Errorf("Expected status 422 naming the recordTarget, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+}
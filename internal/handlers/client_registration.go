@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// ClientRegistrationResponse is the RFC 7591 registration response
+type ClientRegistrationResponse struct {
+	ClientID                string              `json:"client_id"`
+	ClientSecret            string              `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64               `json:"client_id_issued_at"`
+	ClientName              string              `json:"client_name"`
+	ClientType              models.ClientType   `json:"client_type"`
+	RedirectURIs            []string            `json:"redirect_uris,omitempty"`
+	GrantTypes              []string            `json:"grant_types"`
+	TokenEndpointAuthMethod string              `json:"token_endpoint_auth_method"`
+	JWKSURI                 string              `json:"jwks_uri,omitempty"`
+	Scope                   string              `json:"scope"`
+	RegistrationStatus      models.ClientStatus `json:"registration_status"`
+}
+
+// ClientListResponse is the admin listing of registered clients
+type ClientListResponse struct {
+	Clients []*models.RegisteredClient `json:"clients"`
+}
+
+// ClientReviewRequest is the optional body for approving or rejecting a client
+type ClientReviewRequest struct {
+	Note string `json:"note"`
+}
+
+// ClientRegistrationHandler serves self-service registration and the admin approval workflow
+type ClientRegistrationHandler struct {
+	registrationService *service.ClientRegistrationService
+}
+
+// NewClientRegistrationHandler creates a new instance of ClientRegistrationHandler
+func NewClientRegistrationHandler(registrationService *service.ClientRegistrationService) *ClientRegistrationHandler {
+	return &ClientRegistrationHandler{
+		registrationService: registrationService,
+	}
+}
+
+// Register handles POST /oauth/register - registers a partner app pending admin approval
+func (handler *ClientRegistrationHandler) Register(w http.ResponseWriter, r *http.Request) {
+	var registrationRequest models.ClientRegistrationRequest
+	decodeError := json.NewDecoder(r.Body).Decode(&registrationRequest)
+	if decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid client registration JSON"))
+		return
+	}
+
+	client, clientSecret, registerError := handler.registrationService.Register(r.Context(), &registrationRequest)
+	if registerError != nil {
+		middleware.WriteError(w, r, registerError)
+		return
+	}
+
+	registrationResponse := ClientRegistrationResponse{
+		ClientID:                client.ClientID,
+		ClientSecret:            clientSecret,
+		ClientIDIssuedAt:        client.CreatedAt.Unix(),
+		ClientName:              client.ClientName,
+		ClientType:              client.ClientType,
+		RedirectURIs:            client.RedirectURIs,
+		GrantTypes:              client.GrantTypes,
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		JWKSURI:                 client.JWKSURI,
+		Scope:                   strings.Join(client.Scopes, " "),
+		RegistrationStatus:      client.Status,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(registrationResponse)
+}
+
+// List handles GET /admin/clients?status=pending - lists registered clients for review
+func (handler *ClientRegistrationHandler) List(w http.ResponseWriter, r *http.Request) {
+	clients, listError := handler.registrationService.ListClients(r.Context(), models.ClientStatus(r.URL.Query().Get("status")))
+	if listError != nil {
+		middleware.WriteError(w, r, listError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ClientListResponse{Clients: clients})
+}
+
+// Approve handles POST /admin/clients/{clientId}/approve - approves a pending client
+func (handler *ClientRegistrationHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	handler.review(w, r, handler.registrationService.ApproveClient)
+}
+
+// Reject handles POST /admin/clients/{clientId}/reject - rejects a pending client
+func (handler *ClientRegistrationHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	handler.review(w, r, handler.registrationService.RejectClient)
+}
+
+// review applies an approval decision with an optional reviewer note
+func (handler *ClientRegistrationHandler) review(
+	w http.ResponseWriter,
+	r *http.Request,
+	decide func(ctx context.Context, clientID string, reviewNote string) (*models.RegisteredClient, error),
+) {
+	clientID := chi.URLParam(r, "clientId")
+
+	var reviewRequest ClientReviewRequest
+	if r.ContentLength != 0 {
+		decodeError := json.NewDecoder(r.Body).Decode(&reviewRequest)
+		if decodeError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid review JSON"))
+			return
+		}
+	}
+
+	client, reviewError := decide(r.Context(), clientID, reviewRequest.Note)
+	if reviewError != nil {
+		middleware.WriteError(w, r, reviewError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(client)
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// MockClientRepository implements ClientRepository interface for testing
+type MockClientRepository struct {
+	clients map[string]*models.RegisteredClient
+}
+
+// Create stores a client
+func (mock *MockClientRepository) Create(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error) {
+	mock.clients[client.ClientID] = client
+	return client, nil
+}
+
+// GetByID retrieves a client by ID
+func (mock *MockClientRepository) GetByID(ctx context.Context, clientID string) (*models.RegisteredClient, error) {
+	client, exists := mock.clients[clientID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return client, nil
+}
+
+// List retrieves clients filtered by status
+func (mock *MockClientRepository) List(ctx context.Context, status models.ClientStatus) ([]*models.RegisteredClient, error) {
+	clients := []*models.RegisteredClient{}
+	for _, client := range mock.clients {
+		if status == "" || client.Status == status {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+// UpdateStatus stores the client's review decision
+func (mock *MockClientRepository) UpdateStatus(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error) {
+	mock.clients[client.ClientID] = client
+	return client, nil
+}
+
+// newTestClientRegistrationHandler creates a handler backed by an in-memory repository
+func newTestClientRegistrationHandler() *ClientRegistrationHandler {
+	clientRepository := &MockClientRepository{clients: make(map[string]*models.RegisteredClient)}
+	return NewClientRegistrationHandler(service.NewClientRegistrationService(clientRepository))
+}
+
+// newClientReviewRequest builds a review request for the client from a caller with the given roles
+func newClientReviewRequest(clientID string, decision string, roles ...string) *http.Request {
+	reviewRequest := withRoles(httptest.NewRequest(http.MethodPost, "/admin/clients/"+clientID+"/"+decision, bytes.NewBufferString(`{"note": "ok"}`)), roles...)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("clientId", clientID)
+	return reviewRequest.WithContext(context.WithValue(reviewRequest.Context(), chi.RouteCtxKey, routeContext))
+}
+
+// TestClientRegistrationHandler_RegisterAndApprove verifies the registration and approval flow
+func TestClientRegistrationHandler_RegisterAndApprove(t *testing.T) {
+	handler := newTestClientRegistrationHandler()
+
+	registrationBody := `{"client_name": "Clinic Portal", "redirect_uris": ["https://portal.example.org/cb"]}`
+	registerRecorder := httptest.NewRecorder()
+	handler.Register(registerRecorder, httptest.NewRequest(http.MethodPost, "/oauth/register", bytes.NewBufferString(registrationBody)))
+
+	if registerRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", registerRecorder.Code, registerRecorder.Body.String())
+	}
+	var registrationResponse ClientRegistrationResponse
+	json.NewDecoder(registerRecorder.Body).Decode(&registrationResponse)
+	if registrationResponse.ClientID == "" || registrationResponse.ClientSecret == "" {
+		t.Fatal("Expected client ID and secret in the response")
+	}
+	if registrationResponse.RegistrationStatus != models.ClientStatusPending {
+		t.Errorf("Expected pending registration, got %s", registrationResponse.RegistrationStatus)
+	}
+
+	// The pending client shows up in the admin review queue
+	listRecorder := httptest.NewRecorder()
+	handler.List(listRecorder, httptest.NewRequest(http.MethodGet, "/admin/clients?status=pending", nil))
+	var listResponse ClientListResponse
+	json.NewDecoder(listRecorder.Body).Decode(&listResponse)
+	if len(listResponse.Clients) != 1 {
+		t.Fatalf("Expected 1 pending client, got %d", len(listResponse.Clients))
+	}
+
+	// Only the admin role may review registrations
+	forbiddenRecorder := httptest.NewRecorder()
+	handler.Reject(forbiddenRecorder, newClientReviewRequest(registrationResponse.ClientID, "reject", auth.RoleCompliance))
+	if forbiddenRecorder.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 for a non-admin reviewer, got %d", forbiddenRecorder.Code)
+	}
+
+	approveRequest := newClientReviewRequest(registrationResponse.ClientID, "approve", auth.RoleAdmin)
+	approveRecorder := httptest.NewRecorder()
+	handler.Approve(approveRecorder, approveRequest)
+
+	if approveRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", approveRecorder.Code)
+	}
+	var approvedClient models.RegisteredClient
+	json.NewDecoder(approveRecorder.Body).Decode(&approvedClient)
+	if approvedClient.Status != models.ClientStatusApproved || approvedClient.ReviewNote != "ok" {
+		t.Errorf("Expected approved client with note, got %+v", approvedClient)
+	}
+}
+
+// TestClientRegistrationHandler_RegisterInvalid verifies invalid registrations return 400
+func TestClientRegistrationHandler_RegisterInvalid(t *testing.T) {
+	handler := newTestClientRegistrationHandler()
+
+	for _, registrationBody := range []string{`not json`, `{"client_name": "App"}`} {
+		recorder := httptest.NewRecorder()
+		handler.Register(recorder, httptest.NewRequest(http.MethodPost, "/oauth/register", bytes.NewBufferString(registrationBody)))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", registrationBody, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SetCompartmentService enables $everything and _revinclude, which read the other stores in parallel
+func (handler *PatientHandler) SetCompartmentService(compartmentService *service.CompartmentService) {
+	handler.compartmentService = compartmentService
+}
+
+// Everything handles GET /fhir/Patient/{id}/$everything - the patient and everything in its compartment as a searchset Bundle
+// _type limits the Bundle to the listed resource types, and _since to resources changed after that instant.
+// When a store fails or times out the Bundle is still returned, with a warning OperationOutcome entry
+func (handler *PatientHandler) Everything(w http.ResponseWriter, r *http.Request) {
+	if handler.compartmentService == nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Operation", "$everything"))
+		return
+	}
+
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	options, parseError := parseEverythingOptions(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, parseError)
+		return
+	}
+
+	everything, everythingError := handler.compartmentService.Everything(r.Context(), internalPatientID, options)
+	var appError *apperrors.AppError
+	if errors.As(everythingError, &appError) {
+		middleware.WriteError(w, r, appError)
+		return
+	}
+	if everythingError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Patient", patientID))
+		return
+	}
+
+	ctx := r.Context()
+	var patientEntries []fhir.BundleEntry
+	if everything.IncludePatient {
+		exposePatient(ctx, handler.idCodec, everything.Patient)
+		patientEntries = []fhir.BundleEntry{searchEntry(r, everything.Patient, fhir.SearchEntryModeMatch)}
+	}
+	compartmentEntries := make([]fhir.BundleEntry, 0)
+	for _, allergyIntolerance := range everything.AllergyIntolerances {
+		exposeAllergyIntolerance(ctx, handler.idCodec, allergyIntolerance)
+		compartmentEntries = append(compartmentEntries, searchEntry(r, allergyIntolerance, fhir.SearchEntryModeMatch))
+	}
+	for _, condition := range everything.Conditions {
+		exposeCondition(ctx, handler.idCodec, condition)
+		compartmentEntries = append(compartmentEntries, searchEntry(r, condition, fhir.SearchEntryModeMatch))
+	}
+	for _, diagnosticReport := range everything.DiagnosticReports {
+		exposeDiagnosticReport(ctx, handler.idCodec, diagnosticReport)
+		compartmentEntries = append(compartmentEntries, searchEntry(r, diagnosticReport, fhir.SearchEntryModeMatch))
+	}
+	for _, encounter := range everything.Encounters {
+		exposeEncounter(ctx, handler.idCodec, encounter)
+		compartmentEntries = append(compartmentEntries, searchEntry(r, encounter, fhir.SearchEntryModeMatch))
+	}
+	for _, immunization := range everything.Immunizations {
+		exposeImmunization(ctx, handler.idCodec, immunization)
+		compartmentEntries = append(compartmentEntries, searchEntry(r, immunization, fhir.SearchEntryModeMatch))
+	}
+	for _, medicationRequest := range everything.MedicationRequests {
+		exposeMedicationRequest(ctx, handler.idCodec, medicationRequest)
+		compartmentEntries = append(compartmentEntries, searchEntry(r, medicationRequest, fhir.SearchEntryModeMatch))
+	}
+	observationEntries := handler.observationEntries(r, everything.Observations, fhir.SearchEntryModeMatch)
+
+	writeSearchBundle(w, r, bundle.MergeEntries(patientEntries, compartmentEntries, observationEntries, outcomeEntries(r, everything.Issues)))
+}
+
+// parseEverythingOptions reads the _type and _since parameters of $everything
+// _type may be repeated and each value may list several comma-separated types
+func parseEverythingOptions(r *http.Request) (service.EverythingOptions, error) {
+	queryValues := r.URL.Query()
+
+	var options service.EverythingOptions
+	for _, typeList := range queryValues["_type"] {
+		for _, resourceType := range strings.Split(typeList, ",") {
+			if resourceType = strings.TrimSpace(resourceType); resourceType != "" {
+				options.Types = append(options.Types, resourceType)
+			}
+		}
+	}
+
+	if rawSince := queryValues.Get("_since"); rawSince != "" {
+		parsedSince, parseError := time.Parse(time.RFC3339, rawSince)
+		if parseError != nil {
+			return options, apperrors.InvalidInput("_since", "must be a FHIR instant, e.g. 2026-01-02T15:04:05Z")
+		}
+		options.Since = &parsedSince
+	}
+	return options, nil
+}
+
+// writeRevIncludeBundle answers a Patient search with _revinclude as a searchset Bundle of the page's matched
+// patients followed by the observations that reference them
+func (handler *PatientHandler) writeRevIncludeBundle(w http.ResponseWriter, r *http.Request, fhirPatients []*fhir.Patient, internalPatientIDs []string, page bundle.Page) {
+	observations, issues, revIncludeError := handler.compartmentService.RevIncludeObservations(r.Context(), internalPatientIDs)
+	if revIncludeError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read included observations", revIncludeError))
+		return
+	}
+
+	// _elements trims the matched patients; included observations are returned whole
+	requestedElements := utils.ParseElementsParameter(r)
+	patientEntries := make([]fhir.BundleEntry, 0, len(fhirPatients))
+	for _, fhirPatient := range fhirPatients {
+		patientEntries = append(patientEntries, searchEntry(r, subsetElements("Patient", fhirPatient, requestedElements), fhir.SearchEntryModeMatch))
+	}
+
+	writeSearchPage(w, r, bundle.MergeEntries(patientEntries, handler.observationEntries(r, observations, fhir.SearchEntryModeInclude), outcomeEntries(r, issues)), page)
+}
+
+// parseRevIncludes validates the _revinclude parameters, writing a 400 for unsupported ones
+// It reports whether observations should be included and whether the request may continue
+func (handler *PatientHandler) parseRevIncludes(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	revIncludes := r.URL.Query()["_revinclude"]
+	if len(revIncludes) == 0 {
+		return false, true
+	}
+
+	for _, revInclude := range revIncludes {
+		if !slices.Contains(utils.PatientRevIncludes, revInclude) || handler.compartmentService == nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_revinclude", "only Observation:patient and Observation:subject are supported"))
+			return false, false
+		}
+	}
+	return true, true
+}
+
+// observationEntries exposes the observations' IDs and wraps them as Bundle entries
+func (handler *PatientHandler) observationEntries(r *http.Request, observations []*fhir.Observation, mode fhir.SearchEntryMode) []fhir.BundleEntry {
+	entries := make([]fhir.BundleEntry, 0, len(observations))
+	for _, observation := range observations {
+		exposeID(r.Context(), handler.idCodec, "Observation", observation.Id)
+		exposeObservationReferences(r.Context(), handler.idCodec, observation)
+		entries = append(entries, searchEntry(r, observation, mode))
+	}
+	return entries
+}
@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newCompartmentTestRouter routes Patient search and $everything over one patient with one observation
+// plus any additional patients
+func newCompartmentTestRouter(observationService *MockObservationService, additionalPatients ...*models.Patient) *chi.Mux {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", FamilyName: "Smith"}
+	for _, additionalPatient := range additionalPatients {
+		patientRepository.patients[additionalPatient.ID] = additionalPatient
+	}
+	patientService := service.NewPatientService(patientRepository)
+
+	observationID := "obs-1"
+	subject := "Patient/patient-1"
+	observationService.observations[observationID] = &fhir.Observation{Id: &observationID, Subject: &fhir.Reference{Reference: &subject}}
+
+	handler := NewPatientHandlerWithService(patientService)
+	handler.SetCompartmentService(service.NewCompartmentService(patientService, observationService, service.DefaultCompartmentPolicy()))
+
+	router := chi.NewRouter()
+	router.Get("/fhir/Patient", handler.GetAll)
+	router.Get("/fhir/Patient/{id}/$everything", handler.Everything)
+	return router
+}
+
+// decodeSearchBundle decodes a searchset Bundle and returns its entries' search modes and resource types
+func decodeSearchBundle(t *testing.T, recorder *httptest.ResponseRecorder) (fhir.Bundle, []string) {
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var searchBundle fhir.Bundle
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&searchBundle); decodeError != nil {
+		t.Fatalf("Failed to decode Bundle: %v", decodeError)
+	}
+
+	summaries := make([]string, 0, len(searchBundle.Entry))
+	for _, entry := range searchBundle.Entry {
+		var resource struct {
+			ResourceType string `json:"resourceType"`
+		}
+		json.Unmarshal(entry.Resource, &resource)
+		summaries = append(summaries, entry.Search.Mode.Code()+":"+resource.ResourceType)
+	}
+	return searchBundle, summaries
+}
+
+// TestPatientHandler_Everything verifies the compartment is returned as a searchset Bundle
+func TestPatientHandler_Everything(t *testing.T) {
+	router := newCompartmentTestRouter(NewMockObservationService())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/patient-1/$everything", nil))
+
+	searchBundle, summaries := decodeSearchBundle(t, recorder)
+	if len(summaries) != 2 || summaries[0] != "match:Patient" || summaries[1] != "match:Observation" || *searchBundle.Total != 2 {
+		t.Errorf("Expected the patient then its observation, got %v", summaries)
+	}
+
+	missingRecorder := httptest.NewRecorder()
+	router.ServeHTTP(missingRecorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/missing/$everything", nil))
+	if missingRecorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a missing patient, got %d", missingRecorder.Code)
+	}
+}
+
+// stubCompartmentConditions returns one condition of the searched patient
+type stubCompartmentConditions struct{}
+
+// SearchConditions returns the patient's condition
+func (searcher stubCompartmentConditions) SearchConditions(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*fhir.Condition, error) {
+	conditionID := "cond-1"
+	subject := "Patient/" + searchParams.PatientID
+	return []*fhir.Condition{{Id: &conditionID, Subject: fhir.Reference{Reference: &subject}}}, nil
+}
+
+// TestPatientHandler_EverythingFilters verifies other compartment types are returned and _type and _since are parsed
+func TestPatientHandler_EverythingFilters(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", FamilyName: "Smith"}
+	patientService := service.NewPatientService(patientRepository)
+	compartmentService := service.NewCompartmentService(patientService, NewMockObservationService(), service.DefaultCompartmentPolicy())
+	compartmentService.SetSources(service.CompartmentSources{Conditions: stubCompartmentConditions{}})
+	handler := NewPatientHandlerWithService(patientService)
+	handler.SetCompartmentService(compartmentService)
+	router := chi.NewRouter()
+	router.Get("/fhir/Patient/{id}/$everything", handler.Everything)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/patient-1/$everything", nil))
+	if _, summaries := decodeSearchBundle(t, recorder); len(summaries) != 2 || summaries[0] != "match:Patient" || summaries[1] != "match:Condition" {
+		t.Errorf("Expected the patient then its condition, got %v", summaries)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/patient-1/$everything?_type=Condition,Observation", nil))
+	if _, summaries := decodeSearchBundle(t, recorder); len(summaries) != 1 || summaries[0] != "match:Condition" {
+		t.Errorf("Expected only the condition, got %v", summaries)
+	}
+
+	for _, target := range []string{"/fhir/Patient/patient-1/$everything?_type=Practitioner", "/fhir/Patient/patient-1/$everything?_since=yesterday"} {
+		recorder = httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, recorder.Code)
+		}
+	}
+}
+
+// TestPatientHandler_EverythingPartial verifies a failing observation store yields a warning OperationOutcome entry
+func TestPatientHandler_EverythingPartial(t *testing.T) {
+	observationService := NewMockObservationService()
+	observationService.getByPatientError = errors.New("mongo unavailable")
+	router := newCompartmentTestRouter(observationService)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/patient-1/$everything", nil))
+
+	_, summaries := decodeSearchBundle(t, recorder)
+	if len(summaries) != 2 || summaries[0] != "match:Patient" || summaries[1] != "outcome:OperationOutcome" {
+		t.Errorf("Expected the patient and a warning outcome, got %v", summaries)
+	}
+}
+
+// TestPatientHandler_GetAll_RevInclude verifies _revinclude adds observations as include entries
+func TestPatientHandler_GetAll_RevInclude(t *testing.T) {
+	router := newCompartmentTestRouter(NewMockObservationService())
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient?name=Smith&_revinclude=Observation:subject", nil))
+
+	searchBundle, summaries := decodeSearchBundle(t, recorder)
+	if len(summaries) != 2 || summaries[0] != "match:Patient" || summaries[1] != "include:Observation" || *searchBundle.Total != 1 {
+		t.Errorf("Expected one matched patient and one included observation, got %v", summaries)
+	}
+
+	unsupportedRecorder := httptest.NewRecorder()
+	router.ServeHTTP(unsupportedRecorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient?name=Smith&_revinclude=Encounter:patient", nil))
+	if unsupportedRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsupported _revinclude, got %d", unsupportedRecorder.Code)
+	}
+}
+
+// TestPatientHandler_GetAll_RevIncludeOrder verifies included observations are ordered by ID through the bundle merge
+func TestPatientHandler_GetAll_RevIncludeOrder(t *testing.T) {
+	observationService := NewMockObservationService()
+	router := newCompartmentTestRouter(observationService, &models.Patient{ID: "patient-2", FamilyName: "Jones"})
+
+	// patient-2 may be listed second but owns the observation that sorts first
+	observationID := "obs-0"
+	subject := "Patient/patient-2"
+	observationService.observations[observationID] = &fhir.Observation{Id: &observationID, Subject: &fhir.Reference{Reference: &subject}}
+
+	for attempt := 0; attempt < 5; attempt++ {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient?_revinclude=Observation:patient", nil))
+
+		searchBundle, _ := decodeSearchBundle(t, recorder)
+		includedIDs := make([]string, 0)
+		for _, entry := range searchBundle.Entry {
+			if *entry.Search.Mode != fhir.SearchEntryModeInclude {
+				continue
+			}
+			var included fhir.Observation
+			json.Unmarshal(entry.Resource, &included)
+			includedIDs = append(includedIDs, *included.Id)
+		}
+		if len(includedIDs) != 2 || includedIDs[0] != "obs-0" || includedIDs[1] != "obs-1" {
+			t.Fatalf("Expected includes ordered obs-0, obs-1, got %v", includedIDs)
+		}
+	}
+}
+
+// TestObservationHandler_GetAll_Include verifies _include adds each subject patient once as an include entry
+func TestObservationHandler_GetAll_Include(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", FamilyName: "Smith"}
+	patientService := service.NewPatientService(patientRepository)
+
+	observationService := NewMockObservationService()
+	subject := "Patient/patient-1"
+	for _, observationID := range []string{"obs-1", "obs-2"} {
+		observationService.observations[observationID] = &fhir.Observation{Id: &observationID, Subject: &fhir.Reference{Reference: &subject}}
+	}
+	handler := NewObservationHandler(observationService)
+	handler.SetCompartmentService(service.NewCompartmentService(patientService, observationService, service.DefaultCompartmentPolicy()))
+	router := chi.NewRouter()
+	router.Get("/fhir/Observation", handler.GetAll)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Observation?_include=Observation:subject", nil))
+	searchBundle, summaries := decodeSearchBundle(t, recorder)
+	if len(summaries) != 3 || summaries[0] != "match:Observation" || summaries[1] != "match:Observation" || summaries[2] != "include:Patient" {
+		t.Errorf("Expected two matched observations and one included patient, got %v", summaries)
+	}
+	if *searchBundle.Total != 2 {
+		t.Errorf("Expected included patients left out of the total, got %d", *searchBundle.Total)
+	}
+
+	unsupportedRecorder := httptest.NewRecorder()
+	router.ServeHTTP(unsupportedRecorder, httptest.NewRequest(http.MethodGet, "/fhir/Observation?_include=Observation:performer", nil))
+	if unsupportedRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unsupported _include, got %d", unsupportedRecorder.Code)
+	}
+}
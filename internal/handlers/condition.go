@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// ConditionHandler handles Condition FHIR resource requests
+type ConditionHandler struct {
+	conditionService *service.ConditionService
+	idCodec          idcodec.Codec
+	residencyPolicy  *residency.Policy
+}
+
+// NewConditionHandler creates a ConditionHandler backed by the condition service
+func NewConditionHandler(conditionService *service.ConditionService) *ConditionHandler {
+	return &ConditionHandler{
+		conditionService: conditionService,
+	}
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *ConditionHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetResidencyPolicy refuses subject references to patients held in another region
+func (handler *ConditionHandler) SetResidencyPolicy(policy *residency.Policy) {
+	handler.residencyPolicy = policy
+}
+
+// exposeCondition rewrites a condition's ID and subject reference to their exposed forms
+func exposeCondition(ctx context.Context, codec idcodec.Codec, fhirCondition *fhir.Condition) {
+	exposeID(ctx, codec, "Condition", fhirCondition.Id)
+	exposeReference(ctx, codec, fhirCondition.Subject.Reference)
+}
+
+// resolveSubject rewrites an exposed subject reference to the stored patient ID, writing a 400 when it is
+// unknown and a 403 when it points to another region
+func (handler *ConditionHandler) resolveSubject(w http.ResponseWriter, r *http.Request, fhirCondition *fhir.Condition) bool {
+	if handler.residencyPolicy != nil && fhirCondition.Subject.Reference != nil {
+		if residencyError := handler.residencyPolicy.CheckReference(*fhirCondition.Subject.Reference); residencyError != nil {
+			middleware.WriteError(w, r, residencyError)
+			return false
+		}
+	}
+	if resolveError := resolveReference(r.Context(), handler.idCodec, fhirCondition.Subject.Reference); resolveError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("subject", "Unknown patient reference"))
+		return false
+	}
+	return true
+}
+
+// Create handles POST /fhir/Condition - creates a new condition
+func (handler *ConditionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirCondition fhir.Condition
+	if decodeError := encoding.Decode(r, &fhirCondition); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Condition JSON"))
+		return
+	}
+
+	// Translate an exposed patient reference back to the stored ID
+	if !handler.resolveSubject(w, r, &fhirCondition) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdCondition, createError := handler.conditionService.CreateCondition(issueContext, &fhirCondition)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create condition")
+		return
+	}
+	exposeCondition(r.Context(), handler.idCodec, createdCondition)
+
+	writeWriteResult(w, r, http.StatusCreated, createdCondition, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/Condition/{id} - retrieves a condition by ID
+func (handler *ConditionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	conditionID := chi.URLParam(r, "id")
+	internalConditionID, resolved := resolveID(w, r, handler.idCodec, "Condition", conditionID)
+	if !resolved {
+		return
+	}
+
+	fhirCondition, getError := handler.conditionService.GetConditionByID(r.Context(), internalConditionID)
+	if errors.Is(getError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Condition", conditionID))
+		return
+	}
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Condition", conditionID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read condition", getError))
+		return
+	}
+	exposeCondition(r.Context(), handler.idCodec, fhirCondition)
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("Condition", fhirCondition, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/Condition - searches conditions by patient, code, clinical status, or onset date
+func (handler *ConditionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseConditionSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	if searchParams.PatientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", searchParams.PatientID)
+		if !resolved {
+			return
+		}
+		searchParams.PatientID = internalPatientID
+	}
+
+	fhirConditions, searchError := handler.conditionService.SearchConditions(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search conditions", searchError))
+		return
+	}
+	total, countError := handler.conditionService.CountConditions(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count conditions", countError))
+		return
+	}
+	for _, fhirCondition := range fhirConditions {
+		exposeCondition(r.Context(), handler.idCodec, fhirCondition)
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "Condition", fhirConditions, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/Condition/{id} - updates an existing condition
+func (handler *ConditionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	conditionID := chi.URLParam(r, "id")
+
+	var fhirCondition fhir.Condition
+	if decodeError := encoding.Decode(r, &fhirCondition); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Condition JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirCondition.Id != nil && *fhirCondition.Id != conditionID {
+		middleware.WriteError(w, r, apperrors.ValidationError("Condition ID in URL does not match ID in body"))
+		return
+	}
+
+	internalConditionID, resolved := resolveID(w, r, handler.idCodec, "Condition", conditionID)
+	if !resolved {
+		return
+	}
+	if fhirCondition.Id != nil {
+		fhirCondition.Id = &internalConditionID
+	}
+	if !handler.resolveSubject(w, r, &fhirCondition) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedCondition, updateError := handler.conditionService.UpdateCondition(issueContext, internalConditionID, &fhirCondition)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Condition", conditionID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Condition", conditionID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update condition")
+		return
+	}
+	exposeCondition(r.Context(), handler.idCodec, updatedCondition)
+
+	writeWriteResult(w, r, http.StatusOK, updatedCondition, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/Condition/{id} - deletes a condition
+func (handler *ConditionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	conditionID := chi.URLParam(r, "id")
+	internalConditionID, resolved := resolveID(w, r, handler.idCodec, "Condition", conditionID)
+	if !resolved {
+		return
+	}
+
+	if deleteError := handler.conditionService.DeleteCondition(r.Context(), internalConditionID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "Condition", conditionID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockConditionRepository implements ConditionRepository interface for testing
+type MockConditionRepository struct {
+	conditions map[string]*models.Condition
+	deleted    map[string]bool
+	lastSearch *models.ConditionSearchParams
+}
+
+// NewMockConditionRepository creates a new mock repository for testing
+func NewMockConditionRepository() *MockConditionRepository {
+	return &MockConditionRepository{conditions: make(map[string]*models.Condition), deleted: make(map[string]bool)}
+}
+
+// Create stores a condition under a generated ObjectID
+func (mock *MockConditionRepository) Create(ctx context.Context, condition *models.Condition) (*models.Condition, error) {
+	condition.ID = primitive.NewObjectID().Hex()
+	mock.conditions[condition.ID] = condition
+	return condition, nil
+}
+
+// GetByID retrieves a stored condition
+func (mock *MockConditionRepository) GetByID(ctx context.Context, conditionID string) (*models.Condition, error) {
+	if mock.deleted[conditionID] {
+		return nil, deletedError(repository.ErrConditionNotFound)
+	}
+	condition, exists := mock.conditions[conditionID]
+	if !exists {
+		return nil, repository.ErrConditionNotFound
+	}
+	return condition, nil
+}
+
+// Search returns every stored condition and remembers the criteria
+func (mock *MockConditionRepository) Search(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*models.Condition, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.Condition, 0, len(mock.conditions))
+	for _, condition := range mock.conditions {
+		result = append(result, condition)
+	}
+	return result, nil
+}
+
+// Count returns how many conditions are stored, as every search matches them all
+func (mock *MockConditionRepository) Count(ctx context.Context, searchParams *models.ConditionSearchParams) (int, error) {
+	return len(mock.conditions), nil
+}
+
+// Update replaces a stored condition
+func (mock *MockConditionRepository) Update(ctx context.Context, condition *models.Condition) (*models.Condition, error) {
+	if mock.deleted[condition.ID] {
+		return nil, deletedError(repository.ErrConditionNotFound)
+	}
+	if _, exists := mock.conditions[condition.ID]; !exists {
+		return nil, repository.ErrConditionNotFound
+	}
+	mock.conditions[condition.ID] = condition
+	return condition, nil
+}
+
+// Delete marks a stored condition deleted, as the repository does
+func (mock *MockConditionRepository) Delete(ctx context.Context, conditionID string) error {
+	if mock.deleted[conditionID] {
+		return deletedError(repository.ErrConditionNotFound)
+	}
+	if _, exists := mock.conditions[conditionID]; !exists {
+		return repository.ErrConditionNotFound
+	}
+	delete(mock.conditions, conditionID)
+	mock.deleted[conditionID] = true
+	return nil
+}
+
+// newConditionRouter registers the Condition routes as cmd/server does
+func newConditionRouter(handler *ConditionHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/Condition", handler.Create)
+	router.Get("/fhir/Condition/{id}", handler.GetByID)
+	router.Get("/fhir/Condition", handler.GetAll)
+	router.Put("/fhir/Condition/{id}", handler.Update)
+	router.Delete("/fhir/Condition/{id}", handler.Delete)
+	return router
+}
+
+// TestConditionRoutes verifies create, read, search, update, and delete, that deleted conditions are 410, and that unknown ones are 404
+func TestConditionRoutes(t *testing.T) {
+	conditionRepository := NewMockConditionRepository()
+	router := newConditionRouter(NewConditionHandler(service.NewConditionService(conditionRepository)))
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/Condition", `{"resourceType":"Condition",`+
+		`"clinicalStatus":{"coding":[{"system":"http://terminology.hl7.org/CodeSystem/condition-clinical","code":"active"}]},`+
+		`"code":{"coding":[{"system":"http://snomed.info/sct","code":"44054006","display":"Diabetes mellitus type 2"}]},`+
+		`"subject":{"reference":"Patient/patient-1"},"onsetDateTime":"2019-06-01"}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the condition, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdCondition fhir.Condition
+	json.NewDecoder(createRecorder.Body).Decode(&createdCondition)
+	conditionID := *createdCondition.Id
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/Condition/"+conditionID, "")
+	var readCondition fhir.Condition
+	json.NewDecoder(readRecorder.Body).Decode(&readCondition)
+	if readRecorder.Code != http.StatusOK || *readCondition.Subject.Reference != "Patient/patient-1" || readCondition.OnsetDateTime == nil || *readCondition.OnsetDateTime != "2019-06-01" {
+		t.Errorf("Expected the patient's condition with its onset, got %d: %s", readRecorder.Code, readRecorder.Body.String())
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/Condition?patient=Patient/patient-1&code=http://snomed.info/sct|44054006&clinical-status=active&onset-date=ge2019-01-01", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 1 || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one condition, got %s", searchRecorder.Body.String())
+	}
+	lastSearch := conditionRepository.lastSearch
+	if lastSearch.PatientID != "patient-1" || lastSearch.ClinicalStatus != "active" || lastSearch.Code == nil || lastSearch.OnsetDateGreaterThan == nil {
+		t.Errorf("Expected the patient, code, clinical status, and onset passed to the repository, got %+v", lastSearch)
+	}
+
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/Condition/"+conditionID, `{"resourceType":"Condition","id":"`+conditionID+`",`+
+		`"clinicalStatus":{"coding":[{"code":"resolved"}]},"subject":{"reference":"Patient/patient-1"}}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the condition, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+	if mismatchRecorder := serveCRUD(router, http.MethodPut, "/fhir/Condition/"+conditionID, `{"resourceType":"Condition","id":"other","subject":{"reference":"Patient/patient-1"}}`); mismatchRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body ID that differs from the URL, got %d", mismatchRecorder.Code)
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/Condition/"+conditionID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the condition, got %d", deleteRecorder.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveCRUD(router, method, "/fhir/Condition/"+conditionID, ""); recorder.Code != http.StatusGone {
+			t.Errorf("Expected status 410 for %s of a deleted condition, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/Condition/"+primitive.NewObjectID().Hex(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 reading an unknown condition, got %d", recorder.Code)
+	}
+}
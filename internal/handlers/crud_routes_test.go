@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newCRUDRouter registers the Patient and Observation CRUD routes as cmd/server does
+func newCRUDRouter(patientHandler *PatientHandler, observationHandler *ObservationHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/Patient", patientHandler.Create)
+	router.Get("/fhir/Patient/{id}", patientHandler.GetByID)
+	router.Get("/fhir/Patient", patientHandler.GetAll)
+	router.Put("/fhir/Patient/{id}", patientHandler.Update)
+	router.Delete("/fhir/Patient/{id}", patientHandler.Delete)
+	router.Post("/fhir/Observation", observationHandler.Create)
+	router.Get("/fhir/Observation/{id}", observationHandler.GetByID)
+	router.Get("/fhir/Observation", observationHandler.GetAll)
+	router.Put("/fhir/Observation/{id}", observationHandler.Update)
+	router.Delete("/fhir/Observation/{id}", observationHandler.Delete)
+	return router
+}
+
+// deletedError is the error a repository returns for a resource it marked deleted, matching its notFound error
+func deletedError(notFound error) error {
+	return fmt.Errorf("%w: %w", notFound, repository.ErrResourceDeleted)
+}
+
+// serveCRUD sends one request through the router and returns the recorded response
+func serveCRUD(router *chi.Mux, method string, target string, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/fhir+json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestPatientRoutes_UpdateAndDelete verifies PUT and DELETE /fhir/Patient/{id} are routed and report deleted patients as 410
+func TestPatientRoutes_UpdateAndDelete(t *testing.T) {
+	patientID := "6f1c2a3e-8d4b-4c5a-9e7f-0a1b2c3d4e5f"
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients[patientID] = &models.Patient{ID: patientID, FamilyName: "Smith"}
+	router := newCRUDRouter(NewPatientHandlerWithService(service.NewPatientService(patientRepository)), NewObservationHandler(NewMockObservationService()))
+
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/Patient/"+patientID, `{"resourceType":"Patient","id":"`+patientID+`","name":[{"family":"Jones"}]}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the patient, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/Patient/"+patientID, "")
+	var readPatient fhir.Patient
+	json.NewDecoder(readRecorder.Body).Decode(&readPatient)
+	if len(readPatient.Name) != 1 || readPatient.Name[0].Family == nil || *readPatient.Name[0].Family != "Jones" {
+		t.Errorf("Expected the updated name to be read back, got %+v", readPatient.Name)
+	}
+
+	mismatchRecorder := serveCRUD(router, http.MethodPut, "/fhir/Patient/"+patientID, `{"resourceType":"Patient","id":"other","name":[{"family":"Jones"}]}`)
+	if mismatchRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body ID that differs from the URL, got %d", mismatchRecorder.Code)
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/Patient/"+patientID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the patient, got %d", deleteRecorder.Code)
+	}
+	if readRecorder := serveCRUD(router, http.MethodGet, "/fhir/Patient/"+patientID, ""); readRecorder.Code != http.StatusGone {
+		t.Errorf("Expected status 410 reading a deleted patient, got %d", readRecorder.Code)
+	}
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/Patient/"+patientID, ""); deleteRecorder.Code != http.StatusGone {
+		t.Errorf("Expected status 410 deleting a deleted patient, got %d", deleteRecorder.Code)
+	}
+	if updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/Patient/"+patientID, `{"resourceType":"Patient","name":[{"family":"Jones"}]}`); updateRecorder.Code != http.StatusGone {
+		t.Errorf("Expected status 410 updating a deleted patient, got %d", updateRecorder.Code)
+	}
+}
+
+// TestObservationRoutes_UpdateAndDelete verifies PUT and DELETE /fhir/Observation/{id} are routed and report deleted observations as 410
+func TestObservationRoutes_UpdateAndDelete(t *testing.T) {
+	observationService := NewMockObservationService()
+	router := newCRUDRouter(NewPatientHandlerWithService(service.NewPatientService(NewMockPatientRepository())), NewObservationHandler(observationService))
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/Observation", `{"resourceType":"Observation","status":"preliminary","code":{"text":"Heart rate"},"subject":{"reference":"Patient/patient-1"}}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the observation, got %d", createRecorder.Code)
+	}
+	var createdObservation fhir.Observation
+	json.NewDecoder(createRecorder.Body).Decode(&createdObservation)
+	observationPath := "/fhir/Observation/" + *createdObservation.Id
+
+	updateRecorder := serveCRUD(router, http.MethodPut, observationPath, `{"resourceType":"Observation","status":"final","code":{"text":"Heart rate"},"subject":{"reference":"Patient/patient-1"}}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the observation, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+	var readObservation fhir.Observation
+	json.NewDecoder(serveCRUD(router, http.MethodGet, observationPath, "").Body).Decode(&readObservation)
+	if readObservation.Status != fhir.ObservationStatusFinal {
+		t.Errorf("Expected the updated status to be read back, got %s", readObservation.Status.Code())
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, observationPath, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the observation, got %d", deleteRecorder.Code)
+	}
+	if readRecorder := serveCRUD(router, http.MethodGet, observationPath, ""); readRecorder.Code != http.StatusGone {
+		t.Errorf("Expected status 410 reading a deleted observation, got %d", readRecorder.Code)
+	}
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, observationPath, ""); deleteRecorder.Code != http.StatusGone {
+		t.Errorf("Expected status 410 deleting a deleted observation, got %d", deleteRecorder.Code)
+	}
+	if updateRecorder := serveCRUD(router, http.MethodPut, observationPath, `{"resourceType":"Observation","status":"final","code":{"text":"Heart rate"},"subject":{"reference":"Patient/patient-1"}}`); updateRecorder.Code != http.StatusGone {
+		t.Errorf("Expected status 410 updating a deleted observation, got %d", updateRecorder.Code)
+	}
+}
+
+// TestObservationRoutes_IfMatch verifies reads return the version as an ETag and stale If-Match updates get 412
+func TestObservationRoutes_IfMatch(t *testing.T) {
+	router := newCRUDRouter(NewPatientHandlerWithService(service.NewPatientService(NewMockPatientRepository())), NewObservationHandler(NewMockObservationService()))
+	observationBody := `{"resourceType":"Observation","status":"final","code":{"text":"Heart rate"},"subject":{"reference":"Patient/patient-1"}}`
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/Observation", observationBody)
+	var createdObservation fhir.Observation
+	json.NewDecoder(createRecorder.Body).Decode(&createdObservation)
+	observationPath := "/fhir/Observation/" + *createdObservation.Id
+
+	if entityTag := serveCRUD(router, http.MethodGet, observationPath, "").Header().Get("ETag"); entityTag != `W/"1"` {
+		t.Fatalf(`Expected ETag W/"1" on read, got %q`, entityTag)
+	}
+
+	serveConditional := func(ifMatch string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPut, observationPath, strings.NewReader(observationBody))
+		request.Header.Set("Content-Type", "application/fhir+json")
+		request.Header.Set("If-Match", ifMatch)
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	updateRecorder := serveConditional(`W/"1"`)
+	if updateRecorder.Code != http.StatusOK || updateRecorder.Header().Get("ETag") != `W/"2"` {
+		t.Fatalf(`Expected 200 with ETag W/"2", got %d with %q`, updateRecorder.Code, updateRecorder.Header().Get("ETag"))
+	}
+	if staleRecorder := serveConditional(`W/"1"`); staleRecorder.Code != http.StatusPreconditionFailed {
+		t.Errorf("Expected 412 for a stale If-Match, got %d", staleRecorder.Code)
+	}
+	if malformedRecorder := serveConditional("latest"); malformedRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a malformed If-Match, got %d", malformedRecorder.Code)
+	}
+	if wildcardRecorder := serveConditional("*"); wildcardRecorder.Code != http.StatusOK {
+		t.Errorf("Expected If-Match * to accept any version, got %d", wildcardRecorder.Code)
+	}
+}
+
+// TestPatientRoutes_XML verifies a patient can be created in FHIR XML and read back as XML or JSON
+func TestPatientRoutes_XML(t *testing.T) {
+	router := newCRUDRouter(NewPatientHandlerWithService(service.NewPatientService(NewMockPatientRepository())), NewObservationHandler(NewMockObservationService()))
+
+	createRequest := httptest.NewRequest(http.MethodPost, "/fhir/Patient", strings.NewReader(`<Patient xmlns="http://hl7.org/fhir"><active value="true"/><name><family value="Smith"/><given value="Jane"/></name></Patient>`))
+	createRequest.Header.Set("Content-Type", "application/fhir+xml")
+	createRequest.Header.Set("Accept", "application/fhir+xml")
+	createRecorder := httptest.NewRecorder()
+	router.ServeHTTP(createRecorder, createRequest)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the XML patient, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	if contentType := createRecorder.Header().Get("Content-Type"); contentType != "application/fhir+xml" {
+		t.Errorf("Expected an XML response, got %s", contentType)
+	}
+	if !strings.Contains(createRecorder.Body.String(), `<family value="Smith"/><given value="Jane"/>`) {
+		t.Errorf("Expected the created patient as XML, got %s", createRecorder.Body.String())
+	}
+
+	var createdPatient fhir.Patient
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/Patient", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if len(searchset.Entry) != 1 {
+		t.Fatalf("Expected the created patient to be found, got %s", searchRecorder.Body.String())
+	}
+	json.Unmarshal(searchset.Entry[0].Resource, &createdPatient)
+	if createdPatient.Active == nil || !*createdPatient.Active || len(createdPatient.Name) != 1 || createdPatient.Name[0].Given[0] != "Jane" {
+		t.Errorf("Expected the XML patient's elements stored, got %s", searchset.Entry[0].Resource)
+	}
+
+	xmlReadRecorder := serveCRUD(router, http.MethodGet, "/fhir/Patient/"+*createdPatient.Id+"?_format=xml", "")
+	if !strings.HasPrefix(xmlReadRecorder.Body.String(), "<?xml") || !strings.Contains(xmlReadRecorder.Body.String(), `<id value="`+*createdPatient.Id+`"/>`) {
+		t.Errorf("Expected _format=xml to return the patient as XML, got %s", xmlReadRecorder.Body.String())
+	}
+}
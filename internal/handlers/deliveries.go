@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+const (
+	// defaultDeadLetterPageSize is the number of dead letters returned when _count is omitted
+	defaultDeadLetterPageSize = 50
+
+	// maxDeadLetterPageSize caps the number of dead letters returned per request
+	maxDeadLetterPageSize = 500
+)
+
+// DeadLetterResponse is a page of dead-lettered deliveries
+type DeadLetterResponse struct {
+	Deliveries []*models.Delivery `json:"deliveries"`
+}
+
+// DeliveryHandler exposes the outbound delivery queue and its dead letters
+type DeliveryHandler struct {
+	queue *delivery.Queue
+}
+
+// NewDeliveryHandler creates a new instance of DeliveryHandler
+func NewDeliveryHandler(queue *delivery.Queue) *DeliveryHandler {
+	return &DeliveryHandler{
+		queue: queue,
+	}
+}
+
+// Stats handles GET /admin/deliveries - returns queue depth, counters, and open circuits
+func (handler *DeliveryHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, statsError := handler.queue.Stats(r.Context())
+	if statsError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read delivery queue", statsError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
+
+// DeadLetters handles GET /admin/deliveries/dead-letter?_count={n}&_offset={n} - lists dead letters
+func (handler *DeliveryHandler) DeadLetters(w http.ResponseWriter, r *http.Request) {
+	pageSize := defaultDeadLetterPageSize
+	if countParam := r.URL.Query().Get("_count"); countParam != "" {
+		parsedCount, parseError := strconv.Atoi(countParam)
+		if parseError != nil || parsedCount < 1 || parsedCount > maxDeadLetterPageSize {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_count", "must be between 1 and "+strconv.Itoa(maxDeadLetterPageSize)))
+			return
+		}
+		pageSize = parsedCount
+	}
+
+	offset := 0
+	if offsetParam := r.URL.Query().Get("_offset"); offsetParam != "" {
+		parsedOffset, parseError := strconv.Atoi(offsetParam)
+		if parseError != nil || parsedOffset < 0 {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_offset", "must be a non-negative integer"))
+			return
+		}
+		offset = parsedOffset
+	}
+
+	deadLetters, listError := handler.queue.DeadLetters(r.Context(), pageSize, offset)
+	if listError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to list dead letters", listError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(DeadLetterResponse{Deliveries: deadLetters})
+}
+
+// Retry handles POST /admin/deliveries/dead-letter/{id}/retry - requeues a dead letter with its attempts reset
+func (handler *DeliveryHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	handler.changeDeadLetter(w, r, handler.queue.Retry)
+}
+
+// Discard handles DELETE /admin/deliveries/dead-letter/{id} - deletes a dead letter
+func (handler *DeliveryHandler) Discard(w http.ResponseWriter, r *http.Request) {
+	handler.changeDeadLetter(w, r, handler.queue.Discard)
+}
+
+// changeDeadLetter applies an action to the dead letter named in the URL and responds 204
+func (handler *DeliveryHandler) changeDeadLetter(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, id int64) error) {
+	deliveryID := chi.URLParam(r, "id")
+	parsedID, parseError := strconv.ParseInt(deliveryID, 10, 64)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Dead letter", deliveryID))
+		return
+	}
+
+	actionError := action(r.Context(), parsedID)
+	if errors.Is(actionError, sql.ErrNoRows) {
+		middleware.WriteError(w, r, apperrors.NotFound("Dead letter", deliveryID))
+		return
+	}
+	if actionError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to update dead letter", actionError))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// MockDeliveryStore holds a fixed set of dead letters
+type MockDeliveryStore struct {
+	deadLetters map[int64]*models.Delivery
+	requeued    []int64
+}
+
+// Enqueue is unused by the handler tests
+func (store *MockDeliveryStore) Enqueue(ctx context.Context, delivery *models.Delivery) (*models.Delivery, error) {
+	return delivery, nil
+}
+
+// EnqueueAll is unused by the handler tests
+func (store *MockDeliveryStore) EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error) {
+	return deliveries, nil
+}
+
+// ClaimDue is unused by the handler tests
+func (store *MockDeliveryStore) ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*models.Delivery, error) {
+	return nil, nil
+}
+
+// Complete is unused by the handler tests
+func (store *MockDeliveryStore) Complete(ctx context.Context, id int64) error {
+	return nil
+}
+
+// Reschedule is unused by the handler tests
+func (store *MockDeliveryStore) Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	return nil
+}
+
+// MarkDead is unused by the handler tests
+func (store *MockDeliveryStore) MarkDead(ctx context.Context, id int64, attempts int, lastError string) error {
+	return nil
+}
+
+// ListDead returns the dead letters
+func (store *MockDeliveryStore) ListDead(ctx context.Context, limit int, offset int) ([]*models.Delivery, error) {
+	deadLetters := []*models.Delivery{}
+	for _, deadLetter := range store.deadLetters {
+		deadLetters = append(deadLetters, deadLetter)
+	}
+	return deadLetters, nil
+}
+
+// Requeue records the requeue of a known dead letter
+func (store *MockDeliveryStore) Requeue(ctx context.Context, id int64) error {
+	if _, exists := store.deadLetters[id]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(store.deadLetters, id)
+	store.requeued = append(store.requeued, id)
+	return nil
+}
+
+// DeleteDead removes a known dead letter
+func (store *MockDeliveryStore) DeleteDead(ctx context.Context, id int64) error {
+	if _, exists := store.deadLetters[id]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(store.deadLetters, id)
+	return nil
+}
+
+// CountByStatus reports the dead letters
+func (store *MockDeliveryStore) CountByStatus(ctx context.Context) (map[models.DeliveryStatus]int64, error) {
+	return map[models.DeliveryStatus]int64{models.DeliveryStatusDead: int64(len(store.deadLetters))}, nil
+}
+
+// newTestDeliveryHandler creates a handler over one dead letter with ID 1
+func newTestDeliveryHandler() (*DeliveryHandler, *MockDeliveryStore) {
+	store := &MockDeliveryStore{deadLetters: map[int64]*models.Delivery{
+		1: {ID: 1, Kind: delivery.KindWebhook, Destination: "https://partner.example/hook", Status: models.DeliveryStatusDead, Attempts: 12},
+	}}
+	return NewDeliveryHandler(delivery.NewQueue(store, delivery.DefaultPolicy())), store
+}
+
+// deadLetterRequest builds a request with the {id} route parameter set
+func deadLetterRequest(method string, deliveryID string) *http.Request {
+	request := httptest.NewRequest(method, "/admin/deliveries/dead-letter/"+deliveryID, nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", deliveryID)
+	return request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeContext))
+}
+
+// TestDeliveryHandler_StatsAndDeadLetters verifies queue stats and the dead letter listing
+func TestDeliveryHandler_StatsAndDeadLetters(t *testing.T) {
+	handler, _ := newTestDeliveryHandler()
+
+	statsRecorder := httptest.NewRecorder()
+	handler.Stats(statsRecorder, httptest.NewRequest(http.MethodGet, "/admin/deliveries", nil))
+	var stats delivery.Stats
+	json.NewDecoder(statsRecorder.Body).Decode(&stats)
+	if statsRecorder.Code != http.StatusOK || stats.Dead != 1 {
+		t.Errorf("Unexpected stats: %d %+v", statsRecorder.Code, stats)
+	}
+
+	listRecorder := httptest.NewRecorder()
+	handler.DeadLetters(listRecorder, httptest.NewRequest(http.MethodGet, "/admin/deliveries/dead-letter?_count=10", nil))
+	var deadLetterResponse DeadLetterResponse
+	json.NewDecoder(listRecorder.Body).Decode(&deadLetterResponse)
+	if len(deadLetterResponse.Deliveries) != 1 || deadLetterResponse.Deliveries[0].Attempts != 12 {
+		t.Errorf("Unexpected dead letters: %+v", deadLetterResponse)
+	}
+
+	invalidRecorder := httptest.NewRecorder()
+	handler.DeadLetters(invalidRecorder, httptest.NewRequest(http.MethodGet, "/admin/deliveries/dead-letter?_count=0", nil))
+	if invalidRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid _count, got %d", invalidRecorder.Code)
+	}
+}
+
+// TestDeliveryHandler_RetryAndDiscard verifies dead letters can be requeued or deleted, and unknown IDs are 404
+func TestDeliveryHandler_RetryAndDiscard(t *testing.T) {
+	handler, store := newTestDeliveryHandler()
+
+	retryRecorder := httptest.NewRecorder()
+	handler.Retry(retryRecorder, deadLetterRequest(http.MethodPost, "1"))
+	if retryRecorder.Code != http.StatusNoContent || len(store.requeued) != 1 {
+		t.Errorf("Expected 204 and a requeue, got %d %v", retryRecorder.Code, store.requeued)
+	}
+
+	for _, deliveryID := range []string{"1", "abc"} {
+		discardRecorder := httptest.NewRecorder()
+		handler.Discard(discardRecorder, deadLetterRequest(http.MethodDelete, deliveryID))
+		if discardRecorder.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 discarding %s, got %d", deliveryID, discardRecorder.Code)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/deprecation"
+)
+
+// DeprecationUsageResponse represents the deprecated feature usage report
+type DeprecationUsageResponse struct {
+	Features []deprecation.FeatureUsage `json:"features"`
+}
+
+// DeprecationHandler exposes how often deprecated features are still used
+type DeprecationHandler struct {
+	registry *deprecation.Registry
+}
+
+// NewDeprecationHandler creates a new instance of DeprecationHandler
+func NewDeprecationHandler(registry *deprecation.Registry) *DeprecationHandler {
+	return &DeprecationHandler{
+		registry: registry,
+	}
+}
+
+// Usage handles GET /admin/deprecations - returns request counts per deprecated feature and tenant
+func (handler *DeprecationHandler) Usage(w http.ResponseWriter, r *http.Request) {
+	usageResponse := DeprecationUsageResponse{
+		Features: handler.registry.Usage(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(usageResponse)
+}
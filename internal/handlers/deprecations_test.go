@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/deprecation"
+)
+
+// TestDeprecationHandler_Usage verifies usage is reported for each deprecated feature
+func TestDeprecationHandler_Usage(t *testing.T) {
+	registry := deprecation.NewRegistry(deprecation.Config{Features: []deprecation.Feature{{
+		ID:           "patient-search-bare-array",
+		Route:        "/fhir/Patient",
+		DeprecatedAt: time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC),
+		Message:      "use searchset Bundles",
+	}}})
+	registry.Record("patient-search-bare-array", "acme")
+	handler := NewDeprecationHandler(registry)
+
+	recorder := httptest.NewRecorder()
+	handler.Usage(recorder, httptest.NewRequest(http.MethodGet, "/admin/deprecations", nil))
+
+	var usageResponse DeprecationUsageResponse
+	json.NewDecoder(recorder.Body).Decode(&usageResponse)
+	if recorder.Code != http.StatusOK || len(usageResponse.Features) != 1 || usageResponse.Features[0].Tenants["acme"] != 1 {
+		t.Errorf("Unexpected usage response: %d %+v", recorder.Code, usageResponse)
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// DiagnosticReportHandler handles DiagnosticReport FHIR resource requests
+type DiagnosticReportHandler struct {
+	diagnosticReportService *service.DiagnosticReportService
+	idCodec                 idcodec.Codec
+	residencyPolicy         *residency.Policy
+}
+
+// NewDiagnosticReportHandler creates a DiagnosticReportHandler backed by the diagnostic report service
+func NewDiagnosticReportHandler(diagnosticReportService *service.DiagnosticReportService) *DiagnosticReportHandler {
+	return &DiagnosticReportHandler{
+		diagnosticReportService: diagnosticReportService,
+	}
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *DiagnosticReportHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetResidencyPolicy refuses subject references to patients held in another region
+func (handler *DiagnosticReportHandler) SetResidencyPolicy(policy *residency.Policy) {
+	handler.residencyPolicy = policy
+}
+
+// exposeDiagnosticReport rewrites a diagnostic report's ID, subject reference, and result references to their exposed forms
+func exposeDiagnosticReport(ctx context.Context, codec idcodec.Codec, fhirDiagnosticReport *fhir.DiagnosticReport) {
+	exposeID(ctx, codec, "DiagnosticReport", fhirDiagnosticReport.Id)
+	if fhirDiagnosticReport.Subject != nil {
+		exposeReference(ctx, codec, fhirDiagnosticReport.Subject.Reference)
+	}
+	for index := range fhirDiagnosticReport.Result {
+		exposeReference(ctx, codec, fhirDiagnosticReport.Result[index].Reference)
+	}
+}
+
+// resolveReferences rewrites exposed subject and result references to the stored IDs, writing a 400 when one is
+// unknown and a 403 when the subject points to another region
+func (handler *DiagnosticReportHandler) resolveReferences(w http.ResponseWriter, r *http.Request, fhirDiagnosticReport *fhir.DiagnosticReport) bool {
+	if fhirDiagnosticReport.Subject != nil {
+		if handler.residencyPolicy != nil && fhirDiagnosticReport.Subject.Reference != nil {
+			if residencyError := handler.residencyPolicy.CheckReference(*fhirDiagnosticReport.Subject.Reference); residencyError != nil {
+				middleware.WriteError(w, r, residencyError)
+				return false
+			}
+		}
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirDiagnosticReport.Subject.Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("subject", "Unknown patient reference"))
+			return false
+		}
+	}
+	for index := range fhirDiagnosticReport.Result {
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirDiagnosticReport.Result[index].Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("result", "Unknown observation reference"))
+			return false
+		}
+	}
+	return true
+}
+
+// Create handles POST /fhir/DiagnosticReport - creates a new diagnostic report
+func (handler *DiagnosticReportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirDiagnosticReport fhir.DiagnosticReport
+	if decodeError := encoding.Decode(r, &fhirDiagnosticReport); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR DiagnosticReport JSON"))
+		return
+	}
+
+	// Translate exposed patient and observation references back to the stored IDs
+	if !handler.resolveReferences(w, r, &fhirDiagnosticReport) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdDiagnosticReport, createError := handler.diagnosticReportService.CreateDiagnosticReport(issueContext, &fhirDiagnosticReport)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create diagnostic report")
+		return
+	}
+	exposeDiagnosticReport(r.Context(), handler.idCodec, createdDiagnosticReport)
+
+	writeWriteResult(w, r, http.StatusCreated, createdDiagnosticReport, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/DiagnosticReport/{id} - retrieves a diagnostic report by ID
+func (handler *DiagnosticReportHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	diagnosticReportID := chi.URLParam(r, "id")
+	internalDiagnosticReportID, resolved := resolveID(w, r, handler.idCodec, "DiagnosticReport", diagnosticReportID)
+	if !resolved {
+		return
+	}
+
+	fhirDiagnosticReport, getError := handler.diagnosticReportService.GetDiagnosticReportByID(r.Context(), internalDiagnosticReportID)
+	if errors.Is(getError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("DiagnosticReport", diagnosticReportID))
+		return
+	}
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("DiagnosticReport", diagnosticReportID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read diagnostic report", getError))
+		return
+	}
+	exposeDiagnosticReport(r.Context(), handler.idCodec, fhirDiagnosticReport)
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("DiagnosticReport", fhirDiagnosticReport, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/DiagnosticReport - searches diagnostic reports by patient, category, code, or date
+func (handler *DiagnosticReportHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseDiagnosticReportSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	if searchParams.PatientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", searchParams.PatientID)
+		if !resolved {
+			return
+		}
+		searchParams.PatientID = internalPatientID
+	}
+
+	fhirDiagnosticReports, searchError := handler.diagnosticReportService.SearchDiagnosticReports(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search diagnostic reports", searchError))
+		return
+	}
+	total, countError := handler.diagnosticReportService.CountDiagnosticReports(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count diagnostic reports", countError))
+		return
+	}
+	for _, fhirDiagnosticReport := range fhirDiagnosticReports {
+		exposeDiagnosticReport(r.Context(), handler.idCodec, fhirDiagnosticReport)
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "DiagnosticReport", fhirDiagnosticReports, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/DiagnosticReport/{id} - updates an existing diagnostic report
+func (handler *DiagnosticReportHandler) Update(w http.ResponseWriter, r *http.Request) {
+	diagnosticReportID := chi.URLParam(r, "id")
+
+	var fhirDiagnosticReport fhir.DiagnosticReport
+	if decodeError := encoding.Decode(r, &fhirDiagnosticReport); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR DiagnosticReport JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirDiagnosticReport.Id != nil && *fhirDiagnosticReport.Id != diagnosticReportID {
+		middleware.WriteError(w, r, apperrors.ValidationError("DiagnosticReport ID in URL does not match ID in body"))
+		return
+	}
+
+	internalDiagnosticReportID, resolved := resolveID(w, r, handler.idCodec, "DiagnosticReport", diagnosticReportID)
+	if !resolved {
+		return
+	}
+	if fhirDiagnosticReport.Id != nil {
+		fhirDiagnosticReport.Id = &internalDiagnosticReportID
+	}
+	if !handler.resolveReferences(w, r, &fhirDiagnosticReport) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedDiagnosticReport, updateError := handler.diagnosticReportService.UpdateDiagnosticReport(issueContext, internalDiagnosticReportID, &fhirDiagnosticReport)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("DiagnosticReport", diagnosticReportID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("DiagnosticReport", diagnosticReportID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update diagnostic report")
+		return
+	}
+	exposeDiagnosticReport(r.Context(), handler.idCodec, updatedDiagnosticReport)
+
+	writeWriteResult(w, r, http.StatusOK, updatedDiagnosticReport, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/DiagnosticReport/{id} - deletes a diagnostic report
+func (handler *DiagnosticReportHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	diagnosticReportID := chi.URLParam(r, "id")
+	internalDiagnosticReportID, resolved := resolveID(w, r, handler.idCodec, "DiagnosticReport", diagnosticReportID)
+	if !resolved {
+		return
+	}
+
+	if deleteError := handler.diagnosticReportService.DeleteDiagnosticReport(r.Context(), internalDiagnosticReportID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "DiagnosticReport", diagnosticReportID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockDiagnosticReportRepository implements DiagnosticReportRepository interface for testing
+type MockDiagnosticReportRepository struct {
+	diagnosticReports map[string]*models.DiagnosticReport
+	deleted           map[string]bool
+	lastSearch        *models.DiagnosticReportSearchParams
+}
+
+// NewMockDiagnosticReportRepository creates a new mock repository for testing
+func NewMockDiagnosticReportRepository() *MockDiagnosticReportRepository {
+	return &MockDiagnosticReportRepository{diagnosticReports: make(map[string]*models.DiagnosticReport), deleted: make(map[string]bool)}
+}
+
+// Create stores a diagnostic report under a generated ObjectID
+func (mock *MockDiagnosticReportRepository) Create(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error) {
+	diagnosticReport.ID = primitive.NewObjectID().Hex()
+	mock.diagnosticReports[diagnosticReport.ID] = diagnosticReport
+	return diagnosticReport, nil
+}
+
+// GetByID retrieves a stored diagnostic report
+func (mock *MockDiagnosticReportRepository) GetByID(ctx context.Context, diagnosticReportID string) (*models.DiagnosticReport, error) {
+	if mock.deleted[diagnosticReportID] {
+		return nil, deletedError(repository.ErrDiagnosticReportNotFound)
+	}
+	diagnosticReport, exists := mock.diagnosticReports[diagnosticReportID]
+	if !exists {
+		return nil, repository.ErrDiagnosticReportNotFound
+	}
+	return diagnosticReport, nil
+}
+
+// Search returns every stored diagnostic report and remembers the criteria
+func (mock *MockDiagnosticReportRepository) Search(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) ([]*models.DiagnosticReport, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.DiagnosticReport, 0, len(mock.diagnosticReports))
+	for _, diagnosticReport := range mock.diagnosticReports {
+		result = append(result, diagnosticReport)
+	}
+	return result, nil
+}
+
+// Count returns how many diagnostic reports are stored, as every search matches them all
+func (mock *MockDiagnosticReportRepository) Count(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) (int, error) {
+	return len(mock.diagnosticReports), nil
+}
+
+// Update replaces a stored diagnostic report
+func (mock *MockDiagnosticReportRepository) Update(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error) {
+	if mock.deleted[diagnosticReport.ID] {
+		return nil, deletedError(repository.ErrDiagnosticReportNotFound)
+	}
+	if _, exists := mock.diagnosticReports[diagnosticReport.ID]; !exists {
+		return nil, repository.ErrDiagnosticReportNotFound
+	}
+	mock.diagnosticReports[diagnosticReport.ID] = diagnosticReport
+	return diagnosticReport, nil
+}
+
+// Delete marks a stored diagnostic report deleted, as the repository does
+func (mock *MockDiagnosticReportRepository) Delete(ctx context.Context, diagnosticReportID string) error {
+	if mock.deleted[diagnosticReportID] {
+		return deletedError(repository.ErrDiagnosticReportNotFound)
+	}
+	if _, exists := mock.diagnosticReports[diagnosticReportID]; !exists {
+		return repository.ErrDiagnosticReportNotFound
+	}
+	delete(mock.diagnosticReports, diagnosticReportID)
+	mock.deleted[diagnosticReportID] = true
+	return nil
+}
+
+// newDiagnosticReportRouter registers the DiagnosticReport routes as cmd/server does
+func newDiagnosticReportRouter(handler *DiagnosticReportHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/DiagnosticReport", handler.Create)
+	router.Get("/fhir/DiagnosticReport/{id}", handler.GetByID)
+	router.Get("/fhir/DiagnosticReport", handler.GetAll)
+	router.Put("/fhir/DiagnosticReport/{id}", handler.Update)
+	router.Delete("/fhir/DiagnosticReport/{id}", handler.Delete)
+	return router
+}
+
+// TestDiagnosticReportRoutes verifies create, read, search, update, and delete, that deleted diagnostic reports are 410, and that unknown ones are 404
+func TestDiagnosticReportRoutes(t *testing.T) {
+	diagnosticReportRepository := NewMockDiagnosticReportRepository()
+	observationService := NewMockObservationService()
+	observationService.observations["obs-1"] = &fhir.Observation{}
+	router := newDiagnosticReportRouter(NewDiagnosticReportHandler(service.NewDiagnosticReportService(diagnosticReportRepository, observationService)))
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/DiagnosticReport", `{"resourceType":"DiagnosticReport","status":"final",`+
+		`"category":[{"coding":[{"system":"http://terminology.hl7.org/CodeSystem/v2-0074","code":"LAB"}]}],`+
+		`"code":{"coding":[{"system":"http://loinc.org","code":"58410-2","display":"CBC panel"}]},`+
+		`"subject":{"reference":"Patient/patient-1"},"effectiveDateTime":"2024-03-05","result":[{"reference":"Observation/obs-1"}]}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the diagnostic report, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdDiagnosticReport fhir.DiagnosticReport
+	json.NewDecoder(createRecorder.Body).Decode(&createdDiagnosticReport)
+	diagnosticReportID := *createdDiagnosticReport.Id
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/DiagnosticReport/"+diagnosticReportID, "")
+	var readDiagnosticReport fhir.DiagnosticReport
+	json.NewDecoder(readRecorder.Body).Decode(&readDiagnosticReport)
+	if readRecorder.Code != http.StatusOK || *readDiagnosticReport.Subject.Reference != "Patient/patient-1" || len(readDiagnosticReport.Result) != 1 || *readDiagnosticReport.Result[0].Reference != "Observation/obs-1" {
+		t.Errorf("Expected the patient's report with its result, got %d: %s", readRecorder.Code, readRecorder.Body.String())
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/DiagnosticReport?patient=Patient/patient-1&category=LAB&code=http://loinc.org|58410-2&date=ge2024-01-01", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 1 || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one diagnostic report, got %s", searchRecorder.Body.String())
+	}
+	lastSearch := diagnosticReportRepository.lastSearch
+	if lastSearch.PatientID != "patient-1" || lastSearch.Category != "LAB" || lastSearch.Code == nil || lastSearch.DateGreaterThan == nil {
+		t.Errorf("Expected the patient, category, code, and date passed to the repository, got %+v", lastSearch)
+	}
+
+	unknownResultRecorder := serveCRUD(router, http.MethodPut, "/fhir/DiagnosticReport/"+diagnosticReportID, `{"resourceType":"DiagnosticReport","status":"amended",`+
+		`"subject":{"reference":"Patient/patient-1"},"result":[{"reference":"Observation/obs-missing"}]}`)
+	if unknownResultRecorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for a result naming a missing observation, got %d: %s", unknownResultRecorder.Code, unknownResultRecorder.Body.String())
+	}
+
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/DiagnosticReport/"+diagnosticReportID, `{"resourceType":"DiagnosticReport","id":"`+diagnosticReportID+`",`+
+		`"status":"amended","subject":{"reference":"Patient/patient-1"},"result":[{"reference":"Observation/obs-1"}]}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the diagnostic report, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+	if mismatchRecorder := serveCRUD(router, http.MethodPut, "/fhir/DiagnosticReport/"+diagnosticReportID, `{"resourceType":"DiagnosticReport","id":"other","status":"final"}`); mismatchRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body ID that differs from the URL, got %d", mismatchRecorder.Code)
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/DiagnosticReport/"+diagnosticReportID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the diagnostic report, got %d", deleteRecorder.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveCRUD(router, method, "/fhir/DiagnosticReport/"+diagnosticReportID, ""); recorder.Code != http.StatusGone {
+			t.Errorf("Expected status 410 for %s of a deleted diagnostic report, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/DiagnosticReport/"+primitive.NewObjectID().Hex(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 reading an unknown diagnostic report, got %d", recorder.Code)
+	}
+}
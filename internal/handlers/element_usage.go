@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/metrics"
+)
+
+// ElementUsageResponse represents the element usage report
+type ElementUsageResponse struct {
+	Resources []metrics.ResourceElementReport `json:"resources"`
+}
+
+// ElementUsageHandler exposes which resource elements clients request and receive
+type ElementUsageHandler struct {
+	elementRecorder *metrics.ElementRecorder
+}
+
+// NewElementUsageHandler creates a new instance of ElementUsageHandler
+func NewElementUsageHandler(elementRecorder *metrics.ElementRecorder) *ElementUsageHandler {
+	return &ElementUsageHandler{
+		elementRecorder: elementRecorder,
+	}
+}
+
+// Report handles GET /admin/element-usage - returns _elements usage and sampled response contents per resource type
+func (handler *ElementUsageHandler) Report(w http.ResponseWriter, r *http.Request) {
+	usageResponse := ElementUsageResponse{
+		Resources: handler.elementRecorder.Report(),
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(usageResponse)
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/metrics"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestElementUsageHandler_Report verifies the element usage report is returned as JSON
+func TestElementUsageHandler_Report(t *testing.T) {
+	recorder := metrics.NewElementRecorder(map[string][]string{"Patient": {"id", "name"}}, metrics.ElementConfig{})
+	recorder.RecordRequest("Patient", []string{"name"})
+	handler := NewElementUsageHandler(recorder)
+
+	responseRecorder := httptest.NewRecorder()
+	handler.Report(responseRecorder, httptest.NewRequest(http.MethodGet, "/admin/element-usage", nil))
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", responseRecorder.Code)
+	}
+
+	var usageResponse ElementUsageResponse
+	if decodeError := json.NewDecoder(responseRecorder.Body).Decode(&usageResponse); decodeError != nil {
+		t.Fatalf("Failed to decode response: %v", decodeError)
+	}
+	if len(usageResponse.Resources) != 1 || usageResponse.Resources[0].ElementsRequests != 1 {
+		t.Fatalf("Expected one Patient report with one _elements request, got %+v", usageResponse.Resources)
+	}
+	if len(usageResponse.Resources[0].SummaryCandidates) != 1 || usageResponse.Resources[0].SummaryCandidates[0] != "name" {
+		t.Errorf("Expected name as a summary candidate, got %v", usageResponse.Resources[0].SummaryCandidates)
+	}
+}
+
+// TestElementRecorder_PatientSearch verifies a real search response is trimmed by _elements and sampled
+func TestElementRecorder_PatientSearch(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["p1"] = &models.Patient{ID: "p1", FamilyName: "Smith", Gender: "female"}
+	patientHandler := NewPatientHandlerWithService(service.NewPatientService(patientRepository))
+	recorder := metrics.NewElementRecorder(map[string][]string{"Patient": metrics.ResourceElements(fhir.Patient{})},
+		metrics.ElementConfig{SampleRate: 1, ConsentTenants: []string{"acme"}})
+	instrumented := recorder.Instrument("Patient", patientHandler.GetAll)
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?_elements=name", nil)
+	request = request.WithContext(tenant.WithVerifiedTenant(request.Context(), "acme"))
+	responseRecorder := httptest.NewRecorder()
+	instrumented(responseRecorder, request)
+
+	responseBody := responseRecorder.Body.String()
+	if responseRecorder.Code != http.StatusOK || strings.Contains(responseBody, "gender") || !strings.Contains(responseBody, "SUBSETTED") {
+		t.Fatalf("Expected a subsetted search response without gender, got %d: %s", responseRecorder.Code, responseBody)
+	}
+
+	report := recorder.Report()[0]
+	if report.SampledResponses != 1 || report.SampledResources != 1 {
+		t.Fatalf("Expected the search response sampled, got %+v", report)
+	}
+	presentElements := map[string]int64{}
+	for _, usage := range report.Elements {
+		presentElements[usage.Element] = usage.PresentCount
+	}
+	if presentElements["name"] != 1 || presentElements["gender"] != 0 {
+		t.Errorf("Expected name sent and gender trimmed, got %v", presentElements)
+	}
+}
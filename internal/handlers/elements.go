@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+)
+
+// subsettedTagSystem and subsettedTagCode mark a resource trimmed by _elements, as FHIR requires
+const (
+	subsettedTagSystem = "http://terminology.hl7.org/CodeSystem/v3-ObservationValue"
+	subsettedTagCode   = "SUBSETTED"
+)
+
+// alwaysIncludedElements are returned whatever _elements lists
+var alwaysIncludedElements = []string{"resourceType", "id", "meta"}
+
+// mandatoryElements are the required elements of each resource type, also returned whatever _elements lists
+var mandatoryElements = map[string][]string{
+	"Observation": {"status", "code"},
+}
+
+// subsetElements trims a resource to the elements named by _elements, plus its mandatory elements
+// It returns the resource unchanged when elements is nil, that is when the request has no _elements
+// Unknown element names are ignored rather than rejected
+func subsetElements(resourceType string, resource interface{}, elements []string) interface{} {
+	if elements == nil {
+		return resource
+	}
+
+	encodedResource, encodeError := json.Marshal(resource)
+	if encodeError != nil {
+		return resource
+	}
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(encodedResource, &fields) != nil {
+		return resource
+	}
+
+	keptElements := make(map[string]bool)
+	for _, elementGroup := range [][]string{elements, alwaysIncludedElements, mandatoryElements[resourceType]} {
+		for _, element := range elementGroup {
+			keptElements[element] = true
+		}
+	}
+	for element := range fields {
+		if !keptElements[element] {
+			delete(fields, element)
+		}
+	}
+
+	fields["meta"] = addSubsettedTag(fields["meta"])
+	return fields
+}
+
+// addSubsettedTag appends the SUBSETTED tag to an encoded meta element, creating it when absent
+func addSubsettedTag(encodedMeta json.RawMessage) json.RawMessage {
+	meta := map[string]json.RawMessage{}
+	if len(encodedMeta) > 0 {
+		json.Unmarshal(encodedMeta, &meta)
+	}
+
+	var tags []json.RawMessage
+	if encodedTags, hasTags := meta["tag"]; hasTags {
+		json.Unmarshal(encodedTags, &tags)
+	}
+	subsettedTag, _ := json.Marshal(map[string]string{"system": subsettedTagSystem, "code": subsettedTagCode})
+	tags = append(tags, subsettedTag)
+
+	meta["tag"], _ = json.Marshal(tags)
+	updatedMeta, _ := json.Marshal(meta)
+	return updatedMeta
+}
+
+// subsetResources applies subsetElements to each resource of a search result
+func subsetResources[Resource any](resourceType string, resources []Resource, elements []string) []interface{} {
+	subsetted := make([]interface{}, 0, len(resources))
+	for _, resource := range resources {
+		subsetted = append(subsetted, subsetElements(resourceType, resource, elements))
+	}
+	return subsetted
+}
@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestSubsetElements verifies requested and mandatory elements are kept and the result is tagged SUBSETTED
+func TestSubsetElements(t *testing.T) {
+	observationID := "obs-1"
+	tagCode := "reviewed"
+	codeText := "Heart rate"
+	issued := "2024-05-01T12:00:00Z"
+	observation := &fhir.Observation{
+		Id:     &observationID,
+		Status: fhir.ObservationStatusFinal,
+		Code:   fhir.CodeableConcept{Text: &codeText},
+		Issued: &issued,
+		Meta:   &fhir.Meta{Tag: []fhir.Coding{{Code: &tagCode}}},
+	}
+
+	if unchanged := subsetElements("Observation", observation, nil); unchanged != observation {
+		t.Errorf("Expected the resource unchanged without _elements")
+	}
+
+	encoded, _ := json.Marshal(subsetElements("Observation", observation, []string{"subject", "unknownElement"}))
+	var subsetted fhir.Observation
+	json.Unmarshal(encoded, &subsetted)
+
+	if subsetted.Id == nil || subsetted.Status != fhir.ObservationStatusFinal || subsetted.Code.Text == nil || subsetted.Issued != nil {
+		t.Errorf("Expected id, status, and code kept and issued dropped, got %s", encoded)
+	}
+	if len(subsetted.Meta.Tag) != 2 || *subsetted.Meta.Tag[0].Code != "reviewed" || *subsetted.Meta.Tag[1].Code != subsettedTagCode {
+		t.Errorf("Expected the existing tag followed by SUBSETTED, got %s", encoded)
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// EncounterHandler handles Encounter FHIR resource requests
+type EncounterHandler struct {
+	encounterService *service.EncounterService
+	idCodec          idcodec.Codec
+	residencyPolicy  *residency.Policy
+}
+
+// NewEncounterHandler creates an EncounterHandler backed by the encounter service
+func NewEncounterHandler(encounterService *service.EncounterService) *EncounterHandler {
+	return &EncounterHandler{
+		encounterService: encounterService,
+	}
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *EncounterHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetResidencyPolicy refuses subject references to patients held in another region
+func (handler *EncounterHandler) SetResidencyPolicy(policy *residency.Policy) {
+	handler.residencyPolicy = policy
+}
+
+// exposeEncounter rewrites an encounter's ID and its subject and participant references to their exposed forms
+func exposeEncounter(ctx context.Context, codec idcodec.Codec, fhirEncounter *fhir.Encounter) {
+	exposeID(ctx, codec, "Encounter", fhirEncounter.Id)
+	if fhirEncounter.Subject != nil {
+		exposeReference(ctx, codec, fhirEncounter.Subject.Reference)
+	}
+	for index := range fhirEncounter.Participant {
+		if fhirEncounter.Participant[index].Individual != nil {
+			exposeReference(ctx, codec, fhirEncounter.Participant[index].Individual.Reference)
+		}
+	}
+}
+
+// resolveReferences rewrites exposed subject and participant references to the stored IDs, writing a 400 when
+// one is unknown and a 403 when the subject points to another region
+func (handler *EncounterHandler) resolveReferences(w http.ResponseWriter, r *http.Request, fhirEncounter *fhir.Encounter) bool {
+	if fhirEncounter.Subject != nil {
+		if handler.residencyPolicy != nil && fhirEncounter.Subject.Reference != nil {
+			if residencyError := handler.residencyPolicy.CheckReference(*fhirEncounter.Subject.Reference); residencyError != nil {
+				middleware.WriteError(w, r, residencyError)
+				return false
+			}
+		}
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirEncounter.Subject.Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("subject", "Unknown patient reference"))
+			return false
+		}
+	}
+	for index := range fhirEncounter.Participant {
+		if fhirEncounter.Participant[index].Individual == nil {
+			continue
+		}
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirEncounter.Participant[index].Individual.Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("participant", "Unknown practitioner reference"))
+			return false
+		}
+	}
+	return true
+}
+
+// Create handles POST /fhir/Encounter - creates a new encounter
+func (handler *EncounterHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirEncounter fhir.Encounter
+	if decodeError := encoding.Decode(r, &fhirEncounter); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Encounter JSON"))
+		return
+	}
+
+	// Translate exposed patient and practitioner references back to the stored IDs
+	if !handler.resolveReferences(w, r, &fhirEncounter) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdEncounter, createError := handler.encounterService.CreateEncounter(issueContext, &fhirEncounter)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create encounter")
+		return
+	}
+	exposeEncounter(r.Context(), handler.idCodec, createdEncounter)
+
+	writeWriteResult(w, r, http.StatusCreated, createdEncounter, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/Encounter/{id} - retrieves an encounter by ID
+func (handler *EncounterHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	encounterID := chi.URLParam(r, "id")
+	internalEncounterID, resolved := resolveID(w, r, handler.idCodec, "Encounter", encounterID)
+	if !resolved {
+		return
+	}
+
+	fhirEncounter, getError := handler.encounterService.GetEncounterByID(r.Context(), internalEncounterID)
+	if errors.Is(getError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Encounter", encounterID))
+		return
+	}
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Encounter", encounterID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read encounter", getError))
+		return
+	}
+	exposeEncounter(r.Context(), handler.idCodec, fhirEncounter)
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("Encounter", fhirEncounter, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/Encounter - searches encounters by patient, status, or date
+func (handler *EncounterHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseEncounterSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	if searchParams.PatientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", searchParams.PatientID)
+		if !resolved {
+			return
+		}
+		searchParams.PatientID = internalPatientID
+	}
+
+	fhirEncounters, searchError := handler.encounterService.SearchEncounters(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search encounters", searchError))
+		return
+	}
+	total, countError := handler.encounterService.CountEncounters(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count encounters", countError))
+		return
+	}
+	for _, fhirEncounter := range fhirEncounters {
+		exposeEncounter(r.Context(), handler.idCodec, fhirEncounter)
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "Encounter", fhirEncounters, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/Encounter/{id} - updates an existing encounter
+func (handler *EncounterHandler) Update(w http.ResponseWriter, r *http.Request) {
+	encounterID := chi.URLParam(r, "id")
+
+	var fhirEncounter fhir.Encounter
+	if decodeError := encoding.Decode(r, &fhirEncounter); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Encounter JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirEncounter.Id != nil && *fhirEncounter.Id != encounterID {
+		middleware.WriteError(w, r, apperrors.ValidationError("Encounter ID in URL does not match ID in body"))
+		return
+	}
+
+	internalEncounterID, resolved := resolveID(w, r, handler.idCodec, "Encounter", encounterID)
+	if !resolved {
+		return
+	}
+	if fhirEncounter.Id != nil {
+		fhirEncounter.Id = &internalEncounterID
+	}
+	if !handler.resolveReferences(w, r, &fhirEncounter) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedEncounter, updateError := handler.encounterService.UpdateEncounter(issueContext, internalEncounterID, &fhirEncounter)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Encounter", encounterID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Encounter", encounterID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update encounter")
+		return
+	}
+	exposeEncounter(r.Context(), handler.idCodec, updatedEncounter)
+
+	writeWriteResult(w, r, http.StatusOK, updatedEncounter, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/Encounter/{id} - deletes an encounter
+func (handler *EncounterHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	encounterID := chi.URLParam(r, "id")
+	internalEncounterID, resolved := resolveID(w, r, handler.idCodec, "Encounter", encounterID)
+	if !resolved {
+		return
+	}
+
+	if deleteError := handler.encounterService.DeleteEncounter(r.Context(), internalEncounterID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "Encounter", encounterID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockEncounterRepository implements EncounterRepository interface for testing
+type MockEncounterRepository struct {
+	encounters map[string]*models.Encounter
+	deleted    map[string]bool
+	lastSearch *models.EncounterSearchParams
+}
+
+// NewMockEncounterRepository creates a new mock repository for testing
+func NewMockEncounterRepository() *MockEncounterRepository {
+	return &MockEncounterRepository{encounters: make(map[string]*models.Encounter), deleted: make(map[string]bool)}
+}
+
+// Create stores an encounter under a generated UUID
+func (mock *MockEncounterRepository) Create(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error) {
+	encounter.ID = uuid.NewString()
+	mock.encounters[encounter.ID] = encounter
+	return encounter, nil
+}
+
+// GetByID retrieves a stored encounter
+func (mock *MockEncounterRepository) GetByID(ctx context.Context, encounterID string) (*models.Encounter, error) {
+	if mock.deleted[encounterID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	encounter, exists := mock.encounters[encounterID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return encounter, nil
+}
+
+// Search returns every stored encounter and remembers the criteria
+func (mock *MockEncounterRepository) Search(ctx context.Context, searchParams *models.EncounterSearchParams) ([]*models.Encounter, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.Encounter, 0, len(mock.encounters))
+	for _, encounter := range mock.encounters {
+		result = append(result, encounter)
+	}
+	return result, nil
+}
+
+// Count returns how many encounters are stored, as every search matches them all
+func (mock *MockEncounterRepository) Count(ctx context.Context, searchParams *models.EncounterSearchParams) (int, error) {
+	return len(mock.encounters), nil
+}
+
+// Update replaces a stored encounter
+func (mock *MockEncounterRepository) Update(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error) {
+	if mock.deleted[encounter.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.encounters[encounter.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.encounters[encounter.ID] = encounter
+	return encounter, nil
+}
+
+// Delete marks a stored encounter deleted, as the repository does
+func (mock *MockEncounterRepository) Delete(ctx context.Context, encounterID string) error {
+	if mock.deleted[encounterID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.encounters[encounterID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.encounters, encounterID)
+	mock.deleted[encounterID] = true
+	return nil
+}
+
+// newEncounterRouter registers the Encounter routes as cmd/server does
+func newEncounterRouter(handler *EncounterHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/Encounter", handler.Create)
+	router.Get("/fhir/Encounter/{id}", handler.GetByID)
+	router.Get("/fhir/Encounter", handler.GetAll)
+	router.Put("/fhir/Encounter/{id}", handler.Update)
+	router.Delete("/fhir/Encounter/{id}", handler.Delete)
+	return router
+}
+
+// TestEncounterRoutes verifies create, read, search, update, and delete, that deleted encounters are 410, and that unknown ones are 404
+func TestEncounterRoutes(t *testing.T) {
+	encounterRepository := NewMockEncounterRepository()
+	router := newEncounterRouter(NewEncounterHandler(service.NewEncounterService(encounterRepository)))
+	patientID := uuid.NewString()
+	practitionerID := uuid.NewString()
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/Encounter", `{"resourceType":"Encounter","status":"in-progress",`+
+		`"class":{"system":"http://terminology.hl7.org/CodeSystem/v3-ActCode","code":"AMB"},"subject":{"reference":"Patient/`+patientID+`"},`+
+		`"participant":[{"individual":{"reference":"Practitioner/`+practitionerID+`"}}],"period":{"start":"2024-03-01T09:30:00Z"}}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the encounter, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdEncounter fhir.Encounter
+	json.NewDecoder(createRecorder.Body).Decode(&createdEncounter)
+	encounterID := *createdEncounter.Id
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/Encounter/"+encounterID, "")
+	var readEncounter fhir.Encounter
+	json.NewDecoder(readRecorder.Body).Decode(&readEncounter)
+	if readRecorder.Code != http.StatusOK || readEncounter.Status != fhir.EncounterStatusInProgress || *readEncounter.Subject.Reference != "Patient/"+patientID {
+		t.Errorf("Expected the in-progress encounter for the patient, got %d: %+v", readRecorder.Code, readEncounter)
+	}
+	if len(readEncounter.Participant) != 1 || *readEncounter.Participant[0].Individual.Reference != "Practitioner/"+practitionerID {
+		t.Errorf("Expected the practitioner participant, got %+v", readEncounter.Participant)
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/Encounter?patient=Patient/"+patientID+"&status=in-progress&_count=5", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 1 || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one encounter, got %s", searchRecorder.Body.String())
+	}
+	if encounterRepository.lastSearch.PatientID != patientID || encounterRepository.lastSearch.Status != "in-progress" || encounterRepository.lastSearch.Limit != 5 {
+		t.Errorf("Expected the patient, status, and page size passed to the repository, got %+v", encounterRepository.lastSearch)
+	}
+
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/Encounter/"+encounterID, `{"resourceType":"Encounter","id":"`+encounterID+`","status":"finished","class":{"code":"AMB"}}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the encounter, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+	if mismatchRecorder := serveCRUD(router, http.MethodPut, "/fhir/Encounter/"+encounterID, `{"resourceType":"Encounter","id":"other","status":"finished","class":{"code":"AMB"}}`); mismatchRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body ID that differs from the URL, got %d", mismatchRecorder.Code)
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/Encounter/"+encounterID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the encounter, got %d", deleteRecorder.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveCRUD(router, method, "/fhir/Encounter/"+encounterID, ""); recorder.Code != http.StatusGone {
+			t.Errorf("Expected status 410 for %s of a deleted encounter, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/Encounter/"+uuid.NewString(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 reading an unknown encounter, got %d", recorder.Code)
+	}
+}
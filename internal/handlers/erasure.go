@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// ErasureRequest is the body for requesting a patient's erasure
+type ErasureRequest struct {
+	// Reason is required and recorded on the deletion certificate (for example the data subject request reference)
+	Reason string `json:"reason"`
+}
+
+// ErasureHandler serves the compliance endpoints for patient right-to-erasure requests
+type ErasureHandler struct {
+	erasureService *service.ErasureService
+}
+
+// NewErasureHandler creates a new instance of ErasureHandler
+func NewErasureHandler(erasureService *service.ErasureService) *ErasureHandler {
+	return &ErasureHandler{
+		erasureService: erasureService,
+	}
+}
+
+// Request handles POST /admin/patients/{id}/erasure - queues erasure of the patient (compliance role only)
+func (handler *ErasureHandler) Request(w http.ResponseWriter, r *http.Request) {
+	var erasureRequest ErasureRequest
+	if decodeError := json.NewDecoder(r.Body).Decode(&erasureRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid erasure request JSON"))
+		return
+	}
+
+	erasure, requestError := handler.erasureService.Request(r.Context(), chi.URLParam(r, "id"), erasureRequest.Reason)
+	if requestError != nil {
+		middleware.WriteError(w, r, requestError)
+		return
+	}
+
+	w.Header().Set("Location", "/admin/erasures/"+strconv.FormatInt(erasure.ID, 10))
+	writeErasure(w, http.StatusAccepted, erasure)
+}
+
+// Status handles GET /admin/erasures/{id} - reports the erasure's progress (compliance role only)
+func (handler *ErasureHandler) Status(w http.ResponseWriter, r *http.Request) {
+	erasureID, parsed := parseErasureID(w, r)
+	if !parsed {
+		return
+	}
+
+	erasure, getError := handler.erasureService.Get(r.Context(), erasureID)
+	if getError != nil {
+		middleware.WriteError(w, r, getError)
+		return
+	}
+
+	writeErasure(w, http.StatusOK, erasure)
+}
+
+// Cancel handles POST /admin/erasures/{id}/cancel - stops the erasure and restores the patient's records (compliance role only)
+func (handler *ErasureHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	erasureID, parsed := parseErasureID(w, r)
+	if !parsed {
+		return
+	}
+
+	erasure, cancelError := handler.erasureService.Cancel(r.Context(), erasureID)
+	if cancelError != nil {
+		middleware.WriteError(w, r, cancelError)
+		return
+	}
+
+	writeErasure(w, http.StatusAccepted, erasure)
+}
+
+// Certificate handles GET /admin/erasures/{id}/certificate - returns the deletion certificate once the records are purged
+func (handler *ErasureHandler) Certificate(w http.ResponseWriter, r *http.Request) {
+	erasureID, parsed := parseErasureID(w, r)
+	if !parsed {
+		return
+	}
+
+	certificate, getError := handler.erasureService.Certificate(r.Context(), erasureID)
+	if getError != nil {
+		middleware.WriteError(w, r, getError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(certificate)
+}
+
+// parseErasureID reads the erasure ID from the URL, responding 404 when it is not a number
+func parseErasureID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	rawErasureID := chi.URLParam(r, "id")
+	erasureID, parseError := strconv.ParseInt(rawErasureID, 10, 64)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound("PatientErasure", rawErasureID))
+		return 0, false
+	}
+	return erasureID, true
+}
+
+// writeErasure responds with an erasure as JSON
+func writeErasure(w http.ResponseWriter, status int, erasure *models.PatientErasure) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(erasure)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// StubErasureRepository implements ErasureRepository with at most one erasure
+type StubErasureRepository struct {
+	erasure *models.PatientErasure
+}
+
+// Create stores the erasure as pending
+func (stub *StubErasureRepository) Create(ctx context.Context, erasure *models.PatientErasure) (*models.PatientErasure, error) {
+	if stub.erasure != nil {
+		return nil, sql.ErrNoRows
+	}
+	erasure.ID = 1
+	erasure.Status = models.ErasureStatusPending
+	stub.erasure = erasure
+	return erasure, nil
+}
+
+// Get returns the stored erasure
+func (stub *StubErasureRepository) Get(ctx context.Context, erasureID int64) (*models.PatientErasure, error) {
+	if stub.erasure == nil || erasureID != stub.erasure.ID {
+		return nil, sql.ErrNoRows
+	}
+	return stub.erasure, nil
+}
+
+// NextDue reports nothing due
+func (stub *StubErasureRepository) NextDue(ctx context.Context, now time.Time) (*models.PatientErasure, error) {
+	return nil, sql.ErrNoRows
+}
+
+// StartStep does nothing
+func (stub *StubErasureRepository) StartStep(ctx context.Context, erasureID int64, stepsStarted int) error {
+	return nil
+}
+
+// RecordHeld does nothing
+func (stub *StubErasureRepository) RecordHeld(ctx context.Context, erasureID int64, store string, count int64) error {
+	return nil
+}
+
+// MarkHeld does nothing
+func (stub *StubErasureRepository) MarkHeld(ctx context.Context, erasureID int64, purgeAfter time.Time) error {
+	return nil
+}
+
+// StartCompensation does nothing
+func (stub *StubErasureRepository) StartCompensation(ctx context.Context, erasureID int64, failure string) error {
+	return nil
+}
+
+// Cancel switches the stored erasure to compensating
+func (stub *StubErasureRepository) Cancel(ctx context.Context, erasureID int64, actor string) error {
+	stub.erasure.Status = models.ErasureStatusCompensating
+	stub.erasure.CancelledBy = actor
+	return nil
+}
+
+// RecordRestored does nothing
+func (stub *StubErasureRepository) RecordRestored(ctx context.Context, erasureID int64, stepsStarted int) error {
+	return nil
+}
+
+// FinishCompensation does nothing
+func (stub *StubErasureRepository) FinishCompensation(ctx context.Context, erasureID int64) error {
+	return nil
+}
+
+// StartPurge does nothing
+func (stub *StubErasureRepository) StartPurge(ctx context.Context, erasureID int64) error {
+	return nil
+}
+
+// MarkPurged does nothing
+func (stub *StubErasureRepository) MarkPurged(ctx context.Context, erasureID int64, purgedCounts map[string]int64, certificate *models.DeletionCertificate) error {
+	return nil
+}
+
+// GetCertificate reports no certificate
+func (stub *StubErasureRepository) GetCertificate(ctx context.Context, erasureID int64) (*models.DeletionCertificate, error) {
+	return nil, sql.ErrNoRows
+}
+
+// HoldPatientRecords holds nothing
+func (stub *StubErasureRepository) HoldPatientRecords(ctx context.Context, erasureID int64, patientID string) (int64, error) {
+	return 0, nil
+}
+
+// RestorePatientRecords restores nothing
+func (stub *StubErasureRepository) RestorePatientRecords(ctx context.Context, erasureID int64) error {
+	return sql.ErrNoRows
+}
+
+// PurgePatientRecords purges nothing
+func (stub *StubErasureRepository) PurgePatientRecords(ctx context.Context, erasureID int64) (int64, error) {
+	return 0, nil
+}
+
+// ClearCompartmentSnapshots clears nothing
+func (stub *StubErasureRepository) ClearCompartmentSnapshots(ctx context.Context, patientID string) (int64, error) {
+	return 0, nil
+}
+
+// erasureRequest builds a request with the given id URL parameter and an optional principal
+func erasureRequest(method string, path string, id string, body string, principal *auth.Principal) *http.Request {
+	request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", id)
+	ctx := context.WithValue(request.Context(), chi.RouteCtxKey, routeContext)
+	if principal != nil {
+		ctx = auth.WithPrincipal(ctx, *principal)
+	}
+	return request.WithContext(ctx)
+}
+
+// TestErasureHandler_RequestAndCancel verifies the compliance flow for requesting, reading, and cancelling an erasure
+func TestErasureHandler_RequestAndCancel(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1"}
+	erasureService := service.NewErasureService(&StubErasureRepository{}, patientRepository, service.DefaultErasurePolicy())
+	erasureHandler := NewErasureHandler(erasureService)
+
+	recorder := httptest.NewRecorder()
+	erasureHandler.Request(recorder, erasureRequest(http.MethodPost, "/admin/patients/patient-1/erasure", "patient-1", `{"reason":"DSR-7"}`, nil))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 without compliance role, got %d", recorder.Code)
+	}
+
+	compliance := &auth.Principal{ID: "api-key:privacy", Roles: []string{auth.RoleCompliance}}
+	recorder = httptest.NewRecorder()
+	erasureHandler.Request(recorder, erasureRequest(http.MethodPost, "/admin/patients/patient-1/erasure", "patient-1", `{"reason":"DSR-7"}`, compliance))
+	if recorder.Code != http.StatusAccepted || recorder.Header().Get("Location") != "/admin/erasures/1" {
+		t.Fatalf("Expected status 202 with a Location, got %d %q", recorder.Code, recorder.Header().Get("Location"))
+	}
+
+	recorder = httptest.NewRecorder()
+	erasureHandler.Request(recorder, erasureRequest(http.MethodPost, "/admin/patients/patient-1/erasure", "patient-1", `{"reason":"DSR-8"}`, compliance))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for a second erasure, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	erasureHandler.Status(recorder, erasureRequest(http.MethodGet, "/admin/erasures/1", "1", "", compliance))
+	var erasure models.PatientErasure
+	json.NewDecoder(recorder.Body).Decode(&erasure)
+	if recorder.Code != http.StatusOK || erasure.Status != models.ErasureStatusPending || erasure.RequestedBy != "api-key:privacy" {
+		t.Errorf("Expected the pending erasure, got %d %+v", recorder.Code, erasure)
+	}
+
+	recorder = httptest.NewRecorder()
+	erasureHandler.Certificate(recorder, erasureRequest(http.MethodGet, "/admin/erasures/1/certificate", "1", "", compliance))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 before the purge, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	erasureHandler.Cancel(recorder, erasureRequest(http.MethodPost, "/admin/erasures/1/cancel", "1", "", compliance))
+	if recorder.Code != http.StatusAccepted {
+		t.Errorf("Expected status 202 cancelling, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	erasureHandler.Status(recorder, erasureRequest(http.MethodGet, "/admin/erasures/abc", "abc", "", compliance))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a malformed ID, got %d", recorder.Code)
+	}
+}
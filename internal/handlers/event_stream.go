@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/streaming"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// eventStreamWriteTimeout bounds each write to a stream client so a stalled client fails fast
+const eventStreamWriteTimeout = 10 * time.Second
+
+// EventStreamHandler streams resource events to dashboards as NDJSON
+// Each connection is a hub subscription with a bounded buffer and an idle timeout,
+// so a slow dashboard is disconnected instead of growing server memory
+type EventStreamHandler struct {
+	hub *streaming.Hub
+}
+
+// NewEventStreamHandler creates a new instance of EventStreamHandler
+func NewEventStreamHandler(hub *streaming.Hub) *EventStreamHandler {
+	return &EventStreamHandler{
+		hub: hub,
+	}
+}
+
+// Consumer returns an event bus handler that publishes each event to its tenant's stream topic
+func (handler *EventStreamHandler) Consumer() events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		encodedEvent, encodeError := json.Marshal(event)
+		if encodeError != nil {
+			return encodeError
+		}
+		handler.hub.Publish(eventStreamTopic(event.TenantID), encodedEvent)
+		return nil
+	}
+}
+
+// Stream handles GET /stream/events?_type={types} - streams the caller's tenant events as NDJSON until the client disconnects
+func (handler *EventStreamHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	resourceTypes := map[string]bool{}
+	for _, resourceType := range strings.Split(r.URL.Query().Get("_type"), ",") {
+		if resourceType = strings.TrimSpace(resourceType); resourceType != "" {
+			resourceTypes[resourceType] = true
+		}
+	}
+
+	subscription := handler.hub.Subscribe(eventStreamTopic(tenant.FromContext(r.Context())))
+	defer subscription.Close()
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	writer := streaming.NewWriter(w, eventStreamWriteTimeout)
+	writer.Flush()
+
+	for {
+		message, nextError := subscription.Next(r.Context())
+		if nextError != nil {
+			if errors.Is(nextError, streaming.ErrSlowConsumer) || errors.Is(nextError, streaming.ErrIdleTimeout) {
+				log.Info().Err(nextError).Str("topic", subscription.Topic()).Msg("Event stream client disconnected")
+			}
+			return
+		}
+
+		if len(resourceTypes) > 0 && !resourceTypes[eventResourceType(message)] {
+			continue
+		}
+
+		// Flush every event: dashboards want them as they happen, and the write deadline provides backpressure
+		if writeError := writer.WriteRecord(message); writeError != nil {
+			return
+		}
+		if flushError := writer.Flush(); flushError != nil {
+			return
+		}
+	}
+}
+
+// Stats handles GET /admin/streams - returns subscriber counts and slow consumer counters
+func (handler *EventStreamHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(handler.hub.Stats())
+}
+
+// eventStreamTopic keeps each tenant's events on its own topic
+func eventStreamTopic(tenantID string) string {
+	return "events:" + tenantID
+}
+
+// eventResourceType reads the resource type from an encoded event
+func eventResourceType(message []byte) string {
+	var event events.Event
+	json.Unmarshal(message, &event)
+	return event.ResourceType
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/streaming"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// TestEventStreamHandler_Stream verifies events are streamed as NDJSON to their tenant only, filtered by _type
+func TestEventStreamHandler_Stream(t *testing.T) {
+	hub := streaming.NewHub(streaming.DefaultConfig())
+	handler := NewEventStreamHandler(hub)
+	server := httptest.NewServer(tenant.NewResolver(nil).Middleware(http.HandlerFunc(handler.Stream)))
+	defer server.Close()
+
+	request, _ := http.NewRequest(http.MethodGet, server.URL+"/stream/events?_type=Observation", nil)
+	request.Header.Set(tenant.HeaderTenantID, "tenant-a")
+	response, requestError := http.DefaultClient.Do(request)
+	if requestError != nil {
+		t.Fatalf("Failed to open stream: %v", requestError)
+	}
+	defer response.Body.Close()
+	if response.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Errorf("Expected NDJSON, got %s", response.Header.Get("Content-Type"))
+	}
+
+	// Wait for the subscription so the published events are not missed
+	for hub.Stats().Subscribers == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	consumer := handler.Consumer()
+	consumer(context.Background(), events.Event{Type: events.EventResourceCreated, ResourceType: "Patient", ResourceID: "p1", TenantID: "tenant-a"})
+	consumer(context.Background(), events.Event{Type: events.EventResourceCreated, ResourceType: "Observation", ResourceID: "o-other", TenantID: "tenant-b"})
+	consumer(context.Background(), events.Event{Type: events.EventResourceCreated, ResourceType: "Observation", ResourceID: "o1", TenantID: "tenant-a"})
+
+	line, readError := bufio.NewReader(response.Body).ReadBytes('\n')
+	if readError != nil {
+		t.Fatalf("Failed to read event: %v", readError)
+	}
+	var event events.Event
+	json.Unmarshal(line, &event)
+	if event.ResourceID != "o1" {
+		t.Errorf("Expected only tenant-a's Observation event, got %s", line)
+	}
+}
+
+// TestEventStreamHandler_SlowConsumer verifies a subscriber that stops draining is disconnected
+func TestEventStreamHandler_SlowConsumer(t *testing.T) {
+	hub := streaming.NewHub(streaming.Config{BufferSize: 2, OverflowPolicy: streaming.OverflowDisconnect})
+	handler := NewEventStreamHandler(hub)
+	subscription := hub.Subscribe(eventStreamTopic("tenant-a"))
+
+	consumer := handler.Consumer()
+	for index := 0; index < 3; index++ {
+		consumer(context.Background(), events.Event{ResourceType: "Patient", TenantID: "tenant-a"})
+	}
+
+	<-subscription.Done()
+	if subscription.Err() != streaming.ErrSlowConsumer || hub.Stats().SlowDisconnects != 1 {
+		t.Errorf("Expected a slow consumer disconnect, got %v with %+v", subscription.Err(), hub.Stats())
+	}
+}
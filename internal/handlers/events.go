@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+)
+
+// EventStatsResponse reports per-consumer event bus counters
+type EventStatsResponse struct {
+	Consumers []events.ConsumerStats `json:"consumers"`
+
+	// Outbox reports the Kafka event outbox when publishing is enabled
+	Outbox *events.RelayStats `json:"outbox,omitempty"`
+}
+
+// EventsHandler exposes internal event bus metrics
+type EventsHandler struct {
+	eventBus *events.Bus
+	relay    *events.Relay
+}
+
+// NewEventsHandler creates a new instance of EventsHandler
+func NewEventsHandler(eventBus *events.Bus) *EventsHandler {
+	return &EventsHandler{
+		eventBus: eventBus,
+	}
+}
+
+// SetRelay adds the Kafka outbox relay's depth and counters to the stats
+func (handler *EventsHandler) SetRelay(relay *events.Relay) {
+	handler.relay = relay
+}
+
+// Stats handles GET /admin/events - returns queue depth and delivery counters per consumer
+func (handler *EventsHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	statsResponse := EventStatsResponse{Consumers: handler.eventBus.Stats()}
+	if handler.relay != nil {
+		relayStats, statsError := handler.relay.Stats(r.Context())
+		if statsError != nil {
+			middleware.WriteError(w, r, apperrors.Internal("Failed to read event outbox", statsError))
+			return
+		}
+		statsResponse.Outbox = &relayStats
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(statsResponse)
+}
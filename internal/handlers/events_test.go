@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+)
+
+// TestEventsHandler_Stats verifies consumer counters are reported
+func TestEventsHandler_Stats(t *testing.T) {
+	eventBus := events.NewBus()
+	eventBus.Subscribe("audit", 8, func(ctx context.Context, event events.Event) error { return nil })
+	eventBus.Publish(context.Background(), events.Event{Type: events.EventResourceCreated, ResourceID: "1"})
+	eventBus.Shutdown(context.Background())
+
+	recorder := httptest.NewRecorder()
+	NewEventsHandler(eventBus).Stats(recorder, httptest.NewRequest(http.MethodGet, "/admin/events", nil))
+
+	var statsResponse EventStatsResponse
+	json.NewDecoder(recorder.Body).Decode(&statsResponse)
+	if len(statsResponse.Consumers) != 1 || statsResponse.Consumers[0].Delivered != 1 || statsResponse.Consumers[0].QueueCapacity != 8 {
+		t.Errorf("Unexpected stats: %+v", statsResponse)
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/database"
+	"github.com/nathannewyen/fhir-health-interop/internal/health"
 )
 
 // HealthResponse represents the health check response structure
@@ -11,16 +14,37 @@ type HealthResponse struct {
 	Status    string `json:"status"`
 	Timestamp string `json:"timestamp"`
 	Service   string `json:"service"`
+
+	// MongoDBPool is the MongoDB connection pool's state, when a pool is reported
+	MongoDBPool *database.MongoPoolStats `json:"mongodbPool,omitempty"`
+}
+
+// MongoPoolReporter reports the state of the MongoDB connection pool
+type MongoPoolReporter interface {
+	PoolStats() database.MongoPoolStats
 }
 
 // HealthHandler handles health check requests
-type HealthHandler struct{}
+type HealthHandler struct {
+	mongoPool         MongoPoolReporter
+	dependencyChecker *health.Checker
+}
 
 // NewHealthHandler creates a new instance of HealthHandler
 func NewHealthHandler() *HealthHandler {
 	return &HealthHandler{}
 }
 
+// SetMongoPool includes the MongoDB connection pool's state in every health response
+func (healthHandler *HealthHandler) SetMongoPool(mongoPool MongoPoolReporter) {
+	healthHandler.mongoPool = mongoPool
+}
+
+// SetDependencyChecker sets the dependency checks behind the readiness endpoint
+func (healthHandler *HealthHandler) SetDependencyChecker(dependencyChecker *health.Checker) {
+	healthHandler.dependencyChecker = dependencyChecker
+}
+
 // Check returns the health status of the service
 func (healthHandler *HealthHandler) Check(writer http.ResponseWriter, request *http.Request) {
 	// Build health response with current timestamp and service status
@@ -29,6 +53,10 @@ func (healthHandler *HealthHandler) Check(writer http.ResponseWriter, request *h
 		Timestamp: time.Now().UTC().Format(time.RFC3339),
 		Service:   "fhir-health-interop",
 	}
+	if healthHandler.mongoPool != nil {
+		poolStats := healthHandler.mongoPool.PoolStats()
+		healthResponse.MongoDBPool = &poolStats
+	}
 
 	// Set response headers for JSON content type
 	writer.Header().Set("Content-Type", "application/json")
@@ -37,3 +65,28 @@ func (healthHandler *HealthHandler) Check(writer http.ResponseWriter, request *h
 	// Encode and write the JSON response
 	json.NewEncoder(writer).Encode(healthResponse)
 }
+
+// Live handles GET /health/live - 200 while the process is serving requests; dependencies are not checked,
+// so an outage of a database never gets healthy instances restarted
+func (healthHandler *HealthHandler) Live(writer http.ResponseWriter, request *http.Request) {
+	healthHandler.Check(writer, request)
+}
+
+// Ready handles GET /health/ready - pings every dependency and returns 503 when any is down,
+// so load balancers rotate the instance out until its dependencies answer again
+func (healthHandler *HealthHandler) Ready(writer http.ResponseWriter, request *http.Request) {
+	report := health.Report{Status: health.StatusUp, CheckedAt: time.Now().UTC(), Dependencies: []health.DependencyStatus{}}
+	if healthHandler.dependencyChecker != nil {
+		report = healthHandler.dependencyChecker.Check(request.Context())
+	}
+
+	statusCode := http.StatusOK
+	if !report.Up() {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.WriteHeader(statusCode)
+	json.NewEncoder(writer).Encode(report)
+}
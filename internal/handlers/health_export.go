@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/healthexport"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SetHealthExportService enables $health-export, which the companion app uses to copy vital signs to the phone
+func (handler *PatientHandler) SetHealthExportService(healthExportService *service.HealthExportService) {
+	handler.healthExportService = healthExportService
+}
+
+// HealthExport handles GET /fhir/Patient/{id}/$health-export?format=healthkit|googlefit&start=
+// It returns the patient's vital signs as Apple HealthKit or Google Fit JSON, from the optional start
+// (a YYYY-MM-DD day or an RFC 3339 timestamp) onwards
+// Health data leaves the FHIR API here, so anonymous callers are refused even where anonymous reads are allowed
+func (handler *PatientHandler) HealthExport(w http.ResponseWriter, r *http.Request) {
+	if handler.healthExportService == nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Operation", "$health-export"))
+		return
+	}
+	if auth.FromContext(r.Context()).ID == auth.AnonymousPrincipalID {
+		middleware.WriteError(w, r, apperrors.Unauthorized("An API key is required to export health data"))
+		return
+	}
+
+	queryParams := r.URL.Query()
+	format, formatError := healthexport.ParseFormat(queryParams.Get("format"))
+	if formatError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("format", formatError.Error()))
+		return
+	}
+	since, startValid := parseExportStart(queryParams.Get("start"))
+	if !startValid {
+		middleware.WriteError(w, r, apperrors.InvalidInput("start", "must be a date (YYYY-MM-DD) or an RFC 3339 timestamp"))
+		return
+	}
+
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	export, exportError := handler.healthExportService.Export(r.Context(), internalPatientID, format, since, func(observation *fhir.Observation) {
+		exposeID(r.Context(), handler.idCodec, "Observation", observation.Id)
+	})
+	var appError *apperrors.AppError
+	if errors.As(exportError, &appError) {
+		middleware.WriteError(w, r, appError)
+		return
+	}
+	if exportError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Patient", patientID))
+		return
+	}
+	export.Patient = patientID
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(export)
+}
+
+// parseExportStart parses the optional start of an export as a day or a timestamp
+func parseExportStart(rawStart string) (*time.Time, bool) {
+	if rawStart == "" {
+		return nil, true
+	}
+	for _, layout := range []string{time.RFC3339, rollupDateLayout} {
+		if start, parseError := time.Parse(layout, rawStart); parseError == nil {
+			return &start, true
+		}
+	}
+	return nil, false
+}
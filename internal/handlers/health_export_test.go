@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/healthexport"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newHealthExportTestRouter routes $health-export over one patient with one heart rate observation
+// Requests carry an API key principal unless anonymous is set
+func newHealthExportTestRouter(anonymous bool) (*chi.Mux, *MockObservationService) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", FamilyName: "Smith"}
+	patientService := service.NewPatientService(patientRepository)
+
+	observationService := NewMockObservationService()
+	observationID := "obs-1"
+	system := "http://loinc.org"
+	code := "8867-4"
+	effective := "2024-05-01T08:00:00Z"
+	value := json.Number("72")
+	unit := "/min"
+	observationService.observations[observationID] = &fhir.Observation{
+		Id:                &observationID,
+		Code:              fhir.CodeableConcept{Coding: []fhir.Coding{{System: &system, Code: &code}}},
+		EffectiveDateTime: &effective,
+		ValueQuantity:     &fhir.Quantity{Value: &value, Unit: &unit},
+	}
+
+	handler := NewPatientHandlerWithService(patientService)
+	handler.SetHealthExportService(service.NewHealthExportService(patientService, observationService))
+
+	router := chi.NewRouter()
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if anonymous {
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{ID: "app-key"})))
+		})
+	})
+	router.Get("/fhir/Patient/{id}/$health-export", handler.HealthExport)
+	return router, observationService
+}
+
+// TestPatientHandler_HealthExport verifies the patient's vital signs are exported in the requested format
+func TestPatientHandler_HealthExport(t *testing.T) {
+	router, observationService := newHealthExportTestRouter(false)
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/patient-1/$health-export?format=healthkit&start=2024-04-01", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if recorder.Header().Get("Cache-Control") != "no-store" {
+		t.Errorf("Expected the export not to be cached, got %q", recorder.Header().Get("Cache-Control"))
+	}
+	var export healthexport.Export
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&export); decodeError != nil {
+		t.Fatalf("Failed to decode export: %v", decodeError)
+	}
+	if export.Patient != "patient-1" || export.HealthKit == nil || len(export.HealthKit.Samples) != 1 {
+		t.Errorf("Expected one HealthKit sample for patient-1, got %+v", export)
+	}
+
+	searchParams := observationService.lastSearchParams
+	if searchParams.PatientID != "patient-1" || searchParams.DateGreaterThan == nil || searchParams.SortOrder != "asc" {
+		t.Errorf("Expected the patient's observations from start, oldest first, got %+v", searchParams)
+	}
+}
+
+// TestPatientHandler_HealthExportRejections verifies anonymous callers, bad parameters, and unknown patients are refused
+func TestPatientHandler_HealthExportRejections(t *testing.T) {
+	testCases := []struct {
+		name           string
+		anonymous      bool
+		path           string
+		expectedStatus int
+	}{
+		{"anonymous", true, "/fhir/Patient/patient-1/$health-export?format=healthkit", http.StatusUnauthorized},
+		{"missing format", false, "/fhir/Patient/patient-1/$health-export", http.StatusBadRequest},
+		{"invalid start", false, "/fhir/Patient/patient-1/$health-export?format=googlefit&start=yesterday", http.StatusBadRequest},
+		{"unknown patient", false, "/fhir/Patient/patient-2/$health-export?format=googlefit", http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			router, _ := newHealthExportTestRouter(testCase.anonymous)
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, testCase.path, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
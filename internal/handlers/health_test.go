@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/database"
+	"github.com/nathannewyen/fhir-health-interop/internal/health"
 )
 
 // TestHealthHandler_Check verifies the health check endpoint returns correct response
@@ -66,3 +72,86 @@ func TestNewHealthHandler(t *testing.T) {
 		t.Error("Expected NewHealthHandler to return non-nil instance")
 	}
 }
+
+// stubMongoPool reports fixed pool stats for testing
+type stubMongoPool struct {
+	stats database.MongoPoolStats
+}
+
+// PoolStats returns the fixed stats
+func (pool stubMongoPool) PoolStats() database.MongoPoolStats {
+	return pool.stats
+}
+
+// TestHealthHandler_Check_MongoPool verifies the MongoDB pool's state is reported only once a pool is set
+func TestHealthHandler_Check_MongoPool(t *testing.T) {
+	healthHandler := NewHealthHandler()
+
+	responseRecorder := httptest.NewRecorder()
+	healthHandler.Check(responseRecorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	var healthResponse HealthResponse
+	json.NewDecoder(responseRecorder.Body).Decode(&healthResponse)
+	if healthResponse.MongoDBPool != nil {
+		t.Errorf("Expected no pool stats without a pool, got %+v", healthResponse.MongoDBPool)
+	}
+
+	poolStats := database.MongoPoolStats{MaxPoolSize: 100, OpenConnections: 6, InUseConnections: 2, IdleConnections: 4}
+	healthHandler.SetMongoPool(stubMongoPool{stats: poolStats})
+	responseRecorder = httptest.NewRecorder()
+	healthHandler.Check(responseRecorder, httptest.NewRequest(http.MethodGet, "/health", nil))
+	healthResponse = HealthResponse{}
+	json.NewDecoder(responseRecorder.Body).Decode(&healthResponse)
+	if healthResponse.MongoDBPool == nil || *healthResponse.MongoDBPool != poolStats {
+		t.Errorf("Expected the pool stats %+v, got %+v", poolStats, healthResponse.MongoDBPool)
+	}
+}
+
+// TestHealthHandler_Ready verifies readiness lists each dependency and turns 503 when one is down
+func TestHealthHandler_Ready(t *testing.T) {
+	mongoError := error(nil)
+	dependencyChecker := health.NewChecker(time.Second)
+	dependencyChecker.Add("postgres", func(ctx context.Context) error { return nil })
+	dependencyChecker.Add("mongodb", func(ctx context.Context) error { return mongoError })
+	healthHandler := NewHealthHandler()
+	healthHandler.SetDependencyChecker(dependencyChecker)
+
+	responseRecorder := httptest.NewRecorder()
+	healthHandler.Ready(responseRecorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	var report health.Report
+	json.NewDecoder(responseRecorder.Body).Decode(&report)
+	if responseRecorder.Code != http.StatusOK || !report.Up() || len(report.Dependencies) != 2 {
+		t.Fatalf("Expected 200 with both dependencies up, got %d %+v", responseRecorder.Code, report)
+	}
+
+	mongoError = errors.New("server selection timeout")
+	responseRecorder = httptest.NewRecorder()
+	healthHandler.Ready(responseRecorder, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+	report = health.Report{}
+	json.NewDecoder(responseRecorder.Body).Decode(&report)
+	if responseRecorder.Code != http.StatusServiceUnavailable || report.Status != health.StatusDown {
+		t.Fatalf("Expected 503 while MongoDB is down, got %d %+v", responseRecorder.Code, report)
+	}
+	if mongodb := report.Dependencies[1]; mongodb.Name != "mongodb" || mongodb.Status != health.StatusDown || mongodb.Error != "server selection timeout" {
+		t.Errorf("Expected MongoDB reported down with its error, got %+v", mongodb)
+	}
+	if responseRecorder.Header().Get("Cache-Control") != "no-store" {
+		t.Error("Expected readiness responses not to be cached")
+	}
+}
+
+// TestHealthHandler_Live verifies liveness answers 200 without checking dependencies
+func TestHealthHandler_Live(t *testing.T) {
+	dependencyChecker := health.NewChecker(time.Second)
+	dependencyChecker.Add("postgres", func(ctx context.Context) error {
+		t.Error("Expected liveness not to check dependencies")
+		return nil
+	})
+	healthHandler := NewHealthHandler()
+	healthHandler.SetDependencyChecker(dependencyChecker)
+
+	responseRecorder := httptest.NewRecorder()
+	healthHandler.Live(responseRecorder, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+	if responseRecorder.Code != http.StatusOK {
+		t.Errorf("Expected 200, got %d", responseRecorder.Code)
+	}
+}
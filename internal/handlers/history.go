@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Query parameters selecting a point-in-time read
+const (
+	// asOfTimeParameter reads the resource as it was at an RFC 3339 instant
+	asOfTimeParameter = "_at"
+
+	// asOfVersionParameter reads a specific version of the resource
+	asOfVersionParameter = "asOfVersion"
+)
+
+// isPointInTimeRead reports whether the request asks for a past state instead of the current one
+func isPointInTimeRead(r *http.Request) bool {
+	query := r.URL.Query()
+	return query.Has(asOfTimeParameter) || query.Has(asOfVersionParameter)
+}
+
+// parseAsOfTime reads the _at instant
+func parseAsOfTime(r *http.Request) (time.Time, *apperrors.AppError) {
+	at, parseError := time.Parse(time.RFC3339, r.URL.Query().Get(asOfTimeParameter))
+	if parseError != nil {
+		return time.Time{}, apperrors.InvalidInput(asOfTimeParameter, "must be an RFC 3339 instant such as 2024-05-01T12:00:00Z")
+	}
+	return at, nil
+}
+
+// readPointInTime answers GET /fhir/{type}/{id}?_at={instant} or ?asOfVersion={n} from the change log snapshots
+func readPointInTime(w http.ResponseWriter, r *http.Request, historyService *service.HistoryService, codec idcodec.Codec, resourceType string, resourceID string, internalID string) {
+	if historyService == nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput(asOfTimeParameter, "point-in-time reads are not enabled"))
+		return
+	}
+
+	query := r.URL.Query()
+	if query.Has(asOfTimeParameter) && query.Has(asOfVersionParameter) {
+		middleware.WriteError(w, r, apperrors.ValidationError("Use either _at or asOfVersion, not both"))
+		return
+	}
+
+	var resource interface{}
+	var readError error
+	if query.Has(asOfVersionParameter) {
+		version, parseError := strconv.Atoi(query.Get(asOfVersionParameter))
+		if parseError != nil || version < 1 {
+			middleware.WriteError(w, r, apperrors.InvalidInput(asOfVersionParameter, "must be a positive integer"))
+			return
+		}
+		resource, readError = historyService.ReadVersion(r.Context(), resourceType, internalID, version)
+	} else {
+		at, parseError := parseAsOfTime(r)
+		if parseError != nil {
+			middleware.WriteError(w, r, parseError)
+			return
+		}
+		resource, readError = historyService.ReadAsOf(r.Context(), resourceType, internalID, at)
+	}
+	if readError != nil {
+		writeHistoryError(w, r, readError, resourceType, resourceID)
+		return
+	}
+
+	exposeHistoricalIDs(r.Context(), codec, resource)
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(resource)
+}
+
+// readVersion answers GET /fhir/{type}/{id}/_history/{version} from the change log snapshots
+func readVersion(w http.ResponseWriter, r *http.Request, historyService *service.HistoryService, codec idcodec.Codec, resourceType string, resourceID string, internalID string) {
+	if historyService == nil {
+		middleware.WriteError(w, r, apperrors.NotFound(resourceType, resourceID))
+		return
+	}
+	version, parseError := strconv.Atoi(chi.URLParam(r, "version"))
+	if parseError != nil || version < 1 {
+		middleware.WriteError(w, r, apperrors.InvalidInput("version", "must be a positive integer"))
+		return
+	}
+
+	resource, readError := historyService.ReadVersion(r.Context(), resourceType, internalID, version)
+	if readError != nil {
+		writeHistoryError(w, r, readError, resourceType, resourceID)
+		return
+	}
+
+	exposeHistoricalIDs(r.Context(), codec, resource)
+	w.Header().Set("ETag", versionTag(strconv.Itoa(version)))
+	encoding.Write(w, r, http.StatusOK, resource)
+}
+
+// writeVersionHistory answers GET /fhir/{type}/{id}/_history with a history Bundle of the resource's versions,
+// newest first. Deletes are entries with a DELETE request and no resource
+func writeVersionHistory(w http.ResponseWriter, r *http.Request, historyService *service.HistoryService, codec idcodec.Codec, resourceType string, resourceID string, internalID string) {
+	if historyService == nil {
+		middleware.WriteError(w, r, apperrors.NotFound(resourceType, resourceID))
+		return
+	}
+
+	versions, listError := historyService.ListVersions(r.Context(), resourceType, internalID)
+	if errors.Is(listError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound(resourceType, resourceID))
+		return
+	}
+	if listError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read "+resourceType+" history", listError))
+		return
+	}
+
+	fullURL := fhirBaseURL(r) + "/" + resourceType + "/" + resourceID
+	entries := make([]fhir.BundleEntry, 0, len(versions))
+	for _, version := range versions {
+		entry := fhir.BundleEntry{FullUrl: &fullURL}
+		if version.Resource != nil {
+			exposeHistoricalIDs(r.Context(), codec, version.Resource)
+			entry.Resource, _ = json.Marshal(version.Resource)
+		}
+
+		etag := versionTag(strconv.Itoa(version.Change.Version))
+		lastModified := version.Change.ChangedAt.UTC().Format(time.RFC3339)
+		switch version.Change.Operation {
+		case models.ChangeOperationCreate:
+			entry.Request = &fhir.BundleEntryRequest{Method: fhir.HTTPVerbPOST, Url: resourceType}
+			entry.Response = &fhir.BundleEntryResponse{Status: bundle.ResponseStatus(http.StatusCreated), Etag: &etag, LastModified: &lastModified}
+		case models.ChangeOperationDelete:
+			entry.Request = &fhir.BundleEntryRequest{Method: fhir.HTTPVerbDELETE, Url: resourceType + "/" + resourceID}
+			entry.Response = &fhir.BundleEntryResponse{Status: bundle.ResponseStatus(http.StatusNoContent), Etag: &etag, LastModified: &lastModified}
+		default:
+			entry.Request = &fhir.BundleEntryRequest{Method: fhir.HTTPVerbPUT, Url: resourceType + "/" + resourceID}
+			entry.Response = &fhir.BundleEntryResponse{Status: bundle.ResponseStatus(http.StatusOK), Etag: &etag, LastModified: &lastModified}
+		}
+		entries = append(entries, entry)
+	}
+
+	total := len(entries)
+	encoding.Write(w, r, http.StatusOK, fhir.Bundle{Type: fhir.BundleTypeHistory, Total: &total, Entry: entries})
+}
+
+// writeHistoryError reports a failed point-in-time read
+func writeHistoryError(w http.ResponseWriter, r *http.Request, readError error, resourceType string, resourceID string) {
+	switch {
+	case errors.Is(readError, service.ErrResourceNotFound):
+		middleware.WriteError(w, r, apperrors.NotFound(resourceType, resourceID))
+	case errors.Is(readError, service.ErrResourceDeleted):
+		middleware.WriteError(w, r, apperrors.Gone(resourceType, resourceID))
+	default:
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read "+resourceType+" history", readError))
+	}
+}
+
+// exposeHistoricalIDs translates the internal IDs in a snapshot into the IDs shown to the client
+func exposeHistoricalIDs(ctx context.Context, codec idcodec.Codec, resource interface{}) {
+	switch snapshot := resource.(type) {
+	case *fhir.Patient:
+		exposePatient(ctx, codec, snapshot)
+	case *fhir.Observation:
+		exposeID(ctx, codec, "Observation", snapshot.Id)
+		exposeObservationReferences(ctx, codec, snapshot)
+	}
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// historyPatientID is the UUID of the patient whose history is seeded
+const historyPatientID = "3a9d0c1e-7b2f-4e6a-8c5d-1f2e3a4b5c6d"
+
+// StubSnapshotRepository implements SnapshotRepository over a fixed list of changes in recording order
+type StubSnapshotRepository struct {
+	changes []*models.ResourceChange
+}
+
+// FindVersion returns the change that produced the given version
+func (stub *StubSnapshotRepository) FindVersion(ctx context.Context, resourceType string, resourceID string, version int) (*models.ResourceChange, error) {
+	for _, change := range stub.changes {
+		if change.ResourceType == resourceType && change.ResourceID == resourceID && change.Version == version {
+			return change, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// FindAsOf returns the latest change recorded at or before the given time
+func (stub *StubSnapshotRepository) FindAsOf(ctx context.Context, resourceType string, resourceID string, at time.Time) (*models.ResourceChange, error) {
+	var latest *models.ResourceChange
+	for _, change := range stub.changes {
+		if change.ResourceType == resourceType && change.ResourceID == resourceID && !change.ChangedAt.After(at) {
+			latest = change
+		}
+	}
+	if latest == nil {
+		return nil, sql.ErrNoRows
+	}
+	return latest, nil
+}
+
+// ListVersions returns up to limit of the resource's changes, newest first
+func (stub *StubSnapshotRepository) ListVersions(ctx context.Context, resourceType string, resourceID string, limit int) ([]*models.ResourceChange, error) {
+	versions := []*models.ResourceChange{}
+	for index := len(stub.changes) - 1; index >= 0 && len(versions) < limit; index-- {
+		change := stub.changes[index]
+		if change.ResourceType == resourceType && change.ResourceID == resourceID {
+			versions = append(versions, change)
+		}
+	}
+	return versions, nil
+}
+
+// ListCompartmentAsOf returns live observations in the compartment at the given time
+func (stub *StubSnapshotRepository) ListCompartmentAsOf(ctx context.Context, patientID string, at time.Time) ([]*models.ResourceChange, error) {
+	result := []*models.ResourceChange{}
+	for _, change := range stub.changes {
+		if change.ResourceType == "Observation" && change.CompartmentPatientID == patientID && !change.ChangedAt.After(at) {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// newHistoryTestRouter seeds a patient created at 12:00, renamed at 13:00, and deleted at 15:00, with an observation recorded at 14:00
+func newHistoryTestRouter() *chi.Mux {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := func(resource interface{}) json.RawMessage {
+		encoded, _ := json.Marshal(resource)
+		return encoded
+	}
+	patientID := historyPatientID
+	smith, jones := "Smith", "Jones"
+	observationID := "obs-1"
+	subject := "Patient/" + historyPatientID
+
+	historyService := service.NewHistoryService(&StubSnapshotRepository{changes: []*models.ResourceChange{
+		{ResourceType: "Patient", ResourceID: historyPatientID, Operation: models.ChangeOperationCreate, Version: 1, ChangedAt: start,
+			Snapshot: snapshot(fhir.Patient{Id: &patientID, Name: []fhir.HumanName{{Family: &smith}}}), CompartmentPatientID: historyPatientID},
+		{ResourceType: "Patient", ResourceID: historyPatientID, Operation: models.ChangeOperationUpdate, Version: 2, ChangedAt: start.Add(time.Hour),
+			Snapshot: snapshot(fhir.Patient{Id: &patientID, Name: []fhir.HumanName{{Family: &jones}}}), CompartmentPatientID: historyPatientID},
+		{ResourceType: "Observation", ResourceID: observationID, Operation: models.ChangeOperationCreate, Version: 1, ChangedAt: start.Add(2 * time.Hour),
+			Snapshot: snapshot(fhir.Observation{Id: &observationID, Subject: &fhir.Reference{Reference: &subject}}), CompartmentPatientID: historyPatientID},
+		{ResourceType: "Patient", ResourceID: historyPatientID, Operation: models.ChangeOperationDelete, Version: 3, ChangedAt: start.Add(3 * time.Hour),
+			CompartmentPatientID: historyPatientID},
+	}})
+
+	// The recorded changes end in a delete, so the repository holds the patient deleted
+	patientRepository := NewMockPatientRepository()
+	patientRepository.deleted[historyPatientID] = true
+	patientHandler := NewPatientHandlerWithService(service.NewPatientService(patientRepository))
+	patientHandler.SetHistoryService(historyService)
+
+	router := chi.NewRouter()
+	router.Get("/fhir/Patient/{id}", patientHandler.GetByID)
+	router.Get("/fhir/Patient/{id}/$snapshot", patientHandler.Snapshot)
+	router.Get("/fhir/Patient/{id}/_history", patientHandler.History)
+	router.Get("/fhir/Patient/{id}/_history/{version}", patientHandler.VRead)
+	return router
+}
+
+// TestPatientHandler_GetByID_PointInTime verifies _at and asOfVersion reads and their failures
+func TestPatientHandler_GetByID_PointInTime(t *testing.T) {
+	router := newHistoryTestRouter()
+
+	testCases := []struct {
+		name            string
+		query           string
+		expectedStatus  int
+		expectedFamily  string
+		expectedVersion string
+	}{
+		{"between versions", "?_at=2024-05-01T12:30:00Z", http.StatusOK, "Smith", "1"},
+		{"after rename", "?_at=2024-05-01T13:00:00Z", http.StatusOK, "Jones", "2"},
+		{"specific version", "?asOfVersion=1", http.StatusOK, "Smith", "1"},
+		{"before creation", "?_at=2024-05-01T11:00:00Z", http.StatusNotFound, "", ""},
+		{"after deletion", "?_at=2024-05-01T16:00:00Z", http.StatusGone, "", ""},
+		{"deleted version", "?asOfVersion=3", http.StatusGone, "", ""},
+		{"unknown version", "?asOfVersion=9", http.StatusNotFound, "", ""},
+		{"invalid instant", "?_at=yesterday", http.StatusBadRequest, "", ""},
+		{"invalid version", "?asOfVersion=0", http.StatusBadRequest, "", ""},
+		{"both parameters", "?_at=2024-05-01T12:30:00Z&asOfVersion=1", http.StatusBadRequest, "", ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/"+historyPatientID+testCase.query, nil))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("Expected status %d, got %d: %s", testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+			if testCase.expectedStatus != http.StatusOK {
+				return
+			}
+			var patient fhir.Patient
+			json.NewDecoder(recorder.Body).Decode(&patient)
+			if *patient.Name[0].Family != testCase.expectedFamily || *patient.Meta.VersionId != testCase.expectedVersion {
+				t.Errorf("Expected %s version %s, got %s version %s", testCase.expectedFamily, testCase.expectedVersion, *patient.Name[0].Family, *patient.Meta.VersionId)
+			}
+		})
+	}
+}
+
+// TestPatientHandler_Snapshot verifies the compartment bundle only holds resources recorded by the requested time
+func TestPatientHandler_Snapshot(t *testing.T) {
+	router := newHistoryTestRouter()
+
+	testCases := []struct {
+		at              string
+		expectedStatus  int
+		expectedEntries int
+	}{
+		{"2024-05-01T13:30:00Z", http.StatusOK, 1},
+		{"2024-05-01T14:30:00Z", http.StatusOK, 2},
+		{"2024-05-01T16:00:00Z", http.StatusGone, 0},
+		{"not-a-time", http.StatusBadRequest, 0},
+	}
+
+	for _, testCase := range testCases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/"+historyPatientID+"/$snapshot?_at="+testCase.at, nil))
+
+		if recorder.Code != testCase.expectedStatus {
+			t.Fatalf("_at=%s: expected status %d, got %d: %s", testCase.at, testCase.expectedStatus, recorder.Code, recorder.Body.String())
+		}
+		if testCase.expectedStatus != http.StatusOK {
+			continue
+		}
+		var bundle fhir.Bundle
+		json.NewDecoder(recorder.Body).Decode(&bundle)
+		if bundle.Type != fhir.BundleTypeCollection || len(bundle.Entry) != testCase.expectedEntries {
+			t.Errorf("_at=%s: expected a collection with %d entries, got %s with %d", testCase.at, testCase.expectedEntries, bundle.Type.Code(), len(bundle.Entry))
+		}
+	}
+}
+
+// TestPatientHandler_GetByID_Deleted verifies reads of a deleted patient get 410 while unknown patients get 404
+func TestPatientHandler_GetByID_Deleted(t *testing.T) {
+	router := newHistoryTestRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/"+historyPatientID, nil))
+	if recorder.Code != http.StatusGone {
+		t.Errorf("Expected 410 for the deleted patient, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/7c1e2d3f-0000-4000-8000-000000000000", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a patient that never existed, got %d", recorder.Code)
+	}
+}
+
+// TestPatientHandler_History verifies the history Bundle lists every version newest first, the delete included
+func TestPatientHandler_History(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	newHistoryTestRouter().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/"+historyPatientID+"/_history", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var history fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&history)
+	if history.Type != fhir.BundleTypeHistory || *history.Total != 3 || len(history.Entry) != 3 {
+		t.Fatalf("Expected a history of 3 versions, got %s with %d entries", history.Type.Code(), len(history.Entry))
+	}
+	deleted := history.Entry[0]
+	if deleted.Request.Method != fhir.HTTPVerbDELETE || deleted.Resource != nil || *deleted.Response.Etag != `W/"3"` {
+		t.Errorf("Expected the delete first without a resource, got %+v", deleted)
+	}
+	var created fhir.Patient
+	json.Unmarshal(history.Entry[2].Resource, &created)
+	if history.Entry[2].Request.Method != fhir.HTTPVerbPOST || *created.Name[0].Family != "Smith" {
+		t.Errorf("Expected the create last with its snapshot, got %+v", history.Entry[2])
+	}
+}
+
+// TestPatientHandler_VRead verifies version reads and the 410 for the delete's version
+func TestPatientHandler_VRead(t *testing.T) {
+	router := newHistoryTestRouter()
+
+	testCases := []struct {
+		version        string
+		expectedStatus int
+	}{
+		{"2", http.StatusOK},
+		{"3", http.StatusGone},
+		{"9", http.StatusNotFound},
+		{"latest", http.StatusBadRequest},
+	}
+	for _, testCase := range testCases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/fhir/Patient/"+historyPatientID+"/_history/"+testCase.version, nil))
+		if recorder.Code != testCase.expectedStatus {
+			t.Errorf("version %s: expected %d, got %d", testCase.version, testCase.expectedStatus, recorder.Code)
+		}
+	}
+}
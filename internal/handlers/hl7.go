@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/adt"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/hl7"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+)
+
+// maxHL7MessageBytes caps the size of an inbound HL7 v2 message
+const maxHL7MessageBytes = 1 << 20
+
+// acknowledgementStatuses maps ACK codes to HTTP statuses, so HTTP senders that only look at the status
+// retry AE and give up on AR, as this server's delivery queue does for webhooks
+var acknowledgementStatuses = map[string]int{
+	hl7.AcknowledgementAccept: http.StatusOK,
+	hl7.AcknowledgementReject: http.StatusBadRequest,
+	hl7.AcknowledgementError:  http.StatusInternalServerError,
+}
+
+// HL7Handler receives HL7 v2 messages over HTTP
+type HL7Handler struct {
+	ingester *hl7.Ingester
+}
+
+// NewHL7Handler creates a new instance of HL7Handler
+func NewHL7Handler(ingester *hl7.Ingester) *HL7Handler {
+	return &HL7Handler{
+		ingester: ingester,
+	}
+}
+
+// Receive handles POST /hl7/v2 - ingests an ER7-encoded ADT A01, A04, or A08 or ORU R01 and returns the ACK
+func (handler *HL7Handler) Receive(w http.ResponseWriter, r *http.Request) {
+	rawMessage, readError := io.ReadAll(http.MaxBytesReader(w, r.Body, maxHL7MessageBytes))
+	if readError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Unreadable HL7 v2 message"))
+		return
+	}
+
+	result := handler.ingester.Ingest(r.Context(), rawMessage)
+
+	w.Header().Set("Content-Type", adt.ContentType)
+	w.WriteHeader(acknowledgementStatuses[result.Code])
+	w.Write(result.Acknowledgement)
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/adt"
+	"github.com/nathannewyen/fhir-health-interop/internal/hl7"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// postHL7 sends an ER7 message to the HL7 handler
+func postHL7(handler *HL7Handler, message string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/hl7/v2", strings.NewReader(message))
+	request.Header.Set("Content-Type", adt.ContentType)
+	recorder := httptest.NewRecorder()
+	handler.Receive(recorder, request)
+	return recorder
+}
+
+// TestHL7Handler_UpsertsPatientAndAcknowledges verifies an A04 creates a patient, an A08 updates it, and rejections are 400
+func TestHL7Handler_UpsertsPatientAndAcknowledges(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	handler := NewHL7Handler(hl7.NewIngester(service.NewPatientService(patientRepository)))
+
+	registerRecorder := postHL7(handler, "MSH|^~\\&|REG|EAST|FHIRHUB|MAIN|20261001083015||ADT^A04^ADT_A01|MSG1|P|2.5.1\r"+
+		"PID|1||MRN001^^^MAIN^MR||Doe^Jane||19900115|F\r")
+	if registerRecorder.Code != http.StatusOK || !strings.Contains(registerRecorder.Body.String(), "MSA|AA|MSG1") {
+		t.Fatalf("Expected status 200 with AA, got %d: %q", registerRecorder.Code, registerRecorder.Body.String())
+	}
+	if contentType := registerRecorder.Header().Get("Content-Type"); contentType != adt.ContentType {
+		t.Errorf("Expected an ER7 acknowledgement, got %s", contentType)
+	}
+	if len(patientRepository.patients) != 1 {
+		t.Fatalf("Expected one stored patient, got %d", len(patientRepository.patients))
+	}
+
+	updateRecorder := postHL7(handler, "MSH|^~\\&|REG|EAST|FHIRHUB|MAIN|20261002083015||ADT^A08^ADT_A01|MSG2|P|2.5.1\n"+
+		"PID|1||MRN001^^^MAIN^MR||Doe-Smith^Jane\n")
+	if updateRecorder.Code != http.StatusOK || !strings.Contains(updateRecorder.Body.String(), "MSA|AA|MSG2") {
+		t.Fatalf("Expected status 200 with AA, got %d: %q", updateRecorder.Code, updateRecorder.Body.String())
+	}
+	for _, storedPatient := range patientRepository.patients {
+		if storedPatient.FamilyName != "Doe-Smith" || storedPatient.IdentifierValue != "MRN001" || storedPatient.BirthDate == nil || !storedPatient.Active {
+			t.Errorf("Expected the updated active patient with its birth date kept, got %+v", storedPatient)
+		}
+	}
+	if len(patientRepository.patients) != 1 {
+		t.Errorf("Expected the update to keep one patient, got %d", len(patientRepository.patients))
+	}
+
+	rejectRecorder := postHL7(handler, "MSH|^~\\&|LAB|EAST|FHIRHUB|MAIN|20261002083015||ORU^R01^ORU_R01|MSG3|P|2.5.1\r")
+	if rejectRecorder.Code != http.StatusBadRequest || !strings.Contains(rejectRecorder.Body.String(), "MSA|AR|MSG3") {
+		t.Errorf("Expected status 400 with AR, got %d: %q", rejectRecorder.Code, rejectRecorder.Body.String())
+	}
+}
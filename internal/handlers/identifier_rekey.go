@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// maxRekeyMappingBytes caps the size of an uploaded mapping file
+const maxRekeyMappingBytes = 32 << 20
+
+// dataOperationSystem is the code system for Provenance.activity
+const dataOperationSystem = "http://terminology.hl7.org/CodeSystem/v3-DataOperation"
+
+// RekeyAuditResponse is a re-keying job with its audit history
+type RekeyAuditResponse struct {
+	Job     *models.RekeyJob      `json:"job"`
+	Records []*models.RekeyRecord `json:"records"`
+}
+
+// IdentifierRekeyHandler serves the admin endpoints for bulk patient identifier re-keying
+type IdentifierRekeyHandler struct {
+	rekeyService *service.IdentifierRekeyService
+}
+
+// NewIdentifierRekeyHandler creates a new instance of IdentifierRekeyHandler
+func NewIdentifierRekeyHandler(rekeyService *service.IdentifierRekeyService) *IdentifierRekeyHandler {
+	return &IdentifierRekeyHandler{
+		rekeyService: rekeyService,
+	}
+}
+
+// Start handles POST /admin/identifier-rekeys?reason={reason} - queues a job from a CSV mapping file (identity-admin role only)
+func (handler *IdentifierRekeyHandler) Start(w http.ResponseWriter, r *http.Request) {
+	mappingFile := http.MaxBytesReader(w, r.Body, maxRekeyMappingBytes)
+	job, startError := handler.rekeyService.StartJob(r.Context(), mappingFile, r.URL.Query().Get("reason"))
+	if startError != nil {
+		middleware.WriteError(w, r, startError)
+		return
+	}
+
+	w.Header().Set("Location", "/admin/identifier-rekeys/"+strconv.FormatInt(job.ID, 10))
+	writeRekeyJob(w, http.StatusAccepted, job)
+}
+
+// Status handles GET /admin/identifier-rekeys/{id} - reports the job's progress
+func (handler *IdentifierRekeyHandler) Status(w http.ResponseWriter, r *http.Request) {
+	jobID, parsed := parseRekeyJobID(w, r)
+	if !parsed {
+		return
+	}
+
+	job, getError := handler.rekeyService.GetJob(r.Context(), jobID)
+	if getError != nil {
+		middleware.WriteError(w, r, getError)
+		return
+	}
+
+	writeRekeyJob(w, http.StatusOK, job)
+}
+
+// Audit handles GET /admin/identifier-rekeys/{id}/audit - lists every rewrite, skip, and rollback of the job
+func (handler *IdentifierRekeyHandler) Audit(w http.ResponseWriter, r *http.Request) {
+	jobID, parsed := parseRekeyJobID(w, r)
+	if !parsed {
+		return
+	}
+
+	job, records, listError := handler.rekeyService.ListRecords(r.Context(), jobID)
+	if listError != nil {
+		middleware.WriteError(w, r, listError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(RekeyAuditResponse{Job: job, Records: records})
+}
+
+// Provenance handles GET /admin/identifier-rekeys/{id}/provenance - returns a Provenance resource for every identifier the job changed
+func (handler *IdentifierRekeyHandler) Provenance(w http.ResponseWriter, r *http.Request) {
+	jobID, parsed := parseRekeyJobID(w, r)
+	if !parsed {
+		return
+	}
+
+	job, records, listError := handler.rekeyService.ListRecords(r.Context(), jobID)
+	if listError != nil {
+		middleware.WriteError(w, r, listError)
+		return
+	}
+
+	entries := []fhir.BundleEntry{}
+	for _, record := range records {
+		if record.Action != models.RekeyActionRekeyed && record.Action != models.RekeyActionRolledBack {
+			continue
+		}
+		encodedProvenance, encodeError := json.Marshal(rekeyProvenance(job, record))
+		if encodeError != nil {
+			middleware.WriteError(w, r, apperrors.Internal("Failed to encode provenance", encodeError))
+			return
+		}
+		entries = append(entries, fhir.BundleEntry{Resource: encodedProvenance})
+	}
+
+	total := len(entries)
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(fhir.Bundle{
+		Type:  fhir.BundleTypeCollection,
+		Total: &total,
+		Entry: entries,
+	})
+}
+
+// Rollback handles POST /admin/identifier-rekeys/{id}/rollback - stops the job and restores the old identifiers (identity-admin role only)
+func (handler *IdentifierRekeyHandler) Rollback(w http.ResponseWriter, r *http.Request) {
+	jobID, parsed := parseRekeyJobID(w, r)
+	if !parsed {
+		return
+	}
+
+	job, rollbackError := handler.rekeyService.Rollback(r.Context(), jobID)
+	if rollbackError != nil {
+		middleware.WriteError(w, r, rollbackError)
+		return
+	}
+
+	writeRekeyJob(w, http.StatusAccepted, job)
+}
+
+// parseRekeyJobID reads the job ID from the URL, responding 404 when it is not a number
+func parseRekeyJobID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	rawJobID := chi.URLParam(r, "id")
+	jobID, parseError := strconv.ParseInt(rawJobID, 10, 64)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound("RekeyJob", rawJobID))
+		return 0, false
+	}
+	return jobID, true
+}
+
+// writeRekeyJob responds with a job as JSON
+func writeRekeyJob(w http.ResponseWriter, status int, job *models.RekeyJob) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(job)
+}
+
+// rekeyProvenance describes one identifier change as a Provenance targeting the patient
+// The entity is the identifier the patient had before the change
+func rekeyProvenance(job *models.RekeyJob, record *models.RekeyRecord) fhir.Provenance {
+	provenanceID := "rekey-" + strconv.FormatInt(record.ID, 10)
+	patientReference := "Patient/" + record.PatientID
+	activitySystem := dataOperationSystem
+	activityCode := "UPDATE"
+	activityText := "identifier " + string(record.Action)
+	actor := job.Actor
+	previousDisplay := record.OldSystem + "|" + record.OldValue
+	if record.Action == models.RekeyActionRolledBack {
+		actor = job.RollbackActor
+		previousDisplay = record.NewSystem + "|" + record.NewValue
+	}
+	reason := job.Reason
+
+	return fhir.Provenance{
+		Id:       &provenanceID,
+		Target:   []fhir.Reference{{Reference: &patientReference}},
+		Recorded: record.OccurredAt.UTC().Format(time.RFC3339),
+		Reason:   []fhir.CodeableConcept{{Text: &reason}},
+		Activity: &fhir.CodeableConcept{
+			Coding: []fhir.Coding{{System: &activitySystem, Code: &activityCode}},
+			Text:   &activityText,
+		},
+		Agent: []fhir.ProvenanceAgent{{Who: fhir.Reference{Display: &actor}}},
+		Entity: []fhir.ProvenanceEntity{{
+			Role: fhir.ProvenanceEntityRoleRevision,
+			What: fhir.Reference{Display: &previousDisplay},
+		}},
+	}
+}
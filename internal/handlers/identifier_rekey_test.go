@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// StubRekeyRepository implements IdentifierRekeyRepository with one completed job and its records
+type StubRekeyRepository struct {
+	job     *models.RekeyJob
+	records []*models.RekeyRecord
+	created []models.IdentifierMapping
+}
+
+// CreateJob remembers the mappings and returns a new running job
+func (stub *StubRekeyRepository) CreateJob(ctx context.Context, job *models.RekeyJob, mappings []models.IdentifierMapping) (*models.RekeyJob, error) {
+	stub.created = mappings
+	job.ID = 7
+	job.Status = models.RekeyJobStatusRunning
+	job.Total = len(mappings)
+	return job, nil
+}
+
+// GetJob returns the stored job
+func (stub *StubRekeyRepository) GetJob(ctx context.Context, jobID int64) (*models.RekeyJob, error) {
+	if jobID != stub.job.ID {
+		return nil, sql.ErrNoRows
+	}
+	return stub.job, nil
+}
+
+// NextActiveJob reports no active job
+func (stub *StubRekeyRepository) NextActiveJob(ctx context.Context) (*models.RekeyJob, error) {
+	return nil, sql.ErrNoRows
+}
+
+// StartRollback switches the stored job to rolling back
+func (stub *StubRekeyRepository) StartRollback(ctx context.Context, jobID int64, actor string) error {
+	stub.job.Status = models.RekeyJobStatusRollingBack
+	stub.job.RollbackActor = actor
+	return nil
+}
+
+// ApplyBatch does nothing
+func (stub *StubRekeyRepository) ApplyBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	return nil, nil
+}
+
+// RollbackBatch does nothing
+func (stub *StubRekeyRepository) RollbackBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	return nil, nil
+}
+
+// ListRecords returns the stored records
+func (stub *StubRekeyRepository) ListRecords(ctx context.Context, jobID int64) ([]*models.RekeyRecord, error) {
+	return stub.records, nil
+}
+
+// newRekeyTestRouter routes the re-keying endpoints over a completed job with one rewrite and one skip
+func newRekeyTestRouter() (*chi.Mux, *StubRekeyRepository) {
+	occurredAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stub := &StubRekeyRepository{
+		job: &models.RekeyJob{ID: 1, Status: models.RekeyJobStatusCompleted, Reason: "MRN migration", Actor: "api-key:records"},
+		records: []*models.RekeyRecord{
+			{ID: 10, JobID: 1, PatientID: "patient-1", Action: models.RekeyActionRekeyed, OldSystem: "urn:old", OldValue: "A1", NewSystem: "urn:new", NewValue: "B1", Actor: "api-key:records", OccurredAt: occurredAt},
+			{ID: 11, JobID: 1, Action: models.RekeyActionSkipped, OldSystem: "urn:old", OldValue: "A9", NewSystem: "urn:new", NewValue: "B9", Detail: "line 3: no patient has the old identifier", Actor: "api-key:records", OccurredAt: occurredAt},
+		},
+	}
+	handler := NewIdentifierRekeyHandler(service.NewIdentifierRekeyService(stub, service.NewPatientService(NewMockPatientRepository()), service.DefaultRekeyPolicy()))
+
+	router := chi.NewRouter()
+	router.Post("/admin/identifier-rekeys", handler.Start)
+	router.Get("/admin/identifier-rekeys/{id}", handler.Status)
+	router.Get("/admin/identifier-rekeys/{id}/provenance", handler.Provenance)
+	router.Post("/admin/identifier-rekeys/{id}/rollback", handler.Rollback)
+	return router, stub
+}
+
+// withRoles attaches a principal with the given roles to the request
+func withRoles(request *http.Request, roles ...string) *http.Request {
+	return request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{ID: "api-key:records", Roles: roles}))
+}
+
+// TestIdentifierRekeyHandler_Start verifies jobs are accepted only from the identity-admin role
+func TestIdentifierRekeyHandler_Start(t *testing.T) {
+	router, stub := newRekeyTestRouter()
+	mappingFile := "old_system,old_value,new_system,new_value\nurn:old,A1,urn:new,B1\n"
+
+	testCases := []struct {
+		name           string
+		target         string
+		body           string
+		roles          []string
+		expectedStatus int
+	}{
+		{"no role", "/admin/identifier-rekeys?reason=MRN+migration", mappingFile, nil, http.StatusForbidden},
+		{"missing reason", "/admin/identifier-rekeys", mappingFile, []string{auth.RoleIdentityAdmin}, http.StatusBadRequest},
+		{"invalid mapping", "/admin/identifier-rekeys?reason=MRN+migration", "old,new\nA1,B1\n", []string{auth.RoleIdentityAdmin}, http.StatusBadRequest},
+		{"accepted", "/admin/identifier-rekeys?reason=MRN+migration", mappingFile, []string{auth.RoleIdentityAdmin}, http.StatusAccepted},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, withRoles(httptest.NewRequest(http.MethodPost, testCase.target, strings.NewReader(testCase.body)), testCase.roles...))
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+
+	if len(stub.created) != 1 {
+		t.Fatalf("Expected one mapping stored, got %d", len(stub.created))
+	}
+}
+
+// TestIdentifierRekeyHandler_Provenance verifies a Provenance is returned for each rewrite but not for skips
+func TestIdentifierRekeyHandler_Provenance(t *testing.T) {
+	router, _ := newRekeyTestRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/identifier-rekeys/1/provenance", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	var bundle fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&bundle)
+	if len(bundle.Entry) != 1 {
+		t.Fatalf("Expected one Provenance, got %d", len(bundle.Entry))
+	}
+	var provenance fhir.Provenance
+	json.Unmarshal(bundle.Entry[0].Resource, &provenance)
+	if *provenance.Target[0].Reference != "Patient/patient-1" || *provenance.Entity[0].What.Display != "urn:old|A1" {
+		t.Errorf("Unexpected provenance target %s and entity %s", *provenance.Target[0].Reference, *provenance.Entity[0].What.Display)
+	}
+	if *provenance.Agent[0].Who.Display != "api-key:records" || *provenance.Reason[0].Text != "MRN migration" {
+		t.Errorf("Expected agent and reason from the job, got %+v", provenance)
+	}
+
+	for _, target := range []string{"/admin/identifier-rekeys/abc/provenance", "/admin/identifier-rekeys/2/provenance"} {
+		notFoundRecorder := httptest.NewRecorder()
+		router.ServeHTTP(notFoundRecorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if notFoundRecorder.Code != http.StatusNotFound {
+			t.Errorf("Expected 404 for %s, got %d", target, notFoundRecorder.Code)
+		}
+	}
+}
+
+// TestIdentifierRekeyHandler_Rollback verifies a completed job can be rolled back once
+func TestIdentifierRekeyHandler_Rollback(t *testing.T) {
+	router, stub := newRekeyTestRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, withRoles(httptest.NewRequest(http.MethodPost, "/admin/identifier-rekeys/1/rollback", nil), auth.RoleIdentityAdmin))
+	if recorder.Code != http.StatusAccepted || stub.job.Status != models.RekeyJobStatusRollingBack {
+		t.Fatalf("Expected 202 and a rolling back job, got %d with %s", recorder.Code, stub.job.Status)
+	}
+
+	againRecorder := httptest.NewRecorder()
+	router.ServeHTTP(againRecorder, withRoles(httptest.NewRequest(http.MethodPost, "/admin/identifier-rekeys/1/rollback", nil), auth.RoleIdentityAdmin))
+	if againRecorder.Code != http.StatusConflict {
+		t.Errorf("Expected 409 rolling back twice, got %d", againRecorder.Code)
+	}
+}
@@ -0,0 +1,216 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// ImmunizationHandler handles Immunization FHIR resource requests
+type ImmunizationHandler struct {
+	immunizationService *service.ImmunizationService
+	idCodec             idcodec.Codec
+	residencyPolicy     *residency.Policy
+}
+
+// NewImmunizationHandler creates an ImmunizationHandler backed by the immunization service
+func NewImmunizationHandler(immunizationService *service.ImmunizationService) *ImmunizationHandler {
+	return &ImmunizationHandler{
+		immunizationService: immunizationService,
+	}
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *ImmunizationHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetResidencyPolicy refuses patient references to patients held in another region
+func (handler *ImmunizationHandler) SetResidencyPolicy(policy *residency.Policy) {
+	handler.residencyPolicy = policy
+}
+
+// exposeImmunization rewrites an immunization's ID and patient reference to their exposed forms
+func exposeImmunization(ctx context.Context, codec idcodec.Codec, fhirImmunization *fhir.Immunization) {
+	exposeID(ctx, codec, "Immunization", fhirImmunization.Id)
+	exposeReference(ctx, codec, fhirImmunization.Patient.Reference)
+}
+
+// resolvePatient rewrites an exposed patient reference to the stored patient ID, writing a 400 when it is
+// unknown and a 403 when it points to another region
+func (handler *ImmunizationHandler) resolvePatient(w http.ResponseWriter, r *http.Request, fhirImmunization *fhir.Immunization) bool {
+	if handler.residencyPolicy != nil && fhirImmunization.Patient.Reference != nil {
+		if residencyError := handler.residencyPolicy.CheckReference(*fhirImmunization.Patient.Reference); residencyError != nil {
+			middleware.WriteError(w, r, residencyError)
+			return false
+		}
+	}
+	if resolveError := resolveReference(r.Context(), handler.idCodec, fhirImmunization.Patient.Reference); resolveError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("patient", "Unknown patient reference"))
+		return false
+	}
+	return true
+}
+
+// Create handles POST /fhir/Immunization - creates a new immunization
+func (handler *ImmunizationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirImmunization fhir.Immunization
+	if decodeError := encoding.Decode(r, &fhirImmunization); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Immunization JSON"))
+		return
+	}
+
+	// Translate an exposed patient reference back to the stored ID
+	if !handler.resolvePatient(w, r, &fhirImmunization) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdImmunization, createError := handler.immunizationService.CreateImmunization(issueContext, &fhirImmunization)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create immunization")
+		return
+	}
+	exposeImmunization(r.Context(), handler.idCodec, createdImmunization)
+
+	writeWriteResult(w, r, http.StatusCreated, createdImmunization, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/Immunization/{id} - retrieves an immunization by ID
+func (handler *ImmunizationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	immunizationID := chi.URLParam(r, "id")
+	internalImmunizationID, resolved := resolveID(w, r, handler.idCodec, "Immunization", immunizationID)
+	if !resolved {
+		return
+	}
+
+	fhirImmunization, getError := handler.immunizationService.GetImmunizationByID(r.Context(), internalImmunizationID)
+	if errors.Is(getError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Immunization", immunizationID))
+		return
+	}
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Immunization", immunizationID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read immunization", getError))
+		return
+	}
+	exposeImmunization(r.Context(), handler.idCodec, fhirImmunization)
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("Immunization", fhirImmunization, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/Immunization - searches immunizations by patient, vaccine code, status, lot number, or date
+func (handler *ImmunizationHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseImmunizationSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	if searchParams.PatientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", searchParams.PatientID)
+		if !resolved {
+			return
+		}
+		searchParams.PatientID = internalPatientID
+	}
+
+	fhirImmunizations, searchError := handler.immunizationService.SearchImmunizations(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search immunizations", searchError))
+		return
+	}
+	total, countError := handler.immunizationService.CountImmunizations(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count immunizations", countError))
+		return
+	}
+	for _, fhirImmunization := range fhirImmunizations {
+		exposeImmunization(r.Context(), handler.idCodec, fhirImmunization)
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "Immunization", fhirImmunizations, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/Immunization/{id} - updates an existing immunization
+func (handler *ImmunizationHandler) Update(w http.ResponseWriter, r *http.Request) {
+	immunizationID := chi.URLParam(r, "id")
+
+	var fhirImmunization fhir.Immunization
+	if decodeError := encoding.Decode(r, &fhirImmunization); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Immunization JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirImmunization.Id != nil && *fhirImmunization.Id != immunizationID {
+		middleware.WriteError(w, r, apperrors.ValidationError("Immunization ID in URL does not match ID in body"))
+		return
+	}
+
+	internalImmunizationID, resolved := resolveID(w, r, handler.idCodec, "Immunization", immunizationID)
+	if !resolved {
+		return
+	}
+	if fhirImmunization.Id != nil {
+		fhirImmunization.Id = &internalImmunizationID
+	}
+	if !handler.resolvePatient(w, r, &fhirImmunization) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedImmunization, updateError := handler.immunizationService.UpdateImmunization(issueContext, internalImmunizationID, &fhirImmunization)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Immunization", immunizationID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Immunization", immunizationID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update immunization")
+		return
+	}
+	exposeImmunization(r.Context(), handler.idCodec, updatedImmunization)
+
+	writeWriteResult(w, r, http.StatusOK, updatedImmunization, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/Immunization/{id} - deletes an immunization
+func (handler *ImmunizationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	immunizationID := chi.URLParam(r, "id")
+	internalImmunizationID, resolved := resolveID(w, r, handler.idCodec, "Immunization", immunizationID)
+	if !resolved {
+		return
+	}
+
+	if deleteError := handler.immunizationService.DeleteImmunization(r.Context(), internalImmunizationID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "Immunization", immunizationID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockImmunizationRepository implements ImmunizationRepository interface for testing
+type MockImmunizationRepository struct {
+	immunizations map[string]*models.Immunization
+	deleted       map[string]bool
+	lastSearch    *models.ImmunizationSearchParams
+}
+
+// NewMockImmunizationRepository creates a new mock repository for testing
+func NewMockImmunizationRepository() *MockImmunizationRepository {
+	return &MockImmunizationRepository{immunizations: make(map[string]*models.Immunization), deleted: make(map[string]bool)}
+}
+
+// Create stores an immunization under a generated UUID
+func (mock *MockImmunizationRepository) Create(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error) {
+	immunization.ID = uuid.NewString()
+	mock.immunizations[immunization.ID] = immunization
+	return immunization, nil
+}
+
+// GetByID retrieves a stored immunization
+func (mock *MockImmunizationRepository) GetByID(ctx context.Context, immunizationID string) (*models.Immunization, error) {
+	if mock.deleted[immunizationID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	immunization, exists := mock.immunizations[immunizationID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return immunization, nil
+}
+
+// Search returns every stored immunization and remembers the criteria
+func (mock *MockImmunizationRepository) Search(ctx context.Context, searchParams *models.ImmunizationSearchParams) ([]*models.Immunization, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.Immunization, 0, len(mock.immunizations))
+	for _, immunization := range mock.immunizations {
+		result = append(result, immunization)
+	}
+	return result, nil
+}
+
+// Count returns how many immunizations are stored, as every search matches them all
+func (mock *MockImmunizationRepository) Count(ctx context.Context, searchParams *models.ImmunizationSearchParams) (int, error) {
+	return len(mock.immunizations), nil
+}
+
+// Update replaces a stored immunization
+func (mock *MockImmunizationRepository) Update(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error) {
+	if mock.deleted[immunization.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.immunizations[immunization.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.immunizations[immunization.ID] = immunization
+	return immunization, nil
+}
+
+// Delete marks a stored immunization deleted, as the repository does
+func (mock *MockImmunizationRepository) Delete(ctx context.Context, immunizationID string) error {
+	if mock.deleted[immunizationID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.immunizations[immunizationID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.immunizations, immunizationID)
+	mock.deleted[immunizationID] = true
+	return nil
+}
+
+// newImmunizationRouter registers the Immunization routes as cmd/server does
+func newImmunizationRouter(handler *ImmunizationHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/Immunization", handler.Create)
+	router.Get("/fhir/Immunization/{id}", handler.GetByID)
+	router.Get("/fhir/Immunization", handler.GetAll)
+	router.Put("/fhir/Immunization/{id}", handler.Update)
+	router.Delete("/fhir/Immunization/{id}", handler.Delete)
+	return router
+}
+
+// TestImmunizationRoutes verifies create, read, search, update, and delete, that deleted requests are 410, and that unknown ones are 404
+func TestImmunizationRoutes(t *testing.T) {
+	immunizationRepository := NewMockImmunizationRepository()
+	router := newImmunizationRouter(NewImmunizationHandler(service.NewImmunizationService(immunizationRepository)))
+	patientID := uuid.NewString()
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/Immunization", `{"resourceType":"Immunization","status":"completed",`+
+		`"vaccineCode":{"coding":[{"system":"http://hl7.org/fhir/sid/cvx","code":"208"}]},"patient":{"reference":"Patient/`+patientID+`"},`+
+		`"occurrenceDateTime":"2024-10-02T14:30:00Z","lotNumber":"AAJN11K"}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the immunization, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdImmunization fhir.Immunization
+	json.NewDecoder(createRecorder.Body).Decode(&createdImmunization)
+	immunizationID := *createdImmunization.Id
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/Immunization/"+immunizationID, "")
+	var readImmunization fhir.Immunization
+	json.NewDecoder(readRecorder.Body).Decode(&readImmunization)
+	if readRecorder.Code != http.StatusOK || readImmunization.Status != fhir.ImmunizationStatusCodesCompleted || *readImmunization.Patient.Reference != "Patient/"+patientID {
+		t.Errorf("Expected the completed immunization for the patient, got %d: %+v", readRecorder.Code, readImmunization)
+	}
+	if readImmunization.LotNumber == nil || *readImmunization.LotNumber != "AAJN11K" || readImmunization.OccurrenceDateTime != "2024-10-02T14:30:00Z" {
+		t.Errorf("Expected the lot number and occurrence, got %+v", readImmunization)
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/Immunization?patient=Patient/"+patientID+"&vaccine-code=208&status=completed&lot-number=AAJN11K&date=ge2024-01-01", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 1 || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one immunization, got %s", searchRecorder.Body.String())
+	}
+	lastSearch := immunizationRepository.lastSearch
+	if lastSearch.PatientID != patientID || lastSearch.VaccineCode == nil || lastSearch.VaccineCode.Code != "208" || lastSearch.Status != "completed" || lastSearch.LotNumber != "AAJN11K" || lastSearch.DateGreaterThan == nil {
+		t.Errorf("Expected the patient, vaccine code, status, lot number, and date passed to the repository, got %+v", lastSearch)
+	}
+
+	if invalidRecorder := serveCRUD(router, http.MethodPut, "/fhir/Immunization/"+immunizationID, `{"resourceType":"Immunization","status":"in-progress"}`); invalidRecorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an unknown status, got %d", invalidRecorder.Code)
+	}
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/Immunization/"+immunizationID, `{"resourceType":"Immunization","id":"`+immunizationID+`","status":"entered-in-error"}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the immunization, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/Immunization/"+immunizationID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the immunization, got %d", deleteRecorder.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveCRUD(router, method, "/fhir/Immunization/"+immunizationID, ""); recorder.Code != http.StatusGone {
+			t.Errorf("Expected status 410 for %s of a deleted immunization, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/Immunization/"+uuid.NewString(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 reading an unknown immunization, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/jobs"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+const (
+	// defaultJobPageSize is the number of jobs returned when _count is omitted
+	defaultJobPageSize = 50
+
+	// maxJobPageSize caps the number of jobs returned per request
+	maxJobPageSize = 500
+)
+
+// JobListResponse is a page of background jobs
+type JobListResponse struct {
+	Jobs []*models.Job `json:"jobs"`
+}
+
+// JobHandler exposes the background job queue to administrators
+type JobHandler struct {
+	queue *jobs.Queue
+}
+
+// NewJobHandler creates a new instance of JobHandler
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{
+		queue: queue,
+	}
+}
+
+// List handles GET /admin/jobs?status={status}&kind={kind}&_count={n}&_offset={n} - lists jobs, newest first
+func (handler *JobHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter := models.JobFilter{
+		Status: models.JobStatus(r.URL.Query().Get("status")),
+		Kind:   r.URL.Query().Get("kind"),
+	}
+	switch filter.Status {
+	case "", models.JobStatusQueued, models.JobStatusRunning, models.JobStatusSucceeded, models.JobStatusFailed, models.JobStatusCancelled:
+	default:
+		middleware.WriteError(w, r, apperrors.InvalidInput("status", "must be one of queued, running, succeeded, failed, cancelled"))
+		return
+	}
+
+	pageSize := defaultJobPageSize
+	if countParam := r.URL.Query().Get("_count"); countParam != "" {
+		parsedCount, parseError := strconv.Atoi(countParam)
+		if parseError != nil || parsedCount < 1 || parsedCount > maxJobPageSize {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_count", "must be between 1 and "+strconv.Itoa(maxJobPageSize)))
+			return
+		}
+		pageSize = parsedCount
+	}
+
+	offset := 0
+	if offsetParam := r.URL.Query().Get("_offset"); offsetParam != "" {
+		parsedOffset, parseError := strconv.Atoi(offsetParam)
+		if parseError != nil || parsedOffset < 0 {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_offset", "must be a non-negative integer"))
+			return
+		}
+		offset = parsedOffset
+	}
+
+	listed, listError := handler.queue.List(r.Context(), filter, pageSize, offset)
+	if listError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to list jobs", listError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(JobListResponse{Jobs: listed})
+}
+
+// Get handles GET /admin/jobs/{id} - returns a job's status, progress, and last error
+func (handler *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	jobID := chi.URLParam(r, "id")
+	job, getError := handler.queue.Get(r.Context(), jobID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		middleware.WriteError(w, r, apperrors.NotFound("Job", jobID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read job", getError))
+		return
+	}
+
+	writeJob(w, http.StatusOK, job)
+}
+
+// Cancel handles POST /admin/jobs/{id}/cancel - cancels a queued or running job (operator or admin role);
+// a running job stops at its next heartbeat
+func (handler *JobHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).HasAnyRole(auth.RoleOperator, auth.RoleAdmin) {
+		middleware.WriteError(w, r, apperrors.Forbidden("Jobs can only be cancelled by the operator or admin role"))
+		return
+	}
+
+	jobID := chi.URLParam(r, "id")
+	job, getError := handler.queue.Get(r.Context(), jobID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		middleware.WriteError(w, r, apperrors.NotFound("Job", jobID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read job", getError))
+		return
+	}
+
+	cancelError := handler.queue.Cancel(r.Context(), jobID)
+	if errors.Is(cancelError, sql.ErrNoRows) {
+		middleware.WriteError(w, r, apperrors.Conflict("Job", "the job already finished"))
+		return
+	}
+	if cancelError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to cancel job", cancelError))
+		return
+	}
+
+	if cancelled, getError := handler.queue.Get(r.Context(), jobID); getError == nil {
+		job = cancelled
+	}
+	writeJob(w, http.StatusAccepted, job)
+}
+
+// writeJob writes a job as JSON with the given status
+func writeJob(w http.ResponseWriter, statusCode int, job *models.Job) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(job)
+}
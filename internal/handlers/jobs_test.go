@@ -0,0 +1,208 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/jobs"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// MockJobStore holds a fixed set of jobs
+type MockJobStore struct {
+	jobs       map[string]*models.Job
+	lastFilter models.JobFilter
+}
+
+// Enqueue is unused by the handler tests
+func (store *MockJobStore) Enqueue(ctx context.Context, job *models.Job) (*models.Job, error) {
+	return job, nil
+}
+
+// Get returns a stored job
+func (store *MockJobStore) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	job, exists := store.jobs[jobID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// List returns the jobs matching the filter
+func (store *MockJobStore) List(ctx context.Context, filter models.JobFilter, limit int, offset int) ([]*models.Job, error) {
+	store.lastFilter = filter
+	listed := []*models.Job{}
+	for _, job := range store.jobs {
+		if filter.Status == "" || job.Status == filter.Status {
+			listed = append(listed, job)
+		}
+	}
+	return listed, nil
+}
+
+// ClaimNext is unused by the handler tests
+func (store *MockJobStore) ClaimNext(ctx context.Context, kinds []string, now time.Time, staleBefore time.Time) (*models.Job, error) {
+	return nil, sql.ErrNoRows
+}
+
+// Heartbeat is unused by the handler tests
+func (store *MockJobStore) Heartbeat(ctx context.Context, jobID string, progress string) error {
+	return nil
+}
+
+// Succeed is unused by the handler tests
+func (store *MockJobStore) Succeed(ctx context.Context, jobID string, result []byte, expiresAt time.Time) error {
+	return nil
+}
+
+// Reschedule is unused by the handler tests
+func (store *MockJobStore) Reschedule(ctx context.Context, jobID string, runAfter time.Time, lastError string) error {
+	return nil
+}
+
+// Fail is unused by the handler tests
+func (store *MockJobStore) Fail(ctx context.Context, jobID string, lastError string, expiresAt time.Time) error {
+	return nil
+}
+
+// Cancel marks an unfinished job cancelled
+func (store *MockJobStore) Cancel(ctx context.Context, jobID string, expiresAt time.Time) error {
+	job, exists := store.jobs[jobID]
+	if !exists || job.Status.Finished() {
+		return sql.ErrNoRows
+	}
+	job.Status = models.JobStatusCancelled
+	return nil
+}
+
+// Discard is unused by the handler tests
+func (store *MockJobStore) Discard(ctx context.Context, jobID string) error {
+	return nil
+}
+
+// DeleteExpired is unused by the handler tests
+func (store *MockJobStore) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+const (
+	// runningJobID and failedJobID name the jobs held by newJobRouter's store
+	runningJobID = "0b7d4c1e-2f3a-4b5c-9d6e-7f8091a2b3c4"
+	failedJobID  = "1c8e5d2f-3a4b-4c6d-8e7f-8091a2b3c4d5"
+)
+
+// newJobRouter serves the admin job routes over a store holding a running and a failed job
+func newJobRouter() (*MockJobStore, *chi.Mux) {
+	store := &MockJobStore{jobs: map[string]*models.Job{
+		runningJobID: {ID: runningJobID, Kind: "async-request", Status: models.JobStatusRunning, Progress: "In progress", Payload: []byte("secret")},
+		failedJobID:  {ID: failedJobID, Kind: "async-request", Status: models.JobStatusFailed, LastError: "timeout"},
+	}}
+	handler := NewJobHandler(jobs.NewQueue(store, jobs.DefaultPolicy()))
+
+	router := chi.NewRouter()
+	router.Get("/admin/jobs", handler.List)
+	router.Get("/admin/jobs/{id}", handler.Get)
+	router.Post("/admin/jobs/{id}/cancel", handler.Cancel)
+	return store, router
+}
+
+func TestJobHandler_List(t *testing.T) {
+	store, router := newJobRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/jobs?status=failed&kind=async-request", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var response JobListResponse
+	json.Unmarshal(recorder.Body.Bytes(), &response)
+	if len(response.Jobs) != 1 || response.Jobs[0].LastError != "timeout" {
+		t.Errorf("Expected the failed job, got %+v", response.Jobs)
+	}
+	if store.lastFilter.Kind != "async-request" {
+		t.Errorf("Expected the kind filter to be passed on, got %+v", store.lastFilter)
+	}
+}
+
+func TestJobHandler_ListInvalidParameters(t *testing.T) {
+	_, router := newJobRouter()
+
+	for _, query := range []string{"status=done", "_count=0", "_count=501", "_offset=-1"} {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/jobs?"+query, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", query, recorder.Code)
+		}
+	}
+}
+
+func TestJobHandler_GetHidesPayload(t *testing.T) {
+	_, router := newJobRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/jobs/"+runningJobID, nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	var job map[string]any
+	json.Unmarshal(recorder.Body.Bytes(), &job)
+	if job["progress"] != "In progress" || job["payload"] != nil {
+		t.Errorf("Expected progress without the payload, got %v", job)
+	}
+
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/jobs/missing", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", recorder.Code)
+	}
+}
+
+func TestJobHandler_CancelRequiresOperator(t *testing.T) {
+	store, router := newJobRouter()
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/admin/jobs/"+runningJobID+"/cancel", nil))
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", recorder.Code)
+	}
+	if store.jobs[runningJobID].Status != models.JobStatusRunning {
+		t.Errorf("Expected the job to keep running, got %s", store.jobs[runningJobID].Status)
+	}
+}
+
+func TestJobHandler_Cancel(t *testing.T) {
+	store, router := newJobRouter()
+
+	testCases := []struct {
+		jobID    string
+		expected int
+	}{
+		{runningJobID, http.StatusAccepted},
+		{failedJobID, http.StatusConflict},
+		{"6f1c2d3e-4a5b-4c6d-8e9f-000000000000", http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodPost, "/admin/jobs/"+testCase.jobID+"/cancel", nil)
+		request = request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{ID: "api-key:ops", Roles: []string{auth.RoleOperator}}))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		if recorder.Code != testCase.expected {
+			t.Errorf("Cancel %s: expected status %d, got %d", testCase.jobID, testCase.expected, recorder.Code)
+		}
+	}
+	if store.jobs[runningJobID].Status != models.JobStatusCancelled {
+		t.Errorf("Expected the running job to be cancelled, got %s", store.jobs[runningJobID].Status)
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// LegalHoldRequest is the body for placing or releasing a legal hold
+type LegalHoldRequest struct {
+	// Reason is required and recorded in the audit history (case or matter reference)
+	Reason string `json:"reason"`
+}
+
+// LegalHoldHandler serves the compliance endpoints for patient legal holds
+type LegalHoldHandler struct {
+	holdService *service.LegalHoldService
+}
+
+// NewLegalHoldHandler creates a new instance of LegalHoldHandler
+func NewLegalHoldHandler(holdService *service.LegalHoldService) *LegalHoldHandler {
+	return &LegalHoldHandler{
+		holdService: holdService,
+	}
+}
+
+// Place handles PUT /admin/patients/{id}/legal-hold - places or updates a hold (compliance role only)
+func (handler *LegalHoldHandler) Place(w http.ResponseWriter, r *http.Request) {
+	var holdRequest LegalHoldRequest
+	if decodeError := json.NewDecoder(r.Body).Decode(&holdRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid legal hold JSON"))
+		return
+	}
+
+	hold, placeError := handler.holdService.PlaceHold(r.Context(), chi.URLParam(r, "id"), holdRequest.Reason)
+	if placeError != nil {
+		middleware.WriteError(w, r, placeError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(hold)
+}
+
+// Release handles DELETE /admin/patients/{id}/legal-hold - lifts a hold (compliance role only)
+func (handler *LegalHoldHandler) Release(w http.ResponseWriter, r *http.Request) {
+	var holdRequest LegalHoldRequest
+	if decodeError := json.NewDecoder(r.Body).Decode(&holdRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid legal hold JSON"))
+		return
+	}
+
+	releaseError := handler.holdService.ReleaseHold(r.Context(), chi.URLParam(r, "id"), holdRequest.Reason)
+	if releaseError != nil {
+		middleware.WriteError(w, r, releaseError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Status handles GET /admin/patients/{id}/legal-hold - reports the hold and its audit history (compliance role only)
+func (handler *LegalHoldHandler) Status(w http.ResponseWriter, r *http.Request) {
+	status, statusError := handler.holdService.GetStatus(r.Context(), chi.URLParam(r, "id"))
+	if statusError != nil {
+		middleware.WriteError(w, r, statusError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
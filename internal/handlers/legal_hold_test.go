@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// MockLegalHoldRepository implements LegalHoldRepository in memory for testing
+type MockLegalHoldRepository struct {
+	holds  map[string]*models.LegalHold
+	events []*models.LegalHoldEvent
+}
+
+// Place stores the hold
+func (mock *MockLegalHoldRepository) Place(ctx context.Context, hold *models.LegalHold) (*models.LegalHold, error) {
+	mock.holds[hold.PatientID] = hold
+	return hold, nil
+}
+
+// Release removes the hold
+func (mock *MockLegalHoldRepository) Release(ctx context.Context, patientID string, actor string, reason string) error {
+	if _, exists := mock.holds[patientID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.holds, patientID)
+	return nil
+}
+
+// Get returns the active hold
+func (mock *MockLegalHoldRepository) Get(ctx context.Context, patientID string) (*models.LegalHold, error) {
+	hold, exists := mock.holds[patientID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return hold, nil
+}
+
+// RecordEvent appends to the history
+func (mock *MockLegalHoldRepository) RecordEvent(ctx context.Context, event *models.LegalHoldEvent) error {
+	mock.events = append(mock.events, event)
+	return nil
+}
+
+// ListEvents returns the recorded history
+func (mock *MockLegalHoldRepository) ListEvents(ctx context.Context, patientID string) ([]*models.LegalHoldEvent, error) {
+	return mock.events, nil
+}
+
+// legalHoldRequest builds a request for the patient's legal hold with an optional principal
+func legalHoldRequest(method string, patientID string, body string, principal *auth.Principal) *http.Request {
+	request := httptest.NewRequest(method, "/admin/patients/"+patientID+"/legal-hold", bytes.NewBufferString(body))
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", patientID)
+	ctx := context.WithValue(request.Context(), chi.RouteCtxKey, routeContext)
+	if principal != nil {
+		ctx = auth.WithPrincipal(ctx, *principal)
+	}
+	return request.WithContext(ctx)
+}
+
+// TestLegalHoldHandler_PlaceBlocksDelete verifies the compliance flow and that held patients cannot be deleted
+func TestLegalHoldHandler_PlaceBlocksDelete(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1"}
+	holdService := service.NewLegalHoldService(&MockLegalHoldRepository{holds: make(map[string]*models.LegalHold)}, patientRepository)
+	holdHandler := NewLegalHoldHandler(holdService)
+
+	recorder := httptest.NewRecorder()
+	holdHandler.Place(recorder, legalHoldRequest(http.MethodPut, "patient-1", `{"reason":"Case 42"}`, nil))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 without compliance role, got %d", recorder.Code)
+	}
+
+	compliance := &auth.Principal{ID: "api-key:legal", Roles: []string{auth.RoleCompliance}}
+	recorder = httptest.NewRecorder()
+	holdHandler.Place(recorder, legalHoldRequest(http.MethodPut, "patient-1", `{"reason":"Case 42"}`, compliance))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	patientService := service.NewPatientService(patientRepository)
+	patientService.SetDeletionGuard(holdService)
+	patientHandler := NewPatientHandlerWithService(patientService)
+	recorder = httptest.NewRecorder()
+	patientHandler.Delete(recorder, legalHoldRequest(http.MethodDelete, "patient-1", "", nil))
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 deleting a held patient, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	holdHandler.Status(recorder, legalHoldRequest(http.MethodGet, "patient-1", "", nil))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 reading the hold without compliance role, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	holdHandler.Status(recorder, legalHoldRequest(http.MethodGet, "patient-1", "", compliance))
+	var status service.LegalHoldStatus
+	json.NewDecoder(recorder.Body).Decode(&status)
+	if !status.Held || len(status.History) != 1 || status.History[0].Action != models.LegalHoldActionDeletionBlocked {
+		t.Errorf("Expected held status with the blocked deletion, got %+v", status)
+	}
+
+	recorder = httptest.NewRecorder()
+	holdHandler.Release(recorder, legalHoldRequest(http.MethodDelete, "patient-1", `{"reason":"Case closed"}`, compliance))
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204, got %d", recorder.Code)
+	}
+}
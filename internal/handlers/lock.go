@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/locking"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// AcquireLockRequest is the body of a lock acquisition or renewal
+type AcquireLockRequest struct {
+	// Owner identifies the clerk or integration editing the record
+	Owner string `json:"owner"`
+
+	// TTLSeconds is the requested lock lifetime; defaults to 5 minutes, capped at 30
+	TTLSeconds int `json:"ttl_seconds"`
+}
+
+// LockHandler exposes advisory edit locks on patient records
+type LockHandler struct {
+	lockManager *locking.Manager
+	idCodec     idcodec.Codec
+}
+
+// NewLockHandler creates a new instance of LockHandler
+func NewLockHandler(lockManager *locking.Manager) *LockHandler {
+	return &LockHandler{
+		lockManager: lockManager,
+	}
+}
+
+// SetIDCodec accepts the opaque patient IDs issued by the codec; locks are keyed by the internal ID
+// so they match the checks made by the Patient write endpoints
+func (handler *LockHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// Acquire handles POST /locks/Patient/{id} - acquires or renews an edit lock
+func (handler *LockHandler) Acquire(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	var lockRequest AcquireLockRequest
+	if decodeError := json.NewDecoder(r.Body).Decode(&lockRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid lock request JSON"))
+		return
+	}
+	if lockRequest.TTLSeconds < 0 {
+		middleware.WriteError(w, r, apperrors.InvalidInput("ttl_seconds", "must not be negative"))
+		return
+	}
+
+	acquiredLock, acquireError := handler.lockManager.Acquire(r.Context(), "Patient", internalPatientID, lockRequest.Owner, time.Duration(lockRequest.TTLSeconds)*time.Second)
+	if acquireError != nil {
+		writeLockError(w, r, acquiredLock, acquireError)
+		return
+	}
+
+	writeLock(w, http.StatusOK, acquiredLock, patientID)
+}
+
+// Get handles GET /locks/Patient/{id} - returns the active lock
+func (handler *LockHandler) Get(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	activeLock, getError := handler.lockManager.Get(r.Context(), "Patient", internalPatientID)
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read lock", getError))
+		return
+	}
+	if activeLock == nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Lock for Patient", patientID))
+		return
+	}
+
+	writeLock(w, http.StatusOK, activeLock, patientID)
+}
+
+// Release handles DELETE /locks/Patient/{id} - releases the caller's lock
+// The caller identifies itself with X-Edit-Lock-Owner; ?force=true releases a lock held by anyone
+func (handler *LockHandler) Release(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+	owner := r.Header.Get(locking.HeaderLockOwner)
+
+	force := false
+	if forceValue := r.URL.Query().Get("force"); forceValue != "" {
+		parsedForce, parseError := strconv.ParseBool(forceValue)
+		if parseError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("force", "must be true or false"))
+			return
+		}
+		force = parsedForce
+	}
+
+	releasedLock, releaseError := handler.lockManager.Release(r.Context(), "Patient", internalPatientID, owner, force)
+	if releaseError != nil {
+		writeLockError(w, r, releasedLock, releaseError)
+		return
+	}
+
+	if releasedLock.Owner != owner {
+		log.Warn().
+			Str("patient_id", patientID).
+			Str("lock_owner", releasedLock.Owner).
+			Str("released_by", owner).
+			Msg("Edit lock force-released")
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeLock sends a lock as JSON, showing the patient ID the client used rather than the internal one
+func writeLock(w http.ResponseWriter, statusCode int, lock *models.EditLock, patientID string) {
+	exposedLock := *lock
+	exposedLock.ResourceID = patientID
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(exposedLock)
+}
+
+// writeLockError maps lock manager errors to HTTP errors
+func writeLockError(w http.ResponseWriter, r *http.Request, currentLock *models.EditLock, lockError error) {
+	switch {
+	case errors.Is(lockError, locking.ErrOwnerRequired):
+		middleware.WriteError(w, r, apperrors.InvalidInput("owner", "is required"))
+	case errors.Is(lockError, locking.ErrNotLocked):
+		middleware.WriteError(w, r, apperrors.NotFound("Lock for Patient", chi.URLParam(r, "id")))
+	case errors.Is(lockError, locking.ErrLockHeld):
+		setLockHeaders(w, currentLock)
+		middleware.WriteError(w, r, lockConflict(currentLock))
+	default:
+		middleware.WriteError(w, r, apperrors.Internal("Failed to update lock", lockError))
+	}
+}
+
+// lockConflict describes who holds a lock and until when
+func lockConflict(currentLock *models.EditLock) *apperrors.AppError {
+	return apperrors.Conflict(currentLock.ResourceType, fmt.Sprintf("record is being edited by %s until %s", currentLock.Owner, currentLock.ExpiresAt.UTC().Format(time.RFC3339)))
+}
+
+// setLockHeaders surfaces an active lock on a response
+func setLockHeaders(w http.ResponseWriter, activeLock *models.EditLock) {
+	if activeLock == nil {
+		return
+	}
+	w.Header().Set(locking.HeaderLockOwner, activeLock.Owner)
+	w.Header().Set(locking.HeaderLockExpires, activeLock.ExpiresAt.UTC().Format(time.RFC3339))
+}
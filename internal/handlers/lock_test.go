@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/locking"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// newLockRequest builds a request with the patient id route parameter set
+func newLockRequest(method string, target string, body string, patientID string) *http.Request {
+	request := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", patientID)
+	return request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeContext))
+}
+
+// TestLockHandler_AcquireGetRelease verifies the lock lifecycle over HTTP
+func TestLockHandler_AcquireGetRelease(t *testing.T) {
+	handler := NewLockHandler(locking.NewManager(locking.NewMemoryStore()))
+
+	recorder := httptest.NewRecorder()
+	handler.Acquire(recorder, newLockRequest(http.MethodPost, "/locks/Patient/p1", `{"owner": "clerk-a", "ttl_seconds": 60}`, "p1"))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 on acquire, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.Acquire(recorder, newLockRequest(http.MethodPost, "/locks/Patient/p1", `{"owner": "clerk-b"}`, "p1"))
+	if recorder.Code != http.StatusConflict || recorder.Header().Get(locking.HeaderLockOwner) != "clerk-a" {
+		t.Errorf("Expected 409 naming clerk-a, got %d with owner %q", recorder.Code, recorder.Header().Get(locking.HeaderLockOwner))
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.Get(recorder, newLockRequest(http.MethodGet, "/locks/Patient/p1", "", "p1"))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 on get, got %d", recorder.Code)
+	}
+
+	releaseRequest := newLockRequest(http.MethodDelete, "/locks/Patient/p1", "", "p1")
+	releaseRequest.Header.Set(locking.HeaderLockOwner, "clerk-a")
+	recorder = httptest.NewRecorder()
+	handler.Release(recorder, releaseRequest)
+	if recorder.Code != http.StatusNoContent {
+		t.Errorf("Expected status 204 on release, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.Get(recorder, newLockRequest(http.MethodGet, "/locks/Patient/p1", "", "p1"))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after release, got %d", recorder.Code)
+	}
+}
+
+// TestLockHandler_ForceRelease verifies force releases another owner's lock
+func TestLockHandler_ForceRelease(t *testing.T) {
+	lockManager := locking.NewManager(locking.NewMemoryStore())
+	lockManager.Acquire(context.Background(), "Patient", "p1", "clerk-a", 0)
+	handler := NewLockHandler(lockManager)
+
+	recorder := httptest.NewRecorder()
+	handler.Release(recorder, newLockRequest(http.MethodDelete, "/locks/Patient/p1", "", "p1"))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 without owner or force, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.Release(recorder, newLockRequest(http.MethodDelete, "/locks/Patient/p1?force=true", "", "p1"))
+	if remainingLock, _ := lockManager.Get(context.Background(), "Patient", "p1"); recorder.Code != http.StatusNoContent || remainingLock != nil {
+		t.Errorf("Expected forced release, got %d", recorder.Code)
+	}
+}
+
+// TestPatientHandler_EditLock verifies reads surface the lock and participating writers are refused
+func TestPatientHandler_EditLock(t *testing.T) {
+	mockRepository := NewMockPatientRepository()
+	mockRepository.patients["p1"] = &models.Patient{ID: "p1", FamilyName: "Smith"}
+	lockManager := locking.NewManager(locking.NewMemoryStore())
+	lockManager.Acquire(context.Background(), "Patient", "p1", "clerk-a", 0)
+	handler := NewPatientHandlerWithService(service.NewPatientService(mockRepository))
+	handler.SetLockManager(lockManager)
+
+	recorder := httptest.NewRecorder()
+	handler.GetByID(recorder, newLockRequest(http.MethodGet, "/fhir/Patient/p1", "", "p1"))
+	if recorder.Header().Get(locking.HeaderLockOwner) != "clerk-a" || recorder.Header().Get(locking.HeaderLockExpires) == "" {
+		t.Errorf("Expected lock headers on read, got %v", recorder.Header())
+	}
+
+	updateBody := `{"resourceType": "Patient", "name": [{"family": "Jones"}]}`
+	updateRequest := newLockRequest(http.MethodPut, "/fhir/Patient/p1", updateBody, "p1")
+	updateRequest.Header.Set(locking.HeaderLockOwner, "clerk-b")
+	recorder = httptest.NewRecorder()
+	handler.Update(recorder, updateRequest)
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 for another lock owner, got %d", recorder.Code)
+	}
+
+	// Clients that do not participate in locking are not blocked
+	recorder = httptest.NewRecorder()
+	handler.Update(recorder, newLockRequest(http.MethodPut, "/fhir/Patient/p1", updateBody, "p1"))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 for a non-participating client, got %d", recorder.Code)
+	}
+}
+
+// TestLockHandler_OpaqueIDs verifies a lock taken with an opaque ID is held on the internal ID,
+// so the Patient write and read endpoints see it
+func TestLockHandler_OpaqueIDs(t *testing.T) {
+	codec := newTestIDCodec(t)
+	opaqueID := codec.Encode("acme", "Patient", "p1")
+	mockRepository := NewMockPatientRepository()
+	mockRepository.patients["p1"] = &models.Patient{ID: "p1", FamilyName: "Smith"}
+	lockManager := locking.NewManager(locking.NewMemoryStore())
+	lockHandler := NewLockHandler(lockManager)
+	lockHandler.SetIDCodec(codec)
+	patientHandler := NewPatientHandlerWithService(service.NewPatientService(mockRepository))
+	patientHandler.SetLockManager(lockManager)
+	patientHandler.SetIDCodec(codec)
+
+	recorder := httptest.NewRecorder()
+	lockHandler.Acquire(recorder, requestWithID(http.MethodPost, "/locks/Patient/"+opaqueID, []byte(`{"owner": "clerk-a"}`), "acme", opaqueID))
+	var acquiredLock models.EditLock
+	json.NewDecoder(recorder.Body).Decode(&acquiredLock)
+	if internalLock, _ := lockManager.Get(context.Background(), "Patient", "p1"); recorder.Code != http.StatusOK || acquiredLock.ResourceID != opaqueID || internalLock == nil {
+		t.Fatalf("Expected the lock held on p1 and reported as %s, got %d %+v", opaqueID, recorder.Code, acquiredLock)
+	}
+
+	recorder = httptest.NewRecorder()
+	patientHandler.GetByID(recorder, requestWithID(http.MethodGet, "/fhir/Patient/"+opaqueID, nil, "acme", opaqueID))
+	if recorder.Header().Get(locking.HeaderLockOwner) != "clerk-a" {
+		t.Errorf("Expected lock headers on read, got %v", recorder.Header())
+	}
+
+	metaRequest := requestWithID(http.MethodPost, "/fhir/Patient/"+opaqueID+"/$meta-add", []byte(`{"resourceType": "Parameters"}`), "acme", opaqueID)
+	metaRequest.Header.Set(locking.HeaderLockOwner, "clerk-b")
+	recorder = httptest.NewRecorder()
+	patientHandler.MetaAdd(recorder, metaRequest)
+	if recorder.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 adding tags under another owner's lock, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	lockHandler.Get(recorder, requestWithID(http.MethodGet, "/locks/Patient/p1", nil, "acme", "p1"))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a raw internal ID, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MedicationRequestHandler handles MedicationRequest FHIR resource requests
+type MedicationRequestHandler struct {
+	medicationRequestService *service.MedicationRequestService
+	idCodec                  idcodec.Codec
+	residencyPolicy          *residency.Policy
+}
+
+// NewMedicationRequestHandler creates a MedicationRequestHandler backed by the medication request service
+func NewMedicationRequestHandler(medicationRequestService *service.MedicationRequestService) *MedicationRequestHandler {
+	return &MedicationRequestHandler{
+		medicationRequestService: medicationRequestService,
+	}
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *MedicationRequestHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetResidencyPolicy refuses subject references to patients held in another region
+func (handler *MedicationRequestHandler) SetResidencyPolicy(policy *residency.Policy) {
+	handler.residencyPolicy = policy
+}
+
+// exposeMedicationRequest rewrites a medication request's ID and its subject, encounter, and requester references to their exposed forms
+func exposeMedicationRequest(ctx context.Context, codec idcodec.Codec, fhirMedicationRequest *fhir.MedicationRequest) {
+	exposeID(ctx, codec, "MedicationRequest", fhirMedicationRequest.Id)
+	exposeReference(ctx, codec, fhirMedicationRequest.Subject.Reference)
+	if fhirMedicationRequest.Encounter != nil {
+		exposeReference(ctx, codec, fhirMedicationRequest.Encounter.Reference)
+	}
+	if fhirMedicationRequest.Requester != nil {
+		exposeReference(ctx, codec, fhirMedicationRequest.Requester.Reference)
+	}
+}
+
+// resolveReferences rewrites exposed subject, encounter, and requester references to the stored IDs, writing a 400
+// when one is unknown and a 403 when the subject points to another region
+func (handler *MedicationRequestHandler) resolveReferences(w http.ResponseWriter, r *http.Request, fhirMedicationRequest *fhir.MedicationRequest) bool {
+	if handler.residencyPolicy != nil && fhirMedicationRequest.Subject.Reference != nil {
+		if residencyError := handler.residencyPolicy.CheckReference(*fhirMedicationRequest.Subject.Reference); residencyError != nil {
+			middleware.WriteError(w, r, residencyError)
+			return false
+		}
+	}
+	if resolveError := resolveReference(r.Context(), handler.idCodec, fhirMedicationRequest.Subject.Reference); resolveError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("subject", "Unknown patient reference"))
+		return false
+	}
+	if fhirMedicationRequest.Encounter != nil {
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirMedicationRequest.Encounter.Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("encounter", "Unknown encounter reference"))
+			return false
+		}
+	}
+	if fhirMedicationRequest.Requester != nil {
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirMedicationRequest.Requester.Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("requester", "Unknown practitioner reference"))
+			return false
+		}
+	}
+	return true
+}
+
+// Create handles POST /fhir/MedicationRequest - creates a new medication request
+func (handler *MedicationRequestHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirMedicationRequest fhir.MedicationRequest
+	if decodeError := encoding.Decode(r, &fhirMedicationRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR MedicationRequest JSON"))
+		return
+	}
+
+	// Translate exposed patient, encounter, and practitioner references back to the stored IDs
+	if !handler.resolveReferences(w, r, &fhirMedicationRequest) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdMedicationRequest, createError := handler.medicationRequestService.CreateMedicationRequest(issueContext, &fhirMedicationRequest)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create medication request")
+		return
+	}
+	exposeMedicationRequest(r.Context(), handler.idCodec, createdMedicationRequest)
+
+	writeWriteResult(w, r, http.StatusCreated, createdMedicationRequest, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/MedicationRequest/{id} - retrieves a medication request by ID
+func (handler *MedicationRequestHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	medicationRequestID := chi.URLParam(r, "id")
+	internalMedicationRequestID, resolved := resolveID(w, r, handler.idCodec, "MedicationRequest", medicationRequestID)
+	if !resolved {
+		return
+	}
+
+	fhirMedicationRequest, getError := handler.medicationRequestService.GetMedicationRequestByID(r.Context(), internalMedicationRequestID)
+	if errors.Is(getError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("MedicationRequest", medicationRequestID))
+		return
+	}
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("MedicationRequest", medicationRequestID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read medication request", getError))
+		return
+	}
+	exposeMedicationRequest(r.Context(), handler.idCodec, fhirMedicationRequest)
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("MedicationRequest", fhirMedicationRequest, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/MedicationRequest - searches medication requests by patient, status, intent, or authored date
+func (handler *MedicationRequestHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseMedicationRequestSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	if searchParams.PatientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", searchParams.PatientID)
+		if !resolved {
+			return
+		}
+		searchParams.PatientID = internalPatientID
+	}
+
+	fhirMedicationRequests, searchError := handler.medicationRequestService.SearchMedicationRequests(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search medication requests", searchError))
+		return
+	}
+	total, countError := handler.medicationRequestService.CountMedicationRequests(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count medication requests", countError))
+		return
+	}
+	for _, fhirMedicationRequest := range fhirMedicationRequests {
+		exposeMedicationRequest(r.Context(), handler.idCodec, fhirMedicationRequest)
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "MedicationRequest", fhirMedicationRequests, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/MedicationRequest/{id} - updates an existing medication request
+func (handler *MedicationRequestHandler) Update(w http.ResponseWriter, r *http.Request) {
+	medicationRequestID := chi.URLParam(r, "id")
+
+	var fhirMedicationRequest fhir.MedicationRequest
+	if decodeError := encoding.Decode(r, &fhirMedicationRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR MedicationRequest JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirMedicationRequest.Id != nil && *fhirMedicationRequest.Id != medicationRequestID {
+		middleware.WriteError(w, r, apperrors.ValidationError("MedicationRequest ID in URL does not match ID in body"))
+		return
+	}
+
+	internalMedicationRequestID, resolved := resolveID(w, r, handler.idCodec, "MedicationRequest", medicationRequestID)
+	if !resolved {
+		return
+	}
+	if fhirMedicationRequest.Id != nil {
+		fhirMedicationRequest.Id = &internalMedicationRequestID
+	}
+	if !handler.resolveReferences(w, r, &fhirMedicationRequest) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedMedicationRequest, updateError := handler.medicationRequestService.UpdateMedicationRequest(issueContext, internalMedicationRequestID, &fhirMedicationRequest)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("MedicationRequest", medicationRequestID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("MedicationRequest", medicationRequestID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update medication request")
+		return
+	}
+	exposeMedicationRequest(r.Context(), handler.idCodec, updatedMedicationRequest)
+
+	writeWriteResult(w, r, http.StatusOK, updatedMedicationRequest, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/MedicationRequest/{id} - deletes a medication request
+func (handler *MedicationRequestHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	medicationRequestID := chi.URLParam(r, "id")
+	internalMedicationRequestID, resolved := resolveID(w, r, handler.idCodec, "MedicationRequest", medicationRequestID)
+	if !resolved {
+		return
+	}
+
+	if deleteError := handler.medicationRequestService.DeleteMedicationRequest(r.Context(), internalMedicationRequestID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "MedicationRequest", medicationRequestID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockMedicationRequestRepository implements MedicationRequestRepository interface for testing
+type MockMedicationRequestRepository struct {
+	medicationRequests map[string]*models.MedicationRequest
+	deleted            map[string]bool
+	lastSearch         *models.MedicationRequestSearchParams
+}
+
+// NewMockMedicationRequestRepository creates a new mock repository for testing
+func NewMockMedicationRequestRepository() *MockMedicationRequestRepository {
+	return &MockMedicationRequestRepository{medicationRequests: make(map[string]*models.MedicationRequest), deleted: make(map[string]bool)}
+}
+
+// Create stores a medication request under a generated UUID
+func (mock *MockMedicationRequestRepository) Create(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error) {
+	medicationRequest.ID = uuid.NewString()
+	mock.medicationRequests[medicationRequest.ID] = medicationRequest
+	return medicationRequest, nil
+}
+
+// GetByID retrieves a stored medication request
+func (mock *MockMedicationRequestRepository) GetByID(ctx context.Context, medicationRequestID string) (*models.MedicationRequest, error) {
+	if mock.deleted[medicationRequestID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	medicationRequest, exists := mock.medicationRequests[medicationRequestID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return medicationRequest, nil
+}
+
+// Search returns every stored medication request and remembers the criteria
+func (mock *MockMedicationRequestRepository) Search(ctx context.Context, searchParams *models.MedicationRequestSearchParams) ([]*models.MedicationRequest, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.MedicationRequest, 0, len(mock.medicationRequests))
+	for _, medicationRequest := range mock.medicationRequests {
+		result = append(result, medicationRequest)
+	}
+	return result, nil
+}
+
+// Count returns how many medication requests are stored, as every search matches them all
+func (mock *MockMedicationRequestRepository) Count(ctx context.Context, searchParams *models.MedicationRequestSearchParams) (int, error) {
+	return len(mock.medicationRequests), nil
+}
+
+// Update replaces a stored medication request
+func (mock *MockMedicationRequestRepository) Update(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error) {
+	if mock.deleted[medicationRequest.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.medicationRequests[medicationRequest.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.medicationRequests[medicationRequest.ID] = medicationRequest
+	return medicationRequest, nil
+}
+
+// Delete marks a stored medication request deleted, as the repository does
+func (mock *MockMedicationRequestRepository) Delete(ctx context.Context, medicationRequestID string) error {
+	if mock.deleted[medicationRequestID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.medicationRequests[medicationRequestID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.medicationRequests, medicationRequestID)
+	mock.deleted[medicationRequestID] = true
+	return nil
+}
+
+// newMedicationRequestRouter registers the MedicationRequest routes as cmd/server does
+func newMedicationRequestRouter(handler *MedicationRequestHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/MedicationRequest", handler.Create)
+	router.Get("/fhir/MedicationRequest/{id}", handler.GetByID)
+	router.Get("/fhir/MedicationRequest", handler.GetAll)
+	router.Put("/fhir/MedicationRequest/{id}", handler.Update)
+	router.Delete("/fhir/MedicationRequest/{id}", handler.Delete)
+	return router
+}
+
+// TestMedicationRequestRoutes verifies create, read, search, update, and delete, that deleted requests are 410, and that unknown ones are 404
+func TestMedicationRequestRoutes(t *testing.T) {
+	medicationRequestRepository := NewMockMedicationRequestRepository()
+	router := newMedicationRequestRouter(NewMedicationRequestHandler(service.NewMedicationRequestService(medicationRequestRepository)))
+	patientID := uuid.NewString()
+	practitionerID := uuid.NewString()
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/MedicationRequest", `{"resourceType":"MedicationRequest","status":"active","intent":"order",`+
+		`"medicationCodeableConcept":{"coding":[{"system":"http://www.nlm.nih.gov/research/umls/rxnorm","code":"197361"}]},"subject":{"reference":"Patient/`+patientID+`"},`+
+		`"requester":{"reference":"Practitioner/`+practitionerID+`"},"authoredOn":"2024-03-01T09:45:00Z","dosageInstruction":[{"text":"1 tablet by mouth daily"}]}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the medication request, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdRequest fhir.MedicationRequest
+	json.NewDecoder(createRecorder.Body).Decode(&createdRequest)
+	medicationRequestID := *createdRequest.Id
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/MedicationRequest/"+medicationRequestID, "")
+	var readRequest fhir.MedicationRequest
+	json.NewDecoder(readRecorder.Body).Decode(&readRequest)
+	if readRecorder.Code != http.StatusOK || readRequest.Status != "active" || *readRequest.Subject.Reference != "Patient/"+patientID {
+		t.Errorf("Expected the active request for the patient, got %d: %+v", readRecorder.Code, readRequest)
+	}
+	if readRequest.Requester == nil || *readRequest.Requester.Reference != "Practitioner/"+practitionerID {
+		t.Errorf("Expected the practitioner requester, got %+v", readRequest.Requester)
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/MedicationRequest?patient=Patient/"+patientID+"&status=active&intent=order&authoredon=ge2024-01-01", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 1 || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one medication request, got %s", searchRecorder.Body.String())
+	}
+	lastSearch := medicationRequestRepository.lastSearch
+	if lastSearch.PatientID != patientID || lastSearch.Status != "active" || lastSearch.Intent != "order" || lastSearch.AuthoredOnGreaterThan == nil {
+		t.Errorf("Expected the patient, status, intent, and authored date passed to the repository, got %+v", lastSearch)
+	}
+
+	if invalidRecorder := serveCRUD(router, http.MethodPut, "/fhir/MedicationRequest/"+medicationRequestID, `{"resourceType":"MedicationRequest","status":"paused","intent":"order"}`); invalidRecorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for an unknown status, got %d", invalidRecorder.Code)
+	}
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/MedicationRequest/"+medicationRequestID, `{"resourceType":"MedicationRequest","id":"`+medicationRequestID+`","status":"stopped","intent":"order"}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the medication request, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/MedicationRequest/"+medicationRequestID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the medication request, got %d", deleteRecorder.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveCRUD(router, method, "/fhir/MedicationRequest/"+medicationRequestID, ""); recorder.Code != http.StatusGone {
+			t.Errorf("Expected status 410 for %s of a deleted medication request, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/MedicationRequest/"+uuid.NewString(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 reading an unknown medication request, got %d", recorder.Code)
+	}
+}
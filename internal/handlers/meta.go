@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// tagChange applies a $meta-add or $meta-delete to one stored resource and returns the resulting tags
+type tagChange func(ctx context.Context, resourceID string, tags models.Tags) (models.Tags, error)
+
+// changeTags handles POST /fhir/{type}/{id}/$meta-add and $meta-delete once the caller has resolved the ID
+// The body is a Parameters resource with a "meta" parameter; only meta.tag is supported
+func changeTags(w http.ResponseWriter, r *http.Request, resourceType string, resourceID string, internalID string, change tagChange) {
+	tags, readError := readMetaParameter(r)
+	if readError != nil {
+		middleware.WriteError(w, r, readError)
+		return
+	}
+
+	resultTags, changeError := change(r.Context(), internalID, tags)
+	if errors.Is(changeError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone(resourceType, resourceID))
+		return
+	}
+	if errors.Is(changeError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound(resourceType, resourceID))
+		return
+	}
+	if changeError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to update "+resourceType+" tags", changeError))
+		return
+	}
+
+	writeMeta(w, &fhir.Meta{Tag: models.TagsToFHIR(resultTags)})
+}
+
+// readMetaParameter decodes the tags from the operation's "meta" parameter
+func readMetaParameter(r *http.Request) (models.Tags, *apperrors.AppError) {
+	var parameters fhir.Parameters
+	if decodeError := json.NewDecoder(r.Body).Decode(&parameters); decodeError != nil {
+		return nil, apperrors.InvalidInput("body", "Invalid FHIR Parameters JSON")
+	}
+
+	var meta *fhir.Meta
+	for _, parameter := range parameters.Parameter {
+		if parameter.Name == "meta" {
+			meta = parameter.ValueMeta
+		}
+	}
+	if meta == nil {
+		return nil, apperrors.InvalidInput("meta", "a meta parameter with valueMeta is required")
+	}
+	if len(meta.Profile) > 0 || len(meta.Security) > 0 {
+		return nil, apperrors.InvalidInput("meta", "only meta.tag is supported")
+	}
+	if len(meta.Tag) == 0 {
+		return nil, apperrors.InvalidInput("meta.tag", "at least one tag is required")
+	}
+
+	tags := models.Tags{}
+	for _, coding := range meta.Tag {
+		if coding.Code == nil || *coding.Code == "" {
+			return nil, apperrors.InvalidInput("meta.tag", "every tag needs a code")
+		}
+		tag := models.Tag{Code: *coding.Code}
+		if coding.System != nil {
+			tag.System = *coding.System
+		}
+		if coding.Display != nil {
+			tag.Display = *coding.Display
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags, nil
+}
+
+// writeMeta responds with a Parameters resource whose "return" parameter is the resource's meta
+func writeMeta(w http.ResponseWriter, meta *fhir.Meta) {
+	if meta == nil {
+		meta = &fhir.Meta{}
+	}
+
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(fhir.Parameters{
+		Parameter: []fhir.ParametersParameter{{Name: "return", ValueMeta: meta}},
+	})
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// taggedPatientID is a stored patient's UUID
+const taggedPatientID = "6f1c2a3e-8d4b-4c5a-9e7f-0a1b2c3d4e5f"
+
+// newMetaTestRouter routes the patient and observation meta operations
+func newMetaTestRouter(patientRepository *MockPatientRepository, observationService *MockObservationService) *chi.Mux {
+	patientHandler := NewPatientHandlerWithService(service.NewPatientService(patientRepository))
+	observationHandler := NewObservationHandler(observationService)
+
+	router := chi.NewRouter()
+	router.Get("/fhir/Patient/{id}/$meta", patientHandler.Meta)
+	router.Post("/fhir/Patient/{id}/$meta-add", patientHandler.MetaAdd)
+	router.Post("/fhir/Patient/{id}/$meta-delete", patientHandler.MetaDelete)
+	router.Post("/fhir/Observation/{id}/$meta-add", observationHandler.MetaAdd)
+	return router
+}
+
+// metaParameters builds a $meta-add/$meta-delete body with the given tag codes
+func metaParameters(codes ...string) string {
+	tags := []map[string]string{}
+	for _, code := range codes {
+		tags = append(tags, map[string]string{"system": "http://example.org/workflow", "code": code})
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"resourceType": "Parameters",
+		"parameter":    []interface{}{map[string]interface{}{"name": "meta", "valueMeta": map[string]interface{}{"tag": tags}}},
+	})
+	return string(body)
+}
+
+// decodeReturnedTags reads the tag codes from an operation's "return" parameter
+func decodeReturnedTags(t *testing.T, recorder *httptest.ResponseRecorder) []string {
+	var parameters fhir.Parameters
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&parameters); decodeError != nil {
+		t.Fatalf("Failed to decode response: %v", decodeError)
+	}
+	if len(parameters.Parameter) != 1 || parameters.Parameter[0].Name != "return" || parameters.Parameter[0].ValueMeta == nil {
+		t.Fatalf("Expected a single return parameter with valueMeta, got %+v", parameters.Parameter)
+	}
+
+	codes := []string{}
+	for _, coding := range parameters.Parameter[0].ValueMeta.Tag {
+		codes = append(codes, *coding.Code)
+	}
+	return codes
+}
+
+// TestPatientHandler_MetaAddAndDelete verifies tags are added, merged, removed, and read back
+func TestPatientHandler_MetaAddAndDelete(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients[taggedPatientID] = &models.Patient{ID: taggedPatientID, FamilyName: "Smith"}
+	router := newMetaTestRouter(patientRepository, NewMockObservationService())
+
+	steps := []struct {
+		method       string
+		path         string
+		body         string
+		expectedTags []string
+	}{
+		{http.MethodPost, "/fhir/Patient/" + taggedPatientID + "/$meta-add", metaParameters("needs-review", "imported"), []string{"needs-review", "imported"}},
+		{http.MethodPost, "/fhir/Patient/" + taggedPatientID + "/$meta-add", metaParameters("needs-review"), []string{"needs-review", "imported"}},
+		{http.MethodPost, "/fhir/Patient/" + taggedPatientID + "/$meta-delete", metaParameters("needs-review"), []string{"imported"}},
+		{http.MethodGet, "/fhir/Patient/" + taggedPatientID + "/$meta", "", []string{"imported"}},
+	}
+
+	for _, step := range steps {
+		request := httptest.NewRequest(step.method, step.path, bytes.NewBufferString(step.body))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("%s %s: expected status 200, got %d: %s", step.method, step.path, recorder.Code, recorder.Body.String())
+		}
+		returnedTags := decodeReturnedTags(t, recorder)
+		if len(returnedTags) != len(step.expectedTags) {
+			t.Fatalf("%s %s: expected tags %v, got %v", step.method, step.path, step.expectedTags, returnedTags)
+		}
+		for index, code := range step.expectedTags {
+			if returnedTags[index] != code {
+				t.Errorf("%s %s: expected tags %v, got %v", step.method, step.path, step.expectedTags, returnedTags)
+			}
+		}
+	}
+}
+
+// TestPatientHandler_MetaAdd_Rejected verifies invalid bodies and unknown patients are refused
+func TestPatientHandler_MetaAdd_Rejected(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients[taggedPatientID] = &models.Patient{ID: taggedPatientID}
+	router := newMetaTestRouter(patientRepository, NewMockObservationService())
+
+	testCases := []struct {
+		name           string
+		patientID      string
+		body           string
+		expectedStatus int
+	}{
+		{"not parameters", taggedPatientID, `{`, http.StatusBadRequest},
+		{"no meta parameter", taggedPatientID, `{"resourceType":"Parameters","parameter":[]}`, http.StatusBadRequest},
+		{"security labels", taggedPatientID, `{"resourceType":"Parameters","parameter":[{"name":"meta","valueMeta":{"security":[{"code":"R"}]}}]}`, http.StatusBadRequest},
+		{"tag without code", taggedPatientID, `{"resourceType":"Parameters","parameter":[{"name":"meta","valueMeta":{"tag":[{"system":"http://example.org"}]}}]}`, http.StatusBadRequest},
+		{"unknown patient", "0f1c2a3e-8d4b-4c5a-9e7f-0a1b2c3d4e5f", metaParameters("needs-review"), http.StatusNotFound},
+		{"malformed patient ID", "not-a-uuid", metaParameters("needs-review"), http.StatusNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPost, "/fhir/Patient/"+testCase.patientID+"/$meta-add", bytes.NewBufferString(testCase.body))
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", testCase.expectedStatus, recorder.Code, recorder.Body.String())
+			}
+		})
+	}
+}
+
+// TestObservationHandler_MetaAdd verifies observation tags are added through the service
+func TestObservationHandler_MetaAdd(t *testing.T) {
+	observationService := NewMockObservationService()
+	observationID := "obs-1"
+	observationService.observations[observationID] = &fhir.Observation{Id: &observationID}
+	router := newMetaTestRouter(NewMockPatientRepository(), observationService)
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Observation/obs-1/$meta-add", bytes.NewBufferString(metaParameters("needs-review")))
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if returnedTags := decodeReturnedTags(t, recorder); len(returnedTags) != 1 || returnedTags[0] != "needs-review" {
+		t.Errorf("Expected [needs-review], got %v", returnedTags)
+	}
+	if observationService.observations[observationID].Meta == nil {
+		t.Error("Expected the observation's meta to be updated")
+	}
+}
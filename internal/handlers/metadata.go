@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MetadataHandler serves the server's CapabilityStatement
+type MetadataHandler struct {
+	statement fhir.CapabilityStatement
+}
+
+// NewMetadataHandler creates a new instance of MetadataHandler
+func NewMetadataHandler(statement fhir.CapabilityStatement) *MetadataHandler {
+	return &MetadataHandler{
+		statement: statement,
+	}
+}
+
+// SetStatement replaces the statement served; call it before the server starts handling requests
+func (handler *MetadataHandler) SetStatement(statement fhir.CapabilityStatement) {
+	handler.statement = statement
+}
+
+// Capabilities handles GET /fhir/metadata - returns the supported resources, search parameters, and operations
+func (handler *MetadataHandler) Capabilities(w http.ResponseWriter, r *http.Request) {
+	encoding.Write(w, r, http.StatusOK, handler.statement)
+}
@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/capability"
+)
+
+// TestMetadataHandler_Capabilities verifies the CapabilityStatement lists each resource's search parameters and operations
+func TestMetadataHandler_Capabilities(t *testing.T) {
+	handler := NewMetadataHandler(capability.Statement(capability.Resources(), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	recorder := httptest.NewRecorder()
+	handler.Capabilities(recorder, httptest.NewRequest(http.MethodGet, "/fhir/metadata", nil))
+
+	var statement struct {
+		ResourceType string `json:"resourceType"`
+		Rest         []struct {
+			Resource []struct {
+				Type        string `json:"type"`
+				SearchParam []struct {
+					Name string `json:"name"`
+					Type string `json:"type"`
+				} `json:"searchParam"`
+				Operation []struct {
+					Name string `json:"name"`
+				} `json:"operation"`
+				SearchRevInclude []string `json:"searchRevInclude"`
+			} `json:"resource"`
+		} `json:"rest"`
+	}
+	json.NewDecoder(recorder.Body).Decode(&statement)
+	if recorder.Code != http.StatusOK || statement.ResourceType != "CapabilityStatement" || len(statement.Rest) != 1 {
+		t.Fatalf("Unexpected metadata response: %d %+v", recorder.Code, statement)
+	}
+
+	patientResource := statement.Rest[0].Resource[0]
+	if patientResource.Type != "Patient" || len(patientResource.SearchRevInclude) != 2 {
+		t.Errorf("Expected Patient with its _revinclude targets first, got %+v", patientResource)
+	}
+	if patientResource.SearchParam[0].Name != "name" || patientResource.SearchParam[0].Type != "string" {
+		t.Errorf("Expected the name search parameter to be a string, got %+v", patientResource.SearchParam[0])
+	}
+	if patientResource.Operation[0].Name != "everything" {
+		t.Errorf("Expected $everything first, got %+v", patientResource.Operation)
+	}
+}
@@ -2,13 +2,22 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
 	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
 	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/searchcost"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
 	"github.com/nathannewyen/fhir-health-interop/internal/utils"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
@@ -19,14 +28,26 @@ type ObservationServiceInterface interface {
 	GetObservationByID(ctx context.Context, observationID string) (*fhir.Observation, error)
 	GetObservationsByPatientID(ctx context.Context, patientID string, limit int, offset int) ([]*fhir.Observation, error)
 	GetAllObservations(ctx context.Context, limit int, offset int) ([]*fhir.Observation, error)
-	SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error)
-	UpdateObservation(ctx context.Context, observationID string, fhirObservation *fhir.Observation) (*fhir.Observation, error)
+	SearchObservationsPage(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, *models.PageCursor, error)
+	StreamObservations(ctx context.Context, searchParams *models.ObservationSearchParams, visit func(fhirObservation *fhir.Observation) error) error
+	CountObservations(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error)
+	UpdateObservation(ctx context.Context, observationID string, fhirObservation *fhir.Observation, expectedVersion int) (*fhir.Observation, error)
+	LastObservations(ctx context.Context, lastNParams *models.ObservationLastNParams) ([]*fhir.Observation, error)
 	DeleteObservation(ctx context.Context, observationID string) error
+	AddObservationTags(ctx context.Context, observationID string, tags models.Tags) (models.Tags, error)
+	RemoveObservationTags(ctx context.Context, observationID string, tags models.Tags) (models.Tags, error)
 }
 
 // ObservationHandler handles Observation FHIR resource requests
 type ObservationHandler struct {
 	observationService ObservationServiceInterface
+	searchPolicy       *searchcost.Policy
+	idCodec            idcodec.Codec
+	historyService     *service.HistoryService
+	residencyPolicy    *residency.Policy
+
+	// compartmentService serves _include
+	compartmentService *service.CompartmentService
 }
 
 // NewObservationHandler creates a new observation handler instance
@@ -36,27 +57,122 @@ func NewObservationHandler(observationService ObservationServiceInterface) *Obse
 	}
 }
 
+// SetSearchPolicy enables cost-based guardrails that downgrade or reject expensive searches
+func (handler *ObservationHandler) SetSearchPolicy(policy searchcost.Policy) {
+	handler.searchPolicy = &policy
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+// Patient references in observation bodies and the patient search parameter are translated too
+func (handler *ObservationHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetHistoryService enables point-in-time reads with _at and asOfVersion
+func (handler *ObservationHandler) SetHistoryService(historyService *service.HistoryService) {
+	handler.historyService = historyService
+}
+
+// SetCompartmentService enables _include, which reads the observations' subject patients in parallel
+func (handler *ObservationHandler) SetCompartmentService(compartmentService *service.CompartmentService) {
+	handler.compartmentService = compartmentService
+}
+
+// SetResidencyPolicy refuses subject references to patients held in another region
+func (handler *ObservationHandler) SetResidencyPolicy(policy *residency.Policy) {
+	handler.residencyPolicy = policy
+}
+
+// exposeObservation rewrites the observation's ID and references to their exposed forms
+func (handler *ObservationHandler) exposeObservation(ctx context.Context, fhirObservation *fhir.Observation) {
+	exposeID(ctx, handler.idCodec, "Observation", fhirObservation.Id)
+	exposeObservationReferences(ctx, handler.idCodec, fhirObservation)
+}
+
+// exposeObservationReferences rewrites an observation's subject, encounter, and performer references to their exposed forms
+func exposeObservationReferences(ctx context.Context, codec idcodec.Codec, fhirObservation *fhir.Observation) {
+	if fhirObservation.Subject != nil {
+		exposeReference(ctx, codec, fhirObservation.Subject.Reference)
+	}
+	if fhirObservation.Encounter != nil {
+		exposeReference(ctx, codec, fhirObservation.Encounter.Reference)
+	}
+	for index := range fhirObservation.Performer {
+		exposeReference(ctx, codec, fhirObservation.Performer[index].Reference)
+	}
+}
+
+// resolveSubject rewrites an exposed subject reference to the stored patient ID, writing a 400 when it is unknown
+// and a 403 when it points to another region
+func (handler *ObservationHandler) resolveSubject(w http.ResponseWriter, r *http.Request, fhirObservation *fhir.Observation) bool {
+	if fhirObservation.Subject == nil {
+		return true
+	}
+	if handler.residencyPolicy != nil && fhirObservation.Subject.Reference != nil {
+		if residencyError := handler.residencyPolicy.CheckReference(*fhirObservation.Subject.Reference); residencyError != nil {
+			middleware.WriteError(w, r, residencyError)
+			return false
+		}
+	}
+	if resolveError := resolveReference(r.Context(), handler.idCodec, fhirObservation.Subject.Reference); resolveError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("subject", "Unknown patient reference"))
+		return false
+	}
+	return true
+}
+
+// resolvePerformers rewrites exposed performer references to the stored practitioner IDs, writing a 400 when one is unknown
+func (handler *ObservationHandler) resolvePerformers(w http.ResponseWriter, r *http.Request, fhirObservation *fhir.Observation) bool {
+	for index := range fhirObservation.Performer {
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirObservation.Performer[index].Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("performer", "Unknown practitioner reference"))
+			return false
+		}
+	}
+	return true
+}
+
+// resolveEncounter rewrites an exposed encounter reference to the stored encounter ID, writing a 400 when it is unknown
+func (handler *ObservationHandler) resolveEncounter(w http.ResponseWriter, r *http.Request, fhirObservation *fhir.Observation) bool {
+	if fhirObservation.Encounter == nil {
+		return true
+	}
+	if resolveError := resolveReference(r.Context(), handler.idCodec, fhirObservation.Encounter.Reference); resolveError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("encounter", "Unknown encounter reference"))
+		return false
+	}
+	return true
+}
+
 // Create handles POST /fhir/Observation - creates a new observation
 func (handler *ObservationHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Parse the FHIR Observation from request body
 	var fhirObservation fhir.Observation
-	decodeError := json.NewDecoder(r.Body).Decode(&fhirObservation)
+	decodeError := encoding.Decode(r, &fhirObservation)
 	if decodeError != nil {
 		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Observation JSON"))
 		return
 	}
 
+	// Translate exposed patient, encounter, and practitioner references back to the stored IDs
+	if !handler.resolveSubject(w, r, &fhirObservation) || !handler.resolveEncounter(w, r, &fhirObservation) || !handler.resolvePerformers(w, r, &fhirObservation) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
 	// Create observation using service layer
-	createdObservation, createError := handler.observationService.CreateObservation(r.Context(), &fhirObservation)
+	createdObservation, createError := handler.observationService.CreateObservation(issueContext, &fhirObservation)
 	if createError != nil {
-		middleware.WriteError(w, r, apperrors.Internal("Failed to create observation", createError))
+		writeWriteError(w, r, createError, "Failed to create observation")
 		return
 	}
+	handler.exposeObservation(r.Context(), createdObservation)
 
 	// Return created observation with 201 status
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(createdObservation)
+	setVersionTag(w, createdObservation.Meta)
+	writeWriteResult(w, r, http.StatusCreated, createdObservation, issueCollector.Issues())
 }
 
 // GetByID handles GET /fhir/Observation/{id} - retrieves an observation by ID
@@ -68,17 +184,29 @@ func (handler *ObservationHandler) GetByID(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Translate an exposed ID back to the stored one
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+
+	// Answer reads of a past time or version from the change log
+	if isPointInTimeRead(r) {
+		readPointInTime(w, r, handler.historyService, handler.idCodec, "Observation", observationID, internalObservationID)
+		return
+	}
+
 	// Get observation using service layer
-	fhirObservation, getError := handler.observationService.GetObservationByID(r.Context(), observationID)
+	fhirObservation, getError := handler.observationService.GetObservationByID(r.Context(), internalObservationID)
 	if getError != nil {
-		middleware.WriteError(w, r, apperrors.NotFound("Observation", observationID))
+		writeNotFoundOrGone(w, r, getError, "Observation", observationID)
 		return
 	}
+	handler.exposeObservation(r.Context(), fhirObservation)
 
-	// Return observation
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(fhirObservation)
+	// Return observation with its version as the ETag, for use in If-Match on update
+	setVersionTag(w, fhirObservation.Meta)
+	encoding.Write(w, r, http.StatusOK, subsetElements("Observation", fhirObservation, utils.ParseElementsParameter(r)))
 }
 
 // GetByPatientID handles GET /fhir/Observation?patient={id} - retrieves observations for a patient
@@ -90,17 +218,31 @@ func (handler *ObservationHandler) GetByPatientID(w http.ResponseWriter, r *http
 		return
 	}
 
+	// Translate an exposed patient ID back to the stored one
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
 	// Get observations using service layer with default pagination
-	fhirObservations, getError := handler.observationService.GetObservationsByPatientID(r.Context(), patientID, 100, 0)
+	page := bundle.Page{Count: 100, Offset: 0}
+	fhirObservations, getError := handler.observationService.GetObservationsByPatientID(r.Context(), internalPatientID, page.Count, page.Offset)
 	if getError != nil {
 		middleware.WriteError(w, r, apperrors.Internal("Failed to retrieve observations", getError))
 		return
 	}
+	total, countError := handler.observationService.CountObservations(r.Context(), &models.ObservationSearchParams{PatientID: internalPatientID})
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count observations", countError))
+		return
+	}
+	page.Total = total
+	for _, fhirObservation := range fhirObservations {
+		handler.exposeObservation(r.Context(), fhirObservation)
+	}
 
-	// Return observations
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(fhirObservations)
+	// Return observations as a searchset Bundle
+	writeSearchResults(w, r, "Observation", fhirObservations, utils.ParseElementsParameter(r), page)
 }
 
 // GetAll handles GET /fhir/Observation - retrieves all observations with optional search parameters
@@ -108,21 +250,176 @@ func (handler *ObservationHandler) GetAll(w http.ResponseWriter, r *http.Request
 	// Parse search parameters from query string
 	searchParams, parseError := utils.ParseObservationSearchParams(r)
 	if parseError != nil {
-		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		middleware.WriteError(w, r, apperrors.ValidationError(parseError.Error()))
+		return
+	}
+
+	// _include adds the observations' subject patients to the Bundle as include entries
+	includePatients, parsed := handler.parseIncludes(w, r)
+	if !parsed {
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	if searchParams.PatientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", searchParams.PatientID)
+		if !resolved {
+			return
+		}
+		searchParams.PatientID = internalPatientID
+	}
+
+	// Translate an exposed encounter ID back to the stored one
+	if searchParams.EncounterID != "" {
+		internalEncounterID, resolved := resolveID(w, r, handler.idCodec, "Encounter", searchParams.EncounterID)
+		if !resolved {
+			return
+		}
+		searchParams.EncounterID = internalEncounterID
+	}
+
+	// _stream sends every match, up to maxStreamedResults, in one Bundle written as the matches are read
+	streamResults, validStream := parseStreamParameter(w, r)
+	if !validStream {
+		return
+	}
+	if streamResults {
+		if includePatients {
+			middleware.WriteError(w, r, apperrors.ValidationError("_stream cannot be combined with _include"))
+			return
+		}
+		searchParams.Limit = maxStreamedResults
+		searchParams.CursorPaging = false
+	}
+
+	// Refuse or shrink searches that would scan far more data than they return
+	if !applySearchPolicy(w, r, handler.searchPolicy, searchcost.EstimateObservationSearch(searchParams), &searchParams.Limit) {
+		return
+	}
+	if streamResults {
+		handler.streamSearch(w, r, searchParams)
 		return
 	}
 
 	// Search observations using service layer
-	fhirObservations, searchError := handler.observationService.SearchObservations(r.Context(), searchParams)
+	fhirObservations, nextCursor, searchError := handler.observationService.SearchObservationsPage(r.Context(), searchParams)
 	if searchError != nil {
 		middleware.WriteError(w, r, apperrors.Internal("Failed to search observations", searchError))
 		return
 	}
+	total, countError := handler.observationService.CountObservations(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count observations", countError))
+		return
+	}
+	page := searchPage(r, total, searchParams.Limit, searchParams.Offset, searchParams.CursorPaging, nextCursor)
+	if includePatients {
+		handler.writeIncludeBundle(w, r, fhirObservations, page)
+		return
+	}
+	for _, fhirObservation := range fhirObservations {
+		handler.exposeObservation(r.Context(), fhirObservation)
+	}
+
+	// Return this page of observations as a searchset Bundle with the total and paging links
+	writeSearchResults(w, r, "Observation", fhirObservations, utils.ParseElementsParameter(r), page)
+}
+
+// streamSearch responds to a _stream search with every match, written to the client as it is read
+func (handler *ObservationHandler) streamSearch(w http.ResponseWriter, r *http.Request, searchParams *models.ObservationSearchParams) {
+	total, countError := handler.observationService.CountObservations(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count observations", countError))
+		return
+	}
+
+	streamSearchResults(w, r, "Observation", total, utils.ParseElementsParameter(r), func(visit func(fhirObservation *fhir.Observation) error) error {
+		return handler.observationService.StreamObservations(r.Context(), searchParams, func(fhirObservation *fhir.Observation) error {
+			handler.exposeObservation(r.Context(), fhirObservation)
+			return visit(fhirObservation)
+		})
+	})
+}
+
+// parseIncludes validates the _include parameters, writing a 400 for unsupported ones
+// It reports whether subject patients should be included and whether the request may continue
+func (handler *ObservationHandler) parseIncludes(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	includes := r.URL.Query()["_include"]
+	if len(includes) == 0 {
+		return false, true
+	}
+
+	for _, include := range includes {
+		if !slices.Contains(utils.ObservationIncludes, include) || handler.compartmentService == nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_include", "only Observation:subject and Observation:patient are supported"))
+			return false, false
+		}
+	}
+	return true, true
+}
+
+// writeIncludeBundle answers an Observation search with _include as a searchset Bundle of the page's matched
+// observations followed by the patients they are about
+// The patients are read with the stored subject references, so this runs before the observations are exposed
+func (handler *ObservationHandler) writeIncludeBundle(w http.ResponseWriter, r *http.Request, fhirObservations []*fhir.Observation, page bundle.Page) {
+	patients, issues, includeError := handler.compartmentService.IncludeSubjectPatients(r.Context(), fhirObservations)
+	if includeError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read included patients", includeError))
+		return
+	}
 
-	// Return observations
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(fhirObservations)
+	// _elements trims the matched observations; included patients are returned whole
+	requestedElements := utils.ParseElementsParameter(r)
+	observationEntries := make([]fhir.BundleEntry, 0, len(fhirObservations))
+	for _, fhirObservation := range fhirObservations {
+		handler.exposeObservation(r.Context(), fhirObservation)
+		observationEntries = append(observationEntries, searchEntry(r, subsetElements("Observation", fhirObservation, requestedElements), fhir.SearchEntryModeMatch))
+	}
+	patientEntries := make([]fhir.BundleEntry, 0, len(patients))
+	for _, patient := range patients {
+		exposePatient(r.Context(), handler.idCodec, patient)
+		patientEntries = append(patientEntries, searchEntry(r, patient, fhir.SearchEntryModeInclude))
+	}
+
+	writeSearchPage(w, r, bundle.MergeEntries(observationEntries, patientEntries, outcomeEntries(r, issues)), page)
+}
+
+// LastN handles GET /fhir/Observation/$lastn - retrieves the patient's most recent observations of each code
+// The result is a searchset of at most max observations per code, grouped by code and newest first
+func (handler *ObservationHandler) LastN(w http.ResponseWriter, r *http.Request) {
+	lastNParams, parseError := utils.ParseObservationLastNParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError(parseError.Error()))
+		return
+	}
+	if lastNParams.PatientID == "" {
+		middleware.WriteError(w, r, apperrors.ValidationError("The patient parameter is required"))
+		return
+	}
+	if len(lastNParams.Codes) == 0 && len(lastNParams.Categories) == 0 {
+		middleware.WriteError(w, r, apperrors.ValidationError("A code or category parameter is required"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", lastNParams.PatientID)
+	if !resolved {
+		return
+	}
+	lastNParams.PatientID = internalPatientID
+
+	fhirObservations, lastNError := handler.observationService.LastObservations(r.Context(), lastNParams)
+	if lastNError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to retrieve last observations", lastNError))
+		return
+	}
+	for _, fhirObservation := range fhirObservations {
+		handler.exposeObservation(r.Context(), fhirObservation)
+	}
+
+	// Every matching observation is returned in one page
+	page := bundle.Page{Total: len(fhirObservations), Count: len(fhirObservations)}
+	writeSearchResults(w, r, "Observation", fhirObservations, utils.ParseElementsParameter(r), page)
 }
 
 // Update handles PUT /fhir/Observation/{id} - updates an existing observation
@@ -136,7 +433,7 @@ func (handler *ObservationHandler) Update(w http.ResponseWriter, r *http.Request
 
 	// Parse the FHIR Observation from request body
 	var fhirObservation fhir.Observation
-	decodeError := json.NewDecoder(r.Body).Decode(&fhirObservation)
+	decodeError := encoding.Decode(r, &fhirObservation)
 	if decodeError != nil {
 		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Observation JSON"))
 		return
@@ -148,17 +445,51 @@ func (handler *ObservationHandler) Update(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// An If-Match header makes the update apply only to the version the client last read
+	expectedVersion, versionError := requiredVersion(r)
+	if versionError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("If-Match", versionError.Error()))
+		return
+	}
+
+	// Translate exposed IDs back to the stored ones
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+	if fhirObservation.Id != nil {
+		fhirObservation.Id = &internalObservationID
+	}
+	if !handler.resolveSubject(w, r, &fhirObservation) || !handler.resolveEncounter(w, r, &fhirObservation) || !handler.resolvePerformers(w, r, &fhirObservation) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
 	// Update observation using service layer
-	updatedObservation, updateError := handler.observationService.UpdateObservation(r.Context(), observationID, &fhirObservation)
+	updatedObservation, updateError := handler.observationService.UpdateObservation(issueContext, internalObservationID, &fhirObservation, expectedVersion)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Observation", observationID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Observation", observationID))
+		return
+	}
+	if errors.Is(updateError, service.ErrVersionConflict) {
+		middleware.WriteError(w, r, apperrors.PreconditionFailed(fmt.Sprintf("Observation %s is no longer at version %d; read it again and reapply the change", observationID, expectedVersion)))
+		return
+	}
 	if updateError != nil {
-		middleware.WriteError(w, r, apperrors.Internal("Failed to update observation", updateError))
+		writeWriteError(w, r, updateError, "Failed to update observation")
 		return
 	}
+	handler.exposeObservation(r.Context(), updatedObservation)
 
 	// Return updated observation with 200 OK
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedObservation)
+	setVersionTag(w, updatedObservation.Meta)
+	writeWriteResult(w, r, http.StatusOK, updatedObservation, issueCollector.Issues())
 }
 
 // Delete handles DELETE /fhir/Observation/{id} - deletes an observation
@@ -170,13 +501,76 @@ func (handler *ObservationHandler) Delete(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// Translate an exposed ID back to the stored one
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+
 	// Delete observation using service layer
-	deleteError := handler.observationService.DeleteObservation(r.Context(), observationID)
+	deleteError := handler.observationService.DeleteObservation(r.Context(), internalObservationID)
 	if deleteError != nil {
-		middleware.WriteError(w, r, apperrors.NotFound("Observation", observationID))
+		writeDeleteError(w, r, deleteError, "Observation", observationID)
 		return
 	}
 
 	// Return 204 No Content on successful deletion
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// Meta handles GET /fhir/Observation/{id}/$meta - returns the observation's meta
+func (handler *ObservationHandler) Meta(w http.ResponseWriter, r *http.Request) {
+	observationID := chi.URLParam(r, "id")
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+
+	fhirObservation, getError := handler.observationService.GetObservationByID(r.Context(), internalObservationID)
+	if getError != nil {
+		writeNotFoundOrGone(w, r, getError, "Observation", observationID)
+		return
+	}
+
+	writeMeta(w, fhirObservation.Meta)
+}
+
+// MetaAdd handles POST /fhir/Observation/{id}/$meta-add - adds tags to the observation
+func (handler *ObservationHandler) MetaAdd(w http.ResponseWriter, r *http.Request) {
+	observationID := chi.URLParam(r, "id")
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+	changeTags(w, r, "Observation", observationID, internalObservationID, handler.observationService.AddObservationTags)
+}
+
+// MetaDelete handles POST /fhir/Observation/{id}/$meta-delete - removes tags from the observation
+func (handler *ObservationHandler) MetaDelete(w http.ResponseWriter, r *http.Request) {
+	observationID := chi.URLParam(r, "id")
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+	changeTags(w, r, "Observation", observationID, internalObservationID, handler.observationService.RemoveObservationTags)
+}
+
+// History handles GET /fhir/Observation/{id}/_history - lists the observation's versions, deletes included, newest first
+func (handler *ObservationHandler) History(w http.ResponseWriter, r *http.Request) {
+	observationID := chi.URLParam(r, "id")
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+	writeVersionHistory(w, r, handler.historyService, handler.idCodec, "Observation", observationID, internalObservationID)
+}
+
+// VRead handles GET /fhir/Observation/{id}/_history/{version} - reads one version of the observation, answering 410 for a delete
+func (handler *ObservationHandler) VRead(w http.ResponseWriter, r *http.Request) {
+	observationID := chi.URLParam(r, "id")
+	internalObservationID, resolved := resolveID(w, r, handler.idCodec, "Observation", observationID)
+	if !resolved {
+		return
+	}
+	readVersion(w, r, handler.historyService, handler.idCodec, "Observation", observationID, internalObservationID)
+}
@@ -0,0 +1,268 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/streaming"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/nathannewyen/fhir-health-interop/internal/websocket"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// observationPushWriteTimeout bounds each message sent to a dashboard so a stalled client fails fast
+const observationPushWriteTimeout = 10 * time.Second
+
+// maxObservationPushCommand is the largest subscribe or unsubscribe message a client may send
+const maxObservationPushCommand = 4096
+
+// maxPushedPatients caps how many patients one connection may follow, bounding its hub subscriptions
+const maxPushedPatients = 100
+
+// ObservationConverter converts stored observations the way reads return them
+type ObservationConverter interface {
+	ObservationToFHIR(observation *models.Observation) *fhir.Observation
+}
+
+// ObservationPushHandler pushes newly created observations to dashboards over WebSocket
+// Each followed patient is a hub subscription with a bounded buffer, so a dashboard that stops
+// reading is disconnected instead of growing server memory
+type ObservationPushHandler struct {
+	hub                  *streaming.Hub
+	observationConverter ObservationConverter
+	idCodec              idcodec.Codec
+}
+
+// NewObservationPushHandler creates a new instance of ObservationPushHandler
+func NewObservationPushHandler(hub *streaming.Hub, observationConverter ObservationConverter) *ObservationPushHandler {
+	return &ObservationPushHandler{
+		hub:                  hub,
+		observationConverter: observationConverter,
+	}
+}
+
+// SetIDCodec makes the handler accept and send opaque patient and observation IDs
+func (handler *ObservationPushHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// observationPushCommand is a message from a client choosing which patients to follow
+type observationPushCommand struct {
+	Action  string `json:"action"`
+	Patient string `json:"patient"`
+}
+
+// observationPushMessage is a message sent to a client: an observation, an acknowledgement, or an error
+type observationPushMessage struct {
+	Type     string          `json:"type"`
+	Patient  string          `json:"patient,omitempty"`
+	Resource json.RawMessage `json:"resource,omitempty"`
+	Message  string          `json:"message,omitempty"`
+}
+
+// Consumer returns an event bus handler that publishes each created observation to its patient's topic
+func (handler *ObservationPushHandler) Consumer() events.Handler {
+	return func(ctx context.Context, event events.Event) error {
+		if event.Type != events.EventResourceCreated || event.ResourceType != "Observation" {
+			return nil
+		}
+		observation, isObservation := event.Resource.(*models.Observation)
+		if !isObservation || observation.PatientID == "" {
+			return nil
+		}
+
+		encodedObservation, encodeError := json.Marshal(handler.observationConverter.ObservationToFHIR(observation))
+		if encodeError != nil {
+			return encodeError
+		}
+		handler.hub.Publish(observationPushTopic(event.TenantID, observation.PatientID), encodedObservation)
+		return nil
+	}
+}
+
+// Serve handles GET /ws?patient={id} - pushes the tenant's new observations for the followed patients until the client disconnects
+// Clients follow more patients with {"action":"subscribe","patient":"123"} and stop with "unsubscribe"
+func (handler *ObservationPushHandler) Serve(w http.ResponseWriter, r *http.Request) {
+	conn, upgradeError := websocket.Upgrade(w, r, maxObservationPushCommand)
+	if errors.Is(upgradeError, websocket.ErrNotWebSocket) {
+		middleware.WriteError(w, r, apperrors.ValidationError("Expected a WebSocket upgrade request"))
+		return
+	}
+	if upgradeError != nil {
+		log.Error().Err(upgradeError).Msg("Failed to upgrade observation push connection")
+		return
+	}
+
+	sessionContext, cancelSession := context.WithCancel(r.Context())
+	session := &observationPushSession{
+		handler:       handler,
+		conn:          conn,
+		ctx:           sessionContext,
+		subscriptions: map[string]*streaming.Subscription{},
+	}
+	defer func() {
+		cancelSession()
+		session.closeSubscriptions()
+		conn.Close()
+	}()
+
+	for _, patientIDs := range r.URL.Query()["patient"] {
+		for _, patientID := range strings.Split(patientIDs, ",") {
+			if patientID = strings.TrimSpace(patientID); patientID != "" {
+				session.subscribe(patientID)
+			}
+		}
+	}
+
+	for {
+		_, message, readError := conn.ReadMessage()
+		if readError != nil {
+			return
+		}
+
+		var command observationPushCommand
+		if decodeError := json.Unmarshal(message, &command); decodeError != nil || command.Patient == "" {
+			session.send(observationPushMessage{Type: "error", Message: `expected {"action":"subscribe","patient":"{id}"} or "unsubscribe"`})
+			continue
+		}
+		switch command.Action {
+		case "subscribe":
+			session.subscribe(command.Patient)
+		case "unsubscribe":
+			session.unsubscribe(command.Patient)
+		default:
+			session.send(observationPushMessage{Type: "error", Patient: command.Patient, Message: "unknown action " + command.Action})
+		}
+	}
+}
+
+// observationPushSession is one client connection and the patients it follows
+type observationPushSession struct {
+	handler *ObservationPushHandler
+	conn    *websocket.Conn
+	ctx     context.Context
+
+	mutex sync.Mutex
+	// subscriptions holds each followed patient's hub subscription, keyed by the ID the client sent
+	subscriptions map[string]*streaming.Subscription
+}
+
+// subscribe starts forwarding the patient's new observations
+func (session *observationPushSession) subscribe(patientID string) {
+	internalPatientID := patientID
+	if session.handler.idCodec != nil {
+		decodedID, decodeError := session.handler.idCodec.Decode(tenant.VerifiedFromContext(session.ctx), "Patient", patientID)
+		if decodeError != nil {
+			session.send(observationPushMessage{Type: "error", Patient: patientID, Message: "unknown patient"})
+			return
+		}
+		internalPatientID = decodedID
+	}
+
+	session.mutex.Lock()
+	if _, following := session.subscriptions[patientID]; following {
+		session.mutex.Unlock()
+		session.send(observationPushMessage{Type: "subscribed", Patient: patientID})
+		return
+	}
+	if len(session.subscriptions) >= maxPushedPatients {
+		session.mutex.Unlock()
+		session.send(observationPushMessage{Type: "error", Patient: patientID, Message: "too many patients followed on one connection"})
+		return
+	}
+	subscription := session.handler.hub.Subscribe(observationPushTopic(tenant.FromContext(session.ctx), internalPatientID))
+	session.subscriptions[patientID] = subscription
+	session.mutex.Unlock()
+
+	// Acknowledge before forwarding so the client never sees an observation for a patient it has not been told it follows
+	session.send(observationPushMessage{Type: "subscribed", Patient: patientID})
+	go session.forward(patientID, subscription)
+}
+
+// unsubscribe stops forwarding the patient's observations
+func (session *observationPushSession) unsubscribe(patientID string) {
+	session.mutex.Lock()
+	subscription, following := session.subscriptions[patientID]
+	delete(session.subscriptions, patientID)
+	session.mutex.Unlock()
+
+	if following {
+		subscription.Close()
+	}
+	session.send(observationPushMessage{Type: "unsubscribed", Patient: patientID})
+}
+
+// forward sends the patient's observations until the subscription or the connection ends
+// A client too slow to keep up is disconnected so it can reconnect and catch up with a search
+func (session *observationPushSession) forward(patientID string, subscription *streaming.Subscription) {
+	for {
+		encodedObservation, nextError := subscription.Next(session.ctx)
+		if nextError != nil {
+			if errors.Is(nextError, streaming.ErrSlowConsumer) || errors.Is(nextError, streaming.ErrIdleTimeout) {
+				log.Info().Err(nextError).Str("topic", subscription.Topic()).Msg("Observation push client disconnected")
+				session.conn.CloseWithReason(websocket.ClosePolicyViolation, "slow consumer")
+			}
+			return
+		}
+
+		exposedObservation, exposeError := session.expose(encodedObservation)
+		if exposeError != nil {
+			log.Error().Err(exposeError).Str("topic", subscription.Topic()).Msg("Failed to expose pushed observation")
+			continue
+		}
+		message := observationPushMessage{Type: "observation", Patient: patientID, Resource: exposedObservation}
+		if sendError := session.send(message); sendError != nil {
+			session.conn.Close()
+			return
+		}
+	}
+}
+
+// send writes one message to the client
+func (session *observationPushSession) send(message observationPushMessage) error {
+	encodedMessage, encodeError := json.Marshal(message)
+	if encodeError != nil {
+		return encodeError
+	}
+	return session.conn.WriteText(encodedMessage, time.Now().Add(observationPushWriteTimeout))
+}
+
+// expose rewrites an observation's IDs to the opaque IDs issued to the client's tenant
+func (session *observationPushSession) expose(encodedObservation []byte) (json.RawMessage, error) {
+	if session.handler.idCodec == nil {
+		return encodedObservation, nil
+	}
+	var fhirObservation fhir.Observation
+	if decodeError := json.Unmarshal(encodedObservation, &fhirObservation); decodeError != nil {
+		return nil, decodeError
+	}
+	exposeID(session.ctx, session.handler.idCodec, "Observation", fhirObservation.Id)
+	exposeObservationReferences(session.ctx, session.handler.idCodec, &fhirObservation)
+	return json.Marshal(fhirObservation)
+}
+
+// closeSubscriptions ends every hub subscription of the session
+func (session *observationPushSession) closeSubscriptions() {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	for patientID, subscription := range session.subscriptions {
+		subscription.Close()
+		delete(session.subscriptions, patientID)
+	}
+}
+
+// observationPushTopic keeps each tenant's patients on their own topics
+func observationPushTopic(tenantID string, patientID string) string {
+	return "observations:" + tenantID + ":" + patientID
+}
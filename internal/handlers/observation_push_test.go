@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/streaming"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// plainObservationConverter converts observations with the default mapper
+type plainObservationConverter struct{}
+
+// ObservationToFHIR converts the observation without site hooks
+func (plainObservationConverter) ObservationToFHIR(observation *models.Observation) *fhir.Observation {
+	return models.NewObservationMapper().ToFHIR(observation)
+}
+
+// pushClient is a minimal WebSocket client for the observation push endpoint
+type pushClient struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+}
+
+// dialPush connects to the push endpoint at the path, failing the test unless the upgrade succeeds
+func dialPush(t *testing.T, server *httptest.Server, path string) *pushClient {
+	netConn, dialError := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if dialError != nil {
+		t.Fatalf("Failed to dial the test server: %v", dialError)
+	}
+	netConn.Write([]byte("GET " + path + " HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	reader := bufio.NewReader(netConn)
+	if response, readError := http.ReadResponse(reader, nil); readError != nil || response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected the connection upgraded, got %v (%v)", response, readError)
+	}
+	return &pushClient{netConn: netConn, reader: reader}
+}
+
+// send writes a masked text frame
+func (client *pushClient) send(message string) {
+	frame := []byte{0x81, 0x80 | byte(len(message)), 0, 0, 0, 0}
+	client.netConn.Write(append(frame, message...))
+}
+
+// receive reads the next text message sent by the server
+func (client *pushClient) receive(t *testing.T) observationPushMessage {
+	client.netConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	header := make([]byte, 2)
+	if _, readError := io.ReadFull(client.reader, header); readError != nil {
+		t.Fatalf("Failed to read a message: %v", readError)
+	}
+	payloadLength := int(header[1])
+	if payloadLength == 126 {
+		extended := make([]byte, 2)
+		io.ReadFull(client.reader, extended)
+		payloadLength = int(extended[0])<<8 | int(extended[1])
+	}
+	payload := make([]byte, payloadLength)
+	io.ReadFull(client.reader, payload)
+
+	var message observationPushMessage
+	json.Unmarshal(payload, &message)
+	return message
+}
+
+// TestObservationPushHandler verifies clients receive only new observations of the patients they follow in their tenant
+func TestObservationPushHandler(t *testing.T) {
+	hub := streaming.NewHub(streaming.DefaultConfig())
+	pushHandler := NewObservationPushHandler(hub, plainObservationConverter{})
+	server := httptest.NewServer(tenant.NewResolver(nil).Middleware(http.HandlerFunc(pushHandler.Serve)))
+	defer server.Close()
+
+	client := dialPush(t, server, "/ws?patient=123")
+	if acknowledgement := client.receive(t); acknowledgement.Type != "subscribed" || acknowledgement.Patient != "123" {
+		t.Fatalf("Expected the query's patient followed, got %+v", acknowledgement)
+	}
+	client.send(`{"action":"subscribe","patient":"456"}`)
+	if acknowledgement := client.receive(t); acknowledgement.Type != "subscribed" || acknowledgement.Patient != "456" {
+		t.Fatalf("Expected the second patient followed, got %+v", acknowledgement)
+	}
+
+	consume := pushHandler.Consumer()
+	publish := func(eventType events.EventType, tenantID string, observation *models.Observation) {
+		event := events.Event{Type: eventType, ResourceType: "Observation", ResourceID: observation.ID, TenantID: tenantID, Resource: observation}
+		if consumeError := consume(context.Background(), event); consumeError != nil {
+			t.Fatalf("Expected the event consumed, got %v", consumeError)
+		}
+	}
+	publish(events.EventResourceCreated, tenant.DefaultTenantID, &models.Observation{ID: "unfollowed", PatientID: "789", Code: "8480-6", Status: "final"})
+	publish(events.EventResourceCreated, "clinic-b", &models.Observation{ID: "other-tenant", PatientID: "123", Code: "8480-6", Status: "final"})
+	publish(events.EventResourceUpdated, tenant.DefaultTenantID, &models.Observation{ID: "updated", PatientID: "123", Code: "8480-6", Status: "final"})
+	publish(events.EventResourceCreated, tenant.DefaultTenantID, &models.Observation{ID: "obs-1", PatientID: "456", Code: "8480-6", Status: "final"})
+
+	pushed := client.receive(t)
+	var pushedObservation fhir.Observation
+	json.Unmarshal(pushed.Resource, &pushedObservation)
+	if pushed.Type != "observation" || pushed.Patient != "456" || pushedObservation.Id == nil || *pushedObservation.Id != "obs-1" {
+		t.Fatalf("Expected only the followed patient's new observation, got %+v", pushed)
+	}
+
+	client.send(`{"action":"unsubscribe","patient":"456"}`)
+	if acknowledgement := client.receive(t); acknowledgement.Type != "unsubscribed" {
+		t.Fatalf("Expected the patient unfollowed, got %+v", acknowledgement)
+	}
+	publish(events.EventResourceCreated, tenant.DefaultTenantID, &models.Observation{ID: "obs-2", PatientID: "456", Code: "8480-6", Status: "final"})
+	client.send(`{"action":"watch"}`)
+	if reply := client.receive(t); reply.Type != "error" {
+		t.Errorf("Expected an unfollowed patient's observation skipped and the bad command refused, got %+v", reply)
+	}
+}
+
+// TestObservationPushHandler_NotWebSocket verifies plain requests are refused with 400
+func TestObservationPushHandler_NotWebSocket(t *testing.T) {
+	pushHandler := NewObservationPushHandler(streaming.NewHub(streaming.DefaultConfig()), plainObservationConverter{})
+	recorder := httptest.NewRecorder()
+	pushHandler.Serve(recorder, httptest.NewRequest(http.MethodGet, "/ws?patient=123", nil))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", recorder.Code)
+	}
+}
@@ -7,25 +7,36 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
 // MockObservationService mocks the observation service for testing
 type MockObservationService struct {
-	observations       map[string]*fhir.Observation
-	createError        error
-	getByIDError       error
-	getByPatientError  error
-	getAllError        error
+	observations      map[string]*fhir.Observation
+	deleted           map[string]bool
+	createError       error
+	getByIDError      error
+	getByPatientError error
+	getAllError       error
+	createIssues      []outcome.Issue
+	lastSearchParams  *models.ObservationSearchParams
+	lastLastNParams   *models.ObservationLastNParams
+	nextCursor        *models.PageCursor
 }
 
 func NewMockObservationService() *MockObservationService {
 	return &MockObservationService{
 		observations: make(map[string]*fhir.Observation),
+		deleted:      make(map[string]bool),
 	}
 }
 
@@ -33,8 +44,11 @@ func (mock *MockObservationService) CreateObservation(ctx context.Context, fhirO
 	if mock.createError != nil {
 		return nil, mock.createError
 	}
+	outcome.Collect(ctx, mock.createIssues...)
 	id := "created-id-123"
+	versionID := "1"
 	fhirObservation.Id = &id
+	fhirObservation.Meta = &fhir.Meta{VersionId: &versionID}
 	mock.observations[id] = fhirObservation
 	return fhirObservation, nil
 }
@@ -43,9 +57,12 @@ func (mock *MockObservationService) GetObservationByID(ctx context.Context, obse
 	if mock.getByIDError != nil {
 		return nil, mock.getByIDError
 	}
+	if mock.deleted[observationID] {
+		return nil, deletedError(repository.ErrObservationNotFound)
+	}
 	observation, exists := mock.observations[observationID]
 	if !exists {
-		return nil, errors.New("observation not found")
+		return nil, repository.ErrObservationNotFound
 	}
 	return observation, nil
 }
@@ -76,25 +93,107 @@ func (mock *MockObservationService) GetAllObservations(ctx context.Context, limi
 	return result, nil
 }
 
-func (mock *MockObservationService) UpdateObservation(ctx context.Context, observationID string, fhirObservation *fhir.Observation) (*fhir.Observation, error) {
+func (mock *MockObservationService) UpdateObservation(ctx context.Context, observationID string, fhirObservation *fhir.Observation, expectedVersion int) (*fhir.Observation, error) {
+	if mock.deleted[observationID] {
+		return nil, service.ErrResourceDeleted
+	}
+	storedObservation, exists := mock.observations[observationID]
+	if !exists {
+		return nil, service.ErrResourceNotFound
+	}
+	storedVersion := 1
+	if storedObservation.Meta != nil && storedObservation.Meta.VersionId != nil {
+		storedVersion, _ = strconv.Atoi(*storedObservation.Meta.VersionId)
+	}
+	if expectedVersion != 0 && expectedVersion != storedVersion {
+		return nil, service.ErrVersionConflict
+	}
+	versionID := strconv.Itoa(storedVersion + 1)
+	fhirObservation.Id = &observationID
+	fhirObservation.Meta = &fhir.Meta{VersionId: &versionID}
+	mock.observations[observationID] = fhirObservation
 	return fhirObservation, nil
 }
 
 func (mock *MockObservationService) SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error) {
+	observations, _, searchError := mock.SearchObservationsPage(ctx, searchParams)
+	return observations, searchError
+}
+
+func (mock *MockObservationService) SearchObservationsPage(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, *models.PageCursor, error) {
+	mock.lastSearchParams = searchParams
 	if mock.getAllError != nil {
-		return nil, mock.getAllError
+		return nil, nil, mock.getAllError
 	}
 	result := make([]*fhir.Observation, 0, len(mock.observations))
 	for _, observation := range mock.observations {
 		result = append(result, observation)
 	}
-	return result, nil
+	return result, mock.nextCursor, nil
+}
+
+func (mock *MockObservationService) StreamObservations(ctx context.Context, searchParams *models.ObservationSearchParams, visit func(fhirObservation *fhir.Observation) error) error {
+	observations, _, searchError := mock.SearchObservationsPage(ctx, searchParams)
+	if searchError != nil {
+		return searchError
+	}
+	for _, observation := range observations {
+		if visitError := visit(observation); visitError != nil {
+			return visitError
+		}
+	}
+	return nil
+}
+
+func (mock *MockObservationService) CountObservations(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error) {
+	if mock.getAllError != nil {
+		return 0, mock.getAllError
+	}
+	return len(mock.observations), nil
+}
+
+func (mock *MockObservationService) LastObservations(ctx context.Context, lastNParams *models.ObservationLastNParams) ([]*fhir.Observation, error) {
+	mock.lastLastNParams = lastNParams
+	return mock.GetObservationsByPatientID(ctx, lastNParams.PatientID, lastNParams.Max, 0)
 }
 
 func (mock *MockObservationService) DeleteObservation(ctx context.Context, observationID string) error {
+	if mock.deleted[observationID] {
+		return service.ErrResourceDeleted
+	}
+	if _, exists := mock.observations[observationID]; !exists {
+		return service.ErrResourceNotFound
+	}
+	delete(mock.observations, observationID)
+	mock.deleted[observationID] = true
 	return nil
 }
 
+func (mock *MockObservationService) AddObservationTags(ctx context.Context, observationID string, tags models.Tags) (models.Tags, error) {
+	return mock.changeTags(observationID, func(currentTags models.Tags) models.Tags { return currentTags.Add(tags...) })
+}
+
+func (mock *MockObservationService) RemoveObservationTags(ctx context.Context, observationID string, tags models.Tags) (models.Tags, error) {
+	return mock.changeTags(observationID, func(currentTags models.Tags) models.Tags { return currentTags.Remove(tags...) })
+}
+
+func (mock *MockObservationService) changeTags(observationID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	if mock.deleted[observationID] {
+		return nil, service.ErrResourceDeleted
+	}
+	observation, exists := mock.observations[observationID]
+	if !exists {
+		return nil, service.ErrResourceNotFound
+	}
+	var currentTags models.Tags
+	if observation.Meta != nil {
+		currentTags, _ = models.TagsFromFHIR(observation.Meta.Tag, "Observation.meta.tag")
+	}
+	changedTags := change(currentTags)
+	observation.Meta = &fhir.Meta{Tag: models.TagsToFHIR(changedTags)}
+	return changedTags, nil
+}
+
 // TestObservationHandler_Create_Success verifies observation creation
 func TestObservationHandler_Create_Success(t *testing.T) {
 	mockService := NewMockObservationService()
@@ -123,6 +222,30 @@ func TestObservationHandler_Create_Success(t *testing.T) {
 	}
 }
 
+// TestObservationHandler_Create_CrossRegionSubject verifies a subject held by another region's deployment is refused
+func TestObservationHandler_Create_CrossRegionSubject(t *testing.T) {
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	residencyPolicy, _ := residency.NewPolicy(residency.Config{Regions: map[string]residency.Region{
+		"eu": {BaseURL: "https://eu.fhir.example.org"},
+		"us": {BaseURL: "https://us.fhir.example.org"},
+	}}, "eu")
+	handler.SetResidencyPolicy(residencyPolicy)
+
+	requestBody := `{"resourceType": "Observation", "status": "final", "subject": {"reference": "https://us.fhir.example.org/fhir/Patient/123"}}`
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Observation", bytes.NewBufferString(requestBody))
+	recorder := httptest.NewRecorder()
+
+	handler.Create(recorder, request)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403, got %d", recorder.Code)
+	}
+	if len(mockService.observations) != 0 {
+		t.Error("Expected no observation to be created")
+	}
+}
+
 // TestObservationHandler_Create_InvalidJSON verifies error on invalid JSON
 func TestObservationHandler_Create_InvalidJSON(t *testing.T) {
 	mockService := NewMockObservationService()
@@ -259,10 +382,10 @@ func TestObservationHandler_GetByPatientID_Success(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", recorder.Code)
 	}
 
-	var responseObservations []*fhir.Observation
-	json.NewDecoder(recorder.Body).Decode(&responseObservations)
-	if len(responseObservations) != 2 {
-		t.Errorf("Expected 2 observations, got %d", len(responseObservations))
+	var searchset fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&searchset)
+	if searchset.Total == nil || *searchset.Total != 2 || len(searchset.Entry) != 2 {
+		t.Errorf("Expected a searchset of 2 observations, got %+v", searchset)
 	}
 }
 
@@ -325,10 +448,58 @@ func TestObservationHandler_GetAll_Success(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", recorder.Code)
 	}
 
-	var responseObservations []*fhir.Observation
-	json.NewDecoder(recorder.Body).Decode(&responseObservations)
-	if len(responseObservations) != 2 {
-		t.Errorf("Expected 2 observations, got %d", len(responseObservations))
+	var searchset fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&searchset)
+	if searchset.Total == nil || *searchset.Total != 2 || len(searchset.Entry) != 2 {
+		t.Errorf("Expected a searchset of 2 observations, got %+v", searchset)
+	}
+}
+
+// TestObservationHandler_GetAll_Stream verifies _stream writes every match in one Bundle with only a self link
+func TestObservationHandler_GetAll_Stream(t *testing.T) {
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	code := "test"
+	for _, observationID := range []string{"obs-1", "obs-2", "obs-3"} {
+		mockService.observations[observationID] = &fhir.Observation{
+			Id:     &observationID,
+			Status: fhir.ObservationStatusFinal,
+			Code:   fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &code}}},
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?_stream=true&_count=1", nil)
+	recorder := httptest.NewRecorder()
+	handler.GetAll(recorder, request)
+
+	if recorder.Code != http.StatusOK || recorder.Header().Get("Content-Type") != "application/fhir+json" {
+		t.Fatalf("Expected a 200 FHIR JSON response, got %d %s", recorder.Code, recorder.Header().Get("Content-Type"))
+	}
+	if mockService.lastSearchParams.Limit != maxStreamedResults {
+		t.Errorf("Expected the stream to ignore _count, got limit %d", mockService.lastSearchParams.Limit)
+	}
+	var searchset fhir.Bundle
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&searchset); decodeError != nil {
+		t.Fatalf("Expected a valid Bundle, got %v", decodeError)
+	}
+	if searchset.Total == nil || *searchset.Total != 3 || len(searchset.Entry) != 3 {
+		t.Errorf("Expected every observation streamed, got %+v", searchset)
+	}
+	if len(searchset.Link) != 1 || searchset.Link[0].Relation != "self" {
+		t.Errorf("Expected only a self link, got %+v", searchset.Link)
+	}
+}
+
+// TestObservationHandler_GetAll_StreamInvalid verifies unsupported _stream values and XML streams are rejected
+func TestObservationHandler_GetAll_StreamInvalid(t *testing.T) {
+	handler := NewObservationHandler(NewMockObservationService())
+
+	for _, target := range []string{"/fhir/Observation?_stream=yes", "/fhir/Observation?_stream=true&_format=xml"} {
+		recorder := httptest.NewRecorder()
+		handler.GetAll(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, recorder.Code)
+		}
 	}
 }
 
@@ -385,3 +556,44 @@ func TestNewObservationHandler(t *testing.T) {
 		t.Error("Expected service to be set")
 	}
 }
+
+// TestObservationHandler_LastN verifies $lastn returns the patient's observations as a searchset
+func TestObservationHandler_LastN(t *testing.T) {
+	mockService := NewMockObservationService()
+	observationID := "obs-1"
+	patientReference := "Patient/patient-123"
+	mockService.observations[observationID] = &fhir.Observation{Id: &observationID, Subject: &fhir.Reference{Reference: &patientReference}}
+	handler := NewObservationHandler(mockService)
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation/$lastn?patient=patient-123&category=vital-signs&max=2", nil)
+	recorder := httptest.NewRecorder()
+	handler.LastN(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var searchBundle struct {
+		Type  string `json:"type"`
+		Total int    `json:"total"`
+	}
+	json.Unmarshal(recorder.Body.Bytes(), &searchBundle)
+	if searchBundle.Type != "searchset" || searchBundle.Total != 1 {
+		t.Errorf("Expected a searchset of one observation, got %s", recorder.Body.String())
+	}
+	if mockService.lastLastNParams.Max != 2 || mockService.lastLastNParams.Categories[0] != "vital-signs" {
+		t.Errorf("Expected the 2 newest vital signs to be requested, got %+v", mockService.lastLastNParams)
+	}
+}
+
+// TestObservationHandler_LastN_RequiresPatientAndCriteria verifies $lastn needs a patient and a code or category
+func TestObservationHandler_LastN_RequiresPatientAndCriteria(t *testing.T) {
+	handler := NewObservationHandler(NewMockObservationService())
+
+	for _, target := range []string{"/fhir/Observation/$lastn?category=vital-signs", "/fhir/Observation/$lastn?patient=patient-123", "/fhir/Observation/$lastn?patient=patient-123&code=8480-6&max=0"} {
+		recorder := httptest.NewRecorder()
+		handler.LastN(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", target, recorder.Code)
+		}
+	}
+}
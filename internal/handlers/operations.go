@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+)
+
+// defaultDrainTimeout bounds how long a drain request waits for in-flight requests
+const defaultDrainTimeout = 30 * time.Second
+
+// OperationsStatusResponse reports the server's operational state
+type OperationsStatusResponse struct {
+	Maintenance bool  `json:"maintenance"`
+	Draining    bool  `json:"draining"`
+	InFlight    int64 `json:"in_flight"`
+	Drained     *bool `json:"drained,omitempty"`
+}
+
+// ToggleRequest is the body for enabling or disabling an operational mode
+type ToggleRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+// OperationsHandler serves runbook endpoints for maintenance mode and connection draining
+type OperationsHandler struct {
+	operationalState *middleware.OperationalState
+}
+
+// NewOperationsHandler creates a new instance of OperationsHandler
+func NewOperationsHandler(operationalState *middleware.OperationalState) *OperationsHandler {
+	return &OperationsHandler{
+		operationalState: operationalState,
+	}
+}
+
+// Status handles GET /admin/operations - reports maintenance, drain, and in-flight counts
+func (handler *OperationsHandler) Status(w http.ResponseWriter, r *http.Request) {
+	handler.writeStatus(w, nil)
+}
+
+// SetMaintenance handles PUT /admin/operations/maintenance - turns maintenance mode on or off
+func (handler *OperationsHandler) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !requireOperator(w, r) {
+		return
+	}
+
+	var toggleRequest ToggleRequest
+	decodeError := json.NewDecoder(r.Body).Decode(&toggleRequest)
+	if decodeError != nil || toggleRequest.Enabled == nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("enabled", "must be true or false"))
+		return
+	}
+
+	handler.operationalState.SetMaintenance(*toggleRequest.Enabled)
+	handler.writeStatus(w, nil)
+}
+
+// Drain handles POST /admin/operations/drain - stops accepting new requests and waits for in-flight ones
+// The optional timeout query parameter (e.g. ?timeout=10s) bounds the wait
+func (handler *OperationsHandler) Drain(w http.ResponseWriter, r *http.Request) {
+	if !requireOperator(w, r) {
+		return
+	}
+
+	drainTimeout := defaultDrainTimeout
+	if timeoutParam := r.URL.Query().Get("timeout"); timeoutParam != "" {
+		parsedTimeout, parseError := time.ParseDuration(timeoutParam)
+		if parseError != nil || parsedTimeout <= 0 {
+			middleware.WriteError(w, r, apperrors.InvalidInput("timeout", "must be a positive duration such as 30s"))
+			return
+		}
+		drainTimeout = parsedTimeout
+	}
+
+	handler.operationalState.SetDraining(true)
+
+	// Wait for in-flight requests to finish; a timeout is reported rather than treated as an error
+	drainContext, cancel := context.WithTimeout(r.Context(), drainTimeout)
+	defer cancel()
+	drained := handler.operationalState.WaitForDrain(drainContext) == nil
+
+	handler.writeStatus(w, &drained)
+}
+
+// Resume handles DELETE /admin/operations/drain - accepts new requests again after a drain
+func (handler *OperationsHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	if !requireOperator(w, r) {
+		return
+	}
+
+	handler.operationalState.SetDraining(false)
+	handler.writeStatus(w, nil)
+}
+
+// requireOperator writes a 403 and returns false unless the caller has the operator or admin role
+func requireOperator(w http.ResponseWriter, r *http.Request) bool {
+	if !auth.FromContext(r.Context()).HasAnyRole(auth.RoleOperator, auth.RoleAdmin) {
+		middleware.WriteError(w, r, apperrors.Forbidden("Maintenance and drain can only be changed by the operator or admin role"))
+		return false
+	}
+	return true
+}
+
+// writeStatus writes the current operational state as JSON
+func (handler *OperationsHandler) writeStatus(w http.ResponseWriter, drained *bool) {
+	statusResponse := OperationsStatusResponse{
+		Maintenance: handler.operationalState.Maintenance(),
+		Draining:    handler.operationalState.Draining(),
+		InFlight:    handler.operationalState.InFlight(),
+		Drained:     drained,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(statusResponse)
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+)
+
+// decodeOperationsStatus decodes the operations status response
+func decodeOperationsStatus(t *testing.T, recorder *httptest.ResponseRecorder) OperationsStatusResponse {
+	var statusResponse OperationsStatusResponse
+	decodeError := json.NewDecoder(recorder.Body).Decode(&statusResponse)
+	if decodeError != nil {
+		t.Fatalf("Failed to decode response: %v", decodeError)
+	}
+	return statusResponse
+}
+
+// TestOperationsHandler_SetMaintenance verifies maintenance mode can be toggled
+func TestOperationsHandler_SetMaintenance(t *testing.T) {
+	state := middleware.NewOperationalState(30 * time.Second)
+	handler := NewOperationsHandler(state)
+
+	request := withRoles(httptest.NewRequest(http.MethodPut, "/admin/operations/maintenance", bytes.NewBufferString(`{"enabled": true}`)), auth.RoleOperator)
+	recorder := httptest.NewRecorder()
+	handler.SetMaintenance(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	if !decodeOperationsStatus(t, recorder).Maintenance || !state.Maintenance() {
+		t.Error("Expected maintenance mode to be enabled")
+	}
+}
+
+// TestOperationsHandler_SetMaintenance_InvalidBody verifies a missing flag is rejected
+func TestOperationsHandler_SetMaintenance_InvalidBody(t *testing.T) {
+	handler := NewOperationsHandler(middleware.NewOperationalState(0))
+
+	request := withRoles(httptest.NewRequest(http.MethodPut, "/admin/operations/maintenance", bytes.NewBufferString(`{}`)), auth.RoleOperator)
+	recorder := httptest.NewRecorder()
+	handler.SetMaintenance(recorder, request)
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", recorder.Code)
+	}
+}
+
+// TestOperationsHandler_RequiresOperator verifies maintenance and drain changes are refused without the operator or admin role
+func TestOperationsHandler_RequiresOperator(t *testing.T) {
+	state := middleware.NewOperationalState(0)
+	handler := NewOperationsHandler(state)
+
+	maintenanceRecorder := httptest.NewRecorder()
+	handler.SetMaintenance(maintenanceRecorder, withRoles(httptest.NewRequest(http.MethodPut, "/admin/operations/maintenance", bytes.NewBufferString(`{"enabled": true}`)), auth.RoleCompliance))
+	drainRecorder := httptest.NewRecorder()
+	handler.Drain(drainRecorder, httptest.NewRequest(http.MethodPost, "/admin/operations/drain?timeout=1s", nil))
+
+	if maintenanceRecorder.Code != http.StatusForbidden || drainRecorder.Code != http.StatusForbidden {
+		t.Errorf("Expected status 403 for both, got %d and %d", maintenanceRecorder.Code, drainRecorder.Code)
+	}
+	if state.Maintenance() || state.Draining() {
+		t.Error("Expected refused requests not to change the operational state")
+	}
+
+	state.SetDraining(true)
+	resumeRecorder := httptest.NewRecorder()
+	handler.Resume(resumeRecorder, httptest.NewRequest(http.MethodDelete, "/admin/operations/drain", nil))
+	if resumeRecorder.Code != http.StatusForbidden || !state.Draining() {
+		t.Errorf("Expected resume to be refused, got %d", resumeRecorder.Code)
+	}
+}
+
+// TestOperationsHandler_DrainAndResume verifies draining completes when idle and can be undone
+func TestOperationsHandler_DrainAndResume(t *testing.T) {
+	state := middleware.NewOperationalState(0)
+	handler := NewOperationsHandler(state)
+
+	drainRequest := withRoles(httptest.NewRequest(http.MethodPost, "/admin/operations/drain?timeout=1s", nil), auth.RoleAdmin)
+	drainRecorder := httptest.NewRecorder()
+	handler.Drain(drainRecorder, drainRequest)
+
+	drainStatus := decodeOperationsStatus(t, drainRecorder)
+	if !drainStatus.Draining {
+		t.Error("Expected server to be draining")
+	}
+	if drainStatus.Drained == nil || !*drainStatus.Drained {
+		t.Error("Expected drain to complete with no in-flight requests")
+	}
+
+	resumeRecorder := httptest.NewRecorder()
+	handler.Resume(resumeRecorder, withRoles(httptest.NewRequest(http.MethodDelete, "/admin/operations/drain", nil), auth.RoleAdmin))
+	if decodeOperationsStatus(t, resumeRecorder).Draining {
+		t.Error("Expected draining to stop after resume")
+	}
+}
+
+// TestOperationsHandler_Drain_InvalidTimeout verifies bad timeouts are rejected
+func TestOperationsHandler_Drain_InvalidTimeout(t *testing.T) {
+	state := middleware.NewOperationalState(0)
+	handler := NewOperationsHandler(state)
+
+	recorder := httptest.NewRecorder()
+	handler.Drain(recorder, withRoles(httptest.NewRequest(http.MethodPost, "/admin/operations/drain?timeout=soon", nil), auth.RoleOperator))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400, got %d", recorder.Code)
+	}
+	if state.Draining() {
+		t.Error("Expected invalid request not to start a drain")
+	}
+}
+
+// TestOperationsHandler_Status verifies the status endpoint reports the state
+func TestOperationsHandler_Status(t *testing.T) {
+	state := middleware.NewOperationalState(0)
+	state.SetMaintenance(true)
+	handler := NewOperationsHandler(state)
+
+	recorder := httptest.NewRecorder()
+	handler.Status(recorder, httptest.NewRequest(http.MethodGet, "/admin/operations", nil))
+
+	statusResponse := decodeOperationsStatus(t, recorder)
+	if !statusResponse.Maintenance || statusResponse.Draining || statusResponse.InFlight != 0 {
+		t.Errorf("Unexpected status: %+v", statusResponse)
+	}
+}
@@ -1,25 +1,46 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
 	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/locking"
 	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/searchcost"
 	"github.com/nathannewyen/fhir-health-interop/internal/service"
 	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/rs/zerolog/log"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
 // PatientHandler handles Patient FHIR resource requests
 type PatientHandler struct {
 	patientService *service.PatientService
-}
+	lockManager    *locking.Manager
+	searchPolicy   *searchcost.Policy
+	idCodec        idcodec.Codec
+	historyService *service.HistoryService
+
+	// compartmentService serves $everything and _revinclude
+	compartmentService *service.CompartmentService
+
+	// healthExportService serves $health-export
+	healthExportService *service.HealthExportService
+
+	// mergeService serves $merge
+	mergeService *service.PatientMergeService
 
-// NewPatientHandler creates a new instance of PatientHandler
-func NewPatientHandler() *PatientHandler {
-	return &PatientHandler{}
+	// matchService serves $match
+	matchService *service.PatientMatchService
 }
 
 // NewPatientHandlerWithService creates a PatientHandler with a service layer
@@ -29,27 +50,95 @@ func NewPatientHandlerWithService(patientService *service.PatientService) *Patie
 	}
 }
 
+// SetSearchPolicy enables cost-based guardrails that downgrade or reject expensive searches
+func (handler *PatientHandler) SetSearchPolicy(policy searchcost.Policy) {
+	handler.searchPolicy = &policy
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *PatientHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// SetHistoryService enables point-in-time reads with _at and asOfVersion
+func (handler *PatientHandler) SetHistoryService(historyService *service.HistoryService) {
+	handler.historyService = historyService
+}
+
+// SetLockManager enables advisory edit locks: reads report the holder and writes from
+// a client identifying itself with X-Edit-Lock-Owner are refused while someone else holds the lock
+func (handler *PatientHandler) SetLockManager(lockManager *locking.Manager) {
+	handler.lockManager = lockManager
+}
+
+// exposePatient rewrites a patient's ID and link references to their exposed forms
+func exposePatient(ctx context.Context, codec idcodec.Codec, fhirPatient *fhir.Patient) {
+	exposeID(ctx, codec, "Patient", fhirPatient.Id)
+	for index := range fhirPatient.Link {
+		exposeReference(ctx, codec, fhirPatient.Link[index].Other.Reference)
+	}
+}
+
+// resolveLinks rewrites exposed link references to the stored patient IDs, writing a 400 when one is unknown
+func (handler *PatientHandler) resolveLinks(w http.ResponseWriter, r *http.Request, fhirPatient *fhir.Patient) bool {
+	for index := range fhirPatient.Link {
+		if resolveError := resolveReference(r.Context(), handler.idCodec, fhirPatient.Link[index].Other.Reference); resolveError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput("link", "Unknown patient reference"))
+			return false
+		}
+	}
+	return true
+}
+
+// checkEditLock reports whether the request may write the patient, writing a 409 when it may not
+// patientID is the internal ID, which locks are keyed by whatever ID form clients see
+// Clients that do not send X-Edit-Lock-Owner are not participating in locking and are always allowed
+func (handler *PatientHandler) checkEditLock(w http.ResponseWriter, r *http.Request, patientID string) bool {
+	owner := r.Header.Get(locking.HeaderLockOwner)
+	if handler.lockManager == nil || owner == "" {
+		return true
+	}
+
+	activeLock, allowed, checkError := handler.lockManager.CheckWrite(r.Context(), "Patient", patientID, owner)
+	if checkError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to check edit lock", checkError))
+		return false
+	}
+	if !allowed {
+		setLockHeaders(w, activeLock)
+		middleware.WriteError(w, r, lockConflict(activeLock))
+	}
+	return allowed
+}
+
 // Create handles POST /fhir/Patient - creates a new patient
 func (handler *PatientHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Parse the FHIR Patient from request body
 	var fhirPatient fhir.Patient
-	decodeError := json.NewDecoder(r.Body).Decode(&fhirPatient)
+	decodeError := encoding.Decode(r, &fhirPatient)
 	if decodeError != nil {
 		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Patient JSON"))
 		return
 	}
 
+	// Translate exposed link references back to the stored IDs
+	if !handler.resolveLinks(w, r, &fhirPatient) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
 	// Create patient using service layer
-	createdPatient, createError := handler.patientService.CreatePatient(r.Context(), &fhirPatient)
+	createdPatient, createError := handler.patientService.CreatePatient(issueContext, &fhirPatient)
 	if createError != nil {
-		middleware.WriteError(w, r, apperrors.Internal("Failed to create patient", createError))
+		writeWriteError(w, r, createError, "Failed to create patient")
 		return
 	}
+	exposePatient(r.Context(), handler.idCodec, createdPatient)
 
 	// Return created patient with 201 status
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(createdPatient)
+	writeWriteResult(w, r, http.StatusCreated, createdPatient, issueCollector.Issues())
 }
 
 // GetByID handles GET /fhir/Patient/{id} - retrieves a patient by ID
@@ -61,17 +150,37 @@ func (handler *PatientHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Translate an exposed ID back to the stored one
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	// Answer reads of a past time or version from the change log
+	if isPointInTimeRead(r) {
+		readPointInTime(w, r, handler.historyService, handler.idCodec, "Patient", patientID, internalPatientID)
+		return
+	}
+
 	// Get patient using service layer
-	fhirPatient, getError := handler.patientService.GetPatientByID(r.Context(), patientID)
+	fhirPatient, getError := handler.patientService.GetPatientByID(r.Context(), internalPatientID)
 	if getError != nil {
-		middleware.WriteError(w, r, apperrors.NotFound("Patient", patientID))
+		writeNotFoundOrGone(w, r, getError, "Patient", patientID)
 		return
 	}
+	exposePatient(r.Context(), handler.idCodec, fhirPatient)
+
+	// Surface any edit lock so clients can warn before editing
+	if handler.lockManager != nil {
+		activeLock, lockError := handler.lockManager.Get(r.Context(), "Patient", internalPatientID)
+		if lockError != nil {
+			log.Warn().Err(lockError).Str("patient_id", internalPatientID).Msg("Failed to read edit lock; serving the patient without it")
+		}
+		setLockHeaders(w, activeLock)
+	}
 
 	// Return patient
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(fhirPatient)
+	encoding.Write(w, r, http.StatusOK, subsetElements("Patient", fhirPatient, utils.ParseElementsParameter(r)))
 }
 
 // GetAll handles GET /fhir/Patient - retrieves all patients with optional search parameters
@@ -79,21 +188,83 @@ func (handler *PatientHandler) GetAll(w http.ResponseWriter, r *http.Request) {
 	// Parse search parameters from query string
 	searchParams, parseError := utils.ParsePatientSearchParams(r)
 	if parseError != nil {
-		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		middleware.WriteError(w, r, apperrors.ValidationError(parseError.Error()))
+		return
+	}
+
+	// Only observations can be reverse-included, and only when the compartment service is configured
+	includeObservations, validRevIncludes := handler.parseRevIncludes(w, r)
+	if !validRevIncludes {
+		return
+	}
+
+	// _stream sends every match, up to maxStreamedResults, in one Bundle written as the matches are read
+	streamResults, validStream := parseStreamParameter(w, r)
+	if !validStream {
+		return
+	}
+	if streamResults {
+		if includeObservations {
+			middleware.WriteError(w, r, apperrors.ValidationError("_stream cannot be combined with _revinclude"))
+			return
+		}
+		searchParams.Limit = maxStreamedResults
+		searchParams.CursorPaging = false
+	}
+
+	// Refuse or shrink searches that would scan far more data than they return
+	if !applySearchPolicy(w, r, handler.searchPolicy, searchcost.EstimatePatientSearch(searchParams), &searchParams.Limit) {
+		return
+	}
+	if streamResults {
+		handler.streamSearch(w, r, searchParams)
 		return
 	}
 
 	// Search patients using service layer
-	fhirPatients, searchError := handler.patientService.SearchPatients(r.Context(), searchParams)
+	fhirPatients, nextCursor, searchError := handler.patientService.SearchPatientsPage(r.Context(), searchParams)
 	if searchError != nil {
 		middleware.WriteError(w, r, apperrors.Internal("Failed to search patients", searchError))
 		return
 	}
+	total, countError := handler.patientService.CountPatients(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count patients", countError))
+		return
+	}
+	page := searchPage(r, total, searchParams.Limit, searchParams.Offset, searchParams.CursorPaging, nextCursor)
+	internalPatientIDs := make([]string, 0, len(fhirPatients))
+	for _, fhirPatient := range fhirPatients {
+		if fhirPatient.Id != nil {
+			internalPatientIDs = append(internalPatientIDs, *fhirPatient.Id)
+		}
+		exposePatient(r.Context(), handler.idCodec, fhirPatient)
+	}
+
+	// _revinclude adds the patients' observations to the Bundle as include entries
+	if includeObservations {
+		handler.writeRevIncludeBundle(w, r, fhirPatients, internalPatientIDs, page)
+		return
+	}
+
+	// Return this page of patients as a searchset Bundle with the total and paging links
+	writeSearchResults(w, r, "Patient", fhirPatients, utils.ParseElementsParameter(r), page)
+}
+
+// streamSearch responds to a _stream search with every match, written to the client as it is read
+func (handler *PatientHandler) streamSearch(w http.ResponseWriter, r *http.Request, searchParams *models.PatientSearchParams) {
+	total, countError := handler.patientService.CountPatients(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count patients", countError))
+		return
+	}
 
-	// Return patients as FHIR Bundle (simplified - just array for now)
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(fhirPatients)
+	streamSearchResults(w, r, "Patient", total, utils.ParseElementsParameter(r), func(visit func(fhirPatient *fhir.Patient) error) error {
+		return handler.patientService.StreamPatients(r.Context(), searchParams, func(fhirPatient *fhir.Patient) error {
+			exposePatient(r.Context(), handler.idCodec, fhirPatient)
+			return visit(fhirPatient)
+		})
+	})
 }
 
 // Update handles PUT /fhir/Patient/{id} - updates an existing patient
@@ -107,7 +278,7 @@ func (handler *PatientHandler) Update(w http.ResponseWriter, r *http.Request) {
 
 	// Parse the FHIR Patient from request body
 	var fhirPatient fhir.Patient
-	decodeError := json.NewDecoder(r.Body).Decode(&fhirPatient)
+	decodeError := encoding.Decode(r, &fhirPatient)
 	if decodeError != nil {
 		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Patient JSON"))
 		return
@@ -119,17 +290,44 @@ func (handler *PatientHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Translate an exposed ID back to the stored one
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+	if fhirPatient.Id != nil {
+		fhirPatient.Id = &internalPatientID
+	}
+	if !handler.resolveLinks(w, r, &fhirPatient) {
+		return
+	}
+
+	// Refuse the edit while another participating client holds the lock
+	if !handler.checkEditLock(w, r, internalPatientID) {
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
 	// Update patient using service layer (ID is passed separately)
-	updatedPatient, updateError := handler.patientService.UpdatePatient(r.Context(), patientID, &fhirPatient)
+	updatedPatient, updateError := handler.patientService.UpdatePatient(issueContext, internalPatientID, &fhirPatient)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Patient", patientID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Patient", patientID))
+		return
+	}
 	if updateError != nil {
-		middleware.WriteError(w, r, apperrors.Internal("Failed to update patient", updateError))
+		writeWriteError(w, r, updateError, "Failed to update patient")
 		return
 	}
+	exposePatient(r.Context(), handler.idCodec, updatedPatient)
 
 	// Return updated patient with 200 OK
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(updatedPatient)
+	writeWriteResult(w, r, http.StatusOK, updatedPatient, issueCollector.Issues())
 }
 
 // Delete handles DELETE /fhir/Patient/{id} - deletes a patient
@@ -141,10 +339,21 @@ func (handler *PatientHandler) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Translate an exposed ID back to the stored one
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	// Refuse the delete while another participating client holds the lock
+	if !handler.checkEditLock(w, r, internalPatientID) {
+		return
+	}
+
 	// Delete patient using service layer
-	deleteError := handler.patientService.DeletePatient(r.Context(), patientID)
+	deleteError := handler.patientService.DeletePatient(r.Context(), internalPatientID)
 	if deleteError != nil {
-		middleware.WriteError(w, r, apperrors.NotFound("Patient", patientID))
+		writeDeleteError(w, r, deleteError, "Patient", patientID)
 		return
 	}
 
@@ -152,44 +361,104 @@ func (handler *PatientHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// GetSamplePatient returns a hardcoded sample FHIR Patient resource
-// This endpoint demonstrates the FHIR Patient structure before database integration
-func (h *PatientHandler) GetSamplePatient(w http.ResponseWriter, r *http.Request) {
-	// Create a sample FHIR Patient resource with hardcoded data
-	// Using pointers for optional FHIR fields as per the library spec
-	patientIdentifier := "12345"
-	patientIdentifierSystem := "http://hospital.example.org/patients"
-	patientFamilyName := "Smith"
-	patientGivenName := "John"
-	patientGender := fhir.AdministrativeGenderMale
-	patientBirthDate := "1990-01-15"
-	patientActive := true
-
-	// Build the FHIR Patient resource following R4 specification
-	// Note: ResourceType is automatically handled by the FHIR library marshaling
-	samplePatient := fhir.Patient{
-		Id:     &patientIdentifier,
-		Active: &patientActive,
-		Name: []fhir.HumanName{
-			{
-				Family: &patientFamilyName,
-				Given:  []string{patientGivenName},
-			},
-		},
-		Gender:    &patientGender,
-		BirthDate: &patientBirthDate,
-		Identifier: []fhir.Identifier{
-			{
-				System: &patientIdentifierSystem,
-				Value:  &patientIdentifier,
-			},
-		},
-	}
-
-	// Set FHIR-compliant response headers
-	w.Header().Set("Content-Type", "application/fhir+json")
-	w.WriteHeader(http.StatusOK)
-
-	// Encode and return the Patient resource as JSON
-	json.NewEncoder(w).Encode(samplePatient)
+// Meta handles GET /fhir/Patient/{id}/$meta - returns the patient's meta
+func (handler *PatientHandler) Meta(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	fhirPatient, getError := handler.patientService.GetPatientByID(r.Context(), internalPatientID)
+	if getError != nil {
+		writeNotFoundOrGone(w, r, getError, "Patient", patientID)
+		return
+	}
+
+	writeMeta(w, fhirPatient.Meta)
+}
+
+// MetaAdd handles POST /fhir/Patient/{id}/$meta-add - adds tags to the patient
+func (handler *PatientHandler) MetaAdd(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved || !handler.checkEditLock(w, r, internalPatientID) {
+		return
+	}
+	changeTags(w, r, "Patient", patientID, internalPatientID, handler.patientService.AddPatientTags)
+}
+
+// MetaDelete handles POST /fhir/Patient/{id}/$meta-delete - removes tags from the patient
+func (handler *PatientHandler) MetaDelete(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved || !handler.checkEditLock(w, r, internalPatientID) {
+		return
+	}
+	changeTags(w, r, "Patient", patientID, internalPatientID, handler.patientService.RemovePatientTags)
+}
+
+// Snapshot handles GET /fhir/Patient/{id}/$snapshot?_at={instant} - returns the patient's compartment as it was at that time
+func (handler *PatientHandler) Snapshot(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+	if handler.historyService == nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput(asOfTimeParameter, "point-in-time reads are not enabled"))
+		return
+	}
+
+	at, parseError := parseAsOfTime(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, parseError)
+		return
+	}
+
+	resources, readError := handler.historyService.PatientCompartmentAsOf(r.Context(), internalPatientID, at)
+	if readError != nil {
+		writeHistoryError(w, r, readError, "Patient", patientID)
+		return
+	}
+
+	entries := make([]fhir.BundleEntry, 0, len(resources))
+	for _, resource := range resources {
+		exposeHistoricalIDs(r.Context(), handler.idCodec, resource)
+		encodedResource, encodeError := json.Marshal(resource)
+		if encodeError != nil {
+			middleware.WriteError(w, r, apperrors.Internal("Failed to encode snapshot", encodeError))
+			return
+		}
+		entries = append(entries, fhir.BundleEntry{Resource: encodedResource})
+	}
+
+	total := len(entries)
+	timestamp := at.UTC().Format(time.RFC3339)
+	encoding.Write(w, r, http.StatusOK, fhir.Bundle{
+		Type:      fhir.BundleTypeCollection,
+		Timestamp: &timestamp,
+		Total:     &total,
+		Entry:     entries,
+	})
+}
+
+// History handles GET /fhir/Patient/{id}/_history - lists the patient's versions, deletes included, newest first
+func (handler *PatientHandler) History(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+	writeVersionHistory(w, r, handler.historyService, handler.idCodec, "Patient", patientID, internalPatientID)
+}
+
+// VRead handles GET /fhir/Patient/{id}/_history/{version} - reads one version of the patient, answering 410 for a delete
+func (handler *PatientHandler) VRead(w http.ResponseWriter, r *http.Request) {
+	patientID := chi.URLParam(r, "id")
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+	readVersion(w, r, handler.historyService, handler.idCodec, "Patient", patientID, internalPatientID)
 }
@@ -3,10 +3,12 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/go-chi/chi/v5"
@@ -18,6 +20,7 @@ import (
 // MockPatientRepository implements repository.PatientRepository for handler tests
 type MockPatientRepository struct {
 	patients     map[string]*models.Patient
+	deleted      map[string]bool
 	createError  error
 	getByIDError error
 	getAllError  error
@@ -26,6 +29,7 @@ type MockPatientRepository struct {
 func NewMockPatientRepository() *MockPatientRepository {
 	return &MockPatientRepository{
 		patients: make(map[string]*models.Patient),
+		deleted:  make(map[string]bool),
 	}
 }
 
@@ -39,6 +43,9 @@ func (mock *MockPatientRepository) Create(ctx context.Context, patient *models.P
 }
 
 func (mock *MockPatientRepository) GetByID(ctx context.Context, patientID string) (*models.Patient, error) {
+	if mock.deleted[patientID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
 	if mock.getByIDError != nil {
 		return nil, mock.getByIDError
 	}
@@ -61,6 +68,13 @@ func (mock *MockPatientRepository) GetAll(ctx context.Context, limit int, offset
 }
 
 func (mock *MockPatientRepository) Update(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
+	if mock.deleted[patient.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.patients[patient.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.patients[patient.ID] = patient
 	return patient, nil
 }
 
@@ -75,10 +89,50 @@ func (mock *MockPatientRepository) Search(ctx context.Context, searchParams *mod
 	return result, nil
 }
 
+func (mock *MockPatientRepository) Count(ctx context.Context, searchParams *models.PatientSearchParams) (int, error) {
+	if mock.getAllError != nil {
+		return 0, mock.getAllError
+	}
+	return len(mock.patients), nil
+}
+
+func (mock *MockPatientRepository) Stream(ctx context.Context, searchParams *models.PatientSearchParams, visit func(patient *models.Patient) error) error {
+	results, searchError := mock.Search(ctx, searchParams)
+	if searchError != nil {
+		return searchError
+	}
+	for _, result := range results {
+		if visitError := visit(result); visitError != nil {
+			return visitError
+		}
+	}
+	return nil
+}
+
 func (mock *MockPatientRepository) Delete(ctx context.Context, patientID string) error {
+	if mock.deleted[patientID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.patients[patientID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.patients, patientID)
+	mock.deleted[patientID] = true
 	return nil
 }
 
+func (mock *MockPatientRepository) UpdateTags(ctx context.Context, patientID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	if mock.deleted[patientID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	patient, exists := mock.patients[patientID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	patient.Tags = change(patient.Tags)
+	return patient.Tags, nil
+}
+
 // TestPatientHandler_Create_Success verifies POST /fhir/Patient creates a patient
 func TestPatientHandler_Create_Success(t *testing.T) {
 	mockRepo := NewMockPatientRepository()
@@ -256,15 +310,89 @@ func TestPatientHandler_GetAll_Success(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", recorder.Code)
 	}
 
-	// Verify response is array
-	var responsePatients []*fhir.Patient
-	decodeError := json.NewDecoder(recorder.Body).Decode(&responsePatients)
+	// Verify response is a searchset Bundle
+	var searchset fhir.Bundle
+	decodeError := json.NewDecoder(recorder.Body).Decode(&searchset)
 	if decodeError != nil {
 		t.Fatalf("Failed to decode response: %v", decodeError)
 	}
 
-	if len(responsePatients) != 2 {
-		t.Errorf("Expected 2 patients, got %d", len(responsePatients))
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 2 || len(searchset.Entry) != 2 {
+		t.Fatalf("Expected a searchset of 2 patients, got %+v", searchset)
+	}
+	for _, entry := range searchset.Entry {
+		if entry.FullUrl == nil || !strings.HasPrefix(*entry.FullUrl, "http://example.com/fhir/Patient/uuid-") {
+			t.Errorf("Expected an absolute fullUrl, got %v", entry.FullUrl)
+		}
+		if entry.Search == nil || entry.Search.Mode == nil || *entry.Search.Mode != fhir.SearchEntryModeMatch {
+			t.Errorf("Expected a match entry, got %+v", entry.Search)
+		}
+	}
+}
+
+// TestPatientHandler_GetAll_Paging verifies the searchset total counts every match and links page through them
+func TestPatientHandler_GetAll_Paging(t *testing.T) {
+	mockRepo := NewMockPatientRepository()
+	for _, patientID := range []string{"uuid-1", "uuid-2", "uuid-3", "uuid-4", "uuid-5"} {
+		mockRepo.patients[patientID] = &models.Patient{ID: patientID, FamilyName: "Smith"}
+	}
+	handler := NewPatientHandlerWithService(service.NewPatientService(mockRepo))
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?family=Smith&_count=2&_offset=2", nil)
+	recorder := httptest.NewRecorder()
+	handler.GetAll(recorder, request)
+
+	var searchset fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&searchset)
+	if searchset.Total == nil || *searchset.Total != 5 {
+		t.Fatalf("Expected total 5, got %v", searchset.Total)
+	}
+
+	expectedLinks := map[string]string{
+		"self":     "http://example.com/fhir/Patient?_count=2&_offset=2&family=Smith",
+		"next":     "http://example.com/fhir/Patient?_count=2&_offset=4&family=Smith",
+		"previous": "http://example.com/fhir/Patient?_count=2&_offset=0&family=Smith",
+	}
+	if len(searchset.Link) != len(expectedLinks) {
+		t.Fatalf("Expected self, next, and previous links, got %+v", searchset.Link)
+	}
+	for _, link := range searchset.Link {
+		if expectedLinks[link.Relation] != link.Url {
+			t.Errorf("Expected %s link %s, got %s", link.Relation, expectedLinks[link.Relation], link.Url)
+		}
+	}
+}
+
+// TestPatientHandler_GetAll_CursorPaging verifies searches in creation order link to the next page with a _cursor
+func TestPatientHandler_GetAll_CursorPaging(t *testing.T) {
+	mockRepo := NewMockPatientRepository()
+	for _, patientID := range []string{"uuid-1", "uuid-2", "uuid-3", "uuid-4", "uuid-5"} {
+		mockRepo.patients[patientID] = &models.Patient{ID: patientID, FamilyName: "Smith"}
+	}
+	handler := NewPatientHandlerWithService(service.NewPatientService(mockRepo))
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?family=Smith&_count=2", nil)
+	recorder := httptest.NewRecorder()
+	handler.GetAll(recorder, request)
+
+	var searchset fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&searchset)
+	if len(searchset.Entry) != 2 {
+		t.Fatalf("Expected a page of 2 patients, got %d", len(searchset.Entry))
+	}
+
+	links := map[string]string{}
+	for _, link := range searchset.Link {
+		links[link.Relation] = link.Url
+	}
+	if links["self"] != "http://example.com/fhir/Patient?_count=2&family=Smith" {
+		t.Errorf("Expected the self link without a cursor, got %s", links["self"])
+	}
+	if !strings.Contains(links["next"], "_cursor=") || strings.Contains(links["next"], "_offset=") {
+		t.Errorf("Expected the next link to carry a _cursor, got %s", links["next"])
+	}
+	if _, hasPrevious := links["previous"]; hasPrevious {
+		t.Errorf("Expected no previous link with cursors, got %s", links["previous"])
 	}
 }
 
@@ -283,11 +411,11 @@ func TestPatientHandler_GetAll_Empty(t *testing.T) {
 		t.Errorf("Expected status 200, got %d", recorder.Code)
 	}
 
-	var responsePatients []*fhir.Patient
-	json.NewDecoder(recorder.Body).Decode(&responsePatients)
+	var searchset fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&searchset)
 
-	if len(responsePatients) != 0 {
-		t.Errorf("Expected 0 patients, got %d", len(responsePatients))
+	if searchset.Total == nil || *searchset.Total != 0 || len(searchset.Entry) != 0 {
+		t.Errorf("Expected an empty searchset, got %+v", searchset)
 	}
 }
 
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/matching"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// defaultMatchCount is how many matches $match returns when the request gives no count
+const defaultMatchCount = 10
+
+// maxMatchCount is the most matches $match returns however large the requested count
+const maxMatchCount = 100
+
+// SetMatchService enables $match, which finds stored patients that may be the patient described
+func (handler *PatientHandler) SetMatchService(matchService *service.PatientMatchService) {
+	handler.matchService = matchService
+}
+
+// Match handles POST /fhir/Patient/$match - finds stored patients that may be the same person
+// The body is a Parameters resource with the partial Patient as "resource" and optional "count" and
+// "onlyCertainMatches", or the Patient itself. The response is a searchset Bundle of the matches, best first,
+// each entry carrying its score and a match-grade extension
+func (handler *PatientHandler) Match(w http.ResponseWriter, r *http.Request) {
+	if handler.matchService == nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Operation", "$match"))
+		return
+	}
+
+	fhirPatient, count, onlyCertain, requestError := parseMatchRequest(r)
+	if requestError != nil {
+		middleware.WriteError(w, r, requestError)
+		return
+	}
+
+	matches, matchError := handler.matchService.Match(r.Context(), fhirPatient, count, onlyCertain)
+	if matchError != nil {
+		middleware.WriteError(w, r, matchError)
+		return
+	}
+
+	entries := make([]fhir.BundleEntry, 0, len(matches))
+	for _, match := range matches {
+		exposePatient(r.Context(), handler.idCodec, match.Patient)
+		entry := searchEntry(r, match.Patient, fhir.SearchEntryModeMatch)
+		score := json.Number(strconv.FormatFloat(match.Score, 'f', -1, 64))
+		grade := string(match.Grade)
+		entry.Search.Score = &score
+		entry.Search.Extension = []fhir.Extension{{Url: matching.GradeExtensionURL, ValueCode: &grade}}
+		entries = append(entries, entry)
+	}
+	writeSearchBundle(w, r, entries)
+}
+
+// parseMatchRequest reads the patient to match, the count, and onlyCertainMatches from a $match body
+func parseMatchRequest(r *http.Request) (*fhir.Patient, int, bool, error) {
+	var body json.RawMessage
+	if decodeError := encoding.Decode(r, &body); decodeError != nil {
+		return nil, 0, false, apperrors.InvalidInput("body", "Invalid FHIR resource")
+	}
+	fhirPatient := &fhir.Patient{}
+	if matchResourceType(body) == "Patient" {
+		if decodeError := json.Unmarshal(body, fhirPatient); decodeError != nil {
+			return nil, 0, false, apperrors.InvalidInput("body", "Invalid FHIR Patient JSON")
+		}
+		return fhirPatient, defaultMatchCount, false, nil
+	}
+
+	var parameters fhir.Parameters
+	if matchResourceType(body) != "Parameters" || json.Unmarshal(body, &parameters) != nil {
+		return nil, 0, false, apperrors.InvalidInput("body", "must be a FHIR Parameters or Patient resource")
+	}
+	count := defaultMatchCount
+	onlyCertain := false
+	hasResource := false
+	for _, parameter := range parameters.Parameter {
+		switch parameter.Name {
+		case "resource":
+			if matchResourceType(parameter.Resource) != "Patient" || json.Unmarshal(parameter.Resource, fhirPatient) != nil {
+				return nil, 0, false, apperrors.InvalidInput("resource", "must be a Patient resource")
+			}
+			hasResource = true
+		case "count":
+			if parameter.ValueInteger == nil || *parameter.ValueInteger < 1 {
+				return nil, 0, false, apperrors.InvalidInput("count", "must be a positive valueInteger")
+			}
+			count = min(*parameter.ValueInteger, maxMatchCount)
+		case "onlyCertainMatches":
+			if parameter.ValueBoolean == nil {
+				return nil, 0, false, apperrors.InvalidInput("onlyCertainMatches", "must be a valueBoolean")
+			}
+			onlyCertain = *parameter.ValueBoolean
+		}
+	}
+	if !hasResource {
+		return nil, 0, false, apperrors.InvalidInput("resource", "a Patient resource is required")
+	}
+	return fhirPatient, count, onlyCertain, nil
+}
+
+// matchResourceType returns the resourceType of a $match body or parameter, or "" when it has none
+func matchResourceType(resource json.RawMessage) string {
+	var typed struct {
+		ResourceType string `json:"resourceType"`
+	}
+	json.Unmarshal(resource, &typed)
+	return typed.ResourceType
+}
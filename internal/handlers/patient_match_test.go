@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/matching"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newMatchTestRouter routes $match over Jane Smith and a namesake born on another day
+func newMatchTestRouter() *chi.Mux {
+	birthDate := time.Date(1980, time.March, 4, 0, 0, 0, 0, time.UTC)
+	otherBirthDate := time.Date(1991, time.July, 20, 0, 0, 0, 0, time.UTC)
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", IdentifierSystem: "http://hospital.example.org/mrn", IdentifierValue: "12345", FamilyName: "Smith", GivenName: "Jane", Gender: "female", BirthDate: &birthDate}
+	patientRepository.patients["patient-2"] = &models.Patient{ID: "patient-2", FamilyName: "Smith", GivenName: "Jane", Gender: "female", BirthDate: &otherBirthDate}
+
+	patientService := service.NewPatientService(patientRepository)
+	handler := NewPatientHandlerWithService(patientService)
+	handler.SetMatchService(service.NewPatientMatchService(patientService, matching.DefaultConfig()))
+
+	router := chi.NewRouter()
+	router.Post("/fhir/Patient/$match", handler.Match)
+	return router
+}
+
+// postMatch sends a $match request and returns the recorded response
+func postMatch(router *chi.Mux, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient/$match", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/fhir+json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+// TestPatientHandler_Match verifies matches come back best first with their score and match grade
+func TestPatientHandler_Match(t *testing.T) {
+	router := newMatchTestRouter()
+	body := `{"resourceType":"Parameters","parameter":[` +
+		`{"name":"resource","resource":{"resourceType":"Patient","identifier":[{"system":"http://hospital.example.org/mrn","value":"12345"}],` +
+		`"name":[{"family":"Smith","given":["Jane"]}],"gender":"female","birthDate":"1980-03-04"}},` +
+		`{"name":"count","valueInteger":5}]}`
+
+	recorder := postMatch(router, body)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var searchset fhir.Bundle
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&searchset); decodeError != nil {
+		t.Fatalf("Failed to decode Bundle: %v", decodeError)
+	}
+	if searchset.Type != fhir.BundleTypeSearchset || len(searchset.Entry) != 2 {
+		t.Fatalf("Expected a searchset with both patients, got %+v", searchset)
+	}
+
+	expectedGrades := []string{"certain", "possible"}
+	expectedScores := []string{"1", "0.4"}
+	for index, entry := range searchset.Entry {
+		if entry.Search == nil || entry.Search.Score == nil || string(*entry.Search.Score) != expectedScores[index] {
+			t.Errorf("Expected entry %d to score %s, got %+v", index, expectedScores[index], entry.Search)
+			continue
+		}
+		if len(entry.Search.Extension) != 1 || entry.Search.Extension[0].Url != matching.GradeExtensionURL || *entry.Search.Extension[0].ValueCode != expectedGrades[index] {
+			t.Errorf("Expected entry %d to be graded %s, got %+v", index, expectedGrades[index], entry.Search.Extension)
+		}
+	}
+}
+
+// TestPatientHandler_Match_PatientBody verifies a bare Patient body is matched with the default options
+func TestPatientHandler_Match_PatientBody(t *testing.T) {
+	recorder := postMatch(newMatchTestRouter(), `{"resourceType":"Patient","name":[{"family":"Smith","given":["Jane"]}],"birthDate":"1980-03-04"}`)
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var searchset fhir.Bundle
+	json.NewDecoder(recorder.Body).Decode(&searchset)
+	if len(searchset.Entry) != 1 {
+		t.Errorf("Expected only the patient with the same birth date, got %d entries", len(searchset.Entry))
+	}
+}
+
+// TestPatientHandler_Match_InvalidRequests verifies malformed $match requests get 400
+func TestPatientHandler_Match_InvalidRequests(t *testing.T) {
+	router := newMatchTestRouter()
+	invalidBodies := []string{
+		`not json`,
+		`{"resourceType":"Observation"}`,
+		`{"resourceType":"Parameters","parameter":[{"name":"count","valueInteger":5}]}`,
+		`{"resourceType":"Parameters","parameter":[{"name":"resource","resource":{"resourceType":"Observation"}}]}`,
+		`{"resourceType":"Parameters","parameter":[{"name":"resource","resource":{"resourceType":"Patient","name":[{"family":"Smith"}]}},{"name":"count","valueInteger":0}]}`,
+		`{"resourceType":"Patient","gender":"female"}`,
+	}
+	for _, body := range invalidBodies {
+		if recorder := postMatch(router, body); recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, recorder.Code)
+		}
+	}
+}
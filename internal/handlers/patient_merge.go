@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SetMergeService enables $merge, which merges a duplicate patient into the patient it duplicates
+func (handler *PatientHandler) SetMergeService(mergeService *service.PatientMergeService) {
+	handler.mergeService = mergeService
+}
+
+// Merge handles POST /fhir/Patient/$merge - merges the source-patient into the target-patient (identity-admin role only)
+// The body is a Parameters resource with source-patient and target-patient references. The response is a
+// Parameters resource with an OperationOutcome as "outcome" and the surviving target patient as "result"
+func (handler *PatientHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	if handler.mergeService == nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Operation", "$merge"))
+		return
+	}
+
+	var parameters fhir.Parameters
+	if decodeError := json.NewDecoder(r.Body).Decode(&parameters); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Parameters JSON"))
+		return
+	}
+	sourceID, sourceFound := handler.mergeParameter(w, r, &parameters, "source-patient")
+	if !sourceFound {
+		return
+	}
+	targetID, targetFound := handler.mergeParameter(w, r, &parameters, "target-patient")
+	if !targetFound {
+		return
+	}
+
+	mergeResult, mergeError := handler.mergeService.Merge(r.Context(), sourceID, targetID)
+	if mergeError != nil {
+		middleware.WriteError(w, r, mergeError)
+		return
+	}
+	exposePatient(r.Context(), handler.idCodec, mergeResult.Target)
+
+	sourceReference := "Patient/" + sourceID
+	exposeReference(r.Context(), handler.idCodec, &sourceReference)
+	diagnostics := fmt.Sprintf("Merged %s into Patient/%s; %d observations moved", sourceReference, *mergeResult.Target.Id, mergeResult.ReassignedObservations)
+	encodedOutcome, _ := json.Marshal(outcome.New([]outcome.Issue{outcome.Information(fhir.IssueTypeInformational, diagnostics)}))
+	encodedTarget, _ := json.Marshal(mergeResult.Target)
+
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(fhir.Parameters{
+		Parameter: []fhir.ParametersParameter{
+			{Name: "outcome", Resource: encodedOutcome},
+			{Name: "result", Resource: encodedTarget},
+		},
+	})
+}
+
+// mergeParameter returns the stored ID of the patient a $merge parameter references, writing a 400 when the
+// parameter is missing or is not a Patient reference, and a 404 when the ID is unknown
+func (handler *PatientHandler) mergeParameter(w http.ResponseWriter, r *http.Request, parameters *fhir.Parameters, name string) (string, bool) {
+	for _, parameter := range parameters.Parameter {
+		if parameter.Name != name {
+			continue
+		}
+		if parameter.ValueReference == nil || parameter.ValueReference.Reference == nil {
+			break
+		}
+		patientID, isPatientReference := strings.CutPrefix(*parameter.ValueReference.Reference, "Patient/")
+		if !isPatientReference || patientID == "" || strings.Contains(patientID, "/") {
+			break
+		}
+		return resolveID(w, r, handler.idCodec, "Patient", patientID)
+	}
+
+	middleware.WriteError(w, r, apperrors.InvalidInput(name, "a valueReference to Patient/{id} is required"))
+	return "", false
+}
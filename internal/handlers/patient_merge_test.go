@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Patients taking part in the $merge tests
+const (
+	duplicatePatientID = "5a1e6c2d-8b3f-4e7a-9c0d-1f2e3a4b5c6d"
+	survivingPatientID = "7b2f8d3e-9c4a-4f8b-8d1e-2a3b4c5d6e7f"
+)
+
+// mergeObservations keeps observation IDs by patient and moves them when reassigned
+type mergeObservations struct {
+	patientIDs map[string]string
+}
+
+// SearchObservations returns the searched patient's observations
+func (stub *mergeObservations) SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error) {
+	observations := []*fhir.Observation{}
+	for observationID, patientID := range stub.patientIDs {
+		if patientID == searchParams.PatientID {
+			observations = append(observations, &fhir.Observation{Id: &observationID})
+		}
+	}
+	return observations, nil
+}
+
+// ReassignObservation moves the observation
+func (stub *mergeObservations) ReassignObservation(ctx context.Context, observationID string, patientID string) error {
+	stub.patientIDs[observationID] = patientID
+	return nil
+}
+
+// newMergeTestRouter routes $merge over a duplicate patient with one observation and the patient it duplicates
+func newMergeTestRouter() (*chi.Mux, *MockPatientRepository, *mergeObservations) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients[duplicatePatientID] = &models.Patient{ID: duplicatePatientID, Active: true, FamilyName: "Smith"}
+	patientRepository.patients[survivingPatientID] = &models.Patient{ID: survivingPatientID, Active: true, FamilyName: "Smith"}
+	observations := &mergeObservations{patientIDs: map[string]string{"obs-1": duplicatePatientID}}
+
+	patientService := service.NewPatientService(patientRepository)
+	handler := NewPatientHandlerWithService(patientService)
+	handler.SetMergeService(service.NewPatientMergeService(patientService, observations))
+
+	router := chi.NewRouter()
+	router.Post("/fhir/Patient/$merge", handler.Merge)
+	return router, patientRepository, observations
+}
+
+// mergeRequest builds an identity-admin $merge request with the given body
+func mergeRequest(body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient/$merge", strings.NewReader(body))
+	principal := auth.Principal{ID: "api-key:records", Roles: []string{auth.RoleIdentityAdmin}}
+	return request.WithContext(auth.WithPrincipal(request.Context(), principal))
+}
+
+// TestPatientHandler_Merge verifies $merge moves the duplicate's observations, marks it replaced by the
+// surviving patient, and returns the outcome with the surviving patient as the result
+func TestPatientHandler_Merge(t *testing.T) {
+	router, patientRepository, observations := newMergeTestRouter()
+	body := `{"resourceType":"Parameters","parameter":[` +
+		`{"name":"source-patient","valueReference":{"reference":"Patient/` + duplicatePatientID + `"}},` +
+		`{"name":"target-patient","valueReference":{"reference":"Patient/` + survivingPatientID + `"}}]}`
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, mergeRequest(body))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	var parameters fhir.Parameters
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&parameters); decodeError != nil {
+		t.Fatalf("Failed to decode Parameters: %v", decodeError)
+	}
+	if len(parameters.Parameter) != 2 || parameters.Parameter[0].Name != "outcome" || parameters.Parameter[1].Name != "result" {
+		t.Fatalf("Expected outcome and result parameters, got %+v", parameters.Parameter)
+	}
+	var result fhir.Patient
+	json.Unmarshal(parameters.Parameter[1].Resource, &result)
+	if result.Id == nil || *result.Id != survivingPatientID {
+		t.Errorf("Expected the surviving patient as the result, got %+v", result)
+	}
+	if !strings.Contains(string(parameters.Parameter[0].Resource), "1 observations moved") {
+		t.Errorf("Expected the outcome to count the moved observation, got %s", parameters.Parameter[0].Resource)
+	}
+
+	duplicate := patientRepository.patients[duplicatePatientID]
+	if duplicate.Active || duplicate.ReplacedBy != survivingPatientID || observations.patientIDs["obs-1"] != survivingPatientID {
+		t.Errorf("Expected the duplicate inactive and its observation moved, got %+v and %v", duplicate, observations.patientIDs)
+	}
+}
+
+// TestPatientHandler_Merge_InvalidParameters verifies $merge requires two Patient references
+func TestPatientHandler_Merge_InvalidParameters(t *testing.T) {
+	router, _, observations := newMergeTestRouter()
+	invalidBodies := []string{
+		`not json`,
+		`{"resourceType":"Parameters","parameter":[{"name":"source-patient","valueReference":{"reference":"Patient/` + duplicatePatientID + `"}}]}`,
+		`{"resourceType":"Parameters","parameter":[{"name":"source-patient","valueReference":{"reference":"Observation/obs-1"}},` +
+			`{"name":"target-patient","valueReference":{"reference":"Patient/` + survivingPatientID + `"}}]}`,
+	}
+	for _, body := range invalidBodies {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, mergeRequest(body))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status 400, got %d", body, recorder.Code)
+		}
+	}
+	if observations.patientIDs["obs-1"] != duplicatePatientID {
+		t.Error("Expected no observation moved")
+	}
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// PractitionerHandler handles Practitioner FHIR resource requests
+type PractitionerHandler struct {
+	practitionerService *service.PractitionerService
+	idCodec             idcodec.Codec
+}
+
+// NewPractitionerHandler creates a PractitionerHandler backed by the practitioner service
+func NewPractitionerHandler(practitionerService *service.PractitionerService) *PractitionerHandler {
+	return &PractitionerHandler{
+		practitionerService: practitionerService,
+	}
+}
+
+// SetIDCodec exposes opaque IDs from the codec in responses and accepts only those IDs on requests
+func (handler *PractitionerHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// Create handles POST /fhir/Practitioner - creates a new practitioner
+func (handler *PractitionerHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirPractitioner fhir.Practitioner
+	if decodeError := encoding.Decode(r, &fhirPractitioner); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Practitioner JSON"))
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdPractitioner, createError := handler.practitionerService.CreatePractitioner(issueContext, &fhirPractitioner)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create practitioner")
+		return
+	}
+	exposeID(r.Context(), handler.idCodec, "Practitioner", createdPractitioner.Id)
+
+	writeWriteResult(w, r, http.StatusCreated, createdPractitioner, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/Practitioner/{id} - retrieves a practitioner by ID
+func (handler *PractitionerHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	practitionerID := chi.URLParam(r, "id")
+	internalPractitionerID, resolved := resolveID(w, r, handler.idCodec, "Practitioner", practitionerID)
+	if !resolved {
+		return
+	}
+
+	fhirPractitioner, getError := handler.practitionerService.GetPractitionerByID(r.Context(), internalPractitionerID)
+	if errors.Is(getError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Practitioner", practitionerID))
+		return
+	}
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Practitioner", practitionerID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read practitioner", getError))
+		return
+	}
+	exposeID(r.Context(), handler.idCodec, "Practitioner", fhirPractitioner.Id)
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("Practitioner", fhirPractitioner, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/Practitioner - searches practitioners by name, identifier, or specialty
+func (handler *PractitionerHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParsePractitionerSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	fhirPractitioners, searchError := handler.practitionerService.SearchPractitioners(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search practitioners", searchError))
+		return
+	}
+	total, countError := handler.practitionerService.CountPractitioners(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count practitioners", countError))
+		return
+	}
+	for _, fhirPractitioner := range fhirPractitioners {
+		exposeID(r.Context(), handler.idCodec, "Practitioner", fhirPractitioner.Id)
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "Practitioner", fhirPractitioners, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/Practitioner/{id} - updates an existing practitioner
+func (handler *PractitionerHandler) Update(w http.ResponseWriter, r *http.Request) {
+	practitionerID := chi.URLParam(r, "id")
+
+	var fhirPractitioner fhir.Practitioner
+	if decodeError := encoding.Decode(r, &fhirPractitioner); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Practitioner JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirPractitioner.Id != nil && *fhirPractitioner.Id != practitionerID {
+		middleware.WriteError(w, r, apperrors.ValidationError("Practitioner ID in URL does not match ID in body"))
+		return
+	}
+
+	internalPractitionerID, resolved := resolveID(w, r, handler.idCodec, "Practitioner", practitionerID)
+	if !resolved {
+		return
+	}
+	if fhirPractitioner.Id != nil {
+		fhirPractitioner.Id = &internalPractitionerID
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedPractitioner, updateError := handler.practitionerService.UpdatePractitioner(issueContext, internalPractitionerID, &fhirPractitioner)
+	if errors.Is(updateError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone("Practitioner", practitionerID))
+		return
+	}
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Practitioner", practitionerID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update practitioner")
+		return
+	}
+	exposeID(r.Context(), handler.idCodec, "Practitioner", updatedPractitioner.Id)
+
+	writeWriteResult(w, r, http.StatusOK, updatedPractitioner, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/Practitioner/{id} - deletes a practitioner
+func (handler *PractitionerHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	practitionerID := chi.URLParam(r, "id")
+	internalPractitionerID, resolved := resolveID(w, r, handler.idCodec, "Practitioner", practitionerID)
+	if !resolved {
+		return
+	}
+
+	if deleteError := handler.practitionerService.DeletePractitioner(r.Context(), internalPractitionerID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "Practitioner", practitionerID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockPractitionerRepository implements PractitionerRepository interface for testing
+type MockPractitionerRepository struct {
+	practitioners map[string]*models.Practitioner
+	deleted       map[string]bool
+	lastSearch    *models.PractitionerSearchParams
+}
+
+// NewMockPractitionerRepository creates a new mock repository for testing
+func NewMockPractitionerRepository() *MockPractitionerRepository {
+	return &MockPractitionerRepository{practitioners: make(map[string]*models.Practitioner), deleted: make(map[string]bool)}
+}
+
+// Create stores a practitioner under a generated UUID
+func (mock *MockPractitionerRepository) Create(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error) {
+	practitioner.ID = uuid.NewString()
+	mock.practitioners[practitioner.ID] = practitioner
+	return practitioner, nil
+}
+
+// GetByID retrieves a stored practitioner
+func (mock *MockPractitionerRepository) GetByID(ctx context.Context, practitionerID string) (*models.Practitioner, error) {
+	if mock.deleted[practitionerID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	practitioner, exists := mock.practitioners[practitionerID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return practitioner, nil
+}
+
+// Search returns every stored practitioner and remembers the criteria
+func (mock *MockPractitionerRepository) Search(ctx context.Context, searchParams *models.PractitionerSearchParams) ([]*models.Practitioner, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.Practitioner, 0, len(mock.practitioners))
+	for _, practitioner := range mock.practitioners {
+		result = append(result, practitioner)
+	}
+	return result, nil
+}
+
+// Count returns how many practitioners are stored, as every search matches them all
+func (mock *MockPractitionerRepository) Count(ctx context.Context, searchParams *models.PractitionerSearchParams) (int, error) {
+	return len(mock.practitioners), nil
+}
+
+// Update replaces a stored practitioner
+func (mock *MockPractitionerRepository) Update(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error) {
+	if mock.deleted[practitioner.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.practitioners[practitioner.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.practitioners[practitioner.ID] = practitioner
+	return practitioner, nil
+}
+
+// Delete marks a stored practitioner deleted, as the repository does
+func (mock *MockPractitionerRepository) Delete(ctx context.Context, practitionerID string) error {
+	if mock.deleted[practitionerID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.practitioners[practitionerID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.practitioners, practitionerID)
+	mock.deleted[practitionerID] = true
+	return nil
+}
+
+// newPractitionerRouter registers the Practitioner routes as cmd/server does
+func newPractitionerRouter(handler *PractitionerHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/Practitioner", handler.Create)
+	router.Get("/fhir/Practitioner/{id}", handler.GetByID)
+	router.Get("/fhir/Practitioner", handler.GetAll)
+	router.Put("/fhir/Practitioner/{id}", handler.Update)
+	router.Delete("/fhir/Practitioner/{id}", handler.Delete)
+	return router
+}
+
+// TestPractitionerRoutes verifies create, read, search, update, and delete, that deleted practitioners are 410, and that unknown ones are 404
+func TestPractitionerRoutes(t *testing.T) {
+	practitionerRepository := NewMockPractitionerRepository()
+	router := newPractitionerRouter(NewPractitionerHandler(service.NewPractitionerService(practitionerRepository)))
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/Practitioner", `{"resourceType":"Practitioner","name":[{"family":"House","given":["Gregory"]}],`+
+		`"qualification":[{"code":{"coding":[{"system":"http://nucc.org/provider-taxonomy","code":"207RI0200X"}]}}]}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the practitioner, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdPractitioner fhir.Practitioner
+	json.NewDecoder(createRecorder.Body).Decode(&createdPractitioner)
+	practitionerID := *createdPractitioner.Id
+
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/Practitioner/"+practitionerID, "")
+	var readPractitioner fhir.Practitioner
+	json.NewDecoder(readRecorder.Body).Decode(&readPractitioner)
+	if readRecorder.Code != http.StatusOK || len(readPractitioner.Qualification) != 1 || *readPractitioner.Qualification[0].Code.Coding[0].Code != "207RI0200X" {
+		t.Errorf("Expected the practitioner with its specialty, got %d: %+v", readRecorder.Code, readPractitioner)
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/Practitioner?specialty=207RI0200X&_count=5", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || searchset.Total == nil || *searchset.Total != 1 || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one practitioner, got %s", searchRecorder.Body.String())
+	}
+	if practitionerRepository.lastSearch.Specialty == nil || practitionerRepository.lastSearch.Specialty.Code != "207RI0200X" || practitionerRepository.lastSearch.Limit != 5 {
+		t.Errorf("Expected the specialty and page size passed to the repository, got %+v", practitionerRepository.lastSearch)
+	}
+
+	updateRecorder := serveCRUD(router, http.MethodPut, "/fhir/Practitioner/"+practitionerID, `{"resourceType":"Practitioner","id":"`+practitionerID+`","name":[{"family":"Wilson"}]}`)
+	if updateRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 updating the practitioner, got %d: %s", updateRecorder.Code, updateRecorder.Body.String())
+	}
+	if mismatchRecorder := serveCRUD(router, http.MethodPut, "/fhir/Practitioner/"+practitionerID, `{"resourceType":"Practitioner","id":"other"}`); mismatchRecorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for a body ID that differs from the URL, got %d", mismatchRecorder.Code)
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/Practitioner/"+practitionerID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the practitioner, got %d", deleteRecorder.Code)
+	}
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if recorder := serveCRUD(router, method, "/fhir/Practitioner/"+practitionerID, ""); recorder.Code != http.StatusGone {
+			t.Errorf("Expected status 410 for %s of a deleted practitioner, got %d", method, recorder.Code)
+		}
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/Practitioner/"+uuid.NewString(), ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 reading an unknown practitioner, got %d", recorder.Code)
+	}
+	if recorder := serveCRUD(router, http.MethodPut, "/fhir/Practitioner/not-a-uuid", `{"resourceType":"Practitioner","name":[{"family":"Wilson"}]}`); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 updating a malformed ID, got %d", recorder.Code)
+	}
+}
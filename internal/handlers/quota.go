@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/quota"
+)
+
+// TenantUsageResponse lists usage for every known tenant
+type TenantUsageResponse struct {
+	Tenants []quota.Usage `json:"tenants"`
+}
+
+// QuotaHandler exposes per-tenant quota usage for billing
+type QuotaHandler struct {
+	enforcer *quota.Enforcer
+}
+
+// NewQuotaHandler creates a new instance of QuotaHandler
+func NewQuotaHandler(enforcer *quota.Enforcer) *QuotaHandler {
+	return &QuotaHandler{
+		enforcer: enforcer,
+	}
+}
+
+// AllUsage handles GET /admin/tenants/usage - returns usage and limits for all tenants
+func (handler *QuotaHandler) AllUsage(w http.ResponseWriter, r *http.Request) {
+	usageReport, usageError := handler.enforcer.AllUsage(r.Context())
+	if usageError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read tenant usage", usageError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(TenantUsageResponse{Tenants: usageReport})
+}
+
+// TenantUsage handles GET /admin/tenants/{tenantId}/usage - returns usage and limits for one tenant
+func (handler *QuotaHandler) TenantUsage(w http.ResponseWriter, r *http.Request) {
+	usage, usageError := handler.enforcer.Usage(r.Context(), chi.URLParam(r, "tenantId"))
+	if usageError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read tenant usage", usageError))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(usage)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/quota"
+)
+
+// TestQuotaHandler_AllUsage verifies usage is listed for each tenant
+func TestQuotaHandler_AllUsage(t *testing.T) {
+	enforcer := quota.NewEnforcer(quota.Config{Default: quota.Limits{MaxPatients: 10}}, quota.NewMemoryUsageStore())
+	reservation, _, _ := enforcer.Reserve(context.Background(), "acme", "Patient", "", 100)
+	reservation.Commit(context.Background(), "p1")
+	handler := NewQuotaHandler(enforcer)
+
+	recorder := httptest.NewRecorder()
+	handler.AllUsage(recorder, httptest.NewRequest(http.MethodGet, "/admin/tenants/usage", nil))
+
+	var usageResponse TenantUsageResponse
+	json.NewDecoder(recorder.Body).Decode(&usageResponse)
+	if len(usageResponse.Tenants) != 1 || usageResponse.Tenants[0].Patients != 1 || usageResponse.Tenants[0].Limits.MaxPatients != 10 {
+		t.Errorf("Unexpected usage response: %+v", usageResponse)
+	}
+}
+
+// TestQuotaHandler_TenantUsage verifies usage for a single tenant
+func TestQuotaHandler_TenantUsage(t *testing.T) {
+	enforcer := quota.NewEnforcer(quota.Config{}, quota.NewMemoryUsageStore())
+	reservation, _, _ := enforcer.Reserve(context.Background(), "acme", "Observation", "", 50)
+	reservation.Commit(context.Background(), "o1")
+	handler := NewQuotaHandler(enforcer)
+
+	request := httptest.NewRequest(http.MethodGet, "/admin/tenants/acme/usage", nil)
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("tenantId", "acme")
+	request = request.WithContext(context.WithValue(request.Context(), chi.RouteCtxKey, routeContext))
+	recorder := httptest.NewRecorder()
+	handler.TenantUsage(recorder, request)
+
+	var usage quota.Usage
+	json.NewDecoder(recorder.Body).Decode(&usage)
+	if usage.TenantID != "acme" || usage.ObservationsToday != 1 || usage.StorageBytes != 50 {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/warmup"
+)
+
+// ReadinessHandler reports whether startup warm-up has finished so load balancers can hold traffic back
+type ReadinessHandler struct {
+	warmer *warmup.Warmer
+}
+
+// NewReadinessHandler creates a new instance of ReadinessHandler
+func NewReadinessHandler(warmer *warmup.Warmer) *ReadinessHandler {
+	return &ReadinessHandler{
+		warmer: warmer,
+	}
+}
+
+// Check handles GET /ready - 200 once warm-up has finished, 503 with its progress before then
+// Draining servers are already answered with 503 by the maintenance middleware
+func (handler *ReadinessHandler) Check(w http.ResponseWriter, r *http.Request) {
+	status := handler.warmer.Status()
+
+	statusCode := http.StatusOK
+	if !status.Ready {
+		statusCode = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(status)
+}
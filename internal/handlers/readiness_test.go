@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/warmup"
+)
+
+// TestReadinessHandler_Check verifies readiness flips only after warm-up finishes
+func TestReadinessHandler_Check(t *testing.T) {
+	warmer := warmup.NewWarmer()
+	warmer.Add("noop", func(ctx context.Context) error { return nil })
+	handler := NewReadinessHandler(warmer)
+
+	recorder := httptest.NewRecorder()
+	handler.Check(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected status 503 before warm-up, got %d", recorder.Code)
+	}
+
+	warmer.Run(context.Background(), time.Second)
+
+	recorder = httptest.NewRecorder()
+	handler.Check(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 after warm-up, got %d", recorder.Code)
+	}
+
+	var status warmup.Status
+	json.NewDecoder(recorder.Body).Decode(&status)
+	if !status.Ready || len(status.Steps) != 1 || status.Steps[0].Name != "noop" {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+}
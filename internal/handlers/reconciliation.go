@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/reconcile"
+)
+
+// ReconciliationHandler exposes the Postgres/Mongo integrity reconciliation job
+type ReconciliationHandler struct {
+	reconciler *reconcile.Reconciler
+}
+
+// NewReconciliationHandler creates a new instance of ReconciliationHandler
+func NewReconciliationHandler(reconciler *reconcile.Reconciler) *ReconciliationHandler {
+	return &ReconciliationHandler{
+		reconciler: reconciler,
+	}
+}
+
+// LastReport handles GET /admin/reconciliation - returns the latest reconciliation report
+func (handler *ReconciliationHandler) LastReport(w http.ResponseWriter, r *http.Request) {
+	lastReport := handler.reconciler.LastReport()
+	if lastReport == nil {
+		middleware.WriteError(w, r, apperrors.NotFound("Reconciliation report", "latest"))
+		return
+	}
+
+	writeReconciliationReport(w, lastReport)
+}
+
+// Run handles POST /admin/reconciliation/run - runs a reconciliation immediately
+// A run that fails part-way still returns its partial report with the error recorded
+func (handler *ReconciliationHandler) Run(w http.ResponseWriter, r *http.Request) {
+	report, runError := handler.reconciler.Run(r.Context())
+	if errors.Is(runError, reconcile.ErrAlreadyRunning) {
+		middleware.WriteError(w, r, apperrors.Conflict("Reconciliation", "a run is already in progress"))
+		return
+	}
+
+	writeReconciliationReport(w, report)
+}
+
+// writeReconciliationReport sends a report as JSON
+func writeReconciliationReport(w http.ResponseWriter, report *reconcile.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(report)
+}
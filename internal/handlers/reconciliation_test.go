@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/reconcile"
+)
+
+// stubPatientIDs returns a fixed set of patient IDs
+type stubPatientIDs []string
+
+// ListIDs returns the stubbed IDs
+func (patientIDs stubPatientIDs) ListIDs(ctx context.Context) ([]string, error) {
+	return patientIDs, nil
+}
+
+// Exists reports whether the ID is among the stubbed IDs
+func (patientIDs stubPatientIDs) Exists(ctx context.Context, patientID string) (bool, error) {
+	return slices.Contains(patientIDs, patientID), nil
+}
+
+// stubObservationCounts returns fixed counts and quarantines nothing
+type stubObservationCounts map[string]int64
+
+// CountByPatient returns the stubbed counts
+func (counts stubObservationCounts) CountByPatient(ctx context.Context) (map[string]int64, error) {
+	return counts, nil
+}
+
+// QuarantineByPatient does nothing
+func (counts stubObservationCounts) QuarantineByPatient(ctx context.Context, patientID string) ([]string, error) {
+	return nil, nil
+}
+
+// TestReconciliationHandler_RunAndLastReport verifies a manual run and report retrieval
+func TestReconciliationHandler_RunAndLastReport(t *testing.T) {
+	reconciler := reconcile.NewReconciler(stubPatientIDs{"p1"}, stubObservationCounts{"p1": 2, "ghost": 1}, nil, false)
+	handler := NewReconciliationHandler(reconciler)
+
+	recorder := httptest.NewRecorder()
+	handler.LastReport(recorder, httptest.NewRequest(http.MethodGet, "/admin/reconciliation", nil))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 before the first run, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.Run(recorder, httptest.NewRequest(http.MethodPost, "/admin/reconciliation/run", nil))
+	var runReport reconcile.Report
+	json.NewDecoder(recorder.Body).Decode(&runReport)
+	if recorder.Code != http.StatusOK || len(runReport.Orphans) != 1 || runReport.Orphans[0].PatientID != "ghost" {
+		t.Errorf("Unexpected run response %d: %+v", recorder.Code, runReport)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.LastReport(recorder, httptest.NewRequest(http.MethodGet, "/admin/reconciliation", nil))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200 after a run, got %d", recorder.Code)
+	}
+}
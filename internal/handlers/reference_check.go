@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"strings"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/residency"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// SubjectReferenceChecker checks observation subject references for exhaustive validation the way
+// observation writes resolve them: across regions, through the ID codec, and against stored patients
+type SubjectReferenceChecker struct {
+	patientService  *service.PatientService
+	idCodec         idcodec.Codec
+	residencyPolicy *residency.Policy
+}
+
+// NewSubjectReferenceChecker creates a checker that looks referenced patients up in patientService
+func NewSubjectReferenceChecker(patientService *service.PatientService) *SubjectReferenceChecker {
+	return &SubjectReferenceChecker{
+		patientService: patientService,
+	}
+}
+
+// SetIDCodec makes the checker accept only exposed patient IDs, as the observation endpoints do
+func (checker *SubjectReferenceChecker) SetIDCodec(codec idcodec.Codec) {
+	checker.idCodec = codec
+}
+
+// SetResidencyPolicy refuses references to patients held in another region
+func (checker *SubjectReferenceChecker) SetResidencyPolicy(policy *residency.Policy) {
+	checker.residencyPolicy = policy
+}
+
+// Check returns a 403 for cross-region references, a 404 for patients that do not exist or whose ID was not
+// issued to the caller's tenant, and nil otherwise; references that are not relative Patient references
+// are left for the validator to report
+func (checker *SubjectReferenceChecker) Check(ctx context.Context, reference string) error {
+	if checker.residencyPolicy != nil {
+		if residencyError := checker.residencyPolicy.CheckReference(reference); residencyError != nil {
+			return residencyError
+		}
+	}
+
+	patientID, isPatient := strings.CutPrefix(reference, "Patient/")
+	if !isPatient || patientID == "" || strings.Contains(patientID, "/") {
+		return nil
+	}
+
+	internalReference := reference
+	if resolveError := resolveReference(ctx, checker.idCodec, &internalReference); resolveError != nil {
+		return apperrors.NotFound("Patient", patientID)
+	}
+	if _, getError := checker.patientService.GetPatientByID(ctx, strings.TrimPrefix(internalReference, "Patient/")); getError != nil {
+		return apperrors.NotFound("Patient", patientID)
+	}
+	return nil
+}
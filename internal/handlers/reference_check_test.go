@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// TestSubjectReferenceChecker verifies subjects resolve through the ID codec to stored patients
+func TestSubjectReferenceChecker(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", FamilyName: "Smith"}
+	checker := NewSubjectReferenceChecker(service.NewPatientService(patientRepository))
+	codec, _ := idcodec.NewHMACCodec(bytes.Repeat([]byte("k"), 32))
+	checker.SetIDCodec(codec)
+	ctx := tenant.WithVerifiedTenant(context.Background(), tenant.DefaultTenantID)
+
+	testCases := []struct {
+		name           string
+		reference      string
+		expectedStatus int
+	}{
+		{"exposed ID of a stored patient", "Patient/" + codec.Encode(tenant.DefaultTenantID, "Patient", "patient-1"), http.StatusOK},
+		{"exposed ID of a missing patient", "Patient/" + codec.Encode(tenant.DefaultTenantID, "Patient", "patient-2"), http.StatusNotFound},
+		{"raw internal ID", "Patient/patient-1", http.StatusNotFound},
+		{"not a patient reference", "Group/1", http.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			status := http.StatusOK
+			var appError *apperrors.AppError
+			if checkError := checker.Check(ctx, testCase.reference); errors.As(checkError, &appError) {
+				status = appError.StatusCode
+			}
+			if status != testCase.expectedStatus {
+				t.Errorf("Expected %d for %s, got %d", testCase.expectedStatus, testCase.reference, status)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// IDs are keyed to the tenant of the request's API key, never to a client-set X-Tenant-ID, so a client
+// cannot pick another tenant's key by naming that tenant
+
+// resolveID translates an ID received from a client into the internal ID
+// IDs the codec did not issue are reported as not found, so probing reveals nothing about which IDs exist
+func resolveID(w http.ResponseWriter, r *http.Request, codec idcodec.Codec, resourceType string, externalID string) (string, bool) {
+	if codec == nil {
+		return externalID, true
+	}
+
+	internalID, decodeError := codec.Decode(tenant.VerifiedFromContext(r.Context()), resourceType, externalID)
+	if decodeError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound(resourceType, externalID))
+		return "", false
+	}
+	return internalID, true
+}
+
+// exposeID translates an internal ID into the ID shown to the client
+func exposeID(ctx context.Context, codec idcodec.Codec, resourceType string, id *string) {
+	if codec == nil || id == nil || *id == "" {
+		return
+	}
+	exposedID := codec.Encode(tenant.VerifiedFromContext(ctx), resourceType, *id)
+	*id = exposedID
+}
+
+// exposeReference rewrites a relative "Type/id" reference to use the exposed ID
+func exposeReference(ctx context.Context, codec idcodec.Codec, reference *string) {
+	if codec == nil || reference == nil {
+		return
+	}
+	resourceType, id, isRelative := strings.Cut(*reference, "/")
+	if !isRelative || id == "" || strings.Contains(id, "/") {
+		return
+	}
+	exposedReference := resourceType + "/" + codec.Encode(tenant.VerifiedFromContext(ctx), resourceType, id)
+	*reference = exposedReference
+}
+
+// resolveReference rewrites a relative "Type/id" reference from a client to use the internal ID
+// It returns idcodec.ErrInvalidID when the referenced ID was not issued by the codec
+func resolveReference(ctx context.Context, codec idcodec.Codec, reference *string) error {
+	if codec == nil || reference == nil {
+		return nil
+	}
+	resourceType, id, isRelative := strings.Cut(*reference, "/")
+	if !isRelative || id == "" || strings.Contains(id, "/") {
+		return nil
+	}
+	internalID, decodeError := codec.Decode(tenant.VerifiedFromContext(ctx), resourceType, id)
+	if decodeError != nil {
+		return decodeError
+	}
+	resolvedReference := resourceType + "/" + internalID
+	*reference = resolvedReference
+	return nil
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newTestIDCodec creates an HMAC codec with a fixed secret
+func newTestIDCodec(t *testing.T) *idcodec.HMACCodec {
+	codec, codecError := idcodec.NewHMACCodec([]byte("0123456789abcdef0123456789abcdef"))
+	if codecError != nil {
+		t.Fatalf("Failed to create codec: %v", codecError)
+	}
+	return codec
+}
+
+// requestWithID builds a request from an API key mapped to tenantID, with the chi id URL parameter set
+func requestWithID(method string, target string, body []byte, tenantID string, id string) *http.Request {
+	request := httptest.NewRequest(method, target, bytes.NewReader(body))
+	routeContext := chi.NewRouteContext()
+	routeContext.URLParams.Add("id", id)
+	ctx := context.WithValue(tenant.WithVerifiedTenant(request.Context(), tenantID), chi.RouteCtxKey, routeContext)
+	return request.WithContext(ctx)
+}
+
+// TestObservationHandler_GetByID_ExposesOpaqueIDs verifies opaque IDs are accepted and returned
+func TestObservationHandler_GetByID_ExposesOpaqueIDs(t *testing.T) {
+	codec := newTestIDCodec(t)
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	handler.SetIDCodec(codec)
+
+	observationID := "65a1f0c2e4b0a1b2c3d4e5f6"
+	patientReference := "Patient/3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f"
+	mockService.observations[observationID] = &fhir.Observation{Id: &observationID, Subject: &fhir.Reference{Reference: &patientReference}}
+
+	externalID := codec.Encode("acme", "Observation", observationID)
+	recorder := httptest.NewRecorder()
+	handler.GetByID(recorder, requestWithID(http.MethodGet, "/fhir/Observation/"+externalID, nil, "acme", externalID))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+
+	var responseObservation fhir.Observation
+	json.NewDecoder(recorder.Body).Decode(&responseObservation)
+	if *responseObservation.Id != externalID {
+		t.Errorf("Expected exposed ID %s, got %s", externalID, *responseObservation.Id)
+	}
+	expectedReference := "Patient/" + codec.Encode("acme", "Patient", "3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f")
+	if *responseObservation.Subject.Reference != expectedReference {
+		t.Errorf("Expected subject %s, got %s", expectedReference, *responseObservation.Subject.Reference)
+	}
+}
+
+// TestObservationHandler_GetByID_RejectsInternalAndForeignIDs verifies raw and cross-tenant IDs are not found
+func TestObservationHandler_GetByID_RejectsInternalAndForeignIDs(t *testing.T) {
+	codec := newTestIDCodec(t)
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	handler.SetIDCodec(codec)
+
+	observationID := "65a1f0c2e4b0a1b2c3d4e5f6"
+	mockService.observations[observationID] = &fhir.Observation{Id: &observationID}
+
+	for _, requestedID := range []string{observationID, codec.Encode("globex", "Observation", observationID)} {
+		recorder := httptest.NewRecorder()
+		handler.GetByID(recorder, requestWithID(http.MethodGet, "/fhir/Observation/"+requestedID, nil, "acme", requestedID))
+
+		if recorder.Code != http.StatusNotFound {
+			t.Errorf("Expected status 404 for %s, got %d", requestedID, recorder.Code)
+		}
+	}
+}
+
+// TestObservationHandler_Create_ResolvesSubject verifies exposed patient references are stored as internal IDs
+func TestObservationHandler_Create_ResolvesSubject(t *testing.T) {
+	codec := newTestIDCodec(t)
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	handler.SetIDCodec(codec)
+
+	patientID := "3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f"
+	exposedReference := "Patient/" + codec.Encode("acme", "Patient", patientID)
+	body, _ := json.Marshal(fhir.Observation{Status: fhir.ObservationStatusFinal, Subject: &fhir.Reference{Reference: &exposedReference}})
+
+	recorder := httptest.NewRecorder()
+	handler.Create(recorder, requestWithID(http.MethodPost, "/fhir/Observation", body, "acme", ""))
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", recorder.Code)
+	}
+	var responseObservation fhir.Observation
+	json.NewDecoder(recorder.Body).Decode(&responseObservation)
+	if *responseObservation.Subject.Reference != exposedReference {
+		t.Errorf("Expected response subject %s, got %s", exposedReference, *responseObservation.Subject.Reference)
+	}
+	if *responseObservation.Id != codec.Encode("acme", "Observation", "created-id-123") {
+		t.Errorf("Expected exposed observation ID, got %s", *responseObservation.Id)
+	}
+
+	unknownReference := "Patient/" + patientID
+	body, _ = json.Marshal(fhir.Observation{Status: fhir.ObservationStatusFinal, Subject: &fhir.Reference{Reference: &unknownReference}})
+	recorder = httptest.NewRecorder()
+	handler.Create(recorder, requestWithID(http.MethodPost, "/fhir/Observation", body, "acme", ""))
+
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for internal patient reference, got %d", recorder.Code)
+	}
+}
+
+// TestPatientHandler_GetByID_RejectsUnissuedID verifies guessed patient IDs are not found
+func TestPatientHandler_GetByID_RejectsUnissuedID(t *testing.T) {
+	handler := NewPatientHandlerWithService(nil)
+	handler.SetIDCodec(newTestIDCodec(t))
+
+	recorder := httptest.NewRecorder()
+	handler.GetByID(recorder, requestWithID(http.MethodGet, "/fhir/Patient/1", nil, "acme", "1"))
+
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404, got %d", recorder.Code)
+	}
+}
+
+// TestResolveID_IgnoresTenantHeader verifies a client naming a tenant in X-Tenant-ID gets the default
+// tenant's key, so it cannot use IDs issued to that tenant's API key
+func TestResolveID_IgnoresTenantHeader(t *testing.T) {
+	codec := newTestIDCodec(t)
+	acmeID := codec.Encode("acme", "Patient", "p1")
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient/"+acmeID, nil)
+	request = request.WithContext(tenant.WithTenant(request.Context(), "acme"))
+
+	recorder := httptest.NewRecorder()
+	if _, resolved := resolveID(recorder, request, codec, "Patient", acmeID); resolved || recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an ID issued to acme's API key, got %d", recorder.Code)
+	}
+
+	defaultID := codec.Encode(tenant.DefaultTenantID, "Patient", "p1")
+	if internalID, resolved := resolveID(httptest.NewRecorder(), request, codec, "Patient", defaultID); !resolved || internalID != "p1" {
+		t.Errorf("Expected the default tenant's ID to resolve, got %q", internalID)
+	}
+}
+
+// TestResolveReference verifies relative references are translated and other forms are left alone
+func TestResolveReference(t *testing.T) {
+	codec := newTestIDCodec(t)
+	ctx := tenant.WithVerifiedTenant(context.Background(), "acme")
+	patientID := "3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f"
+
+	reference := "Patient/" + codec.Encode("acme", "Patient", patientID)
+	if resolveError := resolveReference(ctx, codec, &reference); resolveError != nil || reference != "Patient/"+patientID {
+		t.Errorf("Expected Patient/%s, got %s (%v)", patientID, reference, resolveError)
+	}
+
+	absoluteReference := "https://example.org/fhir/Patient/123"
+	if resolveError := resolveReference(ctx, codec, &absoluteReference); resolveError != nil || absoluteReference != "https://example.org/fhir/Patient/123" {
+		t.Errorf("Expected absolute reference unchanged, got %s (%v)", absoluteReference, resolveError)
+	}
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/rollup"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// rollupDateLayout is the day format used by rollup query and backfill parameters
+const rollupDateLayout = "2006-01-02"
+
+// RollupResponse is the body of a daily rollup query
+type RollupResponse struct {
+	Patient string                      `json:"patient"`
+	Rollups []*models.ObservationRollup `json:"rollups"`
+}
+
+// BackfillRequest is the body for recomputing rollups over a day range
+type BackfillRequest struct {
+	// From is the first day to recompute (YYYY-MM-DD)
+	From string `json:"from"`
+
+	// To is the day after the last one to recompute (YYYY-MM-DD)
+	To string `json:"to"`
+}
+
+// RollupHandler serves daily observation rollups and controls the rollup job
+type RollupHandler struct {
+	rollupService *service.RollupService
+	rollupJob     *rollup.Job
+	idCodec       idcodec.Codec
+}
+
+// NewRollupHandler creates a new rollup handler instance
+func NewRollupHandler(rollupService *service.RollupService, rollupJob *rollup.Job) *RollupHandler {
+	return &RollupHandler{
+		rollupService: rollupService,
+		rollupJob:     rollupJob,
+	}
+}
+
+// SetIDCodec accepts only opaque patient IDs from the codec
+func (handler *RollupHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// Query handles GET /fhir/Observation/$daily-rollup?patient={id}&code=[system|]code&start=&end=
+// It returns the patient's daily count, min, max, and average for the code between the optional days
+func (handler *RollupHandler) Query(w http.ResponseWriter, r *http.Request) {
+	queryParams := r.URL.Query()
+	patientID := queryParams.Get("patient")
+	if patientID == "" {
+		middleware.WriteError(w, r, apperrors.InvalidInput("patient", "is required"))
+		return
+	}
+	internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", patientID)
+	if !resolved {
+		return
+	}
+
+	rollupQuery := models.RollupQuery{PatientID: internalPatientID, Code: queryParams.Get("code")}
+	if codeSystem, code, hasSystem := strings.Cut(rollupQuery.Code, "|"); hasSystem {
+		rollupQuery.CodeSystem = codeSystem
+		rollupQuery.Code = code
+	}
+
+	for _, dayParam := range []struct {
+		name   string
+		target **time.Time
+	}{{"start", &rollupQuery.From}, {"end", &rollupQuery.To}} {
+		rawDay := queryParams.Get(dayParam.name)
+		if rawDay == "" {
+			continue
+		}
+		parsedDay, parseError := time.Parse(rollupDateLayout, rawDay)
+		if parseError != nil {
+			middleware.WriteError(w, r, apperrors.InvalidInput(dayParam.name, "must be a date in YYYY-MM-DD format"))
+			return
+		}
+		*dayParam.target = &parsedDay
+	}
+
+	rollups, queryError := handler.rollupService.DailyRollups(r.Context(), rollupQuery)
+	if queryError != nil {
+		middleware.WriteError(w, r, queryError)
+		return
+	}
+
+	// Rollups name the patient by the ID the client sent, never the stored one
+	for _, dailyRollup := range rollups {
+		dailyRollup.PatientID = patientID
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(RollupResponse{Patient: patientID, Rollups: rollups})
+}
+
+// Status handles GET /admin/rollups - the rollup run in progress and the last finished run
+func (handler *RollupHandler) Status(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(handler.rollupJob.Status())
+}
+
+// Backfill handles POST /admin/rollups/backfill - queues a recomputation of a day range (operator or admin role)
+func (handler *RollupHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	if !auth.FromContext(r.Context()).HasAnyRole(auth.RoleOperator, auth.RoleAdmin) {
+		middleware.WriteError(w, r, apperrors.Forbidden("Rollup backfills can only be started by the operator or admin role"))
+		return
+	}
+
+	var backfillRequest BackfillRequest
+	if decodeError := json.NewDecoder(r.Body).Decode(&backfillRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid backfill JSON"))
+		return
+	}
+	from, fromError := time.Parse(rollupDateLayout, backfillRequest.From)
+	to, toError := time.Parse(rollupDateLayout, backfillRequest.To)
+	if fromError != nil || toError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("from", "from and to must be dates in YYYY-MM-DD format"))
+		return
+	}
+
+	queuedRun, backfillError := handler.rollupJob.RequestBackfill(from, to)
+	switch {
+	case errors.Is(backfillError, rollup.ErrInvalidRange):
+		middleware.WriteError(w, r, apperrors.InvalidInput("to", "must be after from"))
+		return
+	case errors.Is(backfillError, rollup.ErrAlreadyRunning):
+		middleware.WriteError(w, r, apperrors.Conflict("Rollup", "a run is already in progress"))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(queuedRun)
+}
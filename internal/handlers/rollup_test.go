@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/rollup"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// stubRollupRepository returns fixed rollups and records the last query
+type stubRollupRepository struct {
+	rollups   []*models.ObservationRollup
+	lastQuery models.RollupQuery
+}
+
+// ReplaceDays is unused by the handler
+func (repository *stubRollupRepository) ReplaceDays(ctx context.Context, from time.Time, to time.Time, rollups []*models.ObservationRollup) error {
+	return nil
+}
+
+// List records the query and returns the stored rollups
+func (repository *stubRollupRepository) List(ctx context.Context, query models.RollupQuery) ([]*models.ObservationRollup, error) {
+	repository.lastQuery = query
+	return repository.rollups, nil
+}
+
+// TestRollupHandler_Query verifies the code token and day range are parsed and patients are named by the requested ID
+func TestRollupHandler_Query(t *testing.T) {
+	codec := newTestIDCodec(t)
+	rollupRepository := &stubRollupRepository{rollups: []*models.ObservationRollup{
+		{PatientID: "p1", CodeSystem: "http://loinc.org", Code: "8867-4", Count: 2, Min: 60, Max: 80, Sum: 140, Average: 70},
+	}}
+	handler := NewRollupHandler(service.NewRollupService(rollupRepository), nil)
+	handler.SetIDCodec(codec)
+
+	exposedID := codec.Encode("acme", "Patient", "p1")
+	target := "/fhir/Observation/$daily-rollup?patient=" + exposedID + "&code=http://loinc.org|8867-4&start=2024-01-01&end=2024-12-31"
+	recorder := httptest.NewRecorder()
+	handler.Query(recorder, requestWithID(http.MethodGet, target, nil, "acme", ""))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	query := rollupRepository.lastQuery
+	if query.PatientID != "p1" || query.CodeSystem != "http://loinc.org" || query.Code != "8867-4" || query.From == nil || query.To.Format(rollupDateLayout) != "2024-12-31" {
+		t.Errorf("Unexpected query %+v", query)
+	}
+	var rollupResponse RollupResponse
+	json.NewDecoder(recorder.Body).Decode(&rollupResponse)
+	if len(rollupResponse.Rollups) != 1 || rollupResponse.Rollups[0].PatientID != exposedID || rollupResponse.Rollups[0].Average != 70 {
+		t.Errorf("Expected one rollup for the exposed patient, got %+v", rollupResponse.Rollups)
+	}
+
+	for _, invalidTarget := range []string{"/fhir/Observation/$daily-rollup?code=8867-4", "/fhir/Observation/$daily-rollup?patient=" + exposedID + "&code=8867-4&start=January"} {
+		recorder = httptest.NewRecorder()
+		handler.Query(recorder, requestWithID(http.MethodGet, invalidTarget, nil, "acme", ""))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected status 400 for %s, got %d", invalidTarget, recorder.Code)
+		}
+	}
+}
+
+// TestRollupHandler_Backfill verifies backfills need the operator role and only one may be queued
+func TestRollupHandler_Backfill(t *testing.T) {
+	handler := NewRollupHandler(nil, rollup.NewJob(nil, nil, 3))
+	backfillBody := []byte(`{"from": "2020-01-01", "to": "2024-01-01"}`)
+
+	recorder := httptest.NewRecorder()
+	handler.Backfill(recorder, httptest.NewRequest(http.MethodPost, "/admin/rollups/backfill", bytes.NewReader(backfillBody)))
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403 without the operator role, got %d", recorder.Code)
+	}
+
+	expectedStatuses := []int{http.StatusAccepted, http.StatusConflict}
+	for _, expectedStatus := range expectedStatuses {
+		request := httptest.NewRequest(http.MethodPost, "/admin/rollups/backfill", bytes.NewReader(backfillBody))
+		request = request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{ID: "api-key:ops", Roles: []string{auth.RoleOperator}}))
+		recorder = httptest.NewRecorder()
+		handler.Backfill(recorder, request)
+		if recorder.Code != expectedStatus {
+			t.Errorf("Expected status %d, got %d", expectedStatus, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/streaming"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// maxStreamedResults caps a streamed search, as search exports are capped; its total tells the client
+// whether matches were left out
+const maxStreamedResults = 100000
+
+// searchStreamWriteTimeout bounds each write of a streamed search, so a client that stops reading releases
+// the search's database cursor
+const searchStreamWriteTimeout = 30 * time.Second
+
+// fhirBaseURL returns the absolute address of the FHIR endpoints as the client reached them,
+// used to build each search entry's fullUrl
+func fhirBaseURL(r *http.Request) string {
+	return middleware.RequestScheme(r) + "://" + r.Host + "/fhir"
+}
+
+// searchURL returns the absolute address of the search as the client sent it, the base of its paging links
+func searchURL(r *http.Request) *url.URL {
+	return &url.URL{Scheme: middleware.RequestScheme(r), Host: r.Host, Path: r.URL.Path, RawQuery: r.URL.RawQuery}
+}
+
+// searchPage describes the page of a search read with the request's paging parameters
+// A cursor-paged search links to the next page with nextCursor, or has no next page when it is nil
+func searchPage(r *http.Request, total int, count int, offset int, cursorPaging bool, nextCursor *models.PageCursor) bundle.Page {
+	if !cursorPaging {
+		return bundle.Page{Total: total, Count: count, Offset: offset}
+	}
+	page := bundle.Page{Total: total, Count: count, CursorPaging: true, Cursor: r.URL.Query().Get("_cursor")}
+	if nextCursor != nil {
+		page.NextCursor = utils.EncodePageCursor(nextCursor)
+	}
+	return page
+}
+
+// searchEntry wraps a resource as a searchset Bundle entry with the given search mode
+func searchEntry(r *http.Request, resource interface{}, mode fhir.SearchEntryMode) fhir.BundleEntry {
+	return bundle.SearchEntry(fhirBaseURL(r), resource, mode)
+}
+
+// outcomeEntries reports partial-result warnings as an OperationOutcome entry, or nothing when there are none
+func outcomeEntries(r *http.Request, issues []outcome.Issue) []fhir.BundleEntry {
+	if len(issues) == 0 {
+		return nil
+	}
+	return []fhir.BundleEntry{searchEntry(r, outcome.New(issues), fhir.SearchEntryModeOutcome)}
+}
+
+// writeSearchResults responds to a search with a searchset Bundle of one page of matches, trimmed to any
+// _elements requested
+func writeSearchResults[Resource any](w http.ResponseWriter, r *http.Request, resourceType string, resources []Resource, elements []string, page bundle.Page) {
+	writeSearchPage(w, r, bundle.SearchEntries(fhirBaseURL(r), subsetResources(resourceType, resources, elements), fhir.SearchEntryModeMatch), page)
+}
+
+// writeSearchPage responds with a searchset Bundle of one page of a search, whose total counts every match
+// and whose links lead to the neighboring pages
+func writeSearchPage(w http.ResponseWriter, r *http.Request, entries []fhir.BundleEntry, page bundle.Page) {
+	encoding.Write(w, r, http.StatusOK, bundle.PagedSearchset(entries, searchURL(r), page))
+}
+
+// writeSearchBundle responds with a searchset Bundle whose total counts the match entries
+func writeSearchBundle(w http.ResponseWriter, r *http.Request, entries []fhir.BundleEntry) {
+	encoding.Write(w, r, http.StatusOK, bundle.Searchset(entries))
+}
+
+// parseStreamParameter reports whether the search asked for _stream=true, writing a 400 for other values
+// and for XML responses, which are not streamed
+func parseStreamParameter(w http.ResponseWriter, r *http.Request) (bool, bool) {
+	switch r.URL.Query().Get("_stream") {
+	case "", "false":
+		return false, true
+	case "true":
+	default:
+		middleware.WriteError(w, r, apperrors.InvalidInput("_stream", "must be true or false"))
+		return false, false
+	}
+	if encoding.ResponseFormat(r) == encoding.FormatXML {
+		middleware.WriteError(w, r, apperrors.ValidationError("_stream is only supported for JSON responses"))
+		return false, false
+	}
+	return true, true
+}
+
+// streamSearchResults responds to a search with a searchset Bundle whose match entries are written, trimmed
+// to any _elements requested, as stream reads them, instead of being built in memory first
+// The 200 is sent before the first match is read, so a search that fails partway ends the Bundle with an
+// outcome entry reporting the failure; the Bundle has only a self link
+func streamSearchResults[Resource any](w http.ResponseWriter, r *http.Request, resourceType string, total int, elements []string, stream func(visit func(resource Resource) error) error) {
+	w.Header().Set("Content-Type", encoding.JSONMediaType)
+	w.WriteHeader(http.StatusOK)
+	searchset, startError := bundle.NewSearchsetWriter(streaming.NewWriter(w, searchStreamWriteTimeout), total, []fhir.BundleLink{{Relation: "self", Url: searchURL(r).String()}})
+	if startError != nil {
+		return
+	}
+
+	var writeError error
+	streamError := stream(func(resource Resource) error {
+		writeError = searchset.WriteEntry(searchEntry(r, subsetElements(resourceType, resource, elements), fhir.SearchEntryModeMatch))
+		return writeError
+	})
+	if writeError != nil {
+		log.Info().Err(writeError).Str("path", r.URL.Path).Msg("Search stream client stopped reading")
+		return
+	}
+	if streamError != nil {
+		log.Error().Err(streamError).Str("path", r.URL.Path).Msg("Search stream failed partway")
+		failure := outcome.Error(fhir.IssueTypeException, "The search failed partway; the Bundle holds the matches read before the failure")
+		if searchset.WriteEntry(searchEntry(r, outcome.New([]outcome.Issue{failure}), fhir.SearchEntryModeOutcome)) != nil {
+			return
+		}
+	}
+	searchset.Close()
+}
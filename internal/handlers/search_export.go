@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+// SearchExportRequest is the body for requesting a search export
+type SearchExportRequest struct {
+	// ResourceType is the resource searched: Patient or Observation
+	ResourceType string `json:"resourceType"`
+
+	// Query is the search's query string, as it would be sent to the resource's search endpoint
+	// _count and _offset are ignored: the export holds every match
+	Query string `json:"query"`
+
+	// Format is ndjson (the default) or csv
+	Format string `json:"format"`
+}
+
+// SearchExportHandler serves search exports and their signed downloads
+type SearchExportHandler struct {
+	exportService *service.SearchExportService
+	idCodec       idcodec.Codec
+}
+
+// NewSearchExportHandler creates a new instance of SearchExportHandler
+func NewSearchExportHandler(exportService *service.SearchExportService) *SearchExportHandler {
+	return &SearchExportHandler{
+		exportService: exportService,
+	}
+}
+
+// SetIDCodec makes the handler accept exposed patient IDs in export queries
+func (handler *SearchExportHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// ExportExposer returns the exposer that rewrites exported resources' IDs the way the search endpoints do
+func ExportExposer(codec idcodec.Codec) service.ExportResourceExposer {
+	return func(ctx context.Context, resource interface{}) {
+		exposeHistoricalIDs(ctx, codec, resource)
+	}
+}
+
+// Create handles POST /exports - queues an export of every resource matching the search
+func (handler *SearchExportHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var exportRequest SearchExportRequest
+	if decodeError := json.NewDecoder(r.Body).Decode(&exportRequest); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid export request JSON"))
+		return
+	}
+
+	query := strings.TrimPrefix(exportRequest.Query, "?")
+	queryValues, parseError := url.ParseQuery(query)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("query", "Invalid search query string"))
+		return
+	}
+
+	// Translate an exposed patient ID back to the stored one, as the observation search does
+	if patientID := queryValues.Get("patient"); exportRequest.ResourceType == "Observation" && patientID != "" {
+		internalPatientID, resolved := resolveID(w, r, handler.idCodec, "Patient", strings.TrimPrefix(patientID, "Patient/"))
+		if !resolved {
+			return
+		}
+		queryValues.Set("patient", internalPatientID)
+	}
+
+	export, requestError := handler.exportService.Request(r.Context(), exportRequest.ResourceType, query, queryValues.Encode(), exportRequest.Format)
+	if requestError != nil {
+		middleware.WriteError(w, r, requestError)
+		return
+	}
+
+	w.Header().Set("Location", "/exports/"+strconv.FormatInt(export.ID, 10))
+	writeSearchExport(w, http.StatusAccepted, export)
+}
+
+// Status handles GET /exports/{id} - reports the export's progress and, once ready, a fresh download link
+func (handler *SearchExportHandler) Status(w http.ResponseWriter, r *http.Request) {
+	exportID, parsed := parseSearchExportID(w, r)
+	if !parsed {
+		return
+	}
+
+	export, getError := handler.exportService.Get(r.Context(), exportID)
+	if getError != nil {
+		middleware.WriteError(w, r, getError)
+		return
+	}
+
+	writeSearchExport(w, http.StatusOK, export)
+}
+
+// Download handles GET /exports/{id}/download - returns the export file to holders of a valid signed link
+func (handler *SearchExportHandler) Download(w http.ResponseWriter, r *http.Request) {
+	exportID, parsed := parseSearchExportID(w, r)
+	if !parsed {
+		return
+	}
+
+	export, content, downloadError := handler.exportService.Download(r.Context(), exportID, r.URL.Query().Get("expires"), r.URL.Query().Get("signature"), models.SearchExportDownload{
+		RemoteAddress: r.RemoteAddr,
+		UserAgent:     r.UserAgent(),
+	})
+	if downloadError != nil {
+		middleware.WriteError(w, r, downloadError)
+		return
+	}
+
+	contentType := "application/fhir+ndjson"
+	if export.Format == models.SearchExportFormatCSV {
+		contentType = "text/csv"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-export-%d.%s"`, strings.ToLower(export.ResourceType), export.ID, export.Format))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	w.Write(content)
+}
+
+// parseSearchExportID reads the export ID from the URL, responding 404 when it is not a number
+func parseSearchExportID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	rawExportID := chi.URLParam(r, "id")
+	exportID, parseError := strconv.ParseInt(rawExportID, 10, 64)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.NotFound("SearchExport", rawExportID))
+		return 0, false
+	}
+	return exportID, true
+}
+
+// writeSearchExport responds with an export as JSON
+func writeSearchExport(w http.ResponseWriter, status int, export *models.SearchExport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(export)
+}
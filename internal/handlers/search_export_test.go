@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// StubSearchExportRepository implements SearchExportRepository with at most one export
+type StubSearchExportRepository struct {
+	export    *models.SearchExport
+	downloads []*models.SearchExportDownload
+}
+
+// Create stores the export as pending
+func (stub *StubSearchExportRepository) Create(ctx context.Context, export *models.SearchExport) (*models.SearchExport, error) {
+	export.ID = 1
+	export.Status = models.SearchExportStatusPending
+	stub.export = export
+	return export, nil
+}
+
+// Get returns the stored export
+func (stub *StubSearchExportRepository) Get(ctx context.Context, exportID int64) (*models.SearchExport, error) {
+	if stub.export == nil || exportID != stub.export.ID {
+		return nil, sql.ErrNoRows
+	}
+	copied := *stub.export
+	return &copied, nil
+}
+
+// ClaimNext marks the stored export running when it is pending
+func (stub *StubSearchExportRepository) ClaimNext(ctx context.Context, staleBefore time.Time) (*models.SearchExport, error) {
+	if stub.export == nil || stub.export.Status != models.SearchExportStatusPending {
+		return nil, sql.ErrNoRows
+	}
+	stub.export.Status = models.SearchExportStatusRunning
+	return stub.Get(ctx, stub.export.ID)
+}
+
+// Complete stores the sealed file
+func (stub *StubSearchExportRepository) Complete(ctx context.Context, exportID int64, content []byte, resourceCount int, truncated bool, expiresAt time.Time) error {
+	stub.export.Status = models.SearchExportStatusReady
+	stub.export.Content = content
+	stub.export.ResourceCount = resourceCount
+	stub.export.ExpiresAt = &expiresAt
+	return nil
+}
+
+// Fail records the failure
+func (stub *StubSearchExportRepository) Fail(ctx context.Context, exportID int64, failure string) error {
+	stub.export.Status = models.SearchExportStatusFailed
+	stub.export.Error = failure
+	return nil
+}
+
+// Content returns the sealed file
+func (stub *StubSearchExportRepository) Content(ctx context.Context, exportID int64) ([]byte, error) {
+	return stub.export.Content, nil
+}
+
+// ExpireBefore expires nothing
+func (stub *StubSearchExportRepository) ExpireBefore(ctx context.Context, now time.Time) (int64, error) {
+	return 0, nil
+}
+
+// RecordDownload appends to the audit trail
+func (stub *StubSearchExportRepository) RecordDownload(ctx context.Context, download *models.SearchExportDownload) error {
+	stub.downloads = append(stub.downloads, download)
+	return nil
+}
+
+// ListDownloads returns the audit trail
+func (stub *StubSearchExportRepository) ListDownloads(ctx context.Context, exportID int64) ([]*models.SearchExportDownload, error) {
+	return stub.downloads, nil
+}
+
+// StubExportObservationSearcher returns one observation for the patient it was searched for
+type StubExportObservationSearcher struct {
+	searchedPatientID string
+}
+
+// SearchObservations records the patient searched and returns one observation on the first page
+func (stub *StubExportObservationSearcher) SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error) {
+	stub.searchedPatientID = searchParams.PatientID
+	if searchParams.Offset > 0 {
+		return nil, nil
+	}
+	observationID := "obs-1"
+	subject := "Patient/" + searchParams.PatientID
+	return []*fhir.Observation{{Id: &observationID, Subject: &fhir.Reference{Reference: &subject}, Status: fhir.ObservationStatusFinal}}, nil
+}
+
+// setupSearchExportHandler creates an export handler with opaque IDs over stubs and the router serving it
+func setupSearchExportHandler(t *testing.T) (*chi.Mux, *service.SearchExportService, *StubSearchExportRepository, *StubExportObservationSearcher, idcodec.Codec) {
+	t.Helper()
+	exportRepository := &StubSearchExportRepository{}
+	observationSearcher := &StubExportObservationSearcher{}
+	exportService, serviceError := service.NewSearchExportService(exportRepository, nil, observationSearcher, bytes.Repeat([]byte{3}, service.SearchExportKeySize), service.DefaultSearchExportPolicy())
+	if serviceError != nil {
+		t.Fatalf("Expected no error, got %v", serviceError)
+	}
+	codec, _ := idcodec.NewHMACCodec(bytes.Repeat([]byte("s"), 32))
+	exportService.SetExposer(ExportExposer(codec))
+
+	exportHandler := NewSearchExportHandler(exportService)
+	exportHandler.SetIDCodec(codec)
+	router := chi.NewRouter()
+	router.Post("/exports", exportHandler.Create)
+	router.Get("/exports/{id}", exportHandler.Status)
+	router.Get("/exports/{id}/download", exportHandler.Download)
+	return router, exportService, exportRepository, observationSearcher, codec
+}
+
+// analystRequest builds a request from an authenticated caller of the default tenant
+func analystRequest(method string, target string, body string) *http.Request {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	ctx := auth.WithPrincipal(request.Context(), auth.Principal{ID: "api-key:analyst"})
+	return request.WithContext(tenant.WithVerifiedTenant(ctx, tenant.DefaultTenantID))
+}
+
+// TestSearchExportHandler_Create verifies exposed patient IDs are resolved and the export is accepted
+func TestSearchExportHandler_Create(t *testing.T) {
+	router, _, exportRepository, _, codec := setupSearchExportHandler(t)
+	exposedPatientID := codec.Encode(tenant.DefaultTenantID, "Patient", "p-1")
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, analystRequest(http.MethodPost, "/exports", `{"resourceType": "Observation", "query": "?patient=Patient/`+exposedPatientID+`&code=8867-4"}`))
+
+	if recorder.Code != http.StatusAccepted || recorder.Header().Get("Location") != "/exports/1" {
+		t.Fatalf("Expected 202 with a Location, got %d %q", recorder.Code, recorder.Header().Get("Location"))
+	}
+	if exportRepository.export.SearchQuery != "code=8867-4&patient=p-1" || !strings.Contains(exportRepository.export.Query, exposedPatientID) {
+		t.Errorf("Expected the stored search to use the internal ID and the query to keep the exposed one, got %q / %q", exportRepository.export.SearchQuery, exportRepository.export.Query)
+	}
+
+	unknownRecorder := httptest.NewRecorder()
+	router.ServeHTTP(unknownRecorder, analystRequest(http.MethodPost, "/exports", `{"resourceType": "Observation", "query": "patient=p-1"}`))
+	if unknownRecorder.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a raw internal patient ID, got %d", unknownRecorder.Code)
+	}
+
+	anonymousRecorder := httptest.NewRecorder()
+	router.ServeHTTP(anonymousRecorder, httptest.NewRequest(http.MethodPost, "/exports", strings.NewReader(`{"resourceType": "Patient"}`)))
+	if anonymousRecorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without an API key, got %d", anonymousRecorder.Code)
+	}
+}
+
+// TestSearchExportHandler_Download verifies a ready export is served as an audited attachment through its signed link
+func TestSearchExportHandler_Download(t *testing.T) {
+	router, exportService, exportRepository, observationSearcher, codec := setupSearchExportHandler(t)
+	exposedPatientID := codec.Encode(tenant.DefaultTenantID, "Patient", "p-1")
+	router.ServeHTTP(httptest.NewRecorder(), analystRequest(http.MethodPost, "/exports", `{"resourceType": "Observation", "query": "patient=`+exposedPatientID+`"}`))
+	exportService.ProcessNext(context.Background())
+	if observationSearcher.searchedPatientID != "p-1" {
+		t.Errorf("Expected the export to search the internal patient ID, got %q", observationSearcher.searchedPatientID)
+	}
+
+	statusRecorder := httptest.NewRecorder()
+	router.ServeHTTP(statusRecorder, analystRequest(http.MethodGet, "/exports/1", ""))
+	var export models.SearchExport
+	json.NewDecoder(statusRecorder.Body).Decode(&export)
+	if export.Status != models.SearchExportStatusReady || export.DownloadURL == "" {
+		t.Fatalf("Expected a ready export with a download link, got %+v", export)
+	}
+
+	downloadRecorder := httptest.NewRecorder()
+	router.ServeHTTP(downloadRecorder, httptest.NewRequest(http.MethodGet, export.DownloadURL, nil))
+	if downloadRecorder.Code != http.StatusOK || downloadRecorder.Header().Get("Content-Type") != "application/fhir+ndjson" {
+		t.Fatalf("Expected the NDJSON file, got %d %q", downloadRecorder.Code, downloadRecorder.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(downloadRecorder.Header().Get("Content-Disposition"), `filename="observation-export-1.ndjson"`) {
+		t.Errorf("Expected an attachment filename, got %q", downloadRecorder.Header().Get("Content-Disposition"))
+	}
+	if !strings.Contains(downloadRecorder.Body.String(), `"reference":"Patient/`+exposedPatientID+`"`) {
+		t.Errorf("Expected exported subjects to use exposed IDs, got %s", downloadRecorder.Body.String())
+	}
+	if len(exportRepository.downloads) != 1 || exportRepository.downloads[0].DownloadedBy != auth.AnonymousPrincipalID {
+		t.Errorf("Expected the anonymous download to be audited, got %+v", exportRepository.downloads)
+	}
+
+	forgedRecorder := httptest.NewRecorder()
+	router.ServeHTTP(forgedRecorder, httptest.NewRequest(http.MethodGet, "/exports/1/download?expires=9999999999&signature=00", nil))
+	if forgedRecorder.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a forged link, got %d", forgedRecorder.Code)
+	}
+}
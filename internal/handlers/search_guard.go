@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/searchcost"
+	"github.com/rs/zerolog/log"
+)
+
+// applySearchPolicy checks a search estimate against the policy before the search runs
+// Rejected searches get a 422 OperationOutcome naming the parameters to add and false is returned;
+// downgraded searches have their page size reduced and the reason reported in Warning headers
+func applySearchPolicy(w http.ResponseWriter, r *http.Request, policy *searchcost.Policy, estimate searchcost.Estimate, limit *int) bool {
+	if policy == nil {
+		return true
+	}
+
+	decision, issues := policy.Decide(estimate)
+	switch decision {
+	case searchcost.Reject:
+		log.Warn().
+			Str("path", r.URL.Path).
+			Str("query", r.URL.RawQuery).
+			Int("estimated_cost", estimate.Cost).
+			Str("parameters", estimate.Parameters()).
+			Msg("Rejected expensive search")
+		outcome.WriteForRequest(w, r, http.StatusUnprocessableEntity, issues)
+		return false
+	case searchcost.Downgrade:
+		if *limit > policy.DowngradeLimit {
+			*limit = policy.DowngradeLimit
+		}
+		for _, issue := range issues {
+			w.Header().Add("Warning", "199 - "+strconv.Quote(issue.Diagnostics))
+		}
+	}
+
+	return true
+}
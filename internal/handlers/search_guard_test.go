@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/searchcost"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestObservationHandler_GetAll_RejectsExpensiveSearch verifies abusive searches get a 422 naming the parameter to add
+func TestObservationHandler_GetAll_RejectsExpensiveSearch(t *testing.T) {
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	handler.SetSearchPolicy(searchcost.DefaultPolicy())
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?_offset=5000", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.GetAll(recorder, request)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("Expected status 422, got %d", recorder.Code)
+	}
+	if mockService.lastSearchParams != nil {
+		t.Error("Expected rejected search not to reach the service")
+	}
+
+	var operationOutcome fhir.OperationOutcome
+	json.NewDecoder(recorder.Body).Decode(&operationOutcome)
+	if len(operationOutcome.Issue) == 0 || operationOutcome.Issue[0].Code != fhir.IssueTypeTooCostly {
+		t.Fatalf("Expected too-costly issues, got %+v", operationOutcome.Issue)
+	}
+	if !strings.Contains(*operationOutcome.Issue[0].Diagnostics, "'patient'") {
+		t.Errorf("Expected diagnostics to name the patient parameter, got %s", *operationOutcome.Issue[0].Diagnostics)
+	}
+}
+
+// TestObservationHandler_GetAll_DowngradesExpensiveSearch verifies costly searches run with a reduced page size
+func TestObservationHandler_GetAll_DowngradesExpensiveSearch(t *testing.T) {
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	handler.SetSearchPolicy(searchcost.DefaultPolicy())
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?_count=500", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.GetAll(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	if mockService.lastSearchParams.Limit != searchcost.DefaultPolicy().DowngradeLimit {
+		t.Errorf("Expected limit %d, got %d", searchcost.DefaultPolicy().DowngradeLimit, mockService.lastSearchParams.Limit)
+	}
+	if len(recorder.Header().Values("Warning")) == 0 {
+		t.Error("Expected Warning headers explaining the downgrade")
+	}
+}
+
+// TestObservationHandler_GetAll_AllowsScopedSearch verifies patient-scoped searches are untouched
+func TestObservationHandler_GetAll_AllowsScopedSearch(t *testing.T) {
+	mockService := NewMockObservationService()
+	handler := NewObservationHandler(mockService)
+	handler.SetSearchPolicy(searchcost.DefaultPolicy())
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?patient=patient-1&_count=50", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.GetAll(recorder, request)
+
+	if recorder.Code != http.StatusOK || mockService.lastSearchParams.Limit != 50 {
+		t.Errorf("Expected unchanged search, got status %d", recorder.Code)
+	}
+	if len(recorder.Header().Values("Warning")) != 0 {
+		t.Error("Expected no Warning headers")
+	}
+}
+
+// TestPatientHandler_GetAll_RejectsShortSubstring verifies single-character name scans are rejected
+func TestPatientHandler_GetAll_RejectsShortSubstring(t *testing.T) {
+	handler := NewPatientHandlerWithService(nil)
+	handler.SetSearchPolicy(searchcost.DefaultPolicy())
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?name=a", nil)
+	recorder := httptest.NewRecorder()
+
+	handler.GetAll(recorder, request)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", recorder.Code)
+	}
+}
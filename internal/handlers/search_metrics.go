@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/dedup"
+	"github.com/nathannewyen/fhir-health-interop/internal/metrics"
+)
+
+// SearchMetricsResponse represents the search parameter usage report
+type SearchMetricsResponse struct {
+	Resources []metrics.ResourceSearchReport `json:"resources"`
+
+	// Deduplication reports how often identical concurrent searches shared one repository call
+	Deduplication []dedup.Report `json:"deduplication,omitempty"`
+}
+
+// SearchMetricsHandler exposes search parameter usage statistics
+type SearchMetricsHandler struct {
+	searchRecorder *metrics.SearchRecorder
+	searchGroup    *dedup.Group
+}
+
+// NewSearchMetricsHandler creates a new instance of SearchMetricsHandler
+func NewSearchMetricsHandler(searchRecorder *metrics.SearchRecorder) *SearchMetricsHandler {
+	return &SearchMetricsHandler{
+		searchRecorder: searchRecorder,
+	}
+}
+
+// SetSearchGroup adds the group's collapse rates to the report
+func (handler *SearchMetricsHandler) SetSearchGroup(searchGroup *dedup.Group) {
+	handler.searchGroup = searchGroup
+}
+
+// Report handles GET /admin/search-metrics - returns parameter and combination usage per resource type
+func (handler *SearchMetricsHandler) Report(w http.ResponseWriter, r *http.Request) {
+	metricsResponse := SearchMetricsResponse{
+		Resources: handler.searchRecorder.Report(),
+	}
+	if handler.searchGroup != nil {
+		metricsResponse.Deduplication = handler.searchGroup.Report()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(metricsResponse)
+}
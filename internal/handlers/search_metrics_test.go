@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/metrics"
+)
+
+// TestSearchMetricsHandler_Report verifies the usage report is returned as JSON
+func TestSearchMetricsHandler_Report(t *testing.T) {
+	recorder := metrics.NewSearchRecorder(map[string][]string{"Patient": {"name", "gender"}})
+	recorder.Record("Patient", []string{"name"}, 5*time.Millisecond)
+	handler := NewSearchMetricsHandler(recorder)
+
+	request := httptest.NewRequest(http.MethodGet, "/admin/search-metrics", nil)
+	responseRecorder := httptest.NewRecorder()
+
+	handler.Report(responseRecorder, request)
+
+	if responseRecorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", responseRecorder.Code)
+	}
+
+	var metricsResponse SearchMetricsResponse
+	decodeError := json.NewDecoder(responseRecorder.Body).Decode(&metricsResponse)
+	if decodeError != nil {
+		t.Fatalf("Failed to decode response: %v", decodeError)
+	}
+
+	if len(metricsResponse.Resources) != 1 {
+		t.Fatalf("Expected 1 resource report, got %d", len(metricsResponse.Resources))
+	}
+	if metricsResponse.Resources[0].ParameterCounts["name"] != 1 {
+		t.Errorf("Expected name count 1, got %v", metricsResponse.Resources[0].ParameterCounts)
+	}
+	if len(metricsResponse.Resources[0].UnusedParameters) != 1 || metricsResponse.Resources[0].UnusedParameters[0] != "gender" {
+		t.Errorf("Expected gender to be unused, got %v", metricsResponse.Resources[0].UnusedParameters)
+	}
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/bundle"
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SubscriptionHandler handles Subscription FHIR resource requests
+type SubscriptionHandler struct {
+	subscriptionService *service.SubscriptionService
+}
+
+// NewSubscriptionHandler creates a SubscriptionHandler backed by the subscription service
+func NewSubscriptionHandler(subscriptionService *service.SubscriptionService) *SubscriptionHandler {
+	return &SubscriptionHandler{
+		subscriptionService: subscriptionService,
+	}
+}
+
+// Create handles POST /fhir/Subscription - registers a subscription
+func (handler *SubscriptionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var fhirSubscription fhir.Subscription
+	if decodeError := encoding.Decode(r, &fhirSubscription); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Subscription JSON"))
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	createdSubscription, createError := handler.subscriptionService.CreateSubscription(issueContext, &fhirSubscription)
+	if createError != nil {
+		writeWriteError(w, r, createError, "Failed to create subscription")
+		return
+	}
+
+	writeWriteResult(w, r, http.StatusCreated, createdSubscription, issueCollector.Issues())
+}
+
+// GetByID handles GET /fhir/Subscription/{id} - retrieves a subscription, including the error that stopped it
+func (handler *SubscriptionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "id")
+
+	fhirSubscription, getError := handler.subscriptionService.GetSubscriptionByID(r.Context(), subscriptionID)
+	if errors.Is(getError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Subscription", subscriptionID))
+		return
+	}
+	if getError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to read subscription", getError))
+		return
+	}
+
+	encoding.Write(w, r, http.StatusOK, subsetElements("Subscription", fhirSubscription, utils.ParseElementsParameter(r)))
+}
+
+// GetAll handles GET /fhir/Subscription - lists the tenant's subscriptions, optionally by status
+func (handler *SubscriptionHandler) GetAll(w http.ResponseWriter, r *http.Request) {
+	searchParams, parseError := utils.ParseSubscriptionSearchParams(r)
+	if parseError != nil {
+		middleware.WriteError(w, r, apperrors.ValidationError("Invalid search parameters"))
+		return
+	}
+
+	fhirSubscriptions, searchError := handler.subscriptionService.SearchSubscriptions(r.Context(), searchParams)
+	if searchError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to search subscriptions", searchError))
+		return
+	}
+	total, countError := handler.subscriptionService.CountSubscriptions(r.Context(), searchParams)
+	if countError != nil {
+		middleware.WriteError(w, r, apperrors.Internal("Failed to count subscriptions", countError))
+		return
+	}
+
+	page := bundle.Page{Total: total, Count: searchParams.Limit, Offset: searchParams.Offset}
+	writeSearchResults(w, r, "Subscription", fhirSubscriptions, utils.ParseElementsParameter(r), page)
+}
+
+// Update handles PUT /fhir/Subscription/{id} - replaces a subscription, e.g. to turn it off or restart it
+func (handler *SubscriptionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "id")
+
+	var fhirSubscription fhir.Subscription
+	if decodeError := encoding.Decode(r, &fhirSubscription); decodeError != nil {
+		middleware.WriteError(w, r, apperrors.InvalidInput("body", "Invalid FHIR Subscription JSON"))
+		return
+	}
+
+	// Validate that the ID in the URL matches the ID in the body (if provided)
+	if fhirSubscription.Id != nil && *fhirSubscription.Id != subscriptionID {
+		middleware.WriteError(w, r, apperrors.ValidationError("Subscription ID in URL does not match ID in body"))
+		return
+	}
+
+	// Collect warnings about data the mapper could not store
+	issueContext, issueCollector := outcome.WithCollector(r.Context())
+
+	updatedSubscription, updateError := handler.subscriptionService.UpdateSubscription(issueContext, subscriptionID, &fhirSubscription)
+	if errors.Is(updateError, service.ErrResourceNotFound) {
+		middleware.WriteError(w, r, apperrors.NotFound("Subscription", subscriptionID))
+		return
+	}
+	if updateError != nil {
+		writeWriteError(w, r, updateError, "Failed to update subscription")
+		return
+	}
+
+	writeWriteResult(w, r, http.StatusOK, updatedSubscription, issueCollector.Issues())
+}
+
+// Delete handles DELETE /fhir/Subscription/{id} - deletes a subscription
+func (handler *SubscriptionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	subscriptionID := chi.URLParam(r, "id")
+
+	if deleteError := handler.subscriptionService.DeleteSubscription(r.Context(), subscriptionID); deleteError != nil {
+		writeDeleteError(w, r, deleteError, "Subscription", subscriptionID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
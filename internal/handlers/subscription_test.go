@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockSubscriptionRepository implements SubscriptionRepository interface for testing
+type MockSubscriptionRepository struct {
+	subscriptions map[string]*models.Subscription
+	lastSearch    *models.SubscriptionSearchParams
+}
+
+// NewMockSubscriptionRepository creates a new mock repository for testing
+func NewMockSubscriptionRepository() *MockSubscriptionRepository {
+	return &MockSubscriptionRepository{subscriptions: make(map[string]*models.Subscription)}
+}
+
+// Create stores a subscription under a generated UUID
+func (mock *MockSubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	subscription.ID = uuid.NewString()
+	mock.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+// GetByID retrieves a stored subscription
+func (mock *MockSubscriptionRepository) GetByID(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	subscription, exists := mock.subscriptions[subscriptionID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return subscription, nil
+}
+
+// Search returns every stored subscription and remembers the criteria
+func (mock *MockSubscriptionRepository) Search(ctx context.Context, searchParams *models.SubscriptionSearchParams) ([]*models.Subscription, error) {
+	mock.lastSearch = searchParams
+	result := make([]*models.Subscription, 0, len(mock.subscriptions))
+	for _, subscription := range mock.subscriptions {
+		result = append(result, subscription)
+	}
+	return result, nil
+}
+
+// Count returns how many subscriptions are stored, as every search matches them all
+func (mock *MockSubscriptionRepository) Count(ctx context.Context, searchParams *models.SubscriptionSearchParams) (int, error) {
+	return len(mock.subscriptions), nil
+}
+
+// Update replaces a stored subscription, keeping its tenant
+func (mock *MockSubscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	stored, exists := mock.subscriptions[subscription.ID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	subscription.TenantID = stored.TenantID
+	mock.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+// Delete removes a stored subscription
+func (mock *MockSubscriptionRepository) Delete(ctx context.Context, subscriptionID string) error {
+	if _, exists := mock.subscriptions[subscriptionID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.subscriptions, subscriptionID)
+	return nil
+}
+
+// ListActive returns the active stored subscriptions
+func (mock *MockSubscriptionRepository) ListActive(ctx context.Context) ([]*models.Subscription, error) {
+	active := []*models.Subscription{}
+	for _, subscription := range mock.subscriptions {
+		if subscription.Status == models.SubscriptionStatusActive {
+			active = append(active, subscription)
+		}
+	}
+	return active, nil
+}
+
+// RecordError puts a stored subscription in error
+func (mock *MockSubscriptionRepository) RecordError(ctx context.Context, subscriptionID string, message string) error {
+	subscription, exists := mock.subscriptions[subscriptionID]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	subscription.Status = models.SubscriptionStatusError
+	subscription.Error = message
+	return nil
+}
+
+// newSubscriptionRouter registers the Subscription routes as cmd/server does
+func newSubscriptionRouter(handler *SubscriptionHandler) *chi.Mux {
+	router := chi.NewRouter()
+	router.Post("/fhir/Subscription", handler.Create)
+	router.Get("/fhir/Subscription/{id}", handler.GetByID)
+	router.Get("/fhir/Subscription", handler.GetAll)
+	router.Put("/fhir/Subscription/{id}", handler.Update)
+	router.Delete("/fhir/Subscription/{id}", handler.Delete)
+	return router
+}
+
+// TestSubscriptionRoutes verifies subscriptions are activated on create, show the error that stopped them, and are rejected when they cannot be notified
+func TestSubscriptionRoutes(t *testing.T) {
+	subscriptionRepository := NewMockSubscriptionRepository()
+	router := newSubscriptionRouter(NewSubscriptionHandler(service.NewSubscriptionService(subscriptionRepository)))
+
+	createRecorder := serveCRUD(router, http.MethodPost, "/fhir/Subscription", `{"resourceType":"Subscription","status":"requested","reason":"Blood pressure alerts",`+
+		`"criteria":"Observation?code=8480-6","channel":{"type":"rest-hook","endpoint":"https://alerts.example.com/notify","payload":"application/fhir+json"}}`)
+	if createRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201 creating the subscription, got %d: %s", createRecorder.Code, createRecorder.Body.String())
+	}
+	var createdSubscription fhir.Subscription
+	json.NewDecoder(createRecorder.Body).Decode(&createdSubscription)
+	if createdSubscription.Status != fhir.SubscriptionStatusActive {
+		t.Errorf("Expected the requested subscription activated, got %s", createdSubscription.Status.Code())
+	}
+	subscriptionID := *createdSubscription.Id
+
+	subscriptionRepository.RecordError(context.Background(), subscriptionID, "webhook responded 410")
+	readRecorder := serveCRUD(router, http.MethodGet, "/fhir/Subscription/"+subscriptionID, "")
+	var readSubscription fhir.Subscription
+	json.NewDecoder(readRecorder.Body).Decode(&readSubscription)
+	if readRecorder.Code != http.StatusOK || readSubscription.Status != fhir.SubscriptionStatusError || readSubscription.Error == nil || *readSubscription.Error != "webhook responded 410" {
+		t.Errorf("Expected the subscription in error with its reason, got %d: %s", readRecorder.Code, readRecorder.Body.String())
+	}
+
+	searchRecorder := serveCRUD(router, http.MethodGet, "/fhir/Subscription?status=error&_count=5", "")
+	var searchset fhir.Bundle
+	json.NewDecoder(searchRecorder.Body).Decode(&searchset)
+	if searchset.Type != fhir.BundleTypeSearchset || len(searchset.Entry) != 1 {
+		t.Errorf("Expected a searchset with one subscription, got %s", searchRecorder.Body.String())
+	}
+	if subscriptionRepository.lastSearch.Status != "error" || subscriptionRepository.lastSearch.Limit != 5 {
+		t.Errorf("Expected the status and page size passed to the repository, got %+v", subscriptionRepository.lastSearch)
+	}
+
+	rejectedRecorder := serveCRUD(router, http.MethodPost, "/fhir/Subscription", `{"resourceType":"Subscription","status":"requested","reason":"Encounters",`+
+		`"criteria":"Encounter?patient=123","channel":{"type":"rest-hook","endpoint":"https://alerts.example.com/notify"}}`)
+	if rejectedRecorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422 for criteria that cannot be matched, got %d: %s", rejectedRecorder.Code, rejectedRecorder.Body.String())
+	}
+
+	if deleteRecorder := serveCRUD(router, http.MethodDelete, "/fhir/Subscription/"+subscriptionID, ""); deleteRecorder.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204 deleting the subscription, got %d", deleteRecorder.Code)
+	}
+	if recorder := serveCRUD(router, http.MethodGet, "/fhir/Subscription/"+subscriptionID, ""); recorder.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 for a deleted subscription, got %d", recorder.Code)
+	}
+}
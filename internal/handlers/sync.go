@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/idcodec"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+)
+
+const (
+	// defaultSyncPageSize is the number of changes returned when _count is omitted
+	defaultSyncPageSize = 100
+
+	// maxSyncPageSize caps the number of changes returned per request
+	maxSyncPageSize = 1000
+)
+
+// ChangeFeedResponse is the compact change feed returned by the sync endpoint
+type ChangeFeedResponse struct {
+	Changes []*models.ResourceChange `json:"changes"`
+	Cursor  string                   `json:"cursor"`
+	HasMore bool                     `json:"has_more"`
+}
+
+// SyncHandler serves the differential sync API
+type SyncHandler struct {
+	syncService *service.SyncService
+	idCodec     idcodec.Codec
+}
+
+// NewSyncHandler creates a new instance of SyncHandler
+func NewSyncHandler(syncService *service.SyncService) *SyncHandler {
+	return &SyncHandler{
+		syncService: syncService,
+	}
+}
+
+// SetIDCodec exposes changed resources by the opaque IDs the codec issues to the caller's tenant
+func (handler *SyncHandler) SetIDCodec(codec idcodec.Codec) {
+	handler.idCodec = codec
+}
+
+// Changes handles GET /sync/changes?since={cursor}&_count={n} - returns changes after the cursor
+func (handler *SyncHandler) Changes(w http.ResponseWriter, r *http.Request) {
+	pageSize := defaultSyncPageSize
+	if countParam := r.URL.Query().Get("_count"); countParam != "" {
+		parsedCount, parseError := strconv.Atoi(countParam)
+		if parseError != nil || parsedCount < 1 || parsedCount > maxSyncPageSize {
+			middleware.WriteError(w, r, apperrors.InvalidInput("_count", "must be between 1 and "+strconv.Itoa(maxSyncPageSize)))
+			return
+		}
+		pageSize = parsedCount
+	}
+
+	changePage, changesError := handler.syncService.ChangesSince(r.Context(), r.URL.Query().Get("since"), pageSize)
+	if changesError != nil {
+		middleware.WriteError(w, r, changesError)
+		return
+	}
+
+	// Copies are exposed so the opaque IDs never reach the service's change records
+	exposedChanges := make([]*models.ResourceChange, 0, len(changePage.Changes))
+	for _, change := range changePage.Changes {
+		exposedChange := *change
+		exposeID(r.Context(), handler.idCodec, exposedChange.ResourceType, &exposedChange.ResourceID)
+		exposedChanges = append(exposedChanges, &exposedChange)
+	}
+
+	feedResponse := ChangeFeedResponse{
+		Changes: exposedChanges,
+		Cursor:  changePage.Cursor,
+		HasMore: changePage.HasMore,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(feedResponse)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// MockChangeRepository implements ChangeRepository interface for testing
+type MockChangeRepository struct {
+	changes []*models.ResourceChange
+}
+
+// InTransaction runs work without transactional semantics
+func (mock *MockChangeRepository) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return work(ctx)
+}
+
+// Record appends a change with the next sequence
+func (mock *MockChangeRepository) Record(ctx context.Context, change *models.ResourceChange) (*models.ResourceChange, error) {
+	change.Sequence = int64(len(mock.changes) + 1)
+	change.Version = 1
+	mock.changes = append(mock.changes, change)
+	return change, nil
+}
+
+// ListSince returns changes after the given sequence
+func (mock *MockChangeRepository) ListSince(ctx context.Context, afterSequence int64, limit int) ([]*models.ResourceChange, error) {
+	result := []*models.ResourceChange{}
+	for _, change := range mock.changes {
+		if change.Sequence > afterSequence && len(result) < limit {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// TestSyncHandler_Changes verifies the feed is returned with a follow-up cursor
+func TestSyncHandler_Changes(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	changeRepository.Record(context.Background(), &models.ResourceChange{ResourceType: "Patient", ResourceID: "p1", Operation: models.ChangeOperationCreate})
+	changeRepository.Record(context.Background(), &models.ResourceChange{ResourceType: "Observation", ResourceID: "o1", Operation: models.ChangeOperationCreate})
+	handler := NewSyncHandler(service.NewSyncService(changeRepository))
+
+	recorder := httptest.NewRecorder()
+	handler.Changes(recorder, httptest.NewRequest(http.MethodGet, "/sync/changes?_count=1", nil))
+
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", recorder.Code)
+	}
+	var feedResponse ChangeFeedResponse
+	if decodeError := json.NewDecoder(recorder.Body).Decode(&feedResponse); decodeError != nil {
+		t.Fatalf("Failed to decode response: %v", decodeError)
+	}
+	if len(feedResponse.Changes) != 1 || feedResponse.Changes[0].ResourceID != "p1" || !feedResponse.HasMore {
+		t.Fatalf("Unexpected first page: %+v", feedResponse)
+	}
+
+	nextRecorder := httptest.NewRecorder()
+	handler.Changes(nextRecorder, httptest.NewRequest(http.MethodGet, "/sync/changes?since="+feedResponse.Cursor, nil))
+	var nextResponse ChangeFeedResponse
+	json.NewDecoder(nextRecorder.Body).Decode(&nextResponse)
+	if len(nextResponse.Changes) != 1 || nextResponse.Changes[0].ResourceID != "o1" || nextResponse.HasMore {
+		t.Errorf("Unexpected second page: %+v", nextResponse)
+	}
+}
+
+// TestSyncHandler_Changes_InvalidParameters verifies bad cursors and counts are rejected
+func TestSyncHandler_Changes_InvalidParameters(t *testing.T) {
+	handler := NewSyncHandler(service.NewSyncService(&MockChangeRepository{}))
+
+	for _, target := range []string{"/sync/changes?since=bogus", "/sync/changes?_count=0", "/sync/changes?_count=abc"} {
+		recorder := httptest.NewRecorder()
+		handler.Changes(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", target, recorder.Code)
+		}
+	}
+}
+
+// TestSyncHandler_Changes_OpaqueIDs verifies changed resources are listed by the caller's opaque IDs
+func TestSyncHandler_Changes_OpaqueIDs(t *testing.T) {
+	codec := newTestIDCodec(t)
+	changeRepository := &MockChangeRepository{}
+	changeRepository.Record(context.Background(), &models.ResourceChange{ResourceType: "Patient", ResourceID: "p1", Operation: models.ChangeOperationCreate})
+	handler := NewSyncHandler(service.NewSyncService(changeRepository))
+	handler.SetIDCodec(codec)
+
+	recorder := httptest.NewRecorder()
+	handler.Changes(recorder, httptest.NewRequest(http.MethodGet, "/sync/changes", nil).WithContext(tenant.WithVerifiedTenant(context.Background(), "acme")))
+
+	var feedResponse ChangeFeedResponse
+	json.NewDecoder(recorder.Body).Decode(&feedResponse)
+	if len(feedResponse.Changes) != 1 || feedResponse.Changes[0].ResourceID != codec.Encode("acme", "Patient", "p1") {
+		t.Errorf("Expected the opaque ID issued to acme, got %+v", feedResponse.Changes)
+	}
+	if changeRepository.changes[0].ResourceID != "p1" {
+		t.Errorf("Expected the stored change to keep its internal ID, got %s", changeRepository.changes[0].ResourceID)
+	}
+}
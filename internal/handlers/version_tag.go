@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// errInvalidVersionTag is returned for an If-Match header that does not name a single resource version
+var errInvalidVersionTag = errors.New(`If-Match must name one version, e.g. W/"3"`)
+
+// versionTag returns the weak ETag FHIR uses for a resource version, e.g. W/"3"
+func versionTag(versionID string) string {
+	return `W/"` + versionID + `"`
+}
+
+// setVersionTag sends the ETag of the resource version described by meta, when it carries one
+func setVersionTag(w http.ResponseWriter, meta *fhir.Meta) {
+	if meta != nil && meta.VersionId != nil {
+		w.Header().Set("ETag", versionTag(*meta.VersionId))
+	}
+}
+
+// requiredVersion parses the request's If-Match header into the version a write must find the resource at
+// It returns 0 when there is no header or it is "*", which any existing version satisfies
+func requiredVersion(r *http.Request) (int, error) {
+	ifMatch := strings.TrimSpace(r.Header.Get("If-Match"))
+	if ifMatch == "" || ifMatch == "*" {
+		return 0, nil
+	}
+
+	quotedVersion := strings.TrimPrefix(ifMatch, "W/")
+	if len(quotedVersion) < 2 || !strings.HasPrefix(quotedVersion, `"`) || !strings.HasSuffix(quotedVersion, `"`) {
+		return 0, errInvalidVersionTag
+	}
+	version, parseError := strconv.Atoi(quotedVersion[1 : len(quotedVersion)-1])
+	if parseError != nil || version < 1 {
+		return 0, errInvalidVersionTag
+	}
+	return version, nil
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// writeWriteError reports a failed create or update
+// Resources rejected for unmappable data get a 422 OperationOutcome; anything else is an internal error
+func writeWriteError(w http.ResponseWriter, r *http.Request, writeError error, message string) {
+	var rejection *outcome.RejectionError
+	if errors.As(writeError, &rejection) {
+		outcome.WriteForRequest(w, r, http.StatusUnprocessableEntity, rejection.Issues)
+		return
+	}
+
+	middleware.WriteError(w, r, apperrors.Internal(message, writeError))
+}
+
+// writeDeleteError reports a failed delete
+// Application errors such as legal hold conflicts are passed through, and a resource already deleted is
+// reported as gone; anything else is reported as not found
+func writeDeleteError(w http.ResponseWriter, r *http.Request, deleteError error, resourceType string, resourceID string) {
+	var appError *apperrors.AppError
+	if errors.As(deleteError, &appError) {
+		middleware.WriteError(w, r, appError)
+		return
+	}
+
+	writeNotFoundOrGone(w, r, deleteError, resourceType, resourceID)
+}
+
+// writeNotFoundOrGone reports a resource that could not be read: 410 Gone when it was deleted, and 404 otherwise
+func writeNotFoundOrGone(w http.ResponseWriter, r *http.Request, readError error, resourceType string, resourceID string) {
+	if errors.Is(readError, service.ErrResourceDeleted) {
+		middleware.WriteError(w, r, apperrors.Gone(resourceType, resourceID))
+		return
+	}
+	middleware.WriteError(w, r, apperrors.NotFound(resourceType, resourceID))
+}
+
+// writeWriteResult sends a created or updated resource along with any warnings raised while storing it
+// Clients sending "Prefer: return=OperationOutcome" receive the warnings as the body instead of the resource;
+// otherwise each warning is reported in a Warning header so the FHIR resource body stays unchanged
+func writeWriteResult(w http.ResponseWriter, r *http.Request, statusCode int, resource interface{}, issues []outcome.Issue) {
+	if prefersOperationOutcome(r) {
+		if len(issues) == 0 {
+			issues = []outcome.Issue{outcome.Information(fhir.IssueTypeInformational, "Resource stored without issues")}
+		}
+		encoding.Write(w, r, statusCode, outcome.New(issues))
+		return
+	}
+
+	for _, issue := range issues {
+		w.Header().Add("Warning", "199 - "+strconv.Quote(issue.Diagnostics))
+	}
+
+	encoding.Write(w, r, statusCode, resource)
+}
+
+// prefersOperationOutcome reports whether the client asked for an OperationOutcome response body
+func prefersOperationOutcome(r *http.Request) bool {
+	return hasPreference(r, "return=OperationOutcome")
+}
+
+// hasPreference reports whether the request's Prefer header lists the preference, ignoring case
+func hasPreference(r *http.Request, wanted string) bool {
+	for _, preference := range strings.Split(r.Header.Get("Prefer"), ",") {
+		if strings.EqualFold(strings.TrimSpace(preference), wanted) {
+			return true
+		}
+	}
+	return false
+}
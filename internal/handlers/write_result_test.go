@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestObservationHandler_Create_ReportsMappingWarnings verifies warnings are sent as Warning headers
+func TestObservationHandler_Create_ReportsMappingWarnings(t *testing.T) {
+	mockService := NewMockObservationService()
+	mockService.createIssues = []outcome.Issue{outcome.Warning(fhir.IssueTypeValue, "Observation.issued was not stored")}
+	handler := NewObservationHandler(mockService)
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Observation", bytes.NewBufferString(`{"status": "final"}`))
+	recorder := httptest.NewRecorder()
+
+	handler.Create(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Warning") != `199 - "Observation.issued was not stored"` {
+		t.Errorf("Unexpected Warning header: %q", recorder.Header().Get("Warning"))
+	}
+	if !strings.Contains(recorder.Body.String(), `"resourceType":"Observation"`) {
+		t.Errorf("Expected the resource body, got %s", recorder.Body.String())
+	}
+}
+
+// TestObservationHandler_Create_PreferOperationOutcome verifies the warnings replace the body on request
+func TestObservationHandler_Create_PreferOperationOutcome(t *testing.T) {
+	mockService := NewMockObservationService()
+	mockService.createIssues = []outcome.Issue{outcome.Warning(fhir.IssueTypeValue, "Observation.issued was not stored")}
+	handler := NewObservationHandler(mockService)
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Observation", bytes.NewBufferString(`{"status": "final"}`))
+	request.Header.Set("Prefer", "handling=lenient, return=OperationOutcome")
+	recorder := httptest.NewRecorder()
+
+	handler.Create(recorder, request)
+
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", recorder.Code)
+	}
+	body := recorder.Body.String()
+	if !strings.Contains(body, `"resourceType":"OperationOutcome"`) || !strings.Contains(body, "Observation.issued was not stored") {
+		t.Errorf("Expected OperationOutcome with the warning, got %s", body)
+	}
+}
+
+// TestObservationHandler_Create_RejectedMapping verifies strict rejections become 422 OperationOutcomes
+func TestObservationHandler_Create_RejectedMapping(t *testing.T) {
+	mockService := NewMockObservationService()
+	mockService.createError = &outcome.RejectionError{Issues: []outcome.Issue{outcome.Error(fhir.IssueTypeValue, "bad issued")}}
+	handler := NewObservationHandler(mockService)
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Observation", bytes.NewBufferString(`{"status": "final"}`))
+	recorder := httptest.NewRecorder()
+
+	handler.Create(recorder, request)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), "bad issued") {
+		t.Errorf("Expected rejection diagnostics, got %s", recorder.Body.String())
+	}
+}
+
+// TestPatientHandler_Create_ReportsIgnoredElements verifies unsupported elements are reported in Warning headers or,
+// with Prefer: return=OperationOutcome, as informational issues in the body, while the patient is still created
+func TestPatientHandler_Create_ReportsIgnoredElements(t *testing.T) {
+	handler := NewPatientHandlerWithService(service.NewPatientService(NewMockPatientRepository()))
+	patientBody := `{"resourceType":"Patient","name":[{"family":"Smith","given":["John"]}],"telecom":[{"system":"phone","value":"555-0100"}]}`
+
+	headerRecorder := httptest.NewRecorder()
+	handler.Create(headerRecorder, httptest.NewRequest(http.MethodPost, "/fhir/Patient", strings.NewReader(patientBody)))
+
+	if headerRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", headerRecorder.Code)
+	}
+	if headerRecorder.Header().Get("Warning") != `199 - "Patient.telecom is not supported and was not stored"` {
+		t.Errorf("Unexpected Warning header: %q", headerRecorder.Header().Get("Warning"))
+	}
+	if !strings.Contains(headerRecorder.Body.String(), `"resourceType":"Patient"`) {
+		t.Errorf("Expected the patient body, got %s", headerRecorder.Body.String())
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", strings.NewReader(patientBody))
+	request.Header.Set("Prefer", "return=OperationOutcome")
+	outcomeRecorder := httptest.NewRecorder()
+	handler.Create(outcomeRecorder, request)
+
+	if outcomeRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", outcomeRecorder.Code)
+	}
+	var operationOutcome fhir.OperationOutcome
+	json.NewDecoder(outcomeRecorder.Body).Decode(&operationOutcome)
+	if len(operationOutcome.Issue) != 1 || operationOutcome.Issue[0].Severity != fhir.IssueSeverityInformation || operationOutcome.Issue[0].Expression[0] != "Patient.telecom" {
+		t.Errorf("Expected one informational issue for Patient.telecom, got %+v", operationOutcome.Issue)
+	}
+}
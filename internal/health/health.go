@@ -0,0 +1,101 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Dependency states reported by the readiness check
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// CheckFunc verifies one dependency answers, for example by pinging a database
+type CheckFunc func(ctx context.Context) error
+
+// check is a named dependency check
+type check struct {
+	name string
+	run  CheckFunc
+}
+
+// DependencyStatus records how one dependency answered
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// Report is the readiness result: up only when every dependency is up
+type Report struct {
+	Status       string             `json:"status"`
+	CheckedAt    time.Time          `json:"checked_at"`
+	Dependencies []DependencyStatus `json:"dependencies"`
+}
+
+// Up reports whether every dependency answered
+func (report Report) Up() bool {
+	return report.Status == StatusUp
+}
+
+// Checker runs every dependency check on demand, each bounded by the same timeout
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+}
+
+// NewChecker creates a checker with no checks, giving each check at most timeout to answer
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Add registers a dependency check; reports list dependencies in the order they were added
+func (checker *Checker) Add(name string, run CheckFunc) {
+	checker.checks = append(checker.checks, check{name: name, run: run})
+}
+
+// Check runs every check concurrently, so one slow dependency delays the report by at most the timeout
+func (checker *Checker) Check(ctx context.Context) Report {
+	report := Report{
+		Status:       StatusUp,
+		CheckedAt:    time.Now().UTC(),
+		Dependencies: make([]DependencyStatus, len(checker.checks)),
+	}
+
+	var waitGroup sync.WaitGroup
+	for checkIndex, dependencyCheck := range checker.checks {
+		waitGroup.Go(func() {
+			report.Dependencies[checkIndex] = checker.run(ctx, dependencyCheck)
+		})
+	}
+	waitGroup.Wait()
+
+	for _, dependency := range report.Dependencies {
+		if dependency.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// run executes one check under the timeout and times it
+func (checker *Checker) run(ctx context.Context, dependencyCheck check) DependencyStatus {
+	checkContext, cancel := context.WithTimeout(ctx, checker.timeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	checkError := dependencyCheck.run(checkContext)
+	dependency := DependencyStatus{
+		Name:      dependencyCheck.name,
+		Status:    StatusUp,
+		LatencyMS: float64(time.Since(startedAt).Microseconds()) / 1000,
+	}
+	if checkError != nil {
+		dependency.Status = StatusDown
+		dependency.Error = checkError.Error()
+	}
+	return dependency
+}
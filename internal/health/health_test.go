@@ -0,0 +1,54 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestChecker_AllUp verifies the report is up when every dependency answers
+func TestChecker_AllUp(t *testing.T) {
+	checker := NewChecker(time.Second)
+	checker.Add("postgres", func(ctx context.Context) error { return nil })
+	checker.Add("mongodb", func(ctx context.Context) error { return nil })
+
+	report := checker.Check(context.Background())
+	if !report.Up() || len(report.Dependencies) != 2 {
+		t.Fatalf("Expected both dependencies up, got %+v", report)
+	}
+	if report.Dependencies[0].Name != "postgres" || report.Dependencies[1].Name != "mongodb" {
+		t.Errorf("Expected dependencies in the order they were added, got %+v", report.Dependencies)
+	}
+}
+
+// TestChecker_DependencyDown verifies a failing or hanging dependency marks the report down within the timeout
+func TestChecker_DependencyDown(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Add("postgres", func(ctx context.Context) error { return nil })
+	checker.Add("mongodb", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	checker.Add("cache", func(ctx context.Context) error { return errors.New("connection refused") })
+
+	startedAt := time.Now()
+	report := checker.Check(context.Background())
+	if elapsed := time.Since(startedAt); elapsed > time.Second {
+		t.Errorf("Expected the hanging check to be cut off at the timeout, took %v", elapsed)
+	}
+
+	if report.Up() {
+		t.Fatal("Expected the report to be down")
+	}
+	postgres, mongodb, cache := report.Dependencies[0], report.Dependencies[1], report.Dependencies[2]
+	if postgres.Status != StatusUp || postgres.Error != "" {
+		t.Errorf("Expected postgres up, got %+v", postgres)
+	}
+	if mongodb.Status != StatusDown || mongodb.Error != context.DeadlineExceeded.Error() || mongodb.LatencyMS < 50 {
+		t.Errorf("Expected mongodb down after the timeout, got %+v", mongodb)
+	}
+	if cache.Status != StatusDown || cache.Error != "connection refused" {
+		t.Errorf("Expected the cache's error, got %+v", cache)
+	}
+}
@@ -0,0 +1,113 @@
+package healthexport
+
+import (
+	"fmt"
+	"sort"
+)
+
+// googleFitDataSourceSuffix completes the raw data source IDs of exported datasets
+const googleFitDataSourceSuffix = ":fhir-health-interop"
+
+// googleFitFieldCounts is the number of fields in a data point of each Google Fit data type
+// Fields the export has no value for are sent empty
+var googleFitFieldCounts = map[string]int{
+	"com.google.heart_rate.bpm":    1,
+	"com.google.body.temperature":  2,
+	"com.google.oxygen_saturation": 5,
+	"com.google.blood_pressure":    4,
+	"com.google.weight":            1,
+	"com.google.height":            1,
+}
+
+// GoogleFitPayload holds one Google Fit dataset per data type, ready for the Fitness REST API's
+// users.dataSources.datasets.patch
+type GoogleFitPayload struct {
+	DataSets []GoogleFitDataSet `json:"dataSets"`
+}
+
+// GoogleFitDataSet is every exported point of one data type
+// Nanosecond times are strings, as in the Fitness REST API
+type GoogleFitDataSet struct {
+	DataSourceID   string           `json:"dataSourceId"`
+	DataTypeName   string           `json:"dataTypeName"`
+	MinStartTimeNs int64            `json:"minStartTimeNs,string"`
+	MaxEndTimeNs   int64            `json:"maxEndTimeNs,string"`
+	Point          []GoogleFitPoint `json:"point"`
+}
+
+// GoogleFitPoint is one data point; Value holds the data type's fields in order
+type GoogleFitPoint struct {
+	DataTypeName   string           `json:"dataTypeName"`
+	StartTimeNanos int64            `json:"startTimeNanos,string"`
+	EndTimeNanos   int64            `json:"endTimeNanos,string"`
+	Value          []GoogleFitValue `json:"value"`
+}
+
+// GoogleFitValue is one field of a data point; FloatValue is nil for fields without a value
+type GoogleFitValue struct {
+	FloatValue *float64 `json:"fpVal,omitempty"`
+}
+
+// buildGoogleFit converts readings into Google Fit datasets
+// Values of one reading that share a data type, such as systolic and diastolic pressure, form one point
+// Vital signs Google Fit has no data type for are returned as skipped
+func buildGoogleFit(readings []reading) (*GoogleFitPayload, []Skipped) {
+	dataSetsByType := make(map[string]*GoogleFitDataSet)
+	skipped := make([]Skipped, 0)
+
+	for _, exportReading := range readings {
+		pointsByType := make(map[string]*GoogleFitPoint)
+		var pointTypes []string
+		for _, value := range exportReading.values {
+			dataTypeName := value.vital.googleFitType
+			if dataTypeName == "" {
+				skipped = append(skipped, Skipped{
+					Observation: exportReading.observationID,
+					Reason:      fmt.Sprintf("Google Fit has no data type for %s", value.vital.display),
+				})
+				continue
+			}
+
+			point, exists := pointsByType[dataTypeName]
+			if !exists {
+				point = &GoogleFitPoint{
+					DataTypeName:   dataTypeName,
+					StartTimeNanos: exportReading.effective.UnixNano(),
+					EndTimeNanos:   exportReading.effective.UnixNano(),
+					Value:          make([]GoogleFitValue, googleFitFieldCounts[dataTypeName]),
+				}
+				pointsByType[dataTypeName] = point
+				pointTypes = append(pointTypes, dataTypeName)
+			}
+			fieldValue := value.amount * value.vital.googleFitScale
+			point.Value[value.vital.googleFitField].FloatValue = &fieldValue
+		}
+
+		for _, dataTypeName := range pointTypes {
+			point := pointsByType[dataTypeName]
+			dataSet, exists := dataSetsByType[dataTypeName]
+			if !exists {
+				dataSet = &GoogleFitDataSet{
+					DataSourceID:   "raw:" + dataTypeName + googleFitDataSourceSuffix,
+					DataTypeName:   dataTypeName,
+					MinStartTimeNs: point.StartTimeNanos,
+					MaxEndTimeNs:   point.EndTimeNanos,
+				}
+				dataSetsByType[dataTypeName] = dataSet
+			}
+			dataSet.MinStartTimeNs = min(dataSet.MinStartTimeNs, point.StartTimeNanos)
+			dataSet.MaxEndTimeNs = max(dataSet.MaxEndTimeNs, point.EndTimeNanos)
+			dataSet.Point = append(dataSet.Point, *point)
+		}
+	}
+
+	payload := &GoogleFitPayload{DataSets: make([]GoogleFitDataSet, 0, len(dataSetsByType))}
+	for _, dataSet := range dataSetsByType {
+		payload.DataSets = append(payload.DataSets, *dataSet)
+	}
+	sort.Slice(payload.DataSets, func(left int, right int) bool {
+		return payload.DataSets[left].DataTypeName < payload.DataSets[right].DataTypeName
+	})
+
+	return payload, skipped
+}
@@ -0,0 +1,342 @@
+package healthexport
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Format is a consumer health platform an export is shaped for
+type Format string
+
+// Supported export formats
+const (
+	// FormatHealthKit shapes the export as Apple HealthKit quantity samples and correlations
+	FormatHealthKit Format = "healthkit"
+
+	// FormatGoogleFit shapes the export as Google Fit datasets
+	FormatGoogleFit Format = "googlefit"
+)
+
+// loincSystem is the code system every exported vital sign is coded in
+const loincSystem = "http://loinc.org"
+
+// ParseFormat validates a requested export format
+func ParseFormat(rawFormat string) (Format, error) {
+	switch Format(rawFormat) {
+	case FormatHealthKit, FormatGoogleFit:
+		return Format(rawFormat), nil
+	}
+	return "", fmt.Errorf("unsupported export format %q, expected %s or %s", rawFormat, FormatHealthKit, FormatGoogleFit)
+}
+
+// Export is a patient's vital signs converted for a health platform
+// Exactly one of HealthKit and GoogleFit is set, matching Format
+type Export struct {
+	Patient   string            `json:"patient"`
+	Format    Format            `json:"format"`
+	HealthKit *HealthKitPayload `json:"healthkit,omitempty"`
+	GoogleFit *GoogleFitPayload `json:"googlefit,omitempty"`
+
+	// Skipped lists the vital-sign observations, or values within them, that could not be exported and why
+	Skipped []Skipped `json:"skipped"`
+
+	// Truncated is set when the export stopped at its size limit; request the rest with a later start
+	Truncated bool `json:"truncated"`
+}
+
+// Skipped explains why an observation or one of its values was left out of an export
+type Skipped struct {
+	Observation string `json:"observation"`
+	Reason      string `json:"reason"`
+}
+
+// Build converts the vital signs among observations into an export for format
+func Build(format Format, observations []*fhir.Observation) *Export {
+	readings, skipped := extractReadings(observations)
+
+	export := &Export{Format: format}
+	switch format {
+	case FormatHealthKit:
+		export.HealthKit = buildHealthKit(readings)
+	case FormatGoogleFit:
+		var unsupported []Skipped
+		export.GoogleFit, unsupported = buildGoogleFit(readings)
+		skipped = append(skipped, unsupported...)
+	}
+	export.Skipped = skipped
+
+	return export
+}
+
+// vital maps one LOINC vital sign onto the health platforms
+type vital struct {
+	display string
+
+	// units converts each accepted unit spelling to the vital's canonical unit
+	units map[string]func(float64) float64
+
+	// healthKitType and healthKitUnit name the HealthKit quantity type and unit string;
+	// healthKitScale converts the canonical value into that unit
+	healthKitType  string
+	healthKitUnit  string
+	healthKitScale float64
+
+	// googleFitType and googleFitField locate the value within a Google Fit data point, and
+	// googleFitScale converts the canonical value; an empty type means Google Fit has no equivalent
+	googleFitType  string
+	googleFitField int
+	googleFitScale float64
+}
+
+// unchanged accepts a value already in the canonical unit
+func unchanged(value float64) float64 {
+	return value
+}
+
+// scaledBy returns a conversion that multiplies by factor
+func scaledBy(factor float64) func(float64) float64 {
+	return func(value float64) float64 {
+		return value * factor
+	}
+}
+
+// fahrenheitToCelsius converts a temperature in degrees Fahrenheit to degrees Celsius
+func fahrenheitToCelsius(value float64) float64 {
+	return (value - 32) * 5 / 9
+}
+
+// Vital signs that can be exported; the comment on each names its canonical unit
+var (
+	// heartRate is in beats per minute
+	heartRate = &vital{
+		display:        "Heart rate",
+		units:          map[string]func(float64) float64{"/min": unchanged, "beats/min": unchanged, "{beats}/min": unchanged, "bpm": unchanged},
+		healthKitType:  "HKQuantityTypeIdentifierHeartRate",
+		healthKitUnit:  "count/min",
+		healthKitScale: 1,
+		googleFitType:  "com.google.heart_rate.bpm",
+		googleFitScale: 1,
+	}
+
+	// respiratoryRate is in breaths per minute
+	respiratoryRate = &vital{
+		display:        "Respiratory rate",
+		units:          map[string]func(float64) float64{"/min": unchanged, "breaths/min": unchanged, "{breaths}/min": unchanged},
+		healthKitType:  "HKQuantityTypeIdentifierRespiratoryRate",
+		healthKitUnit:  "count/min",
+		healthKitScale: 1,
+	}
+
+	// bodyTemperature is in degrees Celsius
+	bodyTemperature = &vital{
+		display:        "Body temperature",
+		units:          map[string]func(float64) float64{"Cel": unchanged, "°C": unchanged, "C": unchanged, "[degF]": fahrenheitToCelsius, "°F": fahrenheitToCelsius, "F": fahrenheitToCelsius},
+		healthKitType:  "HKQuantityTypeIdentifierBodyTemperature",
+		healthKitUnit:  "degC",
+		healthKitScale: 1,
+		googleFitType:  "com.google.body.temperature",
+		googleFitScale: 1,
+	}
+
+	// oxygenSaturation is a percentage; HealthKit expects a fraction between 0 and 1
+	oxygenSaturation = &vital{
+		display:        "Oxygen saturation",
+		units:          map[string]func(float64) float64{"%": unchanged},
+		healthKitType:  "HKQuantityTypeIdentifierOxygenSaturation",
+		healthKitUnit:  "%",
+		healthKitScale: 0.01,
+		googleFitType:  "com.google.oxygen_saturation",
+		googleFitScale: 1,
+	}
+
+	// systolicPressure is in millimetres of mercury
+	systolicPressure = &vital{
+		display:        "Systolic blood pressure",
+		units:          map[string]func(float64) float64{"mm[Hg]": unchanged, "mmHg": unchanged},
+		healthKitType:  "HKQuantityTypeIdentifierBloodPressureSystolic",
+		healthKitUnit:  "mmHg",
+		healthKitScale: 1,
+		googleFitType:  "com.google.blood_pressure",
+		googleFitScale: 1,
+	}
+
+	// diastolicPressure is in millimetres of mercury
+	diastolicPressure = &vital{
+		display:        "Diastolic blood pressure",
+		units:          map[string]func(float64) float64{"mm[Hg]": unchanged, "mmHg": unchanged},
+		healthKitType:  "HKQuantityTypeIdentifierBloodPressureDiastolic",
+		healthKitUnit:  "mmHg",
+		healthKitScale: 1,
+		googleFitType:  "com.google.blood_pressure",
+		googleFitField: 1,
+		googleFitScale: 1,
+	}
+
+	// bodyWeight is in kilograms
+	bodyWeight = &vital{
+		display:        "Body weight",
+		units:          map[string]func(float64) float64{"kg": unchanged, "g": scaledBy(0.001), "[lb_av]": scaledBy(0.45359237), "lb": scaledBy(0.45359237), "lbs": scaledBy(0.45359237)},
+		healthKitType:  "HKQuantityTypeIdentifierBodyMass",
+		healthKitUnit:  "kg",
+		healthKitScale: 1,
+		googleFitType:  "com.google.weight",
+		googleFitScale: 1,
+	}
+
+	// bodyHeight is in centimetres; Google Fit expects metres
+	bodyHeight = &vital{
+		display:        "Body height",
+		units:          map[string]func(float64) float64{"cm": unchanged, "m": scaledBy(100), "[in_i]": scaledBy(2.54), "in": scaledBy(2.54)},
+		healthKitType:  "HKQuantityTypeIdentifierHeight",
+		healthKitUnit:  "cm",
+		healthKitScale: 1,
+		googleFitType:  "com.google.height",
+		googleFitScale: 0.01,
+	}
+
+	// bodyMassIndex is in kilograms per square metre
+	bodyMassIndex = &vital{
+		display:        "Body mass index",
+		units:          map[string]func(float64) float64{"kg/m2": unchanged, "kg/m^2": unchanged},
+		healthKitType:  "HKQuantityTypeIdentifierBodyMassIndex",
+		healthKitUnit:  "count",
+		healthKitScale: 1,
+	}
+)
+
+// vitalsByCode maps LOINC codes to the vital signs they measure
+var vitalsByCode = map[string]*vital{
+	"8867-4":  heartRate,
+	"9279-1":  respiratoryRate,
+	"8310-5":  bodyTemperature,
+	"59408-5": oxygenSaturation,
+	"2708-6":  oxygenSaturation,
+	"8480-6":  systolicPressure,
+	"8462-4":  diastolicPressure,
+	"29463-7": bodyWeight,
+	"8302-2":  bodyHeight,
+	"39156-5": bodyMassIndex,
+}
+
+// reading is one exportable observation: when it was taken and its vital-sign values in canonical units
+type reading struct {
+	observationID string
+	effective     time.Time
+	values        []vitalValue
+}
+
+// vitalValue is one vital-sign value in its canonical unit
+type vitalValue struct {
+	vital  *vital
+	amount float64
+}
+
+// codedQuantity is an observation's or component's code with its quantity value and the vital sign it measures
+type codedQuantity struct {
+	code     fhir.CodeableConcept
+	quantity *fhir.Quantity
+	vital    *vital
+}
+
+// value returns the reading's value for a vital, if it has one
+func (exportReading reading) value(target *vital) (float64, bool) {
+	for _, candidate := range exportReading.values {
+		if candidate.vital == target {
+			return candidate.amount, true
+		}
+	}
+	return 0, false
+}
+
+// extractReadings maps observations and their components to vital-sign values
+// Observations that measure no supported vital sign are left out silently, since a patient's record holds
+// many other observations; a vital sign without an effective time, or in an unknown unit, is listed as skipped
+func extractReadings(observations []*fhir.Observation) ([]reading, []Skipped) {
+	readings := make([]reading, 0, len(observations))
+	skipped := make([]Skipped, 0)
+
+	for _, observation := range observations {
+		candidates := []codedQuantity{{code: observation.Code, quantity: observation.ValueQuantity}}
+		for _, component := range observation.Component {
+			candidates = append(candidates, codedQuantity{code: component.Code, quantity: component.ValueQuantity})
+		}
+		vitalCandidates := make([]codedQuantity, 0, len(candidates))
+		for _, candidate := range candidates {
+			candidate.vital = vitalFor(candidate.code)
+			if candidate.vital != nil && candidate.quantity != nil && candidate.quantity.Value != nil {
+				vitalCandidates = append(vitalCandidates, candidate)
+			}
+		}
+		if len(vitalCandidates) == 0 {
+			continue
+		}
+
+		observationReference := "Observation/"
+		if observation.Id != nil {
+			observationReference += *observation.Id
+		}
+		if observation.EffectiveDateTime == nil {
+			skipped = append(skipped, Skipped{Observation: observationReference, Reason: "no effective date-time"})
+			continue
+		}
+		effective, parseError := time.Parse(time.RFC3339, *observation.EffectiveDateTime)
+		if parseError != nil {
+			skipped = append(skipped, Skipped{Observation: observationReference, Reason: "effective date-time is not a full timestamp"})
+			continue
+		}
+
+		exportReading := reading{observationID: observationReference, effective: effective.UTC()}
+		for _, candidate := range vitalCandidates {
+			amount, convertError := convert(candidate.vital, candidate.quantity)
+			if convertError != nil {
+				skipped = append(skipped, Skipped{Observation: observationReference, Reason: convertError.Error()})
+				continue
+			}
+			exportReading.values = append(exportReading.values, vitalValue{vital: candidate.vital, amount: amount})
+		}
+		if len(exportReading.values) > 0 {
+			readings = append(readings, exportReading)
+		}
+	}
+
+	return readings, skipped
+}
+
+// vitalFor returns the vital sign a LOINC-coded concept measures, or nil when it is not one
+func vitalFor(concept fhir.CodeableConcept) *vital {
+	for _, coding := range concept.Coding {
+		if coding.System == nil || *coding.System != loincSystem || coding.Code == nil {
+			continue
+		}
+		if matchedVital, supported := vitalsByCode[*coding.Code]; supported {
+			return matchedVital
+		}
+	}
+	return nil
+}
+
+// convert returns a quantity's value in the vital's canonical unit
+// The UCUM code is preferred; the human-readable unit is used when no code was sent
+func convert(matchedVital *vital, quantity *fhir.Quantity) (float64, error) {
+	amount, parseError := strconv.ParseFloat(quantity.Value.String(), 64)
+	if parseError != nil {
+		return 0, fmt.Errorf("%s value %q is not a number", matchedVital.display, quantity.Value.String())
+	}
+
+	unit := ""
+	if quantity.Unit != nil {
+		unit = *quantity.Unit
+	}
+	if quantity.Code != nil {
+		unit = *quantity.Code
+	}
+
+	conversion, accepted := matchedVital.units[unit]
+	if !accepted {
+		return 0, fmt.Errorf("%s unit %q cannot be converted", matchedVital.display, unit)
+	}
+	return conversion(amount), nil
+}
@@ -0,0 +1,143 @@
+package healthexport
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// loincConcept returns a concept coded in LOINC
+func loincConcept(code string) fhir.CodeableConcept {
+	system := loincSystem
+	return fhir.CodeableConcept{Coding: []fhir.Coding{{System: &system, Code: &code}}}
+}
+
+// quantity returns a quantity with the given value and unit
+func quantity(value string, unit string) *fhir.Quantity {
+	number := json.Number(value)
+	return &fhir.Quantity{Value: &number, Unit: &unit}
+}
+
+// observation returns an observation with an ID, code, effective time, and optional value
+func observation(observationID string, code string, effective string, value *fhir.Quantity) *fhir.Observation {
+	fhirObservation := &fhir.Observation{Id: &observationID, Code: loincConcept(code), ValueQuantity: value}
+	if effective != "" {
+		fhirObservation.EffectiveDateTime = &effective
+	}
+	return fhirObservation
+}
+
+// bloodPressurePanel returns a blood pressure panel with systolic and diastolic components
+func bloodPressurePanel(observationID string, effective string) *fhir.Observation {
+	panel := observation(observationID, "85354-9", effective, nil)
+	panel.Component = []fhir.ObservationComponent{
+		{Code: loincConcept("8480-6"), ValueQuantity: quantity("120", "mm[Hg]")},
+		{Code: loincConcept("8462-4"), ValueQuantity: quantity("80", "mm[Hg]")},
+	}
+	return panel
+}
+
+// TestBuild_HealthKit verifies samples, unit conversion, and blood pressure correlations
+func TestBuild_HealthKit(t *testing.T) {
+	export := Build(FormatHealthKit, []*fhir.Observation{
+		observation("hr", "8867-4", "2024-05-01T08:00:00Z", quantity("72", "/min")),
+		observation("spo2", "59408-5", "2024-05-01T08:00:00Z", quantity("97", "%")),
+		observation("weight", "29463-7", "2024-05-01T08:00:00Z", quantity("150", "[lb_av]")),
+		bloodPressurePanel("bp", "2024-05-01T08:05:00Z"),
+	})
+
+	payload := export.HealthKit
+	if payload == nil || export.GoogleFit != nil {
+		t.Fatalf("Expected only a HealthKit payload, got %+v", export)
+	}
+	if len(payload.Samples) != 3 || len(payload.Correlations) != 1 {
+		t.Fatalf("Expected 3 samples and 1 correlation, got %d and %d", len(payload.Samples), len(payload.Correlations))
+	}
+	if payload.Samples[0].Type != "HKQuantityTypeIdentifierHeartRate" || payload.Samples[0].Unit != "count/min" || payload.Samples[0].Metadata[externalUUIDKey] != "Observation/hr" {
+		t.Errorf("Expected a heart rate sample tagged with its observation, got %+v", payload.Samples[0])
+	}
+	if payload.Samples[1].Value != 0.97 {
+		t.Errorf("Expected oxygen saturation as a fraction, got %v", payload.Samples[1].Value)
+	}
+	if math.Abs(payload.Samples[2].Value-68.0388555) > 0.0001 || payload.Samples[2].Unit != "kg" {
+		t.Errorf("Expected weight converted to kilograms, got %v %s", payload.Samples[2].Value, payload.Samples[2].Unit)
+	}
+
+	correlation := payload.Correlations[0]
+	if correlation.Type != bloodPressureCorrelation || len(correlation.Objects) != 2 {
+		t.Fatalf("Expected a blood pressure correlation of two samples, got %+v", correlation)
+	}
+	if correlation.Objects[0].Type != "HKQuantityTypeIdentifierBloodPressureSystolic" || correlation.Objects[1].Value != 80 {
+		t.Errorf("Expected systolic and diastolic samples, got %+v", correlation.Objects)
+	}
+}
+
+// TestBuild_GoogleFit verifies datasets per data type, shared blood pressure points, and unsupported vitals
+func TestBuild_GoogleFit(t *testing.T) {
+	export := Build(FormatGoogleFit, []*fhir.Observation{
+		observation("height", "8302-2", "2024-05-01T08:00:00Z", quantity("180", "cm")),
+		observation("resp", "9279-1", "2024-05-01T08:00:00Z", quantity("16", "/min")),
+		bloodPressurePanel("bp", "2024-05-01T08:05:00Z"),
+	})
+
+	dataSets := export.GoogleFit.DataSets
+	if len(dataSets) != 2 || dataSets[0].DataTypeName != "com.google.blood_pressure" || dataSets[1].DataTypeName != "com.google.height" {
+		t.Fatalf("Expected blood pressure and height datasets, got %+v", dataSets)
+	}
+
+	pressurePoint := dataSets[0].Point[0]
+	if len(pressurePoint.Value) != 4 || *pressurePoint.Value[0].FloatValue != 120 || *pressurePoint.Value[1].FloatValue != 80 || pressurePoint.Value[2].FloatValue != nil {
+		t.Errorf("Expected systolic and diastolic in one point, got %+v", pressurePoint.Value)
+	}
+	if *dataSets[1].Point[0].Value[0].FloatValue != 1.8 {
+		t.Errorf("Expected height in metres, got %v", *dataSets[1].Point[0].Value[0].FloatValue)
+	}
+
+	if len(export.Skipped) != 1 || export.Skipped[0].Observation != "Observation/resp" {
+		t.Errorf("Expected respiratory rate skipped, got %+v", export.Skipped)
+	}
+
+	encoded, _ := json.Marshal(dataSets[1])
+	if !strings.Contains(string(encoded), `"startTimeNanos":"1714550400000000000"`) {
+		t.Errorf("Expected nanosecond times encoded as strings, got %s", encoded)
+	}
+}
+
+// TestBuild_SkipsUnexportableObservations verifies each reason a vital sign is left out, and that other
+// observations are left out without one
+func TestBuild_SkipsUnexportableObservations(t *testing.T) {
+	export := Build(FormatHealthKit, []*fhir.Observation{
+		observation("undated", "8867-4", "", quantity("72", "/min")),
+		observation("glucose", "2339-0", "2024-05-01T08:00:00Z", quantity("5.4", "mmol/L")),
+		observation("stones", "29463-7", "2024-05-01T08:00:00Z", quantity("11", "[stone_av]")),
+	})
+
+	if len(export.HealthKit.Samples) != 0 {
+		t.Errorf("Expected no samples, got %+v", export.HealthKit.Samples)
+	}
+	expectedReasons := map[string]string{
+		"Observation/undated": "no effective date-time",
+		"Observation/stones":  `Body weight unit "[stone_av]" cannot be converted`,
+	}
+	for _, skipped := range export.Skipped {
+		if expectedReasons[skipped.Observation] == skipped.Reason {
+			delete(expectedReasons, skipped.Observation)
+		}
+	}
+	if len(expectedReasons) != 0 || len(export.Skipped) != 2 {
+		t.Errorf("Expected skip reasons %v, got %+v", expectedReasons, export.Skipped)
+	}
+}
+
+// TestParseFormat verifies only the supported formats are accepted
+func TestParseFormat(t *testing.T) {
+	if format, parseError := ParseFormat("googlefit"); parseError != nil || format != FormatGoogleFit {
+		t.Errorf("Expected googlefit accepted, got %q (%v)", format, parseError)
+	}
+	if _, parseError := ParseFormat("fitbit"); parseError == nil {
+		t.Error("Expected an unsupported format to be rejected")
+	}
+}
@@ -0,0 +1,83 @@
+package healthexport
+
+import "time"
+
+// bloodPressureCorrelation is the HealthKit correlation type pairing systolic and diastolic samples
+const bloodPressureCorrelation = "HKCorrelationTypeIdentifierBloodPressure"
+
+// externalUUIDKey is the HealthKit metadata key the app uses to recognise samples it has already saved
+const externalUUIDKey = "HKExternalUUID"
+
+// HealthKitPayload holds HealthKit quantity samples ready to be saved with HKHealthStore
+// Blood pressure pairs are grouped into correlations, as HealthKit requires, instead of standalone samples
+type HealthKitPayload struct {
+	Samples      []HealthKitSample      `json:"samples"`
+	Correlations []HealthKitCorrelation `json:"correlations"`
+}
+
+// HealthKitSample is one HKQuantitySample
+type HealthKitSample struct {
+	// Type is the HKQuantityTypeIdentifier
+	Type string `json:"type"`
+
+	Value float64 `json:"value"`
+
+	// Unit is the HKUnit string the value is expressed in
+	Unit string `json:"unit"`
+
+	StartDate time.Time `json:"startDate"`
+	EndDate   time.Time `json:"endDate"`
+
+	// Metadata carries the source observation's reference under HKExternalUUID
+	Metadata map[string]string `json:"metadata"`
+}
+
+// HealthKitCorrelation is one HKCorrelation grouping samples taken together
+type HealthKitCorrelation struct {
+	Type      string            `json:"type"`
+	StartDate time.Time         `json:"startDate"`
+	EndDate   time.Time         `json:"endDate"`
+	Metadata  map[string]string `json:"metadata"`
+	Objects   []HealthKitSample `json:"objects"`
+}
+
+// buildHealthKit converts readings into HealthKit samples and blood pressure correlations
+func buildHealthKit(readings []reading) *HealthKitPayload {
+	payload := &HealthKitPayload{Samples: []HealthKitSample{}, Correlations: []HealthKitCorrelation{}}
+
+	for _, exportReading := range readings {
+		metadata := map[string]string{externalUUIDKey: exportReading.observationID}
+		_, hasSystolic := exportReading.value(systolicPressure)
+		_, hasDiastolic := exportReading.value(diastolicPressure)
+		correlated := hasSystolic && hasDiastolic
+
+		var pressureSamples []HealthKitSample
+		for _, value := range exportReading.values {
+			sample := HealthKitSample{
+				Type:      value.vital.healthKitType,
+				Value:     value.amount * value.vital.healthKitScale,
+				Unit:      value.vital.healthKitUnit,
+				StartDate: exportReading.effective,
+				EndDate:   exportReading.effective,
+				Metadata:  metadata,
+			}
+			if correlated && (value.vital == systolicPressure || value.vital == diastolicPressure) {
+				pressureSamples = append(pressureSamples, sample)
+				continue
+			}
+			payload.Samples = append(payload.Samples, sample)
+		}
+
+		if correlated {
+			payload.Correlations = append(payload.Correlations, HealthKitCorrelation{
+				Type:      bloodPressureCorrelation,
+				StartDate: exportReading.effective,
+				EndDate:   exportReading.effective,
+				Metadata:  metadata,
+				Objects:   pressureSamples,
+			})
+		}
+	}
+
+	return payload
+}
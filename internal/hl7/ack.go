@@ -0,0 +1,109 @@
+package hl7
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/adt"
+)
+
+// Acknowledgement codes returned in MSA-1
+const (
+	// AcknowledgementAccept (AA) reports the message was processed
+	AcknowledgementAccept = "AA"
+
+	// AcknowledgementError (AE) reports a failure the sender may resolve by sending the message again
+	AcknowledgementError = "AE"
+
+	// AcknowledgementReject (AR) reports a message that will fail however often it is sent
+	AcknowledgementReject = "AR"
+)
+
+// HL7 table 0357 error codes reported in ERR-3
+const (
+	// ErrorRequiredFieldMissing (101) reports an empty field the message must carry
+	ErrorRequiredFieldMissing = "101"
+
+	// ErrorDataType (102) reports a value that does not match its data type
+	ErrorDataType = "102"
+
+	// ErrorTableValueNotFound (103) reports a code outside the values accepted for the field
+	ErrorTableValueNotFound = "103"
+
+	// ErrorApplicationInternal (207) reports a failure to store what the segment carried
+	ErrorApplicationInternal = "207"
+)
+
+// errorCodeNames are the HL7 table 0357 display names of the error codes
+var errorCodeNames = map[string]string{
+	ErrorRequiredFieldMissing: "Required field missing",
+	ErrorDataType:             "Data type error",
+	ErrorTableValueNotFound:   "Table value not found",
+	ErrorApplicationInternal:  "Application internal error",
+}
+
+// ErrorDetail locates one problem in a message, rendered as an ERR segment of the acknowledgement
+type ErrorDetail struct {
+	// Segment and Sequence name the segment, such as the second OBX; Field is its field number, or 0 for the whole segment
+	Segment  string
+	Sequence int
+	Field    int
+
+	// Code is an HL7 table 0357 error code
+	Code string
+
+	// Text describes the problem to the sender's staff
+	Text string
+}
+
+// defaultVersion is MSH-12 of acknowledgements to messages that do not declare a version
+const defaultVersion = "2.5.1"
+
+// Acknowledge renders the ACK for a message; message is nil when it could not be parsed
+// The sending and receiving application and facility are swapped from the message, MSA-2 echoes its control ID,
+// and each error detail follows the MSA segment as an ERR segment
+func Acknowledge(message *Message, code string, text string, controlID string, timestamp time.Time, errorDetails []ErrorDetail) []byte {
+	var sendingApplication, sendingFacility, receivingApplication, receivingFacility, event, acknowledgedControlID string
+	processingID := "P"
+	version := defaultVersion
+	if message != nil {
+		sendingApplication = reencode(message, message.Field("MSH", 5))
+		sendingFacility = reencode(message, message.Field("MSH", 6))
+		receivingApplication = reencode(message, message.Field("MSH", 3))
+		receivingFacility = reencode(message, message.Field("MSH", 4))
+		_, event = message.MessageType()
+		acknowledgedControlID = message.ControlID()
+		if sentProcessingID := message.Component(message.Field("MSH", 11), 1); sentProcessingID != "" {
+			processingID = sentProcessingID
+		}
+		if sentVersion := message.Component(message.Field("MSH", 12), 1); sentVersion != "" {
+			version = sentVersion
+		}
+	}
+
+	segments := []string{
+		strings.Join([]string{`MSH|^~\&`, sendingApplication, sendingFacility, receivingApplication, receivingFacility,
+			timestamp.UTC().Format("20060102150405"), "", "ACK^" + adt.Escape(event) + "^ACK", adt.Escape(controlID),
+			adt.Escape(processingID), adt.Escape(version)}, "|"),
+		strings.Join([]string{"MSA", code, adt.Escape(acknowledgedControlID), adt.Escape(text)}, "|"),
+	}
+	for _, errorDetail := range errorDetails {
+		location := errorDetail.Segment + "^" + strconv.Itoa(errorDetail.Sequence)
+		if errorDetail.Field > 0 {
+			location += "^" + strconv.Itoa(errorDetail.Field)
+		}
+		segments = append(segments, strings.Join([]string{"ERR", "", location,
+			errorDetail.Code + "^" + errorCodeNames[errorDetail.Code] + "^HL70357", "E", "", "", "", adt.Escape(errorDetail.Text)}, "|"))
+	}
+	return []byte(strings.Join(segments, "\r") + "\r")
+}
+
+// reencode rewrites a field value from the message's delimiters into the standard ones the acknowledgement uses
+func reencode(message *Message, fieldValue string) string {
+	components := strings.Split(fieldValue, string(message.Delimiters.Component))
+	for index := range components {
+		components[index] = adt.Escape(message.Component(fieldValue, index+1))
+	}
+	return strings.Join(components, "^")
+}
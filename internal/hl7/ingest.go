@@ -0,0 +1,285 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/service"
+	"github.com/nathannewyen/fhir-health-interop/internal/tracing"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Trigger events accepted by the ingester
+const (
+	// EventAdmit (A01) admits an inpatient
+	EventAdmit = "A01"
+
+	// EventRegister (A04) registers an outpatient
+	EventRegister = "A04"
+
+	// EventUpdate (A08) updates patient information
+	EventUpdate = "A08"
+
+	// EventObservationResult (R01) reports unsolicited observation results in an ORU message
+	EventObservationResult = "R01"
+)
+
+// supportedEvents are the ADT trigger events whose PID segment is upserted
+var supportedEvents = map[string]bool{
+	EventAdmit:    true,
+	EventRegister: true,
+	EventUpdate:   true,
+}
+
+// PatientStore finds, creates, and updates patients; *service.PatientService satisfies it
+type PatientStore interface {
+	SearchPatients(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, error)
+	CreatePatient(ctx context.Context, fhirPatient *fhir.Patient) (*fhir.Patient, error)
+	UpdatePatient(ctx context.Context, patientID string, fhirPatient *fhir.Patient) (*fhir.Patient, error)
+}
+
+// ObservationStore creates observations; *service.ObservationService satisfies it
+type ObservationStore interface {
+	CreateObservation(ctx context.Context, fhirObservation *fhir.Observation) (*fhir.Observation, error)
+}
+
+// Transactor runs work in a single database transaction that commits when work succeeds and rolls back when it fails
+type Transactor interface {
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+}
+
+// Result is the outcome of ingesting one message
+type Result struct {
+	// Code is the MSA-1 acknowledgement code
+	Code string
+
+	// PatientID is the created, updated, or observed patient, empty unless Code is AA
+	PatientID string
+
+	// ObservationIDs are the observations created from an ORU message's OBX segments, in segment order
+	ObservationIDs []string
+
+	// Acknowledgement is the ER7-encoded ACK to return to the sender
+	Acknowledgement []byte
+}
+
+// Ingester upserts the patients carried by inbound ADT messages and stores the results carried by ORU messages
+type Ingester struct {
+	patients PatientStore
+
+	// observations and transactor are nil unless observation results are accepted
+	observations ObservationStore
+	transactor   Transactor
+
+	// now and newControlID are replaceable for tests
+	now          func() time.Time
+	newControlID func() string
+}
+
+// NewIngester creates an ingester writing patients through the store
+func NewIngester(patients PatientStore) *Ingester {
+	return &Ingester{
+		patients:     patients,
+		now:          time.Now,
+		newControlID: newControlID,
+	}
+}
+
+// SetObservationStore accepts ORU R01 messages, creating their observations in one transaction run by transactor
+func (ingester *Ingester) SetObservationStore(observations ObservationStore, transactor Transactor) {
+	ingester.observations = observations
+	ingester.transactor = transactor
+}
+
+// Ingest handles an ADT A01, A04, or A08 or an ORU R01 message and returns its acknowledgement
+// The patient is matched on the PID-3 identifier: for ADT messages a new identifier creates a patient and a known
+// one updates it, while ORU results must belong to a patient registered before
+// Messages that cannot succeed, such as other message types or an identifier shared by several patients, are
+// rejected with AR; failures to store the message's contents are reported with AE so the sender retries
+func (ingester *Ingester) Ingest(ctx context.Context, raw []byte) Result {
+	ctx, span := tracing.Start(ctx, "hl7.Ingest")
+	defer span.End()
+
+	result := ingester.ingest(ctx, raw)
+	span.SetAttributes(attribute.String("hl7.acknowledgement_code", result.Code))
+	if result.Code == AcknowledgementError {
+		span.SetStatus(codes.Error, "message contents could not be stored")
+	}
+	return result
+}
+
+// ingest matches the message's patient and stores the message's contents, acknowledging the outcome
+func (ingester *Ingester) ingest(ctx context.Context, raw []byte) Result {
+	message, parseError := Parse(raw)
+	if parseError != nil {
+		return ingester.acknowledge(nil, AcknowledgementReject, "", parseError, nil)
+	}
+
+	messageCode, event := message.MessageType()
+	isResult := messageCode == "ORU" && event == EventObservationResult && ingester.observations != nil
+	if !isResult && (messageCode != "ADT" || !supportedEvents[event]) {
+		return ingester.acknowledge(message, AcknowledgementReject, "", fmt.Errorf("%s message with trigger event %s is not supported: send %s", messageCode, event, ingester.supportedMessages()), nil)
+	}
+	if !message.HasSegment("PID") {
+		return ingester.acknowledge(message, AcknowledgementReject, "", fmt.Errorf("message has no PID segment"), nil)
+	}
+
+	identifierSystem, identifierValue, identifierError := PatientIdentifier(message)
+	if identifierError != nil {
+		return ingester.acknowledge(message, AcknowledgementReject, "", identifierError, nil)
+	}
+
+	matchingPatients, searchError := ingester.patients.SearchPatients(ctx, &models.PatientSearchParams{
+		Identifier: &models.IdentifierCriterion{Systems: []string{identifierSystem}, Value: identifierValue},
+		Limit:      2,
+	})
+	if searchError != nil {
+		return ingester.acknowledge(message, AcknowledgementError, "", fmt.Errorf("failed to find patient: %w", searchError), nil)
+	}
+
+	switch {
+	case len(matchingPatients) > 1:
+		return ingester.acknowledge(message, AcknowledgementReject, "", fmt.Errorf("identifier %s|%s matches several patients", identifierSystem, identifierValue), nil)
+	case isResult && len(matchingPatients) == 0:
+		return ingester.acknowledge(message, AcknowledgementReject, "", fmt.Errorf("identifier %s|%s matches no patient: send the patient's ADT registration first", identifierSystem, identifierValue), nil)
+	case isResult:
+		return ingester.storeResults(ctx, message, *matchingPatients[0].Id)
+	case len(matchingPatients) == 0:
+		return ingester.createPatient(ctx, message, identifierSystem, identifierValue)
+	default:
+		return ingester.updatePatient(ctx, message, matchingPatients[0])
+	}
+}
+
+// supportedMessages lists the message types the ingester accepts, for rejections of other types
+func (ingester *Ingester) supportedMessages() string {
+	if ingester.observations != nil {
+		return "ADT A01, A04, or A08, or ORU R01"
+	}
+	return "ADT A01, A04, or A08"
+}
+
+// createPatient stores a new active patient with the PID identifier and demographics
+func (ingester *Ingester) createPatient(ctx context.Context, message *Message, identifierSystem string, identifierValue string) Result {
+	active := true
+	identifier := fhir.Identifier{Value: &identifierValue}
+	if identifierSystem != "" {
+		identifier.System = &identifierSystem
+	}
+	fhirPatient := &fhir.Patient{Active: &active, Identifier: []fhir.Identifier{identifier}}
+	if applyError := ApplyDemographics(message, fhirPatient); applyError != nil {
+		return ingester.acknowledge(message, AcknowledgementReject, "", applyError, nil)
+	}
+
+	createdPatient, createError := ingester.patients.CreatePatient(ctx, fhirPatient)
+	if createError != nil {
+		return ingester.acknowledge(message, writeFailureCode(createError), "", fmt.Errorf("failed to create patient: %w", createError), nil)
+	}
+	return ingester.acknowledge(message, AcknowledgementAccept, *createdPatient.Id, nil, nil)
+}
+
+// updatePatient applies the PID demographics to a stored patient, keeping whatever the message leaves out
+func (ingester *Ingester) updatePatient(ctx context.Context, message *Message, storedPatient *fhir.Patient) Result {
+	patientID := *storedPatient.Id
+	if applyError := ApplyDemographics(message, storedPatient); applyError != nil {
+		return ingester.acknowledge(message, AcknowledgementReject, "", applyError, nil)
+	}
+
+	if _, updateError := ingester.patients.UpdatePatient(ctx, patientID, storedPatient); updateError != nil {
+		return ingester.acknowledge(message, writeFailureCode(updateError), "", fmt.Errorf("failed to update patient %s: %w", patientID, updateError), nil)
+	}
+	return ingester.acknowledge(message, AcknowledgementAccept, patientID, nil, nil)
+}
+
+// storeResults creates an observation for every OBX segment of an ORU message, all of them or none
+// OBX segments that cannot be mapped are each reported in an ERR segment, and nothing is stored
+func (ingester *Ingester) storeResults(ctx context.Context, message *Message, patientID string) Result {
+	observationResults, errorDetails := MapObservations(message, patientID)
+	if len(errorDetails) > 0 {
+		return ingester.acknowledge(message, AcknowledgementReject, "", fmt.Errorf("%d OBX fields could not be mapped to observations", len(errorDetails)), errorDetails)
+	}
+	if len(observationResults) == 0 {
+		return ingester.acknowledge(message, AcknowledgementReject, "", fmt.Errorf("message has no OBX segment"), nil)
+	}
+
+	// Observations are stored in MongoDB, so the scope removes those already created when a later one fails
+	scopeContext, scope := service.WithTransactionScope(ctx)
+	observationIDs := []string{}
+	var failedResult ObservationResult
+	transactionError := ingester.transactor.InTransaction(scopeContext, func(transactionContext context.Context) error {
+		for _, observationResult := range observationResults {
+			createdObservation, createError := ingester.observations.CreateObservation(transactionContext, observationResult.Observation)
+			if createError != nil {
+				failedResult = observationResult
+				return fmt.Errorf("failed to create observation for OBX %d: %w", observationResult.Sequence, createError)
+			}
+			observationIDs = append(observationIDs, *createdObservation.Id)
+		}
+		return nil
+	})
+	if transactionError != nil {
+		scope.Rollback(ctx)
+		failureCode := writeFailureCode(transactionError)
+		failureText := "Failed to store the observation"
+		if failureCode == AcknowledgementReject {
+			failureText = transactionError.Error()
+		}
+		return ingester.acknowledge(message, failureCode, "", transactionError, []ErrorDetail{
+			{Segment: "OBX", Sequence: failedResult.Sequence, Code: ErrorApplicationInternal, Text: failureText},
+		})
+	}
+	scope.Commit(ctx)
+
+	result := ingester.acknowledge(message, AcknowledgementAccept, patientID, nil, nil)
+	result.ObservationIDs = observationIDs
+	return result
+}
+
+// writeFailureCode rejects writes the services refused as invalid and reports other failures as retryable
+func writeFailureCode(writeError error) string {
+	var rejection *outcome.RejectionError
+	if errors.As(writeError, &rejection) {
+		return AcknowledgementReject
+	}
+	return AcknowledgementError
+}
+
+// acknowledge logs the outcome and renders the ACK, carrying the failure's text in MSA-3 and the error details
+// in ERR segments
+func (ingester *Ingester) acknowledge(message *Message, code string, patientID string, failure error, errorDetails []ErrorDetail) Result {
+	var controlID, messageCode, text string
+	if message != nil {
+		controlID = message.ControlID()
+		messageCode, _ = message.MessageType()
+	}
+	switch code {
+	case AcknowledgementAccept:
+		log.Info().Str("control_id", controlID).Str("message_type", messageCode).Str("patient_id", patientID).Msg("Ingested HL7 v2 message")
+	case AcknowledgementReject:
+		text = failure.Error()
+		log.Warn().Err(failure).Str("control_id", controlID).Msg("Rejected HL7 v2 message")
+	default:
+		text = "Failed to store the message's contents"
+		log.Error().Err(failure).Str("control_id", controlID).Msg("Failed to ingest HL7 v2 message")
+	}
+
+	return Result{
+		Code:            code,
+		PatientID:       patientID,
+		Acknowledgement: Acknowledge(message, code, text, ingester.newControlID(), ingester.now(), errorDetails),
+	}
+}
+
+// newControlID returns a unique MSH-10 value within the 20-character limit
+func newControlID() string {
+	return strings.ToUpper(strings.ReplaceAll(uuid.NewString(), "-", "")[:20])
+}
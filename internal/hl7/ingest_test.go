@@ -0,0 +1,261 @@
+package hl7
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// recordingStore keeps patients in memory, matching searches on identifier value, and records writes
+type recordingStore struct {
+	patients   []*fhir.Patient
+	searches   []*models.PatientSearchParams
+	created    int
+	updated    int
+	writeError error
+}
+
+// SearchPatients returns the patients with the searched identifier value
+func (store *recordingStore) SearchPatients(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, error) {
+	store.searches = append(store.searches, searchParams)
+	matches := []*fhir.Patient{}
+	for _, storedPatient := range store.patients {
+		if *storedPatient.Identifier[0].Value == searchParams.Identifier.Value {
+			matches = append(matches, storedPatient)
+		}
+	}
+	return matches, nil
+}
+
+// CreatePatient stores the patient under a sequential ID unless a write error is set
+func (store *recordingStore) CreatePatient(ctx context.Context, fhirPatient *fhir.Patient) (*fhir.Patient, error) {
+	if store.writeError != nil {
+		return nil, store.writeError
+	}
+	store.created++
+	patientID := "patient-" + string(rune('0'+len(store.patients)))
+	fhirPatient.Id = &patientID
+	store.patients = append(store.patients, fhirPatient)
+	return fhirPatient, nil
+}
+
+// UpdatePatient replaces the stored patient unless a write error is set
+func (store *recordingStore) UpdatePatient(ctx context.Context, patientID string, fhirPatient *fhir.Patient) (*fhir.Patient, error) {
+	if store.writeError != nil {
+		return nil, store.writeError
+	}
+	store.updated++
+	for index, storedPatient := range store.patients {
+		if *storedPatient.Id == patientID {
+			store.patients[index] = fhirPatient
+		}
+	}
+	return fhirPatient, nil
+}
+
+// recordingObservationStore keeps created observations in memory, failing the create numbered failOn (from 1)
+type recordingObservationStore struct {
+	created     []*fhir.Observation
+	failOn      int
+	createError error
+}
+
+// CreateObservation stores the observation under a sequential ID unless it is the one set to fail
+func (store *recordingObservationStore) CreateObservation(ctx context.Context, fhirObservation *fhir.Observation) (*fhir.Observation, error) {
+	if len(store.created)+1 == store.failOn {
+		return nil, store.createError
+	}
+	observationID := "observation-" + string(rune('0'+len(store.created)))
+	fhirObservation.Id = &observationID
+	store.created = append(store.created, fhirObservation)
+	return fhirObservation, nil
+}
+
+// recordingTransactor runs work directly and counts the transactions that failed
+type recordingTransactor struct {
+	rolledBack int
+}
+
+// InTransaction runs work, counting it as rolled back when it fails
+func (transactor *recordingTransactor) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	workError := work(ctx)
+	if workError != nil {
+		transactor.rolledBack++
+	}
+	return workError
+}
+
+// newTestIngester creates an ingester with a fixed clock and control ID
+func newTestIngester(store PatientStore) *Ingester {
+	ingester := NewIngester(store)
+	ingester.now = func() time.Time { return time.Date(2026, 10, 1, 8, 30, 16, 0, time.UTC) }
+	ingester.newControlID = func() string { return "ACK1" }
+	return ingester
+}
+
+// TestIngest_CreatesThenUpdatesPatient verifies an A01 creates the patient and an A08 updates it in place
+func TestIngest_CreatesThenUpdatesPatient(t *testing.T) {
+	store := &recordingStore{}
+	ingester := newTestIngester(store)
+
+	admitResult := ingester.Ingest(context.Background(), []byte(testADT))
+	if admitResult.Code != AcknowledgementAccept || admitResult.PatientID != "patient-0" || store.created != 1 {
+		t.Fatalf("Expected the A01 to create patient-0, got %+v after %d creates", admitResult, store.created)
+	}
+	searchedIdentifier := store.searches[0].Identifier
+	if len(searchedIdentifier.Systems) != 1 || searchedIdentifier.Systems[0] != "urn:oid:1.2.3.4" || searchedIdentifier.Value != "MRN001" {
+		t.Errorf("Expected a search for the MR identifier, got %+v", searchedIdentifier)
+	}
+	createdPatient := store.patients[0]
+	if *createdPatient.Identifier[0].System != "urn:oid:1.2.3.4" || createdPatient.Active == nil || !*createdPatient.Active || *createdPatient.BirthDate != "1980-02-29" {
+		t.Errorf("Expected an active patient with the identifier and birth date, got %+v", createdPatient)
+	}
+
+	update := "MSH|^~\\&|REG|EAST|FHIRHUB|MAIN|20261002090000||ADT^A08^ADT_A01|MSG00002|P|2.5.1\r" +
+		"PID|1||MRN001^^^Hospital&1.2.3.4&ISO^MR||Smith^Ann\r"
+	updateResult := ingester.Ingest(context.Background(), []byte(update))
+	if updateResult.Code != AcknowledgementAccept || updateResult.PatientID != "patient-0" || store.created != 1 || store.updated != 1 {
+		t.Fatalf("Expected the A08 to update patient-0, got %+v after %d creates and %d updates", updateResult, store.created, store.updated)
+	}
+	updatedPatient := store.patients[0]
+	if *updatedPatient.Name[0].Family != "Smith" || *updatedPatient.BirthDate != "1980-02-29" {
+		t.Errorf("Expected the new family name and the kept birth date, got %+v", updatedPatient)
+	}
+}
+
+// TestIngest_AcknowledgesWithSwappedRouting verifies the ACK's MSH routing, trigger event, and MSA
+func TestIngest_AcknowledgesWithSwappedRouting(t *testing.T) {
+	result := newTestIngester(&recordingStore{}).Ingest(context.Background(), []byte(testADT))
+
+	expected := "MSH|^~\\&|FHIRHUB|MAIN|REG|EAST|20261001083016||ACK^A01^ACK|ACK1|P|2.5.1\rMSA|AA|MSG00001|\r"
+	if string(result.Acknowledgement) != expected {
+		t.Errorf("Expected ACK %q, got %q", expected, result.Acknowledgement)
+	}
+}
+
+// TestIngest_RejectsMessagesThatCannotSucceed verifies AR for unparseable, unsupported, incomplete, and ambiguous messages
+func TestIngest_RejectsMessagesThatCannotSucceed(t *testing.T) {
+	ambiguousValue := "MRN001"
+	store := &recordingStore{patients: []*fhir.Patient{
+		{Identifier: []fhir.Identifier{{Value: &ambiguousValue}}},
+		{Identifier: []fhir.Identifier{{Value: &ambiguousValue}}},
+	}}
+	ingester := newTestIngester(store)
+
+	testCases := map[string]struct {
+		message      string
+		expectedText string
+	}{
+		"not hl7":      {message: `{"resourceType":"Patient"}`, expectedText: "MSH"},
+		"unsupported":  {message: "MSH|^~\\&|REG||||||ORU^R01|MSG1|P|2.5.1\rPID|1||MRN9\r", expectedText: "ORU message with trigger event R01"},
+		"no pid":       {message: "MSH|^~\\&|REG||||||ADT^A04|MSG2|P|2.5.1\rEVN|A04\r", expectedText: "PID"},
+		"no id":        {message: "MSH|^~\\&|REG||||||ADT^A04|MSG3|P|2.5.1\rPID|1||\r", expectedText: "PID-3"},
+		"bad gender":   {message: "MSH|^~\\&|REG||||||ADT^A04|MSG4|P|2.5.1\rPID|1||MRN9|||||Q\r", expectedText: "PID-8"},
+		"ambiguous id": {message: "MSH|^~\\&|REG||||||ADT^A04|MSG5|P|2.5.1\rPID|1||MRN001\r", expectedText: "several patients"},
+	}
+	for name, testCase := range testCases {
+		result := ingester.Ingest(context.Background(), []byte(testCase.message))
+		if result.Code != AcknowledgementReject || !strings.Contains(string(result.Acknowledgement), "MSA|AR|") {
+			t.Errorf("%s: expected AR, got %q", name, result.Acknowledgement)
+		}
+		if !strings.Contains(string(result.Acknowledgement), testCase.expectedText) {
+			t.Errorf("%s: expected the ACK to mention %q, got %q", name, testCase.expectedText, result.Acknowledgement)
+		}
+	}
+	if store.created != 0 || store.updated != 0 {
+		t.Errorf("Expected nothing written, got %d creates and %d updates", store.created, store.updated)
+	}
+}
+
+// TestIngest_ReportsWriteFailures verifies store failures are retryable AE and refused writes are AR
+func TestIngest_ReportsWriteFailures(t *testing.T) {
+	store := &recordingStore{writeError: errors.New("connection reset")}
+	ingester := newTestIngester(store)
+
+	if result := ingester.Ingest(context.Background(), []byte(testADT)); result.Code != AcknowledgementError || strings.Contains(string(result.Acknowledgement), "connection reset") {
+		t.Errorf("Expected AE without the internal error, got %q", result.Acknowledgement)
+	}
+
+	store.writeError = &outcome.RejectionError{}
+	if result := ingester.Ingest(context.Background(), []byte(testADT)); result.Code != AcknowledgementReject {
+		t.Errorf("Expected AR for a refused write, got %q", result.Acknowledgement)
+	}
+}
+
+// newResultIngester creates a test ingester accepting results for a registered patient with medical record number MRN001
+func newResultIngester(observations *recordingObservationStore, transactor *recordingTransactor) *Ingester {
+	patientID, identifierValue := "patient-0", "MRN001"
+	ingester := newTestIngester(&recordingStore{patients: []*fhir.Patient{
+		{Id: &patientID, Identifier: []fhir.Identifier{{Value: &identifierValue}}},
+	}})
+	ingester.SetObservationStore(observations, transactor)
+	return ingester
+}
+
+// TestIngest_StoresObservationResults verifies an R01 creates an observation per OBX for the registered patient
+func TestIngest_StoresObservationResults(t *testing.T) {
+	observations := &recordingObservationStore{}
+	result := newResultIngester(observations, &recordingTransactor{}).Ingest(context.Background(), []byte(testORU))
+
+	if result.Code != AcknowledgementAccept || result.PatientID != "patient-0" || len(result.ObservationIDs) != 3 || result.ObservationIDs[2] != "observation-2" {
+		t.Fatalf("Expected AA with three observations of patient-0, got %+v", result)
+	}
+	expected := "MSH|^~\\&|FHIRHUB|MAIN|LAB|EAST|20261001083016||ACK^R01^ACK|ACK1|P|2.5.1\rMSA|AA|LAB00001|\r"
+	if string(result.Acknowledgement) != expected {
+		t.Errorf("Expected ACK %q, got %q", expected, result.Acknowledgement)
+	}
+}
+
+// TestIngest_RejectsUnmappableResultsWithErrorSegments verifies AR with an ERR segment per problem and nothing stored
+func TestIngest_RejectsUnmappableResultsWithErrorSegments(t *testing.T) {
+	observations := &recordingObservationStore{}
+	ingester := newResultIngester(observations, &recordingTransactor{})
+
+	message := "MSH|^~\\&|LAB||||||ORU^R01|LAB2|P|2.5.1\rPID|1||MRN001\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|||||F\r" +
+		"OBX|2|NM|2345-7^Glucose^LN||high|mg/dL|||||F\r"
+	result := ingester.Ingest(context.Background(), []byte(message))
+	if result.Code != AcknowledgementReject || len(observations.created) != 0 {
+		t.Fatalf("Expected AR without stored observations, got %q after %d creates", result.Acknowledgement, len(observations.created))
+	}
+	expectedError := "ERR||OBX^2^5|102^Data type error^HL70357|E||||OBX-5 value \"high\" is not a number\r"
+	if !strings.HasSuffix(string(result.Acknowledgement), expectedError) {
+		t.Errorf("Expected the ACK to end with %q, got %q", expectedError, result.Acknowledgement)
+	}
+
+	unknownPatient := "MSH|^~\\&|LAB||||||ORU^R01|LAB3|P|2.5.1\rPID|1||MRN404\rOBX|1|NM|2345-7^Glucose^LN||95|mg/dL|||||F\r"
+	if result := ingester.Ingest(context.Background(), []byte(unknownPatient)); result.Code != AcknowledgementReject || !strings.Contains(string(result.Acknowledgement), "ADT registration") {
+		t.Errorf("Expected AR for results of an unregistered patient, got %q", result.Acknowledgement)
+	}
+
+	noResults := "MSH|^~\\&|LAB||||||ORU^R01|LAB4|P|2.5.1\rPID|1||MRN001\rOBR|1\r"
+	if result := ingester.Ingest(context.Background(), []byte(noResults)); result.Code != AcknowledgementReject || !strings.Contains(string(result.Acknowledgement), "OBX") {
+		t.Errorf("Expected AR for a message without OBX segments, got %q", result.Acknowledgement)
+	}
+}
+
+// TestIngest_RollsBackResultsWhenAnObservationFails verifies a failed create rolls back the whole message
+func TestIngest_RollsBackResultsWhenAnObservationFails(t *testing.T) {
+	observations := &recordingObservationStore{failOn: 2, createError: errors.New("connection reset")}
+	transactor := &recordingTransactor{}
+	ingester := newResultIngester(observations, transactor)
+
+	result := ingester.Ingest(context.Background(), []byte(testORU))
+	if result.Code != AcknowledgementError || transactor.rolledBack != 1 || len(result.ObservationIDs) != 0 {
+		t.Fatalf("Expected AE after a rolled-back transaction, got %+v", result)
+	}
+	if !strings.Contains(string(result.Acknowledgement), "ERR||OBX^2|207^") || strings.Contains(string(result.Acknowledgement), "connection reset") {
+		t.Errorf("Expected an ERR for the second OBX without the internal error, got %q", result.Acknowledgement)
+	}
+
+	observations.created, observations.createError = nil, &outcome.RejectionError{}
+	if result := ingester.Ingest(context.Background(), []byte(testORU)); result.Code != AcknowledgementReject {
+		t.Errorf("Expected AR for a refused observation, got %q", result.Acknowledgement)
+	}
+}
@@ -0,0 +1,192 @@
+package hl7
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Delimiters are the separators a message declares in MSH-1 and MSH-2
+type Delimiters struct {
+	Field        byte
+	Component    byte
+	Repetition   byte
+	Escape       byte
+	Subcomponent byte
+}
+
+// Message is a parsed ER7-encoded HL7 v2 message
+// Field values are kept as sent; Component and Subcomponent unescape the parts they return
+type Message struct {
+	Delimiters Delimiters
+
+	segments []Segment
+}
+
+// Segment is one segment of a message
+type Segment struct {
+	// fields hold the segment name first, so index n is field n
+	fields []string
+}
+
+// Name returns the segment ID, such as PID or OBX
+func (segment Segment) Name() string {
+	return segment.fields[0]
+}
+
+// Field returns field fieldNumber as sent, or empty when the segment has fewer fields
+func (segment Segment) Field(fieldNumber int) string {
+	if fieldNumber < 1 || fieldNumber >= len(segment.fields) {
+		return ""
+	}
+	return segment.fields[fieldNumber]
+}
+
+// Parse reads an ER7-encoded message whose segments end with carriage returns
+// Line feeds are accepted as segment separators too, since messages pasted or posted over HTTP often carry them
+func Parse(raw []byte) (*Message, error) {
+	text := strings.ReplaceAll(strings.ReplaceAll(string(raw), "\r\n", "\r"), "\n", "\r")
+	if !strings.HasPrefix(text, "MSH") || len(text) < 8 {
+		return nil, fmt.Errorf("message must start with an MSH segment")
+	}
+
+	delimiters := Delimiters{
+		Field:        text[3],
+		Component:    text[4],
+		Repetition:   text[5],
+		Escape:       text[6],
+		Subcomponent: text[7],
+	}
+	message := &Message{Delimiters: delimiters}
+	for _, line := range strings.Split(text, "\r") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		fields := strings.Split(line, string(delimiters.Field))
+		if fields[0] == "MSH" {
+			// MSH-1 is the field separator itself, so it is not between two separators like other fields
+			fields = append([]string{"MSH", string(delimiters.Field)}, fields[1:]...)
+		}
+		message.segments = append(message.segments, Segment{fields: fields})
+	}
+
+	return message, nil
+}
+
+// Segments returns the message's segments in order
+func (message *Message) Segments() []Segment {
+	return message.segments
+}
+
+// Field returns field fieldNumber of the first segmentName segment as sent, or empty when either is absent
+func (message *Message) Field(segmentName string, fieldNumber int) string {
+	for _, segment := range message.segments {
+		if segment.Name() == segmentName {
+			return segment.Field(fieldNumber)
+		}
+	}
+	return ""
+}
+
+// HasSegment reports whether the message contains a segmentName segment
+func (message *Message) HasSegment(segmentName string) bool {
+	for _, segment := range message.segments {
+		if segment.Name() == segmentName {
+			return true
+		}
+	}
+	return false
+}
+
+// Repetitions splits a field value into its repetitions
+func (message *Message) Repetitions(fieldValue string) []string {
+	if fieldValue == "" {
+		return nil
+	}
+	return strings.Split(fieldValue, string(message.Delimiters.Repetition))
+}
+
+// Component returns component componentNumber (from 1) of a field value, unescaped
+func (message *Message) Component(fieldValue string, componentNumber int) string {
+	return message.Unescape(part(message.rawComponent(fieldValue, componentNumber), message.Delimiters.Subcomponent, 1))
+}
+
+// Subcomponent returns subcomponent subcomponentNumber (from 1) of component componentNumber of a field value, unescaped
+func (message *Message) Subcomponent(fieldValue string, componentNumber int, subcomponentNumber int) string {
+	return message.Unescape(part(message.rawComponent(fieldValue, componentNumber), message.Delimiters.Subcomponent, subcomponentNumber))
+}
+
+// rawComponent returns component componentNumber of a field value without unescaping it
+func (message *Message) rawComponent(fieldValue string, componentNumber int) string {
+	return part(fieldValue, message.Delimiters.Component, componentNumber)
+}
+
+// part returns the numbered part (from 1) of value split on separator, or empty when there are fewer parts
+func part(value string, separator byte, number int) string {
+	parts := strings.Split(value, string(separator))
+	if number < 1 || number > len(parts) {
+		return ""
+	}
+	return parts[number-1]
+}
+
+// Unescape replaces the message's escape sequences with the characters they stand for
+// Formatting and character set sequences are dropped, and an unterminated escape is kept as sent
+func (message *Message) Unescape(value string) string {
+	escape := string(message.Delimiters.Escape)
+	if !strings.Contains(value, escape) {
+		return value
+	}
+
+	var unescaped strings.Builder
+	for {
+		start := strings.Index(value, escape)
+		if start < 0 {
+			unescaped.WriteString(value)
+			return unescaped.String()
+		}
+		end := strings.Index(value[start+1:], escape)
+		if end < 0 {
+			unescaped.WriteString(value)
+			return unescaped.String()
+		}
+		unescaped.WriteString(value[:start])
+		unescaped.WriteString(message.escapedText(value[start+1 : start+1+end]))
+		value = value[start+end+2:]
+	}
+}
+
+// escapedText returns the text an escape sequence body such as F or X0D stands for
+func (message *Message) escapedText(sequence string) string {
+	switch {
+	case sequence == "F":
+		return string(message.Delimiters.Field)
+	case sequence == "S":
+		return string(message.Delimiters.Component)
+	case sequence == "T":
+		return string(message.Delimiters.Subcomponent)
+	case sequence == "R":
+		return string(message.Delimiters.Repetition)
+	case sequence == "E":
+		return string(message.Delimiters.Escape)
+	case strings.HasPrefix(sequence, "X"):
+		decoded, decodeError := hex.DecodeString(sequence[1:])
+		if decodeError != nil {
+			return ""
+		}
+		return string(decoded)
+	default:
+		return ""
+	}
+}
+
+// MessageType returns the message code and trigger event from MSH-9, such as ADT and A01
+func (message *Message) MessageType() (string, string) {
+	messageType := message.Field("MSH", 9)
+	return message.Component(messageType, 1), message.Component(messageType, 2)
+}
+
+// ControlID returns MSH-10, which the acknowledgement echoes in MSA-2
+func (message *Message) ControlID() string {
+	return message.Unescape(message.Field("MSH", 10))
+}
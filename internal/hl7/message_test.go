@@ -0,0 +1,121 @@
+package hl7
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// testADT is an A01 with two PID-3 identifiers, an escaped family name, and line feed segment separators
+const testADT = "MSH|^~\\&|REG|EAST|FHIRHUB|MAIN|20261001083015||ADT^A01^ADT_A01|MSG00001|P|2.5.1\n" +
+	"EVN|A01|20261001083015\n" +
+	"PID|1||9001^^^SSA^SS~MRN001^^^Hospital&1.2.3.4&ISO^MR||O'Brien\\F\\Smith^Ann||19800229|F\n" +
+	"PV1|1|I\n"
+
+// TestParse_ReadsFieldsComponentsAndEscapes verifies MSH numbering, repetitions, subcomponents, and unescaping
+func TestParse_ReadsFieldsComponentsAndEscapes(t *testing.T) {
+	message, parseError := Parse([]byte(testADT))
+	if parseError != nil {
+		t.Fatalf("Expected the message to parse, got %v", parseError)
+	}
+
+	if message.Field("MSH", 1) != "|" || message.Field("MSH", 3) != "REG" || message.ControlID() != "MSG00001" {
+		t.Errorf("Expected MSH-1 as the separator and MSH-3 and MSH-10 in place, got %q, %q, %q", message.Field("MSH", 1), message.Field("MSH", 3), message.ControlID())
+	}
+	if messageCode, event := message.MessageType(); messageCode != "ADT" || event != "A01" {
+		t.Errorf("Expected ADT^A01, got %s^%s", messageCode, event)
+	}
+
+	identifiers := message.Repetitions(message.Field("PID", 3))
+	if len(identifiers) != 2 || message.Subcomponent(identifiers[1], 4, 2) != "1.2.3.4" {
+		t.Fatalf("Expected two identifiers with an ISO assigning authority, got %q", identifiers)
+	}
+	if familyName := message.Component(message.Field("PID", 5), 1); familyName != "O'Brien|Smith" {
+		t.Errorf("Expected the escaped field separator to be restored, got %q", familyName)
+	}
+	if message.Field("PID", 30) != "" || message.Field("ZPD", 1) != "" || message.HasSegment("ZPD") {
+		t.Error("Expected absent fields and segments to read as empty")
+	}
+}
+
+// TestParse_RejectsMessagesWithoutMSH verifies other text is not read as a message
+func TestParse_RejectsMessagesWithoutMSH(t *testing.T) {
+	for _, raw := range []string{"", "PID|1||MRN001", "MSH|^~"} {
+		if _, parseError := Parse([]byte(raw)); parseError == nil {
+			t.Errorf("Expected %q to be rejected", raw)
+		}
+	}
+}
+
+// TestUnescape_DecodesHexAndDropsFormatting verifies hex sequences, unknown sequences, and unterminated escapes
+func TestUnescape_DecodesHexAndDropsFormatting(t *testing.T) {
+	message, _ := Parse([]byte(testADT))
+	testCases := map[string]string{
+		`line\X0D0A\break`: "line\r\nbreak",
+		`\H\bold\N\`:       "bold",
+		`a\E\b\S\c\T\d\R\`: `a\b^c&d~`,
+		`trailing\F`:       `trailing\F`,
+	}
+	for escaped, expected := range testCases {
+		if unescaped := message.Unescape(escaped); unescaped != expected {
+			t.Errorf("Unescape(%q): expected %q, got %q", escaped, expected, unescaped)
+		}
+	}
+}
+
+// TestPatientIdentifier_PrefersMedicalRecordNumber verifies the MR identifier and assigning authority systems are chosen
+func TestPatientIdentifier_PrefersMedicalRecordNumber(t *testing.T) {
+	testCases := map[string]struct {
+		identifiers    string
+		expectedSystem string
+		expectedValue  string
+	}{
+		"iso authority":       {identifiers: "9001^^^SSA^SS~MRN001^^^Hospital&1.2.3.4&ISO^MR", expectedSystem: "urn:oid:1.2.3.4", expectedValue: "MRN001"},
+		"uri authority":       {identifiers: "MRN002^^^&http://hospital.example/mrn&URI^MR", expectedSystem: "http://hospital.example/mrn", expectedValue: "MRN002"},
+		"namespace authority": {identifiers: "MRN003^^^MAIN", expectedSystem: "MAIN", expectedValue: "MRN003"},
+		"first without type":  {identifiers: "^^^SSA~A7^^^EAST~B8^^^WEST", expectedSystem: "EAST", expectedValue: "A7"},
+	}
+	for name, testCase := range testCases {
+		message, _ := Parse([]byte("MSH|^~\\&|REG\rPID|1||" + testCase.identifiers + "\r"))
+		system, value, identifierError := PatientIdentifier(message)
+		if identifierError != nil || system != testCase.expectedSystem || value != testCase.expectedValue {
+			t.Errorf("%s: expected %s|%s, got %s|%s (%v)", name, testCase.expectedSystem, testCase.expectedValue, system, value, identifierError)
+		}
+	}
+
+	message, _ := Parse([]byte("MSH|^~\\&|REG\rPID|1||^^^MAIN\r"))
+	if _, _, identifierError := PatientIdentifier(message); identifierError == nil {
+		t.Error("Expected an error for PID-3 without an identifier value")
+	}
+}
+
+// TestApplyDemographics_KeepsEmptyAndClearsNullFields verifies HL7 update semantics for name, birth date, and gender
+func TestApplyDemographics_KeepsEmptyAndClearsNullFields(t *testing.T) {
+	message, _ := Parse([]byte(testADT))
+	fhirPatient := &fhir.Patient{}
+	if applyError := ApplyDemographics(message, fhirPatient); applyError != nil {
+		t.Fatalf("Expected the demographics to apply, got %v", applyError)
+	}
+	if len(fhirPatient.Name) != 1 || *fhirPatient.Name[0].Family != "O'Brien|Smith" || fhirPatient.Name[0].Given[0] != "Ann" {
+		t.Errorf("Expected the PID-5 name, got %+v", fhirPatient.Name)
+	}
+	if *fhirPatient.BirthDate != "1980-02-29" || *fhirPatient.Gender != fhir.AdministrativeGenderFemale {
+		t.Errorf("Expected the PID-7 birth date and PID-8 gender, got %s and %v", *fhirPatient.BirthDate, fhirPatient.Gender)
+	}
+
+	update, _ := Parse([]byte("MSH|^~\\&|REG\rPID|1||MRN001||||\"\"|M\r"))
+	if applyError := ApplyDemographics(update, fhirPatient); applyError != nil {
+		t.Fatalf("Expected the update to apply, got %v", applyError)
+	}
+	if len(fhirPatient.Name) != 1 || fhirPatient.BirthDate != nil || *fhirPatient.Gender != fhir.AdministrativeGenderMale {
+		t.Errorf("Expected the name kept, the birth date cleared, and the gender replaced, got %+v", fhirPatient)
+	}
+
+	for _, invalidPID := range []string{"PID|1||MRN001||||1980", "PID|1||MRN001||||19801399", "PID|1||MRN001|||||X"} {
+		invalid, _ := Parse([]byte("MSH|^~\\&|REG\r" + invalidPID + "\r"))
+		if applyError := ApplyDemographics(invalid, &fhir.Patient{}); applyError == nil || !strings.Contains(applyError.Error(), "PID-") {
+			t.Errorf("Expected %q to be rejected naming the field, got %v", invalidPID, applyError)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package hl7
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// loincCodingSystem is the HL7 table 0396 name of LOINC in coded elements
+const loincCodingSystem = "LN"
+
+// loincSystem is the FHIR system URI of LOINC codes
+const loincSystem = "http://loinc.org"
+
+// laboratoryCategory is the observation category of results received as ORU messages
+const laboratoryCategory = "laboratory"
+
+// observationCategorySystem is the FHIR system of observation category codes
+const observationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+
+// resultStatuses maps the HL7 table 0085 result statuses that can be stored to FHIR observation statuses
+// Deleted (D), wrong-patient (W), and cannot-be-obtained (X) results have no observation to store
+var resultStatuses = map[string]fhir.ObservationStatus{
+	"F": fhir.ObservationStatusFinal,
+	"C": fhir.ObservationStatusAmended,
+	"P": fhir.ObservationStatusPreliminary,
+	"S": fhir.ObservationStatusPreliminary,
+	"R": fhir.ObservationStatusPreliminary,
+	"I": fhir.ObservationStatusRegistered,
+}
+
+// dateTimeLayouts are the DTM layouts accepted, keyed by the number of digits before any fraction or offset
+var dateTimeLayouts = map[int]string{
+	8:  hl7DateLayout,
+	10: "2006010215",
+	12: "200601021504",
+	14: "20060102150405",
+}
+
+// ObservationResult is one OBX segment mapped to an Observation
+type ObservationResult struct {
+	// Sequence is the OBX segment's position among the message's OBX segments, from 1
+	Sequence int
+
+	Observation *fhir.Observation
+}
+
+// MapObservations converts every OBX segment of a message into a laboratory Observation of the patient
+// An OBX without its own observation time (OBX-14) takes the time of the OBR it follows (OBR-7)
+// Every OBX that cannot be mapped is reported in the error details; observations are only returned when all of them map
+func MapObservations(message *Message, patientID string) ([]ObservationResult, []ErrorDetail) {
+	var observationResults []ObservationResult
+	var errorDetails []ErrorDetail
+	requestTime := ""
+	sequence := 0
+	for _, segment := range message.Segments() {
+		switch segment.Name() {
+		case "OBR":
+			requestTime = segment.Field(7)
+		case "OBX":
+			sequence++
+			fhirObservation, fieldErrors := mapObservation(message, segment, requestTime, patientID)
+			for _, fieldError := range fieldErrors {
+				fieldError.Segment = "OBX"
+				fieldError.Sequence = sequence
+				errorDetails = append(errorDetails, fieldError)
+			}
+			observationResults = append(observationResults, ObservationResult{Sequence: sequence, Observation: fhirObservation})
+		}
+	}
+
+	if len(errorDetails) > 0 {
+		return nil, errorDetails
+	}
+	return observationResults, nil
+}
+
+// mapObservation converts one OBX segment, returning the field errors without their segment location
+func mapObservation(message *Message, segment Segment, requestTime string, patientID string) (*fhir.Observation, []ErrorDetail) {
+	var fieldErrors []ErrorDetail
+	patientReference := "Patient/" + patientID
+	categoryCode, categorySystem := laboratoryCategory, observationCategorySystem
+	fhirObservation := &fhir.Observation{
+		Subject: &fhir.Reference{Reference: &patientReference},
+		Category: []fhir.CodeableConcept{{
+			Coding: []fhir.Coding{{System: &categorySystem, Code: &categoryCode}},
+		}},
+	}
+
+	coding, codeError := loincCoding(message, segment.Field(3))
+	if codeError != nil {
+		fieldErrors = append(fieldErrors, *codeError)
+	} else {
+		fhirObservation.Code = fhir.CodeableConcept{Coding: []fhir.Coding{coding}}
+	}
+
+	if valueError := applyValue(message, segment, fhirObservation); valueError != nil {
+		fieldErrors = append(fieldErrors, *valueError)
+	}
+
+	resultStatus := message.Component(segment.Field(11), 1)
+	fhirStatus, storable := resultStatuses[resultStatus]
+	switch {
+	case resultStatus == "":
+		fieldErrors = append(fieldErrors, ErrorDetail{Field: 11, Code: ErrorRequiredFieldMissing, Text: "OBX-11 result status is required"})
+	case !storable:
+		fieldErrors = append(fieldErrors, ErrorDetail{Field: 11, Code: ErrorTableValueNotFound, Text: fmt.Sprintf("OBX-11 result status %q cannot be stored as an observation", resultStatus)})
+	default:
+		fhirObservation.Status = fhirStatus
+	}
+
+	observationTime := segment.Field(14)
+	if observationTime == "" {
+		observationTime = requestTime
+	}
+	if observationTime != "" {
+		effectiveTime, timeError := ParseDateTime(message.Component(observationTime, 1))
+		if timeError != nil {
+			fieldErrors = append(fieldErrors, ErrorDetail{Field: 14, Code: ErrorDataType, Text: fmt.Sprintf("observation time (OBX-14 or OBR-7) is invalid: %v", timeError)})
+		} else {
+			effectiveDateTime := effectiveTime.UTC().Format("2006-01-02T15:04:05Z")
+			fhirObservation.EffectiveDateTime = &effectiveDateTime
+		}
+	}
+
+	return fhirObservation, fieldErrors
+}
+
+// loincCoding returns the LOINC coding in an OBX-3 coded element, from its primary or alternate identifier
+func loincCoding(message *Message, identifier string) (fhir.Coding, *ErrorDetail) {
+	if identifier == "" {
+		return fhir.Coding{}, &ErrorDetail{Field: 3, Code: ErrorRequiredFieldMissing, Text: "OBX-3 observation identifier is required"}
+	}
+
+	// A coded element carries the primary identifier in components 1-3 and the alternate one in components 4-6
+	for _, firstComponent := range []int{1, 4} {
+		if message.Component(identifier, firstComponent+2) != loincCodingSystem {
+			continue
+		}
+		code := message.Component(identifier, firstComponent)
+		if code == "" {
+			continue
+		}
+		system := loincSystem
+		coding := fhir.Coding{System: &system, Code: &code}
+		if display := message.Component(identifier, firstComponent+1); display != "" {
+			coding.Display = &display
+		}
+		return coding, nil
+	}
+	return fhir.Coding{}, &ErrorDetail{Field: 3, Code: ErrorTableValueNotFound, Text: fmt.Sprintf("OBX-3 observation identifier %q has no LOINC (LN) code", identifier)}
+}
+
+// applyValue sets the observation's value from OBX-5 according to the OBX-2 value type
+// Numeric values become quantities in the OBX-6 unit; text and coded values become strings
+func applyValue(message *Message, segment Segment, fhirObservation *fhir.Observation) *ErrorDetail {
+	valueType := segment.Field(2)
+	observationValue := segment.Field(5)
+	if observationValue == "" {
+		return &ErrorDetail{Field: 5, Code: ErrorRequiredFieldMissing, Text: "OBX-5 observation value is required"}
+	}
+
+	switch valueType {
+	case "NM":
+		number, parseError := strconv.ParseFloat(strings.TrimSpace(message.Unescape(observationValue)), 64)
+		if parseError != nil {
+			return &ErrorDetail{Field: 5, Code: ErrorDataType, Text: fmt.Sprintf("OBX-5 value %q is not a number", observationValue)}
+		}
+		quantityValue := json.Number(strconv.FormatFloat(number, 'f', -1, 64))
+		quantity := &fhir.Quantity{Value: &quantityValue}
+		if unit := message.Component(segment.Field(6), 1); unit != "" {
+			quantity.Unit = &unit
+		}
+		fhirObservation.ValueQuantity = quantity
+	case "ST", "TX", "FT":
+		text := message.Unescape(observationValue)
+		fhirObservation.ValueString = &text
+	case "CE", "CWE":
+		// The coded value is stored as its text, or as its code when the sender gives no text
+		text := message.Component(observationValue, 2)
+		if text == "" {
+			text = message.Component(observationValue, 1)
+		}
+		fhirObservation.ValueString = &text
+	case "":
+		return &ErrorDetail{Field: 2, Code: ErrorRequiredFieldMissing, Text: "OBX-2 value type is required"}
+	default:
+		return &ErrorDetail{Field: 2, Code: ErrorTableValueNotFound, Text: fmt.Sprintf("OBX-2 value type %q is not supported: send NM, ST, TX, FT, CE, or CWE", valueType)}
+	}
+	return nil
+}
+
+// ParseDateTime reads an HL7 DTM value (or CDA TS value) of at least day precision, such as 20261001083016-0500
+// Fractional seconds are ignored, and a value without a UTC offset is taken to be in UTC
+func ParseDateTime(value string) (time.Time, error) {
+	digits, location := value, time.UTC
+	if offsetIndex := strings.IndexAny(value, "+-"); offsetIndex >= 0 {
+		digits = value[:offsetIndex]
+		offset := value[offsetIndex+1:]
+		if len(offset) != 4 {
+			return time.Time{}, fmt.Errorf("UTC offset %q is not +/-HHMM", value[offsetIndex:])
+		}
+		hours, hoursError := strconv.Atoi(offset[:2])
+		minutes, minutesError := strconv.Atoi(offset[2:])
+		if hoursError != nil || minutesError != nil {
+			return time.Time{}, fmt.Errorf("UTC offset %q is not +/-HHMM", value[offsetIndex:])
+		}
+		offsetSeconds := hours*3600 + minutes*60
+		if value[offsetIndex] == '-' {
+			offsetSeconds = -offsetSeconds
+		}
+		location = time.FixedZone(value[offsetIndex:], offsetSeconds)
+	}
+	digits, _, _ = strings.Cut(digits, ".")
+
+	layout, known := dateTimeLayouts[len(digits)]
+	if !known {
+		return time.Time{}, fmt.Errorf("%q is not a YYYYMMDD[HH[MM[SS]]] date-time", value)
+	}
+	return time.ParseInLocation(layout, digits, location)
+}
@@ -0,0 +1,102 @@
+package hl7
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// testORU is an R01 with a numeric result timed by its OBR, a corrected coded result with its own time and an
+// alternate LOINC code, and a text result
+const testORU = "MSH|^~\\&|LAB|EAST|FHIRHUB|MAIN|20261001090000||ORU^R01^ORU_R01|LAB00001|P|2.5.1\r" +
+	"PID|1||MRN001^^^Hospital&1.2.3.4&ISO^MR||Smith^Ann\r" +
+	"OBR|1||ORD1|24323-8^Comprehensive metabolic panel^LN|||20261001073000-0500\r" +
+	"OBX|1|NM|2345-7^Glucose^LN||95.0|mg/dL^^UCUM|70-99|N|||F\r" +
+	"OBX|2|CWE|GLU-Q^Urine glucose^L^5792-7^Glucose [Presence] in Urine^LN||NEG^Negative^HL70078||||||C|||20261001081500\r" +
+	"OBX|3|TX|8251-1^Service comment^LN||Specimen slightly hemolyzed||||||P\r"
+
+// TestMapObservations_MapsCodesValuesStatusesAndTimes verifies each OBX becomes a laboratory Observation of the patient
+func TestMapObservations_MapsCodesValuesStatusesAndTimes(t *testing.T) {
+	message, _ := Parse([]byte(testORU))
+	observationResults, errorDetails := MapObservations(message, "patient-0")
+	if len(errorDetails) != 0 || len(observationResults) != 3 {
+		t.Fatalf("Expected three observations without errors, got %d and %+v", len(observationResults), errorDetails)
+	}
+
+	glucose := observationResults[0].Observation
+	if *glucose.Code.Coding[0].System != "http://loinc.org" || *glucose.Code.Coding[0].Code != "2345-7" || *glucose.Subject.Reference != "Patient/patient-0" {
+		t.Errorf("Expected the LOINC code and patient subject, got %+v", glucose)
+	}
+	if glucose.ValueQuantity.Value.String() != "95" || *glucose.ValueQuantity.Unit != "mg/dL" || glucose.Status != fhir.ObservationStatusFinal {
+		t.Errorf("Expected a final 95 mg/dL quantity, got %+v", glucose)
+	}
+	if *glucose.EffectiveDateTime != "2026-10-01T12:30:00Z" || *glucose.Category[0].Coding[0].Code != "laboratory" {
+		t.Errorf("Expected the OBR-7 time in UTC and the laboratory category, got %s", *glucose.EffectiveDateTime)
+	}
+
+	urineGlucose := observationResults[1].Observation
+	if *urineGlucose.Code.Coding[0].Code != "5792-7" || *urineGlucose.ValueString != "Negative" || urineGlucose.Status != fhir.ObservationStatusAmended {
+		t.Errorf("Expected the alternate LOINC code, the coded text, and the amended status, got %+v", urineGlucose)
+	}
+	if *urineGlucose.EffectiveDateTime != "2026-10-01T08:15:00Z" {
+		t.Errorf("Expected the OBX-14 time without an offset to be read as UTC, got %s", *urineGlucose.EffectiveDateTime)
+	}
+
+	comment := observationResults[2].Observation
+	if *comment.ValueString != "Specimen slightly hemolyzed" || comment.Status != fhir.ObservationStatusPreliminary || observationResults[2].Sequence != 3 {
+		t.Errorf("Expected the preliminary text result as the third OBX, got %+v", comment)
+	}
+}
+
+// TestMapObservations_ReportsEveryUnmappableField verifies each problem is located by OBX sequence and field
+func TestMapObservations_ReportsEveryUnmappableField(t *testing.T) {
+	message, _ := Parse([]byte("MSH|^~\\&|LAB||||||ORU^R01|LAB2|P|2.5.1\rPID|1||MRN001\r" +
+		"OBX|1|NM|2345-7^Glucose^LN||95|mg/dL|||||F\r" +
+		"OBX|2|NM|GLU^Glucose^L||high|mg/dL|||||X|||2026-10-01\r" +
+		"OBX|3|ED|2345-7^Glucose^LN||data||||||\r"))
+	observationResults, errorDetails := MapObservations(message, "patient-0")
+	if observationResults != nil {
+		t.Errorf("Expected no observations when any OBX fails, got %d", len(observationResults))
+	}
+
+	expected := []ErrorDetail{
+		{Segment: "OBX", Sequence: 2, Field: 3, Code: ErrorTableValueNotFound},
+		{Segment: "OBX", Sequence: 2, Field: 5, Code: ErrorDataType},
+		{Segment: "OBX", Sequence: 2, Field: 11, Code: ErrorTableValueNotFound},
+		{Segment: "OBX", Sequence: 2, Field: 14, Code: ErrorDataType},
+		{Segment: "OBX", Sequence: 3, Field: 2, Code: ErrorTableValueNotFound},
+		{Segment: "OBX", Sequence: 3, Field: 11, Code: ErrorRequiredFieldMissing},
+	}
+	if len(errorDetails) != len(expected) {
+		t.Fatalf("Expected %d error details, got %+v", len(expected), errorDetails)
+	}
+	for index, expectedDetail := range expected {
+		errorDetail := errorDetails[index]
+		if errorDetail.Segment != expectedDetail.Segment || errorDetail.Sequence != expectedDetail.Sequence || errorDetail.Field != expectedDetail.Field || errorDetail.Code != expectedDetail.Code || errorDetail.Text == "" {
+			t.Errorf("Error detail %d: expected %+v, got %+v", index, expectedDetail, errorDetail)
+		}
+	}
+}
+
+// TestParseDateTime_ReadsPrecisionsAndOffsets verifies DTM precisions, fractions, offsets, and malformed values
+func TestParseDateTime_ReadsPrecisionsAndOffsets(t *testing.T) {
+	testCases := map[string]string{
+		"20261001":              "2026-10-01T00:00:00Z",
+		"2026100108":            "2026-10-01T08:00:00Z",
+		"20261001083016.1234":   "2026-10-01T08:30:16Z",
+		"20261001083016+0130":   "2026-10-01T07:00:16Z",
+		"20261001083016.5-0500": "2026-10-01T13:30:16Z",
+	}
+	for value, expected := range testCases {
+		parsedTime, parseError := ParseDateTime(value)
+		if parseError != nil || parsedTime.UTC().Format("2006-01-02T15:04:05Z") != expected {
+			t.Errorf("ParseDateTime(%q): expected %s, got %v (%v)", value, expected, parsedTime.UTC(), parseError)
+		}
+	}
+
+	for _, invalid := range []string{"2026", "202610011", "20261301", "20261001+05", "20261001-ab00"} {
+		if _, parseError := ParseDateTime(invalid); parseError == nil {
+			t.Errorf("Expected %q to be rejected", invalid)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package hl7
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// nullValue is the HL7 value that clears a field, as opposed to an empty field, which leaves it unchanged
+const nullValue = `""`
+
+// medicalRecordNumberType is the CX-5 identifier type code of medical record numbers
+const medicalRecordNumberType = "MR"
+
+// hl7DateLayout is the date part of DT and DTM values
+const hl7DateLayout = "20060102"
+
+// genders maps HL7 table 0001 codes to FHIR administrative gender
+var genders = map[string]fhir.AdministrativeGender{
+	"M": fhir.AdministrativeGenderMale,
+	"F": fhir.AdministrativeGenderFemale,
+	"O": fhir.AdministrativeGenderOther,
+	"A": fhir.AdministrativeGenderOther,
+	"U": fhir.AdministrativeGenderUnknown,
+	"N": fhir.AdministrativeGenderUnknown,
+}
+
+// PatientIdentifier returns the system and value of the patient identifier in PID-3
+// The medical record number is preferred when PID-3 repeats; the system is read from the assigning authority
+func PatientIdentifier(message *Message) (string, string, error) {
+	repetitions := message.Repetitions(message.Field("PID", 3))
+	chosen := ""
+	for _, repetition := range repetitions {
+		if message.Component(repetition, 1) == "" {
+			continue
+		}
+		if message.Component(repetition, 5) == medicalRecordNumberType {
+			chosen = repetition
+			break
+		}
+		if chosen == "" {
+			chosen = repetition
+		}
+	}
+	if chosen == "" {
+		return "", "", fmt.Errorf("PID-3 must carry a patient identifier")
+	}
+
+	return assigningAuthoritySystem(message, chosen), message.Component(chosen, 1), nil
+}
+
+// assigningAuthoritySystem turns CX-4 into an identifier system: an ISO universal ID becomes a urn:oid,
+// another universal ID is used as is, and otherwise the namespace ID names the system
+func assigningAuthoritySystem(message *Message, identifier string) string {
+	namespaceID := message.Subcomponent(identifier, 4, 1)
+	universalID := message.Subcomponent(identifier, 4, 2)
+	universalIDType := message.Subcomponent(identifier, 4, 3)
+	switch {
+	case universalID != "" && universalIDType == "ISO":
+		return "urn:oid:" + universalID
+	case universalID != "":
+		return universalID
+	default:
+		return namespaceID
+	}
+}
+
+// ApplyDemographics copies the name, birth date, and gender in PID-5, PID-7, and PID-8 onto a FHIR patient
+// Empty fields leave the patient's value unchanged and "" clears it, as HL7 specifies for updates
+func ApplyDemographics(message *Message, fhirPatient *fhir.Patient) error {
+	name := message.Field("PID", 5)
+	switch name {
+	case "":
+	case nullValue:
+		fhirPatient.Name = nil
+	default:
+		firstName := message.Repetitions(name)[0]
+		familyName := message.Subcomponent(firstName, 1, 1)
+		humanName := fhir.HumanName{Family: &familyName}
+		if givenName := message.Component(firstName, 2); givenName != "" {
+			humanName.Given = []string{givenName}
+		}
+		fhirPatient.Name = []fhir.HumanName{humanName}
+	}
+
+	birthDate := message.Field("PID", 7)
+	switch birthDate {
+	case "":
+	case nullValue:
+		fhirPatient.BirthDate = nil
+	default:
+		if len(birthDate) < len(hl7DateLayout) {
+			return fmt.Errorf("PID-7 birth date %q is not a YYYYMMDD date", birthDate)
+		}
+		parsedDate, parseError := time.Parse(hl7DateLayout, birthDate[:len(hl7DateLayout)])
+		if parseError != nil {
+			return fmt.Errorf("PID-7 birth date %q is not a YYYYMMDD date", birthDate)
+		}
+		formattedDate := parsedDate.Format("2006-01-02")
+		fhirPatient.BirthDate = &formattedDate
+	}
+
+	gender := message.Field("PID", 8)
+	switch gender {
+	case "":
+	case nullValue:
+		fhirPatient.Gender = nil
+	default:
+		fhirGender, known := genders[message.Component(gender, 1)]
+		if !known {
+			return fmt.Errorf("PID-8 gender %q is not an HL7 table 0001 code", gender)
+		}
+		fhirPatient.Gender = &fhirGender
+	}
+
+	return nil
+}
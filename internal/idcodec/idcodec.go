@@ -0,0 +1,166 @@
+package idcodec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MinimumSecretLength is the shortest secret accepted for HMAC-based codecs
+const MinimumSecretLength = 32
+
+// ErrInvalidID is returned when an external ID was not issued by the codec for this tenant and resource type
+var ErrInvalidID = errors.New("invalid resource ID")
+
+// Codec converts between internal resource IDs and the IDs exposed to API clients
+type Codec interface {
+	// Encode returns the external ID for an internal ID
+	Encode(tenantID string, resourceType string, internalID string) string
+
+	// Decode returns the internal ID for an external ID, or ErrInvalidID
+	Decode(tenantID string, resourceType string, externalID string) (string, error)
+}
+
+// Passthrough exposes internal IDs unchanged
+type Passthrough struct{}
+
+// Encode returns the internal ID
+func (Passthrough) Encode(tenantID string, resourceType string, internalID string) string {
+	return internalID
+}
+
+// Decode returns the external ID
+func (Passthrough) Decode(tenantID string, resourceType string, externalID string) (string, error) {
+	return externalID, nil
+}
+
+// Payload formats; UUIDs and hex ObjectIDs are packed to keep external IDs within FHIR's 64 characters
+const (
+	formatRaw  byte = 0
+	formatUUID byte = 1
+	formatHex  byte = 2
+)
+
+// tagLength is the number of HMAC bytes kept as the synthetic IV and authenticator
+const tagLength = 12
+
+// externalEncoding is base64 over the characters FHIR allows in resource IDs
+var externalEncoding = base64.NewEncoding("ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-.").WithPadding(base64.NoPadding)
+
+// HMACCodec issues opaque, tenant-scoped IDs
+// The external ID is the internal ID encrypted under a per-tenant key, prefixed with a truncated
+// HMAC of the internal ID that doubles as the IV; decoding recomputes the HMAC, so IDs issued
+// to another tenant or for another resource type, and guessed IDs, are rejected
+type HMACCodec struct {
+	secret []byte
+}
+
+// NewHMACCodec creates an HMAC codec; the secret must be at least MinimumSecretLength bytes
+func NewHMACCodec(secret []byte) (*HMACCodec, error) {
+	if len(secret) < MinimumSecretLength {
+		return nil, fmt.Errorf("ID obfuscation secret must be at least %d bytes", MinimumSecretLength)
+	}
+	return &HMACCodec{secret: append([]byte(nil), secret...)}, nil
+}
+
+// Encode returns the opaque external ID for an internal ID
+func (codec *HMACCodec) Encode(tenantID string, resourceType string, internalID string) string {
+	tenantKey := codec.tenantKey(tenantID)
+	payload := packID(internalID)
+	tag := computeTag(tenantKey, resourceType, payload)
+
+	encoded := make([]byte, tagLength+len(payload))
+	copy(encoded, tag)
+	applyKeystream(tenantKey, tag, encoded[tagLength:], payload)
+
+	return externalEncoding.EncodeToString(encoded)
+}
+
+// Decode returns the internal ID for an opaque external ID
+func (codec *HMACCodec) Decode(tenantID string, resourceType string, externalID string) (string, error) {
+	encoded, decodeError := externalEncoding.DecodeString(externalID)
+	if decodeError != nil || len(encoded) <= tagLength {
+		return "", ErrInvalidID
+	}
+
+	tenantKey := codec.tenantKey(tenantID)
+	tag := encoded[:tagLength]
+	payload := make([]byte, len(encoded)-tagLength)
+	applyKeystream(tenantKey, tag, payload, encoded[tagLength:])
+
+	if !hmac.Equal(tag, computeTag(tenantKey, resourceType, payload)) {
+		return "", ErrInvalidID
+	}
+
+	internalID, unpackError := unpackID(payload)
+	if unpackError != nil {
+		return "", ErrInvalidID
+	}
+	return internalID, nil
+}
+
+// tenantKey derives the tenant's 256-bit key from the codec secret
+func (codec *HMACCodec) tenantKey(tenantID string) []byte {
+	mac := hmac.New(sha256.New, codec.secret)
+	mac.Write([]byte("tenant:" + tenantID))
+	return mac.Sum(nil)
+}
+
+// computeTag returns the truncated HMAC binding the payload to its resource type
+func computeTag(tenantKey []byte, resourceType string, payload []byte) []byte {
+	mac := hmac.New(sha256.New, tenantKey)
+	mac.Write([]byte(resourceType + "/"))
+	mac.Write(payload)
+	return mac.Sum(nil)[:tagLength]
+}
+
+// applyKeystream encrypts or decrypts source into destination with AES-CTR seeded by the tag
+func applyKeystream(tenantKey []byte, tag []byte, destination []byte, source []byte) {
+	// The key is always 32 bytes, so the cipher cannot fail to initialise
+	block, _ := aes.NewCipher(tenantKey)
+
+	initializationVector := make([]byte, aes.BlockSize)
+	copy(initializationVector, tag)
+	cipher.NewCTR(block, initializationVector).XORKeyStream(destination, source)
+}
+
+// packID encodes an internal ID compactly, recognising UUIDs and lowercase hex strings
+func packID(internalID string) []byte {
+	if parsedUUID, parseError := uuid.Parse(internalID); parseError == nil && parsedUUID.String() == internalID {
+		return append([]byte{formatUUID}, parsedUUID[:]...)
+	}
+
+	if len(internalID)%2 == 0 {
+		if decoded, hexError := hex.DecodeString(internalID); hexError == nil && hex.EncodeToString(decoded) == internalID {
+			return append([]byte{formatHex}, decoded...)
+		}
+	}
+
+	return append([]byte{formatRaw}, internalID...)
+}
+
+// unpackID reverses packID
+func unpackID(payload []byte) (string, error) {
+	body := payload[1:]
+	switch payload[0] {
+	case formatUUID:
+		parsedUUID, parseError := uuid.FromBytes(body)
+		if parseError != nil {
+			return "", parseError
+		}
+		return parsedUUID.String(), nil
+	case formatHex:
+		return hex.EncodeToString(body), nil
+	case formatRaw:
+		return string(body), nil
+	default:
+		return "", ErrInvalidID
+	}
+}
@@ -0,0 +1,99 @@
+package idcodec
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+)
+
+// fhirIDPattern is the FHIR R4 id datatype
+var fhirIDPattern = regexp.MustCompile(`^[A-Za-z0-9\-\.]{1,64}$`)
+
+// newTestCodec creates a codec with a fixed secret
+func newTestCodec(t *testing.T) *HMACCodec {
+	codec, codecError := NewHMACCodec([]byte("0123456789abcdef0123456789abcdef"))
+	if codecError != nil {
+		t.Fatalf("Failed to create codec: %v", codecError)
+	}
+	return codec
+}
+
+// TestNewHMACCodec_RejectsShortSecret verifies weak secrets are refused
+func TestNewHMACCodec_RejectsShortSecret(t *testing.T) {
+	if _, codecError := NewHMACCodec([]byte("short")); codecError == nil {
+		t.Error("Expected error for short secret")
+	}
+}
+
+// TestHMACCodec_RoundTrip verifies IDs of each supported shape survive encoding
+func TestHMACCodec_RoundTrip(t *testing.T) {
+	codec := newTestCodec(t)
+	internalIDs := []string{
+		"3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f",
+		"65a1f0c2e4b0a1b2c3d4e5f6",
+		"patient-123",
+		"ABCDEF",
+	}
+
+	for _, internalID := range internalIDs {
+		externalID := codec.Encode("acme", "Patient", internalID)
+		if externalID == internalID || !fhirIDPattern.MatchString(externalID) {
+			t.Errorf("Unexpected external ID %q for %q", externalID, internalID)
+		}
+		if codec.Encode("acme", "Patient", internalID) != externalID {
+			t.Errorf("Expected stable external ID for %q", internalID)
+		}
+
+		decodedID, decodeError := codec.Decode("acme", "Patient", externalID)
+		if decodeError != nil || decodedID != internalID {
+			t.Errorf("Expected %q, got %q (%v)", internalID, decodedID, decodeError)
+		}
+	}
+}
+
+// TestHMACCodec_ScopedToTenantAndType verifies IDs cannot be replayed across tenants or resource types
+func TestHMACCodec_ScopedToTenantAndType(t *testing.T) {
+	codec := newTestCodec(t)
+	internalID := "3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f"
+	externalID := codec.Encode("acme", "Patient", internalID)
+
+	if codec.Encode("globex", "Patient", internalID) == externalID {
+		t.Error("Expected different external IDs per tenant")
+	}
+	if _, decodeError := codec.Decode("globex", "Patient", externalID); !errors.Is(decodeError, ErrInvalidID) {
+		t.Errorf("Expected ErrInvalidID for another tenant, got %v", decodeError)
+	}
+	if _, decodeError := codec.Decode("acme", "Observation", externalID); !errors.Is(decodeError, ErrInvalidID) {
+		t.Errorf("Expected ErrInvalidID for another resource type, got %v", decodeError)
+	}
+}
+
+// TestHMACCodec_RejectsForgedIDs verifies internal and tampered IDs are not accepted
+func TestHMACCodec_RejectsForgedIDs(t *testing.T) {
+	codec := newTestCodec(t)
+	externalID := codec.Encode("acme", "Patient", "patient-123")
+	tampered := []byte(externalID)
+	middle := len(tampered) / 2
+	if tampered[middle] == 'A' {
+		tampered[middle] = 'B'
+	} else {
+		tampered[middle] = 'A'
+	}
+
+	for _, forgedID := range []string{"patient-123", "", "not_base64!", string(tampered)} {
+		if _, decodeError := codec.Decode("acme", "Patient", forgedID); !errors.Is(decodeError, ErrInvalidID) {
+			t.Errorf("Expected ErrInvalidID for %q, got %v", forgedID, decodeError)
+		}
+	}
+}
+
+// TestPassthrough verifies the default codec leaves IDs unchanged
+func TestPassthrough(t *testing.T) {
+	var codec Codec = Passthrough{}
+	if codec.Encode("acme", "Patient", "patient-123") != "patient-123" {
+		t.Error("Expected unchanged ID")
+	}
+	if decodedID, _ := codec.Decode("acme", "Patient", "patient-123"); decodedID != "patient-123" {
+		t.Error("Expected unchanged ID")
+	}
+}
@@ -0,0 +1,250 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// HeaderKey is the request header carrying the client's idempotency key
+const HeaderKey = "Idempotency-Key"
+
+// HeaderReplayed is set to "true" on responses replayed from an earlier request
+const HeaderReplayed = "Idempotent-Replayed"
+
+// maxKeyLength bounds keys to the stored column size
+const maxKeyLength = 255
+
+// replayedHeaders are the response headers stored and replayed with the body
+var replayedHeaders = []string{"Content-Type", "Location", "Content-Location", "ETag", "Last-Modified"}
+
+// Store keeps the requests made with an idempotency key and their responses
+type Store interface {
+	// Claim records the request under its key and returns nil, or returns the record already holding the key
+	// An expired record, or an unfinished one created before staleBefore, is replaced rather than returned
+	Claim(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (*models.IdempotencyRecord, error)
+
+	// Complete stores the response of a claimed request
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+
+	// Release removes an unfinished claim so the request can be retried
+	Release(ctx context.Context, clientID string, key string) error
+
+	// DeleteExpired removes records that expired before the given time and returns how many were removed
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Policy controls how long keys are honored
+type Policy struct {
+	// TTL is how long a response is replayed to retries with the same key
+	TTL time.Duration
+
+	// StaleAfter is how long an unfinished request holds its key; after that a server that died mid-request
+	// no longer blocks retries
+	StaleAfter time.Duration
+
+	// PurgeInterval is how often expired keys are deleted
+	PurgeInterval time.Duration
+}
+
+// DefaultPolicy replays responses for a day, frees keys held by requests unfinished after five minutes,
+// and purges expired keys hourly
+func DefaultPolicy() Policy {
+	return Policy{
+		TTL:           24 * time.Hour,
+		StaleAfter:    5 * time.Minute,
+		PurgeInterval: time.Hour,
+	}
+}
+
+// Keeper replays the stored response to POST requests retried with the same Idempotency-Key
+type Keeper struct {
+	store  Store
+	policy Policy
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewKeeper creates a keeper over the store
+func NewKeeper(store Store, policy Policy) *Keeper {
+	return &Keeper{
+		store:  store,
+		policy: policy,
+		now:    time.Now,
+	}
+}
+
+// captureWriter keeps the status code and body written by the handler
+type captureWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader captures the status code before writing
+func (writer *captureWriter) WriteHeader(statusCode int) {
+	writer.statusCode = statusCode
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write copies the body before writing it through
+func (writer *captureWriter) Write(body []byte) (int, error) {
+	writer.body.Write(body)
+	return writer.ResponseWriter.Write(body)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (writer *captureWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// Middleware honors Idempotency-Key on FHIR POST requests, including transaction and batch Bundles
+// The first request with a key is processed and its response stored. A retry with the same key, method, path,
+// and body gets the stored response with Idempotent-Replayed: true instead of creating a duplicate. A retry
+// while the first is still in progress gets 409, and reusing a key for a different request gets 422.
+// Server errors are not stored, so the request can be retried. Keys are scoped to the caller's principal and
+// tenant, so the role, token, and tenant middleware must run first
+func (keeper *Keeper) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idempotencyKey := r.Header.Get(HeaderKey)
+		isFHIRPath := r.URL.Path == "/fhir" || strings.HasPrefix(r.URL.Path, "/fhir/")
+		if idempotencyKey == "" || r.Method != http.MethodPost || !isFHIRPath {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(idempotencyKey) > maxKeyLength {
+			outcome.WriteForRequest(w, r, http.StatusBadRequest, []outcome.Issue{
+				outcome.Error(fhir.IssueTypeValue, "Idempotency-Key must be at most 255 characters"),
+			})
+			return
+		}
+
+		// Hash the body and restore it for downstream handlers
+		bodyBytes, readError := io.ReadAll(r.Body)
+		if readError != nil {
+			outcome.WriteForRequest(w, r, http.StatusBadRequest, []outcome.Issue{
+				outcome.Error(fhir.IssueTypeStructure, "Failed to read request body"),
+			})
+			return
+		}
+		r.Body.Close()
+		r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+		bodyHash := sha256.Sum256(bodyBytes)
+
+		now := keeper.now()
+		record := &models.IdempotencyRecord{
+			ClientID:    auth.FromContext(r.Context()).ID + "|" + tenant.FromContext(r.Context()),
+			Key:         idempotencyKey,
+			Method:      r.Method,
+			Path:        r.URL.Path,
+			RequestHash: hex.EncodeToString(bodyHash[:]),
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(keeper.policy.TTL),
+		}
+		existing, claimError := keeper.store.Claim(r.Context(), record, now.Add(-keeper.policy.StaleAfter))
+		if claimError != nil {
+			// Processing without the key could create the duplicate the client is guarding against
+			log.Error().Err(claimError).Msg("Failed to claim idempotency key")
+			outcome.WriteForRequest(w, r, http.StatusServiceUnavailable, []outcome.Issue{
+				outcome.Error(fhir.IssueTypeTransient, "Idempotency-Key could not be checked; retry the request"),
+			})
+			return
+		}
+		if existing != nil {
+			keeper.answerDuplicate(w, r, record, existing)
+			return
+		}
+
+		// The response is stored even if the client has gone, since its retry is what the record is for
+		storeContext := context.WithoutCancel(r.Context())
+		recorder := &captureWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				keeper.release(storeContext, record)
+				panic(recovered)
+			}
+		}()
+		next.ServeHTTP(recorder, r)
+
+		if recorder.statusCode >= http.StatusInternalServerError {
+			keeper.release(storeContext, record)
+			return
+		}
+		record.Completed = true
+		record.StatusCode = recorder.statusCode
+		record.ResponseBody = recorder.body.Bytes()
+		record.ResponseHeaders = map[string]string{}
+		for _, headerName := range replayedHeaders {
+			if headerValue := recorder.Header().Get(headerName); headerValue != "" {
+				record.ResponseHeaders[headerName] = headerValue
+			}
+		}
+		if completeError := keeper.store.Complete(storeContext, record); completeError != nil {
+			log.Error().Err(completeError).Str("idempotency_key", record.Key).Msg("Failed to store idempotent response")
+		}
+	})
+}
+
+// answerDuplicate replays the stored response, or explains why the request cannot be answered from it
+func (keeper *Keeper) answerDuplicate(w http.ResponseWriter, r *http.Request, record *models.IdempotencyRecord, existing *models.IdempotencyRecord) {
+	if existing.Method != record.Method || existing.Path != record.Path || existing.RequestHash != record.RequestHash {
+		outcome.WriteForRequest(w, r, http.StatusUnprocessableEntity, []outcome.Issue{
+			outcome.Error(fhir.IssueTypeBusinessRule, "Idempotency-Key was already used for a different request"),
+		})
+		return
+	}
+	if !existing.Completed {
+		w.Header().Set("Retry-After", "1")
+		outcome.WriteForRequest(w, r, http.StatusConflict, []outcome.Issue{
+			outcome.Error(fhir.IssueTypeConflict, "A request with this Idempotency-Key is still in progress"),
+		})
+		return
+	}
+
+	for headerName, headerValue := range existing.ResponseHeaders {
+		w.Header().Set(headerName, headerValue)
+	}
+	w.Header().Set(HeaderReplayed, "true")
+	w.WriteHeader(existing.StatusCode)
+	w.Write(existing.ResponseBody)
+}
+
+// release frees the key of a request that failed, logging when that is not possible
+func (keeper *Keeper) release(ctx context.Context, record *models.IdempotencyRecord) {
+	if releaseError := keeper.store.Release(ctx, record.ClientID, record.Key); releaseError != nil {
+		log.Error().Err(releaseError).Str("idempotency_key", record.Key).Msg("Failed to release idempotency key")
+	}
+}
+
+// Run deletes expired keys every PurgeInterval until ctx is cancelled
+func (keeper *Keeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(keeper.policy.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if purged, purgeError := keeper.store.DeleteExpired(ctx, keeper.now()); purgeError != nil {
+			log.Error().Err(purgeError).Msg("Failed to delete expired idempotency keys")
+		} else if purged > 0 {
+			log.Info().Int64("purged", purged).Msg("Deleted expired idempotency keys")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
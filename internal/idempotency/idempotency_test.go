@@ -0,0 +1,220 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// MockStore implements Store in memory for testing
+type MockStore struct {
+	mutex      sync.Mutex
+	records    map[string]*models.IdempotencyRecord
+	claimError error
+}
+
+// newMockStore creates an empty store
+func newMockStore() *MockStore {
+	return &MockStore{records: make(map[string]*models.IdempotencyRecord)}
+}
+
+// Claim stores the record unless its key is held by a live record
+func (mock *MockStore) Claim(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (*models.IdempotencyRecord, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	if mock.claimError != nil {
+		return nil, mock.claimError
+	}
+	recordKey := record.ClientID + "/" + record.Key
+	if existing, exists := mock.records[recordKey]; exists {
+		expired := !existing.ExpiresAt.After(record.CreatedAt)
+		stale := !existing.Completed && existing.CreatedAt.Before(staleBefore)
+		if !expired && !stale {
+			return existing, nil
+		}
+	}
+	storedRecord := *record
+	mock.records[recordKey] = &storedRecord
+	return nil, nil
+}
+
+// Complete stores the response
+func (mock *MockStore) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	storedRecord := *record
+	mock.records[record.ClientID+"/"+record.Key] = &storedRecord
+	return nil
+}
+
+// Release removes an unfinished claim
+func (mock *MockStore) Release(ctx context.Context, clientID string, key string) error {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	if existing, exists := mock.records[clientID+"/"+key]; exists && !existing.Completed {
+		delete(mock.records, clientID+"/"+key)
+	}
+	return nil
+}
+
+// DeleteExpired removes expired records
+func (mock *MockStore) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	mock.mutex.Lock()
+	defer mock.mutex.Unlock()
+	var deleted int64
+	for recordKey, record := range mock.records {
+		if !record.ExpiresAt.After(before) {
+			delete(mock.records, recordKey)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// creatingHandler answers every POST with a newly numbered resource, counting how many it created
+type creatingHandler struct {
+	created    int
+	statusCode int
+}
+
+// ServeHTTP creates the next resource
+func (handler *creatingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	handler.created++
+	if handler.statusCode != 0 {
+		w.WriteHeader(handler.statusCode)
+		return
+	}
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.Header().Set("Location", "/fhir/Patient/p-"+string(rune('0'+handler.created)))
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte(`{"resourceType":"Patient","id":"p-` + string(rune('0'+handler.created)) + `"}`))
+}
+
+// newTestKeeper creates a keeper at a fixed time over the store
+func newTestKeeper(store Store) *Keeper {
+	keeper := NewKeeper(store, DefaultPolicy())
+	keeper.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+	return keeper
+}
+
+// keyedRequest builds a POST by the principal with the idempotency key and body
+func keyedRequest(principalID string, key string, body string) *http.Request {
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", strings.NewReader(body))
+	request.Header.Set(HeaderKey, key)
+	return request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{ID: principalID}))
+}
+
+// TestKeeper_ReplaysRetries verifies a retried POST gets the stored response without creating a duplicate
+func TestKeeper_ReplaysRetries(t *testing.T) {
+	handler := &creatingHandler{}
+	middleware := newTestKeeper(newMockStore()).Middleware(handler)
+
+	first := httptest.NewRecorder()
+	middleware.ServeHTTP(first, keyedRequest("oauth:lab", "key-1", `{"resourceType":"Patient"}`))
+	retry := httptest.NewRecorder()
+	middleware.ServeHTTP(retry, keyedRequest("oauth:lab", "key-1", `{"resourceType":"Patient"}`))
+
+	if handler.created != 1 {
+		t.Fatalf("Expected one patient created, got %d", handler.created)
+	}
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() || retry.Header().Get("Location") != "/fhir/Patient/p-1" {
+		t.Errorf("Expected the original 201 replayed, got %d %s", retry.Code, retry.Body.String())
+	}
+	if retry.Header().Get(HeaderReplayed) != "true" || first.Header().Get(HeaderReplayed) != "" {
+		t.Error("Expected only the replay to be marked as replayed")
+	}
+
+	// Another client's identical key is its own
+	other := httptest.NewRecorder()
+	middleware.ServeHTTP(other, keyedRequest("oauth:pharmacy", "key-1", `{"resourceType":"Patient"}`))
+	if handler.created != 2 || other.Header().Get(HeaderReplayed) != "" {
+		t.Errorf("Expected keys scoped per client, got %d patients", handler.created)
+	}
+}
+
+// TestKeeper_KeyReusedForDifferentRequest verifies reusing a key with another body is refused with 422
+func TestKeeper_KeyReusedForDifferentRequest(t *testing.T) {
+	handler := &creatingHandler{}
+	middleware := newTestKeeper(newMockStore()).Middleware(handler)
+
+	middleware.ServeHTTP(httptest.NewRecorder(), keyedRequest("oauth:lab", "key-1", `{"resourceType":"Patient","gender":"male"}`))
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, keyedRequest("oauth:lab", "key-1", `{"resourceType":"Patient","gender":"female"}`))
+	if recorder.Code != http.StatusUnprocessableEntity || handler.created != 1 {
+		t.Errorf("Expected 422 without a second create, got %d and %d creates", recorder.Code, handler.created)
+	}
+}
+
+// TestKeeper_InProgress verifies a retry while the first request is unfinished gets 409, until the claim is stale
+func TestKeeper_InProgress(t *testing.T) {
+	store := newMockStore()
+	keeper := newTestKeeper(store)
+	store.Claim(context.Background(), &models.IdempotencyRecord{
+		ClientID: "oauth:lab|default", Key: "key-1", Method: http.MethodPost, Path: "/fhir/Patient",
+		RequestHash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", // SHA-256 of an empty body
+		CreatedAt:   keeper.now().Add(-time.Minute), ExpiresAt: keeper.now().Add(time.Hour),
+	}, time.Time{})
+
+	handler := &creatingHandler{}
+	recorder := httptest.NewRecorder()
+	keeper.Middleware(handler).ServeHTTP(recorder, keyedRequest("oauth:lab", "key-1", ""))
+	if recorder.Code != http.StatusConflict || recorder.Header().Get("Retry-After") == "" || handler.created != 0 {
+		t.Errorf("Expected 409 with Retry-After, got %d", recorder.Code)
+	}
+
+	keeper.now = func() time.Time { return time.Date(2026, 10, 16, 9, 10, 0, 0, time.UTC) }
+	recorder = httptest.NewRecorder()
+	keeper.Middleware(handler).ServeHTTP(recorder, keyedRequest("oauth:lab", "key-1", ""))
+	if recorder.Code != http.StatusCreated || handler.created != 1 {
+		t.Errorf("Expected a stale claim to be taken over, got %d", recorder.Code)
+	}
+}
+
+// TestKeeper_ServerErrorsNotStored verifies a 5xx frees the key so the retry is processed
+func TestKeeper_ServerErrorsNotStored(t *testing.T) {
+	handler := &creatingHandler{statusCode: http.StatusServiceUnavailable}
+	middleware := newTestKeeper(newMockStore()).Middleware(handler)
+
+	middleware.ServeHTTP(httptest.NewRecorder(), keyedRequest("oauth:lab", "key-1", "{}"))
+	handler.statusCode = 0
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, keyedRequest("oauth:lab", "key-1", "{}"))
+	if recorder.Code != http.StatusCreated || handler.created != 2 {
+		t.Errorf("Expected the retry to be processed, got %d", recorder.Code)
+	}
+}
+
+// TestKeeper_PassThroughAndFailures verifies requests without a key are untouched and store failures refuse keyed ones
+func TestKeeper_PassThroughAndFailures(t *testing.T) {
+	handler := &creatingHandler{}
+	store := newMockStore()
+	middleware := newTestKeeper(store).Middleware(handler)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		middleware.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fhir/Patient", strings.NewReader("{}")))
+	}
+	if handler.created != 2 {
+		t.Errorf("Expected requests without a key to be processed each time, got %d", handler.created)
+	}
+
+	recorder := httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, keyedRequest("oauth:lab", strings.Repeat("k", 256), "{}"))
+	if recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an overlong key, got %d", recorder.Code)
+	}
+
+	store.claimError = errors.New("connection refused")
+	recorder = httptest.NewRecorder()
+	middleware.ServeHTTP(recorder, keyedRequest("oauth:lab", "key-2", "{}"))
+	if recorder.Code != http.StatusServiceUnavailable || handler.created != 2 {
+		t.Errorf("Expected 503 without processing while the store is down, got %d", recorder.Code)
+	}
+}
@@ -0,0 +1,442 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUnknownKind is returned when enqueuing a kind without a registered handler
+var ErrUnknownKind = errors.New("no handler registered for job kind")
+
+// Store persists jobs and their outcomes
+type Store interface {
+	Enqueue(ctx context.Context, job *models.Job) (*models.Job, error)
+	Get(ctx context.Context, jobID string) (*models.Job, error)
+	List(ctx context.Context, filter models.JobFilter, limit int, offset int) ([]*models.Job, error)
+	ClaimNext(ctx context.Context, kinds []string, now time.Time, staleBefore time.Time) (*models.Job, error)
+	Heartbeat(ctx context.Context, jobID string, progress string) error
+	Succeed(ctx context.Context, jobID string, result []byte, expiresAt time.Time) error
+	Reschedule(ctx context.Context, jobID string, runAfter time.Time, lastError string) error
+	Fail(ctx context.Context, jobID string, lastError string, expiresAt time.Time) error
+	Cancel(ctx context.Context, jobID string, expiresAt time.Time) error
+	Discard(ctx context.Context, jobID string) error
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Progress records how far a running job has got; the latest value is stored with the next heartbeat
+type Progress func(progress string)
+
+// Handler runs one job of a kind and returns its result
+// The context is cancelled when the job is cancelled or the server shuts down; errors are retried with
+// backoff unless marked with Permanent
+type Handler interface {
+	Run(ctx context.Context, job *models.Job, progress Progress) ([]byte, error)
+}
+
+// Finalizer is implemented by handlers that keep their own record of a job, so they hear when it fails or is
+// cancelled, including when no worker was running it; the job carries its final status and last error
+type Finalizer interface {
+	Finalize(ctx context.Context, job *models.Job)
+}
+
+// HandlerFunc adapts a function to the Handler interface
+type HandlerFunc func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error)
+
+// Run calls the function
+func (handlerFunc HandlerFunc) Run(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+	return handlerFunc(ctx, job, progress)
+}
+
+// permanentError marks a failure that retrying cannot fix
+type permanentError struct {
+	err error
+}
+
+// Error implements the error interface
+func (permanent *permanentError) Error() string {
+	return permanent.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (permanent *permanentError) Unwrap() error {
+	return permanent.err
+}
+
+// Permanent wraps an error so the job fails without further attempts
+func Permanent(err error) error {
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether an error was marked with Permanent
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Policy sizes the worker pool and controls retries and retention
+type Policy struct {
+	// Workers is the number of jobs run concurrently by one server
+	Workers int
+
+	// PollInterval is how often idle workers look for due jobs and expired jobs are deleted
+	PollInterval time.Duration
+
+	// HeartbeatInterval is how often a worker stores its job's progress and checks it was not cancelled
+	HeartbeatInterval time.Duration
+
+	// StaleAfter is how long a running job may go without a heartbeat before another worker takes it over
+	StaleAfter time.Duration
+
+	// MaxAttempts is the number of attempts after which a job fails, unless it was queued with its own
+	MaxAttempts int
+
+	// BaseDelay is the wait after the first failed attempt; each further failure doubles it up to MaxDelay
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+
+	// Jitter spreads each delay by up to this fraction either way so retries do not arrive in waves
+	Jitter float64
+
+	// Retention is how long finished jobs and their results are kept
+	Retention time.Duration
+}
+
+// DefaultPolicy returns the job queue settings used unless configured otherwise
+func DefaultPolicy() Policy {
+	return Policy{
+		Workers:           4,
+		PollInterval:      time.Second,
+		HeartbeatInterval: 10 * time.Second,
+		StaleAfter:        time.Minute,
+		MaxAttempts:       5,
+		BaseDelay:         5 * time.Second,
+		MaxDelay:          10 * time.Minute,
+		Jitter:            0.2,
+		Retention:         24 * time.Hour,
+	}
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts
+// random is a value in [0, 1) used to apply jitter
+func (policy Policy) Backoff(failedAttempts int, random float64) time.Duration {
+	if failedAttempts < 1 {
+		failedAttempts = 1
+	}
+
+	delay := float64(policy.BaseDelay) * math.Pow(2, float64(failedAttempts-1))
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		delay = float64(policy.MaxDelay)
+	}
+	delay *= 1 + policy.Jitter*(2*random-1)
+
+	return time.Duration(delay)
+}
+
+// Queue runs background jobs on a pool of workers, retrying failed attempts with backoff
+// Jobs are stored in Postgres, so any instance may run them and a job whose worker stopped is taken over
+// Each kind registers a handler; async requests and bulk exports register theirs
+type Queue struct {
+	store  Store
+	policy Policy
+
+	handlersMutex sync.RWMutex
+	handlers      map[string]Handler
+
+	// now and random are replaceable for tests
+	now    func() time.Time
+	random func() float64
+}
+
+// NewQueue creates a queue backed by store
+func NewQueue(store Store, policy Policy) *Queue {
+	return &Queue{
+		store:    store,
+		policy:   policy,
+		handlers: make(map[string]Handler),
+		now:      time.Now,
+		random:   rand.Float64,
+	}
+}
+
+// RegisterHandler sets the handler that runs jobs of a kind
+func (queue *Queue) RegisterHandler(kind string, handler Handler) {
+	queue.handlersMutex.Lock()
+	defer queue.handlersMutex.Unlock()
+	queue.handlers[kind] = handler
+}
+
+// handler returns the handler for a kind, or nil when none is registered
+func (queue *Queue) handler(kind string) Handler {
+	queue.handlersMutex.RLock()
+	defer queue.handlersMutex.RUnlock()
+	return queue.handlers[kind]
+}
+
+// kinds returns the registered kinds, the only ones this server claims
+func (queue *Queue) kinds() []string {
+	queue.handlersMutex.RLock()
+	defer queue.handlersMutex.RUnlock()
+	kinds := make([]string, 0, len(queue.handlers))
+	for kind := range queue.handlers {
+		kinds = append(kinds, kind)
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// Enqueue stores a job for the next free worker, owned by the caller in ctx
+// maxAttempts of zero uses the policy's; pass 1 for work that must not run twice
+func (queue *Queue) Enqueue(ctx context.Context, kind string, payload []byte, maxAttempts int) (*models.Job, error) {
+	if queue.handler(kind) == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKind, kind)
+	}
+	if maxAttempts < 1 {
+		maxAttempts = queue.policy.MaxAttempts
+	}
+
+	return queue.store.Enqueue(ctx, &models.Job{
+		Kind:        kind,
+		Payload:     payload,
+		MaxAttempts: maxAttempts,
+		PrincipalID: auth.FromContext(ctx).ID,
+		TenantID:    tenant.FromContext(ctx),
+	})
+}
+
+// Get returns a job by ID; sql.ErrNoRows when there is none
+func (queue *Queue) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	if _, parseError := uuid.Parse(jobID); parseError != nil {
+		return nil, sql.ErrNoRows
+	}
+	return queue.store.Get(ctx, jobID)
+}
+
+// List returns jobs matching the filter, newest first, without their payloads and results
+func (queue *Queue) List(ctx context.Context, filter models.JobFilter, limit int, offset int) ([]*models.Job, error) {
+	return queue.store.List(ctx, filter, limit, offset)
+}
+
+// Cancel stops a queued or running job; sql.ErrNoRows when there is none or it already finished
+// A worker running the job notices at its next heartbeat and stops
+func (queue *Queue) Cancel(ctx context.Context, jobID string) error {
+	if _, parseError := uuid.Parse(jobID); parseError != nil {
+		return sql.ErrNoRows
+	}
+	if cancelError := queue.store.Cancel(ctx, jobID, queue.now().Add(queue.policy.Retention)); cancelError != nil {
+		return cancelError
+	}
+
+	log.Info().Str("job_id", jobID).Str("principal", auth.FromContext(ctx).ID).Msg("Job cancelled")
+	if cancelled, getError := queue.store.Get(ctx, jobID); getError == nil {
+		queue.finalize(ctx, cancelled)
+	}
+	return nil
+}
+
+// Discard deletes a finished job and its result; sql.ErrNoRows when there is none or it has not finished
+func (queue *Queue) Discard(ctx context.Context, jobID string) error {
+	if _, parseError := uuid.Parse(jobID); parseError != nil {
+		return sql.ErrNoRows
+	}
+	return queue.store.Discard(ctx, jobID)
+}
+
+// Run starts the policy's number of workers and deletes expired jobs until ctx is cancelled,
+// returning once every worker has stopped
+func (queue *Queue) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	for range queue.policy.Workers {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			queue.work(ctx)
+		}()
+	}
+
+	for {
+		if deleted, deleteError := queue.store.DeleteExpired(ctx, queue.now()); deleteError != nil && ctx.Err() == nil {
+			log.Error().Err(deleteError).Msg("Failed to delete expired jobs")
+		} else if deleted > 0 {
+			log.Info().Int64("deleted", deleted).Msg("Deleted expired jobs")
+		}
+
+		if waitFor(ctx, queue.policy.PollInterval) != nil {
+			workers.Wait()
+			return
+		}
+	}
+}
+
+// work runs due jobs one after another, polling while there are none
+func (queue *Queue) work(ctx context.Context) {
+	for {
+		processed, processError := queue.ProcessNext(ctx)
+		if processError != nil && ctx.Err() == nil {
+			log.Error().Err(processError).Msg("Failed to process job")
+		}
+		if processed != nil && processError == nil && ctx.Err() == nil {
+			continue
+		}
+
+		if waitFor(ctx, queue.policy.PollInterval) != nil {
+			return
+		}
+	}
+}
+
+// ProcessNext claims the next due job and runs it, returning nil when none is due
+// The job's own failures are recorded on it; the returned error is reserved for storage failures
+func (queue *Queue) ProcessNext(ctx context.Context) (*models.Job, error) {
+	kinds := queue.kinds()
+	if len(kinds) == 0 {
+		return nil, nil
+	}
+
+	now := queue.now()
+	job, claimError := queue.store.ClaimNext(ctx, kinds, now, now.Add(-queue.policy.StaleAfter))
+	if errors.Is(claimError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if claimError != nil {
+		return nil, fmt.Errorf("failed to claim job: %w", claimError)
+	}
+
+	// A job taken over from a stopped worker counts that attempt too, so work that must not run twice is failed
+	if job.Attempts > job.MaxAttempts {
+		return job, queue.fail(ctx, job, errors.New("the server stopped while running the job and its attempts are exhausted"))
+	}
+
+	runContext, cancelRun := context.WithCancel(ctx)
+	var progressMutex sync.Mutex
+	latestProgress := job.Progress
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		queue.heartbeat(runContext, cancelRun, job.ID, func() string {
+			progressMutex.Lock()
+			defer progressMutex.Unlock()
+			return latestProgress
+		})
+	}()
+
+	result, runError := queue.run(runContext, job, func(progress string) {
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		latestProgress = progress
+	})
+	cancelled := runContext.Err() != nil
+	cancelRun()
+	<-heartbeatDone
+
+	switch {
+	case ctx.Err() != nil:
+		// Shutting down: another worker takes the job over once it is stale
+		return job, nil
+	case cancelled:
+		log.Info().Str("job_id", job.ID).Str("kind", job.Kind).Msg("Job stopped after cancellation")
+		return job, nil
+	case runError == nil:
+		log.Info().Str("job_id", job.ID).Str("kind", job.Kind).Int("attempts", job.Attempts).Msg("Job succeeded")
+		return job, finished(queue.store.Succeed(ctx, job.ID, result, queue.now().Add(queue.policy.Retention)))
+	case IsPermanent(runError) || job.Attempts >= job.MaxAttempts:
+		return job, queue.fail(ctx, job, runError)
+	}
+
+	runAfter := queue.now().Add(queue.policy.Backoff(job.Attempts, queue.random()))
+	log.Warn().
+		Err(runError).
+		Str("job_id", job.ID).
+		Str("kind", job.Kind).
+		Int("attempts", job.Attempts).
+		Time("run_after", runAfter).
+		Msg("Job attempt failed; retry scheduled")
+	return job, finished(queue.store.Reschedule(ctx, job.ID, runAfter, runError.Error()))
+}
+
+// run calls the kind's handler, reporting a panic as a permanent failure rather than stopping the worker
+func (queue *Queue) run(ctx context.Context, job *models.Job, progress Progress) (result []byte, runError error) {
+	handler := queue.handler(job.Kind)
+	if handler == nil {
+		return nil, Permanent(fmt.Errorf("%w: %s", ErrUnknownKind, job.Kind))
+	}
+
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			runError = Permanent(fmt.Errorf("the job failed unexpectedly: %v", recovered))
+		}
+	}()
+	return handler.Run(ctx, job, progress)
+}
+
+// fail marks the job failed and logs why
+func (queue *Queue) fail(ctx context.Context, job *models.Job, cause error) error {
+	log.Warn().Err(cause).Str("job_id", job.ID).Str("kind", job.Kind).Int("attempts", job.Attempts).Msg("Job failed")
+	failError := queue.store.Fail(ctx, job.ID, cause.Error(), queue.now().Add(queue.policy.Retention))
+	if failError == nil {
+		job.Status, job.LastError = models.JobStatusFailed, cause.Error()
+		queue.finalize(ctx, job)
+	}
+	return finished(failError)
+}
+
+// finalize tells the kind's handler that the job failed or was cancelled, when it keeps its own record
+func (queue *Queue) finalize(ctx context.Context, job *models.Job) {
+	if finalizer, ok := queue.handler(job.Kind).(Finalizer); ok {
+		finalizer.Finalize(ctx, job)
+	}
+}
+
+// heartbeat keeps a running job from looking stale and stores its progress, and stops it once it is no longer running
+func (queue *Queue) heartbeat(ctx context.Context, stop context.CancelFunc, jobID string, progress func() string) {
+	ticker := time.NewTicker(queue.policy.HeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			heartbeatError := queue.store.Heartbeat(ctx, jobID, progress())
+			if errors.Is(heartbeatError, sql.ErrNoRows) {
+				stop()
+				return
+			}
+			if heartbeatError != nil && ctx.Err() == nil {
+				log.Warn().Err(heartbeatError).Str("job_id", jobID).Msg("Failed to record job heartbeat")
+			}
+		}
+	}
+}
+
+// finished ignores sql.ErrNoRows from recording an outcome: the job was cancelled or taken over meanwhile
+func finished(recordError error) error {
+	if errors.Is(recordError, sql.ErrNoRows) {
+		return nil
+	}
+	return recordError
+}
+
+// waitFor sleeps for duration or until ctx is cancelled
+func waitFor(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,493 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// memoryStore is an in-memory Store for tests
+type memoryStore struct {
+	mutex      sync.Mutex
+	jobs       map[string]*models.Job
+	heartbeats int
+}
+
+// newMemoryStore creates an empty store
+func newMemoryStore() *memoryStore {
+	return &memoryStore{jobs: make(map[string]*models.Job)}
+}
+
+// Enqueue stores a queued job
+func (store *memoryStore) Enqueue(ctx context.Context, job *models.Job) (*models.Job, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored := *job
+	stored.ID = uuid.NewString()
+	stored.Status = models.JobStatusQueued
+	stored.CreatedAt = time.Now()
+	store.jobs[stored.ID] = &stored
+	result := stored
+	return &result, nil
+}
+
+// Get returns a copy of a job
+func (store *memoryStore) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.jobs[jobID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	copied := *stored
+	return &copied, nil
+}
+
+// List returns the jobs matching the filter
+func (store *memoryStore) List(ctx context.Context, filter models.JobFilter, limit int, offset int) ([]*models.Job, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	listed := []*models.Job{}
+	for _, stored := range store.jobs {
+		if (filter.Status == "" || stored.Status == filter.Status) && (filter.Kind == "" || stored.Kind == filter.Kind) {
+			copied := *stored
+			listed = append(listed, &copied)
+		}
+	}
+	return listed, nil
+}
+
+// ClaimNext claims the earliest due job of the kinds, or a stale running one
+func (store *memoryStore) ClaimNext(ctx context.Context, kinds []string, now time.Time, staleBefore time.Time) (*models.Job, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	due := []*models.Job{}
+	for _, stored := range store.jobs {
+		queuedAndDue := stored.Status == models.JobStatusQueued && !stored.RunAfter.After(now)
+		stale := stored.Status == models.JobStatusRunning && stored.UpdatedAt.Before(staleBefore)
+		if (queuedAndDue || stale) && containsKind(kinds, stored.Kind) {
+			due = append(due, stored)
+		}
+	}
+	if len(due) == 0 {
+		return nil, sql.ErrNoRows
+	}
+	sort.Slice(due, func(left, right int) bool { return due[left].RunAfter.Before(due[right].RunAfter) })
+
+	claimed := due[0]
+	claimed.Status, claimed.Attempts, claimed.UpdatedAt = models.JobStatusRunning, claimed.Attempts+1, now
+	copied := *claimed
+	return &copied, nil
+}
+
+// containsKind reports whether kind is one of kinds
+func containsKind(kinds []string, kind string) bool {
+	for _, candidate := range kinds {
+		if candidate == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// running returns the job when it is running, holding the mutex
+func (store *memoryStore) running(jobID string) (*models.Job, error) {
+	stored, exists := store.jobs[jobID]
+	if !exists || stored.Status != models.JobStatusRunning {
+		return nil, sql.ErrNoRows
+	}
+	return stored, nil
+}
+
+// Heartbeat records progress
+func (store *memoryStore) Heartbeat(ctx context.Context, jobID string, progress string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.heartbeats++
+	stored, runningError := store.running(jobID)
+	if runningError != nil {
+		return runningError
+	}
+	stored.Progress = progress
+	return nil
+}
+
+// Succeed stores the result
+func (store *memoryStore) Succeed(ctx context.Context, jobID string, result []byte, expiresAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, runningError := store.running(jobID)
+	if runningError != nil {
+		return runningError
+	}
+	stored.Status, stored.Result, stored.ExpiresAt = models.JobStatusSucceeded, result, &expiresAt
+	return nil
+}
+
+// Reschedule queues the job again
+func (store *memoryStore) Reschedule(ctx context.Context, jobID string, runAfter time.Time, lastError string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, runningError := store.running(jobID)
+	if runningError != nil {
+		return runningError
+	}
+	stored.Status, stored.RunAfter, stored.LastError = models.JobStatusQueued, runAfter, lastError
+	return nil
+}
+
+// Fail marks the job failed
+func (store *memoryStore) Fail(ctx context.Context, jobID string, lastError string, expiresAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, runningError := store.running(jobID)
+	if runningError != nil {
+		return runningError
+	}
+	stored.Status, stored.LastError, stored.ExpiresAt = models.JobStatusFailed, lastError, &expiresAt
+	return nil
+}
+
+// Cancel marks an unfinished job cancelled
+func (store *memoryStore) Cancel(ctx context.Context, jobID string, expiresAt time.Time) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.jobs[jobID]
+	if !exists || stored.Status.Finished() {
+		return sql.ErrNoRows
+	}
+	stored.Status, stored.Payload, stored.ExpiresAt = models.JobStatusCancelled, nil, &expiresAt
+	return nil
+}
+
+// Discard deletes a finished job
+func (store *memoryStore) Discard(ctx context.Context, jobID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	stored, exists := store.jobs[jobID]
+	if !exists || !stored.Status.Finished() {
+		return sql.ErrNoRows
+	}
+	delete(store.jobs, jobID)
+	return nil
+}
+
+// DeleteExpired removes expired jobs
+func (store *memoryStore) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	var deleted int64
+	for jobID, stored := range store.jobs {
+		if stored.ExpiresAt != nil && !stored.ExpiresAt.After(before) {
+			delete(store.jobs, jobID)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// newTestQueue creates a queue over a memory store with a fixed clock and no jitter
+func newTestQueue(policy Policy) (*Queue, *memoryStore, time.Time) {
+	store := newMemoryStore()
+	queue := NewQueue(store, policy)
+	now := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	queue.now = func() time.Time { return now }
+	queue.random = func() float64 { return 0.5 }
+	return queue, store, now
+}
+
+// enqueue queues a job of kind "test" or fails the test
+func enqueue(t *testing.T, queue *Queue, maxAttempts int) *models.Job {
+	t.Helper()
+	job, enqueueError := queue.Enqueue(context.Background(), "test", []byte("payload"), maxAttempts)
+	if enqueueError != nil {
+		t.Fatalf("Unexpected enqueue error: %v", enqueueError)
+	}
+	return job
+}
+
+// TestPolicy_Backoff verifies the delay doubles per failure, is capped, and is spread by jitter
+func TestPolicy_Backoff(t *testing.T) {
+	policy := Policy{BaseDelay: time.Second, MaxDelay: 10 * time.Second, Jitter: 0.2}
+
+	testCases := []struct {
+		failedAttempts int
+		random         float64
+		expected       time.Duration
+	}{
+		{1, 0.5, time.Second},
+		{2, 0.5, 2 * time.Second},
+		{3, 0.5, 4 * time.Second},
+		{10, 0.5, 10 * time.Second},
+		{1, 0, 800 * time.Millisecond},
+		{1, 1, 1200 * time.Millisecond},
+		{0, 0.5, time.Second},
+	}
+
+	for _, testCase := range testCases {
+		delay := policy.Backoff(testCase.failedAttempts, testCase.random)
+		if delay != testCase.expected {
+			t.Errorf("Backoff(%d, %v): expected %s, got %s", testCase.failedAttempts, testCase.random, testCase.expected, delay)
+		}
+	}
+}
+
+// TestQueue_EnqueueRecordsTheCaller verifies jobs are owned by the caller and default to the policy's attempts
+func TestQueue_EnqueueRecordsTheCaller(t *testing.T) {
+	queue, _, _ := newTestQueue(DefaultPolicy())
+	queue.RegisterHandler("test", HandlerFunc(func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+		return nil, nil
+	}))
+
+	ctx := tenant.WithTenant(auth.WithPrincipal(context.Background(), auth.Principal{ID: "alice"}), "tenant-a")
+	job, enqueueError := queue.Enqueue(ctx, "test", nil, 0)
+
+	if enqueueError != nil {
+		t.Fatalf("Unexpected enqueue error: %v", enqueueError)
+	}
+	if job.PrincipalID != "alice" || job.TenantID != "tenant-a" || job.MaxAttempts != DefaultPolicy().MaxAttempts {
+		t.Errorf("Expected alice's job in tenant-a with the default attempts, got %+v", job)
+	}
+}
+
+// TestQueue_EnqueueUnknownKind verifies kinds without a handler are refused
+func TestQueue_EnqueueUnknownKind(t *testing.T) {
+	queue, _, _ := newTestQueue(DefaultPolicy())
+
+	_, enqueueError := queue.Enqueue(context.Background(), "missing", nil, 1)
+
+	if !errors.Is(enqueueError, ErrUnknownKind) {
+		t.Errorf("Expected ErrUnknownKind, got %v", enqueueError)
+	}
+}
+
+// TestQueue_ProcessNextSucceeds verifies the handler's result is stored and kept for the retention period
+func TestQueue_ProcessNextSucceeds(t *testing.T) {
+	queue, store, now := newTestQueue(DefaultPolicy())
+	queue.RegisterHandler("test", HandlerFunc(func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+		return append([]byte("done:"), job.Payload...), nil
+	}))
+	job := enqueue(t, queue, 0)
+
+	processed, processError := queue.ProcessNext(context.Background())
+
+	if processError != nil || processed == nil || processed.ID != job.ID {
+		t.Fatalf("Expected the job to be processed, got %v (%v)", processed, processError)
+	}
+	stored := store.jobs[job.ID]
+	if stored.Status != models.JobStatusSucceeded || string(stored.Result) != "done:payload" {
+		t.Errorf("Expected a succeeded job with its result, got %s %q", stored.Status, stored.Result)
+	}
+	if stored.ExpiresAt == nil || !stored.ExpiresAt.Equal(now.Add(DefaultPolicy().Retention)) {
+		t.Errorf("Expected the job to expire after the retention period, got %v", stored.ExpiresAt)
+	}
+
+	if processed, _ := queue.ProcessNext(context.Background()); processed != nil {
+		t.Errorf("Expected nothing left to process, got %+v", processed)
+	}
+}
+
+// TestQueue_ProcessNextRetriesWithBackoff verifies failed attempts are rescheduled until the attempts run out
+func TestQueue_ProcessNextRetriesWithBackoff(t *testing.T) {
+	policy := DefaultPolicy()
+	queue, store, now := newTestQueue(policy)
+	queue.RegisterHandler("test", HandlerFunc(func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+		return nil, errors.New("upstream unavailable")
+	}))
+	job := enqueue(t, queue, 2)
+
+	queue.ProcessNext(context.Background())
+
+	stored := store.jobs[job.ID]
+	if stored.Status != models.JobStatusQueued || stored.LastError != "upstream unavailable" {
+		t.Fatalf("Expected the job to be queued again with its error, got %s %q", stored.Status, stored.LastError)
+	}
+	if !stored.RunAfter.Equal(now.Add(policy.BaseDelay)) {
+		t.Errorf("Expected the retry after %s, got %s", policy.BaseDelay, stored.RunAfter.Sub(now))
+	}
+
+	if processed, _ := queue.ProcessNext(context.Background()); processed != nil {
+		t.Fatal("Expected the retry to wait for its backoff")
+	}
+
+	queue.now = func() time.Time { return stored.RunAfter }
+	queue.ProcessNext(context.Background())
+
+	if stored.Status != models.JobStatusFailed || stored.Attempts != 2 {
+		t.Errorf("Expected the job to fail after 2 attempts, got %s after %d", stored.Status, stored.Attempts)
+	}
+}
+
+// TestQueue_ProcessNextPermanentFailure verifies permanent errors and panics fail the job without retrying
+func TestQueue_ProcessNextPermanentFailure(t *testing.T) {
+	queue, store, _ := newTestQueue(DefaultPolicy())
+	queue.RegisterHandler("test", HandlerFunc(func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+		return nil, Permanent(errors.New("payload is invalid"))
+	}))
+	queue.RegisterHandler("panics", HandlerFunc(func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+		panic("nil map")
+	}))
+	permanentJob := enqueue(t, queue, 0)
+	panickingJob, _ := queue.Enqueue(context.Background(), "panics", nil, 0)
+
+	queue.ProcessNext(context.Background())
+	queue.ProcessNext(context.Background())
+
+	if stored := store.jobs[permanentJob.ID]; stored.Status != models.JobStatusFailed || stored.Attempts != 1 {
+		t.Errorf("Expected the job to fail on its first attempt, got %s after %d", stored.Status, stored.Attempts)
+	}
+	if stored := store.jobs[panickingJob.ID]; stored.Status != models.JobStatusFailed || !strings.Contains(stored.LastError, "nil map") {
+		t.Errorf("Expected the panic to fail the job, got %s %q", stored.Status, stored.LastError)
+	}
+}
+
+// TestQueue_ProcessNextStaleJobOutOfAttempts verifies a job taken over from a stopped worker is not run again
+// once that attempt used up its attempts
+func TestQueue_ProcessNextStaleJobOutOfAttempts(t *testing.T) {
+	queue, store, now := newTestQueue(DefaultPolicy())
+	runs := 0
+	queue.RegisterHandler("test", HandlerFunc(func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+		runs++
+		return nil, nil
+	}))
+	job := enqueue(t, queue, 1)
+	stored := store.jobs[job.ID]
+	stored.Status, stored.Attempts, stored.UpdatedAt = models.JobStatusRunning, 1, now.Add(-time.Hour)
+
+	queue.ProcessNext(context.Background())
+
+	if runs != 0 || stored.Status != models.JobStatusFailed {
+		t.Errorf("Expected the job to fail without running, got %s after %d runs", stored.Status, runs)
+	}
+}
+
+// TestQueue_CancelStopsARunningJob verifies the heartbeat stops a job cancelled while it runs and stores its progress
+func TestQueue_CancelStopsARunningJob(t *testing.T) {
+	policy := DefaultPolicy()
+	policy.HeartbeatInterval = 5 * time.Millisecond
+	queue, store, _ := newTestQueue(policy)
+	started := make(chan string)
+	queue.RegisterHandler("test", HandlerFunc(func(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+		progress("1 of 2")
+		started <- job.ID
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}))
+	enqueue(t, queue, 0)
+
+	processed := make(chan *models.Job)
+	go func() {
+		job, _ := queue.ProcessNext(context.Background())
+		processed <- job
+	}()
+
+	jobID := <-started
+	waitForHeartbeat(t, store)
+	if stored, _ := store.Get(context.Background(), jobID); stored.Progress != "1 of 2" {
+		t.Errorf("Expected the progress to be stored, got %q", stored.Progress)
+	}
+	if cancelError := queue.Cancel(context.Background(), jobID); cancelError != nil {
+		t.Fatalf("Unexpected cancel error: %v", cancelError)
+	}
+
+	select {
+	case <-processed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the cancelled job to stop")
+	}
+	if stored, _ := store.Get(context.Background(), jobID); stored.Status != models.JobStatusCancelled || stored.Payload != nil {
+		t.Errorf("Expected a cancelled job without its payload, got %s", stored.Status)
+	}
+	if cancelError := queue.Cancel(context.Background(), jobID); !errors.Is(cancelError, sql.ErrNoRows) {
+		t.Errorf("Expected a finished job not to be cancelled again, got %v", cancelError)
+	}
+}
+
+// finalizingHandler fails every attempt and records the jobs it hears ended
+type finalizingHandler struct {
+	finalized []*models.Job
+}
+
+// Run fails the attempt
+func (handler *finalizingHandler) Run(ctx context.Context, job *models.Job, progress Progress) ([]byte, error) {
+	return nil, errors.New("upstream unavailable")
+}
+
+// Finalize records the ended job
+func (handler *finalizingHandler) Finalize(ctx context.Context, job *models.Job) {
+	handler.finalized = append(handler.finalized, job)
+}
+
+// TestQueue_Finalizer verifies a handler keeping its own record hears of failures, including those of jobs
+// that never ran, and of cancellations, once each
+func TestQueue_Finalizer(t *testing.T) {
+	queue, store, now := newTestQueue(DefaultPolicy())
+	handler := &finalizingHandler{}
+	queue.RegisterHandler("test", handler)
+
+	failing := enqueue(t, queue, 1)
+	queue.ProcessNext(context.Background())
+
+	stale := enqueue(t, queue, 1)
+	stored := store.jobs[stale.ID]
+	stored.Status, stored.Attempts, stored.UpdatedAt = models.JobStatusRunning, 1, now.Add(-time.Hour)
+	queue.ProcessNext(context.Background())
+
+	queued := enqueue(t, queue, 0)
+	queue.Cancel(context.Background(), queued.ID)
+	queue.Cancel(context.Background(), queued.ID)
+
+	if len(handler.finalized) != 3 {
+		t.Fatalf("Expected 3 finalized jobs, got %d", len(handler.finalized))
+	}
+	if job := handler.finalized[0]; job.ID != failing.ID || job.Status != models.JobStatusFailed || job.LastError != "upstream unavailable" {
+		t.Errorf("Expected the failed job with its error, got %+v", job)
+	}
+	if job := handler.finalized[1]; job.ID != stale.ID || job.Status != models.JobStatusFailed {
+		t.Errorf("Expected the stale job out of attempts to be failed, got %+v", job)
+	}
+	if job := handler.finalized[2]; job.ID != queued.ID || job.Status != models.JobStatusCancelled {
+		t.Errorf("Expected the cancelled job, got %+v", job)
+	}
+}
+
+// waitForHeartbeat waits until the store recorded a heartbeat
+func waitForHeartbeat(t *testing.T, store *memoryStore) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		store.mutex.Lock()
+		heartbeats := store.heartbeats
+		store.mutex.Unlock()
+		if heartbeats > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Expected a heartbeat")
+}
+
+// TestQueue_InvalidIDs verifies IDs that are not UUIDs are reported as missing rather than reaching the store
+func TestQueue_InvalidIDs(t *testing.T) {
+	queue, _, _ := newTestQueue(DefaultPolicy())
+
+	if _, getError := queue.Get(context.Background(), "not-a-uuid"); !errors.Is(getError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows from Get, got %v", getError)
+	}
+	if cancelError := queue.Cancel(context.Background(), "not-a-uuid"); !errors.Is(cancelError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows from Cancel, got %v", cancelError)
+	}
+	if discardError := queue.Discard(context.Background(), "not-a-uuid"); !errors.Is(discardError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows from Discard, got %v", discardError)
+	}
+}
@@ -0,0 +1,130 @@
+package journal
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Outcome is what recovery did with one journaled write
+type Outcome int
+
+const (
+	// OutcomeNone means no journaled write was old enough to recover
+	OutcomeNone Outcome = iota
+
+	// OutcomeCompleted means the write had been applied and its change was recorded
+	OutcomeCompleted
+
+	// OutcomeRolledBack means the write had not been applied and its entry was discarded
+	OutcomeRolledBack
+)
+
+// Recoverer completes or rolls back journaled writes interrupted before their change was recorded
+type Recoverer interface {
+	// RecoverOldest recovers the oldest journaled write made before cutoff
+	RecoverOldest(ctx context.Context, cutoff time.Time) (Outcome, error)
+}
+
+// Report describes one recovery pass
+type Report struct {
+	// Cutoff is the journal time before which writes were treated as interrupted
+	Cutoff     time.Time `json:"cutoff"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// Completed counts applied writes whose change was recorded
+	Completed int `json:"completed"`
+
+	// RolledBack counts writes that were never applied
+	RolledBack int `json:"rolled_back"`
+
+	// Error is set when the pass stopped early
+	Error string `json:"error,omitempty"`
+}
+
+// Job recovers writes journaled by a process that crashed before recording them
+// A write still journaled staleAfter after it was accepted is taken to be interrupted, so staleAfter
+// must exceed the longest write; live writes of other instances are left alone
+type Job struct {
+	recoverer Recoverer
+
+	// staleAfter is how long a journaled write may be in flight before it is recovered
+	staleAfter time.Duration
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewJob creates a recovery job for writes journaled more than staleAfter ago
+func NewJob(recoverer Recoverer, staleAfter time.Duration) *Job {
+	return &Job{
+		recoverer:  recoverer,
+		staleAfter: staleAfter,
+		now:        time.Now,
+	}
+}
+
+// Run recovers every journaled write older than staleAfter, oldest first
+func (job *Job) Run(ctx context.Context) *Report {
+	report := &Report{StartedAt: job.now()}
+	report.Cutoff = report.StartedAt.Add(-job.staleAfter)
+
+	for ctx.Err() == nil {
+		outcome, recoverError := job.recoverer.RecoverOldest(ctx, report.Cutoff)
+		if recoverError != nil {
+			report.Error = recoverError.Error()
+			break
+		}
+		if outcome == OutcomeNone {
+			break
+		}
+		if outcome == OutcomeCompleted {
+			report.Completed++
+		} else {
+			report.RolledBack++
+		}
+	}
+	report.FinishedAt = job.now()
+
+	logReport(report)
+	return report
+}
+
+// RunPeriodically runs the job at startup and then every staleAfter until ctx is cancelled
+// The startup pass recovers what a previous process left; later passes catch writes it left too
+// recently for the startup pass to treat as interrupted
+func (job *Job) RunPeriodically(ctx context.Context) {
+	ticker := time.NewTicker(job.staleAfter)
+	defer ticker.Stop()
+
+	for {
+		// Errors are captured in the report and logged by Run
+		job.Run(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logReport writes a pass summary when it recovered anything, at error level when it failed
+func logReport(report *Report) {
+	if report.Completed == 0 && report.RolledBack == 0 && report.Error == "" {
+		return
+	}
+
+	logEvent := log.Warn()
+	if report.Error != "" {
+		logEvent = log.Error().Str("error", report.Error)
+	}
+	logEvent.
+		Time("cutoff", report.Cutoff).
+		Int("completed", report.Completed).
+		Int("rolled_back", report.RolledBack).
+		Dur("duration", report.FinishedAt.Sub(report.StartedAt)).
+		Msg("Write journal recovery finished")
+}
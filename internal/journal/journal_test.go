@@ -0,0 +1,68 @@
+package journal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeRecoverer returns its outcomes in order, then OutcomeNone, recording each cutoff
+type fakeRecoverer struct {
+	outcomes     []Outcome
+	failAfter    int
+	cutoffs      []time.Time
+	recoverError error
+}
+
+// RecoverOldest returns the next outcome, or recoverError once failAfter calls have been made
+func (recoverer *fakeRecoverer) RecoverOldest(ctx context.Context, cutoff time.Time) (Outcome, error) {
+	recoverer.cutoffs = append(recoverer.cutoffs, cutoff)
+	if recoverer.recoverError != nil && len(recoverer.cutoffs) > recoverer.failAfter {
+		return OutcomeNone, recoverer.recoverError
+	}
+	if len(recoverer.outcomes) == 0 {
+		return OutcomeNone, nil
+	}
+	outcome := recoverer.outcomes[0]
+	recoverer.outcomes = recoverer.outcomes[1:]
+	return outcome, nil
+}
+
+// TestJob_RunRecoversUntilNoneLeft verifies every stale write is recovered with the same cutoff
+func TestJob_RunRecoversUntilNoneLeft(t *testing.T) {
+	recoverer := &fakeRecoverer{outcomes: []Outcome{OutcomeCompleted, OutcomeRolledBack, OutcomeCompleted}}
+	job := NewJob(recoverer, time.Minute)
+	now := time.Date(2024, 6, 1, 4, 0, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	report := job.Run(context.Background())
+
+	if report.Completed != 2 || report.RolledBack != 1 || report.Error != "" {
+		t.Errorf("Expected 2 completed and 1 rolled back, got %+v", report)
+	}
+	expectedCutoff := now.Add(-time.Minute)
+	if len(recoverer.cutoffs) != 4 {
+		t.Fatalf("Expected recovery to stop once nothing was left, got %d calls", len(recoverer.cutoffs))
+	}
+	for _, cutoff := range recoverer.cutoffs {
+		if !cutoff.Equal(expectedCutoff) {
+			t.Errorf("Expected cutoff %v, got %v", expectedCutoff, cutoff)
+		}
+	}
+}
+
+// TestJob_RunStopsOnError verifies a failed recovery ends the pass and is reported
+func TestJob_RunStopsOnError(t *testing.T) {
+	recoverer := &fakeRecoverer{
+		outcomes:     []Outcome{OutcomeCompleted, OutcomeCompleted},
+		failAfter:    1,
+		recoverError: errors.New("database unavailable"),
+	}
+
+	report := NewJob(recoverer, time.Minute).Run(context.Background())
+
+	if report.Completed != 1 || report.Error != "database unavailable" || len(recoverer.cutoffs) != 2 {
+		t.Errorf("Expected one completed write and the error, got %+v after %d calls", report, len(recoverer.cutoffs))
+	}
+}
@@ -0,0 +1,141 @@
+package loadtest
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// Recorder collects request latencies per operation; it is safe for concurrent use
+type Recorder struct {
+	mutex      sync.Mutex
+	operations map[string]*operationSamples
+}
+
+// operationSamples holds the raw measurements for one operation
+type operationSamples struct {
+	latencies []time.Duration
+	errors    int
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{
+		operations: make(map[string]*operationSamples),
+	}
+}
+
+// Record adds one request's latency; failed requests are counted separately and excluded from the percentiles
+func (recorder *Recorder) Record(operation string, latency time.Duration, failed bool) {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	samples, exists := recorder.operations[operation]
+	if !exists {
+		samples = &operationSamples{}
+		recorder.operations[operation] = samples
+	}
+	if failed {
+		samples.errors++
+		return
+	}
+	samples.latencies = append(samples.latencies, latency)
+}
+
+// OperationStats summarizes the latencies of one operation
+type OperationStats struct {
+	Operation  string        `json:"operation"`
+	Requests   int           `json:"requests"`
+	Errors     int           `json:"errors"`
+	Throughput float64       `json:"throughput_per_second"`
+	P50        time.Duration `json:"p50_ns"`
+	P90        time.Duration `json:"p90_ns"`
+	P95        time.Duration `json:"p95_ns"`
+	P99        time.Duration `json:"p99_ns"`
+	Max        time.Duration `json:"max_ns"`
+}
+
+// Report is the outcome of a load test run
+type Report struct {
+	Elapsed    time.Duration    `json:"elapsed_ns"`
+	Operations []OperationStats `json:"operations"`
+}
+
+// Report summarizes everything recorded so far over the elapsed run time, sorted by operation name
+func (recorder *Recorder) Report(elapsed time.Duration) Report {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	report := Report{Elapsed: elapsed, Operations: []OperationStats{}}
+	for operation, samples := range recorder.operations {
+		sorted := append([]time.Duration{}, samples.latencies...)
+		sort.Slice(sorted, func(left, right int) bool { return sorted[left] < sorted[right] })
+
+		stats := OperationStats{
+			Operation: operation,
+			Requests:  len(sorted) + samples.errors,
+			Errors:    samples.errors,
+			P50:       Percentile(sorted, 50),
+			P90:       Percentile(sorted, 90),
+			P95:       Percentile(sorted, 95),
+			P99:       Percentile(sorted, 99),
+		}
+		if len(sorted) > 0 {
+			stats.Max = sorted[len(sorted)-1]
+		}
+		if elapsed > 0 {
+			stats.Throughput = float64(stats.Requests) / elapsed.Seconds()
+		}
+		report.Operations = append(report.Operations, stats)
+	}
+
+	sort.Slice(report.Operations, func(left, right int) bool {
+		return report.Operations[left].Operation < report.Operations[right].Operation
+	})
+	return report
+}
+
+// Percentile returns the nearest-rank percentile of ascending latencies, or zero when there are none
+func Percentile(sorted []time.Duration, percentile float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(percentile / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// WriteText prints the report as an aligned table
+func (report Report) WriteText(writer io.Writer) error {
+	table := tabwriter.NewWriter(writer, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(table, "operation\trequests\terrors\treq/s\tp50\tp90\tp95\tp99\tmax\t")
+
+	totalRequests, totalErrors := 0, 0
+	for _, stats := range report.Operations {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%.1f\t%s\t%s\t%s\t%s\t%s\t\n",
+			stats.Operation, stats.Requests, stats.Errors, stats.Throughput,
+			formatLatency(stats.P50), formatLatency(stats.P90), formatLatency(stats.P95), formatLatency(stats.P99), formatLatency(stats.Max))
+		totalRequests += stats.Requests
+		totalErrors += stats.Errors
+	}
+	if flushError := table.Flush(); flushError != nil {
+		return flushError
+	}
+
+	_, writeError := fmt.Fprintf(writer, "\n%d requests, %d errors in %s\n", totalRequests, totalErrors, report.Elapsed.Round(time.Millisecond))
+	return writeError
+}
+
+// formatLatency rounds a latency for display
+func formatLatency(latency time.Duration) string {
+	if latency >= time.Second {
+		return latency.Round(time.Millisecond).String()
+	}
+	return latency.Round(10 * time.Microsecond).String()
+}
@@ -0,0 +1,67 @@
+package loadtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPercentile verifies nearest-rank percentiles over sorted latencies
+func TestPercentile(t *testing.T) {
+	latencies := make([]time.Duration, 100)
+	for index := range latencies {
+		latencies[index] = time.Duration(index+1) * time.Millisecond
+	}
+
+	testCases := []struct {
+		percentile float64
+		expected   time.Duration
+	}{
+		{50, 50 * time.Millisecond},
+		{90, 90 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+		{0, 1 * time.Millisecond},
+	}
+	for _, testCase := range testCases {
+		if actual := Percentile(latencies, testCase.percentile); actual != testCase.expected {
+			t.Errorf("Expected p%v to be %s, got %s", testCase.percentile, testCase.expected, actual)
+		}
+	}
+
+	if Percentile(nil, 50) != 0 {
+		t.Error("Expected zero percentile with no samples")
+	}
+}
+
+// TestRecorder_Report verifies errors are counted but excluded from latency percentiles
+func TestRecorder_Report(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Record("search", 10*time.Millisecond, false)
+	recorder.Record("search", 30*time.Millisecond, false)
+	recorder.Record("search", 5*time.Second, true)
+	recorder.Record("create-patient", 20*time.Millisecond, false)
+
+	report := recorder.Report(2 * time.Second)
+	if len(report.Operations) != 2 || report.Operations[0].Operation != "create-patient" {
+		t.Fatalf("Expected two operations sorted by name, got %+v", report.Operations)
+	}
+
+	search := report.Operations[1]
+	if search.Requests != 3 || search.Errors != 1 {
+		t.Errorf("Expected 3 requests and 1 error, got %d and %d", search.Requests, search.Errors)
+	}
+	if search.Max != 30*time.Millisecond || search.P50 != 10*time.Millisecond {
+		t.Errorf("Expected failed request excluded from latencies, got p50 %s max %s", search.P50, search.Max)
+	}
+	if search.Throughput != 1.5 {
+		t.Errorf("Expected 1.5 requests per second, got %v", search.Throughput)
+	}
+
+	var output bytes.Buffer
+	report.WriteText(&output)
+	if !strings.Contains(output.String(), "4 requests, 1 errors in 2s") {
+		t.Errorf("Expected totals line, got:\n%s", output.String())
+	}
+}
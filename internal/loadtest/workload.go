@@ -0,0 +1,366 @@
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// Workload steps a worker can pick
+const (
+	// StepCreatePatient registers a new patient
+	StepCreatePatient = "create-patient"
+
+	// StepPostVitals posts a batch of vital-sign observations for a known patient
+	StepPostVitals = "post-vitals"
+
+	// StepSearch runs one of the common patient and observation searches
+	StepSearch = "search"
+)
+
+// Mix weights the workload steps; a step with weight 3 is picked three times as often as one with weight 1
+type Mix map[string]int
+
+// DefaultMix is a read-heavy clinic workload
+func DefaultMix() Mix {
+	return Mix{StepCreatePatient: 1, StepPostVitals: 3, StepSearch: 6}
+}
+
+// ParseMix parses "create-patient=1,post-vitals=3,search=6"; omitted steps are not run
+func ParseMix(rawMix string) (Mix, error) {
+	mix := Mix{}
+	for _, entry := range strings.Split(rawMix, ",") {
+		step, rawWeight, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			return nil, fmt.Errorf("mix entry %q must be step=weight", entry)
+		}
+		if step != StepCreatePatient && step != StepPostVitals && step != StepSearch {
+			return nil, fmt.Errorf("unknown workload step %q", step)
+		}
+		weight, parseError := strconv.Atoi(rawWeight)
+		if parseError != nil || weight < 0 {
+			return nil, fmt.Errorf("weight for %s must be a non-negative integer", step)
+		}
+		mix[step] = weight
+	}
+
+	totalWeight := 0
+	for _, weight := range mix {
+		totalWeight += weight
+	}
+	if totalWeight == 0 {
+		return nil, errors.New("mix must give at least one step a positive weight")
+	}
+	return mix, nil
+}
+
+// pick chooses a step in proportion to its weight
+func (mix Mix) pick(random *rand.Rand) string {
+	steps := make([]string, 0, len(mix))
+	totalWeight := 0
+	for step, weight := range mix {
+		steps = append(steps, step)
+		totalWeight += weight
+	}
+	// Map iteration order is random; sort so a seed always produces the same sequence
+	sort.Strings(steps)
+
+	roll := random.IntN(totalWeight)
+	for _, step := range steps {
+		if roll < mix[step] {
+			return step
+		}
+		roll -= mix[step]
+	}
+	return steps[len(steps)-1]
+}
+
+// Config describes a load test run
+type Config struct {
+	// TargetURL is the server's base URL, such as http://localhost:8080
+	TargetURL string
+
+	// Concurrency is the number of workers issuing requests back to back
+	Concurrency int
+
+	// Duration is how long the workers run after setup
+	Duration time.Duration
+
+	Mix Mix
+
+	// VitalsBatchSize is how many observations a post-vitals step posts
+	VitalsBatchSize int
+
+	// SeedPatients are created before the run so vitals and patient searches have targets
+	SeedPatients int
+
+	// APIKey is sent in the X-API-Key header when set
+	APIKey string
+
+	// Seed makes the generated data and step sequence repeatable
+	Seed uint64
+
+	// RequestTimeout bounds each request
+	RequestTimeout time.Duration
+}
+
+// DefaultConfig runs 8 workers for 30 seconds against a local server
+func DefaultConfig() Config {
+	return Config{
+		TargetURL:       "http://localhost:8080",
+		Concurrency:     8,
+		Duration:        30 * time.Second,
+		Mix:             DefaultMix(),
+		VitalsBatchSize: 5,
+		SeedPatients:    20,
+		Seed:            42,
+		RequestTimeout:  10 * time.Second,
+	}
+}
+
+// Value pools used to generate realistic, repeatable data
+var (
+	loadFamilyNames = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Nguyen", "Lopez"}
+	loadGivenNames  = []string{"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "David", "Susan"}
+	loadGenders     = []string{"male", "female", "other", "unknown"}
+)
+
+// vitalSign is a LOINC-coded vital sign with a plausible value range
+type vitalSign struct {
+	code     string
+	display  string
+	unit     string
+	minValue float64
+	maxValue float64
+}
+
+// vitalSigns are posted in order; a batch larger than the list wraps around
+var vitalSigns = []vitalSign{
+	{"8867-4", "Heart rate", "/min", 50, 120},
+	{"8480-6", "Systolic blood pressure", "mm[Hg]", 95, 170},
+	{"8462-4", "Diastolic blood pressure", "mm[Hg]", 55, 105},
+	{"8310-5", "Body temperature", "Cel", 36, 39.5},
+	{"9279-1", "Respiratory rate", "/min", 10, 26},
+	{"59408-5", "Oxygen saturation", "%", 88, 100},
+}
+
+// Runner drives a mixed workload against a server and records request latencies
+type Runner struct {
+	config   Config
+	client   *http.Client
+	recorder *Recorder
+
+	patientMutex sync.Mutex
+	patientIDs   []string
+}
+
+// NewRunner creates a runner for the given configuration
+func NewRunner(config Config) *Runner {
+	return &Runner{
+		config:   config,
+		client:   &http.Client{Timeout: config.RequestTimeout},
+		recorder: NewRecorder(),
+	}
+}
+
+// Run seeds patients, then runs the workers until the duration elapses or ctx is cancelled
+// Setup requests are not measured; setup failing means the target is unusable and is returned as an error
+func (runner *Runner) Run(ctx context.Context) (Report, error) {
+	setupRandom := rand.New(rand.NewPCG(runner.config.Seed, 0))
+	for index := 0; index < runner.config.SeedPatients; index++ {
+		if _, createError := runner.createPatient(ctx, setupRandom); createError != nil {
+			return Report{}, fmt.Errorf("failed to seed patients: %w", createError)
+		}
+	}
+
+	runContext, cancel := context.WithTimeout(ctx, runner.config.Duration)
+	defer cancel()
+
+	started := time.Now()
+	var workers sync.WaitGroup
+	for workerIndex := 0; workerIndex < runner.config.Concurrency; workerIndex++ {
+		workers.Add(1)
+		go func(workerIndex int) {
+			defer workers.Done()
+			runner.work(runContext, rand.New(rand.NewPCG(runner.config.Seed, uint64(workerIndex+1))))
+		}(workerIndex)
+	}
+	workers.Wait()
+
+	return runner.recorder.Report(time.Since(started)), nil
+}
+
+// work runs randomly picked steps until ctx is done
+func (runner *Runner) work(ctx context.Context, random *rand.Rand) {
+	for ctx.Err() == nil {
+		switch runner.config.Mix.pick(random) {
+		case StepCreatePatient:
+			runner.measure(ctx, StepCreatePatient, func() error {
+				_, createError := runner.createPatient(ctx, random)
+				return createError
+			})
+		case StepPostVitals:
+			patientID := runner.randomPatientID(random)
+			for index := 0; index < runner.config.VitalsBatchSize; index++ {
+				vital := vitalSigns[index%len(vitalSigns)]
+				runner.measure(ctx, StepPostVitals, func() error {
+					return runner.postVital(ctx, random, patientID, vital)
+				})
+			}
+		case StepSearch:
+			name, path := runner.randomSearch(random)
+			runner.measure(ctx, StepSearch+":"+name, func() error {
+				return runner.send(ctx, http.MethodGet, path, nil, nil)
+			})
+		}
+	}
+}
+
+// measure times one request; requests cut off by the end of the run are not recorded
+func (runner *Runner) measure(ctx context.Context, operation string, request func() error) {
+	started := time.Now()
+	requestError := request()
+	if ctx.Err() != nil {
+		return
+	}
+	runner.recorder.Record(operation, time.Since(started), requestError != nil)
+}
+
+// createPatient posts a generated patient and remembers its ID
+func (runner *Runner) createPatient(ctx context.Context, random *rand.Rand) (string, error) {
+	birthDate := time.Date(1940+random.IntN(65), time.Month(1+random.IntN(12)), 1+random.IntN(28), 0, 0, 0, 0, time.UTC)
+	patient := map[string]interface{}{
+		"resourceType": "Patient",
+		"identifier":   []map[string]string{{"system": "urn:loadtest:mrn", "value": fmt.Sprintf("LT-%010d", random.Uint32())}},
+		"active":       true,
+		"name": []map[string]interface{}{{
+			"family": loadFamilyNames[random.IntN(len(loadFamilyNames))],
+			"given":  []string{loadGivenNames[random.IntN(len(loadGivenNames))]},
+		}},
+		"gender":    loadGenders[random.IntN(len(loadGenders))],
+		"birthDate": birthDate.Format("2006-01-02"),
+	}
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if sendError := runner.send(ctx, http.MethodPost, "/fhir/Patient", patient, &created); sendError != nil {
+		return "", sendError
+	}
+	if created.ID == "" {
+		return "", errors.New("created patient has no id")
+	}
+
+	runner.patientMutex.Lock()
+	runner.patientIDs = append(runner.patientIDs, created.ID)
+	runner.patientMutex.Unlock()
+	return created.ID, nil
+}
+
+// postVital posts one vital-sign observation for the patient
+func (runner *Runner) postVital(ctx context.Context, random *rand.Rand, patientID string, vital vitalSign) error {
+	value := vital.minValue + random.Float64()*(vital.maxValue-vital.minValue)
+	observation := map[string]interface{}{
+		"resourceType": "Observation",
+		"status":       "final",
+		"category": []map[string]interface{}{{
+			"coding": []map[string]string{{"system": "http://terminology.hl7.org/CodeSystem/observation-category", "code": "vital-signs"}},
+		}},
+		"code": map[string]interface{}{
+			"coding": []map[string]string{{"system": "http://loinc.org", "code": vital.code, "display": vital.display}},
+		},
+		"subject":           map[string]string{"reference": "Patient/" + patientID},
+		"effectiveDateTime": time.Now().UTC().Format(time.RFC3339),
+		"valueQuantity": map[string]interface{}{
+			"value":  float64(int(value*10)) / 10,
+			"unit":   vital.unit,
+			"system": "http://unitsofmeasure.org",
+			"code":   vital.unit,
+		},
+	}
+	return runner.send(ctx, http.MethodPost, "/fhir/Observation", observation, nil)
+}
+
+// randomPatientID returns a patient created by this run
+func (runner *Runner) randomPatientID(random *rand.Rand) string {
+	runner.patientMutex.Lock()
+	defer runner.patientMutex.Unlock()
+	if len(runner.patientIDs) == 0 {
+		return ""
+	}
+	return runner.patientIDs[random.IntN(len(runner.patientIDs))]
+}
+
+// randomSearch returns the name and path of one of the common searches
+func (runner *Runner) randomSearch(random *rand.Rand) (string, string) {
+	patientID := url.QueryEscape(runner.randomPatientID(random))
+	vital := vitalSigns[random.IntN(len(vitalSigns))]
+
+	switch random.IntN(5) {
+	case 0:
+		return "patient-name", "/fhir/Patient?name=" + loadFamilyNames[random.IntN(len(loadFamilyNames))] + "&_count=20"
+	case 1:
+		return "patient-gender-birthdate", "/fhir/Patient?gender=" + loadGenders[random.IntN(len(loadGenders))] + "&birthdate=ge1980-01-01&_count=20"
+	case 2:
+		return "observation-patient", "/fhir/Observation?patient=" + patientID
+	case 3:
+		return "observation-patient-code", "/fhir/Observation?patient=" + patientID + "&code=" + vital.code
+	default:
+		since := time.Now().UTC().Add(-24 * time.Hour).Format("2006-01-02")
+		return "observation-category-date", "/fhir/Observation?category=vital-signs&date=ge" + since + "&_count=20"
+	}
+}
+
+// send issues a request and decodes a JSON response into result when it is not nil
+// Any status outside 2xx is an error
+func (runner *Runner) send(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var requestBody io.Reader
+	if body != nil {
+		encodedBody, encodeError := json.Marshal(body)
+		if encodeError != nil {
+			return encodeError
+		}
+		requestBody = bytes.NewReader(encodedBody)
+	}
+
+	request, requestError := http.NewRequestWithContext(ctx, method, strings.TrimRight(runner.config.TargetURL, "/")+path, requestBody)
+	if requestError != nil {
+		return requestError
+	}
+	if body != nil {
+		request.Header.Set("Content-Type", "application/fhir+json")
+	}
+	if runner.config.APIKey != "" {
+		request.Header.Set(tenant.HeaderAPIKey, runner.config.APIKey)
+	}
+
+	response, sendError := runner.client.Do(request)
+	if sendError != nil {
+		return sendError
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		io.Copy(io.Discard, response.Body)
+		return fmt.Errorf("%s %s returned %d", method, path, response.StatusCode)
+	}
+	if result == nil {
+		// Read the body so the connection is reused and the timing includes the full response
+		_, readError := io.Copy(io.Discard, response.Body)
+		return readError
+	}
+	return json.NewDecoder(response.Body).Decode(result)
+}
@@ -0,0 +1,114 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestParseMix verifies mix parsing and validation
+func TestParseMix(t *testing.T) {
+	mix, parseError := ParseMix("create-patient=1, search=4")
+	if parseError != nil {
+		t.Fatalf("Expected mix to parse, got %v", parseError)
+	}
+	if mix[StepCreatePatient] != 1 || mix[StepSearch] != 4 || mix[StepPostVitals] != 0 {
+		t.Errorf("Unexpected mix %v", mix)
+	}
+
+	for _, invalidMix := range []string{"search", "delete=1", "search=-1", "search=x", "search=0"} {
+		if _, invalidError := ParseMix(invalidMix); invalidError == nil {
+			t.Errorf("Expected %q to be rejected", invalidMix)
+		}
+	}
+}
+
+// TestRunner_Run verifies the runner seeds patients, exercises every step, and reports latencies
+func TestRunner_Run(t *testing.T) {
+	var mutex sync.Mutex
+	requestsByPath := map[string]int{}
+	var apiKeys []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requestsByPath[r.Method+" "+r.URL.Path]++
+		apiKeys = append(apiKeys, r.Header.Get("X-API-Key"))
+		mutex.Unlock()
+
+		if r.Method == http.MethodPost {
+			var resource map[string]interface{}
+			json.NewDecoder(r.Body).Decode(&resource)
+			if r.URL.Path == "/fhir/Observation" && !strings.HasPrefix(resource["subject"].(map[string]interface{})["reference"].(string), "Patient/patient-") {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"id": "patient-1"})
+			return
+		}
+		w.Write([]byte(`{"resourceType":"Bundle"}`))
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.TargetURL = server.URL
+	config.Concurrency = 4
+	config.Duration = 200 * time.Millisecond
+	config.SeedPatients = 3
+	config.APIKey = "key-1"
+
+	report, runError := NewRunner(config).Run(context.Background())
+	if runError != nil {
+		t.Fatalf("Expected run to succeed, got %v", runError)
+	}
+
+	operations := map[string]OperationStats{}
+	for _, stats := range report.Operations {
+		operations[stats.Operation] = stats
+		if stats.Errors != 0 {
+			t.Errorf("Expected no errors for %s, got %d", stats.Operation, stats.Errors)
+		}
+	}
+	if operations[StepCreatePatient].Requests == 0 || operations[StepPostVitals].Requests == 0 {
+		t.Errorf("Expected creates and vitals to be recorded, got %+v", report.Operations)
+	}
+	searchRecorded := false
+	for operation := range operations {
+		if strings.HasPrefix(operation, StepSearch+":") {
+			searchRecorded = true
+		}
+	}
+	if !searchRecorded {
+		t.Errorf("Expected searches to be recorded per query, got %+v", report.Operations)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if requestsByPath["POST /fhir/Patient"] < 3 {
+		t.Errorf("Expected seed patients to be created, got %d creates", requestsByPath["POST /fhir/Patient"])
+	}
+	for _, apiKey := range apiKeys {
+		if apiKey != "key-1" {
+			t.Fatalf("Expected API key on every request, got %q", apiKey)
+		}
+	}
+}
+
+// TestRunner_RunUnreachable verifies an unreachable target fails during setup
+func TestRunner_RunUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.Close()
+
+	config := DefaultConfig()
+	config.TargetURL = server.URL
+	config.Duration = 50 * time.Millisecond
+
+	if _, runError := NewRunner(config).Run(context.Background()); runError == nil {
+		t.Error("Expected an error when the target is unreachable")
+	}
+}
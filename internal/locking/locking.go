@@ -0,0 +1,134 @@
+package locking
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// Lock durations applied when acquiring
+const (
+	DefaultTTL = 5 * time.Minute
+	MaxTTL     = 30 * time.Minute
+)
+
+// Headers used to surface and claim locks over HTTP
+const (
+	// HeaderLockOwner carries the current holder on reads and the caller's identity on writes
+	HeaderLockOwner = "X-Edit-Lock-Owner"
+
+	// HeaderLockExpires carries the lock expiry (RFC 3339) on reads
+	HeaderLockExpires = "X-Edit-Lock-Expires"
+)
+
+var (
+	// ErrLockHeld is returned when another owner holds an unexpired lock
+	ErrLockHeld = errors.New("resource is locked by another owner")
+
+	// ErrNotLocked is returned when releasing a resource that has no active lock
+	ErrNotLocked = errors.New("resource is not locked")
+
+	// ErrOwnerRequired is returned when acquiring or releasing without an owner
+	ErrOwnerRequired = errors.New("lock owner is required")
+)
+
+// Store keeps edit locks
+// MemoryStore holds them in this process; the PostgreSQL edit lock repository shares them between servers
+type Store interface {
+	// Acquire stores lock unless another owner holds an unexpired lock on the resource at lock.AcquiredAt,
+	// returning the stored lock, or the other owner's lock and false. A lock the owner already holds is
+	// renewed to lock.ExpiresAt and keeps its acquisition time
+	Acquire(ctx context.Context, lock *models.EditLock) (*models.EditLock, bool, error)
+
+	// Delete removes the resource's unexpired lock at now when owner holds it, or whoever holds it when force,
+	// returning the lock it found, nil when there is none, and whether it was removed
+	Delete(ctx context.Context, resourceType string, resourceID string, owner string, force bool, now time.Time) (*models.EditLock, bool, error)
+
+	// Get returns the resource's unexpired lock at now, or nil when it is unlocked
+	Get(ctx context.Context, resourceType string, resourceID string, now time.Time) (*models.EditLock, error)
+}
+
+// Manager applies the lock rules over a Store
+type Manager struct {
+	store Store
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewManager creates a lock manager over store
+func NewManager(store Store) *Manager {
+	return &Manager{
+		store: store,
+		now:   time.Now,
+	}
+}
+
+// Acquire takes or renews the lock for owner; ttl is clamped to (0, MaxTTL] with DefaultTTL when unset
+// When another owner holds an unexpired lock, ErrLockHeld is returned together with that lock
+func (manager *Manager) Acquire(ctx context.Context, resourceType string, resourceID string, owner string, ttl time.Duration) (*models.EditLock, error) {
+	if owner == "" {
+		return nil, ErrOwnerRequired
+	}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if ttl > MaxTTL {
+		ttl = MaxTTL
+	}
+
+	now := manager.now()
+	storedLock, acquired, acquireError := manager.store.Acquire(ctx, &models.EditLock{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Owner:        owner,
+		AcquiredAt:   now,
+		ExpiresAt:    now.Add(ttl),
+	})
+	if acquireError != nil {
+		return nil, acquireError
+	}
+	if !acquired {
+		return storedLock, ErrLockHeld
+	}
+	return storedLock, nil
+}
+
+// Release drops the lock held by owner; force releases a lock held by anyone
+// A non-forced release by another owner returns ErrLockHeld with the current lock
+func (manager *Manager) Release(ctx context.Context, resourceType string, resourceID string, owner string, force bool) (*models.EditLock, error) {
+	if owner == "" && !force {
+		return nil, ErrOwnerRequired
+	}
+
+	existingLock, released, deleteError := manager.store.Delete(ctx, resourceType, resourceID, owner, force, manager.now())
+	switch {
+	case deleteError != nil:
+		return nil, deleteError
+	case existingLock == nil:
+		return nil, ErrNotLocked
+	case !released:
+		return existingLock, ErrLockHeld
+	}
+	return existingLock, nil
+}
+
+// Get returns the active lock on a resource, or nil when it is unlocked
+func (manager *Manager) Get(ctx context.Context, resourceType string, resourceID string) (*models.EditLock, error) {
+	return manager.store.Get(ctx, resourceType, resourceID, manager.now())
+}
+
+// CheckWrite reports whether owner may write the resource: true when unlocked or locked by owner
+// It returns the conflicting lock when another owner holds it
+func (manager *Manager) CheckWrite(ctx context.Context, resourceType string, resourceID string, owner string) (*models.EditLock, bool, error) {
+	activeLock, getError := manager.Get(ctx, resourceType, resourceID)
+	if getError != nil {
+		return nil, false, getError
+	}
+	if activeLock == nil || activeLock.Owner == owner {
+		return activeLock, true, nil
+	}
+	return activeLock, false, nil
+}
@@ -0,0 +1,110 @@
+package locking
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestManager returns a manager with a controllable clock
+func newTestManager() (*Manager, *time.Time) {
+	currentTime := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	manager := NewManager(NewMemoryStore())
+	manager.now = func() time.Time { return currentTime }
+	return manager, &currentTime
+}
+
+// TestManager_AcquireConflictAndRenew verifies a held lock blocks other owners and renews for its owner
+func TestManager_AcquireConflictAndRenew(t *testing.T) {
+	manager, currentTime := newTestManager()
+	ctx := context.Background()
+
+	firstLock, acquireError := manager.Acquire(ctx, "Patient", "p1", "clerk-a", 0)
+	if acquireError != nil || firstLock.ExpiresAt != currentTime.Add(DefaultTTL) {
+		t.Fatalf("Expected default TTL lock, got %+v, %v", firstLock, acquireError)
+	}
+
+	heldLock, conflictError := manager.Acquire(ctx, "Patient", "p1", "clerk-b", time.Minute)
+	if !errors.Is(conflictError, ErrLockHeld) || heldLock.Owner != "clerk-a" {
+		t.Errorf("Expected ErrLockHeld with clerk-a's lock, got %+v, %v", heldLock, conflictError)
+	}
+
+	*currentTime = currentTime.Add(time.Minute)
+	renewedLock, _ := manager.Acquire(ctx, "Patient", "p1", "clerk-a", time.Hour)
+	if renewedLock.AcquiredAt != firstLock.AcquiredAt || renewedLock.ExpiresAt != currentTime.Add(MaxTTL) {
+		t.Errorf("Expected renewal capped at MaxTTL keeping acquisition time, got %+v", renewedLock)
+	}
+}
+
+// TestManager_Expiry verifies expired locks disappear and can be taken by others
+func TestManager_Expiry(t *testing.T) {
+	manager, currentTime := newTestManager()
+	ctx := context.Background()
+	manager.Acquire(ctx, "Patient", "p1", "clerk-a", time.Minute)
+
+	*currentTime = currentTime.Add(time.Minute)
+	if expiredLock, _ := manager.Get(ctx, "Patient", "p1"); expiredLock != nil {
+		t.Error("Expected lock to expire")
+	}
+	if _, acquireError := manager.Acquire(ctx, "Patient", "p1", "clerk-b", 0); acquireError != nil {
+		t.Errorf("Expected expired lock to be acquirable, got %v", acquireError)
+	}
+}
+
+// TestManager_Release verifies owner, non-owner, and forced releases
+func TestManager_Release(t *testing.T) {
+	manager, _ := newTestManager()
+	ctx := context.Background()
+	manager.Acquire(ctx, "Patient", "p1", "clerk-a", 0)
+
+	if _, releaseError := manager.Release(ctx, "Patient", "p1", "clerk-b", false); !errors.Is(releaseError, ErrLockHeld) {
+		t.Errorf("Expected ErrLockHeld for non-owner, got %v", releaseError)
+	}
+	if releasedLock, releaseError := manager.Release(ctx, "Patient", "p1", "supervisor", true); releaseError != nil || releasedLock.Owner != "clerk-a" {
+		t.Errorf("Expected forced release of clerk-a's lock, got %+v, %v", releasedLock, releaseError)
+	}
+	if _, releaseError := manager.Release(ctx, "Patient", "p1", "clerk-a", false); !errors.Is(releaseError, ErrNotLocked) {
+		t.Errorf("Expected ErrNotLocked after release, got %v", releaseError)
+	}
+	if _, acquireError := manager.Acquire(ctx, "Patient", "p1", "", 0); !errors.Is(acquireError, ErrOwnerRequired) {
+		t.Errorf("Expected ErrOwnerRequired, got %v", acquireError)
+	}
+}
+
+// TestManager_CheckWrite verifies only the holder may write a locked resource
+func TestManager_CheckWrite(t *testing.T) {
+	manager, _ := newTestManager()
+	ctx := context.Background()
+
+	if _, allowed, _ := manager.CheckWrite(ctx, "Patient", "p1", "clerk-b"); !allowed {
+		t.Error("Expected unlocked resource to be writable")
+	}
+
+	manager.Acquire(ctx, "Patient", "p1", "clerk-a", 0)
+	if _, allowed, _ := manager.CheckWrite(ctx, "Patient", "p1", "clerk-a"); !allowed {
+		t.Error("Expected holder to be allowed")
+	}
+	if activeLock, allowed, _ := manager.CheckWrite(ctx, "Patient", "p1", "clerk-b"); allowed || activeLock.Owner != "clerk-a" {
+		t.Error("Expected other owner to be refused")
+	}
+}
+
+// TestManager_SharedStore verifies managers on different servers see each other's locks through a shared store
+func TestManager_SharedStore(t *testing.T) {
+	sharedStore := NewMemoryStore()
+	firstServer := NewManager(sharedStore)
+	secondServer := NewManager(sharedStore)
+	ctx := context.Background()
+
+	firstServer.Acquire(ctx, "Patient", "p1", "clerk-a", 0)
+	if heldLock, acquireError := secondServer.Acquire(ctx, "Patient", "p1", "clerk-b", 0); !errors.Is(acquireError, ErrLockHeld) || heldLock.Owner != "clerk-a" {
+		t.Errorf("Expected the other server to refuse clerk-b, got %+v, %v", heldLock, acquireError)
+	}
+	if _, releaseError := secondServer.Release(ctx, "Patient", "p1", "clerk-a", false); releaseError != nil {
+		t.Errorf("Expected clerk-a to release through the other server, got %v", releaseError)
+	}
+	if activeLock, _ := firstServer.Get(ctx, "Patient", "p1"); activeLock != nil {
+		t.Errorf("Expected the release to be seen by the first server, got %+v", activeLock)
+	}
+}
@@ -0,0 +1,99 @@
+package locking
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// MemoryStore keeps edit locks in this process, for tests and single-server deployments
+// Locks are short-lived and expire on their own, so losing them on restart only ends edits early
+type MemoryStore struct {
+	mutex sync.Mutex
+	locks map[string]*models.EditLock
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		locks: make(map[string]*models.EditLock),
+	}
+}
+
+// Acquire stores or renews the lock unless another owner holds it
+func (store *MemoryStore) Acquire(ctx context.Context, lock *models.EditLock) (*models.EditLock, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := lockKey(lock.ResourceType, lock.ResourceID)
+	if existingLock := store.activeLock(key, lock.AcquiredAt); existingLock != nil {
+		if existingLock.Owner != lock.Owner {
+			lockCopy := *existingLock
+			return &lockCopy, false, nil
+		}
+
+		// Renewing keeps the original acquisition time
+		existingLock.ExpiresAt = lock.ExpiresAt
+		lockCopy := *existingLock
+		return &lockCopy, true, nil
+	}
+
+	newLock := *lock
+	store.locks[key] = &newLock
+	lockCopy := newLock
+	return &lockCopy, true, nil
+}
+
+// Delete removes the lock when owner holds it or force is set
+func (store *MemoryStore) Delete(ctx context.Context, resourceType string, resourceID string, owner string, force bool, now time.Time) (*models.EditLock, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := lockKey(resourceType, resourceID)
+	existingLock := store.activeLock(key, now)
+	if existingLock == nil {
+		return nil, false, nil
+	}
+
+	lockCopy := *existingLock
+	if existingLock.Owner != owner && !force {
+		return &lockCopy, false, nil
+	}
+	delete(store.locks, key)
+	return &lockCopy, true, nil
+}
+
+// Get returns a copy of the unexpired lock, or nil
+func (store *MemoryStore) Get(ctx context.Context, resourceType string, resourceID string, now time.Time) (*models.EditLock, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	existingLock := store.activeLock(lockKey(resourceType, resourceID), now)
+	if existingLock == nil {
+		return nil, nil
+	}
+	lockCopy := *existingLock
+	return &lockCopy, nil
+}
+
+// activeLock returns the unexpired lock for key, removing it when expired; callers hold the mutex
+func (store *MemoryStore) activeLock(key string, now time.Time) *models.EditLock {
+	existingLock, exists := store.locks[key]
+	if !exists {
+		return nil
+	}
+
+	if !now.Before(existingLock.ExpiresAt) {
+		delete(store.locks, key)
+		return nil
+	}
+
+	return existingLock
+}
+
+// lockKey builds the map key for a resource
+func lockKey(resourceType string, resourceID string) string {
+	return resourceType + "/" + resourceID
+}
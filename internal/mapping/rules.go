@@ -0,0 +1,249 @@
+package mapping
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Name case conventions supported by PatientRules.NameCase
+const (
+	NameCaseUpper = "upper"
+	NameCaseTitle = "title"
+)
+
+// Rules holds a site's configuration-driven mapping rules
+type Rules struct {
+	Patient     PatientRules     `json:"patient"`
+	Observation ObservationRules `json:"observation"`
+}
+
+// PatientRules describes how a site's Patient resources differ from the defaults
+type PatientRules struct {
+	// IdentifierSystemAliases rewrites site-specific identifier systems (e.g. local OIDs) to canonical ones
+	IdentifierSystemAliases map[string]string `json:"identifier_system_aliases"`
+
+	// PreferredIdentifierSystem is stored when a patient has several identifiers (e.g. the MRN system)
+	PreferredIdentifierSystem string `json:"preferred_identifier_system"`
+
+	// PreferredNameUse selects which name is stored when several are sent (e.g. "official")
+	PreferredNameUse string `json:"preferred_name_use"`
+
+	// NameCase normalizes stored names: "upper", "title", or empty to keep as sent
+	NameCase string `json:"name_case"`
+}
+
+// CodeMapping translates one local code to a standard code
+type CodeMapping struct {
+	SourceSystem  string `json:"source_system"`
+	SourceCode    string `json:"source_code"`
+	TargetSystem  string `json:"target_system"`
+	TargetCode    string `json:"target_code"`
+	TargetDisplay string `json:"target_display"`
+}
+
+// ObservationRules describes how a site's Observation resources differ from the defaults
+type ObservationRules struct {
+	// CodeMappings translate local observation codes to standard ones on the way in
+	CodeMappings []CodeMapping `json:"code_mappings"`
+
+	// CategoryAliases rewrites site-specific categories (e.g. "vitals" to "vital-signs")
+	CategoryAliases map[string]string `json:"category_aliases"`
+}
+
+// LoadRules reads mapping rules from a JSON file
+func LoadRules(path string) (*Rules, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read mapping rules: %w", readError)
+	}
+
+	rules := &Rules{}
+	if decodeError := json.Unmarshal(fileBytes, rules); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse mapping rules: %w", decodeError)
+	}
+
+	if validateError := rules.Validate(); validateError != nil {
+		return nil, validateError
+	}
+
+	return rules, nil
+}
+
+// Validate checks rules for values the hooks cannot apply
+func (rules *Rules) Validate() error {
+	switch rules.Patient.NameCase {
+	case "", NameCaseUpper, NameCaseTitle:
+	default:
+		return fmt.Errorf("invalid patient name_case %q: must be upper or title", rules.Patient.NameCase)
+	}
+
+	// A target that is itself an alias would make the stored system depend on how often rules ran
+	for alias, canonicalSystem := range rules.Patient.IdentifierSystemAliases {
+		if _, chained := rules.Patient.IdentifierSystemAliases[canonicalSystem]; chained {
+			return fmt.Errorf("patient identifier_system_aliases %q maps to %q, which is itself an alias", alias, canonicalSystem)
+		}
+	}
+
+	for index, codeMapping := range rules.Observation.CodeMappings {
+		if codeMapping.SourceCode == "" || codeMapping.TargetCode == "" {
+			return fmt.Errorf("observation code_mappings[%d] must set source_code and target_code", index)
+		}
+	}
+
+	return nil
+}
+
+// EquivalentIdentifierSystems returns the canonical system for an identifier system followed by its aliases in order
+// Searches match all of them so patients stored before an alias was configured are still found
+func (rules *Rules) EquivalentIdentifierSystems(system string) []string {
+	canonicalSystem := system
+	if aliasTarget, isAlias := rules.Patient.IdentifierSystemAliases[system]; isAlias {
+		canonicalSystem = aliasTarget
+	}
+
+	var aliases []string
+	for alias, aliasTarget := range rules.Patient.IdentifierSystemAliases {
+		if aliasTarget == canonicalSystem {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+
+	return append([]string{canonicalSystem}, aliases...)
+}
+
+// PatientHook builds a mapper hook that applies the patient rules
+func (rules *Rules) PatientHook() models.PatientMappingHook {
+	return &patientRulesHook{rules: rules.Patient}
+}
+
+// ObservationHook builds a mapper hook that applies the observation rules
+func (rules *Rules) ObservationHook() models.ObservationMappingHook {
+	return &observationRulesHook{rules: rules.Observation}
+}
+
+// patientRulesHook applies PatientRules during Patient conversions
+type patientRulesHook struct {
+	models.BasePatientMappingHook
+	rules PatientRules
+}
+
+// PreFromFHIR canonicalizes identifier systems and moves preferred identifiers and names first
+// The built-in mapper stores only the first identifier and name
+func (hook *patientRulesHook) PreFromFHIR(fhirPatient *fhir.Patient) {
+	for index := range fhirPatient.Identifier {
+		system := fhirPatient.Identifier[index].System
+		if system == nil {
+			continue
+		}
+		if canonicalSystem, exists := hook.rules.IdentifierSystemAliases[*system]; exists {
+			fhirPatient.Identifier[index].System = &canonicalSystem
+		}
+	}
+
+	if hook.rules.PreferredIdentifierSystem != "" {
+		moveFirst(fhirPatient.Identifier, func(identifier fhir.Identifier) bool {
+			return identifier.System != nil && *identifier.System == hook.rules.PreferredIdentifierSystem
+		})
+	}
+
+	if hook.rules.PreferredNameUse != "" {
+		moveFirst(fhirPatient.Name, func(name fhir.HumanName) bool {
+			return name.Use != nil && name.Use.Code() == hook.rules.PreferredNameUse
+		})
+	}
+}
+
+// PostFromFHIR applies the site's name case convention
+func (hook *patientRulesHook) PostFromFHIR(fhirPatient *fhir.Patient, patient *models.Patient) {
+	patient.FamilyName = applyNameCase(patient.FamilyName, hook.rules.NameCase)
+	patient.GivenName = applyNameCase(patient.GivenName, hook.rules.NameCase)
+}
+
+// observationRulesHook applies ObservationRules during Observation conversions
+type observationRulesHook struct {
+	models.BaseObservationMappingHook
+	rules ObservationRules
+}
+
+// PreFromFHIR puts a standard coding first for any local code with a mapping
+// The local coding is kept so the original code is not lost
+func (hook *observationRulesHook) PreFromFHIR(fhirObservation *fhir.Observation) {
+	for _, coding := range fhirObservation.Code.Coding {
+		codeMapping, found := hook.findCodeMapping(coding)
+		if !found {
+			continue
+		}
+
+		targetCoding := fhir.Coding{Code: &codeMapping.TargetCode}
+		if codeMapping.TargetSystem != "" {
+			targetCoding.System = &codeMapping.TargetSystem
+		}
+		if codeMapping.TargetDisplay != "" {
+			targetCoding.Display = &codeMapping.TargetDisplay
+		}
+		fhirObservation.Code.Coding = append([]fhir.Coding{targetCoding}, fhirObservation.Code.Coding...)
+		return
+	}
+}
+
+// PostFromFHIR rewrites site-specific categories
+func (hook *observationRulesHook) PostFromFHIR(fhirObservation *fhir.Observation, observation *models.Observation) {
+	if canonicalCategory, exists := hook.rules.CategoryAliases[observation.Category]; exists {
+		observation.Category = canonicalCategory
+	}
+}
+
+// findCodeMapping returns the mapping for a coding; an empty source system matches any system
+func (hook *observationRulesHook) findCodeMapping(coding fhir.Coding) (CodeMapping, bool) {
+	if coding.Code == nil {
+		return CodeMapping{}, false
+	}
+
+	for _, codeMapping := range hook.rules.CodeMappings {
+		if codeMapping.SourceCode != *coding.Code {
+			continue
+		}
+		if codeMapping.SourceSystem == "" {
+			return codeMapping, true
+		}
+		if coding.System != nil && *coding.System == codeMapping.SourceSystem {
+			return codeMapping, true
+		}
+	}
+
+	return CodeMapping{}, false
+}
+
+// moveFirst moves the first element matching the predicate to the front, keeping the rest in order
+func moveFirst[T any](items []T, matches func(T) bool) {
+	for index, item := range items {
+		if matches(item) {
+			copy(items[1:index+1], items[:index])
+			items[0] = item
+			return
+		}
+	}
+}
+
+// applyNameCase converts a name to the configured case
+func applyNameCase(name string, nameCase string) string {
+	switch nameCase {
+	case NameCaseUpper:
+		return strings.ToUpper(name)
+	case NameCaseTitle:
+		words := strings.Fields(strings.ToLower(name))
+		for index, word := range words {
+			words[index] = strings.ToUpper(word[:1]) + word[1:]
+		}
+		return strings.Join(words, " ")
+	default:
+		return name
+	}
+}
@@ -0,0 +1,131 @@
+package mapping
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// stringPointer returns a pointer to the given string
+func stringPointer(value string) *string {
+	return &value
+}
+
+// TestPatientHook_IdentifiersAndNames verifies MRN selection, aliasing, and name normalization
+func TestPatientHook_IdentifiersAndNames(t *testing.T) {
+	rules := &Rules{Patient: PatientRules{
+		IdentifierSystemAliases:   map[string]string{"urn:oid:1.2.3": "http://hospital.example.org/mrn"},
+		PreferredIdentifierSystem: "http://hospital.example.org/mrn",
+		PreferredNameUse:          "official",
+		NameCase:                  NameCaseTitle,
+	}}
+	mapper := models.NewPatientMapper()
+	mapper.AddHook(rules.PatientHook())
+
+	nicknameUse := fhir.NameUseNickname
+	officialUse := fhir.NameUseOfficial
+	patient, _ := mapper.FromFHIR(&fhir.Patient{
+		Identifier: []fhir.Identifier{
+			{System: stringPointer("http://hl7.org/fhir/sid/us-ssn"), Value: stringPointer("999-99-9999")},
+			{System: stringPointer("urn:oid:1.2.3"), Value: stringPointer("MRN-42")},
+		},
+		Name: []fhir.HumanName{
+			{Use: &nicknameUse, Family: stringPointer("smitty"), Given: []string{"jo"}},
+			{Use: &officialUse, Family: stringPointer("SMITH"), Given: []string{"mary ann"}},
+		},
+	})
+
+	if patient.IdentifierSystem != "http://hospital.example.org/mrn" || patient.IdentifierValue != "MRN-42" {
+		t.Errorf("Expected canonical MRN identifier, got %s|%s", patient.IdentifierSystem, patient.IdentifierValue)
+	}
+	if patient.FamilyName != "Smith" || patient.GivenName != "Mary Ann" {
+		t.Errorf("Expected official name in title case, got %s, %s", patient.FamilyName, patient.GivenName)
+	}
+}
+
+// TestObservationHook_CodesAndCategories verifies local codes and categories are translated
+func TestObservationHook_CodesAndCategories(t *testing.T) {
+	rules := &Rules{Observation: ObservationRules{
+		CodeMappings: []CodeMapping{{
+			SourceSystem:  "http://hospital.example.org/codes",
+			SourceCode:    "HR",
+			TargetSystem:  "http://loinc.org",
+			TargetCode:    "8867-4",
+			TargetDisplay: "Heart rate",
+		}},
+		CategoryAliases: map[string]string{"vitals": "vital-signs"},
+	}}
+	mapper := models.NewObservationMapper()
+	mapper.AddHook(rules.ObservationHook())
+
+	observation, _ := mapper.FromFHIR(&fhir.Observation{
+		Category: []fhir.CodeableConcept{{Coding: []fhir.Coding{{Code: stringPointer("vitals")}}}},
+		Code: fhir.CodeableConcept{Coding: []fhir.Coding{
+			{System: stringPointer("http://hospital.example.org/codes"), Code: stringPointer("HR")},
+		}},
+	})
+
+	if observation.Code != "8867-4" || observation.CodeSystem != "http://loinc.org" {
+		t.Errorf("Expected LOINC code, got %s|%s", observation.CodeSystem, observation.Code)
+	}
+	if observation.Category != "vital-signs" {
+		t.Errorf("Expected canonical category, got %s", observation.Category)
+	}
+
+	// Codes from other systems are left alone
+	unmapped, _ := mapper.FromFHIR(&fhir.Observation{Code: fhir.CodeableConcept{Coding: []fhir.Coding{
+		{System: stringPointer("http://other.example.org"), Code: stringPointer("HR")},
+	}}})
+	if unmapped.Code != "HR" {
+		t.Errorf("Expected unmapped code to be kept, got %s", unmapped.Code)
+	}
+}
+
+// TestLoadRules verifies rules are loaded and validated
+func TestLoadRules(t *testing.T) {
+	directory := t.TempDir()
+
+	validPath := filepath.Join(directory, "valid.json")
+	os.WriteFile(validPath, []byte(`{"patient": {"name_case": "upper"}, "observation": {"category_aliases": {"labs": "laboratory"}}}`), 0o600)
+	rules, loadError := LoadRules(validPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if rules.Patient.NameCase != NameCaseUpper || rules.Observation.CategoryAliases["labs"] != "laboratory" {
+		t.Errorf("Unexpected rules: %+v", rules)
+	}
+
+	invalidPath := filepath.Join(directory, "invalid.json")
+	os.WriteFile(invalidPath, []byte(`{"patient": {"name_case": "lower"}}`), 0o600)
+	if _, invalidError := LoadRules(invalidPath); invalidError == nil {
+		t.Error("Expected error for unsupported name case")
+	}
+}
+
+// TestEquivalentIdentifierSystems verifies a system and its aliases resolve to the same canonical set
+func TestEquivalentIdentifierSystems(t *testing.T) {
+	rules := &Rules{Patient: PatientRules{IdentifierSystemAliases: map[string]string{
+		"MRN":                     "http://hospital.example.org/mrn",
+		"urn:oid:1.2.3":           "http://hospital.example.org/mrn",
+		"http://hospital.com/mrn": "http://hospital.example.org/mrn",
+	}}}
+	expectedSystems := []string{"http://hospital.example.org/mrn", "MRN", "http://hospital.com/mrn", "urn:oid:1.2.3"}
+
+	for _, system := range []string{"MRN", "http://hospital.example.org/mrn", "urn:oid:1.2.3"} {
+		if systems := rules.EquivalentIdentifierSystems(system); !reflect.DeepEqual(systems, expectedSystems) {
+			t.Errorf("%s: expected %v, got %v", system, expectedSystems, systems)
+		}
+	}
+	if systems := rules.EquivalentIdentifierSystems("http://hl7.org/fhir/sid/us-ssn"); !reflect.DeepEqual(systems, []string{"http://hl7.org/fhir/sid/us-ssn"}) {
+		t.Errorf("Expected an unaliased system alone, got %v", systems)
+	}
+
+	rules.Patient.IdentifierSystemAliases["http://hospital.example.org/mrn"] = "urn:oid:9.9"
+	if validateError := rules.Validate(); validateError == nil {
+		t.Error("Expected an error for an alias that maps to another alias")
+	}
+}
@@ -0,0 +1,234 @@
+package matching
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// Grade is how confident a match is, given as the FHIR match-grade extension's code
+type Grade string
+
+const (
+	// GradeCertain means the candidate is the same patient
+	GradeCertain Grade = "certain"
+
+	// GradeProbable means the candidate is likely the same patient
+	GradeProbable Grade = "probable"
+
+	// GradePossible means the candidate may be the same patient and needs review
+	GradePossible Grade = "possible"
+)
+
+// GradeExtensionURL is the URL of the extension carrying a match's grade on its Bundle entry
+const GradeExtensionURL = "http://hl7.org/fhir/StructureDefinition/match-grade"
+
+// Weights sets how much each compared element adds to a score; only their proportions matter
+type Weights struct {
+	// Identifier is earned by a candidate with the queried identifier value in the same system
+	Identifier float64 `json:"identifier"`
+
+	// Name is earned in proportion to how similar the candidate's family and given names are
+	Name float64 `json:"name"`
+
+	// BirthDate is earned in full by the same birth date and in half by a date one typo away
+	BirthDate float64 `json:"birth_date"`
+
+	// Gender is earned by the same gender
+	Gender float64 `json:"gender"`
+}
+
+// Config configures patient match scoring
+type Config struct {
+	Weights Weights `json:"weights"`
+
+	// CertainScore, ProbableScore, and PossibleScore are the lowest scores given each grade; candidates scoring
+	// below PossibleScore are not matches
+	CertainScore  float64 `json:"certain_score"`
+	ProbableScore float64 `json:"probable_score"`
+	PossibleScore float64 `json:"possible_score"`
+}
+
+// DefaultConfig returns the built-in weights and grade thresholds
+// With them a candidate agreeing on identifier, name, and birth date is certain, one agreeing on identifier
+// and name is probable, and one agreeing only on name and birth date is possible
+func DefaultConfig() Config {
+	return Config{
+		Weights:       Weights{Identifier: 0.4, Name: 0.3, BirthDate: 0.2, Gender: 0.1},
+		CertainScore:  0.9,
+		ProbableScore: 0.7,
+		PossibleScore: 0.4,
+	}
+}
+
+// LoadConfig reads match scoring from a JSON file; settings the file leaves out keep their defaults
+func LoadConfig(path string) (Config, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return Config{}, fmt.Errorf("failed to read patient match config: %w", readError)
+	}
+
+	config := DefaultConfig()
+	if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+		return Config{}, fmt.Errorf("failed to parse patient match config: %w", decodeError)
+	}
+	if validateError := config.Validate(); validateError != nil {
+		return Config{}, validateError
+	}
+
+	return config, nil
+}
+
+// Validate checks for weights and thresholds that cannot grade a match
+func (config Config) Validate() error {
+	weights := config.Weights
+	if weights.Identifier < 0 || weights.Name < 0 || weights.BirthDate < 0 || weights.Gender < 0 {
+		return fmt.Errorf("patient match weights must not be negative")
+	}
+	if config.totalWeight() == 0 {
+		return fmt.Errorf("patient match weights must not all be zero")
+	}
+	if config.PossibleScore <= 0 || config.PossibleScore > config.ProbableScore || config.ProbableScore > config.CertainScore || config.CertainScore > 1 {
+		return fmt.Errorf("patient match thresholds must satisfy 0 < possible_score <= probable_score <= certain_score <= 1")
+	}
+	return nil
+}
+
+// totalWeight is the score a candidate agreeing on every element would earn before scaling
+func (config Config) totalWeight() float64 {
+	return config.Weights.Identifier + config.Weights.Name + config.Weights.BirthDate + config.Weights.Gender
+}
+
+// Score compares a candidate with the queried patient and returns a score between 0 and 1, rounded to four
+// decimals so that sums of weights land exactly on the thresholds they add up to
+// Elements the query leaves out earn nothing, so a sparse query cannot reach the higher grades by itself.
+// Identifier systems are compared as given, so callers canonicalize site aliases first
+func (config Config) Score(query *models.Patient, candidate *models.Patient) float64 {
+	weights := config.Weights
+	score := weights.Identifier*identifierSimilarity(query, candidate) +
+		weights.Name*nameSimilarity(query, candidate) +
+		weights.BirthDate*birthDateSimilarity(query, candidate) +
+		weights.Gender*genderSimilarity(query, candidate)
+	return math.Round(score/config.totalWeight()*10000) / 10000
+}
+
+// Grade returns the grade of a score and whether the score is high enough to be a match at all
+func (config Config) Grade(score float64) (Grade, bool) {
+	switch {
+	case score >= config.CertainScore:
+		return GradeCertain, true
+	case score >= config.ProbableScore:
+		return GradeProbable, true
+	case score >= config.PossibleScore:
+		return GradePossible, true
+	default:
+		return "", false
+	}
+}
+
+// identifierSimilarity is 1 when the candidate has the queried identifier value in the queried system,
+// or in any system when the query gives none
+func identifierSimilarity(query *models.Patient, candidate *models.Patient) float64 {
+	if query.IdentifierValue == "" || query.IdentifierValue != candidate.IdentifierValue {
+		return 0
+	}
+	if query.IdentifierSystem != "" && query.IdentifierSystem != candidate.IdentifierSystem {
+		return 0
+	}
+	return 1
+}
+
+// nameSimilarity averages the trigram similarity of the family and given names the query gives
+func nameSimilarity(query *models.Patient, candidate *models.Patient) float64 {
+	similarity := 0.0
+	comparedNames := 0
+	if query.FamilyName != "" {
+		similarity += TrigramSimilarity(query.FamilyName, candidate.FamilyName)
+		comparedNames++
+	}
+	if query.GivenName != "" {
+		similarity += TrigramSimilarity(query.GivenName, candidate.GivenName)
+		comparedNames++
+	}
+	if comparedNames == 0 {
+		return 0
+	}
+	return similarity / float64(comparedNames)
+}
+
+// birthDateSimilarity is 1 for the same birth date and 0.5 for one that differs in only the year, month,
+// or day, or has the month and day swapped, as data entry typos do
+func birthDateSimilarity(query *models.Patient, candidate *models.Patient) float64 {
+	if query.BirthDate == nil || candidate.BirthDate == nil {
+		return 0
+	}
+	queryYear, queryMonth, queryDay := query.BirthDate.Date()
+	candidateYear, candidateMonth, candidateDay := candidate.BirthDate.Date()
+	agreeing := 0
+	if queryYear == candidateYear {
+		agreeing++
+	}
+	if queryMonth == candidateMonth {
+		agreeing++
+	}
+	if queryDay == candidateDay {
+		agreeing++
+	}
+
+	switch {
+	case agreeing == 3:
+		return 1
+	case agreeing == 2:
+		return 0.5
+	case queryYear == candidateYear && int(queryMonth) == candidateDay && queryDay == int(candidateMonth):
+		return 0.5
+	default:
+		return 0
+	}
+}
+
+// genderSimilarity is 1 when both patients have the same known gender
+func genderSimilarity(query *models.Patient, candidate *models.Patient) float64 {
+	if query.Gender == "" || query.Gender == "unknown" || query.Gender != candidate.Gender {
+		return 0
+	}
+	return 1
+}
+
+// TrigramSimilarity compares two names the way pg_trgm's similarity does: the shared fraction of the
+// three-letter sequences of their lowercased words, each padded with two spaces before and one after
+func TrigramSimilarity(first string, second string) float64 {
+	firstTrigrams := trigrams(first)
+	secondTrigrams := trigrams(second)
+	if len(firstTrigrams) == 0 || len(secondTrigrams) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for trigram := range firstTrigrams {
+		if secondTrigrams[trigram] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(firstTrigrams)+len(secondTrigrams)-shared)
+}
+
+// trigrams returns the set of padded three-letter sequences of a name's words
+func trigrams(name string) map[string]bool {
+	isSeparator := func(character rune) bool {
+		return !unicode.IsLetter(character) && !unicode.IsDigit(character)
+	}
+	trigramSet := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(name), isSeparator) {
+		paddedWord := []rune("  " + word + " ")
+		for start := 0; start+3 <= len(paddedWord); start++ {
+			trigramSet[string(paddedWord[start:start+3])] = true
+		}
+	}
+	return trigramSet
+}
@@ -0,0 +1,129 @@
+package matching
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// birthDate returns a pointer to a date for patient fixtures
+func birthDate(year int, month time.Month, day int) *time.Time {
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	return &date
+}
+
+// TestConfig_ScoreAndGrade verifies the default weights grade candidates by the elements they agree on
+func TestConfig_ScoreAndGrade(t *testing.T) {
+	config := DefaultConfig()
+	query := &models.Patient{
+		IdentifierSystem: "http://hospital.example.org/mrn",
+		IdentifierValue:  "12345",
+		FamilyName:       "Smith",
+		GivenName:        "Jane",
+		Gender:           "female",
+		BirthDate:        birthDate(1980, time.March, 4),
+	}
+
+	testCases := []struct {
+		name          string
+		candidate     *models.Patient
+		expectedScore float64
+		expectedGrade Grade
+		expectedMatch bool
+	}{
+		{
+			name:          "same patient",
+			candidate:     &models.Patient{IdentifierSystem: "http://hospital.example.org/mrn", IdentifierValue: "12345", FamilyName: "Smith", GivenName: "Jane", Gender: "female", BirthDate: birthDate(1980, time.March, 4)},
+			expectedScore: 1,
+			expectedGrade: GradeCertain,
+			expectedMatch: true,
+		},
+		{
+			name:          "identifier, name, and birth date",
+			candidate:     &models.Patient{IdentifierSystem: "http://hospital.example.org/mrn", IdentifierValue: "12345", FamilyName: "Smith", GivenName: "Jane", BirthDate: birthDate(1980, time.March, 4)},
+			expectedScore: 0.9,
+			expectedGrade: GradeCertain,
+			expectedMatch: true,
+		},
+		{
+			name:          "identifier and name",
+			candidate:     &models.Patient{IdentifierSystem: "http://hospital.example.org/mrn", IdentifierValue: "12345", FamilyName: "Smith", GivenName: "Jane"},
+			expectedScore: 0.7,
+			expectedGrade: GradeProbable,
+			expectedMatch: true,
+		},
+		{
+			name:          "name and swapped birth date",
+			candidate:     &models.Patient{FamilyName: "Smith", GivenName: "Jane", Gender: "female", BirthDate: birthDate(1980, time.April, 3)},
+			expectedScore: 0.5,
+			expectedGrade: GradePossible,
+			expectedMatch: true,
+		},
+		{
+			name:          "identifier in another system",
+			candidate:     &models.Patient{IdentifierSystem: "http://clinic.example.org/mrn", IdentifierValue: "12345", FamilyName: "Jones"},
+			expectedScore: 0,
+			expectedMatch: false,
+		},
+	}
+	for _, testCase := range testCases {
+		score := config.Score(query, testCase.candidate)
+		if score != testCase.expectedScore {
+			t.Errorf("%s: expected score %v, got %v", testCase.name, testCase.expectedScore, score)
+		}
+		grade, isMatch := config.Grade(score)
+		if grade != testCase.expectedGrade || isMatch != testCase.expectedMatch {
+			t.Errorf("%s: expected %q (match %v), got %q (match %v)", testCase.name, testCase.expectedGrade, testCase.expectedMatch, grade, isMatch)
+		}
+	}
+}
+
+// TestTrigramSimilarity verifies names compare like pg_trgm, ignoring case and punctuation
+func TestTrigramSimilarity(t *testing.T) {
+	if similarity := TrigramSimilarity("O'Brien", "obrien"); similarity >= 1 || similarity <= 0 {
+		t.Errorf("Expected a partial similarity across punctuation, got %v", similarity)
+	}
+	if similarity := TrigramSimilarity("SMITH", "smith"); similarity != 1 {
+		t.Errorf("Expected case to be ignored, got %v", similarity)
+	}
+	// pg_trgm gives similarity('smith', 'smyth') = 0.333333
+	if similarity := TrigramSimilarity("smith", "smyth"); similarity < 0.333 || similarity > 0.334 {
+		t.Errorf("Expected pg_trgm's similarity for one changed letter, got %v", similarity)
+	}
+	if similarity := TrigramSimilarity("", "smith"); similarity != 0 {
+		t.Errorf("Expected no similarity to an empty name, got %v", similarity)
+	}
+}
+
+// TestLoadConfig verifies site settings override the defaults they name and invalid settings are refused
+func TestLoadConfig(t *testing.T) {
+	directory := t.TempDir()
+	validPath := filepath.Join(directory, "match.json")
+	os.WriteFile(validPath, []byte(`{"weights": {"identifier": 0.6, "name": 0.2, "birth_date": 0.15, "gender": 0.05}, "certain_score": 0.95}`), 0o600)
+
+	config, loadError := LoadConfig(validPath)
+	if loadError != nil {
+		t.Fatalf("Expected the config to load, got %v", loadError)
+	}
+	if config.Weights.Identifier != 0.6 || config.CertainScore != 0.95 || config.ProbableScore != DefaultConfig().ProbableScore {
+		t.Errorf("Expected site settings over the defaults, got %+v", config)
+	}
+
+	invalidConfigs := []string{
+		`{"weights": {"identifier": -1}}`,
+		`{"weights": {"identifier": 0, "name": 0, "birth_date": 0, "gender": 0}}`,
+		`{"probable_score": 0.95}`,
+		`{"possible_score": 0}`,
+		`not json`,
+	}
+	for _, invalidConfig := range invalidConfigs {
+		invalidPath := filepath.Join(directory, "invalid.json")
+		os.WriteFile(invalidPath, []byte(invalidConfig), 0o600)
+		if _, invalidError := LoadConfig(invalidPath); invalidError == nil {
+			t.Errorf("%s: expected the config to be refused", invalidConfig)
+		}
+	}
+}
@@ -0,0 +1,423 @@
+package metrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+)
+
+// unknownElementName buckets _elements values that are not elements of the resource type
+// so arbitrary client input cannot grow the metrics maps without bound
+const unknownElementName = "_unknown"
+
+// maxSampledResponseBytes skips inspecting responses larger than this to bound the memory a sample holds
+const maxSampledResponseBytes = 4 << 20
+
+// summaryCandidateShare is the share of _elements requests an element must appear in to be suggested for _summary
+const summaryCandidateShare = 0.5
+
+// ElementConfig controls response sampling
+// Responses are only inspected for tenants that consented, and then only at SampleRate
+type ElementConfig struct {
+	// SampleRate is the fraction of consenting tenants' responses inspected, from 0 (none) to 1 (all)
+	SampleRate float64
+
+	// ConsentTenants lists the tenants that agreed to have response contents inspected
+	ConsentTenants []string
+}
+
+// ElementRecorder tracks which resource elements clients ask for with _elements and which elements
+// the server actually sends them, to guide _summary defaults and payload trimming
+// It is safe for concurrent use by multiple request goroutines
+type ElementRecorder struct {
+	mutex sync.Mutex
+
+	// knownElements lists the top-level elements per resource type
+	knownElements map[string]map[string]bool
+
+	sampleRate     float64
+	consentTenants map[string]bool
+
+	// resources holds usage statistics keyed by resource type
+	resources map[string]*resourceElementStats
+}
+
+// resourceElementStats holds element usage for a single resource type
+type resourceElementStats struct {
+	requests         int64
+	elementsRequests int64
+	sampledResponses int64
+	sampledResources int64
+	sampledBytes     int64
+	elements         map[string]*elementCounts
+}
+
+// elementCounts holds how often one element was requested and sent
+type elementCounts struct {
+	requested int64
+	present   int64
+	bytes     int64
+}
+
+// ElementUsage summarizes one element of a resource type
+type ElementUsage struct {
+	Element string `json:"element"`
+
+	// RequestedCount is how many _elements requests named the element
+	RequestedCount int64 `json:"requested_count"`
+
+	// RequestedShare is RequestedCount over all _elements requests
+	RequestedShare float64 `json:"requested_share"`
+
+	// PresentCount is how many sampled resources contained the element
+	PresentCount int64 `json:"present_count"`
+
+	// PresentShare is PresentCount over all sampled resources
+	PresentShare float64 `json:"present_share"`
+
+	// AvgBytes is the element's average encoded size where present
+	AvgBytes float64 `json:"avg_bytes"`
+
+	// PayloadShare is the element's share of all sampled resource bytes
+	PayloadShare float64 `json:"payload_share"`
+}
+
+// ResourceElementReport summarizes element usage for a resource type
+type ResourceElementReport struct {
+	ResourceType     string  `json:"resource_type"`
+	Requests         int64   `json:"requests"`
+	ElementsRequests int64   `json:"elements_requests"`
+	SampledResponses int64   `json:"sampled_responses"`
+	SampledResources int64   `json:"sampled_resources"`
+	AvgResponseBytes float64 `json:"avg_response_bytes"`
+
+	Elements []ElementUsage `json:"elements"`
+
+	// SummaryCandidates are the elements most _elements requests ask for, a starting point for a _summary profile
+	SummaryCandidates []string `json:"summary_candidates"`
+
+	// UnrequestedElements are sent in sampled responses but never asked for with _elements
+	UnrequestedElements []string `json:"unrequested_elements"`
+}
+
+// NewElementRecorder creates a recorder for the given top-level elements per resource type
+func NewElementRecorder(knownElements map[string][]string, config ElementConfig) *ElementRecorder {
+	elementSets := make(map[string]map[string]bool, len(knownElements))
+	for resourceType, elements := range knownElements {
+		elementSets[resourceType] = make(map[string]bool, len(elements))
+		for _, element := range elements {
+			elementSets[resourceType][element] = true
+		}
+	}
+
+	consentTenants := make(map[string]bool, len(config.ConsentTenants))
+	for _, tenantID := range config.ConsentTenants {
+		consentTenants[tenantID] = true
+	}
+
+	return &ElementRecorder{
+		knownElements:  elementSets,
+		sampleRate:     config.SampleRate,
+		consentTenants: consentTenants,
+		resources:      make(map[string]*resourceElementStats),
+	}
+}
+
+// ResourceElements lists the top-level JSON element names of a FHIR model struct, such as fhir.Patient{}
+func ResourceElements(resource interface{}) []string {
+	resourceType := reflect.TypeOf(resource)
+	elements := make([]string, 0, resourceType.NumField())
+	for fieldIndex := 0; fieldIndex < resourceType.NumField(); fieldIndex++ {
+		jsonName := strings.Split(resourceType.Field(fieldIndex).Tag.Get("json"), ",")[0]
+		if jsonName == "" || jsonName == "-" {
+			continue
+		}
+		elements = append(elements, jsonName)
+	}
+	sort.Strings(elements)
+	return elements
+}
+
+// RecordRequest stores one read or search and the elements it asked for with _elements (nil when absent)
+func (recorder *ElementRecorder) RecordRequest(resourceType string, requestedElements []string) {
+	normalizedElements := recorder.normalizeElements(resourceType, requestedElements)
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	stats := recorder.statsFor(resourceType)
+	stats.requests++
+	if requestedElements == nil {
+		return
+	}
+	stats.elementsRequests++
+	for _, element := range normalizedElements {
+		stats.elementFor(element).requested++
+	}
+}
+
+// RecordResponse inspects a sampled response body, counting the elements of every resource of the type it contains
+// Single resources, search Bundles, and the bare JSON arrays returned by searches are understood; other bodies are ignored
+func (recorder *ElementRecorder) RecordResponse(resourceType string, body []byte) {
+	resources := responseResources(resourceType, body)
+	if resources == nil {
+		return
+	}
+
+	// Decode outside the lock; only the element names and sizes are kept, never the values
+	var elementSizes []map[string]int
+	for _, resource := range resources {
+		var elements map[string]json.RawMessage
+		if json.Unmarshal(resource, &elements) != nil {
+			continue
+		}
+		var embeddedType string
+		json.Unmarshal(elements["resourceType"], &embeddedType)
+		if embeddedType != resourceType {
+			continue
+		}
+
+		sizes := make(map[string]int, len(elements))
+		for element, value := range elements {
+			if element == "resourceType" {
+				continue
+			}
+			if !recorder.knownElements[resourceType][element] {
+				element = unknownElementName
+			}
+			sizes[element] += len(value)
+		}
+		elementSizes = append(elementSizes, sizes)
+	}
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	stats := recorder.statsFor(resourceType)
+	stats.sampledResponses++
+	stats.sampledBytes += int64(len(body))
+	for _, sizes := range elementSizes {
+		stats.sampledResources++
+		for element, size := range sizes {
+			counts := stats.elementFor(element)
+			counts.present++
+			counts.bytes += int64(size)
+		}
+	}
+}
+
+// responseResources splits a response body into the resources it carries, or returns nil for other bodies
+func responseResources(resourceType string, body []byte) []json.RawMessage {
+	if trimmedBody := bytes.TrimSpace(body); len(trimmedBody) > 0 && trimmedBody[0] == '[' {
+		var resources []json.RawMessage
+		if json.Unmarshal(trimmedBody, &resources) != nil {
+			return nil
+		}
+		return resources
+	}
+
+	var envelope struct {
+		ResourceType string `json:"resourceType"`
+		Entry        []struct {
+			Resource json.RawMessage `json:"resource"`
+		} `json:"entry"`
+	}
+	if json.Unmarshal(body, &envelope) != nil {
+		return nil
+	}
+
+	switch envelope.ResourceType {
+	case resourceType:
+		return []json.RawMessage{body}
+	case "Bundle":
+		resources := make([]json.RawMessage, 0, len(envelope.Entry))
+		for _, entry := range envelope.Entry {
+			resources = append(resources, entry.Resource)
+		}
+		return resources
+	default:
+		return nil
+	}
+}
+
+// Report returns a point-in-time usage summary for every known resource type
+func (recorder *ElementRecorder) Report() []ResourceElementReport {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	resourceTypes := make([]string, 0, len(recorder.knownElements))
+	for resourceType := range recorder.knownElements {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+
+	reports := make([]ResourceElementReport, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		report := ResourceElementReport{
+			ResourceType:        resourceType,
+			Elements:            []ElementUsage{},
+			SummaryCandidates:   []string{},
+			UnrequestedElements: []string{},
+		}
+
+		stats, exists := recorder.resources[resourceType]
+		if !exists {
+			reports = append(reports, report)
+			continue
+		}
+
+		report.Requests = stats.requests
+		report.ElementsRequests = stats.elementsRequests
+		report.SampledResponses = stats.sampledResponses
+		report.SampledResources = stats.sampledResources
+		if stats.sampledResponses > 0 {
+			report.AvgResponseBytes = float64(stats.sampledBytes) / float64(stats.sampledResponses)
+		}
+
+		var totalResourceBytes int64
+		for _, counts := range stats.elements {
+			totalResourceBytes += counts.bytes
+		}
+
+		elementNames := make([]string, 0, len(stats.elements))
+		for element := range stats.elements {
+			elementNames = append(elementNames, element)
+		}
+		sort.Strings(elementNames)
+
+		for _, element := range elementNames {
+			counts := stats.elements[element]
+			usage := ElementUsage{
+				Element:        element,
+				RequestedCount: counts.requested,
+				PresentCount:   counts.present,
+			}
+			if stats.elementsRequests > 0 {
+				usage.RequestedShare = float64(counts.requested) / float64(stats.elementsRequests)
+			}
+			if stats.sampledResources > 0 {
+				usage.PresentShare = float64(counts.present) / float64(stats.sampledResources)
+			}
+			if counts.present > 0 {
+				usage.AvgBytes = float64(counts.bytes) / float64(counts.present)
+			}
+			if totalResourceBytes > 0 {
+				usage.PayloadShare = float64(counts.bytes) / float64(totalResourceBytes)
+			}
+			report.Elements = append(report.Elements, usage)
+
+			if element == unknownElementName {
+				continue
+			}
+			if stats.elementsRequests > 0 && usage.RequestedShare >= summaryCandidateShare {
+				report.SummaryCandidates = append(report.SummaryCandidates, element)
+			}
+			if counts.present > 0 && counts.requested == 0 {
+				report.UnrequestedElements = append(report.UnrequestedElements, element)
+			}
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// Instrument wraps a read or search handler so every request records its _elements
+// and responses to consenting tenants are sampled for the elements actually sent
+func (recorder *ElementRecorder) Instrument(resourceType string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recorder.RecordRequest(resourceType, utils.ParseElementsParameter(r))
+
+		if !recorder.shouldSample(r) {
+			next(w, r)
+			return
+		}
+
+		capture := &capturingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		next(capture, r)
+		if capture.statusCode == http.StatusOK && !capture.overflowed {
+			recorder.RecordResponse(resourceType, capture.body.Bytes())
+		}
+	}
+}
+
+// shouldSample reports whether this request's response may be inspected
+func (recorder *ElementRecorder) shouldSample(r *http.Request) bool {
+	if recorder.sampleRate <= 0 || !recorder.consentTenants[tenant.VerifiedFromContext(r.Context())] {
+		return false
+	}
+	return rand.Float64() < recorder.sampleRate
+}
+
+// statsFor returns the statistics for a resource type, creating them on first use; the caller holds the mutex
+func (recorder *ElementRecorder) statsFor(resourceType string) *resourceElementStats {
+	stats, exists := recorder.resources[resourceType]
+	if !exists {
+		stats = &resourceElementStats{elements: make(map[string]*elementCounts)}
+		recorder.resources[resourceType] = stats
+	}
+	return stats
+}
+
+// elementFor returns the counts for an element, creating them on first use; the caller holds the mutex
+func (stats *resourceElementStats) elementFor(element string) *elementCounts {
+	counts, exists := stats.elements[element]
+	if !exists {
+		counts = &elementCounts{}
+		stats.elements[element] = counts
+	}
+	return counts
+}
+
+// normalizeElements maps unknown elements to a single bucket and returns a de-duplicated list
+func (recorder *ElementRecorder) normalizeElements(resourceType string, requestedElements []string) []string {
+	seen := make(map[string]bool, len(requestedElements))
+	normalizedElements := make([]string, 0, len(requestedElements))
+	for _, element := range requestedElements {
+		if !recorder.knownElements[resourceType][element] {
+			element = unknownElementName
+		}
+		if seen[element] {
+			continue
+		}
+		seen[element] = true
+		normalizedElements = append(normalizedElements, element)
+	}
+	return normalizedElements
+}
+
+// capturingResponseWriter passes a response through while keeping a copy of its body for inspection
+type capturingResponseWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+	overflowed bool
+}
+
+// WriteHeader records the status code before passing it on
+func (writer *capturingResponseWriter) WriteHeader(statusCode int) {
+	writer.statusCode = statusCode
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write copies the body until it grows past the sampling limit, then stops copying
+func (writer *capturingResponseWriter) Write(data []byte) (int, error) {
+	if !writer.overflowed {
+		if writer.body.Len()+len(data) > maxSampledResponseBytes {
+			writer.overflowed = true
+			writer.body.Reset()
+		} else {
+			writer.body.Write(data)
+		}
+	}
+	return writer.ResponseWriter.Write(data)
+}
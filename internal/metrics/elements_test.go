@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newTestElementRecorder creates a recorder for Patient elements that samples every response from tenant-a
+func newTestElementRecorder() *ElementRecorder {
+	return NewElementRecorder(map[string][]string{
+		"Patient": ResourceElements(fhir.Patient{}),
+	}, ElementConfig{SampleRate: 1, ConsentTenants: []string{"tenant-a"}})
+}
+
+// findElement returns the usage of one element
+func findElement(t *testing.T, report ResourceElementReport, element string) ElementUsage {
+	for _, usage := range report.Elements {
+		if usage.Element == element {
+			return usage
+		}
+	}
+	t.Fatalf("Expected usage for %s, got %+v", element, report.Elements)
+	return ElementUsage{}
+}
+
+// TestResourceElements verifies the JSON element names are read from the model struct
+func TestResourceElements(t *testing.T) {
+	elements := map[string]bool{}
+	for _, element := range ResourceElements(fhir.Patient{}) {
+		elements[element] = true
+	}
+	if !elements["name"] || !elements["birthDate"] || !elements["identifier"] {
+		t.Errorf("Expected Patient elements, got %v", elements)
+	}
+}
+
+// TestElementRecorder_RecordRequest verifies _elements usage, unknown element bucketing, and summary candidates
+func TestElementRecorder_RecordRequest(t *testing.T) {
+	recorder := newTestElementRecorder()
+
+	recorder.RecordRequest("Patient", []string{"name", "birthDate", "name"})
+	recorder.RecordRequest("Patient", []string{"name", "secretField"})
+	recorder.RecordRequest("Patient", []string{"gender"})
+	recorder.RecordRequest("Patient", nil)
+
+	report := recorder.Report()[0]
+	if report.Requests != 4 || report.ElementsRequests != 3 {
+		t.Fatalf("Expected 4 requests with 3 using _elements, got %d and %d", report.Requests, report.ElementsRequests)
+	}
+	if findElement(t, report, "name").RequestedCount != 2 {
+		t.Errorf("Expected name requested twice, got %+v", findElement(t, report, "name"))
+	}
+	if findElement(t, report, unknownElementName).RequestedCount != 1 {
+		t.Errorf("Expected unknown elements bucketed, got %+v", report.Elements)
+	}
+	if len(report.SummaryCandidates) != 1 || report.SummaryCandidates[0] != "name" {
+		t.Errorf("Expected name as the only summary candidate, got %v", report.SummaryCandidates)
+	}
+}
+
+// TestElementRecorder_RecordResponse verifies resources in single and Bundle responses are inspected
+func TestElementRecorder_RecordResponse(t *testing.T) {
+	recorder := newTestElementRecorder()
+	recorder.RecordRequest("Patient", []string{"name"})
+
+	recorder.RecordResponse("Patient", []byte(`{"resourceType":"Patient","id":"1","name":[{"family":"Smith"}]}`))
+	recorder.RecordResponse("Patient", []byte(`{"resourceType":"Bundle","entry":[
+		{"resource":{"resourceType":"Patient","id":"2","gender":"female"}},
+		{"resource":{"resourceType":"OperationOutcome","issue":[]}}
+	]}`))
+	recorder.RecordResponse("Patient", []byte(`not json`))
+
+	report := recorder.Report()[0]
+	if report.SampledResponses != 2 || report.SampledResources != 2 {
+		t.Fatalf("Expected 2 sampled responses with 2 patients, got %d and %d", report.SampledResponses, report.SampledResources)
+	}
+	if idUsage := findElement(t, report, "id"); idUsage.PresentCount != 2 || idUsage.PresentShare != 1 || idUsage.AvgBytes != 3 {
+		t.Errorf("Expected id in both patients, got %+v", idUsage)
+	}
+	if len(report.UnrequestedElements) != 2 || report.UnrequestedElements[0] != "gender" || report.UnrequestedElements[1] != "id" {
+		t.Errorf("Expected gender and id sent but never requested, got %v", report.UnrequestedElements)
+	}
+}
+
+// TestElementRecorder_Instrument verifies responses are only sampled for consenting tenants
+func TestElementRecorder_Instrument(t *testing.T) {
+	recorder := newTestElementRecorder()
+	instrumented := recorder.Instrument("Patient", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"resourceType":"Patient","id":"1"}`))
+	})
+
+	for _, tenantID := range []string{"tenant-a", "tenant-b"} {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/Patient/1?_elements=id,name", nil)
+		request = request.WithContext(tenant.WithVerifiedTenant(request.Context(), tenantID))
+		responseRecorder := httptest.NewRecorder()
+		instrumented(responseRecorder, request)
+
+		if responseRecorder.Body.String() != `{"resourceType":"Patient","id":"1"}` {
+			t.Errorf("Expected response passed through, got %s", responseRecorder.Body.String())
+		}
+	}
+
+	report := recorder.Report()[0]
+	if report.ElementsRequests != 2 || findElement(t, report, "name").RequestedCount != 2 {
+		t.Errorf("Expected _elements recorded for both tenants, got %+v", report)
+	}
+	if report.SampledResponses != 1 {
+		t.Errorf("Expected only the consenting tenant sampled, got %d", report.SampledResponses)
+	}
+}
+
+// TestElementRecorder_RecordResponseArray verifies the bare arrays returned by searches are inspected
+func TestElementRecorder_RecordResponseArray(t *testing.T) {
+	recorder := newTestElementRecorder()
+	recorder.RecordResponse("Patient", []byte(` [{"resourceType":"Patient","id":"1","gender":"male"},{"resourceType":"Patient","id":"2"}]`))
+
+	report := recorder.Report()[0]
+	if report.SampledResponses != 1 || report.SampledResources != 2 || findElement(t, report, "gender").PresentCount != 1 {
+		t.Errorf("Expected two sampled patients, one with gender, got %+v", report)
+	}
+}
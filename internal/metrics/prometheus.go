@@ -0,0 +1,134 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// unmatchedRoute labels requests no route matched, so unknown paths cannot grow the label set without bound
+const unmatchedRoute = "unmatched"
+
+// Exporter collects request and database query metrics and serves them in the Prometheus text format
+// Metrics live in the exporter's own registry, so tests and several servers in one process do not collide
+type Exporter struct {
+	registry *prometheus.Registry
+
+	requestsTotal    *prometheus.CounterVec
+	requestDuration  *prometheus.HistogramVec
+	requestsInFlight prometheus.Gauge
+	queryDuration    *prometheus.HistogramVec
+}
+
+// NewExporter creates an exporter with the HTTP, database, Go runtime, and process metrics registered
+func NewExporter() *Exporter {
+	exporter := &Exporter{
+		registry: prometheus.NewRegistry(),
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fhir_http_requests_total",
+			Help: "HTTP requests served, by method, route pattern, and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "fhir_http_request_duration_seconds",
+			Help:    "HTTP request latency, by method, route pattern, and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		requestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "fhir_http_requests_in_flight",
+			Help: "HTTP requests currently being served.",
+		}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "fhir_db_query_duration_seconds",
+			Help:    "Database query latency, by store, operation, and outcome.",
+			Buckets: []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+		}, []string{"store", "operation", "outcome"}),
+	}
+
+	exporter.registry.MustRegister(
+		exporter.requestsTotal,
+		exporter.requestDuration,
+		exporter.requestsInFlight,
+		exporter.queryDuration,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return exporter
+}
+
+// Middleware counts and times every request, labeled by the chi route pattern rather than the raw path
+// so resource IDs do not become label values
+func (exporter *Exporter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exporter.requestsInFlight.Inc()
+		startedAt := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+
+		// Record panics as 500s, then let the panic continue to the recoverer further out
+		defer func() {
+			exporter.requestsInFlight.Dec()
+			statusCode := recorder.statusCode
+			recovered := recover()
+			if recovered != nil {
+				statusCode = http.StatusInternalServerError
+			}
+			labels := prometheus.Labels{"method": r.Method, "route": routePattern(r), "status": strconv.Itoa(statusCode)}
+			exporter.requestsTotal.With(labels).Inc()
+			exporter.requestDuration.With(labels).Observe(time.Since(startedAt).Seconds())
+			if recovered != nil {
+				panic(recovered)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+	})
+}
+
+// ObserveQuery records one database query's latency
+// Exporter implements database.QueryObserver
+func (exporter *Exporter) ObserveQuery(store string, operation string, duration time.Duration, failed bool) {
+	outcome := "success"
+	if failed {
+		outcome = "error"
+	}
+	exporter.queryDuration.WithLabelValues(store, operation, outcome).Observe(duration.Seconds())
+}
+
+// Handler serves GET /metrics in the Prometheus exposition format
+func (exporter *Exporter) Handler() http.Handler {
+	return promhttp.HandlerFor(exporter.registry, promhttp.HandlerOpts{})
+}
+
+// routePattern returns the pattern of the route that served the request, filled in by chi while routing
+func routePattern(r *http.Request) string {
+	routeContext := chi.RouteContext(r.Context())
+	if routeContext == nil {
+		return unmatchedRoute
+	}
+	pattern := routeContext.RoutePattern()
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	return pattern
+}
+
+// statusRecorder captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader captures the status code before writing
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	recorder.statusCode = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// scrape returns the exporter's metrics in the text exposition format
+func scrape(t *testing.T, exporter *Exporter) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	exporter.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected 200 from /metrics, got %d", recorder.Code)
+	}
+	return recorder.Body.String()
+}
+
+// TestExporter_Middleware verifies requests are counted and timed by route pattern, method, and status
+func TestExporter_Middleware(t *testing.T) {
+	exporter := NewExporter()
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+	router.Use(exporter.Middleware)
+	router.Get("/fhir/Patient/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "id") == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+	router.Get("/fhir/Observation/{id}", func(w http.ResponseWriter, r *http.Request) {
+		panic("repository exploded")
+	})
+
+	for _, target := range []string{"/fhir/Patient/p-1", "/fhir/Patient/p-2", "/fhir/Patient/missing", "/fhir/Observation/o-1", "/nowhere"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	exposition := scrape(t, exporter)
+	for _, expectedLine := range []string{
+		`fhir_http_requests_total{method="GET",route="/fhir/Patient/{id}",status="200"} 2`,
+		`fhir_http_requests_total{method="GET",route="/fhir/Patient/{id}",status="404"} 1`,
+		`fhir_http_requests_total{method="GET",route="/fhir/Observation/{id}",status="500"} 1`,
+		`fhir_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`fhir_http_request_duration_seconds_count{method="GET",route="/fhir/Patient/{id}",status="200"} 2`,
+		`fhir_http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(exposition, expectedLine) {
+			t.Errorf("Expected %q in the exposition", expectedLine)
+		}
+	}
+	if strings.Contains(exposition, "p-1") {
+		t.Error("Expected resource IDs never to become label values")
+	}
+}
+
+// TestExporter_ObserveQuery verifies query latencies are recorded per store, operation, and outcome
+func TestExporter_ObserveQuery(t *testing.T) {
+	exporter := NewExporter()
+	exporter.ObserveQuery("postgres", "select", 3*time.Millisecond, false)
+	exporter.ObserveQuery("postgres", "select", 7*time.Millisecond, false)
+	exporter.ObserveQuery("mongodb", "insert", time.Millisecond, true)
+
+	exposition := scrape(t, exporter)
+	for _, expectedLine := range []string{
+		`fhir_db_query_duration_seconds_count{operation="select",outcome="success",store="postgres"} 2`,
+		`fhir_db_query_duration_seconds_sum{operation="select",outcome="success",store="postgres"} 0.01`,
+		`fhir_db_query_duration_seconds_bucket{operation="select",outcome="success",store="postgres",le="0.005"} 1`,
+		`fhir_db_query_duration_seconds_count{operation="insert",outcome="error",store="mongodb"} 1`,
+	} {
+		if !strings.Contains(exposition, expectedLine) {
+			t.Errorf("Expected %q in the exposition", expectedLine)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// unsupportedParameterName buckets query parameters that are not supported search parameters
+// so arbitrary client input cannot grow the metrics maps without bound
+const unsupportedParameterName = "_unsupported"
+
+// noParametersCombination is the combination key used for searches without any parameters
+const noParametersCombination = "(none)"
+
+// SearchRecorder tracks which search parameters and parameter combinations clients use
+// It is safe for concurrent use by multiple request goroutines
+type SearchRecorder struct {
+	mutex sync.Mutex
+
+	// supportedParameters lists the known search parameters per resource type
+	supportedParameters map[string][]string
+
+	// resources holds usage statistics keyed by resource type
+	resources map[string]*resourceSearchStats
+}
+
+// resourceSearchStats holds usage statistics for a single resource type
+type resourceSearchStats struct {
+	parameterCounts   map[string]int64
+	combinationCounts map[string]*CombinationStats
+}
+
+// CombinationStats holds count and latency figures for one parameter combination
+type CombinationStats struct {
+	Count          int64   `json:"count"`
+	TotalLatencyMs float64 `json:"total_latency_ms"`
+	AvgLatencyMs   float64 `json:"avg_latency_ms"`
+	MaxLatencyMs   float64 `json:"max_latency_ms"`
+}
+
+// ResourceSearchReport summarizes search parameter usage for a resource type
+type ResourceSearchReport struct {
+	ResourceType     string                      `json:"resource_type"`
+	ParameterCounts  map[string]int64            `json:"parameter_counts"`
+	UnusedParameters []string                    `json:"unused_parameters"`
+	Combinations     map[string]CombinationStats `json:"combinations"`
+}
+
+// NewSearchRecorder creates a recorder for the given supported parameters per resource type
+func NewSearchRecorder(supportedParameters map[string][]string) *SearchRecorder {
+	return &SearchRecorder{
+		supportedParameters: supportedParameters,
+		resources:           make(map[string]*resourceSearchStats),
+	}
+}
+
+// Record stores a single search execution with its parameter names and latency
+func (recorder *SearchRecorder) Record(resourceType string, parameterNames []string, latency time.Duration) {
+	// Normalize parameter names before taking the lock
+	normalizedNames := recorder.normalizeParameters(resourceType, parameterNames)
+	combinationKey := noParametersCombination
+	if len(normalizedNames) > 0 {
+		combinationKey = strings.Join(normalizedNames, "&")
+	}
+	latencyMs := float64(latency.Microseconds()) / 1000
+
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	// Lazily create the statistics for this resource type
+	stats, exists := recorder.resources[resourceType]
+	if !exists {
+		stats = &resourceSearchStats{
+			parameterCounts:   make(map[string]int64),
+			combinationCounts: make(map[string]*CombinationStats),
+		}
+		recorder.resources[resourceType] = stats
+	}
+
+	// Count each individual parameter
+	for _, parameterName := range normalizedNames {
+		stats.parameterCounts[parameterName]++
+	}
+
+	// Update the combination count and latency figures
+	combination, exists := stats.combinationCounts[combinationKey]
+	if !exists {
+		combination = &CombinationStats{}
+		stats.combinationCounts[combinationKey] = combination
+	}
+	combination.Count++
+	combination.TotalLatencyMs += latencyMs
+	if latencyMs > combination.MaxLatencyMs {
+		combination.MaxLatencyMs = latencyMs
+	}
+}
+
+// Report returns a point-in-time usage summary for every supported resource type
+func (recorder *SearchRecorder) Report() []ResourceSearchReport {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	// Report on every supported resource type, even those never searched
+	resourceTypes := make([]string, 0, len(recorder.supportedParameters))
+	for resourceType := range recorder.supportedParameters {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+
+	reports := make([]ResourceSearchReport, 0, len(resourceTypes))
+	for _, resourceType := range resourceTypes {
+		report := ResourceSearchReport{
+			ResourceType:     resourceType,
+			ParameterCounts:  make(map[string]int64),
+			UnusedParameters: []string{},
+			Combinations:     make(map[string]CombinationStats),
+		}
+
+		stats, exists := recorder.resources[resourceType]
+		if exists {
+			for parameterName, count := range stats.parameterCounts {
+				report.ParameterCounts[parameterName] = count
+			}
+			for combinationKey, combination := range stats.combinationCounts {
+				combinationCopy := *combination
+				combinationCopy.AvgLatencyMs = combination.TotalLatencyMs / float64(combination.Count)
+				report.Combinations[combinationKey] = combinationCopy
+			}
+		}
+
+		// Supported parameters never used are deprecation candidates
+		for _, parameterName := range recorder.supportedParameters[resourceType] {
+			if report.ParameterCounts[parameterName] == 0 {
+				report.UnusedParameters = append(report.UnusedParameters, parameterName)
+			}
+		}
+
+		reports = append(reports, report)
+	}
+
+	return reports
+}
+
+// Instrument wraps a search handler so every request records its parameters and latency
+func (recorder *SearchRecorder) Instrument(resourceType string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		startTime := time.Now()
+
+		next(w, r)
+
+		// Record the query parameter names (values are never stored)
+		parameterNames := make([]string, 0, len(r.URL.Query()))
+		for parameterName := range r.URL.Query() {
+			parameterNames = append(parameterNames, parameterName)
+		}
+		recorder.Record(resourceType, parameterNames, time.Since(startTime))
+	}
+}
+
+// normalizeParameters maps unknown parameters to a single bucket and returns a sorted, de-duplicated list
+func (recorder *SearchRecorder) normalizeParameters(resourceType string, parameterNames []string) []string {
+	// Build lookup of supported parameters (modifiers like name:exact count as the base parameter)
+	supported := make(map[string]bool, len(recorder.supportedParameters[resourceType]))
+	for _, parameterName := range recorder.supportedParameters[resourceType] {
+		supported[parameterName] = true
+	}
+
+	seen := make(map[string]bool, len(parameterNames))
+	normalizedNames := make([]string, 0, len(parameterNames))
+	for _, parameterName := range parameterNames {
+		baseName := strings.SplitN(parameterName, ":", 2)[0]
+		if !supported[baseName] {
+			baseName = unsupportedParameterName
+		}
+		if seen[baseName] {
+			continue
+		}
+		seen[baseName] = true
+		normalizedNames = append(normalizedNames, baseName)
+	}
+
+	sort.Strings(normalizedNames)
+	return normalizedNames
+}
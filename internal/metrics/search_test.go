@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newTestSearchRecorder creates a recorder with a small set of supported parameters
+func newTestSearchRecorder() *SearchRecorder {
+	return NewSearchRecorder(map[string][]string{
+		"Patient":     {"name", "gender", "birthdate"},
+		"Observation": {"patient", "code"},
+	})
+}
+
+// findReport returns the report for a resource type
+func findReport(t *testing.T, reports []ResourceSearchReport, resourceType string) ResourceSearchReport {
+	for _, report := range reports {
+		if report.ResourceType == resourceType {
+			return report
+		}
+	}
+	t.Fatalf("Expected report for %s", resourceType)
+	return ResourceSearchReport{}
+}
+
+// TestSearchRecorder_RecordCombination verifies combinations are keyed by sorted parameter names
+func TestSearchRecorder_RecordCombination(t *testing.T) {
+	recorder := newTestSearchRecorder()
+
+	recorder.Record("Patient", []string{"gender", "name"}, 10*time.Millisecond)
+	recorder.Record("Patient", []string{"name", "gender"}, 30*time.Millisecond)
+
+	report := findReport(t, recorder.Report(), "Patient")
+
+	combination, exists := report.Combinations["gender&name"]
+	if !exists {
+		t.Fatalf("Expected combination gender&name, got %v", report.Combinations)
+	}
+	if combination.Count != 2 {
+		t.Errorf("Expected count 2, got %d", combination.Count)
+	}
+	if combination.AvgLatencyMs != 20 {
+		t.Errorf("Expected average latency 20ms, got %f", combination.AvgLatencyMs)
+	}
+	if combination.MaxLatencyMs != 30 {
+		t.Errorf("Expected max latency 30ms, got %f", combination.MaxLatencyMs)
+	}
+	if report.ParameterCounts["name"] != 2 {
+		t.Errorf("Expected name count 2, got %d", report.ParameterCounts["name"])
+	}
+}
+
+// TestSearchRecorder_UnusedParameters verifies supported but unused parameters are reported
+func TestSearchRecorder_UnusedParameters(t *testing.T) {
+	recorder := newTestSearchRecorder()
+
+	recorder.Record("Patient", []string{"name"}, time.Millisecond)
+
+	report := findReport(t, recorder.Report(), "Patient")
+	if len(report.UnusedParameters) != 2 {
+		t.Fatalf("Expected 2 unused parameters, got %v", report.UnusedParameters)
+	}
+	if report.UnusedParameters[0] != "gender" || report.UnusedParameters[1] != "birthdate" {
+		t.Errorf("Unexpected unused parameters: %v", report.UnusedParameters)
+	}
+
+	// Never-searched resource types still report all parameters as unused
+	observationReport := findReport(t, recorder.Report(), "Observation")
+	if len(observationReport.UnusedParameters) != 2 {
+		t.Errorf("Expected all Observation parameters unused, got %v", observationReport.UnusedParameters)
+	}
+}
+
+// TestSearchRecorder_UnsupportedParametersBucketed verifies unknown parameters share one bucket
+func TestSearchRecorder_UnsupportedParametersBucketed(t *testing.T) {
+	recorder := newTestSearchRecorder()
+
+	recorder.Record("Patient", []string{"random1", "random2", "name:exact"}, time.Millisecond)
+
+	report := findReport(t, recorder.Report(), "Patient")
+	if _, exists := report.Combinations["_unsupported&name"]; !exists {
+		t.Errorf("Expected combination _unsupported&name, got %v", report.Combinations)
+	}
+	if report.ParameterCounts[unsupportedParameterName] != 1 {
+		t.Errorf("Expected unsupported count 1, got %d", report.ParameterCounts[unsupportedParameterName])
+	}
+}
+
+// TestSearchRecorder_NoParameters verifies parameterless searches are recorded
+func TestSearchRecorder_NoParameters(t *testing.T) {
+	recorder := newTestSearchRecorder()
+
+	recorder.Record("Observation", nil, time.Millisecond)
+
+	report := findReport(t, recorder.Report(), "Observation")
+	if report.Combinations[noParametersCombination].Count != 1 {
+		t.Errorf("Expected one parameterless search, got %v", report.Combinations)
+	}
+}
+
+// TestSearchRecorder_Instrument verifies the wrapper records query parameters
+func TestSearchRecorder_Instrument(t *testing.T) {
+	recorder := newTestSearchRecorder()
+
+	handlerCalled := false
+	instrumented := recorder.Instrument("Observation", func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?patient=123&code=8480-6", nil)
+	instrumented(httptest.NewRecorder(), request)
+
+	if !handlerCalled {
+		t.Error("Expected wrapped handler to be called")
+	}
+
+	report := findReport(t, recorder.Report(), "Observation")
+	if report.Combinations["code&patient"].Count != 1 {
+		t.Errorf("Expected combination code&patient, got %v", report.Combinations)
+	}
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/redact"
+	"github.com/rs/zerolog"
+)
+
+// DefaultBodyLogMaxBytes is how much of each body is captured for logging when no limit is configured
+const DefaultBodyLogMaxBytes = 64 * 1024
+
+// Reasons a body is left out of the log; only its size is logged
+const (
+	bodyOmittedTooLarge = "exceeds the body log limit"
+	bodyOmittedNotJSON  = "not JSON"
+)
+
+// bodyCaptureWriter passes the response through while keeping its first maxBytes bytes
+type bodyCaptureWriter struct {
+	http.ResponseWriter
+	statusCode   int
+	maxBytes     int
+	captured     bytes.Buffer
+	bytesWritten int
+}
+
+// WriteHeader captures the status code before writing
+func (writer *bodyCaptureWriter) WriteHeader(statusCode int) {
+	writer.statusCode = statusCode
+	writer.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write keeps a copy of the body up to the limit and counts the rest
+func (writer *bodyCaptureWriter) Write(b []byte) (int, error) {
+	if remaining := writer.maxBytes - writer.captured.Len(); remaining > 0 {
+		writer.captured.Write(b[:min(remaining, len(b))])
+	}
+	bytesWritten, writeError := writer.ResponseWriter.Write(b)
+	writer.bytesWritten += bytesWritten
+	return bytesWritten, writeError
+}
+
+// Unwrap exposes the underlying writer so http.ResponseController can flush streaming responses
+func (writer *bodyCaptureWriter) Unwrap() http.ResponseWriter {
+	return writer.ResponseWriter
+}
+
+// BodyLogger logs the request and response bodies of FHIR endpoints at debug level with protected health
+// information redacted, for troubleshooting
+// Bodies that are not JSON or NDJSON, or that are larger than maxBytes, are never logged, since they cannot be
+// redacted; only their size is. Nothing is buffered while the logger's debug level is disabled
+func BodyLogger(logger zerolog.Logger, redactor *redact.Redactor, maxBytes int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			debugDisabled := logger.GetLevel() > zerolog.DebugLevel || zerolog.GlobalLevel() > zerolog.DebugLevel
+			if debugDisabled || !strings.HasPrefix(r.URL.Path, "/fhir/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Read up to one byte past the limit to tell a full body from a truncated one, then hand the
+			// handler the whole body again
+			var requestBody []byte
+			if r.Body != nil && r.Body != http.NoBody {
+				requestBody, _ = io.ReadAll(io.LimitReader(r.Body, int64(maxBytes)+1))
+				r.Body = readCloser{Reader: io.MultiReader(bytes.NewReader(requestBody), r.Body), Closer: r.Body}
+			}
+
+			captureWriter := &bodyCaptureWriter{ResponseWriter: w, statusCode: http.StatusOK, maxBytes: maxBytes + 1}
+			next.ServeHTTP(captureWriter, r)
+
+			logEvent := logger.Debug().
+				Str("request_id", getRequestID(r.Context())).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str("query", redactor.RedactQuery(r.URL.Query())).
+				Int("status", captureWriter.statusCode)
+			if len(requestBody) > 0 {
+				// A body past the limit was only partly read, so its declared length is reported when known
+				requestSize := max(len(requestBody), int(r.ContentLength))
+				addBody(logEvent, redactor, "request", r.Header.Get("Content-Type"), requestBody, requestSize, maxBytes)
+			}
+			if captureWriter.bytesWritten > 0 {
+				addBody(logEvent, redactor, "response", captureWriter.Header().Get("Content-Type"),
+					captureWriter.captured.Bytes(), captureWriter.bytesWritten, maxBytes)
+			}
+			logEvent.Msg("HTTP bodies")
+		})
+	}
+}
+
+// addBody adds a redacted body to the log event as <prefix>_body, or the reason it was left out as
+// <prefix>_body_omitted, alongside its size in <prefix>_bytes
+// NDJSON bodies are logged as an array of their lines
+func addBody(logEvent *zerolog.Event, redactor *redact.Redactor, prefix string, contentType string, body []byte, bodySize int, maxBytes int) {
+	logEvent.Int(prefix+"_bytes", bodySize)
+	if bodySize > maxBytes {
+		logEvent.Str(prefix+"_body_omitted", bodyOmittedTooLarge)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if strings.HasSuffix(mediaType, "ndjson") {
+		redactedLines, redacted := redactor.RedactNDJSON(body)
+		if !redacted {
+			logEvent.Str(prefix+"_body_omitted", bodyOmittedNotJSON)
+			return
+		}
+		redactedBody, _ := json.Marshal(redactedLines)
+		logEvent.RawJSON(prefix+"_body", redactedBody)
+		return
+	}
+
+	redactedBody, redacted := redactor.RedactJSON(body)
+	if !redacted {
+		logEvent.Str(prefix+"_body_omitted", bodyOmittedNotJSON)
+		return
+	}
+	logEvent.RawJSON(prefix+"_body", redactedBody)
+}
+
+// readCloser joins the replayed request body with the original body's Close
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
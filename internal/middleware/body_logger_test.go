@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/redact"
+	"github.com/rs/zerolog"
+)
+
+// echoHandler answers with the request body it was given
+var echoHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	requestBody, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", r.Header.Get("Content-Type"))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(requestBody)
+})
+
+// TestBodyLogger_RedactsBodies verifies both bodies and the query are logged with protected data redacted,
+// and the handler still receives the whole request body
+func TestBodyLogger_RedactsBodies(t *testing.T) {
+	var logBuffer bytes.Buffer
+	bodyLogger := BodyLogger(zerolog.New(&logBuffer).Level(zerolog.DebugLevel), redact.NewRedactor(redact.Config{}), DefaultBodyLogMaxBytes)
+
+	requestBody := `{"resourceType":"Patient","gender":"female","name":[{"family":"Smith"}],"birthDate":"1980-02-03"}`
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient?family=Smith&_count=5", strings.NewReader(requestBody))
+	request.Header.Set("Content-Type", "application/fhir+json")
+	recorder := httptest.NewRecorder()
+	bodyLogger(echoHandler).ServeHTTP(recorder, request)
+
+	if recorder.Body.String() != requestBody {
+		t.Errorf("Expected the handler to receive the whole body, got %s", recorder.Body.String())
+	}
+	logOutput := logBuffer.String()
+	if strings.Contains(logOutput, "Smith") || strings.Contains(logOutput, "1980-02-03") {
+		t.Errorf("Expected protected data to be redacted, got %s", logOutput)
+	}
+	for _, expected := range []string{`"request_body":{`, `"response_body":{`, `"gender":"female"`, `_count=5`, `"status":201`} {
+		if !strings.Contains(logOutput, expected) {
+			t.Errorf("Expected log to contain %s, got %s", expected, logOutput)
+		}
+	}
+}
+
+// TestBodyLogger_OmitsUnredactableBodies verifies bodies that are not JSON or are too large are left out
+func TestBodyLogger_OmitsUnredactableBodies(t *testing.T) {
+	var logBuffer bytes.Buffer
+	bodyLogger := BodyLogger(zerolog.New(&logBuffer).Level(zerolog.DebugLevel), redact.NewRedactor(redact.Config{}), 32)
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/$hl7v2", strings.NewReader("MSH|^~\\&|LAB|PID|||12345||Smith^Jane"))
+	bodyLogger(echoHandler).ServeHTTP(httptest.NewRecorder(), request)
+	if strings.Contains(logBuffer.String(), "Smith") || !strings.Contains(logBuffer.String(), `"request_body_omitted":"exceeds the body log limit"`) {
+		t.Errorf("Expected the large body to be omitted, got %s", logBuffer.String())
+	}
+
+	logBuffer.Reset()
+	request = httptest.NewRequest(http.MethodPost, "/fhir/$hl7v2", strings.NewReader("PID|||1||Smith"))
+	bodyLogger(echoHandler).ServeHTTP(httptest.NewRecorder(), request)
+	if strings.Contains(logBuffer.String(), "Smith") || !strings.Contains(logBuffer.String(), `"request_body_omitted":"not JSON"`) {
+		t.Errorf("Expected the non-JSON body to be omitted, got %s", logBuffer.String())
+	}
+}
+
+// TestBodyLogger_NDJSON verifies NDJSON responses are logged as an array of redacted lines
+func TestBodyLogger_NDJSON(t *testing.T) {
+	var logBuffer bytes.Buffer
+	bodyLogger := BodyLogger(zerolog.New(&logBuffer).Level(zerolog.DebugLevel), redact.NewRedactor(redact.Config{}), DefaultBodyLogMaxBytes)
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient/$import", strings.NewReader("{\"id\":\"1\",\"name\":\"Smith\"}\n{\"id\":\"2\"}\n"))
+	request.Header.Set("Content-Type", "application/fhir+ndjson")
+	bodyLogger(echoHandler).ServeHTTP(httptest.NewRecorder(), request)
+	if !strings.Contains(logBuffer.String(), `"response_body":[{"id":"1","name":"[REDACTED]"},{"id":"2"}]`) {
+		t.Errorf("Expected the redacted lines as an array, got %s", logBuffer.String())
+	}
+}
+
+// TestBodyLogger_SkipsWhenNotDebugging verifies nothing is logged outside FHIR endpoints or above debug level
+func TestBodyLogger_SkipsWhenNotDebugging(t *testing.T) {
+	var logBuffer bytes.Buffer
+	redactor := redact.NewRedactor(redact.Config{})
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", strings.NewReader(`{"id":"1"}`))
+	BodyLogger(zerolog.New(&logBuffer).Level(zerolog.InfoLevel), redactor, DefaultBodyLogMaxBytes)(echoHandler).ServeHTTP(httptest.NewRecorder(), request)
+
+	request = httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(`{"id":"1"}`))
+	BodyLogger(zerolog.New(&logBuffer).Level(zerolog.DebugLevel), redactor, DefaultBodyLogMaxBytes)(echoHandler).ServeHTTP(httptest.NewRecorder(), request)
+
+	if logBuffer.Len() != 0 {
+		t.Errorf("Expected nothing logged, got %s", logBuffer.String())
+	}
+}
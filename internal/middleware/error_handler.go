@@ -84,6 +84,11 @@ type errorResponseWriter struct {
 	request *http.Request
 }
 
+// Unwrap exposes the underlying writer so http.ResponseController can flush streaming responses
+func (errorWriter *errorResponseWriter) Unwrap() http.ResponseWriter {
+	return errorWriter.ResponseWriter
+}
+
 // getRequestID retrieves request ID from context
 func getRequestID(ctx context.Context) string {
 	if requestID, ok := ctx.Value(RequestIDKey).(string); ok {
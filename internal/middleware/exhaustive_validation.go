@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// HeaderValidationMode lets a client choose how many problems a rejected write reports
+const HeaderValidationMode = "X-Validation-Mode"
+
+// Validation modes accepted in X-Validation-Mode
+const (
+	// ValidationModeExhaustive reports every problem found in the body in one OperationOutcome
+	ValidationModeExhaustive = "exhaustive"
+
+	// ValidationModeFirstError stops at the first problem, as validation always has
+	ValidationModeFirstError = "first-error"
+)
+
+// maxValidationIssues caps the issues reported for one payload so a hostile body cannot inflate the response
+const maxValidationIssues = 100
+
+// jsonNumberType marks decimal elements, which the models keep as json.Number strings
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// jsonUnmarshalerType identifies model types, such as code enums, that parse their own JSON
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// ReferenceChecker reports why a reference in a request body cannot be used, or nil when it can
+// It sees references as the client sent them, before any exposed ID is translated
+type ReferenceChecker func(ctx context.Context, reference string) error
+
+// ValidatorOptions configures the FHIR validator middleware
+type ValidatorOptions struct {
+	// Limits caps element counts in a single resource
+	Limits ResourceLimits
+
+	// StrictParsing rejects elements the resource type does not define; Prefer: handling= overrides it
+	StrictParsing bool
+
+	// Exhaustive reports every problem by default; X-Validation-Mode overrides it
+	Exhaustive bool
+
+	// ReferenceChecker, when set, lets exhaustive validation report references that do not resolve
+	ReferenceChecker ReferenceChecker
+}
+
+// exhaustiveValidationRequested reports whether the request asked for every problem to be reported
+func exhaustiveValidationRequested(r *http.Request, defaultExhaustive bool) bool {
+	switch strings.ToLower(strings.TrimSpace(r.Header.Get(HeaderValidationMode))) {
+	case ValidationModeExhaustive:
+		return true
+	case ValidationModeFirstError:
+		return false
+	}
+	return defaultExhaustive
+}
+
+// ValidateExhaustively checks a request body for every problem the validator knows, rather than stopping
+// at the first: size limits, malformed JSON, unknown elements, elements of the wrong JSON type, invalid
+// codes, missing required elements, and unusable references
+// Unknown elements are errors when strictParsing is set and warnings otherwise
+// resourceType is the type from the URL path; "" for operation bodies such as $meta-add Parameters
+func ValidateExhaustively(ctx context.Context, resourceType string, bodyBytes []byte, limits ResourceLimits, strictParsing bool, referenceChecker ReferenceChecker) []outcome.Issue {
+	issues := limits.Check(resourceType, bodyBytes)
+
+	var body interface{}
+	if decodeError := json.Unmarshal(bodyBytes, &body); decodeError != nil {
+		return append(issues, outcome.Issue{
+			Severity:    fhir.IssueSeverityFatal,
+			Code:        fhir.IssueTypeStructure,
+			Diagnostics: "Body is not valid JSON: " + decodeError.Error(),
+		})
+	}
+	object, isObject := body.(map[string]interface{})
+	if !isObject {
+		return append(issues, outcome.Error(fhir.IssueTypeStructure, "Body must be a JSON object"))
+	}
+
+	if bodyResourceType, isString := object["resourceType"].(string); isString && bodyResourceType != "" {
+		if resourceType != "" && bodyResourceType != resourceType {
+			issues = append(issues, outcome.Error(fhir.IssueTypeInvalid,
+				fmt.Sprintf("resourceType '%s' does not match the %s endpoint", bodyResourceType, resourceType), "resourceType"))
+		}
+		resourceType = bodyResourceType
+	}
+	modelType, supported := strictResourceTypes[resourceType]
+	if !supported {
+		return issues
+	}
+
+	for _, unknownIssue := range UnknownElements(resourceType, bodyBytes) {
+		if !strictParsing {
+			unknownIssue.Severity = fhir.IssueSeverityWarning
+		}
+		issues = append(issues, unknownIssue)
+	}
+
+	delete(object, "resourceType")
+	collectElementIssues(object, modelType, resourceType, &issues)
+	issues = append(issues, requiredElementIssues(resourceType, object)...)
+	issues = append(issues, referenceIssues(ctx, resourceType, object, referenceChecker)...)
+
+	if len(issues) > maxValidationIssues {
+		issues = append(issues[:maxValidationIssues], outcome.Information(fhir.IssueTypeTooCostly,
+			fmt.Sprintf("Only the first %d issues are reported", maxValidationIssues)))
+	}
+	return issues
+}
+
+// collectElementIssues walks value alongside the model type, appending an issue for each element whose
+// JSON type does not match its definition and each code the element's value set does not contain
+// Undefined elements are skipped; UnknownElements reports them
+func collectElementIssues(value interface{}, modelType reflect.Type, path string, issues *[]outcome.Issue) {
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	if value == nil || modelType == rawMessageType || len(*issues) >= maxValidationIssues {
+		return
+	}
+
+	switch {
+	case modelType == jsonNumberType:
+		if _, isNumber := value.(float64); !isNumber {
+			*issues = append(*issues, elementTypeIssue(path, "a number", value))
+		}
+	case modelType.Kind() != reflect.Struct && reflect.PointerTo(modelType).Implements(jsonUnmarshalerType):
+		collectCodeIssue(value, modelType, path, issues)
+	case modelType.Kind() == reflect.Slice:
+		elements, isArray := value.([]interface{})
+		if !isArray {
+			*issues = append(*issues, elementTypeIssue(path, "an array", value))
+			return
+		}
+		for elementIndex, element := range elements {
+			collectElementIssues(element, modelType.Elem(), path+"["+strconv.Itoa(elementIndex)+"]", issues)
+		}
+	case modelType.Kind() == reflect.Struct:
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			*issues = append(*issues, elementTypeIssue(path, "an object", value))
+			return
+		}
+		fieldTypes := jsonFieldTypes(modelType)
+		for _, elementName := range sortedKeys(object) {
+			if fieldType, known := fieldTypes[elementName]; known {
+				collectElementIssues(object[elementName], fieldType, path+"."+elementName, issues)
+			}
+		}
+	case modelType.Kind() == reflect.String:
+		if _, isString := value.(string); !isString {
+			*issues = append(*issues, elementTypeIssue(path, "a string", value))
+		}
+	case modelType.Kind() == reflect.Bool:
+		if _, isBool := value.(bool); !isBool {
+			*issues = append(*issues, elementTypeIssue(path, "true or false", value))
+		}
+	case modelType.Kind() >= reflect.Int && modelType.Kind() <= reflect.Uint64:
+		if number, isNumber := value.(float64); !isNumber || number != math.Trunc(number) {
+			*issues = append(*issues, elementTypeIssue(path, "an integer", value))
+		}
+	}
+}
+
+// collectCodeIssue checks a coded element against the codes its model accepts
+func collectCodeIssue(value interface{}, modelType reflect.Type, path string, issues *[]outcome.Issue) {
+	code, isString := value.(string)
+	if !isString {
+		*issues = append(*issues, elementTypeIssue(path, "a code string", value))
+		return
+	}
+	codeJSON, _ := json.Marshal(code)
+	if unmarshalError := reflect.New(modelType).Interface().(json.Unmarshaler).UnmarshalJSON(codeJSON); unmarshalError != nil {
+		*issues = append(*issues, outcome.Error(fhir.IssueTypeCodeInvalid, fmt.Sprintf("'%s' is not a valid code for %s", code, path), path))
+	}
+}
+
+// elementTypeIssue describes an element whose JSON value has the wrong type
+func elementTypeIssue(path string, expected string, value interface{}) outcome.Issue {
+	return outcome.Error(fhir.IssueTypeStructure, fmt.Sprintf("%s must be %s, got %s", path, expected, jsonTypeName(value)), path)
+}
+
+// jsonTypeName names the JSON type of a decoded value for diagnostics
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "a string"
+	case float64:
+		return "a number"
+	case bool:
+		return "a boolean"
+	case []interface{}:
+		return "an array"
+	case map[string]interface{}:
+		return "an object"
+	default:
+		return "null"
+	}
+}
+
+// requiredElementIssues reports required elements the body leaves out
+// Patients need a name with a family or given part; observations need a status and a code, as FHIR requires
+func requiredElementIssues(resourceType string, body map[string]interface{}) []outcome.Issue {
+	var issues []outcome.Issue
+	switch resourceType {
+	case "Patient":
+		names, _ := body["name"].([]interface{})
+		if len(names) == 0 {
+			return []outcome.Issue{outcome.Error(fhir.IssueTypeRequired, "Patient must have at least one name", "Patient.name")}
+		}
+		for _, name := range names {
+			nameObject, _ := name.(map[string]interface{})
+			if family, _ := nameObject["family"].(string); family != "" {
+				return nil
+			}
+			givenNames, _ := nameObject["given"].([]interface{})
+			for _, givenName := range givenNames {
+				if given, _ := givenName.(string); given != "" {
+					return nil
+				}
+			}
+		}
+		issues = append(issues, outcome.Error(fhir.IssueTypeRequired, "Patient name must have family or given name", "Patient.name"))
+	case "Observation":
+		if _, present := body["status"]; !present {
+			issues = append(issues, outcome.Error(fhir.IssueTypeRequired, "Observation.status is required", "Observation.status"))
+		}
+		if !hasCodeOrText(body["code"]) {
+			issues = append(issues, outcome.Error(fhir.IssueTypeRequired, "Observation.code needs a coding with a code, or text", "Observation.code"))
+		}
+	}
+	return issues
+}
+
+// hasCodeOrText reports whether a CodeableConcept has a coding with a code or a text
+func hasCodeOrText(concept interface{}) bool {
+	conceptObject, _ := concept.(map[string]interface{})
+	if text, _ := conceptObject["text"].(string); text != "" {
+		return true
+	}
+	codings, _ := conceptObject["coding"].([]interface{})
+	for _, coding := range codings {
+		codingObject, _ := coding.(map[string]interface{})
+		if code, _ := codingObject["code"].(string); code != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceIssues checks an observation's subject: it must be a relative Patient reference and, when a
+// checker is configured, one the checker accepts
+func referenceIssues(ctx context.Context, resourceType string, body map[string]interface{}, referenceChecker ReferenceChecker) []outcome.Issue {
+	if resourceType != "Observation" {
+		return nil
+	}
+	subject, present := body["subject"].(map[string]interface{})
+	if !present {
+		return nil
+	}
+	reference, isString := subject["reference"].(string)
+	if !isString || reference == "" {
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeRequired, "Observation.subject must have a reference", "Observation.subject.reference")}
+	}
+
+	if referenceChecker != nil {
+		if checkError := referenceChecker(ctx, reference); checkError != nil {
+			return []outcome.Issue{referenceCheckIssue(reference, checkError)}
+		}
+	}
+	if patientID, isPatient := strings.CutPrefix(reference, "Patient/"); !isPatient || patientID == "" || strings.Contains(patientID, "/") {
+		return []outcome.Issue{outcome.Error(fhir.IssueTypeInvalid,
+			fmt.Sprintf("Observation.subject reference '%s' must be a relative Patient reference such as Patient/123", reference), "Observation.subject.reference")}
+	}
+	return nil
+}
+
+// referenceCheckIssue classifies a reference checker's refusal by the HTTP status it carries
+func referenceCheckIssue(reference string, checkError error) outcome.Issue {
+	issueCode := fhir.IssueTypeException
+	var appError *apperrors.AppError
+	if errors.As(checkError, &appError) {
+		switch appError.StatusCode {
+		case http.StatusNotFound, http.StatusGone, http.StatusBadRequest:
+			issueCode = fhir.IssueTypeNotFound
+		case http.StatusForbidden:
+			issueCode = fhir.IssueTypeForbidden
+		}
+		return outcome.Error(issueCode, fmt.Sprintf("Observation.subject reference '%s': %s", reference, appError.Message), "Observation.subject.reference")
+	}
+	return outcome.Error(issueCode, fmt.Sprintf("Observation.subject reference '%s' could not be checked", reference), "Observation.subject.reference")
+}
@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// issueExpressions lists the first expression of each issue, or "" for issues without one
+func issueExpressions(issues []outcome.Issue) []string {
+	expressions := make([]string, len(issues))
+	for issueIndex, issue := range issues {
+		if len(issue.Expression) > 0 {
+			expressions[issueIndex] = issue.Expression[0]
+		}
+	}
+	return expressions
+}
+
+// TestValidateExhaustively verifies every problem in a body is reported with its element path
+func TestValidateExhaustively(t *testing.T) {
+	testCases := []struct {
+		name                string
+		resourceType        string
+		body                string
+		expectedExpressions []string
+	}{
+		{"valid patient", "Patient", `{"resourceType":"Patient","name":[{"family":"Smith"}],"gender":"female","active":true}`, []string{}},
+		{"every patient problem", "Patient", `{"resourceType":"Patient","name":[{"given":[""]}],"gender":"mle","active":"yes","birthdate":"1980-01-01","identifier":{"value":"x"}}`,
+			[]string{"Patient.birthdate", "Patient.active", "Patient.gender", "Patient.identifier", "Patient.name"}},
+		{"observation required elements", "Observation", `{"resourceType":"Observation","code":{"coding":[{"system":"http://loinc.org"}]}}`,
+			[]string{"Observation.status", "Observation.code"}},
+		{"observation types and references", "Observation", `{"resourceType":"Observation","status":"done","code":{"text":"Heart rate"},"valueQuantity":{"value":"72"},"subject":{"reference":"Group/1"}}`,
+			[]string{"Observation.status", "Observation.valueQuantity.value", "Observation.subject.reference"}},
+		{"mismatched resource type", "Patient", `{"resourceType":"Observation","status":"final","code":{"text":"x"}}`, []string{"resourceType"}},
+		{"operation body", "", `{"resourceType":"Parameters","parameter":[{"name":"meta","valueMeta":{"tag":[{"code":"vip"}]}}]}`, []string{}},
+		{"malformed JSON", "Patient", `{"name":`, []string{""}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			issues := ValidateExhaustively(context.Background(), testCase.resourceType, []byte(testCase.body), DefaultResourceLimits(), false, nil)
+			expressions := issueExpressions(issues)
+			if strings.Join(expressions, ",") != strings.Join(testCase.expectedExpressions, ",") {
+				t.Errorf("Expected issues at %v, got %+v", testCase.expectedExpressions, issues)
+			}
+		})
+	}
+}
+
+// TestValidateExhaustively_UnknownElementSeverity verifies unknown elements only fail strict validation
+func TestValidateExhaustively_UnknownElementSeverity(t *testing.T) {
+	body := []byte(`{"resourceType":"Patient","name":[{"family":"Smith"}],"birthdate":"1980-01-01"}`)
+
+	if lenientIssues := ValidateExhaustively(context.Background(), "Patient", body, DefaultResourceLimits(), false, nil); outcome.HasErrors(lenientIssues) || len(lenientIssues) != 1 {
+		t.Errorf("Expected one warning when lenient, got %+v", lenientIssues)
+	}
+	if strictIssues := ValidateExhaustively(context.Background(), "Patient", body, DefaultResourceLimits(), true, nil); !outcome.HasErrors(strictIssues) {
+		t.Errorf("Expected an error when strict, got %+v", strictIssues)
+	}
+}
+
+// TestValidateExhaustively_ReferenceChecker verifies unresolvable subjects are reported by their checker's status
+func TestValidateExhaustively_ReferenceChecker(t *testing.T) {
+	checker := func(ctx context.Context, reference string) error {
+		if reference == "Patient/missing" {
+			return apperrors.NotFound("Patient", "missing")
+		}
+		return nil
+	}
+
+	missingBody := []byte(`{"resourceType":"Observation","status":"final","code":{"text":"x"},"subject":{"reference":"Patient/missing"}}`)
+	issues := ValidateExhaustively(context.Background(), "Observation", missingBody, DefaultResourceLimits(), false, checker)
+	if len(issues) != 1 || issues[0].Code != fhir.IssueTypeNotFound {
+		t.Errorf("Expected one not-found issue, got %+v", issues)
+	}
+
+	knownBody := []byte(`{"resourceType":"Observation","status":"final","code":{"text":"x"},"subject":{"reference":"Patient/known"}}`)
+	if knownIssues := ValidateExhaustively(context.Background(), "Observation", knownBody, DefaultResourceLimits(), false, checker); len(knownIssues) != 0 {
+		t.Errorf("Expected no issues for a resolvable subject, got %+v", knownIssues)
+	}
+}
+
+// TestNewFHIRValidatorWithOptions_Exhaustive verifies the mode header and server default choose how many problems are reported
+func TestNewFHIRValidatorWithOptions_Exhaustive(t *testing.T) {
+	brokenPatient := `{"resourceType":"Patient","gender":"mle","active":"yes"}`
+
+	testCases := []struct {
+		name              string
+		exhaustive        bool
+		modeHeader        string
+		expectedFHIRIssue int
+	}{
+		{"first error by default", false, "", 0},
+		{"client asks for every error", false, ValidationModeExhaustive, 3},
+		{"exhaustive server", true, "", 3},
+		{"client asks for the first error", true, ValidationModeFirstError, 0},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			handler := NewFHIRValidatorWithOptions(ValidatorOptions{Limits: DefaultResourceLimits(), Exhaustive: testCase.exhaustive})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+			request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", bytes.NewBufferString(brokenPatient))
+			if testCase.modeHeader != "" {
+				request.Header.Set(HeaderValidationMode, testCase.modeHeader)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("Expected 400, got %d", recorder.Code)
+			}
+			if testCase.expectedFHIRIssue == 0 {
+				if !strings.Contains(recorder.Body.String(), "unknown AdministrativeGender code") || strings.Contains(recorder.Body.String(), "active") {
+					t.Errorf("Expected the first error only, got %s", recorder.Body.String())
+				}
+				return
+			}
+			var operationOutcome fhir.OperationOutcome
+			json.NewDecoder(recorder.Body).Decode(&operationOutcome)
+			if len(operationOutcome.Issue) != testCase.expectedFHIRIssue {
+				t.Errorf("Expected %d issues, got %s", testCase.expectedFHIRIssue, recorder.Body.String())
+			}
+		})
+	}
+}
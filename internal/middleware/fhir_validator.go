@@ -5,13 +5,36 @@ import (
 	"encoding/json"
 	"io"
 	"net/http"
+	"strings"
 
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
 	"github.com/rs/zerolog/log"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
-// FHIRValidator middleware validates FHIR resource structure
+// FHIRValidator middleware validates FHIR resource structure using the default resource limits
 func FHIRValidator(next http.Handler) http.Handler {
+	return NewFHIRValidator(DefaultResourceLimits(), false)(next)
+}
+
+// NewFHIRValidator creates a validator middleware that also enforces the given resource limits
+// With strictParsing, bodies containing elements their resource type does not define are rejected
+// instead of having them silently dropped; clients can override it with Prefer: handling=
+func NewFHIRValidator(limits ResourceLimits, strictParsing bool) func(http.Handler) http.Handler {
+	return NewFHIRValidatorWithOptions(ValidatorOptions{Limits: limits, StrictParsing: strictParsing})
+}
+
+// NewFHIRValidatorWithOptions creates a validator middleware that can also report every problem in a body at once
+func NewFHIRValidatorWithOptions(options ValidatorOptions) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return fhirValidatorHandler(options, next)
+	}
+}
+
+// fhirValidatorHandler validates FHIR write requests before passing them to next
+func fhirValidatorHandler(options ValidatorOptions, next http.Handler) http.Handler {
+	limits := options.Limits
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Only validate POST and PUT requests with bodies
 		if r.Method != http.MethodPost && r.Method != http.MethodPut {
@@ -34,12 +57,69 @@ func FHIRValidator(next http.Handler) http.Handler {
 		}
 		defer r.Body.Close()
 
+		// Convert FHIR XML to JSON so every check below, and the handlers after them, read JSON
+		if encoding.RequestFormat(r) == encoding.FormatXML {
+			jsonBytes, convertError := encoding.XMLToJSON(bodyBytes)
+			if convertError != nil {
+				log.Warn().Err(convertError).Str("path", r.URL.Path).Msg("Failed to read FHIR XML body")
+				outcome.WriteForRequest(w, r, http.StatusBadRequest, []outcome.Issue{outcome.Error(fhir.IssueTypeStructure, convertError.Error())})
+				return
+			}
+			bodyBytes = jsonBytes
+			r.Header.Set("Content-Type", encoding.JSONMediaType)
+			r.ContentLength = int64(len(bodyBytes))
+		}
+
 		// Restore the body for downstream handlers
 		r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 		// Determine resource type from path
 		resourceType := extractResourceType(r.URL.Path)
 
+		// Report every problem at once when asked, instead of stopping at the first
+		if exhaustiveValidationRequested(r, options.Exhaustive) {
+			strictParsing := strictParsingRequested(r, options.StrictParsing)
+			issues := ValidateExhaustively(r.Context(), bodyResourceType(r.URL.Path, resourceType), bodyBytes, limits, strictParsing, options.ReferenceChecker)
+			if outcome.HasErrors(issues) {
+				log.Warn().
+					Str("resource_type", resourceType).
+					Str("path", r.URL.Path).
+					Int("issues", len(issues)).
+					Msg("FHIR validation failed")
+
+				outcome.WriteForRequest(w, r, http.StatusBadRequest, issues)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Reject pathological payloads before they reach mapping or storage
+		if limitIssues := limits.Check(resourceType, bodyBytes); len(limitIssues) > 0 {
+			log.Warn().
+				Str("resource_type", resourceType).
+				Str("path", r.URL.Path).
+				Int("violations", len(limitIssues)).
+				Msg("FHIR resource exceeds size limits")
+
+			outcome.WriteForRequest(w, r, http.StatusUnprocessableEntity, limitIssues)
+			return
+		}
+
+		// Reject unknown and misspelled elements before validation reports them as missing
+		if strictParsingRequested(r, options.StrictParsing) {
+			if unknownIssues := UnknownElements(bodyResourceType(r.URL.Path, resourceType), bodyBytes); len(unknownIssues) > 0 {
+				log.Warn().
+					Str("resource_type", resourceType).
+					Str("path", r.URL.Path).
+					Int("unknown_elements", len(unknownIssues)).
+					Msg("FHIR resource contains unknown elements")
+
+				outcome.WriteForRequest(w, r, http.StatusBadRequest, unknownIssues)
+				return
+			}
+		}
+
 		// Validate based on resource type
 		validationError := validateFHIRResource(bodyBytes, resourceType)
 		if validationError != nil {
@@ -88,6 +168,15 @@ func extractResourceType(path string) string {
 	return resourcePath
 }
 
+// bodyResourceType returns the resource type a body without resourceType is assumed to be
+// Operation bodies, such as the Parameters of $meta-add, are not the type named in the path
+func bodyResourceType(path string, resourceType string) string {
+	if strings.Contains(path, "/$") {
+		return ""
+	}
+	return resourceType
+}
+
 // validateFHIRResource validates a FHIR resource based on its type
 func validateFHIRResource(bodyBytes []byte, resourceType string) error {
 	switch resourceType {
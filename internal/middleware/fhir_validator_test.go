@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"bytes"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -105,6 +106,43 @@ func TestFHIRValidator_InvalidJSON(t *testing.T) {
 	}
 }
 
+// TestFHIRValidator_XMLBody verifies XML bodies are validated and passed on as JSON, and malformed XML gets a 400
+func TestFHIRValidator_XMLBody(t *testing.T) {
+	var receivedBody string
+	var receivedContentType string
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodyBytes, _ := io.ReadAll(r.Body)
+		receivedBody = string(bodyBytes)
+		receivedContentType = r.Header.Get("Content-Type")
+		w.WriteHeader(http.StatusOK)
+	})
+	validatorMiddleware := FHIRValidator(testHandler)
+
+	serveXML := func(xmlBody string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", bytes.NewBufferString(xmlBody))
+		request.Header.Set("Content-Type", "application/fhir+xml")
+		recorder := httptest.NewRecorder()
+		validatorMiddleware.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	if recorder := serveXML(`<Patient xmlns="http://hl7.org/fhir"><name><family value="Smith"/></name></Patient>`); recorder.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for a valid XML patient, got %d: %s", recorder.Code, recorder.Body.String())
+	}
+	if receivedContentType != "application/fhir+json" || receivedBody != `{"name":[{"family":"Smith"}],"resourceType":"Patient"}` {
+		t.Errorf("Expected the handler to receive the patient as JSON, got %s %s", receivedContentType, receivedBody)
+	}
+
+	if recorder := serveXML(`<Patient xmlns="http://hl7.org/fhir"><gender value="male"/></Patient>`); recorder.Code != http.StatusBadRequest {
+		t.Errorf("Expected status 400 for an XML patient without a name, got %d", recorder.Code)
+	}
+
+	malformedRecorder := serveXML(`<Patient xmlns="http://hl7.org/fhir"><name>`)
+	if malformedRecorder.Code != http.StatusBadRequest || !strings.Contains(malformedRecorder.Body.String(), "OperationOutcome") {
+		t.Errorf("Expected a 400 OperationOutcome for malformed XML, got %d: %s", malformedRecorder.Code, malformedRecorder.Body.String())
+	}
+}
+
 // TestFHIRValidator_GETRequest verifies GET requests skip validation
 func TestFHIRValidator_GETRequest(t *testing.T) {
 	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -35,6 +35,11 @@ func (rw *responseWriter) Write(b []byte) (int, error) {
 	return bytesWritten, writeError
 }
 
+// Unwrap exposes the underlying writer so http.ResponseController can flush streaming responses
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 // Logger middleware logs HTTP requests with structured logging using zerolog
 func Logger(logger zerolog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
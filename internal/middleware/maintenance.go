@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+)
+
+// drainPollInterval is how often WaitForDrain re-checks the in-flight count
+const drainPollInterval = 50 * time.Millisecond
+
+// OperationalState tracks maintenance mode, connection draining, and in-flight requests
+// It is shared between the Maintenance middleware and the admin operations endpoints
+type OperationalState struct {
+	maintenance atomic.Bool
+	draining    atomic.Bool
+	inFlight    atomic.Int64
+
+	// retryAfter is advertised to clients rejected during maintenance or drain
+	retryAfter time.Duration
+}
+
+// NewOperationalState creates a state in normal operation with the given Retry-After hint
+func NewOperationalState(retryAfter time.Duration) *OperationalState {
+	return &OperationalState{
+		retryAfter: retryAfter,
+	}
+}
+
+// SetMaintenance turns maintenance mode on or off
+func (state *OperationalState) SetMaintenance(enabled bool) {
+	state.maintenance.Store(enabled)
+}
+
+// Maintenance reports whether maintenance mode is on
+func (state *OperationalState) Maintenance() bool {
+	return state.maintenance.Load()
+}
+
+// SetDraining starts or stops connection draining
+func (state *OperationalState) SetDraining(enabled bool) {
+	state.draining.Store(enabled)
+}
+
+// Draining reports whether the server is draining connections
+func (state *OperationalState) Draining() bool {
+	return state.draining.Load()
+}
+
+// InFlight returns the number of requests currently being processed
+func (state *OperationalState) InFlight() int64 {
+	return state.inFlight.Load()
+}
+
+// RetryAfter returns the Retry-After hint given to rejected clients
+func (state *OperationalState) RetryAfter() time.Duration {
+	return state.retryAfter
+}
+
+// WaitForDrain blocks until no requests are in flight or the context ends
+func (state *OperationalState) WaitForDrain(ctx context.Context) error {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for state.InFlight() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+
+	return nil
+}
+
+// Maintenance middleware enforces maintenance mode and draining, and counts in-flight requests
+// Admin endpoints are exempt so operators can always inspect and change the state
+func Maintenance(state *OperationalState) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Admin requests are neither blocked nor counted, so a drain can wait on everything else
+			if strings.HasPrefix(r.URL.Path, "/admin/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// While draining, refuse new work and ask clients to reconnect elsewhere
+			if state.Draining() {
+				w.Header().Set("Connection", "close")
+				writeRetryAfter(w, state.retryAfter)
+				WriteError(w, r, apperrors.ServiceUnavailable("Server is draining connections"))
+				return
+			}
+
+			// In maintenance mode reads still work but writes are rejected
+			if state.Maintenance() && isWriteMethod(r.Method) {
+				writeRetryAfter(w, state.retryAfter)
+				WriteError(w, r, apperrors.ServiceUnavailable("Server is in maintenance mode; writes are temporarily disabled"))
+				return
+			}
+
+			state.inFlight.Add(1)
+			defer state.inFlight.Add(-1)
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isWriteMethod reports whether the HTTP method modifies data
+func isWriteMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// writeRetryAfter sets the Retry-After header in whole seconds
+func writeRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter <= 0 {
+		return
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+}
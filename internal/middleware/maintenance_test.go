@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// okHandler responds with 200 OK
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// TestMaintenance_NormalOperation verifies requests pass through when no mode is active
+func TestMaintenance_NormalOperation(t *testing.T) {
+	state := NewOperationalState(30 * time.Second)
+	handler := Maintenance(state)(okHandler)
+
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", recorder.Code)
+	}
+}
+
+// TestMaintenance_BlocksWritesAllowsReads verifies maintenance mode only rejects writes
+func TestMaintenance_BlocksWritesAllowsReads(t *testing.T) {
+	state := NewOperationalState(30 * time.Second)
+	state.SetMaintenance(true)
+	handler := Maintenance(state)(okHandler)
+
+	readRequest := httptest.NewRequest(http.MethodGet, "/fhir/Patient", nil)
+	readRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(readRecorder, readRequest)
+	if readRecorder.Code != http.StatusOK {
+		t.Errorf("Expected reads to succeed, got %d", readRecorder.Code)
+	}
+
+	for _, method := range []string{http.MethodPost, http.MethodPut, http.MethodDelete} {
+		writeRequest := httptest.NewRequest(method, "/fhir/Patient/1", nil)
+		writeRecorder := httptest.NewRecorder()
+		handler.ServeHTTP(writeRecorder, writeRequest)
+
+		if writeRecorder.Code != http.StatusServiceUnavailable {
+			t.Errorf("Expected %s to return 503, got %d", method, writeRecorder.Code)
+		}
+		if writeRecorder.Header().Get("Retry-After") != "30" {
+			t.Errorf("Expected Retry-After 30, got %q", writeRecorder.Header().Get("Retry-After"))
+		}
+	}
+}
+
+// TestMaintenance_DrainingRejectsAll verifies draining refuses new requests except admin
+func TestMaintenance_DrainingRejectsAll(t *testing.T) {
+	state := NewOperationalState(5 * time.Second)
+	state.SetDraining(true)
+	handler := Maintenance(state)(okHandler)
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient", nil)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 while draining, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Connection") != "close" {
+		t.Error("Expected Connection: close while draining")
+	}
+
+	adminRequest := httptest.NewRequest(http.MethodGet, "/admin/operations", nil)
+	adminRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(adminRecorder, adminRequest)
+	if adminRecorder.Code != http.StatusOK {
+		t.Errorf("Expected admin request to pass, got %d", adminRecorder.Code)
+	}
+}
+
+// TestMaintenance_CountsInFlight verifies in-flight requests are tracked
+func TestMaintenance_CountsInFlight(t *testing.T) {
+	state := NewOperationalState(0)
+	release := make(chan struct{})
+	started := make(chan struct{})
+	blockingHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	handler := Maintenance(state)(blockingHandler)
+
+	go handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fhir/Patient", nil))
+	<-started
+
+	if state.InFlight() != 1 {
+		t.Errorf("Expected 1 in-flight request, got %d", state.InFlight())
+	}
+
+	// Draining should time out while the request is still running
+	shortContext, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if state.WaitForDrain(shortContext) == nil {
+		t.Error("Expected drain to time out with a request in flight")
+	}
+
+	close(release)
+	if drainError := state.WaitForDrain(context.Background()); drainError != nil {
+		t.Errorf("Expected drain to complete, got %v", drainError)
+	}
+}
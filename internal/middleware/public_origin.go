@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// ParsePublicBaseURL parses the address clients reach the server at, such as https://fhir.example.org
+// It must be an absolute http or https URL without a path, query, or credentials
+func ParsePublicBaseURL(rawBaseURL string) (*url.URL, error) {
+	baseURL, parseError := url.Parse(rawBaseURL)
+	if parseError != nil {
+		return nil, fmt.Errorf("invalid public base URL %q: %w", rawBaseURL, parseError)
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" {
+		return nil, fmt.Errorf("public base URL %q must use http or https", rawBaseURL)
+	}
+	if baseURL.Host == "" || baseURL.User != nil {
+		return nil, fmt.Errorf("public base URL %q must name a host without credentials", rawBaseURL)
+	}
+	if strings.Trim(baseURL.Path, "/") != "" || baseURL.RawQuery != "" || baseURL.Fragment != "" {
+		return nil, fmt.Errorf("public base URL %q must not have a path, query, or fragment", rawBaseURL)
+	}
+	return &url.URL{Scheme: baseURL.Scheme, Host: baseURL.Host}, nil
+}
+
+// PublicOrigin records the scheme and host clients reached the server at on each request, so absolute links
+// built from the request point there rather than at the internal address a TLS-terminating proxy forwarded to
+// With a public base URL it is used for every request; when nil, X-Forwarded-Proto and X-Forwarded-Host
+// are honoured, as they only change links sent back to the client that set them
+func PublicOrigin(publicBaseURL *url.URL) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicBaseURL != nil {
+				r.URL.Scheme = publicBaseURL.Scheme
+				r.Host = publicBaseURL.Host
+				next.ServeHTTP(w, r)
+				return
+			}
+			if forwardedProto := strings.ToLower(firstForwardedValue(r.Header.Get("X-Forwarded-Proto"))); forwardedProto == "http" || forwardedProto == "https" {
+				r.URL.Scheme = forwardedProto
+			}
+			if forwardedHost := firstForwardedValue(r.Header.Get("X-Forwarded-Host")); validForwardedHost(forwardedHost) {
+				r.Host = forwardedHost
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequestScheme returns the scheme the client used to reach the server: the one PublicOrigin recorded,
+// otherwise https for requests that arrived over TLS and http for the rest
+func RequestScheme(r *http.Request) string {
+	if r.URL.Scheme != "" {
+		return r.URL.Scheme
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// firstForwardedValue returns the first entry of a forwarded header, the one the outermost proxy set
+func firstForwardedValue(headerValue string) string {
+	firstValue, _, _ := strings.Cut(headerValue, ",")
+	return strings.TrimSpace(firstValue)
+}
+
+// validForwardedHost reports whether a forwarded host is a bare host[:port] safe to put in a link
+func validForwardedHost(host string) bool {
+	if host == "" || strings.ContainsAny(host, "/\\@?# \t") {
+		return false
+	}
+	parsed, parseError := url.Parse("http://" + host)
+	return parseError == nil && parsed.Host == host
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// ResourceLimits caps the element counts accepted in a single resource; zero means unlimited
+type ResourceLimits struct {
+	MaxObservationComponents int `json:"max_observation_components"`
+	MaxPatientNames          int `json:"max_patient_names"`
+	MaxPatientIdentifiers    int `json:"max_patient_identifiers"`
+	MaxContainedResources    int `json:"max_contained_resources"`
+	MaxBundleEntries         int `json:"max_bundle_entries"`
+}
+
+// DefaultResourceLimits returns limits generous enough for real clinical data
+// while rejecting payloads that would only be produced by a broken or hostile client
+func DefaultResourceLimits() ResourceLimits {
+	return ResourceLimits{
+		MaxObservationComponents: 50,
+		MaxPatientNames:          20,
+		MaxPatientIdentifiers:    50,
+		MaxContainedResources:    100,
+		MaxBundleEntries:         1000,
+	}
+}
+
+// LoadResourceLimits reads limits from a JSON file; fields omitted from the file keep their defaults
+func LoadResourceLimits(path string) (ResourceLimits, error) {
+	limits := DefaultResourceLimits()
+
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return limits, fmt.Errorf("failed to read resource limits: %w", readError)
+	}
+
+	if decodeError := json.Unmarshal(fileBytes, &limits); decodeError != nil {
+		return limits, fmt.Errorf("failed to parse resource limits: %w", decodeError)
+	}
+
+	return limits, nil
+}
+
+// resourceElementCounts decodes only the repeating elements that limits apply to,
+// leaving their contents as raw JSON so oversized payloads are never fully mapped
+type resourceElementCounts struct {
+	ResourceType string            `json:"resourceType"`
+	Name         []json.RawMessage `json:"name"`
+	Identifier   []json.RawMessage `json:"identifier"`
+	Component    []json.RawMessage `json:"component"`
+	Contained    []json.RawMessage `json:"contained"`
+	Entry        []struct {
+		Resource json.RawMessage `json:"resource"`
+	} `json:"entry"`
+}
+
+// Check returns an issue for every limit the resource exceeds
+// The resource type in the body takes precedence over the one from the URL path
+// Bundle entries are checked individually so a transaction cannot smuggle oversized resources
+func (limits ResourceLimits) Check(resourceType string, bodyBytes []byte) []outcome.Issue {
+	var counts resourceElementCounts
+	if decodeError := json.Unmarshal(bodyBytes, &counts); decodeError != nil {
+		// Malformed JSON is reported by structural validation
+		return nil
+	}
+
+	if counts.ResourceType != "" {
+		resourceType = counts.ResourceType
+	}
+
+	return limits.checkCounts(resourceType, &counts)
+}
+
+// checkCounts applies the limits for one decoded resource
+func (limits ResourceLimits) checkCounts(resourceType string, counts *resourceElementCounts) []outcome.Issue {
+	var issues []outcome.Issue
+
+	issues = appendLimitIssue(issues, len(counts.Contained), limits.MaxContainedResources, resourceType+".contained")
+
+	switch resourceType {
+	case "Patient":
+		issues = appendLimitIssue(issues, len(counts.Name), limits.MaxPatientNames, "Patient.name")
+		issues = appendLimitIssue(issues, len(counts.Identifier), limits.MaxPatientIdentifiers, "Patient.identifier")
+	case "Observation":
+		issues = appendLimitIssue(issues, len(counts.Component), limits.MaxObservationComponents, "Observation.component")
+	case "Bundle":
+		issues = appendLimitIssue(issues, len(counts.Entry), limits.MaxBundleEntries, "Bundle.entry")
+		if len(issues) > 0 {
+			// Do not inspect entries of a bundle that is already rejected
+			return issues
+		}
+
+		for entryIndex, entry := range counts.Entry {
+			var entryCounts resourceElementCounts
+			if len(entry.Resource) == 0 || json.Unmarshal(entry.Resource, &entryCounts) != nil {
+				continue
+			}
+			for _, entryIssue := range limits.checkCounts(entryCounts.ResourceType, &entryCounts) {
+				entryIssue.Diagnostics = fmt.Sprintf("Bundle.entry[%d]: %s", entryIndex, entryIssue.Diagnostics)
+				elementPath := strings.TrimPrefix(entryIssue.Expression[0], entryCounts.ResourceType+".")
+				entryIssue.Expression = []string{fmt.Sprintf("Bundle.entry[%d].resource.%s", entryIndex, elementPath)}
+				issues = append(issues, entryIssue)
+			}
+		}
+	}
+
+	return issues
+}
+
+// appendLimitIssue adds an issue when count exceeds a non-zero limit
+func appendLimitIssue(issues []outcome.Issue, count int, limit int, expression string) []outcome.Issue {
+	if limit <= 0 || count <= limit {
+		return issues
+	}
+
+	return append(issues, outcome.Error(
+		fhir.IssueTypeTooCostly,
+		fmt.Sprintf("%s has %d elements, which exceeds the limit of %d", expression, count, limit),
+		expression,
+	))
+}
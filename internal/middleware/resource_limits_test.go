@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// repeatJSON returns a JSON array body with count copies of element
+func repeatJSON(element string, count int) string {
+	elements := make([]string, count)
+	for index := range elements {
+		elements[index] = element
+	}
+	return "[" + strings.Join(elements, ",") + "]"
+}
+
+// TestResourceLimits_Check verifies each limit is enforced only when exceeded
+func TestResourceLimits_Check(t *testing.T) {
+	limits := ResourceLimits{
+		MaxObservationComponents: 2,
+		MaxPatientNames:          1,
+		MaxPatientIdentifiers:    2,
+		MaxContainedResources:    1,
+		MaxBundleEntries:         2,
+	}
+
+	testCases := []struct {
+		name               string
+		resourceType       string
+		body               string
+		expectedExpression []string
+	}{
+		{"patient within limits", "Patient", `{"name":[{"family":"Smith"}],"identifier":[{},{}]}`, nil},
+		{"too many names", "Patient", `{"name":[{},{}]}`, []string{"Patient.name"}},
+		{"too many identifiers and contained", "Patient", `{"identifier":` + repeatJSON("{}", 3) + `,"contained":[{},{}]}`, []string{"Patient.contained", "Patient.identifier"}},
+		{"too many components", "Observation", `{"component":` + repeatJSON("{}", 3) + `}`, []string{"Observation.component"}},
+		{"body resource type wins", "", `{"resourceType":"Observation","component":` + repeatJSON("{}", 3) + `}`, []string{"Observation.component"}},
+		{"too many bundle entries", "", `{"resourceType":"Bundle","entry":` + repeatJSON("{}", 3) + `}`, []string{"Bundle.entry"}},
+		{"oversized bundle entry", "", `{"resourceType":"Bundle","entry":[{},{"resource":{"resourceType":"Patient","name":[{},{}]}}]}`, []string{"Bundle.entry[1].resource.name"}},
+		{"malformed JSON is left to validation", "Patient", `{"name":`, nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			issues := limits.Check(testCase.resourceType, []byte(testCase.body))
+
+			if len(issues) != len(testCase.expectedExpression) {
+				t.Fatalf("Expected %d issues, got %+v", len(testCase.expectedExpression), issues)
+			}
+			for index, issue := range issues {
+				if issue.Expression[0] != testCase.expectedExpression[index] {
+					t.Errorf("Expected expression %s, got %s", testCase.expectedExpression[index], issue.Expression[0])
+				}
+			}
+		})
+	}
+}
+
+// TestResourceLimits_ZeroIsUnlimited verifies zero disables a limit
+func TestResourceLimits_ZeroIsUnlimited(t *testing.T) {
+	issues := ResourceLimits{}.Check("Observation", []byte(`{"component":`+repeatJSON("{}", 500)+`}`))
+
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues with zero limits, got %+v", issues)
+	}
+}
+
+// TestLoadResourceLimits verifies omitted fields keep their defaults
+func TestLoadResourceLimits(t *testing.T) {
+	limitsPath := filepath.Join(t.TempDir(), "limits.json")
+	os.WriteFile(limitsPath, []byte(`{"max_bundle_entries": 10}`), 0o600)
+
+	limits, loadError := LoadResourceLimits(limitsPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if limits.MaxBundleEntries != 10 {
+		t.Errorf("Expected overridden bundle limit 10, got %d", limits.MaxBundleEntries)
+	}
+	if limits.MaxPatientNames != DefaultResourceLimits().MaxPatientNames {
+		t.Errorf("Expected default patient name limit, got %d", limits.MaxPatientNames)
+	}
+
+	if _, missingError := LoadResourceLimits(filepath.Join(t.TempDir(), "missing.json")); missingError == nil {
+		t.Error("Expected error for missing file")
+	}
+}
+
+// TestNewFHIRValidator_RejectsOversizedResource verifies limits produce a 422 OperationOutcome
+func TestNewFHIRValidator_RejectsOversizedResource(t *testing.T) {
+	testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Handler should not be called for an oversized resource")
+	})
+	validatorMiddleware := NewFHIRValidator(ResourceLimits{MaxPatientNames: 2}, false)(testHandler)
+
+	patientJSON := fmt.Sprintf(`{"resourceType":"Patient","name":%s}`, repeatJSON(`{"family":"Smith"}`, 3))
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", bytes.NewBufferString(patientJSON))
+	recorder := httptest.NewRecorder()
+
+	validatorMiddleware.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnprocessableEntity {
+		t.Errorf("Expected status 422, got %d", recorder.Code)
+	}
+	if !strings.Contains(recorder.Body.String(), "OperationOutcome") || !strings.Contains(recorder.Body.String(), "exceeds the limit of 2") {
+		t.Errorf("Expected OperationOutcome describing the limit, got: %s", recorder.Body.String())
+	}
+}
@@ -0,0 +1,193 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// maxUnknownElementIssues caps the issues reported for one payload so a hostile body cannot inflate the response
+const maxUnknownElementIssues = 20
+
+// maxSuggestionDistance is the largest edit distance at which an unknown element is treated as a misspelling
+const maxSuggestionDistance = 2
+
+// strictResourceTypes maps the resource types accepted in request bodies to the models whose elements they may use
+var strictResourceTypes = map[string]reflect.Type{
+	"Patient":            reflect.TypeOf(fhir.Patient{}),
+	"Observation":        reflect.TypeOf(fhir.Observation{}),
+	"Parameters":         reflect.TypeOf(fhir.Parameters{}),
+	"Practitioner":       reflect.TypeOf(fhir.Practitioner{}),
+	"Encounter":          reflect.TypeOf(fhir.Encounter{}),
+	"Condition":          reflect.TypeOf(fhir.Condition{}),
+	"MedicationRequest":  reflect.TypeOf(fhir.MedicationRequest{}),
+	"AllergyIntolerance": reflect.TypeOf(fhir.AllergyIntolerance{}),
+	"DiagnosticReport":   reflect.TypeOf(fhir.DiagnosticReport{}),
+	"Immunization":       reflect.TypeOf(fhir.Immunization{}),
+}
+
+// rawMessageType marks elements kept as raw JSON, such as contained resources, which are not checked
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// strictParsingRequested reports whether unknown elements should be rejected for the request
+// Prefer: handling=strict or handling=lenient overrides the server default, as FHIR specifies
+func strictParsingRequested(r *http.Request, defaultStrict bool) bool {
+	for _, preference := range strings.Split(r.Header.Get("Prefer"), ",") {
+		switch strings.ToLower(strings.TrimSpace(preference)) {
+		case "handling=strict":
+			return true
+		case "handling=lenient":
+			return false
+		}
+	}
+	return defaultStrict
+}
+
+// UnknownElements returns an issue for every element of the body that the resource type does not define,
+// suggesting the intended element when the name looks misspelled
+// The body's resourceType takes precedence over the one from the URL path; unsupported types and
+// malformed JSON return no issues and are left to structural validation
+func UnknownElements(resourceType string, bodyBytes []byte) []outcome.Issue {
+	var body map[string]interface{}
+	if decodeError := json.Unmarshal(bodyBytes, &body); decodeError != nil {
+		return nil
+	}
+	if bodyResourceType, isString := body["resourceType"].(string); isString && bodyResourceType != "" {
+		resourceType = bodyResourceType
+	}
+
+	modelType, supported := strictResourceTypes[resourceType]
+	if !supported {
+		return nil
+	}
+
+	delete(body, "resourceType")
+	var issues []outcome.Issue
+	collectUnknownElements(body, modelType, resourceType, &issues)
+	return issues
+}
+
+// collectUnknownElements walks value alongside the model type, appending an issue for each undefined element
+// path is the FHIRPath expression of value
+func collectUnknownElements(value interface{}, modelType reflect.Type, path string, issues *[]outcome.Issue) {
+	for modelType.Kind() == reflect.Pointer {
+		modelType = modelType.Elem()
+	}
+	if modelType == rawMessageType {
+		return
+	}
+
+	switch modelType.Kind() {
+	case reflect.Slice:
+		elements, isArray := value.([]interface{})
+		if !isArray {
+			return
+		}
+		for elementIndex, element := range elements {
+			collectUnknownElements(element, modelType.Elem(), path+"["+strconv.Itoa(elementIndex)+"]", issues)
+		}
+	case reflect.Struct:
+		object, isObject := value.(map[string]interface{})
+		if !isObject {
+			return
+		}
+		fieldTypes := jsonFieldTypes(modelType)
+		for _, elementName := range sortedKeys(object) {
+			if len(*issues) >= maxUnknownElementIssues {
+				return
+			}
+			if fieldType, known := fieldTypes[elementName]; known {
+				collectUnknownElements(object[elementName], fieldType, path+"."+elementName, issues)
+				continue
+			}
+			// _element carries the id and extensions of the primitive element of the same name
+			if _, primitiveKnown := fieldTypes[strings.TrimPrefix(elementName, "_")]; primitiveKnown && strings.HasPrefix(elementName, "_") {
+				continue
+			}
+			*issues = append(*issues, unknownElementIssue(elementName, path, fieldTypes))
+		}
+	}
+}
+
+// unknownElementIssue describes one undefined element, naming the closest defined element when it is a likely misspelling
+func unknownElementIssue(elementName string, path string, fieldTypes map[string]reflect.Type) outcome.Issue {
+	diagnostics := fmt.Sprintf("Unknown element '%s' in %s", elementName, path)
+	if suggestion := closestElement(elementName, fieldTypes); suggestion != "" {
+		diagnostics += fmt.Sprintf("; did you mean '%s'?", suggestion)
+	}
+	return outcome.Error(fhir.IssueTypeStructure, diagnostics, path+"."+elementName)
+}
+
+// closestElement returns the defined element that differs only in case from elementName, or else the
+// nearest one within maxSuggestionDistance edits, or "" when none is close
+func closestElement(elementName string, fieldTypes map[string]reflect.Type) string {
+	closestName := ""
+	closestDistance := maxSuggestionDistance + 1
+	for _, candidate := range sortedKeys(fieldTypes) {
+		if strings.EqualFold(candidate, elementName) {
+			return candidate
+		}
+		if distance := editDistance(strings.ToLower(elementName), strings.ToLower(candidate)); distance < closestDistance {
+			closestName = candidate
+			closestDistance = distance
+		}
+	}
+	// Very short names are within a couple of edits of too many elements to be a useful hint
+	if len(elementName) <= maxSuggestionDistance+1 {
+		return ""
+	}
+	return closestName
+}
+
+// jsonFieldTypes maps the JSON element names of a model struct to their Go types
+func jsonFieldTypes(modelType reflect.Type) map[string]reflect.Type {
+	fieldTypes := make(map[string]reflect.Type, modelType.NumField())
+	for fieldIndex := 0; fieldIndex < modelType.NumField(); fieldIndex++ {
+		field := modelType.Field(fieldIndex)
+		jsonName := strings.Split(field.Tag.Get("json"), ",")[0]
+		if jsonName == "" || jsonName == "-" {
+			continue
+		}
+		fieldTypes[jsonName] = field.Type
+	}
+	return fieldTypes
+}
+
+// sortedKeys returns the keys of a map in order, so issues are reported deterministically
+func sortedKeys[Value any](values map[string]Value) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// editDistance returns the Levenshtein distance between two strings
+func editDistance(first string, second string) int {
+	previousRow := make([]int, len(second)+1)
+	for columnIndex := range previousRow {
+		previousRow[columnIndex] = columnIndex
+	}
+
+	for rowIndex := 1; rowIndex <= len(first); rowIndex++ {
+		currentRow := make([]int, len(second)+1)
+		currentRow[0] = rowIndex
+		for columnIndex := 1; columnIndex <= len(second); columnIndex++ {
+			substitutionCost := 1
+			if first[rowIndex-1] == second[columnIndex-1] {
+				substitutionCost = 0
+			}
+			currentRow[columnIndex] = min(previousRow[columnIndex]+1, currentRow[columnIndex-1]+1, previousRow[columnIndex-1]+substitutionCost)
+		}
+		previousRow = currentRow
+	}
+	return previousRow[len(second)]
+}
@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestUnknownElements verifies undefined elements are reported with their path and a likely correction
+func TestUnknownElements(t *testing.T) {
+	testCases := []struct {
+		name                string
+		resourceType        string
+		body                string
+		expectedDiagnostics []string
+	}{
+		{"known elements", "Patient", `{"resourceType":"Patient","name":[{"family":"Smith"}],"birthDate":"1980-01-01","_birthDate":{"extension":[]}}`, nil},
+		{"case misspelling", "Patient", `{"resourceType":"Patient","birthdate":"1980-01-01"}`, []string{"Unknown element 'birthdate' in Patient; did you mean 'birthDate'?"}},
+		{"nested typo", "Patient", `{"name":[{"famly":"Smith"}]}`, []string{"Unknown element 'famly' in Patient.name[0]; did you mean 'family'?"}},
+		{"no close match", "Observation", `{"resourceType":"Observation","status":"final","favouriteColour":"blue"}`, []string{"Unknown element 'favouriteColour' in Observation"}},
+		{"body type wins", "Patient", `{"resourceType":"Parameters","parameter":[{"name":"meta","valueMeta":{"tag":[]}}]}`, nil},
+		{"unsupported type", "Device", `{"resourceType":"Device","anything":true}`, nil},
+		{"malformed JSON", "Patient", `{"name":`, nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			issues := UnknownElements(testCase.resourceType, []byte(testCase.body))
+			if len(issues) != len(testCase.expectedDiagnostics) {
+				t.Fatalf("Expected %d issues, got %+v", len(testCase.expectedDiagnostics), issues)
+			}
+			for issueIndex, expectedDiagnostics := range testCase.expectedDiagnostics {
+				if issues[issueIndex].Diagnostics != expectedDiagnostics {
+					t.Errorf("Expected %q, got %q", expectedDiagnostics, issues[issueIndex].Diagnostics)
+				}
+			}
+		})
+	}
+}
+
+// TestNewFHIRValidator_StrictParsing verifies strict mode rejects unknown elements and Prefer: handling overrides it
+func TestNewFHIRValidator_StrictParsing(t *testing.T) {
+	misspelledPatient := `{"resourceType":"Patient","name":[{"family":"Smith"}],"birthdate":"1980-01-01"}`
+
+	testCases := []struct {
+		name           string
+		strictParsing  bool
+		preferHeader   string
+		expectedStatus int
+	}{
+		{"lenient by default", false, "", http.StatusOK},
+		{"strict server", true, "", http.StatusBadRequest},
+		{"client asks for strict", false, "handling=strict", http.StatusBadRequest},
+		{"client asks for lenient", true, "return=representation, handling=lenient", http.StatusOK},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			validatorMiddleware := NewFHIRValidator(DefaultResourceLimits(), testCase.strictParsing)(testHandler)
+
+			request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", bytes.NewBufferString(misspelledPatient))
+			if testCase.preferHeader != "" {
+				request.Header.Set("Prefer", testCase.preferHeader)
+			}
+			recorder := httptest.NewRecorder()
+			validatorMiddleware.ServeHTTP(recorder, request)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if testCase.expectedStatus == http.StatusBadRequest && !strings.Contains(recorder.Body.String(), "did you mean 'birthDate'?") {
+				t.Errorf("Expected an OperationOutcome suggesting birthDate, got: %s", recorder.Body.String())
+			}
+		})
+	}
+}
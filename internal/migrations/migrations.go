@@ -0,0 +1,272 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	sqlfiles "github.com/nathannewyen/fhir-health-interop/migrations"
+)
+
+// migrationFilePattern matches migration file names such as 001_create_patients_table.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// advisoryLockKey identifies the PostgreSQL advisory lock held while migrating, so servers starting
+// together with auto-migration apply each migration once
+const advisoryLockKey = 7204431
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version int
+	Name    string
+
+	// Up applies the change and Down reverts it
+	Up   string
+	Down string
+}
+
+// Status is a migration and whether it has been applied
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads the migrations in fsys, ordered by version
+// Every version needs an up file; a missing down file leaves the migration irreversible
+func Load(fsys fs.FS) ([]Migration, error) {
+	fileNames, globError := fs.Glob(fsys, "*.sql")
+	if globError != nil {
+		return nil, globError
+	}
+
+	migrationsByVersion := map[int]*Migration{}
+	for _, fileName := range fileNames {
+		matches := migrationFilePattern.FindStringSubmatch(fileName)
+		if matches == nil {
+			return nil, fmt.Errorf("migration file %s is not named NNN_name.up.sql or NNN_name.down.sql", fileName)
+		}
+		version, _ := strconv.Atoi(matches[1])
+		contents, readError := fs.ReadFile(fsys, fileName)
+		if readError != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", fileName, readError)
+		}
+
+		migration, exists := migrationsByVersion[version]
+		if !exists {
+			migration = &Migration{Version: version, Name: matches[2]}
+			migrationsByVersion[version] = migration
+		}
+		if migration.Name != matches[2] {
+			return nil, fmt.Errorf("migration version %d is used by both %s and %s", version, migration.Name, matches[2])
+		}
+		if matches[3] == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(migrationsByVersion))
+	for _, migration := range migrationsByVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(first Migration, second Migration) int { return first.Version - second.Version })
+	return migrations, nil
+}
+
+// Embedded returns the migrations shipped with the server
+func Embedded() ([]Migration, error) {
+	return Load(sqlfiles.Files)
+}
+
+// Migrator applies and reverts migrations, recording applied versions in the schema_migrations table
+type Migrator struct {
+	databaseConnection *sql.DB
+	migrations         []Migration
+}
+
+// NewMigrator creates a migrator for the given migrations, which must be ordered by version
+func NewMigrator(databaseConnection *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{
+		databaseConnection: databaseConnection,
+		migrations:         migrations,
+	}
+}
+
+// Up applies every migration not applied yet, in version order, and returns those it applied
+// Each migration runs in its own transaction with its schema_migrations row, so a failure leaves the
+// earlier migrations applied and the failed one not
+func (migrator *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	lockError := migrator.withLock(ctx, func(conn *sql.Conn) error {
+		appliedVersions, readError := appliedVersions(ctx, conn)
+		if readError != nil {
+			return readError
+		}
+		for _, migration := range migrator.migrations {
+			if _, alreadyApplied := appliedVersions[migration.Version]; alreadyApplied {
+				continue
+			}
+			applyError := inTransaction(ctx, conn, func(transaction *sql.Tx) error {
+				if _, execError := transaction.ExecContext(ctx, migration.Up); execError != nil {
+					return execError
+				}
+				_, recordError := transaction.ExecContext(ctx, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2)`, migration.Version, migration.Name)
+				return recordError
+			})
+			if applyError != nil {
+				return fmt.Errorf("migration %03d_%s failed: %w", migration.Version, migration.Name, applyError)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, lockError
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns those it reverted
+func (migrator *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	lockError := migrator.withLock(ctx, func(conn *sql.Conn) error {
+		appliedVersions, readError := appliedVersions(ctx, conn)
+		if readError != nil {
+			return readError
+		}
+		for migrationIndex := len(migrator.migrations) - 1; migrationIndex >= 0 && len(reverted) < steps; migrationIndex-- {
+			migration := migrator.migrations[migrationIndex]
+			if _, isApplied := appliedVersions[migration.Version]; !isApplied {
+				continue
+			}
+			if migration.Down == "" {
+				return fmt.Errorf("migration %03d_%s has no down file and cannot be reverted", migration.Version, migration.Name)
+			}
+			revertError := inTransaction(ctx, conn, func(transaction *sql.Tx) error {
+				if _, execError := transaction.ExecContext(ctx, migration.Down); execError != nil {
+					return execError
+				}
+				_, recordError := transaction.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+				return recordError
+			})
+			if revertError != nil {
+				return fmt.Errorf("reverting migration %03d_%s failed: %w", migration.Version, migration.Name, revertError)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+	return reverted, lockError
+}
+
+// Baseline records every migration up to and including version as applied without running it, for
+// databases whose schema was created by running the SQL files by hand
+func (migrator *Migrator) Baseline(ctx context.Context, version int) ([]Migration, error) {
+	var recorded []Migration
+	lockError := migrator.withLock(ctx, func(conn *sql.Conn) error {
+		for _, migration := range migrator.migrations {
+			if migration.Version > version {
+				break
+			}
+			insertResult, insertError := conn.ExecContext(ctx, `
+				INSERT INTO schema_migrations (version, name) VALUES ($1, $2)
+				ON CONFLICT (version) DO NOTHING
+			`, migration.Version, migration.Name)
+			if insertError != nil {
+				return insertError
+			}
+			if inserted, _ := insertResult.RowsAffected(); inserted == 1 {
+				recorded = append(recorded, migration)
+			}
+		}
+		return nil
+	})
+	return recorded, lockError
+}
+
+// Status lists every migration with when it was applied, nil for pending ones
+func (migrator *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	lockError := migrator.withLock(ctx, func(conn *sql.Conn) error {
+		appliedVersions, readError := appliedVersions(ctx, conn)
+		if readError != nil {
+			return readError
+		}
+		for _, migration := range migrator.migrations {
+			status := Status{Migration: migration}
+			if appliedAt, isApplied := appliedVersions[migration.Version]; isApplied {
+				status.AppliedAt = &appliedAt
+			}
+			statuses = append(statuses, status)
+		}
+		return nil
+	})
+	return statuses, lockError
+}
+
+// withLock runs work on one connection holding the migration advisory lock, after creating the
+// schema_migrations table if needed
+func (migrator *Migrator) withLock(ctx context.Context, work func(conn *sql.Conn) error) error {
+	conn, connError := migrator.databaseConnection.Conn(ctx)
+	if connError != nil {
+		return fmt.Errorf("failed to get a database connection: %w", connError)
+	}
+	defer conn.Close()
+
+	// Session advisory locks belong to the connection, so the lock and the migrations share one
+	if _, lockError := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); lockError != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", lockError)
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, advisoryLockKey)
+
+	_, createError := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if createError != nil {
+		return fmt.Errorf("failed to create the schema_migrations table: %w", createError)
+	}
+	return work(conn)
+}
+
+// appliedVersions returns when each applied migration version was applied
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, queryError := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations`)
+	if queryError != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", queryError)
+	}
+	defer rows.Close()
+
+	versions := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if scanError := rows.Scan(&version, &appliedAt); scanError != nil {
+			return nil, scanError
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// inTransaction runs work in a transaction on conn, committing when it succeeds
+func inTransaction(ctx context.Context, conn *sql.Conn, work func(transaction *sql.Tx) error) error {
+	transaction, beginError := conn.BeginTx(ctx, nil)
+	if beginError != nil {
+		return beginError
+	}
+	if workError := work(transaction); workError != nil {
+		transaction.Rollback()
+		return workError
+	}
+	return transaction.Commit()
+}
@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestLoad verifies up and down files are paired and ordered by version
+func TestLoad(t *testing.T) {
+	files := fstest.MapFS{
+		"002_add_tags.up.sql":       {Data: []byte("ALTER TABLE patients ADD COLUMN tags TEXT;")},
+		"002_add_tags.down.sql":     {Data: []byte("ALTER TABLE patients DROP COLUMN tags;")},
+		"001_create_table.up.sql":   {Data: []byte("CREATE TABLE patients (id TEXT);")},
+		"010_add_index.up.sql":      {Data: []byte("CREATE INDEX idx ON patients (id);")},
+		"001_create_table.down.sql": {Data: []byte("DROP TABLE patients;")},
+	}
+
+	migrations, loadError := Load(files)
+	if loadError != nil {
+		t.Fatalf("Expected the migrations to load, got %v", loadError)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("Expected 3 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[1].Version != 2 || migrations[2].Version != 10 {
+		t.Errorf("Expected versions 1, 2, 10, got %d, %d, %d", migrations[0].Version, migrations[1].Version, migrations[2].Version)
+	}
+	if migrations[1].Name != "add_tags" || migrations[1].Down != "ALTER TABLE patients DROP COLUMN tags;" {
+		t.Errorf("Expected add_tags with its down file, got %+v", migrations[1])
+	}
+	if migrations[2].Down != "" {
+		t.Errorf("Expected a migration without a down file to have no Down, got %q", migrations[2].Down)
+	}
+}
+
+// TestLoad_Invalid verifies misnamed files, missing up files, and reused versions are rejected
+func TestLoad_Invalid(t *testing.T) {
+	testCases := []struct {
+		name          string
+		files         fstest.MapFS
+		expectedError string
+	}{
+		{
+			name:          "misnamed file",
+			files:         fstest.MapFS{"create_table.sql": {Data: []byte("SELECT 1;")}},
+			expectedError: "is not named",
+		},
+		{
+			name:          "down without up",
+			files:         fstest.MapFS{"001_create_table.down.sql": {Data: []byte("DROP TABLE patients;")}},
+			expectedError: "has no up file",
+		},
+		{
+			name: "version reused",
+			files: fstest.MapFS{
+				"001_create_table.up.sql": {Data: []byte("SELECT 1;")},
+				"001_add_index.up.sql":    {Data: []byte("SELECT 2;")},
+			},
+			expectedError: "is used by both",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, loadError := Load(testCase.files)
+			if loadError == nil || !strings.Contains(loadError.Error(), testCase.expectedError) {
+				t.Errorf("Expected an error containing %q, got %v", testCase.expectedError, loadError)
+			}
+		})
+	}
+}
+
+// TestEmbedded verifies every shipped migration loads with a down file and versions have no gaps
+func TestEmbedded(t *testing.T) {
+	migrations, loadError := Embedded()
+	if loadError != nil {
+		t.Fatalf("Expected the embedded migrations to load, got %v", loadError)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("Expected embedded migrations")
+	}
+	for migrationIndex, migration := range migrations {
+		if migration.Version != migrationIndex+1 {
+			t.Errorf("Expected version %d, got %03d_%s", migrationIndex+1, migration.Version, migration.Name)
+		}
+		if migration.Down == "" {
+			t.Errorf("Expected migration %03d_%s to have a down file", migration.Version, migration.Name)
+		}
+	}
+}
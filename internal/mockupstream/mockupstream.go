@@ -0,0 +1,400 @@
+package mockupstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/nathannewyen/fhir-health-interop/internal/demo"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// FaultHeader lets a test choose a request's quirks: "error" always fails it, "none" skips every quirk
+const FaultHeader = "X-Mock-Fault"
+
+// Fault header values
+const (
+	FaultError = "error"
+	FaultNone  = "none"
+)
+
+// defaultPageSize is the search page size when _count is not given
+const defaultPageSize = 10
+
+// Config shapes the canned data and the quirks of the mock upstream
+type Config struct {
+	// Patients is how many canned patients to serve; each gets one heart rate observation
+	Patients int
+
+	// Latency delays every response, imitating a slow upstream
+	Latency time.Duration
+
+	// Jitter adds up to this much random delay on top of Latency
+	Jitter time.Duration
+
+	// ErrorRate is the fraction of requests answered with a 500 OperationOutcome (0 to 1)
+	ErrorRate float64
+
+	// Seed makes the random delays and failures repeatable
+	Seed uint64
+}
+
+// DefaultConfig returns a small, quirky upstream: ten patients, 50-250ms responses, and 5% failures
+func DefaultConfig() Config {
+	return Config{
+		Patients:  10,
+		Latency:   50 * time.Millisecond,
+		Jitter:    200 * time.Millisecond,
+		ErrorRate: 0.05,
+		Seed:      1,
+	}
+}
+
+// Server is a fully in-memory FHIR server used as the upstream target when testing client logic
+// It supports read, search, create, update, and delete of Patient and Observation resources
+type Server struct {
+	config Config
+
+	mutex sync.Mutex
+
+	// resources holds every stored resource as decoded JSON, keyed by resource type then ID
+	resources map[string]map[string]map[string]interface{}
+
+	// random drives the quirks; guarded by mutex
+	random *rand.Rand
+
+	// nextID numbers created resources
+	nextID int
+
+	// sleep waits out the simulated latency; replaced in tests
+	sleep func(time.Duration)
+
+	// now stamps meta.lastUpdated; replaced in tests
+	now func() time.Time
+}
+
+// NewServer creates a mock upstream holding the canned resources
+func NewServer(config Config) *Server {
+	server := &Server{
+		config:    config,
+		resources: map[string]map[string]map[string]interface{}{"Patient": {}, "Observation": {}},
+		random:    rand.New(rand.NewPCG(config.Seed, 0)),
+		nextID:    1,
+		sleep:     time.Sleep,
+		now:       time.Now,
+	}
+
+	for seed := 0; seed < config.Patients; seed++ {
+		server.put("Patient", demo.GeneratePatient(seed))
+		server.put("Observation", demo.GenerateObservation(seed))
+	}
+
+	return server
+}
+
+// Handler returns the mock's routes under /fhir with the quirks applied
+func (server *Server) Handler() http.Handler {
+	router := chi.NewRouter()
+	router.Use(server.quirks)
+
+	router.Get("/fhir/metadata", server.metadata)
+	for _, resourceType := range []string{"Patient", "Observation"} {
+		router.Get("/fhir/"+resourceType, server.search(resourceType))
+		router.Post("/fhir/"+resourceType, server.create(resourceType))
+		router.Get("/fhir/"+resourceType+"/{id}", server.read(resourceType))
+		router.Put("/fhir/"+resourceType+"/{id}", server.update(resourceType))
+		router.Delete("/fhir/"+resourceType+"/{id}", server.remove(resourceType))
+	}
+
+	return router
+}
+
+// quirks delays every request and fails a share of them, unless the request opts out with the fault header
+func (server *Server) quirks(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fault := r.Header.Get(FaultHeader)
+		if fault == FaultNone {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		delay, failed := server.rollQuirks()
+		server.sleep(delay)
+		if failed || fault == FaultError {
+			outcome.Write(w, http.StatusInternalServerError, []outcome.Issue{
+				outcome.Error(fhir.IssueTypeTransient, "Simulated upstream failure"),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rollQuirks picks the request's delay and whether it fails
+func (server *Server) rollQuirks() (time.Duration, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	delay := server.config.Latency
+	if server.config.Jitter > 0 {
+		delay += time.Duration(server.random.Int64N(int64(server.config.Jitter)))
+	}
+	failed := server.config.ErrorRate > 0 && server.random.Float64() < server.config.ErrorRate
+
+	return delay, failed
+}
+
+// metadata serves a minimal CapabilityStatement for clients that probe the upstream first
+func (server *Server) metadata(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"resourceType": "CapabilityStatement",
+		"status":       "active",
+		"kind":         "instance",
+		"fhirVersion":  "4.0.1",
+		"format":       []string{"json"},
+		"software":     map[string]string{"name": "fhir-health-interop mock upstream"},
+		"rest": []map[string]interface{}{{
+			"mode": "server",
+			"resource": []map[string]interface{}{
+				{"type": "Patient", "interaction": interactions()},
+				{"type": "Observation", "interaction": interactions(), "searchParam": []map[string]string{{"name": "patient", "type": "reference"}}},
+			},
+		}},
+	})
+}
+
+// interactions lists the interactions the mock supports for every resource type
+func interactions() []map[string]string {
+	return []map[string]string{{"code": "read"}, {"code": "search-type"}, {"code": "create"}, {"code": "update"}, {"code": "delete"}}
+}
+
+// search serves GET /fhir/{type} as a searchset Bundle, paged with _count and _offset
+// Observations can be filtered with patient (or subject) = Patient/{id} or {id}
+func (server *Server) search(resourceType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		pageSize, countError := nonNegativeParameter(query.Get("_count"), defaultPageSize)
+		offset, offsetError := nonNegativeParameter(query.Get("_offset"), 0)
+		if countError != nil || offsetError != nil {
+			writeIssue(w, http.StatusBadRequest, fhir.IssueTypeInvalid, "_count and _offset must be non-negative integers")
+			return
+		}
+
+		patientFilter := query.Get("patient")
+		if patientFilter == "" {
+			patientFilter = query.Get("subject")
+		}
+		if patientFilter != "" && !strings.HasPrefix(patientFilter, "Patient/") {
+			patientFilter = "Patient/" + patientFilter
+		}
+
+		matches := server.list(resourceType, patientFilter)
+		page := matches[min(offset, len(matches)):min(offset+pageSize, len(matches))]
+
+		bundle := map[string]interface{}{
+			"resourceType": "Bundle",
+			"type":         "searchset",
+			"total":        len(matches),
+			"link":         searchLinks(r, resourceType, offset, pageSize, len(matches)),
+		}
+		entries := make([]map[string]interface{}, 0, len(page))
+		for _, resource := range page {
+			entries = append(entries, map[string]interface{}{
+				"fullUrl":  resourceType + "/" + resource["id"].(string),
+				"resource": resource,
+				"search":   map[string]string{"mode": "match"},
+			})
+		}
+		bundle["entry"] = entries
+
+		writeJSON(w, http.StatusOK, bundle)
+	}
+}
+
+// searchLinks builds the self and, when more results remain, next links for a search page
+func searchLinks(r *http.Request, resourceType string, offset int, pageSize int, total int) []map[string]string {
+	pageURL := func(pageOffset int) string {
+		query := r.URL.Query()
+		query.Set("_count", strconv.Itoa(pageSize))
+		query.Set("_offset", strconv.Itoa(pageOffset))
+		return "/fhir/" + resourceType + "?" + query.Encode()
+	}
+
+	links := []map[string]string{{"relation": "self", "url": pageURL(offset)}}
+	if pageSize > 0 && offset+pageSize < total {
+		links = append(links, map[string]string{"relation": "next", "url": pageURL(offset + pageSize)})
+	}
+	return links
+}
+
+// read serves GET /fhir/{type}/{id}
+func (server *Server) read(resourceType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource, exists := server.get(resourceType, chi.URLParam(r, "id"))
+		if !exists {
+			writeIssue(w, http.StatusNotFound, fhir.IssueTypeNotFound, resourceType+"/"+chi.URLParam(r, "id")+" not found")
+			return
+		}
+		writeJSON(w, http.StatusOK, resource)
+	}
+}
+
+// create serves POST /fhir/{type}, assigning a new ID
+func (server *Server) create(resourceType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource, decoded := decodeResource(w, r, resourceType)
+		if !decoded {
+			return
+		}
+
+		server.mutex.Lock()
+		resource["id"] = fmt.Sprintf("mock-%d", server.nextID)
+		server.nextID++
+		server.mutex.Unlock()
+
+		stored := server.put(resourceType, resource)
+		w.Header().Set("Location", "/fhir/"+resourceType+"/"+stored["id"].(string))
+		writeJSON(w, http.StatusCreated, stored)
+	}
+}
+
+// update serves PUT /fhir/{type}/{id}, creating the resource when it does not exist
+func (server *Server) update(resourceType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resource, decoded := decodeResource(w, r, resourceType)
+		if !decoded {
+			return
+		}
+
+		resourceID := chi.URLParam(r, "id")
+		_, existed := server.get(resourceType, resourceID)
+		resource["id"] = resourceID
+		stored := server.put(resourceType, resource)
+
+		status := http.StatusOK
+		if !existed {
+			status = http.StatusCreated
+		}
+		writeJSON(w, status, stored)
+	}
+}
+
+// remove serves DELETE /fhir/{type}/{id}
+func (server *Server) remove(resourceType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		server.mutex.Lock()
+		delete(server.resources[resourceType], chi.URLParam(r, "id"))
+		server.mutex.Unlock()
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// put stores a resource, stamping its type, version, and lastUpdated, and returns the stored copy
+func (server *Server) put(resourceType string, resource interface{}) map[string]interface{} {
+	decoded, isMap := resource.(map[string]interface{})
+	if !isMap {
+		// Canned resources are FHIR structs; store them the way clients would send them
+		resourceBytes, _ := json.Marshal(resource)
+		json.Unmarshal(resourceBytes, &decoded)
+	}
+	decoded["resourceType"] = resourceType
+
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	resourceID := decoded["id"].(string)
+	version := 1
+	if previous, exists := server.resources[resourceType][resourceID]; exists {
+		previousVersion, _ := strconv.Atoi(previous["meta"].(map[string]interface{})["versionId"].(string))
+		version = previousVersion + 1
+	}
+	decoded["meta"] = map[string]interface{}{
+		"versionId":   strconv.Itoa(version),
+		"lastUpdated": server.now().UTC().Format(time.RFC3339),
+	}
+	server.resources[resourceType][resourceID] = decoded
+
+	return decoded
+}
+
+// get returns a stored resource
+func (server *Server) get(resourceType string, resourceID string) (map[string]interface{}, bool) {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	resource, exists := server.resources[resourceType][resourceID]
+	return resource, exists
+}
+
+// list returns the stored resources of a type in ID order, optionally only those whose subject is patientReference
+func (server *Server) list(resourceType string, patientReference string) []map[string]interface{} {
+	server.mutex.Lock()
+	defer server.mutex.Unlock()
+
+	resources := make([]map[string]interface{}, 0, len(server.resources[resourceType]))
+	for _, resource := range server.resources[resourceType] {
+		if patientReference != "" && subjectReference(resource) != patientReference {
+			continue
+		}
+		resources = append(resources, resource)
+	}
+	sort.Slice(resources, func(left int, right int) bool {
+		return resources[left]["id"].(string) < resources[right]["id"].(string)
+	})
+
+	return resources
+}
+
+// subjectReference returns a resource's subject.reference, or empty when it has none
+func subjectReference(resource map[string]interface{}) string {
+	subject, hasSubject := resource["subject"].(map[string]interface{})
+	if !hasSubject {
+		return ""
+	}
+	reference, _ := subject["reference"].(string)
+	return reference
+}
+
+// decodeResource reads a resource body of the expected type, writing a 400 when it is not one
+func decodeResource(w http.ResponseWriter, r *http.Request, resourceType string) (map[string]interface{}, bool) {
+	var resource map[string]interface{}
+	if decodeError := json.NewDecoder(r.Body).Decode(&resource); decodeError != nil || resource["resourceType"] != resourceType {
+		writeIssue(w, http.StatusBadRequest, fhir.IssueTypeInvalid, "Body must be a "+resourceType+" resource")
+		return nil, false
+	}
+	return resource, true
+}
+
+// nonNegativeParameter parses an optional non-negative integer query parameter
+func nonNegativeParameter(rawValue string, defaultValue int) (int, error) {
+	if rawValue == "" {
+		return defaultValue, nil
+	}
+	value, parseError := strconv.Atoi(rawValue)
+	if parseError != nil || value < 0 {
+		return 0, fmt.Errorf("invalid value %q", rawValue)
+	}
+	return value, nil
+}
+
+// writeIssue responds with a single-issue OperationOutcome
+func writeIssue(w http.ResponseWriter, status int, code fhir.IssueType, diagnostics string) {
+	outcome.Write(w, status, []outcome.Issue{outcome.Error(code, diagnostics)})
+}
+
+// writeJSON responds with a FHIR JSON body
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
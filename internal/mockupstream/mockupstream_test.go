@@ -0,0 +1,116 @@
+package mockupstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// newQuietServer creates a mock without quirks and with delays recorded instead of slept
+func newQuietServer(config Config) (*Server, *[]time.Duration) {
+	server := NewServer(config)
+	var delays []time.Duration
+	server.sleep = func(delay time.Duration) {
+		delays = append(delays, delay)
+	}
+	return server, &delays
+}
+
+// serve sends a request to the mock and decodes the JSON response
+func serve(server *Server, method string, path string, body string, fault string) (int, map[string]interface{}) {
+	request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	if fault != "" {
+		request.Header.Set(FaultHeader, fault)
+	}
+	recorder := httptest.NewRecorder()
+	server.Handler().ServeHTTP(recorder, request)
+
+	var decoded map[string]interface{}
+	json.NewDecoder(recorder.Body).Decode(&decoded)
+	return recorder.Code, decoded
+}
+
+// TestServer_SearchPagesCannedResources verifies canned data is searchable with paging and patient filters
+func TestServer_SearchPagesCannedResources(t *testing.T) {
+	server, _ := newQuietServer(Config{Patients: 3})
+
+	status, bundle := serve(server, http.MethodGet, "/fhir/Patient?_count=2", "", "")
+	if status != http.StatusOK || bundle["total"] != float64(3) || len(bundle["entry"].([]interface{})) != 2 {
+		t.Fatalf("Expected the first page of 3 patients, got %d %v", status, bundle)
+	}
+	links := bundle["link"].([]interface{})
+	if len(links) != 2 || links[1].(map[string]interface{})["url"] != "/fhir/Patient?_count=2&_offset=2" {
+		t.Errorf("Expected a next link, got %v", links)
+	}
+
+	_, observations := serve(server, http.MethodGet, "/fhir/Observation?patient=12346", "", "")
+	if observations["total"] != float64(1) {
+		t.Errorf("Expected one observation for the patient, got %v", observations["total"])
+	}
+
+	status, patient := serve(server, http.MethodGet, "/fhir/Patient/12345", "", "")
+	if status != http.StatusOK || patient["meta"].(map[string]interface{})["versionId"] != "1" {
+		t.Errorf("Expected the canned patient at version 1, got %d %v", status, patient)
+	}
+}
+
+// TestServer_WritesAreKeptInMemory verifies create, update, and delete change what later reads return
+func TestServer_WritesAreKeptInMemory(t *testing.T) {
+	server, _ := newQuietServer(Config{})
+
+	status, created := serve(server, http.MethodPost, "/fhir/Patient", `{"resourceType":"Patient","active":true}`, "")
+	if status != http.StatusCreated || created["id"] != "mock-1" {
+		t.Fatalf("Expected the patient created as mock-1, got %d %v", status, created)
+	}
+
+	status, updated := serve(server, http.MethodPut, "/fhir/Patient/mock-1", `{"resourceType":"Patient","active":false}`, "")
+	if status != http.StatusOK || updated["meta"].(map[string]interface{})["versionId"] != "2" {
+		t.Errorf("Expected version 2 after the update, got %d %v", status, updated)
+	}
+
+	if status, _ := serve(server, http.MethodPost, "/fhir/Patient", `{"resourceType":"Observation"}`, ""); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for the wrong resource type, got %d", status)
+	}
+
+	serve(server, http.MethodDelete, "/fhir/Patient/mock-1", "", "")
+	if status, _ := serve(server, http.MethodGet, "/fhir/Patient/mock-1", "", ""); status != http.StatusNotFound {
+		t.Errorf("Expected 404 after the delete, got %d", status)
+	}
+}
+
+// TestServer_Quirks verifies latency, seeded failures, and the fault header
+func TestServer_Quirks(t *testing.T) {
+	server, delays := newQuietServer(Config{Patients: 1, Latency: 100 * time.Millisecond, Jitter: 50 * time.Millisecond, ErrorRate: 0.5, Seed: 7})
+
+	failures := 0
+	for attempt := 0; attempt < 40; attempt++ {
+		status, _ := serve(server, http.MethodGet, "/fhir/Patient/12345", "", "")
+		if status == http.StatusInternalServerError {
+			failures++
+		}
+	}
+	if failures == 0 || failures == 40 {
+		t.Errorf("Expected some but not all requests to fail, got %d of 40", failures)
+	}
+	for _, delay := range *delays {
+		if delay < 100*time.Millisecond || delay >= 150*time.Millisecond {
+			t.Errorf("Expected delays within latency plus jitter, got %s", delay)
+		}
+	}
+
+	if status, _ := serve(server, http.MethodGet, "/fhir/metadata", "", FaultError); status != http.StatusInternalServerError {
+		t.Errorf("Expected a forced failure, got %d", status)
+	}
+	delayCount := len(*delays)
+	for attempt := 0; attempt < 10; attempt++ {
+		if status, _ := serve(server, http.MethodGet, "/fhir/Patient/12345", "", FaultNone); status != http.StatusOK {
+			t.Fatalf("Expected quirks skipped, got %d", status)
+		}
+	}
+	if len(*delays) != delayCount {
+		t.Error("Expected no delay when quirks are skipped")
+	}
+}
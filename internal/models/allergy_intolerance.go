@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// AllergyIntolerance represents an allergy or intolerance on a patient's allergy list
+// Stored in MongoDB alongside conditions
+type AllergyIntolerance struct {
+	ID                 string    `bson:"_id,omitempty"`
+	PatientID          string    `bson:"patient_id"`
+	ClinicalStatus     string    `bson:"clinical_status,omitempty"`
+	VerificationStatus string    `bson:"verification_status,omitempty"`
+	Type               string    `bson:"type,omitempty"`
+	Categories         []string  `bson:"categories,omitempty"`
+	Criticality        string    `bson:"criticality,omitempty"`
+	Code               string    `bson:"code"`
+	CodeSystem         string    `bson:"code_system,omitempty"`
+	CodeDisplay        string    `bson:"code_display,omitempty"`
+	CreatedAt          time.Time `bson:"created_at"`
+	UpdatedAt          time.Time `bson:"updated_at"`
+}
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// AllergyIntoleranceClinicalStatusSystem is the code system of AllergyIntolerance.clinicalStatus codes
+const AllergyIntoleranceClinicalStatusSystem = "http://terminology.hl7.org/CodeSystem/allergyintolerance-clinical"
+
+// AllergyIntoleranceVerificationStatusSystem is the code system of AllergyIntolerance.verificationStatus codes
+const AllergyIntoleranceVerificationStatusSystem = "http://terminology.hl7.org/CodeSystem/allergyintolerance-verification"
+
+// allergyClinicalStatuses are the codes AllergyIntolerance.clinicalStatus may take
+var allergyClinicalStatuses = map[string]bool{"active": true, "inactive": true, "resolved": true}
+
+// allergyVerificationStatuses are the codes AllergyIntolerance.verificationStatus may take
+var allergyVerificationStatuses = map[string]bool{"unconfirmed": true, "confirmed": true, "refuted": true, "entered-in-error": true}
+
+// AllergyIntoleranceMapper converts between domain model and FHIR AllergyIntolerance
+type AllergyIntoleranceMapper struct{}
+
+// NewAllergyIntoleranceMapper creates a new allergy intolerance mapper instance
+func NewAllergyIntoleranceMapper() *AllergyIntoleranceMapper {
+	return &AllergyIntoleranceMapper{}
+}
+
+// ToFHIR converts a domain AllergyIntolerance to a FHIR AllergyIntolerance
+// Type, category, and criticality were stored from their FHIR codes, so they always parse back
+func (mapper *AllergyIntoleranceMapper) ToFHIR(allergy *AllergyIntolerance) *fhir.AllergyIntolerance {
+	fhirAllergy := &fhir.AllergyIntolerance{}
+
+	// Set ID
+	if allergy.ID != "" {
+		fhirAllergy.Id = &allergy.ID
+	}
+
+	// Set clinical and verification status
+	if allergy.ClinicalStatus != "" {
+		fhirAllergy.ClinicalStatus = statusConcept(AllergyIntoleranceClinicalStatusSystem, allergy.ClinicalStatus)
+	}
+	if allergy.VerificationStatus != "" {
+		fhirAllergy.VerificationStatus = statusConcept(AllergyIntoleranceVerificationStatusSystem, allergy.VerificationStatus)
+	}
+
+	// Set type
+	var allergyType fhir.AllergyIntoleranceType
+	if allergy.Type != "" && allergyType.UnmarshalJSON([]byte(allergy.Type)) == nil {
+		fhirAllergy.Type = &allergyType
+	}
+
+	// Set categories
+	for _, categoryCode := range allergy.Categories {
+		var category fhir.AllergyIntoleranceCategory
+		if category.UnmarshalJSON([]byte(categoryCode)) == nil {
+			fhirAllergy.Category = append(fhirAllergy.Category, category)
+		}
+	}
+
+	// Set criticality
+	var criticality fhir.AllergyIntoleranceCriticality
+	if allergy.Criticality != "" && criticality.UnmarshalJSON([]byte(allergy.Criticality)) == nil {
+		fhirAllergy.Criticality = &criticality
+	}
+
+	// Set code
+	if allergy.Code != "" {
+		coding := fhir.Coding{Code: &allergy.Code}
+		if allergy.CodeSystem != "" {
+			coding.System = &allergy.CodeSystem
+		}
+		if allergy.CodeDisplay != "" {
+			coding.Display = &allergy.CodeDisplay
+		}
+		fhirAllergy.Code = &fhir.CodeableConcept{Coding: []fhir.Coding{coding}}
+	}
+
+	// Set patient reference
+	if allergy.PatientID != "" {
+		patientReference := "Patient/" + allergy.PatientID
+		fhirAllergy.Patient = fhir.Reference{Reference: &patientReference}
+	}
+
+	return fhirAllergy
+}
+
+// FromFHIR converts a FHIR AllergyIntolerance to a domain AllergyIntolerance
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *AllergyIntoleranceMapper) FromFHIR(fhirAllergy *fhir.AllergyIntolerance) (*AllergyIntolerance, []outcome.Issue) {
+	var issues []outcome.Issue
+	allergy := &AllergyIntolerance{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// Extract ID
+	if fhirAllergy.Id != nil {
+		allergy.ID = *fhirAllergy.Id
+	}
+
+	// Extract clinical and verification status from their first codings
+	allergy.ClinicalStatus, issues = statusCode(fhirAllergy.ClinicalStatus, allergyClinicalStatuses, "AllergyIntolerance.clinicalStatus", issues)
+	allergy.VerificationStatus, issues = statusCode(fhirAllergy.VerificationStatus, allergyVerificationStatuses, "AllergyIntolerance.verificationStatus", issues)
+
+	// Extract type, categories, and criticality
+	if fhirAllergy.Type != nil {
+		allergy.Type = fhirAllergy.Type.Code()
+	}
+	for _, category := range fhirAllergy.Category {
+		allergy.Categories = append(allergy.Categories, category.Code())
+	}
+	if fhirAllergy.Criticality != nil {
+		allergy.Criticality = fhirAllergy.Criticality.Code()
+	}
+
+	// Extract code
+	if fhirAllergy.Code != nil && len(fhirAllergy.Code.Coding) > 0 {
+		coding := fhirAllergy.Code.Coding[0]
+		if coding.Code != nil {
+			allergy.Code = *coding.Code
+		}
+		if coding.System != nil {
+			allergy.CodeSystem = *coding.System
+		}
+		if coding.Display != nil {
+			allergy.CodeDisplay = *coding.Display
+		}
+	}
+
+	// Extract patient ID from patient reference
+	if fhirAllergy.Patient.Reference != nil {
+		if patientID, isPatient := strings.CutPrefix(*fhirAllergy.Patient.Reference, "Patient/"); isPatient && patientID != "" {
+			allergy.PatientID = patientID
+		}
+	}
+
+	return allergy, issues
+}
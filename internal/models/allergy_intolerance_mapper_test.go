@@ -0,0 +1,71 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestAllergyIntoleranceMapper_RoundTrip verifies statuses, type, categories, criticality, code, and patient survive a round trip
+func TestAllergyIntoleranceMapper_RoundTrip(t *testing.T) {
+	mapper := NewAllergyIntoleranceMapper()
+	clinicalStatus := "active"
+	verificationStatus := "confirmed"
+	allergyType := fhir.AllergyIntoleranceTypeAllergy
+	criticality := fhir.AllergyIntoleranceCriticalityHigh
+	codeSystem := "http://www.nlm.nih.gov/research/umls/rxnorm"
+	code := "7980"
+	codeDisplay := "Penicillin G"
+	patientReference := "Patient/patient-1"
+
+	allergy, issues := mapper.FromFHIR(&fhir.AllergyIntolerance{
+		ClinicalStatus:     &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &clinicalStatus}}},
+		VerificationStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &verificationStatus}}},
+		Type:               &allergyType,
+		Category:           []fhir.AllergyIntoleranceCategory{fhir.AllergyIntoleranceCategoryMedication, fhir.AllergyIntoleranceCategoryBiologic},
+		Criticality:        &criticality,
+		Code:               &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &codeSystem, Code: &code, Display: &codeDisplay}}},
+		Patient:            fhir.Reference{Reference: &patientReference},
+	})
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if allergy.ClinicalStatus != "active" || allergy.Type != "allergy" || allergy.Criticality != "high" || allergy.PatientID != "patient-1" {
+		t.Errorf("Expected the submitted fields, got %+v", allergy)
+	}
+	if len(allergy.Categories) != 2 || allergy.Categories[0] != "medication" || allergy.Categories[1] != "biologic" {
+		t.Errorf("Expected both categories in order, got %v", allergy.Categories)
+	}
+
+	fhirAllergy := mapper.ToFHIR(allergy)
+	if *fhirAllergy.ClinicalStatus.Coding[0].System != AllergyIntoleranceClinicalStatusSystem || *fhirAllergy.ClinicalStatus.Coding[0].Code != "active" {
+		t.Errorf("Expected clinical status active in the allergyintolerance-clinical system, got %+v", fhirAllergy.ClinicalStatus)
+	}
+	if *fhirAllergy.VerificationStatus.Coding[0].System != AllergyIntoleranceVerificationStatusSystem {
+		t.Errorf("Expected the allergyintolerance-verification system, got %+v", fhirAllergy.VerificationStatus)
+	}
+	if *fhirAllergy.Type != allergyType || *fhirAllergy.Criticality != criticality || len(fhirAllergy.Category) != 2 || fhirAllergy.Category[1] != fhir.AllergyIntoleranceCategoryBiologic {
+		t.Errorf("Expected type, criticality, and categories back, got %+v", fhirAllergy)
+	}
+	if *fhirAllergy.Code.Coding[0].Display != codeDisplay || *fhirAllergy.Patient.Reference != patientReference {
+		t.Errorf("Expected the code and patient back, got %+v", fhirAllergy)
+	}
+}
+
+// TestAllergyIntoleranceMapper_FromFHIR_ReportsDroppedValues verifies statuses outside the allergy value sets are reported and not stored
+func TestAllergyIntoleranceMapper_FromFHIR_ReportsDroppedValues(t *testing.T) {
+	clinicalStatus := "remission"
+	verificationStatus := "provisional"
+
+	allergy, issues := NewAllergyIntoleranceMapper().FromFHIR(&fhir.AllergyIntolerance{
+		ClinicalStatus:     &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &clinicalStatus}}},
+		VerificationStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &verificationStatus}}},
+	})
+
+	if allergy.ClinicalStatus != "" || allergy.VerificationStatus != "" {
+		t.Errorf("Expected no statuses, got %+v", allergy)
+	}
+	if len(issues) != 2 || issues[0].Expression[0] != "AllergyIntolerance.clinicalStatus" || issues[1].Expression[0] != "AllergyIntolerance.verificationStatus" {
+		t.Errorf("Expected issues for clinicalStatus and verificationStatus, got %+v", issues)
+	}
+}
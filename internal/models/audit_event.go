@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+)
+
+// Audit event actions, the FHIR AuditEvent.action codes
+const (
+	AuditActionCreate  = "C"
+	AuditActionRead    = "R"
+	AuditActionUpdate  = "U"
+	AuditActionDelete  = "D"
+	AuditActionExecute = "E"
+)
+
+// Audit event outcomes, the FHIR AuditEvent.outcome codes
+const (
+	// AuditOutcomeSuccess is a request that succeeded
+	AuditOutcomeSuccess = "0"
+
+	// AuditOutcomeMinorFailure is a request refused or rejected with a 4xx status
+	AuditOutcomeMinorFailure = "4"
+
+	// AuditOutcomeSeriousFailure is a request that failed with a 5xx status
+	AuditOutcomeSeriousFailure = "8"
+)
+
+// AuditEvent records one access to clinical data: who did what to which resource, when, and how it ended
+// This model maps to the audit_events table and is served as a FHIR AuditEvent
+type AuditEvent struct {
+	// Unique identifier for the audit event (UUID)
+	ID string `json:"id"`
+
+	// Action is C, R, U, D, or E (searches and operations)
+	Action string `json:"action"`
+
+	// Interaction is the FHIR RESTful interaction, such as read, search-type, or operation
+	Interaction string `json:"interaction"`
+
+	// Outcome is 0, 4, or 8, and StatusCode the HTTP status the request was answered with
+	Outcome    string `json:"outcome"`
+	StatusCode int    `json:"status_code"`
+
+	// AgentID is the principal that made the request, such as an API key fingerprint or oauth:{client_id}
+	AgentID string `json:"agent_id"`
+
+	// AgentRoles are the roles the principal held
+	AgentRoles []string `json:"agent_roles"`
+
+	// TenantID is the tenant the request was made for
+	TenantID string `json:"tenant_id"`
+
+	// ClientAddress is the network address the request came from
+	ClientAddress string `json:"client_address"`
+
+	// Entity accessed; EntityID is empty for searches and type-level operations
+	EntityType string `json:"entity_type"`
+	EntityID   string `json:"entity_id"`
+
+	// Query is the raw query string of searches
+	Query string `json:"query"`
+
+	// Request details for correlating with application logs
+	Method    string `json:"method"`
+	Path      string `json:"path"`
+	RequestID string `json:"request_id"`
+
+	RecordedAt time.Time `json:"recorded_at"`
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// auditEventSourceName identifies this server as the observer of the events it records
+const auditEventSourceName = "fhir-health-interop"
+
+// auditEventActions maps stored actions to their FHIR codes
+var auditEventActions = map[string]fhir.AuditEventAction{
+	AuditActionCreate:  fhir.AuditEventActionC,
+	AuditActionRead:    fhir.AuditEventActionR,
+	AuditActionUpdate:  fhir.AuditEventActionU,
+	AuditActionDelete:  fhir.AuditEventActionD,
+	AuditActionExecute: fhir.AuditEventActionE,
+}
+
+// auditEventOutcomes maps stored outcomes to their FHIR codes
+var auditEventOutcomes = map[string]fhir.AuditEventOutcome{
+	AuditOutcomeSuccess:        fhir.AuditEventOutcome0,
+	AuditOutcomeMinorFailure:   fhir.AuditEventOutcome4,
+	AuditOutcomeSeriousFailure: fhir.AuditEventOutcome8,
+}
+
+// AuditOutcomeForStatus classifies an HTTP status as a success, minor failure, or serious failure
+func AuditOutcomeForStatus(statusCode int) string {
+	switch {
+	case statusCode >= http.StatusInternalServerError:
+		return AuditOutcomeSeriousFailure
+	case statusCode >= http.StatusBadRequest:
+		return AuditOutcomeMinorFailure
+	}
+	return AuditOutcomeSuccess
+}
+
+// AuditEventMapper converts stored audit events to FHIR AuditEvent resources
+// Audit events are only written by the server, so there is no FromFHIR
+type AuditEventMapper struct{}
+
+// NewAuditEventMapper creates a new instance of AuditEventMapper
+func NewAuditEventMapper() *AuditEventMapper {
+	return &AuditEventMapper{}
+}
+
+// ToFHIR converts a domain AuditEvent to a FHIR AuditEvent of type rest
+func (mapper *AuditEventMapper) ToFHIR(event *AuditEvent) *fhir.AuditEvent {
+	typeSystem, typeCode, typeDisplay := "http://terminology.hl7.org/CodeSystem/audit-event-type", "rest", "RESTful Operation"
+	interactionSystem := "http://hl7.org/fhir/restful-interaction"
+	action := auditEventActions[event.Action]
+	eventOutcome := auditEventOutcomes[event.Outcome]
+	outcomeDescription := strconv.Itoa(event.StatusCode) + " " + http.StatusText(event.StatusCode)
+	sourceName := auditEventSourceName
+
+	fhirEvent := &fhir.AuditEvent{
+		Id:          &event.ID,
+		Type:        fhir.Coding{System: &typeSystem, Code: &typeCode, Display: &typeDisplay},
+		Subtype:     []fhir.Coding{{System: &interactionSystem, Code: &event.Interaction}},
+		Action:      &action,
+		Recorded:    event.RecordedAt.UTC().Format(time.RFC3339),
+		Outcome:     &eventOutcome,
+		OutcomeDesc: &outcomeDescription,
+		Source:      fhir.AuditEventSource{Observer: fhir.Reference{Display: &sourceName}},
+	}
+
+	// Set the requesting agent, its roles, and its network address
+	agent := fhir.AuditEventAgent{
+		Who:       &fhir.Reference{Identifier: &fhir.Identifier{Value: &event.AgentID}},
+		Requestor: true,
+	}
+	for roleIndex := range event.AgentRoles {
+		agent.Role = append(agent.Role, fhir.CodeableConcept{Text: &event.AgentRoles[roleIndex]})
+	}
+	if event.ClientAddress != "" {
+		// Network type 2 is an IP address
+		networkType := fhir.AuditEventAgentNetworkType2
+		agent.Network = &fhir.AuditEventAgentNetwork{Address: &event.ClientAddress, Type: &networkType}
+	}
+	fhirEvent.Agent = []fhir.AuditEventAgent{agent}
+
+	// Set the tenant as the site the event happened at
+	if event.TenantID != "" {
+		fhirEvent.Source.Site = &event.TenantID
+	}
+
+	// Set the accessed resource, or the search that was run, with the query base64-encoded as FHIR requires
+	resourceTypeSystem := "http://hl7.org/fhir/resource-types"
+	entity := fhir.AuditEventEntity{Type: &fhir.Coding{System: &resourceTypeSystem, Code: &event.EntityType}}
+	if event.EntityID != "" {
+		entityReference := event.EntityType + "/" + event.EntityID
+		entity.What = &fhir.Reference{Reference: &entityReference}
+	}
+	if event.Query != "" {
+		encodedQuery := base64.StdEncoding.EncodeToString([]byte(event.Query))
+		entity.Query = &encodedQuery
+	}
+	fhirEvent.Entity = []fhir.AuditEventEntity{entity}
+
+	return fhirEvent
+}
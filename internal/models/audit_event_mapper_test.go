@@ -0,0 +1,104 @@
+package models
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestAuditOutcomeForStatus verifies 2xx and 3xx succeed, 4xx are minor failures, and 5xx serious ones
+func TestAuditOutcomeForStatus(t *testing.T) {
+	testCases := map[int]string{
+		http.StatusOK:                  AuditOutcomeSuccess,
+		http.StatusNotModified:         AuditOutcomeSuccess,
+		http.StatusBadRequest:          AuditOutcomeMinorFailure,
+		http.StatusForbidden:           AuditOutcomeMinorFailure,
+		http.StatusInternalServerError: AuditOutcomeSeriousFailure,
+		http.StatusServiceUnavailable:  AuditOutcomeSeriousFailure,
+	}
+	for statusCode, expected := range testCases {
+		if outcome := AuditOutcomeForStatus(statusCode); outcome != expected {
+			t.Errorf("Status %d: expected outcome %s, got %s", statusCode, expected, outcome)
+		}
+	}
+}
+
+// TestAuditEventMapper_ToFHIR verifies the action, outcome, agent, source, and accessed entity are mapped
+func TestAuditEventMapper_ToFHIR(t *testing.T) {
+	fhirEvent := NewAuditEventMapper().ToFHIR(&AuditEvent{
+		ID:            "audit-1",
+		Action:        AuditActionUpdate,
+		Interaction:   "update",
+		Outcome:       AuditOutcomeMinorFailure,
+		StatusCode:    http.StatusConflict,
+		AgentID:       "api-key:abc123",
+		AgentRoles:    []string{"clinician", "operator"},
+		TenantID:      "tenant-a",
+		ClientAddress: "10.0.0.7",
+		EntityType:    "Patient",
+		EntityID:      "patient-1",
+		RecordedAt:    time.Date(2026, 10, 1, 8, 30, 0, 0, time.FixedZone("PDT", -7*3600)),
+	})
+
+	if *fhirEvent.Id != "audit-1" || *fhirEvent.Type.Code != "rest" || *fhirEvent.Subtype[0].Code != "update" {
+		t.Errorf("Expected a rest event of the update interaction, got %+v", fhirEvent)
+	}
+	if *fhirEvent.Action != fhir.AuditEventActionU || *fhirEvent.Outcome != fhir.AuditEventOutcome4 || *fhirEvent.OutcomeDesc != "409 Conflict" {
+		t.Errorf("Expected action U with outcome 4 and the status text, got %v %v %q", *fhirEvent.Action, *fhirEvent.Outcome, *fhirEvent.OutcomeDesc)
+	}
+	if fhirEvent.Recorded != "2026-10-01T15:30:00Z" {
+		t.Errorf("Expected the recorded time in UTC, got %s", fhirEvent.Recorded)
+	}
+	if *fhirEvent.Source.Site != "tenant-a" || *fhirEvent.Source.Observer.Display != auditEventSourceName {
+		t.Errorf("Expected the tenant as the site observed by this server, got %+v", fhirEvent.Source)
+	}
+
+	if len(fhirEvent.Agent) != 1 {
+		t.Fatalf("Expected one agent, got %d", len(fhirEvent.Agent))
+	}
+	agent := fhirEvent.Agent[0]
+	if !agent.Requestor || *agent.Who.Identifier.Value != "api-key:abc123" || len(agent.Role) != 2 || *agent.Role[1].Text != "operator" {
+		t.Errorf("Expected the requesting principal with its roles, got %+v", agent)
+	}
+	if agent.Network == nil || *agent.Network.Address != "10.0.0.7" || *agent.Network.Type != fhir.AuditEventAgentNetworkType2 {
+		t.Errorf("Expected the client IP address, got %+v", agent.Network)
+	}
+
+	if len(fhirEvent.Entity) != 1 || *fhirEvent.Entity[0].What.Reference != "Patient/patient-1" || *fhirEvent.Entity[0].Type.Code != "Patient" || fhirEvent.Entity[0].Query != nil {
+		t.Errorf("Expected the accessed patient without a query, got %+v", fhirEvent.Entity)
+	}
+}
+
+// TestAuditEventMapper_ToFHIR_Search verifies a search records the base64 query, no entity reference, and no network
+// when the client address is unknown
+func TestAuditEventMapper_ToFHIR_Search(t *testing.T) {
+	fhirEvent := NewAuditEventMapper().ToFHIR(&AuditEvent{
+		ID:          "audit-2",
+		Action:      AuditActionExecute,
+		Interaction: "search-type",
+		Outcome:     AuditOutcomeSuccess,
+		StatusCode:  http.StatusOK,
+		AgentID:     "oauth:portal",
+		EntityType:  "Observation",
+		Query:       "code=8480-6&patient=patient-1",
+	})
+
+	if *fhirEvent.Action != fhir.AuditEventActionE || *fhirEvent.Outcome != fhir.AuditEventOutcome0 {
+		t.Errorf("Expected action E with outcome 0, got %v %v", *fhirEvent.Action, *fhirEvent.Outcome)
+	}
+	if fhirEvent.Agent[0].Network != nil || len(fhirEvent.Agent[0].Role) != 0 || fhirEvent.Source.Site != nil {
+		t.Errorf("Expected no network, roles, or site, got %+v", fhirEvent)
+	}
+
+	entity := fhirEvent.Entity[0]
+	if entity.What != nil || *entity.Type.Code != "Observation" {
+		t.Errorf("Expected a type-level entity, got %+v", entity)
+	}
+	decodedQuery, decodeError := base64.StdEncoding.DecodeString(*entity.Query)
+	if decodeError != nil || string(decodedQuery) != "code=8480-6&patient=patient-1" {
+		t.Errorf("Expected the base64-encoded query, got %q (%v)", decodedQuery, decodeError)
+	}
+}
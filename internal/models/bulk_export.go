@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+)
+
+// BulkExportStatus is the state of a bulk data export
+type BulkExportStatus string
+
+const (
+	// BulkExportStatusPending exports wait for a worker to start their job
+	BulkExportStatusPending BulkExportStatus = "pending"
+
+	// BulkExportStatusRunning exports are being written to their files
+	BulkExportStatusRunning BulkExportStatus = "running"
+
+	// BulkExportStatusComplete exports can be downloaded until they expire
+	BulkExportStatusComplete BulkExportStatus = "complete"
+
+	// BulkExportStatusFailed exports stopped on an error
+	BulkExportStatusFailed BulkExportStatus = "failed"
+
+	// BulkExportStatusCancelled exports were deleted by their requester and their files removed
+	BulkExportStatusCancelled BulkExportStatus = "cancelled"
+
+	// BulkExportStatusExpired exports passed their retention and their files were removed
+	BulkExportStatusExpired BulkExportStatus = "expired"
+)
+
+// BulkExport is a FHIR Bulk Data $export job writing one NDJSON file per resource type
+// This model maps to the bulk_exports table
+type BulkExport struct {
+	ID int64 `json:"id"`
+
+	// Request is the kick-off request URL, echoed in the completion manifest
+	Request string `json:"request"`
+
+	// Types are the resource types exported, in the order their files are written
+	Types []string `json:"types"`
+
+	// Since limits the export to resources changed after it, when set
+	Since *time.Time `json:"since,omitempty"`
+
+	Status      BulkExportStatus `json:"status"`
+	RequestedBy string           `json:"requested_by"`
+	TenantID    string           `json:"-"`
+
+	// JobID is the bulk-export background job writing the export's files
+	JobID string `json:"job_id,omitempty"`
+
+	// Progress describes how far a running export has got, for the X-Progress header
+	Progress string `json:"progress,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	// Files are the written NDJSON files, set once the export is complete
+	Files []*BulkExportFile `json:"files,omitempty"`
+
+	// CreatedAt is the export's transaction time: every change before it is in the files
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+}
+
+// BulkExportFile is one NDJSON file of a bulk export, holding every exported resource of one type
+// This model maps to the bulk_export_files table
+type BulkExportFile struct {
+	ExportID      int64  `json:"export_id"`
+	ResourceType  string `json:"resource_type"`
+	ResourceCount int    `json:"resource_count"`
+}
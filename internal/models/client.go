@@ -0,0 +1,92 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ClientType classifies a registered application and drives its default scopes
+type ClientType string
+
+const (
+	// ClientTypePublic is a browser or mobile app that cannot keep a secret
+	ClientTypePublic ClientType = "public"
+
+	// ClientTypeConfidential is a server-side app that authenticates with a client secret
+	ClientTypeConfidential ClientType = "confidential"
+
+	// ClientTypeBackend is a system-to-system service that authenticates with a signed JWT
+	ClientTypeBackend ClientType = "backend"
+)
+
+// ClientStatus tracks a registration through the admin approval workflow
+type ClientStatus string
+
+const (
+	// ClientStatusPending is a new registration awaiting admin review
+	ClientStatusPending ClientStatus = "pending"
+
+	// ClientStatusApproved is a registration that may request tokens
+	ClientStatusApproved ClientStatus = "approved"
+
+	// ClientStatusRejected is a registration an admin declined
+	ClientStatusRejected ClientStatus = "rejected"
+)
+
+// RegisteredClient represents a partner application registered via dynamic client registration
+// This model maps to the oauth_clients table
+type RegisteredClient struct {
+	// Issued client identifier (UUID)
+	ClientID string `json:"client_id"`
+
+	// Human-readable application name
+	ClientName string `json:"client_name"`
+
+	// Public, confidential, or backend
+	ClientType ClientType `json:"client_type"`
+
+	// How the client authenticates at the token endpoint (none, client_secret_basic, private_key_jwt)
+	TokenEndpointAuthMethod string `json:"token_endpoint_auth_method"`
+
+	// OAuth2 grant types the client may use
+	GrantTypes []string `json:"grant_types"`
+
+	// Allowed redirect URIs for authorization code flows
+	RedirectURIs []string `json:"redirect_uris"`
+
+	// Location of the client's public keys (backend clients)
+	JWKSURI string `json:"jwks_uri,omitempty"`
+
+	// Inline public key set (backend clients)
+	JWKS json.RawMessage `json:"jwks,omitempty"`
+
+	// Scopes granted to the client
+	Scopes []string `json:"scopes"`
+
+	// SHA-256 hash of the client secret (confidential clients only); never serialized
+	SecretHash string `json:"-"`
+
+	// Approval workflow state
+	Status ClientStatus `json:"status"`
+
+	// Reviewer's note recorded on approval or rejection
+	ReviewNote string `json:"review_note,omitempty"`
+
+	// Audit timestamps
+	CreatedAt  time.Time  `json:"created_at"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// ClientRegistrationRequest is the registration metadata a partner app submits
+// Field names follow RFC 7591 (OAuth 2.0 Dynamic Client Registration)
+type ClientRegistrationRequest struct {
+	ClientName              string          `json:"client_name"`
+	RedirectURIs            []string        `json:"redirect_uris"`
+	GrantTypes              []string        `json:"grant_types"`
+	TokenEndpointAuthMethod string          `json:"token_endpoint_auth_method"`
+	JWKSURI                 string          `json:"jwks_uri"`
+	JWKS                    json.RawMessage `json:"jwks"`
+
+	// Space-separated scopes; defaults are applied per client type when empty
+	Scope string `json:"scope"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+// Condition represents a problem, diagnosis, or health concern on a patient's problem list
+// Stored in MongoDB alongside observations
+type Condition struct {
+	ID                 string     `bson:"_id,omitempty"`
+	PatientID          string     `bson:"patient_id"`
+	ClinicalStatus     string     `bson:"clinical_status,omitempty"`
+	VerificationStatus string     `bson:"verification_status,omitempty"`
+	Code               string     `bson:"code"`
+	CodeSystem         string     `bson:"code_system,omitempty"`
+	CodeDisplay        string     `bson:"code_display,omitempty"`
+	OnsetDateTime      string     `bson:"onset_date_time,omitempty"`
+	OnsetDate          *time.Time `bson:"onset_date,omitempty"`
+	CreatedAt          time.Time  `bson:"created_at"`
+	UpdatedAt          time.Time  `bson:"updated_at"`
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// ConditionClinicalStatusSystem is the code system of Condition.clinicalStatus codes
+const ConditionClinicalStatusSystem = "http://terminology.hl7.org/CodeSystem/condition-clinical"
+
+// ConditionVerificationStatusSystem is the code system of Condition.verificationStatus codes
+const ConditionVerificationStatusSystem = "http://terminology.hl7.org/CodeSystem/condition-ver-status"
+
+// conditionClinicalStatuses are the codes Condition.clinicalStatus may take
+var conditionClinicalStatuses = map[string]bool{
+	"active": true, "recurrence": true, "relapse": true, "inactive": true, "remission": true, "resolved": true,
+}
+
+// conditionVerificationStatuses are the codes Condition.verificationStatus may take
+var conditionVerificationStatuses = map[string]bool{
+	"unconfirmed": true, "provisional": true, "differential": true, "confirmed": true, "refuted": true, "entered-in-error": true,
+}
+
+// clinicalDateLayouts are the forms stored for a condition onset, report effective date, or immunization occurrence:
+// a full RFC 3339 date-time or a calendar day
+var clinicalDateLayouts = []string{time.RFC3339, "2006-01-02"}
+
+// ConditionMapper converts between domain model and FHIR Condition
+type ConditionMapper struct{}
+
+// NewConditionMapper creates a new condition mapper instance
+func NewConditionMapper() *ConditionMapper {
+	return &ConditionMapper{}
+}
+
+// ToFHIR converts a domain Condition to a FHIR Condition
+func (mapper *ConditionMapper) ToFHIR(condition *Condition) *fhir.Condition {
+	fhirCondition := &fhir.Condition{}
+
+	// Set ID
+	if condition.ID != "" {
+		fhirCondition.Id = &condition.ID
+	}
+
+	// Set clinical and verification status
+	if condition.ClinicalStatus != "" {
+		fhirCondition.ClinicalStatus = statusConcept(ConditionClinicalStatusSystem, condition.ClinicalStatus)
+	}
+	if condition.VerificationStatus != "" {
+		fhirCondition.VerificationStatus = statusConcept(ConditionVerificationStatusSystem, condition.VerificationStatus)
+	}
+
+	// Set code
+	if condition.Code != "" {
+		coding := fhir.Coding{Code: &condition.Code}
+		if condition.CodeSystem != "" {
+			coding.System = &condition.CodeSystem
+		}
+		if condition.CodeDisplay != "" {
+			coding.Display = &condition.CodeDisplay
+		}
+		fhirCondition.Code = &fhir.CodeableConcept{Coding: []fhir.Coding{coding}}
+	}
+
+	// Set subject (patient reference)
+	if condition.PatientID != "" {
+		patientReference := "Patient/" + condition.PatientID
+		fhirCondition.Subject = fhir.Reference{Reference: &patientReference}
+	}
+
+	// Set onset exactly as it was submitted
+	if condition.OnsetDateTime != "" {
+		fhirCondition.OnsetDateTime = &condition.OnsetDateTime
+	}
+
+	return fhirCondition
+}
+
+// statusConcept builds a status CodeableConcept holding one coding from system, shared by Condition and AllergyIntolerance
+func statusConcept(system string, code string) *fhir.CodeableConcept {
+	return &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &system, Code: &code}}}
+}
+
+// FromFHIR converts a FHIR Condition to a domain Condition
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *ConditionMapper) FromFHIR(fhirCondition *fhir.Condition) (*Condition, []outcome.Issue) {
+	var issues []outcome.Issue
+	condition := &Condition{
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// Extract ID
+	if fhirCondition.Id != nil {
+		condition.ID = *fhirCondition.Id
+	}
+
+	// Extract clinical and verification status from their first codings
+	condition.ClinicalStatus, issues = statusCode(fhirCondition.ClinicalStatus, conditionClinicalStatuses, "Condition.clinicalStatus", issues)
+	condition.VerificationStatus, issues = statusCode(fhirCondition.VerificationStatus, conditionVerificationStatuses, "Condition.verificationStatus", issues)
+
+	// Extract code
+	if fhirCondition.Code != nil && len(fhirCondition.Code.Coding) > 0 {
+		coding := fhirCondition.Code.Coding[0]
+		if coding.Code != nil {
+			condition.Code = *coding.Code
+		}
+		if coding.System != nil {
+			condition.CodeSystem = *coding.System
+		}
+		if coding.Display != nil {
+			condition.CodeDisplay = *coding.Display
+		}
+	}
+
+	// Extract patient ID from subject reference
+	if fhirCondition.Subject.Reference != nil {
+		if patientID, isPatient := strings.CutPrefix(*fhirCondition.Subject.Reference, "Patient/"); isPatient && patientID != "" {
+			condition.PatientID = patientID
+		}
+	}
+
+	// Extract onset date, keeping the submitted text so a day is not returned as a timestamp
+	if fhirCondition.OnsetDateTime != nil {
+		for _, layout := range clinicalDateLayouts {
+			if parsedTime, parseError := time.Parse(layout, *fhirCondition.OnsetDateTime); parseError == nil {
+				condition.OnsetDateTime = *fhirCondition.OnsetDateTime
+				condition.OnsetDate = &parsedTime
+				break
+			}
+		}
+		if condition.OnsetDate == nil {
+			issues = append(issues, droppedElementIssue("Condition.onsetDateTime", *fhirCondition.OnsetDateTime, "an RFC 3339 date-time or a YYYY-MM-DD date"))
+		}
+	}
+
+	return condition, issues
+}
+
+// statusCode reads the code of a status concept's first coding, adding a dropped-element issue when it is not one of allowedCodes
+func statusCode(concept *fhir.CodeableConcept, allowedCodes map[string]bool, expression string, issues []outcome.Issue) (string, []outcome.Issue) {
+	if concept == nil || len(concept.Coding) == 0 || concept.Coding[0].Code == nil {
+		return "", issues
+	}
+	code := *concept.Coding[0].Code
+	if !allowedCodes[code] {
+		return "", append(issues, droppedElementIssue(expression, code, "a code from the FHIR value set"))
+	}
+	return code, issues
+}
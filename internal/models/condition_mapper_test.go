@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestConditionMapper_RoundTrip verifies statuses, code, subject, and onset survive a round trip
+func TestConditionMapper_RoundTrip(t *testing.T) {
+	mapper := NewConditionMapper()
+	clinicalStatus := "active"
+	verificationStatus := "confirmed"
+	codeSystem := "http://snomed.info/sct"
+	code := "44054006"
+	codeDisplay := "Diabetes mellitus type 2"
+	patientReference := "Patient/patient-1"
+	onset := "2019-06-15"
+
+	condition, issues := mapper.FromFHIR(&fhir.Condition{
+		ClinicalStatus:     &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &clinicalStatus}}},
+		VerificationStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &verificationStatus}}},
+		Code:               &fhir.CodeableConcept{Coding: []fhir.Coding{{System: &codeSystem, Code: &code, Display: &codeDisplay}}},
+		Subject:            fhir.Reference{Reference: &patientReference},
+		OnsetDateTime:      &onset,
+	})
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if condition.ClinicalStatus != "active" || condition.VerificationStatus != "confirmed" || condition.Code != code || condition.PatientID != "patient-1" {
+		t.Errorf("Expected the submitted fields, got %+v", condition)
+	}
+	if condition.OnsetDate == nil || condition.OnsetDate.Format("2006-01-02") != onset {
+		t.Errorf("Expected onset %s to be parsed, got %v", onset, condition.OnsetDate)
+	}
+
+	fhirCondition := mapper.ToFHIR(condition)
+	if *fhirCondition.ClinicalStatus.Coding[0].System != ConditionClinicalStatusSystem || *fhirCondition.ClinicalStatus.Coding[0].Code != "active" {
+		t.Errorf("Expected clinical status active in the condition-clinical system, got %+v", fhirCondition.ClinicalStatus)
+	}
+	if *fhirCondition.VerificationStatus.Coding[0].System != ConditionVerificationStatusSystem {
+		t.Errorf("Expected the condition-ver-status system, got %+v", fhirCondition.VerificationStatus)
+	}
+	if *fhirCondition.Code.Coding[0].Display != codeDisplay || *fhirCondition.Subject.Reference != patientReference {
+		t.Errorf("Expected the code and subject back, got %+v", fhirCondition)
+	}
+	if *fhirCondition.OnsetDateTime != onset {
+		t.Errorf("Expected onset %s returned as submitted, got %s", onset, *fhirCondition.OnsetDateTime)
+	}
+}
+
+// TestConditionMapper_FromFHIR_ReportsDroppedValues verifies unknown statuses and unparseable onsets are reported and not stored
+func TestConditionMapper_FromFHIR_ReportsDroppedValues(t *testing.T) {
+	clinicalStatus := "chronic"
+	onset := "childhood"
+
+	condition, issues := NewConditionMapper().FromFHIR(&fhir.Condition{
+		ClinicalStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &clinicalStatus}}},
+		OnsetDateTime:  &onset,
+	})
+
+	if condition.ClinicalStatus != "" || condition.OnsetDateTime != "" || condition.OnsetDate != nil {
+		t.Errorf("Expected no clinical status or onset, got %+v", condition)
+	}
+	if len(issues) != 2 || issues[0].Expression[0] != "Condition.clinicalStatus" || issues[1].Expression[0] != "Condition.onsetDateTime" {
+		t.Errorf("Expected issues for clinicalStatus and onsetDateTime, got %+v", issues)
+	}
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+)
+
+// DeliveryStatus is the state of a queued outbound delivery
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending deliveries are waiting for their next attempt
+	DeliveryStatusPending DeliveryStatus = "pending"
+
+	// DeliveryStatusDead deliveries exhausted their attempts or failed permanently
+	DeliveryStatusDead DeliveryStatus = "dead"
+)
+
+// Delivery is an outbound payload awaiting delivery or held as a dead letter
+// This model maps to the delivery_queue table
+type Delivery struct {
+	ID int64 `json:"id"`
+
+	// Kind selects the sender: webhook, subscription, or kafka
+	Kind string `json:"kind"`
+
+	// Destination is the URL, topic, or subscription the payload is sent to
+	Destination string `json:"destination"`
+
+	Payload     []byte `json:"-"`
+	ContentType string `json:"content_type"`
+
+	Status        DeliveryStatus `json:"status"`
+	Attempts      int            `json:"attempts"`
+	LastError     string         `json:"last_error,omitempty"`
+	NextAttemptAt time.Time      `json:"next_attempt_at"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// DiagnosticReport represents a report grouping the observations produced by one diagnostic investigation
+// Stored in MongoDB next to the observations it references
+type DiagnosticReport struct {
+	ID                   string     `bson:"_id,omitempty"`
+	PatientID            string     `bson:"patient_id"`
+	Status               string     `bson:"status"`
+	Category             string     `bson:"category,omitempty"`
+	CategorySystem       string     `bson:"category_system,omitempty"`
+	CategoryDisplay      string     `bson:"category_display,omitempty"`
+	Code                 string     `bson:"code"`
+	CodeSystem           string     `bson:"code_system,omitempty"`
+	CodeDisplay          string     `bson:"code_display,omitempty"`
+	EffectiveDateTime    string     `bson:"effective_date_time,omitempty"`
+	EffectiveDate        *time.Time `bson:"effective_date,omitempty"`
+	ResultObservationIDs []string   `bson:"result_observation_ids,omitempty"`
+	Conclusion           string     `bson:"conclusion,omitempty"`
+	CreatedAt            time.Time  `bson:"created_at"`
+	UpdatedAt            time.Time  `bson:"updated_at"`
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// DiagnosticReportMapper converts between domain model and FHIR DiagnosticReport
+type DiagnosticReportMapper struct{}
+
+// NewDiagnosticReportMapper creates a new diagnostic report mapper instance
+func NewDiagnosticReportMapper() *DiagnosticReportMapper {
+	return &DiagnosticReportMapper{}
+}
+
+// ToFHIR converts a domain DiagnosticReport to a FHIR DiagnosticReport
+func (mapper *DiagnosticReportMapper) ToFHIR(report *DiagnosticReport) *fhir.DiagnosticReport {
+	// Stored statuses were read through DiagnosticReportStatus.Code, so they always parse; anything else reads as unknown
+	status := fhir.DiagnosticReportStatusUnknown
+	var parsedStatus fhir.DiagnosticReportStatus
+	if parsedStatus.UnmarshalJSON([]byte(report.Status)) == nil {
+		status = parsedStatus
+	}
+	fhirReport := &fhir.DiagnosticReport{Status: status}
+
+	// Set ID
+	if report.ID != "" {
+		fhirReport.Id = &report.ID
+	}
+
+	// Set category
+	if report.Category != "" {
+		fhirReport.Category = []fhir.CodeableConcept{{Coding: []fhir.Coding{reportCoding(report.Category, report.CategorySystem, report.CategoryDisplay)}}}
+	}
+
+	// Set code
+	if report.Code != "" {
+		fhirReport.Code = fhir.CodeableConcept{Coding: []fhir.Coding{reportCoding(report.Code, report.CodeSystem, report.CodeDisplay)}}
+	}
+
+	// Set subject (patient reference)
+	if report.PatientID != "" {
+		patientReference := "Patient/" + report.PatientID
+		fhirReport.Subject = &fhir.Reference{Reference: &patientReference}
+	}
+
+	// Set effective date exactly as it was submitted
+	if report.EffectiveDateTime != "" {
+		fhirReport.EffectiveDateTime = &report.EffectiveDateTime
+	}
+
+	// Set result references to the grouped observations
+	for _, observationID := range report.ResultObservationIDs {
+		observationReference := "Observation/" + observationID
+		fhirReport.Result = append(fhirReport.Result, fhir.Reference{Reference: &observationReference})
+	}
+
+	// Set conclusion
+	if report.Conclusion != "" {
+		fhirReport.Conclusion = &report.Conclusion
+	}
+
+	return fhirReport
+}
+
+// reportCoding builds a coding from a stored code and its optional system and display
+func reportCoding(code string, system string, display string) fhir.Coding {
+	coding := fhir.Coding{Code: &code}
+	if system != "" {
+		coding.System = &system
+	}
+	if display != "" {
+		coding.Display = &display
+	}
+	return coding
+}
+
+// FromFHIR converts a FHIR DiagnosticReport to a domain DiagnosticReport
+// Results that are not Observation/{id} references are left out; the service rejects them before mapping
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *DiagnosticReportMapper) FromFHIR(fhirReport *fhir.DiagnosticReport) (*DiagnosticReport, []outcome.Issue) {
+	var issues []outcome.Issue
+	report := &DiagnosticReport{
+		Status:    fhirReport.Status.Code(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// Extract ID
+	if fhirReport.Id != nil {
+		report.ID = *fhirReport.Id
+	}
+
+	// Extract category from the first coding
+	if len(fhirReport.Category) > 0 && len(fhirReport.Category[0].Coding) > 0 {
+		report.Category, report.CategorySystem, report.CategoryDisplay = codingParts(fhirReport.Category[0].Coding[0])
+	}
+
+	// Extract code from the first coding
+	if len(fhirReport.Code.Coding) > 0 {
+		report.Code, report.CodeSystem, report.CodeDisplay = codingParts(fhirReport.Code.Coding[0])
+	}
+
+	// Extract patient ID from subject reference
+	if fhirReport.Subject != nil && fhirReport.Subject.Reference != nil {
+		if patientID, isPatient := strings.CutPrefix(*fhirReport.Subject.Reference, "Patient/"); isPatient && patientID != "" {
+			report.PatientID = patientID
+		}
+	}
+
+	// Extract effective date, keeping the submitted text so a day is not returned as a timestamp
+	if fhirReport.EffectiveDateTime != nil {
+		for _, layout := range clinicalDateLayouts {
+			if parsedTime, parseError := time.Parse(layout, *fhirReport.EffectiveDateTime); parseError == nil {
+				report.EffectiveDateTime = *fhirReport.EffectiveDateTime
+				report.EffectiveDate = &parsedTime
+				break
+			}
+		}
+		if report.EffectiveDate == nil {
+			issues = append(issues, droppedElementIssue("DiagnosticReport.effectiveDateTime", *fhirReport.EffectiveDateTime, "an RFC 3339 date-time or a YYYY-MM-DD date"))
+		}
+	}
+
+	// Extract the IDs of the result observations
+	report.ResultObservationIDs = DiagnosticReportResultIDs(fhirReport)
+
+	// Extract conclusion
+	if fhirReport.Conclusion != nil {
+		report.Conclusion = *fhirReport.Conclusion
+	}
+
+	return report, issues
+}
+
+// codingParts reads the code, system, and display of a coding, leaving absent parts empty
+func codingParts(coding fhir.Coding) (string, string, string) {
+	var code, system, display string
+	if coding.Code != nil {
+		code = *coding.Code
+	}
+	if coding.System != nil {
+		system = *coding.System
+	}
+	if coding.Display != nil {
+		display = *coding.Display
+	}
+	return code, system, display
+}
+
+// DiagnosticReportResultIDs returns the observation IDs of a report's Observation/{id} result references, in order
+func DiagnosticReportResultIDs(fhirReport *fhir.DiagnosticReport) []string {
+	var observationIDs []string
+	for _, result := range fhirReport.Result {
+		if result.Reference == nil {
+			continue
+		}
+		if observationID, isObservation := strings.CutPrefix(*result.Reference, "Observation/"); isObservation && observationID != "" {
+			observationIDs = append(observationIDs, observationID)
+		}
+	}
+	return observationIDs
+}
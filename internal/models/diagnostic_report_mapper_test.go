@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestDiagnosticReportMapper_RoundTrip verifies status, category, code, subject, effective date, results, and conclusion survive a round trip
+func TestDiagnosticReportMapper_RoundTrip(t *testing.T) {
+	mapper := NewDiagnosticReportMapper()
+	categorySystem := "http://terminology.hl7.org/CodeSystem/v2-0074"
+	category := "LAB"
+	codeSystem := "http://loinc.org"
+	code := "58410-2"
+	codeDisplay := "CBC panel - Blood by Automated count"
+	patientReference := "Patient/patient-1"
+	effectiveDateTime := "2024-03-05"
+	conclusion := "Within normal limits"
+	firstResult := "Observation/obs-1"
+	secondResult := "Observation/obs-2"
+
+	report, issues := mapper.FromFHIR(&fhir.DiagnosticReport{
+		Status:            fhir.DiagnosticReportStatusFinal,
+		Category:          []fhir.CodeableConcept{{Coding: []fhir.Coding{{System: &categorySystem, Code: &category}}}},
+		Code:              fhir.CodeableConcept{Coding: []fhir.Coding{{System: &codeSystem, Code: &code, Display: &codeDisplay}}},
+		Subject:           &fhir.Reference{Reference: &patientReference},
+		EffectiveDateTime: &effectiveDateTime,
+		Result:            []fhir.Reference{{Reference: &firstResult}, {Reference: &secondResult}},
+		Conclusion:        &conclusion,
+	})
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if report.Status != "final" || report.Category != "LAB" || report.Code != "58410-2" || report.PatientID != "patient-1" || report.EffectiveDate == nil {
+		t.Errorf("Expected the submitted fields, got %+v", report)
+	}
+	if len(report.ResultObservationIDs) != 2 || report.ResultObservationIDs[1] != "obs-2" {
+		t.Errorf("Expected both result observation IDs in order, got %v", report.ResultObservationIDs)
+	}
+
+	fhirReport := mapper.ToFHIR(report)
+	if fhirReport.Status != fhir.DiagnosticReportStatusFinal || *fhirReport.Category[0].Coding[0].System != categorySystem {
+		t.Errorf("Expected the final status and category system back, got %+v", fhirReport)
+	}
+	if *fhirReport.Code.Coding[0].Display != codeDisplay || *fhirReport.Subject.Reference != patientReference || *fhirReport.EffectiveDateTime != effectiveDateTime {
+		t.Errorf("Expected the code, subject, and submitted effective date back, got %+v", fhirReport)
+	}
+	if len(fhirReport.Result) != 2 || *fhirReport.Result[0].Reference != firstResult || *fhirReport.Conclusion != conclusion {
+		t.Errorf("Expected the results and conclusion back, got %+v", fhirReport)
+	}
+}
+
+// TestDiagnosticReportMapper_FromFHIR_ReportsDroppedEffectiveDate verifies an unparseable effective date is reported and not stored
+func TestDiagnosticReportMapper_FromFHIR_ReportsDroppedEffectiveDate(t *testing.T) {
+	effectiveDateTime := "March 2024"
+
+	report, issues := NewDiagnosticReportMapper().FromFHIR(&fhir.DiagnosticReport{EffectiveDateTime: &effectiveDateTime})
+	if report.EffectiveDateTime != "" || report.EffectiveDate != nil {
+		t.Errorf("Expected the effective date to be dropped, got %+v", report)
+	}
+	if len(issues) != 1 || issues[0].Expression[0] != "DiagnosticReport.effectiveDateTime" {
+		t.Errorf("Expected one issue for the effective date, got %+v", issues)
+	}
+}
@@ -0,0 +1,15 @@
+package models
+
+import (
+	"time"
+)
+
+// EditLock is an advisory edit lock on a single resource
+// This model maps to the edit_locks table
+type EditLock struct {
+	ResourceType string    `json:"resource_type"`
+	ResourceID   string    `json:"resource_id"`
+	Owner        string    `json:"owner"`
+	AcquiredAt   time.Time `json:"acquired_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Encounter represents a patient visit in the database
+// This model maps to the encounters table and can be converted to FHIR format
+type Encounter struct {
+	// Unique identifier for the encounter (UUID)
+	ID string `json:"id"`
+
+	// Encounter status code (planned, arrived, in-progress, finished, ...)
+	Status string `json:"status"`
+
+	// Class coding classifying the visit (e.g., ambulatory, inpatient)
+	ClassSystem  string `json:"class_system"`
+	ClassCode    string `json:"class_code"`
+	ClassDisplay string `json:"class_display"`
+
+	// Subject patient ID; empty when the encounter names no patient
+	PatientID string `json:"patient_id"`
+
+	// IDs of the practitioners taking part, in participant order
+	ParticipantPractitionerIDs []string `json:"participant_practitioner_ids"`
+
+	// Visit period; PeriodEnd is nil while the encounter is ongoing
+	PeriodStart *time.Time `json:"period_start"`
+	PeriodEnd   *time.Time `json:"period_end"`
+
+	// Audit timestamps
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// EncounterMapper handles conversion between domain Encounter model and FHIR Encounter resource
+type EncounterMapper struct{}
+
+// NewEncounterMapper creates a new instance of EncounterMapper
+func NewEncounterMapper() *EncounterMapper {
+	return &EncounterMapper{}
+}
+
+// ToFHIR converts a domain Encounter to a FHIR Encounter
+func (mapper *EncounterMapper) ToFHIR(encounter *Encounter) *fhir.Encounter {
+	// Stored statuses were read through EncounterStatus.Code, so they always parse; anything else reads as unknown
+	status := fhir.EncounterStatusUnknown
+	var parsedStatus fhir.EncounterStatus
+	if parsedStatus.UnmarshalJSON([]byte(encounter.Status)) == nil {
+		status = parsedStatus
+	}
+
+	fhirEncounter := &fhir.Encounter{
+		Id:     &encounter.ID,
+		Status: status,
+	}
+
+	// Set class
+	if encounter.ClassCode != "" {
+		fhirEncounter.Class.Code = &encounter.ClassCode
+		if encounter.ClassSystem != "" {
+			fhirEncounter.Class.System = &encounter.ClassSystem
+		}
+		if encounter.ClassDisplay != "" {
+			fhirEncounter.Class.Display = &encounter.ClassDisplay
+		}
+	}
+
+	// Set subject (patient reference)
+	if encounter.PatientID != "" {
+		patientReference := "Patient/" + encounter.PatientID
+		fhirEncounter.Subject = &fhir.Reference{Reference: &patientReference}
+	}
+
+	// Set participants (practitioner references)
+	for _, practitionerID := range encounter.ParticipantPractitionerIDs {
+		practitionerReference := "Practitioner/" + practitionerID
+		fhirEncounter.Participant = append(fhirEncounter.Participant, fhir.EncounterParticipant{
+			Individual: &fhir.Reference{Reference: &practitionerReference},
+		})
+	}
+
+	// Set period
+	if encounter.PeriodStart != nil || encounter.PeriodEnd != nil {
+		fhirEncounter.Period = &fhir.Period{}
+		if encounter.PeriodStart != nil {
+			periodStart := encounter.PeriodStart.UTC().Format(time.RFC3339)
+			fhirEncounter.Period.Start = &periodStart
+		}
+		if encounter.PeriodEnd != nil {
+			periodEnd := encounter.PeriodEnd.UTC().Format(time.RFC3339)
+			fhirEncounter.Period.End = &periodEnd
+		}
+	}
+
+	return fhirEncounter
+}
+
+// FromFHIR converts a FHIR Encounter to a domain Encounter
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *EncounterMapper) FromFHIR(fhirEncounter *fhir.Encounter) (*Encounter, []outcome.Issue) {
+	encounter := &Encounter{Status: fhirEncounter.Status.Code()}
+	var issues []outcome.Issue
+
+	// Map ID
+	if fhirEncounter.Id != nil {
+		encounter.ID = *fhirEncounter.Id
+	}
+
+	// Map class
+	if fhirEncounter.Class.Code != nil {
+		encounter.ClassCode = *fhirEncounter.Class.Code
+		if fhirEncounter.Class.System != nil {
+			encounter.ClassSystem = *fhirEncounter.Class.System
+		}
+		if fhirEncounter.Class.Display != nil {
+			encounter.ClassDisplay = *fhirEncounter.Class.Display
+		}
+	}
+
+	// Map patient ID from subject reference
+	if fhirEncounter.Subject != nil && fhirEncounter.Subject.Reference != nil {
+		if patientID, isPatient := strings.CutPrefix(*fhirEncounter.Subject.Reference, "Patient/"); isPatient && patientID != "" {
+			encounter.PatientID = patientID
+		}
+	}
+
+	// Map practitioner participants; participants of other types are not stored
+	for _, participant := range fhirEncounter.Participant {
+		if participant.Individual == nil || participant.Individual.Reference == nil {
+			continue
+		}
+		if practitionerID, isPractitioner := strings.CutPrefix(*participant.Individual.Reference, "Practitioner/"); isPractitioner && practitionerID != "" {
+			encounter.ParticipantPractitionerIDs = append(encounter.ParticipantPractitionerIDs, practitionerID)
+		}
+	}
+
+	// Map period
+	if fhirEncounter.Period != nil {
+		encounter.PeriodStart, issues = parseDateTimeElement(fhirEncounter.Period.Start, "Encounter.period.start", issues)
+		encounter.PeriodEnd, issues = parseDateTimeElement(fhirEncounter.Period.End, "Encounter.period.end", issues)
+	}
+
+	return encounter, issues
+}
+
+// parseDateTimeElement parses a dateTime element such as a period boundary, adding a dropped-element issue when it is not an RFC 3339 date-time
+func parseDateTimeElement(value *string, expression string, issues []outcome.Issue) (*time.Time, []outcome.Issue) {
+	if value == nil {
+		return nil, issues
+	}
+	parsedTime, parseError := time.Parse(time.RFC3339, *value)
+	if parseError != nil {
+		return nil, append(issues, droppedElementIssue(expression, *value, "an RFC 3339 date-time"))
+	}
+	return &parsedTime, issues
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestEncounterMapper_RoundTrip verifies status, class, subject, participants, and period survive a round trip
+func TestEncounterMapper_RoundTrip(t *testing.T) {
+	mapper := NewEncounterMapper()
+	periodStart := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	periodEnd := time.Date(2024, 3, 1, 10, 15, 0, 0, time.UTC)
+
+	fhirEncounter := mapper.ToFHIR(&Encounter{
+		ID:                         "encounter-1",
+		Status:                     "finished",
+		ClassSystem:                "http://terminology.hl7.org/CodeSystem/v3-ActCode",
+		ClassCode:                  "AMB",
+		ClassDisplay:               "ambulatory",
+		PatientID:                  "patient-1",
+		ParticipantPractitionerIDs: []string{"practitioner-1", "practitioner-2"},
+		PeriodStart:                &periodStart,
+		PeriodEnd:                  &periodEnd,
+	})
+
+	if fhirEncounter.Status != fhir.EncounterStatusFinished {
+		t.Errorf("Expected status finished, got %s", fhirEncounter.Status.Code())
+	}
+	if *fhirEncounter.Class.Code != "AMB" || *fhirEncounter.Subject.Reference != "Patient/patient-1" {
+		t.Errorf("Expected class AMB for Patient/patient-1, got %+v", fhirEncounter)
+	}
+	if len(fhirEncounter.Participant) != 2 || *fhirEncounter.Participant[1].Individual.Reference != "Practitioner/practitioner-2" {
+		t.Errorf("Expected two practitioner participants, got %+v", fhirEncounter.Participant)
+	}
+	if *fhirEncounter.Period.Start != "2024-03-01T09:30:00Z" || *fhirEncounter.Period.End != "2024-03-01T10:15:00Z" {
+		t.Errorf("Expected the visit period, got %+v", fhirEncounter.Period)
+	}
+
+	encounter, issues := mapper.FromFHIR(fhirEncounter)
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if encounter.Status != "finished" || encounter.ClassSystem != "http://terminology.hl7.org/CodeSystem/v3-ActCode" || encounter.PatientID != "patient-1" {
+		t.Errorf("Expected the stored fields back, got %+v", encounter)
+	}
+	if len(encounter.ParticipantPractitionerIDs) != 2 || encounter.ParticipantPractitionerIDs[0] != "practitioner-1" {
+		t.Errorf("Expected both practitioner IDs in order, got %v", encounter.ParticipantPractitionerIDs)
+	}
+	if !encounter.PeriodStart.Equal(periodStart) || !encounter.PeriodEnd.Equal(periodEnd) {
+		t.Errorf("Expected the period back, got %v to %v", encounter.PeriodStart, encounter.PeriodEnd)
+	}
+}
+
+// TestEncounterMapper_FromFHIR_DropsUnmappedValues verifies unparseable period ends and non-practitioner participants are not stored
+func TestEncounterMapper_FromFHIR_DropsUnmappedValues(t *testing.T) {
+	periodStart := "March 1st"
+	relatedPersonReference := "RelatedPerson/related-1"
+
+	encounter, issues := NewEncounterMapper().FromFHIR(&fhir.Encounter{
+		Status:      fhir.EncounterStatusInProgress,
+		Period:      &fhir.Period{Start: &periodStart},
+		Participant: []fhir.EncounterParticipant{{Individual: &fhir.Reference{Reference: &relatedPersonReference}}},
+	})
+
+	if encounter.Status != "in-progress" {
+		t.Errorf("Expected status in-progress, got %q", encounter.Status)
+	}
+	if encounter.PeriodStart != nil || encounter.PeriodEnd != nil {
+		t.Errorf("Expected no period, got %v to %v", encounter.PeriodStart, encounter.PeriodEnd)
+	}
+	if len(encounter.ParticipantPractitionerIDs) != 0 {
+		t.Errorf("Expected no practitioner participants, got %v", encounter.ParticipantPractitionerIDs)
+	}
+	if len(issues) != 1 || issues[0].Expression[0] != "Encounter.period.start" {
+		t.Errorf("Expected one issue for Encounter.period.start, got %+v", issues)
+	}
+}
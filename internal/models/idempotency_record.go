@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+)
+
+// IdempotencyRecord is a POST request made with an Idempotency-Key and, once it finishes, the response
+// replayed to retries of it
+// This model maps to the idempotency_keys table
+type IdempotencyRecord struct {
+	// ClientID scopes the key to the caller, so two clients choosing the same key never see each other's responses
+	ClientID string
+	Key      string
+
+	Method string
+	Path   string
+
+	// RequestHash is the SHA-256 of the request body, to tell a retry from a different request reusing the key
+	RequestHash string
+
+	// Completed is false while the first request is still being processed
+	Completed bool
+
+	StatusCode      int
+	ResponseHeaders map[string]string
+	ResponseBody    []byte
+
+	CreatedAt time.Time
+
+	// ExpiresAt is when the key may be used for a new request
+	ExpiresAt time.Time
+}
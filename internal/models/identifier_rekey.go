@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+)
+
+// RekeyJobStatus is the state of a bulk identifier re-keying job
+type RekeyJobStatus string
+
+const (
+	// RekeyJobStatusRunning jobs still have mappings waiting to be applied
+	RekeyJobStatusRunning RekeyJobStatus = "running"
+
+	// RekeyJobStatusCompleted jobs have applied or skipped every mapping
+	RekeyJobStatusCompleted RekeyJobStatus = "completed"
+
+	// RekeyJobStatusRollingBack jobs are restoring the old identifiers
+	RekeyJobStatusRollingBack RekeyJobStatus = "rolling_back"
+
+	// RekeyJobStatusRolledBack jobs have restored every identifier they could
+	RekeyJobStatusRolledBack RekeyJobStatus = "rolled_back"
+)
+
+// RekeyAction is the kind of entry in a re-keying job's audit history
+type RekeyAction string
+
+const (
+	// RekeyActionRekeyed records a patient's identifier being rewritten
+	RekeyActionRekeyed RekeyAction = "rekeyed"
+
+	// RekeyActionSkipped records a mapping that could not be applied
+	RekeyActionSkipped RekeyAction = "skipped"
+
+	// RekeyActionRolledBack records a patient's old identifier being restored
+	RekeyActionRolledBack RekeyAction = "rolled_back"
+
+	// RekeyActionRollbackSkipped records a rewrite that could not be undone
+	RekeyActionRollbackSkipped RekeyAction = "rollback_skipped"
+)
+
+// IdentifierMapping is one line of a re-keying mapping file
+type IdentifierMapping struct {
+	// Line is the mapping's line in the uploaded file
+	Line int `json:"line"`
+
+	OldSystem string `json:"old_system"`
+	OldValue  string `json:"old_value"`
+	NewSystem string `json:"new_system"`
+	NewValue  string `json:"new_value"`
+}
+
+// RekeyJob is a bulk rewrite of patient identifiers from an uploaded mapping
+// This model maps to the identifier_rekey_jobs table; the counts are derived from its mappings and audit history
+type RekeyJob struct {
+	ID            int64          `json:"id"`
+	Status        RekeyJobStatus `json:"status"`
+	Reason        string         `json:"reason"`
+	Actor         string         `json:"actor"`
+	RollbackActor string         `json:"rollback_actor,omitempty"`
+
+	Total      int `json:"total"`
+	Processed  int `json:"processed"`
+	Rekeyed    int `json:"rekeyed"`
+	Skipped    int `json:"skipped"`
+	RolledBack int `json:"rolled_back"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RekeyRecord is one entry in a re-keying job's audit history
+// This model maps to the identifier_rekey_audit table
+type RekeyRecord struct {
+	ID    int64 `json:"id"`
+	JobID int64 `json:"job_id"`
+
+	// PatientID is empty when no patient had the old identifier
+	PatientID string      `json:"patient_id,omitempty"`
+	Action    RekeyAction `json:"action"`
+
+	OldSystem string `json:"old_system"`
+	OldValue  string `json:"old_value"`
+	NewSystem string `json:"new_system"`
+	NewValue  string `json:"new_value"`
+
+	// Detail explains a skip
+	Detail string `json:"detail,omitempty"`
+
+	// RevertsID is the rekeyed entry a rollback entry reverts; zero otherwise
+	RevertsID int64 `json:"reverts_id,omitempty"`
+
+	Actor      string    `json:"actor"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
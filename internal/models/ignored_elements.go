@@ -0,0 +1,100 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// IgnoredElementIssues compares a submitted resource with the resource as stored and returns an
+// informational issue for every element the mappers do not support and silently left out
+// Both resources are compared by their JSON form; only element presence matters, not changed values
+// Elements at or under an expression in alreadyReported (values that failed to map) are not reported again
+func IgnoredElementIssues(resourceType string, submitted interface{}, stored interface{}, alreadyReported []outcome.Issue) []outcome.Issue {
+	submittedElements, submittedError := genericJSON(submitted)
+	storedElements, storedError := genericJSON(stored)
+	if submittedError != nil || storedError != nil {
+		return nil
+	}
+
+	var ignoredPaths []string
+	collectIgnoredPaths(resourceType, submittedElements, storedElements, &ignoredPaths)
+
+	var issues []outcome.Issue
+	for _, path := range ignoredPaths {
+		if coveredByIssues(path, alreadyReported) {
+			continue
+		}
+		issues = append(issues, outcome.Information(
+			fhir.IssueTypeNotSupported,
+			fmt.Sprintf("%s is not supported and was not stored", path),
+			path,
+		))
+	}
+	return issues
+}
+
+// genericJSON round-trips a value through JSON into maps and slices
+func genericJSON(value interface{}) (interface{}, error) {
+	encoded, encodeError := json.Marshal(value)
+	if encodeError != nil {
+		return nil, encodeError
+	}
+
+	var decoded interface{}
+	decodeError := json.Unmarshal(encoded, &decoded)
+	return decoded, decodeError
+}
+
+// collectIgnoredPaths appends the paths present in submitted but missing from stored
+// A missing object or array item is reported once, without descending into it
+func collectIgnoredPaths(path string, submitted interface{}, stored interface{}, ignoredPaths *[]string) {
+	switch submittedValue := submitted.(type) {
+	case map[string]interface{}:
+		storedObject, _ := stored.(map[string]interface{})
+
+		elementNames := make([]string, 0, len(submittedValue))
+		for elementName := range submittedValue {
+			elementNames = append(elementNames, elementName)
+		}
+		sort.Strings(elementNames)
+
+		for _, elementName := range elementNames {
+			if submittedValue[elementName] == nil {
+				continue
+			}
+			storedElement, present := storedObject[elementName]
+			if !present {
+				*ignoredPaths = append(*ignoredPaths, path+"."+elementName)
+				continue
+			}
+			collectIgnoredPaths(path+"."+elementName, submittedValue[elementName], storedElement, ignoredPaths)
+		}
+	case []interface{}:
+		storedArray, _ := stored.([]interface{})
+		for index, submittedItem := range submittedValue {
+			itemPath := fmt.Sprintf("%s[%d]", path, index)
+			if index >= len(storedArray) {
+				*ignoredPaths = append(*ignoredPaths, itemPath)
+				continue
+			}
+			collectIgnoredPaths(itemPath, submittedItem, storedArray[index], ignoredPaths)
+		}
+	}
+}
+
+// coveredByIssues reports whether path is, or is inside, an element one of the issues already describes
+func coveredByIssues(path string, issues []outcome.Issue) bool {
+	for _, issue := range issues {
+		for _, expression := range issue.Expression {
+			if path == expression || strings.HasPrefix(path, expression+".") || strings.HasPrefix(path, expression+"[") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestIgnoredElementIssues verifies unsupported elements and extra array items are reported once each
+func TestIgnoredElementIssues(t *testing.T) {
+	family := "Smith"
+	secondFamily := "Jones"
+	phone := "555-0100"
+	birthDate := "1990-13-45"
+	submitted := &fhir.Patient{
+		Name:      []fhir.HumanName{{Family: &family, Given: []string{"John", "Paul"}}, {Family: &secondFamily}},
+		Telecom:   []fhir.ContactPoint{{Value: &phone}},
+		BirthDate: &birthDate,
+	}
+	stored := &fhir.Patient{
+		Name: []fhir.HumanName{{Family: &family, Given: []string{"John"}}},
+	}
+	alreadyReported := []outcome.Issue{outcome.Warning(fhir.IssueTypeValue, "bad date", "Patient.birthDate")}
+
+	issues := IgnoredElementIssues("Patient", submitted, stored, alreadyReported)
+
+	expectedPaths := []string{"Patient.name[0].given[1]", "Patient.name[1]", "Patient.telecom"}
+	if len(issues) != len(expectedPaths) {
+		t.Fatalf("Expected %d issues, got %+v", len(expectedPaths), issues)
+	}
+	for index, expectedPath := range expectedPaths {
+		if issues[index].Expression[0] != expectedPath {
+			t.Errorf("Expected issue %d for %s, got %v", index, expectedPath, issues[index].Expression)
+		}
+		if issues[index].Severity != fhir.IssueSeverityInformation || issues[index].Code != fhir.IssueTypeNotSupported {
+			t.Errorf("Expected informational not-supported issue, got %+v", issues[index])
+		}
+	}
+}
+
+// TestIgnoredElementIssues_NothingDropped verifies a fully stored resource produces no issues
+func TestIgnoredElementIssues_NothingDropped(t *testing.T) {
+	family := "Smith"
+	patient := &fhir.Patient{Name: []fhir.HumanName{{Family: &family}}}
+
+	if issues := IgnoredElementIssues("Patient", patient, patient, nil); len(issues) != 0 {
+		t.Errorf("Expected no issues, got %+v", issues)
+	}
+}
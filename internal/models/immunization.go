@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+// Immunization represents a vaccination record in the database
+// This model maps to the immunizations table and can be converted to FHIR format
+type Immunization struct {
+	// Unique identifier for the immunization (UUID)
+	ID string `json:"id"`
+
+	// Immunization status code (completed, entered-in-error, not-done)
+	Status string `json:"status"`
+
+	// Coding of the administered vaccine (e.g., CVX)
+	VaccineSystem  string `json:"vaccine_system"`
+	VaccineCode    string `json:"vaccine_code"`
+	VaccineDisplay string `json:"vaccine_display"`
+
+	// Vaccinated patient ID; empty when the immunization names no patient
+	PatientID string `json:"patient_id"`
+
+	// When the vaccine was given, exactly as submitted, and the parsed time used for date searches
+	OccurrenceDateTime string     `json:"occurrence_date_time"`
+	OccurredAt         *time.Time `json:"occurred_at"`
+
+	// Vaccine lot number
+	LotNumber string `json:"lot_number"`
+
+	// Audit timestamps
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
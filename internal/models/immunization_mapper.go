@@ -0,0 +1,134 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// immunizationStatuses are the codes of the FHIR immunization-status value set
+// The FHIR models accept every event status for Immunization.status, so the narrower value set is checked here
+var immunizationStatuses = map[fhir.ImmunizationStatusCodes]bool{
+	fhir.ImmunizationStatusCodesCompleted:      true,
+	fhir.ImmunizationStatusCodesEnteredInError: true,
+	fhir.ImmunizationStatusCodesNotDone:        true,
+}
+
+// ImmunizationMapper handles conversion between domain Immunization model and FHIR Immunization resource
+type ImmunizationMapper struct{}
+
+// NewImmunizationMapper creates a new instance of ImmunizationMapper
+func NewImmunizationMapper() *ImmunizationMapper {
+	return &ImmunizationMapper{}
+}
+
+// ImmunizationCodeIssues returns an error issue for a status outside the FHIR immunization-status value set
+// Status is required and decodes as "preparation" when left out, so an immunization with this issue cannot be stored
+func ImmunizationCodeIssues(fhirImmunization *fhir.Immunization) []outcome.Issue {
+	if immunizationStatuses[fhirImmunization.Status] {
+		return nil
+	}
+	return []outcome.Issue{outcome.Error(fhir.IssueTypeCodeInvalid,
+		fmt.Sprintf("Immunization.status value %q is not one of completed, entered-in-error, or not-done", fhirImmunization.Status.Code()), "Immunization.status")}
+}
+
+// ToFHIR converts a domain Immunization to a FHIR Immunization
+func (mapper *ImmunizationMapper) ToFHIR(immunization *Immunization) *fhir.Immunization {
+	// Stored statuses were read through ImmunizationStatusCodes.Code, so they always parse; anything else reads as unknown
+	status := fhir.ImmunizationStatusCodesUnknown
+	var parsedStatus fhir.ImmunizationStatusCodes
+	if parsedStatus.UnmarshalJSON([]byte(immunization.Status)) == nil {
+		status = parsedStatus
+	}
+	fhirImmunization := &fhir.Immunization{
+		Id:     &immunization.ID,
+		Status: status,
+	}
+
+	// Set vaccine coding
+	if immunization.VaccineCode != "" {
+		coding := fhir.Coding{Code: &immunization.VaccineCode}
+		if immunization.VaccineSystem != "" {
+			coding.System = &immunization.VaccineSystem
+		}
+		if immunization.VaccineDisplay != "" {
+			coding.Display = &immunization.VaccineDisplay
+		}
+		fhirImmunization.VaccineCode.Coding = []fhir.Coding{coding}
+	}
+
+	// Set patient reference
+	if immunization.PatientID != "" {
+		patientReference := "Patient/" + immunization.PatientID
+		fhirImmunization.Patient.Reference = &patientReference
+	}
+
+	// Set occurrence exactly as it was submitted
+	fhirImmunization.OccurrenceDateTime = immunization.OccurrenceDateTime
+
+	// Set lot number
+	if immunization.LotNumber != "" {
+		fhirImmunization.LotNumber = &immunization.LotNumber
+	}
+
+	return fhirImmunization
+}
+
+// FromFHIR converts a FHIR Immunization to a domain Immunization
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *ImmunizationMapper) FromFHIR(fhirImmunization *fhir.Immunization) (*Immunization, []outcome.Issue) {
+	immunization := &Immunization{
+		Status: fhirImmunization.Status.Code(),
+	}
+	var issues []outcome.Issue
+
+	// Map ID
+	if fhirImmunization.Id != nil {
+		immunization.ID = *fhirImmunization.Id
+	}
+
+	// Map vaccine from the first coding
+	if len(fhirImmunization.VaccineCode.Coding) > 0 {
+		coding := fhirImmunization.VaccineCode.Coding[0]
+		if coding.Code != nil {
+			immunization.VaccineCode = *coding.Code
+		}
+		if coding.System != nil {
+			immunization.VaccineSystem = *coding.System
+		}
+		if coding.Display != nil {
+			immunization.VaccineDisplay = *coding.Display
+		}
+	}
+
+	// Map patient ID from patient reference
+	if fhirImmunization.Patient.Reference != nil {
+		if patientID, isPatient := strings.CutPrefix(*fhirImmunization.Patient.Reference, "Patient/"); isPatient && patientID != "" {
+			immunization.PatientID = patientID
+		}
+	}
+
+	// Map occurrence, keeping the submitted text so a day is not returned as a timestamp
+	if fhirImmunization.OccurrenceDateTime != "" {
+		for _, layout := range clinicalDateLayouts {
+			if parsedTime, parseError := time.Parse(layout, fhirImmunization.OccurrenceDateTime); parseError == nil {
+				immunization.OccurrenceDateTime = fhirImmunization.OccurrenceDateTime
+				immunization.OccurredAt = &parsedTime
+				break
+			}
+		}
+		if immunization.OccurredAt == nil {
+			issues = append(issues, droppedElementIssue("Immunization.occurrenceDateTime", fhirImmunization.OccurrenceDateTime, "an RFC 3339 date-time or a YYYY-MM-DD date"))
+		}
+	}
+
+	// Map lot number
+	if fhirImmunization.LotNumber != nil {
+		immunization.LotNumber = *fhirImmunization.LotNumber
+	}
+
+	return immunization, issues
+}
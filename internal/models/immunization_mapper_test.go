@@ -0,0 +1,63 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestImmunizationMapper_RoundTrip verifies status, vaccine code, patient, occurrence, and lot number survive a round trip
+func TestImmunizationMapper_RoundTrip(t *testing.T) {
+	mapper := NewImmunizationMapper()
+	vaccineSystem := "http://hl7.org/fhir/sid/cvx"
+	vaccineCode := "208"
+	vaccineDisplay := "COVID-19, mRNA, LNP-S, PF, 30 mcg/0.3 mL dose"
+	patientReference := "Patient/patient-1"
+	lotNumber := "EW0182"
+
+	immunization, issues := mapper.FromFHIR(&fhir.Immunization{
+		Status:             fhir.ImmunizationStatusCodesCompleted,
+		VaccineCode:        fhir.CodeableConcept{Coding: []fhir.Coding{{System: &vaccineSystem, Code: &vaccineCode, Display: &vaccineDisplay}}},
+		Patient:            fhir.Reference{Reference: &patientReference},
+		OccurrenceDateTime: "2021-04-15",
+		LotNumber:          &lotNumber,
+	})
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if immunization.Status != "completed" || immunization.VaccineCode != "208" || immunization.PatientID != "patient-1" || immunization.OccurredAt == nil {
+		t.Errorf("Expected the submitted fields, got %+v", immunization)
+	}
+
+	fhirImmunization := mapper.ToFHIR(immunization)
+	if fhirImmunization.Status != fhir.ImmunizationStatusCodesCompleted || *fhirImmunization.VaccineCode.Coding[0].System != vaccineSystem {
+		t.Errorf("Expected the completed status and CVX system back, got %+v", fhirImmunization)
+	}
+	if *fhirImmunization.Patient.Reference != patientReference || fhirImmunization.OccurrenceDateTime != "2021-04-15" || *fhirImmunization.LotNumber != lotNumber {
+		t.Errorf("Expected the patient, submitted occurrence, and lot number back, got %+v", fhirImmunization)
+	}
+}
+
+// TestImmunizationMapper_FromFHIR_ReportsDroppedOccurrence verifies an unparseable occurrence is reported and not stored
+func TestImmunizationMapper_FromFHIR_ReportsDroppedOccurrence(t *testing.T) {
+	immunization, issues := NewImmunizationMapper().FromFHIR(&fhir.Immunization{Status: fhir.ImmunizationStatusCodesCompleted, OccurrenceDateTime: "spring 2021"})
+	if immunization.OccurrenceDateTime != "" || immunization.OccurredAt != nil {
+		t.Errorf("Expected the occurrence to be dropped, got %+v", immunization)
+	}
+	if len(issues) != 1 || issues[0].Expression[0] != "Immunization.occurrenceDateTime" {
+		t.Errorf("Expected one issue for Immunization.occurrenceDateTime, got %+v", issues)
+	}
+}
+
+// TestImmunizationCodeIssues verifies statuses outside the immunization-status value set are errors
+func TestImmunizationCodeIssues(t *testing.T) {
+	for _, status := range []fhir.ImmunizationStatusCodes{fhir.ImmunizationStatusCodesCompleted, fhir.ImmunizationStatusCodesNotDone, fhir.ImmunizationStatusCodesEnteredInError} {
+		if issues := ImmunizationCodeIssues(&fhir.Immunization{Status: status}); len(issues) != 0 {
+			t.Errorf("Expected no issues for status %s, got %+v", status, issues)
+		}
+	}
+	issues := ImmunizationCodeIssues(&fhir.Immunization{Status: fhir.ImmunizationStatusCodesInProgress})
+	if len(issues) != 1 || issues[0].Expression[0] != "Immunization.status" {
+		t.Errorf("Expected one issue for Immunization.status, got %+v", issues)
+	}
+}
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// JobStatus is the state of a background job
+type JobStatus string
+
+const (
+	// JobStatusQueued jobs wait for a worker, either new or retrying after a failed attempt
+	JobStatusQueued JobStatus = "queued"
+
+	// JobStatusRunning jobs are being run by a worker
+	JobStatusRunning JobStatus = "running"
+
+	// JobStatusSucceeded jobs finished and hold their result until they expire
+	JobStatusSucceeded JobStatus = "succeeded"
+
+	// JobStatusFailed jobs exhausted their attempts or failed permanently
+	JobStatusFailed JobStatus = "failed"
+
+	// JobStatusCancelled jobs were cancelled before they finished
+	JobStatusCancelled JobStatus = "cancelled"
+)
+
+// Finished reports whether a job in the status will not run again
+func (status JobStatus) Finished() bool {
+	return status == JobStatusSucceeded || status == JobStatusFailed || status == JobStatusCancelled
+}
+
+// Job is a unit of background work run by the job queue, with its progress and outcome
+// This model maps to the jobs table
+type Job struct {
+	ID string `json:"id"`
+
+	// Kind selects the handler that runs the job, e.g. async-request
+	Kind string `json:"kind"`
+
+	// Payload is the kind's input; it may hold PHI, so it is never listed
+	Payload []byte `json:"-"`
+
+	Status JobStatus `json:"status"`
+
+	// Progress is the handler's latest description of how far the job has got, e.g. "3 of 10 files"
+	Progress string `json:"progress,omitempty"`
+
+	// Attempts counts the times a worker started the job, including attempts cut short by a stopped worker
+	Attempts    int    `json:"attempts"`
+	MaxAttempts int    `json:"max_attempts"`
+	LastError   string `json:"last_error,omitempty"`
+
+	// Result is the kind's output once the job succeeded; like the payload it is never listed
+	Result []byte `json:"-"`
+
+	// The caller who queued the job, so kinds that serve results back can check who asks for them
+	PrincipalID string `json:"principal_id"`
+	TenantID    string `json:"tenant_id"`
+
+	// RunAfter is when a queued job may next be started; retries are pushed back by the backoff
+	RunAfter   time.Time  `json:"run_after"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// ExpiresAt is when a finished job is deleted
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// JobFilter narrows a job listing; empty fields match every job
+type JobFilter struct {
+	Status JobStatus
+	Kind   string
+}
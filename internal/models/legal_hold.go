@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+)
+
+// LegalHoldAction is the kind of entry in the legal hold audit history
+type LegalHoldAction string
+
+const (
+	// LegalHoldActionPlaced records a hold being placed or its reason changed
+	LegalHoldActionPlaced LegalHoldAction = "placed"
+
+	// LegalHoldActionReleased records a hold being lifted
+	LegalHoldActionReleased LegalHoldAction = "released"
+
+	// LegalHoldActionDeletionBlocked records a deletion refused because of a hold
+	LegalHoldActionDeletionBlocked LegalHoldAction = "deletion_blocked"
+)
+
+// LegalHold is an active hold preventing deletion of a patient and its related resources
+// This model maps to the patient_legal_holds table
+type LegalHold struct {
+	PatientID string    `json:"patient_id"`
+	Reason    string    `json:"reason"`
+	PlacedBy  string    `json:"placed_by"`
+	PlacedAt  time.Time `json:"placed_at"`
+}
+
+// LegalHoldEvent is one entry in a patient's legal hold audit history
+// This model maps to the legal_hold_audit table
+type LegalHoldEvent struct {
+	ID        int64           `json:"id"`
+	PatientID string          `json:"patient_id"`
+	Action    LegalHoldAction `json:"action"`
+
+	// Resource the action applied to: the patient, or a related resource whose deletion was blocked
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+
+	Actor      string    `json:"actor"`
+	Reason     string    `json:"reason,omitempty"`
+	OccurredAt time.Time `json:"occurred_at"`
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// PatientMappingHook lets a deployment adjust Patient conversions without forking PatientMapper
+// Pre-hooks see the input before the built-in mapping; post-hooks see both input and result
+type PatientMappingHook interface {
+	// PreFromFHIR may normalize an incoming FHIR Patient (e.g. rewrite identifier systems)
+	PreFromFHIR(fhirPatient *fhir.Patient)
+
+	// PostFromFHIR may adjust the domain Patient produced from the FHIR resource
+	PostFromFHIR(fhirPatient *fhir.Patient, patient *Patient)
+
+	// PreToFHIR may adjust the domain Patient before it is converted
+	PreToFHIR(patient *Patient)
+
+	// PostToFHIR may add to or adjust the outgoing FHIR Patient
+	PostToFHIR(patient *Patient, fhirPatient *fhir.Patient)
+}
+
+// ObservationMappingHook lets a deployment adjust Observation conversions without forking ObservationMapper
+type ObservationMappingHook interface {
+	// PreFromFHIR may normalize an incoming FHIR Observation (e.g. translate local codes)
+	PreFromFHIR(fhirObservation *fhir.Observation)
+
+	// PostFromFHIR may adjust the domain Observation produced from the FHIR resource
+	PostFromFHIR(fhirObservation *fhir.Observation, observation *Observation)
+
+	// PreToFHIR may adjust the domain Observation before it is converted
+	PreToFHIR(observation *Observation)
+
+	// PostToFHIR may add to or adjust the outgoing FHIR Observation
+	PostToFHIR(observation *Observation, fhirObservation *fhir.Observation)
+}
+
+// BasePatientMappingHook implements PatientMappingHook with no-ops
+// Embed it to implement only the hooks a site needs
+type BasePatientMappingHook struct{}
+
+// PreFromFHIR does nothing
+func (BasePatientMappingHook) PreFromFHIR(fhirPatient *fhir.Patient) {}
+
+// PostFromFHIR does nothing
+func (BasePatientMappingHook) PostFromFHIR(fhirPatient *fhir.Patient, patient *Patient) {}
+
+// PreToFHIR does nothing
+func (BasePatientMappingHook) PreToFHIR(patient *Patient) {}
+
+// PostToFHIR does nothing
+func (BasePatientMappingHook) PostToFHIR(patient *Patient, fhirPatient *fhir.Patient) {}
+
+// BaseObservationMappingHook implements ObservationMappingHook with no-ops
+// Embed it to implement only the hooks a site needs
+type BaseObservationMappingHook struct{}
+
+// PreFromFHIR does nothing
+func (BaseObservationMappingHook) PreFromFHIR(fhirObservation *fhir.Observation) {}
+
+// PostFromFHIR does nothing
+func (BaseObservationMappingHook) PostFromFHIR(fhirObservation *fhir.Observation, observation *Observation) {
+}
+
+// PreToFHIR does nothing
+func (BaseObservationMappingHook) PreToFHIR(observation *Observation) {}
+
+// PostToFHIR does nothing
+func (BaseObservationMappingHook) PostToFHIR(observation *Observation, fhirObservation *fhir.Observation) {
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// recordingPatientHook records hook calls and tags the converted resources
+type recordingPatientHook struct {
+	BasePatientMappingHook
+	name  string
+	calls *[]string
+}
+
+// PreFromFHIR records the call and rewrites the family name
+func (hook recordingPatientHook) PreFromFHIR(fhirPatient *fhir.Patient) {
+	*hook.calls = append(*hook.calls, hook.name+":pre-from")
+	family := "Hooked"
+	fhirPatient.Name[0].Family = &family
+}
+
+// PostFromFHIR records the call
+func (hook recordingPatientHook) PostFromFHIR(fhirPatient *fhir.Patient, patient *Patient) {
+	*hook.calls = append(*hook.calls, hook.name+":post-from")
+}
+
+// PostToFHIR records the call and adds a language
+func (hook recordingPatientHook) PostToFHIR(patient *Patient, fhirPatient *fhir.Patient) {
+	*hook.calls = append(*hook.calls, hook.name+":post-to")
+	language := "en"
+	fhirPatient.Language = &language
+}
+
+// TestPatientMapper_HooksRunInOrder verifies pre and post hooks wrap the built-in mapping
+func TestPatientMapper_HooksRunInOrder(t *testing.T) {
+	var calls []string
+	mapper := NewPatientMapper()
+	mapper.AddHook(recordingPatientHook{name: "first", calls: &calls})
+	mapper.AddHook(recordingPatientHook{name: "second", calls: &calls})
+
+	family := "Smith"
+	patient, _ := mapper.FromFHIR(&fhir.Patient{Name: []fhir.HumanName{{Family: &family}}})
+	if patient.FamilyName != "Hooked" {
+		t.Errorf("Expected pre-hook to affect mapping, got %s", patient.FamilyName)
+	}
+
+	fhirPatient := mapper.ToFHIR(patient)
+	if fhirPatient.Language == nil || *fhirPatient.Language != "en" {
+		t.Error("Expected post-hook to modify the FHIR resource")
+	}
+
+	expectedCalls := []string{"first:pre-from", "second:pre-from", "first:post-from", "second:post-from", "first:post-to", "second:post-to"}
+	if len(calls) != len(expectedCalls) {
+		t.Fatalf("Expected %v, got %v", expectedCalls, calls)
+	}
+	for index := range expectedCalls {
+		if calls[index] != expectedCalls[index] {
+			t.Errorf("Expected call %d to be %s, got %s", index, expectedCalls[index], calls[index])
+		}
+	}
+}
+
+// unitObservationHook converts stored units on the way out
+type unitObservationHook struct {
+	BaseObservationMappingHook
+}
+
+// PreToFHIR rewrites a legacy unit
+func (unitObservationHook) PreToFHIR(observation *Observation) {
+	if observation.ValueUnit == "bpm" {
+		observation.ValueUnit = "beats/minute"
+	}
+}
+
+// TestObservationMapper_Hook verifies observation hooks are applied
+func TestObservationMapper_Hook(t *testing.T) {
+	mapper := NewObservationMapper()
+	mapper.AddHook(unitObservationHook{})
+
+	heartRate := 72.0
+	fhirObservation := mapper.ToFHIR(&Observation{Code: "8867-4", ValueQuantity: &heartRate, ValueUnit: "bpm"})
+
+	if fhirObservation.ValueQuantity == nil || fhirObservation.ValueQuantity.Unit == nil || *fhirObservation.ValueQuantity.Unit != "beats/minute" {
+		t.Errorf("Expected hook to rewrite the unit, got %+v", fhirObservation.ValueQuantity)
+	}
+}
@@ -0,0 +1,17 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// droppedElementIssue reports an element whose value could not be mapped and was left out of the domain model
+func droppedElementIssue(expression string, value string, expected string) outcome.Issue {
+	return outcome.Warning(
+		fhir.IssueTypeValue,
+		fmt.Sprintf("%s value %q is not %s and was not stored", expression, value, expected),
+		expression,
+	)
+}
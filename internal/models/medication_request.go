@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+)
+
+// MedicationRequest represents a prescription in the database
+// This model maps to the medication_requests table and can be converted to FHIR format
+type MedicationRequest struct {
+	// Unique identifier for the medication request (UUID)
+	ID string `json:"id"`
+
+	// Request status code (active, on-hold, cancelled, completed, ...)
+	Status string `json:"status"`
+
+	// Request intent code (proposal, plan, order, ...)
+	Intent string `json:"intent"`
+
+	// Coding of the requested medication (e.g., RxNorm)
+	MedicationSystem  string `json:"medication_system"`
+	MedicationCode    string `json:"medication_code"`
+	MedicationDisplay string `json:"medication_display"`
+
+	// Subject patient ID; empty when the request names no patient
+	PatientID string `json:"patient_id"`
+
+	// Encounter the request was written in; empty when none was given
+	EncounterID string `json:"encounter_id"`
+
+	// Practitioner who wrote the request; empty when none was given
+	RequesterPractitionerID string `json:"requester_practitioner_id"`
+
+	// When the request was written
+	AuthoredOn *time.Time `json:"authored_on"`
+
+	// Free-text dosage instructions
+	DosageInstructionText string `json:"dosage_instruction_text"`
+
+	// Audit timestamps
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
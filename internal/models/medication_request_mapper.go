@@ -0,0 +1,157 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// medicationRequestStatuses are the codes of the FHIR medicationrequest-status value set
+var medicationRequestStatuses = map[string]bool{
+	"active": true, "on-hold": true, "cancelled": true, "completed": true,
+	"entered-in-error": true, "stopped": true, "draft": true, "unknown": true,
+}
+
+// medicationRequestIntents are the codes of the FHIR medicationrequest-intent value set
+var medicationRequestIntents = map[string]bool{
+	"proposal": true, "plan": true, "order": true, "original-order": true,
+	"reflex-order": true, "filler-order": true, "instance-order": true, "option": true,
+}
+
+// MedicationRequestMapper handles conversion between domain MedicationRequest model and FHIR MedicationRequest resource
+type MedicationRequestMapper struct{}
+
+// NewMedicationRequestMapper creates a new instance of MedicationRequestMapper
+func NewMedicationRequestMapper() *MedicationRequestMapper {
+	return &MedicationRequestMapper{}
+}
+
+// MedicationRequestCodeIssues returns an error issue for a status or intent that is missing or outside its FHIR value set
+// Both elements are required, so a request with either issue cannot be stored
+func MedicationRequestCodeIssues(fhirMedicationRequest *fhir.MedicationRequest) []outcome.Issue {
+	var issues []outcome.Issue
+	if !medicationRequestStatuses[fhirMedicationRequest.Status] {
+		issues = append(issues, outcome.Error(fhir.IssueTypeCodeInvalid,
+			fmt.Sprintf("MedicationRequest.status value %q is not a code from the FHIR value set", fhirMedicationRequest.Status), "MedicationRequest.status"))
+	}
+	if !medicationRequestIntents[fhirMedicationRequest.Intent] {
+		issues = append(issues, outcome.Error(fhir.IssueTypeCodeInvalid,
+			fmt.Sprintf("MedicationRequest.intent value %q is not a code from the FHIR value set", fhirMedicationRequest.Intent), "MedicationRequest.intent"))
+	}
+	return issues
+}
+
+// ToFHIR converts a domain MedicationRequest to a FHIR MedicationRequest
+func (mapper *MedicationRequestMapper) ToFHIR(medicationRequest *MedicationRequest) *fhir.MedicationRequest {
+	fhirMedicationRequest := &fhir.MedicationRequest{
+		Id:     &medicationRequest.ID,
+		Status: medicationRequest.Status,
+		Intent: medicationRequest.Intent,
+	}
+
+	// Set medication coding
+	if medicationRequest.MedicationCode != "" {
+		coding := fhir.Coding{Code: &medicationRequest.MedicationCode}
+		if medicationRequest.MedicationSystem != "" {
+			coding.System = &medicationRequest.MedicationSystem
+		}
+		if medicationRequest.MedicationDisplay != "" {
+			coding.Display = &medicationRequest.MedicationDisplay
+		}
+		fhirMedicationRequest.MedicationCodeableConcept.Coding = []fhir.Coding{coding}
+	}
+
+	// Set subject (patient reference)
+	if medicationRequest.PatientID != "" {
+		patientReference := "Patient/" + medicationRequest.PatientID
+		fhirMedicationRequest.Subject.Reference = &patientReference
+	}
+
+	// Set encounter reference
+	if medicationRequest.EncounterID != "" {
+		encounterReference := "Encounter/" + medicationRequest.EncounterID
+		fhirMedicationRequest.Encounter = &fhir.Reference{Reference: &encounterReference}
+	}
+
+	// Set requester (practitioner reference)
+	if medicationRequest.RequesterPractitionerID != "" {
+		practitionerReference := "Practitioner/" + medicationRequest.RequesterPractitionerID
+		fhirMedicationRequest.Requester = &fhir.Reference{Reference: &practitionerReference}
+	}
+
+	// Set authored date
+	if medicationRequest.AuthoredOn != nil {
+		authoredOn := medicationRequest.AuthoredOn.UTC().Format(time.RFC3339)
+		fhirMedicationRequest.AuthoredOn = &authoredOn
+	}
+
+	// Set dosage instructions
+	if medicationRequest.DosageInstructionText != "" {
+		fhirMedicationRequest.DosageInstruction = []fhir.Dosage{{Text: &medicationRequest.DosageInstructionText}}
+	}
+
+	return fhirMedicationRequest
+}
+
+// FromFHIR converts a FHIR MedicationRequest to a domain MedicationRequest
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *MedicationRequestMapper) FromFHIR(fhirMedicationRequest *fhir.MedicationRequest) (*MedicationRequest, []outcome.Issue) {
+	medicationRequest := &MedicationRequest{
+		Status: fhirMedicationRequest.Status,
+		Intent: fhirMedicationRequest.Intent,
+	}
+	var issues []outcome.Issue
+
+	// Map ID
+	if fhirMedicationRequest.Id != nil {
+		medicationRequest.ID = *fhirMedicationRequest.Id
+	}
+
+	// Map medication from the first coding
+	if len(fhirMedicationRequest.MedicationCodeableConcept.Coding) > 0 {
+		coding := fhirMedicationRequest.MedicationCodeableConcept.Coding[0]
+		if coding.Code != nil {
+			medicationRequest.MedicationCode = *coding.Code
+		}
+		if coding.System != nil {
+			medicationRequest.MedicationSystem = *coding.System
+		}
+		if coding.Display != nil {
+			medicationRequest.MedicationDisplay = *coding.Display
+		}
+	}
+
+	// Map patient ID from subject reference
+	if fhirMedicationRequest.Subject.Reference != nil {
+		if patientID, isPatient := strings.CutPrefix(*fhirMedicationRequest.Subject.Reference, "Patient/"); isPatient && patientID != "" {
+			medicationRequest.PatientID = patientID
+		}
+	}
+
+	// Map encounter ID from encounter reference
+	if fhirMedicationRequest.Encounter != nil && fhirMedicationRequest.Encounter.Reference != nil {
+		if encounterID, isEncounter := strings.CutPrefix(*fhirMedicationRequest.Encounter.Reference, "Encounter/"); isEncounter && encounterID != "" {
+			medicationRequest.EncounterID = encounterID
+		}
+	}
+
+	// Map practitioner ID from requester reference; requesters of other types are not stored
+	if fhirMedicationRequest.Requester != nil && fhirMedicationRequest.Requester.Reference != nil {
+		if practitionerID, isPractitioner := strings.CutPrefix(*fhirMedicationRequest.Requester.Reference, "Practitioner/"); isPractitioner && practitionerID != "" {
+			medicationRequest.RequesterPractitionerID = practitionerID
+		}
+	}
+
+	// Map authored date
+	medicationRequest.AuthoredOn, issues = parseDateTimeElement(fhirMedicationRequest.AuthoredOn, "MedicationRequest.authoredOn", issues)
+
+	// Map the text of the first dosage instruction; structured timing and dose are not stored
+	if len(fhirMedicationRequest.DosageInstruction) > 0 && fhirMedicationRequest.DosageInstruction[0].Text != nil {
+		medicationRequest.DosageInstructionText = *fhirMedicationRequest.DosageInstruction[0].Text
+	}
+
+	return medicationRequest, issues
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestMedicationRequestMapper_RoundTrip verifies status, intent, medication, references, authored date, and dosage survive a round trip
+func TestMedicationRequestMapper_RoundTrip(t *testing.T) {
+	mapper := NewMedicationRequestMapper()
+	authoredOn := time.Date(2024, 3, 1, 9, 45, 0, 0, time.UTC)
+
+	fhirMedicationRequest := mapper.ToFHIR(&MedicationRequest{
+		ID:                      "request-1",
+		Status:                  "active",
+		Intent:                  "order",
+		MedicationSystem:        "http://www.nlm.nih.gov/research/umls/rxnorm",
+		MedicationCode:          "197361",
+		MedicationDisplay:       "Amlodipine 5 MG Oral Tablet",
+		PatientID:               "patient-1",
+		EncounterID:             "encounter-1",
+		RequesterPractitionerID: "practitioner-1",
+		AuthoredOn:              &authoredOn,
+		DosageInstructionText:   "1 tablet by mouth daily",
+	})
+
+	if fhirMedicationRequest.Status != "active" || fhirMedicationRequest.Intent != "order" {
+		t.Errorf("Expected an active order, got %s %s", fhirMedicationRequest.Status, fhirMedicationRequest.Intent)
+	}
+	if *fhirMedicationRequest.MedicationCodeableConcept.Coding[0].Code != "197361" || *fhirMedicationRequest.Subject.Reference != "Patient/patient-1" {
+		t.Errorf("Expected the RxNorm code for Patient/patient-1, got %+v", fhirMedicationRequest)
+	}
+	if *fhirMedicationRequest.Encounter.Reference != "Encounter/encounter-1" || *fhirMedicationRequest.Requester.Reference != "Practitioner/practitioner-1" {
+		t.Errorf("Expected the encounter and requester references, got %+v %+v", fhirMedicationRequest.Encounter, fhirMedicationRequest.Requester)
+	}
+	if *fhirMedicationRequest.AuthoredOn != "2024-03-01T09:45:00Z" || *fhirMedicationRequest.DosageInstruction[0].Text != "1 tablet by mouth daily" {
+		t.Errorf("Expected the authored date and dosage text, got %+v", fhirMedicationRequest)
+	}
+
+	medicationRequest, issues := mapper.FromFHIR(fhirMedicationRequest)
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if medicationRequest.MedicationSystem != "http://www.nlm.nih.gov/research/umls/rxnorm" || medicationRequest.PatientID != "patient-1" ||
+		medicationRequest.EncounterID != "encounter-1" || medicationRequest.RequesterPractitionerID != "practitioner-1" {
+		t.Errorf("Expected the stored fields back, got %+v", medicationRequest)
+	}
+	if !medicationRequest.AuthoredOn.Equal(authoredOn) || medicationRequest.DosageInstructionText != "1 tablet by mouth daily" {
+		t.Errorf("Expected the authored date and dosage back, got %+v", medicationRequest)
+	}
+}
+
+// TestMedicationRequestMapper_FromFHIR_DropsUnmappedValues verifies unparseable dates and non-practitioner requesters are not stored
+func TestMedicationRequestMapper_FromFHIR_DropsUnmappedValues(t *testing.T) {
+	authoredOn := "yesterday"
+	organizationReference := "Organization/organization-1"
+
+	medicationRequest, issues := NewMedicationRequestMapper().FromFHIR(&fhir.MedicationRequest{
+		Status:     "draft",
+		Intent:     "proposal",
+		AuthoredOn: &authoredOn,
+		Requester:  &fhir.Reference{Reference: &organizationReference},
+	})
+
+	if medicationRequest.AuthoredOn != nil || medicationRequest.RequesterPractitionerID != "" {
+		t.Errorf("Expected no authored date or requester, got %+v", medicationRequest)
+	}
+	if len(issues) != 1 || issues[0].Expression[0] != "MedicationRequest.authoredOn" {
+		t.Errorf("Expected one issue for MedicationRequest.authoredOn, got %+v", issues)
+	}
+}
+
+// TestMedicationRequestCodeIssues verifies missing or unknown statuses and intents are errors
+func TestMedicationRequestCodeIssues(t *testing.T) {
+	if issues := MedicationRequestCodeIssues(&fhir.MedicationRequest{Status: "active", Intent: "order"}); len(issues) != 0 {
+		t.Errorf("Expected no issues for an active order, got %+v", issues)
+	}
+
+	issues := MedicationRequestCodeIssues(&fhir.MedicationRequest{Status: "pending"})
+	if len(issues) != 2 || issues[0].Expression[0] != "MedicationRequest.status" || issues[1].Expression[0] != "MedicationRequest.intent" {
+		t.Fatalf("Expected issues for the unknown status and missing intent, got %+v", issues)
+	}
+	if issues[0].Severity != fhir.IssueSeverityError {
+		t.Errorf("Expected error severity, got %s", issues[0].Severity.Code())
+	}
+}
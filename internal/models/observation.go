@@ -7,30 +7,66 @@ import (
 // Observation represents a clinical observation (vitals, lab results, etc.)
 // Stored in MongoDB due to variable structure
 type Observation struct {
-	ID             string                 `bson:"_id,omitempty"`
-	PatientID      string                 `bson:"patient_id"`
-	Status         string                 `bson:"status"`
-	Category       string                 `bson:"category"`
-	Code           string                 `bson:"code"`
-	CodeSystem     string                 `bson:"code_system"`
-	CodeDisplay    string                 `bson:"code_display"`
-	ValueQuantity  *float64               `bson:"value_quantity,omitempty"`
-	ValueUnit      string                 `bson:"value_unit,omitempty"`
-	ValueString    string                 `bson:"value_string,omitempty"`
-	EffectiveDate  *time.Time             `bson:"effective_date,omitempty"`
-	IssuedDate     time.Time              `bson:"issued_date"`
-	Components     []ObservationComponent `bson:"components,omitempty"`
-	CreatedAt      time.Time              `bson:"created_at"`
-	UpdatedAt      time.Time              `bson:"updated_at"`
+	ID              string                 `bson:"_id,omitempty"`
+	PatientID       string                 `bson:"patient_id"`
+	PerformerID     string                 `bson:"performer_id,omitempty"`
+	EncounterID     string                 `bson:"encounter_id,omitempty"`
+	Status          string                 `bson:"status"`
+	Category        string                 `bson:"category"`
+	CategoryDisplay string                 `bson:"category_display,omitempty"`
+	Code            string                 `bson:"code"`
+	CodeSystem      string                 `bson:"code_system"`
+	CodeDisplay     string                 `bson:"code_display"`
+	ValueQuantity   *float64               `bson:"value_quantity,omitempty"`
+	ValueUnit       string                 `bson:"value_unit,omitempty"`
+	CanonicalValue  *float64               `bson:"canonical_value,omitempty"`
+	CanonicalUnit   string                 `bson:"canonical_unit,omitempty"`
+	ValueString     string                 `bson:"value_string,omitempty"`
+	EffectiveDate   *time.Time             `bson:"effective_date,omitempty"`
+	IssuedDate      time.Time              `bson:"issued_date"`
+	Components      []ObservationComponent `bson:"components,omitempty"`
+	ReferenceRanges []ReferenceRange       `bson:"reference_ranges,omitempty"`
+	Interpretation  *Interpretation        `bson:"interpretation,omitempty"`
+	Tags            Tags                   `bson:"tags,omitempty"`
+	Version         int                    `bson:"version,omitempty"`
+	CreatedAt       time.Time              `bson:"created_at"`
+	UpdatedAt       time.Time              `bson:"updated_at"`
+	DeletedAt       *time.Time             `bson:"deleted_at,omitempty"`
+}
+
+// ReferenceRange is a range of normal values for the observation's value, such as one reported by the lab
+// Either bound may be missing, as in "< 200 mg/dL"; Text describes ranges that are not numeric
+type ReferenceRange struct {
+	Low  *float64 `bson:"low,omitempty"`
+	High *float64 `bson:"high,omitempty"`
+	Unit string   `bson:"unit,omitempty"`
+	Text string   `bson:"text,omitempty"`
+}
+
+// Interpretation is a coded assessment of an observation's value (e.g. high, low, questionable)
+type Interpretation struct {
+	System  string `bson:"system,omitempty"`
+	Code    string `bson:"code"`
+	Display string `bson:"display,omitempty"`
+}
+
+// CurrentVersion returns the observation's version, counting observations stored before versioning as version 1
+func (observation *Observation) CurrentVersion() int {
+	if observation.Version < 1 {
+		return 1
+	}
+	return observation.Version
 }
 
 // ObservationComponent represents a component of a complex observation
 // Example: Blood pressure has systolic and diastolic components
 type ObservationComponent struct {
-	Code          string   `bson:"code"`
-	CodeSystem    string   `bson:"code_system"`
-	CodeDisplay   string   `bson:"code_display"`
-	ValueQuantity *float64 `bson:"value_quantity,omitempty"`
-	ValueUnit     string   `bson:"value_unit,omitempty"`
-	ValueString   string   `bson:"value_string,omitempty"`
+	Code           string   `bson:"code"`
+	CodeSystem     string   `bson:"code_system"`
+	CodeDisplay    string   `bson:"code_display"`
+	ValueQuantity  *float64 `bson:"value_quantity,omitempty"`
+	ValueUnit      string   `bson:"value_unit,omitempty"`
+	CanonicalValue *float64 `bson:"canonical_value,omitempty"`
+	CanonicalUnit  string   `bson:"canonical_unit,omitempty"`
+	ValueString    string   `bson:"value_string,omitempty"`
 }
@@ -2,22 +2,65 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
 // ObservationMapper converts between domain model and FHIR Observation
-type ObservationMapper struct{}
+type ObservationMapper struct {
+	// Site-specific hooks run around every conversion, in registration order
+	hooks []ObservationMappingHook
+}
 
 // NewObservationMapper creates a new observation mapper instance
 func NewObservationMapper() *ObservationMapper {
 	return &ObservationMapper{}
 }
 
-// ToFHIR converts domain Observation to FHIR Observation
+// AddHook registers a site-specific hook; hooks run in the order they were added
+func (mapper *ObservationMapper) AddHook(hook ObservationMappingHook) {
+	mapper.hooks = append(mapper.hooks, hook)
+}
+
+// ToFHIR converts a domain Observation to a FHIR Observation, running registered hooks around the conversion
 func (mapper *ObservationMapper) ToFHIR(observation *Observation) *fhir.Observation {
+	for _, hook := range mapper.hooks {
+		hook.PreToFHIR(observation)
+	}
+
+	fhirObservation := mapper.toFHIR(observation)
+
+	for _, hook := range mapper.hooks {
+		hook.PostToFHIR(observation, fhirObservation)
+	}
+
+	return fhirObservation
+}
+
+// FromFHIR converts a FHIR Observation to a domain Observation, running registered hooks around the conversion
+// Pre-hooks may modify the incoming resource before it is read
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) (*Observation, []outcome.Issue) {
+	for _, hook := range mapper.hooks {
+		hook.PreFromFHIR(fhirObservation)
+	}
+
+	observation, issues := mapper.fromFHIR(fhirObservation)
+
+	for _, hook := range mapper.hooks {
+		hook.PostFromFHIR(fhirObservation, observation)
+	}
+
+	return observation, issues
+}
+
+// toFHIR performs the built-in conversion of a domain Observation to a FHIR Observation
+func (mapper *ObservationMapper) toFHIR(observation *Observation) *fhir.Observation {
 	fhirObservation := &fhir.Observation{}
 
 	// Set ID
@@ -40,14 +83,18 @@ func (mapper *ObservationMapper) ToFHIR(observation *Observation) *fhir.Observat
 		fhirObservation.Status = status
 	}
 
-	// Set category
+	// Set category, showing the code itself when no display was stored
 	if observation.Category != "" {
+		categoryDisplay := observation.Category
+		if observation.CategoryDisplay != "" {
+			categoryDisplay = observation.CategoryDisplay
+		}
 		fhirObservation.Category = []fhir.CodeableConcept{
 			{
 				Coding: []fhir.Coding{
 					{
 						Code:    &observation.Category,
-						Display: &observation.Category,
+						Display: &categoryDisplay,
 					},
 				},
 			},
@@ -73,6 +120,18 @@ func (mapper *ObservationMapper) ToFHIR(observation *Observation) *fhir.Observat
 		}
 	}
 
+	// Set performer (practitioner reference)
+	if observation.PerformerID != "" {
+		performerReference := "Practitioner/" + observation.PerformerID
+		fhirObservation.Performer = []fhir.Reference{{Reference: &performerReference}}
+	}
+
+	// Set encounter reference
+	if observation.EncounterID != "" {
+		encounterReference := "Encounter/" + observation.EncounterID
+		fhirObservation.Encounter = &fhir.Reference{Reference: &encounterReference}
+	}
+
 	// Set effective date
 	if observation.EffectiveDate != nil {
 		effectiveDateString := observation.EffectiveDate.Format("2006-01-02T15:04:05Z")
@@ -125,11 +184,80 @@ func (mapper *ObservationMapper) ToFHIR(observation *Observation) *fhir.Observat
 		fhirObservation.Component = fhirComponents
 	}
 
+	// Set reference ranges
+	for _, referenceRange := range observation.ReferenceRanges {
+		fhirRange := fhir.ObservationReferenceRange{}
+		if referenceRange.Low != nil {
+			fhirRange.Low = referenceRangeQuantity(*referenceRange.Low, referenceRange.Unit)
+		}
+		if referenceRange.High != nil {
+			fhirRange.High = referenceRangeQuantity(*referenceRange.High, referenceRange.Unit)
+		}
+		if referenceRange.Text != "" {
+			text := referenceRange.Text
+			fhirRange.Text = &text
+		}
+		fhirObservation.ReferenceRange = append(fhirObservation.ReferenceRange, fhirRange)
+	}
+
+	// Set interpretation
+	if observation.Interpretation != nil {
+		fhirObservation.Interpretation = []fhir.CodeableConcept{
+			{
+				Coding: []fhir.Coding{
+					{
+						System:  &observation.Interpretation.System,
+						Code:    &observation.Interpretation.Code,
+						Display: &observation.Interpretation.Display,
+					},
+				},
+			},
+		}
+	}
+
+	// Set the version and workflow tags
+	versionID := strconv.Itoa(observation.CurrentVersion())
+	fhirObservation.Meta = &fhir.Meta{VersionId: &versionID}
+	if len(observation.Tags) > 0 {
+		fhirObservation.Meta.Tag = TagsToFHIR(observation.Tags)
+	}
+
 	return fhirObservation
 }
 
-// FromFHIR converts FHIR Observation to domain Observation
-func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Observation {
+// referenceRangeQuantity builds the FHIR quantity of a reference range bound
+func referenceRangeQuantity(value float64, unit string) *fhir.Quantity {
+	valueNumber := json.Number(strconv.FormatFloat(value, 'f', -1, 64))
+	quantity := &fhir.Quantity{Value: &valueNumber}
+	if unit != "" {
+		quantity.Unit = &unit
+	}
+	return quantity
+}
+
+// referenceRangeBound reads the value and unit of one reference range bound; a value that is not a number is
+// dropped and reported in issues
+func referenceRangeBound(quantity *fhir.Quantity, expression string, issues []outcome.Issue) (*float64, string, []outcome.Issue) {
+	if quantity == nil || quantity.Value == nil {
+		return nil, "", issues
+	}
+	boundValue, parseError := quantity.Value.Float64()
+	if parseError != nil {
+		return nil, "", append(issues, droppedElementIssue(expression, quantity.Value.String(), "a decimal number"))
+	}
+	if quantity.Unit != nil {
+		return &boundValue, *quantity.Unit, issues
+	}
+	if quantity.Code != nil {
+		return &boundValue, *quantity.Code, issues
+	}
+	return &boundValue, "", issues
+}
+
+// fromFHIR performs the built-in conversion of a FHIR Observation to a domain Observation
+func (mapper *ObservationMapper) fromFHIR(fhirObservation *fhir.Observation) (*Observation, []outcome.Issue) {
+	var issues []outcome.Issue
+
 	// Map FHIR status to string
 	statusString := "final"
 	switch fhirObservation.Status {
@@ -160,6 +288,9 @@ func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Ob
 		if fhirObservation.Category[0].Coding[0].Code != nil {
 			observation.Category = *fhirObservation.Category[0].Coding[0].Code
 		}
+		if fhirObservation.Category[0].Coding[0].Display != nil {
+			observation.CategoryDisplay = *fhirObservation.Category[0].Coding[0].Display
+		}
 	}
 
 	// Extract code
@@ -185,11 +316,27 @@ func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Ob
 		}
 	}
 
+	// Extract practitioner ID from the first performer; other performer types are not stored
+	if len(fhirObservation.Performer) > 0 && fhirObservation.Performer[0].Reference != nil {
+		if practitionerID, isPractitioner := strings.CutPrefix(*fhirObservation.Performer[0].Reference, "Practitioner/"); isPractitioner && practitionerID != "" {
+			observation.PerformerID = practitionerID
+		}
+	}
+
+	// Extract encounter ID from the encounter reference
+	if fhirObservation.Encounter != nil && fhirObservation.Encounter.Reference != nil {
+		if encounterID, isEncounter := strings.CutPrefix(*fhirObservation.Encounter.Reference, "Encounter/"); isEncounter && encounterID != "" {
+			observation.EncounterID = encounterID
+		}
+	}
+
 	// Extract effective date
 	if fhirObservation.EffectiveDateTime != nil {
 		parsedTime, parseError := time.Parse("2006-01-02T15:04:05Z", *fhirObservation.EffectiveDateTime)
 		if parseError == nil {
 			observation.EffectiveDate = &parsedTime
+		} else {
+			issues = append(issues, droppedElementIssue("Observation.effectiveDateTime", *fhirObservation.EffectiveDateTime, "a UTC date-time (YYYY-MM-DDThh:mm:ssZ)"))
 		}
 	}
 
@@ -198,6 +345,8 @@ func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Ob
 		parsedTime, parseError := time.Parse(time.RFC3339, *fhirObservation.Issued)
 		if parseError == nil {
 			observation.IssuedDate = parsedTime
+		} else {
+			issues = append(issues, droppedElementIssue("Observation.issued", *fhirObservation.Issued, "an RFC 3339 instant"))
 		}
 	}
 
@@ -206,9 +355,14 @@ func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Ob
 		valueFloat, parseError := fhirObservation.ValueQuantity.Value.Float64()
 		if parseError == nil {
 			observation.ValueQuantity = &valueFloat
+		} else {
+			issues = append(issues, droppedElementIssue("Observation.valueQuantity.value", fhirObservation.ValueQuantity.Value.String(), "a decimal number"))
 		}
+		// The coded unit stands in for a missing human-readable one
 		if fhirObservation.ValueQuantity.Unit != nil {
 			observation.ValueUnit = *fhirObservation.ValueQuantity.Unit
+		} else if fhirObservation.ValueQuantity.Code != nil {
+			observation.ValueUnit = *fhirObservation.ValueQuantity.Code
 		}
 	} else if fhirObservation.ValueString != nil {
 		observation.ValueString = *fhirObservation.ValueString
@@ -217,7 +371,7 @@ func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Ob
 	// Extract components
 	if len(fhirObservation.Component) > 0 {
 		components := make([]ObservationComponent, 0, len(fhirObservation.Component))
-		for _, fhirComponent := range fhirObservation.Component {
+		for componentIndex, fhirComponent := range fhirObservation.Component {
 			component := ObservationComponent{}
 
 			if len(fhirComponent.Code.Coding) > 0 {
@@ -237,9 +391,14 @@ func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Ob
 				componentValueFloat, parseError := fhirComponent.ValueQuantity.Value.Float64()
 				if parseError == nil {
 					component.ValueQuantity = &componentValueFloat
+				} else {
+					expression := fmt.Sprintf("Observation.component[%d].valueQuantity.value", componentIndex)
+					issues = append(issues, droppedElementIssue(expression, fhirComponent.ValueQuantity.Value.String(), "a decimal number"))
 				}
 				if fhirComponent.ValueQuantity.Unit != nil {
 					component.ValueUnit = *fhirComponent.ValueQuantity.Unit
+				} else if fhirComponent.ValueQuantity.Code != nil {
+					component.ValueUnit = *fhirComponent.ValueQuantity.Code
 				}
 			} else if fhirComponent.ValueString != nil {
 				component.ValueString = *fhirComponent.ValueString
@@ -250,5 +409,45 @@ func (mapper *ObservationMapper) FromFHIR(fhirObservation *fhir.Observation) *Ob
 		observation.Components = components
 	}
 
-	return observation
+	// Extract reference ranges
+	for rangeIndex, fhirRange := range fhirObservation.ReferenceRange {
+		referenceRange := ReferenceRange{}
+		var lowUnit, highUnit string
+		referenceRange.Low, lowUnit, issues = referenceRangeBound(fhirRange.Low, fmt.Sprintf("Observation.referenceRange[%d].low.value", rangeIndex), issues)
+		referenceRange.High, highUnit, issues = referenceRangeBound(fhirRange.High, fmt.Sprintf("Observation.referenceRange[%d].high.value", rangeIndex), issues)
+		// Both bounds are expected in one unit; the low bound's is kept
+		referenceRange.Unit = lowUnit
+		if referenceRange.Unit == "" {
+			referenceRange.Unit = highUnit
+		}
+		if fhirRange.Text != nil {
+			referenceRange.Text = *fhirRange.Text
+		}
+		if referenceRange.Low != nil || referenceRange.High != nil || referenceRange.Text != "" {
+			observation.ReferenceRanges = append(observation.ReferenceRanges, referenceRange)
+		}
+	}
+
+	// Extract interpretation
+	if len(fhirObservation.Interpretation) > 0 && len(fhirObservation.Interpretation[0].Coding) > 0 {
+		coding := fhirObservation.Interpretation[0].Coding[0]
+		if coding.Code != nil {
+			observation.Interpretation = &Interpretation{Code: *coding.Code}
+			if coding.System != nil {
+				observation.Interpretation.System = *coding.System
+			}
+			if coding.Display != nil {
+				observation.Interpretation.Display = *coding.Display
+			}
+		}
+	}
+
+	// Extract workflow tags
+	if fhirObservation.Meta != nil {
+		tags, tagIssues := TagsFromFHIR(fhirObservation.Meta.Tag, "Observation.meta.tag")
+		observation.Tags = tags
+		issues = append(issues, tagIssues...)
+	}
+
+	return observation, issues
 }
@@ -177,7 +177,7 @@ func TestObservationMapper_FromFHIR(t *testing.T) {
 		},
 	}
 
-	observation := mapper.FromFHIR(fhirObservation)
+	observation, _ := mapper.FromFHIR(fhirObservation)
 
 	if observation.ID != "fhir-id-123" {
 		t.Errorf("Expected ID fhir-id-123, got %s", observation.ID)
@@ -234,7 +234,7 @@ func TestObservationMapper_FromFHIR_WithComponents(t *testing.T) {
 		},
 	}
 
-	observation := mapper.FromFHIR(fhirObservation)
+	observation, _ := mapper.FromFHIR(fhirObservation)
 
 	if len(observation.Components) != 2 {
 		t.Errorf("Expected 2 components, got %d", len(observation.Components))
@@ -258,13 +258,71 @@ func TestObservationMapper_FromFHIR_WithValueString(t *testing.T) {
 		ValueString: &valueString,
 	}
 
-	observation := mapper.FromFHIR(fhirObservation)
+	observation, _ := mapper.FromFHIR(fhirObservation)
 
 	if observation.ValueString != "Normal" {
 		t.Errorf("Expected value string Normal, got %s", observation.ValueString)
 	}
 }
 
+// TestObservationMapper_FromFHIR_UnitCode verifies the coded unit is kept when the quantity has no unit text
+func TestObservationMapper_FromFHIR_UnitCode(t *testing.T) {
+	mapper := NewObservationMapper()
+
+	code := "2339-0"
+	value := json.Number("99")
+	unitSystem := "http://unitsofmeasure.org"
+	unitCode := "mg/dL"
+	fhirObservation := &fhir.Observation{
+		Status: fhir.ObservationStatusFinal,
+		Code: fhir.CodeableConcept{
+			Coding: []fhir.Coding{{Code: &code}},
+		},
+		ValueQuantity: &fhir.Quantity{Value: &value, System: &unitSystem, Code: &unitCode},
+	}
+
+	observation, _ := mapper.FromFHIR(fhirObservation)
+
+	if observation.ValueUnit != "mg/dL" {
+		t.Errorf("Expected unit mg/dL from the unit code, got %s", observation.ValueUnit)
+	}
+}
+
+// TestObservationMapper_ReferenceRange verifies reference ranges survive the round trip and malformed bounds are reported
+func TestObservationMapper_ReferenceRange(t *testing.T) {
+	mapper := NewObservationMapper()
+
+	code := "2339-0"
+	low := json.Number("70")
+	high := json.Number("99")
+	badHigh := json.Number("high")
+	unit := "mg/dL"
+	text := "Fasting"
+	fhirObservation := &fhir.Observation{
+		Status: fhir.ObservationStatusFinal,
+		Code: fhir.CodeableConcept{
+			Coding: []fhir.Coding{{Code: &code}},
+		},
+		ReferenceRange: []fhir.ObservationReferenceRange{
+			{Low: &fhir.Quantity{Value: &low, Unit: &unit}, High: &fhir.Quantity{Value: &high, Unit: &unit}, Text: &text},
+			{High: &fhir.Quantity{Value: &badHigh}},
+		},
+	}
+
+	observation, issues := mapper.FromFHIR(fhirObservation)
+	if len(observation.ReferenceRanges) != 1 || *observation.ReferenceRanges[0].Low != 70 || *observation.ReferenceRanges[0].High != 99 || observation.ReferenceRanges[0].Unit != "mg/dL" {
+		t.Fatalf("Expected the numeric range stored, got %+v", observation.ReferenceRanges)
+	}
+	if len(issues) != 1 || !strings.Contains(issues[0].Diagnostics, "referenceRange[1].high") {
+		t.Errorf("Expected the malformed bound reported, got %+v", issues)
+	}
+
+	roundTrip := mapper.ToFHIR(observation).ReferenceRange
+	if len(roundTrip) != 1 || roundTrip[0].Low.Value.String() != "70" || *roundTrip[0].High.Unit != "mg/dL" || *roundTrip[0].Text != "Fasting" {
+		t.Errorf("Expected the range written back, got %+v", roundTrip)
+	}
+}
+
 // TestObservationMapper_FromFHIR_StatusMapping verifies status conversion
 func TestObservationMapper_FromFHIR_StatusMapping(t *testing.T) {
 	mapper := NewObservationMapper()
@@ -287,7 +345,7 @@ func TestObservationMapper_FromFHIR_StatusMapping(t *testing.T) {
 				Coding: []fhir.Coding{{Code: &code}},
 			},
 		}
-		observation := mapper.FromFHIR(fhirObservation)
+		observation, _ := mapper.FromFHIR(fhirObservation)
 		if observation.Status != tc.expected {
 			t.Errorf("Expected status %s, got %s", tc.expected, observation.Status)
 		}
@@ -317,7 +375,7 @@ func TestObservationMapper_FromFHIR_ComponentWithValueString(t *testing.T) {
 		},
 	}
 
-	observation := mapper.FromFHIR(fhirObservation)
+	observation, _ := mapper.FromFHIR(fhirObservation)
 
 	if len(observation.Components) != 1 {
 		t.Fatal("Expected 1 component")
@@ -327,6 +385,62 @@ func TestObservationMapper_FromFHIR_ComponentWithValueString(t *testing.T) {
 	}
 }
 
+// TestObservationMapper_CategoryDisplay verifies a category display survives a round trip and the code stands in without one
+func TestObservationMapper_CategoryDisplay(t *testing.T) {
+	mapper := NewObservationMapper()
+	categoryCode := "vital-signs"
+	categoryDisplay := "Vital Signs"
+
+	observation, _ := mapper.FromFHIR(&fhir.Observation{
+		Category: []fhir.CodeableConcept{{Coding: []fhir.Coding{{Code: &categoryCode, Display: &categoryDisplay}}}},
+	})
+	if observation.CategoryDisplay != categoryDisplay {
+		t.Fatalf("Expected category display %q, got %q", categoryDisplay, observation.CategoryDisplay)
+	}
+	if display := *mapper.ToFHIR(observation).Category[0].Coding[0].Display; display != categoryDisplay {
+		t.Errorf("Expected category display %q, got %q", categoryDisplay, display)
+	}
+
+	observation.CategoryDisplay = ""
+	if display := *mapper.ToFHIR(observation).Category[0].Coding[0].Display; display != categoryCode {
+		t.Errorf("Expected the category code as display, got %q", display)
+	}
+}
+
+// TestObservationMapper_Performer verifies a practitioner performer round-trips and other performers are not stored
+func TestObservationMapper_Performer(t *testing.T) {
+	mapper := NewObservationMapper()
+	practitionerReference := "Practitioner/practitioner-1"
+
+	observation, _ := mapper.FromFHIR(&fhir.Observation{Performer: []fhir.Reference{{Reference: &practitionerReference}}})
+	if observation.PerformerID != "practitioner-1" {
+		t.Fatalf("Expected performer practitioner-1, got %q", observation.PerformerID)
+	}
+	if performer := mapper.ToFHIR(observation).Performer; len(performer) != 1 || *performer[0].Reference != practitionerReference {
+		t.Errorf("Expected the performer %s, got %+v", practitionerReference, performer)
+	}
+
+	organizationReference := "Organization/lab-1"
+	observation, _ = mapper.FromFHIR(&fhir.Observation{Performer: []fhir.Reference{{Reference: &organizationReference}}})
+	if observation.PerformerID != "" || mapper.ToFHIR(observation).Performer != nil {
+		t.Errorf("Expected an organization performer not to be stored, got %q", observation.PerformerID)
+	}
+}
+
+// TestObservationMapper_Encounter verifies an encounter reference round-trips
+func TestObservationMapper_Encounter(t *testing.T) {
+	mapper := NewObservationMapper()
+	encounterReference := "Encounter/encounter-1"
+
+	observation, _ := mapper.FromFHIR(&fhir.Observation{Encounter: &fhir.Reference{Reference: &encounterReference}})
+	if observation.EncounterID != "encounter-1" {
+		t.Fatalf("Expected encounter encounter-1, got %q", observation.EncounterID)
+	}
+	if encounter := mapper.ToFHIR(observation).Encounter; encounter == nil || *encounter.Reference != encounterReference {
+		t.Errorf("Expected the encounter %s, got %+v", encounterReference, encounter)
+	}
+}
+
 // TestNewObservationMapper verifies constructor
 func TestNewObservationMapper(t *testing.T) {
 	mapper := NewObservationMapper()
@@ -334,3 +448,53 @@ func TestNewObservationMapper(t *testing.T) {
 		t.Error("Expected non-nil mapper")
 	}
 }
+
+// TestObservationMapper_FromFHIR_ReportsDroppedValues verifies every dropped element is reported
+func TestObservationMapper_FromFHIR_ReportsDroppedValues(t *testing.T) {
+	mapper := NewObservationMapper()
+	effectiveDateTime := "yesterday"
+	issued := "2024-01-15"
+	badValue := json.Number("1.2.3")
+
+	observation, issues := mapper.FromFHIR(&fhir.Observation{
+		EffectiveDateTime: &effectiveDateTime,
+		Issued:            &issued,
+		ValueQuantity:     &fhir.Quantity{Value: &badValue},
+		Component: []fhir.ObservationComponent{
+			{ValueString: &effectiveDateTime},
+			{ValueQuantity: &fhir.Quantity{Value: &badValue}},
+		},
+	})
+
+	if observation.EffectiveDate != nil || observation.ValueQuantity != nil || observation.Components[1].ValueQuantity != nil {
+		t.Error("Expected unparseable values to be left unset")
+	}
+
+	expectedExpressions := []string{
+		"Observation.effectiveDateTime",
+		"Observation.issued",
+		"Observation.valueQuantity.value",
+		"Observation.component[1].valueQuantity.value",
+	}
+	if len(issues) != len(expectedExpressions) {
+		t.Fatalf("Expected %d issues, got %+v", len(expectedExpressions), issues)
+	}
+	for index, issue := range issues {
+		if issue.Expression[0] != expectedExpressions[index] {
+			t.Errorf("Expected expression %s, got %s", expectedExpressions[index], issue.Expression[0])
+		}
+	}
+}
+
+// TestObservationMapper_FromFHIR_NoIssuesForValidInput verifies clean input produces no issues
+func TestObservationMapper_FromFHIR_NoIssuesForValidInput(t *testing.T) {
+	mapper := NewObservationMapper()
+	effectiveDateTime := "2024-01-15T10:30:00Z"
+	value := json.Number("72")
+
+	_, issues := mapper.FromFHIR(&fhir.Observation{EffectiveDateTime: &effectiveDateTime, ValueQuantity: &fhir.Quantity{Value: &value}})
+
+	if len(issues) != 0 {
+		t.Errorf("Expected no issues, got %+v", issues)
+	}
+}
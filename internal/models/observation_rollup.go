@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// ObservationRollup aggregates one patient's numeric values for one code and unit over one UTC day
+// Component values (such as systolic and diastolic pressure) are rolled up under their own codes
+type ObservationRollup struct {
+	PatientID  string    `json:"patient_id" bson:"patient_id"`
+	CodeSystem string    `json:"code_system,omitempty" bson:"code_system"`
+	Code       string    `json:"code" bson:"code"`
+	Unit       string    `json:"unit,omitempty" bson:"unit"`
+	Day        time.Time `json:"day" bson:"day"`
+	Count      int64     `json:"count" bson:"count"`
+	Min        float64   `json:"min" bson:"min"`
+	Max        float64   `json:"max" bson:"max"`
+	Sum        float64   `json:"sum" bson:"sum"`
+	Average    float64   `json:"average" bson:"-"`
+}
+
+// RollupQuery selects one patient's rollups for a code within a day range
+type RollupQuery struct {
+	PatientID string
+	Code      string
+
+	// CodeSystem narrows the code to one system; empty matches the code in any system
+	CodeSystem string
+
+	// From and To bound the days, inclusive; nil leaves that end open
+	From *time.Time
+	To   *time.Time
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// OutboxEvent is a resource event waiting to be published to Kafka
+// This model maps to the event_outbox table
+type OutboxEvent struct {
+	ID int64 `json:"id"`
+
+	Topic string `json:"topic"`
+
+	// Key is the Kafka message key, the resource ID
+	Key string `json:"key"`
+
+	Payload []byte `json:"-"`
+
+	Attempts  int       `json:"attempts"`
+	LastError string    `json:"last_error,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -31,6 +31,12 @@ type Patient struct {
 	// Patient's birth date
 	BirthDate *time.Time `json:"birth_date"`
 
+	// Workflow labels exposed as meta.tag
+	Tags Tags `json:"tags,omitempty"`
+
+	// ID of the patient this duplicate was merged into, exposed as a replaced-by link; empty unless merged
+	ReplacedBy string `json:"replaced_by,omitempty"`
+
 	// Audit timestamps
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
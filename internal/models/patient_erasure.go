@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// ErasureStatus is the state of a patient erasure saga
+type ErasureStatus string
+
+const (
+	// ErasureStatusPending erasures are withdrawing the patient's records from each store
+	ErasureStatusPending ErasureStatus = "pending"
+
+	// ErasureStatusHeld erasures have withdrawn every record and wait out the waiting period
+	ErasureStatusHeld ErasureStatus = "held"
+
+	// ErasureStatusCompensating erasures are restoring withdrawn records after a failure or cancellation
+	ErasureStatusCompensating ErasureStatus = "compensating"
+
+	// ErasureStatusPurging erasures are permanently deleting the withdrawn records
+	ErasureStatusPurging ErasureStatus = "purging"
+
+	// ErasureStatusPurged erasures are complete and have a deletion certificate
+	ErasureStatusPurged ErasureStatus = "purged"
+
+	// ErasureStatusFailed erasures stopped on an error and restored everything they withdrew
+	ErasureStatusFailed ErasureStatus = "failed"
+
+	// ErasureStatusCancelled erasures were cancelled and restored everything they withdrew
+	ErasureStatusCancelled ErasureStatus = "cancelled"
+)
+
+// PatientErasure is a right-to-erasure request for one patient and the progress of its saga
+// This model maps to the patient_erasures table
+type PatientErasure struct {
+	ID          int64         `json:"id"`
+	PatientID   string        `json:"patient_id"`
+	Status      ErasureStatus `json:"status"`
+	Reason      string        `json:"reason"`
+	RequestedBy string        `json:"requested_by"`
+	CancelledBy string        `json:"cancelled_by,omitempty"`
+
+	// StepsStarted is how many saga steps have begun withdrawing records
+	StepsStarted int `json:"steps_started"`
+
+	// Error explains the failure that started compensation
+	Error string `json:"error,omitempty"`
+
+	// Records withdrawn and purged per store
+	HeldCounts   map[string]int64 `json:"held_counts"`
+	PurgedCounts map[string]int64 `json:"purged_counts,omitempty"`
+
+	// PurgeAfter is when the waiting period ends; unset until every store has been withdrawn
+	PurgeAfter *time.Time `json:"purge_after,omitempty"`
+
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	PurgedAt  *time.Time `json:"purged_at,omitempty"`
+}
+
+// DeletionCertificate attests that a patient's records were permanently deleted
+// Digest is the hex SHA-256 of the certificate's JSON encoding with Digest left empty
+type DeletionCertificate struct {
+	ErasureID   int64     `json:"erasure_id"`
+	PatientID   string    `json:"patient_id"`
+	Reason      string    `json:"reason"`
+	RequestedBy string    `json:"requested_by"`
+	RequestedAt time.Time `json:"requested_at"`
+	PurgedAt    time.Time `json:"purged_at"`
+
+	// PurgedRecords counts the records deleted from each store
+	PurgedRecords map[string]int64 `json:"purged_records"`
+
+	Digest string `json:"digest"`
+}
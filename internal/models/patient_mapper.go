@@ -1,21 +1,63 @@
 package models
 
 import (
+	"strings"
 	"time"
 
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
 // PatientMapper handles conversion between domain Patient model and FHIR Patient resource
-type PatientMapper struct{}
+type PatientMapper struct {
+	// Site-specific hooks run around every conversion, in registration order
+	hooks []PatientMappingHook
+}
 
 // NewPatientMapper creates a new instance of PatientMapper
 func NewPatientMapper() *PatientMapper {
 	return &PatientMapper{}
 }
 
-// ToFHIR converts a domain Patient model to a FHIR Patient resource
+// AddHook registers a site-specific hook; hooks run in the order they were added
+func (mapper *PatientMapper) AddHook(hook PatientMappingHook) {
+	mapper.hooks = append(mapper.hooks, hook)
+}
+
+// ToFHIR converts a domain Patient to a FHIR Patient, running registered hooks around the conversion
 func (mapper *PatientMapper) ToFHIR(patient *Patient) *fhir.Patient {
+	for _, hook := range mapper.hooks {
+		hook.PreToFHIR(patient)
+	}
+
+	fhirPatient := mapper.toFHIR(patient)
+
+	for _, hook := range mapper.hooks {
+		hook.PostToFHIR(patient, fhirPatient)
+	}
+
+	return fhirPatient
+}
+
+// FromFHIR converts a FHIR Patient to a domain Patient, running registered hooks around the conversion
+// Pre-hooks may modify the incoming resource before it is read
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *PatientMapper) FromFHIR(fhirPatient *fhir.Patient) (*Patient, []outcome.Issue) {
+	for _, hook := range mapper.hooks {
+		hook.PreFromFHIR(fhirPatient)
+	}
+
+	patient, issues := mapper.fromFHIR(fhirPatient)
+
+	for _, hook := range mapper.hooks {
+		hook.PostFromFHIR(fhirPatient, patient)
+	}
+
+	return patient, issues
+}
+
+// toFHIR performs the built-in conversion of a domain Patient to a FHIR Patient
+func (mapper *PatientMapper) toFHIR(patient *Patient) *fhir.Patient {
 	// Convert gender string to FHIR AdministrativeGender enum
 	var fhirGender *fhir.AdministrativeGender
 	if patient.Gender != "" {
@@ -54,12 +96,29 @@ func (mapper *PatientMapper) ToFHIR(patient *Patient) *fhir.Patient {
 		}
 	}
 
+	// Add workflow tags if present
+	if len(patient.Tags) > 0 {
+		fhirPatient.Meta = &fhir.Meta{Tag: TagsToFHIR(patient.Tags)}
+	}
+
+	// Point a merged duplicate at the patient it was merged into
+	if patient.ReplacedBy != "" {
+		replacementReference := "Patient/" + patient.ReplacedBy
+		fhirPatient.Link = []fhir.PatientLink{
+			{
+				Other: fhir.Reference{Reference: &replacementReference},
+				Type:  fhir.LinkTypeReplacedBy,
+			},
+		}
+	}
+
 	return fhirPatient
 }
 
-// FromFHIR converts a FHIR Patient resource to a domain Patient model
-func (mapper *PatientMapper) FromFHIR(fhirPatient *fhir.Patient) *Patient {
+// fromFHIR performs the built-in conversion of a FHIR Patient to a domain Patient
+func (mapper *PatientMapper) fromFHIR(fhirPatient *fhir.Patient) (*Patient, []outcome.Issue) {
 	patient := &Patient{}
+	var issues []outcome.Issue
 
 	// Map ID
 	if fhirPatient.Id != nil {
@@ -91,6 +150,8 @@ func (mapper *PatientMapper) FromFHIR(fhirPatient *fhir.Patient) *Patient {
 		parsedDate, parseError := time.Parse("2006-01-02", *fhirPatient.BirthDate)
 		if parseError == nil {
 			patient.BirthDate = &parsedDate
+		} else {
+			issues = append(issues, droppedElementIssue("Patient.birthDate", *fhirPatient.BirthDate, "a full date (YYYY-MM-DD)"))
 		}
 	}
 
@@ -104,7 +165,27 @@ func (mapper *PatientMapper) FromFHIR(fhirPatient *fhir.Patient) *Patient {
 		}
 	}
 
-	return patient
+	// Map workflow tags
+	if fhirPatient.Meta != nil {
+		tags, tagIssues := TagsFromFHIR(fhirPatient.Meta.Tag, "Patient.meta.tag")
+		patient.Tags = tags
+		issues = append(issues, tagIssues...)
+	}
+
+	// Map the replaced-by link of a merged duplicate; other links are not stored
+	for _, link := range fhirPatient.Link {
+		if link.Type != fhir.LinkTypeReplacedBy || link.Other.Reference == nil {
+			continue
+		}
+		replacementID, isPatientReference := strings.CutPrefix(*link.Other.Reference, "Patient/")
+		if !isPatientReference || replacementID == "" {
+			issues = append(issues, droppedElementIssue("Patient.link.other", *link.Other.Reference, "a Patient/{id} reference"))
+			continue
+		}
+		patient.ReplacedBy = replacementID
+	}
+
+	return patient, issues
 }
 
 // mapGenderToFHIR converts a string gender to FHIR AdministrativeGender enum
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -114,7 +115,7 @@ func TestPatientMapper_FromFHIR(t *testing.T) {
 	}
 
 	// Convert to domain model
-	domainPatient := mapper.FromFHIR(fhirPatient)
+	domainPatient, _ := mapper.FromFHIR(fhirPatient)
 
 	// Verify ID
 	if domainPatient.ID != patientID {
@@ -224,3 +225,44 @@ func TestNewPatientMapper(t *testing.T) {
 		t.Error("Expected NewPatientMapper to return non-nil instance")
 	}
 }
+
+// TestPatientMapper_FromFHIR_ReportsUnparseableBirthDate verifies dropped dates are reported as warnings
+func TestPatientMapper_FromFHIR_ReportsUnparseableBirthDate(t *testing.T) {
+	mapper := NewPatientMapper()
+	birthDate := "1990-13"
+
+	patient, issues := mapper.FromFHIR(&fhir.Patient{BirthDate: &birthDate})
+
+	if patient.BirthDate != nil {
+		t.Error("Expected unparseable birth date to be left unset")
+	}
+	if len(issues) != 1 || issues[0].Severity != fhir.IssueSeverityWarning || issues[0].Expression[0] != "Patient.birthDate" {
+		t.Fatalf("Expected one birthDate warning, got %+v", issues)
+	}
+	if !strings.Contains(issues[0].Diagnostics, "1990-13") {
+		t.Errorf("Expected diagnostics to include the dropped value, got %s", issues[0].Diagnostics)
+	}
+}
+
+// TestPatientMapper_ReplacedByLink verifies a merged patient's replaced-by link round-trips and that links
+// which are not Patient references are reported instead of stored
+func TestPatientMapper_ReplacedByLink(t *testing.T) {
+	mapper := NewPatientMapper()
+
+	fhirPatient := mapper.ToFHIR(&Patient{ID: "duplicate-id", ReplacedBy: "surviving-id"})
+	if len(fhirPatient.Link) != 1 || fhirPatient.Link[0].Type != fhir.LinkTypeReplacedBy || *fhirPatient.Link[0].Other.Reference != "Patient/surviving-id" {
+		t.Fatalf("Expected a replaced-by link to the surviving patient, got %+v", fhirPatient.Link)
+	}
+	if roundTripped, issues := mapper.FromFHIR(fhirPatient); roundTripped.ReplacedBy != "surviving-id" || len(issues) != 0 {
+		t.Errorf("Expected the link back without issues, got %q and %v", roundTripped.ReplacedBy, issues)
+	}
+	if unmerged := mapper.ToFHIR(&Patient{ID: "patient-id"}); len(unmerged.Link) != 0 {
+		t.Errorf("Expected no link for a patient that was not merged, got %+v", unmerged.Link)
+	}
+
+	relatedPerson := "RelatedPerson/person-1"
+	fhirPatient.Link[0].Other.Reference = &relatedPerson
+	if mapped, issues := mapper.FromFHIR(fhirPatient); mapped.ReplacedBy != "" || len(issues) != 1 {
+		t.Errorf("Expected a RelatedPerson link to be reported and not stored, got %q and %v", mapped.ReplacedBy, issues)
+	}
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+)
+
+// Practitioner represents a practitioner record in the database
+// This model maps to the practitioners table and can be converted to FHIR format
+type Practitioner struct {
+	// Unique identifier for the practitioner (UUID)
+	ID string `json:"id"`
+
+	// FHIR identifier system (e.g., "http://hl7.org/fhir/sid/us-npi")
+	IdentifierSystem string `json:"identifier_system"`
+
+	// FHIR identifier value (e.g., NPI number)
+	IdentifierValue string `json:"identifier_value"`
+
+	// Whether the practitioner record is active
+	Active bool `json:"active"`
+
+	// Practitioner's family (last) name
+	FamilyName string `json:"family_name"`
+
+	// Practitioner's given (first) name
+	GivenName string `json:"given_name"`
+
+	// Administrative gender (male, female, other, unknown)
+	Gender string `json:"gender"`
+
+	// Specialty coding, read from the first qualification code
+	SpecialtySystem  string `json:"specialty_system"`
+	SpecialtyCode    string `json:"specialty_code"`
+	SpecialtyDisplay string `json:"specialty_display"`
+
+	// Audit timestamps
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
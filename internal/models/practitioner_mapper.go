@@ -0,0 +1,121 @@
+package models
+
+import (
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// PractitionerMapper handles conversion between domain Practitioner model and FHIR Practitioner resource
+type PractitionerMapper struct{}
+
+// NewPractitionerMapper creates a new instance of PractitionerMapper
+func NewPractitionerMapper() *PractitionerMapper {
+	return &PractitionerMapper{}
+}
+
+// ToFHIR converts a domain Practitioner to a FHIR Practitioner
+func (mapper *PractitionerMapper) ToFHIR(practitioner *Practitioner) *fhir.Practitioner {
+	// Convert gender string to FHIR AdministrativeGender enum
+	var fhirGender *fhir.AdministrativeGender
+	if practitioner.Gender != "" {
+		gender := mapGenderToFHIR(practitioner.Gender)
+		fhirGender = &gender
+	}
+
+	// Build the FHIR Practitioner resource
+	fhirPractitioner := &fhir.Practitioner{
+		Id:     &practitioner.ID,
+		Active: &practitioner.Active,
+		Name: []fhir.HumanName{
+			{
+				Family: &practitioner.FamilyName,
+				Given:  []string{practitioner.GivenName},
+			},
+		},
+		Gender: fhirGender,
+	}
+
+	// Add identifier if present
+	if practitioner.IdentifierSystem != "" && practitioner.IdentifierValue != "" {
+		fhirPractitioner.Identifier = []fhir.Identifier{
+			{
+				System: &practitioner.IdentifierSystem,
+				Value:  &practitioner.IdentifierValue,
+			},
+		}
+	}
+
+	// R4 Practitioner has no specialty element; the specialty is carried as the qualification code
+	if practitioner.SpecialtyCode != "" {
+		specialtyCoding := fhir.Coding{Code: &practitioner.SpecialtyCode}
+		if practitioner.SpecialtySystem != "" {
+			specialtyCoding.System = &practitioner.SpecialtySystem
+		}
+		if practitioner.SpecialtyDisplay != "" {
+			specialtyCoding.Display = &practitioner.SpecialtyDisplay
+		}
+		fhirPractitioner.Qualification = []fhir.PractitionerQualification{
+			{Code: fhir.CodeableConcept{Coding: []fhir.Coding{specialtyCoding}}},
+		}
+	}
+
+	return fhirPractitioner
+}
+
+// FromFHIR converts a FHIR Practitioner to a domain Practitioner
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *PractitionerMapper) FromFHIR(fhirPractitioner *fhir.Practitioner) (*Practitioner, []outcome.Issue) {
+	practitioner := &Practitioner{}
+	var issues []outcome.Issue
+
+	// Map ID
+	if fhirPractitioner.Id != nil {
+		practitioner.ID = *fhirPractitioner.Id
+	}
+
+	// Map Active status
+	if fhirPractitioner.Active != nil {
+		practitioner.Active = *fhirPractitioner.Active
+	}
+
+	// Map Name (take first name entry)
+	if len(fhirPractitioner.Name) > 0 {
+		if fhirPractitioner.Name[0].Family != nil {
+			practitioner.FamilyName = *fhirPractitioner.Name[0].Family
+		}
+		if len(fhirPractitioner.Name[0].Given) > 0 {
+			practitioner.GivenName = fhirPractitioner.Name[0].Given[0]
+		}
+	}
+
+	// Map Gender
+	if fhirPractitioner.Gender != nil {
+		practitioner.Gender = mapGenderFromFHIR(*fhirPractitioner.Gender)
+	}
+
+	// Map Identifier (take first identifier entry)
+	if len(fhirPractitioner.Identifier) > 0 {
+		if fhirPractitioner.Identifier[0].System != nil {
+			practitioner.IdentifierSystem = *fhirPractitioner.Identifier[0].System
+		}
+		if fhirPractitioner.Identifier[0].Value != nil {
+			practitioner.IdentifierValue = *fhirPractitioner.Identifier[0].Value
+		}
+	}
+
+	// Map specialty from the first qualification's first coding
+	if len(fhirPractitioner.Qualification) > 0 && len(fhirPractitioner.Qualification[0].Code.Coding) > 0 {
+		coding := fhirPractitioner.Qualification[0].Code.Coding[0]
+		if coding.Code != nil {
+			practitioner.SpecialtyCode = *coding.Code
+			if coding.System != nil {
+				practitioner.SpecialtySystem = *coding.System
+			}
+			if coding.Display != nil {
+				practitioner.SpecialtyDisplay = *coding.Display
+			}
+		}
+	}
+
+	return practitioner, issues
+}
@@ -0,0 +1,85 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestPractitionerMapper_ToFHIR verifies the specialty is written as the qualification code
+func TestPractitionerMapper_ToFHIR(t *testing.T) {
+	mapper := NewPractitionerMapper()
+
+	fhirPractitioner := mapper.ToFHIR(&Practitioner{
+		ID:               "practitioner-1",
+		IdentifierSystem: "http://hl7.org/fhir/sid/us-npi",
+		IdentifierValue:  "1234567893",
+		Active:           true,
+		FamilyName:       "House",
+		GivenName:        "Gregory",
+		Gender:           "male",
+		SpecialtySystem:  "http://nucc.org/provider-taxonomy",
+		SpecialtyCode:    "207RI0200X",
+		SpecialtyDisplay: "Infectious Disease Physician",
+	})
+
+	if *fhirPractitioner.Id != "practitioner-1" || *fhirPractitioner.Name[0].Family != "House" || fhirPractitioner.Name[0].Given[0] != "Gregory" {
+		t.Errorf("Expected practitioner-1 named Gregory House, got %+v", fhirPractitioner)
+	}
+	if fhirPractitioner.Gender == nil || *fhirPractitioner.Gender != fhir.AdministrativeGenderMale {
+		t.Errorf("Expected gender male, got %v", fhirPractitioner.Gender)
+	}
+	if len(fhirPractitioner.Identifier) != 1 || *fhirPractitioner.Identifier[0].Value != "1234567893" {
+		t.Errorf("Expected the NPI identifier, got %+v", fhirPractitioner.Identifier)
+	}
+	if len(fhirPractitioner.Qualification) != 1 || len(fhirPractitioner.Qualification[0].Code.Coding) != 1 {
+		t.Fatalf("Expected one qualification coding, got %+v", fhirPractitioner.Qualification)
+	}
+	specialtyCoding := fhirPractitioner.Qualification[0].Code.Coding[0]
+	if *specialtyCoding.System != "http://nucc.org/provider-taxonomy" || *specialtyCoding.Code != "207RI0200X" || *specialtyCoding.Display != "Infectious Disease Physician" {
+		t.Errorf("Expected the specialty coding, got %+v", specialtyCoding)
+	}
+}
+
+// TestPractitionerMapper_ToFHIR_NoSpecialty verifies practitioners without a specialty have no qualification
+func TestPractitionerMapper_ToFHIR_NoSpecialty(t *testing.T) {
+	fhirPractitioner := NewPractitionerMapper().ToFHIR(&Practitioner{ID: "practitioner-2", FamilyName: "Wilson", GivenName: "James"})
+
+	if fhirPractitioner.Qualification != nil || fhirPractitioner.Identifier != nil || fhirPractitioner.Gender != nil {
+		t.Errorf("Expected no qualification, identifier, or gender, got %+v", fhirPractitioner)
+	}
+}
+
+// TestPractitionerMapper_FromFHIR verifies the first name, identifier, and qualification coding are read
+func TestPractitionerMapper_FromFHIR(t *testing.T) {
+	familyName := "Cuddy"
+	active := false
+	gender := fhir.AdministrativeGenderFemale
+	identifierSystem := "http://hl7.org/fhir/sid/us-npi"
+	identifierValue := "1245319599"
+	specialtyCode := "207V00000X"
+	fhirPractitioner := &fhir.Practitioner{
+		Active:     &active,
+		Name:       []fhir.HumanName{{Family: &familyName, Given: []string{"Lisa", "M"}}},
+		Gender:     &gender,
+		Identifier: []fhir.Identifier{{System: &identifierSystem, Value: &identifierValue}},
+		Qualification: []fhir.PractitionerQualification{
+			{Code: fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &specialtyCode}}}},
+		},
+	}
+
+	practitioner, issues := NewPractitionerMapper().FromFHIR(fhirPractitioner)
+
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if practitioner.FamilyName != "Cuddy" || practitioner.GivenName != "Lisa" || practitioner.Active || practitioner.Gender != "female" {
+		t.Errorf("Expected an inactive female practitioner named Lisa Cuddy, got %+v", practitioner)
+	}
+	if practitioner.IdentifierSystem != identifierSystem || practitioner.IdentifierValue != identifierValue {
+		t.Errorf("Expected the NPI identifier, got %s|%s", practitioner.IdentifierSystem, practitioner.IdentifierValue)
+	}
+	if practitioner.SpecialtyCode != "207V00000X" || practitioner.SpecialtySystem != "" {
+		t.Errorf("Expected specialty code 207V00000X without a system, got %s|%s", practitioner.SpecialtySystem, practitioner.SpecialtyCode)
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChangeOperation identifies the kind of write recorded in the change log
+type ChangeOperation string
+
+const (
+	// ChangeOperationCreate records a newly created resource
+	ChangeOperationCreate ChangeOperation = "create"
+
+	// ChangeOperationUpdate records a modified resource
+	ChangeOperationUpdate ChangeOperation = "update"
+
+	// ChangeOperationDelete records a removed resource
+	ChangeOperationDelete ChangeOperation = "delete"
+)
+
+// ResourceChange is one entry in the append-only change log
+// This model maps to the resource_changes table
+type ResourceChange struct {
+	// Monotonically increasing position in the change log
+	Sequence int64 `json:"sequence"`
+
+	// FHIR resource type (e.g., "Patient", "Observation")
+	ResourceType string `json:"resource_type"`
+
+	// Logical ID of the changed resource
+	ResourceID string `json:"resource_id"`
+
+	// Version of the resource after this change (starts at 1)
+	Version int `json:"version"`
+
+	// Kind of write that produced the change
+	Operation ChangeOperation `json:"operation"`
+
+	// Time the change was recorded
+	ChangedAt time.Time `json:"changed_at"`
+
+	// Snapshot is the FHIR JSON of the resource after the change; empty for deletes
+	// It backs point-in-time reads and is left out of the compact sync feed
+	Snapshot json.RawMessage `json:"-"`
+
+	// CompartmentPatientID is the patient whose compartment the resource belonged to after the change
+	CompartmentPatientID string `json:"-"`
+}
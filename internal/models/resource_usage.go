@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// ResourceUsage is the quota usage held by one stored resource
+// This model maps to the tenant_resource_usage table
+type ResourceUsage struct {
+	ResourceType string
+	ResourceID   string
+
+	// TenantID is the tenant the resource counts against
+	TenantID string
+
+	// PayloadBytes is the size of the last stored payload
+	PayloadBytes int64
+
+	CreatedAt time.Time
+}
+
+// TenantUsage is a tenant's quota counters
+// This model maps to the tenant_quota_usage table
+type TenantUsage struct {
+	TenantID string
+
+	// Patients counts every stored patient; StorageBytes sums the payloads of every stored resource
+	Patients     int64
+	StorageBytes int64
+
+	// ObservationsToday counts observations created on ObservationDay, a UTC date (YYYY-MM-DD)
+	ObservationsToday int64
+	ObservationDay    string
+}
+
+// UsageChange is a change to a tenant's counters, with the limits the counters it grows must stay within
+type UsageChange struct {
+	TenantID     string
+	Patients     int64
+	Observations int64
+	StorageBytes int64
+
+	// Day is the UTC date (YYYY-MM-DD) the observation change counts toward
+	Day string
+
+	// MaxPatients, MaxObservationsPerDay, and MaxStorageBytes cap the counters; zero means unlimited
+	MaxPatients           int64
+	MaxObservationsPerDay int64
+	MaxStorageBytes       int64
+}
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+)
+
+// SearchExportStatus is the state of a search export
+type SearchExportStatus string
+
+const (
+	// SearchExportStatusPending exports wait for the export runner
+	SearchExportStatusPending SearchExportStatus = "pending"
+
+	// SearchExportStatusRunning exports are being materialized
+	SearchExportStatusRunning SearchExportStatus = "running"
+
+	// SearchExportStatusReady exports can be downloaded until they expire
+	SearchExportStatusReady SearchExportStatus = "ready"
+
+	// SearchExportStatusFailed exports stopped on an error
+	SearchExportStatusFailed SearchExportStatus = "failed"
+
+	// SearchExportStatusExpired exports passed their retention and their file was deleted
+	SearchExportStatusExpired SearchExportStatus = "expired"
+)
+
+// Search export file formats
+const (
+	// SearchExportFormatNDJSON writes one FHIR resource per line
+	SearchExportFormatNDJSON = "ndjson"
+
+	// SearchExportFormatCSV writes one row of flattened elements per resource
+	SearchExportFormatCSV = "csv"
+)
+
+// SearchExport is a search whose full result set is materialized into an encrypted file
+// This model maps to the search_exports table
+type SearchExport struct {
+	ID           int64  `json:"id"`
+	ResourceType string `json:"resource_type"`
+
+	// Query is the search criteria as the client sent them
+	Query string `json:"query"`
+
+	// SearchQuery is Query with exposed IDs translated to stored ones
+	SearchQuery string `json:"-"`
+
+	Format      string             `json:"format"`
+	Status      SearchExportStatus `json:"status"`
+	RequestedBy string             `json:"requested_by"`
+	TenantID    string             `json:"-"`
+
+	ResourceCount int `json:"resource_count"`
+
+	// Truncated is set when the results exceeded the export limit and only the first ones were written
+	Truncated bool   `json:"truncated,omitempty"`
+	Error     string `json:"error,omitempty"`
+
+	// Content is the sealed file; it is only loaded for downloads
+	Content []byte `json:"-"`
+
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+
+	// DownloadURL is a freshly signed link, set on ready exports shown to their requester
+	DownloadURL          string     `json:"download_url,omitempty"`
+	DownloadURLExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+
+	// Downloads is the audit trail, set on exports shown to their requester
+	Downloads []*SearchExportDownload `json:"downloads,omitempty"`
+}
+
+// SearchExportDownload records one download of a search export
+// This model maps to the search_export_downloads table
+type SearchExportDownload struct {
+	ExportID      int64     `json:"export_id"`
+	DownloadedBy  string    `json:"downloaded_by"`
+	RemoteAddress string    `json:"remote_address,omitempty"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	DownloadedAt  time.Time `json:"downloaded_at"`
+}
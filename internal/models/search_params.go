@@ -2,19 +2,59 @@ package models
 
 import "time"
 
+// ValueCriteria holds the values of a search parameter that may be repeated or comma-separated
+// Every group (one per repeated parameter) must match; a group matches when any of its values does
+type ValueCriteria [][]string
+
+// PageCursor marks the last match of a page of a search sorted by created_at, so the next page can start
+// after it without skipping rows; the ID breaks ties between matches created at the same time
+type PageCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// SortedByCreation reports whether a search sorted by sortBy is ordered by created_at, the only order
+// cursors can continue
+func SortedByCreation(sortBy string) bool {
+	return sortBy == "" || sortBy == "created_at"
+}
+
+// NameMatch is how a name search value is compared, chosen by the parameter's modifier
+type NameMatch string
+
+const (
+	// NameMatchDefault matches any part of the name ignoring case, and similar names when fuzzy matching is on
+	NameMatchDefault NameMatch = ""
+
+	// NameMatchContains (:contains) matches any part of the name ignoring case
+	NameMatchContains NameMatch = "contains"
+
+	// NameMatchExact (:exact) matches the whole name, case included
+	NameMatchExact NameMatch = "exact"
+)
+
+// NameCriterion is one name search parameter: values of which any matches, and how they are compared
+type NameCriterion struct {
+	Values []string
+	Match  NameMatch
+}
+
+// NameCriteria holds parsed name search parameters; every criterion must match
+type NameCriteria []NameCriterion
+
 // PatientSearchParams contains filter criteria for patient search
 type PatientSearchParams struct {
-	// Name searches both given_name and family_name (partial match, case-insensitive)
-	Name string
+	// Name searches both given_name and family_name
+	Name NameCriteria
 
-	// FamilyName searches family_name only (partial match, case-insensitive)
-	FamilyName string
+	// FamilyName searches family_name only
+	FamilyName NameCriteria
 
-	// GivenName searches given_name only (partial match, case-insensitive)
-	GivenName string
+	// GivenName searches given_name only
+	GivenName NameCriteria
 
 	// Gender filters by exact gender match
-	Gender string
+	Gender ValueCriteria
 
 	// BirthDate filters by exact birth date
 	BirthDate *time.Time
@@ -28,6 +68,12 @@ type PatientSearchParams struct {
 	// Active filters by active status (nil means no filter)
 	Active *bool
 
+	// Identifier filters by identifier system and value (nil means no filter)
+	Identifier *IdentifierCriterion
+
+	// Tags filters by meta.tag (_tag)
+	Tags TagCriteria
+
 	// SortBy specifies the field to sort by (name, birthdate, etc.)
 	SortBy string
 
@@ -39,6 +85,22 @@ type PatientSearchParams struct {
 
 	// Offset specifies number of results to skip (for pagination)
 	Offset int
+
+	// CursorPaging pages with _cursor rather than _offset; it is set for searches sorted by created_at that
+	// give no _offset
+	CursorPaging bool
+
+	// After continues a cursor-paged search with the match after this one, in place of Offset (_cursor)
+	After *PageCursor
+}
+
+// IdentifierCriterion holds a parsed identifier search value
+type IdentifierCriterion struct {
+	// Systems lists the identifier systems that match; empty matches any system and "" matches identifiers without one
+	Systems []string
+
+	// Value must equal the identifier value; empty matches any value
+	Value string
 }
 
 // ObservationSearchParams contains filter criteria for observation search
@@ -46,14 +108,17 @@ type ObservationSearchParams struct {
 	// PatientID filters observations for a specific patient
 	PatientID string
 
+	// EncounterID filters observations recorded during a specific encounter
+	EncounterID string
+
 	// Code filters by observation code (exact match)
-	Code string
+	Code ValueCriteria
 
 	// Category filters by observation category
-	Category string
+	Category ValueCriteria
 
 	// Status filters by observation status (final, preliminary, etc.)
-	Status string
+	Status ValueCriteria
 
 	// DateGreaterThan filters observations with effective date >= this value
 	DateGreaterThan *time.Time
@@ -61,6 +126,12 @@ type ObservationSearchParams struct {
 	// DateLessThan filters observations with effective date <= this value
 	DateLessThan *time.Time
 
+	// ValueQuantity filters by the observation's quantity value and unit (value-quantity)
+	ValueQuantity QuantityCriteria
+
+	// Tags filters by meta.tag (_tag)
+	Tags TagCriteria
+
 	// SortBy specifies the field to sort by (effective_date, code, etc.)
 	SortBy string
 
@@ -72,4 +143,283 @@ type ObservationSearchParams struct {
 
 	// Offset specifies number of results to skip (for pagination)
 	Offset int
+
+	// CursorPaging pages with _cursor rather than _offset; it is set for searches sorted by created_at that
+	// give no _offset
+	CursorPaging bool
+
+	// After continues a cursor-paged search with the match after this one, in place of Offset (_cursor)
+	After *PageCursor
+}
+
+// QuantityCriterion holds a parsed value-quantity search value
+type QuantityCriterion struct {
+	// Comparator is eq, ne, gt, lt, ge, or le; ap is parsed as an eq range of 10% either side of the value
+	Comparator string
+
+	// Value is compared against the observation's value by gt, lt, ge, and le
+	Value float64
+
+	// Low and High bound the values eq matches and ne excludes, Low <= value < High, from the precision
+	// the value was given with: 5.4 matches 5.35 up to but not including 5.45
+	Low  float64
+	High float64
+
+	// Unit must equal the observation's unit; empty matches any unit
+	Unit string
+
+	// CanonicalUnit is set when Unit converts to a canonical unit; observations stored with canonical values in
+	// it then match by CanonicalValue, CanonicalLow, and CanonicalHigh, which are Value, Low, and High converted
+	CanonicalUnit  string
+	CanonicalValue float64
+	CanonicalLow   float64
+	CanonicalHigh  float64
+}
+
+// QuantityCriteria holds parsed value-quantity search values
+// Every group must match; a group matches when any of its values does
+type QuantityCriteria [][]QuantityCriterion
+
+// ObservationLastNParams contains the criteria of Observation/$lastn: a patient's most recent observations
+// of each code
+type ObservationLastNParams struct {
+	// PatientID is the subject patient and is required
+	PatientID string
+
+	// Codes limits the result to observations with any of these codes
+	Codes []CodingCriterion
+
+	// Categories limits the result to observations in any of these categories
+	Categories []string
+
+	// DateGreaterThan and DateLessThan bound the effective date
+	DateGreaterThan *time.Time
+	DateLessThan    *time.Time
+
+	// Max is the number of observations returned per code
+	Max int
+}
+
+// PractitionerSearchParams contains filter criteria for practitioner search
+type PractitionerSearchParams struct {
+	// Name searches both given_name and family_name (partial match, case-insensitive)
+	Name string
+
+	// FamilyName searches family_name only (partial match, case-insensitive)
+	FamilyName string
+
+	// GivenName searches given_name only (partial match, case-insensitive)
+	GivenName string
+
+	// Active filters by active status (nil means no filter)
+	Active *bool
+
+	// Identifier filters by identifier system and value (nil means no filter)
+	Identifier *IdentifierCriterion
+
+	// Specialty filters by the qualification code (nil means no filter)
+	Specialty *CodingCriterion
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// EncounterSearchParams contains filter criteria for encounter search
+type EncounterSearchParams struct {
+	// PatientID filters encounters for a specific subject patient
+	PatientID string
+
+	// Status filters by encounter status (planned, in-progress, finished, etc.)
+	Status string
+
+	// DateGreaterThan keeps encounters whose period ends on or after this value, including ongoing ones
+	DateGreaterThan *time.Time
+
+	// DateLessThan keeps encounters whose period starts on or before this value
+	DateLessThan *time.Time
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// ConditionSearchParams contains filter criteria for condition search
+type ConditionSearchParams struct {
+	// PatientID filters conditions for a specific subject patient
+	PatientID string
+
+	// Code filters by the condition code (nil means no filter)
+	Code *CodingCriterion
+
+	// ClinicalStatus filters by clinical status (active, resolved, etc.)
+	ClinicalStatus string
+
+	// OnsetDateGreaterThan filters conditions with onset date >= this value
+	OnsetDateGreaterThan *time.Time
+
+	// OnsetDateLessThan filters conditions with onset date <= this value
+	OnsetDateLessThan *time.Time
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// CodingCriterion holds a parsed token search value matched against a single coding
+type CodingCriterion struct {
+	// System must equal the coding system unless AnySystem is set; empty matches codings without one
+	System string
+
+	// Code must equal the coding code; empty matches any code
+	Code string
+
+	// AnySystem is set when the token gave no system, so codings from every system match
+	AnySystem bool
+}
+
+// MedicationRequestSearchParams contains filter criteria for medication request search
+type MedicationRequestSearchParams struct {
+	// PatientID filters medication requests for a specific subject patient
+	PatientID string
+
+	// Status filters by request status (active, on-hold, completed, etc.)
+	Status string
+
+	// Intent filters by request intent (proposal, plan, order, etc.)
+	Intent string
+
+	// AuthoredOnGreaterThan filters requests written on or after this date
+	AuthoredOnGreaterThan *time.Time
+
+	// AuthoredOnLessThan filters requests written on or before this date
+	AuthoredOnLessThan *time.Time
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// AllergyIntoleranceSearchParams contains filter criteria for allergy intolerance search
+type AllergyIntoleranceSearchParams struct {
+	// PatientID filters allergies for a specific patient
+	PatientID string
+
+	// ClinicalStatus filters by clinical status (active, inactive, resolved)
+	ClinicalStatus string
+
+	// Category filters by category (food, medication, environment, biologic)
+	Category string
+
+	// Criticality filters by criticality (low, high, unable-to-assess)
+	Criticality string
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// DiagnosticReportSearchParams contains filter criteria for diagnostic report search
+type DiagnosticReportSearchParams struct {
+	// PatientID filters reports for a specific patient
+	PatientID string
+
+	// Category filters by category code (e.g., LAB)
+	Category string
+
+	// Code filters by report code, optionally restricted to a system
+	Code *CodingCriterion
+
+	// DateGreaterThan filters reports with effective date >= this value
+	DateGreaterThan *time.Time
+
+	// DateLessThan filters reports with effective date <= this value
+	DateLessThan *time.Time
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// ImmunizationSearchParams contains filter criteria for immunization search
+type ImmunizationSearchParams struct {
+	// PatientID filters immunizations for a specific patient
+	PatientID string
+
+	// VaccineCode filters by vaccine code, optionally restricted to a system
+	VaccineCode *CodingCriterion
+
+	// Status filters by status (completed, entered-in-error, not-done)
+	Status string
+
+	// LotNumber filters by vaccine lot number
+	LotNumber string
+
+	// DateGreaterThan filters immunizations given on or after this time
+	DateGreaterThan *time.Time
+
+	// DateLessThan filters immunizations given on or before this time
+	DateLessThan *time.Time
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// AuditEventSearchParams represents search criteria for audit events
+type AuditEventSearchParams struct {
+	// AgentID filters by the principal that made the request
+	AgentID string
+
+	// EntityType and EntityID filter by the accessed resource; EntityID alone matches any type
+	EntityType string
+	EntityID   string
+
+	// Action filters by C, R, U, D, or E
+	Action string
+
+	// Outcome filters by 0, 4, or 8
+	Outcome string
+
+	// TenantID filters by the tenant the request was made for
+	TenantID string
+
+	// DateGreaterThan and DateLessThan bound when the event was recorded
+	DateGreaterThan *time.Time
+	DateLessThan    *time.Time
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
+}
+
+// SubscriptionSearchParams contains filter criteria for subscription search
+type SubscriptionSearchParams struct {
+	// TenantID limits the search to one tenant's subscriptions
+	TenantID string
+
+	// Status filters by subscription status (empty means no filter)
+	Status string
+
+	// Limit specifies maximum number of results to return
+	Limit int
+
+	// Offset specifies number of results to skip (for pagination)
+	Offset int
 }
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"time"
+)
+
+// SubscriptionStatus is the state of a subscription
+type SubscriptionStatus string
+
+const (
+	// SubscriptionStatusRequested is the status clients create subscriptions with; the server activates them
+	SubscriptionStatusRequested SubscriptionStatus = "requested"
+
+	// SubscriptionStatusActive subscriptions are notified of matching writes
+	SubscriptionStatusActive SubscriptionStatus = "active"
+
+	// SubscriptionStatusError subscriptions stopped after their endpoint refused a notification
+	SubscriptionStatusError SubscriptionStatus = "error"
+
+	// SubscriptionStatusOff subscriptions were turned off by the client
+	SubscriptionStatusOff SubscriptionStatus = "off"
+)
+
+// Subscription is a client's request to be notified of writes matching a search criteria
+// This model maps to the subscriptions table and can be converted to FHIR format
+type Subscription struct {
+	// Unique identifier for the subscription (UUID)
+	ID string `json:"id"`
+
+	Status SubscriptionStatus `json:"status"`
+
+	// Reason says why the subscription was made, for operators
+	Reason string `json:"reason"`
+
+	// Criteria is the search whose matches are notified (e.g. "Observation?patient=123&code=8480-6")
+	Criteria string `json:"criteria"`
+
+	// ChannelType is the notification channel; only rest-hook is supported
+	ChannelType string `json:"channel_type"`
+
+	// Endpoint is the URL notifications are POSTed to
+	Endpoint string `json:"endpoint"`
+
+	// Payload is the MIME type of the resource sent with each notification; empty sends no body
+	Payload string `json:"payload"`
+
+	// Headers are sent with each notification, as "Name: value"
+	Headers []string `json:"headers"`
+
+	// End is when the subscription stops being notified (nil means never)
+	End *time.Time `json:"end"`
+
+	// Error describes the last notification failure that stopped the subscription
+	Error string `json:"error"`
+
+	// TenantID is the tenant that made the subscription; only its writes are notified
+	TenantID string `json:"tenant_id"`
+
+	// Audit timestamps
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
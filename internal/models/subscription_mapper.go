@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SubscriptionMapper handles conversion between domain Subscription model and FHIR Subscription resource
+type SubscriptionMapper struct{}
+
+// NewSubscriptionMapper creates a new instance of SubscriptionMapper
+func NewSubscriptionMapper() *SubscriptionMapper {
+	return &SubscriptionMapper{}
+}
+
+// subscriptionStatusToFHIR maps stored statuses to the FHIR subscription-status codes
+var subscriptionStatusToFHIR = map[SubscriptionStatus]fhir.SubscriptionStatus{
+	SubscriptionStatusRequested: fhir.SubscriptionStatusRequested,
+	SubscriptionStatusActive:    fhir.SubscriptionStatusActive,
+	SubscriptionStatusError:     fhir.SubscriptionStatusError,
+	SubscriptionStatusOff:       fhir.SubscriptionStatusOff,
+}
+
+// ToFHIR converts a domain Subscription to a FHIR Subscription
+func (mapper *SubscriptionMapper) ToFHIR(subscription *Subscription) *fhir.Subscription {
+	fhirSubscription := &fhir.Subscription{
+		Id:       &subscription.ID,
+		Status:   subscriptionStatusToFHIR[subscription.Status],
+		Reason:   subscription.Reason,
+		Criteria: subscription.Criteria,
+		Channel: fhir.SubscriptionChannel{
+			Type:     fhir.SubscriptionChannelTypeRestHook,
+			Endpoint: &subscription.Endpoint,
+			Header:   subscription.Headers,
+		},
+	}
+
+	// Add the payload MIME type; without one notifications carry no body
+	if subscription.Payload != "" {
+		fhirSubscription.Channel.Payload = &subscription.Payload
+	}
+
+	// Add end time if present
+	if subscription.End != nil {
+		end := subscription.End.UTC().Format(time.RFC3339)
+		fhirSubscription.End = &end
+	}
+
+	// Add the failure that stopped the subscription
+	if subscription.Error != "" {
+		fhirSubscription.Error = &subscription.Error
+	}
+
+	return fhirSubscription
+}
+
+// FromFHIR converts a FHIR Subscription to a domain Subscription
+// The returned issues are warnings for elements that could not be mapped and were dropped
+func (mapper *SubscriptionMapper) FromFHIR(fhirSubscription *fhir.Subscription) (*Subscription, []outcome.Issue) {
+	subscription := &Subscription{
+		Status:      SubscriptionStatus(fhirSubscription.Status.Code()),
+		Reason:      fhirSubscription.Reason,
+		Criteria:    fhirSubscription.Criteria,
+		ChannelType: fhirSubscription.Channel.Type.Code(),
+		Headers:     fhirSubscription.Channel.Header,
+	}
+	var issues []outcome.Issue
+
+	// Map ID
+	if fhirSubscription.Id != nil {
+		subscription.ID = *fhirSubscription.Id
+	}
+
+	// Map channel endpoint and payload
+	if fhirSubscription.Channel.Endpoint != nil {
+		subscription.Endpoint = *fhirSubscription.Channel.Endpoint
+	}
+	if fhirSubscription.Channel.Payload != nil {
+		subscription.Payload = *fhirSubscription.Channel.Payload
+	}
+
+	// Map end time, an instant
+	if fhirSubscription.End != nil {
+		if end, parseError := time.Parse(time.RFC3339, *fhirSubscription.End); parseError == nil {
+			subscription.End = &end
+		} else {
+			issues = append(issues, droppedElementIssue("Subscription.end", *fhirSubscription.End, "an RFC 3339 instant"))
+		}
+	}
+
+	return subscription, issues
+}
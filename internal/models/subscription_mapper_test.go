@@ -0,0 +1,92 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestSubscriptionMapper_RoundTrip verifies status, criteria, channel, and end survive a round trip
+func TestSubscriptionMapper_RoundTrip(t *testing.T) {
+	mapper := NewSubscriptionMapper()
+	subscriptionID := "sub-1"
+	endpoint := "https://example.org/notify"
+	payload := "application/fhir+json"
+	end := "2026-12-31T23:59:59Z"
+
+	subscription, issues := mapper.FromFHIR(&fhir.Subscription{
+		Id:       &subscriptionID,
+		Status:   fhir.SubscriptionStatusRequested,
+		Reason:   "Notify the lab system of new vitals",
+		Criteria: "Observation?code=8480-6",
+		End:      &end,
+		Channel: fhir.SubscriptionChannel{
+			Type:     fhir.SubscriptionChannelTypeRestHook,
+			Endpoint: &endpoint,
+			Payload:  &payload,
+			Header:   []string{"Authorization: Bearer secret"},
+		},
+	})
+	if len(issues) != 0 {
+		t.Errorf("Expected no mapping issues, got %+v", issues)
+	}
+	if subscription.ID != subscriptionID || subscription.Status != SubscriptionStatusRequested || subscription.Criteria != "Observation?code=8480-6" {
+		t.Errorf("Expected the submitted ID, status, and criteria, got %+v", subscription)
+	}
+	if subscription.ChannelType != "rest-hook" || subscription.Endpoint != endpoint || subscription.Payload != payload || len(subscription.Headers) != 1 {
+		t.Errorf("Expected the submitted channel, got %+v", subscription)
+	}
+	if subscription.End == nil || !subscription.End.Equal(time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("Expected the end instant, got %v", subscription.End)
+	}
+
+	fhirSubscription := mapper.ToFHIR(subscription)
+	if *fhirSubscription.Id != subscriptionID || fhirSubscription.Status != fhir.SubscriptionStatusRequested || fhirSubscription.Reason != subscription.Reason {
+		t.Errorf("Expected the ID, status, and reason back, got %+v", fhirSubscription)
+	}
+	if fhirSubscription.Criteria != "Observation?code=8480-6" || fhirSubscription.End == nil || *fhirSubscription.End != end {
+		t.Errorf("Expected the criteria and end back, got %+v", fhirSubscription)
+	}
+	channel := fhirSubscription.Channel
+	if channel.Type != fhir.SubscriptionChannelTypeRestHook || *channel.Endpoint != endpoint || *channel.Payload != payload || channel.Header[0] != "Authorization: Bearer secret" {
+		t.Errorf("Expected the channel back, got %+v", channel)
+	}
+	if fhirSubscription.Error != nil {
+		t.Errorf("Expected no error on a healthy subscription, got %q", *fhirSubscription.Error)
+	}
+}
+
+// TestSubscriptionMapper_ToFHIR_Statuses verifies every stored status maps to its FHIR code, and an errored
+// subscription carries its error without a payload when none was asked for
+func TestSubscriptionMapper_ToFHIR_Statuses(t *testing.T) {
+	mapper := NewSubscriptionMapper()
+	testCases := map[SubscriptionStatus]fhir.SubscriptionStatus{
+		SubscriptionStatusRequested: fhir.SubscriptionStatusRequested,
+		SubscriptionStatusActive:    fhir.SubscriptionStatusActive,
+		SubscriptionStatusError:     fhir.SubscriptionStatusError,
+		SubscriptionStatusOff:       fhir.SubscriptionStatusOff,
+	}
+	for status, expected := range testCases {
+		if fhirSubscription := mapper.ToFHIR(&Subscription{Status: status}); fhirSubscription.Status != expected {
+			t.Errorf("Status %s: expected %s, got %s", status, expected.Code(), fhirSubscription.Status.Code())
+		}
+	}
+
+	errored := mapper.ToFHIR(&Subscription{Status: SubscriptionStatusError, Endpoint: "https://example.org/gone", Error: "410 Gone"})
+	if errored.Error == nil || *errored.Error != "410 Gone" || errored.Channel.Payload != nil || errored.End != nil {
+		t.Errorf("Expected the error without a payload or end, got %+v", errored)
+	}
+}
+
+// TestSubscriptionMapper_FromFHIR_ReportsDroppedEnd verifies an end that is not an instant is reported and not stored
+func TestSubscriptionMapper_FromFHIR_ReportsDroppedEnd(t *testing.T) {
+	end := "next week"
+	subscription, issues := NewSubscriptionMapper().FromFHIR(&fhir.Subscription{Status: fhir.SubscriptionStatusRequested, End: &end})
+	if subscription.End != nil {
+		t.Errorf("Expected the end to be dropped, got %v", subscription.End)
+	}
+	if len(issues) != 1 || issues[0].Expression[0] != "Subscription.end" {
+		t.Errorf("Expected one issue for Subscription.end, got %+v", issues)
+	}
+}
@@ -0,0 +1,147 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Tag is a meta.tag coding used by workflow systems to label resources
+type Tag struct {
+	System  string `json:"system,omitempty" bson:"system,omitempty"`
+	Code    string `json:"code,omitempty" bson:"code,omitempty"`
+	Display string `json:"display,omitempty" bson:"display,omitempty"`
+}
+
+// Tags is a resource's tag list; it is stored as JSONB for patients and as an array for observations
+type Tags []Tag
+
+// Value encodes the tags for a JSONB column
+func (tags Tags) Value() (driver.Value, error) {
+	if tags == nil {
+		return "[]", nil
+	}
+	encoded, encodeError := json.Marshal(tags)
+	if encodeError != nil {
+		return nil, encodeError
+	}
+	return string(encoded), nil
+}
+
+// Scan decodes the tags from a JSONB column
+func (tags *Tags) Scan(source interface{}) error {
+	var encoded []byte
+	switch value := source.(type) {
+	case nil:
+		*tags = nil
+		return nil
+	case []byte:
+		encoded = value
+	case string:
+		encoded = []byte(value)
+	default:
+		return fmt.Errorf("cannot scan %T into tags", source)
+	}
+	return json.Unmarshal(encoded, tags)
+}
+
+// Add returns the tags with added merged in; a tag already present keeps its position and takes the new display
+func (tags Tags) Add(added ...Tag) Tags {
+	merged := append(Tags{}, tags...)
+	for _, addedTag := range added {
+		existingIndex := merged.indexOf(addedTag)
+		if existingIndex < 0 {
+			merged = append(merged, addedTag)
+			continue
+		}
+		if addedTag.Display != "" {
+			merged[existingIndex].Display = addedTag.Display
+		}
+	}
+	return merged
+}
+
+// Remove returns the tags without any matching removed by system and code; display is ignored
+func (tags Tags) Remove(removed ...Tag) Tags {
+	remaining := Tags{}
+	for _, tag := range tags {
+		if (Tags(removed)).indexOf(tag) < 0 {
+			remaining = append(remaining, tag)
+		}
+	}
+	return remaining
+}
+
+// indexOf finds the tag with the same system and code
+func (tags Tags) indexOf(wanted Tag) int {
+	for index, tag := range tags {
+		if tag.System == wanted.System && tag.Code == wanted.Code {
+			return index
+		}
+	}
+	return -1
+}
+
+// TagsToFHIR converts tags to meta.tag codings
+func TagsToFHIR(tags Tags) []fhir.Coding {
+	codings := make([]fhir.Coding, 0, len(tags))
+	for _, tag := range tags {
+		coding := fhir.Coding{}
+		if tag.System != "" {
+			system := tag.System
+			coding.System = &system
+		}
+		code := tag.Code
+		coding.Code = &code
+		if tag.Display != "" {
+			display := tag.Display
+			coding.Display = &display
+		}
+		codings = append(codings, coding)
+	}
+	return codings
+}
+
+// TagsFromFHIR converts meta.tag codings to tags
+// The returned issues are warnings for codings without a code, which are dropped
+func TagsFromFHIR(codings []fhir.Coding, expression string) (Tags, []outcome.Issue) {
+	var tags Tags
+	var issues []outcome.Issue
+	for _, coding := range codings {
+		if coding.Code == nil || *coding.Code == "" {
+			var system string
+			if coding.System != nil {
+				system = *coding.System
+			}
+			issues = append(issues, droppedElementIssue(expression, system, "a coding with a code"))
+			continue
+		}
+
+		tag := Tag{Code: *coding.Code}
+		if coding.System != nil {
+			tag.System = *coding.System
+		}
+		if coding.Display != nil {
+			tag.Display = *coding.Display
+		}
+		tags = tags.Add(tag)
+	}
+	return tags, issues
+}
+
+// TagCriterion is one parsed _tag search value
+type TagCriterion struct {
+	// System must equal the tag's system; with AnySystem unset, an empty System matches only tags without one
+	System string
+	// Code must equal the tag's code; empty matches any code
+	Code string
+	// AnySystem is set for a bare code, which matches that code in any system
+	AnySystem bool
+}
+
+// TagCriteria holds parsed _tag search values
+// Every group must match; a group matches when the resource has any of its tags
+type TagCriteria [][]TagCriterion
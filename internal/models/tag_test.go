@@ -0,0 +1,82 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestTags_Add verifies duplicates are merged by system and code and take the newer display
+func TestTags_Add(t *testing.T) {
+	tags := Tags{{System: "http://example.org/workflow", Code: "needs-review"}}
+
+	merged := tags.Add(
+		Tag{System: "http://example.org/workflow", Code: "needs-review", Display: "Needs review"},
+		Tag{Code: "imported"},
+	)
+
+	expected := Tags{
+		{System: "http://example.org/workflow", Code: "needs-review", Display: "Needs review"},
+		{Code: "imported"},
+	}
+	if !reflect.DeepEqual(merged, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, merged)
+	}
+	if tags[0].Display != "" {
+		t.Error("Expected Add to leave the original tags unchanged")
+	}
+}
+
+// TestTags_Remove verifies tags are removed by system and code regardless of display
+func TestTags_Remove(t *testing.T) {
+	tags := Tags{
+		{System: "http://example.org/workflow", Code: "needs-review", Display: "Needs review"},
+		{Code: "needs-review"},
+	}
+
+	remaining := tags.Remove(Tag{System: "http://example.org/workflow", Code: "needs-review"})
+
+	expected := Tags{{Code: "needs-review"}}
+	if !reflect.DeepEqual(remaining, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, remaining)
+	}
+}
+
+// TestTags_ValueAndScan verifies tags round-trip through their JSONB encoding
+func TestTags_ValueAndScan(t *testing.T) {
+	tags := Tags{{System: "http://example.org/workflow", Code: "needs-review", Display: "Needs review"}}
+
+	encoded, valueError := tags.Value()
+	if valueError != nil {
+		t.Fatalf("Expected no error, got %v", valueError)
+	}
+
+	var decoded Tags
+	if scanError := decoded.Scan([]byte(encoded.(string))); scanError != nil {
+		t.Fatalf("Expected no error, got %v", scanError)
+	}
+	if !reflect.DeepEqual(decoded, tags) {
+		t.Errorf("Expected %+v, got %+v", tags, decoded)
+	}
+
+	emptyEncoded, _ := Tags(nil).Value()
+	if emptyEncoded != "[]" {
+		t.Errorf("Expected nil tags to encode as [], got %v", emptyEncoded)
+	}
+}
+
+// TestTagsFromFHIR verifies codings without a code are dropped with a warning
+func TestTagsFromFHIR(t *testing.T) {
+	code := "needs-review"
+	system := "http://example.org/workflow"
+
+	tags, issues := TagsFromFHIR([]fhir.Coding{{System: &system, Code: &code}, {System: &system}}, "Patient.meta.tag")
+
+	if len(tags) != 1 || tags[0].Code != code || tags[0].System != system {
+		t.Errorf("Expected one needs-review tag, got %+v", tags)
+	}
+	if len(issues) != 1 || issues[0].Expression[0] != "Patient.meta.tag" {
+		t.Errorf("Expected one warning for Patient.meta.tag, got %+v", issues)
+	}
+}
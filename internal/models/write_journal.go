@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+)
+
+// WriteJournalEntry is an accepted write that may be applied but not yet recorded in the change log
+// This model maps to the write_journal table
+type WriteJournalEntry struct {
+	ID int64 `json:"id"`
+
+	ResourceType string          `json:"resource_type"`
+	ResourceID   string          `json:"resource_id"`
+	Operation    ChangeOperation `json:"operation"`
+	TenantID     string          `json:"tenant_id,omitempty"`
+
+	// PreviousPatientID is the patient the resource belonged to before the write, empty for creates
+	PreviousPatientID string `json:"previous_patient_id,omitempty"`
+
+	// CreatedAt is taken before the write is applied
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,70 @@
+package outcome
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+// collectorKey is the context key for the request's issue collector
+type collectorKey struct{}
+
+// Collector gathers non-fatal issues raised while handling a request
+// so handlers can report them alongside a successful response
+type Collector struct {
+	mutex  sync.Mutex
+	issues []Issue
+}
+
+// WithCollector returns a context carrying a collector
+// A collector already attached by middleware is reused so its issues are reported with the handler's
+func WithCollector(ctx context.Context) (context.Context, *Collector) {
+	if collector, exists := ctx.Value(collectorKey{}).(*Collector); exists {
+		return ctx, collector
+	}
+
+	collector := &Collector{}
+	return context.WithValue(ctx, collectorKey{}, collector), collector
+}
+
+// Collect adds issues to the context's collector; it is a no-op when none is attached
+func Collect(ctx context.Context, issues ...Issue) {
+	collector, exists := ctx.Value(collectorKey{}).(*Collector)
+	if !exists {
+		return
+	}
+
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	collector.issues = append(collector.issues, issues...)
+}
+
+// Collected returns the issues gathered so far for the context; nil when no collector is attached
+func Collected(ctx context.Context) []Issue {
+	collector, exists := ctx.Value(collectorKey{}).(*Collector)
+	if !exists {
+		return nil
+	}
+	return collector.Issues()
+}
+
+// Issues returns a copy of the collected issues
+func (collector *Collector) Issues() []Issue {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+	return append([]Issue(nil), collector.issues...)
+}
+
+// RejectionError is returned when issues must fail the request rather than be reported as warnings
+type RejectionError struct {
+	Issues []Issue
+}
+
+// Error implements the error interface
+func (rejection *RejectionError) Error() string {
+	diagnostics := make([]string, 0, len(rejection.Issues))
+	for _, issue := range rejection.Issues {
+		diagnostics = append(diagnostics, issue.Diagnostics)
+	}
+	return "resource rejected: " + strings.Join(diagnostics, "; ")
+}
@@ -0,0 +1,59 @@
+package outcome
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestCollector_GathersIssues verifies issues collected through the context are returned
+func TestCollector_GathersIssues(t *testing.T) {
+	ctx, collector := WithCollector(context.Background())
+
+	Collect(ctx, Warning(fhir.IssueTypeValue, "first"))
+	Collect(ctx, Warning(fhir.IssueTypeValue, "second"), Warning(fhir.IssueTypeValue, "third"))
+
+	issues := collector.Issues()
+	if len(issues) != 3 || issues[0].Diagnostics != "first" || issues[2].Diagnostics != "third" {
+		t.Errorf("Unexpected collected issues: %+v", issues)
+	}
+}
+
+// TestCollect_WithoutCollector verifies collecting without a collector is a no-op
+func TestCollect_WithoutCollector(t *testing.T) {
+	Collect(context.Background(), Warning(fhir.IssueTypeValue, "ignored"))
+}
+
+// TestRejectionError_Error verifies the message lists every diagnostic
+func TestRejectionError_Error(t *testing.T) {
+	rejection := &RejectionError{Issues: []Issue{
+		Error(fhir.IssueTypeValue, "bad birthDate"),
+		Error(fhir.IssueTypeValue, "bad value"),
+	}}
+
+	if !strings.Contains(rejection.Error(), "bad birthDate; bad value") {
+		t.Errorf("Unexpected error message: %s", rejection.Error())
+	}
+}
+
+// TestWithCollector_ReusesAttachedCollector verifies issues collected by middleware reach the handler's collector
+func TestWithCollector_ReusesAttachedCollector(t *testing.T) {
+	middlewareContext, _ := WithCollector(context.Background())
+	Collect(middlewareContext, Warning(fhir.IssueTypeBusinessRule, "deprecated"))
+
+	handlerContext, handlerCollector := WithCollector(middlewareContext)
+	Collect(handlerContext, Warning(fhir.IssueTypeValue, "mapping"))
+
+	issues := handlerCollector.Issues()
+	if len(issues) != 2 || issues[0].Diagnostics != "deprecated" {
+		t.Errorf("Unexpected collected issues: %+v", issues)
+	}
+	if collected := Collected(middlewareContext); len(collected) != 2 {
+		t.Errorf("Expected both issues visible to middleware, got %+v", collected)
+	}
+	if Collected(context.Background()) != nil {
+		t.Error("Expected nil without a collector")
+	}
+}
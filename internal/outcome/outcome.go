@@ -0,0 +1,85 @@
+package outcome
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/encoding"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Issue describes a single problem or note to report in a FHIR OperationOutcome
+type Issue struct {
+	// Severity of the issue (fatal, error, warning, information)
+	Severity fhir.IssueSeverity
+
+	// Code classifies the issue using the FHIR issue-type value set
+	Code fhir.IssueType
+
+	// Diagnostics is a human-readable explanation of the issue
+	Diagnostics string
+
+	// Expression lists FHIRPath expressions of the elements the issue applies to
+	Expression []string
+}
+
+// Error creates an error-severity issue
+func Error(code fhir.IssueType, diagnostics string, expression ...string) Issue {
+	return Issue{Severity: fhir.IssueSeverityError, Code: code, Diagnostics: diagnostics, Expression: expression}
+}
+
+// Warning creates a warning-severity issue
+func Warning(code fhir.IssueType, diagnostics string, expression ...string) Issue {
+	return Issue{Severity: fhir.IssueSeverityWarning, Code: code, Diagnostics: diagnostics, Expression: expression}
+}
+
+// Information creates an information-severity issue
+func Information(code fhir.IssueType, diagnostics string, expression ...string) Issue {
+	return Issue{Severity: fhir.IssueSeverityInformation, Code: code, Diagnostics: diagnostics, Expression: expression}
+}
+
+// New builds a FHIR OperationOutcome resource from a list of issues
+func New(issues []Issue) *fhir.OperationOutcome {
+	operationOutcome := &fhir.OperationOutcome{
+		Issue: make([]fhir.OperationOutcomeIssue, 0, len(issues)),
+	}
+
+	for _, issue := range issues {
+		// Copy diagnostics so the outcome does not alias the caller's issue slice
+		diagnostics := issue.Diagnostics
+		fhirIssue := fhir.OperationOutcomeIssue{
+			Severity:   issue.Severity,
+			Code:       issue.Code,
+			Expression: issue.Expression,
+		}
+		if diagnostics != "" {
+			fhirIssue.Diagnostics = &diagnostics
+		}
+		operationOutcome.Issue = append(operationOutcome.Issue, fhirIssue)
+	}
+
+	return operationOutcome
+}
+
+// HasErrors reports whether any issue is of error or fatal severity
+func HasErrors(issues []Issue) bool {
+	for _, issue := range issues {
+		if issue.Severity == fhir.IssueSeverityError || issue.Severity == fhir.IssueSeverityFatal {
+			return true
+		}
+	}
+	return false
+}
+
+// Write sends an OperationOutcome built from the issues with the given HTTP status
+func Write(w http.ResponseWriter, statusCode int, issues []Issue) {
+	w.Header().Set("Content-Type", "application/fhir+json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(New(issues))
+}
+
+// WriteForRequest sends an OperationOutcome like Write, appending issues already collected for the request
+// such as deprecation notices raised by middleware, in the format the client negotiated
+func WriteForRequest(w http.ResponseWriter, r *http.Request, statusCode int, issues []Issue) {
+	encoding.Write(w, r, statusCode, New(append(append([]Issue(nil), issues...), Collected(r.Context())...)))
+}
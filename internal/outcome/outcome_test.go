@@ -0,0 +1,94 @@
+package outcome
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestNew_BuildsOperationOutcome verifies issues are converted to FHIR issues
+func TestNew_BuildsOperationOutcome(t *testing.T) {
+	issues := []Issue{
+		Error(fhir.IssueTypeRequired, "Patient must have a name", "Patient.name"),
+		Warning(fhir.IssueTypeIncomplete, "Observations unavailable"),
+	}
+
+	operationOutcome := New(issues)
+
+	if len(operationOutcome.Issue) != 2 {
+		t.Fatalf("Expected 2 issues, got %d", len(operationOutcome.Issue))
+	}
+	if operationOutcome.Issue[0].Severity != fhir.IssueSeverityError {
+		t.Errorf("Expected error severity, got %s", operationOutcome.Issue[0].Severity)
+	}
+	if *operationOutcome.Issue[0].Diagnostics != "Patient must have a name" {
+		t.Errorf("Unexpected diagnostics: %s", *operationOutcome.Issue[0].Diagnostics)
+	}
+	if operationOutcome.Issue[0].Expression[0] != "Patient.name" {
+		t.Errorf("Expected expression Patient.name, got %v", operationOutcome.Issue[0].Expression)
+	}
+	if operationOutcome.Issue[1].Expression != nil {
+		t.Errorf("Expected no expression, got %v", operationOutcome.Issue[1].Expression)
+	}
+}
+
+// TestNew_JSONIncludesResourceType verifies the outcome serializes as a FHIR resource
+func TestNew_JSONIncludesResourceType(t *testing.T) {
+	outcomeJSON, marshalError := json.Marshal(New([]Issue{Information(fhir.IssueTypeInformational, "ok")}))
+	if marshalError != nil {
+		t.Fatalf("Failed to marshal: %v", marshalError)
+	}
+
+	if !strings.Contains(string(outcomeJSON), `"resourceType":"OperationOutcome"`) {
+		t.Errorf("Expected resourceType in JSON, got %s", outcomeJSON)
+	}
+	if !strings.Contains(string(outcomeJSON), `"severity":"information"`) {
+		t.Errorf("Expected information severity in JSON, got %s", outcomeJSON)
+	}
+}
+
+// TestHasErrors verifies error detection across severities
+func TestHasErrors(t *testing.T) {
+	if HasErrors([]Issue{Warning(fhir.IssueTypeValue, "w")}) {
+		t.Error("Expected warnings alone not to count as errors")
+	}
+	if !HasErrors([]Issue{Warning(fhir.IssueTypeValue, "w"), Error(fhir.IssueTypeValue, "e")}) {
+		t.Error("Expected error issue to be detected")
+	}
+}
+
+// TestWrite_SendsOperationOutcome verifies the status, content type, and body are written
+func TestWrite_SendsOperationOutcome(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Write(recorder, http.StatusTooManyRequests, []Issue{Error(fhir.IssueTypeThrottled, "Daily limit reached")})
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected status 429, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Content-Type") != "application/fhir+json" {
+		t.Errorf("Expected FHIR content type, got %s", recorder.Header().Get("Content-Type"))
+	}
+	if !strings.Contains(recorder.Body.String(), "throttled") {
+		t.Errorf("Expected throttled issue in body, got %s", recorder.Body.String())
+	}
+}
+
+// TestWriteForRequest_AppendsCollectedIssues verifies issues collected for the request follow the given ones
+func TestWriteForRequest_AppendsCollectedIssues(t *testing.T) {
+	request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", nil)
+	requestContext, _ := WithCollector(request.Context())
+	Collect(requestContext, Warning(fhir.IssueTypeBusinessRule, "deprecated route"))
+
+	recorder := httptest.NewRecorder()
+	WriteForRequest(recorder, request.WithContext(requestContext), http.StatusUnprocessableEntity, []Issue{Error(fhir.IssueTypeValue, "bad value")})
+
+	var operationOutcome fhir.OperationOutcome
+	json.NewDecoder(recorder.Body).Decode(&operationOutcome)
+	if recorder.Code != http.StatusUnprocessableEntity || len(operationOutcome.Issue) != 2 || *operationOutcome.Issue[1].Diagnostics != "deprecated route" {
+		t.Errorf("Unexpected outcome: %d %+v", recorder.Code, operationOutcome)
+	}
+}
@@ -0,0 +1,249 @@
+package plausibility
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// Actions taken when a value falls outside its plausible range
+const (
+	// ActionReject fails the write so the implausible value is never stored
+	ActionReject = "reject"
+
+	// ActionFlag stores the observation with a "questionable" interpretation and warns the caller
+	ActionFlag = "flag"
+
+	// ActionLog stores the observation unchanged and only logs the value
+	ActionLog = "log"
+)
+
+// InterpretationSystem is the code system of the interpretation given to flagged observations
+const InterpretationSystem = "https://github.com/nathannewyen/fhir-health-interop/CodeSystem/plausibility"
+
+// QuestionableCode is the interpretation code given to flagged observations
+const QuestionableCode = "questionable"
+
+// defaultRulesJSON holds the built-in vital-sign ranges
+//
+//go:embed rules.json
+var defaultRulesJSON []byte
+
+// Rule is the plausible range of one observation code
+type Rule struct {
+	// Code is the observation or component code the rule applies to (e.g. LOINC "8867-4")
+	Code string `json:"code"`
+
+	// Display names the measurement in diagnostics
+	Display string `json:"display"`
+
+	// Min and Max bound the plausible values, inclusive
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+
+	// Unit is the unit the range is expressed in; values sent in another unit are not checked
+	Unit string `json:"unit"`
+
+	// Aliases are other spellings of Unit that clients send (e.g. "mmHg" for "mm[Hg]")
+	Aliases []string `json:"aliases"`
+
+	// Action overrides the default action for this code: reject, flag, or log
+	Action string `json:"action"`
+}
+
+// Rules configures the plausibility checks
+type Rules struct {
+	// DefaultAction is taken for rules without their own action; flag when empty
+	DefaultAction string `json:"default_action"`
+
+	// Rules are the per-code ranges
+	Rules []Rule `json:"rules"`
+}
+
+// DefaultRules returns the built-in vital-sign ranges
+func DefaultRules() *Rules {
+	rules := &Rules{}
+	if decodeError := json.Unmarshal(defaultRulesJSON, rules); decodeError != nil {
+		panic(fmt.Sprintf("embedded plausibility rules are invalid: %v", decodeError))
+	}
+	return rules
+}
+
+// LoadRules reads site rules from a JSON file and merges them over the built-in ranges
+// A site rule replaces the built-in rule for the same code; other built-in rules are kept
+func LoadRules(path string) (*Rules, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read plausibility rules: %w", readError)
+	}
+
+	siteRules := &Rules{}
+	if decodeError := json.Unmarshal(fileBytes, siteRules); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse plausibility rules: %w", decodeError)
+	}
+
+	rules := DefaultRules().Merge(siteRules)
+	if validateError := rules.Validate(); validateError != nil {
+		return nil, validateError
+	}
+
+	return rules, nil
+}
+
+// Merge returns the rules with the site's rules replacing those for the same code
+func (rules *Rules) Merge(siteRules *Rules) *Rules {
+	merged := &Rules{DefaultAction: rules.DefaultAction}
+	if siteRules.DefaultAction != "" {
+		merged.DefaultAction = siteRules.DefaultAction
+	}
+
+	siteCodes := make(map[string]bool, len(siteRules.Rules))
+	for _, rule := range siteRules.Rules {
+		siteCodes[rule.Code] = true
+	}
+	for _, rule := range rules.Rules {
+		if !siteCodes[rule.Code] {
+			merged.Rules = append(merged.Rules, rule)
+		}
+	}
+	merged.Rules = append(merged.Rules, siteRules.Rules...)
+
+	return merged
+}
+
+// Validate checks rules for ranges and actions the checker cannot apply
+func (rules *Rules) Validate() error {
+	if !validAction(rules.DefaultAction) {
+		return fmt.Errorf("invalid plausibility default_action %q: must be reject, flag, or log", rules.DefaultAction)
+	}
+
+	for index, rule := range rules.Rules {
+		if rule.Code == "" {
+			return fmt.Errorf("plausibility rules[%d] must set code", index)
+		}
+		if rule.Min > rule.Max {
+			return fmt.Errorf("plausibility rule for %s has min %v above max %v", rule.Code, rule.Min, rule.Max)
+		}
+		if !validAction(rule.Action) {
+			return fmt.Errorf("invalid plausibility action %q for %s: must be reject, flag, or log", rule.Action, rule.Code)
+		}
+	}
+
+	return nil
+}
+
+// validAction reports whether an action is known; empty means the default
+func validAction(action string) bool {
+	switch action {
+	case "", ActionReject, ActionFlag, ActionLog:
+		return true
+	}
+	return false
+}
+
+// Finding is a value outside its plausible range
+type Finding struct {
+	// Expression is the FHIRPath of the value (e.g. "Observation.component[0].valueQuantity.value")
+	Expression string
+
+	// Rule is the range the value broke
+	Rule Rule
+
+	// Value is the implausible value
+	Value float64
+
+	// Action is what the rule asks to be done: reject, flag, or log
+	Action string
+}
+
+// Diagnostics describes the finding for logs and OperationOutcome issues
+func (finding Finding) Diagnostics() string {
+	return fmt.Sprintf("%s value %s %s is outside the plausible range %s-%s",
+		finding.Rule.Display,
+		strconv.FormatFloat(finding.Value, 'f', -1, 64),
+		finding.Rule.Unit,
+		strconv.FormatFloat(finding.Rule.Min, 'f', -1, 64),
+		strconv.FormatFloat(finding.Rule.Max, 'f', -1, 64),
+	)
+}
+
+// Checker finds observation values outside their configured plausible ranges
+type Checker struct {
+	rulesByCode   map[string]Rule
+	defaultAction string
+}
+
+// NewChecker creates a checker for validated rules
+func NewChecker(rules *Rules) *Checker {
+	defaultAction := rules.DefaultAction
+	if defaultAction == "" {
+		defaultAction = ActionFlag
+	}
+
+	rulesByCode := make(map[string]Rule, len(rules.Rules))
+	for _, rule := range rules.Rules {
+		rulesByCode[rule.Code] = rule
+	}
+
+	return &Checker{rulesByCode: rulesByCode, defaultAction: defaultAction}
+}
+
+// Check returns the observation's values that are outside their plausible ranges
+// The observation's own value and each component value are checked by their codes
+func (checker *Checker) Check(observation *models.Observation) []Finding {
+	var findings []Finding
+
+	if finding, implausible := checker.check("Observation.valueQuantity.value", observation.Code, observation.ValueQuantity, observation.ValueUnit); implausible {
+		findings = append(findings, finding)
+	}
+
+	for componentIndex, component := range observation.Components {
+		expression := fmt.Sprintf("Observation.component[%d].valueQuantity.value", componentIndex)
+		if finding, implausible := checker.check(expression, component.Code, component.ValueQuantity, component.ValueUnit); implausible {
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
+
+// check compares one value against the rule for its code
+// Values without a rule, or sent in a unit the rule does not know, cannot be judged and pass
+func (checker *Checker) check(expression string, code string, value *float64, unit string) (Finding, bool) {
+	rule, exists := checker.rulesByCode[code]
+	if !exists || value == nil || !rule.acceptsUnit(unit) {
+		return Finding{}, false
+	}
+	if *value >= rule.Min && *value <= rule.Max {
+		return Finding{}, false
+	}
+
+	action := rule.Action
+	if action == "" {
+		action = checker.defaultAction
+	}
+
+	return Finding{Expression: expression, Rule: rule, Value: *value, Action: action}, true
+}
+
+// acceptsUnit reports whether a value's unit matches the rule's; a missing unit is assumed to match
+func (rule Rule) acceptsUnit(unit string) bool {
+	if unit == "" || rule.Unit == "" || unit == rule.Unit {
+		return true
+	}
+	for _, alias := range rule.Aliases {
+		if unit == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// Questionable returns the interpretation given to flagged observations
+func Questionable() *models.Interpretation {
+	return &models.Interpretation{System: InterpretationSystem, Code: QuestionableCode, Display: "Questionable"}
+}
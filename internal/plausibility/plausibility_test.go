@@ -0,0 +1,84 @@
+package plausibility
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// floatPointer returns a pointer to the given value
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+// TestDefaultRules_Valid verifies the embedded ranges parse and validate
+func TestDefaultRules_Valid(t *testing.T) {
+	rules := DefaultRules()
+	if validateError := rules.Validate(); validateError != nil {
+		t.Fatalf("Expected the built-in rules to be valid, got %v", validateError)
+	}
+	if len(rules.Rules) == 0 {
+		t.Fatal("Expected built-in vital-sign rules")
+	}
+}
+
+// TestChecker_Check verifies values, components, and units are judged against their ranges
+func TestChecker_Check(t *testing.T) {
+	checker := NewChecker(DefaultRules())
+
+	heartRate := &models.Observation{Code: "8867-4", ValueQuantity: floatPointer(72), ValueUnit: "beats/min"}
+	if findings := checker.Check(heartRate); len(findings) != 0 {
+		t.Errorf("Expected a normal heart rate to pass, got %+v", findings)
+	}
+
+	heartRate.ValueQuantity = floatPointer(950)
+	findings := checker.Check(heartRate)
+	if len(findings) != 1 || findings[0].Action != ActionFlag || findings[0].Expression != "Observation.valueQuantity.value" {
+		t.Fatalf("Expected one flagged heart rate, got %+v", findings)
+	}
+	if findings[0].Diagnostics() != "Heart rate value 950 /min is outside the plausible range 0-400" {
+		t.Errorf("Unexpected diagnostics %q", findings[0].Diagnostics())
+	}
+
+	fahrenheit := &models.Observation{Code: "8310-5", ValueQuantity: floatPointer(98.6), ValueUnit: "[degF]"}
+	if findings := checker.Check(fahrenheit); len(findings) != 0 {
+		t.Errorf("Expected a value in another unit to be left unchecked, got %+v", findings)
+	}
+
+	bloodPressure := &models.Observation{Code: "85354-9", Components: []models.ObservationComponent{
+		{Code: "8480-6", ValueQuantity: floatPointer(120), ValueUnit: "mmHg"},
+		{Code: "8462-4", ValueQuantity: floatPointer(0), ValueUnit: "mmHg"},
+	}}
+	findings = checker.Check(bloodPressure)
+	if len(findings) != 1 || findings[0].Expression != "Observation.component[1].valueQuantity.value" {
+		t.Errorf("Expected the diastolic component flagged, got %+v", findings)
+	}
+}
+
+// TestLoadRules_MergesSiteRules verifies site rules replace built-in ones by code and are validated
+func TestLoadRules_MergesSiteRules(t *testing.T) {
+	rulesPath := filepath.Join(t.TempDir(), "plausibility.json")
+	os.WriteFile(rulesPath, []byte(`{"default_action": "log", "rules": [{"code": "8867-4", "display": "Heart rate", "min": 20, "max": 250, "unit": "/min", "action": "reject"}]}`), 0o600)
+
+	rules, loadError := LoadRules(rulesPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	checker := NewChecker(rules)
+
+	findings := checker.Check(&models.Observation{Code: "8867-4", ValueQuantity: floatPointer(300)})
+	if len(findings) != 1 || findings[0].Action != ActionReject {
+		t.Errorf("Expected the site range to reject, got %+v", findings)
+	}
+	findings = checker.Check(&models.Observation{Code: "8310-5", ValueQuantity: floatPointer(60)})
+	if len(findings) != 1 || findings[0].Action != ActionLog {
+		t.Errorf("Expected the built-in temperature range with the site default action, got %+v", findings)
+	}
+
+	os.WriteFile(rulesPath, []byte(`{"rules": [{"code": "8867-4", "min": 10, "max": 5, "action": "drop"}]}`), 0o600)
+	if _, invalidError := LoadRules(rulesPath); invalidError == nil {
+		t.Error("Expected an error for an inverted range")
+	}
+}
@@ -0,0 +1,160 @@
+package quota
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// MemoryUsageStore keeps tenant counters and resource usage in this process, for tests and
+// single-server deployments without a database
+type MemoryUsageStore struct {
+	mutex     sync.Mutex
+	tenants   map[string]*models.TenantUsage
+	resources map[string]*models.ResourceUsage
+}
+
+// NewMemoryUsageStore creates an empty in-memory store
+func NewMemoryUsageStore() *MemoryUsageStore {
+	return &MemoryUsageStore{
+		tenants:   make(map[string]*models.TenantUsage),
+		resources: make(map[string]*models.ResourceUsage),
+	}
+}
+
+// Reserve applies change when the counters it grows stay within its limits
+func (store *MemoryUsageStore) Reserve(ctx context.Context, change models.UsageChange) (*models.TenantUsage, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	usage := store.tenantFor(change.TenantID)
+	observationsToday := usage.ObservationsToday
+	if usage.ObservationDay != change.Day {
+		observationsToday = 0
+	}
+
+	if exceeds(change.Patients, usage.Patients, change.MaxPatients) ||
+		exceeds(change.Observations, observationsToday, change.MaxObservationsPerDay) ||
+		exceeds(change.StorageBytes, usage.StorageBytes, change.MaxStorageBytes) {
+		copiedUsage := *usage
+		return &copiedUsage, false, nil
+	}
+
+	usage.Patients += change.Patients
+	usage.ObservationsToday = observationsToday + change.Observations
+	usage.ObservationDay = change.Day
+	usage.StorageBytes += change.StorageBytes
+	copiedUsage := *usage
+	return &copiedUsage, true, nil
+}
+
+// exceeds reports whether growing current by delta passes limit; shrinking and unlimited counters never do
+func exceeds(delta int64, current int64, limit int64) bool {
+	return delta > 0 && limit > 0 && current+delta > limit
+}
+
+// Release reverses a reserved change
+func (store *MemoryUsageStore) Release(ctx context.Context, change models.UsageChange) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	usage := store.tenantFor(change.TenantID)
+	usage.Patients = max(usage.Patients-change.Patients, 0)
+	if usage.ObservationDay == change.Day {
+		usage.ObservationsToday = max(usage.ObservationsToday-change.Observations, 0)
+	}
+	usage.StorageBytes = max(usage.StorageBytes-change.StorageBytes, 0)
+	return nil
+}
+
+// PayloadBytes returns the stored size of a resource
+func (store *MemoryUsageStore) PayloadBytes(ctx context.Context, resourceType string, resourceID string) (int64, bool, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	storedUsage, tracked := store.resources[resourceType+"/"+resourceID]
+	if !tracked {
+		return 0, false, nil
+	}
+	return storedUsage.PayloadBytes, true, nil
+}
+
+// Commit records a written resource's usage, charging an existing resource's tenant its actual growth
+func (store *MemoryUsageStore) Commit(ctx context.Context, usage *models.ResourceUsage, reserved models.UsageChange) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := usage.ResourceType + "/" + usage.ResourceID
+	storedUsage, tracked := store.resources[key]
+	if !tracked {
+		copiedUsage := *usage
+		store.resources[key] = &copiedUsage
+		return nil
+	}
+
+	store.tenantFor(reserved.TenantID).StorageBytes -= reserved.StorageBytes
+	store.tenantFor(storedUsage.TenantID).StorageBytes += usage.PayloadBytes - storedUsage.PayloadBytes
+	storedUsage.PayloadBytes = usage.PayloadBytes
+	return nil
+}
+
+// Delete removes a resource's usage and credits its tenant
+func (store *MemoryUsageStore) Delete(ctx context.Context, resourceType string, resourceID string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	key := resourceType + "/" + resourceID
+	storedUsage, tracked := store.resources[key]
+	if !tracked {
+		return nil
+	}
+	delete(store.resources, key)
+
+	usage := store.tenantFor(storedUsage.TenantID)
+	usage.StorageBytes = max(usage.StorageBytes-storedUsage.PayloadBytes, 0)
+	switch resourceType {
+	case "Patient":
+		usage.Patients = max(usage.Patients-1, 0)
+	case "Observation":
+		if storedUsage.CreatedAt.UTC().Format("2006-01-02") == usage.ObservationDay {
+			usage.ObservationsToday = max(usage.ObservationsToday-1, 0)
+		}
+	}
+	return nil
+}
+
+// TenantUsage returns a copy of a tenant's counters
+func (store *MemoryUsageStore) TenantUsage(ctx context.Context, tenantID string) (*models.TenantUsage, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	copiedUsage := models.TenantUsage{TenantID: tenantID}
+	if usage, exists := store.tenants[tenantID]; exists {
+		copiedUsage = *usage
+	}
+	return &copiedUsage, nil
+}
+
+// ListTenantUsage returns copies of every tenant's counters
+func (store *MemoryUsageStore) ListTenantUsage(ctx context.Context) ([]*models.TenantUsage, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	usageList := make([]*models.TenantUsage, 0, len(store.tenants))
+	for _, usage := range store.tenants {
+		copiedUsage := *usage
+		usageList = append(usageList, &copiedUsage)
+	}
+	return usageList, nil
+}
+
+// tenantFor returns the tenant's counters, creating them; callers must hold the mutex
+func (store *MemoryUsageStore) tenantFor(tenantID string) *models.TenantUsage {
+	usage, exists := store.tenants[tenantID]
+	if !exists {
+		usage = &models.TenantUsage{TenantID: tenantID}
+		store.tenants[tenantID] = usage
+	}
+	return usage
+}
@@ -0,0 +1,155 @@
+package quota
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// statusRecorder captures the status code written by the wrapped handler, and the body when capturing is on
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode  int
+	captureBody bool
+	body        bytes.Buffer
+}
+
+// WriteHeader captures the status code before writing
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	recorder.statusCode = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write copies the body when capturing before writing it through
+func (recorder *statusRecorder) Write(body []byte) (int, error) {
+	if recorder.captureBody {
+		recorder.body.Write(body)
+	}
+	return recorder.ResponseWriter.Write(body)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
+
+// Middleware enforces tenant quotas on FHIR writes and records usage for successful ones
+// Usage is charged to the tenant of the request's API key, never to a client-set X-Tenant-ID,
+// so tenant.Resolver must run first
+func Middleware(enforcer *Enforcer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			resourceType, resourceID, isFHIRPath := parseFHIRPath(r.URL.Path)
+			if !isFHIRPath {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			creating := r.Method == http.MethodPost && resourceID == ""
+			updating := r.Method == http.MethodPut && resourceID != ""
+			deleting := r.Method == http.MethodDelete && resourceID != ""
+			if !creating && !updating && !deleting {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tenantID := tenant.VerifiedFromContext(r.Context())
+			recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK, captureBody: creating}
+			if deleting {
+				next.ServeHTTP(recorder, r)
+				if succeeded(recorder.statusCode) {
+					enforcer.RecordDelete(r.Context(), resourceType, resourceID)
+				}
+				return
+			}
+
+			// Measure the payload and restore the body for downstream handlers
+			bodyBytes, readError := io.ReadAll(r.Body)
+			if readError != nil {
+				outcome.Write(w, http.StatusBadRequest, []outcome.Issue{
+					outcome.Error(fhir.IssueTypeStructure, "Failed to read request body"),
+				})
+				return
+			}
+			r.Body.Close()
+			r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+			reservation, violation, reserveError := enforcer.Reserve(r.Context(), tenantID, resourceType, resourceID, int64(len(bodyBytes)))
+			if reserveError != nil {
+				// Writing without a reservation could overshoot a limit, so the write is refused
+				log.Error().Err(reserveError).Str("tenant_id", tenantID).Msg("Failed to check tenant quota")
+				outcome.WriteForRequest(w, r, http.StatusServiceUnavailable, []outcome.Issue{
+					outcome.Error(fhir.IssueTypeTransient, "Tenant quota usage is unavailable; retry the write"),
+				})
+				return
+			}
+			if violation != nil {
+				writeViolation(w, r, violation)
+				return
+			}
+
+			next.ServeHTTP(recorder, r)
+
+			// Only successful writes count toward usage
+			if !succeeded(recorder.statusCode) {
+				reservation.Release(r.Context())
+				return
+			}
+			reservation.Commit(r.Context(), createdResourceID(recorder.body.Bytes()))
+		})
+	}
+}
+
+// succeeded reports whether a status code is a 2xx success
+func succeeded(statusCode int) bool {
+	return statusCode >= 200 && statusCode < 300
+}
+
+// createdResourceID reads the id of the resource returned by a create, or "" when there is none
+func createdResourceID(responseBody []byte) string {
+	var createdResource struct {
+		ID string `json:"id"`
+	}
+	json.Unmarshal(responseBody, &createdResource)
+	return createdResource.ID
+}
+
+// writeViolation sends an OperationOutcome explaining the exceeded quota
+func writeViolation(w http.ResponseWriter, r *http.Request, violation *Violation) {
+	issueType := fhir.IssueTypeTooCostly
+	if violation.StatusCode == http.StatusTooManyRequests {
+		issueType = fhir.IssueTypeThrottled
+	}
+
+	if violation.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(violation.RetryAfter.Seconds()))))
+	}
+
+	outcome.WriteForRequest(w, r, violation.StatusCode, []outcome.Issue{outcome.Error(issueType, violation.Message)})
+}
+
+// parseFHIRPath extracts the resource type and ID from /fhir/{type} or /fhir/{type}/{id}
+func parseFHIRPath(path string) (resourceType string, resourceID string, isFHIRPath bool) {
+	if !strings.HasPrefix(path, "/fhir/") {
+		return "", "", false
+	}
+
+	segments := strings.Split(strings.Trim(strings.TrimPrefix(path, "/fhir/"), "/"), "/")
+	if len(segments) == 0 || segments[0] == "" || len(segments) > 2 {
+		return "", "", false
+	}
+
+	if len(segments) == 2 {
+		return segments[0], segments[1], true
+	}
+	return segments[0], "", true
+}
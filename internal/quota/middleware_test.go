@@ -0,0 +1,174 @@
+package quota
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// createdHandler drains the body and responds with 201 Created and a resource with a new ID
+var createdHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	io.ReadAll(r.Body)
+	createdCount++
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprintf(w, `{"resourceType":"Patient","id":"created-%d"}`, createdCount)
+})
+
+// createdCount numbers the resources createdHandler returns
+var createdCount int
+
+// newTenantRequest builds a request already resolved to the given tenant from an API key
+func newTenantRequest(method string, target string, body string, tenantID string) *http.Request {
+	request := httptest.NewRequest(method, target, bytes.NewBufferString(body))
+	return request.WithContext(tenant.WithVerifiedTenant(request.Context(), tenantID))
+}
+
+// TestMiddleware_EnforcesAndRecords verifies usage is recorded and the limit enforced
+func TestMiddleware_EnforcesAndRecords(t *testing.T) {
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxPatients: 1}})
+	handler := Middleware(enforcer)(createdHandler)
+
+	firstRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(firstRecorder, newTenantRequest(http.MethodPost, "/fhir/Patient", `{"resourceType":"Patient"}`, "acme"))
+	if firstRecorder.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d", firstRecorder.Code)
+	}
+
+	usage := usageOf(t, enforcer, "acme")
+	if usage.Patients != 1 || usage.StorageBytes != int64(len(`{"resourceType":"Patient"}`)) {
+		t.Errorf("Unexpected usage: %+v", usage)
+	}
+
+	secondRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(secondRecorder, newTenantRequest(http.MethodPost, "/fhir/Patient", `{}`, "acme"))
+	if secondRecorder.Code != http.StatusForbidden {
+		t.Fatalf("Expected status 403, got %d", secondRecorder.Code)
+	}
+	if !strings.Contains(secondRecorder.Body.String(), "OperationOutcome") {
+		t.Errorf("Expected OperationOutcome body, got %s", secondRecorder.Body.String())
+	}
+
+	// Other tenants are unaffected
+	otherRecorder := httptest.NewRecorder()
+	handler.ServeHTTP(otherRecorder, newTenantRequest(http.MethodPost, "/fhir/Patient", `{}`, "globex"))
+	if otherRecorder.Code != http.StatusCreated {
+		t.Errorf("Expected other tenant to succeed, got %d", otherRecorder.Code)
+	}
+}
+
+// TestMiddleware_ThrottledHasRetryAfter verifies daily limits return 429 with Retry-After
+func TestMiddleware_ThrottledHasRetryAfter(t *testing.T) {
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxObservationsPerDay: 1}})
+	reservation, _ := reserve(t, enforcer, "acme", "Observation", "", 0)
+	reservation.Commit(context.Background(), "o1")
+	handler := Middleware(enforcer)(createdHandler)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newTenantRequest(http.MethodPost, "/fhir/Observation", `{}`, "acme"))
+
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected status 429, got %d", recorder.Code)
+	}
+	if recorder.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+	if !strings.Contains(recorder.Body.String(), "throttled") {
+		t.Errorf("Expected throttled issue, got %s", recorder.Body.String())
+	}
+}
+
+// TestMiddleware_FailedWritesNotCounted verifies only successful writes count
+func TestMiddleware_FailedWritesNotCounted(t *testing.T) {
+	enforcer := newTestEnforcer(Config{})
+	failingHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+
+	recorder := httptest.NewRecorder()
+	Middleware(enforcer)(failingHandler).ServeHTTP(recorder, newTenantRequest(http.MethodPost, "/fhir/Patient", `{}`, "acme"))
+
+	if usage := usageOf(t, enforcer, "acme"); usage.Patients != 0 || usage.StorageBytes != 0 {
+		t.Errorf("Expected no usage recorded, got %+v", usage)
+	}
+}
+
+// TestMiddleware_HeaderTenantCannotBypassQuota verifies X-Tenant-ID does not choose the quota tenant
+func TestMiddleware_HeaderTenantCannotBypassQuota(t *testing.T) {
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxPatients: 1}})
+	handler := Middleware(enforcer)(createdHandler)
+
+	for _, headerTenantID := range []string{"first", "second"} {
+		request := httptest.NewRequest(http.MethodPost, "/fhir/Patient", bytes.NewBufferString(`{}`))
+		request = request.WithContext(tenant.WithTenant(request.Context(), headerTenantID))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+
+		if headerTenantID == "second" && recorder.Code != http.StatusForbidden {
+			t.Errorf("Expected a new header tenant to share the default quota, got %d", recorder.Code)
+		}
+	}
+
+	if usage := usageOf(t, enforcer, tenant.DefaultTenantID); usage.Patients != 1 {
+		t.Errorf("Expected header tenants to be charged to the default tenant, got %+v", usage)
+	}
+}
+
+// TestMiddleware_UpdateAndDelete verifies updates charge the size difference and deletes release storage
+func TestMiddleware_UpdateAndDelete(t *testing.T) {
+	enforcer := newTestEnforcer(Config{})
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+	})
+
+	createRecorder := httptest.NewRecorder()
+	Middleware(enforcer)(createdHandler).ServeHTTP(createRecorder, newTenantRequest(http.MethodPost, "/fhir/Patient", `{"name":"long name"}`, "acme"))
+	var created struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(createRecorder.Body).Decode(&created)
+
+	Middleware(enforcer)(okHandler).ServeHTTP(httptest.NewRecorder(), newTenantRequest(http.MethodPut, "/fhir/Patient/"+created.ID, `{}`, "acme"))
+	if usage := usageOf(t, enforcer, "acme"); usage.StorageBytes != 2 {
+		t.Errorf("Expected the update to replace the stored size, got %d bytes", usage.StorageBytes)
+	}
+
+	Middleware(enforcer)(okHandler).ServeHTTP(httptest.NewRecorder(), newTenantRequest(http.MethodDelete, "/fhir/Patient/"+created.ID, "", "acme"))
+	if usage := usageOf(t, enforcer, "acme"); usage.StorageBytes != 0 || usage.Patients != 0 {
+		t.Errorf("Expected the delete to release its usage, got %+v", usage)
+	}
+}
+
+// unavailableUsageStore fails every reservation, as when the database cannot be reached
+type unavailableUsageStore struct {
+	*MemoryUsageStore
+}
+
+// Reserve always fails
+func (store unavailableUsageStore) Reserve(ctx context.Context, change models.UsageChange) (*models.TenantUsage, bool, error) {
+	return nil, false, errors.New("connection refused")
+}
+
+// TestMiddleware_StoreUnavailable verifies writes are refused with 503 when usage cannot be reserved
+func TestMiddleware_StoreUnavailable(t *testing.T) {
+	enforcer := NewEnforcer(Config{}, unavailableUsageStore{NewMemoryUsageStore()})
+	handlerCalled := false
+	handler := Middleware(enforcer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+	}))
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, newTenantRequest(http.MethodPost, "/fhir/Patient", `{}`, "acme"))
+	if recorder.Code != http.StatusServiceUnavailable || handlerCalled {
+		t.Errorf("Expected 503 without running the write, got %d (handler called: %v)", recorder.Code, handlerCalled)
+	}
+}
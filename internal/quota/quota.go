@@ -0,0 +1,320 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// Limits caps what a tenant may store; zero means unlimited
+type Limits struct {
+	MaxPatients           int64 `json:"max_patients"`
+	MaxObservationsPerDay int64 `json:"max_observations_per_day"`
+	MaxStorageBytes       int64 `json:"max_storage_bytes"`
+}
+
+// Config holds default limits and per-tenant overrides
+type Config struct {
+	Default Limits            `json:"default"`
+	Tenants map[string]Limits `json:"tenants"`
+}
+
+// LoadConfig reads a quota configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return config, fmt.Errorf("failed to read quota config: %w", readError)
+	}
+
+	if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+		return config, fmt.Errorf("failed to parse quota config: %w", decodeError)
+	}
+
+	return config, nil
+}
+
+// LimitsFor returns the tenant's override or the default limits
+func (config Config) LimitsFor(tenantID string) Limits {
+	if tenantLimits, exists := config.Tenants[tenantID]; exists {
+		return tenantLimits
+	}
+	return config.Default
+}
+
+// Usage is a tenant's current consumption, reported for billing
+type Usage struct {
+	TenantID          string `json:"tenant_id"`
+	Patients          int64  `json:"patients"`
+	ObservationsToday int64  `json:"observations_today"`
+	StorageBytes      int64  `json:"storage_bytes"`
+
+	// UTC date (YYYY-MM-DD) the observation counter applies to
+	Day string `json:"day"`
+
+	// Limits currently applied to the tenant
+	Limits Limits `json:"limits"`
+}
+
+// Violation describes an exceeded quota
+type Violation struct {
+	// StatusCode is 429 for rate limits that reset and 403 for capacity limits
+	StatusCode int
+
+	// Message explains which limit was hit
+	Message string
+
+	// RetryAfter is set for rate limits that reset at the next UTC day
+	RetryAfter time.Duration
+}
+
+// UsageStore keeps tenant counters and the usage of each stored resource where every server instance
+// enforces the same limits
+type UsageStore interface {
+	// Reserve applies change to the tenant's counters in one atomic step when the counters it grows stay
+	// within its limits, returning the counters after it; when a limit would be exceeded it returns the
+	// current counters and false
+	Reserve(ctx context.Context, change models.UsageChange) (*models.TenantUsage, bool, error)
+
+	// Release reverses a change reserved earlier; the observation count is only returned on the same day
+	Release(ctx context.Context, change models.UsageChange) error
+
+	// PayloadBytes returns the stored size of a resource, and false when the resource is not tracked
+	PayloadBytes(ctx context.Context, resourceType string, resourceID string) (int64, bool, error)
+
+	// Commit records the usage held by a written resource; an existing resource's tenant is charged its
+	// actual growth in place of the storage reserved by reserved
+	Commit(ctx context.Context, usage *models.ResourceUsage, reserved models.UsageChange) error
+
+	// Delete removes a resource's usage and credits its tenant
+	Delete(ctx context.Context, resourceType string, resourceID string) error
+
+	// TenantUsage returns a tenant's counters, zero when it has stored nothing
+	TenantUsage(ctx context.Context, tenantID string) (*models.TenantUsage, error)
+
+	// ListTenantUsage returns the counters of every tenant that has stored resources
+	ListTenantUsage(ctx context.Context) ([]*models.TenantUsage, error)
+}
+
+// Enforcer checks writes against tenant limits and tracks usage
+// Counters live in the UsageStore, which checks and reserves capacity atomically, so instances sharing a
+// store cannot together overshoot a limit
+type Enforcer struct {
+	config Config
+	store  UsageStore
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// Reservation holds capacity for a write in progress until it is committed or released
+type Reservation struct {
+	enforcer     *Enforcer
+	resourceType string
+	resourceID   string
+	payloadBytes int64
+
+	// change is what was reserved: the counts of a create, and the full payload for creates or the growth for updates
+	change models.UsageChange
+}
+
+// NewEnforcer creates an enforcer for the given configuration keeping usage in store
+func NewEnforcer(config Config, store UsageStore) *Enforcer {
+	return &Enforcer{
+		config: config,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// Reserve checks a write of payloadBytes against the tenant's limits and, when it is allowed,
+// reserves the capacity in the same step so concurrent writes cannot overshoot a limit
+// resourceID is empty for creates; for updates only the growth over the stored payload is reserved
+func (enforcer *Enforcer) Reserve(ctx context.Context, tenantID string, resourceType string, resourceID string, payloadBytes int64) (*Reservation, *Violation, error) {
+	limits := enforcer.config.LimitsFor(tenantID)
+	creating := resourceID == ""
+
+	storageDelta := payloadBytes
+	if !creating {
+		storedBytes, tracked, lookupError := enforcer.store.PayloadBytes(ctx, resourceType, resourceID)
+		if lookupError != nil {
+			return nil, nil, fmt.Errorf("failed to read stored usage: %w", lookupError)
+		}
+		if tracked {
+			storageDelta = payloadBytes - storedBytes
+		}
+	}
+
+	change := models.UsageChange{
+		TenantID:              tenantID,
+		StorageBytes:          storageDelta,
+		Day:                   enforcer.dayOf(enforcer.now()),
+		MaxPatients:           limits.MaxPatients,
+		MaxObservationsPerDay: limits.MaxObservationsPerDay,
+		MaxStorageBytes:       limits.MaxStorageBytes,
+	}
+	if creating {
+		switch resourceType {
+		case "Patient":
+			change.Patients = 1
+		case "Observation":
+			change.Observations = 1
+		}
+	}
+
+	usage, reserved, reserveError := enforcer.store.Reserve(ctx, change)
+	if reserveError != nil {
+		return nil, nil, fmt.Errorf("failed to reserve quota usage: %w", reserveError)
+	}
+	if !reserved {
+		return nil, enforcer.violation(tenantID, limits, change, usage), nil
+	}
+
+	return &Reservation{
+		enforcer:     enforcer,
+		resourceType: resourceType,
+		resourceID:   resourceID,
+		payloadBytes: payloadBytes,
+		change:       change,
+	}, nil, nil
+}
+
+// violation explains which limit refused change, given the tenant's counters when it was refused
+func (enforcer *Enforcer) violation(tenantID string, limits Limits, change models.UsageChange, usage *models.TenantUsage) *Violation {
+	if change.Patients > 0 && limits.MaxPatients > 0 && usage.Patients+change.Patients > limits.MaxPatients {
+		return &Violation{
+			StatusCode: http.StatusForbidden,
+			Message:    fmt.Sprintf("Tenant '%s' has reached its limit of %d patients", tenantID, limits.MaxPatients),
+		}
+	}
+
+	observationsToday := usage.ObservationsToday
+	if usage.ObservationDay != change.Day {
+		observationsToday = 0
+	}
+	if change.Observations > 0 && limits.MaxObservationsPerDay > 0 && observationsToday+change.Observations > limits.MaxObservationsPerDay {
+		return &Violation{
+			StatusCode: http.StatusTooManyRequests,
+			Message:    fmt.Sprintf("Tenant '%s' has reached its limit of %d observations per day", tenantID, limits.MaxObservationsPerDay),
+			RetryAfter: enforcer.untilNextDay(),
+		}
+	}
+
+	return &Violation{
+		StatusCode: http.StatusForbidden,
+		Message:    fmt.Sprintf("Tenant '%s' would exceed its storage limit of %d bytes", tenantID, limits.MaxStorageBytes),
+	}
+}
+
+// Commit turns the reservation into usage held by the written resource
+// resourceID names the created resource; it is ignored for updates
+func (reservation *Reservation) Commit(ctx context.Context, resourceID string) {
+	if reservation.resourceID != "" {
+		resourceID = reservation.resourceID
+	}
+	if resourceID == "" {
+		return
+	}
+
+	enforcer := reservation.enforcer
+	usage := &models.ResourceUsage{
+		ResourceType: reservation.resourceType,
+		ResourceID:   resourceID,
+		TenantID:     reservation.change.TenantID,
+		PayloadBytes: reservation.payloadBytes,
+		CreatedAt:    enforcer.now(),
+	}
+	if commitError := enforcer.store.Commit(ctx, usage, reservation.change); commitError != nil {
+		log.Warn().Err(commitError).Str("resource_type", usage.ResourceType).Str("resource_id", usage.ResourceID).Msg("Failed to persist quota usage")
+	}
+}
+
+// Release returns the reserved capacity of a write that did not succeed
+func (reservation *Reservation) Release(ctx context.Context) {
+	if releaseError := reservation.enforcer.store.Release(ctx, reservation.change); releaseError != nil {
+		log.Warn().Err(releaseError).Str("tenant_id", reservation.change.TenantID).Msg("Failed to release quota reservation")
+	}
+}
+
+// RecordDelete releases the count and storage held by a deleted resource
+// The resource's own tenant is credited, whichever tenant deleted it
+func (enforcer *Enforcer) RecordDelete(ctx context.Context, resourceType string, resourceID string) {
+	if deleteError := enforcer.store.Delete(ctx, resourceType, resourceID); deleteError != nil {
+		log.Warn().Err(deleteError).Str("resource_type", resourceType).Str("resource_id", resourceID).Msg("Failed to persist quota usage release")
+	}
+}
+
+// Usage returns current usage for one tenant
+func (enforcer *Enforcer) Usage(ctx context.Context, tenantID string) (Usage, error) {
+	usage, usageError := enforcer.store.TenantUsage(ctx, tenantID)
+	if usageError != nil {
+		return Usage{}, usageError
+	}
+	return enforcer.report(tenantID, usage), nil
+}
+
+// AllUsage returns current usage for every tenant seen or configured, sorted by tenant ID
+func (enforcer *Enforcer) AllUsage(ctx context.Context) ([]Usage, error) {
+	storedUsage, listError := enforcer.store.ListTenantUsage(ctx)
+	if listError != nil {
+		return nil, listError
+	}
+
+	usageByTenant := make(map[string]*models.TenantUsage, len(storedUsage))
+	for _, usage := range storedUsage {
+		usageByTenant[usage.TenantID] = usage
+	}
+	for tenantID := range enforcer.config.Tenants {
+		if _, seen := usageByTenant[tenantID]; !seen {
+			usageByTenant[tenantID] = &models.TenantUsage{TenantID: tenantID}
+		}
+	}
+
+	usageReport := make([]Usage, 0, len(usageByTenant))
+	for tenantID, usage := range usageByTenant {
+		usageReport = append(usageReport, enforcer.report(tenantID, usage))
+	}
+	sort.Slice(usageReport, func(i, j int) bool {
+		return usageReport[i].TenantID < usageReport[j].TenantID
+	})
+
+	return usageReport, nil
+}
+
+// report describes a tenant's stored counters with its limits, counting no observations when none were
+// created today
+func (enforcer *Enforcer) report(tenantID string, usage *models.TenantUsage) Usage {
+	today := enforcer.dayOf(enforcer.now())
+	observationsToday := usage.ObservationsToday
+	if usage.ObservationDay != today {
+		observationsToday = 0
+	}
+	return Usage{
+		TenantID:          tenantID,
+		Patients:          usage.Patients,
+		ObservationsToday: observationsToday,
+		StorageBytes:      usage.StorageBytes,
+		Day:               today,
+		Limits:            enforcer.config.LimitsFor(tenantID),
+	}
+}
+
+// dayOf returns the UTC date the daily observation counter uses for a time
+func (enforcer *Enforcer) dayOf(at time.Time) string {
+	return at.UTC().Format("2006-01-02")
+}
+
+// untilNextDay returns the time remaining until the next UTC midnight
+func (enforcer *Enforcer) untilNextDay() time.Duration {
+	now := enforcer.now().UTC()
+	nextDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	return nextDay.Sub(now)
+}
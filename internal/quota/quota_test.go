@@ -0,0 +1,212 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestEnforcer creates an enforcer keeping usage in a fresh in-memory store
+func newTestEnforcer(config Config) *Enforcer {
+	return NewEnforcer(config, NewMemoryUsageStore())
+}
+
+// reserve reserves a write, failing the test when the store fails
+func reserve(t *testing.T, enforcer *Enforcer, tenantID string, resourceType string, resourceID string, payloadBytes int64) (*Reservation, *Violation) {
+	t.Helper()
+	reservation, violation, reserveError := enforcer.Reserve(context.Background(), tenantID, resourceType, resourceID, payloadBytes)
+	if reserveError != nil {
+		t.Fatalf("Expected no store error, got %v", reserveError)
+	}
+	return reservation, violation
+}
+
+// usageOf returns a tenant's usage, failing the test when the store fails
+func usageOf(t *testing.T, enforcer *Enforcer, tenantID string) Usage {
+	t.Helper()
+	usage, usageError := enforcer.Usage(context.Background(), tenantID)
+	if usageError != nil {
+		t.Fatalf("Expected no store error, got %v", usageError)
+	}
+	return usage
+}
+
+// reserveAndCommit reserves a write and commits it for resourceID, failing the test on a violation
+func reserveAndCommit(t *testing.T, enforcer *Enforcer, tenantID string, resourceType string, existingID string, createdID string, payloadBytes int64) {
+	t.Helper()
+	reservation, violation := reserve(t, enforcer, tenantID, resourceType, existingID, payloadBytes)
+	if violation != nil {
+		t.Fatalf("Expected write to be allowed, got %s", violation.Message)
+	}
+	reservation.Commit(context.Background(), createdID)
+}
+
+// TestEnforcer_MaxPatients verifies the patient cap and release on delete
+func TestEnforcer_MaxPatients(t *testing.T) {
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxPatients: 1}})
+
+	reserveAndCommit(t, enforcer, "acme", "Patient", "", "p1", 10)
+
+	_, violation := reserve(t, enforcer, "acme", "Patient", "", 10)
+	if violation == nil || violation.StatusCode != http.StatusForbidden {
+		t.Fatalf("Expected 403 violation, got %+v", violation)
+	}
+
+	// Updates are not creates and are not capped by patient count
+	if _, updateViolation := reserve(t, enforcer, "acme", "Patient", "p1", 10); updateViolation != nil {
+		t.Error("Expected update to be allowed")
+	}
+
+	enforcer.RecordDelete(context.Background(), "Patient", "p1")
+	if _, releasedViolation := reserve(t, enforcer, "acme", "Patient", "", 10); releasedViolation != nil {
+		t.Error("Expected capacity to be released after delete")
+	}
+}
+
+// TestEnforcer_ReserveIsAtomic verifies concurrent creates cannot overshoot the limit
+func TestEnforcer_ReserveIsAtomic(t *testing.T) {
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxPatients: 5}})
+
+	var allowed atomic.Int64
+	var waitGroup sync.WaitGroup
+	for attempt := 0; attempt < 50; attempt++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			if _, violation, _ := enforcer.Reserve(context.Background(), "acme", "Patient", "", 1); violation == nil {
+				allowed.Add(1)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	if allowed.Load() != 5 {
+		t.Errorf("Expected exactly 5 reservations, got %d", allowed.Load())
+	}
+}
+
+// TestEnforcer_ReleaseReturnsCapacity verifies a failed write gives back its reservation
+func TestEnforcer_ReleaseReturnsCapacity(t *testing.T) {
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxPatients: 1, MaxStorageBytes: 100}})
+
+	reservation, _ := reserve(t, enforcer, "acme", "Patient", "", 80)
+	reservation.Release(context.Background())
+
+	if usage := usageOf(t, enforcer, "acme"); usage.Patients != 0 || usage.StorageBytes != 0 {
+		t.Errorf("Expected no usage after release, got %+v", usage)
+	}
+}
+
+// TestEnforcer_ObservationsPerDayResets verifies the daily limit resets at UTC midnight
+func TestEnforcer_ObservationsPerDayResets(t *testing.T) {
+	currentTime := time.Date(2025, 3, 1, 23, 0, 0, 0, time.UTC)
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxObservationsPerDay: 1}})
+	enforcer.now = func() time.Time { return currentTime }
+
+	reserveAndCommit(t, enforcer, "acme", "Observation", "", "o1", 10)
+	_, violation := reserve(t, enforcer, "acme", "Observation", "", 10)
+	if violation == nil || violation.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 violation, got %+v", violation)
+	}
+	if violation.RetryAfter != time.Hour {
+		t.Errorf("Expected retry after 1h, got %s", violation.RetryAfter)
+	}
+
+	currentTime = currentTime.Add(2 * time.Hour)
+	if _, nextDayViolation := reserve(t, enforcer, "acme", "Observation", "", 10); nextDayViolation != nil {
+		t.Error("Expected limit to reset on a new day")
+	}
+}
+
+// TestEnforcer_StorageAndOverrides verifies storage limits and per-tenant overrides
+func TestEnforcer_StorageAndOverrides(t *testing.T) {
+	enforcer := newTestEnforcer(Config{
+		Default: Limits{MaxStorageBytes: 100},
+		Tenants: map[string]Limits{"enterprise": {}},
+	})
+
+	reserveAndCommit(t, enforcer, "acme", "Patient", "", "p1", 90)
+	if _, violation := reserve(t, enforcer, "acme", "Observation", "", 20); violation == nil {
+		t.Error("Expected storage limit violation")
+	}
+	if _, violation := reserve(t, enforcer, "enterprise", "Observation", "", 1000); violation != nil {
+		t.Error("Expected unlimited override to allow the write")
+	}
+
+	usageReport, usageError := enforcer.AllUsage(context.Background())
+	if usageError != nil {
+		t.Fatalf("Expected no store error, got %v", usageError)
+	}
+	if len(usageReport) != 2 || usageReport[0].TenantID != "acme" || usageReport[0].StorageBytes != 90 {
+		t.Errorf("Unexpected usage report: %+v", usageReport)
+	}
+}
+
+// TestEnforcer_UpdatesAndDeletesAdjustStorage verifies updates charge the size difference and deletes release it
+func TestEnforcer_UpdatesAndDeletesAdjustStorage(t *testing.T) {
+	enforcer := newTestEnforcer(Config{Default: Limits{MaxStorageBytes: 100}})
+
+	reserveAndCommit(t, enforcer, "acme", "Patient", "", "p1", 90)
+
+	// Replacing 90 bytes with 95 needs only 5 more, which fits
+	reserveAndCommit(t, enforcer, "acme", "Patient", "p1", "", 95)
+	if usage := usageOf(t, enforcer, "acme"); usage.StorageBytes != 95 {
+		t.Errorf("Expected 95 stored bytes after the update, got %d", usage.StorageBytes)
+	}
+
+	// Shrinking frees storage
+	reserveAndCommit(t, enforcer, "acme", "Patient", "p1", "", 40)
+	if usage := usageOf(t, enforcer, "acme"); usage.StorageBytes != 40 {
+		t.Errorf("Expected 40 stored bytes after shrinking, got %d", usage.StorageBytes)
+	}
+
+	enforcer.RecordDelete(context.Background(), "Patient", "p1")
+	if usage := usageOf(t, enforcer, "acme"); usage.StorageBytes != 0 || usage.Patients != 0 {
+		t.Errorf("Expected the delete to release everything, got %+v", usage)
+	}
+}
+
+// TestEnforcer_SharedStore verifies enforcers sharing a store, as server instances share the database,
+// enforce one limit and see each other's deletes
+func TestEnforcer_SharedStore(t *testing.T) {
+	store := NewMemoryUsageStore()
+	firstInstance := NewEnforcer(Config{Default: Limits{MaxPatients: 2}}, store)
+	secondInstance := NewEnforcer(Config{Default: Limits{MaxPatients: 2}}, store)
+
+	reserveAndCommit(t, firstInstance, "acme", "Patient", "", "p1", 30)
+	reserveAndCommit(t, secondInstance, "acme", "Patient", "", "p2", 20)
+	if usage := usageOf(t, firstInstance, "acme"); usage.Patients != 2 || usage.StorageBytes != 50 {
+		t.Errorf("Expected both instances' patients to be counted, got %+v", usage)
+	}
+	if _, violation := reserve(t, firstInstance, "acme", "Patient", "", 10); violation == nil {
+		t.Error("Expected the other instance's patient to exhaust the limit")
+	}
+
+	secondInstance.RecordDelete(context.Background(), "Patient", "p1")
+	if _, violation := reserve(t, firstInstance, "acme", "Patient", "", 10); violation != nil {
+		t.Errorf("Expected the other instance's delete to free capacity, got %s", violation.Message)
+	}
+}
+
+// TestLoadConfig verifies the JSON configuration format
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "quotas.json")
+	os.WriteFile(configPath, []byte(`{"default": {"max_patients": 100}, "tenants": {"acme": {"max_observations_per_day": 5}}}`), 0o600)
+
+	config, loadError := LoadConfig(configPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if config.LimitsFor("other").MaxPatients != 100 || config.LimitsFor("acme").MaxObservationsPerDay != 5 {
+		t.Errorf("Unexpected config: %+v", config)
+	}
+
+	if _, missingError := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); missingError == nil {
+		t.Error("Expected error for missing file")
+	}
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memorySweepInterval is how often buckets that have refilled completely are dropped
+const memorySweepInterval = time.Minute
+
+// bucket is one client's tokens for one route group
+type bucket struct {
+	tokens    float64
+	updatedAt time.Time
+	rate      Rate
+}
+
+// refill adds the tokens earned since the bucket was last updated, up to its capacity
+func (bucket *bucket) refill(now time.Time) {
+	elapsed := now.Sub(bucket.updatedAt).Seconds()
+	if elapsed > 0 {
+		bucket.tokens = min(bucket.tokens+elapsed*bucket.rate.RequestsPerSecond, bucket.rate.capacity())
+		bucket.updatedAt = now
+	}
+}
+
+// MemoryStore keeps token buckets in this process, for single-server deployments
+type MemoryStore struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take takes one token from the key's bucket, which starts full
+func (store *MemoryStore) Take(ctx context.Context, key string, rate Rate, now time.Time) (Decision, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.sweep(now)
+
+	clientBucket, exists := store.buckets[key]
+	if !exists {
+		clientBucket = &bucket{tokens: rate.capacity(), updatedAt: now}
+		store.buckets[key] = clientBucket
+	}
+	// A changed configuration applies to existing buckets at once
+	clientBucket.rate = rate
+	clientBucket.refill(now)
+
+	if clientBucket.tokens < 1 {
+		return Decision{RetryAfter: retryAfter(clientBucket.tokens, rate)}, nil
+	}
+	clientBucket.tokens--
+	return Decision{Allowed: true, Remaining: int(clientBucket.tokens)}, nil
+}
+
+// sweep drops buckets that would be full by now, since a new full bucket is the same, so idle clients
+// do not hold memory; the caller holds the mutex
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < memorySweepInterval {
+		return
+	}
+	store.lastSweep = now
+
+	for key, clientBucket := range store.buckets {
+		clientBucket.refill(now)
+		if clientBucket.tokens >= clientBucket.rate.capacity() {
+			delete(store.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestMemoryStore_Take verifies a bucket allows its burst, refuses the next request with the time until
+// a token returns, and refills at its rate
+func TestMemoryStore_Take(t *testing.T) {
+	store := NewMemoryStore()
+	rate := Rate{RequestsPerSecond: 2, Burst: 3}
+	startedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		decision, _ := store.Take(context.Background(), "read:client", rate, startedAt)
+		if !decision.Allowed || decision.Remaining != 3-attempt {
+			t.Fatalf("Expected request %d within the burst, got %+v", attempt, decision)
+		}
+	}
+
+	decision, _ := store.Take(context.Background(), "read:client", rate, startedAt)
+	if decision.Allowed || decision.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected a refusal with a 500ms wait, got %+v", decision)
+	}
+
+	decision, _ = store.Take(context.Background(), "read:client", rate, startedAt.Add(500*time.Millisecond))
+	if !decision.Allowed {
+		t.Errorf("Expected a token after 500ms, got %+v", decision)
+	}
+
+	// Other keys have their own buckets
+	if decision, _ := store.Take(context.Background(), "write:client", rate, startedAt); !decision.Allowed {
+		t.Errorf("Expected a separate bucket per key, got %+v", decision)
+	}
+}
+
+// TestMemoryStore_Sweep verifies buckets that have refilled are dropped
+func TestMemoryStore_Sweep(t *testing.T) {
+	store := NewMemoryStore()
+	rate := Rate{RequestsPerSecond: 1, Burst: 1}
+	startedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	store.Take(context.Background(), "read:idle", rate, startedAt)
+	store.Take(context.Background(), "read:active", rate, startedAt.Add(2*time.Minute))
+	if _, exists := store.buckets["read:idle"]; exists {
+		t.Error("Expected the idle bucket to be dropped")
+	}
+	if _, exists := store.buckets["read:active"]; !exists {
+		t.Error("Expected the active bucket to be kept")
+	}
+}
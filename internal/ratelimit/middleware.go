@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Limiter applies each client's rates to its FHIR requests
+type Limiter struct {
+	config Config
+	store  Store
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewLimiter creates a limiter keeping its buckets in store
+func NewLimiter(config Config, store Store) *Limiter {
+	return &Limiter{
+		config: config,
+		store:  store,
+		now:    time.Now,
+	}
+}
+
+// Middleware refuses FHIR requests over the client's rate with 429 and a Retry-After header
+// Clients are identified by the request's principal, so the role and token middleware must run first.
+// Anonymous requests are limited per remote address. When the store fails, requests are let through
+// rather than turning a Redis outage into a FHIR outage
+func (limiter *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/fhir/") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		clientID := clientKey(r)
+		group := routeGroup(r)
+		rate := limiter.config.LimitsFor(clientID).RateFor(group)
+		if rate.Unlimited() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		decision, takeError := limiter.store.Take(r.Context(), group+":"+clientID, rate, limiter.now())
+		if takeError != nil {
+			log.Warn().Err(takeError).Str("client", clientID).Msg("Rate limit store unavailable; request not limited")
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !decision.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(decision.RetryAfter.Seconds())), 1)))
+			outcome.WriteForRequest(w, r, http.StatusTooManyRequests, []outcome.Issue{
+				outcome.Error(fhir.IssueTypeThrottled, fmt.Sprintf("Rate limit of %g %s requests per second exceeded", rate.RequestsPerSecond, group)),
+			})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientKey returns the principal ID, or the remote address for anonymous requests
+func clientKey(r *http.Request) string {
+	principalID := auth.FromContext(r.Context()).ID
+	if principalID != auth.AnonymousPrincipalID {
+		return principalID
+	}
+
+	remoteHost, _, splitError := net.SplitHostPort(r.RemoteAddr)
+	if splitError != nil {
+		remoteHost = r.RemoteAddr
+	}
+	return auth.AnonymousPrincipalID + ":" + remoteHost
+}
+
+// routeGroup classifies reads and searches, including POST _search, as reads and everything else as writes
+func routeGroup(r *http.Request) string {
+	switch {
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		return GroupRead
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/_search"):
+		return GroupRead
+	default:
+		return GroupWrite
+	}
+}
@@ -0,0 +1,124 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+)
+
+// failingStore fails every take, as a store would while Redis is down
+type failingStore struct{}
+
+// Take always fails
+func (failingStore) Take(ctx context.Context, key string, rate Rate, now time.Time) (Decision, error) {
+	return Decision{}, errors.New("connection refused")
+}
+
+// okHandler answers 200 to every request it receives
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+// newTestLimiter creates a limiter allowing one read and one write per second per client, at a fixed time
+func newTestLimiter(store Store) *Limiter {
+	limiter := NewLimiter(Config{Default: Limits{
+		Read:  Rate{RequestsPerSecond: 1, Burst: 1},
+		Write: Rate{RequestsPerSecond: 1, Burst: 1},
+	}}, store)
+	limiter.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC) }
+	return limiter
+}
+
+// clientRequest builds a request made by the principal
+func clientRequest(method string, target string, principalID string) *http.Request {
+	request := httptest.NewRequest(method, target, nil)
+	return request.WithContext(auth.WithPrincipal(request.Context(), auth.Principal{ID: principalID}))
+}
+
+// TestMiddleware_TooManyRequests verifies requests over the rate get 429 with Retry-After
+func TestMiddleware_TooManyRequests(t *testing.T) {
+	handler := newTestLimiter(NewMemoryStore()).Middleware(okHandler)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, clientRequest(http.MethodGet, "/fhir/Patient", "oauth:app"))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("Expected the first read to pass, got %d", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, clientRequest(http.MethodGet, "/fhir/Patient/1", "oauth:app"))
+	if recorder.Code != http.StatusTooManyRequests || recorder.Header().Get("Retry-After") != "1" {
+		t.Errorf("Expected 429 with Retry-After 1, got %d %q", recorder.Code, recorder.Header().Get("Retry-After"))
+	}
+}
+
+// TestMiddleware_SeparateGroupsAndClients verifies reads, writes, and each client have their own buckets
+func TestMiddleware_SeparateGroupsAndClients(t *testing.T) {
+	handler := newTestLimiter(NewMemoryStore()).Middleware(okHandler)
+
+	for _, request := range []*http.Request{
+		clientRequest(http.MethodGet, "/fhir/Patient", "oauth:app"),
+		clientRequest(http.MethodPost, "/fhir/Patient", "oauth:app"),
+		clientRequest(http.MethodGet, "/fhir/Patient", "api-key:0a1b2c3d"),
+	} {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected %s %s by %s to pass, got %d", request.Method, request.URL.Path, auth.FromContext(request.Context()).ID, recorder.Code)
+		}
+	}
+
+	// POST _search is a read, so the app's read bucket is already empty
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, clientRequest(http.MethodPost, "/fhir/Patient/_search", "oauth:app"))
+	if recorder.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected POST _search to count as a read, got %d", recorder.Code)
+	}
+}
+
+// TestMiddleware_AnonymousByAddress verifies anonymous callers are limited per remote address
+func TestMiddleware_AnonymousByAddress(t *testing.T) {
+	handler := newTestLimiter(NewMemoryStore()).Middleware(okHandler)
+
+	for _, remoteAddr := range []string{"10.0.0.1:5000", "10.0.0.2:5000"} {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/metadata", nil)
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		if recorder.Code != http.StatusOK {
+			t.Errorf("Expected the first request from %s to pass, got %d", remoteAddr, recorder.Code)
+		}
+	}
+}
+
+// TestMiddleware_PassThrough verifies paths outside /fhir/, unlimited groups, and store failures are not limited
+func TestMiddleware_PassThrough(t *testing.T) {
+	handler := newTestLimiter(NewMemoryStore()).Middleware(okHandler)
+	for attempt := 0; attempt < 3; attempt++ {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, clientRequest(http.MethodGet, "/health", "oauth:app"))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected /health never to be limited, got %d", recorder.Code)
+		}
+	}
+
+	unlimited := NewLimiter(Config{}, NewMemoryStore()).Middleware(okHandler)
+	for attempt := 0; attempt < 3; attempt++ {
+		recorder := httptest.NewRecorder()
+		unlimited.ServeHTTP(recorder, clientRequest(http.MethodPost, "/fhir/Patient", "oauth:app"))
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Expected a zero rate to be unlimited, got %d", recorder.Code)
+		}
+	}
+
+	recorder := httptest.NewRecorder()
+	newTestLimiter(failingStore{}).Middleware(okHandler).ServeHTTP(recorder, clientRequest(http.MethodGet, "/fhir/Patient", "oauth:app"))
+	if recorder.Code != http.StatusOK {
+		t.Errorf("Expected requests through while the store is down, got %d", recorder.Code)
+	}
+}
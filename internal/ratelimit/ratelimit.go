@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"time"
+)
+
+// Route groups limited separately, so a client bulk-loading data cannot starve its own reads
+const (
+	GroupRead  = "read"
+	GroupWrite = "write"
+)
+
+// Rate is a token bucket: it refills at RequestsPerSecond up to Burst requests
+// A zero RequestsPerSecond leaves the group unlimited
+type Rate struct {
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	Burst             int     `json:"burst"`
+}
+
+// Unlimited reports whether the rate imposes no limit
+func (rate Rate) Unlimited() bool {
+	return rate.RequestsPerSecond <= 0
+}
+
+// capacity is the bucket size; a burst below one still lets a single request through
+func (rate Rate) capacity() float64 {
+	return math.Max(float64(rate.Burst), 1)
+}
+
+// Limits holds the rates of each route group
+type Limits struct {
+	Read  Rate `json:"read"`
+	Write Rate `json:"write"`
+}
+
+// RateFor returns the rate of the route group
+func (limits Limits) RateFor(group string) Rate {
+	if group == GroupWrite {
+		return limits.Write
+	}
+	return limits.Read
+}
+
+// Config holds default limits and per-client overrides
+// Clients are keyed by principal ID: "api-key:<fingerprint>" for API keys and "oauth:<client_id>" for tokens
+type Config struct {
+	Default Limits            `json:"default"`
+	Clients map[string]Limits `json:"clients"`
+}
+
+// LoadConfig reads a rate limit configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return config, fmt.Errorf("failed to read rate limit config: %w", readError)
+	}
+
+	if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+		return config, fmt.Errorf("failed to parse rate limit config: %w", decodeError)
+	}
+
+	return config, nil
+}
+
+// LimitsFor returns the client's override or the default limits
+func (config Config) LimitsFor(clientID string) Limits {
+	if clientLimits, exists := config.Clients[clientID]; exists {
+		return clientLimits
+	}
+	return config.Default
+}
+
+// Decision is the outcome of taking a token from a bucket
+type Decision struct {
+	// Allowed is true when a token was available and taken
+	Allowed bool
+
+	// Remaining is the whole number of tokens left after the decision
+	Remaining int
+
+	// RetryAfter is how long until a token is available again, set when the request is refused
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets
+// MemoryStore limits each server separately; RedisStore shares the buckets between servers
+type Store interface {
+	// Take takes one token from the bucket named by key, refilled at rate as of now
+	Take(ctx context.Context, key string, rate Rate, now time.Time) (Decision, error)
+}
+
+// retryAfter returns how long the bucket takes to refill from tokens to one whole token
+func retryAfter(tokens float64, rate Rate) time.Duration {
+	return time.Duration((1 - tokens) / rate.RequestsPerSecond * float64(time.Second))
+}
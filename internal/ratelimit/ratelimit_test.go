@@ -0,0 +1,38 @@
+package ratelimit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLoadConfig verifies default limits and client overrides are read from a JSON file
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "rate-limits.json")
+	os.WriteFile(configPath, []byte(`{
+		"default": {"read": {"requests_per_second": 20, "burst": 40}, "write": {"requests_per_second": 5, "burst": 10}},
+		"clients": {"oauth:bulk-loader": {"read": {"requests_per_second": 5, "burst": 5}, "write": {"requests_per_second": 50, "burst": 100}}}
+	}`), 0o600)
+
+	config, loadError := LoadConfig(configPath)
+	if loadError != nil {
+		t.Fatalf("Expected the config to load, got %v", loadError)
+	}
+	if config.LimitsFor("api-key:0a1b2c3d").RateFor(GroupWrite) != (Rate{RequestsPerSecond: 5, Burst: 10}) {
+		t.Errorf("Expected the default write rate, got %+v", config.LimitsFor("api-key:0a1b2c3d"))
+	}
+	if config.LimitsFor("oauth:bulk-loader").RateFor(GroupWrite).Burst != 100 {
+		t.Errorf("Expected the client's write override, got %+v", config.LimitsFor("oauth:bulk-loader"))
+	}
+
+	if _, loadError := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); loadError == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+// TestRate_Unlimited verifies a zero rate imposes no limit
+func TestRate_Unlimited(t *testing.T) {
+	if !(Rate{}).Unlimited() || (Rate{RequestsPerSecond: 1}).Unlimited() {
+		t.Error("Expected only a zero rate to be unlimited")
+	}
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisKeyPrefix namespaces the bucket keys in a shared Redis
+const redisKeyPrefix = "fhir:ratelimit:"
+
+// takeScript refills and takes from a bucket atomically, so servers sharing Redis never overspend a bucket
+// The bucket is a hash of tokens and updated_at (Unix milliseconds) that expires once it would be full again.
+// It returns whether a token was taken and the tokens left, as a string to keep the fraction
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call("HMGET", KEYS[1], "tokens", "updated_at")
+local tokens = tonumber(bucket[1])
+local updatedAt = tonumber(bucket[2])
+if tokens == nil or updatedAt == nil then
+  tokens = capacity
+  updatedAt = now
+end
+
+-- A server whose clock is behind must not take back tokens another server already added
+if now > updatedAt then
+  tokens = math.min(tokens + (now - updatedAt) / 1000 * rate, capacity)
+  updatedAt = now
+end
+
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "updated_at", tostring(updatedAt))
+redis.call("PEXPIRE", KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps token buckets in Redis so every server behind a load balancer shares one limit per client
+// Servers should keep their clocks synchronized, since each passes its own time to the script
+type RedisStore struct {
+	client redis.Scripter
+}
+
+// NewRedisStore creates a store over a Redis client
+func NewRedisStore(client redis.Scripter) *RedisStore {
+	return &RedisStore{
+		client: client,
+	}
+}
+
+// Take takes one token from the key's bucket, which starts full
+func (store *RedisStore) Take(ctx context.Context, key string, rate Rate, now time.Time) (Decision, error) {
+	result, scriptError := takeScript.Run(ctx, store.client, []string{redisKeyPrefix + key},
+		rate.RequestsPerSecond, rate.capacity(), now.UnixMilli()).Slice()
+	if scriptError != nil {
+		return Decision{}, fmt.Errorf("failed to take a rate limit token from Redis: %w", scriptError)
+	}
+	if len(result) != 2 {
+		return Decision{}, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+
+	allowed, _ := result[0].(int64)
+	rawTokens, _ := result[1].(string)
+	tokens, parseError := strconv.ParseFloat(rawTokens, 64)
+	if parseError != nil {
+		return Decision{}, fmt.Errorf("unexpected rate limit token count %q: %w", rawTokens, parseError)
+	}
+
+	if allowed != 1 {
+		return Decision{RetryAfter: retryAfter(tokens, rate)}, nil
+	}
+	return Decision{Allowed: true, Remaining: int(tokens)}, nil
+}
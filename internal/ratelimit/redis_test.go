@@ -0,0 +1,56 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// TestRedisStore_Take verifies the shared bucket allows its burst, refuses the next request, refills, and expires
+func TestRedisStore_Take(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr()})
+	defer client.Close()
+
+	// Two stores over one Redis stand in for two servers
+	firstServer := NewRedisStore(client)
+	secondServer := NewRedisStore(client)
+	rate := Rate{RequestsPerSecond: 2, Burst: 2}
+	startedAt := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+
+	for _, store := range []*RedisStore{firstServer, secondServer} {
+		decision, takeError := store.Take(context.Background(), "write:client", rate, startedAt)
+		if takeError != nil || !decision.Allowed {
+			t.Fatalf("Expected the request within the burst, got %+v, %v", decision, takeError)
+		}
+	}
+
+	decision, _ := firstServer.Take(context.Background(), "write:client", rate, startedAt)
+	if decision.Allowed || decision.RetryAfter != 500*time.Millisecond {
+		t.Errorf("Expected a refusal shared across servers with a 500ms wait, got %+v", decision)
+	}
+
+	decision, _ = secondServer.Take(context.Background(), "write:client", rate, startedAt.Add(time.Second))
+	if !decision.Allowed || decision.Remaining != 1 {
+		t.Errorf("Expected two tokens back after a second, got %+v", decision)
+	}
+
+	if ttl := redisServer.TTL(redisKeyPrefix + "write:client"); ttl <= 0 || ttl > 2*time.Second {
+		t.Errorf("Expected the bucket to expire once it would be full, got a TTL of %v", ttl)
+	}
+}
+
+// TestRedisStore_Unavailable verifies an unreachable Redis is reported as an error
+func TestRedisStore_Unavailable(t *testing.T) {
+	redisServer := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: redisServer.Addr(), MaxRetries: -1})
+	defer client.Close()
+	redisServer.Close()
+
+	if _, takeError := NewRedisStore(client).Take(context.Background(), "read:client", Rate{RequestsPerSecond: 1, Burst: 1}, time.Now()); takeError == nil {
+		t.Error("Expected an error when Redis is down")
+	}
+}
@@ -0,0 +1,274 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrAlreadyRunning is returned when a run is requested while another is in progress
+var ErrAlreadyRunning = errors.New("reconciliation is already running")
+
+// PatientIDSource lists the patients stored in PostgreSQL
+type PatientIDSource interface {
+	ListIDs(ctx context.Context) ([]string, error)
+	Exists(ctx context.Context, patientID string) (bool, error)
+}
+
+// ObservationStore counts and quarantines observations stored in MongoDB
+type ObservationStore interface {
+	CountByPatient(ctx context.Context) (map[string]int64, error)
+	QuarantineByPatient(ctx context.Context, patientID string) ([]string, error)
+}
+
+// Ledger provides the expected observation count per patient and is kept in step with quarantines
+type Ledger interface {
+	ExpectedCounts(ctx context.Context) (map[string]int64, error)
+	Adjust(ctx context.Context, patientID string, delta int64) error
+}
+
+// OrphanGroup is a set of observations referencing a patient that does not exist
+type OrphanGroup struct {
+	PatientID        string `json:"patient_id"`
+	ObservationCount int64  `json:"observation_count"`
+}
+
+// CountMismatch is a patient whose stored observation count differs from the ledger
+type CountMismatch struct {
+	PatientID string `json:"patient_id"`
+	Expected  int64  `json:"expected"`
+	Actual    int64  `json:"actual"`
+}
+
+// Report summarizes one reconciliation run
+type Report struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+
+	// PatientsChecked is the number of patients in PostgreSQL
+	PatientsChecked int `json:"patients_checked"`
+
+	// ObservationsChecked is the number of observations in MongoDB at the start of the run
+	ObservationsChecked int64 `json:"observations_checked"`
+
+	Orphans         []OrphanGroup   `json:"orphans"`
+	CountMismatches []CountMismatch `json:"count_mismatches"`
+
+	// Quarantined is the number of orphaned observations moved out of circulation
+	Quarantined int64 `json:"quarantined"`
+
+	// Error is set when the run could not complete
+	Error string `json:"error,omitempty"`
+}
+
+// Healthy reports whether the run completed without finding drift
+func (report *Report) Healthy() bool {
+	return report.Error == "" && len(report.Orphans) == 0 && len(report.CountMismatches) == 0
+}
+
+// Reconciler cross-checks observations in MongoDB against patients in PostgreSQL
+type Reconciler struct {
+	patients     PatientIDSource
+	observations ObservationStore
+	ledger       Ledger
+	quarantine   bool
+
+	mutex      sync.Mutex
+	running    bool
+	lastReport *Report
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewReconciler creates a reconciler; ledger may be nil to skip count verification
+// When quarantine is true, orphaned observations are moved to the quarantine collection
+func NewReconciler(patients PatientIDSource, observations ObservationStore, ledger Ledger, quarantine bool) *Reconciler {
+	return &Reconciler{
+		patients:     patients,
+		observations: observations,
+		ledger:       ledger,
+		quarantine:   quarantine,
+		now:          time.Now,
+	}
+}
+
+// Run performs one reconciliation and stores its report as the latest
+func (reconciler *Reconciler) Run(ctx context.Context) (*Report, error) {
+	reconciler.mutex.Lock()
+	if reconciler.running {
+		reconciler.mutex.Unlock()
+		return nil, ErrAlreadyRunning
+	}
+	reconciler.running = true
+	reconciler.mutex.Unlock()
+
+	report := &Report{StartedAt: reconciler.now(), Orphans: []OrphanGroup{}, CountMismatches: []CountMismatch{}}
+	runError := reconciler.check(ctx, report)
+	if runError != nil {
+		report.Error = runError.Error()
+	}
+	report.FinishedAt = reconciler.now()
+	logReport(report)
+
+	reconciler.mutex.Lock()
+	reconciler.running = false
+	reconciler.lastReport = report
+	reconciler.mutex.Unlock()
+
+	return report, runError
+}
+
+// LastReport returns the most recent report, or nil before the first run
+func (reconciler *Reconciler) LastReport() *Report {
+	reconciler.mutex.Lock()
+	defer reconciler.mutex.Unlock()
+	return reconciler.lastReport
+}
+
+// check fills the report with orphans and count mismatches
+func (reconciler *Reconciler) check(ctx context.Context, report *Report) error {
+	patientIDs, listError := reconciler.patients.ListIDs(ctx)
+	if listError != nil {
+		return listError
+	}
+	report.PatientsChecked = len(patientIDs)
+
+	knownPatients := make(map[string]bool, len(patientIDs))
+	for _, patientID := range patientIDs {
+		knownPatients[patientID] = true
+	}
+
+	actualCounts, countError := reconciler.observations.CountByPatient(ctx)
+	if countError != nil {
+		return countError
+	}
+
+	orphanIDs := []string{}
+	for patientID, observationCount := range actualCounts {
+		report.ObservationsChecked += observationCount
+
+		// Observations without a subject are not tied to a patient and cannot be orphaned
+		if patientID == "" || knownPatients[patientID] {
+			continue
+		}
+		report.Orphans = append(report.Orphans, OrphanGroup{PatientID: patientID, ObservationCount: observationCount})
+		orphanIDs = append(orphanIDs, patientID)
+	}
+	sort.Slice(report.Orphans, func(left, right int) bool {
+		return report.Orphans[left].PatientID < report.Orphans[right].PatientID
+	})
+
+	if reconciler.ledger != nil {
+		expectedCounts, ledgerError := reconciler.ledger.ExpectedCounts(ctx)
+		if ledgerError != nil {
+			return ledgerError
+		}
+		report.CountMismatches = compareCounts(expectedCounts, actualCounts)
+	}
+
+	// Quarantine last so the report reflects what was found before anything moved
+	if reconciler.quarantine {
+		sort.Strings(orphanIDs)
+		for _, orphanID := range orphanIDs {
+			if quarantineError := reconciler.quarantineOrphan(ctx, orphanID, report); quarantineError != nil {
+				return quarantineError
+			}
+		}
+	}
+
+	return nil
+}
+
+// quarantineOrphan moves an orphan patient's observations out of circulation and removes them from the ledger
+// The patient is checked again first: it may have been created since the patient list was read
+func (reconciler *Reconciler) quarantineOrphan(ctx context.Context, patientID string, report *Report) error {
+	exists, existsError := reconciler.patients.Exists(ctx, patientID)
+	if existsError != nil {
+		return existsError
+	}
+	if exists {
+		log.Info().Str("patient_id", patientID).Msg("Skipping quarantine: patient was created during reconciliation")
+		return nil
+	}
+
+	quarantinedIDs, quarantineError := reconciler.observations.QuarantineByPatient(ctx, patientID)
+	quarantined := int64(len(quarantinedIDs))
+	report.Quarantined += quarantined
+	if quarantined > 0 && reconciler.ledger != nil {
+		if adjustError := reconciler.ledger.Adjust(ctx, patientID, -quarantined); adjustError != nil {
+			return adjustError
+		}
+	}
+	return quarantineError
+}
+
+// compareCounts returns every patient whose expected and actual counts differ, sorted by patient ID
+func compareCounts(expectedCounts map[string]int64, actualCounts map[string]int64) []CountMismatch {
+	mismatches := []CountMismatch{}
+
+	for patientID, expected := range expectedCounts {
+		if actual := actualCounts[patientID]; actual != expected {
+			mismatches = append(mismatches, CountMismatch{PatientID: patientID, Expected: expected, Actual: actual})
+		}
+	}
+	for patientID, actual := range actualCounts {
+		if _, inLedger := expectedCounts[patientID]; !inLedger && patientID != "" {
+			mismatches = append(mismatches, CountMismatch{PatientID: patientID, Expected: 0, Actual: actual})
+		}
+	}
+
+	sort.Slice(mismatches, func(left, right int) bool {
+		return mismatches[left].PatientID < mismatches[right].PatientID
+	})
+	return mismatches
+}
+
+// logReport writes a summary line, at warn level when drift was found
+func logReport(report *Report) {
+	logEvent := log.Info()
+	if !report.Healthy() {
+		logEvent = log.Warn()
+	}
+
+	logEvent.
+		Int("patients_checked", report.PatientsChecked).
+		Int64("observations_checked", report.ObservationsChecked).
+		Int("orphan_patients", len(report.Orphans)).
+		Int("count_mismatches", len(report.CountMismatches)).
+		Int64("quarantined", report.Quarantined).
+		Str("error", report.Error).
+		Dur("duration", report.FinishedAt.Sub(report.StartedAt)).
+		Msg("Integrity reconciliation finished")
+}
+
+// RunDaily runs the reconciler every day at the given UTC hour until ctx is cancelled
+func (reconciler *Reconciler) RunDaily(ctx context.Context, hourUTC int) {
+	for {
+		delay := NextRun(reconciler.now(), hourUTC).Sub(reconciler.now())
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			// Errors are captured in the report and logged by Run
+			reconciler.Run(ctx)
+		}
+	}
+}
+
+// NextRun returns the next time at the given UTC hour strictly after now
+func NextRun(now time.Time, hourUTC int) time.Time {
+	utcNow := now.UTC()
+	nextRun := time.Date(utcNow.Year(), utcNow.Month(), utcNow.Day(), hourUTC, 0, 0, 0, time.UTC)
+	if !nextRun.After(utcNow) {
+		nextRun = nextRun.AddDate(0, 0, 1)
+	}
+	return nextRun
+}
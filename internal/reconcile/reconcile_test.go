@@ -0,0 +1,167 @@
+package reconcile
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakePatients returns a fixed set of patient IDs, plus patients created after the list was read
+type fakePatients struct {
+	patientIDs   []string
+	createdLater []string
+	listError    error
+}
+
+// ListIDs returns the configured IDs
+func (patients *fakePatients) ListIDs(ctx context.Context) ([]string, error) {
+	return patients.patientIDs, patients.listError
+}
+
+// Exists reports whether the patient was listed or created later
+func (patients *fakePatients) Exists(ctx context.Context, patientID string) (bool, error) {
+	return slices.Contains(patients.patientIDs, patientID) || slices.Contains(patients.createdLater, patientID), nil
+}
+
+// fakeObservations returns fixed counts and records quarantine requests
+type fakeObservations struct {
+	counts      map[string]int64
+	quarantined []string
+}
+
+// CountByPatient returns the configured counts
+func (observations *fakeObservations) CountByPatient(ctx context.Context) (map[string]int64, error) {
+	return observations.counts, nil
+}
+
+// QuarantineByPatient records the patient and reports an ID for each of its observations
+func (observations *fakeObservations) QuarantineByPatient(ctx context.Context, patientID string) ([]string, error) {
+	observations.quarantined = append(observations.quarantined, patientID)
+	quarantinedIDs := []string{}
+	for index := range observations.counts[patientID] {
+		quarantinedIDs = append(quarantinedIDs, fmt.Sprintf("%s-%d", patientID, index))
+	}
+	return quarantinedIDs, nil
+}
+
+// fakeLedger returns fixed expected counts and applies adjustments
+type fakeLedger map[string]int64
+
+// ExpectedCounts returns the ledger contents
+func (ledger fakeLedger) ExpectedCounts(ctx context.Context) (map[string]int64, error) {
+	return ledger, nil
+}
+
+// Adjust applies a count change
+func (ledger fakeLedger) Adjust(ctx context.Context, patientID string, delta int64) error {
+	ledger[patientID] += delta
+	return nil
+}
+
+// TestReconciler_ReportsOrphansAndMismatches verifies drift detection without quarantine
+func TestReconciler_ReportsOrphansAndMismatches(t *testing.T) {
+	observations := &fakeObservations{counts: map[string]int64{"p1": 3, "p2": 1, "ghost": 2, "": 4}}
+	ledger := fakeLedger{"p1": 3, "p2": 2, "p3": 1}
+	reconciler := NewReconciler(&fakePatients{patientIDs: []string{"p1", "p2", "p3"}}, observations, ledger, false)
+
+	report, runError := reconciler.Run(context.Background())
+	if runError != nil {
+		t.Fatalf("Expected no error, got %v", runError)
+	}
+
+	if report.PatientsChecked != 3 || report.ObservationsChecked != 10 {
+		t.Errorf("Unexpected totals: %+v", report)
+	}
+	if len(report.Orphans) != 1 || report.Orphans[0] != (OrphanGroup{PatientID: "ghost", ObservationCount: 2}) {
+		t.Errorf("Unexpected orphans: %+v", report.Orphans)
+	}
+
+	expectedMismatches := []CountMismatch{
+		{PatientID: "ghost", Expected: 0, Actual: 2},
+		{PatientID: "p2", Expected: 2, Actual: 1},
+		{PatientID: "p3", Expected: 1, Actual: 0},
+	}
+	if len(report.CountMismatches) != len(expectedMismatches) {
+		t.Fatalf("Expected %d mismatches, got %+v", len(expectedMismatches), report.CountMismatches)
+	}
+	for index, mismatch := range report.CountMismatches {
+		if mismatch != expectedMismatches[index] {
+			t.Errorf("Expected %+v, got %+v", expectedMismatches[index], mismatch)
+		}
+	}
+
+	if len(observations.quarantined) != 0 || report.Healthy() {
+		t.Error("Expected no quarantine and an unhealthy report")
+	}
+	if reconciler.LastReport() != report {
+		t.Error("Expected report to be kept as the latest")
+	}
+}
+
+// TestReconciler_QuarantinesOrphans verifies orphans are moved when quarantine is enabled
+func TestReconciler_QuarantinesOrphans(t *testing.T) {
+	observations := &fakeObservations{counts: map[string]int64{"p1": 1, "ghost": 2}}
+	reconciler := NewReconciler(&fakePatients{patientIDs: []string{"p1"}}, observations, nil, true)
+
+	report, _ := reconciler.Run(context.Background())
+
+	if report.Quarantined != 2 || len(observations.quarantined) != 1 || observations.quarantined[0] != "ghost" {
+		t.Errorf("Expected ghost's observations quarantined, got %+v / %v", report, observations.quarantined)
+	}
+	if len(report.CountMismatches) != 0 {
+		t.Error("Expected no count verification without a ledger")
+	}
+}
+
+// TestReconciler_QuarantineRechecksAndUpdatesLedger verifies patients created mid-run are left alone
+// and quarantined observations are removed from the ledger
+func TestReconciler_QuarantineRechecksAndUpdatesLedger(t *testing.T) {
+	observations := &fakeObservations{counts: map[string]int64{"ghost": 2, "new-patient": 1}}
+	ledger := fakeLedger{"ghost": 2, "new-patient": 1}
+	patients := &fakePatients{createdLater: []string{"new-patient"}}
+	reconciler := NewReconciler(patients, observations, ledger, true)
+
+	report, runError := reconciler.Run(context.Background())
+	if runError != nil {
+		t.Fatalf("Expected no error, got %v", runError)
+	}
+
+	if len(observations.quarantined) != 1 || observations.quarantined[0] != "ghost" || report.Quarantined != 2 {
+		t.Errorf("Expected only ghost to be quarantined, got %v (%d)", observations.quarantined, report.Quarantined)
+	}
+	if ledger["ghost"] != 0 || ledger["new-patient"] != 1 {
+		t.Errorf("Expected ghost's ledger count to drop to 0, got %v", ledger)
+	}
+}
+
+// TestReconciler_RecordsErrors verifies a failed run is reported
+func TestReconciler_RecordsErrors(t *testing.T) {
+	reconciler := NewReconciler(&fakePatients{listError: errors.New("postgres down")}, &fakeObservations{}, nil, false)
+
+	report, runError := reconciler.Run(context.Background())
+
+	if runError == nil || report.Error != "postgres down" || report.Healthy() {
+		t.Errorf("Expected failed report, got %+v", report)
+	}
+}
+
+// TestNextRun verifies scheduling at the configured UTC hour
+func TestNextRun(t *testing.T) {
+	testCases := []struct {
+		now      time.Time
+		expected time.Time
+	}{
+		{time.Date(2024, 6, 1, 1, 30, 0, 0, time.UTC), time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 6, 1, 2, 0, 0, 0, time.UTC), time.Date(2024, 6, 2, 2, 0, 0, 0, time.UTC)},
+		{time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC), time.Date(2025, 1, 1, 2, 0, 0, 0, time.UTC)},
+	}
+
+	for _, testCase := range testCases {
+		if nextRun := NextRun(testCase.now, 2); !nextRun.Equal(testCase.expected) {
+			t.Errorf("NextRun(%s) = %s, expected %s", testCase.now, nextRun, testCase.expected)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// DefaultReplacement replaces every redacted value
+const DefaultReplacement = "[REDACTED]"
+
+// DefaultFields are the JSON fields redacted at any depth: names, birth dates, identifiers, contact details,
+// addresses, photos, and the narrative div that repeats them
+var DefaultFields = []string{
+	"name", "given", "family", "birthDate", "identifier", "address", "telecom", "contact", "photo", "div",
+}
+
+// DefaultParameters are the search parameters whose values are redacted from logged query strings
+var DefaultParameters = []string{
+	"name", "given", "family", "birthdate", "identifier", "address", "address-city", "address-postalcode",
+	"phone", "email", "telecom",
+}
+
+// Config selects what the redactor removes
+type Config struct {
+	// Fields are JSON field names redacted wherever they appear; they replace DefaultFields when set
+	Fields []string `json:"fields"`
+
+	// AdditionalFields are redacted on top of Fields, for site-specific extensions
+	AdditionalFields []string `json:"additional_fields"`
+
+	// Parameters are query parameters whose values are redacted; they replace DefaultParameters when set
+	Parameters []string `json:"parameters"`
+
+	// Replacement replaces each redacted value; DefaultReplacement when empty
+	Replacement string `json:"replacement"`
+}
+
+// LoadConfig reads a redaction configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return config, fmt.Errorf("failed to read redaction config: %w", readError)
+	}
+	if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+		return config, fmt.Errorf("failed to parse redaction config: %w", decodeError)
+	}
+	return config, nil
+}
+
+// Redactor removes protected health information from JSON bodies and query strings before they are logged
+// Reference displays are redacted too, because they usually repeat the referenced patient's name
+type Redactor struct {
+	fields      map[string]bool
+	parameters  map[string]bool
+	replacement string
+}
+
+// NewRedactor creates a redactor from the config, using the defaults for anything left empty
+func NewRedactor(config Config) *Redactor {
+	fields := DefaultFields
+	if len(config.Fields) > 0 {
+		fields = config.Fields
+	}
+	parameters := DefaultParameters
+	if len(config.Parameters) > 0 {
+		parameters = config.Parameters
+	}
+	replacement := DefaultReplacement
+	if config.Replacement != "" {
+		replacement = config.Replacement
+	}
+
+	redactor := &Redactor{
+		fields:      make(map[string]bool),
+		parameters:  make(map[string]bool),
+		replacement: replacement,
+	}
+	for _, field := range slices.Concat(fields, config.AdditionalFields) {
+		redactor.fields[field] = true
+	}
+	for _, parameter := range parameters {
+		redactor.parameters[parameter] = true
+	}
+	return redactor
+}
+
+// RedactJSON returns the JSON document with every configured field replaced, or false when the body is not JSON
+// Bodies that cannot be parsed are never returned, since they cannot be checked for protected data
+func (redactor *Redactor) RedactJSON(body []byte) (json.RawMessage, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var document interface{}
+	if decodeError := decoder.Decode(&document); decodeError != nil || decoder.More() {
+		return nil, false
+	}
+
+	redactedBody, encodeError := json.Marshal(redactor.redactValue(document))
+	if encodeError != nil {
+		return nil, false
+	}
+	return redactedBody, true
+}
+
+// RedactNDJSON redacts each line of a newline-delimited JSON body, or returns false when a line is not JSON
+func (redactor *Redactor) RedactNDJSON(body []byte) ([]json.RawMessage, bool) {
+	redactedLines := []json.RawMessage{}
+	for _, line := range bytes.Split(body, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		redactedLine, redacted := redactor.RedactJSON(line)
+		if !redacted {
+			return nil, false
+		}
+		redactedLines = append(redactedLines, redactedLine)
+	}
+	return redactedLines, true
+}
+
+// RedactQuery returns the query string with the values of protected parameters replaced
+// Modifiers such as name:exact are matched on the parameter name before the colon
+func (redactor *Redactor) RedactQuery(query url.Values) string {
+	redactedQuery := url.Values{}
+	for parameter, values := range query {
+		parameterName, _, _ := strings.Cut(parameter, ":")
+		if !redactor.parameters[parameterName] {
+			redactedQuery[parameter] = values
+			continue
+		}
+		for range values {
+			redactedQuery.Add(parameter, redactor.replacement)
+		}
+	}
+	return redactedQuery.Encode()
+}
+
+// redactValue walks a decoded JSON value, replacing configured fields in every object
+// URLs such as Bundle links and transaction entry requests keep their path but have their search values redacted
+func (redactor *Redactor) redactValue(value interface{}) interface{} {
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		_, isReference := typedValue["reference"]
+		for field, fieldValue := range typedValue {
+			if redactor.fields[field] || (isReference && field == "display") {
+				typedValue[field] = redactor.replacement
+				continue
+			}
+			if rawURL, isString := fieldValue.(string); isString && field == "url" {
+				typedValue[field] = redactor.redactURL(rawURL)
+				continue
+			}
+			typedValue[field] = redactor.redactValue(fieldValue)
+		}
+		return typedValue
+	case []interface{}:
+		for index, element := range typedValue {
+			typedValue[index] = redactor.redactValue(element)
+		}
+		return typedValue
+	default:
+		return value
+	}
+}
+
+// redactURL redacts the protected search values in a URL's query string
+func (redactor *Redactor) redactURL(rawURL string) string {
+	path, rawQuery, hasQuery := strings.Cut(rawURL, "?")
+	if !hasQuery {
+		return rawURL
+	}
+	query, parseError := url.ParseQuery(rawQuery)
+	if parseError != nil {
+		return path + "?" + redactor.replacement
+	}
+	return path + "?" + redactor.RedactQuery(query)
+}
@@ -0,0 +1,114 @@
+package redact
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRedactor_RedactJSON verifies protected fields are replaced at any depth while clinical data is kept
+func TestRedactor_RedactJSON(t *testing.T) {
+	redactor := NewRedactor(Config{})
+	body := `{"resourceType":"Patient","id":"p-1","gender":"female","birthDate":"1980-02-03",
+		"name":[{"family":"Smith","given":["Jane"]}],
+		"identifier":[{"system":"urn:mrn","value":"12345"}],
+		"generalPractitioner":[{"reference":"Practitioner/1","display":"Dr. Adams"}],
+		"text":{"status":"generated","div":"<div>Jane Smith</div>"},
+		"extension":[{"url":"http://example.org/score","valueDecimal":1.50}]}`
+
+	redactedBody, redacted := redactor.RedactJSON([]byte(body))
+	if !redacted {
+		t.Fatal("Expected a JSON body to be redacted")
+	}
+	for _, protectedValue := range []string{"Smith", "Jane", "1980-02-03", "12345", "Dr. Adams"} {
+		if strings.Contains(string(redactedBody), protectedValue) {
+			t.Errorf("Expected %q to be redacted, got %s", protectedValue, redactedBody)
+		}
+	}
+
+	var document map[string]interface{}
+	json.Unmarshal(redactedBody, &document)
+	if document["id"] != "p-1" || document["gender"] != "female" || document["name"] != DefaultReplacement {
+		t.Errorf("Expected the id and gender kept and the name replaced, got %s", redactedBody)
+	}
+	if !strings.Contains(string(redactedBody), `"valueDecimal":1.50`) {
+		t.Errorf("Expected numbers to keep their precision, got %s", redactedBody)
+	}
+}
+
+// TestRedactor_RedactJSON_NotJSON verifies bodies that cannot be parsed are refused rather than passed through
+func TestRedactor_RedactJSON_NotJSON(t *testing.T) {
+	redactor := NewRedactor(Config{})
+	for _, body := range []string{"MSH|^~\\&|LAB|Smith^Jane", `{"name":"Smith"} trailing`, ""} {
+		if _, redacted := redactor.RedactJSON([]byte(body)); redacted {
+			t.Errorf("Expected %q to be refused", body)
+		}
+	}
+}
+
+// TestRedactor_RedactJSON_BundleURLs verifies search values in Bundle links keep the path but lose protected values
+func TestRedactor_RedactJSON_BundleURLs(t *testing.T) {
+	redactor := NewRedactor(Config{})
+	body := `{"resourceType":"Bundle","link":[{"relation":"self","url":"http://localhost/fhir/Patient?family=Smith&_count=10"}]}`
+
+	redactedBody, _ := redactor.RedactJSON([]byte(body))
+	if strings.Contains(string(redactedBody), "Smith") || !strings.Contains(string(redactedBody), "_count=10") {
+		t.Errorf("Expected only the family value redacted from the link, got %s", redactedBody)
+	}
+}
+
+// TestRedactor_RedactNDJSON verifies each line is redacted and a line that is not JSON refuses the whole body
+func TestRedactor_RedactNDJSON(t *testing.T) {
+	redactor := NewRedactor(Config{})
+
+	redactedLines, redacted := redactor.RedactNDJSON([]byte("{\"id\":\"1\",\"name\":\"Smith\"}\n{\"id\":\"2\",\"name\":\"Jones\"}\n"))
+	if !redacted || len(redactedLines) != 2 || strings.Contains(string(redactedLines[1]), "Jones") {
+		t.Errorf("Expected two redacted lines, got %s", redactedLines)
+	}
+
+	if _, redacted := redactor.RedactNDJSON([]byte("{\"id\":\"1\"}\nSmith\n")); redacted {
+		t.Error("Expected a body with a line that is not JSON to be refused")
+	}
+}
+
+// TestRedactor_RedactQuery verifies protected search values are replaced, including with modifiers
+func TestRedactor_RedactQuery(t *testing.T) {
+	redactor := NewRedactor(Config{})
+	query := url.Values{"name:exact": {"Smith"}, "birthdate": {"1980-02-03"}, "gender": {"female"}}
+
+	redactedQuery := redactor.RedactQuery(query)
+	if strings.Contains(redactedQuery, "Smith") || strings.Contains(redactedQuery, "1980") || !strings.Contains(redactedQuery, "gender=female") {
+		t.Errorf("Expected the name and birthdate redacted and the gender kept, got %s", redactedQuery)
+	}
+}
+
+// TestNewRedactor_Config verifies configured fields, additional fields, and the replacement are used
+func TestNewRedactor_Config(t *testing.T) {
+	redactor := NewRedactor(Config{Fields: []string{"note"}, AdditionalFields: []string{"mrn"}, Replacement: "***"})
+
+	redactedBody, _ := redactor.RedactJSON([]byte(`{"name":"Smith","note":"called Jane","mrn":"42"}`))
+	if string(redactedBody) != `{"mrn":"***","name":"Smith","note":"***"}` {
+		t.Errorf("Expected only the configured fields replaced, got %s", redactedBody)
+	}
+}
+
+// TestLoadConfig verifies a redaction config is read from a JSON file
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "redaction.json")
+	os.WriteFile(configPath, []byte(`{"additional_fields":["mrn"],"replacement":"***"}`), 0o600)
+
+	config, loadError := LoadConfig(configPath)
+	if loadError != nil {
+		t.Fatalf("Expected the config to load, got %v", loadError)
+	}
+	if len(config.AdditionalFields) != 1 || config.Replacement != "***" {
+		t.Errorf("Expected the additional field and replacement, got %+v", config)
+	}
+
+	if _, loadError := LoadConfig(filepath.Join(t.TempDir(), "missing.json")); loadError == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
@@ -0,0 +1,178 @@
+package referencerange
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// InterpretationSystem is the code system of computed interpretations
+const InterpretationSystem = "http://terminology.hl7.org/CodeSystem/v3-ObservationInterpretation"
+
+// Interpretation codes given to values compared against a reference range
+const (
+	// LowCode marks a value below the range
+	LowCode = "L"
+
+	// NormalCode marks a value within the range
+	NormalCode = "N"
+
+	// HighCode marks a value above the range
+	HighCode = "H"
+)
+
+// defaultRangesJSON holds the built-in adult reference ranges
+//
+//go:embed ranges.json
+var defaultRangesJSON []byte
+
+// Range is the reference range of one observation code
+type Range struct {
+	// Code is the observation code the range applies to (e.g. LOINC "2339-0")
+	Code string `json:"code"`
+
+	// Display names the measurement in logs
+	Display string `json:"display"`
+
+	// Low and High bound the normal values, inclusive; a missing bound leaves that side open
+	Low  *float64 `json:"low"`
+	High *float64 `json:"high"`
+
+	// Unit is the unit the range is expressed in; values sent in another unit are not interpreted
+	Unit string `json:"unit"`
+
+	// Aliases are other spellings of Unit that clients send (e.g. "mmHg" for "mm[Hg]")
+	Aliases []string `json:"aliases"`
+}
+
+// Ranges configures the reference ranges
+type Ranges struct {
+	Ranges []Range `json:"ranges"`
+}
+
+// DefaultRanges returns the built-in adult reference ranges
+func DefaultRanges() *Ranges {
+	ranges := &Ranges{}
+	if decodeError := json.Unmarshal(defaultRangesJSON, ranges); decodeError != nil {
+		panic(fmt.Sprintf("embedded reference ranges are invalid: %v", decodeError))
+	}
+	return ranges
+}
+
+// LoadRanges reads site ranges from a JSON file and merges them over the built-in ones
+// A site range replaces the built-in range for the same code; other built-in ranges are kept
+func LoadRanges(path string) (*Ranges, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read reference ranges: %w", readError)
+	}
+
+	siteRanges := &Ranges{}
+	if decodeError := json.Unmarshal(fileBytes, siteRanges); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse reference ranges: %w", decodeError)
+	}
+
+	ranges := DefaultRanges().Merge(siteRanges)
+	if validateError := ranges.Validate(); validateError != nil {
+		return nil, validateError
+	}
+
+	return ranges, nil
+}
+
+// Merge returns the ranges with the site's ranges replacing those for the same code
+func (ranges *Ranges) Merge(siteRanges *Ranges) *Ranges {
+	merged := &Ranges{}
+
+	siteCodes := make(map[string]bool, len(siteRanges.Ranges))
+	for _, siteRange := range siteRanges.Ranges {
+		siteCodes[siteRange.Code] = true
+	}
+	for _, builtInRange := range ranges.Ranges {
+		if !siteCodes[builtInRange.Code] {
+			merged.Ranges = append(merged.Ranges, builtInRange)
+		}
+	}
+	merged.Ranges = append(merged.Ranges, siteRanges.Ranges...)
+
+	return merged
+}
+
+// Validate checks the ranges for bounds the interpreter cannot apply
+func (ranges *Ranges) Validate() error {
+	for index, referenceRange := range ranges.Ranges {
+		if referenceRange.Code == "" {
+			return fmt.Errorf("reference ranges[%d] must set code", index)
+		}
+		if referenceRange.Low == nil && referenceRange.High == nil {
+			return fmt.Errorf("reference range for %s must set low, high, or both", referenceRange.Code)
+		}
+		if referenceRange.Low != nil && referenceRange.High != nil && *referenceRange.Low > *referenceRange.High {
+			return fmt.Errorf("reference range for %s has low %v above high %v", referenceRange.Code, *referenceRange.Low, *referenceRange.High)
+		}
+	}
+	return nil
+}
+
+// acceptsUnit reports whether a value's unit matches the range's; a missing unit is assumed to match
+func (referenceRange Range) acceptsUnit(unit string) bool {
+	return unit == "" || referenceRange.Unit == "" || unit == referenceRange.Unit || slices.Contains(referenceRange.Aliases, unit)
+}
+
+// Interpreter computes interpretations of observation values from reference ranges
+type Interpreter struct {
+	rangesByCode map[string]Range
+}
+
+// NewInterpreter creates an interpreter for validated ranges
+func NewInterpreter(ranges *Ranges) *Interpreter {
+	rangesByCode := make(map[string]Range, len(ranges.Ranges))
+	for _, referenceRange := range ranges.Ranges {
+		rangesByCode[referenceRange.Code] = referenceRange
+	}
+	return &Interpreter{rangesByCode: rangesByCode}
+}
+
+// Interpret returns the interpretation of the observation's value, and false when it cannot be interpreted
+// The observation's own numeric reference range in the value's unit is used first, since the lab that
+// measured the value knows its range best; otherwise the configured range for the code, compared with the
+// value as sent or, when the range is in the value's canonical unit, with the canonical value
+func (interpreter *Interpreter) Interpret(observation *models.Observation) (*models.Interpretation, bool) {
+	if observation.ValueQuantity == nil {
+		return nil, false
+	}
+
+	for _, observedRange := range observation.ReferenceRanges {
+		if (observedRange.Low != nil || observedRange.High != nil) && (observedRange.Unit == "" || observation.ValueUnit == "" || observedRange.Unit == observation.ValueUnit) {
+			return interpretation(*observation.ValueQuantity, observedRange.Low, observedRange.High), true
+		}
+	}
+
+	configuredRange, exists := interpreter.rangesByCode[observation.Code]
+	if !exists {
+		return nil, false
+	}
+	if configuredRange.acceptsUnit(observation.ValueUnit) {
+		return interpretation(*observation.ValueQuantity, configuredRange.Low, configuredRange.High), true
+	}
+	if observation.CanonicalValue != nil && configuredRange.acceptsUnit(observation.CanonicalUnit) {
+		return interpretation(*observation.CanonicalValue, configuredRange.Low, configuredRange.High), true
+	}
+	return nil, false
+}
+
+// interpretation compares a value against inclusive bounds, either of which may be open
+func interpretation(value float64, low *float64, high *float64) *models.Interpretation {
+	switch {
+	case low != nil && value < *low:
+		return &models.Interpretation{System: InterpretationSystem, Code: LowCode, Display: "Low"}
+	case high != nil && value > *high:
+		return &models.Interpretation{System: InterpretationSystem, Code: HighCode, Display: "High"}
+	default:
+		return &models.Interpretation{System: InterpretationSystem, Code: NormalCode, Display: "Normal"}
+	}
+}
@@ -0,0 +1,97 @@
+package referencerange
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// floatPointer returns a pointer to value
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+// TestDefaultRanges_Validate verifies the built-in ranges are consistent
+func TestDefaultRanges_Validate(t *testing.T) {
+	if validateError := DefaultRanges().Validate(); validateError != nil {
+		t.Fatalf("Expected the built-in reference ranges to be valid, got %v", validateError)
+	}
+}
+
+// TestInterpreter_Interpret verifies values are interpreted against the configured range for their code
+func TestInterpreter_Interpret(t *testing.T) {
+	interpreter := NewInterpreter(DefaultRanges())
+	testCases := []struct {
+		name         string
+		observation  *models.Observation
+		expectedCode string
+	}{
+		{name: "high glucose", observation: &models.Observation{Code: "2339-0", ValueQuantity: floatPointer(140), ValueUnit: "mg/dL"}, expectedCode: HighCode},
+		{name: "low glucose", observation: &models.Observation{Code: "2339-0", ValueQuantity: floatPointer(60), ValueUnit: "mg/dl"}, expectedCode: LowCode},
+		{name: "glucose at the bound", observation: &models.Observation{Code: "2339-0", ValueQuantity: floatPointer(99), ValueUnit: "mg/dL"}, expectedCode: NormalCode},
+		{name: "open low bound", observation: &models.Observation{Code: "2093-3", ValueQuantity: floatPointer(20), ValueUnit: "mg/dL"}, expectedCode: NormalCode},
+		{name: "canonical value", observation: &models.Observation{Code: "2339-0", ValueQuantity: floatPointer(7), ValueUnit: "mmol/L", CanonicalValue: floatPointer(126.1), CanonicalUnit: "mg/dL"}, expectedCode: HighCode},
+		{
+			name: "lab range first",
+			observation: &models.Observation{Code: "2339-0", ValueQuantity: floatPointer(105), ValueUnit: "mg/dL", ReferenceRanges: []models.ReferenceRange{
+				{Text: "fasting"},
+				{Low: floatPointer(70), High: floatPointer(140), Unit: "mg/dL"},
+			}},
+			expectedCode: NormalCode,
+		},
+	}
+	for _, testCase := range testCases {
+		interpretation, interpreted := interpreter.Interpret(testCase.observation)
+		if !interpreted || interpretation.Code != testCase.expectedCode || interpretation.System != InterpretationSystem {
+			t.Errorf("%s: expected %s, got %+v", testCase.name, testCase.expectedCode, interpretation)
+		}
+	}
+
+	uninterpreted := []*models.Observation{
+		{Code: "2339-0", ValueString: "positive"},
+		{Code: "2339-0", ValueQuantity: floatPointer(7), ValueUnit: "mmol/L"},
+		{Code: "1751-7", ValueQuantity: floatPointer(4), ValueUnit: "g/dL"},
+	}
+	for _, observation := range uninterpreted {
+		if interpretation, interpreted := interpreter.Interpret(observation); interpreted {
+			t.Errorf("Expected %+v not to be interpreted, got %+v", observation, interpretation)
+		}
+	}
+}
+
+// TestLoadRanges verifies site ranges replace built-in ones and invalid ranges are refused
+func TestLoadRanges(t *testing.T) {
+	sitePath := filepath.Join(t.TempDir(), "ranges.json")
+	os.WriteFile(sitePath, []byte(`{"ranges": [{"code": "2339-0", "display": "Glucose", "low": 70, "high": 140, "unit": "mg/dL"}]}`), 0o600)
+
+	ranges, loadError := LoadRanges(sitePath)
+	if loadError != nil {
+		t.Fatalf("Expected the site ranges to load, got %v", loadError)
+	}
+	interpretation, _ := NewInterpreter(ranges).Interpret(&models.Observation{Code: "2339-0", ValueQuantity: floatPointer(120), ValueUnit: "mg/dL"})
+	if interpretation.Code != NormalCode {
+		t.Errorf("Expected the site range to replace the built-in one, got %+v", interpretation)
+	}
+
+	invalidRanges := []string{
+		`{"ranges": [{"code": "2339-0", "low": 140, "high": 70}]}`,
+		`{"ranges": [{"code": "2339-0"}]}`,
+		`{"ranges": [{"low": 1}]}`,
+		`not json`,
+	}
+	for _, invalidRange := range invalidRanges {
+		os.WriteFile(sitePath, []byte(invalidRange), 0o600)
+		if _, invalidError := LoadRanges(sitePath); invalidError == nil {
+			t.Errorf("Expected %s to be refused", invalidRange)
+		}
+	}
+}
+
+// TestLoadRanges_ExampleFile verifies the shipped example ranges load
+func TestLoadRanges_ExampleFile(t *testing.T) {
+	if _, loadError := LoadRanges(filepath.Join("..", "..", "config", "reference-ranges.example.json")); loadError != nil {
+		t.Fatalf("Expected example ranges to load, got %v", loadError)
+	}
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AllergyIntoleranceRepository defines the interface for allergy intolerance data access
+type AllergyIntoleranceRepository interface {
+	// Create inserts a new allergy intolerance and returns it with its generated ID
+	Create(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error)
+
+	// GetByID retrieves an allergy intolerance by ID
+	GetByID(ctx context.Context, allergyIntoleranceID string) (*models.AllergyIntolerance, error)
+
+	// Search retrieves allergy intolerances matching the search criteria
+	Search(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) ([]*models.AllergyIntolerance, error)
+
+	// Count returns how many allergy intolerances match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) (int, error)
+
+	// Update replaces the allergy intolerance's content
+	Update(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error)
+
+	// Delete marks an allergy intolerance deleted by ID
+	Delete(ctx context.Context, allergyIntoleranceID string) error
+}
+
+// ErrAllergyIntoleranceNotFound is returned when no allergy intolerance has the requested ID
+var ErrAllergyIntoleranceNotFound = errors.New("allergy intolerance not found")
+
+// MongoAllergyIntoleranceRepository implements AllergyIntoleranceRepository using MongoDB
+type MongoAllergyIntoleranceRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoAllergyIntoleranceRepository creates a new MongoDB allergy intolerance repository
+func NewMongoAllergyIntoleranceRepository(database *mongo.Database) *MongoAllergyIntoleranceRepository {
+	return &MongoAllergyIntoleranceRepository{
+		collection: database.Collection("allergy_intolerances"),
+	}
+}
+
+// Create inserts a new allergy intolerance into MongoDB under a generated ID
+func (repository *MongoAllergyIntoleranceRepository) Create(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error) {
+	// The server assigns IDs, and timestamps are set on every insert
+	allergyIntolerance.ID = ""
+	allergyIntolerance.CreatedAt = time.Now()
+	allergyIntolerance.UpdatedAt = time.Now()
+
+	result, insertError := repository.collection.InsertOne(ctx, allergyIntolerance)
+	if insertError != nil {
+		return nil, fmt.Errorf("failed to insert allergy intolerance: %w", insertError)
+	}
+
+	// Set the generated ID
+	if objectID, ok := result.InsertedID.(primitive.ObjectID); ok {
+		allergyIntolerance.ID = objectID.Hex()
+	}
+
+	return allergyIntolerance, nil
+}
+
+// GetByID retrieves an allergy intolerance by ID
+// Returns ErrAllergyIntoleranceNotFound when the ID is malformed or no allergy intolerance has it, wrapped with ErrResourceDeleted when it was deleted
+func (repository *MongoAllergyIntoleranceRepository) GetByID(ctx context.Context, allergyIntoleranceID string) (*models.AllergyIntolerance, error) {
+	objectID, convertError := primitive.ObjectIDFromHex(allergyIntoleranceID)
+	if convertError != nil {
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrAllergyIntoleranceNotFound, allergyIntoleranceID)
+	}
+
+	var allergyIntolerance models.AllergyIntolerance
+	findError := repository.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&allergyIntolerance)
+	if errors.Is(findError, mongo.ErrNoDocuments) {
+		return nil, missingDocumentError(ctx, repository.collection, objectID, ErrAllergyIntoleranceNotFound)
+	}
+	if findError != nil {
+		return nil, fmt.Errorf("failed to find allergy intolerance: %w", findError)
+	}
+
+	return &allergyIntolerance, nil
+}
+
+// allergyIntoleranceSearchFilter builds the filter matching allergies on the search criteria, shared by
+// Search and Count so a page and its total always agree
+func allergyIntoleranceSearchFilter(searchParams *models.AllergyIntoleranceSearchParams) bson.M {
+	filter := bson.M{}
+
+	// Add patient ID filter
+	if searchParams.PatientID != "" {
+		filter["patient_id"] = searchParams.PatientID
+	}
+
+	// Add clinical status filter
+	if searchParams.ClinicalStatus != "" {
+		filter["clinical_status"] = searchParams.ClinicalStatus
+	}
+
+	// Add category filter; matching one element of the stored categories array is enough
+	if searchParams.Category != "" {
+		filter["categories"] = searchParams.Category
+	}
+
+	// Add criticality filter
+	if searchParams.Criticality != "" {
+		filter["criticality"] = searchParams.Criticality
+	}
+
+	return notDeleted(filter)
+}
+
+// Search retrieves allergy intolerances matching the search criteria, most recently recorded first
+func (repository *MongoAllergyIntoleranceRepository) Search(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) ([]*models.AllergyIntolerance, error) {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(searchParams.Limit)).
+		SetSkip(int64(searchParams.Offset))
+
+	cursor, findError := repository.collection.Find(ctx, allergyIntoleranceSearchFilter(searchParams), findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("failed to search allergy intolerances: %w", findError)
+	}
+	defer cursor.Close(ctx)
+
+	allergyIntolerances := make([]*models.AllergyIntolerance, 0)
+	if decodeError := cursor.All(ctx, &allergyIntolerances); decodeError != nil {
+		return nil, fmt.Errorf("failed to decode allergy intolerances: %w", decodeError)
+	}
+
+	return allergyIntolerances, nil
+}
+
+// Count returns how many allergy intolerances match the search criteria, ignoring the page's limit and offset
+func (repository *MongoAllergyIntoleranceRepository) Count(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) (int, error) {
+	total, countError := repository.collection.CountDocuments(ctx, allergyIntoleranceSearchFilter(searchParams))
+	if countError != nil {
+		return 0, countError
+	}
+	return int(total), nil
+}
+
+// Update replaces an existing allergy intolerance's content, keeping its creation time
+// Returns ErrAllergyIntoleranceNotFound when the ID is malformed or no allergy intolerance has it, wrapped with ErrResourceDeleted when it was deleted
+func (repository *MongoAllergyIntoleranceRepository) Update(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error) {
+	objectID, convertError := primitive.ObjectIDFromHex(allergyIntolerance.ID)
+	if convertError != nil {
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrAllergyIntoleranceNotFound, allergyIntolerance.ID)
+	}
+
+	allergyIntolerance.UpdatedAt = time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"patient_id":          allergyIntolerance.PatientID,
+			"clinical_status":     allergyIntolerance.ClinicalStatus,
+			"verification_status": allergyIntolerance.VerificationStatus,
+			"type":                allergyIntolerance.Type,
+			"categories":          allergyIntolerance.Categories,
+			"criticality":         allergyIntolerance.Criticality,
+			"code":                allergyIntolerance.Code,
+			"code_system":         allergyIntolerance.CodeSystem,
+			"code_display":        allergyIntolerance.CodeDisplay,
+			"updated_at":          allergyIntolerance.UpdatedAt,
+		},
+	}
+
+	var updatedAllergyIntolerance models.AllergyIntolerance
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	updateError := repository.collection.FindOneAndUpdate(ctx, notDeleted(bson.M{"_id": objectID}), update, updateOptions).Decode(&updatedAllergyIntolerance)
+	if errors.Is(updateError, mongo.ErrNoDocuments) {
+		return nil, missingDocumentError(ctx, repository.collection, objectID, ErrAllergyIntoleranceNotFound)
+	}
+	if updateError != nil {
+		return nil, fmt.Errorf("failed to update allergy intolerance: %w", updateError)
+	}
+
+	return &updatedAllergyIntolerance, nil
+}
+
+// Delete marks an allergy intolerance deleted by ID, leaving the document for a read of it to answer as deleted
+// Returns ErrAllergyIntoleranceNotFound when the ID is malformed or no allergy intolerance has it, wrapped with ErrResourceDeleted when it was already deleted
+func (repository *MongoAllergyIntoleranceRepository) Delete(ctx context.Context, allergyIntoleranceID string) error {
+	objectID, convertError := primitive.ObjectIDFromHex(allergyIntoleranceID)
+	if convertError != nil {
+		return fmt.Errorf("%w: invalid ID %q", ErrAllergyIntoleranceNotFound, allergyIntoleranceID)
+	}
+
+	return softDeleteDocument(ctx, repository.collection, objectID, ErrAllergyIntoleranceNotFound)
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestMongoAllergyIntoleranceRepository_CRUD verifies an allergy is created, read, updated, and deleted
+func TestMongoAllergyIntoleranceRepository_CRUD(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	allergyIntoleranceRepository := NewMongoAllergyIntoleranceRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, allergyIntoleranceRepository.collection)
+	ctx := context.Background()
+
+	createdAllergyIntolerance, createError := allergyIntoleranceRepository.Create(ctx, &models.AllergyIntolerance{
+		PatientID:      "patient-123",
+		ClinicalStatus: "active",
+		Categories:     []string{"medication"},
+		Criticality:    "high",
+		Code:           "7980",
+		CodeSystem:     "http://www.nlm.nih.gov/research/umls/rxnorm",
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create allergy intolerance: %v", createError)
+	}
+	if createdAllergyIntolerance.ID == "" {
+		t.Fatalf("Expected a generated ID, got %+v", createdAllergyIntolerance)
+	}
+
+	createdAllergyIntolerance.ClinicalStatus = "resolved"
+	if _, updateError := allergyIntoleranceRepository.Update(ctx, createdAllergyIntolerance); updateError != nil {
+		t.Fatalf("Failed to update allergy intolerance: %v", updateError)
+	}
+
+	storedAllergyIntolerance, getError := allergyIntoleranceRepository.GetByID(ctx, createdAllergyIntolerance.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read allergy intolerance: %v", getError)
+	}
+	if storedAllergyIntolerance.ClinicalStatus != "resolved" || storedAllergyIntolerance.Code != "7980" {
+		t.Errorf("Expected the resolved allergy intolerance, got %+v", storedAllergyIntolerance)
+	}
+
+	if deleteError := allergyIntoleranceRepository.Delete(ctx, createdAllergyIntolerance.ID); deleteError != nil {
+		t.Fatalf("Failed to delete allergy intolerance: %v", deleteError)
+	}
+	if deleteError := allergyIntoleranceRepository.Delete(ctx, createdAllergyIntolerance.ID); !errors.Is(deleteError, ErrAllergyIntoleranceNotFound) {
+		t.Errorf("Expected ErrAllergyIntoleranceNotFound deleting twice, got %v", deleteError)
+	}
+	if _, getError := allergyIntoleranceRepository.GetByID(ctx, "not-an-object-id"); !errors.Is(getError, ErrAllergyIntoleranceNotFound) {
+		t.Errorf("Expected ErrAllergyIntoleranceNotFound for a malformed ID, got %v", getError)
+	}
+}
+
+// TestMongoAllergyIntoleranceRepository_Search verifies patient, clinical status, category, and criticality filters and the total
+func TestMongoAllergyIntoleranceRepository_Search(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	allergyIntoleranceRepository := NewMongoAllergyIntoleranceRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, allergyIntoleranceRepository.collection)
+	ctx := context.Background()
+
+	for _, allergyIntolerance := range []*models.AllergyIntolerance{
+		{PatientID: "patient-1", ClinicalStatus: "active", Categories: []string{"medication"}, Criticality: "high", Code: "7980"},
+		{PatientID: "patient-1", ClinicalStatus: "resolved", Categories: []string{"food", "environment"}, Criticality: "low", Code: "91935009"},
+		{PatientID: "patient-2", ClinicalStatus: "active", Categories: []string{"food"}, Code: "91935009"},
+	} {
+		if _, createError := allergyIntoleranceRepository.Create(ctx, allergyIntolerance); createError != nil {
+			t.Fatalf("Failed to create allergy intolerance: %v", createError)
+		}
+	}
+
+	testCases := map[string]struct {
+		searchParams  models.AllergyIntoleranceSearchParams
+		expectedCount int
+	}{
+		"patient":         {searchParams: models.AllergyIntoleranceSearchParams{PatientID: "patient-1"}, expectedCount: 2},
+		"clinical status": {searchParams: models.AllergyIntoleranceSearchParams{ClinicalStatus: "active"}, expectedCount: 2},
+		"category":        {searchParams: models.AllergyIntoleranceSearchParams{Category: "food"}, expectedCount: 2},
+		"criticality":     {searchParams: models.AllergyIntoleranceSearchParams{Criticality: "high"}, expectedCount: 1},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		allergyIntolerances, searchError := allergyIntoleranceRepository.Search(ctx, &testCase.searchParams)
+		if searchError != nil {
+			t.Fatalf("%s: search failed: %v", name, searchError)
+		}
+		total, countError := allergyIntoleranceRepository.Count(ctx, &testCase.searchParams)
+		if countError != nil {
+			t.Fatalf("%s: count failed: %v", name, countError)
+		}
+		if len(allergyIntolerances) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d allergy intolerances, got %d with total %d", name, testCase.expectedCount, len(allergyIntolerances), total)
+		}
+	}
+}
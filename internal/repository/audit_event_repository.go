@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// AuditEventRepository defines the interface for the append-only audit trail
+type AuditEventRepository interface {
+	// Create inserts an audit event and returns it with its ID and recording time
+	Create(ctx context.Context, event *models.AuditEvent) (*models.AuditEvent, error)
+
+	// GetByID retrieves an audit event by its unique identifier
+	GetByID(ctx context.Context, eventID string) (*models.AuditEvent, error)
+
+	// Search retrieves audit events matching the search criteria, newest first
+	Search(ctx context.Context, searchParams *models.AuditEventSearchParams) ([]*models.AuditEvent, error)
+
+	// Count returns how many audit events match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.AuditEventSearchParams) (int, error)
+}
+
+// auditEventColumns are the columns read into a models.AuditEvent by scanAuditEvent, in order
+const auditEventColumns = `id, action, interaction, outcome, status_code, agent_id, agent_roles, tenant_id,
+		client_address, entity_type, entity_id, query, method, path, request_id, recorded_at`
+
+// PostgresAuditEventRepository implements AuditEventRepository using PostgreSQL
+type PostgresAuditEventRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresAuditEventRepository creates a new PostgreSQL audit event repository instance
+func NewPostgresAuditEventRepository(databaseConnection *sql.DB) *PostgresAuditEventRepository {
+	return &PostgresAuditEventRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// scanAuditEvent reads one row selected with auditEventColumns
+func scanAuditEvent(row interface{ Scan(...interface{}) error }) (*models.AuditEvent, error) {
+	event := &models.AuditEvent{}
+	scanError := row.Scan(
+		&event.ID,
+		&event.Action,
+		&event.Interaction,
+		&event.Outcome,
+		&event.StatusCode,
+		&event.AgentID,
+		pq.Array(&event.AgentRoles),
+		&event.TenantID,
+		&event.ClientAddress,
+		&event.EntityType,
+		&event.EntityID,
+		&event.Query,
+		&event.Method,
+		&event.Path,
+		&event.RequestID,
+		&event.RecordedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+	return event, nil
+}
+
+// Create inserts an audit event; the database assigns its ID and recording time
+func (repository *PostgresAuditEventRepository) Create(ctx context.Context, event *models.AuditEvent) (*models.AuditEvent, error) {
+	insertQuery := `
+		INSERT INTO audit_events (action, interaction, outcome, status_code, agent_id, agent_roles, tenant_id,
+			client_address, entity_type, entity_id, query, method, path, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING ` + auditEventColumns
+
+	agentRoles := event.AgentRoles
+	if agentRoles == nil {
+		agentRoles = []string{}
+	}
+	return scanAuditEvent(repository.databaseConnection.QueryRowContext(ctx, insertQuery,
+		event.Action,
+		event.Interaction,
+		event.Outcome,
+		event.StatusCode,
+		event.AgentID,
+		pq.Array(agentRoles),
+		event.TenantID,
+		event.ClientAddress,
+		event.EntityType,
+		event.EntityID,
+		event.Query,
+		event.Method,
+		event.Path,
+		event.RequestID,
+	))
+}
+
+// GetByID retrieves an audit event by ID
+// Returns sql.ErrNoRows when the audit event does not exist
+func (repository *PostgresAuditEventRepository) GetByID(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	return scanAuditEvent(repository.databaseConnection.QueryRowContext(ctx,
+		`SELECT `+auditEventColumns+` FROM audit_events WHERE id = $1`, eventID))
+}
+
+// auditEventSearchConditions builds the WHERE conditions and parameters shared by Search and Count
+func auditEventSearchConditions(searchParams *models.AuditEventSearchParams) (string, []interface{}) {
+	conditions := ""
+	queryParameters := []interface{}{}
+	parameterIndex := 1
+
+	// Add equality filters in a fixed order so the query text stays stable
+	equalityFilters := []struct {
+		column string
+		value  string
+	}{
+		{"agent_id", searchParams.AgentID},
+		{"entity_type", searchParams.EntityType},
+		{"entity_id", searchParams.EntityID},
+		{"action", searchParams.Action},
+		{"outcome", searchParams.Outcome},
+		{"tenant_id", searchParams.TenantID},
+	}
+	for _, filter := range equalityFilters {
+		if filter.value != "" {
+			conditions += ` AND ` + filter.column + ` = $` + fmt.Sprint(parameterIndex)
+			queryParameters = append(queryParameters, filter.value)
+			parameterIndex++
+		}
+	}
+
+	// Add recording date range filters
+	if searchParams.DateGreaterThan != nil {
+		conditions += ` AND recorded_at >= $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.DateGreaterThan)
+		parameterIndex++
+	}
+	if searchParams.DateLessThan != nil {
+		conditions += ` AND recorded_at <= $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.DateLessThan)
+	}
+
+	return conditions, queryParameters
+}
+
+// Search retrieves audit events matching the criteria, newest first
+func (repository *PostgresAuditEventRepository) Search(ctx context.Context, searchParams *models.AuditEventSearchParams) ([]*models.AuditEvent, error) {
+	conditions, queryParameters := auditEventSearchConditions(searchParams)
+	parameterIndex := len(queryParameters) + 1
+
+	searchQuery := `SELECT ` + auditEventColumns + ` FROM audit_events WHERE 1=1` + conditions +
+		` ORDER BY recorded_at DESC, id LIMIT $` + fmt.Sprint(parameterIndex) + ` OFFSET $` + fmt.Sprint(parameterIndex+1)
+	queryParameters = append(queryParameters, searchParams.Limit, searchParams.Offset)
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, searchQuery, queryParameters...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	events := []*models.AuditEvent{}
+	for rows.Next() {
+		event, scanError := scanAuditEvent(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// Count returns how many audit events match the search criteria, ignoring the page's limit and offset
+func (repository *PostgresAuditEventRepository) Count(ctx context.Context, searchParams *models.AuditEventSearchParams) (int, error) {
+	conditions, queryParameters := auditEventSearchConditions(searchParams)
+
+	var total int
+	countError := repository.databaseConnection.QueryRowContext(ctx, `SELECT COUNT(*) FROM audit_events WHERE 1=1`+conditions, queryParameters...).Scan(&total)
+	if countError != nil {
+		return 0, countError
+	}
+	return total, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupAuditEvents removes every audit event
+func cleanupAuditEvents(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM audit_events"); deleteError != nil {
+		t.Fatalf("Failed to cleanup audit_events: %v", deleteError)
+	}
+}
+
+// TestPostgresAuditEventRepository_CreateAndSearch verifies events are stored and found by their search criteria
+func TestPostgresAuditEventRepository_CreateAndSearch(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupAuditEvents(t, databaseConnection)
+	defer cleanupAuditEvents(t, databaseConnection)
+
+	ctx := context.Background()
+	auditRepository := NewPostgresAuditEventRepository(databaseConnection)
+	read, createError := auditRepository.Create(ctx, &models.AuditEvent{
+		Action: models.AuditActionRead, Interaction: "read", Outcome: models.AuditOutcomeSuccess, StatusCode: 200,
+		AgentID: "api-key:1a2b3c4d", AgentRoles: []string{"clinician"}, EntityType: "Patient", EntityID: "p-1",
+		Method: "GET", Path: "/fhir/Patient/p-1",
+	})
+	if createError != nil {
+		t.Fatalf("Expected no error, got %v", createError)
+	}
+	if read.ID == "" || read.RecordedAt.IsZero() || len(read.AgentRoles) != 1 {
+		t.Errorf("Expected the stored event with its ID and time, got %+v", read)
+	}
+	auditRepository.Create(ctx, &models.AuditEvent{
+		Action: models.AuditActionDelete, Interaction: "delete", Outcome: models.AuditOutcomeMinorFailure, StatusCode: 409,
+		AgentID: "oauth:app", EntityType: "Patient", EntityID: "p-1", Method: "DELETE", Path: "/fhir/Patient/p-1",
+	})
+
+	fetched, getError := auditRepository.GetByID(ctx, read.ID)
+	if getError != nil || fetched.Path != "/fhir/Patient/p-1" {
+		t.Errorf("Expected the event by ID, got %+v (%v)", fetched, getError)
+	}
+	if _, missingError := auditRepository.GetByID(ctx, uuid.NewString()); !errors.Is(missingError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for an unknown ID, got %v", missingError)
+	}
+
+	hourAgo := time.Now().Add(-time.Hour)
+	testCases := map[string]struct {
+		searchParams  models.AuditEventSearchParams
+		expectedCount int
+	}{
+		"entity":         {models.AuditEventSearchParams{EntityType: "Patient", EntityID: "p-1"}, 2},
+		"agent":          {models.AuditEventSearchParams{AgentID: "oauth:app"}, 1},
+		"failed deletes": {models.AuditEventSearchParams{Action: models.AuditActionDelete, Outcome: models.AuditOutcomeMinorFailure}, 1},
+		"recent":         {models.AuditEventSearchParams{DateGreaterThan: &hourAgo}, 2},
+		"before":         {models.AuditEventSearchParams{DateLessThan: &hourAgo}, 0},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		events, searchError := auditRepository.Search(ctx, &testCase.searchParams)
+		total, countError := auditRepository.Count(ctx, &testCase.searchParams)
+		if searchError != nil || countError != nil || len(events) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d events, got %d (total %d, errors %v %v)", name, testCase.expectedCount, len(events), total, searchError, countError)
+		}
+	}
+}
@@ -0,0 +1,356 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/database"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Benchmarks run against the same local databases as the integration tests and are
+// skipped when those are unavailable. Data volume and seed are configurable so runs
+// are comparable across releases:
+//
+//	BENCH_VOLUME=10000 BENCH_SEED=7 go test ./internal/repository -run '^$' -bench .
+//
+// or simply: make bench BENCH_VOLUME=10000
+
+// Benchmark data defaults
+const (
+	defaultBenchmarkVolume = 1000
+	defaultBenchmarkSeed   = 42
+)
+
+// Value pools used to generate realistic, repeatable data
+var (
+	benchmarkFamilyNames = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Nguyen", "Lopez"}
+	benchmarkGivenNames  = []string{"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "David", "Susan"}
+	benchmarkGenders     = []string{"male", "female", "other", "unknown"}
+	benchmarkCodes       = []string{"8867-4", "8480-6", "8462-4", "8310-5", "29463-7", "2339-0"}
+	benchmarkCategories  = []string{"vital-signs", "laboratory", "survey"}
+	benchmarkStatuses    = []string{"final", "preliminary", "amended"}
+)
+
+// benchmarkVolume returns the number of records to seed (BENCH_VOLUME)
+func benchmarkVolume(b *testing.B) int {
+	return benchmarkIntSetting(b, "BENCH_VOLUME", defaultBenchmarkVolume)
+}
+
+// benchmarkRandom returns a deterministic generator seeded from BENCH_SEED
+func benchmarkRandom(b *testing.B) *rand.Rand {
+	seed := benchmarkIntSetting(b, "BENCH_SEED", defaultBenchmarkSeed)
+	return rand.New(rand.NewPCG(uint64(seed), uint64(seed)))
+}
+
+// benchmarkIntSetting reads a positive integer environment setting
+func benchmarkIntSetting(b *testing.B, name string, defaultValue int) int {
+	rawValue := os.Getenv(name)
+	if rawValue == "" {
+		return defaultValue
+	}
+
+	parsedValue, parseError := strconv.Atoi(rawValue)
+	if parseError != nil || parsedValue <= 0 {
+		b.Fatalf("%s must be a positive integer, got %q", name, rawValue)
+	}
+	return parsedValue
+}
+
+// setupBenchmarkDatabase connects to PostgreSQL or skips the benchmark
+func setupBenchmarkDatabase(b *testing.B) *sql.DB {
+	connectionString := "host=localhost port=5432 user=fhir_user password=fhir_password dbname=fhir_health_db sslmode=disable"
+
+	databaseConnection, connectionError := sql.Open("postgres", connectionString)
+	if connectionError != nil {
+		b.Skipf("PostgreSQL unavailable: %v", connectionError)
+	}
+	if pingError := databaseConnection.Ping(); pingError != nil {
+		databaseConnection.Close()
+		b.Skipf("PostgreSQL unavailable: %v", pingError)
+	}
+
+	return databaseConnection
+}
+
+// setupBenchmarkMongo connects to MongoDB or skips the benchmark
+func setupBenchmarkMongo(b *testing.B) *mongo.Database {
+	mongoDatabase, mongoError := database.NewMongoConnection(database.MongoConfig{
+		Host:     "localhost",
+		Port:     "27017",
+		User:     "fhir_user",
+		Password: "fhir_password",
+		Database: "admin",
+	})
+	if mongoError != nil {
+		b.Skipf("MongoDB unavailable: %v", mongoError)
+	}
+
+	return mongoDatabase
+}
+
+// generateBenchmarkPatient builds a patient from the random generator
+func generateBenchmarkPatient(random *rand.Rand, index int) *models.Patient {
+	birthDate := time.Date(1940+random.IntN(80), time.Month(1+random.IntN(12)), 1+random.IntN(28), 0, 0, 0, 0, time.UTC)
+	return &models.Patient{
+		IdentifierSystem: "http://hospital.example.org/mrn",
+		IdentifierValue:  fmt.Sprintf("BENCH-%08d", index),
+		Active:           random.IntN(10) > 0,
+		FamilyName:       benchmarkFamilyNames[random.IntN(len(benchmarkFamilyNames))],
+		GivenName:        benchmarkGivenNames[random.IntN(len(benchmarkGivenNames))],
+		Gender:           benchmarkGenders[random.IntN(len(benchmarkGenders))],
+		BirthDate:        &birthDate,
+	}
+}
+
+// generateBenchmarkObservation builds an observation from the random generator
+func generateBenchmarkObservation(random *rand.Rand, patientCount int) *models.Observation {
+	value := 40 + random.Float64()*160
+	effectiveDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(random.IntN(365*24)) * time.Hour)
+	observation := &models.Observation{
+		PatientID:     fmt.Sprintf("bench-patient-%d", random.IntN(patientCount)),
+		Status:        benchmarkStatuses[random.IntN(len(benchmarkStatuses))],
+		Category:      benchmarkCategories[random.IntN(len(benchmarkCategories))],
+		Code:          benchmarkCodes[random.IntN(len(benchmarkCodes))],
+		CodeSystem:    "http://loinc.org",
+		ValueQuantity: &value,
+		ValueUnit:     "1",
+		EffectiveDate: &effectiveDate,
+	}
+
+	// A share of observations are multi-component (e.g. blood pressure) to exercise decoding
+	if random.IntN(4) == 0 {
+		systolic := 100 + random.Float64()*40
+		diastolic := 60 + random.Float64()*30
+		observation.Components = []models.ObservationComponent{
+			{Code: "8480-6", CodeSystem: "http://loinc.org", ValueQuantity: &systolic, ValueUnit: "mm[Hg]"},
+			{Code: "8462-4", CodeSystem: "http://loinc.org", ValueQuantity: &diastolic, ValueUnit: "mm[Hg]"},
+		}
+	}
+
+	return observation
+}
+
+// seedBenchmarkPatients replaces the patients table contents with a seeded data set
+func seedBenchmarkPatients(b *testing.B, databaseConnection *sql.DB, volume int) *PostgresPatientRepository {
+	b.Helper()
+	if _, deleteError := databaseConnection.Exec("DELETE FROM patients"); deleteError != nil {
+		b.Fatalf("Failed to clear patients: %v", deleteError)
+	}
+
+	patientRepository := NewPostgresPatientRepository(databaseConnection)
+	random := benchmarkRandom(b)
+	for index := 0; index < volume; index++ {
+		if _, createError := patientRepository.Create(context.Background(), generateBenchmarkPatient(random, index)); createError != nil {
+			b.Fatalf("Failed to seed patient: %v", createError)
+		}
+	}
+
+	return patientRepository
+}
+
+// seedBenchmarkObservations replaces the observations collection contents with a seeded data set
+func seedBenchmarkObservations(b *testing.B, mongoDatabase *mongo.Database, volume int) *MongoObservationRepository {
+	b.Helper()
+	observationRepository := NewMongoObservationRepository(mongoDatabase)
+	if _, deleteError := observationRepository.collection.DeleteMany(context.Background(), bson.M{}); deleteError != nil {
+		b.Fatalf("Failed to clear observations: %v", deleteError)
+	}
+
+	random := benchmarkRandom(b)
+	patientCount := max(volume/10, 1)
+	for index := 0; index < volume; index++ {
+		if _, createError := observationRepository.Create(context.Background(), generateBenchmarkObservation(random, patientCount)); createError != nil {
+			b.Fatalf("Failed to seed observation: %v", createError)
+		}
+	}
+
+	return observationRepository
+}
+
+// paginationOffsets returns shallow, middle, and deep offsets for the data volume
+func paginationOffsets(volume int, pageSize int) []int {
+	deepest := max(volume-pageSize, 0)
+	return []int{0, deepest / 2, deepest}
+}
+
+// BenchmarkPostgresPatientRepository_Create measures insert throughput
+func BenchmarkPostgresPatientRepository_Create(b *testing.B) {
+	databaseConnection := setupBenchmarkDatabase(b)
+	defer databaseConnection.Close()
+	defer databaseConnection.Exec("DELETE FROM patients")
+
+	patientRepository := NewPostgresPatientRepository(databaseConnection)
+	random := benchmarkRandom(b)
+
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		if _, createError := patientRepository.Create(context.Background(), generateBenchmarkPatient(random, index)); createError != nil {
+			b.Fatalf("Create failed: %v", createError)
+		}
+	}
+}
+
+// BenchmarkPostgresPatientRepository_Search measures latency across filter combinations
+func BenchmarkPostgresPatientRepository_Search(b *testing.B) {
+	databaseConnection := setupBenchmarkDatabase(b)
+	defer databaseConnection.Close()
+	defer databaseConnection.Exec("DELETE FROM patients")
+
+	patientRepository := seedBenchmarkPatients(b, databaseConnection, benchmarkVolume(b))
+	active := true
+	rangeStart := time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC)
+	rangeEnd := time.Date(1990, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	filterCombinations := map[string]*models.PatientSearchParams{
+		"name":                {Name: models.NameCriteria{{Values: []string{"smi"}}}, Limit: 20},
+		"family_exact_prefix": {FamilyName: models.NameCriteria{{Values: []string{"Nguyen"}}}, Limit: 20},
+		"gender":              {Gender: models.ValueCriteria{{"female"}}, Limit: 20},
+		"birthdate_range":     {BirthDateGreaterThan: &rangeStart, BirthDateLessThan: &rangeEnd, Limit: 20},
+		"name_gender_active":  {Name: models.NameCriteria{{Values: []string{"jo"}}}, Gender: models.ValueCriteria{{"male"}}, Active: &active, Limit: 20},
+		"sorted_by_birthdate": {Gender: models.ValueCriteria{{"female"}}, SortBy: "birthdate", SortOrder: "desc", Limit: 20},
+	}
+
+	for name, searchParams := range filterCombinations {
+		b.Run(name, func(b *testing.B) {
+			for index := 0; index < b.N; index++ {
+				if _, searchError := patientRepository.Search(context.Background(), searchParams); searchError != nil {
+					b.Fatalf("Search failed: %v", searchError)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPostgresPatientRepository_PaginationDepth measures how latency grows with offset
+func BenchmarkPostgresPatientRepository_PaginationDepth(b *testing.B) {
+	databaseConnection := setupBenchmarkDatabase(b)
+	defer databaseConnection.Close()
+	defer databaseConnection.Exec("DELETE FROM patients")
+
+	volume := benchmarkVolume(b)
+	patientRepository := seedBenchmarkPatients(b, databaseConnection, volume)
+
+	for _, offset := range paginationOffsets(volume, 20) {
+		b.Run(fmt.Sprintf("offset_%d", offset), func(b *testing.B) {
+			for index := 0; index < b.N; index++ {
+				if _, getError := patientRepository.GetAll(context.Background(), 20, offset); getError != nil {
+					b.Fatalf("GetAll failed: %v", getError)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPostgresPatientRepository_LargeResult measures scanning the full data set in one page
+func BenchmarkPostgresPatientRepository_LargeResult(b *testing.B) {
+	databaseConnection := setupBenchmarkDatabase(b)
+	defer databaseConnection.Close()
+	defer databaseConnection.Exec("DELETE FROM patients")
+
+	volume := benchmarkVolume(b)
+	patientRepository := seedBenchmarkPatients(b, databaseConnection, volume)
+
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		patients, getError := patientRepository.GetAll(context.Background(), volume, 0)
+		if getError != nil {
+			b.Fatalf("GetAll failed: %v", getError)
+		}
+		if len(patients) != volume {
+			b.Fatalf("Expected %d patients, got %d", volume, len(patients))
+		}
+	}
+}
+
+// BenchmarkMongoObservationRepository_Create measures insert throughput
+func BenchmarkMongoObservationRepository_Create(b *testing.B) {
+	mongoDatabase := setupBenchmarkMongo(b)
+	observationRepository := NewMongoObservationRepository(mongoDatabase)
+	defer observationRepository.collection.DeleteMany(context.Background(), bson.M{})
+
+	random := benchmarkRandom(b)
+
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		if _, createError := observationRepository.Create(context.Background(), generateBenchmarkObservation(random, 100)); createError != nil {
+			b.Fatalf("Create failed: %v", createError)
+		}
+	}
+}
+
+// BenchmarkMongoObservationRepository_Search measures latency across filter combinations
+func BenchmarkMongoObservationRepository_Search(b *testing.B) {
+	mongoDatabase := setupBenchmarkMongo(b)
+	volume := benchmarkVolume(b)
+	observationRepository := seedBenchmarkObservations(b, mongoDatabase, volume)
+	defer observationRepository.collection.DeleteMany(context.Background(), bson.M{})
+
+	rangeStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rangeEnd := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+
+	filterCombinations := map[string]*models.ObservationSearchParams{
+		"patient":              {PatientID: "bench-patient-1", Limit: 20},
+		"code":                 {Code: models.ValueCriteria{{"8867-4"}}, Limit: 20},
+		"category_status":      {Category: models.ValueCriteria{{"vital-signs"}}, Status: models.ValueCriteria{{"final"}}, Limit: 20},
+		"date_range":           {DateGreaterThan: &rangeStart, DateLessThan: &rangeEnd, Limit: 20},
+		"patient_code_date":    {PatientID: "bench-patient-1", Code: models.ValueCriteria{{"8867-4"}}, DateGreaterThan: &rangeStart, Limit: 20},
+		"sorted_by_date":       {Category: models.ValueCriteria{{"laboratory"}}, SortBy: "effective_date", SortOrder: "desc", Limit: 20},
+		"code_sorted_page_two": {Code: models.ValueCriteria{{"8480-6"}}, SortBy: "effective_date", Limit: 20, Offset: 20},
+	}
+
+	for name, searchParams := range filterCombinations {
+		b.Run(name, func(b *testing.B) {
+			for index := 0; index < b.N; index++ {
+				if _, searchError := observationRepository.Search(context.Background(), searchParams); searchError != nil {
+					b.Fatalf("Search failed: %v", searchError)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMongoObservationRepository_PaginationDepth measures how latency grows with skip
+func BenchmarkMongoObservationRepository_PaginationDepth(b *testing.B) {
+	mongoDatabase := setupBenchmarkMongo(b)
+	volume := benchmarkVolume(b)
+	observationRepository := seedBenchmarkObservations(b, mongoDatabase, volume)
+	defer observationRepository.collection.DeleteMany(context.Background(), bson.M{})
+
+	for _, offset := range paginationOffsets(volume, 20) {
+		b.Run(fmt.Sprintf("offset_%d", offset), func(b *testing.B) {
+			for index := 0; index < b.N; index++ {
+				if _, getError := observationRepository.GetAll(context.Background(), 20, offset); getError != nil {
+					b.Fatalf("GetAll failed: %v", getError)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkMongoObservationRepository_LargeResult measures decoding the full data set in one page
+func BenchmarkMongoObservationRepository_LargeResult(b *testing.B) {
+	mongoDatabase := setupBenchmarkMongo(b)
+	volume := benchmarkVolume(b)
+	observationRepository := seedBenchmarkObservations(b, mongoDatabase, volume)
+	defer observationRepository.collection.DeleteMany(context.Background(), bson.M{})
+
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		observations, getError := observationRepository.GetAll(context.Background(), volume, 0)
+		if getError != nil {
+			b.Fatalf("GetAll failed: %v", getError)
+		}
+		if len(observations) != volume {
+			b.Fatalf("Expected %d observations, got %d", volume, len(observations))
+		}
+	}
+}
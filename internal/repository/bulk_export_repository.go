@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// BulkExportRepository defines the interface for bulk export jobs and the files they produce
+type BulkExportRepository interface {
+	// InTransaction runs work in a transaction shared by the repositories it calls
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+
+	// Create inserts a pending export written by export.JobID
+	Create(ctx context.Context, export *models.BulkExport) (*models.BulkExport, error)
+
+	// Get retrieves an export with its files; sql.ErrNoRows when there is none
+	Get(ctx context.Context, exportID int64) (*models.BulkExport, error)
+
+	// GetByJob retrieves the export a job writes, without its files; sql.ErrNoRows when there is none
+	GetByJob(ctx context.Context, jobID string) (*models.BulkExport, error)
+
+	// Start marks the pending export a job writes running, or restarts it when an earlier attempt left it
+	// running, and returns it; sql.ErrNoRows when it was cancelled or has finished
+	Start(ctx context.Context, jobID string) (*models.BulkExport, error)
+
+	// UpdateProgress records a running export's progress, which also keeps it from going stale;
+	// sql.ErrNoRows when the export is no longer running, for example because it was cancelled
+	UpdateProgress(ctx context.Context, exportID int64, progress string) error
+
+	// Complete records the files of a running export and makes it downloadable until expiresAt
+	Complete(ctx context.Context, exportID int64, files []*models.BulkExportFile, expiresAt time.Time) error
+
+	// Fail ends a pending or running export with an error; sql.ErrNoRows when it is in neither state
+	Fail(ctx context.Context, exportID int64, failure string) error
+
+	// Cancel ends a pending, running, or complete export; sql.ErrNoRows when it is in none of those states
+	Cancel(ctx context.Context, exportID int64) error
+
+	// ExpireBefore marks complete exports that expired before now expired and returns their IDs
+	ExpireBefore(ctx context.Context, now time.Time) ([]int64, error)
+}
+
+// PostgresBulkExportRepository implements BulkExportRepository using PostgreSQL
+type PostgresBulkExportRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresBulkExportRepository creates a new PostgreSQL bulk export repository instance
+func NewPostgresBulkExportRepository(databaseConnection *sql.DB) *PostgresBulkExportRepository {
+	return &PostgresBulkExportRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// bulkExportColumns lists the columns scanned by scanBulkExport
+const bulkExportColumns = `id, request, types, since, status, requested_by, tenant_id, COALESCE(job_id::text, ''), progress, error,
+	created_at, updated_at, completed_at, expires_at`
+
+// InTransaction runs work in a transaction shared by the repositories it calls
+func (repository *PostgresBulkExportRepository) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return runInTransaction(ctx, repository.databaseConnection, work)
+}
+
+// Create inserts a pending export, in the caller's transaction when ctx carries one
+func (repository *PostgresBulkExportRepository) Create(ctx context.Context, export *models.BulkExport) (*models.BulkExport, error) {
+	insertQuery := `
+		INSERT INTO bulk_exports (request, types, since, status, requested_by, tenant_id, job_id)
+		VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, '')::uuid)
+		RETURNING ` + bulkExportColumns
+
+	return scanBulkExport(executorFor(ctx, repository.databaseConnection).QueryRowContext(ctx, insertQuery,
+		export.Request, pq.Array(export.Types), export.Since, models.BulkExportStatusPending, export.RequestedBy, export.TenantID, export.JobID))
+}
+
+// Get retrieves an export by ID along with its files, in the order of its types
+func (repository *PostgresBulkExportRepository) Get(ctx context.Context, exportID int64) (*models.BulkExport, error) {
+	export, getError := scanBulkExport(repository.databaseConnection.QueryRowContext(ctx,
+		"SELECT "+bulkExportColumns+" FROM bulk_exports WHERE id = $1", exportID))
+	if getError != nil {
+		return nil, getError
+	}
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, `
+		SELECT export_id, resource_type, resource_count
+		FROM bulk_export_files
+		WHERE export_id = $1
+		ORDER BY array_position($2::TEXT[], resource_type::TEXT)`, exportID, pq.Array(export.Types))
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		file := &models.BulkExportFile{}
+		if scanError := rows.Scan(&file.ExportID, &file.ResourceType, &file.ResourceCount); scanError != nil {
+			return nil, scanError
+		}
+		export.Files = append(export.Files, file)
+	}
+	return export, rows.Err()
+}
+
+// GetByJob retrieves the export written by a job
+func (repository *PostgresBulkExportRepository) GetByJob(ctx context.Context, jobID string) (*models.BulkExport, error) {
+	return scanBulkExport(repository.databaseConnection.QueryRowContext(ctx,
+		"SELECT "+bulkExportColumns+" FROM bulk_exports WHERE job_id = $1", jobID))
+}
+
+// Start marks the export running and clears the progress an earlier attempt left
+func (repository *PostgresBulkExportRepository) Start(ctx context.Context, jobID string) (*models.BulkExport, error) {
+	startQuery := `
+		UPDATE bulk_exports
+		SET status = $2, progress = '', updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = $1 AND status = ANY($3)
+		RETURNING ` + bulkExportColumns
+
+	return scanBulkExport(repository.databaseConnection.QueryRowContext(ctx, startQuery, jobID, models.BulkExportStatusRunning,
+		pq.Array([]string{string(models.BulkExportStatusPending), string(models.BulkExportStatusRunning)})))
+}
+
+// UpdateProgress records the progress of a running export
+func (repository *PostgresBulkExportRepository) UpdateProgress(ctx context.Context, exportID int64, progress string) error {
+	result, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE bulk_exports
+		SET progress = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $3`, exportID, progress, models.BulkExportStatusRunning)
+	return runningExportUpdated(result, updateError)
+}
+
+// Complete inserts the export's files and marks it complete in one transaction
+func (repository *PostgresBulkExportRepository) Complete(ctx context.Context, exportID int64, files []*models.BulkExportFile, expiresAt time.Time) error {
+	return runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		transaction := executorFor(transactionContext, repository.databaseConnection)
+		result, updateError := transaction.ExecContext(transactionContext, `
+			UPDATE bulk_exports
+			SET status = $2, progress = '', expires_at = $3, completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND status = $4`, exportID, models.BulkExportStatusComplete, expiresAt, models.BulkExportStatusRunning)
+		if completeError := runningExportUpdated(result, updateError); completeError != nil {
+			return completeError
+		}
+
+		for _, file := range files {
+			if _, insertError := transaction.ExecContext(transactionContext, `
+				INSERT INTO bulk_export_files (export_id, resource_type, resource_count)
+				VALUES ($1, $2, $3)`, exportID, file.ResourceType, file.ResourceCount); insertError != nil {
+				return insertError
+			}
+		}
+		return nil
+	})
+}
+
+// Fail marks the pending or running export failed
+func (repository *PostgresBulkExportRepository) Fail(ctx context.Context, exportID int64, failure string) error {
+	result, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE bulk_exports
+		SET status = $2, error = $3, progress = '', completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = ANY($4)`, exportID, models.BulkExportStatusFailed, failure,
+		pq.Array([]string{string(models.BulkExportStatusPending), string(models.BulkExportStatusRunning)}))
+	return runningExportUpdated(result, updateError)
+}
+
+// Cancel marks a pending, running, or complete export cancelled
+func (repository *PostgresBulkExportRepository) Cancel(ctx context.Context, exportID int64) error {
+	result, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE bulk_exports
+		SET status = $2, progress = '', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = ANY($3)`, exportID, models.BulkExportStatusCancelled,
+		pq.Array([]string{string(models.BulkExportStatusPending), string(models.BulkExportStatusRunning), string(models.BulkExportStatusComplete)}))
+	return runningExportUpdated(result, updateError)
+}
+
+// ExpireBefore marks expired complete exports expired, keeping their rows so their status stays readable
+func (repository *PostgresBulkExportRepository) ExpireBefore(ctx context.Context, now time.Time) ([]int64, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, `
+		UPDATE bulk_exports
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND expires_at <= $3
+		RETURNING id`, models.BulkExportStatusExpired, models.BulkExportStatusComplete, now)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	expiredIDs := []int64{}
+	for rows.Next() {
+		var exportID int64
+		if scanError := rows.Scan(&exportID); scanError != nil {
+			return nil, scanError
+		}
+		expiredIDs = append(expiredIDs, exportID)
+	}
+	return expiredIDs, rows.Err()
+}
+
+// scanBulkExport reads one row selected with bulkExportColumns
+func scanBulkExport(row *sql.Row) (*models.BulkExport, error) {
+	export := &models.BulkExport{}
+	var status string
+	var since, completedAt, expiresAt sql.NullTime
+	scanError := row.Scan(&export.ID, &export.Request, pq.Array(&export.Types), &since, &status, &export.RequestedBy,
+		&export.TenantID, &export.JobID, &export.Progress, &export.Error, &export.CreatedAt, &export.UpdatedAt, &completedAt, &expiresAt)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	export.Status = models.BulkExportStatus(status)
+	if since.Valid {
+		export.Since = &since.Time
+	}
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	return export, nil
+}
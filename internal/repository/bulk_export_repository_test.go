@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupBulkExportTestData empties the bulk export tables; files are removed with their exports
+func cleanupBulkExportTestData(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM bulk_exports"); deleteError != nil {
+		t.Fatalf("Failed to cleanup bulk exports: %v", deleteError)
+	}
+}
+
+// createTestBulkExport inserts a pending export of patients and observations written by jobID
+func createTestBulkExport(t *testing.T, exportRepository *PostgresBulkExportRepository, jobID string) *models.BulkExport {
+	t.Helper()
+	since := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	export, createError := exportRepository.Create(context.Background(), &models.BulkExport{
+		Request:     "https://fhir.example.org/fhir/$export?_type=Patient,Observation",
+		Types:       []string{"Patient", "Observation"},
+		Since:       &since,
+		RequestedBy: "alice",
+		TenantID:    "default",
+		JobID:       jobID,
+	})
+	if createError != nil {
+		t.Fatalf("Expected the export to be created, got %v", createError)
+	}
+	return export
+}
+
+// TestPostgresBulkExportRepository_Lifecycle verifies creating, starting, completing, reading, and expiring an export
+func TestPostgresBulkExportRepository_Lifecycle(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupBulkExportTestData(t, databaseConnection)
+	defer cleanupBulkExportTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	exportRepository := NewPostgresBulkExportRepository(databaseConnection)
+	jobID := "6f1c2a0e-8d3b-4f6a-9c2e-1b7d5e4a3c21"
+
+	created := createTestBulkExport(t, exportRepository, jobID)
+	if created.ID == 0 || created.Status != models.BulkExportStatusPending || created.JobID != jobID || created.Since == nil {
+		t.Fatalf("Expected a pending export of the job, got %+v", created)
+	}
+	if len(created.Types) != 2 || created.Types[0] != "Patient" || created.RequestedBy != "alice" {
+		t.Errorf("Expected the requested types and requester, got %+v", created)
+	}
+
+	byJob, getError := exportRepository.GetByJob(ctx, jobID)
+	if getError != nil || byJob.ID != created.ID {
+		t.Fatalf("Expected the export of the job, got %+v (%v)", byJob, getError)
+	}
+	if _, getError := exportRepository.GetByJob(ctx, "00000000-0000-0000-0000-000000000000"); !errors.Is(getError, sql.ErrNoRows) {
+		t.Errorf("Expected no export for another job, got %v", getError)
+	}
+
+	// Progress is only recorded while the export runs
+	if progressError := exportRepository.UpdateProgress(ctx, created.ID, "Patient: 10"); !errors.Is(progressError, sql.ErrNoRows) {
+		t.Errorf("Expected a pending export not to record progress, got %v", progressError)
+	}
+	started, startError := exportRepository.Start(ctx, jobID)
+	if startError != nil || started.Status != models.BulkExportStatusRunning {
+		t.Fatalf("Expected the export to start, got %+v (%v)", started, startError)
+	}
+	if progressError := exportRepository.UpdateProgress(ctx, created.ID, "Patient: 10"); progressError != nil {
+		t.Fatalf("Unexpected progress error: %v", progressError)
+	}
+
+	// A retried attempt starts the running export again with its progress cleared
+	restarted, startError := exportRepository.Start(ctx, jobID)
+	if startError != nil || restarted.Status != models.BulkExportStatusRunning || restarted.Progress != "" {
+		t.Fatalf("Expected the export to restart without progress, got %+v (%v)", restarted, startError)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	files := []*models.BulkExportFile{
+		{ExportID: created.ID, ResourceType: "Observation", ResourceCount: 7},
+		{ExportID: created.ID, ResourceType: "Patient", ResourceCount: 3},
+	}
+	if completeError := exportRepository.Complete(ctx, created.ID, files, expiresAt); completeError != nil {
+		t.Fatalf("Unexpected complete error: %v", completeError)
+	}
+	if completeError := exportRepository.Complete(ctx, created.ID, files, expiresAt); !errors.Is(completeError, sql.ErrNoRows) {
+		t.Errorf("Expected a complete export not to complete again, got %v", completeError)
+	}
+	if _, startError := exportRepository.Start(ctx, jobID); !errors.Is(startError, sql.ErrNoRows) {
+		t.Errorf("Expected a complete export not to start again, got %v", startError)
+	}
+	if failError := exportRepository.Fail(ctx, created.ID, "late"); !errors.Is(failError, sql.ErrNoRows) {
+		t.Errorf("Expected a complete export not to fail, got %v", failError)
+	}
+
+	completed, getError := exportRepository.Get(ctx, created.ID)
+	if getError != nil || completed.Status != models.BulkExportStatusComplete || completed.CompletedAt == nil || completed.ExpiresAt == nil {
+		t.Fatalf("Expected the complete export, got %+v (%v)", completed, getError)
+	}
+	if len(completed.Files) != 2 || completed.Files[0].ResourceType != "Patient" || completed.Files[1].ResourceCount != 7 {
+		t.Errorf("Expected the files in the order of the export's types, got %+v", completed.Files)
+	}
+
+	if expiredIDs, expireError := exportRepository.ExpireBefore(ctx, time.Now()); expireError != nil || len(expiredIDs) != 0 {
+		t.Errorf("Expected nothing to expire yet, got %v (%v)", expiredIDs, expireError)
+	}
+	expiredIDs, expireError := exportRepository.ExpireBefore(ctx, expiresAt.Add(time.Minute))
+	if expireError != nil || len(expiredIDs) != 1 || expiredIDs[0] != created.ID {
+		t.Fatalf("Expected the export to expire, got %v (%v)", expiredIDs, expireError)
+	}
+	expired, _ := exportRepository.Get(ctx, created.ID)
+	if expired.Status != models.BulkExportStatusExpired {
+		t.Errorf("Expected the expired export to stay readable, got %+v", expired)
+	}
+	if cancelError := exportRepository.Cancel(ctx, created.ID); !errors.Is(cancelError, sql.ErrNoRows) {
+		t.Errorf("Expected an expired export not to be cancelled, got %v", cancelError)
+	}
+}
+
+// TestPostgresBulkExportRepository_FailAndCancel verifies pending and running exports fail or are cancelled
+// once, and a finished export keeps its status
+func TestPostgresBulkExportRepository_FailAndCancel(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupBulkExportTestData(t, databaseConnection)
+	defer cleanupBulkExportTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	exportRepository := NewPostgresBulkExportRepository(databaseConnection)
+
+	pendingExport := createTestBulkExport(t, exportRepository, "0b9e3d7c-2f41-4a8e-b6d5-7c1a9e2f4b30")
+	if failError := exportRepository.Fail(ctx, pendingExport.ID, "the job ran out of attempts"); failError != nil {
+		t.Fatalf("Unexpected fail error: %v", failError)
+	}
+	failed, _ := exportRepository.Get(ctx, pendingExport.ID)
+	if failed.Status != models.BulkExportStatusFailed || failed.Error != "the job ran out of attempts" || failed.CompletedAt == nil {
+		t.Errorf("Expected the export to fail with its error, got %+v", failed)
+	}
+	if cancelError := exportRepository.Cancel(ctx, pendingExport.ID); !errors.Is(cancelError, sql.ErrNoRows) {
+		t.Errorf("Expected a failed export not to be cancelled, got %v", cancelError)
+	}
+
+	runningJobID := "9a4f6b2d-1c8e-4d3a-a7f5-2e6b8c0d1f94"
+	runningExport := createTestBulkExport(t, exportRepository, runningJobID)
+	if _, startError := exportRepository.Start(ctx, runningJobID); startError != nil {
+		t.Fatalf("Unexpected start error: %v", startError)
+	}
+	if cancelError := exportRepository.Cancel(ctx, runningExport.ID); cancelError != nil {
+		t.Fatalf("Unexpected cancel error: %v", cancelError)
+	}
+	cancelled, _ := exportRepository.Get(ctx, runningExport.ID)
+	if cancelled.Status != models.BulkExportStatusCancelled {
+		t.Errorf("Expected the export to be cancelled, got %+v", cancelled)
+	}
+	if _, startError := exportRepository.Start(ctx, runningJobID); !errors.Is(startError, sql.ErrNoRows) {
+		t.Errorf("Expected a cancelled export not to start again, got %v", startError)
+	}
+	if progressError := exportRepository.UpdateProgress(ctx, runningExport.ID, "Patient: 1"); !errors.Is(progressError, sql.ErrNoRows) {
+		t.Errorf("Expected a cancelled export not to record progress, got %v", progressError)
+	}
+}
+
+// TestPostgresBulkExportRepository_CreateInTransaction verifies an export created in a rolled back transaction
+// is not kept
+func TestPostgresBulkExportRepository_CreateInTransaction(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupBulkExportTestData(t, databaseConnection)
+	defer cleanupBulkExportTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	exportRepository := NewPostgresBulkExportRepository(databaseConnection)
+	jobID := "4d2b8e6f-3a1c-4e9b-8f7d-5c0a2b4e6d18"
+	rollback := errors.New("enqueue failed")
+
+	transactionError := exportRepository.InTransaction(ctx, func(transactionContext context.Context) error {
+		if _, createError := exportRepository.Create(transactionContext, &models.BulkExport{
+			Request: "https://fhir.example.org/fhir/$export", Types: []string{"Patient"}, RequestedBy: "alice", JobID: jobID,
+		}); createError != nil {
+			return createError
+		}
+		return rollback
+	})
+	if !errors.Is(transactionError, rollback) {
+		t.Fatalf("Expected the transaction to return its error, got %v", transactionError)
+	}
+	if _, getError := exportRepository.GetByJob(ctx, jobID); !errors.Is(getError, sql.ErrNoRows) {
+		t.Errorf("Expected the rolled back export not to exist, got %v", getError)
+	}
+}
@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// changeLogLockKey is the transaction-scoped advisory lock taken before appending to the change log
+// Holding it until commit makes sequences become visible in order, so a sync cursor never
+// passes a sequence whose transaction has not committed yet
+const changeLogLockKey = 7_300_212
+
+// ChangeRepository defines the interface for the append-only resource change log
+type ChangeRepository interface {
+	// InTransaction runs work in a database transaction; Postgres writes made with the context
+	// passed to work, including Record, commit or roll back together
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+
+	// Record appends a change, assigning its sequence and the resource's next version
+	Record(ctx context.Context, change *models.ResourceChange) (*models.ResourceChange, error)
+
+	// ListSince returns changes with a sequence greater than afterSequence, oldest first
+	ListSince(ctx context.Context, afterSequence int64, limit int) ([]*models.ResourceChange, error)
+}
+
+// SnapshotRepository reads resource snapshots recorded in the change log for point-in-time reads
+type SnapshotRepository interface {
+	// FindVersion returns the change that produced the given version; sql.ErrNoRows when there is none
+	FindVersion(ctx context.Context, resourceType string, resourceID string, version int) (*models.ResourceChange, error)
+
+	// FindAsOf returns the latest change recorded at or before the given time; sql.ErrNoRows when there is none
+	FindAsOf(ctx context.Context, resourceType string, resourceID string, at time.Time) (*models.ResourceChange, error)
+
+	// ListCompartmentAsOf returns the latest change at or before the given time for every resource
+	// that was in the patient's compartment at that time and not deleted
+	ListCompartmentAsOf(ctx context.Context, patientID string, at time.Time) ([]*models.ResourceChange, error)
+
+	// ListVersions returns up to limit of the resource's most recent changes, deletes included, newest first
+	ListVersions(ctx context.Context, resourceType string, resourceID string, limit int) ([]*models.ResourceChange, error)
+}
+
+// ChangedResourceRepository finds the resources the change log recorded writes to, for incremental exports
+type ChangedResourceRepository interface {
+	// ListChangedSince returns the IDs of the resources of a type with a change recorded after since
+	ListChangedSince(ctx context.Context, resourceType string, since time.Time) ([]string, error)
+}
+
+// ChangeRecordListener is told about each recorded change inside the transaction that records it
+// An error rolls back the change and the write it describes
+type ChangeRecordListener interface {
+	ChangeRecorded(ctx context.Context, change *models.ResourceChange) error
+}
+
+// PostgresChangeRepository implements ChangeRepository using PostgreSQL
+type PostgresChangeRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+
+	// Listeners called for every recorded change, such as the event outbox
+	recordListeners []ChangeRecordListener
+}
+
+// NewPostgresChangeRepository creates a new PostgreSQL change log repository instance
+func NewPostgresChangeRepository(databaseConnection *sql.DB) *PostgresChangeRepository {
+	return &PostgresChangeRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// InTransaction runs work in a transaction shared by the repositories it calls
+func (repository *PostgresChangeRepository) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return runInTransaction(ctx, repository.databaseConnection, work)
+}
+
+// AddRecordListener registers a listener called for every recorded change before it commits
+// Register listeners at startup, before the repository records changes
+func (repository *PostgresChangeRepository) AddRecordListener(listener ChangeRecordListener) {
+	repository.recordListeners = append(repository.recordListeners, listener)
+}
+
+// Record appends a change to the log and returns it with sequence, version, and timestamp set
+// It joins the transaction carried by ctx, so the change commits with the write it describes
+func (repository *PostgresChangeRepository) Record(ctx context.Context, change *models.ResourceChange) (*models.ResourceChange, error) {
+	// The version is derived from the resource's latest recorded version; the unique
+	// constraint on (resource_type, resource_id, version) rejects concurrent duplicates
+	insertQuery := `
+		INSERT INTO resource_changes (resource_type, resource_id, version, operation, snapshot, compartment_patient_id)
+		VALUES ($1, $2, (
+			SELECT COALESCE(MAX(version), 0) + 1
+			FROM resource_changes
+			WHERE resource_type = $1 AND resource_id = $2
+		), $3, $4, NULLIF($5, ''))
+		RETURNING sequence, version, changed_at
+	`
+
+	// A delete carries no snapshot; store NULL rather than an empty document
+	var snapshot interface{}
+	if len(change.Snapshot) > 0 {
+		snapshot = []byte(change.Snapshot)
+	}
+
+	recordError := runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		executor := executorFor(transactionContext, repository.databaseConnection)
+		if _, lockError := executor.ExecContext(transactionContext, `SELECT pg_advisory_xact_lock($1)`, changeLogLockKey); lockError != nil {
+			return lockError
+		}
+
+		scanError := executor.QueryRowContext(
+			transactionContext,
+			insertQuery,
+			change.ResourceType,
+			change.ResourceID,
+			change.Operation,
+			snapshot,
+			change.CompartmentPatientID,
+		).Scan(&change.Sequence, &change.Version, &change.ChangedAt)
+		if scanError != nil {
+			return scanError
+		}
+
+		for _, listener := range repository.recordListeners {
+			if listenerError := listener.ChangeRecorded(transactionContext, change); listenerError != nil {
+				return listenerError
+			}
+		}
+		return nil
+	})
+
+	if recordError != nil {
+		return nil, recordError
+	}
+
+	return change, nil
+}
+
+// ListSince returns up to limit changes recorded after the given sequence
+// Record's advisory lock serializes appends through commit, so every visible sequence has no
+// uncommitted sequence below it and the returned page is safe to use as a cursor
+func (repository *PostgresChangeRepository) ListSince(ctx context.Context, afterSequence int64, limit int) ([]*models.ResourceChange, error) {
+	// SQL query to page through the log in sequence order
+	selectQuery := `
+		SELECT sequence, resource_type, resource_id, version, operation, changed_at
+		FROM resource_changes
+		WHERE sequence > $1
+		ORDER BY sequence ASC
+		LIMIT $2
+	`
+
+	// Execute the query
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, afterSequence, limit)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	// Scan results into change structs
+	changes := []*models.ResourceChange{}
+	for rows.Next() {
+		change := &models.ResourceChange{}
+		scanError := rows.Scan(
+			&change.Sequence,
+			&change.ResourceType,
+			&change.ResourceID,
+			&change.Version,
+			&change.Operation,
+			&change.ChangedAt,
+		)
+		if scanError != nil {
+			return nil, scanError
+		}
+		changes = append(changes, change)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return changes, nil
+}
+
+// snapshotColumns are selected by the point-in-time queries, in scanSnapshotChange order
+const snapshotColumns = `sequence, resource_type, resource_id, version, operation, changed_at, snapshot, COALESCE(compartment_patient_id, '')`
+
+// scanSnapshotChange scans a change including its snapshot
+func scanSnapshotChange(scanner rowScanner) (*models.ResourceChange, error) {
+	change := &models.ResourceChange{}
+	var snapshot []byte
+	scanError := scanner.Scan(
+		&change.Sequence,
+		&change.ResourceType,
+		&change.ResourceID,
+		&change.Version,
+		&change.Operation,
+		&change.ChangedAt,
+		&snapshot,
+		&change.CompartmentPatientID,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+	change.Snapshot = snapshot
+	return change, nil
+}
+
+// FindVersion returns the change that produced the given version of a resource
+func (repository *PostgresChangeRepository) FindVersion(ctx context.Context, resourceType string, resourceID string, version int) (*models.ResourceChange, error) {
+	selectQuery := `SELECT ` + snapshotColumns + `
+		FROM resource_changes
+		WHERE resource_type = $1 AND resource_id = $2 AND version = $3
+	`
+	return scanSnapshotChange(repository.databaseConnection.QueryRowContext(ctx, selectQuery, resourceType, resourceID, version))
+}
+
+// FindAsOf returns the latest change to a resource recorded at or before the given time
+func (repository *PostgresChangeRepository) FindAsOf(ctx context.Context, resourceType string, resourceID string, at time.Time) (*models.ResourceChange, error) {
+	selectQuery := `SELECT ` + snapshotColumns + `
+		FROM resource_changes
+		WHERE resource_type = $1 AND resource_id = $2 AND changed_at <= $3
+		ORDER BY version DESC
+		LIMIT 1
+	`
+	return scanSnapshotChange(repository.databaseConnection.QueryRowContext(ctx, selectQuery, resourceType, resourceID, at))
+}
+
+// ListCompartmentAsOf returns the state at the given time of every resource in the patient's compartment
+// The latest change per resource is found first and then filtered, so resources that later moved
+// out of the compartment or were deleted by that time are excluded
+func (repository *PostgresChangeRepository) ListCompartmentAsOf(ctx context.Context, patientID string, at time.Time) ([]*models.ResourceChange, error) {
+	selectQuery := `SELECT ` + snapshotColumns + ` FROM (
+			SELECT DISTINCT ON (resource_type, resource_id) *
+			FROM resource_changes
+			WHERE changed_at <= $2 AND (resource_type, resource_id) IN (
+				SELECT resource_type, resource_id FROM resource_changes WHERE compartment_patient_id = $1
+			)
+			ORDER BY resource_type, resource_id, version DESC
+		) latest
+		WHERE compartment_patient_id = $1 AND operation <> 'delete'
+		ORDER BY resource_type DESC, changed_at ASC
+	`
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, patientID, at)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	changes := []*models.ResourceChange{}
+	for rows.Next() {
+		change, scanError := scanSnapshotChange(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		changes = append(changes, change)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return changes, nil
+}
+
+// ListVersions returns the resource's most recent changes, newest first, for its version history
+func (repository *PostgresChangeRepository) ListVersions(ctx context.Context, resourceType string, resourceID string, limit int) ([]*models.ResourceChange, error) {
+	selectQuery := `SELECT ` + snapshotColumns + `
+		FROM resource_changes
+		WHERE resource_type = $1 AND resource_id = $2
+		ORDER BY version DESC
+		LIMIT $3
+	`
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, resourceType, resourceID, limit)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	changes := []*models.ResourceChange{}
+	for rows.Next() {
+		change, scanError := scanSnapshotChange(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		changes = append(changes, change)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return changes, nil
+}
+
+// ListChangedSince returns the IDs of the resources of a type written after since, deleted ones included
+func (repository *PostgresChangeRepository) ListChangedSince(ctx context.Context, resourceType string, since time.Time) ([]string, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, `
+		SELECT DISTINCT resource_id
+		FROM resource_changes
+		WHERE resource_type = $1 AND changed_at > $2
+	`, resourceType, since)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	resourceIDs := []string{}
+	for rows.Next() {
+		var resourceID string
+		if scanError := rows.Scan(&resourceID); scanError != nil {
+			return nil, scanError
+		}
+		resourceIDs = append(resourceIDs, resourceID)
+	}
+	return resourceIDs, rows.Err()
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupChangeTestData removes all entries from the resource_changes table
+func cleanupChangeTestData(t *testing.T, databaseConnection *sql.DB) {
+	_, deleteError := databaseConnection.Exec("DELETE FROM resource_changes")
+	if deleteError != nil {
+		t.Fatalf("Failed to cleanup change log: %v", deleteError)
+	}
+}
+
+// TestPostgresChangeRepository_RecordAssignsVersions verifies versions increment per resource
+func TestPostgresChangeRepository_RecordAssignsVersions(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupChangeTestData(t, databaseConnection)
+	defer cleanupChangeTestData(t, databaseConnection)
+
+	changeRepository := NewPostgresChangeRepository(databaseConnection)
+	ctx := context.Background()
+
+	firstChange, recordError := changeRepository.Record(ctx, &models.ResourceChange{
+		ResourceType: "Patient", ResourceID: "p1", Operation: models.ChangeOperationCreate,
+	})
+	if recordError != nil {
+		t.Fatalf("Failed to record change: %v", recordError)
+	}
+	secondChange, _ := changeRepository.Record(ctx, &models.ResourceChange{
+		ResourceType: "Patient", ResourceID: "p1", Operation: models.ChangeOperationUpdate,
+	})
+	otherChange, _ := changeRepository.Record(ctx, &models.ResourceChange{
+		ResourceType: "Patient", ResourceID: "p2", Operation: models.ChangeOperationCreate,
+	})
+
+	if firstChange.Version != 1 || secondChange.Version != 2 {
+		t.Errorf("Expected versions 1 and 2, got %d and %d", firstChange.Version, secondChange.Version)
+	}
+	if otherChange.Version != 1 {
+		t.Errorf("Expected version 1 for a different resource, got %d", otherChange.Version)
+	}
+	if secondChange.Sequence <= firstChange.Sequence {
+		t.Error("Expected sequences to increase")
+	}
+}
+
+// TestPostgresChangeRepository_ListSince verifies paging from a watermark
+func TestPostgresChangeRepository_ListSince(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupChangeTestData(t, databaseConnection)
+	defer cleanupChangeTestData(t, databaseConnection)
+
+	changeRepository := NewPostgresChangeRepository(databaseConnection)
+	ctx := context.Background()
+
+	for _, resourceID := range []string{"a", "b", "c"} {
+		changeRepository.Record(ctx, &models.ResourceChange{
+			ResourceType: "Observation", ResourceID: resourceID, Operation: models.ChangeOperationCreate,
+		})
+	}
+
+	firstPage, listError := changeRepository.ListSince(ctx, 0, 2)
+	if listError != nil {
+		t.Fatalf("Failed to list changes: %v", listError)
+	}
+	if len(firstPage) != 2 || firstPage[0].ResourceID != "a" {
+		t.Fatalf("Expected first page [a b], got %d entries", len(firstPage))
+	}
+
+	secondPage, _ := changeRepository.ListSince(ctx, firstPage[1].Sequence, 2)
+	if len(secondPage) != 1 || secondPage[0].ResourceID != "c" {
+		t.Errorf("Expected second page [c], got %d entries", len(secondPage))
+	}
+}
+
+// TestPostgresChangeRepository_InTransactionRollsBack verifies a failed unit of work leaves no change behind
+func TestPostgresChangeRepository_InTransactionRollsBack(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupChangeTestData(t, databaseConnection)
+	defer cleanupChangeTestData(t, databaseConnection)
+
+	changeRepository := NewPostgresChangeRepository(databaseConnection)
+	ctx := context.Background()
+
+	writeError := errors.New("resource write failed")
+	transactionError := changeRepository.InTransaction(ctx, func(transactionContext context.Context) error {
+		if _, recordError := changeRepository.Record(transactionContext, &models.ResourceChange{
+			ResourceType: "Patient", ResourceID: "p1", Operation: models.ChangeOperationCreate,
+		}); recordError != nil {
+			return recordError
+		}
+		return writeError
+	})
+	if !errors.Is(transactionError, writeError) {
+		t.Fatalf("Expected the write error, got %v", transactionError)
+	}
+
+	changes, _ := changeRepository.ListSince(ctx, 0, 10)
+	if len(changes) != 0 {
+		t.Errorf("Expected the change to be rolled back, found %d", len(changes))
+	}
+}
+
+// failingRecordListener refuses every recorded change
+type failingRecordListener struct{}
+
+// ChangeRecorded fails the change's transaction
+func (failingRecordListener) ChangeRecorded(ctx context.Context, change *models.ResourceChange) error {
+	return errors.New("outbox unavailable")
+}
+
+// TestPostgresChangeRepository_RecordListenerFailureRollsBack verifies a failing listener keeps the change out of the log
+func TestPostgresChangeRepository_RecordListenerFailureRollsBack(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupChangeTestData(t, databaseConnection)
+	defer cleanupChangeTestData(t, databaseConnection)
+
+	changeRepository := NewPostgresChangeRepository(databaseConnection)
+	changeRepository.AddRecordListener(failingRecordListener{})
+	ctx := context.Background()
+
+	if _, recordError := changeRepository.Record(ctx, &models.ResourceChange{
+		ResourceType: "Patient", ResourceID: "p1", Operation: models.ChangeOperationCreate,
+	}); recordError == nil {
+		t.Fatal("Expected the listener failure returned")
+	}
+	if changes, _ := changeRepository.ListSince(ctx, 0, 10); len(changes) != 0 {
+		t.Errorf("Expected the change rolled back, got %d changes", len(changes))
+	}
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// ClientRepository defines the interface for registered OAuth2 client storage
+type ClientRepository interface {
+	// Create stores a new client registration
+	Create(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error)
+
+	// GetByID retrieves a client by its client ID
+	GetByID(ctx context.Context, clientID string) (*models.RegisteredClient, error)
+
+	// List retrieves clients, optionally filtered by status (empty status returns all)
+	List(ctx context.Context, status models.ClientStatus) ([]*models.RegisteredClient, error)
+
+	// UpdateStatus records a review decision for a client
+	UpdateStatus(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error)
+}
+
+// PostgresClientRepository implements ClientRepository using PostgreSQL
+type PostgresClientRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresClientRepository creates a new PostgreSQL client repository instance
+func NewPostgresClientRepository(databaseConnection *sql.DB) *PostgresClientRepository {
+	return &PostgresClientRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// clientColumns lists the columns read by every client query, in scan order
+const clientColumns = `client_id, client_name, client_type, token_endpoint_auth_method, grant_types, redirect_uris,
+	jwks_uri, jwks, scopes, secret_hash, status, review_note, created_at, reviewed_at`
+
+// Create inserts a new client registration
+func (repository *PostgresClientRepository) Create(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error) {
+	insertQuery := `
+		INSERT INTO oauth_clients (client_id, client_name, client_type, token_endpoint_auth_method, grant_types,
+			redirect_uris, jwks_uri, jwks, scopes, secret_hash, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING created_at
+	`
+
+	scanError := repository.databaseConnection.QueryRowContext(
+		ctx,
+		insertQuery,
+		client.ClientID,
+		client.ClientName,
+		client.ClientType,
+		client.TokenEndpointAuthMethod,
+		pq.Array(client.GrantTypes),
+		pq.Array(client.RedirectURIs),
+		nullableString(client.JWKSURI),
+		nullableBytes(client.JWKS),
+		pq.Array(client.Scopes),
+		nullableString(client.SecretHash),
+		client.Status,
+	).Scan(&client.CreatedAt)
+
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return client, nil
+}
+
+// GetByID retrieves a client by its client ID
+func (repository *PostgresClientRepository) GetByID(ctx context.Context, clientID string) (*models.RegisteredClient, error) {
+	selectQuery := `SELECT ` + clientColumns + ` FROM oauth_clients WHERE client_id = $1`
+
+	return scanClient(repository.databaseConnection.QueryRowContext(ctx, selectQuery, clientID))
+}
+
+// List retrieves clients, oldest first, optionally filtered by status
+func (repository *PostgresClientRepository) List(ctx context.Context, status models.ClientStatus) ([]*models.RegisteredClient, error) {
+	selectQuery := `SELECT ` + clientColumns + ` FROM oauth_clients WHERE ($1 = '' OR status = $1) ORDER BY created_at ASC`
+
+	// Execute the query
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, string(status))
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	// Scan results into client structs
+	clients := []*models.RegisteredClient{}
+	for rows.Next() {
+		client, scanError := scanClient(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		clients = append(clients, client)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return clients, nil
+}
+
+// UpdateStatus stores the client's status, review note, and review time
+func (repository *PostgresClientRepository) UpdateStatus(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error) {
+	updateQuery := `
+		UPDATE oauth_clients
+		SET status = $1, review_note = $2, reviewed_at = $3
+		WHERE client_id = $4
+	`
+
+	result, updateError := repository.databaseConnection.ExecContext(
+		ctx,
+		updateQuery,
+		client.Status,
+		nullableString(client.ReviewNote),
+		client.ReviewedAt,
+		client.ClientID,
+	)
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	// Report a missing client the same way GetByID does
+	rowsAffected, rowsError := result.RowsAffected()
+	if rowsError != nil {
+		return nil, rowsError
+	}
+	if rowsAffected == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return client, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanClient reads one client row selected with clientColumns
+func scanClient(row rowScanner) (*models.RegisteredClient, error) {
+	client := &models.RegisteredClient{}
+	var jwksURI, secretHash, reviewNote sql.NullString
+	var jwks []byte
+
+	scanError := row.Scan(
+		&client.ClientID,
+		&client.ClientName,
+		&client.ClientType,
+		&client.TokenEndpointAuthMethod,
+		pq.Array(&client.GrantTypes),
+		pq.Array(&client.RedirectURIs),
+		&jwksURI,
+		&jwks,
+		pq.Array(&client.Scopes),
+		&secretHash,
+		&client.Status,
+		&reviewNote,
+		&client.CreatedAt,
+		&client.ReviewedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	client.JWKSURI = jwksURI.String
+	client.JWKS = jwks
+	client.SecretHash = secretHash.String
+	client.ReviewNote = reviewNote.String
+
+	return client, nil
+}
+
+// nullableString stores empty strings as NULL
+func nullableString(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// nullableBytes stores empty byte slices as NULL
+func nullableBytes(value []byte) interface{} {
+	if len(value) == 0 {
+		return nil
+	}
+	return value
+}
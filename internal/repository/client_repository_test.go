@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupClientTestData removes all entries from the oauth_clients table
+func cleanupClientTestData(t *testing.T, databaseConnection *sql.DB) {
+	_, deleteError := databaseConnection.Exec("DELETE FROM oauth_clients")
+	if deleteError != nil {
+		t.Fatalf("Failed to cleanup clients: %v", deleteError)
+	}
+}
+
+// TestPostgresClientRepository_CreateAndReview verifies a registration round-trips and can be approved
+func TestPostgresClientRepository_CreateAndReview(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupClientTestData(t, databaseConnection)
+	defer cleanupClientTestData(t, databaseConnection)
+
+	clientRepository := NewPostgresClientRepository(databaseConnection)
+	ctx := context.Background()
+
+	client := &models.RegisteredClient{
+		ClientID:                uuid.NewString(),
+		ClientName:              "Partner App",
+		ClientType:              models.ClientTypeBackend,
+		TokenEndpointAuthMethod: "private_key_jwt",
+		GrantTypes:              []string{"client_credentials"},
+		JWKS:                    []byte(`{"keys": []}`),
+		Scopes:                  []string{"system/*.read"},
+		Status:                  models.ClientStatusPending,
+	}
+	if _, createError := clientRepository.Create(ctx, client); createError != nil {
+		t.Fatalf("Failed to create client: %v", createError)
+	}
+
+	pendingClients, listError := clientRepository.List(ctx, models.ClientStatusPending)
+	if listError != nil || len(pendingClients) != 1 {
+		t.Fatalf("Expected 1 pending client, got %d (%v)", len(pendingClients), listError)
+	}
+	if pendingClients[0].Scopes[0] != "system/*.read" || len(pendingClients[0].JWKS) == 0 {
+		t.Errorf("Unexpected stored client: %+v", pendingClients[0])
+	}
+
+	reviewedAt := time.Now()
+	client.Status = models.ClientStatusApproved
+	client.ReviewNote = "verified partner"
+	client.ReviewedAt = &reviewedAt
+	if _, updateError := clientRepository.UpdateStatus(ctx, client); updateError != nil {
+		t.Fatalf("Failed to update client: %v", updateError)
+	}
+
+	storedClient, getError := clientRepository.GetByID(ctx, client.ClientID)
+	if getError != nil {
+		t.Fatalf("Failed to get client: %v", getError)
+	}
+	if storedClient.Status != models.ClientStatusApproved || storedClient.ReviewNote != "verified partner" {
+		t.Errorf("Expected approved client with note, got %+v", storedClient)
+	}
+}
+
+// TestPostgresClientRepository_UpdateStatusNotFound verifies unknown clients are reported
+func TestPostgresClientRepository_UpdateStatusNotFound(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+
+	clientRepository := NewPostgresClientRepository(databaseConnection)
+	_, updateError := clientRepository.UpdateStatus(context.Background(), &models.RegisteredClient{
+		ClientID: uuid.NewString(),
+		Status:   models.ClientStatusApproved,
+	})
+
+	if !errors.Is(updateError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows, got %v", updateError)
+	}
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ConditionRepository defines the interface for condition data access
+type ConditionRepository interface {
+	// Create inserts a new condition and returns it with its generated ID
+	Create(ctx context.Context, condition *models.Condition) (*models.Condition, error)
+
+	// GetByID retrieves a condition by ID
+	GetByID(ctx context.Context, conditionID string) (*models.Condition, error)
+
+	// Search retrieves conditions matching the search criteria
+	Search(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*models.Condition, error)
+
+	// Count returns how many conditions match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.ConditionSearchParams) (int, error)
+
+	// Update replaces the condition's content
+	Update(ctx context.Context, condition *models.Condition) (*models.Condition, error)
+
+	// Delete marks a condition deleted by ID
+	Delete(ctx context.Context, conditionID string) error
+}
+
+// ErrConditionNotFound is returned when no condition has the requested ID
+var ErrConditionNotFound = errors.New("condition not found")
+
+// MongoConditionRepository implements ConditionRepository using MongoDB
+type MongoConditionRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoConditionRepository creates a new MongoDB condition repository
+func NewMongoConditionRepository(database *mongo.Database) *MongoConditionRepository {
+	return &MongoConditionRepository{
+		collection: database.Collection("conditions"),
+	}
+}
+
+// Create inserts a new condition into MongoDB under a generated ID
+func (repository *MongoConditionRepository) Create(ctx context.Context, condition *models.Condition) (*models.Condition, error) {
+	// The server assigns IDs, and timestamps are set on every insert
+	condition.ID = ""
+	condition.CreatedAt = time.Now()
+	condition.UpdatedAt = time.Now()
+
+	result, insertError := repository.collection.InsertOne(ctx, condition)
+	if insertError != nil {
+		return nil, fmt.Errorf("failed to insert condition: %w", insertError)
+	}
+
+	// Set the generated ID
+	if objectID, ok := result.InsertedID.(primitive.ObjectID); ok {
+		condition.ID = objectID.Hex()
+	}
+
+	return condition, nil
+}
+
+// GetByID retrieves a condition by ID
+// Returns ErrConditionNotFound when the ID is malformed or no condition has it, wrapped with ErrResourceDeleted when it was deleted
+func (repository *MongoConditionRepository) GetByID(ctx context.Context, conditionID string) (*models.Condition, error) {
+	objectID, convertError := primitive.ObjectIDFromHex(conditionID)
+	if convertError != nil {
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrConditionNotFound, conditionID)
+	}
+
+	var condition models.Condition
+	findError := repository.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&condition)
+	if errors.Is(findError, mongo.ErrNoDocuments) {
+		return nil, missingDocumentError(ctx, repository.collection, objectID, ErrConditionNotFound)
+	}
+	if findError != nil {
+		return nil, fmt.Errorf("failed to find condition: %w", findError)
+	}
+
+	return &condition, nil
+}
+
+// conditionSearchFilter builds the filter matching conditions on the search criteria, shared by
+// Search and Count so a page and its total always agree
+func conditionSearchFilter(searchParams *models.ConditionSearchParams) bson.M {
+	filter := bson.M{}
+
+	// Add patient ID filter
+	if searchParams.PatientID != "" {
+		filter["patient_id"] = searchParams.PatientID
+	}
+
+	// Add code filter; "|code" matches codes stored without a system
+	if searchParams.Code != nil {
+		switch {
+		case searchParams.Code.System != "":
+			filter["code_system"] = searchParams.Code.System
+		case !searchParams.Code.AnySystem:
+			filter["code_system"] = bson.M{"$in": bson.A{nil, ""}}
+		}
+		if searchParams.Code.Code != "" {
+			filter["code"] = searchParams.Code.Code
+		}
+	}
+
+	// Add clinical status filter
+	if searchParams.ClinicalStatus != "" {
+		filter["clinical_status"] = searchParams.ClinicalStatus
+	}
+
+	// Add onset date range filters
+	if searchParams.OnsetDateGreaterThan != nil || searchParams.OnsetDateLessThan != nil {
+		onsetRange := bson.M{}
+		if searchParams.OnsetDateGreaterThan != nil {
+			onsetRange["$gte"] = searchParams.OnsetDateGreaterThan
+		}
+		if searchParams.OnsetDateLessThan != nil {
+			onsetRange["$lte"] = searchParams.OnsetDateLessThan
+		}
+		filter["onset_date"] = onsetRange
+	}
+
+	return notDeleted(filter)
+}
+
+// Search retrieves conditions matching the search criteria, most recently recorded first
+func (repository *MongoConditionRepository) Search(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*models.Condition, error) {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(searchParams.Limit)).
+		SetSkip(int64(searchParams.Offset))
+
+	cursor, findError := repository.collection.Find(ctx, conditionSearchFilter(searchParams), findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("failed to search conditions: %w", findError)
+	}
+	defer cursor.Close(ctx)
+
+	conditions := make([]*models.Condition, 0)
+	if decodeError := cursor.All(ctx, &conditions); decodeError != nil {
+		return nil, fmt.Errorf("failed to decode conditions: %w", decodeError)
+	}
+
+	return conditions, nil
+}
+
+// Count returns how many conditions match the search criteria, ignoring the page's limit and offset
+func (repository *MongoConditionRepository) Count(ctx context.Context, searchParams *models.ConditionSearchParams) (int, error) {
+	total, countError := repository.collection.CountDocuments(ctx, conditionSearchFilter(searchParams))
+	if countError != nil {
+		return 0, countError
+	}
+	return int(total), nil
+}
+
+// Update replaces an existing condition's content, keeping its creation time
+// Returns ErrConditionNotFound when the ID is malformed or no condition has it, wrapped with ErrResourceDeleted when it was deleted
+func (repository *MongoConditionRepository) Update(ctx context.Context, condition *models.Condition) (*models.Condition, error) {
+	objectID, convertError := primitive.ObjectIDFromHex(condition.ID)
+	if convertError != nil {
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrConditionNotFound, condition.ID)
+	}
+
+	condition.UpdatedAt = time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"patient_id":          condition.PatientID,
+			"clinical_status":     condition.ClinicalStatus,
+			"verification_status": condition.VerificationStatus,
+			"code":                condition.Code,
+			"code_system":         condition.CodeSystem,
+			"code_display":        condition.CodeDisplay,
+			"onset_date_time":     condition.OnsetDateTime,
+			"onset_date":          condition.OnsetDate,
+			"updated_at":          condition.UpdatedAt,
+		},
+	}
+
+	var updatedCondition models.Condition
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	updateError := repository.collection.FindOneAndUpdate(ctx, notDeleted(bson.M{"_id": objectID}), update, updateOptions).Decode(&updatedCondition)
+	if errors.Is(updateError, mongo.ErrNoDocuments) {
+		return nil, missingDocumentError(ctx, repository.collection, objectID, ErrConditionNotFound)
+	}
+	if updateError != nil {
+		return nil, fmt.Errorf("failed to update condition: %w", updateError)
+	}
+
+	return &updatedCondition, nil
+}
+
+// Delete marks a condition deleted by ID, leaving the document for a read of it to answer as deleted
+// Returns ErrConditionNotFound when the ID is malformed or no condition has it, wrapped with ErrResourceDeleted when it was already deleted
+func (repository *MongoConditionRepository) Delete(ctx context.Context, conditionID string) error {
+	objectID, convertError := primitive.ObjectIDFromHex(conditionID)
+	if convertError != nil {
+		return fmt.Errorf("%w: invalid ID %q", ErrConditionNotFound, conditionID)
+	}
+
+	return softDeleteDocument(ctx, repository.collection, objectID, ErrConditionNotFound)
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestMongoConditionRepository_CRUD verifies a condition is created, read, updated, and deleted
+func TestMongoConditionRepository_CRUD(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	conditionRepository := NewMongoConditionRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, conditionRepository.collection)
+	ctx := context.Background()
+
+	createdCondition, createError := conditionRepository.Create(ctx, &models.Condition{
+		PatientID:      "patient-123",
+		ClinicalStatus: "active",
+		Code:           "44054006",
+		CodeSystem:     "http://snomed.info/sct",
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create condition: %v", createError)
+	}
+	if createdCondition.ID == "" {
+		t.Fatalf("Expected a generated ID, got %+v", createdCondition)
+	}
+
+	createdCondition.ClinicalStatus = "resolved"
+	if _, updateError := conditionRepository.Update(ctx, createdCondition); updateError != nil {
+		t.Fatalf("Failed to update condition: %v", updateError)
+	}
+
+	storedCondition, getError := conditionRepository.GetByID(ctx, createdCondition.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read condition: %v", getError)
+	}
+	if storedCondition.ClinicalStatus != "resolved" || storedCondition.Code != "44054006" {
+		t.Errorf("Expected the resolved condition, got %+v", storedCondition)
+	}
+
+	if deleteError := conditionRepository.Delete(ctx, createdCondition.ID); deleteError != nil {
+		t.Fatalf("Failed to delete condition: %v", deleteError)
+	}
+	if _, getError := conditionRepository.GetByID(ctx, createdCondition.ID); !errors.Is(getError, ErrResourceDeleted) || !errors.Is(getError, ErrConditionNotFound) {
+		t.Errorf("Expected the deleted condition reported as deleted, got %v", getError)
+	}
+	if _, updateError := conditionRepository.Update(ctx, createdCondition); !errors.Is(updateError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted updating the deleted condition, got %v", updateError)
+	}
+	if deleteError := conditionRepository.Delete(ctx, createdCondition.ID); !errors.Is(deleteError, ErrConditionNotFound) || !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrConditionNotFound and ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+	if count, _ := conditionRepository.Count(ctx, &models.ConditionSearchParams{PatientID: "patient-123"}); count != 0 {
+		t.Errorf("Expected the deleted condition left out of searches, got %d", count)
+	}
+	if _, getError := conditionRepository.GetByID(ctx, "not-an-object-id"); !errors.Is(getError, ErrConditionNotFound) {
+		t.Errorf("Expected ErrConditionNotFound for a malformed ID, got %v", getError)
+	}
+}
+
+// TestMongoConditionRepository_Search verifies patient, code, clinical status, and onset filters and the total
+func TestMongoConditionRepository_Search(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	conditionRepository := NewMongoConditionRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, conditionRepository.collection)
+	ctx := context.Background()
+
+	earlyOnset := time.Date(2015, 4, 1, 0, 0, 0, 0, time.UTC)
+	lateOnset := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	for _, condition := range []*models.Condition{
+		{PatientID: "patient-1", ClinicalStatus: "active", CodeSystem: "http://snomed.info/sct", Code: "44054006", OnsetDate: &earlyOnset},
+		{PatientID: "patient-1", ClinicalStatus: "resolved", Code: "195662009", OnsetDate: &lateOnset},
+		{PatientID: "patient-2", ClinicalStatus: "active", CodeSystem: "http://snomed.info/sct", Code: "44054006"},
+	} {
+		if _, createError := conditionRepository.Create(ctx, condition); createError != nil {
+			t.Fatalf("Failed to create condition: %v", createError)
+		}
+	}
+
+	onsetCutoff := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		searchParams  models.ConditionSearchParams
+		expectedCount int
+	}{
+		"patient":         {searchParams: models.ConditionSearchParams{PatientID: "patient-1"}, expectedCount: 2},
+		"code any system": {searchParams: models.ConditionSearchParams{Code: &models.CodingCriterion{Code: "44054006", AnySystem: true}}, expectedCount: 2},
+		"code no system":  {searchParams: models.ConditionSearchParams{Code: &models.CodingCriterion{Code: "195662009"}}, expectedCount: 1},
+		"clinical status": {searchParams: models.ConditionSearchParams{ClinicalStatus: "active"}, expectedCount: 2},
+		"onset after":     {searchParams: models.ConditionSearchParams{OnsetDateGreaterThan: &onsetCutoff}, expectedCount: 1},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		conditions, searchError := conditionRepository.Search(ctx, &testCase.searchParams)
+		if searchError != nil {
+			t.Fatalf("%s: search failed: %v", name, searchError)
+		}
+		total, countError := conditionRepository.Count(ctx, &testCase.searchParams)
+		if countError != nil {
+			t.Fatalf("%s: count failed: %v", name, countError)
+		}
+		if len(conditions) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d conditions, got %d with total %d", name, testCase.expectedCount, len(conditions), total)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// DeliveryRepository defines the interface for the persistent outbound delivery queue
+type DeliveryRepository interface {
+	// Enqueue stores a new pending delivery, due immediately unless NextAttemptAt is set
+	Enqueue(ctx context.Context, delivery *models.Delivery) (*models.Delivery, error)
+
+	// EnqueueAll stores several pending deliveries in one transaction, so either all are queued or none are
+	EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error)
+
+	// ClaimDue returns up to limit pending deliveries due at now and leases them until leaseUntil
+	// so other workers skip them while they are being sent
+	ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*models.Delivery, error)
+
+	// Complete removes a delivered delivery
+	Complete(ctx context.Context, id int64) error
+
+	// Reschedule records a failed or deferred attempt and the time of the next one
+	Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string) error
+
+	// MarkDead moves a delivery to the dead letters
+	MarkDead(ctx context.Context, id int64, attempts int, lastError string) error
+
+	// ListDead returns dead letters, oldest first
+	ListDead(ctx context.Context, limit int, offset int) ([]*models.Delivery, error)
+
+	// Requeue moves a dead letter back to pending with its attempts reset; sql.ErrNoRows when not dead
+	Requeue(ctx context.Context, id int64) error
+
+	// DeleteDead discards a dead letter; sql.ErrNoRows when not dead
+	DeleteDead(ctx context.Context, id int64) error
+
+	// CountByStatus returns the number of deliveries in each status
+	CountByStatus(ctx context.Context) (map[models.DeliveryStatus]int64, error)
+}
+
+// PostgresDeliveryRepository implements DeliveryRepository using PostgreSQL
+type PostgresDeliveryRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresDeliveryRepository creates a new PostgreSQL delivery queue repository instance
+func NewPostgresDeliveryRepository(databaseConnection *sql.DB) *PostgresDeliveryRepository {
+	return &PostgresDeliveryRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// deliveryColumns lists the columns scanned by scanDelivery
+const deliveryColumns = `id, kind, destination, payload, content_type, status, attempts, last_error, next_attempt_at, created_at, updated_at`
+
+// Enqueue inserts a pending delivery, in the caller's transaction when ctx carries one
+func (repository *PostgresDeliveryRepository) Enqueue(ctx context.Context, delivery *models.Delivery) (*models.Delivery, error) {
+	nextAttemptAt := delivery.NextAttemptAt
+	if nextAttemptAt.IsZero() {
+		nextAttemptAt = time.Now()
+	}
+
+	insertQuery := `
+		INSERT INTO delivery_queue (kind, destination, payload, content_type, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + deliveryColumns
+
+	row := executorFor(ctx, repository.databaseConnection).QueryRowContext(ctx, insertQuery,
+		delivery.Kind, delivery.Destination, delivery.Payload, delivery.ContentType, nextAttemptAt)
+	return scanDelivery(row)
+}
+
+// EnqueueAll inserts the deliveries in one transaction, joining the caller's when ctx carries one
+func (repository *PostgresDeliveryRepository) EnqueueAll(ctx context.Context, deliveries []*models.Delivery) ([]*models.Delivery, error) {
+	queued := make([]*models.Delivery, 0, len(deliveries))
+	transactionError := runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		for _, delivery := range deliveries {
+			stored, enqueueError := repository.Enqueue(transactionContext, delivery)
+			if enqueueError != nil {
+				return enqueueError
+			}
+			queued = append(queued, stored)
+		}
+		return nil
+	})
+	if transactionError != nil {
+		return nil, transactionError
+	}
+	return queued, nil
+}
+
+// ClaimDue leases due deliveries; SKIP LOCKED lets several instances claim without blocking each other
+func (repository *PostgresDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, leaseUntil time.Time, limit int) ([]*models.Delivery, error) {
+	claimQuery := `
+		UPDATE delivery_queue
+		SET next_attempt_at = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT id FROM delivery_queue
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + deliveryColumns
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, claimQuery, now, leaseUntil, limit)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+// Complete deletes a delivered row
+func (repository *PostgresDeliveryRepository) Complete(ctx context.Context, id int64) error {
+	_, execError := repository.databaseConnection.ExecContext(ctx, "DELETE FROM delivery_queue WHERE id = $1", id)
+	return execError
+}
+
+// Reschedule stores the attempt count, error, and next attempt time
+func (repository *PostgresDeliveryRepository) Reschedule(ctx context.Context, id int64, attempts int, nextAttemptAt time.Time, lastError string) error {
+	updateQuery := `
+		UPDATE delivery_queue
+		SET attempts = $2, next_attempt_at = $3, last_error = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, execError := repository.databaseConnection.ExecContext(ctx, updateQuery, id, attempts, nextAttemptAt, lastError)
+	return execError
+}
+
+// MarkDead moves a delivery to the dead letters
+func (repository *PostgresDeliveryRepository) MarkDead(ctx context.Context, id int64, attempts int, lastError string) error {
+	updateQuery := `
+		UPDATE delivery_queue
+		SET status = 'dead', attempts = $2, last_error = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, execError := repository.databaseConnection.ExecContext(ctx, updateQuery, id, attempts, lastError)
+	return execError
+}
+
+// ListDead retrieves dead letters in the order they were created
+func (repository *PostgresDeliveryRepository) ListDead(ctx context.Context, limit int, offset int) ([]*models.Delivery, error) {
+	selectQuery := `
+		SELECT ` + deliveryColumns + `
+		FROM delivery_queue
+		WHERE status = 'dead'
+		ORDER BY created_at, id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, limit, offset)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	return scanDeliveries(rows)
+}
+
+// Requeue returns a dead letter to the pending queue, due immediately
+func (repository *PostgresDeliveryRepository) Requeue(ctx context.Context, id int64) error {
+	updateQuery := `
+		UPDATE delivery_queue
+		SET status = 'pending', attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'dead'
+	`
+	return execAffectingOne(ctx, repository.databaseConnection, updateQuery, id)
+}
+
+// DeleteDead removes a dead letter
+func (repository *PostgresDeliveryRepository) DeleteDead(ctx context.Context, id int64) error {
+	return execAffectingOne(ctx, repository.databaseConnection, "DELETE FROM delivery_queue WHERE id = $1 AND status = 'dead'", id)
+}
+
+// CountByStatus counts deliveries per status
+func (repository *PostgresDeliveryRepository) CountByStatus(ctx context.Context) (map[models.DeliveryStatus]int64, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, "SELECT status, COUNT(*) FROM delivery_queue GROUP BY status")
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	counts := map[models.DeliveryStatus]int64{}
+	for rows.Next() {
+		var status models.DeliveryStatus
+		var count int64
+		if scanError := rows.Scan(&status, &count); scanError != nil {
+			return nil, scanError
+		}
+		counts[status] = count
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return counts, nil
+}
+
+// execAffectingOne runs a statement for one row and returns sql.ErrNoRows when nothing matched
+func execAffectingOne(ctx context.Context, databaseConnection *sql.DB, query string, id int64) error {
+	result, execError := databaseConnection.ExecContext(ctx, query, id)
+	if execError != nil {
+		return execError
+	}
+	if affectedRows, _ := result.RowsAffected(); affectedRows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// scanDelivery reads one row selected with deliveryColumns
+func scanDelivery(row rowScanner) (*models.Delivery, error) {
+	delivery := &models.Delivery{}
+	scanError := row.Scan(
+		&delivery.ID,
+		&delivery.Kind,
+		&delivery.Destination,
+		&delivery.Payload,
+		&delivery.ContentType,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.LastError,
+		&delivery.NextAttemptAt,
+		&delivery.CreatedAt,
+		&delivery.UpdatedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+	return delivery, nil
+}
+
+// scanDeliveries reads every row selected with deliveryColumns
+func scanDeliveries(rows *sql.Rows) ([]*models.Delivery, error) {
+	deliveries := []*models.Delivery{}
+	for rows.Next() {
+		delivery, scanError := scanDelivery(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return deliveries, nil
+}
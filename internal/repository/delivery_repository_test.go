@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupDeliveryTestData empties the delivery queue
+func cleanupDeliveryTestData(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM delivery_queue"); deleteError != nil {
+		t.Fatalf("Failed to cleanup delivery_queue: %v", deleteError)
+	}
+}
+
+// TestPostgresDeliveryRepository_Lifecycle verifies claiming, leasing, retry scheduling, and dead letters
+func TestPostgresDeliveryRepository_Lifecycle(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupDeliveryTestData(t, databaseConnection)
+	defer cleanupDeliveryTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	deliveryRepository := NewPostgresDeliveryRepository(databaseConnection)
+
+	queued, enqueueError := deliveryRepository.Enqueue(ctx, &models.Delivery{
+		Kind: "webhook", Destination: "https://partner.example/hook", ContentType: "application/json", Payload: []byte(`{"n":1}`),
+	})
+	if enqueueError != nil || queued.Status != models.DeliveryStatusPending {
+		t.Fatalf("Unexpected enqueue result %+v (%v)", queued, enqueueError)
+	}
+
+	now := time.Now().Add(time.Second)
+	claimed, claimError := deliveryRepository.ClaimDue(ctx, now, now.Add(time.Minute), 10)
+	if claimError != nil || len(claimed) != 1 || string(claimed[0].Payload) != `{"n":1}` {
+		t.Fatalf("Expected one claimed delivery, got %+v (%v)", claimed, claimError)
+	}
+
+	// A leased delivery is not claimed again until the lease expires
+	if reclaimed, _ := deliveryRepository.ClaimDue(ctx, now, now.Add(time.Minute), 10); len(reclaimed) != 0 {
+		t.Errorf("Expected leased delivery to be skipped, got %d", len(reclaimed))
+	}
+
+	deliveryRepository.Reschedule(ctx, queued.ID, 1, now, "connection refused")
+	if rescheduled, _ := deliveryRepository.ClaimDue(ctx, now, now.Add(time.Minute), 10); len(rescheduled) != 1 || rescheduled[0].Attempts != 1 {
+		t.Errorf("Expected rescheduled delivery with one attempt, got %+v", rescheduled)
+	}
+
+	if requeueError := deliveryRepository.Requeue(ctx, queued.ID); !errors.Is(requeueError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows requeuing a pending delivery, got %v", requeueError)
+	}
+
+	deliveryRepository.MarkDead(ctx, queued.ID, 5, "webhook responded 410")
+	deadLetters, listError := deliveryRepository.ListDead(ctx, 10, 0)
+	if listError != nil || len(deadLetters) != 1 || deadLetters[0].LastError != "webhook responded 410" {
+		t.Fatalf("Unexpected dead letters %+v (%v)", deadLetters, listError)
+	}
+
+	counts, _ := deliveryRepository.CountByStatus(ctx)
+	if counts[models.DeliveryStatusDead] != 1 || counts[models.DeliveryStatusPending] != 0 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+
+	if requeueError := deliveryRepository.Requeue(ctx, queued.ID); requeueError != nil {
+		t.Fatalf("Expected no error requeuing, got %v", requeueError)
+	}
+	deliveryRepository.MarkDead(ctx, queued.ID, 1, "again")
+	if deleteError := deliveryRepository.DeleteDead(ctx, queued.ID); deleteError != nil {
+		t.Errorf("Expected no error deleting dead letter, got %v", deleteError)
+	}
+	if deleteError := deliveryRepository.DeleteDead(ctx, queued.ID); !errors.Is(deleteError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", deleteError)
+	}
+}
+
+// TestPostgresDeliveryRepository_EnqueueAllRollsBackWithCaller verifies deliveries queued in a caller's
+// transaction are discarded when that transaction rolls back
+func TestPostgresDeliveryRepository_EnqueueAllRollsBackWithCaller(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupDeliveryTestData(t, databaseConnection)
+	defer cleanupDeliveryTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	deliveryRepository := NewPostgresDeliveryRepository(databaseConnection)
+	deliveries := []*models.Delivery{
+		{Kind: "webhook", Destination: "https://one.example/hook", ContentType: "application/json", Payload: []byte(`{}`)},
+		{Kind: "mllp", Destination: "lab:2575", ContentType: "x-application/hl7-v2+er7", Payload: []byte("MSH|")},
+	}
+
+	callerError := errors.New("write failed")
+	transactionError := runInTransaction(ctx, databaseConnection, func(transactionContext context.Context) error {
+		if _, enqueueError := deliveryRepository.EnqueueAll(transactionContext, deliveries); enqueueError != nil {
+			return enqueueError
+		}
+		return callerError
+	})
+	if !errors.Is(transactionError, callerError) {
+		t.Fatalf("Expected the caller's error, got %v", transactionError)
+	}
+	if counts, _ := deliveryRepository.CountByStatus(ctx); counts[models.DeliveryStatusPending] != 0 {
+		t.Errorf("Expected no deliveries after rollback, got %v", counts)
+	}
+
+	if queued, enqueueError := deliveryRepository.EnqueueAll(ctx, deliveries); enqueueError != nil || len(queued) != 2 {
+		t.Errorf("Expected both deliveries queued, got %d (%v)", len(queued), enqueueError)
+	}
+}
@@ -0,0 +1,214 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DiagnosticReportRepository defines the interface for diagnostic report data access
+type DiagnosticReportRepository interface {
+	// Create inserts a new diagnostic report and returns it with its generated ID
+	Create(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error)
+
+	// GetByID retrieves a diagnostic report by ID
+	GetByID(ctx context.Context, diagnosticReportID string) (*models.DiagnosticReport, error)
+
+	// Search retrieves diagnostic reports matching the search criteria
+	Search(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) ([]*models.DiagnosticReport, error)
+
+	// Count returns how many diagnostic reports match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) (int, error)
+
+	// Update replaces the diagnostic report's content
+	Update(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error)
+
+	// Delete marks a diagnostic report deleted by ID
+	Delete(ctx context.Context, diagnosticReportID string) error
+}
+
+// ErrDiagnosticReportNotFound is returned when no diagnostic report has the requested ID
+var ErrDiagnosticReportNotFound = errors.New("diagnostic report not found")
+
+// MongoDiagnosticReportRepository implements DiagnosticReportRepository using MongoDB
+type MongoDiagnosticReportRepository struct {
+	collection *mongo.Collection
+}
+
+// NewMongoDiagnosticReportRepository creates a new MongoDB diagnostic report repository
+func NewMongoDiagnosticReportRepository(database *mongo.Database) *MongoDiagnosticReportRepository {
+	return &MongoDiagnosticReportRepository{
+		collection: database.Collection("diagnostic_reports"),
+	}
+}
+
+// Create inserts a new diagnostic report into MongoDB under a generated ID
+func (repository *MongoDiagnosticReportRepository) Create(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error) {
+	// The server assigns IDs, and timestamps are set on every insert
+	diagnosticReport.ID = ""
+	diagnosticReport.CreatedAt = time.Now()
+	diagnosticReport.UpdatedAt = time.Now()
+
+	result, insertError := repository.collection.InsertOne(ctx, diagnosticReport)
+	if insertError != nil {
+		return nil, fmt.Errorf("failed to insert diagnostic report: %w", insertError)
+	}
+
+	// Set the generated ID
+	if objectID, ok := result.InsertedID.(primitive.ObjectID); ok {
+		diagnosticReport.ID = objectID.Hex()
+	}
+
+	return diagnosticReport, nil
+}
+
+// GetByID retrieves a diagnostic report by ID
+// Returns ErrDiagnosticReportNotFound when the ID is malformed or no diagnostic report has it, wrapped with ErrResourceDeleted when it was deleted
+func (repository *MongoDiagnosticReportRepository) GetByID(ctx context.Context, diagnosticReportID string) (*models.DiagnosticReport, error) {
+	objectID, convertError := primitive.ObjectIDFromHex(diagnosticReportID)
+	if convertError != nil {
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrDiagnosticReportNotFound, diagnosticReportID)
+	}
+
+	var diagnosticReport models.DiagnosticReport
+	findError := repository.collection.FindOne(ctx, notDeleted(bson.M{"_id": objectID})).Decode(&diagnosticReport)
+	if errors.Is(findError, mongo.ErrNoDocuments) {
+		return nil, missingDocumentError(ctx, repository.collection, objectID, ErrDiagnosticReportNotFound)
+	}
+	if findError != nil {
+		return nil, fmt.Errorf("failed to find diagnostic report: %w", findError)
+	}
+
+	return &diagnosticReport, nil
+}
+
+// diagnosticReportSearchFilter builds the filter matching diagnostic reports on the search criteria, shared by
+// Search and Count so a page and its total always agree
+func diagnosticReportSearchFilter(searchParams *models.DiagnosticReportSearchParams) bson.M {
+	filter := bson.M{}
+
+	// Add patient ID filter
+	if searchParams.PatientID != "" {
+		filter["patient_id"] = searchParams.PatientID
+	}
+
+	// Add category filter
+	if searchParams.Category != "" {
+		filter["category"] = searchParams.Category
+	}
+
+	// Add code filter; "|code" matches codes stored without a system
+	if searchParams.Code != nil {
+		switch {
+		case searchParams.Code.System != "":
+			filter["code_system"] = searchParams.Code.System
+		case !searchParams.Code.AnySystem:
+			filter["code_system"] = bson.M{"$in": bson.A{nil, ""}}
+		}
+		if searchParams.Code.Code != "" {
+			filter["code"] = searchParams.Code.Code
+		}
+	}
+
+	// Add effective date range filters
+	if searchParams.DateGreaterThan != nil || searchParams.DateLessThan != nil {
+		effectiveRange := bson.M{}
+		if searchParams.DateGreaterThan != nil {
+			effectiveRange["$gte"] = searchParams.DateGreaterThan
+		}
+		if searchParams.DateLessThan != nil {
+			effectiveRange["$lte"] = searchParams.DateLessThan
+		}
+		filter["effective_date"] = effectiveRange
+	}
+
+	return notDeleted(filter)
+}
+
+// Search retrieves diagnostic reports matching the search criteria, most recently recorded first
+func (repository *MongoDiagnosticReportRepository) Search(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) ([]*models.DiagnosticReport, error) {
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(searchParams.Limit)).
+		SetSkip(int64(searchParams.Offset))
+
+	cursor, findError := repository.collection.Find(ctx, diagnosticReportSearchFilter(searchParams), findOptions)
+	if findError != nil {
+		return nil, fmt.Errorf("failed to search diagnostic reports: %w", findError)
+	}
+	defer cursor.Close(ctx)
+
+	diagnosticReports := make([]*models.DiagnosticReport, 0)
+	if decodeError := cursor.All(ctx, &diagnosticReports); decodeError != nil {
+		return nil, fmt.Errorf("failed to decode diagnostic reports: %w", decodeError)
+	}
+
+	return diagnosticReports, nil
+}
+
+// Count returns how many diagnostic reports match the search criteria, ignoring the page's limit and offset
+func (repository *MongoDiagnosticReportRepository) Count(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) (int, error) {
+	total, countError := repository.collection.CountDocuments(ctx, diagnosticReportSearchFilter(searchParams))
+	if countError != nil {
+		return 0, countError
+	}
+	return int(total), nil
+}
+
+// Update replaces an existing diagnostic report's content, keeping its creation time
+// Returns ErrDiagnosticReportNotFound when the ID is malformed or no diagnostic report has it, wrapped with ErrResourceDeleted when it was deleted
+func (repository *MongoDiagnosticReportRepository) Update(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error) {
+	objectID, convertError := primitive.ObjectIDFromHex(diagnosticReport.ID)
+	if convertError != nil {
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrDiagnosticReportNotFound, diagnosticReport.ID)
+	}
+
+	diagnosticReport.UpdatedAt = time.Now()
+	update := bson.M{
+		"$set": bson.M{
+			"patient_id":             diagnosticReport.PatientID,
+			"status":                 diagnosticReport.Status,
+			"category":               diagnosticReport.Category,
+			"category_system":        diagnosticReport.CategorySystem,
+			"category_display":       diagnosticReport.CategoryDisplay,
+			"code":                   diagnosticReport.Code,
+			"code_system":            diagnosticReport.CodeSystem,
+			"code_display":           diagnosticReport.CodeDisplay,
+			"effective_date_time":    diagnosticReport.EffectiveDateTime,
+			"effective_date":         diagnosticReport.EffectiveDate,
+			"result_observation_ids": diagnosticReport.ResultObservationIDs,
+			"conclusion":             diagnosticReport.Conclusion,
+			"updated_at":             diagnosticReport.UpdatedAt,
+		},
+	}
+
+	var updatedDiagnosticReport models.DiagnosticReport
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	updateError := repository.collection.FindOneAndUpdate(ctx, notDeleted(bson.M{"_id": objectID}), update, updateOptions).Decode(&updatedDiagnosticReport)
+	if errors.Is(updateError, mongo.ErrNoDocuments) {
+		return nil, missingDocumentError(ctx, repository.collection, objectID, ErrDiagnosticReportNotFound)
+	}
+	if updateError != nil {
+		return nil, fmt.Errorf("failed to update diagnostic report: %w", updateError)
+	}
+
+	return &updatedDiagnosticReport, nil
+}
+
+// Delete marks a diagnostic report deleted by ID, leaving the document for a read of it to answer as deleted
+// Returns ErrDiagnosticReportNotFound when the ID is malformed or no diagnostic report has it, wrapped with ErrResourceDeleted when it was already deleted
+func (repository *MongoDiagnosticReportRepository) Delete(ctx context.Context, diagnosticReportID string) error {
+	objectID, convertError := primitive.ObjectIDFromHex(diagnosticReportID)
+	if convertError != nil {
+		return fmt.Errorf("%w: invalid ID %q", ErrDiagnosticReportNotFound, diagnosticReportID)
+	}
+
+	return softDeleteDocument(ctx, repository.collection, objectID, ErrDiagnosticReportNotFound)
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestMongoDiagnosticReportRepository_CRUD verifies a diagnostic report is created, read, updated, and deleted
+func TestMongoDiagnosticReportRepository_CRUD(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	diagnosticReportRepository := NewMongoDiagnosticReportRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, diagnosticReportRepository.collection)
+	ctx := context.Background()
+
+	createdReport, createError := diagnosticReportRepository.Create(ctx, &models.DiagnosticReport{
+		PatientID:            "patient-123",
+		Status:               "preliminary",
+		Code:                 "58410-2",
+		CodeSystem:           "http://loinc.org",
+		ResultObservationIDs: []string{"obs-1", "obs-2"},
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create diagnostic report: %v", createError)
+	}
+	if createdReport.ID == "" {
+		t.Fatalf("Expected a generated ID, got %+v", createdReport)
+	}
+
+	createdReport.Status = "final"
+	createdReport.Conclusion = "Within normal limits"
+	if _, updateError := diagnosticReportRepository.Update(ctx, createdReport); updateError != nil {
+		t.Fatalf("Failed to update diagnostic report: %v", updateError)
+	}
+
+	storedReport, getError := diagnosticReportRepository.GetByID(ctx, createdReport.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read diagnostic report: %v", getError)
+	}
+	if storedReport.Status != "final" || storedReport.Conclusion != "Within normal limits" || len(storedReport.ResultObservationIDs) != 2 {
+		t.Errorf("Expected the final report with both results, got %+v", storedReport)
+	}
+
+	if deleteError := diagnosticReportRepository.Delete(ctx, createdReport.ID); deleteError != nil {
+		t.Fatalf("Failed to delete diagnostic report: %v", deleteError)
+	}
+	if deleteError := diagnosticReportRepository.Delete(ctx, createdReport.ID); !errors.Is(deleteError, ErrDiagnosticReportNotFound) {
+		t.Errorf("Expected ErrDiagnosticReportNotFound deleting twice, got %v", deleteError)
+	}
+	if _, getError := diagnosticReportRepository.GetByID(ctx, "not-an-object-id"); !errors.Is(getError, ErrDiagnosticReportNotFound) {
+		t.Errorf("Expected ErrDiagnosticReportNotFound for a malformed ID, got %v", getError)
+	}
+}
+
+// TestMongoDiagnosticReportRepository_Search verifies patient, category, code, and date filters and the total
+func TestMongoDiagnosticReportRepository_Search(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	diagnosticReportRepository := NewMongoDiagnosticReportRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, diagnosticReportRepository.collection)
+	ctx := context.Background()
+
+	earlyEffective := time.Date(2023, 2, 1, 0, 0, 0, 0, time.UTC)
+	lateEffective := time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)
+	for _, report := range []*models.DiagnosticReport{
+		{PatientID: "patient-1", Status: "final", Category: "LAB", CodeSystem: "http://loinc.org", Code: "58410-2", EffectiveDate: &earlyEffective},
+		{PatientID: "patient-1", Status: "final", Category: "RAD", Code: "36643-5", EffectiveDate: &lateEffective},
+		{PatientID: "patient-2", Status: "final", Category: "LAB", CodeSystem: "http://loinc.org", Code: "58410-2"},
+	} {
+		if _, createError := diagnosticReportRepository.Create(ctx, report); createError != nil {
+			t.Fatalf("Failed to create diagnostic report: %v", createError)
+		}
+	}
+
+	dateCutoff := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		searchParams  models.DiagnosticReportSearchParams
+		expectedCount int
+	}{
+		"patient":         {searchParams: models.DiagnosticReportSearchParams{PatientID: "patient-1"}, expectedCount: 2},
+		"category":        {searchParams: models.DiagnosticReportSearchParams{Category: "LAB"}, expectedCount: 2},
+		"code any system": {searchParams: models.DiagnosticReportSearchParams{Code: &models.CodingCriterion{Code: "58410-2", AnySystem: true}}, expectedCount: 2},
+		"code no system":  {searchParams: models.DiagnosticReportSearchParams{Code: &models.CodingCriterion{Code: "36643-5"}}, expectedCount: 1},
+		"date after":      {searchParams: models.DiagnosticReportSearchParams{DateGreaterThan: &dateCutoff}, expectedCount: 1},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		reports, searchError := diagnosticReportRepository.Search(ctx, &testCase.searchParams)
+		if searchError != nil {
+			t.Fatalf("%s: search failed: %v", name, searchError)
+		}
+		total, countError := diagnosticReportRepository.Count(ctx, &testCase.searchParams)
+		if countError != nil {
+			t.Fatalf("%s: count failed: %v", name, countError)
+		}
+		if len(reports) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d diagnostic reports, got %d with total %d", name, testCase.expectedCount, len(reports), total)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// EditLockRepository defines the interface for advisory edit locks shared by every server instance
+type EditLockRepository interface {
+	// Acquire stores lock unless another owner holds an unexpired lock on the resource at lock.AcquiredAt,
+	// returning the stored lock, or the other owner's lock and false. A lock the owner already holds is
+	// renewed to lock.ExpiresAt and keeps its acquisition time
+	Acquire(ctx context.Context, lock *models.EditLock) (*models.EditLock, bool, error)
+
+	// Delete removes the resource's unexpired lock at now when owner holds it, or whoever holds it when force,
+	// returning the lock it found, nil when there is none, and whether it was removed
+	Delete(ctx context.Context, resourceType string, resourceID string, owner string, force bool, now time.Time) (*models.EditLock, bool, error)
+
+	// Get returns the resource's unexpired lock at now, or nil when it is unlocked
+	Get(ctx context.Context, resourceType string, resourceID string, now time.Time) (*models.EditLock, error)
+}
+
+// PostgresEditLockRepository implements EditLockRepository using PostgreSQL
+type PostgresEditLockRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresEditLockRepository creates a new PostgreSQL edit lock repository instance
+func NewPostgresEditLockRepository(databaseConnection *sql.DB) *PostgresEditLockRepository {
+	return &PostgresEditLockRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// editLockColumns lists the columns scanned by scanEditLock
+const editLockColumns = `resource_type, resource_id, owner, acquired_at, expires_at`
+
+// editLockAcquireAttempts bounds how often Acquire retries when the other owner's lock is released or
+// expires between its insert and its read of that lock
+const editLockAcquireAttempts = 3
+
+// Acquire deletes expired locks, then inserts the lock in one statement that only replaces the resource's
+// row when it is expired or already held by the owner, so two servers can never both take a lock
+func (repository *PostgresEditLockRepository) Acquire(ctx context.Context, lock *models.EditLock) (*models.EditLock, bool, error) {
+	if _, sweepError := repository.databaseConnection.ExecContext(ctx,
+		`DELETE FROM edit_locks WHERE expires_at <= $1`, lock.AcquiredAt); sweepError != nil {
+		return nil, false, sweepError
+	}
+
+	acquireQuery := `
+		INSERT INTO edit_locks (` + editLockColumns + `)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (resource_type, resource_id) DO UPDATE
+		SET owner = EXCLUDED.owner,
+			acquired_at = CASE
+				WHEN edit_locks.owner = EXCLUDED.owner AND edit_locks.expires_at > EXCLUDED.acquired_at THEN edit_locks.acquired_at
+				ELSE EXCLUDED.acquired_at
+			END,
+			expires_at = EXCLUDED.expires_at
+		WHERE edit_locks.owner = EXCLUDED.owner OR edit_locks.expires_at <= EXCLUDED.acquired_at
+		RETURNING ` + editLockColumns
+
+	for attempt := 0; attempt < editLockAcquireAttempts; attempt++ {
+		storedLock, acquireError := scanEditLock(repository.databaseConnection.QueryRowContext(ctx, acquireQuery,
+			lock.ResourceType, lock.ResourceID, lock.Owner, lock.AcquiredAt, lock.ExpiresAt))
+		if acquireError == nil {
+			return storedLock, true, nil
+		}
+		if !errors.Is(acquireError, sql.ErrNoRows) {
+			return nil, false, acquireError
+		}
+
+		heldLock, getError := repository.Get(ctx, lock.ResourceType, lock.ResourceID, lock.AcquiredAt)
+		if getError != nil {
+			return nil, false, getError
+		}
+		if heldLock != nil {
+			return heldLock, false, nil
+		}
+	}
+	return nil, false, fmt.Errorf("edit lock on %s/%s kept changing while acquiring it", lock.ResourceType, lock.ResourceID)
+}
+
+// Delete removes the lock in one conditional DELETE, reading the lock it left in place when it removed none
+func (repository *PostgresEditLockRepository) Delete(ctx context.Context, resourceType string, resourceID string, owner string, force bool, now time.Time) (*models.EditLock, bool, error) {
+	deletedLock, deleteError := scanEditLock(repository.databaseConnection.QueryRowContext(ctx, `
+		DELETE FROM edit_locks
+		WHERE resource_type = $1 AND resource_id = $2 AND expires_at > $3 AND (owner = $4 OR $5)
+		RETURNING `+editLockColumns, resourceType, resourceID, now, owner, force))
+	if deleteError == nil {
+		return deletedLock, true, nil
+	}
+	if !errors.Is(deleteError, sql.ErrNoRows) {
+		return nil, false, deleteError
+	}
+
+	heldLock, getError := repository.Get(ctx, resourceType, resourceID, now)
+	return heldLock, false, getError
+}
+
+// Get reads the resource's row of edit_locks when it has not expired
+func (repository *PostgresEditLockRepository) Get(ctx context.Context, resourceType string, resourceID string, now time.Time) (*models.EditLock, error) {
+	activeLock, scanError := scanEditLock(repository.databaseConnection.QueryRowContext(ctx,
+		"SELECT "+editLockColumns+" FROM edit_locks WHERE resource_type = $1 AND resource_id = $2 AND expires_at > $3",
+		resourceType, resourceID, now))
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return activeLock, scanError
+}
+
+// scanEditLock reads one row selected with editLockColumns
+func scanEditLock(row *sql.Row) (*models.EditLock, error) {
+	lock := &models.EditLock{}
+	if scanError := row.Scan(&lock.ResourceType, &lock.ResourceID, &lock.Owner, &lock.AcquiredAt, &lock.ExpiresAt); scanError != nil {
+		return nil, scanError
+	}
+	return lock, nil
+}
@@ -0,0 +1,100 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupEditLockTestData empties the edit_locks table
+func cleanupEditLockTestData(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM edit_locks"); deleteError != nil {
+		t.Fatalf("Failed to cleanup edit locks: %v", deleteError)
+	}
+}
+
+// TestPostgresEditLockRepository_Lifecycle verifies acquiring, renewing, conflicting, releasing, and expiring a lock
+func TestPostgresEditLockRepository_Lifecycle(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupEditLockTestData(t, databaseConnection)
+	defer cleanupEditLockTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	lockRepository := NewPostgresEditLockRepository(databaseConnection)
+	acquiredAt := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	clerkLock := &models.EditLock{ResourceType: "Patient", ResourceID: "p1", Owner: "clerk-a", AcquiredAt: acquiredAt, ExpiresAt: acquiredAt.Add(5 * time.Minute)}
+
+	storedLock, acquired, acquireError := lockRepository.Acquire(ctx, clerkLock)
+	if acquireError != nil || !acquired || storedLock.Owner != "clerk-a" {
+		t.Fatalf("Expected clerk-a to take the lock, got %+v %v (%v)", storedLock, acquired, acquireError)
+	}
+
+	otherLock := &models.EditLock{ResourceType: "Patient", ResourceID: "p1", Owner: "clerk-b", AcquiredAt: acquiredAt.Add(time.Minute), ExpiresAt: acquiredAt.Add(6 * time.Minute)}
+	heldLock, acquired, _ := lockRepository.Acquire(ctx, otherLock)
+	if acquired || heldLock == nil || heldLock.Owner != "clerk-a" {
+		t.Errorf("Expected clerk-b to be refused with clerk-a's lock, got %+v %v", heldLock, acquired)
+	}
+
+	renewal := &models.EditLock{ResourceType: "Patient", ResourceID: "p1", Owner: "clerk-a", AcquiredAt: acquiredAt.Add(2 * time.Minute), ExpiresAt: acquiredAt.Add(20 * time.Minute)}
+	renewedLock, acquired, _ := lockRepository.Acquire(ctx, renewal)
+	if !acquired || !renewedLock.AcquiredAt.Equal(acquiredAt) || !renewedLock.ExpiresAt.Equal(renewal.ExpiresAt) {
+		t.Errorf("Expected the renewal to keep the acquisition time, got %+v %v", renewedLock, acquired)
+	}
+
+	checkedAt := acquiredAt.Add(3 * time.Minute)
+	if existingLock, released, _ := lockRepository.Delete(ctx, "Patient", "p1", "clerk-b", false, checkedAt); released || existingLock.Owner != "clerk-a" {
+		t.Errorf("Expected clerk-b's release to be refused, got %+v %v", existingLock, released)
+	}
+	if releasedLock, released, _ := lockRepository.Delete(ctx, "Patient", "p1", "supervisor", true, checkedAt); !released || releasedLock.Owner != "clerk-a" {
+		t.Errorf("Expected the forced release of clerk-a's lock, got %+v %v", releasedLock, released)
+	}
+	if missingLock, released, _ := lockRepository.Delete(ctx, "Patient", "p1", "clerk-a", false, checkedAt); released || missingLock != nil {
+		t.Errorf("Expected nothing left to release, got %+v %v", missingLock, released)
+	}
+
+	// An expired lock is no lock, and is taken over by the next owner
+	lockRepository.Acquire(ctx, clerkLock)
+	expiredAt := clerkLock.ExpiresAt
+	if expiredLock, _ := lockRepository.Get(ctx, "Patient", "p1", expiredAt); expiredLock != nil {
+		t.Errorf("Expected the lock to expire, got %+v", expiredLock)
+	}
+	takeover := &models.EditLock{ResourceType: "Patient", ResourceID: "p1", Owner: "clerk-b", AcquiredAt: expiredAt, ExpiresAt: expiredAt.Add(time.Minute)}
+	if takenLock, acquired, _ := lockRepository.Acquire(ctx, takeover); !acquired || takenLock.Owner != "clerk-b" || !takenLock.AcquiredAt.Equal(expiredAt) {
+		t.Errorf("Expected clerk-b to take the expired lock, got %+v %v", takenLock, acquired)
+	}
+}
+
+// TestPostgresEditLockRepository_AcquireIsAtomic verifies only one of many concurrent owners takes a lock
+func TestPostgresEditLockRepository_AcquireIsAtomic(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupEditLockTestData(t, databaseConnection)
+	defer cleanupEditLockTestData(t, databaseConnection)
+
+	lockRepository := NewPostgresEditLockRepository(databaseConnection)
+	acquiredAt := time.Now()
+	var acquiredCount atomic.Int64
+	var waitGroup sync.WaitGroup
+	for attempt := 0; attempt < 20; attempt++ {
+		waitGroup.Add(1)
+		go func(owner string) {
+			defer waitGroup.Done()
+			lock := &models.EditLock{ResourceType: "Patient", ResourceID: "p1", Owner: owner, AcquiredAt: acquiredAt, ExpiresAt: acquiredAt.Add(time.Minute)}
+			if _, acquired, acquireError := lockRepository.Acquire(context.Background(), lock); acquireError == nil && acquired {
+				acquiredCount.Add(1)
+			}
+		}(fmt.Sprintf("clerk-%d", attempt))
+	}
+	waitGroup.Wait()
+
+	if acquiredCount.Load() != 1 {
+		t.Errorf("Expected exactly one owner to take the lock, got %d", acquiredCount.Load())
+	}
+}
@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// EncounterRepository defines the interface for encounter data operations
+type EncounterRepository interface {
+	// Create inserts a new encounter record and returns the created encounter with ID
+	Create(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error)
+
+	// GetByID retrieves an encounter by its unique identifier
+	GetByID(ctx context.Context, encounterID string) (*models.Encounter, error)
+
+	// Search retrieves encounters matching the search criteria
+	Search(ctx context.Context, searchParams *models.EncounterSearchParams) ([]*models.Encounter, error)
+
+	// Count returns how many encounters match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.EncounterSearchParams) (int, error)
+
+	// Update modifies an existing encounter record
+	Update(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error)
+
+	// Delete marks an encounter deleted by ID
+	Delete(ctx context.Context, encounterID string) error
+}
+
+// encounterColumns are the columns read into a models.Encounter by scanEncounter, in order
+const encounterColumns = `id, status, class_system, class_code, class_display, patient_id, participant_practitioner_ids,
+		period_start, period_end, created_at, updated_at`
+
+// PostgresEncounterRepository implements EncounterRepository using PostgreSQL
+type PostgresEncounterRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresEncounterRepository creates a new PostgreSQL encounter repository instance
+func NewPostgresEncounterRepository(databaseConnection *sql.DB) *PostgresEncounterRepository {
+	return &PostgresEncounterRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// scanEncounter reads one row selected with encounterColumns
+// Optional columns are NULL when the encounter left them out, so they are read through nullable types
+func scanEncounter(row interface{ Scan(...interface{}) error }) (*models.Encounter, error) {
+	encounter := &models.Encounter{}
+	var classSystem, classCode, classDisplay, patientID sql.NullString
+	var periodStart, periodEnd sql.NullTime
+	scanError := row.Scan(
+		&encounter.ID,
+		&encounter.Status,
+		&classSystem,
+		&classCode,
+		&classDisplay,
+		&patientID,
+		pq.Array(&encounter.ParticipantPractitionerIDs),
+		&periodStart,
+		&periodEnd,
+		&encounter.CreatedAt,
+		&encounter.UpdatedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	encounter.ClassSystem = classSystem.String
+	encounter.ClassCode = classCode.String
+	encounter.ClassDisplay = classDisplay.String
+	encounter.PatientID = patientID.String
+	if periodStart.Valid {
+		encounter.PeriodStart = &periodStart.Time
+	}
+	if periodEnd.Valid {
+		encounter.PeriodEnd = &periodEnd.Time
+	}
+	return encounter, nil
+}
+
+// encounterParticipantIDs passes the participant practitioner IDs to the UUID array column, using an empty array for none
+func encounterParticipantIDs(encounter *models.Encounter) interface{} {
+	if encounter.ParticipantPractitionerIDs == nil {
+		return pq.Array([]string{})
+	}
+	return pq.Array(encounter.ParticipantPractitionerIDs)
+}
+
+// Create inserts a new encounter record into the database
+func (repository *PostgresEncounterRepository) Create(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error) {
+	insertQuery := `
+		INSERT INTO encounters (status, class_system, class_code, class_display, patient_id, participant_practitioner_ids,
+			period_start, period_end)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		insertQuery,
+		encounter.Status,
+		encounter.ClassSystem,
+		encounter.ClassCode,
+		encounter.ClassDisplay,
+		encounter.PatientID,
+		encounterParticipantIDs(encounter),
+		encounter.PeriodStart,
+		encounter.PeriodEnd,
+	).Scan(&encounter.ID, &encounter.CreatedAt, &encounter.UpdatedAt)
+
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return encounter, nil
+}
+
+// GetByID retrieves an encounter by its unique identifier
+// Returns sql.ErrNoRows when the encounter does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresEncounterRepository) GetByID(ctx context.Context, encounterID string) (*models.Encounter, error) {
+	executor := executorFor(ctx, repository.databaseConnection)
+	selectQuery := `SELECT ` + encounterColumns + ` FROM encounters WHERE id = $1 AND ` + notDeletedCondition
+	encounter, scanError := scanEncounter(executor.QueryRowContext(ctx, selectQuery, encounterID))
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executor, "encounters", encounterID)
+	}
+	return encounter, scanError
+}
+
+// Update modifies an existing encounter record in the database
+// Returns sql.ErrNoRows when the encounter does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresEncounterRepository) Update(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error) {
+	updateQuery := `
+		UPDATE encounters
+		SET status = $1, class_system = $2, class_code = $3, class_display = $4, patient_id = NULLIF($5, '')::uuid,
+			participant_practitioner_ids = $6, period_start = $7, period_end = $8, updated_at = $9
+		WHERE id = $10 AND deleted_at IS NULL
+		RETURNING created_at, updated_at
+	`
+
+	// Set the updated timestamp
+	encounter.UpdatedAt = time.Now()
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		updateQuery,
+		encounter.Status,
+		encounter.ClassSystem,
+		encounter.ClassCode,
+		encounter.ClassDisplay,
+		encounter.PatientID,
+		encounterParticipantIDs(encounter),
+		encounter.PeriodStart,
+		encounter.PeriodEnd,
+		encounter.UpdatedAt,
+		encounter.ID,
+	).Scan(&encounter.CreatedAt, &encounter.UpdatedAt)
+
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executorFor(ctx, repository.databaseConnection), "encounters", encounter.ID)
+	}
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return encounter, nil
+}
+
+// encounterSearchConditions builds the AND clauses that filter encounters on the search criteria, shared
+// by Search and Count so a page and its total always agree; the returned parameters are numbered from $1
+func encounterSearchConditions(searchParams *models.EncounterSearchParams) (string, []interface{}) {
+	conditions := ""
+	queryParameters := []interface{}{}
+	parameterIndex := 1
+
+	// Add subject patient filter
+	if searchParams.PatientID != "" {
+		conditions += ` AND patient_id = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.PatientID)
+		parameterIndex++
+	}
+
+	// Add status filter
+	if searchParams.Status != "" {
+		conditions += ` AND status = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.Status)
+		parameterIndex++
+	}
+
+	// Add date range filters; the period overlaps the range, and an encounter without an end is still ongoing
+	if searchParams.DateGreaterThan != nil {
+		conditions += ` AND (period_end IS NULL OR period_end >= $` + fmt.Sprint(parameterIndex) + `)`
+		queryParameters = append(queryParameters, *searchParams.DateGreaterThan)
+		parameterIndex++
+	}
+	if searchParams.DateLessThan != nil {
+		conditions += ` AND period_start <= $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.DateLessThan)
+		parameterIndex++
+	}
+
+	return conditions, queryParameters
+}
+
+// Search retrieves encounters matching the search criteria, most recent visit first
+func (repository *PostgresEncounterRepository) Search(ctx context.Context, searchParams *models.EncounterSearchParams) ([]*models.Encounter, error) {
+	conditions, queryParameters := encounterSearchConditions(searchParams)
+	parameterIndex := len(queryParameters) + 1
+
+	searchQuery := `SELECT ` + encounterColumns + ` FROM encounters WHERE ` + notDeletedCondition + conditions +
+		` ORDER BY period_start DESC NULLS LAST, created_at DESC, id LIMIT $` + fmt.Sprint(parameterIndex) + ` OFFSET $` + fmt.Sprint(parameterIndex+1)
+	queryParameters = append(queryParameters, searchParams.Limit, searchParams.Offset)
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, searchQuery, queryParameters...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	encounters := []*models.Encounter{}
+	for rows.Next() {
+		encounter, scanError := scanEncounter(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		encounters = append(encounters, encounter)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return encounters, nil
+}
+
+// Count returns how many encounters match the search criteria, ignoring the page's limit and offset
+func (repository *PostgresEncounterRepository) Count(ctx context.Context, searchParams *models.EncounterSearchParams) (int, error) {
+	conditions, queryParameters := encounterSearchConditions(searchParams)
+
+	var total int
+	countError := repository.databaseConnection.QueryRowContext(ctx, `SELECT COUNT(*) FROM encounters WHERE `+notDeletedCondition+conditions, queryParameters...).Scan(&total)
+	if countError != nil {
+		return 0, countError
+	}
+	return total, nil
+}
+
+// Delete marks an encounter deleted by ID, leaving the row for a read of it to answer as deleted
+// Returns sql.ErrNoRows when the encounter does not exist, wrapped with ErrResourceDeleted when it was already deleted
+func (repository *PostgresEncounterRepository) Delete(ctx context.Context, encounterID string) error {
+	return softDeleteRow(ctx, executorFor(ctx, repository.databaseConnection), "encounters", encounterID)
+}
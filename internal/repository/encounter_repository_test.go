@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupEncounters removes all test data from the encounters table
+func cleanupEncounters(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM encounters"); deleteError != nil {
+		t.Fatalf("Failed to cleanup encounters: %v", deleteError)
+	}
+}
+
+// TestPostgresEncounterRepository_CRUD verifies an encounter is created, read, updated, and deleted
+func TestPostgresEncounterRepository_CRUD(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupEncounters(t, databaseConnection)
+	defer cleanupEncounters(t, databaseConnection)
+
+	encounterRepository := NewPostgresEncounterRepository(databaseConnection)
+	ctx := context.Background()
+	periodStart := time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC)
+	practitionerID := uuid.NewString()
+
+	createdEncounter, createError := encounterRepository.Create(ctx, &models.Encounter{
+		Status:                     "in-progress",
+		ClassSystem:                "http://terminology.hl7.org/CodeSystem/v3-ActCode",
+		ClassCode:                  "AMB",
+		PatientID:                  uuid.NewString(),
+		ParticipantPractitionerIDs: []string{practitionerID},
+		PeriodStart:                &periodStart,
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create encounter: %v", createError)
+	}
+	if createdEncounter.ID == "" || createdEncounter.CreatedAt.IsZero() {
+		t.Fatalf("Expected a generated ID and timestamps, got %+v", createdEncounter)
+	}
+
+	periodEnd := periodStart.Add(45 * time.Minute)
+	createdEncounter.Status = "finished"
+	createdEncounter.PeriodEnd = &periodEnd
+	if _, updateError := encounterRepository.Update(ctx, createdEncounter); updateError != nil {
+		t.Fatalf("Failed to update encounter: %v", updateError)
+	}
+
+	storedEncounter, getError := encounterRepository.GetByID(ctx, createdEncounter.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read encounter: %v", getError)
+	}
+	if storedEncounter.Status != "finished" || storedEncounter.PeriodEnd == nil || !storedEncounter.PeriodEnd.Equal(periodEnd) {
+		t.Errorf("Expected the finished encounter with its end, got %+v", storedEncounter)
+	}
+	if len(storedEncounter.ParticipantPractitionerIDs) != 1 || storedEncounter.ParticipantPractitionerIDs[0] != practitionerID {
+		t.Errorf("Expected participant %s, got %v", practitionerID, storedEncounter.ParticipantPractitionerIDs)
+	}
+
+	if deleteError := encounterRepository.Delete(ctx, createdEncounter.ID); deleteError != nil {
+		t.Fatalf("Failed to delete encounter: %v", deleteError)
+	}
+	if deleteError := encounterRepository.Delete(ctx, createdEncounter.ID); !errors.Is(deleteError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", deleteError)
+	}
+}
+
+// TestPostgresEncounterRepository_Search verifies patient, status, and period overlap filters and the total
+func TestPostgresEncounterRepository_Search(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupEncounters(t, databaseConnection)
+	defer cleanupEncounters(t, databaseConnection)
+
+	encounterRepository := NewPostgresEncounterRepository(databaseConnection)
+	ctx := context.Background()
+	patientID := uuid.NewString()
+	januaryStart := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	januaryEnd := time.Date(2024, 1, 12, 17, 0, 0, 0, time.UTC)
+	marchStart := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, encounter := range []*models.Encounter{
+		{Status: "finished", PatientID: patientID, PeriodStart: &januaryStart, PeriodEnd: &januaryEnd},
+		{Status: "in-progress", PatientID: patientID, PeriodStart: &marchStart},
+		{Status: "finished", PatientID: uuid.NewString(), PeriodStart: &marchStart},
+	} {
+		if _, createError := encounterRepository.Create(ctx, encounter); createError != nil {
+			t.Fatalf("Failed to create encounter: %v", createError)
+		}
+	}
+
+	februaryFirst := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	januaryEleventh := time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		searchParams  models.EncounterSearchParams
+		expectedCount int
+	}{
+		"patient":          {searchParams: models.EncounterSearchParams{PatientID: patientID}, expectedCount: 2},
+		"status":           {searchParams: models.EncounterSearchParams{Status: "finished"}, expectedCount: 2},
+		"ongoing after":    {searchParams: models.EncounterSearchParams{DateGreaterThan: &februaryFirst}, expectedCount: 2},
+		"before":           {searchParams: models.EncounterSearchParams{DateLessThan: &februaryFirst}, expectedCount: 1},
+		"spanning the day": {searchParams: models.EncounterSearchParams{DateGreaterThan: &januaryEleventh, DateLessThan: &januaryEleventh}, expectedCount: 1},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		encounters, searchError := encounterRepository.Search(ctx, &testCase.searchParams)
+		if searchError != nil {
+			t.Fatalf("%s: search failed: %v", name, searchError)
+		}
+		total, countError := encounterRepository.Count(ctx, &testCase.searchParams)
+		if countError != nil {
+			t.Fatalf("%s: count failed: %v", name, countError)
+		}
+		if len(encounters) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d encounters, got %d with total %d", name, testCase.expectedCount, len(encounters), total)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// ErasureRepository defines the interface for patient erasure sagas and the Postgres records they withdraw
+type ErasureRepository interface {
+	// Create stores a pending erasure; sql.ErrNoRows when the patient already has one in progress
+	Create(ctx context.Context, erasure *models.PatientErasure) (*models.PatientErasure, error)
+
+	// Get returns the erasure; sql.ErrNoRows when it does not exist
+	Get(ctx context.Context, erasureID int64) (*models.PatientErasure, error)
+
+	// NextDue returns the oldest erasure with work to do at now; sql.ErrNoRows when there is none
+	NextDue(ctx context.Context, now time.Time) (*models.PatientErasure, error)
+
+	// StartStep records that a pending erasure has begun its stepsStarted-th step; sql.ErrNoRows when it is no longer pending
+	StartStep(ctx context.Context, erasureID int64, stepsStarted int) error
+
+	// RecordHeld adds to the count of records a step withdrew from its store
+	RecordHeld(ctx context.Context, erasureID int64, store string, count int64) error
+
+	// MarkHeld moves a pending erasure to held until purgeAfter; sql.ErrNoRows when it is no longer pending
+	MarkHeld(ctx context.Context, erasureID int64, purgeAfter time.Time) error
+
+	// StartCompensation moves a pending erasure to compensating after a failed step; sql.ErrNoRows when it is no longer pending
+	StartCompensation(ctx context.Context, erasureID int64, failure string) error
+
+	// Cancel moves a pending or held erasure to compensating; sql.ErrNoRows when it is in neither state
+	Cancel(ctx context.Context, erasureID int64, actor string) error
+
+	// RecordRestored lowers the steps still to be restored after a step's records are put back
+	RecordRestored(ctx context.Context, erasureID int64, stepsStarted int) error
+
+	// FinishCompensation ends a compensating erasure as cancelled when it was cancelled, failed otherwise
+	FinishCompensation(ctx context.Context, erasureID int64) error
+
+	// StartPurge moves a held erasure to purging; sql.ErrNoRows when it is no longer held
+	StartPurge(ctx context.Context, erasureID int64) error
+
+	// MarkPurged completes a purging erasure with its per-store purge counts and deletion certificate
+	MarkPurged(ctx context.Context, erasureID int64, purgedCounts map[string]int64, certificate *models.DeletionCertificate) error
+
+	// GetCertificate returns a purged erasure's deletion certificate; sql.ErrNoRows when it has none
+	GetCertificate(ctx context.Context, erasureID int64) (*models.DeletionCertificate, error)
+
+	// HoldPatientRecords copies the patient row and its daily rollups aside for the erasure and removes
+	// them, returning the rows held; sql.ErrNoRows when the patient row no longer exists
+	// A patient that was deleted is held too: its row is kept until erased
+	HoldPatientRecords(ctx context.Context, erasureID int64, patientID string) (int64, error)
+
+	// RestorePatientRecords puts the held patient row and rollups back; sql.ErrNoRows when the erasure holds none
+	RestorePatientRecords(ctx context.Context, erasureID int64) error
+
+	// PurgePatientRecords permanently deletes the held rows, returning how many there were
+	PurgePatientRecords(ctx context.Context, erasureID int64) (int64, error)
+
+	// ClearCompartmentSnapshots removes the resource snapshots kept in the change log for the patient's
+	// compartment, returning how many were cleared; the change entries themselves stay for sync consumers
+	ClearCompartmentSnapshots(ctx context.Context, patientID string) (int64, error)
+}
+
+// PostgresErasureRepository implements ErasureRepository using PostgreSQL
+type PostgresErasureRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresErasureRepository creates a new PostgreSQL patient erasure repository instance
+func NewPostgresErasureRepository(databaseConnection *sql.DB) *PostgresErasureRepository {
+	return &PostgresErasureRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// selectErasureQuery reads an erasure's columns in the order scanErasure expects
+const selectErasureQuery = `
+	SELECT id, patient_id, status, reason, requested_by, cancelled_by, steps_started, error,
+		held_counts, purged_counts, purge_after, created_at, updated_at, purged_at
+	FROM patient_erasures
+`
+
+// Create inserts a pending erasure unless the patient already has an erasure in progress
+func (repository *PostgresErasureRepository) Create(ctx context.Context, erasure *models.PatientErasure) (*models.PatientErasure, error) {
+	// The partial unique index on active erasures turns a concurrent duplicate into no row
+	insertQuery := `
+		INSERT INTO patient_erasures (patient_id, status, reason, requested_by)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (patient_id) WHERE status IN ('pending', 'held', 'compensating', 'purging') DO NOTHING
+		RETURNING id
+	`
+	var erasureID int64
+	scanError := repository.databaseConnection.QueryRowContext(ctx, insertQuery,
+		erasure.PatientID, models.ErasureStatusPending, erasure.Reason, erasure.RequestedBy).Scan(&erasureID)
+	if scanError != nil {
+		return nil, scanError
+	}
+	return repository.Get(ctx, erasureID)
+}
+
+// Get retrieves an erasure by ID
+func (repository *PostgresErasureRepository) Get(ctx context.Context, erasureID int64) (*models.PatientErasure, error) {
+	return scanErasure(repository.databaseConnection.QueryRowContext(ctx, selectErasureQuery+" WHERE id = $1", erasureID))
+}
+
+// NextDue retrieves the oldest erasure that is withdrawing, compensating, or purging, or whose waiting period has ended
+func (repository *PostgresErasureRepository) NextDue(ctx context.Context, now time.Time) (*models.PatientErasure, error) {
+	return scanErasure(repository.databaseConnection.QueryRowContext(ctx,
+		selectErasureQuery+" WHERE status IN ($1, $2, $3) OR (status = $4 AND purge_after <= $5) ORDER BY id LIMIT 1",
+		models.ErasureStatusPending, models.ErasureStatusCompensating, models.ErasureStatusPurging, models.ErasureStatusHeld, now))
+}
+
+// StartStep advances the pending erasure's started step count
+func (repository *PostgresErasureRepository) StartStep(ctx context.Context, erasureID int64, stepsStarted int) error {
+	return repository.transition(ctx, erasureID, "steps_started = GREATEST(steps_started, $3)", []models.ErasureStatus{models.ErasureStatusPending}, stepsStarted)
+}
+
+// RecordHeld adds the records a step withdrew to the store's count in held_counts
+// Counts add up so a step resumed after a restart keeps what its first attempt withdrew
+func (repository *PostgresErasureRepository) RecordHeld(ctx context.Context, erasureID int64, store string, count int64) error {
+	updateQuery := `
+		UPDATE patient_erasures
+		SET held_counts = held_counts || jsonb_build_object($2::text, COALESCE((held_counts->>$2)::bigint, 0) + $3), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`
+	_, updateError := repository.databaseConnection.ExecContext(ctx, updateQuery, erasureID, store, count)
+	return updateError
+}
+
+// MarkHeld starts the waiting period of a pending erasure
+func (repository *PostgresErasureRepository) MarkHeld(ctx context.Context, erasureID int64, purgeAfter time.Time) error {
+	return repository.transition(ctx, erasureID, "status = 'held', purge_after = $3", []models.ErasureStatus{models.ErasureStatusPending}, purgeAfter)
+}
+
+// StartCompensation switches a pending erasure to compensating, recording the failure
+func (repository *PostgresErasureRepository) StartCompensation(ctx context.Context, erasureID int64, failure string) error {
+	return repository.transition(ctx, erasureID, "status = 'compensating', error = $3", []models.ErasureStatus{models.ErasureStatusPending}, failure)
+}
+
+// Cancel switches a pending or held erasure to compensating, attributing it to actor
+func (repository *PostgresErasureRepository) Cancel(ctx context.Context, erasureID int64, actor string) error {
+	return repository.transition(ctx, erasureID, "status = 'compensating', cancelled_by = $3",
+		[]models.ErasureStatus{models.ErasureStatusPending, models.ErasureStatusHeld}, actor)
+}
+
+// RecordRestored lowers the started step count of a compensating erasure
+func (repository *PostgresErasureRepository) RecordRestored(ctx context.Context, erasureID int64, stepsStarted int) error {
+	return repository.transition(ctx, erasureID, "steps_started = $3", []models.ErasureStatus{models.ErasureStatusCompensating}, stepsStarted)
+}
+
+// FinishCompensation ends a compensating erasure
+func (repository *PostgresErasureRepository) FinishCompensation(ctx context.Context, erasureID int64) error {
+	return repository.transition(ctx, erasureID,
+		"status = CASE WHEN cancelled_by <> '' THEN 'cancelled' ELSE 'failed' END, held_counts = '{}', purge_after = NULL",
+		[]models.ErasureStatus{models.ErasureStatusCompensating})
+}
+
+// StartPurge claims a held erasure for purging so it can no longer be cancelled
+func (repository *PostgresErasureRepository) StartPurge(ctx context.Context, erasureID int64) error {
+	return repository.transition(ctx, erasureID, "status = 'purging'", []models.ErasureStatus{models.ErasureStatusHeld})
+}
+
+// MarkPurged completes a purging erasure
+func (repository *PostgresErasureRepository) MarkPurged(ctx context.Context, erasureID int64, purgedCounts map[string]int64, certificate *models.DeletionCertificate) error {
+	encodedCounts, countsError := json.Marshal(purgedCounts)
+	if countsError != nil {
+		return countsError
+	}
+	encodedCertificate, certificateError := json.Marshal(certificate)
+	if certificateError != nil {
+		return certificateError
+	}
+
+	return repository.transition(ctx, erasureID, "status = 'purged', purged_counts = $3, certificate = $4, purged_at = $5",
+		[]models.ErasureStatus{models.ErasureStatusPurging}, encodedCounts, encodedCertificate, certificate.PurgedAt)
+}
+
+// GetCertificate reads the certificate issued when the erasure was purged
+func (repository *PostgresErasureRepository) GetCertificate(ctx context.Context, erasureID int64) (*models.DeletionCertificate, error) {
+	var encodedCertificate []byte
+	scanError := repository.databaseConnection.QueryRowContext(ctx,
+		"SELECT certificate FROM patient_erasures WHERE id = $1 AND certificate IS NOT NULL", erasureID).Scan(&encodedCertificate)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	certificate := &models.DeletionCertificate{}
+	if decodeError := json.Unmarshal(encodedCertificate, certificate); decodeError != nil {
+		return nil, decodeError
+	}
+	return certificate, nil
+}
+
+// transition applies assignments to the erasure when its status is one of from
+// Assignment placeholders start at $3; $1 is the erasure ID and $2 the allowed statuses
+func (repository *PostgresErasureRepository) transition(ctx context.Context, erasureID int64, assignments string, from []models.ErasureStatus, arguments ...interface{}) error {
+	allowedStatuses := make([]string, len(from))
+	for index, status := range from {
+		allowedStatuses[index] = string(status)
+	}
+
+	updateQuery := `
+		UPDATE patient_erasures
+		SET ` + assignments + `, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = ANY($2)
+	`
+	result, updateError := repository.databaseConnection.ExecContext(ctx, updateQuery, append([]interface{}{erasureID, pq.Array(allowedStatuses)}, arguments...)...)
+	if updateError != nil {
+		return updateError
+	}
+	if updatedRows, _ := result.RowsAffected(); updatedRows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// HoldPatientRecords copies the patient and its rollups into patient_erasure_holds and removes their rows
+// The patient row is removed outright rather than marked deleted, so only the hold keeps it until the purge.
+// It joins the transaction carried by ctx, so the hold commits with the change recorded for it
+func (repository *PostgresErasureRepository) HoldPatientRecords(ctx context.Context, erasureID int64, patientID string) (int64, error) {
+	var heldRows int64
+	holdError := runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		executor := executorFor(transactionContext, repository.databaseConnection)
+
+		holdQuery := `
+			INSERT INTO patient_erasure_holds (erasure_id, patient, rollups)
+			SELECT $1, to_jsonb(patients), COALESCE((
+				SELECT jsonb_agg(to_jsonb(rollups))
+				FROM observation_daily_rollups rollups
+				WHERE rollups.patient_id = $2
+			), '[]'::jsonb)
+			FROM patients
+			WHERE id::text = $2
+			RETURNING 1 + jsonb_array_length(rollups)
+		`
+		if scanError := executor.QueryRowContext(transactionContext, holdQuery, erasureID, patientID).Scan(&heldRows); scanError != nil {
+			return scanError
+		}
+
+		if _, deleteError := executor.ExecContext(transactionContext, "DELETE FROM observation_daily_rollups WHERE patient_id = $1", patientID); deleteError != nil {
+			return deleteError
+		}
+		_, deleteError := executor.ExecContext(transactionContext, "DELETE FROM patients WHERE id::text = $1", patientID)
+		return deleteError
+	})
+	if holdError != nil {
+		return 0, holdError
+	}
+	return heldRows, nil
+}
+
+// RestorePatientRecords reinserts the held rows, keeping any row that was recreated meanwhile, and drops the hold
+// It joins the transaction carried by ctx, so the restore commits with the change recorded for it
+func (repository *PostgresErasureRepository) RestorePatientRecords(ctx context.Context, erasureID int64) error {
+	return runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		executor := executorFor(transactionContext, repository.databaseConnection)
+
+		restorePatientQuery := `
+			INSERT INTO patients
+			SELECT restored.* FROM patient_erasure_holds holds
+			CROSS JOIN LATERAL jsonb_populate_record(NULL::patients, holds.patient) restored
+			WHERE holds.erasure_id = $1
+			ON CONFLICT (id) DO NOTHING
+		`
+		if _, restoreError := executor.ExecContext(transactionContext, restorePatientQuery, erasureID); restoreError != nil {
+			return restoreError
+		}
+
+		restoreRollupsQuery := `
+			INSERT INTO observation_daily_rollups
+			SELECT restored.* FROM patient_erasure_holds holds
+			CROSS JOIN LATERAL jsonb_populate_recordset(NULL::observation_daily_rollups, holds.rollups) restored
+			WHERE holds.erasure_id = $1
+			ON CONFLICT DO NOTHING
+		`
+		if _, restoreError := executor.ExecContext(transactionContext, restoreRollupsQuery, erasureID); restoreError != nil {
+			return restoreError
+		}
+
+		result, deleteError := executor.ExecContext(transactionContext, "DELETE FROM patient_erasure_holds WHERE erasure_id = $1", erasureID)
+		if deleteError != nil {
+			return deleteError
+		}
+		if deletedRows, _ := result.RowsAffected(); deletedRows == 0 {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+// PurgePatientRecords deletes the erasure's held rows; an erasure that holds none purges nothing
+func (repository *PostgresErasureRepository) PurgePatientRecords(ctx context.Context, erasureID int64) (int64, error) {
+	var purgedRows int64
+	scanError := repository.databaseConnection.QueryRowContext(ctx,
+		"DELETE FROM patient_erasure_holds WHERE erasure_id = $1 RETURNING 1 + jsonb_array_length(rollups)", erasureID).Scan(&purgedRows)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if scanError != nil {
+		return 0, scanError
+	}
+	return purgedRows, nil
+}
+
+// ClearCompartmentSnapshots nulls the snapshots of every change recorded in the patient's compartment
+func (repository *PostgresErasureRepository) ClearCompartmentSnapshots(ctx context.Context, patientID string) (int64, error) {
+	result, updateError := repository.databaseConnection.ExecContext(ctx,
+		"UPDATE resource_changes SET snapshot = NULL WHERE compartment_patient_id = $1 AND snapshot IS NOT NULL", patientID)
+	if updateError != nil {
+		return 0, updateError
+	}
+	return result.RowsAffected()
+}
+
+// scanErasure reads one erasure row selected with selectErasureQuery
+func scanErasure(row rowScanner) (*models.PatientErasure, error) {
+	erasure := &models.PatientErasure{}
+	var encodedHeldCounts []byte
+	var encodedPurgedCounts []byte
+	var purgeAfter sql.NullTime
+	var purgedAt sql.NullTime
+	scanError := row.Scan(
+		&erasure.ID,
+		&erasure.PatientID,
+		&erasure.Status,
+		&erasure.Reason,
+		&erasure.RequestedBy,
+		&erasure.CancelledBy,
+		&erasure.StepsStarted,
+		&erasure.Error,
+		&encodedHeldCounts,
+		&encodedPurgedCounts,
+		&purgeAfter,
+		&erasure.CreatedAt,
+		&erasure.UpdatedAt,
+		&purgedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	if decodeError := json.Unmarshal(encodedHeldCounts, &erasure.HeldCounts); decodeError != nil {
+		return nil, decodeError
+	}
+	if decodeError := json.Unmarshal(encodedPurgedCounts, &erasure.PurgedCounts); decodeError != nil {
+		return nil, decodeError
+	}
+	if purgeAfter.Valid {
+		erasure.PurgeAfter = &purgeAfter.Time
+	}
+	if purgedAt.Valid {
+		erasure.PurgedAt = &purgedAt.Time
+	}
+	return erasure, nil
+}
@@ -0,0 +1,138 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupErasureTestData removes erasures, their holds, and rollups before patients can be cleaned up
+func cleanupErasureTestData(t *testing.T, databaseConnection *sql.DB) {
+	for _, table := range []string{"patient_erasure_holds", "patient_erasures", "observation_daily_rollups"} {
+		if _, deleteError := databaseConnection.Exec("DELETE FROM " + table); deleteError != nil {
+			t.Fatalf("Failed to cleanup %s: %v", table, deleteError)
+		}
+	}
+	cleanupTestData(t, databaseConnection)
+}
+
+// TestPostgresErasureRepository_Lifecycle verifies one active erasure per patient and the saga's state transitions
+func TestPostgresErasureRepository_Lifecycle(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupErasureTestData(t, databaseConnection)
+	defer cleanupErasureTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	erasureRepository := NewPostgresErasureRepository(databaseConnection)
+	erasure, createError := erasureRepository.Create(ctx, &models.PatientErasure{PatientID: "patient-1", Reason: "DSR-7", RequestedBy: "api-key:privacy"})
+	if createError != nil || erasure.Status != models.ErasureStatusPending {
+		t.Fatalf("Expected a pending erasure, got %+v (%v)", erasure, createError)
+	}
+	if _, duplicateError := erasureRepository.Create(ctx, &models.PatientErasure{PatientID: "patient-1", Reason: "DSR-8", RequestedBy: "api-key:privacy"}); !errors.Is(duplicateError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows for a second active erasure, got %v", duplicateError)
+	}
+
+	erasureRepository.StartStep(ctx, erasure.ID, 1)
+	erasureRepository.RecordHeld(ctx, erasure.ID, "observations", 4)
+	erasureRepository.RecordHeld(ctx, erasure.ID, "observations", 1)
+	purgeAfter := time.Now().Add(time.Hour)
+	if heldError := erasureRepository.MarkHeld(ctx, erasure.ID, purgeAfter); heldError != nil {
+		t.Fatalf("Expected no error, got %v", heldError)
+	}
+
+	if _, dueError := erasureRepository.NextDue(ctx, time.Now()); !errors.Is(dueError, sql.ErrNoRows) {
+		t.Errorf("Expected nothing due during the waiting period, got %v", dueError)
+	}
+	due, dueError := erasureRepository.NextDue(ctx, purgeAfter.Add(time.Second))
+	if dueError != nil || due.ID != erasure.ID || due.HeldCounts["observations"] != 5 || due.StepsStarted != 1 {
+		t.Fatalf("Expected the held erasure due with 5 observations, got %+v (%v)", due, dueError)
+	}
+
+	if startError := erasureRepository.StartPurge(ctx, erasure.ID); startError != nil {
+		t.Fatalf("Expected no error, got %v", startError)
+	}
+	if cancelError := erasureRepository.Cancel(ctx, erasure.ID, "api-key:privacy"); !errors.Is(cancelError, sql.ErrNoRows) {
+		t.Errorf("Expected a purging erasure to refuse cancellation, got %v", cancelError)
+	}
+
+	certificate := &models.DeletionCertificate{ErasureID: erasure.ID, PatientID: "patient-1", PurgedAt: time.Now().UTC(), Digest: "abc"}
+	if markError := erasureRepository.MarkPurged(ctx, erasure.ID, map[string]int64{"observations": 5}, certificate); markError != nil {
+		t.Fatalf("Expected no error, got %v", markError)
+	}
+	storedCertificate, certificateError := erasureRepository.GetCertificate(ctx, erasure.ID)
+	if certificateError != nil || storedCertificate.Digest != "abc" {
+		t.Errorf("Expected the stored certificate, got %+v (%v)", storedCertificate, certificateError)
+	}
+
+	if _, createError := erasureRepository.Create(ctx, &models.PatientErasure{PatientID: "patient-1", Reason: "DSR-9", RequestedBy: "api-key:privacy"}); createError != nil {
+		t.Errorf("Expected a new erasure once the first was purged, got %v", createError)
+	}
+}
+
+// TestPostgresErasureRepository_HoldRestoreAndPurgePatient verifies the patient and its rollups are held, restored, and purged
+func TestPostgresErasureRepository_HoldRestoreAndPurgePatient(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupErasureTestData(t, databaseConnection)
+	defer cleanupErasureTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	patientRepository := NewPostgresPatientRepository(databaseConnection)
+	patient, _ := patientRepository.Create(ctx, &models.Patient{FamilyName: "Erase", GivenName: "Me", Active: true})
+	rollupRepository := NewPostgresRollupRepository(databaseConnection)
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	rollupRepository.ReplaceDays(ctx, day, day.AddDate(0, 0, 1), []*models.ObservationRollup{
+		{PatientID: patient.ID, Code: "8867-4", Day: day, Count: 2, Min: 60, Max: 80, Sum: 140},
+	})
+
+	erasureRepository := NewPostgresErasureRepository(databaseConnection)
+	erasure, _ := erasureRepository.Create(ctx, &models.PatientErasure{PatientID: patient.ID, Reason: "DSR-7", RequestedBy: "api-key:privacy"})
+
+	heldRows, holdError := erasureRepository.HoldPatientRecords(ctx, erasure.ID, patient.ID)
+	if holdError != nil {
+		t.Fatalf("Expected no error holding, got %v", holdError)
+	}
+	if heldRows != 2 {
+		t.Errorf("Expected the patient and one rollup held, got %d", heldRows)
+	}
+	var storedRows int
+	databaseConnection.QueryRow("SELECT COUNT(*) FROM patients WHERE id = $1", patient.ID).Scan(&storedRows)
+	if storedRows != 0 {
+		t.Error("Expected the patient row to be removed, not marked deleted")
+	}
+	if _, repeatError := erasureRepository.HoldPatientRecords(ctx, erasure.ID, patient.ID); !errors.Is(repeatError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows holding a withdrawn patient, got %v", repeatError)
+	}
+
+	if restoreError := erasureRepository.RestorePatientRecords(ctx, erasure.ID); restoreError != nil {
+		t.Fatalf("Expected no error restoring, got %v", restoreError)
+	}
+	restored, getError := patientRepository.GetByID(ctx, patient.ID)
+	if getError != nil || restored.FamilyName != "Erase" {
+		t.Errorf("Expected the patient restored, got %+v (%v)", restored, getError)
+	}
+
+	// A deleted patient is held as it is and comes back deleted
+	patientRepository.Delete(ctx, patient.ID)
+	if _, holdError := erasureRepository.HoldPatientRecords(ctx, erasure.ID, patient.ID); holdError != nil {
+		t.Fatalf("Expected no error holding a deleted patient, got %v", holdError)
+	}
+	erasureRepository.RestorePatientRecords(ctx, erasure.ID)
+	if _, getError := patientRepository.GetByID(ctx, patient.ID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected the restored patient still deleted, got %v", getError)
+	}
+
+	erasureRepository.HoldPatientRecords(ctx, erasure.ID, patient.ID)
+	purgedRows, purgeError := erasureRepository.PurgePatientRecords(ctx, erasure.ID)
+	if purgeError != nil || purgedRows != 2 {
+		t.Errorf("Expected 2 rows purged, got %d (%v)", purgedRows, purgeError)
+	}
+	if repeatRows, _ := erasureRepository.PurgePatientRecords(ctx, erasure.ID); repeatRows != 0 {
+		t.Errorf("Expected a repeated purge to delete nothing, got %d", repeatRows)
+	}
+}
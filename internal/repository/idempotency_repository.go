@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// IdempotencyRepository defines the interface for stored Idempotency-Key requests and their responses
+type IdempotencyRepository interface {
+	// Claim records the request under its key and returns nil, or returns the record already holding the key
+	// An expired record, or an unfinished one created before staleBefore, is replaced rather than returned
+	Claim(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (*models.IdempotencyRecord, error)
+
+	// Complete stores the response of a claimed request
+	Complete(ctx context.Context, record *models.IdempotencyRecord) error
+
+	// Release removes an unfinished claim so the request can be retried
+	Release(ctx context.Context, clientID string, key string) error
+
+	// DeleteExpired removes records that expired before the given time and returns how many were removed
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PostgresIdempotencyRepository implements IdempotencyRepository using PostgreSQL
+type PostgresIdempotencyRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresIdempotencyRepository creates a new PostgreSQL idempotency repository instance
+func NewPostgresIdempotencyRepository(databaseConnection *sql.DB) *PostgresIdempotencyRepository {
+	return &PostgresIdempotencyRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// Claim inserts the record unless its key is held; record.CreatedAt is taken as the current time
+// The insert does nothing on conflict, so of two concurrent requests with one key exactly one claims it
+func (repository *PostgresIdempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyRecord, staleBefore time.Time) (*models.IdempotencyRecord, error) {
+	_, deleteError := repository.databaseConnection.ExecContext(ctx, `
+		DELETE FROM idempotency_keys
+		WHERE client_id = $1 AND idempotency_key = $2 AND (expires_at <= $3 OR (NOT completed AND created_at < $4))
+	`, record.ClientID, record.Key, record.CreatedAt, staleBefore)
+	if deleteError != nil {
+		return nil, fmt.Errorf("failed to clear an expired idempotency key: %w", deleteError)
+	}
+
+	insertResult, insertError := repository.databaseConnection.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (client_id, idempotency_key, method, path, request_hash, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (client_id, idempotency_key) DO NOTHING
+	`, record.ClientID, record.Key, record.Method, record.Path, record.RequestHash, record.CreatedAt, record.ExpiresAt)
+	if insertError != nil {
+		return nil, fmt.Errorf("failed to claim idempotency key: %w", insertError)
+	}
+	if inserted, _ := insertResult.RowsAffected(); inserted == 1 {
+		return nil, nil
+	}
+
+	existing := &models.IdempotencyRecord{}
+	var rawHeaders []byte
+	scanError := repository.databaseConnection.QueryRowContext(ctx, `
+		SELECT client_id, idempotency_key, method, path, request_hash, completed, status_code, response_headers,
+			response_body, created_at, expires_at
+		FROM idempotency_keys
+		WHERE client_id = $1 AND idempotency_key = $2
+	`, record.ClientID, record.Key).Scan(
+		&existing.ClientID,
+		&existing.Key,
+		&existing.Method,
+		&existing.Path,
+		&existing.RequestHash,
+		&existing.Completed,
+		&existing.StatusCode,
+		&rawHeaders,
+		&existing.ResponseBody,
+		&existing.CreatedAt,
+		&existing.ExpiresAt,
+	)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		// The holder was released between the insert and the read; the caller's retry will claim it
+		return nil, fmt.Errorf("idempotency key %q was released while being claimed", record.Key)
+	}
+	if scanError != nil {
+		return nil, fmt.Errorf("failed to read idempotency key: %w", scanError)
+	}
+	if decodeError := json.Unmarshal(rawHeaders, &existing.ResponseHeaders); decodeError != nil {
+		return nil, fmt.Errorf("failed to decode stored response headers: %w", decodeError)
+	}
+	return existing, nil
+}
+
+// Complete stores the status, headers, and body of the claimed request
+func (repository *PostgresIdempotencyRepository) Complete(ctx context.Context, record *models.IdempotencyRecord) error {
+	responseHeaders := record.ResponseHeaders
+	if responseHeaders == nil {
+		responseHeaders = map[string]string{}
+	}
+	rawHeaders, encodeError := json.Marshal(responseHeaders)
+	if encodeError != nil {
+		return fmt.Errorf("failed to encode response headers: %w", encodeError)
+	}
+
+	_, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE idempotency_keys
+		SET completed = TRUE, status_code = $3, response_headers = $4, response_body = $5
+		WHERE client_id = $1 AND idempotency_key = $2
+	`, record.ClientID, record.Key, record.StatusCode, rawHeaders, record.ResponseBody)
+	return updateError
+}
+
+// Release deletes the claim if it has not completed
+func (repository *PostgresIdempotencyRepository) Release(ctx context.Context, clientID string, key string) error {
+	_, deleteError := repository.databaseConnection.ExecContext(ctx,
+		`DELETE FROM idempotency_keys WHERE client_id = $1 AND idempotency_key = $2 AND NOT completed`, clientID, key)
+	return deleteError
+}
+
+// DeleteExpired deletes every record whose expiry has passed
+func (repository *PostgresIdempotencyRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	deleteResult, deleteError := repository.databaseConnection.ExecContext(ctx, `DELETE FROM idempotency_keys WHERE expires_at <= $1`, before)
+	if deleteError != nil {
+		return 0, deleteError
+	}
+	return deleteResult.RowsAffected()
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupIdempotencyKeys removes every idempotency key
+func cleanupIdempotencyKeys(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM idempotency_keys"); deleteError != nil {
+		t.Fatalf("Failed to cleanup idempotency_keys: %v", deleteError)
+	}
+}
+
+// TestPostgresIdempotencyRepository_ClaimAndComplete verifies one claim per key, the stored response, and expiry
+func TestPostgresIdempotencyRepository_ClaimAndComplete(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupIdempotencyKeys(t, databaseConnection)
+	defer cleanupIdempotencyKeys(t, databaseConnection)
+
+	ctx := context.Background()
+	idempotencyRepository := NewPostgresIdempotencyRepository(databaseConnection)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	record := &models.IdempotencyRecord{
+		ClientID: "oauth:lab|default", Key: "key-1", Method: "POST", Path: "/fhir/Patient",
+		RequestHash: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		CreatedAt:   now, ExpiresAt: now.Add(time.Hour),
+	}
+
+	if existing, claimError := idempotencyRepository.Claim(ctx, record, now.Add(-time.Minute)); claimError != nil || existing != nil {
+		t.Fatalf("Expected the first claim to succeed, got %+v (%v)", existing, claimError)
+	}
+	existing, claimError := idempotencyRepository.Claim(ctx, record, now.Add(-time.Minute))
+	if claimError != nil || existing == nil || existing.Completed {
+		t.Fatalf("Expected the unfinished claim back, got %+v (%v)", existing, claimError)
+	}
+
+	record.Completed = true
+	record.StatusCode = 201
+	record.ResponseHeaders = map[string]string{"Location": "/fhir/Patient/p-1"}
+	record.ResponseBody = []byte(`{"resourceType":"Patient","id":"p-1"}`)
+	if completeError := idempotencyRepository.Complete(ctx, record); completeError != nil {
+		t.Fatalf("Expected no error, got %v", completeError)
+	}
+	existing, _ = idempotencyRepository.Claim(ctx, record, now.Add(-time.Minute))
+	if existing == nil || !existing.Completed || existing.StatusCode != 201 || existing.ResponseHeaders["Location"] != "/fhir/Patient/p-1" || string(existing.ResponseBody) != string(record.ResponseBody) {
+		t.Errorf("Expected the stored response, got %+v", existing)
+	}
+
+	// A completed record is not released, but an expired one is replaced by a new claim
+	idempotencyRepository.Release(ctx, record.ClientID, record.Key)
+	later := *record
+	later.CreatedAt = now.Add(2 * time.Hour)
+	later.ExpiresAt = later.CreatedAt.Add(time.Hour)
+	if existing, _ := idempotencyRepository.Claim(ctx, &later, later.CreatedAt.Add(-time.Minute)); existing != nil {
+		t.Errorf("Expected the expired key to be claimed again, got %+v", existing)
+	}
+
+	deleted, deleteError := idempotencyRepository.DeleteExpired(ctx, now.Add(4*time.Hour))
+	if deleteError != nil || deleted != 1 {
+		t.Errorf("Expected one expired key deleted, got %d (%v)", deleted, deleteError)
+	}
+}
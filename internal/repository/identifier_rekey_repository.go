@@ -0,0 +1,490 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// IdentifierRekeyRepository defines the interface for bulk identifier re-keying jobs and their audit history
+type IdentifierRekeyRepository interface {
+	// CreateJob stores a running job with its mappings
+	CreateJob(ctx context.Context, job *models.RekeyJob, mappings []models.IdentifierMapping) (*models.RekeyJob, error)
+
+	// GetJob returns the job with its counts; sql.ErrNoRows when it does not exist
+	GetJob(ctx context.Context, jobID int64) (*models.RekeyJob, error)
+
+	// NextActiveJob returns the oldest running or rolling-back job; sql.ErrNoRows when there is none
+	NextActiveJob(ctx context.Context) (*models.RekeyJob, error)
+
+	// StartRollback moves a running or completed job to rolling back; sql.ErrNoRows when no such job is in either state
+	StartRollback(ctx context.Context, jobID int64, actor string) error
+
+	// ApplyBatch applies up to limit unprocessed mappings in one transaction and completes the job when none remain
+	ApplyBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error)
+
+	// RollbackBatch reverts up to limit rewrites in one transaction and marks the job rolled back when none remain
+	RollbackBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error)
+
+	// ListRecords returns the job's audit history, oldest first
+	ListRecords(ctx context.Context, jobID int64) ([]*models.RekeyRecord, error)
+}
+
+// PostgresIdentifierRekeyRepository implements IdentifierRekeyRepository using PostgreSQL
+type PostgresIdentifierRekeyRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresIdentifierRekeyRepository creates a new PostgreSQL identifier re-keying repository instance
+func NewPostgresIdentifierRekeyRepository(databaseConnection *sql.DB) *PostgresIdentifierRekeyRepository {
+	return &PostgresIdentifierRekeyRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// selectJobQuery reads a job with counts derived from its mappings and audit history
+const selectJobQuery = `
+	SELECT jobs.id, jobs.status, jobs.reason, jobs.actor, jobs.rollback_actor, jobs.created_at, jobs.updated_at,
+		mappings.total, mappings.processed, audit.rekeyed, audit.skipped, audit.rolled_back
+	FROM identifier_rekey_jobs jobs
+	CROSS JOIN LATERAL (
+		SELECT COUNT(*) AS total, COUNT(processed_at) AS processed
+		FROM identifier_rekey_mappings WHERE job_id = jobs.id
+	) mappings
+	CROSS JOIN LATERAL (
+		SELECT COUNT(*) FILTER (WHERE action = 'rekeyed') AS rekeyed,
+			COUNT(*) FILTER (WHERE action = 'skipped') AS skipped,
+			COUNT(*) FILTER (WHERE action = 'rolled_back') AS rolled_back
+		FROM identifier_rekey_audit WHERE job_id = jobs.id
+	) audit
+`
+
+// insertRekeyRecordQuery appends one audit entry
+const insertRekeyRecordQuery = `
+	INSERT INTO identifier_rekey_audit (job_id, patient_id, action, old_system, old_value, new_system, new_value, detail, reverts_id, actor)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	RETURNING id, occurred_at
+`
+
+// CreateJob inserts the job and its mappings in one transaction
+func (repository *PostgresIdentifierRekeyRepository) CreateJob(ctx context.Context, job *models.RekeyJob, mappings []models.IdentifierMapping) (*models.RekeyJob, error) {
+	transaction, beginError := repository.databaseConnection.BeginTx(ctx, nil)
+	if beginError != nil {
+		return nil, beginError
+	}
+	defer transaction.Rollback()
+
+	insertJobQuery := `
+		INSERT INTO identifier_rekey_jobs (status, reason, actor)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+	job.Status = models.RekeyJobStatusRunning
+	scanError := transaction.QueryRowContext(ctx, insertJobQuery, job.Status, job.Reason, job.Actor).Scan(&job.ID, &job.CreatedAt, &job.UpdatedAt)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	insertMapping, prepareError := transaction.PrepareContext(ctx, `
+		INSERT INTO identifier_rekey_mappings (job_id, line, old_system, old_value, new_system, new_value)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`)
+	if prepareError != nil {
+		return nil, prepareError
+	}
+	defer insertMapping.Close()
+
+	for _, mapping := range mappings {
+		_, insertError := insertMapping.ExecContext(ctx, job.ID, mapping.Line, mapping.OldSystem, mapping.OldValue, mapping.NewSystem, mapping.NewValue)
+		if insertError != nil {
+			return nil, insertError
+		}
+	}
+
+	if commitError := transaction.Commit(); commitError != nil {
+		return nil, commitError
+	}
+	job.Total = len(mappings)
+	return job, nil
+}
+
+// GetJob retrieves a job and its counts
+func (repository *PostgresIdentifierRekeyRepository) GetJob(ctx context.Context, jobID int64) (*models.RekeyJob, error) {
+	return scanRekeyJob(repository.databaseConnection.QueryRowContext(ctx, selectJobQuery+" WHERE jobs.id = $1", jobID))
+}
+
+// NextActiveJob retrieves the oldest job that still has work to do
+func (repository *PostgresIdentifierRekeyRepository) NextActiveJob(ctx context.Context) (*models.RekeyJob, error) {
+	return scanRekeyJob(repository.databaseConnection.QueryRowContext(ctx,
+		selectJobQuery+" WHERE jobs.status IN ($1, $2) ORDER BY jobs.id LIMIT 1",
+		models.RekeyJobStatusRunning, models.RekeyJobStatusRollingBack))
+}
+
+// StartRollback switches the job to rolling back; unprocessed mappings of a running job are abandoned
+func (repository *PostgresIdentifierRekeyRepository) StartRollback(ctx context.Context, jobID int64, actor string) error {
+	updateQuery := `
+		UPDATE identifier_rekey_jobs
+		SET status = $1, rollback_actor = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND status IN ($4, $5)
+	`
+	result, updateError := repository.databaseConnection.ExecContext(ctx, updateQuery,
+		models.RekeyJobStatusRollingBack, actor, jobID, models.RekeyJobStatusRunning, models.RekeyJobStatusCompleted)
+	if updateError != nil {
+		return updateError
+	}
+	if updatedRows, _ := result.RowsAffected(); updatedRows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ApplyBatch rewrites the identifiers for the next mappings in file order
+// A mapping is skipped when no patient or several patients have the old identifier, or another patient already has the new one
+func (repository *PostgresIdentifierRekeyRepository) ApplyBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	transaction, beginError := repository.databaseConnection.BeginTx(ctx, nil)
+	if beginError != nil {
+		return nil, beginError
+	}
+	defer transaction.Rollback()
+
+	// Locking the job serializes batches with a rollback being started
+	actor, lockError := lockRekeyJob(ctx, transaction, jobID, models.RekeyJobStatusRunning, "actor")
+	if lockError != nil {
+		return nil, lockError
+	}
+	if actor == "" {
+		return nil, nil
+	}
+
+	selectMappingsQuery := `
+		SELECT id, line, old_system, old_value, new_system, new_value
+		FROM identifier_rekey_mappings
+		WHERE job_id = $1 AND processed_at IS NULL
+		ORDER BY line
+		LIMIT $2
+	`
+	rows, queryError := transaction.QueryContext(ctx, selectMappingsQuery, jobID, limit)
+	if queryError != nil {
+		return nil, queryError
+	}
+	mappingIDs := []int64{}
+	mappings := []models.IdentifierMapping{}
+	for rows.Next() {
+		var mappingID int64
+		var mapping models.IdentifierMapping
+		if scanError := rows.Scan(&mappingID, &mapping.Line, &mapping.OldSystem, &mapping.OldValue, &mapping.NewSystem, &mapping.NewValue); scanError != nil {
+			rows.Close()
+			return nil, scanError
+		}
+		mappingIDs = append(mappingIDs, mappingID)
+		mappings = append(mappings, mapping)
+	}
+	rows.Close()
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	records := []*models.RekeyRecord{}
+	for index, mapping := range mappings {
+		record, applyError := applyMapping(ctx, transaction, jobID, actor, mapping)
+		if applyError != nil {
+			return nil, applyError
+		}
+		records = append(records, record)
+
+		_, markError := transaction.ExecContext(ctx, "UPDATE identifier_rekey_mappings SET processed_at = CURRENT_TIMESTAMP WHERE id = $1", mappingIDs[index])
+		if markError != nil {
+			return nil, markError
+		}
+	}
+
+	if finishError := finishRekeyBatch(ctx, transaction, jobID, len(mappings) < limit, models.RekeyJobStatusCompleted); finishError != nil {
+		return nil, finishError
+	}
+	if commitError := transaction.Commit(); commitError != nil {
+		return nil, commitError
+	}
+	return records, nil
+}
+
+// applyMapping rewrites one patient's identifier, or records why the mapping was skipped
+func applyMapping(ctx context.Context, transaction *sql.Tx, jobID int64, actor string, mapping models.IdentifierMapping) (*models.RekeyRecord, error) {
+	record := &models.RekeyRecord{
+		JobID:     jobID,
+		Action:    models.RekeyActionSkipped,
+		OldSystem: mapping.OldSystem,
+		OldValue:  mapping.OldValue,
+		NewSystem: mapping.NewSystem,
+		NewValue:  mapping.NewValue,
+		Actor:     actor,
+	}
+
+	patientIDs, findError := lockPatientsByIdentifier(ctx, transaction, mapping.OldSystem, mapping.OldValue)
+	if findError != nil {
+		return nil, findError
+	}
+
+	switch {
+	case len(patientIDs) == 0:
+		record.Detail = fmt.Sprintf("line %d: no patient has the old identifier", mapping.Line)
+	case len(patientIDs) > 1:
+		record.Detail = fmt.Sprintf("line %d: %d patients share the old identifier", mapping.Line, len(patientIDs))
+	default:
+		record.PatientID = patientIDs[0]
+		taken, takenError := identifierTaken(ctx, transaction, mapping.NewSystem, mapping.NewValue, record.PatientID)
+		if takenError != nil {
+			return nil, takenError
+		}
+		if taken {
+			record.Detail = fmt.Sprintf("line %d: the new identifier already belongs to another patient", mapping.Line)
+			break
+		}
+
+		_, updateError := transaction.ExecContext(ctx,
+			"UPDATE patients SET identifier_system = $1, identifier_value = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3",
+			mapping.NewSystem, mapping.NewValue, record.PatientID)
+		if updateError != nil {
+			return nil, updateError
+		}
+		record.Action = models.RekeyActionRekeyed
+	}
+
+	return record, insertRekeyRecord(ctx, transaction, record)
+}
+
+// RollbackBatch restores the old identifiers for the most recent rewrites not yet reverted
+// A rewrite is left alone when the patient's identifier changed since, or another patient now has the old identifier
+func (repository *PostgresIdentifierRekeyRepository) RollbackBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	transaction, beginError := repository.databaseConnection.BeginTx(ctx, nil)
+	if beginError != nil {
+		return nil, beginError
+	}
+	defer transaction.Rollback()
+
+	actor, lockError := lockRekeyJob(ctx, transaction, jobID, models.RekeyJobStatusRollingBack, "rollback_actor")
+	if lockError != nil {
+		return nil, lockError
+	}
+	if actor == "" {
+		return nil, nil
+	}
+
+	selectRewritesQuery := `
+		SELECT id, patient_id, old_system, old_value, new_system, new_value
+		FROM identifier_rekey_audit rewrites
+		WHERE job_id = $1 AND action = $2
+			AND NOT EXISTS (SELECT 1 FROM identifier_rekey_audit reverted WHERE reverted.reverts_id = rewrites.id)
+		ORDER BY id DESC
+		LIMIT $3
+	`
+	rows, queryError := transaction.QueryContext(ctx, selectRewritesQuery, jobID, models.RekeyActionRekeyed, limit)
+	if queryError != nil {
+		return nil, queryError
+	}
+	rewrites := []*models.RekeyRecord{}
+	for rows.Next() {
+		rewrite := &models.RekeyRecord{}
+		if scanError := rows.Scan(&rewrite.ID, &rewrite.PatientID, &rewrite.OldSystem, &rewrite.OldValue, &rewrite.NewSystem, &rewrite.NewValue); scanError != nil {
+			rows.Close()
+			return nil, scanError
+		}
+		rewrites = append(rewrites, rewrite)
+	}
+	rows.Close()
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	records := []*models.RekeyRecord{}
+	for _, rewrite := range rewrites {
+		record, revertError := revertRewrite(ctx, transaction, jobID, actor, rewrite)
+		if revertError != nil {
+			return nil, revertError
+		}
+		records = append(records, record)
+	}
+
+	if finishError := finishRekeyBatch(ctx, transaction, jobID, len(rewrites) < limit, models.RekeyJobStatusRolledBack); finishError != nil {
+		return nil, finishError
+	}
+	if commitError := transaction.Commit(); commitError != nil {
+		return nil, commitError
+	}
+	return records, nil
+}
+
+// revertRewrite restores one patient's old identifier, or records why it could not be restored
+func revertRewrite(ctx context.Context, transaction *sql.Tx, jobID int64, actor string, rewrite *models.RekeyRecord) (*models.RekeyRecord, error) {
+	record := &models.RekeyRecord{
+		JobID:     jobID,
+		PatientID: rewrite.PatientID,
+		Action:    models.RekeyActionRollbackSkipped,
+		OldSystem: rewrite.OldSystem,
+		OldValue:  rewrite.OldValue,
+		NewSystem: rewrite.NewSystem,
+		NewValue:  rewrite.NewValue,
+		RevertsID: rewrite.ID,
+		Actor:     actor,
+	}
+
+	taken, takenError := identifierTaken(ctx, transaction, rewrite.OldSystem, rewrite.OldValue, rewrite.PatientID)
+	if takenError != nil {
+		return nil, takenError
+	}
+	if taken {
+		record.Detail = "the old identifier now belongs to another patient"
+		return record, insertRekeyRecord(ctx, transaction, record)
+	}
+
+	updateQuery := `
+		UPDATE patients SET identifier_system = $1, identifier_value = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND identifier_system = $4 AND identifier_value = $5 AND deleted_at IS NULL
+	`
+	result, updateError := transaction.ExecContext(ctx, updateQuery,
+		rewrite.OldSystem, rewrite.OldValue, rewrite.PatientID, rewrite.NewSystem, rewrite.NewValue)
+	if updateError != nil {
+		return nil, updateError
+	}
+	if updatedRows, _ := result.RowsAffected(); updatedRows == 0 {
+		record.Detail = "the patient's identifier changed after the re-key, or the patient was deleted"
+	} else {
+		record.Action = models.RekeyActionRolledBack
+	}
+
+	return record, insertRekeyRecord(ctx, transaction, record)
+}
+
+// ListRecords retrieves a job's audit history in the order it happened
+func (repository *PostgresIdentifierRekeyRepository) ListRecords(ctx context.Context, jobID int64) ([]*models.RekeyRecord, error) {
+	selectQuery := `
+		SELECT id, job_id, patient_id, action, old_system, old_value, new_system, new_value, detail, COALESCE(reverts_id, 0), actor, occurred_at
+		FROM identifier_rekey_audit
+		WHERE job_id = $1
+		ORDER BY id
+	`
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, jobID)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	records := []*models.RekeyRecord{}
+	for rows.Next() {
+		record := &models.RekeyRecord{}
+		scanError := rows.Scan(
+			&record.ID,
+			&record.JobID,
+			&record.PatientID,
+			&record.Action,
+			&record.OldSystem,
+			&record.OldValue,
+			&record.NewSystem,
+			&record.NewValue,
+			&record.Detail,
+			&record.RevertsID,
+			&record.Actor,
+			&record.OccurredAt,
+		)
+		if scanError != nil {
+			return nil, scanError
+		}
+		records = append(records, record)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return records, nil
+}
+
+// scanRekeyJob reads a row selected with selectJobQuery
+func scanRekeyJob(row rowScanner) (*models.RekeyJob, error) {
+	job := &models.RekeyJob{}
+	scanError := row.Scan(
+		&job.ID,
+		&job.Status,
+		&job.Reason,
+		&job.Actor,
+		&job.RollbackActor,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.Total,
+		&job.Processed,
+		&job.Rekeyed,
+		&job.Skipped,
+		&job.RolledBack,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+	return job, nil
+}
+
+// lockRekeyJob locks the job row and returns the given actor column, or empty when the job is not in the wanted status
+func lockRekeyJob(ctx context.Context, transaction *sql.Tx, jobID int64, wantedStatus models.RekeyJobStatus, actorColumn string) (string, error) {
+	var status models.RekeyJobStatus
+	var actor string
+	lockQuery := "SELECT status, " + actorColumn + " FROM identifier_rekey_jobs WHERE id = $1 FOR UPDATE"
+	scanError := transaction.QueryRowContext(ctx, lockQuery, jobID).Scan(&status, &actor)
+	if scanError != nil {
+		return "", scanError
+	}
+	if status != wantedStatus {
+		return "", nil
+	}
+	return actor, nil
+}
+
+// finishRekeyBatch touches the job and moves it to finalStatus when the batch found no more work
+func finishRekeyBatch(ctx context.Context, transaction *sql.Tx, jobID int64, done bool, finalStatus models.RekeyJobStatus) error {
+	if !done {
+		_, touchError := transaction.ExecContext(ctx, "UPDATE identifier_rekey_jobs SET updated_at = CURRENT_TIMESTAMP WHERE id = $1", jobID)
+		return touchError
+	}
+	_, finishError := transaction.ExecContext(ctx,
+		"UPDATE identifier_rekey_jobs SET status = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2", finalStatus, jobID)
+	return finishError
+}
+
+// lockPatientsByIdentifier locks and returns the IDs of the patients with the identifier, leaving out deleted ones
+func lockPatientsByIdentifier(ctx context.Context, transaction *sql.Tx, system string, value string) ([]string, error) {
+	rows, queryError := transaction.QueryContext(ctx,
+		"SELECT id FROM patients WHERE identifier_system = $1 AND identifier_value = $2 AND deleted_at IS NULL FOR UPDATE", system, value)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	patientIDs := []string{}
+	for rows.Next() {
+		var patientID string
+		if scanError := rows.Scan(&patientID); scanError != nil {
+			return nil, scanError
+		}
+		patientIDs = append(patientIDs, patientID)
+	}
+	return patientIDs, rows.Err()
+}
+
+// identifierTaken reports whether a patient other than patientID, and not deleted, has the identifier
+func identifierTaken(ctx context.Context, transaction *sql.Tx, system string, value string, patientID string) (bool, error) {
+	var taken bool
+	existsQuery := "SELECT EXISTS (SELECT 1 FROM patients WHERE identifier_system = $1 AND identifier_value = $2 AND id <> $3 AND deleted_at IS NULL)"
+	scanError := transaction.QueryRowContext(ctx, existsQuery, system, value, patientID).Scan(&taken)
+	return taken, scanError
+}
+
+// insertRekeyRecord appends an audit entry and fills in its ID and time
+func insertRekeyRecord(ctx context.Context, transaction *sql.Tx, record *models.RekeyRecord) error {
+	revertsID := sql.NullInt64{Int64: record.RevertsID, Valid: record.RevertsID != 0}
+	return transaction.QueryRowContext(ctx, insertRekeyRecordQuery,
+		record.JobID, record.PatientID, record.Action, record.OldSystem, record.OldValue,
+		record.NewSystem, record.NewValue, record.Detail, revertsID, record.Actor,
+	).Scan(&record.ID, &record.OccurredAt)
+}
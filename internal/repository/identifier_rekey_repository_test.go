@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupRekeyTestData removes re-keying jobs and their history before patients can be cleaned up
+func cleanupRekeyTestData(t *testing.T, databaseConnection *sql.DB) {
+	for _, table := range []string{"identifier_rekey_audit", "identifier_rekey_mappings", "identifier_rekey_jobs"} {
+		if _, deleteError := databaseConnection.Exec("DELETE FROM " + table); deleteError != nil {
+			t.Fatalf("Failed to cleanup %s: %v", table, deleteError)
+		}
+	}
+	cleanupTestData(t, databaseConnection)
+}
+
+// TestPostgresIdentifierRekeyRepository_ApplyAndRollback verifies batched rewrites, skips, and rollback
+func TestPostgresIdentifierRekeyRepository_ApplyAndRollback(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupRekeyTestData(t, databaseConnection)
+	defer cleanupRekeyTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	patientRepository := NewPostgresPatientRepository(databaseConnection)
+	patientIDs := map[string]string{}
+	for _, identifierValue := range []string{"A1", "A2", "B3"} {
+		identifierSystem := "urn:old"
+		if identifierValue == "B3" {
+			identifierSystem = "urn:new"
+		}
+		patient, createError := patientRepository.Create(ctx, &models.Patient{
+			IdentifierSystem: identifierSystem, IdentifierValue: identifierValue, FamilyName: "Rekey", GivenName: identifierValue, Active: true,
+		})
+		if createError != nil {
+			t.Fatalf("Failed to create patient: %v", createError)
+		}
+		patientIDs[identifierValue] = patient.ID
+	}
+
+	rekeyRepository := NewPostgresIdentifierRekeyRepository(databaseConnection)
+	job, createError := rekeyRepository.CreateJob(ctx, &models.RekeyJob{Reason: "MRN migration", Actor: "api-key:records"}, []models.IdentifierMapping{
+		{Line: 2, OldSystem: "urn:old", OldValue: "A1", NewSystem: "urn:new", NewValue: "B1"},
+		{Line: 3, OldSystem: "urn:old", OldValue: "A2", NewSystem: "urn:new", NewValue: "B3"},
+		{Line: 4, OldSystem: "urn:old", OldValue: "A9", NewSystem: "urn:new", NewValue: "B9"},
+	})
+	if createError != nil {
+		t.Fatalf("Expected no error creating job, got %v", createError)
+	}
+
+	firstBatch, applyError := rekeyRepository.ApplyBatch(ctx, job.ID, 2)
+	if applyError != nil || len(firstBatch) != 2 {
+		t.Fatalf("Expected a batch of 2, got %d (%v)", len(firstBatch), applyError)
+	}
+	if firstBatch[0].Action != models.RekeyActionRekeyed || firstBatch[1].Action != models.RekeyActionSkipped {
+		t.Errorf("Expected a rewrite then a conflict skip, got %s and %s", firstBatch[0].Action, firstBatch[1].Action)
+	}
+	rekeyRepository.ApplyBatch(ctx, job.ID, 2)
+
+	job, _ = rekeyRepository.GetJob(ctx, job.ID)
+	if job.Status != models.RekeyJobStatusCompleted || job.Processed != 3 || job.Rekeyed != 1 || job.Skipped != 2 {
+		t.Fatalf("Unexpected job after applying %+v", job)
+	}
+	rekeyedPatient, _ := patientRepository.GetByID(ctx, patientIDs["A1"])
+	if rekeyedPatient.IdentifierSystem != "urn:new" || rekeyedPatient.IdentifierValue != "B1" {
+		t.Errorf("Expected urn:new|B1, got %s|%s", rekeyedPatient.IdentifierSystem, rekeyedPatient.IdentifierValue)
+	}
+
+	if rollbackError := rekeyRepository.StartRollback(ctx, job.ID, "api-key:records"); rollbackError != nil {
+		t.Fatalf("Expected no error starting rollback, got %v", rollbackError)
+	}
+	if _, applyError := rekeyRepository.ApplyBatch(ctx, job.ID, 2); applyError != nil {
+		t.Errorf("Expected applying a rolling back job to do nothing, got %v", applyError)
+	}
+	rollbackRecords, rollbackError := rekeyRepository.RollbackBatch(ctx, job.ID, 2)
+	if rollbackError != nil || len(rollbackRecords) != 1 || rollbackRecords[0].Action != models.RekeyActionRolledBack {
+		t.Fatalf("Expected one rolled back entry, got %+v (%v)", rollbackRecords, rollbackError)
+	}
+
+	restoredPatient, _ := patientRepository.GetByID(ctx, patientIDs["A1"])
+	if restoredPatient.IdentifierSystem != "urn:old" || restoredPatient.IdentifierValue != "A1" {
+		t.Errorf("Expected urn:old|A1 restored, got %s|%s", restoredPatient.IdentifierSystem, restoredPatient.IdentifierValue)
+	}
+	job, _ = rekeyRepository.GetJob(ctx, job.ID)
+	if job.Status != models.RekeyJobStatusRolledBack || job.RolledBack != 1 {
+		t.Errorf("Unexpected job after rollback %+v", job)
+	}
+
+	records, _ := rekeyRepository.ListRecords(ctx, job.ID)
+	if len(records) != 4 || records[3].RevertsID != records[0].ID {
+		t.Errorf("Expected 4 audit entries with the rollback reverting the first, got %+v", records)
+	}
+}
@@ -0,0 +1,271 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// ImmunizationRepository defines the interface for immunization data operations
+type ImmunizationRepository interface {
+	// Create inserts a new immunization record and returns the created immunization with ID
+	Create(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error)
+
+	// GetByID retrieves an immunization by its unique identifier
+	GetByID(ctx context.Context, immunizationID string) (*models.Immunization, error)
+
+	// Search retrieves immunizations matching the search criteria
+	Search(ctx context.Context, searchParams *models.ImmunizationSearchParams) ([]*models.Immunization, error)
+
+	// Count returns how many immunizations match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.ImmunizationSearchParams) (int, error)
+
+	// Update modifies an existing immunization record
+	Update(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error)
+
+	// Delete marks an immunization deleted by ID
+	Delete(ctx context.Context, immunizationID string) error
+}
+
+// immunizationColumns are the columns read into a models.Immunization by scanImmunization, in order
+const immunizationColumns = `id, status, vaccine_system, vaccine_code, vaccine_display, patient_id,
+		occurrence_date_time, occurred_at, lot_number, created_at, updated_at`
+
+// PostgresImmunizationRepository implements ImmunizationRepository using PostgreSQL
+type PostgresImmunizationRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresImmunizationRepository creates a new PostgreSQL immunization repository instance
+func NewPostgresImmunizationRepository(databaseConnection *sql.DB) *PostgresImmunizationRepository {
+	return &PostgresImmunizationRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// scanImmunization reads one row selected with immunizationColumns
+// Optional columns are NULL when the immunization left them out, so they are read through nullable types
+func scanImmunization(row interface{ Scan(...interface{}) error }) (*models.Immunization, error) {
+	immunization := &models.Immunization{}
+	var vaccineSystem, vaccineCode, vaccineDisplay, patientID, occurrenceDateTime, lotNumber sql.NullString
+	var occurredAt sql.NullTime
+	scanError := row.Scan(
+		&immunization.ID,
+		&immunization.Status,
+		&vaccineSystem,
+		&vaccineCode,
+		&vaccineDisplay,
+		&patientID,
+		&occurrenceDateTime,
+		&occurredAt,
+		&lotNumber,
+		&immunization.CreatedAt,
+		&immunization.UpdatedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	immunization.VaccineSystem = vaccineSystem.String
+	immunization.VaccineCode = vaccineCode.String
+	immunization.VaccineDisplay = vaccineDisplay.String
+	immunization.PatientID = patientID.String
+	immunization.OccurrenceDateTime = occurrenceDateTime.String
+	immunization.LotNumber = lotNumber.String
+	if occurredAt.Valid {
+		immunization.OccurredAt = &occurredAt.Time
+	}
+	return immunization, nil
+}
+
+// Create inserts a new immunization record into the database
+func (repository *PostgresImmunizationRepository) Create(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error) {
+	insertQuery := `
+		INSERT INTO immunizations (status, vaccine_system, vaccine_code, vaccine_display, patient_id,
+			occurrence_date_time, occurred_at, lot_number)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		insertQuery,
+		immunization.Status,
+		immunization.VaccineSystem,
+		immunization.VaccineCode,
+		immunization.VaccineDisplay,
+		immunization.PatientID,
+		immunization.OccurrenceDateTime,
+		immunization.OccurredAt,
+		immunization.LotNumber,
+	).Scan(&immunization.ID, &immunization.CreatedAt, &immunization.UpdatedAt)
+
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return immunization, nil
+}
+
+// GetByID retrieves an immunization by its unique identifier
+// Returns sql.ErrNoRows when the immunization does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresImmunizationRepository) GetByID(ctx context.Context, immunizationID string) (*models.Immunization, error) {
+	executor := executorFor(ctx, repository.databaseConnection)
+	selectQuery := `SELECT ` + immunizationColumns + ` FROM immunizations WHERE id = $1 AND ` + notDeletedCondition
+	immunization, scanError := scanImmunization(executor.QueryRowContext(ctx, selectQuery, immunizationID))
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executor, "immunizations", immunizationID)
+	}
+	return immunization, scanError
+}
+
+// Update modifies an existing immunization record in the database
+// Returns sql.ErrNoRows when the immunization does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresImmunizationRepository) Update(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error) {
+	updateQuery := `
+		UPDATE immunizations
+		SET status = $1, vaccine_system = $2, vaccine_code = $3, vaccine_display = $4, patient_id = NULLIF($5, '')::uuid,
+			occurrence_date_time = $6, occurred_at = $7, lot_number = $8, updated_at = $9
+		WHERE id = $10 AND deleted_at IS NULL
+		RETURNING created_at, updated_at
+	`
+
+	// Set the updated timestamp
+	immunization.UpdatedAt = time.Now()
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		updateQuery,
+		immunization.Status,
+		immunization.VaccineSystem,
+		immunization.VaccineCode,
+		immunization.VaccineDisplay,
+		immunization.PatientID,
+		immunization.OccurrenceDateTime,
+		immunization.OccurredAt,
+		immunization.LotNumber,
+		immunization.UpdatedAt,
+		immunization.ID,
+	).Scan(&immunization.CreatedAt, &immunization.UpdatedAt)
+
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executorFor(ctx, repository.databaseConnection), "immunizations", immunization.ID)
+	}
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return immunization, nil
+}
+
+// immunizationSearchConditions builds the AND clauses that filter immunizations on the search criteria,
+// shared by Search and Count so a page and its total always agree; the returned parameters are numbered from $1
+func immunizationSearchConditions(searchParams *models.ImmunizationSearchParams) (string, []interface{}) {
+	conditions := ""
+	queryParameters := []interface{}{}
+	parameterIndex := 1
+
+	// Add patient filter
+	if searchParams.PatientID != "" {
+		conditions += ` AND patient_id = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.PatientID)
+		parameterIndex++
+	}
+
+	// Add vaccine code filter; "|code" matches vaccines stored without a system
+	if searchParams.VaccineCode != nil {
+		if !searchParams.VaccineCode.AnySystem {
+			conditions += ` AND COALESCE(vaccine_system, '') = $` + fmt.Sprint(parameterIndex)
+			queryParameters = append(queryParameters, searchParams.VaccineCode.System)
+			parameterIndex++
+		}
+		if searchParams.VaccineCode.Code != "" {
+			conditions += ` AND vaccine_code = $` + fmt.Sprint(parameterIndex)
+			queryParameters = append(queryParameters, searchParams.VaccineCode.Code)
+			parameterIndex++
+		}
+	}
+
+	// Add status filter
+	if searchParams.Status != "" {
+		conditions += ` AND status = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.Status)
+		parameterIndex++
+	}
+
+	// Add lot number filter
+	if searchParams.LotNumber != "" {
+		conditions += ` AND lot_number = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.LotNumber)
+		parameterIndex++
+	}
+
+	// Add occurrence date range filters
+	if searchParams.DateGreaterThan != nil {
+		conditions += ` AND occurred_at >= $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.DateGreaterThan)
+		parameterIndex++
+	}
+	if searchParams.DateLessThan != nil {
+		conditions += ` AND occurred_at <= $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.DateLessThan)
+		parameterIndex++
+	}
+
+	return conditions, queryParameters
+}
+
+// Search retrieves immunizations matching the search criteria, most recently given first
+func (repository *PostgresImmunizationRepository) Search(ctx context.Context, searchParams *models.ImmunizationSearchParams) ([]*models.Immunization, error) {
+	conditions, queryParameters := immunizationSearchConditions(searchParams)
+	parameterIndex := len(queryParameters) + 1
+
+	searchQuery := `SELECT ` + immunizationColumns + ` FROM immunizations WHERE ` + notDeletedCondition + conditions +
+		` ORDER BY occurred_at DESC NULLS LAST, created_at DESC, id LIMIT $` + fmt.Sprint(parameterIndex) + ` OFFSET $` + fmt.Sprint(parameterIndex+1)
+	queryParameters = append(queryParameters, searchParams.Limit, searchParams.Offset)
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, searchQuery, queryParameters...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	immunizations := []*models.Immunization{}
+	for rows.Next() {
+		immunization, scanError := scanImmunization(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		immunizations = append(immunizations, immunization)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return immunizations, nil
+}
+
+// Count returns how many immunizations match the search criteria, ignoring the page's limit and offset
+func (repository *PostgresImmunizationRepository) Count(ctx context.Context, searchParams *models.ImmunizationSearchParams) (int, error) {
+	conditions, queryParameters := immunizationSearchConditions(searchParams)
+
+	var total int
+	countError := repository.databaseConnection.QueryRowContext(ctx, `SELECT COUNT(*) FROM immunizations WHERE `+notDeletedCondition+conditions, queryParameters...).Scan(&total)
+	if countError != nil {
+		return 0, countError
+	}
+	return total, nil
+}
+
+// Delete marks an immunization deleted by ID, leaving the row for a read of it to answer as deleted
+// Returns sql.ErrNoRows when the immunization does not exist, wrapped with ErrResourceDeleted when it was already deleted
+func (repository *PostgresImmunizationRepository) Delete(ctx context.Context, immunizationID string) error {
+	return softDeleteRow(ctx, executorFor(ctx, repository.databaseConnection), "immunizations", immunizationID)
+}
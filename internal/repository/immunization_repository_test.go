@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupImmunizations removes all test data from the immunizations table
+func cleanupImmunizations(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM immunizations"); deleteError != nil {
+		t.Fatalf("Failed to cleanup immunizations: %v", deleteError)
+	}
+}
+
+// TestPostgresImmunizationRepository_CRUD verifies an immunization is created, read, updated, and deleted
+func TestPostgresImmunizationRepository_CRUD(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupImmunizations(t, databaseConnection)
+	defer cleanupImmunizations(t, databaseConnection)
+
+	immunizationRepository := NewPostgresImmunizationRepository(databaseConnection)
+	ctx := context.Background()
+	occurredAt := time.Date(2024, 10, 2, 14, 30, 0, 0, time.UTC)
+
+	createdImmunization, createError := immunizationRepository.Create(ctx, &models.Immunization{
+		Status:             "completed",
+		VaccineSystem:      "http://hl7.org/fhir/sid/cvx",
+		VaccineCode:        "140",
+		PatientID:          uuid.NewString(),
+		OccurrenceDateTime: "2024-10-02T14:30:00Z",
+		OccurredAt:         &occurredAt,
+		LotNumber:          "AAJN11K",
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create immunization: %v", createError)
+	}
+	if createdImmunization.ID == "" || createdImmunization.CreatedAt.IsZero() {
+		t.Fatalf("Expected a generated ID and timestamps, got %+v", createdImmunization)
+	}
+
+	createdImmunization.Status = "entered-in-error"
+	if _, updateError := immunizationRepository.Update(ctx, createdImmunization); updateError != nil {
+		t.Fatalf("Failed to update immunization: %v", updateError)
+	}
+
+	storedImmunization, getError := immunizationRepository.GetByID(ctx, createdImmunization.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read immunization: %v", getError)
+	}
+	if storedImmunization.Status != "entered-in-error" || storedImmunization.LotNumber != "AAJN11K" || storedImmunization.VaccineDisplay != "" {
+		t.Errorf("Expected the entered-in-error immunization from lot AAJN11K without a display, got %+v", storedImmunization)
+	}
+	if storedImmunization.OccurredAt == nil || !storedImmunization.OccurredAt.Equal(occurredAt) || storedImmunization.OccurrenceDateTime != "2024-10-02T14:30:00Z" {
+		t.Errorf("Expected the occurrence date, got %+v", storedImmunization)
+	}
+
+	if deleteError := immunizationRepository.Delete(ctx, createdImmunization.ID); deleteError != nil {
+		t.Fatalf("Failed to delete immunization: %v", deleteError)
+	}
+	if deleteError := immunizationRepository.Delete(ctx, createdImmunization.ID); !errors.Is(deleteError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", deleteError)
+	}
+}
+
+// TestPostgresImmunizationRepository_Search verifies patient, vaccine code, status, lot number, and date filters and the total
+func TestPostgresImmunizationRepository_Search(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupImmunizations(t, databaseConnection)
+	defer cleanupImmunizations(t, databaseConnection)
+
+	immunizationRepository := NewPostgresImmunizationRepository(databaseConnection)
+	ctx := context.Background()
+	patientID := uuid.NewString()
+	cvxSystem := "http://hl7.org/fhir/sid/cvx"
+	january := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	march := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, immunization := range []*models.Immunization{
+		{Status: "completed", VaccineSystem: cvxSystem, VaccineCode: "140", PatientID: patientID, OccurredAt: &january, LotNumber: "LOT-1"},
+		{Status: "completed", VaccineSystem: cvxSystem, VaccineCode: "208", PatientID: patientID, OccurredAt: &march, LotNumber: "LOT-2"},
+		{Status: "not-done", VaccineCode: "140", PatientID: uuid.NewString(), OccurredAt: &march},
+	} {
+		if _, createError := immunizationRepository.Create(ctx, immunization); createError != nil {
+			t.Fatalf("Failed to create immunization: %v", createError)
+		}
+	}
+
+	februaryFirst := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		searchParams  models.ImmunizationSearchParams
+		expectedCount int
+	}{
+		"patient":        {searchParams: models.ImmunizationSearchParams{PatientID: patientID}, expectedCount: 2},
+		"code any":       {searchParams: models.ImmunizationSearchParams{VaccineCode: &models.CodingCriterion{Code: "140", AnySystem: true}}, expectedCount: 2},
+		"code no system": {searchParams: models.ImmunizationSearchParams{VaccineCode: &models.CodingCriterion{Code: "140"}}, expectedCount: 1},
+		"status":         {searchParams: models.ImmunizationSearchParams{Status: "not-done"}, expectedCount: 1},
+		"lot number":     {searchParams: models.ImmunizationSearchParams{LotNumber: "LOT-2"}, expectedCount: 1},
+		"after":          {searchParams: models.ImmunizationSearchParams{DateGreaterThan: &februaryFirst}, expectedCount: 2},
+		"before":         {searchParams: models.ImmunizationSearchParams{DateLessThan: &februaryFirst}, expectedCount: 1},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		immunizations, searchError := immunizationRepository.Search(ctx, &testCase.searchParams)
+		if searchError != nil {
+			t.Fatalf("%s: search failed: %v", name, searchError)
+		}
+		total, countError := immunizationRepository.Count(ctx, &testCase.searchParams)
+		if countError != nil {
+			t.Fatalf("%s: count failed: %v", name, countError)
+		}
+		if len(immunizations) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d immunizations, got %d with total %d", name, testCase.expectedCount, len(immunizations), total)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// JobRepository defines the interface for the persistent background job queue
+type JobRepository interface {
+	// Enqueue stores a new queued job, due immediately unless RunAfter is set
+	Enqueue(ctx context.Context, job *models.Job) (*models.Job, error)
+
+	// Get returns a job by ID; sql.ErrNoRows when there is none
+	Get(ctx context.Context, jobID string) (*models.Job, error)
+
+	// List returns jobs matching the filter, newest first
+	List(ctx context.Context, filter models.JobFilter, limit int, offset int) ([]*models.Job, error)
+
+	// ClaimNext marks the next due job of one of the kinds running and counts the attempt, also taking over
+	// running jobs not touched since staleBefore; sql.ErrNoRows when none is due
+	ClaimNext(ctx context.Context, kinds []string, now time.Time, staleBefore time.Time) (*models.Job, error)
+
+	// Heartbeat records a running job's progress; sql.ErrNoRows once it is no longer running
+	Heartbeat(ctx context.Context, jobID string, progress string) error
+
+	// Succeed stores a running job's result; sql.ErrNoRows when it is no longer running
+	Succeed(ctx context.Context, jobID string, result []byte, expiresAt time.Time) error
+
+	// Reschedule returns a running job to the queue after a failed attempt; sql.ErrNoRows when it is no longer running
+	Reschedule(ctx context.Context, jobID string, runAfter time.Time, lastError string) error
+
+	// Fail marks a running job failed; sql.ErrNoRows when it is no longer running
+	Fail(ctx context.Context, jobID string, lastError string, expiresAt time.Time) error
+
+	// Cancel marks a queued or running job cancelled, dropping its payload; sql.ErrNoRows when it already finished
+	Cancel(ctx context.Context, jobID string, expiresAt time.Time) error
+
+	// Discard deletes a finished job; sql.ErrNoRows when it is still queued or running
+	Discard(ctx context.Context, jobID string) error
+
+	// DeleteExpired removes finished jobs that expired before the given time and returns how many were removed
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// PostgresJobRepository implements JobRepository using PostgreSQL
+type PostgresJobRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresJobRepository creates a new PostgreSQL job repository instance
+func NewPostgresJobRepository(databaseConnection *sql.DB) *PostgresJobRepository {
+	return &PostgresJobRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// jobColumns lists the columns scanned by scanJob
+const jobColumns = `id, kind, payload, result, status, progress, attempts, max_attempts, last_error, principal_id, tenant_id,
+	run_after, created_at, updated_at, started_at, finished_at, expires_at`
+
+// Enqueue inserts a queued job, in the caller's transaction when ctx carries one
+func (repository *PostgresJobRepository) Enqueue(ctx context.Context, job *models.Job) (*models.Job, error) {
+	runAfter := job.RunAfter
+	if runAfter.IsZero() {
+		runAfter = time.Now()
+	}
+
+	insertQuery := `
+		INSERT INTO jobs (kind, payload, status, max_attempts, principal_id, tenant_id, run_after)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + jobColumns
+
+	return scanJob(executorFor(ctx, repository.databaseConnection).QueryRowContext(ctx, insertQuery,
+		job.Kind, job.Payload, models.JobStatusQueued, job.MaxAttempts, job.PrincipalID, job.TenantID, runAfter))
+}
+
+// Get retrieves a job by ID
+func (repository *PostgresJobRepository) Get(ctx context.Context, jobID string) (*models.Job, error) {
+	return scanJob(repository.databaseConnection.QueryRowContext(ctx, `SELECT `+jobColumns+` FROM jobs WHERE id = $1`, jobID))
+}
+
+// List returns a page of jobs, newest first, without their payloads and results
+func (repository *PostgresJobRepository) List(ctx context.Context, filter models.JobFilter, limit int, offset int) ([]*models.Job, error) {
+	listQuery := `
+		SELECT id, kind, NULL::BYTEA, NULL::BYTEA, status, progress, attempts, max_attempts, last_error, principal_id, tenant_id,
+			run_after, created_at, updated_at, started_at, finished_at, expires_at
+		FROM jobs
+		WHERE ($1 = '' OR status = $1) AND ($2 = '' OR kind = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, listQuery, string(filter.Status), filter.Kind, limit, offset)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	jobs := []*models.Job{}
+	for rows.Next() {
+		job, scanError := scanJob(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimNext claims the next due job; SKIP LOCKED lets several workers claim without blocking each other
+func (repository *PostgresJobRepository) ClaimNext(ctx context.Context, kinds []string, now time.Time, staleBefore time.Time) (*models.Job, error) {
+	claimQuery := `
+		UPDATE jobs
+		SET status = $1, attempts = attempts + 1, started_at = $4, updated_at = $4
+		WHERE id = (
+			SELECT id FROM jobs
+			WHERE kind = ANY($3) AND ((status = $2 AND run_after <= $4) OR (status = $1 AND updated_at < $5))
+			ORDER BY run_after, created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	return scanJob(repository.databaseConnection.QueryRowContext(ctx, claimQuery,
+		models.JobStatusRunning, models.JobStatusQueued, pq.Array(kinds), now, staleBefore))
+}
+
+// Heartbeat refreshes a running job's updated_at and progress
+func (repository *PostgresJobRepository) Heartbeat(ctx context.Context, jobID string, progress string) error {
+	result, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE jobs
+		SET progress = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $2`, jobID, models.JobStatusRunning, progress)
+	return runningExportUpdated(result, updateError)
+}
+
+// Succeed stores the result and keeps the job until expiresAt
+func (repository *PostgresJobRepository) Succeed(ctx context.Context, jobID string, result []byte, expiresAt time.Time) error {
+	updateResult, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $3, result = $4, last_error = '', finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, expires_at = $5
+		WHERE id = $1 AND status = $2`,
+		jobID, models.JobStatusRunning, models.JobStatusSucceeded, result, expiresAt)
+	return runningExportUpdated(updateResult, updateError)
+}
+
+// Reschedule queues the job again for runAfter, recording why the attempt failed
+func (repository *PostgresJobRepository) Reschedule(ctx context.Context, jobID string, runAfter time.Time, lastError string) error {
+	result, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $3, run_after = $4, last_error = $5, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $2`,
+		jobID, models.JobStatusRunning, models.JobStatusQueued, runAfter, lastError)
+	return runningExportUpdated(result, updateError)
+}
+
+// Fail records why the job failed and keeps it until expiresAt
+func (repository *PostgresJobRepository) Fail(ctx context.Context, jobID string, lastError string, expiresAt time.Time) error {
+	result, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $3, last_error = $4, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, expires_at = $5
+		WHERE id = $1 AND status = $2`,
+		jobID, models.JobStatusRunning, models.JobStatusFailed, lastError, expiresAt)
+	return runningExportUpdated(result, updateError)
+}
+
+// Cancel marks the job cancelled; a worker running it stops at its next heartbeat
+func (repository *PostgresJobRepository) Cancel(ctx context.Context, jobID string, expiresAt time.Time) error {
+	result, updateError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE jobs
+		SET status = $4, payload = NULL, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, expires_at = $5
+		WHERE id = $1 AND status IN ($2, $3)`,
+		jobID, models.JobStatusQueued, models.JobStatusRunning, models.JobStatusCancelled, expiresAt)
+	return runningExportUpdated(result, updateError)
+}
+
+// Discard deletes a finished job and its result
+func (repository *PostgresJobRepository) Discard(ctx context.Context, jobID string) error {
+	result, deleteError := repository.databaseConnection.ExecContext(ctx,
+		"DELETE FROM jobs WHERE id = $1 AND status NOT IN ($2, $3)", jobID, models.JobStatusQueued, models.JobStatusRunning)
+	return runningExportUpdated(result, deleteError)
+}
+
+// DeleteExpired removes finished jobs whose expiry has passed
+func (repository *PostgresJobRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, deleteError := repository.databaseConnection.ExecContext(ctx, "DELETE FROM jobs WHERE expires_at <= $1", before)
+	if deleteError != nil {
+		return 0, deleteError
+	}
+	return result.RowsAffected()
+}
+
+// scanJob reads one row selected with jobColumns
+func scanJob(row rowScanner) (*models.Job, error) {
+	job := &models.Job{}
+	var status string
+	var startedAt, finishedAt, expiresAt sql.NullTime
+	scanError := row.Scan(&job.ID, &job.Kind, &job.Payload, &job.Result, &status, &job.Progress, &job.Attempts, &job.MaxAttempts,
+		&job.LastError, &job.PrincipalID, &job.TenantID, &job.RunAfter, &job.CreatedAt, &job.UpdatedAt, &startedAt, &finishedAt, &expiresAt)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	job.Status = models.JobStatus(status)
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	if expiresAt.Valid {
+		job.ExpiresAt = &expiresAt.Time
+	}
+	return job, nil
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupJobTestData empties the jobs table
+func cleanupJobTestData(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM jobs"); deleteError != nil {
+		t.Fatalf("Failed to cleanup jobs: %v", deleteError)
+	}
+}
+
+// TestPostgresJobRepository_Lifecycle verifies claiming, retry scheduling, stale takeover, and finishing a job
+func TestPostgresJobRepository_Lifecycle(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupJobTestData(t, databaseConnection)
+	defer cleanupJobTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	jobRepository := NewPostgresJobRepository(databaseConnection)
+
+	queued, enqueueError := jobRepository.Enqueue(ctx, &models.Job{
+		Kind: "test", Payload: []byte(`{"n":1}`), MaxAttempts: 3, PrincipalID: "alice", TenantID: "default",
+	})
+	if enqueueError != nil || queued.Status != models.JobStatusQueued || queued.ID == "" {
+		t.Fatalf("Unexpected enqueue result %+v (%v)", queued, enqueueError)
+	}
+
+	now := time.Now().Add(time.Second)
+	if _, claimError := jobRepository.ClaimNext(ctx, []string{"other"}, now, now.Add(-time.Minute)); !errors.Is(claimError, sql.ErrNoRows) {
+		t.Errorf("Expected other kinds not to be claimed, got %v", claimError)
+	}
+
+	claimed, claimError := jobRepository.ClaimNext(ctx, []string{"test"}, now, now.Add(-time.Minute))
+	if claimError != nil || claimed.Status != models.JobStatusRunning || claimed.Attempts != 1 || string(claimed.Payload) != `{"n":1}` {
+		t.Fatalf("Expected the job to be claimed, got %+v (%v)", claimed, claimError)
+	}
+
+	// A running job is not claimed again while its heartbeat is recent
+	if _, claimError := jobRepository.ClaimNext(ctx, []string{"test"}, now, now.Add(-time.Minute)); !errors.Is(claimError, sql.ErrNoRows) {
+		t.Errorf("Expected the running job to be skipped, got %v", claimError)
+	}
+
+	if heartbeatError := jobRepository.Heartbeat(ctx, queued.ID, "1 of 2"); heartbeatError != nil {
+		t.Fatalf("Unexpected heartbeat error: %v", heartbeatError)
+	}
+	if rescheduleError := jobRepository.Reschedule(ctx, queued.ID, now.Add(time.Hour), "timeout"); rescheduleError != nil {
+		t.Fatalf("Unexpected reschedule error: %v", rescheduleError)
+	}
+	if _, claimError := jobRepository.ClaimNext(ctx, []string{"test"}, now, now.Add(-time.Minute)); !errors.Is(claimError, sql.ErrNoRows) {
+		t.Errorf("Expected the retry to wait for its backoff, got %v", claimError)
+	}
+
+	// A running job whose worker stopped is taken over once its heartbeat is older than staleBefore
+	later := now.Add(2 * time.Hour)
+	jobRepository.ClaimNext(ctx, []string{"test"}, later, later.Add(-time.Minute))
+	takenOver, claimError := jobRepository.ClaimNext(ctx, []string{"test"}, later, later.Add(time.Minute))
+	if claimError != nil || takenOver.Attempts != 3 || takenOver.Progress != "1 of 2" || takenOver.LastError != "timeout" {
+		t.Fatalf("Expected the stale job to be taken over, got %+v (%v)", takenOver, claimError)
+	}
+
+	if discardError := jobRepository.Discard(ctx, queued.ID); !errors.Is(discardError, sql.ErrNoRows) {
+		t.Errorf("Expected a running job not to be discarded, got %v", discardError)
+	}
+	if succeedError := jobRepository.Succeed(ctx, queued.ID, []byte("result"), later); succeedError != nil {
+		t.Fatalf("Unexpected succeed error: %v", succeedError)
+	}
+	if failError := jobRepository.Fail(ctx, queued.ID, "late", later); !errors.Is(failError, sql.ErrNoRows) {
+		t.Errorf("Expected a finished job not to be failed, got %v", failError)
+	}
+
+	succeeded, getError := jobRepository.Get(ctx, queued.ID)
+	if getError != nil || succeeded.Status != models.JobStatusSucceeded || string(succeeded.Result) != "result" ||
+		succeeded.FinishedAt == nil || succeeded.ExpiresAt == nil || succeeded.LastError != "" {
+		t.Fatalf("Unexpected succeeded job %+v (%v)", succeeded, getError)
+	}
+
+	if cancelError := jobRepository.Cancel(ctx, queued.ID, later); !errors.Is(cancelError, sql.ErrNoRows) {
+		t.Errorf("Expected a finished job not to be cancelled, got %v", cancelError)
+	}
+	if discardError := jobRepository.Discard(ctx, queued.ID); discardError != nil {
+		t.Errorf("Unexpected discard error: %v", discardError)
+	}
+	if _, getError := jobRepository.Get(ctx, queued.ID); !errors.Is(getError, sql.ErrNoRows) {
+		t.Errorf("Expected the discarded job to be gone, got %v", getError)
+	}
+}
+
+// TestPostgresJobRepository_ListCancelAndExpire verifies filtered listing, cancellation, and expiry
+func TestPostgresJobRepository_ListCancelAndExpire(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupJobTestData(t, databaseConnection)
+	defer cleanupJobTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	jobRepository := NewPostgresJobRepository(databaseConnection)
+
+	first, _ := jobRepository.Enqueue(ctx, &models.Job{Kind: "test", Payload: []byte("secret"), MaxAttempts: 1})
+	jobRepository.Enqueue(ctx, &models.Job{Kind: "other", MaxAttempts: 1})
+
+	listed, listError := jobRepository.List(ctx, models.JobFilter{Kind: "test"}, 10, 0)
+	if listError != nil || len(listed) != 1 || listed[0].ID != first.ID || listed[0].Payload != nil {
+		t.Fatalf("Expected one listed job without its payload, got %+v (%v)", listed, listError)
+	}
+
+	if cancelError := jobRepository.Cancel(ctx, first.ID, time.Now().Add(-time.Second)); cancelError != nil {
+		t.Fatalf("Unexpected cancel error: %v", cancelError)
+	}
+	cancelled, _ := jobRepository.Get(ctx, first.ID)
+	if cancelled.Status != models.JobStatusCancelled || cancelled.Payload != nil {
+		t.Errorf("Expected a cancelled job without its payload, got %+v", cancelled)
+	}
+
+	if listed, _ := jobRepository.List(ctx, models.JobFilter{Status: models.JobStatusQueued}, 10, 0); len(listed) != 1 || listed[0].Kind != "other" {
+		t.Errorf("Expected only the other job to be queued, got %+v", listed)
+	}
+
+	deleted, deleteError := jobRepository.DeleteExpired(ctx, time.Now())
+	if deleteError != nil || deleted != 1 {
+		t.Errorf("Expected one expired job to be deleted, got %d (%v)", deleted, deleteError)
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// LedgerRepository defines the interface for the expected observation count ledger
+type LedgerRepository interface {
+	// Adjust adds delta (positive or negative) to a patient's expected observation count
+	Adjust(ctx context.Context, patientID string, delta int64) error
+
+	// ExpectedCounts returns the expected observation count for every patient in the ledger
+	ExpectedCounts(ctx context.Context) (map[string]int64, error)
+}
+
+// PostgresLedgerRepository implements LedgerRepository using PostgreSQL
+type PostgresLedgerRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresLedgerRepository creates a new PostgreSQL ledger repository instance
+func NewPostgresLedgerRepository(databaseConnection *sql.DB) *PostgresLedgerRepository {
+	return &PostgresLedgerRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// Adjust upserts the patient's row and applies the delta atomically
+func (repository *PostgresLedgerRepository) Adjust(ctx context.Context, patientID string, delta int64) error {
+	upsertQuery := `
+		INSERT INTO observation_ledger (patient_id, observation_count)
+		VALUES ($1, $2)
+		ON CONFLICT (patient_id) DO UPDATE
+		SET observation_count = observation_ledger.observation_count + EXCLUDED.observation_count,
+			updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, execError := repository.databaseConnection.ExecContext(ctx, upsertQuery, patientID, delta)
+	return execError
+}
+
+// ExpectedCounts returns every ledger row as a patient ID to count map
+func (repository *PostgresLedgerRepository) ExpectedCounts(ctx context.Context) (map[string]int64, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, "SELECT patient_id, observation_count FROM observation_ledger")
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	expectedCounts := make(map[string]int64)
+	for rows.Next() {
+		var patientID string
+		var observationCount int64
+		if scanError := rows.Scan(&patientID, &observationCount); scanError != nil {
+			return nil, scanError
+		}
+		expectedCounts[patientID] = observationCount
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return expectedCounts, nil
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+// cleanupLedgerTestData removes all entries from the observation_ledger table
+func cleanupLedgerTestData(t *testing.T, databaseConnection *sql.DB) {
+	_, deleteError := databaseConnection.Exec("DELETE FROM observation_ledger")
+	if deleteError != nil {
+		t.Fatalf("Failed to cleanup observation ledger: %v", deleteError)
+	}
+}
+
+// TestPostgresLedgerRepository_AdjustAndExpectedCounts verifies deltas accumulate per patient
+func TestPostgresLedgerRepository_AdjustAndExpectedCounts(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupLedgerTestData(t, databaseConnection)
+	defer cleanupLedgerTestData(t, databaseConnection)
+
+	ledgerRepository := NewPostgresLedgerRepository(databaseConnection)
+	ctx := context.Background()
+
+	ledgerRepository.Adjust(ctx, "patient-1", 1)
+	ledgerRepository.Adjust(ctx, "patient-1", 1)
+	ledgerRepository.Adjust(ctx, "patient-1", -1)
+	if adjustError := ledgerRepository.Adjust(ctx, "patient-2", 1); adjustError != nil {
+		t.Fatalf("Expected no error, got %v", adjustError)
+	}
+
+	expectedCounts, countsError := ledgerRepository.ExpectedCounts(ctx)
+	if countsError != nil {
+		t.Fatalf("Expected no error, got %v", countsError)
+	}
+	if expectedCounts["patient-1"] != 1 || expectedCounts["patient-2"] != 1 {
+		t.Errorf("Unexpected ledger counts: %v", expectedCounts)
+	}
+}
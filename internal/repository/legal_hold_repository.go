@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// LegalHoldRepository defines the interface for legal hold storage and its audit history
+type LegalHoldRepository interface {
+	// Place creates or replaces the patient's hold and records the change in the audit history
+	Place(ctx context.Context, hold *models.LegalHold) (*models.LegalHold, error)
+
+	// Release removes the patient's hold and records the release; sql.ErrNoRows when none is active
+	Release(ctx context.Context, patientID string, actor string, reason string) error
+
+	// Get returns the patient's active hold; sql.ErrNoRows when none is active
+	Get(ctx context.Context, patientID string) (*models.LegalHold, error)
+
+	// RecordEvent appends an entry to the audit history
+	RecordEvent(ctx context.Context, event *models.LegalHoldEvent) error
+
+	// ListEvents returns the patient's audit history, oldest first
+	ListEvents(ctx context.Context, patientID string) ([]*models.LegalHoldEvent, error)
+}
+
+// PostgresLegalHoldRepository implements LegalHoldRepository using PostgreSQL
+type PostgresLegalHoldRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresLegalHoldRepository creates a new PostgreSQL legal hold repository instance
+func NewPostgresLegalHoldRepository(databaseConnection *sql.DB) *PostgresLegalHoldRepository {
+	return &PostgresLegalHoldRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// insertAuditQuery appends one audit entry
+const insertAuditQuery = `
+	INSERT INTO legal_hold_audit (patient_id, action, resource_type, resource_id, actor, reason)
+	VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+// Place upserts the hold and its audit entry in one transaction
+func (repository *PostgresLegalHoldRepository) Place(ctx context.Context, hold *models.LegalHold) (*models.LegalHold, error) {
+	transaction, beginError := repository.databaseConnection.BeginTx(ctx, nil)
+	if beginError != nil {
+		return nil, beginError
+	}
+	defer transaction.Rollback()
+
+	upsertQuery := `
+		INSERT INTO patient_legal_holds (patient_id, reason, placed_by)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (patient_id) DO UPDATE
+		SET reason = EXCLUDED.reason, placed_by = EXCLUDED.placed_by, placed_at = CURRENT_TIMESTAMP
+		RETURNING placed_at
+	`
+	scanError := transaction.QueryRowContext(ctx, upsertQuery, hold.PatientID, hold.Reason, hold.PlacedBy).Scan(&hold.PlacedAt)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	_, auditError := transaction.ExecContext(ctx, insertAuditQuery,
+		hold.PatientID, models.LegalHoldActionPlaced, "Patient", hold.PatientID, hold.PlacedBy, hold.Reason)
+	if auditError != nil {
+		return nil, auditError
+	}
+
+	if commitError := transaction.Commit(); commitError != nil {
+		return nil, commitError
+	}
+	return hold, nil
+}
+
+// Release deletes the hold and records the release in one transaction
+func (repository *PostgresLegalHoldRepository) Release(ctx context.Context, patientID string, actor string, reason string) error {
+	transaction, beginError := repository.databaseConnection.BeginTx(ctx, nil)
+	if beginError != nil {
+		return beginError
+	}
+	defer transaction.Rollback()
+
+	result, deleteError := transaction.ExecContext(ctx, "DELETE FROM patient_legal_holds WHERE patient_id = $1", patientID)
+	if deleteError != nil {
+		return deleteError
+	}
+	if deletedRows, _ := result.RowsAffected(); deletedRows == 0 {
+		return sql.ErrNoRows
+	}
+
+	_, auditError := transaction.ExecContext(ctx, insertAuditQuery,
+		patientID, models.LegalHoldActionReleased, "Patient", patientID, actor, reason)
+	if auditError != nil {
+		return auditError
+	}
+
+	return transaction.Commit()
+}
+
+// Get retrieves the active hold for a patient
+func (repository *PostgresLegalHoldRepository) Get(ctx context.Context, patientID string) (*models.LegalHold, error) {
+	selectQuery := `
+		SELECT patient_id, reason, placed_by, placed_at
+		FROM patient_legal_holds
+		WHERE patient_id = $1
+	`
+
+	hold := &models.LegalHold{}
+	scanError := repository.databaseConnection.QueryRowContext(ctx, selectQuery, patientID).Scan(
+		&hold.PatientID,
+		&hold.Reason,
+		&hold.PlacedBy,
+		&hold.PlacedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return hold, nil
+}
+
+// RecordEvent appends an audit entry
+func (repository *PostgresLegalHoldRepository) RecordEvent(ctx context.Context, event *models.LegalHoldEvent) error {
+	_, execError := repository.databaseConnection.ExecContext(ctx, insertAuditQuery,
+		event.PatientID, event.Action, event.ResourceType, event.ResourceID, event.Actor, event.Reason)
+	return execError
+}
+
+// ListEvents retrieves a patient's audit history in the order it happened
+func (repository *PostgresLegalHoldRepository) ListEvents(ctx context.Context, patientID string) ([]*models.LegalHoldEvent, error) {
+	selectQuery := `
+		SELECT id, patient_id, action, resource_type, resource_id, actor, reason, occurred_at
+		FROM legal_hold_audit
+		WHERE patient_id = $1
+		ORDER BY occurred_at, id
+	`
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, patientID)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	events := []*models.LegalHoldEvent{}
+	for rows.Next() {
+		event := &models.LegalHoldEvent{}
+		scanError := rows.Scan(
+			&event.ID,
+			&event.PatientID,
+			&event.Action,
+			&event.ResourceType,
+			&event.ResourceID,
+			&event.Actor,
+			&event.Reason,
+			&event.OccurredAt,
+		)
+		if scanError != nil {
+			return nil, scanError
+		}
+		events = append(events, event)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return events, nil
+}
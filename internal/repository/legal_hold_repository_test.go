@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupLegalHoldTestData removes holds and audit entries before patients can be cleaned up
+func cleanupLegalHoldTestData(t *testing.T, databaseConnection *sql.DB) {
+	for _, table := range []string{"patient_legal_holds", "legal_hold_audit"} {
+		if _, deleteError := databaseConnection.Exec("DELETE FROM " + table); deleteError != nil {
+			t.Fatalf("Failed to cleanup %s: %v", table, deleteError)
+		}
+	}
+	cleanupTestData(t, databaseConnection)
+}
+
+// TestPostgresLegalHoldRepository_Lifecycle verifies placing, reading, and releasing a hold with its history
+func TestPostgresLegalHoldRepository_Lifecycle(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupLegalHoldTestData(t, databaseConnection)
+	defer cleanupLegalHoldTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	patient, createError := NewPostgresPatientRepository(databaseConnection).Create(ctx, &models.Patient{FamilyName: "Held", GivenName: "Patient", Active: true})
+	if createError != nil {
+		t.Fatalf("Failed to create patient: %v", createError)
+	}
+
+	holdRepository := NewPostgresLegalHoldRepository(databaseConnection)
+	if _, getError := holdRepository.Get(ctx, patient.ID); !errors.Is(getError, sql.ErrNoRows) {
+		t.Fatalf("Expected sql.ErrNoRows before placing, got %v", getError)
+	}
+
+	if _, placeError := holdRepository.Place(ctx, &models.LegalHold{PatientID: patient.ID, Reason: "Case 42", PlacedBy: "api-key:legal"}); placeError != nil {
+		t.Fatalf("Expected no error placing hold, got %v", placeError)
+	}
+	hold, getError := holdRepository.Get(ctx, patient.ID)
+	if getError != nil || hold.Reason != "Case 42" || hold.PlacedAt.IsZero() {
+		t.Fatalf("Unexpected hold %+v (%v)", hold, getError)
+	}
+
+	// The foreign key refuses deleting a held patient even if the service check is bypassed
+	if _, deleteError := databaseConnection.ExecContext(ctx, "DELETE FROM patients WHERE id = $1", patient.ID); deleteError == nil {
+		t.Error("Expected database to refuse deleting a held patient")
+	}
+
+	holdRepository.RecordEvent(ctx, &models.LegalHoldEvent{
+		PatientID: patient.ID, Action: models.LegalHoldActionDeletionBlocked, ResourceType: "Patient", ResourceID: patient.ID, Actor: "anonymous",
+	})
+	if releaseError := holdRepository.Release(ctx, patient.ID, "api-key:legal", "Case closed"); releaseError != nil {
+		t.Fatalf("Expected no error releasing hold, got %v", releaseError)
+	}
+	if releaseError := holdRepository.Release(ctx, patient.ID, "api-key:legal", "Again"); !errors.Is(releaseError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows releasing twice, got %v", releaseError)
+	}
+
+	events, listError := holdRepository.ListEvents(ctx, patient.ID)
+	if listError != nil || len(events) != 3 {
+		t.Fatalf("Expected 3 audit entries, got %d (%v)", len(events), listError)
+	}
+	expectedActions := []models.LegalHoldAction{models.LegalHoldActionPlaced, models.LegalHoldActionDeletionBlocked, models.LegalHoldActionReleased}
+	for index, event := range events {
+		if event.Action != expectedActions[index] {
+			t.Errorf("Expected action %s at %d, got %s", expectedActions[index], index, event.Action)
+		}
+	}
+}
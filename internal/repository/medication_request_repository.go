@@ -0,0 +1,265 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// MedicationRequestRepository defines the interface for medication request data operations
+type MedicationRequestRepository interface {
+	// Create inserts a new medication request record and returns the created request with ID
+	Create(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error)
+
+	// GetByID retrieves a medication request by its unique identifier
+	GetByID(ctx context.Context, medicationRequestID string) (*models.MedicationRequest, error)
+
+	// Search retrieves medication requests matching the search criteria
+	Search(ctx context.Context, searchParams *models.MedicationRequestSearchParams) ([]*models.MedicationRequest, error)
+
+	// Count returns how many medication requests match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.MedicationRequestSearchParams) (int, error)
+
+	// Update modifies an existing medication request record
+	Update(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error)
+
+	// Delete marks a medication request deleted by ID
+	Delete(ctx context.Context, medicationRequestID string) error
+}
+
+// medicationRequestColumns are the columns read into a models.MedicationRequest by scanMedicationRequest, in order
+const medicationRequestColumns = `id, status, intent, medication_system, medication_code, medication_display, patient_id,
+		encounter_id, requester_practitioner_id, authored_on, dosage_instruction_text, created_at, updated_at`
+
+// PostgresMedicationRequestRepository implements MedicationRequestRepository using PostgreSQL
+type PostgresMedicationRequestRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresMedicationRequestRepository creates a new PostgreSQL medication request repository instance
+func NewPostgresMedicationRequestRepository(databaseConnection *sql.DB) *PostgresMedicationRequestRepository {
+	return &PostgresMedicationRequestRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// scanMedicationRequest reads one row selected with medicationRequestColumns
+// Optional columns are NULL when the request left them out, so they are read through nullable types
+func scanMedicationRequest(row interface{ Scan(...interface{}) error }) (*models.MedicationRequest, error) {
+	medicationRequest := &models.MedicationRequest{}
+	var medicationSystem, medicationCode, medicationDisplay, patientID, encounterID, requesterID, dosageText sql.NullString
+	var authoredOn sql.NullTime
+	scanError := row.Scan(
+		&medicationRequest.ID,
+		&medicationRequest.Status,
+		&medicationRequest.Intent,
+		&medicationSystem,
+		&medicationCode,
+		&medicationDisplay,
+		&patientID,
+		&encounterID,
+		&requesterID,
+		&authoredOn,
+		&dosageText,
+		&medicationRequest.CreatedAt,
+		&medicationRequest.UpdatedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	medicationRequest.MedicationSystem = medicationSystem.String
+	medicationRequest.MedicationCode = medicationCode.String
+	medicationRequest.MedicationDisplay = medicationDisplay.String
+	medicationRequest.PatientID = patientID.String
+	medicationRequest.EncounterID = encounterID.String
+	medicationRequest.RequesterPractitionerID = requesterID.String
+	medicationRequest.DosageInstructionText = dosageText.String
+	if authoredOn.Valid {
+		medicationRequest.AuthoredOn = &authoredOn.Time
+	}
+	return medicationRequest, nil
+}
+
+// Create inserts a new medication request record into the database
+func (repository *PostgresMedicationRequestRepository) Create(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error) {
+	insertQuery := `
+		INSERT INTO medication_requests (status, intent, medication_system, medication_code, medication_display, patient_id,
+			encounter_id, requester_practitioner_id, authored_on, dosage_instruction_text)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, '')::uuid, NULLIF($7, '')::uuid, NULLIF($8, '')::uuid, $9, $10)
+		RETURNING id, created_at, updated_at
+	`
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		insertQuery,
+		medicationRequest.Status,
+		medicationRequest.Intent,
+		medicationRequest.MedicationSystem,
+		medicationRequest.MedicationCode,
+		medicationRequest.MedicationDisplay,
+		medicationRequest.PatientID,
+		medicationRequest.EncounterID,
+		medicationRequest.RequesterPractitionerID,
+		medicationRequest.AuthoredOn,
+		medicationRequest.DosageInstructionText,
+	).Scan(&medicationRequest.ID, &medicationRequest.CreatedAt, &medicationRequest.UpdatedAt)
+
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return medicationRequest, nil
+}
+
+// GetByID retrieves a medication request by its unique identifier
+// Returns sql.ErrNoRows when the medication request does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresMedicationRequestRepository) GetByID(ctx context.Context, medicationRequestID string) (*models.MedicationRequest, error) {
+	executor := executorFor(ctx, repository.databaseConnection)
+	selectQuery := `SELECT ` + medicationRequestColumns + ` FROM medication_requests WHERE id = $1 AND ` + notDeletedCondition
+	medicationRequest, scanError := scanMedicationRequest(executor.QueryRowContext(ctx, selectQuery, medicationRequestID))
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executor, "medication_requests", medicationRequestID)
+	}
+	return medicationRequest, scanError
+}
+
+// Update modifies an existing medication request record in the database
+// Returns sql.ErrNoRows when the medication request does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresMedicationRequestRepository) Update(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error) {
+	updateQuery := `
+		UPDATE medication_requests
+		SET status = $1, intent = $2, medication_system = $3, medication_code = $4, medication_display = $5,
+			patient_id = NULLIF($6, '')::uuid, encounter_id = NULLIF($7, '')::uuid, requester_practitioner_id = NULLIF($8, '')::uuid,
+			authored_on = $9, dosage_instruction_text = $10, updated_at = $11
+		WHERE id = $12 AND deleted_at IS NULL
+		RETURNING created_at, updated_at
+	`
+
+	// Set the updated timestamp
+	medicationRequest.UpdatedAt = time.Now()
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		updateQuery,
+		medicationRequest.Status,
+		medicationRequest.Intent,
+		medicationRequest.MedicationSystem,
+		medicationRequest.MedicationCode,
+		medicationRequest.MedicationDisplay,
+		medicationRequest.PatientID,
+		medicationRequest.EncounterID,
+		medicationRequest.RequesterPractitionerID,
+		medicationRequest.AuthoredOn,
+		medicationRequest.DosageInstructionText,
+		medicationRequest.UpdatedAt,
+		medicationRequest.ID,
+	).Scan(&medicationRequest.CreatedAt, &medicationRequest.UpdatedAt)
+
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executorFor(ctx, repository.databaseConnection), "medication_requests", medicationRequest.ID)
+	}
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return medicationRequest, nil
+}
+
+// medicationRequestSearchConditions builds the AND clauses that filter medication requests on the search criteria,
+// shared by Search and Count so a page and its total always agree; the returned parameters are numbered from $1
+func medicationRequestSearchConditions(searchParams *models.MedicationRequestSearchParams) (string, []interface{}) {
+	conditions := ""
+	queryParameters := []interface{}{}
+	parameterIndex := 1
+
+	// Add subject patient filter
+	if searchParams.PatientID != "" {
+		conditions += ` AND patient_id = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.PatientID)
+		parameterIndex++
+	}
+
+	// Add status filter
+	if searchParams.Status != "" {
+		conditions += ` AND status = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.Status)
+		parameterIndex++
+	}
+
+	// Add intent filter
+	if searchParams.Intent != "" {
+		conditions += ` AND intent = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, searchParams.Intent)
+		parameterIndex++
+	}
+
+	// Add authored date range filters
+	if searchParams.AuthoredOnGreaterThan != nil {
+		conditions += ` AND authored_on >= $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.AuthoredOnGreaterThan)
+		parameterIndex++
+	}
+	if searchParams.AuthoredOnLessThan != nil {
+		conditions += ` AND authored_on <= $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.AuthoredOnLessThan)
+		parameterIndex++
+	}
+
+	return conditions, queryParameters
+}
+
+// Search retrieves medication requests matching the search criteria, most recently written first
+func (repository *PostgresMedicationRequestRepository) Search(ctx context.Context, searchParams *models.MedicationRequestSearchParams) ([]*models.MedicationRequest, error) {
+	conditions, queryParameters := medicationRequestSearchConditions(searchParams)
+	parameterIndex := len(queryParameters) + 1
+
+	searchQuery := `SELECT ` + medicationRequestColumns + ` FROM medication_requests WHERE ` + notDeletedCondition + conditions +
+		` ORDER BY authored_on DESC NULLS LAST, created_at DESC, id LIMIT $` + fmt.Sprint(parameterIndex) + ` OFFSET $` + fmt.Sprint(parameterIndex+1)
+	queryParameters = append(queryParameters, searchParams.Limit, searchParams.Offset)
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, searchQuery, queryParameters...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	medicationRequests := []*models.MedicationRequest{}
+	for rows.Next() {
+		medicationRequest, scanError := scanMedicationRequest(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		medicationRequests = append(medicationRequests, medicationRequest)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return medicationRequests, nil
+}
+
+// Count returns how many medication requests match the search criteria, ignoring the page's limit and offset
+func (repository *PostgresMedicationRequestRepository) Count(ctx context.Context, searchParams *models.MedicationRequestSearchParams) (int, error) {
+	conditions, queryParameters := medicationRequestSearchConditions(searchParams)
+
+	var total int
+	countError := repository.databaseConnection.QueryRowContext(ctx, `SELECT COUNT(*) FROM medication_requests WHERE `+notDeletedCondition+conditions, queryParameters...).Scan(&total)
+	if countError != nil {
+		return 0, countError
+	}
+	return total, nil
+}
+
+// Delete marks a medication request deleted by ID, leaving the row for a read of it to answer as deleted
+// Returns sql.ErrNoRows when the medication request does not exist, wrapped with ErrResourceDeleted when it was already deleted
+func (repository *PostgresMedicationRequestRepository) Delete(ctx context.Context, medicationRequestID string) error {
+	return softDeleteRow(ctx, executorFor(ctx, repository.databaseConnection), "medication_requests", medicationRequestID)
+}
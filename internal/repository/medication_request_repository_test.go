@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupMedicationRequests removes all test data from the medication_requests table
+func cleanupMedicationRequests(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM medication_requests"); deleteError != nil {
+		t.Fatalf("Failed to cleanup medication requests: %v", deleteError)
+	}
+}
+
+// TestPostgresMedicationRequestRepository_CRUD verifies a medication request is created, read, updated, and deleted
+func TestPostgresMedicationRequestRepository_CRUD(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupMedicationRequests(t, databaseConnection)
+	defer cleanupMedicationRequests(t, databaseConnection)
+
+	medicationRequestRepository := NewPostgresMedicationRequestRepository(databaseConnection)
+	ctx := context.Background()
+	authoredOn := time.Date(2024, 3, 1, 9, 45, 0, 0, time.UTC)
+	requesterID := uuid.NewString()
+
+	createdRequest, createError := medicationRequestRepository.Create(ctx, &models.MedicationRequest{
+		Status:                  "active",
+		Intent:                  "order",
+		MedicationSystem:        "http://www.nlm.nih.gov/research/umls/rxnorm",
+		MedicationCode:          "197361",
+		PatientID:               uuid.NewString(),
+		RequesterPractitionerID: requesterID,
+		AuthoredOn:              &authoredOn,
+		DosageInstructionText:   "1 tablet by mouth daily",
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create medication request: %v", createError)
+	}
+	if createdRequest.ID == "" || createdRequest.CreatedAt.IsZero() {
+		t.Fatalf("Expected a generated ID and timestamps, got %+v", createdRequest)
+	}
+
+	createdRequest.Status = "stopped"
+	if _, updateError := medicationRequestRepository.Update(ctx, createdRequest); updateError != nil {
+		t.Fatalf("Failed to update medication request: %v", updateError)
+	}
+
+	storedRequest, getError := medicationRequestRepository.GetByID(ctx, createdRequest.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read medication request: %v", getError)
+	}
+	if storedRequest.Status != "stopped" || storedRequest.RequesterPractitionerID != requesterID || storedRequest.EncounterID != "" {
+		t.Errorf("Expected the stopped request by %s without an encounter, got %+v", requesterID, storedRequest)
+	}
+	if storedRequest.AuthoredOn == nil || !storedRequest.AuthoredOn.Equal(authoredOn) || storedRequest.DosageInstructionText != "1 tablet by mouth daily" {
+		t.Errorf("Expected the authored date and dosage text, got %+v", storedRequest)
+	}
+
+	if deleteError := medicationRequestRepository.Delete(ctx, createdRequest.ID); deleteError != nil {
+		t.Fatalf("Failed to delete medication request: %v", deleteError)
+	}
+	if deleteError := medicationRequestRepository.Delete(ctx, createdRequest.ID); !errors.Is(deleteError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", deleteError)
+	}
+}
+
+// TestPostgresMedicationRequestRepository_Search verifies patient, status, intent, and authored date filters and the total
+func TestPostgresMedicationRequestRepository_Search(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupMedicationRequests(t, databaseConnection)
+	defer cleanupMedicationRequests(t, databaseConnection)
+
+	medicationRequestRepository := NewPostgresMedicationRequestRepository(databaseConnection)
+	ctx := context.Background()
+	patientID := uuid.NewString()
+	january := time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC)
+	march := time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+	for _, medicationRequest := range []*models.MedicationRequest{
+		{Status: "completed", Intent: "order", PatientID: patientID, AuthoredOn: &january},
+		{Status: "active", Intent: "order", PatientID: patientID, AuthoredOn: &march},
+		{Status: "active", Intent: "plan", PatientID: uuid.NewString(), AuthoredOn: &march},
+	} {
+		if _, createError := medicationRequestRepository.Create(ctx, medicationRequest); createError != nil {
+			t.Fatalf("Failed to create medication request: %v", createError)
+		}
+	}
+
+	februaryFirst := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	testCases := map[string]struct {
+		searchParams  models.MedicationRequestSearchParams
+		expectedCount int
+	}{
+		"patient": {searchParams: models.MedicationRequestSearchParams{PatientID: patientID}, expectedCount: 2},
+		"status":  {searchParams: models.MedicationRequestSearchParams{Status: "active"}, expectedCount: 2},
+		"intent":  {searchParams: models.MedicationRequestSearchParams{Status: "active", Intent: "order"}, expectedCount: 1},
+		"after":   {searchParams: models.MedicationRequestSearchParams{AuthoredOnGreaterThan: &februaryFirst}, expectedCount: 2},
+		"before":  {searchParams: models.MedicationRequestSearchParams{AuthoredOnLessThan: &februaryFirst}, expectedCount: 1},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		medicationRequests, searchError := medicationRequestRepository.Search(ctx, &testCase.searchParams)
+		if searchError != nil {
+			t.Fatalf("%s: search failed: %v", name, searchError)
+		}
+		total, countError := medicationRequestRepository.Count(ctx, &testCase.searchParams)
+		if countError != nil {
+			t.Fatalf("%s: count failed: %v", name, countError)
+		}
+		if len(medicationRequests) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d medication requests, got %d with total %d", name, testCase.expectedCount, len(medicationRequests), total)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// archiveCollectionName holds observations moved out of the primary collection once they are old enough
+const archiveCollectionName = "observations_archive"
+
+// archiveBatchSize bounds how many observations one archival step moves at a time
+const archiveBatchSize = 1000
+
+// namespaceExistsCode is the MongoDB error code for creating a collection that already exists
+const namespaceExistsCode = 48
+
+// SetArchiveAfter turns on archival tiering: observations effective more than archiveAfter ago live in the
+// archive collection, and reads include it whenever the archive may hold matching observations
+// Zero turns tiering off and leaves the archive out of every read
+func (repository *MongoObservationRepository) SetArchiveAfter(archiveAfter time.Duration) {
+	repository.archiveAfter = archiveAfter
+}
+
+// EnsureArchiveCollection creates the archive collection with zstd block compression, and the indexes its
+// reads use, when it does not exist yet
+// Compression is fixed when a collection is created, so an existing archive is left as it is
+func (repository *MongoObservationRepository) EnsureArchiveCollection(ctx context.Context) error {
+	storageEngine := bson.M{"wiredTiger": bson.M{"configString": "block_compressor=zstd"}}
+	createError := repository.archive.Database().CreateCollection(ctx, archiveCollectionName, options.CreateCollection().SetStorageEngine(storageEngine))
+	var commandError mongo.CommandError
+	if createError != nil && !(errors.As(createError, &commandError) && commandError.Code == namespaceExistsCode) {
+		return fmt.Errorf("failed to create observation archive: %w", createError)
+	}
+
+	_, indexError := repository.archive.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "patient_id", Value: 1}}},
+		{Keys: bson.D{{Key: "effective_date", Value: 1}}},
+	})
+	if indexError != nil {
+		return fmt.Errorf("failed to index observation archive: %w", indexError)
+	}
+	return nil
+}
+
+// ArchiveBefore moves every observation effective before cutoff into the archive and returns how many it moved
+// Observations move in batches, each copied before it is deleted, so an interrupted run can be repeated
+func (repository *MongoObservationRepository) ArchiveBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	return moveObservationBatches(ctx, repository.collection, repository.archive, bson.M{"effective_date": bson.M{"$lt": cutoff}}, func(document bson.M) {
+		document["archived_at"] = time.Now()
+	})
+}
+
+// RestoreSince moves archived observations that are no longer older than cutoff back into the primary
+// collection and returns how many it moved
+// This picks up observations whose effective date was edited, and brings observations back after the
+// archival age is lengthened
+func (repository *MongoObservationRepository) RestoreSince(ctx context.Context, cutoff time.Time) (int64, error) {
+	return moveObservationBatches(ctx, repository.archive, repository.collection, bson.M{"effective_date": bson.M{"$not": bson.M{"$lt": cutoff}}}, func(document bson.M) {
+		delete(document, "archived_at")
+	})
+}
+
+// moveObservationBatches moves the documents matching filter from source to target archiveBatchSize at a time
+func moveObservationBatches(ctx context.Context, source *mongo.Collection, target *mongo.Collection, filter bson.M, prepare func(document bson.M)) (int64, error) {
+	var moved int64
+	for {
+		movedObservations, moveError := moveObservations(ctx, source, target, filter, prepare, options.Find().SetLimit(archiveBatchSize))
+		moved += int64(len(movedObservations))
+		if moveError != nil {
+			return moved, moveError
+		}
+		if len(movedObservations) < archiveBatchSize {
+			return moved, nil
+		}
+	}
+}
+
+// tiered reports whether archival tiering is on
+func (repository *MongoObservationRepository) tiered() bool {
+	return repository.archiveAfter > 0
+}
+
+// archiveBoundary returns the effective date before which observations may be archived
+// The archival job only ever uses older cutoffs, so nothing effective at or after it is in the archive
+func (repository *MongoObservationRepository) archiveBoundary() time.Time {
+	return time.Now().Add(-repository.archiveAfter)
+}
+
+// archiveMayHold reports whether the archive can hold observations effective at or after from
+// A nil from means the read has no lower bound on the effective date
+func (repository *MongoObservationRepository) archiveMayHold(from *time.Time) bool {
+	if !repository.tiered() {
+		return false
+	}
+	if from == nil {
+		return true
+	}
+	return from.Before(repository.archiveBoundary())
+}
+
+// findTiered runs a sorted, paged find over the primary collection, adding the archive when it may hold matches
+// from is the earliest effective date filter allows, or nil when it has no lower bound
+func (repository *MongoObservationRepository) findTiered(ctx context.Context, filter bson.M, sort bson.D, limit int, offset int, from *time.Time) (*mongo.Cursor, error) {
+	if !repository.archiveMayHold(from) {
+		findOptions := options.Find()
+		findOptions.SetLimit(int64(limit))
+		findOptions.SetSkip(int64(offset))
+		findOptions.SetSort(sort)
+		return repository.collection.Find(ctx, filter, findOptions)
+	}
+
+	// Each tier contributes at most the first offset+limit matches, so neither is read in full for one page
+	tierStages := bson.A{bson.M{"$match": filter}, bson.M{"$sort": sort}}
+	if limit > 0 {
+		tierStages = append(tierStages, bson.M{"$limit": offset + limit})
+	}
+	pipeline := bson.A{}
+	pipeline = append(pipeline, tierStages...)
+	pipeline = append(pipeline, bson.M{"$unionWith": bson.M{"coll": archiveCollectionName, "pipeline": tierStages}}, bson.M{"$sort": sort})
+	if offset > 0 {
+		pipeline = append(pipeline, bson.M{"$skip": offset})
+	}
+	if limit > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	}
+	return repository.collection.Aggregate(ctx, pipeline)
+}
+
+// withArchive adds the archive to an aggregation after its leading stages when the archive may hold matches
+// The archived documents pass through the same leading stages before joining the pipeline
+func (repository *MongoObservationRepository) withArchive(pipeline mongo.Pipeline, leadingStages int, from *time.Time) mongo.Pipeline {
+	if !repository.archiveMayHold(from) {
+		return pipeline
+	}
+
+	archiveStages := bson.A{}
+	for _, stage := range pipeline[:leadingStages] {
+		archiveStages = append(archiveStages, stage)
+	}
+	tieredPipeline := mongo.Pipeline{}
+	tieredPipeline = append(tieredPipeline, pipeline[:leadingStages]...)
+	tieredPipeline = append(tieredPipeline, bson.D{{Key: "$unionWith", Value: bson.M{"coll": archiveCollectionName, "pipeline": archiveStages}}})
+	return append(tieredPipeline, pipeline[leadingStages:]...)
+}
+
+// updateArchived applies update to an archived observation, moving it back to the primary collection when its
+// new effective date is too recent for the archive
+func (repository *MongoObservationRepository) updateArchived(ctx context.Context, filter bson.M, update bson.M) (*models.Observation, error) {
+	var updatedObservation models.Observation
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	if updateError := repository.archive.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&updatedObservation); updateError != nil {
+		return nil, updateError
+	}
+
+	if updatedObservation.EffectiveDate != nil && updatedObservation.EffectiveDate.Before(repository.archiveBoundary()) {
+		return &updatedObservation, nil
+	}
+	_, moveError := moveObservations(ctx, repository.archive, repository.collection, filter, func(document bson.M) {
+		delete(document, "archived_at")
+	})
+	if moveError != nil {
+		return nil, moveError
+	}
+	return &updatedObservation, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestMongoObservationRepository_ArchiveTiers verifies old observations move to the archive and stay readable
+func TestMongoObservationRepository_ArchiveTiers(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+	defer cleanupMongoTestData(t, repository.archive)
+
+	ctx := context.Background()
+	repository.SetArchiveAfter(30 * 24 * time.Hour)
+	if ensureError := repository.EnsureArchiveCollection(ctx); ensureError != nil {
+		t.Fatalf("Expected the archive to be created, got %v", ensureError)
+	}
+
+	oldDate := time.Now().AddDate(-1, 0, 0).UTC().Truncate(time.Second)
+	recentDate := time.Now().AddDate(0, 0, -1).UTC().Truncate(time.Second)
+	oldObservation, _ := repository.Create(ctx, &models.Observation{PatientID: "archive-patient", Status: "final", Code: "8867-4", EffectiveDate: &oldDate})
+	repository.Create(ctx, &models.Observation{PatientID: "archive-patient", Status: "final", Code: "8867-4", EffectiveDate: &recentDate})
+
+	archived, archiveError := repository.ArchiveBefore(ctx, time.Now().AddDate(0, 0, -30))
+	if archiveError != nil || archived != 1 {
+		t.Fatalf("Expected 1 observation archived, got %d (%v)", archived, archiveError)
+	}
+
+	if _, findError := repository.GetByID(ctx, oldObservation.ID); findError != nil {
+		t.Errorf("Expected the archived observation to be found by ID, got %v", findError)
+	}
+
+	allObservations, searchError := repository.Search(ctx, &models.ObservationSearchParams{PatientID: "archive-patient", Limit: 10})
+	if searchError != nil || len(allObservations) != 2 {
+		t.Errorf("Expected both tiers searched, got %d (%v)", len(allObservations), searchError)
+	}
+
+	recentFrom := time.Now().AddDate(0, 0, -7)
+	recentObservations, _ := repository.Search(ctx, &models.ObservationSearchParams{PatientID: "archive-patient", DateGreaterThan: &recentFrom, Limit: 10})
+	if len(recentObservations) != 1 {
+		t.Errorf("Expected only the recent observation, got %d", len(recentObservations))
+	}
+
+	counts, _ := repository.CountByPatient(ctx)
+	if counts["archive-patient"] != 2 {
+		t.Errorf("Expected archived observations counted, got %d", counts["archive-patient"])
+	}
+
+	restored, restoreError := repository.RestoreSince(ctx, oldDate.Add(-time.Hour))
+	if restoreError != nil || restored != 1 {
+		t.Errorf("Expected the archived observation restored, got %d (%v)", restored, restoreError)
+	}
+}
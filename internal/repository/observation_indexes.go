@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// observationIndexes back the supported observation searches and sorts
+// Equality fields come first, then the sorted or ranged field, so one index serves both the filter and the order
+var observationIndexes = []mongo.IndexModel{
+	// Patient compartment reads and patient searches in the default newest-first order; _id is the page
+	// cursor's tiebreaker
+	{Keys: bson.D{{Key: "patient_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}, Options: options.Index().SetName("patient_id_created_at_id")},
+	// Patient timelines and date ranges
+	{Keys: bson.D{{Key: "patient_id", Value: 1}, {Key: "effective_date", Value: -1}}, Options: options.Index().SetName("patient_id_effective_date")},
+	// Patient and code searches, $lastn, and daily rollups
+	{Keys: bson.D{{Key: "patient_id", Value: 1}, {Key: "code", Value: 1}, {Key: "effective_date", Value: -1}}, Options: options.Index().SetName("patient_id_code_effective_date")},
+	// Code, category, and status searches across patients
+	{Keys: bson.D{{Key: "code", Value: 1}, {Key: "effective_date", Value: -1}}, Options: options.Index().SetName("code_effective_date")},
+	{Keys: bson.D{{Key: "category", Value: 1}, {Key: "effective_date", Value: -1}}, Options: options.Index().SetName("category_effective_date")},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "effective_date", Value: -1}}, Options: options.Index().SetName("status_effective_date")},
+	// Date-only searches, sorting by date, and the archival job's age cutoff
+	{Keys: bson.D{{Key: "effective_date", Value: -1}}, Options: options.Index().SetName("effective_date")},
+	// Unfiltered listings in the default order, and their page cursors
+	{Keys: bson.D{{Key: "created_at", Value: -1}, {Key: "_id", Value: -1}}, Options: options.Index().SetName("created_at_id")},
+}
+
+// EnsureIndexes creates the observation search indexes missing from the primary collection and returns the
+// names of those it created
+// An index is already present when one with the same keys exists under any name, so indexes created by
+// hand are not duplicated or renamed. MongoDB 4.2 and later build indexes on a populated collection while
+// reads and writes continue, locking it exclusively only at the start and end of the build
+func (repository *MongoObservationRepository) EnsureIndexes(ctx context.Context) ([]string, error) {
+	existingKeys, listError := indexKeySignatures(ctx, repository.collection)
+	if listError != nil {
+		return nil, fmt.Errorf("failed to list observation indexes: %w", listError)
+	}
+
+	var missingIndexes []mongo.IndexModel
+	for _, index := range observationIndexes {
+		if _, exists := existingKeys[keySignature(index.Keys.(bson.D))]; !exists {
+			missingIndexes = append(missingIndexes, index)
+		}
+	}
+	if len(missingIndexes) == 0 {
+		return nil, nil
+	}
+
+	createdNames, createError := repository.collection.Indexes().CreateMany(ctx, missingIndexes)
+	if createError != nil {
+		return nil, fmt.Errorf("failed to create observation indexes: %w", createError)
+	}
+	return createdNames, nil
+}
+
+// indexKeySignatures returns the key signature of every index on the collection
+// A collection that does not exist yet has no indexes
+func indexKeySignatures(ctx context.Context, collection *mongo.Collection) (map[string]struct{}, error) {
+	signatures := map[string]struct{}{}
+	indexCursor, listError := collection.Indexes().List(ctx)
+	var commandError mongo.CommandError
+	if errors.As(listError, &commandError) && commandError.Code == namespaceNotFoundCode {
+		return signatures, nil
+	}
+	if listError != nil {
+		return nil, listError
+	}
+	defer indexCursor.Close(ctx)
+
+	for indexCursor.Next(ctx) {
+		var index struct {
+			Keys bson.D `bson:"key"`
+		}
+		if decodeError := indexCursor.Decode(&index); decodeError != nil {
+			return nil, decodeError
+		}
+		signatures[keySignature(index.Keys)] = struct{}{}
+	}
+	return signatures, indexCursor.Err()
+}
+
+// keySignature renders index keys as "field:direction" pairs in order
+// Directions are printed rather than compared, since the server returns them as int32 or double
+func keySignature(keys bson.D) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s:%v", key.Key, key.Value))
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TestMongoObservationRepository_EnsureIndexes verifies missing indexes are created once and indexes made by hand are kept
+func TestMongoObservationRepository_EnsureIndexes(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	ctx := context.Background()
+	repository.collection.Indexes().DropAll(ctx)
+	_, createError := repository.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "patient_id", Value: 1}, {Key: "created_at", Value: -1}, {Key: "_id", Value: -1}},
+		Options: options.Index().SetName("hand_made_patient_index"),
+	})
+	if createError != nil {
+		t.Fatalf("Expected the hand-made index to be created, got %v", createError)
+	}
+
+	createdNames, ensureError := repository.EnsureIndexes(ctx)
+	if ensureError != nil {
+		t.Fatalf("Expected the indexes to be created, got %v", ensureError)
+	}
+	if len(createdNames) != len(observationIndexes)-1 || slices.Contains(createdNames, "patient_id_created_at_id") {
+		t.Errorf("Expected every index but the hand-made one created, got %v", createdNames)
+	}
+
+	createdAgain, ensureAgainError := repository.EnsureIndexes(ctx)
+	if ensureAgainError != nil || len(createdAgain) != 0 {
+		t.Errorf("Expected nothing created on the second run, got %v (%v)", createdAgain, ensureAgainError)
+	}
+}
+
+// TestKeySignature verifies directions returned by the server as doubles match those declared as integers
+func TestKeySignature(t *testing.T) {
+	declared := keySignature(bson.D{{Key: "patient_id", Value: 1}, {Key: "effective_date", Value: -1}})
+	returned := keySignature(bson.D{{Key: "patient_id", Value: int32(1)}, {Key: "effective_date", Value: float64(-1)}})
+	if declared != returned {
+		t.Errorf("Expected %q to match %q", returned, declared)
+	}
+}
@@ -20,13 +20,57 @@ type ObservationRepository interface {
 	GetByPatientID(ctx context.Context, patientID string, limit int, offset int) ([]*models.Observation, error)
 	GetAll(ctx context.Context, limit int, offset int) ([]*models.Observation, error)
 	Search(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*models.Observation, error)
+	// Count returns how many observations match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error)
+	// Stream calls visit with each observation Search would return without holding them all in memory
+	Stream(ctx context.Context, searchParams *models.ObservationSearchParams, visit func(observation *models.Observation) error) error
+	// Update replaces the observation's content and increments its version; when observation.Version is set
+	// the update only applies to that version and fails with ErrObservationVersionConflict otherwise
 	Update(ctx context.Context, observation *models.Observation) (*models.Observation, error)
 	Delete(ctx context.Context, observationID string) error
+	UpdateTags(ctx context.Context, observationID string, change func(models.Tags) models.Tags) (models.Tags, error)
+	// LastN returns the patient's most recent observations of each code, at most Max per code, grouped by code
+	// and newest first within a code
+	LastN(ctx context.Context, lastNParams *models.ObservationLastNParams) ([]*models.Observation, error)
 }
 
+// ObservationErasureStore withdraws a patient's observations while an erasure waits out its waiting period
+// Each method can be repeated after an interruption without losing or duplicating observations
+type ObservationErasureStore interface {
+	// HoldForErasure moves the patient's observations aside under the erasure and returns the ones it moved
+	HoldForErasure(ctx context.Context, erasureID int64, patientID string) ([]*models.Observation, error)
+
+	// RestoreErasure moves the erasure's observations back and returns the ones it moved
+	RestoreErasure(ctx context.Context, erasureID int64) ([]*models.Observation, error)
+
+	// PurgeErasure permanently deletes the erasure's observations and returns how many there were
+	PurgeErasure(ctx context.Context, erasureID int64) (int64, error)
+}
+
+// ObservationQuarantineStore finds observations of patients that do not exist and moves them out of circulation
+type ObservationQuarantineStore interface {
+	// CountByPatient returns how many observations each patient has
+	CountByPatient(ctx context.Context) (map[string]int64, error)
+
+	// QuarantineByPatient moves the patient's observations into quarantine and returns the IDs of the ones it moved
+	QuarantineByPatient(ctx context.Context, patientID string) ([]string, error)
+}
+
+// ErrObservationNotFound is returned when no observation has the requested ID
+var ErrObservationNotFound = errors.New("observation not found")
+
+// ErrObservationVersionConflict is returned when a versioned update finds the observation at another version
+var ErrObservationVersionConflict = errors.New("observation version conflict")
+
 // MongoObservationRepository implements ObservationRepository using MongoDB
 type MongoObservationRepository struct {
 	collection *mongo.Collection
+
+	// archive holds observations moved out of collection by the archival job
+	archive *mongo.Collection
+
+	// archiveAfter is the age past which observations are archived; zero leaves the archive out of every read
+	archiveAfter time.Duration
 }
 
 // NewMongoObservationRepository creates a new MongoDB observation repository
@@ -34,17 +78,32 @@ func NewMongoObservationRepository(database *mongo.Database) *MongoObservationRe
 	collection := database.Collection("observations")
 	return &MongoObservationRepository{
 		collection: collection,
+		archive:    database.Collection(archiveCollectionName),
 	}
 }
 
+// NewObservationID returns a new observation ID, for writes that must know the ID before the observation is stored
+func NewObservationID() string {
+	return primitive.NewObjectID().Hex()
+}
+
 // Create inserts a new observation into MongoDB
+// The observation is stored under its ID when one from NewObservationID is set, and under a generated one otherwise
 func (repository *MongoObservationRepository) Create(ctx context.Context, observation *models.Observation) (*models.Observation, error) {
-	// Set timestamps
+	// Set timestamps, and the first version unless a stored observation is being put back
 	observation.CreatedAt = time.Now()
 	observation.UpdatedAt = time.Now()
+	if observation.Version == 0 {
+		observation.Version = 1
+	}
+
+	document, documentError := observationDocument(observation)
+	if documentError != nil {
+		return nil, documentError
+	}
 
 	// Insert document
-	result, insertError := repository.collection.InsertOne(ctx, observation)
+	result, insertError := repository.collection.InsertOne(ctx, document)
 	if insertError != nil {
 		return nil, fmt.Errorf("failed to insert observation: %w", insertError)
 	}
@@ -57,21 +116,49 @@ func (repository *MongoObservationRepository) Create(ctx context.Context, observ
 	return observation, nil
 }
 
+// observationDocument returns the document to insert for an observation, keyed by its ObjectID when the ID is set
+// The model's string ID would otherwise be stored as a string _id that no ObjectID lookup finds
+func observationDocument(observation *models.Observation) (interface{}, error) {
+	if observation.ID == "" {
+		return observation, nil
+	}
+
+	objectID, convertError := primitive.ObjectIDFromHex(observation.ID)
+	if convertError != nil {
+		return nil, fmt.Errorf("invalid observation ID: %w", convertError)
+	}
+	encodedObservation, encodeError := bson.Marshal(observation)
+	if encodeError != nil {
+		return nil, fmt.Errorf("failed to encode observation: %w", encodeError)
+	}
+	var document bson.M
+	if decodeError := bson.Unmarshal(encodedObservation, &document); decodeError != nil {
+		return nil, fmt.Errorf("failed to encode observation: %w", decodeError)
+	}
+	document["_id"] = objectID
+	return document, nil
+}
+
 // GetByID retrieves an observation by ID
+// Returns ErrObservationNotFound when the ID is malformed or no observation has it, wrapped with ErrResourceDeleted
+// when it was deleted
 func (repository *MongoObservationRepository) GetByID(ctx context.Context, observationID string) (*models.Observation, error) {
 	// Convert string ID to ObjectID
 	objectID, convertError := primitive.ObjectIDFromHex(observationID)
 	if convertError != nil {
-		return nil, fmt.Errorf("invalid observation ID: %w", convertError)
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrObservationNotFound, observationID)
 	}
 
-	// Find document
+	// Find document, falling back to the archive
 	var observation models.Observation
-	filter := bson.M{"_id": objectID}
+	filter := notDeleted(bson.M{"_id": objectID})
 	findError := repository.collection.FindOne(ctx, filter).Decode(&observation)
+	if errors.Is(findError, mongo.ErrNoDocuments) && repository.tiered() {
+		findError = repository.archive.FindOne(ctx, filter).Decode(&observation)
+	}
 	if findError != nil {
 		if errors.Is(findError, mongo.ErrNoDocuments) {
-			return nil, fmt.Errorf("observation not found")
+			return nil, repository.missingObservationError(ctx, objectID)
 		}
 		return nil, fmt.Errorf("failed to find observation: %w", findError)
 	}
@@ -79,19 +166,22 @@ func (repository *MongoObservationRepository) GetByID(ctx context.Context, obser
 	return &observation, nil
 }
 
+// missingObservationError explains why no live observation has objectID, in whichever tier holds it
+func (repository *MongoObservationRepository) missingObservationError(ctx context.Context, objectID primitive.ObjectID) error {
+	missingError := missingDocumentError(ctx, repository.collection, objectID, ErrObservationNotFound)
+	if !errors.Is(missingError, ErrResourceDeleted) && errors.Is(missingError, ErrObservationNotFound) && repository.tiered() {
+		return missingDocumentError(ctx, repository.archive, objectID, ErrObservationNotFound)
+	}
+	return missingError
+}
+
 // GetByPatientID retrieves all observations for a specific patient
 func (repository *MongoObservationRepository) GetByPatientID(ctx context.Context, patientID string, limit int, offset int) ([]*models.Observation, error) {
 	// Build filter
-	filter := bson.M{"patient_id": patientID}
+	filter := notDeleted(bson.M{"patient_id": patientID})
 
-	// Set options
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
-	findOptions.SetSort(bson.M{"created_at": -1}) // Sort by newest first
-
-	// Execute query
-	cursor, findError := repository.collection.Find(ctx, filter, findOptions)
+	// Execute query, newest first, across both tiers
+	cursor, findError := repository.findTiered(ctx, filter, bson.D{{Key: "created_at", Value: -1}}, limit, offset, nil)
 	if findError != nil {
 		return nil, fmt.Errorf("failed to find observations: %w", findError)
 	}
@@ -108,14 +198,8 @@ func (repository *MongoObservationRepository) GetByPatientID(ctx context.Context
 
 // GetAll retrieves all observations with pagination
 func (repository *MongoObservationRepository) GetAll(ctx context.Context, limit int, offset int) ([]*models.Observation, error) {
-	// Set options
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(limit))
-	findOptions.SetSkip(int64(offset))
-	findOptions.SetSort(bson.M{"created_at": -1})
-
-	// Execute query
-	cursor, findError := repository.collection.Find(ctx, bson.M{}, findOptions)
+	// Execute query, newest first, across both tiers
+	cursor, findError := repository.findTiered(ctx, notDeleted(bson.M{}), bson.D{{Key: "created_at", Value: -1}}, limit, offset, nil)
 	if findError != nil {
 		return nil, fmt.Errorf("failed to find observations: %w", findError)
 	}
@@ -130,9 +214,9 @@ func (repository *MongoObservationRepository) GetAll(ctx context.Context, limit
 	return observations, nil
 }
 
-// Search retrieves observations matching the search criteria with dynamic filtering
-func (repository *MongoObservationRepository) Search(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*models.Observation, error) {
-	// Build dynamic filter based on search parameters
+// observationSearchFilter builds the filter matching observations on the search criteria, shared by
+// Search and Count so a page and its total always agree
+func observationSearchFilter(searchParams *models.ObservationSearchParams) bson.M {
 	filter := bson.M{}
 
 	// Add patient ID filter
@@ -140,20 +224,17 @@ func (repository *MongoObservationRepository) Search(ctx context.Context, search
 		filter["patient_id"] = searchParams.PatientID
 	}
 
-	// Add code filter
-	if searchParams.Code != "" {
-		filter["code"] = searchParams.Code
+	// Add encounter ID filter
+	if searchParams.EncounterID != "" {
+		filter["encounter_id"] = searchParams.EncounterID
 	}
 
-	// Add category filter
-	if searchParams.Category != "" {
-		filter["category"] = searchParams.Category
-	}
-
-	// Add status filter
-	if searchParams.Status != "" {
-		filter["status"] = searchParams.Status
-	}
+	// Add code, category, and status filters; every group must match and a group matches when the field
+	// has any of its values
+	andConditions := bson.A{}
+	andConditions = append(andConditions, valueConditions("code", searchParams.Code)...)
+	andConditions = append(andConditions, valueConditions("category", searchParams.Category)...)
+	andConditions = append(andConditions, valueConditions("status", searchParams.Status)...)
 
 	// Add date range filters
 	if searchParams.DateGreaterThan != nil {
@@ -170,10 +251,117 @@ func (repository *MongoObservationRepository) Search(ctx context.Context, search
 		filter["effective_date"].(bson.M)["$lte"] = searchParams.DateLessThan
 	}
 
-	// Build options for sorting and pagination
-	findOptions := options.Find()
-	findOptions.SetLimit(int64(searchParams.Limit))
-	findOptions.SetSkip(int64(searchParams.Offset))
+	// Add value-quantity filters; every group must match and a group matches when any of its quantities does
+	for _, quantityGroup := range searchParams.ValueQuantity {
+		alternatives := bson.A{}
+		for _, criterion := range quantityGroup {
+			alternatives = append(alternatives, quantityFilter(criterion))
+		}
+		andConditions = append(andConditions, bson.M{"$or": alternatives})
+	}
+
+	// Add tag filters; every group must match and a group matches when any of its tags is present
+	for _, tagGroup := range searchParams.Tags {
+		alternatives := bson.A{}
+		for _, criterion := range tagGroup {
+			alternatives = append(alternatives, bson.M{"tags": bson.M{"$elemMatch": tagFilter(criterion)}})
+		}
+		andConditions = append(andConditions, bson.M{"$or": alternatives})
+	}
+	if len(andConditions) > 0 {
+		filter["$and"] = andConditions
+	}
+
+	return notDeleted(filter)
+}
+
+// observationsAfter matches the observations that follow the cursor in creation order
+// sortOrder is -1 for newest first and 1 for oldest first
+func observationsAfter(after *models.PageCursor, sortOrder int) (bson.M, error) {
+	afterID, convertError := primitive.ObjectIDFromHex(after.ID)
+	if convertError != nil {
+		return nil, fmt.Errorf("invalid page cursor ID %q: %w", after.ID, convertError)
+	}
+	comparison := "$lt"
+	if sortOrder == 1 {
+		comparison = "$gt"
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"created_at": bson.M{comparison: after.CreatedAt}},
+		bson.M{"created_at": after.CreatedAt, "_id": bson.M{comparison: afterID}},
+	}}, nil
+}
+
+// quantityFilter matches observations whose value compares to the criterion, in its unit when one is given
+// A criterion with a canonical unit also matches observations stored with a canonical value in that unit, so a
+// search in mmol/L finds values sent in mg/dL. Observations without a quantity value never match, not even ne
+func quantityFilter(criterion models.QuantityCriterion) bson.M {
+	valueFilter := valueComparison("value_quantity", criterion.Comparator, criterion.Value, criterion.Low, criterion.High)
+	if criterion.Unit == "" {
+		return valueFilter
+	}
+
+	unitFilter := bson.M{"$and": bson.A{valueFilter, bson.M{"value_unit": criterion.Unit}}}
+	if criterion.CanonicalUnit == "" {
+		return unitFilter
+	}
+	canonicalFilter := valueComparison("canonical_value", criterion.Comparator, criterion.CanonicalValue, criterion.CanonicalLow, criterion.CanonicalHigh)
+	return bson.M{"$or": bson.A{
+		unitFilter,
+		bson.M{"$and": bson.A{canonicalFilter, bson.M{"canonical_unit": criterion.CanonicalUnit}}},
+	}}
+}
+
+// valueComparison compares a numeric field by a value-quantity comparator: gt, lt, ge, and le against value,
+// and eq and ne against the range low up to but not including high
+func valueComparison(field string, comparator string, value float64, low float64, high float64) bson.M {
+	switch comparator {
+	case "gt":
+		return bson.M{field: bson.M{"$gt": value}}
+	case "lt":
+		return bson.M{field: bson.M{"$lt": value}}
+	case "ge":
+		return bson.M{field: bson.M{"$gte": value}}
+	case "le":
+		return bson.M{field: bson.M{"$lte": value}}
+	case "ne":
+		return bson.M{"$or": bson.A{
+			bson.M{field: bson.M{"$lt": low}},
+			bson.M{field: bson.M{"$gte": high}},
+		}}
+	default:
+		return bson.M{field: bson.M{"$gte": low, "$lt": high}}
+	}
+}
+
+// valueConditions returns an $in condition on the field for each group of values
+func valueConditions(field string, criteria models.ValueCriteria) bson.A {
+	conditions := bson.A{}
+	for _, valueGroup := range criteria {
+		conditions = append(conditions, bson.M{field: bson.M{"$in": valueGroup}})
+	}
+	return conditions
+}
+
+// Search retrieves observations matching the search criteria with dynamic filtering
+func (repository *MongoObservationRepository) Search(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*models.Observation, error) {
+	observations := make([]*models.Observation, 0)
+	streamError := repository.Stream(ctx, searchParams, func(observation *models.Observation) error {
+		observations = append(observations, observation)
+		return nil
+	})
+	if streamError != nil {
+		return nil, streamError
+	}
+	return observations, nil
+}
+
+// Stream calls visit with each observation Search would return, in order, decoding each document only when
+// the previous observation has been visited, so the driver holds one batch at a time
+// An error from visit stops the stream and is returned
+func (repository *MongoObservationRepository) Stream(ctx context.Context, searchParams *models.ObservationSearchParams, visit func(observation *models.Observation) error) error {
+	// Filter on the search criteria
+	filter := observationSearchFilter(searchParams)
 
 	// Add sorting
 	sortBy := "created_at"
@@ -196,69 +384,163 @@ func (repository *MongoObservationRepository) Search(ctx context.Context, search
 		sortOrder = 1
 	}
 
-	findOptions.SetSort(bson.M{sortBy: sortOrder})
+	// Continue after the previous page's last observation instead of skipping the rows before it
+	offset := searchParams.Offset
+	if searchParams.After != nil && sortBy == "created_at" {
+		afterCondition, cursorError := observationsAfter(searchParams.After, sortOrder)
+		if cursorError != nil {
+			return cursorError
+		}
+		filter = bson.M{"$and": bson.A{filter, afterCondition}}
+		offset = 0
+	}
 
-	// Execute query
-	cursor, findError := repository.collection.Find(ctx, filter, findOptions)
+	// Execute query; the archive is only read when the date range reaches back into it
+	// _id breaks ties, so observations created in the same millisecond keep one order across pages
+	sort := bson.D{{Key: sortBy, Value: sortOrder}, {Key: "_id", Value: sortOrder}}
+	cursor, findError := repository.findTiered(ctx, filter, sort, searchParams.Limit, offset, searchParams.DateGreaterThan)
 	if findError != nil {
-		return nil, fmt.Errorf("failed to search observations: %w", findError)
+		return fmt.Errorf("failed to search observations: %w", findError)
 	}
 	defer cursor.Close(ctx)
 
-	// Decode results
-	observations := make([]*models.Observation, 0)
-	if decodeError := cursor.All(ctx, &observations); decodeError != nil {
-		return nil, fmt.Errorf("failed to decode observations: %w", decodeError)
+	// Decode and visit each observation in turn
+	for cursor.Next(ctx) {
+		observation := &models.Observation{}
+		if decodeError := cursor.Decode(observation); decodeError != nil {
+			return fmt.Errorf("failed to decode observations: %w", decodeError)
+		}
+		if visitError := visit(observation); visitError != nil {
+			return visitError
+		}
+	}
+	if cursorError := cursor.Err(); cursorError != nil {
+		return fmt.Errorf("failed to search observations: %w", cursorError)
 	}
+	return nil
+}
 
-	return observations, nil
+// Count returns how many observations match the search criteria, ignoring the page's limit and offset
+// The archive is counted too when the date range reaches back into it, as Search reads it
+func (repository *MongoObservationRepository) Count(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error) {
+	filter := observationSearchFilter(searchParams)
+
+	total, countError := repository.collection.CountDocuments(ctx, filter)
+	if countError != nil {
+		return 0, countError
+	}
+	if repository.archiveMayHold(searchParams.DateGreaterThan) {
+		archivedTotal, archiveCountError := repository.archive.CountDocuments(ctx, filter)
+		if archiveCountError != nil {
+			return 0, archiveCountError
+		}
+		total += archivedTotal
+	}
+	return int(total), nil
 }
 
 // Update modifies an existing observation
+// Returns ErrObservationNotFound when no observation has its ID, wrapped with ErrResourceDeleted when it was deleted
 func (repository *MongoObservationRepository) Update(ctx context.Context, observation *models.Observation) (*models.Observation, error) {
 	// Convert string ID to ObjectID
 	objectID, convertError := primitive.ObjectIDFromHex(observation.ID)
 	if convertError != nil {
-		return nil, fmt.Errorf("invalid observation ID: %w", convertError)
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrObservationNotFound, observation.ID)
 	}
 
 	// Update timestamp
 	observation.UpdatedAt = time.Now()
 
+	// Observations stored before versioning count as version 1; stamp it so the increment below yields 2
+	if stampError := repository.stampFirstVersion(ctx, objectID); stampError != nil {
+		return nil, stampError
+	}
+
 	// Build filter and update (exclude _id field as it's immutable in MongoDB)
-	filter := bson.M{"_id": objectID}
+	// Tags are left untouched; they change only through UpdateTags
+	filter := notDeleted(bson.M{"_id": objectID})
+	if observation.Version > 0 {
+		filter["version"] = observation.Version
+	}
 	update := bson.M{
+		"$inc": bson.M{"version": 1},
 		"$set": bson.M{
-			"patient_id":     observation.PatientID,
-			"status":         observation.Status,
-			"category":       observation.Category,
-			"code":           observation.Code,
-			"code_system":    observation.CodeSystem,
-			"code_display":   observation.CodeDisplay,
-			"value_quantity": observation.ValueQuantity,
-			"value_unit":     observation.ValueUnit,
-			"value_string":   observation.ValueString,
-			"effective_date": observation.EffectiveDate,
-			"issued_date":    observation.IssuedDate,
-			"components":     observation.Components,
-			"updated_at":     observation.UpdatedAt,
+			"patient_id":       observation.PatientID,
+			"performer_id":     observation.PerformerID,
+			"encounter_id":     observation.EncounterID,
+			"status":           observation.Status,
+			"category":         observation.Category,
+			"category_display": observation.CategoryDisplay,
+			"code":             observation.Code,
+			"code_system":      observation.CodeSystem,
+			"code_display":     observation.CodeDisplay,
+			"value_quantity":   observation.ValueQuantity,
+			"value_unit":       observation.ValueUnit,
+			"canonical_value":  observation.CanonicalValue,
+			"canonical_unit":   observation.CanonicalUnit,
+			"value_string":     observation.ValueString,
+			"effective_date":   observation.EffectiveDate,
+			"issued_date":      observation.IssuedDate,
+			"components":       observation.Components,
+			"reference_ranges": observation.ReferenceRanges,
+			"interpretation":   observation.Interpretation,
+			"updated_at":       observation.UpdatedAt,
 		},
 	}
 
-	// Execute update
-	updateResult, updateError := repository.collection.UpdateOne(ctx, filter, update)
+	// Execute update, reading back the stored document so tags set through UpdateTags are returned
+	var updatedObservation models.Observation
+	updateOptions := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	updateError := repository.collection.FindOneAndUpdate(ctx, filter, update, updateOptions).Decode(&updatedObservation)
+	if errors.Is(updateError, mongo.ErrNoDocuments) && repository.tiered() {
+		archivedObservation, archiveError := repository.updateArchived(ctx, filter, update)
+		if archiveError == nil {
+			return archivedObservation, nil
+		}
+		updateError = archiveError
+	}
 	if updateError != nil {
+		if errors.Is(updateError, mongo.ErrNoDocuments) {
+			return nil, repository.missedUpdateError(ctx, observation)
+		}
 		return nil, fmt.Errorf("failed to update observation: %w", updateError)
 	}
 
-	if updateResult.MatchedCount == 0 {
-		return nil, fmt.Errorf("observation not found")
+	return &updatedObservation, nil
+}
+
+// stampFirstVersion sets version 1 on an observation stored before versioning, in whichever tier holds it
+func (repository *MongoObservationRepository) stampFirstVersion(ctx context.Context, objectID primitive.ObjectID) error {
+	filter := bson.M{"_id": objectID, "version": bson.M{"$exists": false}}
+	stamp := bson.M{"$set": bson.M{"version": 1}}
+	if _, stampError := repository.collection.UpdateOne(ctx, filter, stamp); stampError != nil {
+		return fmt.Errorf("failed to stamp observation version: %w", stampError)
 	}
+	if repository.tiered() {
+		if _, stampError := repository.archive.UpdateOne(ctx, filter, stamp); stampError != nil {
+			return fmt.Errorf("failed to stamp archived observation version: %w", stampError)
+		}
+	}
+	return nil
+}
 
-	return observation, nil
+// missedUpdateError explains an update that matched no document: the observation was deleted, or a versioned
+// update of an observation that still exists lost to a newer version; anything else did not find the observation
+func (repository *MongoObservationRepository) missedUpdateError(ctx context.Context, observation *models.Observation) error {
+	_, getError := repository.GetByID(ctx, observation.ID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return getError
+	}
+	if getError != nil || observation.Version == 0 {
+		return ErrObservationNotFound
+	}
+	return ErrObservationVersionConflict
 }
 
-// Delete removes an observation by ID
+// Delete marks an observation deleted by ID, in whichever tier holds it, leaving the document for a read of
+// it to answer as deleted
+// Returns ErrObservationNotFound when no observation has the ID, wrapped with ErrResourceDeleted when it was
+// already deleted
 func (repository *MongoObservationRepository) Delete(ctx context.Context, observationID string) error {
 	// Convert string ID to ObjectID
 	objectID, convertError := primitive.ObjectIDFromHex(observationID)
@@ -266,16 +548,407 @@ func (repository *MongoObservationRepository) Delete(ctx context.Context, observ
 		return fmt.Errorf("invalid observation ID: %w", convertError)
 	}
 
-	// Delete document
-	filter := bson.M{"_id": objectID}
-	deleteResult, deleteError := repository.collection.DeleteOne(ctx, filter)
+	// Mark the document deleted, falling back to the archive
+	deleteError := softDeleteDocument(ctx, repository.collection, objectID, ErrObservationNotFound)
+	if !errors.Is(deleteError, ErrResourceDeleted) && errors.Is(deleteError, ErrObservationNotFound) && repository.tiered() {
+		deleteError = softDeleteDocument(ctx, repository.archive, objectID, ErrObservationNotFound)
+	}
+	return deleteError
+}
+
+// maxTagUpdateAttempts bounds retries when another writer changes an observation's tags concurrently
+const maxTagUpdateAttempts = 5
+
+// UpdateTags applies change to the observation's tags
+// The write only succeeds if the tags are unchanged since they were read, and is retried otherwise
+func (repository *MongoObservationRepository) UpdateTags(ctx context.Context, observationID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	objectID, convertError := primitive.ObjectIDFromHex(observationID)
+	if convertError != nil {
+		return nil, fmt.Errorf("%w: invalid ID %q", ErrObservationNotFound, observationID)
+	}
+
+	// tier is the collection holding the observation, switched to the archive when it is not in the primary one
+	tier := repository.collection
+	for attempt := 0; attempt < maxTagUpdateAttempts; attempt++ {
+		var stored struct {
+			Tags models.Tags `bson:"tags"`
+		}
+		findOptions := options.FindOne().SetProjection(bson.M{"tags": 1})
+		findError := tier.FindOne(ctx, notDeleted(bson.M{"_id": objectID}), findOptions).Decode(&stored)
+		if errors.Is(findError, mongo.ErrNoDocuments) && tier == repository.collection && repository.tiered() {
+			tier = repository.archive
+			findError = tier.FindOne(ctx, notDeleted(bson.M{"_id": objectID}), findOptions).Decode(&stored)
+		}
+		if findError != nil {
+			if errors.Is(findError, mongo.ErrNoDocuments) {
+				return nil, repository.missingObservationError(ctx, objectID)
+			}
+			return nil, fmt.Errorf("failed to find observation: %w", findError)
+		}
+
+		// Match the tags exactly as read; a missing field and an empty list are equivalent
+		filter := notDeleted(bson.M{"_id": objectID, "tags": stored.Tags})
+		if len(stored.Tags) == 0 {
+			filter["tags"] = bson.M{"$in": bson.A{nil, bson.A{}}}
+		}
+
+		changedTags := change(stored.Tags)
+		updateResult, updateError := tier.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"tags": changedTags}})
+		if updateError != nil {
+			return nil, fmt.Errorf("failed to update observation tags: %w", updateError)
+		}
+		if updateResult.MatchedCount == 1 {
+			return changedTags, nil
+		}
+	}
+
+	return nil, fmt.Errorf("observation tags changed concurrently %d times", maxTagUpdateAttempts)
+}
+
+// tagFilter matches an embedded tag; an empty code matches any code, and an empty system matches
+// only tags without one unless the criterion allows any system
+func tagFilter(criterion models.TagCriterion) bson.M {
+	condition := bson.M{}
+	switch {
+	case criterion.System != "":
+		condition["system"] = criterion.System
+	case !criterion.AnySystem:
+		condition["system"] = bson.M{"$in": bson.A{nil, ""}}
+	}
+	if criterion.Code != "" {
+		condition["code"] = criterion.Code
+	}
+	return condition
+}
+
+// quarantineCollectionName holds observations removed from circulation by reconciliation
+const quarantineCollectionName = "observations_quarantine"
+
+// CountByPatient returns the number of stored observations per patient ID, archived ones included and deleted
+// ones left out
+func (repository *MongoObservationRepository) CountByPatient(ctx context.Context) (map[string]int64, error) {
+	pipeline := repository.withArchive(mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{})}},
+		{{Key: "$group", Value: bson.M{"_id": "$patient_id", "count": bson.M{"$sum": 1}}}},
+	}, 1, nil)
+
+	cursor, aggregateError := repository.collection.Aggregate(ctx, pipeline)
+	if aggregateError != nil {
+		return nil, fmt.Errorf("failed to count observations by patient: %w", aggregateError)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		PatientID string `bson:"_id"`
+		Count     int64  `bson:"count"`
+	}
+	if decodeError := cursor.All(ctx, &groups); decodeError != nil {
+		return nil, fmt.Errorf("failed to decode observation counts: %w", decodeError)
+	}
+
+	counts := make(map[string]int64, len(groups))
+	for _, group := range groups {
+		counts[group.PatientID] = group.Count
+	}
+
+	return counts, nil
+}
+
+// QuarantineByPatient moves every observation for the given patient into the quarantine collection and
+// returns the IDs of the ones it moved
+// Documents are copied before they are deleted, and only the copied documents are deleted, so an
+// observation written while the run is in progress stays in place and an interrupted run can be repeated
+// Archived observations are quarantined too
+func (repository *MongoObservationRepository) QuarantineByPatient(ctx context.Context, patientID string) ([]string, error) {
+	quarantinedIDs, quarantineError := quarantineFrom(ctx, repository.collection, patientID)
+	if quarantineError != nil || !repository.tiered() {
+		return quarantinedIDs, quarantineError
+	}
+	archivedIDs, archiveError := quarantineFrom(ctx, repository.archive, patientID)
+	return append(quarantinedIDs, archivedIDs...), archiveError
+}
+
+// quarantineFrom moves the patient's observations from source into the quarantine collection, returning their IDs
+func quarantineFrom(ctx context.Context, source *mongo.Collection, patientID string) ([]string, error) {
+	cursor, findError := source.Find(ctx, bson.M{"patient_id": patientID})
+	if findError != nil {
+		return nil, fmt.Errorf("failed to find observations to quarantine: %w", findError)
+	}
+	defer cursor.Close(ctx)
+
+	quarantineCollection := source.Database().Collection(quarantineCollectionName)
+	quarantinedAt := time.Now()
+	copiedIDs := []interface{}{}
+	for cursor.Next(ctx) {
+		var document bson.M
+		if decodeError := cursor.Decode(&document); decodeError != nil {
+			return nil, fmt.Errorf("failed to decode observation to quarantine: %w", decodeError)
+		}
+		document["quarantined_at"] = quarantinedAt
+
+		_, replaceError := quarantineCollection.ReplaceOne(ctx, bson.M{"_id": document["_id"]}, document, options.Replace().SetUpsert(true))
+		if replaceError != nil {
+			return nil, fmt.Errorf("failed to copy observation to quarantine: %w", replaceError)
+		}
+		copiedIDs = append(copiedIDs, document["_id"])
+	}
+	if cursorError := cursor.Err(); cursorError != nil {
+		return nil, fmt.Errorf("failed to read observations to quarantine: %w", cursorError)
+	}
+	if len(copiedIDs) == 0 {
+		return nil, nil
+	}
+
+	if _, deleteError := source.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": copiedIDs}}); deleteError != nil {
+		return nil, fmt.Errorf("failed to remove quarantined observations: %w", deleteError)
+	}
+
+	quarantinedIDs := make([]string, 0, len(copiedIDs))
+	for _, copiedID := range copiedIDs {
+		if objectID, isObjectID := copiedID.(primitive.ObjectID); isObjectID {
+			quarantinedIDs = append(quarantinedIDs, objectID.Hex())
+		}
+	}
+	return quarantinedIDs, nil
+}
+
+// erasureHoldCollectionName holds observations withdrawn by a patient erasure until it is purged or cancelled
+const erasureHoldCollectionName = "observations_pending_erasure"
+
+// HoldForErasure moves every observation for the patient into the erasure hold collection, tagged with the erasure
+// As in QuarantineByPatient, documents are copied before only the copied ones are deleted
+// Archived observations are held too
+func (repository *MongoObservationRepository) HoldForErasure(ctx context.Context, erasureID int64, patientID string) ([]*models.Observation, error) {
+	holdCollection := repository.collection.Database().Collection(erasureHoldCollectionName)
+	holdDocument := func(document bson.M) {
+		document["erasure_id"] = erasureID
+		document["held_at"] = time.Now()
+		delete(document, "archived_at")
+	}
+
+	heldObservations, holdError := moveObservations(ctx, repository.collection, holdCollection, bson.M{"patient_id": patientID}, holdDocument)
+	if holdError != nil || !repository.tiered() {
+		return heldObservations, holdError
+	}
+	archivedObservations, archiveError := moveObservations(ctx, repository.archive, holdCollection, bson.M{"patient_id": patientID}, holdDocument)
+	if archiveError != nil {
+		return nil, archiveError
+	}
+	return append(heldObservations, archivedObservations...), nil
+}
+
+// RestoreErasure moves the erasure's observations from the hold collection back into circulation
+// Observations that were archived return to the primary collection; the next archival run archives them again
+func (repository *MongoObservationRepository) RestoreErasure(ctx context.Context, erasureID int64) ([]*models.Observation, error) {
+	holdCollection := repository.collection.Database().Collection(erasureHoldCollectionName)
+	return moveObservations(ctx, holdCollection, repository.collection, bson.M{"erasure_id": erasureID}, func(document bson.M) {
+		delete(document, "erasure_id")
+		delete(document, "held_at")
+	})
+}
+
+// PurgeErasure deletes the erasure's observations from the hold collection
+func (repository *MongoObservationRepository) PurgeErasure(ctx context.Context, erasureID int64) (int64, error) {
+	holdCollection := repository.collection.Database().Collection(erasureHoldCollectionName)
+	deleteResult, deleteError := holdCollection.DeleteMany(ctx, bson.M{"erasure_id": erasureID})
 	if deleteError != nil {
-		return fmt.Errorf("failed to delete observation: %w", deleteError)
+		return 0, fmt.Errorf("failed to purge held observations: %w", deleteError)
 	}
+	return deleteResult.DeletedCount, nil
+}
 
-	if deleteResult.DeletedCount == 0 {
-		return fmt.Errorf("observation not found")
+// moveObservations copies the documents matching filter from source to target, adjusted by prepare,
+// then deletes the copied documents from source and returns them as observations
+func moveObservations(ctx context.Context, source *mongo.Collection, target *mongo.Collection, filter bson.M, prepare func(document bson.M), findOptions ...*options.FindOptions) ([]*models.Observation, error) {
+	cursor, findError := source.Find(ctx, filter, findOptions...)
+	if findError != nil {
+		return nil, fmt.Errorf("failed to find observations to move: %w", findError)
 	}
+	defer cursor.Close(ctx)
 
-	return nil
+	movedObservations := []*models.Observation{}
+	copiedIDs := []interface{}{}
+	for cursor.Next(ctx) {
+		var document bson.M
+		if decodeError := cursor.Decode(&document); decodeError != nil {
+			return nil, fmt.Errorf("failed to decode observation to move: %w", decodeError)
+		}
+		observation := &models.Observation{}
+		if decodeError := cursor.Decode(observation); decodeError != nil {
+			return nil, fmt.Errorf("failed to decode observation to move: %w", decodeError)
+		}
+		prepare(document)
+
+		_, replaceError := target.ReplaceOne(ctx, bson.M{"_id": document["_id"]}, document, options.Replace().SetUpsert(true))
+		if replaceError != nil {
+			return nil, fmt.Errorf("failed to copy observation: %w", replaceError)
+		}
+		copiedIDs = append(copiedIDs, document["_id"])
+		movedObservations = append(movedObservations, observation)
+	}
+	if cursorError := cursor.Err(); cursorError != nil {
+		return nil, fmt.Errorf("failed to read observations to move: %w", cursorError)
+	}
+	if len(copiedIDs) == 0 {
+		return movedObservations, nil
+	}
+
+	if _, deleteError := source.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": copiedIDs}}); deleteError != nil {
+		return nil, fmt.Errorf("failed to remove moved observations: %w", deleteError)
+	}
+	return movedObservations, nil
+}
+
+// LastN returns the patient's Max most recent observations of each code, ordered by code and then newest first
+// Observations are ranked by effective date, and by creation time among observations effective at the same time
+// Archived observations are included when the date range reaches back into the archive
+func (repository *MongoObservationRepository) LastN(ctx context.Context, lastNParams *models.ObservationLastNParams) ([]*models.Observation, error) {
+	filter := notDeleted(bson.M{"patient_id": lastNParams.PatientID})
+	if len(lastNParams.Categories) > 0 {
+		filter["category"] = bson.M{"$in": lastNParams.Categories}
+	}
+	if len(lastNParams.Codes) > 0 {
+		codeAlternatives := bson.A{}
+		for _, criterion := range lastNParams.Codes {
+			codeAlternative := bson.M{}
+			if criterion.Code != "" {
+				codeAlternative["code"] = criterion.Code
+			}
+			if !criterion.AnySystem {
+				codeAlternative["code_system"] = criterion.System
+			}
+			codeAlternatives = append(codeAlternatives, codeAlternative)
+		}
+		filter["$or"] = codeAlternatives
+	}
+	if lastNParams.DateGreaterThan != nil || lastNParams.DateLessThan != nil {
+		effectiveDate := bson.M{}
+		if lastNParams.DateGreaterThan != nil {
+			effectiveDate["$gte"] = lastNParams.DateGreaterThan
+		}
+		if lastNParams.DateLessThan != nil {
+			effectiveDate["$lte"] = lastNParams.DateLessThan
+		}
+		filter["effective_date"] = effectiveDate
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		// $topN keeps only Max observations per code while grouping, so a long history is never held whole
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"code_system": "$code_system", "code": "$code"},
+			"observations": bson.M{"$topN": bson.M{
+				"n":      lastNParams.Max,
+				"sortBy": bson.D{{Key: "effective_date", Value: -1}, {Key: "created_at", Value: -1}},
+				"output": "$$ROOT",
+			}},
+		}}},
+		{{Key: "$unwind", Value: "$observations"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$observations"}}},
+		{{Key: "$sort", Value: bson.D{{Key: "code_system", Value: 1}, {Key: "code", Value: 1}, {Key: "effective_date", Value: -1}, {Key: "created_at", Value: -1}}}},
+	}
+
+	cursor, aggregateError := repository.collection.Aggregate(ctx, repository.withArchive(pipeline, 1, lastNParams.DateGreaterThan))
+	if aggregateError != nil {
+		return nil, fmt.Errorf("failed to find last observations: %w", aggregateError)
+	}
+	defer cursor.Close(ctx)
+
+	observations := make([]*models.Observation, 0)
+	if decodeError := cursor.All(ctx, &observations); decodeError != nil {
+		return nil, fmt.Errorf("failed to decode observations: %w", decodeError)
+	}
+
+	return observations, nil
+}
+
+// DailyAggregates computes per-patient, per-code daily aggregates of the numeric values of observations
+// effective in [from, to)
+// Component values are aggregated under their own codes, and values in different units are kept apart
+// Archived observations are included when the range reaches back into the archive
+func (repository *MongoObservationRepository) DailyAggregates(ctx context.Context, from time.Time, to time.Time) ([]*models.ObservationRollup, error) {
+	componentValues := bson.M{"$map": bson.M{
+		"input": bson.M{"$ifNull": bson.A{"$components", bson.A{}}},
+		"as":    "component",
+		"in": bson.M{
+			"code_system": "$$component.code_system",
+			"code":        "$$component.code",
+			"unit":        "$$component.value_unit",
+			"value":       "$$component.value_quantity",
+		},
+	}}
+	observationValue := bson.M{
+		"code_system": "$code_system",
+		"code":        "$code",
+		"unit":        "$value_unit",
+		"value":       "$value_quantity",
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: notDeleted(bson.M{"effective_date": bson.M{"$gte": from, "$lt": to}})}},
+		{{Key: "$project", Value: bson.M{
+			"patient_id": 1,
+			"day": bson.M{"$dateFromParts": bson.M{
+				"year":  bson.M{"$year": "$effective_date"},
+				"month": bson.M{"$month": "$effective_date"},
+				"day":   bson.M{"$dayOfMonth": "$effective_date"},
+			}},
+			"values": bson.M{"$concatArrays": bson.A{bson.A{observationValue}, componentValues}},
+		}}},
+		{{Key: "$unwind", Value: "$values"}},
+		{{Key: "$match", Value: bson.M{"values.value": bson.M{"$type": "number"}}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{
+				"patient_id":  "$patient_id",
+				"code_system": "$values.code_system",
+				"code":        "$values.code",
+				"unit":        "$values.unit",
+				"day":         "$day",
+			},
+			"count": bson.M{"$sum": 1},
+			"min":   bson.M{"$min": "$values.value"},
+			"max":   bson.M{"$max": "$values.value"},
+			"sum":   bson.M{"$sum": "$values.value"},
+		}}},
+	}
+
+	cursor, aggregateError := repository.collection.Aggregate(ctx, repository.withArchive(pipeline, 1, &from))
+	if aggregateError != nil {
+		return nil, fmt.Errorf("failed to aggregate daily observations: %w", aggregateError)
+	}
+	defer cursor.Close(ctx)
+
+	var groups []struct {
+		Key struct {
+			PatientID  string    `bson:"patient_id"`
+			CodeSystem string    `bson:"code_system"`
+			Code       string    `bson:"code"`
+			Unit       string    `bson:"unit"`
+			Day        time.Time `bson:"day"`
+		} `bson:"_id"`
+		Count int64   `bson:"count"`
+		Min   float64 `bson:"min"`
+		Max   float64 `bson:"max"`
+		Sum   float64 `bson:"sum"`
+	}
+	if decodeError := cursor.All(ctx, &groups); decodeError != nil {
+		return nil, fmt.Errorf("failed to decode daily aggregates: %w", decodeError)
+	}
+
+	rollups := make([]*models.ObservationRollup, 0, len(groups))
+	for _, group := range groups {
+		rollups = append(rollups, &models.ObservationRollup{
+			PatientID:  group.Key.PatientID,
+			CodeSystem: group.Key.CodeSystem,
+			Code:       group.Key.Code,
+			Unit:       group.Key.Unit,
+			Day:        group.Key.Day.UTC(),
+			Count:      group.Count,
+			Min:        group.Min,
+			Max:        group.Max,
+			Sum:        group.Sum,
+		})
+	}
+
+	return rollups, nil
 }
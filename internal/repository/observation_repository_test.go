@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
@@ -262,6 +263,7 @@ func TestMongoObservationRepository_Update(t *testing.T) {
 	// Update observation
 	createdObservation.Status = "final"
 	createdObservation.Code = "new-code"
+	createdObservation.Interpretation = &models.Interpretation{Code: "questionable"}
 	updatedObservation, updateError := repository.Update(context.Background(), createdObservation)
 
 	if updateError != nil {
@@ -273,6 +275,40 @@ func TestMongoObservationRepository_Update(t *testing.T) {
 	if updatedObservation.Code != "new-code" {
 		t.Errorf("Expected code new-code, got %s", updatedObservation.Code)
 	}
+	if updatedObservation.Interpretation == nil || updatedObservation.Interpretation.Code != "questionable" {
+		t.Errorf("Expected the interpretation stored, got %+v", updatedObservation.Interpretation)
+	}
+}
+
+// TestMongoObservationRepository_Update_Version verifies updates increment the version and stale versions are refused
+func TestMongoObservationRepository_Update_Version(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	createdObservation, _ := repository.Create(context.Background(), &models.Observation{PatientID: "patient-123", Status: "preliminary"})
+	if createdObservation.Version != 1 {
+		t.Fatalf("Expected a new observation at version 1, got %d", createdObservation.Version)
+	}
+
+	createdObservation.Status = "final"
+	updatedObservation, updateError := repository.Update(context.Background(), createdObservation)
+	if updateError != nil {
+		t.Fatalf("Expected no error, got %v", updateError)
+	}
+	if updatedObservation.Version != 2 {
+		t.Errorf("Expected version 2 after the update, got %d", updatedObservation.Version)
+	}
+
+	createdObservation.Version = 1
+	if _, staleError := repository.Update(context.Background(), createdObservation); !errors.Is(staleError, ErrObservationVersionConflict) {
+		t.Errorf("Expected ErrObservationVersionConflict for a stale version, got %v", staleError)
+	}
+
+	createdObservation.Version = 0
+	if unconditionalObservation, unconditionalError := repository.Update(context.Background(), createdObservation); unconditionalError != nil || unconditionalObservation.Version != 3 {
+		t.Errorf("Expected an update without a version to apply as version 3, got %v", unconditionalError)
+	}
 }
 
 // TestMongoObservationRepository_Update_InvalidID verifies invalid ID handling
@@ -339,9 +375,15 @@ func TestMongoObservationRepository_Delete(t *testing.T) {
 	}
 
 	// Verify deletion
-	retrievedObservation, _ := repository.GetByID(context.Background(), createdObservation.ID)
-	if retrievedObservation != nil {
-		t.Error("Expected observation to be deleted")
+	retrievedObservation, getError := repository.GetByID(context.Background(), createdObservation.ID)
+	if retrievedObservation != nil || !errors.Is(getError, ErrResourceDeleted) || !errors.Is(getError, ErrObservationNotFound) {
+		t.Errorf("Expected the observation reported as deleted, got %+v (%v)", retrievedObservation, getError)
+	}
+	if patientObservations, _ := repository.GetByPatientID(context.Background(), "patient-123", 10, 0); len(patientObservations) != 0 {
+		t.Errorf("Expected the deleted observation left out of listings, got %d", len(patientObservations))
+	}
+	if repeatError := repository.Delete(context.Background(), createdObservation.ID); !errors.Is(repeatError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", repeatError)
 	}
 }
 
@@ -382,3 +424,75 @@ func TestNewMongoObservationRepository(t *testing.T) {
 		t.Error("Expected collection to be set")
 	}
 }
+
+// TestMongoObservationRepository_CountAndQuarantine verifies per-patient counts and quarantining orphans
+func TestMongoObservationRepository_CountAndQuarantine(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	quarantineCollection := mongoDatabase.Collection(quarantineCollectionName)
+	defer cleanupMongoTestData(t, repository.collection)
+	defer cleanupMongoTestData(t, quarantineCollection)
+
+	for _, patientID := range []string{"patient-1", "patient-1"} {
+		repository.Create(context.Background(), &models.Observation{PatientID: patientID, Status: "final", Code: "8867-4"})
+	}
+	orphan, _ := repository.Create(context.Background(), &models.Observation{PatientID: "orphan-1", Status: "final", Code: "8867-4"})
+
+	counts, countError := repository.CountByPatient(context.Background())
+	if countError != nil {
+		t.Fatalf("Expected no error, got %v", countError)
+	}
+	if counts["patient-1"] != 2 || counts["orphan-1"] != 1 {
+		t.Errorf("Unexpected counts: %v", counts)
+	}
+
+	quarantinedIDs, quarantineError := repository.QuarantineByPatient(context.Background(), "orphan-1")
+	if quarantineError != nil {
+		t.Fatalf("Expected no error, got %v", quarantineError)
+	}
+	if len(quarantinedIDs) != 1 || quarantinedIDs[0] != orphan.ID {
+		t.Errorf("Expected the orphan's ID quarantined, got %v", quarantinedIDs)
+	}
+
+	quarantineCount, _ := quarantineCollection.CountDocuments(context.Background(), bson.M{"patient_id": "orphan-1"})
+	remainingCounts, _ := repository.CountByPatient(context.Background())
+	if quarantineCount != 1 || remainingCounts["orphan-1"] != 0 {
+		t.Errorf("Expected orphan moved to quarantine, got %d quarantined and counts %v", quarantineCount, remainingCounts)
+	}
+}
+
+// TestMongoObservationRepository_LastN verifies only the Max most recent observations of each code are returned,
+// ordered by code and then newest first
+func TestMongoObservationRepository_LastN(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	baseTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 5; day++ {
+		for _, code := range []string{"8867-4", "8480-6"} {
+			effectiveDate := baseTime.AddDate(0, 0, day)
+			repository.Create(context.Background(), &models.Observation{
+				PatientID: "patient-1", Status: "final", Code: code, CodeSystem: "http://loinc.org", EffectiveDate: &effectiveDate,
+			})
+		}
+	}
+
+	observations, lastNError := repository.LastN(context.Background(), &models.ObservationLastNParams{PatientID: "patient-1", Max: 2})
+	if lastNError != nil {
+		t.Fatalf("Expected no error, got %v", lastNError)
+	}
+	if len(observations) != 4 {
+		t.Fatalf("Expected 2 observations for each of 2 codes, got %d", len(observations))
+	}
+	expectedOrder := []struct {
+		code string
+		day  int
+	}{{"8480-6", 4}, {"8480-6", 3}, {"8867-4", 4}, {"8867-4", 3}}
+	for index, expected := range expectedOrder {
+		observation := observations[index]
+		if observation.Code != expected.code || !observation.EffectiveDate.Equal(baseTime.AddDate(0, 0, expected.day)) {
+			t.Errorf("Position %d: expected %s on day %d, got %s at %v", index, expected.code, expected.day, observation.Code, observation.EffectiveDate)
+		}
+	}
+}
@@ -130,7 +130,7 @@ func TestObservationRepository_Search_ByCode(t *testing.T) {
 	setupObservationSearchTestData(t, repository)
 
 	searchParams := &models.ObservationSearchParams{
-		Code:   "8480-6", // Systolic BP
+		Code:   models.ValueCriteria{{"8480-6"}}, // Systolic BP
 		Limit:  10,
 		Offset: 0,
 	}
@@ -159,7 +159,7 @@ func TestObservationRepository_Search_ByCategory(t *testing.T) {
 	setupObservationSearchTestData(t, repository)
 
 	searchParams := &models.ObservationSearchParams{
-		Category: "vital-signs",
+		Category: models.ValueCriteria{{"vital-signs"}},
 		Limit:    10,
 		Offset:   0,
 	}
@@ -190,7 +190,7 @@ func TestObservationRepository_Search_ByStatus(t *testing.T) {
 	setupObservationSearchTestData(t, repository)
 
 	searchParams := &models.ObservationSearchParams{
-		Status: "final",
+		Status: models.ValueCriteria{{"final"}},
 		Limit:  10,
 		Offset: 0,
 	}
@@ -288,8 +288,8 @@ func TestObservationRepository_Search_CombinedFilters(t *testing.T) {
 
 	searchParams := &models.ObservationSearchParams{
 		PatientID: "patient-001",
-		Category:  "vital-signs",
-		Status:    "final",
+		Category:  models.ValueCriteria{{"vital-signs"}},
+		Status:    models.ValueCriteria{{"final"}},
 		Limit:     10,
 		Offset:    0,
 	}
@@ -438,6 +438,40 @@ func TestObservationRepository_Search_Pagination(t *testing.T) {
 	}
 }
 
+// TestObservationRepository_Search_Cursor verifies following cursors visits every observation once in creation order
+func TestObservationRepository_Search_Cursor(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	setupObservationSearchTestData(t, repository)
+	total, _ := repository.Count(context.Background(), &models.ObservationSearchParams{})
+
+	seenIDs := map[string]bool{}
+	var after *models.PageCursor
+	for pageNumber := 0; pageNumber <= total; pageNumber++ {
+		page, searchError := repository.Search(context.Background(), &models.ObservationSearchParams{Limit: 2, CursorPaging: true, After: after})
+		if searchError != nil {
+			t.Fatalf("Expected no error, got %v", searchError)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, observation := range page {
+			if seenIDs[observation.ID] {
+				t.Fatalf("Expected each observation once, got %s again", observation.ID)
+			}
+			seenIDs[observation.ID] = true
+		}
+		lastObservation := page[len(page)-1]
+		after = &models.PageCursor{CreatedAt: lastObservation.CreatedAt, ID: lastObservation.ID}
+	}
+
+	if len(seenIDs) != total {
+		t.Errorf("Expected %d observations across the pages, got %d", total, len(seenIDs))
+	}
+}
+
 // TestObservationRepository_Search_EmptyResults tests search with no matches
 func TestObservationRepository_Search_EmptyResults(t *testing.T) {
 	mongoDatabase := setupTestMongoDB(t)
@@ -498,7 +532,7 @@ func TestObservationRepository_Search_PatientAndCode(t *testing.T) {
 
 	searchParams := &models.ObservationSearchParams{
 		PatientID: "patient-001",
-		Code:      "8480-6",
+		Code:      models.ValueCriteria{{"8480-6"}},
 		Limit:     10,
 		Offset:    0,
 	}
@@ -574,9 +608,9 @@ func TestObservationRepository_Search_AllFilters(t *testing.T) {
 
 	searchParams := &models.ObservationSearchParams{
 		PatientID:       "patient-001",
-		Code:            "8480-6",
-		Category:        "vital-signs",
-		Status:          "final",
+		Code:            models.ValueCriteria{{"8480-6"}},
+		Category:        models.ValueCriteria{{"vital-signs"}},
+		Status:          models.ValueCriteria{{"final"}},
 		DateGreaterThan: &startDate,
 		DateLessThan:    &endDate,
 		SortBy:          "effective_date",
@@ -605,3 +639,138 @@ func TestObservationRepository_Search_AllFilters(t *testing.T) {
 	}
 }
 
+// TestObservationRepository_Search_ByTag tests tagging observations and searching by _tag, with and without a system
+func TestObservationRepository_Search_ByTag(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	setupObservationSearchTestData(t, repository)
+
+	allObservations, _ := repository.GetAll(context.Background(), 10, 0)
+	importedTag := models.Tag{Code: "imported-from-lis"}
+	addImported := func(currentTags models.Tags) models.Tags { return currentTags.Add(importedTag) }
+	if _, tagError := repository.UpdateTags(context.Background(), allObservations[0].ID, addImported); tagError != nil {
+		t.Fatalf("Failed to tag observation: %v", tagError)
+	}
+	sourceTag := models.Tag{System: "http://example.org/source", Code: "imported-from-lis"}
+	addSource := func(currentTags models.Tags) models.Tags { return currentTags.Add(sourceTag) }
+	if _, tagError := repository.UpdateTags(context.Background(), allObservations[1].ID, addSource); tagError != nil {
+		t.Fatalf("Failed to tag observation: %v", tagError)
+	}
+
+	anySystemResults, searchError := repository.Search(context.Background(), &models.ObservationSearchParams{
+		Tags:  models.TagCriteria{{{Code: "imported-from-lis", AnySystem: true}}},
+		Limit: 10,
+	})
+	if searchError != nil || len(anySystemResults) != 2 {
+		t.Fatalf("Expected both tagged observations for a bare code, got %d (%v)", len(anySystemResults), searchError)
+	}
+
+	searchParams := &models.ObservationSearchParams{
+		Tags:   models.TagCriteria{{{Code: "imported-from-lis"}}},
+		Limit:  10,
+		Offset: 0,
+	}
+
+	results, searchError := repository.Search(context.Background(), searchParams)
+
+	if searchError != nil {
+		t.Fatalf("Expected no error, got %v", searchError)
+	}
+
+	if len(results) != 1 || results[0].ID != allObservations[0].ID {
+		t.Errorf("Expected only the observation tagged without a system, got %d results", len(results))
+	}
+}
+
+// TestObservationRepository_Search_MultipleValues verifies comma-separated values match any and repeated parameters must all match
+func TestObservationRepository_Search_MultipleValues(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	setupObservationSearchTestData(t, repository)
+
+	anyCodeResults, searchError := repository.Search(context.Background(), &models.ObservationSearchParams{
+		Code:  models.ValueCriteria{{"8480-6", "8462-4"}},
+		Limit: 10,
+	})
+	if searchError != nil {
+		t.Fatalf("Expected no error, got %v", searchError)
+	}
+	if len(anyCodeResults) != 2 {
+		t.Errorf("Expected the systolic and diastolic observations, got %d", len(anyCodeResults))
+	}
+
+	everyCategoryCount, countError := repository.Count(context.Background(), &models.ObservationSearchParams{
+		Category: models.ValueCriteria{{"vital-signs"}, {"laboratory"}},
+	})
+	if countError != nil {
+		t.Fatalf("Expected no error, got %v", countError)
+	}
+	if everyCategoryCount != 0 {
+		t.Errorf("Expected no observation in both categories, got %d", everyCategoryCount)
+	}
+}
+
+// TestObservationRepository_Search_ValueQuantity verifies value-quantity comparators, ranges, and units
+func TestObservationRepository_Search_ValueQuantity(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	setupObservationSearchTestData(t, repository)
+
+	testCases := []struct {
+		name          string
+		valueQuantity models.QuantityCriteria
+		expectedCount int
+	}{
+		{"greater than", models.QuantityCriteria{{{Comparator: "gt", Value: 100}}}, 2},
+		{"range in a unit", models.QuantityCriteria{{{Comparator: "ge", Value: 80, Unit: "mmHg"}}, {{Comparator: "lt", Value: 100}}}, 1},
+		{"other unit", models.QuantityCriteria{{{Comparator: "gt", Value: 100, Unit: "mmHg"}}}, 1},
+		{"equal within precision", models.QuantityCriteria{{{Comparator: "eq", Low: 98.55, High: 98.65}}}, 1},
+		{"not equal", models.QuantityCriteria{{{Comparator: "ne", Low: 119.5, High: 120.5}}}, 4},
+		{"any of", models.QuantityCriteria{{{Comparator: "lt", Value: 75}, {Comparator: "gt", Value: 150}}}, 2},
+	}
+
+	for _, testCase := range testCases {
+		count, countError := repository.Count(context.Background(), &models.ObservationSearchParams{ValueQuantity: testCase.valueQuantity})
+		if countError != nil {
+			t.Fatalf("%s: expected no error, got %v", testCase.name, countError)
+		}
+		if count != testCase.expectedCount {
+			t.Errorf("%s: expected %d observations, got %d", testCase.name, testCase.expectedCount, count)
+		}
+	}
+}
+
+// TestObservationRepository_Search_CanonicalValueQuantity verifies criteria with a canonical unit match values
+// stored in other units by their canonical value, and values in the searched unit as before
+func TestObservationRepository_Search_CanonicalValueQuantity(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+
+	glucoseObservations := []*models.Observation{
+		{PatientID: "patient-001", Status: "final", Code: "2339-0", ValueQuantity: floatPtr(5.5), ValueUnit: "mmol/L", CanonicalValue: floatPtr(99.088), CanonicalUnit: "mg/dL"},
+		{PatientID: "patient-001", Status: "final", Code: "2339-0", ValueQuantity: floatPtr(140), ValueUnit: "mg/dL", CanonicalValue: floatPtr(140), CanonicalUnit: "mg/dL"},
+		{PatientID: "patient-001", Status: "final", Code: "2339-0", ValueQuantity: floatPtr(120), ValueUnit: "mg/dL"},
+	}
+	for _, observation := range glucoseObservations {
+		if _, createError := repository.Create(context.Background(), observation); createError != nil {
+			t.Fatalf("Failed to create test observation: %v", createError)
+		}
+	}
+
+	count, countError := repository.Count(context.Background(), &models.ObservationSearchParams{
+		ValueQuantity: models.QuantityCriteria{{{Comparator: "gt", Value: 90, Unit: "mg/dL", CanonicalUnit: "mg/dL", CanonicalValue: 90}}},
+	})
+	if countError != nil {
+		t.Fatalf("Expected no error, got %v", countError)
+	}
+	if count != 3 {
+		t.Errorf("Expected the mmol/L value, the canonical mg/dL value, and the value stored before normalization, got %d", count)
+	}
+}
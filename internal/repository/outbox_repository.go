@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// outboxRelayLockKey is the transaction-scoped advisory lock held while relaying the outbox
+// Only one instance relays at a time, so events reach Kafka in the order they were written
+const outboxRelayLockKey = 7_300_213
+
+// OutboxRepository defines the interface for the event outbox
+type OutboxRepository interface {
+	// Append stores an event, joining the transaction carried by ctx so it commits with the write it describes
+	Append(ctx context.Context, event *models.OutboxEvent) error
+
+	// InTransaction runs work in a database transaction shared by the calls made with the context passed to work
+	InTransaction(ctx context.Context, work func(ctx context.Context) error) error
+
+	// TryLockRelay takes the relay lock for the transaction carried by ctx; false when another relay holds it
+	TryLockRelay(ctx context.Context) (bool, error)
+
+	// ListOldest returns up to limit events, oldest first
+	ListOldest(ctx context.Context, limit int) ([]*models.OutboxEvent, error)
+
+	// Delete removes published events
+	Delete(ctx context.Context, ids []int64) error
+
+	// RecordFailure counts a failed attempt to publish an event and keeps the error
+	RecordFailure(ctx context.Context, id int64, lastError string) error
+
+	// Count returns the number of events waiting to be published
+	Count(ctx context.Context) (int64, error)
+}
+
+// PostgresOutboxRepository implements OutboxRepository using PostgreSQL
+type PostgresOutboxRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresOutboxRepository creates a new PostgreSQL event outbox repository instance
+func NewPostgresOutboxRepository(databaseConnection *sql.DB) *PostgresOutboxRepository {
+	return &PostgresOutboxRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// Append inserts an event and sets its ID and creation time
+func (repository *PostgresOutboxRepository) Append(ctx context.Context, event *models.OutboxEvent) error {
+	insertQuery := `
+		INSERT INTO event_outbox (topic, event_key, payload)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+	return executorFor(ctx, repository.databaseConnection).
+		QueryRowContext(ctx, insertQuery, event.Topic, event.Key, event.Payload).
+		Scan(&event.ID, &event.CreatedAt)
+}
+
+// InTransaction runs work in a transaction shared by the repositories it calls
+func (repository *PostgresOutboxRepository) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return runInTransaction(ctx, repository.databaseConnection, work)
+}
+
+// TryLockRelay takes the relay advisory lock without waiting; it is released when the transaction ends
+func (repository *PostgresOutboxRepository) TryLockRelay(ctx context.Context) (bool, error) {
+	var locked bool
+	lockError := executorFor(ctx, repository.databaseConnection).
+		QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxRelayLockKey).
+		Scan(&locked)
+	return locked, lockError
+}
+
+// ListOldest retrieves the oldest events in ID order
+func (repository *PostgresOutboxRepository) ListOldest(ctx context.Context, limit int) ([]*models.OutboxEvent, error) {
+	selectQuery := `
+		SELECT id, topic, event_key, payload, attempts, last_error, created_at
+		FROM event_outbox
+		ORDER BY id
+		LIMIT $1
+	`
+
+	rows, queryError := executorFor(ctx, repository.databaseConnection).QueryContext(ctx, selectQuery, limit)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	var outboxEvents []*models.OutboxEvent
+	for rows.Next() {
+		outboxEvent := &models.OutboxEvent{}
+		scanError := rows.Scan(
+			&outboxEvent.ID,
+			&outboxEvent.Topic,
+			&outboxEvent.Key,
+			&outboxEvent.Payload,
+			&outboxEvent.Attempts,
+			&outboxEvent.LastError,
+			&outboxEvent.CreatedAt,
+		)
+		if scanError != nil {
+			return nil, scanError
+		}
+		outboxEvents = append(outboxEvents, outboxEvent)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return outboxEvents, nil
+}
+
+// Delete removes the events with the given IDs
+func (repository *PostgresOutboxRepository) Delete(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, execError := executorFor(ctx, repository.databaseConnection).
+		ExecContext(ctx, "DELETE FROM event_outbox WHERE id = ANY($1)", pq.Array(ids))
+	return execError
+}
+
+// RecordFailure increments the event's attempts and stores the error
+func (repository *PostgresOutboxRepository) RecordFailure(ctx context.Context, id int64, lastError string) error {
+	_, execError := executorFor(ctx, repository.databaseConnection).
+		ExecContext(ctx, "UPDATE event_outbox SET attempts = attempts + 1, last_error = $2 WHERE id = $1", id, lastError)
+	return execError
+}
+
+// Count returns the number of events in the outbox
+func (repository *PostgresOutboxRepository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	countError := repository.databaseConnection.QueryRowContext(ctx, "SELECT COUNT(*) FROM event_outbox").Scan(&count)
+	return count, countError
+}
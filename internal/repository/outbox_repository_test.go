@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupOutboxTestData empties the event outbox
+func cleanupOutboxTestData(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM event_outbox"); deleteError != nil {
+		t.Fatalf("Failed to cleanup event_outbox: %v", deleteError)
+	}
+}
+
+// TestPostgresOutboxRepository_AppendListDelete verifies events are listed oldest first and removed once published
+func TestPostgresOutboxRepository_AppendListDelete(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupOutboxTestData(t, databaseConnection)
+	defer cleanupOutboxTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	outboxRepository := NewPostgresOutboxRepository(databaseConnection)
+
+	first := &models.OutboxEvent{Topic: "fhir.patient", Key: "p-1", Payload: []byte(`{"n":1}`)}
+	second := &models.OutboxEvent{Topic: "fhir.observation", Key: "o-1", Payload: []byte(`{"n":2}`)}
+	for _, outboxEvent := range []*models.OutboxEvent{first, second} {
+		if appendError := outboxRepository.Append(ctx, outboxEvent); appendError != nil || outboxEvent.ID == 0 {
+			t.Fatalf("Failed to append outbox event: %v", appendError)
+		}
+	}
+
+	outboxRepository.RecordFailure(ctx, first.ID, "leader not available")
+	oldest, listError := outboxRepository.ListOldest(ctx, 10)
+	if listError != nil || len(oldest) != 2 || oldest[0].Key != "p-1" || oldest[0].Attempts != 1 || string(oldest[1].Payload) != `{"n":2}` {
+		t.Fatalf("Unexpected outbox events %+v (%v)", oldest, listError)
+	}
+
+	outboxRepository.Delete(ctx, []int64{first.ID})
+	if count, _ := outboxRepository.Count(ctx); count != 1 {
+		t.Errorf("Expected one event left, got %d", count)
+	}
+}
+
+// TestPostgresOutboxRepository_RelayLock verifies a second relay cannot take the lock while the first holds it
+func TestPostgresOutboxRepository_RelayLock(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+
+	outboxRepository := NewPostgresOutboxRepository(databaseConnection)
+	outboxRepository.InTransaction(context.Background(), func(transactionContext context.Context) error {
+		if locked, lockError := outboxRepository.TryLockRelay(transactionContext); lockError != nil || !locked {
+			t.Fatalf("Expected the first relay to take the lock, got %v (%v)", locked, lockError)
+		}
+		return outboxRepository.InTransaction(context.Background(), func(otherContext context.Context) error {
+			if locked, _ := outboxRepository.TryLockRelay(otherContext); locked {
+				t.Error("Expected the lock refused to a second relay")
+			}
+			return nil
+		})
+	})
+}
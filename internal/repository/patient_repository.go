@@ -3,10 +3,12 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
 
+	"github.com/lib/pq"
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
 )
 
@@ -25,17 +27,30 @@ type PatientRepository interface {
 	// Search retrieves patients matching the search criteria
 	Search(ctx context.Context, searchParams *models.PatientSearchParams) ([]*models.Patient, error)
 
+	// Count returns how many patients match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.PatientSearchParams) (int, error)
+
+	// Stream calls visit with each patient Search would return without holding them all in memory
+	Stream(ctx context.Context, searchParams *models.PatientSearchParams, visit func(patient *models.Patient) error) error
+
 	// Update modifies an existing patient record
 	Update(ctx context.Context, patient *models.Patient) (*models.Patient, error)
 
-	// Delete removes a patient record by ID
+	// Delete marks a patient deleted by ID
 	Delete(ctx context.Context, patientID string) error
+
+	// UpdateTags applies change to the patient's tags atomically and returns the stored result
+	UpdateTags(ctx context.Context, patientID string, change func(models.Tags) models.Tags) (models.Tags, error)
 }
 
 // PostgresPatientRepository implements PatientRepository using PostgreSQL
 type PostgresPatientRepository struct {
 	// Database connection pool
 	databaseConnection *sql.DB
+
+	// nameSimilarity is the pg_trgm similarity at which name searches without a modifier also match
+	// misspelled names; zero turns fuzzy matching off
+	nameSimilarity float64
 }
 
 // NewPostgresPatientRepository creates a new PostgreSQL patient repository instance
@@ -45,17 +60,24 @@ func NewPostgresPatientRepository(databaseConnection *sql.DB) *PostgresPatientRe
 	}
 }
 
+// SetNameSimilarity turns on fuzzy name search: name, family, and given without a modifier also match names
+// whose pg_trgm similarity to the value is at least nameSimilarity, between 0 and 1
+// The trigram index finds candidates at pg_trgm.similarity_threshold (0.3 by default), so lower values act as it
+func (repository *PostgresPatientRepository) SetNameSimilarity(nameSimilarity float64) {
+	repository.nameSimilarity = nameSimilarity
+}
+
 // Create inserts a new patient record into the database
 func (repository *PostgresPatientRepository) Create(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
 	// SQL query to insert a new patient and return the generated ID and timestamps
 	insertQuery := `
-		INSERT INTO patients (identifier_system, identifier_value, active, family_name, given_name, gender, birth_date)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO patients (identifier_system, identifier_value, active, family_name, given_name, gender, birth_date, tags, replaced_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, '')::uuid)
 		RETURNING id, created_at, updated_at
 	`
 
 	// Execute the insert query and scan the returned values
-	scanError := repository.databaseConnection.QueryRowContext(
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
 		ctx,
 		insertQuery,
 		patient.IdentifierSystem,
@@ -65,6 +87,8 @@ func (repository *PostgresPatientRepository) Create(ctx context.Context, patient
 		patient.GivenName,
 		patient.Gender,
 		patient.BirthDate,
+		patient.Tags,
+		patient.ReplacedBy,
 	).Scan(&patient.ID, &patient.CreatedAt, &patient.UpdatedAt)
 
 	if scanError != nil {
@@ -75,19 +99,21 @@ func (repository *PostgresPatientRepository) Create(ctx context.Context, patient
 }
 
 // GetByID retrieves a patient by their unique identifier
+// Returns sql.ErrNoRows when the patient does not exist, wrapped with ErrResourceDeleted when it was deleted
 func (repository *PostgresPatientRepository) GetByID(ctx context.Context, patientID string) (*models.Patient, error) {
 	// SQL query to select a patient by ID
 	selectQuery := `
-		SELECT id, identifier_system, identifier_value, active, family_name, given_name, gender, birth_date, created_at, updated_at
+		SELECT id, identifier_system, identifier_value, active, family_name, given_name, gender, birth_date, tags, COALESCE(replaced_by::text, ''), created_at, updated_at
 		FROM patients
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	// Create a new patient instance to hold the result
 	patient := &models.Patient{}
 
-	// Execute the query and scan the result into the patient struct
-	scanError := repository.databaseConnection.QueryRowContext(ctx, selectQuery, patientID).Scan(
+	// Execute the query and scan the result into the patient struct; inside a transaction the read sees its uncommitted writes
+	executor := executorFor(ctx, repository.databaseConnection)
+	scanError := executor.QueryRowContext(ctx, selectQuery, patientID).Scan(
 		&patient.ID,
 		&patient.IdentifierSystem,
 		&patient.IdentifierValue,
@@ -96,10 +122,15 @@ func (repository *PostgresPatientRepository) GetByID(ctx context.Context, patien
 		&patient.GivenName,
 		&patient.Gender,
 		&patient.BirthDate,
+		&patient.Tags,
+		&patient.ReplacedBy,
 		&patient.CreatedAt,
 		&patient.UpdatedAt,
 	)
 
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executor, "patients", patientID)
+	}
 	if scanError != nil {
 		return nil, scanError
 	}
@@ -111,8 +142,9 @@ func (repository *PostgresPatientRepository) GetByID(ctx context.Context, patien
 func (repository *PostgresPatientRepository) GetAll(ctx context.Context, limit int, offset int) ([]*models.Patient, error) {
 	// SQL query to select all patients with limit and offset for pagination
 	selectAllQuery := `
-		SELECT id, identifier_system, identifier_value, active, family_name, given_name, gender, birth_date, created_at, updated_at
+		SELECT id, identifier_system, identifier_value, active, family_name, given_name, gender, birth_date, tags, COALESCE(replaced_by::text, ''), created_at, updated_at
 		FROM patients
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2
 	`
@@ -139,6 +171,8 @@ func (repository *PostgresPatientRepository) GetAll(ctx context.Context, limit i
 			&patient.GivenName,
 			&patient.Gender,
 			&patient.BirthDate,
+			&patient.Tags,
+			&patient.ReplacedBy,
 			&patient.CreatedAt,
 			&patient.UpdatedAt,
 		)
@@ -157,20 +191,23 @@ func (repository *PostgresPatientRepository) GetAll(ctx context.Context, limit i
 }
 
 // Update modifies an existing patient record in the database
+// Returns sql.ErrNoRows when the patient does not exist, wrapped with ErrResourceDeleted when it was deleted
 func (repository *PostgresPatientRepository) Update(ctx context.Context, patient *models.Patient) (*models.Patient, error) {
 	// SQL query to update a patient and return the updated timestamp
+	// Tags are left untouched; they change only through UpdateTags
 	updateQuery := `
 		UPDATE patients
-		SET identifier_system = $1, identifier_value = $2, active = $3, family_name = $4, given_name = $5, gender = $6, birth_date = $7, updated_at = $8
-		WHERE id = $9
-		RETURNING updated_at
+		SET identifier_system = $1, identifier_value = $2, active = $3, family_name = $4, given_name = $5, gender = $6, birth_date = $7, updated_at = $8,
+			replaced_by = NULLIF($10, '')::uuid
+		WHERE id = $9 AND deleted_at IS NULL
+		RETURNING updated_at, tags
 	`
 
 	// Set the updated timestamp
 	patient.UpdatedAt = time.Now()
 
 	// Execute the update query
-	scanError := repository.databaseConnection.QueryRowContext(
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
 		ctx,
 		updateQuery,
 		patient.IdentifierSystem,
@@ -182,8 +219,12 @@ func (repository *PostgresPatientRepository) Update(ctx context.Context, patient
 		patient.BirthDate,
 		patient.UpdatedAt,
 		patient.ID,
-	).Scan(&patient.UpdatedAt)
+		patient.ReplacedBy,
+	).Scan(&patient.UpdatedAt, &patient.Tags)
 
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executorFor(ctx, repository.databaseConnection), "patients", patient.ID)
+	}
 	if scanError != nil {
 		return nil, scanError
 	}
@@ -191,75 +232,181 @@ func (repository *PostgresPatientRepository) Update(ctx context.Context, patient
 	return patient, nil
 }
 
-// Search retrieves patients matching the search criteria with dynamic filtering
-func (repository *PostgresPatientRepository) Search(ctx context.Context, searchParams *models.PatientSearchParams) ([]*models.Patient, error) {
-	// Build dynamic query with WHERE clauses based on search parameters
-	baseQuery := `
-		SELECT id, identifier_system, identifier_value, active, family_name, given_name, gender, birth_date, created_at, updated_at
-		FROM patients
-		WHERE 1=1
-	`
-
-	// Store query parameters for prepared statement
+// patientSearchConditions builds the AND clauses that filter patients on the search criteria, shared by
+// Search and Count so a page and its total always agree; the returned parameters are numbered from $1
+// nameSimilarity is the fuzzy name matching threshold, zero when fuzzy matching is off
+func patientSearchConditions(searchParams *models.PatientSearchParams, nameSimilarity float64) (string, []interface{}) {
+	conditions := ""
 	queryParameters := []interface{}{}
 	parameterIndex := 1
 
-	// Add name filter (searches both given_name and family_name)
-	if searchParams.Name != "" {
-		baseQuery += ` AND (LOWER(given_name) LIKE $` + fmt.Sprint(parameterIndex) + ` OR LOWER(family_name) LIKE $` + fmt.Sprint(parameterIndex) + `)`
-		queryParameters = append(queryParameters, "%"+strings.ToLower(searchParams.Name)+"%")
-		parameterIndex++
-	}
-
-	// Add family name filter
-	if searchParams.FamilyName != "" {
-		baseQuery += ` AND LOWER(family_name) LIKE $` + fmt.Sprint(parameterIndex)
-		queryParameters = append(queryParameters, "%"+strings.ToLower(searchParams.FamilyName)+"%")
-		parameterIndex++
+	// Add name filters; name searches both given_name and family_name. Every criterion must match and a
+	// criterion matches when any of its names does
+	nameFilters := []struct {
+		columns  []string
+		criteria models.NameCriteria
+	}{
+		{[]string{"given_name", "family_name"}, searchParams.Name},
+		{[]string{"family_name"}, searchParams.FamilyName},
+		{[]string{"given_name"}, searchParams.GivenName},
 	}
-
-	// Add given name filter
-	if searchParams.GivenName != "" {
-		baseQuery += ` AND LOWER(given_name) LIKE $` + fmt.Sprint(parameterIndex)
-		queryParameters = append(queryParameters, "%"+strings.ToLower(searchParams.GivenName)+"%")
-		parameterIndex++
+	for _, nameFilter := range nameFilters {
+		for _, criterion := range nameFilter.criteria {
+			if len(criterion.Values) == 0 {
+				continue
+			}
+			nameClause, nameParameters := nameCondition(nameFilter.columns, criterion, nameSimilarity, parameterIndex)
+			conditions += ` AND ` + nameClause
+			queryParameters = append(queryParameters, nameParameters...)
+			parameterIndex += len(nameParameters)
+		}
 	}
 
-	// Add gender filter
-	if searchParams.Gender != "" {
-		baseQuery += ` AND gender = $` + fmt.Sprint(parameterIndex)
-		queryParameters = append(queryParameters, searchParams.Gender)
+	// Add gender filters
+	for _, genderGroup := range searchParams.Gender {
+		conditions += ` AND gender = ANY($` + fmt.Sprint(parameterIndex) + `)`
+		queryParameters = append(queryParameters, pq.Array(genderGroup))
 		parameterIndex++
 	}
 
 	// Add birth date exact filter
 	if searchParams.BirthDate != nil {
-		baseQuery += ` AND birth_date = $` + fmt.Sprint(parameterIndex)
+		conditions += ` AND birth_date = $` + fmt.Sprint(parameterIndex)
 		queryParameters = append(queryParameters, searchParams.BirthDate)
 		parameterIndex++
 	}
 
 	// Add birth date greater than or equal filter
 	if searchParams.BirthDateGreaterThan != nil {
-		baseQuery += ` AND birth_date >= $` + fmt.Sprint(parameterIndex)
+		conditions += ` AND birth_date >= $` + fmt.Sprint(parameterIndex)
 		queryParameters = append(queryParameters, searchParams.BirthDateGreaterThan)
 		parameterIndex++
 	}
 
 	// Add birth date less than or equal filter
 	if searchParams.BirthDateLessThan != nil {
-		baseQuery += ` AND birth_date <= $` + fmt.Sprint(parameterIndex)
+		conditions += ` AND birth_date <= $` + fmt.Sprint(parameterIndex)
 		queryParameters = append(queryParameters, searchParams.BirthDateLessThan)
 		parameterIndex++
 	}
 
 	// Add active status filter
 	if searchParams.Active != nil {
-		baseQuery += ` AND active = $` + fmt.Sprint(parameterIndex)
+		conditions += ` AND active = $` + fmt.Sprint(parameterIndex)
 		queryParameters = append(queryParameters, *searchParams.Active)
 		parameterIndex++
 	}
 
+	// Add identifier filter; site aliases of the system are expanded by the service
+	if searchParams.Identifier != nil {
+		if len(searchParams.Identifier.Systems) > 0 {
+			systemColumn := "identifier_system"
+			for _, system := range searchParams.Identifier.Systems {
+				if system == "" {
+					// "|value" matches identifiers stored without a system
+					systemColumn = "COALESCE(identifier_system, '')"
+				}
+			}
+			conditions += ` AND ` + systemColumn + ` = ANY($` + fmt.Sprint(parameterIndex) + `)`
+			queryParameters = append(queryParameters, pq.Array(searchParams.Identifier.Systems))
+			parameterIndex++
+		}
+		if searchParams.Identifier.Value != "" {
+			conditions += ` AND identifier_value = $` + fmt.Sprint(parameterIndex)
+			queryParameters = append(queryParameters, searchParams.Identifier.Value)
+			parameterIndex++
+		}
+	}
+
+	// Add tag filters; every group must match and a group matches when any of its tags is contained
+	for _, tagGroup := range searchParams.Tags {
+		tagClauses := []string{}
+		for _, criterion := range tagGroup {
+			containedTag := models.Tags{{System: criterion.System, Code: criterion.Code}}
+			if criterion.AnySystem || criterion.System != "" {
+				tagClauses = append(tagClauses, `tags @> $`+fmt.Sprint(parameterIndex)+`::jsonb`)
+				queryParameters = append(queryParameters, containedTag)
+				parameterIndex++
+				continue
+			}
+
+			// "|code" matches only tags without a system; the containment check keeps the GIN index usable
+			tagClauses = append(tagClauses, `(tags @> $`+fmt.Sprint(parameterIndex)+`::jsonb AND EXISTS (SELECT 1 FROM jsonb_array_elements(tags) AS tag WHERE tag->>'code' = $`+fmt.Sprint(parameterIndex+1)+` AND tag->>'system' IS NULL))`)
+			queryParameters = append(queryParameters, containedTag, criterion.Code)
+			parameterIndex += 2
+		}
+		conditions += ` AND (` + strings.Join(tagClauses, ` OR `) + `)`
+	}
+
+	return conditions, queryParameters
+}
+
+// nameCondition builds the clause matching a name criterion in any of the columns, with parameters
+// numbered from firstParameterIndex
+// Each alternative is a separate comparison on LOWER(column), so the trigram indexes serve substring and
+// similarity matches alike
+func nameCondition(columns []string, criterion models.NameCriterion, nameSimilarity float64, firstParameterIndex int) (string, []interface{}) {
+	alternatives := []string{}
+	queryParameters := []interface{}{}
+	nextPlaceholder := func(parameter interface{}) string {
+		queryParameters = append(queryParameters, parameter)
+		return `$` + fmt.Sprint(firstParameterIndex+len(queryParameters)-1)
+	}
+
+	for _, value := range criterion.Values {
+		if criterion.Match == models.NameMatchExact {
+			valuePlaceholder := nextPlaceholder(value)
+			for _, column := range columns {
+				alternatives = append(alternatives, column+` = `+valuePlaceholder)
+			}
+			continue
+		}
+
+		patternPlaceholder := nextPlaceholder("%" + strings.ToLower(value) + "%")
+		for _, column := range columns {
+			alternatives = append(alternatives, `LOWER(`+column+`) LIKE `+patternPlaceholder)
+		}
+		if criterion.Match == models.NameMatchDefault && nameSimilarity > 0 {
+			// % finds candidates through the index; similarity applies the configured threshold
+			valuePlaceholder := nextPlaceholder(strings.ToLower(value))
+			similarityPlaceholder := nextPlaceholder(nameSimilarity)
+			for _, column := range columns {
+				alternatives = append(alternatives, `(LOWER(`+column+`) % `+valuePlaceholder+` AND similarity(LOWER(`+column+`), `+valuePlaceholder+`) >= `+similarityPlaceholder+`)`)
+			}
+		}
+	}
+	return `(` + strings.Join(alternatives, ` OR `) + `)`, queryParameters
+}
+
+// Search retrieves patients matching the search criteria with dynamic filtering
+func (repository *PostgresPatientRepository) Search(ctx context.Context, searchParams *models.PatientSearchParams) ([]*models.Patient, error) {
+	patients := []*models.Patient{}
+	streamError := repository.Stream(ctx, searchParams, func(patient *models.Patient) error {
+		patients = append(patients, patient)
+		return nil
+	})
+	if streamError != nil {
+		return nil, streamError
+	}
+	return patients, nil
+}
+
+// Stream calls visit with each patient Search would return, in order, scanning each row only when the
+// previous patient has been visited; the query's connection is held until the last one
+// An error from visit stops the stream and is returned
+func (repository *PostgresPatientRepository) Stream(ctx context.Context, searchParams *models.PatientSearchParams, visit func(patient *models.Patient) error) error {
+	// Build dynamic query with WHERE clauses based on search parameters
+	baseQuery := `
+		SELECT id, identifier_system, identifier_value, active, family_name, given_name, gender, birth_date, tags, COALESCE(replaced_by::text, ''), created_at, updated_at
+		FROM patients
+		WHERE deleted_at IS NULL
+	`
+
+	// Filter on the search criteria; parameters continue after those the filter bound
+	conditions, queryParameters := patientSearchConditions(searchParams, repository.nameSimilarity)
+	baseQuery += conditions
+	parameterIndex := len(queryParameters) + 1
+
 	// Add sorting
 	sortBy := "created_at"
 	if searchParams.SortBy != "" {
@@ -288,21 +435,34 @@ func (repository *PostgresPatientRepository) Search(ctx context.Context, searchP
 		sortOrder = "ASC"
 	}
 
-	baseQuery += ` ORDER BY ` + sortBy + ` ` + sortOrder
+	// Continue a cursor-paged search after the previous page's last match, in place of an offset
+	offset := searchParams.Offset
+	if searchParams.After != nil && sortBy == "created_at" {
+		comparison := "<"
+		if sortOrder == "ASC" {
+			comparison = ">"
+		}
+		baseQuery += ` AND (created_at, id) ` + comparison + ` ($` + fmt.Sprint(parameterIndex) + `, $` + fmt.Sprint(parameterIndex+1) + `)`
+		queryParameters = append(queryParameters, searchParams.After.CreatedAt, searchParams.After.ID)
+		parameterIndex += 2
+		offset = 0
+	}
+
+	// The ID breaks ties, so patients created at the same time keep one order across pages
+	baseQuery += ` ORDER BY ` + sortBy + ` ` + sortOrder + `, id ` + sortOrder
 
 	// Add pagination
 	baseQuery += ` LIMIT $` + fmt.Sprint(parameterIndex) + ` OFFSET $` + fmt.Sprint(parameterIndex+1)
-	queryParameters = append(queryParameters, searchParams.Limit, searchParams.Offset)
+	queryParameters = append(queryParameters, searchParams.Limit, offset)
 
 	// Execute the query
 	rows, queryError := repository.databaseConnection.QueryContext(ctx, baseQuery, queryParameters...)
 	if queryError != nil {
-		return nil, queryError
+		return queryError
 	}
 	defer rows.Close()
 
-	// Scan results into patient structs
-	patients := []*models.Patient{}
+	// Scan and visit each patient in turn
 	for rows.Next() {
 		patient := &models.Patient{}
 		scanError := rows.Scan(
@@ -314,30 +474,96 @@ func (repository *PostgresPatientRepository) Search(ctx context.Context, searchP
 			&patient.GivenName,
 			&patient.Gender,
 			&patient.BirthDate,
+			&patient.Tags,
+			&patient.ReplacedBy,
 			&patient.CreatedAt,
 			&patient.UpdatedAt,
 		)
 		if scanError != nil {
-			return nil, scanError
+			return scanError
+		}
+		if visitError := visit(patient); visitError != nil {
+			return visitError
 		}
-		patients = append(patients, patient)
 	}
 
 	// Check for errors during iteration
-	if rowsError := rows.Err(); rowsError != nil {
-		return nil, rowsError
-	}
+	return rows.Err()
+}
 
-	return patients, nil
+// Count returns how many patients match the search criteria, ignoring the page's limit and offset
+func (repository *PostgresPatientRepository) Count(ctx context.Context, searchParams *models.PatientSearchParams) (int, error) {
+	conditions, queryParameters := patientSearchConditions(searchParams, repository.nameSimilarity)
+
+	var total int
+	countError := repository.databaseConnection.QueryRowContext(ctx, `SELECT COUNT(*) FROM patients WHERE `+notDeletedCondition+conditions, queryParameters...).Scan(&total)
+	if countError != nil {
+		return 0, countError
+	}
+	return total, nil
 }
 
-// Delete removes a patient record from the database by ID
+// Delete marks a patient deleted by ID, leaving the row for a read of it to answer as deleted
+// Returns sql.ErrNoRows when the patient does not exist, wrapped with ErrResourceDeleted when it was already deleted
 func (repository *PostgresPatientRepository) Delete(ctx context.Context, patientID string) error {
-	// SQL query to delete a patient by ID
-	deleteQuery := `DELETE FROM patients WHERE id = $1`
+	return softDeleteRow(ctx, executorFor(ctx, repository.databaseConnection), "patients", patientID)
+}
+
+// UpdateTags applies change to the patient's tags inside a transaction that locks the row
+// Returns sql.ErrNoRows when the patient does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresPatientRepository) UpdateTags(ctx context.Context, patientID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	var changedTags models.Tags
+	transactionError := runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		executor := executorFor(transactionContext, repository.databaseConnection)
+
+		var currentTags models.Tags
+		selectError := executor.QueryRowContext(transactionContext, `SELECT tags FROM patients WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, patientID).Scan(&currentTags)
+		if errors.Is(selectError, sql.ErrNoRows) {
+			return missingRowError(transactionContext, executor, "patients", patientID)
+		}
+		if selectError != nil {
+			return selectError
+		}
+
+		changedTags = change(currentTags)
+		_, updateError := executor.ExecContext(transactionContext, `UPDATE patients SET tags = $1 WHERE id = $2`, changedTags, patientID)
+		return updateError
+	})
+	if transactionError != nil {
+		return nil, transactionError
+	}
+	return changedTags, nil
+}
+
+// Exists reports whether a patient with the given ID is stored and not deleted, used by integrity reconciliation
+// The ID is compared as text so IDs that are not UUIDs are reported missing rather than failing
+func (repository *PostgresPatientRepository) Exists(ctx context.Context, patientID string) (bool, error) {
+	var exists bool
+	scanError := repository.databaseConnection.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM patients WHERE id::text = $1 AND deleted_at IS NULL)`, patientID).Scan(&exists)
+	return exists, scanError
+}
+
+// ListIDs returns the IDs of every patient that is not deleted, used by integrity reconciliation
+func (repository *PostgresPatientRepository) ListIDs(ctx context.Context) ([]string, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, `SELECT id FROM patients WHERE deleted_at IS NULL`)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	patientIDs := []string{}
+	for rows.Next() {
+		var patientID string
+		if scanError := rows.Scan(&patientID); scanError != nil {
+			return nil, scanError
+		}
+		patientIDs = append(patientIDs, patientID)
+	}
 
-	// Execute the delete query
-	_, execError := repository.databaseConnection.ExecContext(ctx, deleteQuery, patientID)
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
 
-	return execError
+	return patientIDs, nil
 }
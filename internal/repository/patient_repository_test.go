@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"testing"
 	"time"
 
@@ -270,9 +271,43 @@ func TestPostgresPatientRepository_Delete(t *testing.T) {
 		t.Fatalf("Failed to delete patient: %v", deleteError)
 	}
 
-	// Verify patient no longer exists
+	// Verify the patient reads as deleted and is left out of lookups
 	_, getError := patientRepository.GetByID(context.Background(), createdPatient.ID)
-	if getError == nil {
-		t.Error("Expected error when getting deleted patient")
+	if !errors.Is(getError, ErrResourceDeleted) || !errors.Is(getError, sql.ErrNoRows) {
+		t.Errorf("Expected the deleted patient reported as deleted, got %v", getError)
+	}
+	if exists, _ := patientRepository.Exists(context.Background(), createdPatient.ID); exists {
+		t.Error("Expected the deleted patient not to exist")
+	}
+	if count, _ := patientRepository.Count(context.Background(), &models.PatientSearchParams{}); count != 0 {
+		t.Errorf("Expected the deleted patient left out of searches, got %d", count)
+	}
+	if repeatError := patientRepository.Delete(context.Background(), createdPatient.ID); !errors.Is(repeatError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", repeatError)
+	}
+}
+
+// TestPostgresPatientRepository_ListIDs verifies every stored patient ID is returned
+func TestPostgresPatientRepository_ListIDs(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupTestData(t, databaseConnection)
+	defer cleanupTestData(t, databaseConnection)
+
+	repository := NewPostgresPatientRepository(databaseConnection)
+	firstPatient, _ := repository.Create(context.Background(), &models.Patient{FamilyName: "Smith", GivenName: "Ann"})
+	secondPatient, _ := repository.Create(context.Background(), &models.Patient{FamilyName: "Jones", GivenName: "Bob"})
+
+	patientIDs, listError := repository.ListIDs(context.Background())
+	if listError != nil {
+		t.Fatalf("Expected no error, got %v", listError)
+	}
+
+	listed := map[string]bool{}
+	for _, patientID := range patientIDs {
+		listed[patientID] = true
+	}
+	if len(patientIDs) != 2 || !listed[firstPatient.ID] || !listed[secondPatient.ID] {
+		t.Errorf("Expected both patient IDs, got %v", patientIDs)
 	}
 }
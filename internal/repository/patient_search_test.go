@@ -87,7 +87,7 @@ func TestPatientRepository_Search_ByName(t *testing.T) {
 	setupPatientSearchTestData(t, repository)
 
 	searchParams := &models.PatientSearchParams{
-		Name:   "Smith",
+		Name:   models.NameCriteria{{Values: []string{"Smith"}}},
 		Limit:  10,
 		Offset: 0,
 	}
@@ -119,7 +119,7 @@ func TestPatientRepository_Search_ByFamilyName(t *testing.T) {
 	setupPatientSearchTestData(t, repository)
 
 	searchParams := &models.PatientSearchParams{
-		FamilyName: "Johnson",
+		FamilyName: models.NameCriteria{{Values: []string{"Johnson"}}},
 		Limit:      10,
 		Offset:     0,
 	}
@@ -148,7 +148,7 @@ func TestPatientRepository_Search_ByGivenName(t *testing.T) {
 	setupPatientSearchTestData(t, repository)
 
 	searchParams := &models.PatientSearchParams{
-		GivenName: "John",
+		GivenName: models.NameCriteria{{Values: []string{"John"}}},
 		Limit:     10,
 		Offset:    0,
 	}
@@ -177,7 +177,7 @@ func TestPatientRepository_Search_ByGender(t *testing.T) {
 	setupPatientSearchTestData(t, repository)
 
 	searchParams := &models.PatientSearchParams{
-		Gender: "female",
+		Gender: models.ValueCriteria{{"female"}},
 		Limit:  10,
 		Offset: 0,
 	}
@@ -371,7 +371,7 @@ func TestPatientRepository_Search_CombinedFilters(t *testing.T) {
 	activeTrue := true
 	cutoffDate := parseTestDateValue("1990-01-01")
 	searchParams := &models.PatientSearchParams{
-		Gender:               "female",
+		Gender:               models.ValueCriteria{{"female"}},
 		Active:               &activeTrue,
 		BirthDateGreaterThan: &cutoffDate,
 		Limit:                10,
@@ -516,6 +516,40 @@ func TestPatientRepository_Search_Pagination(t *testing.T) {
 	}
 }
 
+// TestPatientRepository_Search_Cursor verifies following cursors visits every patient once in creation order
+func TestPatientRepository_Search_Cursor(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	repository := NewPostgresPatientRepository(testDB)
+	defer cleanupTestData(t, testDB)
+
+	setupPatientSearchTestData(t, repository)
+	total, _ := repository.Count(context.Background(), &models.PatientSearchParams{})
+
+	seenIDs := map[string]bool{}
+	var after *models.PageCursor
+	for pageNumber := 0; pageNumber <= total; pageNumber++ {
+		page, searchError := repository.Search(context.Background(), &models.PatientSearchParams{Limit: 2, CursorPaging: true, After: after})
+		if searchError != nil {
+			t.Fatalf("Expected no error, got %v", searchError)
+		}
+		if len(page) == 0 {
+			break
+		}
+		for _, patient := range page {
+			if seenIDs[patient.ID] {
+				t.Fatalf("Expected each patient once, got %s again", patient.ID)
+			}
+			seenIDs[patient.ID] = true
+		}
+		lastPatient := page[len(page)-1]
+		after = &models.PageCursor{CreatedAt: lastPatient.CreatedAt, ID: lastPatient.ID}
+	}
+
+	if len(seenIDs) != total {
+		t.Errorf("Expected %d patients across the pages, got %d", total, len(seenIDs))
+	}
+}
+
 // TestPatientRepository_Search_EmptyResults tests search with no matches
 func TestPatientRepository_Search_EmptyResults(t *testing.T) {
 	testDB := setupTestDatabase(t)
@@ -525,7 +559,7 @@ func TestPatientRepository_Search_EmptyResults(t *testing.T) {
 	setupPatientSearchTestData(t, repository)
 
 	searchParams := &models.PatientSearchParams{
-		Name:   "NonexistentName",
+		Name:   models.NameCriteria{{Values: []string{"NonexistentName"}}},
 		Limit:  10,
 		Offset: 0,
 	}
@@ -550,7 +584,7 @@ func TestPatientRepository_Search_CaseInsensitive(t *testing.T) {
 	setupPatientSearchTestData(t, repository)
 
 	searchParams := &models.PatientSearchParams{
-		Name:   "smith", // lowercase
+		Name:   models.NameCriteria{{Values: []string{"smith"}}}, // lowercase
 		Limit:  10,
 		Offset: 0,
 	}
@@ -575,7 +609,7 @@ func TestPatientRepository_Search_PartialMatch(t *testing.T) {
 	setupPatientSearchTestData(t, repository)
 
 	searchParams := &models.PatientSearchParams{
-		Name:   "Smi", // partial match
+		Name:   models.NameCriteria{{Values: []string{"Smi"}}}, // partial match
 		Limit:  10,
 		Offset: 0,
 	}
@@ -615,3 +649,159 @@ func TestPatientRepository_Search_DefaultsWithNoFilters(t *testing.T) {
 		t.Errorf("Expected all 5 patients, got %d", len(results))
 	}
 }
+
+// TestPatientRepository_Search_ByTag tests tagging patients and searching by _tag
+func TestPatientRepository_Search_ByTag(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	repository := NewPostgresPatientRepository(testDB)
+	defer cleanupTestData(t, testDB)
+
+	setupPatientSearchTestData(t, repository)
+
+	allPatients, _ := repository.GetAll(context.Background(), 10, 0)
+	reviewTag := models.Tag{System: "http://example.org/workflow", Code: "needs-review"}
+	addReview := func(currentTags models.Tags) models.Tags { return currentTags.Add(reviewTag) }
+	for _, patient := range allPatients[:2] {
+		if _, tagError := repository.UpdateTags(context.Background(), patient.ID, addReview); tagError != nil {
+			t.Fatalf("Failed to tag patient: %v", tagError)
+		}
+	}
+
+	searchParams := &models.PatientSearchParams{
+		Tags:   models.TagCriteria{{{System: "http://example.org/workflow", Code: "needs-review"}}},
+		Limit:  10,
+		Offset: 0,
+	}
+
+	results, searchError := repository.Search(context.Background(), searchParams)
+
+	if searchError != nil {
+		t.Fatalf("Expected no error, got %v", searchError)
+	}
+
+	if len(results) != 2 {
+		t.Errorf("Expected 2 tagged patients, got %d", len(results))
+	}
+
+	for _, patient := range results {
+		if len(patient.Tags) != 1 || patient.Tags[0] != reviewTag {
+			t.Errorf("Expected needs-review tag, got %+v", patient.Tags)
+		}
+	}
+
+	anySystemResults, _ := repository.Search(context.Background(), &models.PatientSearchParams{
+		Tags:  models.TagCriteria{{{Code: "needs-review", AnySystem: true}}},
+		Limit: 10,
+	})
+	noSystemResults, _ := repository.Search(context.Background(), &models.PatientSearchParams{
+		Tags:  models.TagCriteria{{{Code: "needs-review"}}},
+		Limit: 10,
+	})
+	if len(anySystemResults) != 2 || len(noSystemResults) != 0 {
+		t.Errorf("Expected 2 patients for a bare code and none for |needs-review, got %d and %d", len(anySystemResults), len(noSystemResults))
+	}
+}
+
+// TestPatientRepository_Search_ByIdentifier tests filtering by identifier system and value
+func TestPatientRepository_Search_ByIdentifier(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	repository := NewPostgresPatientRepository(testDB)
+	defer cleanupTestData(t, testDB)
+
+	setupPatientSearchTestData(t, repository)
+	repository.Create(context.Background(), &models.Patient{IdentifierSystem: "MRN", IdentifierValue: "P006", Active: true, FamilyName: "Legacy"})
+
+	results, searchError := repository.Search(context.Background(), &models.PatientSearchParams{
+		Identifier: &models.IdentifierCriterion{Systems: []string{"http://hospital.com", "MRN"}, Value: "P006"},
+		Limit:      10,
+	})
+	if searchError != nil {
+		t.Fatalf("Expected no error, got %v", searchError)
+	}
+	if len(results) != 1 || results[0].FamilyName != "Legacy" {
+		t.Errorf("Expected the patient stored under the alias system, got %d results", len(results))
+	}
+
+	results, _ = repository.Search(context.Background(), &models.PatientSearchParams{
+		Identifier: &models.IdentifierCriterion{Systems: []string{"http://hospital.com"}},
+		Limit:      10,
+	})
+	if len(results) != 5 {
+		t.Errorf("Expected 5 patients in the system, got %d", len(results))
+	}
+
+	results, _ = repository.Search(context.Background(), &models.PatientSearchParams{
+		Identifier: &models.IdentifierCriterion{Value: "P002"},
+		Limit:      10,
+	})
+	if len(results) != 1 || results[0].GivenName != "Jane" {
+		t.Errorf("Expected one patient with value P002, got %d", len(results))
+	}
+}
+
+// TestPatientRepository_Search_MultipleValues verifies comma-separated values match any and repeated parameters must all match
+func TestPatientRepository_Search_MultipleValues(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	repository := NewPostgresPatientRepository(testDB)
+	defer cleanupTestData(t, testDB)
+
+	setupPatientSearchTestData(t, repository)
+
+	anyFamilyResults, searchError := repository.Search(context.Background(), &models.PatientSearchParams{
+		FamilyName: models.NameCriteria{{Values: []string{"Smith", "Brown"}}},
+		Limit:      10,
+	})
+	if searchError != nil {
+		t.Fatalf("Expected no error, got %v", searchError)
+	}
+	if len(anyFamilyResults) != 3 {
+		t.Errorf("Expected 3 patients named Smith or Brown, got %d", len(anyFamilyResults))
+	}
+
+	everyNameResults, searchError := repository.Search(context.Background(), &models.PatientSearchParams{
+		Name:   models.NameCriteria{{Values: []string{"Smith"}}, {Values: []string{"Jane"}}},
+		Gender: models.ValueCriteria{{"male", "female"}},
+		Limit:  10,
+	})
+	if searchError != nil {
+		t.Fatalf("Expected no error, got %v", searchError)
+	}
+	if len(everyNameResults) != 1 || everyNameResults[0].GivenName != "Jane" {
+		t.Errorf("Expected only Jane Smith, got %d patients", len(everyNameResults))
+	}
+}
+
+// TestPatientRepository_Search_NameMatching verifies :exact matching and fuzzy matching of misspelled names
+func TestPatientRepository_Search_NameMatching(t *testing.T) {
+	testDB := setupTestDatabase(t)
+	repository := NewPostgresPatientRepository(testDB)
+	defer cleanupTestData(t, testDB)
+
+	setupPatientSearchTestData(t, repository)
+
+	countPatients := func(familyName models.NameCriterion) int {
+		total, countError := repository.Count(context.Background(), &models.PatientSearchParams{FamilyName: models.NameCriteria{familyName}})
+		if countError != nil {
+			t.Fatalf("Expected no error, got %v", countError)
+		}
+		return total
+	}
+
+	if total := countPatients(models.NameCriterion{Values: []string{"Smith"}, Match: models.NameMatchExact}); total != 2 {
+		t.Errorf("Expected 2 patients named exactly Smith, got %d", total)
+	}
+	if total := countPatients(models.NameCriterion{Values: []string{"smith"}, Match: models.NameMatchExact}); total != 0 {
+		t.Errorf("Expected :exact to match case, got %d", total)
+	}
+	if total := countPatients(models.NameCriterion{Values: []string{"Smth"}}); total != 0 {
+		t.Errorf("Expected no fuzzy matches while fuzzy search is off, got %d", total)
+	}
+
+	repository.SetNameSimilarity(0.3)
+	if total := countPatients(models.NameCriterion{Values: []string{"Smth"}}); total != 2 {
+		t.Errorf("Expected the misspelling to match both Smiths, got %d", total)
+	}
+	if total := countPatients(models.NameCriterion{Values: []string{"Smth"}, Match: models.NameMatchContains}); total != 0 {
+		t.Errorf("Expected :contains to stay a substring match, got %d", total)
+	}
+}
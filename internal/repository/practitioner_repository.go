@@ -0,0 +1,281 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// PractitionerRepository defines the interface for practitioner data operations
+type PractitionerRepository interface {
+	// Create inserts a new practitioner record and returns the created practitioner with ID
+	Create(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error)
+
+	// GetByID retrieves a practitioner by their unique identifier
+	GetByID(ctx context.Context, practitionerID string) (*models.Practitioner, error)
+
+	// Search retrieves practitioners matching the search criteria
+	Search(ctx context.Context, searchParams *models.PractitionerSearchParams) ([]*models.Practitioner, error)
+
+	// Count returns how many practitioners match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.PractitionerSearchParams) (int, error)
+
+	// Update modifies an existing practitioner record
+	Update(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error)
+
+	// Delete marks a practitioner deleted by ID
+	Delete(ctx context.Context, practitionerID string) error
+}
+
+// practitionerColumns are the columns read into a models.Practitioner by scanPractitioner, in order
+const practitionerColumns = `id, identifier_system, identifier_value, active, family_name, given_name, gender,
+		specialty_system, specialty_code, specialty_display, created_at, updated_at`
+
+// PostgresPractitionerRepository implements PractitionerRepository using PostgreSQL
+type PostgresPractitionerRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresPractitionerRepository creates a new PostgreSQL practitioner repository instance
+func NewPostgresPractitionerRepository(databaseConnection *sql.DB) *PostgresPractitionerRepository {
+	return &PostgresPractitionerRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// scanPractitioner reads one row selected with practitionerColumns
+// Optional text columns are NULL for rows written outside the service, so they are read through NullString
+func scanPractitioner(row interface{ Scan(...interface{}) error }) (*models.Practitioner, error) {
+	practitioner := &models.Practitioner{}
+	var identifierSystem, identifierValue, gender, specialtySystem, specialtyCode, specialtyDisplay sql.NullString
+	scanError := row.Scan(
+		&practitioner.ID,
+		&identifierSystem,
+		&identifierValue,
+		&practitioner.Active,
+		&practitioner.FamilyName,
+		&practitioner.GivenName,
+		&gender,
+		&specialtySystem,
+		&specialtyCode,
+		&specialtyDisplay,
+		&practitioner.CreatedAt,
+		&practitioner.UpdatedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	practitioner.IdentifierSystem = identifierSystem.String
+	practitioner.IdentifierValue = identifierValue.String
+	practitioner.Gender = gender.String
+	practitioner.SpecialtySystem = specialtySystem.String
+	practitioner.SpecialtyCode = specialtyCode.String
+	practitioner.SpecialtyDisplay = specialtyDisplay.String
+	return practitioner, nil
+}
+
+// Create inserts a new practitioner record into the database
+func (repository *PostgresPractitionerRepository) Create(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error) {
+	insertQuery := `
+		INSERT INTO practitioners (identifier_system, identifier_value, active, family_name, given_name, gender,
+			specialty_system, specialty_code, specialty_display)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		insertQuery,
+		practitioner.IdentifierSystem,
+		practitioner.IdentifierValue,
+		practitioner.Active,
+		practitioner.FamilyName,
+		practitioner.GivenName,
+		practitioner.Gender,
+		practitioner.SpecialtySystem,
+		practitioner.SpecialtyCode,
+		practitioner.SpecialtyDisplay,
+	).Scan(&practitioner.ID, &practitioner.CreatedAt, &practitioner.UpdatedAt)
+
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return practitioner, nil
+}
+
+// GetByID retrieves a practitioner by their unique identifier
+// Returns sql.ErrNoRows when the practitioner does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresPractitionerRepository) GetByID(ctx context.Context, practitionerID string) (*models.Practitioner, error) {
+	executor := executorFor(ctx, repository.databaseConnection)
+	selectQuery := `SELECT ` + practitionerColumns + ` FROM practitioners WHERE id = $1 AND ` + notDeletedCondition
+	practitioner, scanError := scanPractitioner(executor.QueryRowContext(ctx, selectQuery, practitionerID))
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executor, "practitioners", practitionerID)
+	}
+	return practitioner, scanError
+}
+
+// Update modifies an existing practitioner record in the database
+// Returns sql.ErrNoRows when the practitioner does not exist, wrapped with ErrResourceDeleted when it was deleted
+func (repository *PostgresPractitionerRepository) Update(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error) {
+	updateQuery := `
+		UPDATE practitioners
+		SET identifier_system = $1, identifier_value = $2, active = $3, family_name = $4, given_name = $5, gender = $6,
+			specialty_system = $7, specialty_code = $8, specialty_display = $9, updated_at = $10
+		WHERE id = $11 AND deleted_at IS NULL
+		RETURNING created_at, updated_at
+	`
+
+	// Set the updated timestamp
+	practitioner.UpdatedAt = time.Now()
+
+	scanError := executorFor(ctx, repository.databaseConnection).QueryRowContext(
+		ctx,
+		updateQuery,
+		practitioner.IdentifierSystem,
+		practitioner.IdentifierValue,
+		practitioner.Active,
+		practitioner.FamilyName,
+		practitioner.GivenName,
+		practitioner.Gender,
+		practitioner.SpecialtySystem,
+		practitioner.SpecialtyCode,
+		practitioner.SpecialtyDisplay,
+		practitioner.UpdatedAt,
+		practitioner.ID,
+	).Scan(&practitioner.CreatedAt, &practitioner.UpdatedAt)
+
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, missingRowError(ctx, executorFor(ctx, repository.databaseConnection), "practitioners", practitioner.ID)
+	}
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return practitioner, nil
+}
+
+// practitionerSearchConditions builds the AND clauses that filter practitioners on the search criteria, shared
+// by Search and Count so a page and its total always agree; the returned parameters are numbered from $1
+func practitionerSearchConditions(searchParams *models.PractitionerSearchParams) (string, []interface{}) {
+	conditions := ""
+	queryParameters := []interface{}{}
+	parameterIndex := 1
+
+	// Add name filter (searches both given_name and family_name)
+	if searchParams.Name != "" {
+		conditions += ` AND (LOWER(given_name) LIKE $` + fmt.Sprint(parameterIndex) + ` OR LOWER(family_name) LIKE $` + fmt.Sprint(parameterIndex) + `)`
+		queryParameters = append(queryParameters, "%"+strings.ToLower(searchParams.Name)+"%")
+		parameterIndex++
+	}
+
+	// Add family name filter
+	if searchParams.FamilyName != "" {
+		conditions += ` AND LOWER(family_name) LIKE $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, "%"+strings.ToLower(searchParams.FamilyName)+"%")
+		parameterIndex++
+	}
+
+	// Add given name filter
+	if searchParams.GivenName != "" {
+		conditions += ` AND LOWER(given_name) LIKE $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, "%"+strings.ToLower(searchParams.GivenName)+"%")
+		parameterIndex++
+	}
+
+	// Add active status filter
+	if searchParams.Active != nil {
+		conditions += ` AND active = $` + fmt.Sprint(parameterIndex)
+		queryParameters = append(queryParameters, *searchParams.Active)
+		parameterIndex++
+	}
+
+	// Add identifier filter
+	if searchParams.Identifier != nil {
+		if len(searchParams.Identifier.Systems) > 0 {
+			conditions += ` AND COALESCE(identifier_system, '') = ANY($` + fmt.Sprint(parameterIndex) + `)`
+			queryParameters = append(queryParameters, pq.Array(searchParams.Identifier.Systems))
+			parameterIndex++
+		}
+		if searchParams.Identifier.Value != "" {
+			conditions += ` AND identifier_value = $` + fmt.Sprint(parameterIndex)
+			queryParameters = append(queryParameters, searchParams.Identifier.Value)
+			parameterIndex++
+		}
+	}
+
+	// Add specialty filter; "|code" matches specialties stored without a system
+	if searchParams.Specialty != nil {
+		if !searchParams.Specialty.AnySystem {
+			conditions += ` AND COALESCE(specialty_system, '') = $` + fmt.Sprint(parameterIndex)
+			queryParameters = append(queryParameters, searchParams.Specialty.System)
+			parameterIndex++
+		}
+		if searchParams.Specialty.Code != "" {
+			conditions += ` AND specialty_code = $` + fmt.Sprint(parameterIndex)
+			queryParameters = append(queryParameters, searchParams.Specialty.Code)
+			parameterIndex++
+		}
+	}
+
+	return conditions, queryParameters
+}
+
+// Search retrieves practitioners matching the search criteria, newest first
+func (repository *PostgresPractitionerRepository) Search(ctx context.Context, searchParams *models.PractitionerSearchParams) ([]*models.Practitioner, error) {
+	conditions, queryParameters := practitionerSearchConditions(searchParams)
+	parameterIndex := len(queryParameters) + 1
+
+	searchQuery := `SELECT ` + practitionerColumns + ` FROM practitioners WHERE ` + notDeletedCondition + conditions +
+		` ORDER BY created_at DESC, id LIMIT $` + fmt.Sprint(parameterIndex) + ` OFFSET $` + fmt.Sprint(parameterIndex+1)
+	queryParameters = append(queryParameters, searchParams.Limit, searchParams.Offset)
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, searchQuery, queryParameters...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	practitioners := []*models.Practitioner{}
+	for rows.Next() {
+		practitioner, scanError := scanPractitioner(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		practitioners = append(practitioners, practitioner)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return practitioners, nil
+}
+
+// Count returns how many practitioners match the search criteria, ignoring the page's limit and offset
+func (repository *PostgresPractitionerRepository) Count(ctx context.Context, searchParams *models.PractitionerSearchParams) (int, error) {
+	conditions, queryParameters := practitionerSearchConditions(searchParams)
+
+	var total int
+	countError := repository.databaseConnection.QueryRowContext(ctx, `SELECT COUNT(*) FROM practitioners WHERE `+notDeletedCondition+conditions, queryParameters...).Scan(&total)
+	if countError != nil {
+		return 0, countError
+	}
+	return total, nil
+}
+
+// Delete marks a practitioner deleted by ID, leaving the row for a read of it to answer as deleted
+// Returns sql.ErrNoRows when the practitioner does not exist, wrapped with ErrResourceDeleted when it was already deleted
+func (repository *PostgresPractitionerRepository) Delete(ctx context.Context, practitionerID string) error {
+	return softDeleteRow(ctx, executorFor(ctx, repository.databaseConnection), "practitioners", practitionerID)
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupPractitioners removes all test data from the practitioners table
+func cleanupPractitioners(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM practitioners"); deleteError != nil {
+		t.Fatalf("Failed to cleanup practitioners: %v", deleteError)
+	}
+}
+
+// TestPostgresPractitionerRepository_CRUD verifies a practitioner is created, read, updated, and deleted
+func TestPostgresPractitionerRepository_CRUD(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupPractitioners(t, databaseConnection)
+	defer cleanupPractitioners(t, databaseConnection)
+
+	practitionerRepository := NewPostgresPractitionerRepository(databaseConnection)
+	ctx := context.Background()
+
+	createdPractitioner, createError := practitionerRepository.Create(ctx, &models.Practitioner{
+		IdentifierSystem: "http://hl7.org/fhir/sid/us-npi",
+		IdentifierValue:  "1234567893",
+		Active:           true,
+		FamilyName:       "House",
+		GivenName:        "Gregory",
+		SpecialtySystem:  "http://nucc.org/provider-taxonomy",
+		SpecialtyCode:    "207RI0200X",
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create practitioner: %v", createError)
+	}
+	if createdPractitioner.ID == "" || createdPractitioner.CreatedAt.IsZero() {
+		t.Fatalf("Expected a generated ID and timestamps, got %+v", createdPractitioner)
+	}
+
+	createdPractitioner.SpecialtyCode = "207RC0000X"
+	if _, updateError := practitionerRepository.Update(ctx, createdPractitioner); updateError != nil {
+		t.Fatalf("Failed to update practitioner: %v", updateError)
+	}
+
+	storedPractitioner, getError := practitionerRepository.GetByID(ctx, createdPractitioner.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read practitioner: %v", getError)
+	}
+	if storedPractitioner.FamilyName != "House" || storedPractitioner.SpecialtyCode != "207RC0000X" {
+		t.Errorf("Expected the updated specialty, got %+v", storedPractitioner)
+	}
+
+	if deleteError := practitionerRepository.Delete(ctx, createdPractitioner.ID); deleteError != nil {
+		t.Fatalf("Failed to delete practitioner: %v", deleteError)
+	}
+	if _, getError := practitionerRepository.GetByID(ctx, createdPractitioner.ID); !errors.Is(getError, ErrResourceDeleted) || !errors.Is(getError, sql.ErrNoRows) {
+		t.Errorf("Expected the deleted practitioner reported as deleted, got %v", getError)
+	}
+	if _, updateError := practitionerRepository.Update(ctx, createdPractitioner); !errors.Is(updateError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted updating the deleted practitioner, got %v", updateError)
+	}
+	if deleteError := practitionerRepository.Delete(ctx, createdPractitioner.ID); !errors.Is(deleteError, sql.ErrNoRows) || !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected sql.ErrNoRows and ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+	if count, _ := practitionerRepository.Count(ctx, &models.PractitionerSearchParams{}); count != 0 {
+		t.Errorf("Expected the deleted practitioner left out of searches, got %d", count)
+	}
+	if _, getError := practitionerRepository.GetByID(ctx, "6f1c2a3e-8d4b-4c5a-9e7f-0a1b2c3d4e5f"); errors.Is(getError, ErrResourceDeleted) || !errors.Is(getError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows alone for an unknown practitioner, got %v", getError)
+	}
+}
+
+// TestPostgresPractitionerRepository_Search verifies name, identifier, and specialty filters and the total
+func TestPostgresPractitionerRepository_Search(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupPractitioners(t, databaseConnection)
+	defer cleanupPractitioners(t, databaseConnection)
+
+	practitionerRepository := NewPostgresPractitionerRepository(databaseConnection)
+	ctx := context.Background()
+	for _, practitioner := range []*models.Practitioner{
+		{FamilyName: "House", GivenName: "Gregory", Active: true, IdentifierSystem: "http://hl7.org/fhir/sid/us-npi", IdentifierValue: "1234567893", SpecialtySystem: "http://nucc.org/provider-taxonomy", SpecialtyCode: "207RI0200X"},
+		{FamilyName: "Wilson", GivenName: "James", Active: true, SpecialtySystem: "http://nucc.org/provider-taxonomy", SpecialtyCode: "207RX0202X"},
+		{FamilyName: "Cuddy", GivenName: "Lisa", Active: true, SpecialtyCode: "207RI0200X"},
+	} {
+		if _, createError := practitionerRepository.Create(ctx, practitioner); createError != nil {
+			t.Fatalf("Failed to create practitioner: %v", createError)
+		}
+	}
+
+	testCases := map[string]struct {
+		searchParams  models.PractitionerSearchParams
+		expectedCount int
+	}{
+		"name":               {searchParams: models.PractitionerSearchParams{Name: "jam"}, expectedCount: 1},
+		"identifier":         {searchParams: models.PractitionerSearchParams{Identifier: &models.IdentifierCriterion{Systems: []string{"http://hl7.org/fhir/sid/us-npi"}, Value: "1234567893"}}, expectedCount: 1},
+		"specialty any":      {searchParams: models.PractitionerSearchParams{Specialty: &models.CodingCriterion{Code: "207RI0200X", AnySystem: true}}, expectedCount: 2},
+		"specialty system":   {searchParams: models.PractitionerSearchParams{Specialty: &models.CodingCriterion{System: "http://nucc.org/provider-taxonomy", Code: "207RI0200X"}}, expectedCount: 1},
+		"specialty nosystem": {searchParams: models.PractitionerSearchParams{Specialty: &models.CodingCriterion{Code: "207RI0200X"}}, expectedCount: 1},
+	}
+	for name, testCase := range testCases {
+		testCase.searchParams.Limit = 10
+		practitioners, searchError := practitionerRepository.Search(ctx, &testCase.searchParams)
+		if searchError != nil {
+			t.Fatalf("%s: search failed: %v", name, searchError)
+		}
+		total, countError := practitionerRepository.Count(ctx, &testCase.searchParams)
+		if countError != nil {
+			t.Fatalf("%s: count failed: %v", name, countError)
+		}
+		if len(practitioners) != testCase.expectedCount || total != testCase.expectedCount {
+			t.Errorf("%s: expected %d practitioners, got %d with total %d", name, testCase.expectedCount, len(practitioners), total)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// QuotaUsageRepository defines the interface for tenant quota counters and per-resource quota usage
+type QuotaUsageRepository interface {
+	// Reserve applies change to the tenant's counters when the counters it grows stay within its limits,
+	// returning the counters after it, or the current counters and false when a limit would be exceeded
+	Reserve(ctx context.Context, change models.UsageChange) (*models.TenantUsage, bool, error)
+
+	// Release reverses a change reserved earlier; the observation count is only returned on the same day
+	Release(ctx context.Context, change models.UsageChange) error
+
+	// PayloadBytes returns the stored size of a resource, and false when the resource is not tracked
+	PayloadBytes(ctx context.Context, resourceType string, resourceID string) (int64, bool, error)
+
+	// Commit records the usage held by a written resource, charging an existing resource's tenant its
+	// actual growth in place of the reserved storage
+	Commit(ctx context.Context, usage *models.ResourceUsage, reserved models.UsageChange) error
+
+	// Delete removes a resource's usage and credits its tenant
+	Delete(ctx context.Context, resourceType string, resourceID string) error
+
+	// TenantUsage returns a tenant's counters, zero when it has stored nothing
+	TenantUsage(ctx context.Context, tenantID string) (*models.TenantUsage, error)
+
+	// ListTenantUsage returns the counters of every tenant that has stored resources
+	ListTenantUsage(ctx context.Context) ([]*models.TenantUsage, error)
+}
+
+// PostgresQuotaUsageRepository implements QuotaUsageRepository using PostgreSQL
+type PostgresQuotaUsageRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresQuotaUsageRepository creates a new PostgreSQL quota usage repository instance
+func NewPostgresQuotaUsageRepository(databaseConnection *sql.DB) *PostgresQuotaUsageRepository {
+	return &PostgresQuotaUsageRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// tenantUsageColumns lists the columns scanned by scanTenantUsage
+const tenantUsageColumns = `tenant_id, patients, storage_bytes, observations_today, observation_day::text`
+
+// Reserve checks and applies the change in one conditional UPDATE, so concurrent writers on any instance
+// cannot together pass a limit: each one re-checks the limits against the row the previous one left
+func (repository *PostgresQuotaUsageRepository) Reserve(ctx context.Context, change models.UsageChange) (*models.TenantUsage, bool, error) {
+	if _, insertError := repository.databaseConnection.ExecContext(ctx, `
+		INSERT INTO tenant_quota_usage (tenant_id, observation_day)
+		VALUES ($1, $2::date)
+		ON CONFLICT (tenant_id) DO NOTHING`, change.TenantID, change.Day); insertError != nil {
+		return nil, false, insertError
+	}
+
+	reserveQuery := `
+		UPDATE tenant_quota_usage
+		SET patients = patients + $2,
+			observations_today = (CASE WHEN observation_day = $5::date THEN observations_today ELSE 0 END) + $3,
+			observation_day = $5::date,
+			storage_bytes = storage_bytes + $4,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $1
+			AND ($2 <= 0 OR $6 = 0 OR patients + $2 <= $6)
+			AND ($3 <= 0 OR $7 = 0 OR (CASE WHEN observation_day = $5::date THEN observations_today ELSE 0 END) + $3 <= $7)
+			AND ($4 <= 0 OR $8 = 0 OR storage_bytes + $4 <= $8)
+		RETURNING ` + tenantUsageColumns
+
+	usage, reserveError := scanTenantUsage(repository.databaseConnection.QueryRowContext(ctx, reserveQuery,
+		change.TenantID, change.Patients, change.Observations, change.StorageBytes, change.Day,
+		change.MaxPatients, change.MaxObservationsPerDay, change.MaxStorageBytes))
+	if errors.Is(reserveError, sql.ErrNoRows) {
+		currentUsage, currentError := repository.TenantUsage(ctx, change.TenantID)
+		return currentUsage, false, currentError
+	}
+	if reserveError != nil {
+		return nil, false, reserveError
+	}
+	return usage, true, nil
+}
+
+// Release subtracts the change, never taking a counter below zero
+func (repository *PostgresQuotaUsageRepository) Release(ctx context.Context, change models.UsageChange) error {
+	_, execError := repository.databaseConnection.ExecContext(ctx, `
+		UPDATE tenant_quota_usage
+		SET patients = GREATEST(patients - $2, 0),
+			observations_today = CASE WHEN observation_day = $5::date THEN GREATEST(observations_today - $3, 0) ELSE observations_today END,
+			storage_bytes = GREATEST(storage_bytes - $4, 0),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE tenant_id = $1`, change.TenantID, change.Patients, change.Observations, change.StorageBytes, change.Day)
+	return execError
+}
+
+// PayloadBytes reads a resource's row of tenant_resource_usage
+func (repository *PostgresQuotaUsageRepository) PayloadBytes(ctx context.Context, resourceType string, resourceID string) (int64, bool, error) {
+	var payloadBytes int64
+	scanError := repository.databaseConnection.QueryRowContext(ctx, `
+		SELECT payload_bytes FROM tenant_resource_usage WHERE resource_type = $1 AND resource_id = $2`,
+		resourceType, resourceID).Scan(&payloadBytes)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return 0, false, nil
+	}
+	if scanError != nil {
+		return 0, false, scanError
+	}
+	return payloadBytes, true, nil
+}
+
+// Commit inserts a new resource's row, or replaces an existing resource's size and moves the reserved
+// storage to the growth it actually had, in one transaction
+func (repository *PostgresQuotaUsageRepository) Commit(ctx context.Context, usage *models.ResourceUsage, reserved models.UsageChange) error {
+	return runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		transaction := executorFor(transactionContext, repository.databaseConnection)
+
+		var ownerTenantID string
+		var storedBytes int64
+		scanError := transaction.QueryRowContext(transactionContext, `
+			SELECT tenant_id, payload_bytes FROM tenant_resource_usage
+			WHERE resource_type = $1 AND resource_id = $2
+			FOR UPDATE`, usage.ResourceType, usage.ResourceID).Scan(&ownerTenantID, &storedBytes)
+		if errors.Is(scanError, sql.ErrNoRows) {
+			_, insertError := transaction.ExecContext(transactionContext, `
+				INSERT INTO tenant_resource_usage (resource_type, resource_id, tenant_id, payload_bytes, created_at)
+				VALUES ($1, $2, $3, $4, $5)`, usage.ResourceType, usage.ResourceID, usage.TenantID, usage.PayloadBytes, usage.CreatedAt)
+			return insertError
+		}
+		if scanError != nil {
+			return scanError
+		}
+
+		// A concurrent update may have changed the stored size since the reservation was made
+		if _, releaseError := transaction.ExecContext(transactionContext, `
+			UPDATE tenant_quota_usage SET storage_bytes = storage_bytes - $2, updated_at = CURRENT_TIMESTAMP
+			WHERE tenant_id = $1`, reserved.TenantID, reserved.StorageBytes); releaseError != nil {
+			return releaseError
+		}
+		if _, chargeError := transaction.ExecContext(transactionContext, `
+			UPDATE tenant_quota_usage SET storage_bytes = storage_bytes + $2, updated_at = CURRENT_TIMESTAMP
+			WHERE tenant_id = $1`, ownerTenantID, usage.PayloadBytes-storedBytes); chargeError != nil {
+			return chargeError
+		}
+		_, updateError := transaction.ExecContext(transactionContext, `
+			UPDATE tenant_resource_usage SET payload_bytes = $3
+			WHERE resource_type = $1 AND resource_id = $2`, usage.ResourceType, usage.ResourceID, usage.PayloadBytes)
+		return updateError
+	})
+}
+
+// Delete removes a resource's row and takes its count and size off its tenant's counters in one transaction
+// An observation is only taken off the daily count on the UTC day it was created
+func (repository *PostgresQuotaUsageRepository) Delete(ctx context.Context, resourceType string, resourceID string) error {
+	return runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		transaction := executorFor(transactionContext, repository.databaseConnection)
+
+		var deleted models.ResourceUsage
+		scanError := transaction.QueryRowContext(transactionContext, `
+			DELETE FROM tenant_resource_usage
+			WHERE resource_type = $1 AND resource_id = $2
+			RETURNING tenant_id, payload_bytes, created_at`, resourceType, resourceID).Scan(&deleted.TenantID, &deleted.PayloadBytes, &deleted.CreatedAt)
+		if errors.Is(scanError, sql.ErrNoRows) {
+			return nil
+		}
+		if scanError != nil {
+			return scanError
+		}
+
+		_, creditError := transaction.ExecContext(transactionContext, `
+			UPDATE tenant_quota_usage
+			SET storage_bytes = GREATEST(storage_bytes - $2, 0),
+				patients = CASE WHEN $3 = 'Patient' THEN GREATEST(patients - 1, 0) ELSE patients END,
+				observations_today = CASE WHEN $3 = 'Observation' AND observation_day = $4::date
+					THEN GREATEST(observations_today - 1, 0) ELSE observations_today END,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE tenant_id = $1`, deleted.TenantID, deleted.PayloadBytes, resourceType, deleted.CreatedAt.UTC().Format("2006-01-02"))
+		return creditError
+	})
+}
+
+// TenantUsage reads a tenant's row of tenant_quota_usage
+func (repository *PostgresQuotaUsageRepository) TenantUsage(ctx context.Context, tenantID string) (*models.TenantUsage, error) {
+	usage, scanError := scanTenantUsage(repository.databaseConnection.QueryRowContext(ctx,
+		"SELECT "+tenantUsageColumns+" FROM tenant_quota_usage WHERE tenant_id = $1", tenantID))
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return &models.TenantUsage{TenantID: tenantID}, nil
+	}
+	return usage, scanError
+}
+
+// ListTenantUsage reads every row of tenant_quota_usage
+func (repository *PostgresQuotaUsageRepository) ListTenantUsage(ctx context.Context) ([]*models.TenantUsage, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, "SELECT "+tenantUsageColumns+" FROM tenant_quota_usage ORDER BY tenant_id")
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	usageList := []*models.TenantUsage{}
+	for rows.Next() {
+		usage := &models.TenantUsage{}
+		if scanError := rows.Scan(&usage.TenantID, &usage.Patients, &usage.StorageBytes, &usage.ObservationsToday, &usage.ObservationDay); scanError != nil {
+			return nil, scanError
+		}
+		usageList = append(usageList, usage)
+	}
+	return usageList, rows.Err()
+}
+
+// scanTenantUsage reads one row selected with tenantUsageColumns
+func scanTenantUsage(row *sql.Row) (*models.TenantUsage, error) {
+	usage := &models.TenantUsage{}
+	if scanError := row.Scan(&usage.TenantID, &usage.Patients, &usage.StorageBytes, &usage.ObservationsToday, &usage.ObservationDay); scanError != nil {
+		return nil, scanError
+	}
+	return usage, nil
+}
@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupQuotaUsageTestData empties the quota usage tables
+func cleanupQuotaUsageTestData(t *testing.T, databaseConnection *sql.DB) {
+	for _, table := range []string{"tenant_resource_usage", "tenant_quota_usage"} {
+		if _, deleteError := databaseConnection.Exec("DELETE FROM " + table); deleteError != nil {
+			t.Fatalf("Failed to cleanup %s: %v", table, deleteError)
+		}
+	}
+}
+
+// TestPostgresQuotaUsageRepository_ReserveEnforcesLimits verifies a reservation that would pass a limit is
+// refused with the current counters, and the daily observation count starts again on a new day
+func TestPostgresQuotaUsageRepository_ReserveEnforcesLimits(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupQuotaUsageTestData(t, databaseConnection)
+	defer cleanupQuotaUsageTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	usageRepository := NewPostgresQuotaUsageRepository(databaseConnection)
+	patientCreate := models.UsageChange{TenantID: "acme", Patients: 1, StorageBytes: 40, Day: "2026-03-01", MaxPatients: 1, MaxStorageBytes: 100}
+
+	usage, reserved, reserveError := usageRepository.Reserve(ctx, patientCreate)
+	if reserveError != nil || !reserved || usage.Patients != 1 || usage.StorageBytes != 40 {
+		t.Fatalf("Expected the first patient to be reserved, got %+v %v (%v)", usage, reserved, reserveError)
+	}
+	usage, reserved, reserveError = usageRepository.Reserve(ctx, patientCreate)
+	if reserveError != nil || reserved || usage.Patients != 1 {
+		t.Fatalf("Expected the second patient to be refused with the current counters, got %+v %v (%v)", usage, reserved, reserveError)
+	}
+
+	// Storage is checked even when the count is unlimited
+	oversized := models.UsageChange{TenantID: "acme", StorageBytes: 61, Day: "2026-03-01", MaxStorageBytes: 100}
+	if _, reserved, _ := usageRepository.Reserve(ctx, oversized); reserved {
+		t.Error("Expected growth past the storage limit to be refused")
+	}
+	shrink := models.UsageChange{TenantID: "acme", StorageBytes: -30, Day: "2026-03-01", MaxStorageBytes: 10}
+	if usage, reserved, _ := usageRepository.Reserve(ctx, shrink); !reserved || usage.StorageBytes != 10 {
+		t.Errorf("Expected shrinking to be allowed over the limit, got %+v %v", usage, reserved)
+	}
+
+	observationCreate := models.UsageChange{TenantID: "acme", Observations: 1, Day: "2026-03-01", MaxObservationsPerDay: 1}
+	usageRepository.Reserve(ctx, observationCreate)
+	if _, reserved, _ := usageRepository.Reserve(ctx, observationCreate); reserved {
+		t.Error("Expected the daily observation limit to be enforced")
+	}
+	observationCreate.Day = "2026-03-02"
+	usage, reserved, _ = usageRepository.Reserve(ctx, observationCreate)
+	if !reserved || usage.ObservationsToday != 1 || usage.ObservationDay != "2026-03-02" {
+		t.Errorf("Expected the count to start again on a new day, got %+v %v", usage, reserved)
+	}
+}
+
+// TestPostgresQuotaUsageRepository_ReserveIsAtomic verifies concurrent reservations cannot pass a limit
+func TestPostgresQuotaUsageRepository_ReserveIsAtomic(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupQuotaUsageTestData(t, databaseConnection)
+	defer cleanupQuotaUsageTestData(t, databaseConnection)
+
+	usageRepository := NewPostgresQuotaUsageRepository(databaseConnection)
+	var allowed atomic.Int64
+	var waitGroup sync.WaitGroup
+	for attempt := 0; attempt < 20; attempt++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			change := models.UsageChange{TenantID: "acme", Patients: 1, Day: "2026-03-01", MaxPatients: 5}
+			if _, reserved, reserveError := usageRepository.Reserve(context.Background(), change); reserveError == nil && reserved {
+				allowed.Add(1)
+			}
+		}()
+	}
+	waitGroup.Wait()
+
+	if allowed.Load() != 5 {
+		t.Errorf("Expected exactly 5 reservations, got %d", allowed.Load())
+	}
+}
+
+// TestPostgresQuotaUsageRepository_CommitAndDelete verifies resources are tracked, updates charge their actual
+// growth to the owning tenant, releases return capacity, and deletes credit the owner
+func TestPostgresQuotaUsageRepository_CommitAndDelete(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupQuotaUsageTestData(t, databaseConnection)
+	defer cleanupQuotaUsageTestData(t, databaseConnection)
+
+	ctx := context.Background()
+	usageRepository := NewPostgresQuotaUsageRepository(databaseConnection)
+	createdAt := time.Now()
+	today := createdAt.UTC().Format("2006-01-02")
+
+	created := models.UsageChange{TenantID: "acme", Observations: 1, StorageBytes: 90, Day: today}
+	usageRepository.Reserve(ctx, created)
+	observationUsage := &models.ResourceUsage{ResourceType: "Observation", ResourceID: "o1", TenantID: "acme", PayloadBytes: 90, CreatedAt: createdAt}
+	if commitError := usageRepository.Commit(ctx, observationUsage, created); commitError != nil {
+		t.Fatalf("Unexpected commit error: %v", commitError)
+	}
+	if storedBytes, tracked, _ := usageRepository.PayloadBytes(ctx, "Observation", "o1"); !tracked || storedBytes != 90 {
+		t.Fatalf("Expected the observation to be tracked at 90 bytes, got %d %v", storedBytes, tracked)
+	}
+	if _, tracked, _ := usageRepository.PayloadBytes(ctx, "Observation", "missing"); tracked {
+		t.Error("Expected an unknown resource not to be tracked")
+	}
+
+	// Another tenant updates the observation: it reserved 10 bytes of growth, but the owner is charged the actual growth
+	updated := models.UsageChange{TenantID: "globex", StorageBytes: 10, Day: today}
+	usageRepository.Reserve(ctx, updated)
+	observationUsage.PayloadBytes = 95
+	if commitError := usageRepository.Commit(ctx, observationUsage, updated); commitError != nil {
+		t.Fatalf("Unexpected commit error: %v", commitError)
+	}
+	acmeUsage, _ := usageRepository.TenantUsage(ctx, "acme")
+	globexUsage, _ := usageRepository.TenantUsage(ctx, "globex")
+	if acmeUsage.StorageBytes != 95 || globexUsage.StorageBytes != 0 {
+		t.Errorf("Expected the owner to hold the new size, got acme %+v and globex %+v", acmeUsage, globexUsage)
+	}
+
+	failedCreate := models.UsageChange{TenantID: "acme", Patients: 1, StorageBytes: 5, Day: today}
+	usageRepository.Reserve(ctx, failedCreate)
+	if releaseError := usageRepository.Release(ctx, failedCreate); releaseError != nil {
+		t.Fatalf("Unexpected release error: %v", releaseError)
+	}
+	acmeUsage, _ = usageRepository.TenantUsage(ctx, "acme")
+	if acmeUsage.Patients != 0 || acmeUsage.StorageBytes != 95 {
+		t.Errorf("Expected the release to return its capacity, got %+v", acmeUsage)
+	}
+
+	if deleteError := usageRepository.Delete(ctx, "Observation", "o1"); deleteError != nil {
+		t.Fatalf("Unexpected delete error: %v", deleteError)
+	}
+	acmeUsage, _ = usageRepository.TenantUsage(ctx, "acme")
+	if acmeUsage.StorageBytes != 0 || acmeUsage.ObservationsToday != 0 {
+		t.Errorf("Expected the delete to credit the owner, got %+v", acmeUsage)
+	}
+	if deleteError := usageRepository.Delete(ctx, "Observation", "o1"); deleteError != nil {
+		t.Errorf("Expected deleting an untracked resource to do nothing, got %v", deleteError)
+	}
+
+	usageList, listError := usageRepository.ListTenantUsage(ctx)
+	if listError != nil || len(usageList) != 2 || usageList[0].TenantID != "acme" {
+		t.Errorf("Expected both tenants listed in order, got %+v (%v)", usageList, listError)
+	}
+	if unknownUsage, _ := usageRepository.TenantUsage(ctx, "unknown"); unknownUsage.TenantID != "unknown" || unknownUsage.Patients != 0 {
+		t.Errorf("Expected zero usage for an unknown tenant, got %+v", unknownUsage)
+	}
+}
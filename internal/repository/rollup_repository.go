@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// RollupRepository defines the interface for stored daily observation rollups
+type RollupRepository interface {
+	// ReplaceDays replaces every rollup for days in [from, to) with the given ones in one transaction,
+	// so readers never see a partly recomputed range
+	ReplaceDays(ctx context.Context, from time.Time, to time.Time, rollups []*models.ObservationRollup) error
+
+	// List returns the rollups matching query, oldest day first
+	List(ctx context.Context, query models.RollupQuery) ([]*models.ObservationRollup, error)
+}
+
+// PostgresRollupRepository implements RollupRepository using PostgreSQL
+type PostgresRollupRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresRollupRepository creates a new PostgreSQL rollup repository instance
+func NewPostgresRollupRepository(databaseConnection *sql.DB) *PostgresRollupRepository {
+	return &PostgresRollupRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// ReplaceDays deletes the day range and inserts the recomputed rollups
+func (repository *PostgresRollupRepository) ReplaceDays(ctx context.Context, from time.Time, to time.Time, rollups []*models.ObservationRollup) error {
+	return runInTransaction(ctx, repository.databaseConnection, func(transactionContext context.Context) error {
+		executor := executorFor(transactionContext, repository.databaseConnection)
+		_, deleteError := executor.ExecContext(transactionContext, `DELETE FROM observation_daily_rollups WHERE day >= $1::date AND day < $2::date`, from, to)
+		if deleteError != nil {
+			return fmt.Errorf("failed to clear rollups: %w", deleteError)
+		}
+
+		insertQuery := `
+			INSERT INTO observation_daily_rollups (patient_id, code, code_system, unit, day, observation_count, min_value, max_value, sum_value)
+			VALUES ($1, $2, $3, $4, $5::date, $6, $7, $8, $9)
+		`
+		for _, rollup := range rollups {
+			_, insertError := executor.ExecContext(transactionContext, insertQuery,
+				rollup.PatientID, rollup.Code, rollup.CodeSystem, rollup.Unit, rollup.Day,
+				rollup.Count, rollup.Min, rollup.Max, rollup.Sum)
+			if insertError != nil {
+				return fmt.Errorf("failed to store rollup: %w", insertError)
+			}
+		}
+		return nil
+	})
+}
+
+// List returns one patient's rollups for a code, optionally narrowed to a system and day range
+func (repository *PostgresRollupRepository) List(ctx context.Context, query models.RollupQuery) ([]*models.ObservationRollup, error) {
+	selectQuery := `
+		SELECT patient_id, code, code_system, unit, day, observation_count, min_value, max_value, sum_value
+		FROM observation_daily_rollups
+		WHERE patient_id = $1 AND code = $2
+			AND ($3 = '' OR code_system = $3)
+			AND ($4::date IS NULL OR day >= $4::date)
+			AND ($5::date IS NULL OR day <= $5::date)
+		ORDER BY day, code_system, unit
+	`
+
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, query.PatientID, query.Code, query.CodeSystem, query.From, query.To)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	rollups := []*models.ObservationRollup{}
+	for rows.Next() {
+		rollup := &models.ObservationRollup{}
+		scanError := rows.Scan(&rollup.PatientID, &rollup.Code, &rollup.CodeSystem, &rollup.Unit, &rollup.Day,
+			&rollup.Count, &rollup.Min, &rollup.Max, &rollup.Sum)
+		if scanError != nil {
+			return nil, scanError
+		}
+		rollup.Average = rollup.Sum / float64(rollup.Count)
+		rollups = append(rollups, rollup)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return rollups, nil
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupRollupTestData removes all rows from the observation_daily_rollups table
+func cleanupRollupTestData(t *testing.T, databaseConnection *sql.DB) {
+	_, deleteError := databaseConnection.Exec("DELETE FROM observation_daily_rollups")
+	if deleteError != nil {
+		t.Fatalf("Failed to cleanup observation rollups: %v", deleteError)
+	}
+}
+
+// TestPostgresRollupRepository_ReplaceDaysAndList verifies a recomputed range replaces the old rows
+func TestPostgresRollupRepository_ReplaceDaysAndList(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupRollupTestData(t, databaseConnection)
+	defer cleanupRollupTestData(t, databaseConnection)
+
+	rollupRepository := NewPostgresRollupRepository(databaseConnection)
+	ctx := context.Background()
+	firstDay := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	secondDay := firstDay.AddDate(0, 0, 1)
+
+	initialRollups := []*models.ObservationRollup{
+		{PatientID: "patient-1", CodeSystem: "http://loinc.org", Code: "8480-6", Unit: "mmHg", Day: firstDay, Count: 2, Min: 110, Max: 130, Sum: 240},
+		{PatientID: "patient-1", CodeSystem: "http://loinc.org", Code: "8480-6", Unit: "mmHg", Day: secondDay, Count: 1, Min: 125, Max: 125, Sum: 125},
+	}
+	if replaceError := rollupRepository.ReplaceDays(ctx, firstDay, secondDay.AddDate(0, 0, 1), initialRollups); replaceError != nil {
+		t.Fatalf("Expected no error, got %v", replaceError)
+	}
+
+	// Recomputing only the first day leaves the second untouched
+	recomputed := []*models.ObservationRollup{
+		{PatientID: "patient-1", CodeSystem: "http://loinc.org", Code: "8480-6", Unit: "mmHg", Day: firstDay, Count: 3, Min: 100, Max: 130, Sum: 340},
+	}
+	if replaceError := rollupRepository.ReplaceDays(ctx, firstDay, secondDay, recomputed); replaceError != nil {
+		t.Fatalf("Expected no error, got %v", replaceError)
+	}
+
+	rollups, listError := rollupRepository.List(ctx, models.RollupQuery{PatientID: "patient-1", Code: "8480-6"})
+	if listError != nil {
+		t.Fatalf("Expected no error, got %v", listError)
+	}
+	if len(rollups) != 2 {
+		t.Fatalf("Expected 2 rollups, got %d", len(rollups))
+	}
+	if rollups[0].Count != 3 || rollups[0].Min != 100 {
+		t.Errorf("Expected the recomputed first day, got %+v", rollups[0])
+	}
+	if rollups[1].Average != 125 {
+		t.Errorf("Expected average 125 on the second day, got %v", rollups[1].Average)
+	}
+
+	fromSecondDay, listError := rollupRepository.List(ctx, models.RollupQuery{PatientID: "patient-1", Code: "8480-6", From: &secondDay})
+	if listError != nil {
+		t.Fatalf("Expected no error, got %v", listError)
+	}
+	if len(fromSecondDay) != 1 {
+		t.Errorf("Expected 1 rollup from the second day, got %d", len(fromSecondDay))
+	}
+}
+
+// TestMongoObservationRepository_DailyAggregates verifies values and components are aggregated per day and code
+func TestMongoObservationRepository_DailyAggregates(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	repository := NewMongoObservationRepository(mongoDatabase)
+	defer cleanupMongoTestData(t, repository.collection)
+	ctx := context.Background()
+
+	morning := time.Date(2024, 3, 1, 8, 0, 0, 0, time.UTC)
+	evening := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	nextDay := time.Date(2024, 3, 2, 8, 0, 0, 0, time.UTC)
+	systolicMorning, systolicEvening, diastolic, nextDaySystolic := 120.0, 140.0, 80.0, 150.0
+
+	observations := []*models.Observation{
+		{PatientID: "patient-1", Status: "final", Code: "8480-6", CodeSystem: "http://loinc.org", ValueQuantity: &systolicMorning, ValueUnit: "mmHg", EffectiveDate: &morning},
+		{PatientID: "patient-1", Status: "final", Code: "85354-9", CodeSystem: "http://loinc.org", EffectiveDate: &evening, Components: []models.ObservationComponent{
+			{Code: "8480-6", CodeSystem: "http://loinc.org", ValueQuantity: &systolicEvening, ValueUnit: "mmHg"},
+			{Code: "8462-4", CodeSystem: "http://loinc.org", ValueQuantity: &diastolic, ValueUnit: "mmHg"},
+		}},
+		{PatientID: "patient-1", Status: "final", Code: "8480-6", CodeSystem: "http://loinc.org", ValueQuantity: &nextDaySystolic, ValueUnit: "mmHg", EffectiveDate: &nextDay},
+	}
+	for _, observation := range observations {
+		if _, createError := repository.Create(ctx, observation); createError != nil {
+			t.Fatalf("Failed to create observation: %v", createError)
+		}
+	}
+
+	dayStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	rollups, aggregateError := repository.DailyAggregates(ctx, dayStart, dayStart.AddDate(0, 0, 1))
+	if aggregateError != nil {
+		t.Fatalf("Expected no error, got %v", aggregateError)
+	}
+
+	rollupsByCode := map[string]*models.ObservationRollup{}
+	for _, rollup := range rollups {
+		rollupsByCode[rollup.Code] = rollup
+	}
+	if len(rollupsByCode) != 2 {
+		t.Fatalf("Expected rollups for the systolic and diastolic codes only, got %d", len(rollups))
+	}
+	systolic := rollupsByCode["8480-6"]
+	if systolic.Count != 2 || systolic.Min != 120 || systolic.Max != 140 || systolic.Sum != 260 {
+		t.Errorf("Unexpected systolic rollup: %+v", systolic)
+	}
+	if !systolic.Day.Equal(dayStart) {
+		t.Errorf("Expected day %v, got %v", dayStart, systolic.Day)
+	}
+}
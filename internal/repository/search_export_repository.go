@@ -0,0 +1,209 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// SearchExportRepository defines the interface for search exports and their download audit trail
+type SearchExportRepository interface {
+	// Create inserts a pending export
+	Create(ctx context.Context, export *models.SearchExport) (*models.SearchExport, error)
+
+	// Get retrieves an export without its file; sql.ErrNoRows when there is none
+	Get(ctx context.Context, exportID int64) (*models.SearchExport, error)
+
+	// ClaimNext marks the oldest pending export running, or a running one not updated since staleBefore
+	// (its runner stopped), and returns it; sql.ErrNoRows when there is none
+	ClaimNext(ctx context.Context, staleBefore time.Time) (*models.SearchExport, error)
+
+	// Complete stores the sealed file of a running export and makes it ready until expiresAt
+	Complete(ctx context.Context, exportID int64, content []byte, resourceCount int, truncated bool, expiresAt time.Time) error
+
+	// Fail ends a running export with an error
+	Fail(ctx context.Context, exportID int64, failure string) error
+
+	// Content reads a ready export's sealed file; sql.ErrNoRows when it has none
+	Content(ctx context.Context, exportID int64) ([]byte, error)
+
+	// ExpireBefore deletes the files of ready exports that expired before now and returns how many it expired
+	ExpireBefore(ctx context.Context, now time.Time) (int64, error)
+
+	// RecordDownload appends a download to the audit trail
+	RecordDownload(ctx context.Context, download *models.SearchExportDownload) error
+
+	// ListDownloads returns an export's downloads, oldest first
+	ListDownloads(ctx context.Context, exportID int64) ([]*models.SearchExportDownload, error)
+}
+
+// PostgresSearchExportRepository implements SearchExportRepository using PostgreSQL
+type PostgresSearchExportRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresSearchExportRepository creates a new PostgreSQL search export repository instance
+func NewPostgresSearchExportRepository(databaseConnection *sql.DB) *PostgresSearchExportRepository {
+	return &PostgresSearchExportRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// searchExportColumns lists the columns scanned by scanSearchExport
+const searchExportColumns = `id, resource_type, query, search_query, format, status, requested_by, tenant_id,
+	resource_count, truncated, error, created_at, updated_at, completed_at, expires_at`
+
+// Create inserts a pending export
+func (repository *PostgresSearchExportRepository) Create(ctx context.Context, export *models.SearchExport) (*models.SearchExport, error) {
+	insertQuery := `
+		INSERT INTO search_exports (resource_type, query, search_query, format, status, requested_by, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + searchExportColumns
+
+	return scanSearchExport(repository.databaseConnection.QueryRowContext(ctx, insertQuery,
+		export.ResourceType, export.Query, export.SearchQuery, export.Format, models.SearchExportStatusPending, export.RequestedBy, export.TenantID))
+}
+
+// Get retrieves an export by ID
+func (repository *PostgresSearchExportRepository) Get(ctx context.Context, exportID int64) (*models.SearchExport, error) {
+	return scanSearchExport(repository.databaseConnection.QueryRowContext(ctx,
+		"SELECT "+searchExportColumns+" FROM search_exports WHERE id = $1", exportID))
+}
+
+// ClaimNext locks the oldest claimable export with SKIP LOCKED so concurrent runners take different ones
+func (repository *PostgresSearchExportRepository) ClaimNext(ctx context.Context, staleBefore time.Time) (*models.SearchExport, error) {
+	claimQuery := `
+		UPDATE search_exports
+		SET status = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM search_exports
+			WHERE status = $2 OR (status = $1 AND updated_at < $3)
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + searchExportColumns
+
+	return scanSearchExport(repository.databaseConnection.QueryRowContext(ctx, claimQuery,
+		models.SearchExportStatusRunning, models.SearchExportStatusPending, staleBefore))
+}
+
+// Complete stores the file and marks the running export ready
+func (repository *PostgresSearchExportRepository) Complete(ctx context.Context, exportID int64, content []byte, resourceCount int, truncated bool, expiresAt time.Time) error {
+	updateQuery := `
+		UPDATE search_exports
+		SET status = $2, content = $3, resource_count = $4, truncated = $5, expires_at = $6,
+			completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $7
+	`
+	result, updateError := repository.databaseConnection.ExecContext(ctx, updateQuery,
+		exportID, models.SearchExportStatusReady, content, resourceCount, truncated, expiresAt, models.SearchExportStatusRunning)
+	return runningExportUpdated(result, updateError)
+}
+
+// Fail marks the running export failed
+func (repository *PostgresSearchExportRepository) Fail(ctx context.Context, exportID int64, failure string) error {
+	updateQuery := `
+		UPDATE search_exports
+		SET status = $2, error = $3, completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = $4
+	`
+	result, updateError := repository.databaseConnection.ExecContext(ctx, updateQuery,
+		exportID, models.SearchExportStatusFailed, failure, models.SearchExportStatusRunning)
+	return runningExportUpdated(result, updateError)
+}
+
+// Content reads the sealed file of a ready export
+func (repository *PostgresSearchExportRepository) Content(ctx context.Context, exportID int64) ([]byte, error) {
+	var content []byte
+	scanError := repository.databaseConnection.QueryRowContext(ctx,
+		"SELECT content FROM search_exports WHERE id = $1 AND status = $2 AND content IS NOT NULL",
+		exportID, models.SearchExportStatusReady).Scan(&content)
+	return content, scanError
+}
+
+// ExpireBefore clears the files of expired ready exports, keeping the rows and their audit trail
+func (repository *PostgresSearchExportRepository) ExpireBefore(ctx context.Context, now time.Time) (int64, error) {
+	updateQuery := `
+		UPDATE search_exports
+		SET status = $1, content = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE status = $2 AND expires_at <= $3
+	`
+	result, updateError := repository.databaseConnection.ExecContext(ctx, updateQuery,
+		models.SearchExportStatusExpired, models.SearchExportStatusReady, now)
+	if updateError != nil {
+		return 0, updateError
+	}
+	return result.RowsAffected()
+}
+
+// RecordDownload inserts a download audit row
+func (repository *PostgresSearchExportRepository) RecordDownload(ctx context.Context, download *models.SearchExportDownload) error {
+	insertQuery := `
+		INSERT INTO search_export_downloads (export_id, downloaded_by, remote_address, user_agent)
+		VALUES ($1, $2, $3, $4)
+	`
+	_, insertError := repository.databaseConnection.ExecContext(ctx, insertQuery,
+		download.ExportID, download.DownloadedBy, download.RemoteAddress, download.UserAgent)
+	return insertError
+}
+
+// ListDownloads returns the export's downloads, oldest first
+func (repository *PostgresSearchExportRepository) ListDownloads(ctx context.Context, exportID int64) ([]*models.SearchExportDownload, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, `
+		SELECT export_id, downloaded_by, remote_address, user_agent, downloaded_at
+		FROM search_export_downloads
+		WHERE export_id = $1
+		ORDER BY id`, exportID)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	downloads := []*models.SearchExportDownload{}
+	for rows.Next() {
+		download := &models.SearchExportDownload{}
+		if scanError := rows.Scan(&download.ExportID, &download.DownloadedBy, &download.RemoteAddress, &download.UserAgent, &download.DownloadedAt); scanError != nil {
+			return nil, scanError
+		}
+		downloads = append(downloads, download)
+	}
+	return downloads, rows.Err()
+}
+
+// runningExportUpdated returns sql.ErrNoRows when the update found no running export, for example because
+// another runner reclaimed it as stale
+func runningExportUpdated(result sql.Result, updateError error) error {
+	if updateError != nil {
+		return updateError
+	}
+	if updatedRows, _ := result.RowsAffected(); updatedRows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// scanSearchExport reads one row selected with searchExportColumns
+func scanSearchExport(row *sql.Row) (*models.SearchExport, error) {
+	export := &models.SearchExport{}
+	var status string
+	var completedAt, expiresAt sql.NullTime
+	scanError := row.Scan(&export.ID, &export.ResourceType, &export.Query, &export.SearchQuery, &export.Format, &status,
+		&export.RequestedBy, &export.TenantID, &export.ResourceCount, &export.Truncated, &export.Error,
+		&export.CreatedAt, &export.UpdatedAt, &completedAt, &expiresAt)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	export.Status = models.SearchExportStatus(status)
+	if completedAt.Valid {
+		export.CompletedAt = &completedAt.Time
+	}
+	if expiresAt.Valid {
+		export.ExpiresAt = &expiresAt.Time
+	}
+	return export, nil
+}
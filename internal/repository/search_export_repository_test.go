@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupSearchExportTestData removes all exports and, through the cascade, their downloads
+func cleanupSearchExportTestData(t *testing.T, databaseConnection *sql.DB) {
+	_, deleteError := databaseConnection.Exec("DELETE FROM search_exports")
+	if deleteError != nil {
+		t.Fatalf("Failed to cleanup search exports: %v", deleteError)
+	}
+}
+
+// TestPostgresSearchExportRepository_Lifecycle verifies an export is claimed, completed, downloaded, and expired
+func TestPostgresSearchExportRepository_Lifecycle(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupSearchExportTestData(t, databaseConnection)
+	defer cleanupSearchExportTestData(t, databaseConnection)
+
+	exportRepository := NewPostgresSearchExportRepository(databaseConnection)
+	ctx := context.Background()
+
+	export, createError := exportRepository.Create(ctx, &models.SearchExport{
+		ResourceType: "Patient",
+		Query:        "gender=female",
+		SearchQuery:  "gender=female",
+		Format:       models.SearchExportFormatCSV,
+		RequestedBy:  "api-key:analyst",
+		TenantID:     "tenant-a",
+	})
+	if createError != nil {
+		t.Fatalf("Expected no error, got %v", createError)
+	}
+	if export.Status != models.SearchExportStatusPending {
+		t.Errorf("Expected a pending export, got %s", export.Status)
+	}
+
+	claimedExport, claimError := exportRepository.ClaimNext(ctx, time.Now().Add(-time.Hour))
+	if claimError != nil || claimedExport.ID != export.ID || claimedExport.Status != models.SearchExportStatusRunning {
+		t.Fatalf("Expected the export to be claimed, got %+v (%v)", claimedExport, claimError)
+	}
+	if _, secondClaimError := exportRepository.ClaimNext(ctx, time.Now().Add(-time.Hour)); !errors.Is(secondClaimError, sql.ErrNoRows) {
+		t.Errorf("Expected a running export not to be claimed again, got %v", secondClaimError)
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	if completeError := exportRepository.Complete(ctx, export.ID, []byte("sealed"), 12, true, expiresAt); completeError != nil {
+		t.Fatalf("Expected no error, got %v", completeError)
+	}
+	content, contentError := exportRepository.Content(ctx, export.ID)
+	if contentError != nil || string(content) != "sealed" {
+		t.Errorf("Expected the stored file, got %q (%v)", content, contentError)
+	}
+
+	exportRepository.RecordDownload(ctx, &models.SearchExportDownload{ExportID: export.ID, DownloadedBy: "anonymous", RemoteAddress: "10.0.0.1"})
+	downloads, listError := exportRepository.ListDownloads(ctx, export.ID)
+	if listError != nil || len(downloads) != 1 || downloads[0].RemoteAddress != "10.0.0.1" {
+		t.Errorf("Expected one audited download, got %+v (%v)", downloads, listError)
+	}
+
+	expired, expireError := exportRepository.ExpireBefore(ctx, expiresAt.Add(time.Minute))
+	if expireError != nil || expired != 1 {
+		t.Fatalf("Expected one export expired, got %d (%v)", expired, expireError)
+	}
+	expiredExport, _ := exportRepository.Get(ctx, export.ID)
+	if expiredExport.Status != models.SearchExportStatusExpired || expiredExport.ResourceCount != 12 || !expiredExport.Truncated {
+		t.Errorf("Expected the export row kept as expired, got %+v", expiredExport)
+	}
+	if _, contentError := exportRepository.Content(ctx, export.ID); !errors.Is(contentError, sql.ErrNoRows) {
+		t.Errorf("Expected the file to be deleted, got %v", contentError)
+	}
+}
@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrResourceDeleted is returned by reads, updates, and deletes of a resource that was deleted
+// Deletes only set deleted_at, so the repository can tell a deleted resource from one that never existed.
+// The error returned also matches the repository's not-found error, so callers that only tell found from not
+// found keep treating a deleted resource as missing
+var ErrResourceDeleted = errors.New("resource was deleted")
+
+// deletedError returns an error matching both notFound and ErrResourceDeleted
+func deletedError(notFound error) error {
+	return fmt.Errorf("%w: %w", notFound, ErrResourceDeleted)
+}
+
+// notDeletedCondition is the SQL condition leaving out deleted rows
+const notDeletedCondition = "deleted_at IS NULL"
+
+// missingRowError explains why no live row of table has id: ErrResourceDeleted, wrapping sql.ErrNoRows, when
+// the row was deleted, and sql.ErrNoRows when there is no such row
+// table is one of the repository's own table names, never client input
+func missingRowError(ctx context.Context, executor queryExecutor, table string, id string) error {
+	var deleted bool
+	scanError := executor.QueryRowContext(ctx, `SELECT deleted_at IS NOT NULL FROM `+table+` WHERE id = $1`, id).Scan(&deleted)
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return sql.ErrNoRows
+	}
+	if scanError != nil {
+		return scanError
+	}
+	if deleted {
+		return deletedError(sql.ErrNoRows)
+	}
+	return sql.ErrNoRows
+}
+
+// softDeleteRow marks the row of table with id deleted, returning missingRowError when there is no live row
+func softDeleteRow(ctx context.Context, executor queryExecutor, table string, id string) error {
+	result, execError := executor.ExecContext(ctx,
+		`UPDATE `+table+` SET deleted_at = $2 WHERE id = $1 AND `+notDeletedCondition, id, time.Now())
+	if execError != nil {
+		return execError
+	}
+
+	deletedRows, rowsError := result.RowsAffected()
+	if rowsError != nil {
+		return rowsError
+	}
+	if deletedRows == 0 {
+		return missingRowError(ctx, executor, table, id)
+	}
+	return nil
+}
+
+// notDeleted adds the condition leaving out deleted documents to filter and returns it
+// Documents stored before soft delete have no deleted_at field, which a null match also finds
+func notDeleted(filter bson.M) bson.M {
+	filter["deleted_at"] = nil
+	return filter
+}
+
+// missingDocumentError explains why no live document of collection has objectID: notFound wrapped with
+// ErrResourceDeleted when the document was deleted, and notFound when there is no such document
+func missingDocumentError(ctx context.Context, collection *mongo.Collection, objectID interface{}, notFound error) error {
+	deletedCount, countError := collection.CountDocuments(ctx, bson.M{"_id": objectID, "deleted_at": bson.M{"$ne": nil}})
+	if countError != nil {
+		return fmt.Errorf("failed to check for a deleted document: %w", countError)
+	}
+	if deletedCount > 0 {
+		return deletedError(notFound)
+	}
+	return notFound
+}
+
+// softDeleteDocument marks the document of collection with objectID deleted, returning missingDocumentError
+// when there is no live document
+func softDeleteDocument(ctx context.Context, collection *mongo.Collection, objectID interface{}, notFound error) error {
+	updateResult, updateError := collection.UpdateOne(ctx, notDeleted(bson.M{"_id": objectID}), bson.M{"$set": bson.M{"deleted_at": time.Now()}})
+	if updateError != nil {
+		return fmt.Errorf("failed to delete document: %w", updateError)
+	}
+	if updateResult.MatchedCount == 0 {
+		return missingDocumentError(ctx, collection, objectID, notFound)
+	}
+	return nil
+}
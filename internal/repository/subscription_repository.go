@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// SubscriptionRepository defines the interface for subscription data operations
+type SubscriptionRepository interface {
+	// Create inserts a new subscription and returns it with its ID
+	Create(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error)
+
+	// GetByID retrieves a subscription by its unique identifier
+	GetByID(ctx context.Context, subscriptionID string) (*models.Subscription, error)
+
+	// Search retrieves a tenant's subscriptions matching the search criteria
+	Search(ctx context.Context, searchParams *models.SubscriptionSearchParams) ([]*models.Subscription, error)
+
+	// Count returns how many subscriptions match the search criteria across all pages
+	Count(ctx context.Context, searchParams *models.SubscriptionSearchParams) (int, error)
+
+	// Update modifies an existing subscription
+	Update(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error)
+
+	// Delete removes a subscription by ID
+	Delete(ctx context.Context, subscriptionID string) error
+
+	// ListActive retrieves every tenant's active subscriptions that have not ended
+	ListActive(ctx context.Context) ([]*models.Subscription, error)
+
+	// RecordError stops a subscription with the error status and the failure that stopped it
+	RecordError(ctx context.Context, subscriptionID string, message string) error
+}
+
+// subscriptionColumns are the columns read into a models.Subscription by scanSubscription, in order
+const subscriptionColumns = `id, status, reason, criteria, endpoint, payload, headers, end_time, error, tenant_id,
+		created_at, updated_at`
+
+// PostgresSubscriptionRepository implements SubscriptionRepository using PostgreSQL
+type PostgresSubscriptionRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresSubscriptionRepository creates a new PostgreSQL subscription repository instance
+func NewPostgresSubscriptionRepository(databaseConnection *sql.DB) *PostgresSubscriptionRepository {
+	return &PostgresSubscriptionRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// scanSubscription reads one row selected with subscriptionColumns
+func scanSubscription(row rowScanner) (*models.Subscription, error) {
+	subscription := &models.Subscription{}
+	var subscriptionError sql.NullString
+	scanError := row.Scan(
+		&subscription.ID,
+		&subscription.Status,
+		&subscription.Reason,
+		&subscription.Criteria,
+		&subscription.Endpoint,
+		&subscription.Payload,
+		pq.Array(&subscription.Headers),
+		&subscription.End,
+		&subscriptionError,
+		&subscription.TenantID,
+		&subscription.CreatedAt,
+		&subscription.UpdatedAt,
+	)
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	// Only rest-hook subscriptions are stored
+	subscription.ChannelType = "rest-hook"
+	subscription.Error = subscriptionError.String
+	return subscription, nil
+}
+
+// Create inserts a new subscription into the database
+func (repository *PostgresSubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	insertQuery := `
+		INSERT INTO subscriptions (status, reason, criteria, endpoint, payload, headers, end_time, error, tenant_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at
+	`
+
+	scanError := repository.databaseConnection.QueryRowContext(
+		ctx,
+		insertQuery,
+		subscription.Status,
+		subscription.Reason,
+		subscription.Criteria,
+		subscription.Endpoint,
+		subscription.Payload,
+		pq.Array(subscription.Headers),
+		subscription.End,
+		nullableString(subscription.Error),
+		subscription.TenantID,
+	).Scan(&subscription.ID, &subscription.CreatedAt, &subscription.UpdatedAt)
+
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return subscription, nil
+}
+
+// GetByID retrieves a subscription by its unique identifier
+// Returns sql.ErrNoRows when the subscription does not exist
+func (repository *PostgresSubscriptionRepository) GetByID(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	selectQuery := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE id = $1`
+	return scanSubscription(repository.databaseConnection.QueryRowContext(ctx, selectQuery, subscriptionID))
+}
+
+// Update modifies an existing subscription; the tenant that made it is kept
+// Returns sql.ErrNoRows when the subscription does not exist
+func (repository *PostgresSubscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	updateQuery := `
+		UPDATE subscriptions
+		SET status = $1, reason = $2, criteria = $3, endpoint = $4, payload = $5, headers = $6, end_time = $7,
+			error = $8, updated_at = $9
+		WHERE id = $10
+		RETURNING tenant_id, created_at, updated_at
+	`
+
+	// Set the updated timestamp
+	subscription.UpdatedAt = time.Now()
+
+	scanError := repository.databaseConnection.QueryRowContext(
+		ctx,
+		updateQuery,
+		subscription.Status,
+		subscription.Reason,
+		subscription.Criteria,
+		subscription.Endpoint,
+		subscription.Payload,
+		pq.Array(subscription.Headers),
+		subscription.End,
+		nullableString(subscription.Error),
+		subscription.UpdatedAt,
+		subscription.ID,
+	).Scan(&subscription.TenantID, &subscription.CreatedAt, &subscription.UpdatedAt)
+
+	if scanError != nil {
+		return nil, scanError
+	}
+
+	return subscription, nil
+}
+
+// subscriptionSearchConditions builds the AND clauses that filter subscriptions on the search criteria, shared
+// by Search and Count so a page and its total always agree; the returned parameters are numbered from $1
+func subscriptionSearchConditions(searchParams *models.SubscriptionSearchParams) (string, []interface{}) {
+	conditions := ` AND tenant_id = $1`
+	queryParameters := []interface{}{searchParams.TenantID}
+
+	// Add status filter
+	if searchParams.Status != "" {
+		conditions += ` AND status = $` + fmt.Sprint(len(queryParameters)+1)
+		queryParameters = append(queryParameters, searchParams.Status)
+	}
+
+	return conditions, queryParameters
+}
+
+// Search retrieves a tenant's subscriptions matching the search criteria, newest first
+func (repository *PostgresSubscriptionRepository) Search(ctx context.Context, searchParams *models.SubscriptionSearchParams) ([]*models.Subscription, error) {
+	conditions, queryParameters := subscriptionSearchConditions(searchParams)
+	parameterIndex := len(queryParameters) + 1
+
+	searchQuery := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE 1=1` + conditions +
+		` ORDER BY created_at DESC, id LIMIT $` + fmt.Sprint(parameterIndex) + ` OFFSET $` + fmt.Sprint(parameterIndex+1)
+	queryParameters = append(queryParameters, searchParams.Limit, searchParams.Offset)
+
+	return repository.query(ctx, searchQuery, queryParameters...)
+}
+
+// Count returns how many subscriptions match the search criteria, ignoring the page's limit and offset
+func (repository *PostgresSubscriptionRepository) Count(ctx context.Context, searchParams *models.SubscriptionSearchParams) (int, error) {
+	conditions, queryParameters := subscriptionSearchConditions(searchParams)
+
+	var total int
+	countError := repository.databaseConnection.QueryRowContext(ctx, `SELECT COUNT(*) FROM subscriptions WHERE 1=1`+conditions, queryParameters...).Scan(&total)
+	if countError != nil {
+		return 0, countError
+	}
+	return total, nil
+}
+
+// Delete removes a subscription from the database by ID
+// Returns sql.ErrNoRows when the subscription does not exist
+func (repository *PostgresSubscriptionRepository) Delete(ctx context.Context, subscriptionID string) error {
+	result, execError := repository.databaseConnection.ExecContext(ctx, `DELETE FROM subscriptions WHERE id = $1`, subscriptionID)
+	if execError != nil {
+		return execError
+	}
+
+	deletedRows, rowsError := result.RowsAffected()
+	if rowsError != nil {
+		return rowsError
+	}
+	if deletedRows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// ListActive retrieves every tenant's active subscriptions that have not ended
+func (repository *PostgresSubscriptionRepository) ListActive(ctx context.Context) ([]*models.Subscription, error) {
+	selectQuery := `SELECT ` + subscriptionColumns + ` FROM subscriptions
+		WHERE status = $1 AND (end_time IS NULL OR end_time > CURRENT_TIMESTAMP)`
+	return repository.query(ctx, selectQuery, models.SubscriptionStatusActive)
+}
+
+// RecordError stops a subscription with the error status and the failure that stopped it
+// Returns sql.ErrNoRows when the subscription does not exist
+func (repository *PostgresSubscriptionRepository) RecordError(ctx context.Context, subscriptionID string, message string) error {
+	updateQuery := `UPDATE subscriptions SET status = $1, error = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $3`
+
+	result, updateError := repository.databaseConnection.ExecContext(ctx, updateQuery, models.SubscriptionStatusError, message, subscriptionID)
+	if updateError != nil {
+		return updateError
+	}
+
+	updatedRows, rowsError := result.RowsAffected()
+	if rowsError != nil {
+		return rowsError
+	}
+	if updatedRows == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// query runs a select of subscriptionColumns and scans every row
+func (repository *PostgresSubscriptionRepository) query(ctx context.Context, selectQuery string, queryParameters ...interface{}) ([]*models.Subscription, error) {
+	rows, queryError := repository.databaseConnection.QueryContext(ctx, selectQuery, queryParameters...)
+	if queryError != nil {
+		return nil, queryError
+	}
+	defer rows.Close()
+
+	subscriptions := []*models.Subscription{}
+	for rows.Next() {
+		subscription, scanError := scanSubscription(rows)
+		if scanError != nil {
+			return nil, scanError
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	// Check for errors during iteration
+	if rowsError := rows.Err(); rowsError != nil {
+		return nil, rowsError
+	}
+
+	return subscriptions, nil
+}
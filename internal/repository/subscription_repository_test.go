@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupSubscriptions removes all test data from the subscriptions table
+func cleanupSubscriptions(t *testing.T, databaseConnection *sql.DB) {
+	if _, deleteError := databaseConnection.Exec("DELETE FROM subscriptions"); deleteError != nil {
+		t.Fatalf("Failed to cleanup subscriptions: %v", deleteError)
+	}
+}
+
+// TestPostgresSubscriptionRepository_CRUD verifies a subscription is created, read, updated, stopped, and deleted
+func TestPostgresSubscriptionRepository_CRUD(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupSubscriptions(t, databaseConnection)
+	defer cleanupSubscriptions(t, databaseConnection)
+
+	subscriptionRepository := NewPostgresSubscriptionRepository(databaseConnection)
+	ctx := context.Background()
+
+	createdSubscription, createError := subscriptionRepository.Create(ctx, &models.Subscription{
+		Status:   models.SubscriptionStatusActive,
+		Reason:   "Blood pressure alerts",
+		Criteria: "Observation?patient=123&code=8480-6",
+		Endpoint: "https://alerts.example.com/notify",
+		Payload:  "application/fhir+json",
+		Headers:  []string{"Authorization: Bearer secret"},
+		TenantID: "clinic-a",
+	})
+	if createError != nil {
+		t.Fatalf("Failed to create subscription: %v", createError)
+	}
+	if createdSubscription.ID == "" || createdSubscription.CreatedAt.IsZero() {
+		t.Fatalf("Expected a generated ID and timestamps, got %+v", createdSubscription)
+	}
+
+	end := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	createdSubscription.End = &end
+	createdSubscription.TenantID = "clinic-b"
+	if _, updateError := subscriptionRepository.Update(ctx, createdSubscription); updateError != nil {
+		t.Fatalf("Failed to update subscription: %v", updateError)
+	}
+
+	storedSubscription, getError := subscriptionRepository.GetByID(ctx, createdSubscription.ID)
+	if getError != nil {
+		t.Fatalf("Failed to read subscription: %v", getError)
+	}
+	if storedSubscription.End == nil || !storedSubscription.End.Equal(end) || storedSubscription.TenantID != "clinic-a" || len(storedSubscription.Headers) != 1 {
+		t.Errorf("Expected the updated end and the original tenant, got %+v", storedSubscription)
+	}
+
+	active, listError := subscriptionRepository.ListActive(ctx)
+	if listError != nil || len(active) != 1 {
+		t.Fatalf("Expected one active subscription, got %d (%v)", len(active), listError)
+	}
+
+	if recordError := subscriptionRepository.RecordError(ctx, createdSubscription.ID, "webhook responded 410"); recordError != nil {
+		t.Fatalf("Failed to record subscription error: %v", recordError)
+	}
+	stoppedSubscription, _ := subscriptionRepository.GetByID(ctx, createdSubscription.ID)
+	if stoppedSubscription.Status != models.SubscriptionStatusError || stoppedSubscription.Error != "webhook responded 410" {
+		t.Errorf("Expected the subscription stopped with its error, got %+v", stoppedSubscription)
+	}
+	if active, _ := subscriptionRepository.ListActive(ctx); len(active) != 0 {
+		t.Errorf("Expected no active subscriptions after the error, got %d", len(active))
+	}
+
+	tenantSearch := &models.SubscriptionSearchParams{TenantID: "clinic-a", Limit: 10}
+	if subscriptions, _ := subscriptionRepository.Search(ctx, tenantSearch); len(subscriptions) != 1 {
+		t.Errorf("Expected the tenant's subscription found, got %d", len(subscriptions))
+	}
+	if total, _ := subscriptionRepository.Count(ctx, &models.SubscriptionSearchParams{TenantID: "clinic-b"}); total != 0 {
+		t.Errorf("Expected no subscriptions for another tenant, got %d", total)
+	}
+
+	if deleteError := subscriptionRepository.Delete(ctx, createdSubscription.ID); deleteError != nil {
+		t.Fatalf("Failed to delete subscription: %v", deleteError)
+	}
+	if deleteError := subscriptionRepository.Delete(ctx, createdSubscription.ID); !errors.Is(deleteError, sql.ErrNoRows) {
+		t.Errorf("Expected sql.ErrNoRows deleting twice, got %v", deleteError)
+	}
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// transactionContextKey is the context key for the transaction a unit of work runs in
+type transactionContextKey struct{}
+
+// queryExecutor is satisfied by both *sql.DB and *sql.Tx
+type queryExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// executorFor returns the transaction carried by ctx, or the connection pool when there is none
+func executorFor(ctx context.Context, databaseConnection *sql.DB) queryExecutor {
+	if transaction, ok := ctx.Value(transactionContextKey{}).(*sql.Tx); ok {
+		return transaction
+	}
+	return databaseConnection
+}
+
+// runInTransaction runs work in the transaction carried by ctx, or in a new transaction that
+// commits when work succeeds and rolls back when it fails
+// Repositories called with the context passed to work share the transaction
+func runInTransaction(ctx context.Context, databaseConnection *sql.DB, work func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(transactionContextKey{}).(*sql.Tx); ok {
+		return work(ctx)
+	}
+
+	transaction, beginError := databaseConnection.BeginTx(ctx, nil)
+	if beginError != nil {
+		return beginError
+	}
+	defer transaction.Rollback()
+
+	if workError := work(context.WithValue(ctx, transactionContextKey{}, transaction)); workError != nil {
+		return workError
+	}
+	return transaction.Commit()
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// postgresWarmupQueries touch the tables and indexes behind the hottest PostgreSQL queries
+// Catalog and plan caches are per backend, so they run on every pooled connection
+var postgresWarmupQueries = []string{
+	"SELECT id FROM patients WHERE id = '00000000-0000-0000-0000-000000000000'",
+	"SELECT id FROM patients WHERE identifier_system = '' AND identifier_value = '' LIMIT 1",
+	"SELECT id FROM patients WHERE family_name = '' AND given_name = '' LIMIT 1",
+	"SELECT id FROM patients ORDER BY created_at DESC LIMIT 1",
+	"SELECT sequence FROM resource_changes ORDER BY sequence DESC LIMIT 1",
+	"SELECT patient_id FROM observation_ledger LIMIT 1",
+	"SELECT patient_id FROM patient_legal_holds WHERE patient_id = '00000000-0000-0000-0000-000000000000'",
+}
+
+// WarmPostgres opens connections pool connections at once and runs the warm-up queries on each
+// The pool's configuration is left alone: connections is capped at its open limit, since holding more
+// would block, and afterwards the pool keeps as many as its idle limit allows
+func WarmPostgres(ctx context.Context, databaseConnection *sql.DB, connections int) error {
+	if maxOpenConnections := databaseConnection.Stats().MaxOpenConnections; maxOpenConnections > 0 {
+		connections = min(connections, maxOpenConnections)
+	}
+
+	pooledConnections := make([]*sql.Conn, 0, connections)
+	defer func() {
+		for _, pooledConnection := range pooledConnections {
+			pooledConnection.Close()
+		}
+	}()
+
+	// Hold every connection until all are open so the pool cannot hand the same one out twice
+	for range connections {
+		pooledConnection, connError := databaseConnection.Conn(ctx)
+		if connError != nil {
+			return connError
+		}
+		pooledConnections = append(pooledConnections, pooledConnection)
+	}
+
+	var warmupErrors []error
+	for _, pooledConnection := range pooledConnections {
+		for _, warmupQuery := range postgresWarmupQueries {
+			rows, queryError := pooledConnection.QueryContext(ctx, warmupQuery)
+			if queryError != nil {
+				warmupErrors = append(warmupErrors, queryError)
+				continue
+			}
+			rows.Close()
+		}
+	}
+
+	return errors.Join(warmupErrors...)
+}
+
+// namespaceNotFoundCode is the MongoDB error code for a missing collection
+const namespaceNotFoundCode = 26
+
+// Warm opens connections driver connections at once and loads the collection's index metadata
+func (repository *MongoObservationRepository) Warm(ctx context.Context, connections int) error {
+	// A fresh database has no collection yet, which is nothing to warm rather than a failure
+	indexCursor, listError := repository.collection.Indexes().List(ctx)
+	var commandError mongo.CommandError
+	switch {
+	case errors.As(listError, &commandError) && commandError.Code == namespaceNotFoundCode:
+	case listError != nil:
+		return listError
+	default:
+		indexCursor.Close(ctx)
+	}
+
+	// Concurrent queries force the driver to open one connection each
+	warmupErrors := make(chan error, connections)
+	for range connections {
+		go func() {
+			findError := repository.collection.FindOne(ctx, bson.M{"patient_id": ""}, options.FindOne().SetProjection(bson.M{"_id": 1})).Err()
+			if errors.Is(findError, mongo.ErrNoDocuments) {
+				findError = nil
+			}
+			warmupErrors <- findError
+		}()
+	}
+
+	var collectedErrors []error
+	for range connections {
+		collectedErrors = append(collectedErrors, <-warmupErrors)
+	}
+	return errors.Join(collectedErrors...)
+}
@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestWarmPostgres verifies the pool is warmed to the requested number of connections
+func TestWarmPostgres(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	databaseConnection.SetMaxIdleConns(3)
+
+	if warmError := WarmPostgres(context.Background(), databaseConnection, 3); warmError != nil {
+		t.Fatalf("Expected no error, got %v", warmError)
+	}
+
+	if idleConnections := databaseConnection.Stats().Idle; idleConnections != 3 {
+		t.Errorf("Expected 3 idle connections, got %d", idleConnections)
+	}
+}
+
+// TestWarmPostgres_StaysWithinOpenLimit verifies warm-up neither blocks on nor raises the pool's open limit
+func TestWarmPostgres_StaysWithinOpenLimit(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	databaseConnection.SetMaxOpenConns(2)
+	databaseConnection.SetMaxIdleConns(2)
+
+	warmupContext, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if warmError := WarmPostgres(warmupContext, databaseConnection, 5); warmError != nil {
+		t.Fatalf("Expected no error, got %v", warmError)
+	}
+
+	poolStats := databaseConnection.Stats()
+	if poolStats.MaxOpenConnections != 2 || poolStats.Idle != 2 {
+		t.Errorf("Expected the pool limits kept with 2 idle connections, got %+v", poolStats)
+	}
+}
+
+// TestMongoObservationRepository_Warm verifies index metadata and connections are loaded without error
+func TestMongoObservationRepository_Warm(t *testing.T) {
+	mongoDatabase := setupTestMongoDB(t)
+	observationRepository := NewMongoObservationRepository(mongoDatabase)
+
+	if warmError := observationRepository.Warm(context.Background(), 3); warmError != nil {
+		t.Errorf("Expected no error, got %v", warmError)
+	}
+}
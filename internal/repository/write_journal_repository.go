@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// WriteJournalRepository defines the interface for the journal of writes awaiting their change log entry
+type WriteJournalRepository interface {
+	// Append durably records an accepted write before it is applied
+	// It always commits on its own, even when ctx carries a transaction, so the entry survives a crash
+	Append(ctx context.Context, entry *models.WriteJournalEntry) (*models.WriteJournalEntry, error)
+
+	// Complete removes an entry, in the caller's transaction when ctx carries one
+	Complete(ctx context.Context, id int64) error
+
+	// ClaimOldest returns the oldest entry created before cutoff, locked in the transaction ctx carries
+	// so concurrent recoveries skip it; nil when there is none
+	ClaimOldest(ctx context.Context, cutoff time.Time) (*models.WriteJournalEntry, error)
+}
+
+// PostgresWriteJournalRepository implements WriteJournalRepository using PostgreSQL
+type PostgresWriteJournalRepository struct {
+	// Database connection pool
+	databaseConnection *sql.DB
+}
+
+// NewPostgresWriteJournalRepository creates a new PostgreSQL write journal repository instance
+func NewPostgresWriteJournalRepository(databaseConnection *sql.DB) *PostgresWriteJournalRepository {
+	return &PostgresWriteJournalRepository{
+		databaseConnection: databaseConnection,
+	}
+}
+
+// writeJournalColumns lists the columns scanned by scanWriteJournalEntry
+const writeJournalColumns = `id, resource_type, resource_id, operation, tenant_id, previous_patient_id, created_at`
+
+// Append inserts the entry on the connection pool, stamping it with the current time
+func (repository *PostgresWriteJournalRepository) Append(ctx context.Context, entry *models.WriteJournalEntry) (*models.WriteJournalEntry, error) {
+	insertQuery := `
+		INSERT INTO write_journal (resource_type, resource_id, operation, tenant_id, previous_patient_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + writeJournalColumns
+
+	row := repository.databaseConnection.QueryRowContext(ctx, insertQuery,
+		entry.ResourceType, entry.ResourceID, entry.Operation, entry.TenantID, entry.PreviousPatientID, time.Now())
+	return scanWriteJournalEntry(row)
+}
+
+// Complete deletes the entry; an entry already removed is not an error
+func (repository *PostgresWriteJournalRepository) Complete(ctx context.Context, id int64) error {
+	_, deleteError := executorFor(ctx, repository.databaseConnection).ExecContext(ctx, "DELETE FROM write_journal WHERE id = $1", id)
+	return deleteError
+}
+
+// ClaimOldest selects the oldest unlocked entry before cutoff with FOR UPDATE SKIP LOCKED
+func (repository *PostgresWriteJournalRepository) ClaimOldest(ctx context.Context, cutoff time.Time) (*models.WriteJournalEntry, error) {
+	claimQuery := `
+		SELECT ` + writeJournalColumns + `
+		FROM write_journal
+		WHERE created_at < $1
+		ORDER BY created_at, id
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`
+
+	entry, scanError := scanWriteJournalEntry(executorFor(ctx, repository.databaseConnection).QueryRowContext(ctx, claimQuery, cutoff))
+	if errors.Is(scanError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return entry, scanError
+}
+
+// scanWriteJournalEntry reads one row selected with writeJournalColumns
+func scanWriteJournalEntry(row *sql.Row) (*models.WriteJournalEntry, error) {
+	entry := &models.WriteJournalEntry{}
+	var operation string
+	scanError := row.Scan(&entry.ID, &entry.ResourceType, &entry.ResourceID, &operation, &entry.TenantID, &entry.PreviousPatientID, &entry.CreatedAt)
+	if scanError != nil {
+		return nil, scanError
+	}
+	entry.Operation = models.ChangeOperation(operation)
+	return entry, nil
+}
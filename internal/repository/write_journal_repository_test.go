@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// cleanupWriteJournalTestData removes all entries from the write_journal table
+func cleanupWriteJournalTestData(t *testing.T, databaseConnection *sql.DB) {
+	_, deleteError := databaseConnection.Exec("DELETE FROM write_journal")
+	if deleteError != nil {
+		t.Fatalf("Failed to cleanup write journal: %v", deleteError)
+	}
+}
+
+// TestPostgresWriteJournalRepository_AppendClaimComplete verifies entries are claimed oldest first and removed on completion
+func TestPostgresWriteJournalRepository_AppendClaimComplete(t *testing.T) {
+	databaseConnection := setupTestDatabase(t)
+	defer databaseConnection.Close()
+	cleanupWriteJournalTestData(t, databaseConnection)
+	defer cleanupWriteJournalTestData(t, databaseConnection)
+
+	journalRepository := NewPostgresWriteJournalRepository(databaseConnection)
+	ctx := context.Background()
+
+	firstEntry, appendError := journalRepository.Append(ctx, &models.WriteJournalEntry{
+		ResourceType:      "Observation",
+		ResourceID:        "obs-1",
+		Operation:         models.ChangeOperationUpdate,
+		TenantID:          "tenant-a",
+		PreviousPatientID: "patient-1",
+	})
+	if appendError != nil {
+		t.Fatalf("Expected no error, got %v", appendError)
+	}
+	journalRepository.Append(ctx, &models.WriteJournalEntry{ResourceType: "Observation", ResourceID: "obs-2", Operation: models.ChangeOperationCreate})
+
+	if entry, claimError := journalRepository.ClaimOldest(ctx, firstEntry.CreatedAt); claimError != nil || entry != nil {
+		t.Fatalf("Expected nothing before the first entry, got %+v (%v)", entry, claimError)
+	}
+
+	claimedEntry, claimError := journalRepository.ClaimOldest(ctx, time.Now().Add(time.Minute))
+	if claimError != nil {
+		t.Fatalf("Expected no error, got %v", claimError)
+	}
+	if claimedEntry == nil || claimedEntry.ID != firstEntry.ID || claimedEntry.TenantID != "tenant-a" || claimedEntry.PreviousPatientID != "patient-1" {
+		t.Fatalf("Expected the oldest entry, got %+v", claimedEntry)
+	}
+
+	if completeError := journalRepository.Complete(ctx, firstEntry.ID); completeError != nil {
+		t.Fatalf("Expected no error, got %v", completeError)
+	}
+	nextEntry, _ := journalRepository.ClaimOldest(ctx, time.Now().Add(time.Minute))
+	if nextEntry == nil || nextEntry.ResourceID != "obs-2" {
+		t.Errorf("Expected the second entry after completing the first, got %+v", nextEntry)
+	}
+}
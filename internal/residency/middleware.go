@@ -0,0 +1,58 @@
+package residency
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// HeaderHomeRegion names the home region of a tenant refused with 451
+const HeaderHomeRegion = "X-Home-Region"
+
+// tenantDataPrefixes are the routes that read or write tenant data; health, readiness, and
+// admin routes serve the deployment itself
+var tenantDataPrefixes = []string{"/fhir/", "/sync/", "/stream/", "/locks/"}
+
+// metadataPath serves the CapabilityStatement, which describes the server rather than any tenant's data
+const metadataPath = "/fhir/metadata"
+
+// Middleware refuses tenant data requests for tenants not homed in the local region
+// The tenant named by X-Tenant-ID counts as well as one from an API key: a client naming a tenant
+// homed elsewhere is refused rather than having that tenant's data stored here, so tenant.Resolver
+// must run first
+func Middleware(policy *Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isTenantDataPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			tenantID := tenant.FromContext(r.Context())
+			if tenantError := policy.CheckTenant(tenantID); tenantError != nil {
+				if homeRegion := policy.config.HomeRegion(tenantID); homeRegion != "" {
+					w.Header().Set(HeaderHomeRegion, homeRegion)
+				}
+				middleware.WriteError(w, r, tenantError)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isTenantDataPath reports whether the path reads or writes tenant data
+func isTenantDataPath(path string) bool {
+	if path == metadataPath {
+		return false
+	}
+	for _, prefix := range tenantDataPrefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
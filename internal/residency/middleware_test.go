@@ -0,0 +1,47 @@
+package residency
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// TestMiddleware verifies tenant data routes are limited to local tenants and deployment routes are not
+func TestMiddleware(t *testing.T) {
+	policy, _ := NewPolicy(testConfig(), "eu")
+	guarded := Middleware(policy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	testCases := []struct {
+		name           string
+		path           string
+		tenantID       string
+		expectedStatus int
+		expectedRegion string
+	}{
+		{"local tenant", "/fhir/Patient", "acme", http.StatusOK, ""},
+		{"foreign tenant", "/fhir/Patient", "globex", http.StatusUnavailableForLegalReasons, "us"},
+		{"tenant homed by default", "/sync/changes", "initech", http.StatusUnavailableForLegalReasons, "us"},
+		{"deployment route", "/admin/operations", "globex", http.StatusOK, ""},
+		{"capability statement", "/fhir/metadata", "globex", http.StatusOK, ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, testCase.path, nil)
+			request = request.WithContext(tenant.WithTenant(request.Context(), testCase.tenantID))
+			recorder := httptest.NewRecorder()
+			guarded.ServeHTTP(recorder, request)
+
+			if recorder.Code != testCase.expectedStatus {
+				t.Errorf("Expected status %d, got %d", testCase.expectedStatus, recorder.Code)
+			}
+			if homeRegion := recorder.Header().Get(HeaderHomeRegion); homeRegion != testCase.expectedRegion {
+				t.Errorf("Expected home region %q, got %q", testCase.expectedRegion, homeRegion)
+			}
+		})
+	}
+}
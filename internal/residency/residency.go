@@ -0,0 +1,122 @@
+package residency
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/database"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+)
+
+// Region is one data residency region and the storage backends that hold its tenants' data
+type Region struct {
+	// BaseURL is the region's public API address, given to clients whose data lives there
+	BaseURL  string                  `json:"base_url"`
+	Postgres database.PostgresConfig `json:"postgres"`
+	MongoDB  database.MongoConfig    `json:"mongodb"`
+}
+
+// Config maps tenants to their home regions
+// The same file is deployed to every region; each deployment names its own region separately
+type Config struct {
+	Regions map[string]Region `json:"regions"`
+	Tenants map[string]string `json:"tenants"`
+
+	// DefaultRegion is the home of tenants not listed in Tenants; when empty they are refused
+	DefaultRegion string `json:"default_region"`
+}
+
+// LoadConfig reads and validates a residency configuration from a JSON file
+func LoadConfig(path string) (Config, error) {
+	var config Config
+
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return config, fmt.Errorf("failed to read residency config: %w", readError)
+	}
+
+	if decodeError := json.Unmarshal(fileBytes, &config); decodeError != nil {
+		return config, fmt.Errorf("failed to parse residency config: %w", decodeError)
+	}
+
+	return config, config.Validate()
+}
+
+// Validate checks that every region has an address and every tenant's home region is defined
+func (config Config) Validate() error {
+	if len(config.Regions) == 0 {
+		return errors.New("residency config defines no regions")
+	}
+	for regionName, region := range config.Regions {
+		if !strings.HasPrefix(region.BaseURL, "http://") && !strings.HasPrefix(region.BaseURL, "https://") {
+			return fmt.Errorf("region %q needs an http(s) base_url", regionName)
+		}
+	}
+	if _, known := config.Regions[config.DefaultRegion]; config.DefaultRegion != "" && !known {
+		return fmt.Errorf("default_region %q is not a defined region", config.DefaultRegion)
+	}
+	for tenantID, regionName := range config.Tenants {
+		if _, known := config.Regions[regionName]; !known {
+			return fmt.Errorf("tenant %q has undefined home region %q", tenantID, regionName)
+		}
+	}
+	return nil
+}
+
+// HomeRegion returns the tenant's home region, or "" when it has none
+func (config Config) HomeRegion(tenantID string) string {
+	if regionName, listed := config.Tenants[tenantID]; listed {
+		return regionName
+	}
+	return config.DefaultRegion
+}
+
+// Policy keeps a regional deployment to the tenants homed in its region
+type Policy struct {
+	config      Config
+	localRegion string
+}
+
+// NewPolicy creates the policy for the deployment running in localRegion
+func NewPolicy(config Config, localRegion string) (*Policy, error) {
+	if _, known := config.Regions[localRegion]; !known {
+		return nil, fmt.Errorf("local region %q is not a defined region", localRegion)
+	}
+	return &Policy{config: config, localRegion: localRegion}, nil
+}
+
+// LocalRegion returns the name and definition of the region this deployment serves
+func (policy *Policy) LocalRegion() (string, Region) {
+	return policy.localRegion, policy.config.Regions[policy.localRegion]
+}
+
+// CheckTenant allows tenants homed in the local region
+// Tenants homed elsewhere get a 451 naming their region; tenants without a home region get a 403
+func (policy *Policy) CheckTenant(tenantID string) error {
+	homeRegion := policy.config.HomeRegion(tenantID)
+	switch homeRegion {
+	case policy.localRegion:
+		return nil
+	case "":
+		return apperrors.Forbidden("Tenant " + tenantID + " has no home region, so its data cannot be stored in any region")
+	}
+	return apperrors.UnavailableForLegalReasons(fmt.Sprintf("Tenant %s's data is held in region %s and cannot be accessed from region %s; use %s",
+		tenantID, homeRegion, policy.localRegion, policy.config.Regions[homeRegion].BaseURL))
+}
+
+// CheckReference refuses absolute references to resources held by another region's deployment,
+// which would link data across the residency boundary; relative and external references are allowed
+func (policy *Policy) CheckReference(reference string) error {
+	for regionName, region := range policy.config.Regions {
+		if regionName == policy.localRegion {
+			continue
+		}
+		if strings.HasPrefix(reference, strings.TrimSuffix(region.BaseURL, "/")+"/") {
+			return apperrors.Forbidden("Reference " + reference + " points to data held in region " + regionName + "; cross-region references are not allowed")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package residency
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+)
+
+// testConfig homes acme in the EU and globex in the US, with unlisted tenants homed in the US
+func testConfig() Config {
+	return Config{
+		Regions: map[string]Region{
+			"eu": {BaseURL: "https://eu.fhir.example.org"},
+			"us": {BaseURL: "https://us.fhir.example.org/"},
+		},
+		Tenants:       map[string]string{"acme": "eu", "globex": "us"},
+		DefaultRegion: "us",
+	}
+}
+
+// statusOf returns the HTTP status of an AppError, or 0 for nil and other errors
+func statusOf(err error) int {
+	var appError *apperrors.AppError
+	if errors.As(err, &appError) {
+		return appError.StatusCode
+	}
+	return 0
+}
+
+// TestLoadConfig verifies a region file is read and its tenant homes are validated
+func TestLoadConfig(t *testing.T) {
+	configPath := filepath.Join(t.TempDir(), "residency.json")
+	os.WriteFile(configPath, []byte(`{"regions": {"eu": {"base_url": "https://eu.fhir.example.org", "postgres": {"host": "pg.eu.internal", "dbname": "fhir"}}},
+		"tenants": {"acme": "eu"}}`), 0o600)
+
+	config, loadError := LoadConfig(configPath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if config.Regions["eu"].Postgres.Host != "pg.eu.internal" || config.HomeRegion("acme") != "eu" || config.HomeRegion("globex") != "" {
+		t.Errorf("Unexpected config %+v", config)
+	}
+
+	invalidConfigs := map[string]Config{
+		"no regions":       {},
+		"missing base URL": {Regions: map[string]Region{"eu": {}}},
+		"unknown default":  {Regions: testConfig().Regions, DefaultRegion: "apac"},
+		"unknown home":     {Regions: testConfig().Regions, Tenants: map[string]string{"acme": "apac"}},
+	}
+	for name, invalidConfig := range invalidConfigs {
+		if validateError := invalidConfig.Validate(); validateError == nil {
+			t.Errorf("Expected %s to be rejected", name)
+		}
+	}
+}
+
+// TestPolicy_CheckTenant verifies local tenants pass, foreign tenants get 451, and homeless tenants get 403
+func TestPolicy_CheckTenant(t *testing.T) {
+	config := testConfig()
+	config.DefaultRegion = ""
+	policy, policyError := NewPolicy(config, "eu")
+	if policyError != nil {
+		t.Fatalf("Expected no error, got %v", policyError)
+	}
+
+	if checkError := policy.CheckTenant("acme"); checkError != nil {
+		t.Errorf("Expected acme to be allowed in eu, got %v", checkError)
+	}
+	if status := statusOf(policy.CheckTenant("globex")); status != http.StatusUnavailableForLegalReasons {
+		t.Errorf("Expected 451 for a US tenant, got %d", status)
+	}
+	if status := statusOf(policy.CheckTenant("initech")); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a tenant without a home region, got %d", status)
+	}
+
+	if _, unknownError := NewPolicy(config, "apac"); unknownError == nil {
+		t.Error("Expected an undefined local region to be rejected")
+	}
+}
+
+// TestPolicy_CheckReference verifies references into another region are refused
+func TestPolicy_CheckReference(t *testing.T) {
+	policy, _ := NewPolicy(testConfig(), "eu")
+
+	allowedReferences := []string{"Patient/123", "https://eu.fhir.example.org/fhir/Patient/123", "https://registry.example.com/Patient/9"}
+	for _, reference := range allowedReferences {
+		if checkError := policy.CheckReference(reference); checkError != nil {
+			t.Errorf("Expected %s to be allowed, got %v", reference, checkError)
+		}
+	}
+	if status := statusOf(policy.CheckReference("https://us.fhir.example.org/fhir/Patient/123")); status != http.StatusForbidden {
+		t.Errorf("Expected 403 for a US reference, got %d", status)
+	}
+}
@@ -0,0 +1,214 @@
+package rollup
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/reconcile"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrAlreadyRunning is returned when a backfill is requested while a run is in progress or queued
+var ErrAlreadyRunning = errors.New("a rollup run is already in progress")
+
+// ErrInvalidRange is returned for backfills whose end is not after their start
+var ErrInvalidRange = errors.New("rollup range must end after it starts")
+
+// day is the length of one rollup period
+const day = 24 * time.Hour
+
+// Source computes daily aggregates from the stored observations
+type Source interface {
+	DailyAggregates(ctx context.Context, from time.Time, to time.Time) ([]*models.ObservationRollup, error)
+}
+
+// Store replaces the stored rollups for a day range
+type Store interface {
+	ReplaceDays(ctx context.Context, from time.Time, to time.Time, rollups []*models.ObservationRollup) error
+}
+
+// Run describes one rollup run over the days in [From, To)
+type Run struct {
+	From       time.Time  `json:"from"`
+	To         time.Time  `json:"to"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	// DaysCompleted counts the days recomputed so far
+	DaysCompleted int `json:"days_completed"`
+
+	// Rollups is the number of daily aggregates stored
+	Rollups int64 `json:"rollups"`
+
+	// Error is set when the run stopped before reaching To
+	Error string `json:"error,omitempty"`
+}
+
+// Status reports the run in progress and the last finished run
+type Status struct {
+	Current *Run `json:"current,omitempty"`
+	Last    *Run `json:"last,omitempty"`
+}
+
+// Job recomputes daily observation rollups every night and on request for backfills
+type Job struct {
+	source Source
+	store  Store
+
+	// lookbackDays is how many recent days the nightly run recomputes, to pick up late and edited observations
+	lookbackDays int
+
+	backfills chan Run
+
+	mutex   sync.Mutex
+	current *Run
+	queued  bool
+	last    *Run
+
+	// now is replaceable for tests
+	now func() time.Time
+}
+
+// NewJob creates a rollup job whose nightly run recomputes the last lookbackDays days
+func NewJob(source Source, store Store, lookbackDays int) *Job {
+	return &Job{
+		source:       source,
+		store:        store,
+		lookbackDays: lookbackDays,
+		backfills:    make(chan Run, 1),
+		now:          time.Now,
+	}
+}
+
+// RequestBackfill queues a recomputation of every day from the day of from up to the day before to
+// Run picks it up; only one run may be in progress or queued at a time
+func (job *Job) RequestBackfill(from time.Time, to time.Time) (*Run, error) {
+	requestedRun := Run{From: truncateDay(from), To: truncateDay(to)}
+	if !requestedRun.To.After(requestedRun.From) {
+		return nil, ErrInvalidRange
+	}
+
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+	if job.current != nil || job.queued {
+		return nil, ErrAlreadyRunning
+	}
+	job.queued = true
+	job.backfills <- requestedRun
+	return &requestedRun, nil
+}
+
+// Status returns copies of the current and last runs
+func (job *Job) Status() Status {
+	job.mutex.Lock()
+	defer job.mutex.Unlock()
+
+	var status Status
+	if job.current != nil {
+		currentRun := *job.current
+		status.Current = &currentRun
+	}
+	if job.last != nil {
+		lastRun := *job.last
+		status.Last = &lastRun
+	}
+	return status
+}
+
+// Run recomputes recent days every day at the given UTC hour, and runs requested backfills, until ctx is cancelled
+func (job *Job) Run(ctx context.Context, hourUTC int) {
+	for {
+		timer := time.NewTimer(reconcile.NextRun(job.now(), hourUTC).Sub(job.now()))
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case requestedRun := <-job.backfills:
+			timer.Stop()
+			job.execute(ctx, requestedRun, true)
+		case <-timer.C:
+			today := truncateDay(job.now())
+			job.execute(ctx, Run{From: today.AddDate(0, 0, -job.lookbackDays), To: today.AddDate(0, 0, 1)}, false)
+		}
+	}
+}
+
+// execute recomputes the run's range one day at a time, so each day is replaced in its own transaction
+// and a failure leaves every earlier day recomputed
+// A nightly run that finds a backfill in progress is skipped; the backfill covers it
+func (job *Job) execute(ctx context.Context, run Run, backfill bool) {
+	job.mutex.Lock()
+	if backfill {
+		job.queued = false
+	} else if job.current != nil || job.queued {
+		job.mutex.Unlock()
+		return
+	}
+	run.StartedAt = job.now()
+	job.current = &run
+	job.mutex.Unlock()
+
+	var runError error
+	for dayStart := run.From; dayStart.Before(run.To); dayStart = dayStart.Add(day) {
+		var stored int64
+		stored, runError = job.recomputeDay(ctx, dayStart)
+		if runError != nil {
+			break
+		}
+
+		job.mutex.Lock()
+		job.current.DaysCompleted++
+		job.current.Rollups += stored
+		job.mutex.Unlock()
+	}
+
+	job.mutex.Lock()
+	finishedAt := job.now()
+	job.current.FinishedAt = &finishedAt
+	if runError != nil {
+		job.current.Error = runError.Error()
+	}
+	job.last = job.current
+	job.current = nil
+	finishedRun := *job.last
+	job.mutex.Unlock()
+
+	logRun(&finishedRun)
+}
+
+// recomputeDay aggregates one UTC day and replaces its stored rollups
+func (job *Job) recomputeDay(ctx context.Context, dayStart time.Time) (int64, error) {
+	dayEnd := dayStart.Add(day)
+	rollups, aggregateError := job.source.DailyAggregates(ctx, dayStart, dayEnd)
+	if aggregateError != nil {
+		return 0, aggregateError
+	}
+	if replaceError := job.store.ReplaceDays(ctx, dayStart, dayEnd, rollups); replaceError != nil {
+		return 0, replaceError
+	}
+	return int64(len(rollups)), nil
+}
+
+// logRun writes a run summary, at error level when it failed
+func logRun(run *Run) {
+	logEvent := log.Info()
+	if run.Error != "" {
+		logEvent = log.Error().Str("error", run.Error)
+	}
+	logEvent.
+		Time("from", run.From).
+		Time("to", run.To).
+		Int("days_completed", run.DaysCompleted).
+		Int64("rollups", run.Rollups).
+		Msg("Observation rollup finished")
+}
+
+// truncateDay returns the start of the UTC day containing moment
+func truncateDay(moment time.Time) time.Time {
+	utcMoment := moment.UTC()
+	return time.Date(utcMoment.Year(), utcMoment.Month(), utcMoment.Day(), 0, 0, 0, 0, time.UTC)
+}
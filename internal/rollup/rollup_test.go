@@ -0,0 +1,96 @@
+package rollup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// fakeSource returns one rollup per day and fails on failDay
+type fakeSource struct {
+	requestedDays []time.Time
+	failDay       time.Time
+}
+
+// DailyAggregates records the requested day and returns a single rollup for it
+func (source *fakeSource) DailyAggregates(ctx context.Context, from time.Time, to time.Time) ([]*models.ObservationRollup, error) {
+	if from.Equal(source.failDay) {
+		return nil, errors.New("aggregation failed")
+	}
+	source.requestedDays = append(source.requestedDays, from)
+	return []*models.ObservationRollup{{PatientID: "p1", Code: "8867-4", Day: from, Count: 2, Min: 60, Max: 80, Sum: 140}}, nil
+}
+
+// fakeStore keeps the rollups of every replaced day
+type fakeStore struct {
+	days map[time.Time][]*models.ObservationRollup
+}
+
+// ReplaceDays stores the rollups under their range start
+func (store *fakeStore) ReplaceDays(ctx context.Context, from time.Time, to time.Time, rollups []*models.ObservationRollup) error {
+	if to.Sub(from) != day {
+		return errors.New("expected a single day")
+	}
+	store.days[from] = rollups
+	return nil
+}
+
+// TestJob_BackfillRecomputesEachDay verifies a backfill replaces each day separately and reports progress
+func TestJob_BackfillRecomputesEachDay(t *testing.T) {
+	source := &fakeSource{}
+	store := &fakeStore{days: map[time.Time][]*models.ObservationRollup{}}
+	job := NewJob(source, store, 3)
+
+	firstDay := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	requestedRun, requestError := job.RequestBackfill(firstDay.Add(15*time.Hour), firstDay.AddDate(0, 0, 3))
+	if requestError != nil {
+		t.Fatalf("Expected no error, got %v", requestError)
+	}
+	if !requestedRun.From.Equal(firstDay) {
+		t.Errorf("Expected the range to start at the day boundary, got %v", requestedRun.From)
+	}
+	if _, secondError := job.RequestBackfill(firstDay, firstDay.AddDate(0, 0, 1)); !errors.Is(secondError, ErrAlreadyRunning) {
+		t.Errorf("Expected ErrAlreadyRunning while a backfill is queued, got %v", secondError)
+	}
+
+	job.execute(context.Background(), <-job.backfills, true)
+
+	if len(store.days) != 3 || len(source.requestedDays) != 3 {
+		t.Fatalf("Expected 3 days recomputed, got %d stored", len(store.days))
+	}
+	lastRun := job.Status().Last
+	if lastRun == nil || lastRun.DaysCompleted != 3 || lastRun.Rollups != 3 || lastRun.Error != "" || lastRun.FinishedAt == nil {
+		t.Errorf("Expected a completed run of 3 days, got %+v", lastRun)
+	}
+	if _, nextError := job.RequestBackfill(firstDay, firstDay.AddDate(0, 0, 1)); nextError != nil {
+		t.Errorf("Expected a new backfill to be accepted after the run, got %v", nextError)
+	}
+}
+
+// TestJob_FailureKeepsCompletedDays verifies a failing day stops the run and earlier days stay recomputed
+func TestJob_FailureKeepsCompletedDays(t *testing.T) {
+	firstDay := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	source := &fakeSource{failDay: firstDay.AddDate(0, 0, 1)}
+	store := &fakeStore{days: map[time.Time][]*models.ObservationRollup{}}
+	job := NewJob(source, store, 3)
+
+	job.execute(context.Background(), Run{From: firstDay, To: firstDay.AddDate(0, 0, 3)}, false)
+
+	lastRun := job.Status().Last
+	if lastRun.DaysCompleted != 1 || lastRun.Error == "" || len(store.days) != 1 {
+		t.Errorf("Expected the run to stop after one day with an error, got %+v", lastRun)
+	}
+}
+
+// TestJob_RejectsEmptyRange verifies a backfill must cover at least one day
+func TestJob_RejectsEmptyRange(t *testing.T) {
+	job := NewJob(&fakeSource{}, &fakeStore{}, 3)
+	moment := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	if _, requestError := job.RequestBackfill(moment, moment.Add(time.Hour)); !errors.Is(requestError, ErrInvalidRange) {
+		t.Errorf("Expected ErrInvalidRange, got %v", requestError)
+	}
+}
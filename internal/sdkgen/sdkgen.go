@@ -0,0 +1,427 @@
+package sdkgen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"net/http"
+	"strings"
+	"text/template"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/capability"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// searchField is one search parameter as a client SDK exposes it
+type searchField struct {
+	Parameter     string
+	GoName        string
+	Documentation string
+	Repeatable    bool
+	Number        bool
+}
+
+// operationMethod is one $operation as a client SDK method
+type operationMethod struct {
+	Name          string
+	GoName        string
+	Documentation string
+	Instance      bool
+	Post          bool
+}
+
+// resourceView is what the templates need to know about one resource type
+type resourceView struct {
+	Name         string
+	Create       bool
+	Read         bool
+	Update       bool
+	Delete       bool
+	Search       bool
+	SearchFields []searchField
+	Operations   []operationMethod
+}
+
+// GenerateGo renders the Go client for the resources as a gofmt-formatted file in packageName
+func GenerateGo(resources []capability.Resource, packageName string) ([]byte, error) {
+	var rendered bytes.Buffer
+	templateData := map[string]any{"Package": packageName, "Resources": views(resources)}
+	if renderError := goTemplate.Execute(&rendered, templateData); renderError != nil {
+		return nil, fmt.Errorf("failed to render Go client: %w", renderError)
+	}
+
+	formatted, formatError := format.Source(rendered.Bytes())
+	if formatError != nil {
+		return nil, fmt.Errorf("generated Go client does not parse: %w", formatError)
+	}
+	return formatted, nil
+}
+
+// GenerateTypeScript renders the TypeScript client for the resources
+func GenerateTypeScript(resources []capability.Resource) ([]byte, error) {
+	var rendered bytes.Buffer
+	if renderError := typeScriptTemplate.Execute(&rendered, map[string]any{"Resources": views(resources)}); renderError != nil {
+		return nil, fmt.Errorf("failed to render TypeScript client: %w", renderError)
+	}
+	return rendered.Bytes(), nil
+}
+
+// views converts the resource descriptions into template data
+func views(resources []capability.Resource) []resourceView {
+	resourceViews := make([]resourceView, 0, len(resources))
+	for _, resource := range resources {
+		view := resourceView{
+			Name:   resource.Name(),
+			Create: resource.Supports(fhir.TypeRestfulInteractionCreate),
+			Read:   resource.Supports(fhir.TypeRestfulInteractionRead),
+			Update: resource.Supports(fhir.TypeRestfulInteractionUpdate),
+			Delete: resource.Supports(fhir.TypeRestfulInteractionDelete),
+			Search: resource.Supports(fhir.TypeRestfulInteractionSearchType),
+		}
+		for _, parameter := range resource.SearchParameters {
+			view.SearchFields = append(view.SearchFields, searchField{
+				Parameter:     parameter.Name,
+				GoName:        exportedName(parameter.Name),
+				Documentation: parameter.Documentation,
+				Repeatable:    parameter.Repeatable,
+				Number:        parameter.Type == fhir.SearchParamTypeNumber,
+			})
+		}
+		for _, operation := range resource.Operations {
+			view.Operations = append(view.Operations, operationMethod{
+				Name:          operation.Name,
+				GoName:        view.Name + exportedName(operation.Name),
+				Documentation: operation.Documentation,
+				Instance:      operation.Instance,
+				Post:          operation.Method == http.MethodPost,
+			})
+		}
+		resourceViews = append(resourceViews, view)
+	}
+	return resourceViews
+}
+
+// exportedName turns a parameter or operation name such as "_tag" or "meta-add" into "Tag" or "MetaAdd"
+func exportedName(name string) string {
+	var nameBuilder strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(character rune) bool { return character == '_' || character == '-' }) {
+		nameBuilder.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return nameBuilder.String()
+}
+
+// lowerFirst turns a Go method name into its TypeScript spelling
+func lowerFirst(name string) string {
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// propertyName quotes a parameter name such as "clinical-status" that is not a valid TypeScript identifier
+func propertyName(name string) string {
+	if strings.Contains(name, "-") {
+		return `"` + name + `"`
+	}
+	return name
+}
+
+// templateFunctions are shared by both language templates
+var templateFunctions = template.FuncMap{"lowerFirst": lowerFirst, "propertyName": propertyName}
+
+// goTemplate renders the Go client
+var goTemplate = template.Must(template.New("go").Funcs(templateFunctions).Parse(`// Code generated by cmd/sdkgen from the server's CapabilityStatement. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Client calls the FHIR API of one server
+type Client struct {
+	// BaseURL is the server address, e.g. https://fhir.example.com
+	BaseURL string
+
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+
+	// Header is sent with every request, e.g. X-API-Key or X-Tenant-ID
+	Header http.Header
+}
+
+// NewClient creates a client for the server at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Header:     http.Header{},
+	}
+}
+
+// Error is returned for responses outside 2xx; Outcome is set when the server sent an OperationOutcome
+type Error struct {
+	StatusCode int
+	Outcome    *fhir.OperationOutcome
+	Body       []byte
+}
+
+// Error summarizes the status and the first OperationOutcome issue
+func (responseError *Error) Error() string {
+	if responseError.Outcome != nil && len(responseError.Outcome.Issue) > 0 && responseError.Outcome.Issue[0].Diagnostics != nil {
+		return fmt.Sprintf("fhir server returned %d: %s", responseError.StatusCode, *responseError.Outcome.Issue[0].Diagnostics)
+	}
+	return fmt.Sprintf("fhir server returned %d", responseError.StatusCode)
+}
+
+// do sends one request with body encoded as FHIR JSON, decoding a 2xx response into result when it is not nil
+func (client *Client) do(ctx context.Context, method string, path string, query url.Values, body any, result any) error {
+	requestURL := client.BaseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var requestBody io.Reader
+	if body != nil {
+		encodedBody, encodeError := json.Marshal(body)
+		if encodeError != nil {
+			return fmt.Errorf("failed to encode request: %w", encodeError)
+		}
+		requestBody = bytes.NewReader(encodedBody)
+	}
+
+	request, requestError := http.NewRequestWithContext(ctx, method, requestURL, requestBody)
+	if requestError != nil {
+		return requestError
+	}
+	for headerName, headerValues := range client.Header {
+		request.Header[headerName] = headerValues
+	}
+	request.Header.Set("Accept", "application/fhir+json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/fhir+json")
+	}
+
+	response, responseError := client.HTTPClient.Do(request)
+	if responseError != nil {
+		return responseError
+	}
+	defer response.Body.Close()
+
+	responseBody, readError := io.ReadAll(response.Body)
+	if readError != nil {
+		return fmt.Errorf("failed to read response: %w", readError)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		serverError := &Error{StatusCode: response.StatusCode, Body: responseBody}
+		var operationOutcome fhir.OperationOutcome
+		if json.Unmarshal(responseBody, &operationOutcome) == nil && len(operationOutcome.Issue) > 0 {
+			serverError.Outcome = &operationOutcome
+		}
+		return serverError
+	}
+
+	if result == nil || len(responseBody) == 0 {
+		return nil
+	}
+	if decodeError := json.Unmarshal(responseBody, result); decodeError != nil {
+		return fmt.Errorf("failed to decode response: %w", decodeError)
+	}
+	return nil
+}
+
+// searchMatches runs a search and decodes the match entries of the searchset Bundle it returns
+func searchMatches[Resource any](ctx context.Context, client *Client, path string, query url.Values) ([]Resource, error) {
+	var searchset fhir.Bundle
+	if searchError := client.do(ctx, http.MethodGet, path, query, nil, &searchset); searchError != nil {
+		return nil, searchError
+	}
+	resources := make([]Resource, 0, len(searchset.Entry))
+	for _, entry := range searchset.Entry {
+		if entry.Search != nil && entry.Search.Mode != nil && *entry.Search.Mode != fhir.SearchEntryModeMatch {
+			continue
+		}
+		var resource Resource
+		if decodeError := json.Unmarshal(entry.Resource, &resource); decodeError != nil {
+			return nil, fmt.Errorf("failed to decode search entry: %w", decodeError)
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+{{range $resource := .Resources}}{{if .Search}}
+// {{.Name}}Search holds the {{.Name}} search parameters; zero values are left out
+type {{.Name}}Search struct {
+{{- range .SearchFields}}
+	// {{.GoName}} is {{.Parameter}}: {{.Documentation}}
+	{{.GoName}} {{if .Repeatable}}[]string{{else if .Number}}int{{else}}string{{end}}
+{{- end}}
+}
+
+// values encodes the search as query parameters
+func (search {{.Name}}Search) values() url.Values {
+	query := url.Values{}
+{{- range .SearchFields}}
+{{- if .Repeatable}}
+	for _, value := range search.{{.GoName}} {
+		query.Add("{{.Parameter}}", value)
+	}
+{{- else if .Number}}
+	if search.{{.GoName}} > 0 {
+		query.Set("{{.Parameter}}", strconv.Itoa(search.{{.GoName}}))
+	}
+{{- else}}
+	if search.{{.GoName}} != "" {
+		query.Set("{{.Parameter}}", search.{{.GoName}})
+	}
+{{- end}}
+{{- end}}
+	return query
+}
+
+// Search{{.Name}} returns the {{.Name}} resources matching search
+func (client *Client) Search{{.Name}}(ctx context.Context, search {{.Name}}Search) ([]fhir.{{.Name}}, error) {
+	return searchMatches[fhir.{{.Name}}](ctx, client, "/fhir/{{.Name}}", search.values())
+}
+{{end}}{{if .Create}}
+// Create{{.Name}} creates a {{.Name}} and returns it as stored
+func (client *Client) Create{{.Name}}(ctx context.Context, resource *fhir.{{.Name}}) (*fhir.{{.Name}}, error) {
+	var created fhir.{{.Name}}
+	if createError := client.do(ctx, http.MethodPost, "/fhir/{{.Name}}", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+{{end}}{{if .Read}}
+// Read{{.Name}} returns the {{.Name}} with the given ID
+func (client *Client) Read{{.Name}}(ctx context.Context, id string) (*fhir.{{.Name}}, error) {
+	var resource fhir.{{.Name}}
+	if readError := client.do(ctx, http.MethodGet, "/fhir/{{.Name}}/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+{{end}}{{if .Update}}
+// Update{{.Name}} replaces the {{.Name}} with the given ID and returns it as stored
+func (client *Client) Update{{.Name}}(ctx context.Context, id string, resource *fhir.{{.Name}}) (*fhir.{{.Name}}, error) {
+	var updated fhir.{{.Name}}
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/{{.Name}}/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+{{end}}{{if .Delete}}
+// Delete{{.Name}} deletes the {{.Name}} with the given ID
+func (client *Client) Delete{{.Name}}(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/{{.Name}}/"+url.PathEscape(id), nil, nil, nil)
+}
+{{end}}{{range .Operations}}
+// {{.GoName}} invokes ${{.Name}}: {{.Documentation}}
+func (client *Client) {{.GoName}}(ctx context.Context{{if .Instance}}, id string{{end}}, {{if .Post}}body any{{else}}parameters url.Values{{end}}) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, {{if .Post}}http.MethodPost{{else}}http.MethodGet{{end}}, {{if .Instance}}"/fhir/{{$resource.Name}}/"+url.PathEscape(id)+"/${{.Name}}"{{else}}"/fhir/{{$resource.Name}}/${{.Name}}"{{end}}, {{if .Post}}nil, body{{else}}parameters, nil{{end}}, &result)
+}
+{{end}}{{end}}`))
+
+// typeScriptTemplate renders the TypeScript client
+var typeScriptTemplate = template.Must(template.New("typescript").Funcs(templateFunctions).Parse(`// Code generated by cmd/sdkgen from the server's CapabilityStatement. DO NOT EDIT.
+
+/** A FHIR resource as JSON */
+export type Resource = { resourceType: string; id?: string; [element: string]: unknown };
+
+/** Thrown for responses outside 2xx; outcome is set when the server sent an OperationOutcome */
+export class FhirError extends Error {
+  constructor(readonly status: number, readonly outcome?: Resource) {
+    super(` + "`fhir server returned ${status}`" + `);
+  }
+}
+{{range .Resources}}{{if .Search}}
+/** {{.Name}} search parameters; absent values are left out */
+export interface {{.Name}}Search {
+{{- range .SearchFields}}
+  /** {{.Documentation}} */
+  {{propertyName .Parameter}}?: {{if .Repeatable}}string[]{{else if .Number}}number{{else}}string{{end}};
+{{- end}}
+}
+{{end}}{{end}}
+/** The match entries of a searchset Bundle; included resources and outcomes are left out */
+function searchMatches(searchset: Resource): Resource[] {
+  const entries = (searchset.entry ?? []) as { resource: Resource; search?: { mode?: string } }[];
+  return entries.filter((entry) => (entry.search?.mode ?? "match") === "match").map((entry) => entry.resource);
+}
+
+/** Calls the FHIR API of one server */
+export class FhirClient {
+  /** headers are sent with every request, e.g. X-API-Key or X-Tenant-ID */
+  constructor(private readonly baseUrl: string, private readonly headers: Record<string, string> = {}) {
+    this.baseUrl = baseUrl.replace(/\/$/, "");
+  }
+
+  /** Sends one request with body encoded as FHIR JSON and returns the decoded response */
+  private async request<T>(method: string, path: string, query?: Record<string, string | number | string[] | undefined>, body?: unknown): Promise<T> {
+    const params = new URLSearchParams();
+    for (const [name, value] of Object.entries(query ?? {})) {
+      if (Array.isArray(value)) {
+        value.forEach((item) => params.append(name, item));
+      } else if (value !== undefined) {
+        params.set(name, String(value));
+      }
+    }
+    const queryString = params.toString();
+    const headers: Record<string, string> = { ...this.headers, Accept: "application/fhir+json" };
+    if (body !== undefined) {
+      headers["Content-Type"] = "application/fhir+json";
+    }
+
+    const response = await fetch(this.baseUrl + path + (queryString ? "?" + queryString : ""), {
+      method,
+      headers,
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    const text = await response.text();
+    const payload = text ? JSON.parse(text) : undefined;
+    if (!response.ok) {
+      throw new FhirError(response.status, payload?.resourceType === "OperationOutcome" ? payload : undefined);
+    }
+    return payload as T;
+  }
+{{range $resource := .Resources}}{{if .Search}}
+  /** Returns the {{.Name}} resources matching search */
+  async search{{.Name}}(search: {{.Name}}Search = {}): Promise<Resource[]> {
+    const searchset = await this.request<Resource>("GET", "/fhir/{{.Name}}", { ...search });
+    return searchMatches(searchset);
+  }
+{{end}}{{if .Create}}
+  /** Creates a {{.Name}} and returns it as stored */
+  create{{.Name}}(resource: Resource): Promise<Resource> {
+    return this.request("POST", "/fhir/{{.Name}}", undefined, resource);
+  }
+{{end}}{{if .Read}}
+  /** Returns the {{.Name}} with the given ID */
+  read{{.Name}}(id: string): Promise<Resource> {
+    return this.request("GET", "/fhir/{{.Name}}/" + encodeURIComponent(id));
+  }
+{{end}}{{if .Update}}
+  /** Replaces the {{.Name}} with the given ID and returns it as stored */
+  update{{.Name}}(id: string, resource: Resource): Promise<Resource> {
+    return this.request("PUT", "/fhir/{{.Name}}/" + encodeURIComponent(id), undefined, resource);
+  }
+{{end}}{{if .Delete}}
+  /** Deletes the {{.Name}} with the given ID */
+  async delete{{.Name}}(id: string): Promise<void> {
+    await this.request("DELETE", "/fhir/{{.Name}}/" + encodeURIComponent(id));
+  }
+{{end}}{{range .Operations}}
+  /** Invokes ${{.Name}}: {{.Documentation}} */
+  {{lowerFirst .GoName}}({{if .Instance}}id: string, {{end}}{{if .Post}}body: unknown{{else}}parameters: Record<string, string> = {}{{end}}): Promise<unknown> {
+    return this.request({{if .Post}}"POST"{{else}}"GET"{{end}}, {{if .Instance}}"/fhir/{{$resource.Name}}/" + encodeURIComponent(id) + "/${{.Name}}"{{else}}"/fhir/{{$resource.Name}}/${{.Name}}"{{end}}{{if .Post}}, undefined, body{{else}}, parameters{{end}});
+  }
+{{end}}{{end}}}
+`))
@@ -0,0 +1,85 @@
+package sdkgen
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/capability"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestCommittedClientsAreCurrent verifies the committed clients match the server's current capabilities
+func TestCommittedClientsAreCurrent(t *testing.T) {
+	goClient, goError := GenerateGo(capability.Resources(), "fhirclient")
+	if goError != nil {
+		t.Fatalf("Expected no error, got %v", goError)
+	}
+	typeScriptClient, typeScriptError := GenerateTypeScript(capability.Resources())
+	if typeScriptError != nil {
+		t.Fatalf("Expected no error, got %v", typeScriptError)
+	}
+
+	committedClients := map[string][]byte{
+		"../../sdk/go/fhirclient/client.go": goClient,
+		"../../sdk/typescript/client.ts":    typeScriptClient,
+	}
+	for clientPath, generated := range committedClients {
+		committed, readError := os.ReadFile(clientPath)
+		if readError != nil {
+			t.Fatalf("Failed to read %s: %v", clientPath, readError)
+		}
+		if !bytes.Equal(committed, generated) {
+			t.Errorf("%s is out of date; run go generate ./sdk/...", clientPath)
+		}
+	}
+}
+
+// TestGenerateGo_FollowsCapabilities verifies only supported interactions get methods and parameters get typed fields
+func TestGenerateGo_FollowsCapabilities(t *testing.T) {
+	readOnlyResource := capability.Resource{
+		Type:         fhir.ResourceTypeDevice,
+		Interactions: []fhir.TypeRestfulInteraction{fhir.TypeRestfulInteractionRead, fhir.TypeRestfulInteractionSearchType},
+		SearchParameters: []capability.SearchParameter{
+			{Name: "_count", Type: fhir.SearchParamTypeNumber},
+			{Name: "_tag", Type: fhir.SearchParamTypeToken, Repeatable: true},
+		},
+		Operations: []capability.Operation{{Name: "lookup-udi", Method: http.MethodPost}},
+	}
+
+	generated, generateError := GenerateGo([]capability.Resource{readOnlyResource}, "devices")
+	if generateError != nil {
+		t.Fatalf("Expected no error, got %v", generateError)
+	}
+	source := string(generated)
+
+	for _, expected := range []string{"package devices", "func (client *Client) ReadDevice(", "func (client *Client) SearchDevice(", "Count int", "Tag []string", `"/fhir/Device/$lookup-udi", nil, body`} {
+		if !strings.Contains(source, expected) {
+			t.Errorf("Expected generated client to contain %q", expected)
+		}
+	}
+	if strings.Contains(source, "CreateDevice") || strings.Contains(source, "DeleteDevice") {
+		t.Error("Expected no methods for unsupported interactions")
+	}
+}
+
+// TestGenerateTypeScript_QuotesHyphenatedParameters verifies parameters such as clinical-status become quoted properties
+func TestGenerateTypeScript_QuotesHyphenatedParameters(t *testing.T) {
+	searchableResource := capability.Resource{
+		Type:             fhir.ResourceTypeCondition,
+		Interactions:     []fhir.TypeRestfulInteraction{fhir.TypeRestfulInteractionSearchType},
+		SearchParameters: []capability.SearchParameter{{Name: "clinical-status", Type: fhir.SearchParamTypeToken}, {Name: "patient", Type: fhir.SearchParamTypeReference}},
+	}
+
+	generated, generateError := GenerateTypeScript([]capability.Resource{searchableResource})
+	if generateError != nil {
+		t.Fatalf("Expected no error, got %v", generateError)
+	}
+	for _, expected := range []string{`"clinical-status"?: string;`, "patient?: string;"} {
+		if !strings.Contains(string(generated), expected) {
+			t.Errorf("Expected generated client to contain %q", expected)
+		}
+	}
+}
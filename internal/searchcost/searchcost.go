@@ -0,0 +1,196 @@
+package searchcost
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Cost weights for the factors that make a search expensive
+const (
+	costFullScan           = 50
+	costShortSubstring     = 100
+	costUnscopedCollection = 40
+	costUnfilteredRange    = 20
+
+	// offsetCostDivisor adds one cost point per this many skipped rows
+	offsetCostDivisor = 50
+
+	// minimumSubstringLength is the shortest substring that narrows a name search meaningfully
+	minimumSubstringLength = 3
+)
+
+// Factor is one reason a search is expensive, with the parameter that would fix it
+type Factor struct {
+	// Parameter names the search parameter the client should add or change
+	Parameter string
+
+	// Reason explains why the search is expensive
+	Reason string
+
+	// Cost is this factor's contribution to the estimate
+	Cost int
+}
+
+// Estimate is the predicted cost of a search before it runs
+type Estimate struct {
+	Cost    int
+	Factors []Factor
+}
+
+// add records a cost factor
+func (estimate *Estimate) add(parameter string, cost int, reason string) {
+	estimate.Cost += cost
+	estimate.Factors = append(estimate.Factors, Factor{Parameter: parameter, Reason: reason, Cost: cost})
+}
+
+// addOffset charges for rows skipped by deep pagination
+func (estimate *Estimate) addOffset(offset int) {
+	if offsetCost := offset / offsetCostDivisor; offsetCost > 0 {
+		estimate.add("_offset", offsetCost, fmt.Sprintf("skipping %d results reads and discards every one of them; follow the next link's _cursor instead", offset))
+	}
+}
+
+// EstimatePatientSearch predicts the cost of a patient search
+// Name substrings are found through the trigram indexes, which cannot narrow a substring shorter than a trigram
+func EstimatePatientSearch(searchParams *models.PatientSearchParams) Estimate {
+	var estimate Estimate
+
+	nameFilters := map[string]models.NameCriteria{
+		"name":   searchParams.Name,
+		"family": searchParams.FamilyName,
+		"given":  searchParams.GivenName,
+	}
+	hasNameFilter := false
+	for _, parameter := range []string{"name", "family", "given"} {
+		criteria := nameFilters[parameter]
+		if len(criteria) == 0 {
+			continue
+		}
+		hasNameFilter = true
+		if shortestSubstringLength(criteria) < minimumSubstringLength {
+			estimate.add(parameter, costShortSubstring, fmt.Sprintf("substring match on fewer than %d characters scans every patient", minimumSubstringLength))
+		}
+	}
+
+	hasAnyFilter := hasNameFilter || searchParams.BirthDate != nil || searchParams.BirthDateGreaterThan != nil || searchParams.BirthDateLessThan != nil ||
+		len(searchParams.Tags) > 0 || len(searchParams.Gender) > 0 || searchParams.Active != nil || searchParams.Identifier != nil
+	if !hasAnyFilter {
+		estimate.add("name", costFullScan, "no filters: every patient is read and sorted")
+	}
+
+	estimate.addOffset(searchParams.Offset)
+	return estimate
+}
+
+// shortestSubstringLength returns the length in characters of the shortest value matched as a substring;
+// one short alternative is enough to scan every patient, and :exact values are not substrings
+func shortestSubstringLength(criteria models.NameCriteria) int {
+	shortestLength := minimumSubstringLength
+	for _, criterion := range criteria {
+		if criterion.Match == models.NameMatchExact {
+			continue
+		}
+		for _, value := range criterion.Values {
+			shortestLength = min(shortestLength, len([]rune(value)))
+		}
+	}
+	return shortestLength
+}
+
+// EstimateObservationSearch predicts the cost of an observation search
+// Observations are expected to be searched per patient; collection-wide searches need a code and date range
+func EstimateObservationSearch(searchParams *models.ObservationSearchParams) Estimate {
+	var estimate Estimate
+
+	if searchParams.PatientID == "" {
+		estimate.add("patient", costUnscopedCollection, "searches without a patient span every patient's observations")
+
+		hasDateRange := searchParams.DateGreaterThan != nil && searchParams.DateLessThan != nil
+		if len(searchParams.Code) == 0 || !hasDateRange {
+			parameter := "code"
+			if len(searchParams.Code) > 0 {
+				parameter = "date"
+			}
+			estimate.add(parameter, costUnfilteredRange, "collection-wide searches need both a code and a bounded date range")
+		}
+	}
+
+	estimate.addOffset(searchParams.Offset)
+	return estimate
+}
+
+// Decision is the outcome of applying a policy to an estimate
+type Decision int
+
+const (
+	// Allow runs the search unchanged
+	Allow Decision = iota
+
+	// Downgrade runs the search with a reduced page size
+	Downgrade
+
+	// Reject refuses the search
+	Reject
+)
+
+// Policy sets the cost thresholds for downgrading and rejecting searches
+type Policy struct {
+	// DowngradeCost is the estimate at which the page size is reduced; zero disables downgrading
+	DowngradeCost int
+
+	// RejectCost is the estimate at which the search is refused; zero disables rejection
+	RejectCost int
+
+	// DowngradeLimit is the page size applied to downgraded searches
+	DowngradeLimit int
+}
+
+// DefaultPolicy returns thresholds that reject the clearly abusive searches while leaving
+// ordinary scoped searches alone
+func DefaultPolicy() Policy {
+	return Policy{
+		DowngradeCost:  50,
+		RejectCost:     80,
+		DowngradeLimit: 10,
+	}
+}
+
+// Decide classifies an estimate and returns the issues to report to the client
+func (policy Policy) Decide(estimate Estimate) (Decision, []outcome.Issue) {
+	switch {
+	case policy.RejectCost > 0 && estimate.Cost >= policy.RejectCost:
+		return Reject, factorIssues(estimate, fhir.IssueSeverityError, "Search is too expensive to run")
+	case policy.DowngradeCost > 0 && estimate.Cost >= policy.DowngradeCost:
+		message := fmt.Sprintf("Search is expensive; results are limited to %d per page", policy.DowngradeLimit)
+		return Downgrade, factorIssues(estimate, fhir.IssueSeverityWarning, message)
+	default:
+		return Allow, nil
+	}
+}
+
+// factorIssues builds one too-costly issue per factor, naming the parameter to add or change
+func factorIssues(estimate Estimate, severity fhir.IssueSeverity, summary string) []outcome.Issue {
+	issues := make([]outcome.Issue, 0, len(estimate.Factors))
+	for _, factor := range estimate.Factors {
+		issues = append(issues, outcome.Issue{
+			Severity:    severity,
+			Code:        fhir.IssueTypeTooCostly,
+			Diagnostics: fmt.Sprintf("%s: %s; add or narrow the '%s' parameter", summary, factor.Reason, factor.Parameter),
+			Expression:  []string{factor.Parameter},
+		})
+	}
+	return issues
+}
+
+// Parameters lists the parameters named by the estimate's factors, for logging
+func (estimate Estimate) Parameters() string {
+	parameters := make([]string, 0, len(estimate.Factors))
+	for _, factor := range estimate.Factors {
+		parameters = append(parameters, factor.Parameter)
+	}
+	return strings.Join(parameters, ",")
+}
@@ -0,0 +1,105 @@
+package searchcost
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestEstimatePatientSearch verifies the cost factors charged for patient searches
+func TestEstimatePatientSearch(t *testing.T) {
+	birthDate := time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name               string
+		searchParams       models.PatientSearchParams
+		expectedCost       int
+		expectedParameters string
+	}{
+		{"no filters", models.PatientSearchParams{}, costFullScan, "name"},
+		{"short name", models.PatientSearchParams{Name: models.NameCriteria{{Values: []string{"a"}}}}, costShortSubstring, "name"},
+		{"name only", models.PatientSearchParams{Name: models.NameCriteria{{Values: []string{"smith"}}}}, 0, ""},
+		{"short alternative", models.PatientSearchParams{FamilyName: models.NameCriteria{{Values: []string{"smith", "li"}}}}, costShortSubstring, "family"},
+		{"short exact name", models.PatientSearchParams{FamilyName: models.NameCriteria{{Values: []string{"li"}, Match: models.NameMatchExact}}}, 0, ""},
+		{"name and birthdate", models.PatientSearchParams{Name: models.NameCriteria{{Values: []string{"smith"}}}, BirthDate: &birthDate}, 0, ""},
+		{"gender only", models.PatientSearchParams{Gender: models.ValueCriteria{{"female"}}}, 0, ""},
+		{"name and tag", models.PatientSearchParams{Name: models.NameCriteria{{Values: []string{"smith"}}}, Tags: models.TagCriteria{{{Code: "needs-review"}}}}, 0, ""},
+		{"deep offset", models.PatientSearchParams{Gender: models.ValueCriteria{{"female"}}, Offset: 1000}, 1000 / offsetCostDivisor, "_offset"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			estimate := EstimatePatientSearch(&testCase.searchParams)
+			if estimate.Cost != testCase.expectedCost {
+				t.Errorf("Expected cost %d, got %d", testCase.expectedCost, estimate.Cost)
+			}
+			if estimate.Parameters() != testCase.expectedParameters {
+				t.Errorf("Expected parameters %q, got %q", testCase.expectedParameters, estimate.Parameters())
+			}
+		})
+	}
+}
+
+// TestEstimateObservationSearch verifies the cost factors charged for observation searches
+func TestEstimateObservationSearch(t *testing.T) {
+	startDate := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name               string
+		searchParams       models.ObservationSearchParams
+		expectedCost       int
+		expectedParameters string
+	}{
+		{"patient scoped", models.ObservationSearchParams{PatientID: "patient-1"}, 0, ""},
+		{"unscoped", models.ObservationSearchParams{}, costUnscopedCollection + costUnfilteredRange, "patient,code"},
+		{"unscoped with code", models.ObservationSearchParams{Code: models.ValueCriteria{{"8867-4"}}}, costUnscopedCollection + costUnfilteredRange, "patient,date"},
+		{"unscoped with code and range", models.ObservationSearchParams{Code: models.ValueCriteria{{"8867-4"}}, DateGreaterThan: &startDate, DateLessThan: &endDate}, costUnscopedCollection, "patient"},
+		{"unscoped deep offset", models.ObservationSearchParams{Offset: 5000}, costUnscopedCollection + costUnfilteredRange + 5000/offsetCostDivisor, "patient,code,_offset"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			estimate := EstimateObservationSearch(&testCase.searchParams)
+			if estimate.Cost != testCase.expectedCost {
+				t.Errorf("Expected cost %d, got %d", testCase.expectedCost, estimate.Cost)
+			}
+			if estimate.Parameters() != testCase.expectedParameters {
+				t.Errorf("Expected parameters %q, got %q", testCase.expectedParameters, estimate.Parameters())
+			}
+		})
+	}
+}
+
+// TestPolicy_Decide verifies thresholds and the issues reported for each decision
+func TestPolicy_Decide(t *testing.T) {
+	policy := DefaultPolicy()
+
+	decision, issues := policy.Decide(EstimatePatientSearch(&models.PatientSearchParams{Gender: models.ValueCriteria{{"male"}}}))
+	if decision != Allow || len(issues) != 0 {
+		t.Errorf("Expected allow without issues, got %v %v", decision, issues)
+	}
+
+	decision, issues = policy.Decide(EstimatePatientSearch(&models.PatientSearchParams{}))
+	if decision != Downgrade || len(issues) != 1 || issues[0].Severity.Code() != "warning" {
+		t.Errorf("Expected downgrade with one warning, got %v %v", decision, issues)
+	}
+
+	decision, issues = policy.Decide(EstimatePatientSearch(&models.PatientSearchParams{FamilyName: models.NameCriteria{{Values: []string{"a"}}}}))
+	if decision != Reject || len(issues) != 1 {
+		t.Fatalf("Expected reject with one issue, got %v %v", decision, issues)
+	}
+	if !strings.Contains(issues[0].Diagnostics, "'family'") || issues[0].Expression[0] != "family" {
+		t.Errorf("Expected issue naming the family parameter, got %+v", issues[0])
+	}
+}
+
+// TestPolicy_Decide_Disabled verifies zero thresholds never downgrade or reject
+func TestPolicy_Decide_Disabled(t *testing.T) {
+	decision, _ := Policy{}.Decide(EstimatePatientSearch(&models.PatientSearchParams{Name: models.NameCriteria{{Values: []string{"a"}}}}))
+	if decision != Allow {
+		t.Errorf("Expected allow with disabled policy, got %v", decision)
+	}
+}
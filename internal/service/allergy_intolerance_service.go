@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// AllergyIntoleranceService handles business logic for AllergyIntolerance operations
+type AllergyIntoleranceService struct {
+	allergyIntoleranceRepository repository.AllergyIntoleranceRepository
+	allergyIntoleranceMapper     *models.AllergyIntoleranceMapper
+	changeRepository             repository.ChangeRepository
+	eventPublisher               events.Publisher
+	strictMapping                bool
+}
+
+// NewAllergyIntoleranceService creates a new instance of AllergyIntoleranceService
+func NewAllergyIntoleranceService(allergyIntoleranceRepository repository.AllergyIntoleranceRepository) *AllergyIntoleranceService {
+	return &AllergyIntoleranceService{
+		allergyIntoleranceRepository: allergyIntoleranceRepository,
+		allergyIntoleranceMapper:     models.NewAllergyIntoleranceMapper(),
+	}
+}
+
+// SetChangeRepository enables recording allergy intolerance writes to the change log
+func (service *AllergyIntoleranceService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing allergy intolerance write events to internal consumers
+func (service *AllergyIntoleranceService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *AllergyIntoleranceService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// CreateAllergyIntolerance creates a new allergy intolerance from a FHIR AllergyIntolerance resource
+func (service *AllergyIntoleranceService) CreateAllergyIntolerance(ctx context.Context, fhirAllergyIntolerance *fhir.AllergyIntolerance) (*fhir.AllergyIntolerance, error) {
+	domainAllergyIntolerance, mappingIssues := service.allergyIntoleranceMapper.FromFHIR(fhirAllergyIntolerance)
+	if issuesError := handleMappingIssues(ctx, "AllergyIntolerance", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	createdAllergyIntolerance, createdFHIRAllergyIntolerance, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(writeContext context.Context) (*models.AllergyIntolerance, error) {
+		return service.allergyIntoleranceRepository.Create(writeContext, domainAllergyIntolerance)
+	})
+	if createError != nil {
+		return nil, createError
+	}
+
+	reportIgnoredElements(ctx, "AllergyIntolerance", fhirAllergyIntolerance, createdFHIRAllergyIntolerance, mappingIssues)
+
+	// MongoDB cannot join a multi-write transaction, so a rolled-back transaction removes the allergy intolerance again
+	if scope := transactionScopeFrom(ctx); scope != nil {
+		scope.onRollback(func(undoContext context.Context) {
+			service.undoUnrecordedWrite(undoContext, createdAllergyIntolerance.ID, models.ChangeOperationCreate)
+		})
+	}
+
+	return createdFHIRAllergyIntolerance, nil
+}
+
+// GetAllergyIntoleranceByID retrieves an allergy intolerance by ID, reporting ErrResourceNotFound for unknown allergy intolerances
+func (service *AllergyIntoleranceService) GetAllergyIntoleranceByID(ctx context.Context, allergyIntoleranceID string) (*fhir.AllergyIntolerance, error) {
+	domainAllergyIntolerance, getError := service.allergyIntoleranceRepository.GetByID(ctx, allergyIntoleranceID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(getError, repository.ErrAllergyIntoleranceNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+
+	return service.allergyIntoleranceMapper.ToFHIR(domainAllergyIntolerance), nil
+}
+
+// SearchAllergyIntolerances retrieves allergy intolerances matching the search criteria
+func (service *AllergyIntoleranceService) SearchAllergyIntolerances(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) ([]*fhir.AllergyIntolerance, error) {
+	domainAllergyIntolerances, searchError := service.allergyIntoleranceRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirAllergyIntolerances := make([]*fhir.AllergyIntolerance, 0, len(domainAllergyIntolerances))
+	for _, domainAllergyIntolerance := range domainAllergyIntolerances {
+		fhirAllergyIntolerances = append(fhirAllergyIntolerances, service.allergyIntoleranceMapper.ToFHIR(domainAllergyIntolerance))
+	}
+
+	return fhirAllergyIntolerances, nil
+}
+
+// CountAllergyIntolerances returns how many allergy intolerances match the search across all pages, used as the searchset total
+func (service *AllergyIntoleranceService) CountAllergyIntolerances(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) (int, error) {
+	return service.allergyIntoleranceRepository.Count(ctx, searchParams)
+}
+
+// UpdateAllergyIntolerance updates an existing allergy intolerance, reporting ErrResourceNotFound for unknown allergy intolerances
+func (service *AllergyIntoleranceService) UpdateAllergyIntolerance(ctx context.Context, allergyIntoleranceID string, fhirAllergyIntolerance *fhir.AllergyIntolerance) (*fhir.AllergyIntolerance, error) {
+	domainAllergyIntolerance, mappingIssues := service.allergyIntoleranceMapper.FromFHIR(fhirAllergyIntolerance)
+	if issuesError := handleMappingIssues(ctx, "AllergyIntolerance", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	domainAllergyIntolerance.ID = allergyIntoleranceID
+
+	_, updatedFHIRAllergyIntolerance, updateError := service.commitWrite(ctx, allergyIntoleranceID, models.ChangeOperationUpdate, func(writeContext context.Context) (*models.AllergyIntolerance, error) {
+		return service.allergyIntoleranceRepository.Update(writeContext, domainAllergyIntolerance)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, repository.ErrAllergyIntoleranceNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	reportIgnoredElements(ctx, "AllergyIntolerance", fhirAllergyIntolerance, updatedFHIRAllergyIntolerance, mappingIssues)
+	return updatedFHIRAllergyIntolerance, nil
+}
+
+// DeleteAllergyIntolerance removes an allergy intolerance by ID, reporting ErrResourceNotFound for unknown allergy intolerances
+func (service *AllergyIntoleranceService) DeleteAllergyIntolerance(ctx context.Context, allergyIntoleranceID string) error {
+	_, _, deleteError := service.commitWrite(ctx, allergyIntoleranceID, models.ChangeOperationDelete, func(writeContext context.Context) (*models.AllergyIntolerance, error) {
+		return nil, service.allergyIntoleranceRepository.Delete(writeContext, allergyIntoleranceID)
+	})
+	if errors.Is(deleteError, ErrResourceDeleted) {
+		return ErrResourceDeleted
+	}
+	if errors.Is(deleteError, repository.ErrAllergyIntoleranceNotFound) {
+		return ErrResourceNotFound
+	}
+	return deleteError
+}
+
+// commitWrite runs write and records it in the change log, then publishes it to event consumers
+// As with observations, MongoDB cannot join the change log's Postgres transaction, so the change is recorded in
+// a transaction opened before the write and committed only after it succeeds; when the change cannot be
+// recorded a create is undone, while an update or delete stays applied and is logged for repair
+// write returns the stored allergy intolerance, or nil for deletes, whose change carries no compartment
+func (service *AllergyIntoleranceService) commitWrite(ctx context.Context, allergyIntoleranceID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.AllergyIntolerance, error)) (*models.AllergyIntolerance, *fhir.AllergyIntolerance, error) {
+	var writtenAllergyIntolerance *models.AllergyIntolerance
+	var writtenFHIRAllergyIntolerance *fhir.AllergyIntolerance
+	var version int
+	writeApplied := false
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenAllergyIntolerance, writeError = write(ctx)
+		if writeError != nil {
+			return writeError
+		}
+		writeApplied = true
+
+		var snapshot interface{}
+		var compartmentPatientID string
+		if writtenAllergyIntolerance != nil {
+			allergyIntoleranceID = writtenAllergyIntolerance.ID
+			writtenFHIRAllergyIntolerance = service.allergyIntoleranceMapper.ToFHIR(writtenAllergyIntolerance)
+			snapshot = writtenFHIRAllergyIntolerance
+			compartmentPatientID = writtenAllergyIntolerance.PatientID
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "AllergyIntolerance", allergyIntoleranceID, operation, snapshot, compartmentPatientID)
+		return recordError
+	})
+	if transactionError != nil {
+		if writeApplied {
+			service.undoUnrecordedWrite(ctx, allergyIntoleranceID, operation)
+		}
+		return nil, nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenAllergyIntolerance != nil {
+		resource = writtenAllergyIntolerance
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "AllergyIntolerance", allergyIntoleranceID, operation, version, resource)
+	return writtenAllergyIntolerance, writtenFHIRAllergyIntolerance, nil
+}
+
+// undoUnrecordedWrite removes a created allergy intolerance whose change could not be recorded
+// Updates and deletes cannot be undone without the previous version, so they are logged for repair
+func (service *AllergyIntoleranceService) undoUnrecordedWrite(ctx context.Context, allergyIntoleranceID string, operation models.ChangeOperation) {
+	if operation == models.ChangeOperationCreate {
+		if deleteError := service.allergyIntoleranceRepository.Delete(ctx, allergyIntoleranceID); deleteError == nil {
+			return
+		}
+	}
+
+	log.Error().
+		Str("allergy_intolerance_id", allergyIntoleranceID).
+		Str("operation", string(operation)).
+		Msg("AllergyIntolerance write is stored but missing from the change log")
+}
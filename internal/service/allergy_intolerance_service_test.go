@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockAllergyIntoleranceRepository implements AllergyIntoleranceRepository interface for testing
+type MockAllergyIntoleranceRepository struct {
+	allergyIntolerances map[string]*models.AllergyIntolerance
+	deleted             map[string]bool
+}
+
+// NewMockAllergyIntoleranceRepository creates a new mock repository for testing
+func NewMockAllergyIntoleranceRepository() *MockAllergyIntoleranceRepository {
+	return &MockAllergyIntoleranceRepository{allergyIntolerances: make(map[string]*models.AllergyIntolerance), deleted: make(map[string]bool)}
+}
+
+// Create stores an allergy intolerance under a generated ObjectID
+func (mock *MockAllergyIntoleranceRepository) Create(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error) {
+	allergyIntolerance.ID = primitive.NewObjectID().Hex()
+	mock.allergyIntolerances[allergyIntolerance.ID] = allergyIntolerance
+	return allergyIntolerance, nil
+}
+
+// GetByID retrieves a stored allergy intolerance
+func (mock *MockAllergyIntoleranceRepository) GetByID(ctx context.Context, allergyIntoleranceID string) (*models.AllergyIntolerance, error) {
+	if mock.deleted[allergyIntoleranceID] {
+		return nil, deletedError(repository.ErrAllergyIntoleranceNotFound)
+	}
+	allergyIntolerance, exists := mock.allergyIntolerances[allergyIntoleranceID]
+	if !exists {
+		return nil, repository.ErrAllergyIntoleranceNotFound
+	}
+	return allergyIntolerance, nil
+}
+
+// Search returns every stored allergy intolerance
+func (mock *MockAllergyIntoleranceRepository) Search(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) ([]*models.AllergyIntolerance, error) {
+	result := make([]*models.AllergyIntolerance, 0, len(mock.allergyIntolerances))
+	for _, allergyIntolerance := range mock.allergyIntolerances {
+		result = append(result, allergyIntolerance)
+	}
+	return result, nil
+}
+
+// Count returns how many allergy intolerances are stored, as every search matches them all
+func (mock *MockAllergyIntoleranceRepository) Count(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) (int, error) {
+	return len(mock.allergyIntolerances), nil
+}
+
+// Update replaces a stored allergy intolerance
+func (mock *MockAllergyIntoleranceRepository) Update(ctx context.Context, allergyIntolerance *models.AllergyIntolerance) (*models.AllergyIntolerance, error) {
+	if mock.deleted[allergyIntolerance.ID] {
+		return nil, deletedError(repository.ErrAllergyIntoleranceNotFound)
+	}
+	if _, exists := mock.allergyIntolerances[allergyIntolerance.ID]; !exists {
+		return nil, repository.ErrAllergyIntoleranceNotFound
+	}
+	mock.allergyIntolerances[allergyIntolerance.ID] = allergyIntolerance
+	return allergyIntolerance, nil
+}
+
+// Delete marks a stored allergy intolerance deleted, as the repository does
+func (mock *MockAllergyIntoleranceRepository) Delete(ctx context.Context, allergyIntoleranceID string) error {
+	if mock.deleted[allergyIntoleranceID] {
+		return deletedError(repository.ErrAllergyIntoleranceNotFound)
+	}
+	if _, exists := mock.allergyIntolerances[allergyIntoleranceID]; !exists {
+		return repository.ErrAllergyIntoleranceNotFound
+	}
+	delete(mock.allergyIntolerances, allergyIntoleranceID)
+	mock.deleted[allergyIntoleranceID] = true
+	return nil
+}
+
+// TestAllergyIntoleranceService_Lifecycle verifies creates and updates are recorded in the subject's compartment and deletes in none
+func TestAllergyIntoleranceService_Lifecycle(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	allergyIntoleranceService := NewAllergyIntoleranceService(NewMockAllergyIntoleranceRepository())
+	allergyIntoleranceService.SetChangeRepository(changeRepository)
+	ctx := context.Background()
+
+	patientReference := "Patient/patient-1"
+	createdAllergyIntolerance, createError := allergyIntoleranceService.CreateAllergyIntolerance(ctx, &fhir.AllergyIntolerance{Patient: fhir.Reference{Reference: &patientReference}})
+	if createError != nil {
+		t.Fatalf("Expected no error creating the allergy intolerance, got %v", createError)
+	}
+
+	allergyIntoleranceID := *createdAllergyIntolerance.Id
+	resolved := "resolved"
+	if _, updateError := allergyIntoleranceService.UpdateAllergyIntolerance(ctx, allergyIntoleranceID, &fhir.AllergyIntolerance{
+		ClinicalStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &resolved}}},
+		Patient:        fhir.Reference{Reference: &patientReference},
+	}); updateError != nil {
+		t.Fatalf("Expected no error updating the allergy intolerance, got %v", updateError)
+	}
+	if deleteError := allergyIntoleranceService.DeleteAllergyIntolerance(ctx, allergyIntoleranceID); deleteError != nil {
+		t.Fatalf("Expected no error deleting the allergy intolerance, got %v", deleteError)
+	}
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	for index, expectedCompartment := range []string{"patient-1", "patient-1", ""} {
+		change := changeRepository.changes[index]
+		if change.ResourceType != "AllergyIntolerance" || change.ResourceID != allergyIntoleranceID || change.CompartmentPatientID != expectedCompartment {
+			t.Errorf("Change %d: expected AllergyIntolerance/%s in compartment %q, got %+v", index, allergyIntoleranceID, expectedCompartment, change)
+		}
+	}
+
+	if _, getError := allergyIntoleranceService.GetAllergyIntoleranceByID(ctx, allergyIntoleranceID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted reading a deleted allergy intolerance, got %v", getError)
+	}
+	if deleteError := allergyIntoleranceService.DeleteAllergyIntolerance(ctx, allergyIntoleranceID); !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+}
+
+// TestAllergyIntoleranceService_UnrecordedCreateIsUndone verifies an allergy intolerance whose create cannot be recorded is removed again
+func TestAllergyIntoleranceService_UnrecordedCreateIsUndone(t *testing.T) {
+	allergyIntoleranceRepository := NewMockAllergyIntoleranceRepository()
+	allergyIntoleranceService := NewAllergyIntoleranceService(allergyIntoleranceRepository)
+	allergyIntoleranceService.SetChangeRepository(&MockChangeRepository{recordError: errors.New("change log unavailable")})
+
+	if _, createError := allergyIntoleranceService.CreateAllergyIntolerance(context.Background(), &fhir.AllergyIntolerance{}); createError == nil {
+		t.Fatal("Expected the create to fail when its change cannot be recorded")
+	}
+	if len(allergyIntoleranceRepository.allergyIntolerances) != 0 {
+		t.Errorf("Expected the unrecorded allergy intolerance to be removed, got %d stored", len(allergyIntoleranceRepository.allergyIntolerances))
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// AuditEventService records accesses to clinical data and serves the audit trail as FHIR AuditEvents
+type AuditEventService struct {
+	auditEventRepository repository.AuditEventRepository
+	auditEventMapper     *models.AuditEventMapper
+}
+
+// NewAuditEventService creates a new audit event service instance
+func NewAuditEventService(auditEventRepository repository.AuditEventRepository) *AuditEventService {
+	return &AuditEventService{
+		auditEventRepository: auditEventRepository,
+		auditEventMapper:     models.NewAuditEventMapper(),
+	}
+}
+
+// Record persists an audit event; events are append-only and never updated or deleted
+func (service *AuditEventService) Record(ctx context.Context, event *models.AuditEvent) error {
+	_, createError := service.auditEventRepository.Create(ctx, event)
+	return createError
+}
+
+// GetAuditEventByID retrieves an audit event by ID, reporting ErrResourceNotFound for unknown events
+// and for events of another tenant
+func (service *AuditEventService) GetAuditEventByID(ctx context.Context, eventID string) (*fhir.AuditEvent, error) {
+	auditTenant, roleError := requireAuditor(ctx)
+	if roleError != nil {
+		return nil, roleError
+	}
+	if _, parseError := uuid.Parse(eventID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainEvent, getError := service.auditEventRepository.GetByID(ctx, eventID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+	if auditTenant != "" && domainEvent.TenantID != auditTenant {
+		return nil, ErrResourceNotFound
+	}
+
+	return service.auditEventMapper.ToFHIR(domainEvent), nil
+}
+
+// SearchAuditEvents retrieves audit events matching the search criteria, newest first
+func (service *AuditEventService) SearchAuditEvents(ctx context.Context, searchParams *models.AuditEventSearchParams) ([]*fhir.AuditEvent, error) {
+	if scopeError := scopeAuditSearch(ctx, searchParams); scopeError != nil {
+		return nil, scopeError
+	}
+
+	domainEvents, searchError := service.auditEventRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirEvents := make([]*fhir.AuditEvent, len(domainEvents))
+	for index, domainEvent := range domainEvents {
+		fhirEvents[index] = service.auditEventMapper.ToFHIR(domainEvent)
+	}
+
+	return fhirEvents, nil
+}
+
+// CountAuditEvents returns how many audit events match the search across all pages, used as the searchset total
+func (service *AuditEventService) CountAuditEvents(ctx context.Context, searchParams *models.AuditEventSearchParams) (int, error) {
+	if scopeError := scopeAuditSearch(ctx, searchParams); scopeError != nil {
+		return 0, scopeError
+	}
+	return service.auditEventRepository.Count(ctx, searchParams)
+}
+
+// requireAuditor refuses principals without the compliance or admin role and returns the tenant the
+// principal may read events of, empty for admins who may read every tenant's events
+func requireAuditor(ctx context.Context) (string, error) {
+	principal := auth.FromContext(ctx)
+	if principal.HasRole(auth.RoleAdmin) {
+		return "", nil
+	}
+	if !principal.HasRole(auth.RoleCompliance) {
+		return "", apperrors.Forbidden("Audit events can only be read by the compliance or admin role")
+	}
+	return tenant.VerifiedFromContext(ctx), nil
+}
+
+// scopeAuditSearch checks the caller may read audit events and limits the search to the caller's tenant
+func scopeAuditSearch(ctx context.Context, searchParams *models.AuditEventSearchParams) error {
+	auditTenant, roleError := requireAuditor(ctx)
+	if roleError != nil {
+		return roleError
+	}
+	if auditTenant != "" {
+		searchParams.TenantID = auditTenant
+	}
+	return nil
+}
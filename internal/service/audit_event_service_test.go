@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// memoryAuditEventRepository implements AuditEventRepository in memory for testing
+type memoryAuditEventRepository struct {
+	events []*models.AuditEvent
+}
+
+// Create stores the event with a new ID
+func (repository *memoryAuditEventRepository) Create(ctx context.Context, event *models.AuditEvent) (*models.AuditEvent, error) {
+	event.ID = uuid.NewString()
+	event.RecordedAt = time.Now()
+	repository.events = append(repository.events, event)
+	return event, nil
+}
+
+// GetByID returns the stored event
+func (repository *memoryAuditEventRepository) GetByID(ctx context.Context, eventID string) (*models.AuditEvent, error) {
+	for _, event := range repository.events {
+		if event.ID == eventID {
+			return event, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// Search returns the events of the searched tenant, or every event when no tenant is given
+func (repository *memoryAuditEventRepository) Search(ctx context.Context, searchParams *models.AuditEventSearchParams) ([]*models.AuditEvent, error) {
+	events := []*models.AuditEvent{}
+	for _, event := range repository.events {
+		if searchParams.TenantID == "" || event.TenantID == searchParams.TenantID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Count returns the number of events Search finds
+func (repository *memoryAuditEventRepository) Count(ctx context.Context, searchParams *models.AuditEventSearchParams) (int, error) {
+	events, _ := repository.Search(ctx, searchParams)
+	return len(events), nil
+}
+
+// TestAuditEventService_RequiresAuditorRole verifies only the compliance and admin roles read the trail
+func TestAuditEventService_RequiresAuditorRole(t *testing.T) {
+	auditService := NewAuditEventService(&memoryAuditEventRepository{})
+	clinicianContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:clinician", Roles: []string{auth.RoleClinician}})
+
+	_, searchError := auditService.SearchAuditEvents(clinicianContext, &models.AuditEventSearchParams{})
+	var appError *apperrors.AppError
+	if !errors.As(searchError, &appError) || appError.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for a clinician, got %v", searchError)
+	}
+	if _, searchError := auditService.SearchAuditEvents(complianceContext(), &models.AuditEventSearchParams{}); searchError != nil {
+		t.Errorf("Expected the compliance role to search audit events, got %v", searchError)
+	}
+}
+
+// TestAuditEventService_LimitsComplianceToTenant verifies compliance callers only see their tenant's events
+// while admins see every tenant's
+func TestAuditEventService_LimitsComplianceToTenant(t *testing.T) {
+	repository := &memoryAuditEventRepository{}
+	auditService := NewAuditEventService(repository)
+	auditService.Record(context.Background(), &models.AuditEvent{Action: models.AuditActionRead, TenantID: "clinic-a"})
+	auditService.Record(context.Background(), &models.AuditEvent{Action: models.AuditActionRead, TenantID: "clinic-b"})
+
+	clinicContext := tenant.WithVerifiedTenant(complianceContext(), "clinic-a")
+	events, _ := auditService.SearchAuditEvents(clinicContext, &models.AuditEventSearchParams{TenantID: "clinic-b"})
+	if len(events) != 1 || *events[0].Source.Site != "clinic-a" {
+		t.Errorf("Expected only clinic-a's event, got %d events", len(events))
+	}
+	if _, getError := auditService.GetAuditEventByID(clinicContext, repository.events[1].ID); !errors.Is(getError, ErrResourceNotFound) {
+		t.Errorf("Expected another tenant's event to be not found, got %v", getError)
+	}
+
+	adminContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:admin", Roles: []string{auth.RoleAdmin}})
+	total, _ := auditService.CountAuditEvents(adminContext, &models.AuditEventSearchParams{})
+	if total != 2 {
+		t.Errorf("Expected admins to see both tenants' events, got %d", total)
+	}
+}
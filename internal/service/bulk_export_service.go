@@ -0,0 +1,580 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/jobs"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// BulkExportTypes are the resource types a Patient-level bulk export can write, in file order:
+// the patients, then the types in their compartments
+var BulkExportTypes = []string{"Patient", "AllergyIntolerance", "Condition", "DiagnosticReport", "Encounter", "Immunization", "MedicationRequest", "Observation"}
+
+// BulkExportJobKind is the job kind bulk exports are written by
+const BulkExportJobKind = "bulk-export"
+
+// errBulkExportCancelled stops a worker whose export was cancelled while it was being written
+var errBulkExportCancelled = errors.New("bulk export was cancelled")
+
+// errBulkExportEnded ends a job whose export was cancelled or finished before the job started
+var errBulkExportEnded = errors.New("bulk export was cancelled or has already finished")
+
+// BulkExportQueue runs bulk exports as background jobs; jobs.Queue implements it
+type BulkExportQueue interface {
+	Enqueue(ctx context.Context, kind string, payload []byte, maxAttempts int) (*models.Job, error)
+	Cancel(ctx context.Context, jobID string) error
+}
+
+// AllergyIntoleranceSearcher searches allergy intolerances; AllergyIntoleranceService implements it
+type AllergyIntoleranceSearcher interface {
+	SearchAllergyIntolerances(ctx context.Context, searchParams *models.AllergyIntoleranceSearchParams) ([]*fhir.AllergyIntolerance, error)
+}
+
+// ConditionSearcher searches conditions; ConditionService implements it
+type ConditionSearcher interface {
+	SearchConditions(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*fhir.Condition, error)
+}
+
+// DiagnosticReportSearcher searches diagnostic reports; DiagnosticReportService implements it
+type DiagnosticReportSearcher interface {
+	SearchDiagnosticReports(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) ([]*fhir.DiagnosticReport, error)
+}
+
+// EncounterSearcher searches encounters; EncounterService implements it
+type EncounterSearcher interface {
+	SearchEncounters(ctx context.Context, searchParams *models.EncounterSearchParams) ([]*fhir.Encounter, error)
+}
+
+// ImmunizationSearcher searches immunizations; ImmunizationService implements it
+type ImmunizationSearcher interface {
+	SearchImmunizations(ctx context.Context, searchParams *models.ImmunizationSearchParams) ([]*fhir.Immunization, error)
+}
+
+// MedicationRequestSearcher searches medication requests; MedicationRequestService implements it
+type MedicationRequestSearcher interface {
+	SearchMedicationRequests(ctx context.Context, searchParams *models.MedicationRequestSearchParams) ([]*fhir.MedicationRequest, error)
+}
+
+// BulkExportSources are the searches bulk exports page through, one per resource type
+// A type whose searcher is nil cannot be exported
+type BulkExportSources struct {
+	Patients            PatientSearcher
+	AllergyIntolerances AllergyIntoleranceSearcher
+	Conditions          ConditionSearcher
+	DiagnosticReports   DiagnosticReportSearcher
+	Encounters          EncounterSearcher
+	Immunizations       ImmunizationSearcher
+	MedicationRequests  MedicationRequestSearcher
+	Observations        ObservationSearcher
+}
+
+// BulkExportPolicy sizes the pages exports are read in and bounds how long export files are kept
+// Exports are written by the background job queue's workers, which also retry and take them over
+type BulkExportPolicy struct {
+	// PageSize is the number of resources read per search while writing a file
+	PageSize int
+
+	// Retention is how long a complete export's files can be downloaded before they are removed
+	Retention time.Duration
+
+	// PollInterval is how often exports past their retention are expired and their files removed
+	PollInterval time.Duration
+}
+
+// DefaultBulkExportPolicy returns the bulk export settings used unless configured otherwise
+func DefaultBulkExportPolicy() BulkExportPolicy {
+	return BulkExportPolicy{
+		PageSize:     500,
+		Retention:    24 * time.Hour,
+		PollInterval: 5 * time.Second,
+	}
+}
+
+// BulkExportService runs FHIR Bulk Data $export jobs, writing one NDJSON file per resource type into a
+// directory of its own per export
+// Each export is written by a bulk-export job on the background job queue; register the service as that
+// kind's handler
+// Exports are requested by an authenticated caller and only shown to that caller; their files are removed
+// when the export fails, is cancelled, or passes its retention
+type BulkExportService struct {
+	exportRepository repository.BulkExportRepository
+	changedResources repository.ChangedResourceRepository
+	sources          BulkExportSources
+	queue            BulkExportQueue
+	directory        string
+	policy           BulkExportPolicy
+	exposeResource   ExportResourceExposer
+	now              func() time.Time
+	exportableTypes  []string
+}
+
+// NewBulkExportService creates a bulk export service keeping export files under directory, which is created
+// if it does not exist; it must be on a volume fit for patient data, as the files are written unencrypted
+func NewBulkExportService(exportRepository repository.BulkExportRepository, changedResources repository.ChangedResourceRepository, sources BulkExportSources, queue BulkExportQueue, directory string, policy BulkExportPolicy) (*BulkExportService, error) {
+	if makeError := os.MkdirAll(directory, 0o700); makeError != nil {
+		return nil, fmt.Errorf("failed to create bulk export directory: %w", makeError)
+	}
+
+	service := &BulkExportService{
+		exportRepository: exportRepository,
+		changedResources: changedResources,
+		sources:          sources,
+		queue:            queue,
+		directory:        directory,
+		policy:           policy,
+		exposeResource:   func(context.Context, interface{}) {},
+		now:              time.Now,
+	}
+	for _, resourceType := range BulkExportTypes {
+		if service.searchable(resourceType) {
+			service.exportableTypes = append(service.exportableTypes, resourceType)
+		}
+	}
+	return service, nil
+}
+
+// SetExposer sets how stored IDs in exported resources are rewritten for the requesting tenant
+func (service *BulkExportService) SetExposer(exposeResource ExportResourceExposer) {
+	service.exposeResource = exposeResource
+}
+
+// Request queues a bulk export for the calling principal
+// types limits the export to those resource types, and since to resources changed after it; request is
+// the kick-off URL, which the completion manifest echoes
+func (service *BulkExportService) Request(ctx context.Context, request string, types []string, since *time.Time) (*models.BulkExport, error) {
+	principal := auth.FromContext(ctx)
+	if principal.ID == auth.AnonymousPrincipalID {
+		return nil, apperrors.Unauthorized("Bulk exports require an API key")
+	}
+
+	exportTypes := service.exportableTypes
+	if len(types) > 0 {
+		for _, resourceType := range types {
+			if !slices.Contains(service.exportableTypes, resourceType) {
+				return nil, apperrors.InvalidInput("_type", fmt.Sprintf("%s cannot be exported; supported types are %s", resourceType, strings.Join(service.exportableTypes, ", ")))
+			}
+		}
+		exportTypes = nil
+		for _, resourceType := range service.exportableTypes {
+			if slices.Contains(types, resourceType) {
+				exportTypes = append(exportTypes, resourceType)
+			}
+		}
+	}
+	if since != nil && since.After(service.now()) {
+		return nil, apperrors.InvalidInput("_since", "must not be in the future")
+	}
+
+	// The export and its job commit together, so no worker starts a job without its export
+	var export *models.BulkExport
+	transactionError := service.exportRepository.InTransaction(ctx, func(transactionContext context.Context) error {
+		job, enqueueError := service.queue.Enqueue(transactionContext, BulkExportJobKind, nil, 0)
+		if enqueueError != nil {
+			return enqueueError
+		}
+		var createError error
+		export, createError = service.exportRepository.Create(transactionContext, &models.BulkExport{
+			Request:     request,
+			Types:       exportTypes,
+			Since:       since,
+			RequestedBy: principal.ID,
+			TenantID:    tenant.VerifiedFromContext(ctx),
+			JobID:       job.ID,
+		})
+		return createError
+	})
+	if transactionError != nil {
+		return nil, apperrors.Internal("Failed to queue bulk export", transactionError)
+	}
+
+	log.Info().Int64("export_id", export.ID).Str("job_id", export.JobID).Str("principal", principal.ID).Strs("types", exportTypes).Msg("Bulk export requested")
+	return export, nil
+}
+
+// Get returns an export to the principal who requested it
+// Cancelled exports and those of other callers are not found, and expired ones are gone
+func (service *BulkExportService) Get(ctx context.Context, exportID int64) (*models.BulkExport, error) {
+	export, getError := service.requestedExport(ctx, exportID)
+	if getError != nil {
+		return nil, getError
+	}
+	switch export.Status {
+	case models.BulkExportStatusCancelled:
+		return nil, apperrors.NotFound("BulkExport", strconv.FormatInt(exportID, 10))
+	case models.BulkExportStatusExpired:
+		return nil, apperrors.Gone("BulkExport", strconv.FormatInt(exportID, 10))
+	}
+	return export, nil
+}
+
+// Cancel stops a pending or running export, or discards a complete one, and removes its files
+// Its job is cancelled too; a worker writing the export notices at its next page and stops
+func (service *BulkExportService) Cancel(ctx context.Context, exportID int64) error {
+	export, getError := service.Get(ctx, exportID)
+	if getError != nil {
+		return getError
+	}
+
+	cancelError := service.exportRepository.Cancel(ctx, exportID)
+	if errors.Is(cancelError, sql.ErrNoRows) {
+		return apperrors.NotFound("BulkExport", strconv.FormatInt(exportID, 10))
+	}
+	if cancelError != nil {
+		return apperrors.Internal("Failed to cancel bulk export", cancelError)
+	}
+	service.removeFiles(exportID)
+
+	// A complete export's job has already finished
+	if jobError := service.queue.Cancel(ctx, export.JobID); jobError != nil && !errors.Is(jobError, sql.ErrNoRows) {
+		log.Warn().Err(jobError).Int64("export_id", exportID).Str("job_id", export.JobID).Msg("Failed to cancel bulk export job")
+	}
+
+	log.Info().Int64("export_id", exportID).Str("principal", auth.FromContext(ctx).ID).Msg("Bulk export cancelled")
+	return nil
+}
+
+// OpenFile opens the NDJSON file of one resource type of a complete export for its requester
+// The caller closes the returned file
+func (service *BulkExportService) OpenFile(ctx context.Context, exportID int64, resourceType string) (*os.File, error) {
+	export, getError := service.Get(ctx, exportID)
+	if getError != nil {
+		return nil, getError
+	}
+	if export.Status != models.BulkExportStatusComplete {
+		return nil, apperrors.Conflict("BulkExport", "export is "+string(export.Status))
+	}
+	if !slices.ContainsFunc(export.Files, func(file *models.BulkExportFile) bool { return file.ResourceType == resourceType }) {
+		return nil, apperrors.NotFound("BulkExport file", resourceType)
+	}
+
+	file, openError := os.Open(service.filePath(exportID, resourceType))
+	if openError != nil {
+		return nil, apperrors.Internal("Failed to open bulk export file", openError)
+	}
+
+	log.Info().Int64("export_id", exportID).Str("principal", export.RequestedBy).Str("resource_type", resourceType).Msg("Bulk export file downloaded")
+	return file, nil
+}
+
+// RunExpiry expires exports past their retention and removes their files every PollInterval until ctx is
+// cancelled
+func (service *BulkExportService) RunExpiry(ctx context.Context) {
+	for {
+		service.expire(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(service.policy.PollInterval):
+		}
+	}
+}
+
+// expire marks exports past their retention expired and removes their files
+func (service *BulkExportService) expire(ctx context.Context) {
+	expiredIDs, expireError := service.exportRepository.ExpireBefore(ctx, service.now())
+	if expireError != nil {
+		log.Error().Err(expireError).Msg("Failed to expire bulk exports")
+		return
+	}
+	for _, exportID := range expiredIDs {
+		service.removeFiles(exportID)
+	}
+	if len(expiredIDs) > 0 {
+		log.Info().Int("expired", len(expiredIDs)).Msg("Removed expired bulk export files")
+	}
+}
+
+// Run writes the files of the export a bulk-export job was queued for, as the handler of BulkExportJobKind
+// An attempt after a failed one or a stopped worker starts the export over; search and file failures are
+// retried by the queue, which fails the export through Finalize once the job's attempts run out
+func (service *BulkExportService) Run(ctx context.Context, job *models.Job, progress jobs.Progress) ([]byte, error) {
+	export, startError := service.exportRepository.Start(ctx, job.ID)
+	if errors.Is(startError, sql.ErrNoRows) {
+		return nil, jobs.Permanent(errBulkExportEnded)
+	}
+	if startError != nil {
+		return nil, fmt.Errorf("failed to start bulk export: %w", startError)
+	}
+
+	// Files an earlier attempt left behind are removed first
+	service.removeFiles(export.ID)
+	files, writeError := service.writeFiles(ctx, export, progress)
+	if errors.Is(writeError, errBulkExportCancelled) {
+		service.removeFiles(export.ID)
+		return nil, jobs.Permanent(writeError)
+	}
+	if writeError != nil {
+		return nil, writeError
+	}
+
+	expiresAt := service.now().Add(service.policy.Retention)
+	completeError := service.exportRepository.Complete(ctx, export.ID, files, expiresAt)
+	if errors.Is(completeError, sql.ErrNoRows) {
+		// Cancelled after the last page was written
+		service.removeFiles(export.ID)
+		return nil, jobs.Permanent(errBulkExportCancelled)
+	}
+	if completeError != nil {
+		return nil, fmt.Errorf("failed to complete bulk export %d: %w", export.ID, completeError)
+	}
+
+	log.Info().Int64("export_id", export.ID).Str("job_id", job.ID).Int("files", len(files)).Msg("Bulk export complete")
+	return nil, nil
+}
+
+// Finalize fails the export of a bulk-export job that failed, or was cancelled from the job admin endpoints,
+// and removes its files; exports their requester cancelled are left as they are
+func (service *BulkExportService) Finalize(ctx context.Context, job *models.Job) {
+	export, getError := service.exportRepository.GetByJob(ctx, job.ID)
+	if getError != nil {
+		if !errors.Is(getError, sql.ErrNoRows) {
+			log.Error().Err(getError).Str("job_id", job.ID).Msg("Failed to read bulk export of ended job")
+		}
+		return
+	}
+
+	failure := job.LastError
+	if job.Status == models.JobStatusCancelled {
+		failure = "the export's job was cancelled"
+	}
+	failError := service.exportRepository.Fail(ctx, export.ID, failure)
+	if errors.Is(failError, sql.ErrNoRows) {
+		return
+	}
+	if failError != nil {
+		log.Error().Err(failError).Int64("export_id", export.ID).Msg("Failed to record bulk export failure")
+		return
+	}
+
+	service.removeFiles(export.ID)
+	log.Warn().Str("error", failure).Int64("export_id", export.ID).Str("job_id", job.ID).Msg("Bulk export failed")
+}
+
+// requestedExport reads an export, hiding it from everyone but the principal who requested it
+func (service *BulkExportService) requestedExport(ctx context.Context, exportID int64) (*models.BulkExport, error) {
+	export, getError := service.exportRepository.Get(ctx, exportID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, apperrors.NotFound("BulkExport", strconv.FormatInt(exportID, 10))
+	}
+	if getError != nil {
+		return nil, apperrors.Internal("Failed to read bulk export", getError)
+	}
+	if export.RequestedBy != auth.FromContext(ctx).ID {
+		return nil, apperrors.NotFound("BulkExport", strconv.FormatInt(exportID, 10))
+	}
+	return export, nil
+}
+
+// writeFiles writes one NDJSON file per exported type, leaving out types without resources
+// It runs as the export's tenant, so IDs are exposed as the requester sees them
+func (service *BulkExportService) writeFiles(ctx context.Context, export *models.BulkExport, progress jobs.Progress) ([]*models.BulkExportFile, error) {
+	if makeError := os.MkdirAll(service.exportDirectory(export.ID), 0o700); makeError != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", makeError)
+	}
+	tenantContext := tenant.WithVerifiedTenant(ctx, export.TenantID)
+
+	files := []*models.BulkExportFile{}
+	for typeIndex, resourceType := range export.Types {
+		typeProgress := fmt.Sprintf("%s (%d of %d types)", resourceType, typeIndex+1, len(export.Types))
+		resourceCount, writeError := service.writeFile(tenantContext, export, resourceType, typeProgress, progress)
+		if writeError != nil {
+			return nil, writeError
+		}
+		if resourceCount == 0 {
+			os.Remove(service.filePath(export.ID, resourceType))
+			continue
+		}
+		files = append(files, &models.BulkExportFile{ExportID: export.ID, ResourceType: resourceType, ResourceCount: resourceCount})
+	}
+	return files, nil
+}
+
+// writeFile pages through every resource of one type into its NDJSON file and returns how many it wrote
+// Progress is recorded on the export and its job after each page, which detects cancellation by the requester
+func (service *BulkExportService) writeFile(ctx context.Context, export *models.BulkExport, resourceType string, typeProgress string, progress jobs.Progress) (int, error) {
+	changedIDs, changedError := service.changedSince(ctx, export, resourceType)
+	if changedError != nil {
+		return 0, changedError
+	}
+
+	file, createError := os.OpenFile(service.filePath(export.ID, resourceType), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if createError != nil {
+		return 0, fmt.Errorf("failed to create %s file: %w", resourceType, createError)
+	}
+	defer file.Close()
+	buffer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(buffer)
+
+	resourceCount := 0
+	for offset := 0; ; offset += service.policy.PageSize {
+		pageProgress := fmt.Sprintf("%s: %d written", typeProgress, resourceCount)
+		if progressError := service.exportRepository.UpdateProgress(ctx, export.ID, pageProgress); progressError != nil {
+			if errors.Is(progressError, sql.ErrNoRows) {
+				return 0, errBulkExportCancelled
+			}
+			return 0, fmt.Errorf("failed to record progress: %w", progressError)
+		}
+		progress(pageProgress)
+
+		page, searchError := service.readPage(ctx, resourceType, offset)
+		if searchError != nil {
+			return 0, fmt.Errorf("%s search failed at offset %d: %w", resourceType, offset, searchError)
+		}
+		for _, resource := range page {
+			if changedIDs != nil && !changedIDs[bulkExportResourceID(resource)] {
+				continue
+			}
+			service.exposeResource(ctx, resource)
+			if encodeError := encoder.Encode(resource); encodeError != nil {
+				return 0, fmt.Errorf("failed to write %s file: %w", resourceType, encodeError)
+			}
+			resourceCount++
+		}
+		if len(page) < service.policy.PageSize {
+			break
+		}
+	}
+
+	if flushError := buffer.Flush(); flushError != nil {
+		return 0, fmt.Errorf("failed to write %s file: %w", resourceType, flushError)
+	}
+	return resourceCount, file.Close()
+}
+
+// changedSince returns the IDs of the resources of a type changed after the export's _since, or nil when
+// the export has no _since and every resource is written
+func (service *BulkExportService) changedSince(ctx context.Context, export *models.BulkExport, resourceType string) (map[string]bool, error) {
+	if export.Since == nil {
+		return nil, nil
+	}
+	resourceIDs, listError := service.changedResources.ListChangedSince(ctx, resourceType, *export.Since)
+	if listError != nil {
+		return nil, fmt.Errorf("failed to read %s changes: %w", resourceType, listError)
+	}
+	changedIDs := make(map[string]bool, len(resourceIDs))
+	for _, resourceID := range resourceIDs {
+		changedIDs[resourceID] = true
+	}
+	return changedIDs, nil
+}
+
+// searchable reports whether the service has a search for the resource type
+func (service *BulkExportService) searchable(resourceType string) bool {
+	switch resourceType {
+	case "Patient":
+		return service.sources.Patients != nil
+	case "AllergyIntolerance":
+		return service.sources.AllergyIntolerances != nil
+	case "Condition":
+		return service.sources.Conditions != nil
+	case "DiagnosticReport":
+		return service.sources.DiagnosticReports != nil
+	case "Encounter":
+		return service.sources.Encounters != nil
+	case "Immunization":
+		return service.sources.Immunizations != nil
+	case "MedicationRequest":
+		return service.sources.MedicationRequests != nil
+	case "Observation":
+		return service.sources.Observations != nil
+	}
+	return false
+}
+
+// readPage reads one page of every stored resource of a type, starting at offset
+func (service *BulkExportService) readPage(ctx context.Context, resourceType string, offset int) ([]interface{}, error) {
+	limit := service.policy.PageSize
+	switch resourceType {
+	case "Patient":
+		return bulkExportPage(service.sources.Patients.SearchPatients(ctx, &models.PatientSearchParams{Limit: limit, Offset: offset}))
+	case "AllergyIntolerance":
+		return bulkExportPage(service.sources.AllergyIntolerances.SearchAllergyIntolerances(ctx, &models.AllergyIntoleranceSearchParams{Limit: limit, Offset: offset}))
+	case "Condition":
+		return bulkExportPage(service.sources.Conditions.SearchConditions(ctx, &models.ConditionSearchParams{Limit: limit, Offset: offset}))
+	case "DiagnosticReport":
+		return bulkExportPage(service.sources.DiagnosticReports.SearchDiagnosticReports(ctx, &models.DiagnosticReportSearchParams{Limit: limit, Offset: offset}))
+	case "Encounter":
+		return bulkExportPage(service.sources.Encounters.SearchEncounters(ctx, &models.EncounterSearchParams{Limit: limit, Offset: offset}))
+	case "Immunization":
+		return bulkExportPage(service.sources.Immunizations.SearchImmunizations(ctx, &models.ImmunizationSearchParams{Limit: limit, Offset: offset}))
+	case "MedicationRequest":
+		return bulkExportPage(service.sources.MedicationRequests.SearchMedicationRequests(ctx, &models.MedicationRequestSearchParams{Limit: limit, Offset: offset}))
+	case "Observation":
+		return bulkExportPage(service.sources.Observations.SearchObservations(ctx, &models.ObservationSearchParams{Limit: limit, Offset: offset}))
+	}
+	return nil, fmt.Errorf("%s cannot be exported", resourceType)
+}
+
+// bulkExportPage widens a page of search results to the resources the file writer takes
+func bulkExportPage[Resource any](resources []*Resource, searchError error) ([]interface{}, error) {
+	if searchError != nil {
+		return nil, searchError
+	}
+	page := make([]interface{}, len(resources))
+	for index, resource := range resources {
+		page[index] = resource
+	}
+	return page, nil
+}
+
+// bulkExportResourceID returns the stored ID of a resource read by readPage
+func bulkExportResourceID(resource interface{}) string {
+	var resourceID *string
+	switch typedResource := resource.(type) {
+	case *fhir.Patient:
+		resourceID = typedResource.Id
+	case *fhir.AllergyIntolerance:
+		resourceID = typedResource.Id
+	case *fhir.Condition:
+		resourceID = typedResource.Id
+	case *fhir.DiagnosticReport:
+		resourceID = typedResource.Id
+	case *fhir.Encounter:
+		resourceID = typedResource.Id
+	case *fhir.Immunization:
+		resourceID = typedResource.Id
+	case *fhir.MedicationRequest:
+		resourceID = typedResource.Id
+	case *fhir.Observation:
+		resourceID = typedResource.Id
+	}
+	if resourceID == nil {
+		return ""
+	}
+	return *resourceID
+}
+
+// exportDirectory returns the directory holding an export's files
+func (service *BulkExportService) exportDirectory(exportID int64) string {
+	return filepath.Join(service.directory, strconv.FormatInt(exportID, 10))
+}
+
+// filePath returns the path of an export's NDJSON file for a resource type
+func (service *BulkExportService) filePath(exportID int64, resourceType string) string {
+	return filepath.Join(service.exportDirectory(exportID), resourceType+".ndjson")
+}
+
+// removeFiles deletes an export's directory and everything in it
+func (service *BulkExportService) removeFiles(exportID int64) {
+	if removeError := os.RemoveAll(service.exportDirectory(exportID)); removeError != nil {
+		log.Error().Err(removeError).Int64("export_id", exportID).Msg("Failed to remove bulk export files")
+	}
+}
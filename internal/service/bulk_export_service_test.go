@@ -0,0 +1,368 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/jobs"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// memoryBulkExportRepository implements BulkExportRepository in memory
+type memoryBulkExportRepository struct {
+	exports map[int64]*models.BulkExport
+
+	// onProgress runs on every progress update, letting tests act while an export is being written
+	onProgress func(export *models.BulkExport)
+}
+
+// newMemoryBulkExportRepository creates an empty bulk export store
+func newMemoryBulkExportRepository() *memoryBulkExportRepository {
+	return &memoryBulkExportRepository{exports: make(map[int64]*models.BulkExport)}
+}
+
+// InTransaction runs work directly
+func (repository *memoryBulkExportRepository) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	return work(ctx)
+}
+
+// Create stores a pending export
+func (repository *memoryBulkExportRepository) Create(ctx context.Context, export *models.BulkExport) (*models.BulkExport, error) {
+	export.ID = int64(len(repository.exports) + 1)
+	export.Status = models.BulkExportStatusPending
+	repository.exports[export.ID] = export
+	return repository.Get(ctx, export.ID)
+}
+
+// Get returns a copy of the export
+func (repository *memoryBulkExportRepository) Get(ctx context.Context, exportID int64) (*models.BulkExport, error) {
+	export, exists := repository.exports[exportID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	copied := *export
+	return &copied, nil
+}
+
+// GetByJob returns a copy of the export written by a job
+func (repository *memoryBulkExportRepository) GetByJob(ctx context.Context, jobID string) (*models.BulkExport, error) {
+	for _, export := range repository.exports {
+		if export.JobID == jobID {
+			return repository.Get(ctx, export.ID)
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// Start marks the pending or running export written by a job running
+func (repository *memoryBulkExportRepository) Start(ctx context.Context, jobID string) (*models.BulkExport, error) {
+	export, getError := repository.GetByJob(ctx, jobID)
+	if getError != nil {
+		return nil, getError
+	}
+	stored := repository.exports[export.ID]
+	if stored.Status != models.BulkExportStatusPending && stored.Status != models.BulkExportStatusRunning {
+		return nil, sql.ErrNoRows
+	}
+	stored.Status, stored.Progress = models.BulkExportStatusRunning, ""
+	return repository.Get(ctx, export.ID)
+}
+
+// UpdateProgress records the progress of a running export
+func (repository *memoryBulkExportRepository) UpdateProgress(ctx context.Context, exportID int64, progress string) error {
+	export := repository.exports[exportID]
+	if repository.onProgress != nil {
+		repository.onProgress(export)
+	}
+	if export.Status != models.BulkExportStatusRunning {
+		return sql.ErrNoRows
+	}
+	export.Progress = progress
+	return nil
+}
+
+// Complete stores the files of a running export
+func (repository *memoryBulkExportRepository) Complete(ctx context.Context, exportID int64, files []*models.BulkExportFile, expiresAt time.Time) error {
+	export := repository.exports[exportID]
+	if export.Status != models.BulkExportStatusRunning {
+		return sql.ErrNoRows
+	}
+	export.Status = models.BulkExportStatusComplete
+	export.Files = files
+	export.ExpiresAt = &expiresAt
+	return nil
+}
+
+// Fail records why a pending or running export stopped
+func (repository *memoryBulkExportRepository) Fail(ctx context.Context, exportID int64, failure string) error {
+	export := repository.exports[exportID]
+	if export.Status != models.BulkExportStatusPending && export.Status != models.BulkExportStatusRunning {
+		return sql.ErrNoRows
+	}
+	export.Status = models.BulkExportStatusFailed
+	export.Error = failure
+	return nil
+}
+
+// Cancel cancels a pending, running, or complete export
+func (repository *memoryBulkExportRepository) Cancel(ctx context.Context, exportID int64) error {
+	export := repository.exports[exportID]
+	if !slices.Contains([]models.BulkExportStatus{models.BulkExportStatusPending, models.BulkExportStatusRunning, models.BulkExportStatusComplete}, export.Status) {
+		return sql.ErrNoRows
+	}
+	export.Status = models.BulkExportStatusCancelled
+	return nil
+}
+
+// ExpireBefore expires complete exports past their expiry
+func (repository *memoryBulkExportRepository) ExpireBefore(ctx context.Context, now time.Time) ([]int64, error) {
+	expiredIDs := []int64{}
+	for _, export := range repository.exports {
+		if export.Status == models.BulkExportStatusComplete && !export.ExpiresAt.After(now) {
+			export.Status = models.BulkExportStatusExpired
+			expiredIDs = append(expiredIDs, export.ID)
+		}
+	}
+	return expiredIDs, nil
+}
+
+// stubChangedResources returns fixed changed IDs per resource type
+type stubChangedResources map[string][]string
+
+// ListChangedSince returns the resource type's changed IDs
+func (changedResources stubChangedResources) ListChangedSince(ctx context.Context, resourceType string, since time.Time) ([]string, error) {
+	return changedResources[resourceType], nil
+}
+
+// stubBulkExportQueue records the jobs queued and cancelled
+type stubBulkExportQueue struct {
+	queued    []*models.Job
+	cancelled []string
+}
+
+// Enqueue records a queued job
+func (queue *stubBulkExportQueue) Enqueue(ctx context.Context, kind string, payload []byte, maxAttempts int) (*models.Job, error) {
+	job := &models.Job{ID: fmt.Sprintf("job-%d", len(queue.queued)+1), Kind: kind, Payload: payload, MaxAttempts: maxAttempts, Status: models.JobStatusQueued}
+	queue.queued = append(queue.queued, job)
+	return job, nil
+}
+
+// Cancel records a cancelled job
+func (queue *stubBulkExportQueue) Cancel(ctx context.Context, jobID string) error {
+	queue.cancelled = append(queue.cancelled, jobID)
+	return nil
+}
+
+// runBulkExport runs the job of an export the way a job queue worker would, returning the export afterwards
+func runBulkExport(t *testing.T, exportService *BulkExportService, export *models.BulkExport) (*models.BulkExport, error) {
+	t.Helper()
+	_, runError := exportService.Run(context.Background(), &models.Job{ID: export.JobID, Kind: BulkExportJobKind}, func(string) {})
+	ran, getError := exportService.exportRepository.Get(context.Background(), export.ID)
+	if getError != nil {
+		t.Fatalf("Expected the export to be readable, got %v", getError)
+	}
+	return ran, runError
+}
+
+// newTestBulkExportService creates a bulk export service with small pages writing under a temporary directory
+func newTestBulkExportService(t *testing.T, sources BulkExportSources, changedResources stubChangedResources) (*BulkExportService, *memoryBulkExportRepository) {
+	t.Helper()
+	exportRepository := newMemoryBulkExportRepository()
+	policy := DefaultBulkExportPolicy()
+	policy.PageSize = 2
+	exportService, serviceError := NewBulkExportService(exportRepository, changedResources, sources, &stubBulkExportQueue{}, filepath.Join(t.TempDir(), "bulk"), policy)
+	if serviceError != nil {
+		t.Fatalf("Expected the service to be created, got %v", serviceError)
+	}
+	exportService.now = func() time.Time { return time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC) }
+	return exportService, exportRepository
+}
+
+// readBulkExportFile reads a complete export's file of one resource type as its requester
+func readBulkExportFile(t *testing.T, exportService *BulkExportService, exportID int64, resourceType string) []string {
+	t.Helper()
+	file, openError := exportService.OpenFile(exporterContext(), exportID, resourceType)
+	if openError != nil {
+		t.Fatalf("Expected the %s file to open, got %v", resourceType, openError)
+	}
+	defer file.Close()
+	content, _ := io.ReadAll(file)
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
+
+// TestBulkExportService_Request verifies exports need an API key, exportable types, and a past _since
+func TestBulkExportService_Request(t *testing.T) {
+	exportService, _ := newTestBulkExportService(t, BulkExportSources{Patients: &stubPatientSearcher{}, Observations: &stubObservationSearcher{}}, nil)
+
+	_, anonymousError := exportService.Request(context.Background(), "/fhir/Patient/$export", nil, nil)
+	expectStatus(t, anonymousError, http.StatusUnauthorized)
+
+	_, typeError := exportService.Request(exporterContext(), "/fhir/Patient/$export", []string{"Condition"}, nil)
+	expectStatus(t, typeError, http.StatusBadRequest)
+
+	future := exportService.now().Add(time.Hour)
+	_, sinceError := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, &future)
+	expectStatus(t, sinceError, http.StatusBadRequest)
+
+	allTypes, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+	if !slices.Equal(allTypes.Types, []string{"Patient", "Observation"}) || allTypes.RequestedBy != "api-key:analyst" {
+		t.Errorf("Expected every searchable type for the caller, got %+v", allTypes)
+	}
+	queued := exportService.queue.(*stubBulkExportQueue).queued
+	if len(queued) != 1 || queued[0].Kind != BulkExportJobKind || allTypes.JobID != queued[0].ID {
+		t.Errorf("Expected the export to be written by a bulk-export job, got %+v for %+v", queued, allTypes)
+	}
+	reordered, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", []string{"Observation", "Patient", "Observation"}, nil)
+	if !slices.Equal(reordered.Types, []string{"Patient", "Observation"}) {
+		t.Errorf("Expected requested types once each in file order, got %v", reordered.Types)
+	}
+}
+
+// TestBulkExportService_Run verifies every page is written, empty types get no file, and _since filters
+func TestBulkExportService_Run(t *testing.T) {
+	patientSearcher := &stubPatientSearcher{patients: testPatients(5)}
+	sources := BulkExportSources{Patients: patientSearcher, Observations: &stubObservationSearcher{}}
+	exportService, _ := newTestBulkExportService(t, sources, stubChangedResources{"Patient": {"p-2", "p-5"}})
+	exportService.SetExposer(func(ctx context.Context, resource interface{}) {
+		patient := resource.(*fhir.Patient)
+		exposedID := "exposed-" + *patient.Id
+		patient.Id = &exposedID
+	})
+
+	export, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+	processed, processError := runBulkExport(t, exportService, export)
+	if processError != nil {
+		t.Fatalf("Expected no error, got %v", processError)
+	}
+	if processed.Status != models.BulkExportStatusComplete || len(processed.Files) != 1 || processed.Files[0].ResourceCount != 5 {
+		t.Fatalf("Expected one Patient file of 5, got %+v", processed)
+	}
+	if len(patientSearcher.searches) != 3 {
+		t.Errorf("Expected 3 pages of 2, got %d searches", len(patientSearcher.searches))
+	}
+	lines := readBulkExportFile(t, exportService, export.ID, "Patient")
+	if len(lines) != 5 || !strings.Contains(lines[0], `"id":"exposed-p-1"`) {
+		t.Errorf("Expected 5 NDJSON lines with exposed IDs, got %q", lines)
+	}
+	_, missingError := exportService.OpenFile(exporterContext(), export.ID, "Observation")
+	expectStatus(t, missingError, http.StatusNotFound)
+
+	// The exposer rewrote the stored patients' IDs, so the next export reads them afresh
+	patientSearcher.patients = testPatients(5)
+	since := exportService.now().Add(-time.Hour)
+	incremental, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export?_since=x", []string{"Patient"}, &since)
+	runBulkExport(t, exportService, incremental)
+	if lines := readBulkExportFile(t, exportService, incremental.ID, "Patient"); len(lines) != 2 || !strings.Contains(lines[1], `"exposed-p-5"`) {
+		t.Errorf("Expected only the patients changed since, got %q", lines)
+	}
+
+	// A finished export's job has nothing left to write
+	if _, rerunError := runBulkExport(t, exportService, export); !jobs.IsPermanent(rerunError) || !errors.Is(rerunError, errBulkExportEnded) {
+		t.Errorf("Expected a permanent error running a finished export again, got %v", rerunError)
+	}
+}
+
+// TestBulkExportService_RunProgress verifies each page's progress is reported to the job
+func TestBulkExportService_RunProgress(t *testing.T) {
+	exportService, _ := newTestBulkExportService(t, BulkExportSources{Patients: &stubPatientSearcher{patients: testPatients(3)}}, nil)
+	export, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+
+	reported := []string{}
+	_, runError := exportService.Run(context.Background(), &models.Job{ID: export.JobID}, func(progress string) { reported = append(reported, progress) })
+
+	if runError != nil {
+		t.Fatalf("Expected no error, got %v", runError)
+	}
+	if !slices.Equal(reported, []string{"Patient (1 of 1 types): 0 written", "Patient (1 of 1 types): 2 written"}) {
+		t.Errorf("Expected the progress of each page, got %q", reported)
+	}
+}
+
+// TestBulkExportService_Cancel verifies cancelling stops a running export, removes its files, and hides it
+func TestBulkExportService_Cancel(t *testing.T) {
+	exportService, exportRepository := newTestBulkExportService(t, BulkExportSources{Patients: &stubPatientSearcher{patients: testPatients(5)}}, nil)
+	export, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+
+	otherContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:other"})
+	expectStatus(t, exportService.Cancel(otherContext, export.ID), http.StatusNotFound)
+
+	// Cancel once the first page has been written
+	pages := 0
+	exportRepository.onProgress = func(running *models.BulkExport) {
+		if pages++; pages == 2 {
+			exportService.Cancel(exporterContext(), running.ID)
+		}
+	}
+	processed, processError := runBulkExport(t, exportService, export)
+	if !jobs.IsPermanent(processError) || processed.Status != models.BulkExportStatusCancelled {
+		t.Fatalf("Expected the worker to stop on the cancellation, got %+v (%v)", processed, processError)
+	}
+	if cancelled := exportService.queue.(*stubBulkExportQueue).cancelled; !slices.Equal(cancelled, []string{export.JobID}) {
+		t.Errorf("Expected the export's job to be cancelled, got %v", cancelled)
+	}
+	if _, statError := os.Stat(exportService.exportDirectory(export.ID)); !os.IsNotExist(statError) {
+		t.Errorf("Expected the export's files to be removed, got %v", statError)
+	}
+	_, getError := exportService.Get(exporterContext(), export.ID)
+	expectStatus(t, getError, http.StatusNotFound)
+}
+
+// TestBulkExportService_Expire verifies files are removed once the retention passes
+func TestBulkExportService_Expire(t *testing.T) {
+	exportService, _ := newTestBulkExportService(t, BulkExportSources{Patients: &stubPatientSearcher{patients: testPatients(1)}}, nil)
+	export, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+	runBulkExport(t, exportService, export)
+
+	completedAt := exportService.now()
+	exportService.now = func() time.Time { return completedAt.Add(48 * time.Hour) }
+	exportService.expire(context.Background())
+
+	_, getError := exportService.Get(exporterContext(), export.ID)
+	expectStatus(t, getError, http.StatusGone)
+	if _, statError := os.Stat(exportService.filePath(export.ID, "Patient")); !os.IsNotExist(statError) {
+		t.Errorf("Expected the expired file to be removed, got %v", statError)
+	}
+}
+
+// TestBulkExportService_Finalize verifies a failed or administratively cancelled job fails its export and
+// removes its files, while an export its requester cancelled stays cancelled
+func TestBulkExportService_Finalize(t *testing.T) {
+	exportService, exportRepository := newTestBulkExportService(t, BulkExportSources{Patients: &stubPatientSearcher{patients: testPatients(1)}}, nil)
+
+	failing, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+	exportRepository.exports[failing.ID].Status = models.BulkExportStatusRunning
+	os.MkdirAll(exportService.exportDirectory(failing.ID), 0o700)
+	exportService.Finalize(context.Background(), &models.Job{ID: failing.JobID, Status: models.JobStatusFailed, LastError: "Patient search failed"})
+
+	if failed, _ := exportService.Get(exporterContext(), failing.ID); failed.Status != models.BulkExportStatusFailed || failed.Error != "Patient search failed" {
+		t.Errorf("Expected the export to fail with the job's error, got %+v", failed)
+	}
+	if _, statError := os.Stat(exportService.exportDirectory(failing.ID)); !os.IsNotExist(statError) {
+		t.Errorf("Expected the failed export's files to be removed, got %v", statError)
+	}
+
+	stopped, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+	exportService.Finalize(context.Background(), &models.Job{ID: stopped.JobID, Status: models.JobStatusCancelled})
+	if failed, _ := exportService.Get(exporterContext(), stopped.ID); failed.Status != models.BulkExportStatusFailed || failed.Error == "" {
+		t.Errorf("Expected an export whose job was cancelled to fail, got %+v", failed)
+	}
+
+	withdrawn, _ := exportService.Request(exporterContext(), "/fhir/Patient/$export", nil, nil)
+	exportService.Cancel(exporterContext(), withdrawn.ID)
+	exportService.Finalize(context.Background(), &models.Job{ID: withdrawn.JobID, Status: models.JobStatusCancelled})
+	if stored := exportRepository.exports[withdrawn.ID]; stored.Status != models.BulkExportStatusCancelled {
+		t.Errorf("Expected the withdrawn export to stay cancelled, got %s", stored.Status)
+	}
+}
@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// Token endpoint authentication methods accepted at registration
+const (
+	authMethodNone              = "none"
+	authMethodClientSecretBasic = "client_secret_basic"
+	authMethodPrivateKeyJWT     = "private_key_jwt"
+)
+
+// Grant types accepted at registration
+const (
+	grantTypeAuthorizationCode = "authorization_code"
+	grantTypeRefreshToken      = "refresh_token"
+	grantTypeClientCredentials = "client_credentials"
+)
+
+// defaultClientScopes are granted when a registration does not request scopes
+var defaultClientScopes = map[models.ClientType][]string{
+	models.ClientTypePublic:       {"openid", "fhirUser", "launch/patient", "patient/*.read"},
+	models.ClientTypeConfidential: {"openid", "fhirUser", "launch", "user/*.read", "offline_access"},
+	models.ClientTypeBackend:      {"system/*.read"},
+}
+
+// allowedScopePrefixes bounds which scopes each client type may request
+var allowedScopePrefixes = map[models.ClientType][]string{
+	models.ClientTypePublic:       {"openid", "fhirUser", "launch/patient", "patient/"},
+	models.ClientTypeConfidential: {"openid", "fhirUser", "launch", "patient/", "user/", "offline_access"},
+	models.ClientTypeBackend:      {"system/"},
+}
+
+// ClientRegistrationService handles self-service client registration and admin review
+type ClientRegistrationService struct {
+	clientRepository repository.ClientRepository
+}
+
+// NewClientRegistrationService creates a new client registration service instance
+func NewClientRegistrationService(clientRepository repository.ClientRepository) *ClientRegistrationService {
+	return &ClientRegistrationService{
+		clientRepository: clientRepository,
+	}
+}
+
+// Register validates registration metadata and stores a pending client
+// The returned secret is only set for confidential clients and is never retrievable again
+func (service *ClientRegistrationService) Register(ctx context.Context, registrationRequest *models.ClientRegistrationRequest) (*models.RegisteredClient, string, error) {
+	if strings.TrimSpace(registrationRequest.ClientName) == "" {
+		return nil, "", apperrors.InvalidInput("client_name", "is required")
+	}
+
+	client := &models.RegisteredClient{
+		ClientID:     uuid.NewString(),
+		ClientName:   strings.TrimSpace(registrationRequest.ClientName),
+		RedirectURIs: registrationRequest.RedirectURIs,
+		JWKSURI:      registrationRequest.JWKSURI,
+		JWKS:         registrationRequest.JWKS,
+		Status:       models.ClientStatusPending,
+	}
+
+	// Classify the client and apply grant/auth defaults for its type
+	classifyError := classifyClient(client, registrationRequest)
+	if classifyError != nil {
+		return nil, "", classifyError
+	}
+
+	metadataError := validateClientMetadata(client)
+	if metadataError != nil {
+		return nil, "", metadataError
+	}
+
+	scopes, scopeError := resolveClientScopes(client.ClientType, registrationRequest.Scope)
+	if scopeError != nil {
+		return nil, "", scopeError
+	}
+	client.Scopes = scopes
+
+	// Confidential clients receive a secret; only its hash is stored
+	clientSecret := ""
+	if client.ClientType == models.ClientTypeConfidential {
+		generatedSecret, secretError := generateClientSecret()
+		if secretError != nil {
+			return nil, "", secretError
+		}
+		clientSecret = generatedSecret
+		client.SecretHash = HashClientSecret(clientSecret)
+	}
+
+	createdClient, createError := service.clientRepository.Create(ctx, client)
+	if createError != nil {
+		return nil, "", createError
+	}
+
+	return createdClient, clientSecret, nil
+}
+
+// GetClient retrieves a registered client by ID
+func (service *ClientRegistrationService) GetClient(ctx context.Context, clientID string) (*models.RegisteredClient, error) {
+	client, getError := service.clientRepository.GetByID(ctx, clientID)
+	if getError != nil {
+		return nil, clientLookupError(clientID, getError)
+	}
+	return client, nil
+}
+
+// ListClients retrieves registered clients, optionally filtered by status
+func (service *ClientRegistrationService) ListClients(ctx context.Context, status models.ClientStatus) ([]*models.RegisteredClient, error) {
+	switch status {
+	case "", models.ClientStatusPending, models.ClientStatusApproved, models.ClientStatusRejected:
+	default:
+		return nil, apperrors.InvalidInput("status", "must be pending, approved, or rejected")
+	}
+	return service.clientRepository.List(ctx, status)
+}
+
+// ApproveClient moves a pending client to approved
+func (service *ClientRegistrationService) ApproveClient(ctx context.Context, clientID string, reviewNote string) (*models.RegisteredClient, error) {
+	return service.reviewClient(ctx, clientID, models.ClientStatusApproved, reviewNote)
+}
+
+// RejectClient moves a pending client to rejected
+func (service *ClientRegistrationService) RejectClient(ctx context.Context, clientID string, reviewNote string) (*models.RegisteredClient, error) {
+	return service.reviewClient(ctx, clientID, models.ClientStatusRejected, reviewNote)
+}
+
+// reviewClient records an admin decision on a pending registration
+func (service *ClientRegistrationService) reviewClient(ctx context.Context, clientID string, status models.ClientStatus, reviewNote string) (*models.RegisteredClient, error) {
+	if !auth.FromContext(ctx).HasRole(auth.RoleAdmin) {
+		return nil, apperrors.Forbidden("Client registrations can only be reviewed by the admin role")
+	}
+
+	client, getError := service.GetClient(ctx, clientID)
+	if getError != nil {
+		return nil, getError
+	}
+
+	if client.Status != models.ClientStatusPending {
+		return nil, apperrors.Conflict("Client", "registration has already been "+string(client.Status))
+	}
+
+	reviewedAt := time.Now()
+	client.Status = status
+	client.ReviewNote = reviewNote
+	client.ReviewedAt = &reviewedAt
+
+	updatedClient, updateError := service.clientRepository.UpdateStatus(ctx, client)
+	if updateError != nil {
+		return nil, clientLookupError(clientID, updateError)
+	}
+
+	return updatedClient, nil
+}
+
+// HashClientSecret returns the hex-encoded SHA-256 digest stored for a client secret
+func HashClientSecret(clientSecret string) string {
+	digest := sha256.Sum256([]byte(clientSecret))
+	return hex.EncodeToString(digest[:])
+}
+
+// classifyClient derives the client type from its grant types and authentication method
+func classifyClient(client *models.RegisteredClient, registrationRequest *models.ClientRegistrationRequest) *apperrors.AppError {
+	grantTypes := registrationRequest.GrantTypes
+	authMethod := registrationRequest.TokenEndpointAuthMethod
+
+	// Backend services use client credentials with a signed JWT
+	if slices.Contains(grantTypes, grantTypeClientCredentials) {
+		if len(grantTypes) != 1 {
+			return apperrors.InvalidInput("grant_types", "client_credentials cannot be combined with other grant types")
+		}
+		if authMethod != "" && authMethod != authMethodPrivateKeyJWT {
+			return apperrors.InvalidInput("token_endpoint_auth_method", "backend clients must use private_key_jwt")
+		}
+		client.ClientType = models.ClientTypeBackend
+		client.TokenEndpointAuthMethod = authMethodPrivateKeyJWT
+		client.GrantTypes = grantTypes
+		return nil
+	}
+
+	// Everything else is an authorization code client
+	if len(grantTypes) == 0 {
+		grantTypes = []string{grantTypeAuthorizationCode}
+	}
+	for _, grantType := range grantTypes {
+		if grantType != grantTypeAuthorizationCode && grantType != grantTypeRefreshToken {
+			return apperrors.InvalidInput("grant_types", "unsupported grant type '"+grantType+"'")
+		}
+	}
+	client.GrantTypes = grantTypes
+
+	switch authMethod {
+	case authMethodNone:
+		client.ClientType = models.ClientTypePublic
+	case "", authMethodClientSecretBasic:
+		client.ClientType = models.ClientTypeConfidential
+		authMethod = authMethodClientSecretBasic
+	default:
+		return apperrors.InvalidInput("token_endpoint_auth_method", "must be none, client_secret_basic, or private_key_jwt")
+	}
+	client.TokenEndpointAuthMethod = authMethod
+
+	return nil
+}
+
+// validateClientMetadata checks redirect URIs and key material for the client's type
+func validateClientMetadata(client *models.RegisteredClient) *apperrors.AppError {
+	if client.ClientType == models.ClientTypeBackend {
+		if len(client.RedirectURIs) > 0 {
+			return apperrors.InvalidInput("redirect_uris", "backend clients do not use redirects")
+		}
+		if client.JWKSURI == "" && len(client.JWKS) == 0 {
+			return apperrors.InvalidInput("jwks", "backend clients must provide jwks or jwks_uri")
+		}
+		if client.JWKSURI != "" && !isSecureURL(client.JWKSURI) {
+			return apperrors.InvalidInput("jwks_uri", "must be an https URL")
+		}
+		return nil
+	}
+
+	if len(client.RedirectURIs) == 0 {
+		return apperrors.InvalidInput("redirect_uris", "at least one redirect URI is required")
+	}
+	for _, redirectURI := range client.RedirectURIs {
+		parsedURI, parseError := url.Parse(redirectURI)
+		if parseError != nil || !parsedURI.IsAbs() || parsedURI.Fragment != "" {
+			return apperrors.InvalidInput("redirect_uris", "'"+redirectURI+"' must be an absolute URI without a fragment")
+		}
+		if !isSecureURL(redirectURI) {
+			return apperrors.InvalidInput("redirect_uris", "'"+redirectURI+"' must use https (http is only allowed for localhost)")
+		}
+	}
+
+	return nil
+}
+
+// resolveClientScopes applies default scopes or checks requested ones against the client type
+func resolveClientScopes(clientType models.ClientType, requestedScope string) ([]string, *apperrors.AppError) {
+	requestedScopes := strings.Fields(requestedScope)
+	if len(requestedScopes) == 0 {
+		return slices.Clone(defaultClientScopes[clientType]), nil
+	}
+
+	for _, scope := range requestedScopes {
+		allowed := false
+		for _, prefix := range allowedScopePrefixes[clientType] {
+			if scope == prefix || (strings.HasSuffix(prefix, "/") && strings.HasPrefix(scope, prefix)) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return nil, apperrors.InvalidInput("scope", "'"+scope+"' is not available to "+string(clientType)+" clients")
+		}
+	}
+
+	return requestedScopes, nil
+}
+
+// isSecureURL accepts https URLs and plain http for loopback development hosts
+func isSecureURL(rawURL string) bool {
+	parsedURL, parseError := url.Parse(rawURL)
+	if parseError != nil {
+		return false
+	}
+	if parsedURL.Scheme == "https" {
+		return true
+	}
+	hostname := parsedURL.Hostname()
+	return parsedURL.Scheme == "http" && (hostname == "localhost" || hostname == "127.0.0.1")
+}
+
+// generateClientSecret returns a random URL-safe secret
+func generateClientSecret() (string, error) {
+	secretBytes := make([]byte, 32)
+	if _, readError := rand.Read(secretBytes); readError != nil {
+		return "", readError
+	}
+	return base64.RawURLEncoding.EncodeToString(secretBytes), nil
+}
+
+// clientLookupError maps a missing row to a 404 and passes other errors through
+func clientLookupError(clientID string, lookupError error) error {
+	if errors.Is(lookupError, sql.ErrNoRows) {
+		return apperrors.NotFound("Client", clientID)
+	}
+	return lookupError
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// MockClientRepository implements ClientRepository interface for testing
+type MockClientRepository struct {
+	clients map[string]*models.RegisteredClient
+}
+
+// NewMockClientRepository creates a new mock client repository for testing
+func NewMockClientRepository() *MockClientRepository {
+	return &MockClientRepository{
+		clients: make(map[string]*models.RegisteredClient),
+	}
+}
+
+// Create stores a client
+func (mock *MockClientRepository) Create(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error) {
+	mock.clients[client.ClientID] = client
+	return client, nil
+}
+
+// GetByID retrieves a client by ID
+func (mock *MockClientRepository) GetByID(ctx context.Context, clientID string) (*models.RegisteredClient, error) {
+	client, exists := mock.clients[clientID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return client, nil
+}
+
+// List retrieves clients filtered by status
+func (mock *MockClientRepository) List(ctx context.Context, status models.ClientStatus) ([]*models.RegisteredClient, error) {
+	clients := []*models.RegisteredClient{}
+	for _, client := range mock.clients {
+		if status == "" || client.Status == status {
+			clients = append(clients, client)
+		}
+	}
+	return clients, nil
+}
+
+// UpdateStatus stores the client's review decision
+func (mock *MockClientRepository) UpdateStatus(ctx context.Context, client *models.RegisteredClient) (*models.RegisteredClient, error) {
+	if _, exists := mock.clients[client.ClientID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.clients[client.ClientID] = client
+	return client, nil
+}
+
+// expectStatus asserts that an error is an AppError with the given HTTP status
+func expectStatus(t *testing.T, err error, expectedStatus int) {
+	t.Helper()
+	var appError *apperrors.AppError
+	if !errors.As(err, &appError) || appError.StatusCode != expectedStatus {
+		t.Errorf("Expected AppError with status %d, got %v", expectedStatus, err)
+	}
+}
+
+// TestClientRegistrationService_RegisterConfidential verifies defaults and secret issuance
+func TestClientRegistrationService_RegisterConfidential(t *testing.T) {
+	registrationService := NewClientRegistrationService(NewMockClientRepository())
+
+	client, clientSecret, registerError := registrationService.Register(context.Background(), &models.ClientRegistrationRequest{
+		ClientName:   "Clinic Portal",
+		RedirectURIs: []string{"https://portal.example.org/callback"},
+	})
+	if registerError != nil {
+		t.Fatalf("Expected no error, got %v", registerError)
+	}
+
+	if client.ClientType != models.ClientTypeConfidential || client.TokenEndpointAuthMethod != "client_secret_basic" {
+		t.Errorf("Expected confidential client_secret_basic, got %s/%s", client.ClientType, client.TokenEndpointAuthMethod)
+	}
+	if client.Status != models.ClientStatusPending {
+		t.Errorf("Expected pending status, got %s", client.Status)
+	}
+	if clientSecret == "" || client.SecretHash != HashClientSecret(clientSecret) {
+		t.Error("Expected a secret to be issued and only its hash stored")
+	}
+	if len(client.Scopes) == 0 || client.Scopes[0] != "openid" {
+		t.Errorf("Expected default confidential scopes, got %v", client.Scopes)
+	}
+}
+
+// TestClientRegistrationService_RegisterPublicAndBackend verifies type-specific defaults
+func TestClientRegistrationService_RegisterPublicAndBackend(t *testing.T) {
+	registrationService := NewClientRegistrationService(NewMockClientRepository())
+
+	publicClient, publicSecret, publicError := registrationService.Register(context.Background(), &models.ClientRegistrationRequest{
+		ClientName:              "Patient App",
+		RedirectURIs:            []string{"http://localhost:3000/callback"},
+		TokenEndpointAuthMethod: "none",
+	})
+	if publicError != nil {
+		t.Fatalf("Expected no error, got %v", publicError)
+	}
+	if publicClient.ClientType != models.ClientTypePublic || publicSecret != "" {
+		t.Errorf("Expected public client without secret, got %s", publicClient.ClientType)
+	}
+
+	backendClient, _, backendError := registrationService.Register(context.Background(), &models.ClientRegistrationRequest{
+		ClientName: "Lab Integration",
+		GrantTypes: []string{"client_credentials"},
+		JWKSURI:    "https://lab.example.org/.well-known/jwks.json",
+	})
+	if backendError != nil {
+		t.Fatalf("Expected no error, got %v", backendError)
+	}
+	if backendClient.ClientType != models.ClientTypeBackend || backendClient.Scopes[0] != "system/*.read" {
+		t.Errorf("Expected backend client with system scopes, got %s %v", backendClient.ClientType, backendClient.Scopes)
+	}
+}
+
+// TestClientRegistrationService_RegisterValidation verifies invalid metadata is rejected
+func TestClientRegistrationService_RegisterValidation(t *testing.T) {
+	registrationService := NewClientRegistrationService(NewMockClientRepository())
+
+	testCases := map[string]*models.ClientRegistrationRequest{
+		"missing name":          {RedirectURIs: []string{"https://a.example.org/cb"}},
+		"missing redirect":      {ClientName: "App"},
+		"insecure redirect":     {ClientName: "App", RedirectURIs: []string{"http://a.example.org/cb"}},
+		"fragment redirect":     {ClientName: "App", RedirectURIs: []string{"https://a.example.org/cb#x"}},
+		"backend without keys":  {ClientName: "App", GrantTypes: []string{"client_credentials"}},
+		"backend with redirect": {ClientName: "App", GrantTypes: []string{"client_credentials"}, JWKSURI: "https://a.example.org/jwks", RedirectURIs: []string{"https://a.example.org/cb"}},
+		"scope outside type":    {ClientName: "App", RedirectURIs: []string{"https://a.example.org/cb"}, TokenEndpointAuthMethod: "none", Scope: "system/*.read"},
+		"unknown auth method":   {ClientName: "App", RedirectURIs: []string{"https://a.example.org/cb"}, TokenEndpointAuthMethod: "tls_client_auth"},
+	}
+
+	for name, registrationRequest := range testCases {
+		_, _, registerError := registrationService.Register(context.Background(), registrationRequest)
+		if registerError == nil {
+			t.Errorf("%s: expected validation error", name)
+			continue
+		}
+		expectStatus(t, registerError, http.StatusBadRequest)
+	}
+}
+
+// TestClientRegistrationService_ReviewWorkflow verifies approval and the pending-only rule
+func TestClientRegistrationService_ReviewWorkflow(t *testing.T) {
+	registrationService := NewClientRegistrationService(NewMockClientRepository())
+	client, _, _ := registrationService.Register(context.Background(), &models.ClientRegistrationRequest{
+		ClientName:   "Clinic Portal",
+		RedirectURIs: []string{"https://portal.example.org/callback"},
+	})
+
+	adminContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:admin", Roles: []string{auth.RoleAdmin}})
+
+	// Reviews from callers without the admin role are refused and leave the client pending
+	_, forbiddenError := registrationService.ApproveClient(context.Background(), client.ClientID, "")
+	expectStatus(t, forbiddenError, http.StatusForbidden)
+
+	approvedClient, approveError := registrationService.ApproveClient(adminContext, client.ClientID, "verified BAA")
+	if approveError != nil {
+		t.Fatalf("Expected no error, got %v", approveError)
+	}
+	if approvedClient.Status != models.ClientStatusApproved || approvedClient.ReviewedAt == nil {
+		t.Errorf("Expected approved client with review time, got %+v", approvedClient)
+	}
+
+	_, rejectError := registrationService.RejectClient(adminContext, client.ClientID, "too late")
+	expectStatus(t, rejectError, http.StatusConflict)
+
+	_, missingError := registrationService.ApproveClient(adminContext, "unknown", "")
+	expectStatus(t, missingError, http.StatusNotFound)
+
+	_, listError := registrationService.ListClients(context.Background(), "archived")
+	expectStatus(t, listError, http.StatusBadRequest)
+}
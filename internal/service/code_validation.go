@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/terminology"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// codeValidation looks up observation codes on write and acts on the invalid ones
+type codeValidation struct {
+	lookup terminology.CodeSystemLookup
+
+	// action is terminology.ValidationReject or terminology.ValidationWarn
+	action string
+}
+
+// validatedCode is a code of an observation with the FHIRPath of the element it came from
+type validatedCode struct {
+	system     string
+	code       string
+	expression string
+}
+
+// applyCodeValidation checks the observation's code and component codes before the write
+// Invalid codes fail the write or warn the caller, depending on the action. Codes without a system and codes
+// the lookup cannot check pass. A lookup that fails is logged and reported as a warning, never a rejection,
+// so a terminology server outage does not stop observations from being stored
+func applyCodeValidation(ctx context.Context, validation *codeValidation, observation *models.Observation) error {
+	if validation == nil {
+		return nil
+	}
+
+	codes := []validatedCode{{system: observation.CodeSystem, code: observation.Code, expression: "Observation.code"}}
+	for index, component := range observation.Components {
+		codes = append(codes, validatedCode{system: component.CodeSystem, code: component.Code, expression: fmt.Sprintf("Observation.component[%d].code", index)})
+	}
+
+	var rejectedIssues []outcome.Issue
+	for _, checked := range codes {
+		if checked.system == "" || checked.code == "" {
+			continue
+		}
+
+		validity, lookupError := validation.lookup.LookupCode(ctx, checked.system, checked.code)
+		if lookupError != nil {
+			log.Warn().Err(lookupError).Str("system", checked.system).Str("code", checked.code).Msg("Failed to validate observation code")
+			outcome.Collect(ctx, outcome.Warning(fhir.IssueTypeTransient, fmt.Sprintf("Code %s|%s could not be validated", checked.system, checked.code), checked.expression))
+			continue
+		}
+		if validity != terminology.ValidityInvalid {
+			continue
+		}
+
+		diagnostics := fmt.Sprintf("Code %s is not a known code of %s", checked.code, checked.system)
+		log.Warn().
+			Str("patient_id", observation.PatientID).
+			Str("system", checked.system).
+			Str("code", checked.code).
+			Str("action", validation.action).
+			Msg(diagnostics)
+		if validation.action == terminology.ValidationReject {
+			rejectedIssues = append(rejectedIssues, outcome.Error(fhir.IssueTypeCodeInvalid, diagnostics, checked.expression))
+			continue
+		}
+		outcome.Collect(ctx, outcome.Warning(fhir.IssueTypeCodeInvalid, diagnostics, checked.expression))
+	}
+
+	if len(rejectedIssues) > 0 {
+		return &outcome.RejectionError{Issues: rejectedIssues}
+	}
+	return nil
+}
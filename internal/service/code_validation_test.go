@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/terminology"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// failingLookup is a code system lookup whose server cannot be reached
+type failingLookup struct{}
+
+// LookupCode always fails
+func (failingLookup) LookupCode(ctx context.Context, system string, code string) (terminology.Validity, error) {
+	return terminology.ValidityUnchecked, errors.New("terminology server unreachable")
+}
+
+// loincObservation builds a FHIR heart rate observation coded with the given LOINC code
+func loincObservation(code string) *fhir.Observation {
+	fhirObservation := heartRateObservation("72")
+	system := terminology.LOINCSystem
+	fhirObservation.Code.Coding[0].System = &system
+	fhirObservation.Code.Coding[0].Code = &code
+	return fhirObservation
+}
+
+// TestObservationService_CodeValidation verifies invalid codes are rejected or warned about as configured,
+// and codes that cannot be checked are stored
+func TestObservationService_CodeValidation(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	observationService.SetCodeValidation(terminology.Default(), terminology.ValidationReject)
+
+	_, rejectError := observationService.CreateObservation(context.Background(), loincObservation("8867-5"))
+	var rejection *outcome.RejectionError
+	if !errors.As(rejectError, &rejection) || rejection.Issues[0].Code != fhir.IssueTypeCodeInvalid || mockRepo.lastCreated != nil {
+		t.Fatalf("Expected a mistyped LOINC code rejected before storing, got %v", rejectError)
+	}
+
+	// 1751-7 is well formed but not among the built-in codes, so it cannot be checked and is stored
+	for _, storedCode := range []string{"8867-4", "1751-7"} {
+		ctx, collector := outcome.WithCollector(context.Background())
+		if _, createError := observationService.CreateObservation(ctx, loincObservation(storedCode)); createError != nil || len(collector.Issues()) != 0 {
+			t.Errorf("%s: expected the observation stored without warnings, got %v and %+v", storedCode, createError, collector.Issues())
+		}
+	}
+
+	observationService.SetCodeValidation(terminology.Default(), terminology.ValidationWarn)
+	ctx, collector := outcome.WithCollector(context.Background())
+	if _, warnError := observationService.CreateObservation(ctx, loincObservation("8867-5")); warnError != nil || len(collector.Issues()) != 1 {
+		t.Errorf("Expected the mistyped code stored with a warning, got %v and %+v", warnError, collector.Issues())
+	}
+
+	observationService.SetCodeValidation(failingLookup{}, terminology.ValidationReject)
+	ctx, collector = outcome.WithCollector(context.Background())
+	if _, outageError := observationService.CreateObservation(ctx, loincObservation("8867-4")); outageError != nil || len(collector.Issues()) != 1 {
+		t.Errorf("Expected a failed lookup to warn instead of rejecting, got %v and %+v", outageError, collector.Issues())
+	}
+}
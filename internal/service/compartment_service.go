@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/fanout"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// EverythingTypes are the resource types Patient/$everything can return, in Bundle order: the patient, then
+// the types in its compartment
+var EverythingTypes = []string{"Patient", "AllergyIntolerance", "Condition", "DiagnosticReport", "Encounter", "Immunization", "MedicationRequest", "Observation"}
+
+// ObservationReader reads a patient's observations; ObservationService implements it
+type ObservationReader interface {
+	GetObservationsByPatientID(ctx context.Context, patientID string, limit int, offset int) ([]*fhir.Observation, error)
+}
+
+// CompartmentPolicy bounds the parallel reads behind $everything and _revinclude
+type CompartmentPolicy struct {
+	// BranchTimeout bounds each store read; a slow optional read degrades to a warning
+	BranchTimeout time.Duration
+
+	// MaxConcurrency limits how many reads run at once for one request
+	MaxConcurrency int
+
+	// ObservationLimit caps the observations read per patient
+	ObservationLimit int
+
+	// ResourceLimit caps the resources of each other compartment type read per patient
+	ResourceLimit int
+}
+
+// DefaultCompartmentPolicy allows 5 seconds per read, 8 reads at once, and 1000 observations and 1000
+// resources of each other type per patient
+func DefaultCompartmentPolicy() CompartmentPolicy {
+	return CompartmentPolicy{
+		BranchTimeout:    5 * time.Second,
+		MaxConcurrency:   8,
+		ObservationLimit: 1000,
+		ResourceLimit:    1000,
+	}
+}
+
+// CompartmentSources are the searches $everything reads the patient's other resources from
+// A type whose searcher is nil is left out of the result
+type CompartmentSources struct {
+	AllergyIntolerances AllergyIntoleranceSearcher
+	Conditions          ConditionSearcher
+	DiagnosticReports   DiagnosticReportSearcher
+	Encounters          EncounterSearcher
+	Immunizations       ImmunizationSearcher
+	MedicationRequests  MedicationRequestSearcher
+}
+
+// EverythingOptions are the _type and _since filters of Patient/$everything
+type EverythingOptions struct {
+	// Types limits the result to these resource types; empty means every type
+	Types []string
+
+	// Since limits the result to resources changed after it; nil means every resource
+	Since *time.Time
+}
+
+// Includes reports whether the options ask for resources of the type
+func (options EverythingOptions) Includes(resourceType string) bool {
+	return len(options.Types) == 0 || slices.Contains(options.Types, resourceType)
+}
+
+// PatientEverything is the result of Patient/$everything
+// Issues are warnings for stores that failed or timed out, making the result partial
+type PatientEverything struct {
+	Patient *fhir.Patient
+
+	// IncludePatient is false when _type leaves out Patient or the patient has not changed since _since
+	IncludePatient bool
+
+	AllergyIntolerances []*fhir.AllergyIntolerance
+	Conditions          []*fhir.Condition
+	DiagnosticReports   []*fhir.DiagnosticReport
+	Encounters          []*fhir.Encounter
+	Immunizations       []*fhir.Immunization
+	MedicationRequests  []*fhir.MedicationRequest
+	Observations        []*fhir.Observation
+	Issues              []outcome.Issue
+}
+
+// CompartmentService reads a patient's compartment across the Postgres and MongoDB stores in parallel
+type CompartmentService struct {
+	patientService    *PatientService
+	observationReader ObservationReader
+	sources           CompartmentSources
+	changedResources  repository.ChangedResourceRepository
+	policy            CompartmentPolicy
+}
+
+// NewCompartmentService creates a new compartment service instance
+func NewCompartmentService(patientService *PatientService, observationReader ObservationReader, policy CompartmentPolicy) *CompartmentService {
+	return &CompartmentService{
+		patientService:    patientService,
+		observationReader: observationReader,
+		policy:            policy,
+	}
+}
+
+// SetSources adds the patient's other resource types to $everything
+func (service *CompartmentService) SetSources(sources CompartmentSources) {
+	service.sources = sources
+}
+
+// SetChangedResources enables the _since filter of $everything, which reads the change log
+func (service *CompartmentService) SetChangedResources(changedResources repository.ChangedResourceRepository) {
+	service.changedResources = changedResources
+}
+
+// Everything reads the patient and every type in its compartment concurrently, narrowed by the options
+// The patient is required, so its failure fails the request; failing reads of other types return a partial result
+func (service *CompartmentService) Everything(ctx context.Context, patientID string, options EverythingOptions) (*PatientEverything, error) {
+	for _, resourceType := range options.Types {
+		if !slices.Contains(EverythingTypes, resourceType) {
+			return nil, apperrors.InvalidInput("_type", fmt.Sprintf("%s is not in the patient compartment; supported types are %s", resourceType, strings.Join(EverythingTypes, ", ")))
+		}
+	}
+	if options.Since != nil && service.changedResources == nil {
+		return nil, apperrors.InvalidInput("_since", "is not supported by this server")
+	}
+
+	everything := &PatientEverything{}
+	searchLimit := service.policy.ResourceLimit
+	branches := []fanout.Branch{
+		{
+			Name:     "Patient",
+			Required: true,
+			Fetch: func(branchContext context.Context) error {
+				patient, getError := service.patientService.GetPatientByID(branchContext, patientID)
+				if getError != nil {
+					return getError
+				}
+				everything.Patient = patient
+				if !options.Includes("Patient") {
+					return nil
+				}
+				changedPatients, changedError := keepChangedSince(branchContext, service.changedResources, "Patient", options.Since, []*fhir.Patient{patient})
+				everything.IncludePatient = len(changedPatients) == 1
+				return changedError
+			},
+		},
+	}
+	if options.Includes("AllergyIntolerance") && service.sources.AllergyIntolerances != nil {
+		branches = append(branches, compartmentBranch(service, "AllergyIntolerance", options.Since, &everything.AllergyIntolerances, func(branchContext context.Context) ([]*fhir.AllergyIntolerance, error) {
+			return service.sources.AllergyIntolerances.SearchAllergyIntolerances(branchContext, &models.AllergyIntoleranceSearchParams{PatientID: patientID, Limit: searchLimit})
+		}))
+	}
+	if options.Includes("Condition") && service.sources.Conditions != nil {
+		branches = append(branches, compartmentBranch(service, "Condition", options.Since, &everything.Conditions, func(branchContext context.Context) ([]*fhir.Condition, error) {
+			return service.sources.Conditions.SearchConditions(branchContext, &models.ConditionSearchParams{PatientID: patientID, Limit: searchLimit})
+		}))
+	}
+	if options.Includes("DiagnosticReport") && service.sources.DiagnosticReports != nil {
+		branches = append(branches, compartmentBranch(service, "DiagnosticReport", options.Since, &everything.DiagnosticReports, func(branchContext context.Context) ([]*fhir.DiagnosticReport, error) {
+			return service.sources.DiagnosticReports.SearchDiagnosticReports(branchContext, &models.DiagnosticReportSearchParams{PatientID: patientID, Limit: searchLimit})
+		}))
+	}
+	if options.Includes("Encounter") && service.sources.Encounters != nil {
+		branches = append(branches, compartmentBranch(service, "Encounter", options.Since, &everything.Encounters, func(branchContext context.Context) ([]*fhir.Encounter, error) {
+			return service.sources.Encounters.SearchEncounters(branchContext, &models.EncounterSearchParams{PatientID: patientID, Limit: searchLimit})
+		}))
+	}
+	if options.Includes("Immunization") && service.sources.Immunizations != nil {
+		branches = append(branches, compartmentBranch(service, "Immunization", options.Since, &everything.Immunizations, func(branchContext context.Context) ([]*fhir.Immunization, error) {
+			return service.sources.Immunizations.SearchImmunizations(branchContext, &models.ImmunizationSearchParams{PatientID: patientID, Limit: searchLimit})
+		}))
+	}
+	if options.Includes("MedicationRequest") && service.sources.MedicationRequests != nil {
+		branches = append(branches, compartmentBranch(service, "MedicationRequest", options.Since, &everything.MedicationRequests, func(branchContext context.Context) ([]*fhir.MedicationRequest, error) {
+			return service.sources.MedicationRequests.SearchMedicationRequests(branchContext, &models.MedicationRequestSearchParams{PatientID: patientID, Limit: searchLimit})
+		}))
+	}
+	if options.Includes("Observation") {
+		branches = append(branches, compartmentBranch(service, "Observation", options.Since, &everything.Observations, func(branchContext context.Context) ([]*fhir.Observation, error) {
+			return service.observationReader.GetObservationsByPatientID(branchContext, patientID, service.policy.ObservationLimit, 0)
+		}))
+	}
+
+	result, runError := fanout.Run(ctx, service.fanoutOptions(), branches)
+	if runError != nil {
+		return nil, runError
+	}
+
+	everything.Issues = result.Issues()
+	return everything, nil
+}
+
+// compartmentBranch reads one type of the patient's compartment into target, keeping only the resources
+// changed after since
+func compartmentBranch[Resource any](service *CompartmentService, resourceType string, since *time.Time, target *[]*Resource, read func(ctx context.Context) ([]*Resource, error)) fanout.Branch {
+	return fanout.Branch{
+		Name: resourceType,
+		Fetch: func(branchContext context.Context) error {
+			resources, readError := read(branchContext)
+			if readError != nil {
+				return readError
+			}
+			changedResources, changedError := keepChangedSince(branchContext, service.changedResources, resourceType, since, resources)
+			*target = changedResources
+			return changedError
+		},
+	}
+}
+
+// keepChangedSince drops the resources the change log has no write to after since; a nil since keeps them all
+func keepChangedSince[Resource any](ctx context.Context, changedResources repository.ChangedResourceRepository, resourceType string, since *time.Time, resources []*Resource) ([]*Resource, error) {
+	if since == nil {
+		return resources, nil
+	}
+	resourceIDs, listError := changedResources.ListChangedSince(ctx, resourceType, *since)
+	if listError != nil {
+		return nil, fmt.Errorf("failed to read %s changes: %w", resourceType, listError)
+	}
+
+	changedIDs := make(map[string]bool, len(resourceIDs))
+	for _, resourceID := range resourceIDs {
+		changedIDs[resourceID] = true
+	}
+	keptResources := make([]*Resource, 0, len(resources))
+	for _, resource := range resources {
+		if changedIDs[bulkExportResourceID(resource)] {
+			keptResources = append(keptResources, resource)
+		}
+	}
+	return keptResources, nil
+}
+
+// RevIncludeObservations reads the observations referencing each patient, one parallel read per patient
+// Observations are returned in patient order whatever order the reads finish in; failed reads become warnings
+func (service *CompartmentService) RevIncludeObservations(ctx context.Context, patientIDs []string) ([]*fhir.Observation, []outcome.Issue, error) {
+	var resultsMutex sync.Mutex
+	observationsByPatient := make(map[string][]*fhir.Observation, len(patientIDs))
+
+	branches := make([]fanout.Branch, 0, len(patientIDs))
+	for _, patientID := range patientIDs {
+		branches = append(branches, fanout.Branch{
+			Name: "Observation",
+			Fetch: func(branchContext context.Context) error {
+				observations, getError := service.observationReader.GetObservationsByPatientID(branchContext, patientID, service.policy.ObservationLimit, 0)
+				if getError != nil {
+					return getError
+				}
+				resultsMutex.Lock()
+				observationsByPatient[patientID] = observations
+				resultsMutex.Unlock()
+				return nil
+			},
+		})
+	}
+
+	result, runError := fanout.Run(ctx, service.fanoutOptions(), branches)
+	if runError != nil {
+		return nil, nil, runError
+	}
+
+	observations := make([]*fhir.Observation, 0)
+	for _, patientID := range patientIDs {
+		observations = append(observations, observationsByPatient[patientID]...)
+	}
+	return observations, result.Issues(), nil
+}
+
+// IncludeSubjectPatients reads each distinct patient the observations are about, one parallel read per patient
+// Patients are returned in the order the observations first reference them. Observations whose subject is not
+// a Patient, or whose patient no longer exists, add nothing; other failed reads become warnings
+func (service *CompartmentService) IncludeSubjectPatients(ctx context.Context, observations []*fhir.Observation) ([]*fhir.Patient, []outcome.Issue, error) {
+	patientIDs := make([]string, 0)
+	for _, observation := range observations {
+		if observation.Subject == nil || observation.Subject.Reference == nil {
+			continue
+		}
+		patientID, isPatient := strings.CutPrefix(*observation.Subject.Reference, "Patient/")
+		if isPatient && patientID != "" && !slices.Contains(patientIDs, patientID) {
+			patientIDs = append(patientIDs, patientID)
+		}
+	}
+
+	var resultsMutex sync.Mutex
+	patientsByID := make(map[string]*fhir.Patient, len(patientIDs))
+
+	branches := make([]fanout.Branch, 0, len(patientIDs))
+	for _, patientID := range patientIDs {
+		branches = append(branches, fanout.Branch{
+			Name: "Patient",
+			Fetch: func(branchContext context.Context) error {
+				patient, getError := service.patientService.GetPatientByID(branchContext, patientID)
+				if errors.Is(getError, sql.ErrNoRows) {
+					return nil
+				}
+				if getError != nil {
+					return getError
+				}
+				resultsMutex.Lock()
+				patientsByID[patientID] = patient
+				resultsMutex.Unlock()
+				return nil
+			},
+		})
+	}
+
+	result, runError := fanout.Run(ctx, service.fanoutOptions(), branches)
+	if runError != nil {
+		return nil, nil, runError
+	}
+
+	patients := make([]*fhir.Patient, 0, len(patientsByID))
+	for _, patientID := range patientIDs {
+		if patient, found := patientsByID[patientID]; found {
+			patients = append(patients, patient)
+		}
+	}
+	return patients, result.Issues(), nil
+}
+
+// fanoutOptions applies the policy to a fan-out
+func (service *CompartmentService) fanoutOptions() fanout.Options {
+	return fanout.Options{
+		DefaultTimeout: service.policy.BranchTimeout,
+		MaxConcurrency: service.policy.MaxConcurrency,
+	}
+}
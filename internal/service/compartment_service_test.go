@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// stubObservationReader returns one observation per patient, failing or stalling for chosen patients
+type stubObservationReader struct {
+	failing map[string]error
+	stalled map[string]bool
+}
+
+// GetObservationsByPatientID returns an observation for the patient unless it is set to fail or stall
+func (reader *stubObservationReader) GetObservationsByPatientID(ctx context.Context, patientID string, limit int, offset int) ([]*fhir.Observation, error) {
+	if reader.stalled[patientID] {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if failure := reader.failing[patientID]; failure != nil {
+		return nil, failure
+	}
+	observationID := "obs-" + patientID
+	return []*fhir.Observation{{Id: &observationID}}, nil
+}
+
+// newTestCompartmentService creates a compartment service over one stored patient with a short branch timeout
+func newTestCompartmentService(reader *stubObservationReader) *CompartmentService {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1", FamilyName: "Smith"}
+
+	policy := DefaultCompartmentPolicy()
+	policy.BranchTimeout = 20 * time.Millisecond
+	return NewCompartmentService(NewPatientService(patientRepository), reader, policy)
+}
+
+// TestCompartmentService_Everything verifies the patient and its observations are returned together
+func TestCompartmentService_Everything(t *testing.T) {
+	compartmentService := newTestCompartmentService(&stubObservationReader{})
+
+	everything, everythingError := compartmentService.Everything(context.Background(), "patient-1", EverythingOptions{})
+	if everythingError != nil {
+		t.Fatalf("Expected no error, got %v", everythingError)
+	}
+	if *everything.Patient.Id != "patient-1" || !everything.IncludePatient || len(everything.Observations) != 1 || len(everything.Issues) != 0 {
+		t.Errorf("Unexpected result %+v", everything)
+	}
+
+	if _, missingError := compartmentService.Everything(context.Background(), "missing", EverythingOptions{}); missingError == nil {
+		t.Error("Expected an error for a missing patient")
+	}
+}
+
+// stubConditionSearcher returns two conditions of the searched patient
+type stubConditionSearcher struct{}
+
+// SearchConditions returns the patient's conditions
+func (searcher stubConditionSearcher) SearchConditions(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*fhir.Condition, error) {
+	firstID, secondID := "cond-1", "cond-2"
+	subject := "Patient/" + searchParams.PatientID
+	return []*fhir.Condition{
+		{Id: &firstID, Subject: fhir.Reference{Reference: &subject}},
+		{Id: &secondID, Subject: fhir.Reference{Reference: &subject}},
+	}, nil
+}
+
+// stubEncounterSearcher returns one encounter of the searched patient
+type stubEncounterSearcher struct{}
+
+// SearchEncounters returns the patient's encounter
+func (searcher stubEncounterSearcher) SearchEncounters(ctx context.Context, searchParams *models.EncounterSearchParams) ([]*fhir.Encounter, error) {
+	encounterID := "enc-1"
+	return []*fhir.Encounter{{Id: &encounterID}}, nil
+}
+
+// TestCompartmentService_EverythingFilters verifies other compartment types are read and narrowed by _type and _since
+func TestCompartmentService_EverythingFilters(t *testing.T) {
+	compartmentService := newTestCompartmentService(&stubObservationReader{})
+	compartmentService.SetSources(CompartmentSources{Conditions: stubConditionSearcher{}, Encounters: stubEncounterSearcher{}})
+
+	everything, _ := compartmentService.Everything(context.Background(), "patient-1", EverythingOptions{})
+	if len(everything.Conditions) != 2 || len(everything.Encounters) != 1 || len(everything.Observations) != 1 {
+		t.Errorf("Expected every compartment type, got %+v", everything)
+	}
+
+	everything, _ = compartmentService.Everything(context.Background(), "patient-1", EverythingOptions{Types: []string{"Condition"}})
+	if everything.IncludePatient || everything.Patient == nil || len(everything.Conditions) != 2 || len(everything.Encounters) != 0 || len(everything.Observations) != 0 {
+		t.Errorf("Expected only conditions, got %+v", everything)
+	}
+	if _, typeError := compartmentService.Everything(context.Background(), "patient-1", EverythingOptions{Types: []string{"Practitioner"}}); typeError == nil {
+		t.Error("Expected an error for a type outside the compartment")
+	}
+
+	since := time.Now().Add(-time.Hour)
+	if _, sinceError := compartmentService.Everything(context.Background(), "patient-1", EverythingOptions{Since: &since}); sinceError == nil {
+		t.Error("Expected _since to be refused without the change log")
+	}
+	compartmentService.SetChangedResources(stubChangedResources{"Condition": {"cond-2"}, "Observation": {"obs-patient-1"}})
+	everything, _ = compartmentService.Everything(context.Background(), "patient-1", EverythingOptions{Since: &since})
+	if everything.IncludePatient || len(everything.Conditions) != 1 || *everything.Conditions[0].Id != "cond-2" || len(everything.Encounters) != 0 || len(everything.Observations) != 1 {
+		t.Errorf("Expected only the resources changed since, got %+v", everything)
+	}
+}
+
+// TestCompartmentService_EverythingPartial verifies a failing observation store degrades to a warning
+func TestCompartmentService_EverythingPartial(t *testing.T) {
+	compartmentService := newTestCompartmentService(&stubObservationReader{stalled: map[string]bool{"patient-1": true}})
+
+	everything, everythingError := compartmentService.Everything(context.Background(), "patient-1", EverythingOptions{})
+	if everythingError != nil {
+		t.Fatalf("Expected a partial result, got %v", everythingError)
+	}
+	if everything.Patient == nil || len(everything.Issues) != 1 || everything.Issues[0].Code != fhir.IssueTypeTimeout {
+		t.Errorf("Expected the patient and one timeout warning, got %+v", everything)
+	}
+}
+
+// TestCompartmentService_RevIncludeObservations verifies results keep patient order and failures become warnings
+func TestCompartmentService_RevIncludeObservations(t *testing.T) {
+	compartmentService := newTestCompartmentService(&stubObservationReader{failing: map[string]error{"b": errors.New("mongo unavailable")}})
+
+	observations, issues, revIncludeError := compartmentService.RevIncludeObservations(context.Background(), []string{"c", "b", "a"})
+	if revIncludeError != nil {
+		t.Fatalf("Expected no error, got %v", revIncludeError)
+	}
+	if len(observations) != 2 || *observations[0].Id != "obs-c" || *observations[1].Id != "obs-a" {
+		t.Errorf("Expected observations for c then a, got %d", len(observations))
+	}
+	if len(issues) != 1 || issues[0].Code != fhir.IssueTypeIncomplete {
+		t.Errorf("Expected one incomplete warning, got %+v", issues)
+	}
+}
+
+// subjectObservation builds an observation whose subject is the reference
+func subjectObservation(reference string) *fhir.Observation {
+	return &fhir.Observation{Subject: &fhir.Reference{Reference: &reference}}
+}
+
+// TestCompartmentService_IncludeSubjectPatients verifies each referenced patient is read once and missing
+// patients and non-patient subjects add nothing
+func TestCompartmentService_IncludeSubjectPatients(t *testing.T) {
+	compartmentService := newTestCompartmentService(&stubObservationReader{})
+
+	patients, issues, includeError := compartmentService.IncludeSubjectPatients(context.Background(), []*fhir.Observation{
+		subjectObservation("Patient/patient-1"),
+		subjectObservation("Patient/patient-1"),
+		subjectObservation("Patient/deleted"),
+		subjectObservation("Group/ward-3"),
+		{},
+	})
+	if includeError != nil || len(issues) != 0 {
+		t.Fatalf("Expected no error or warnings, got %v %+v", includeError, issues)
+	}
+	if len(patients) != 1 || *patients[0].Id != "patient-1" {
+		t.Errorf("Expected patient-1 once, got %d patients", len(patients))
+	}
+}
+
+// TestCompartmentService_IncludeSubjectPatientsPartial verifies a failed patient read becomes a warning
+func TestCompartmentService_IncludeSubjectPatientsPartial(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.getByIDError = errors.New("postgres unavailable")
+	compartmentService := NewCompartmentService(NewPatientService(patientRepository), &stubObservationReader{}, DefaultCompartmentPolicy())
+
+	patients, issues, includeError := compartmentService.IncludeSubjectPatients(context.Background(), []*fhir.Observation{subjectObservation("Patient/patient-1")})
+	if includeError != nil || len(patients) != 0 || len(issues) != 1 {
+		t.Errorf("Expected no patients and one warning, got %d patients, %+v (%v)", len(patients), issues, includeError)
+	}
+}
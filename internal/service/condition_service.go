@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// ConditionService handles business logic for Condition operations
+type ConditionService struct {
+	conditionRepository repository.ConditionRepository
+	conditionMapper     *models.ConditionMapper
+	changeRepository    repository.ChangeRepository
+	eventPublisher      events.Publisher
+	strictMapping       bool
+}
+
+// NewConditionService creates a new instance of ConditionService
+func NewConditionService(conditionRepository repository.ConditionRepository) *ConditionService {
+	return &ConditionService{
+		conditionRepository: conditionRepository,
+		conditionMapper:     models.NewConditionMapper(),
+	}
+}
+
+// SetChangeRepository enables recording condition writes to the change log
+func (service *ConditionService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing condition write events to internal consumers
+func (service *ConditionService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *ConditionService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// CreateCondition creates a new condition from a FHIR Condition resource
+func (service *ConditionService) CreateCondition(ctx context.Context, fhirCondition *fhir.Condition) (*fhir.Condition, error) {
+	domainCondition, mappingIssues := service.conditionMapper.FromFHIR(fhirCondition)
+	if issuesError := handleMappingIssues(ctx, "Condition", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	createdCondition, createdFHIRCondition, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(writeContext context.Context) (*models.Condition, error) {
+		return service.conditionRepository.Create(writeContext, domainCondition)
+	})
+	if createError != nil {
+		return nil, createError
+	}
+
+	reportIgnoredElements(ctx, "Condition", fhirCondition, createdFHIRCondition, mappingIssues)
+
+	// MongoDB cannot join a multi-write transaction, so a rolled-back transaction removes the condition again
+	if scope := transactionScopeFrom(ctx); scope != nil {
+		scope.onRollback(func(undoContext context.Context) {
+			service.undoUnrecordedWrite(undoContext, createdCondition.ID, models.ChangeOperationCreate)
+		})
+	}
+
+	return createdFHIRCondition, nil
+}
+
+// GetConditionByID retrieves a condition by ID, reporting ErrResourceNotFound for unknown conditions
+func (service *ConditionService) GetConditionByID(ctx context.Context, conditionID string) (*fhir.Condition, error) {
+	domainCondition, getError := service.conditionRepository.GetByID(ctx, conditionID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(getError, repository.ErrConditionNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+
+	return service.conditionMapper.ToFHIR(domainCondition), nil
+}
+
+// SearchConditions retrieves conditions matching the search criteria
+func (service *ConditionService) SearchConditions(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*fhir.Condition, error) {
+	domainConditions, searchError := service.conditionRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirConditions := make([]*fhir.Condition, 0, len(domainConditions))
+	for _, domainCondition := range domainConditions {
+		fhirConditions = append(fhirConditions, service.conditionMapper.ToFHIR(domainCondition))
+	}
+
+	return fhirConditions, nil
+}
+
+// CountConditions returns how many conditions match the search across all pages, used as the searchset total
+func (service *ConditionService) CountConditions(ctx context.Context, searchParams *models.ConditionSearchParams) (int, error) {
+	return service.conditionRepository.Count(ctx, searchParams)
+}
+
+// UpdateCondition updates an existing condition, reporting ErrResourceNotFound for unknown conditions
+func (service *ConditionService) UpdateCondition(ctx context.Context, conditionID string, fhirCondition *fhir.Condition) (*fhir.Condition, error) {
+	domainCondition, mappingIssues := service.conditionMapper.FromFHIR(fhirCondition)
+	if issuesError := handleMappingIssues(ctx, "Condition", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	domainCondition.ID = conditionID
+
+	_, updatedFHIRCondition, updateError := service.commitWrite(ctx, conditionID, models.ChangeOperationUpdate, func(writeContext context.Context) (*models.Condition, error) {
+		return service.conditionRepository.Update(writeContext, domainCondition)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, repository.ErrConditionNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	reportIgnoredElements(ctx, "Condition", fhirCondition, updatedFHIRCondition, mappingIssues)
+	return updatedFHIRCondition, nil
+}
+
+// DeleteCondition removes a condition by ID, reporting ErrResourceNotFound for unknown conditions
+func (service *ConditionService) DeleteCondition(ctx context.Context, conditionID string) error {
+	_, _, deleteError := service.commitWrite(ctx, conditionID, models.ChangeOperationDelete, func(writeContext context.Context) (*models.Condition, error) {
+		return nil, service.conditionRepository.Delete(writeContext, conditionID)
+	})
+	if errors.Is(deleteError, ErrResourceDeleted) {
+		return ErrResourceDeleted
+	}
+	if errors.Is(deleteError, repository.ErrConditionNotFound) {
+		return ErrResourceNotFound
+	}
+	return deleteError
+}
+
+// commitWrite runs write and records it in the change log, then publishes it to event consumers
+// As with observations, MongoDB cannot join the change log's Postgres transaction, so the change is recorded in
+// a transaction opened before the write and committed only after it succeeds; when the change cannot be
+// recorded a create is undone, while an update or delete stays applied and is logged for repair
+// write returns the stored condition, or nil for deletes, whose change carries no compartment
+func (service *ConditionService) commitWrite(ctx context.Context, conditionID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.Condition, error)) (*models.Condition, *fhir.Condition, error) {
+	var writtenCondition *models.Condition
+	var writtenFHIRCondition *fhir.Condition
+	var version int
+	writeApplied := false
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenCondition, writeError = write(ctx)
+		if writeError != nil {
+			return writeError
+		}
+		writeApplied = true
+
+		var snapshot interface{}
+		var compartmentPatientID string
+		if writtenCondition != nil {
+			conditionID = writtenCondition.ID
+			writtenFHIRCondition = service.conditionMapper.ToFHIR(writtenCondition)
+			snapshot = writtenFHIRCondition
+			compartmentPatientID = writtenCondition.PatientID
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "Condition", conditionID, operation, snapshot, compartmentPatientID)
+		return recordError
+	})
+	if transactionError != nil {
+		if writeApplied {
+			service.undoUnrecordedWrite(ctx, conditionID, operation)
+		}
+		return nil, nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenCondition != nil {
+		resource = writtenCondition
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "Condition", conditionID, operation, version, resource)
+	return writtenCondition, writtenFHIRCondition, nil
+}
+
+// undoUnrecordedWrite removes a created condition whose change could not be recorded
+// Updates and deletes cannot be undone without the previous version, so they are logged for repair
+func (service *ConditionService) undoUnrecordedWrite(ctx context.Context, conditionID string, operation models.ChangeOperation) {
+	if operation == models.ChangeOperationCreate {
+		if deleteError := service.conditionRepository.Delete(ctx, conditionID); deleteError == nil {
+			return
+		}
+	}
+
+	log.Error().
+		Str("condition_id", conditionID).
+		Str("operation", string(operation)).
+		Msg("Condition write is stored but missing from the change log")
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockConditionRepository implements ConditionRepository interface for testing
+type MockConditionRepository struct {
+	conditions map[string]*models.Condition
+	deleted    map[string]bool
+}
+
+// NewMockConditionRepository creates a new mock repository for testing
+func NewMockConditionRepository() *MockConditionRepository {
+	return &MockConditionRepository{conditions: make(map[string]*models.Condition), deleted: make(map[string]bool)}
+}
+
+// Create stores a condition under a generated ObjectID
+func (mock *MockConditionRepository) Create(ctx context.Context, condition *models.Condition) (*models.Condition, error) {
+	condition.ID = primitive.NewObjectID().Hex()
+	mock.conditions[condition.ID] = condition
+	return condition, nil
+}
+
+// GetByID retrieves a stored condition
+func (mock *MockConditionRepository) GetByID(ctx context.Context, conditionID string) (*models.Condition, error) {
+	if mock.deleted[conditionID] {
+		return nil, deletedError(repository.ErrConditionNotFound)
+	}
+	condition, exists := mock.conditions[conditionID]
+	if !exists {
+		return nil, repository.ErrConditionNotFound
+	}
+	return condition, nil
+}
+
+// Search returns every stored condition
+func (mock *MockConditionRepository) Search(ctx context.Context, searchParams *models.ConditionSearchParams) ([]*models.Condition, error) {
+	result := make([]*models.Condition, 0, len(mock.conditions))
+	for _, condition := range mock.conditions {
+		result = append(result, condition)
+	}
+	return result, nil
+}
+
+// Count returns how many conditions are stored, as every search matches them all
+func (mock *MockConditionRepository) Count(ctx context.Context, searchParams *models.ConditionSearchParams) (int, error) {
+	return len(mock.conditions), nil
+}
+
+// Update replaces a stored condition
+func (mock *MockConditionRepository) Update(ctx context.Context, condition *models.Condition) (*models.Condition, error) {
+	if mock.deleted[condition.ID] {
+		return nil, deletedError(repository.ErrConditionNotFound)
+	}
+	if _, exists := mock.conditions[condition.ID]; !exists {
+		return nil, repository.ErrConditionNotFound
+	}
+	mock.conditions[condition.ID] = condition
+	return condition, nil
+}
+
+// Delete marks a stored condition deleted, as the repository does
+func (mock *MockConditionRepository) Delete(ctx context.Context, conditionID string) error {
+	if mock.deleted[conditionID] {
+		return deletedError(repository.ErrConditionNotFound)
+	}
+	if _, exists := mock.conditions[conditionID]; !exists {
+		return repository.ErrConditionNotFound
+	}
+	delete(mock.conditions, conditionID)
+	mock.deleted[conditionID] = true
+	return nil
+}
+
+// TestConditionService_Lifecycle verifies creates and updates are recorded in the subject's compartment and deletes in none
+func TestConditionService_Lifecycle(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	conditionService := NewConditionService(NewMockConditionRepository())
+	conditionService.SetChangeRepository(changeRepository)
+	ctx := context.Background()
+
+	patientReference := "Patient/patient-1"
+	createdCondition, createError := conditionService.CreateCondition(ctx, &fhir.Condition{Subject: fhir.Reference{Reference: &patientReference}})
+	if createError != nil {
+		t.Fatalf("Expected no error creating the condition, got %v", createError)
+	}
+
+	conditionID := *createdCondition.Id
+	resolved := "resolved"
+	if _, updateError := conditionService.UpdateCondition(ctx, conditionID, &fhir.Condition{
+		ClinicalStatus: &fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &resolved}}},
+		Subject:        fhir.Reference{Reference: &patientReference},
+	}); updateError != nil {
+		t.Fatalf("Expected no error updating the condition, got %v", updateError)
+	}
+	if deleteError := conditionService.DeleteCondition(ctx, conditionID); deleteError != nil {
+		t.Fatalf("Expected no error deleting the condition, got %v", deleteError)
+	}
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	for index, expectedCompartment := range []string{"patient-1", "patient-1", ""} {
+		change := changeRepository.changes[index]
+		if change.ResourceType != "Condition" || change.ResourceID != conditionID || change.CompartmentPatientID != expectedCompartment {
+			t.Errorf("Change %d: expected Condition/%s in compartment %q, got %+v", index, conditionID, expectedCompartment, change)
+		}
+	}
+
+	if _, getError := conditionService.GetConditionByID(ctx, conditionID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted reading a deleted condition, got %v", getError)
+	}
+	if deleteError := conditionService.DeleteCondition(ctx, conditionID); !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+}
+
+// TestConditionService_UnrecordedCreateIsUndone verifies a condition whose create cannot be recorded is removed again
+func TestConditionService_UnrecordedCreateIsUndone(t *testing.T) {
+	conditionRepository := NewMockConditionRepository()
+	conditionService := NewConditionService(conditionRepository)
+	conditionService.SetChangeRepository(&MockChangeRepository{recordError: errors.New("change log unavailable")})
+
+	if _, createError := conditionService.CreateCondition(context.Background(), &fhir.Condition{}); createError == nil {
+		t.Fatal("Expected the create to fail when its change cannot be recorded")
+	}
+	if len(conditionRepository.conditions) != 0 {
+		t.Errorf("Expected the unrecorded condition to be removed, got %d stored", len(conditionRepository.conditions))
+	}
+}
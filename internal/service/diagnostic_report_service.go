@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// ObservationGetter reads one observation by ID; ObservationService implements it
+type ObservationGetter interface {
+	GetObservationByID(ctx context.Context, observationID string) (*fhir.Observation, error)
+}
+
+// DiagnosticReportService handles business logic for DiagnosticReport operations
+type DiagnosticReportService struct {
+	diagnosticReportRepository repository.DiagnosticReportRepository
+	diagnosticReportMapper     *models.DiagnosticReportMapper
+	observationGetter          ObservationGetter
+	changeRepository           repository.ChangeRepository
+	eventPublisher             events.Publisher
+	strictMapping              bool
+}
+
+// NewDiagnosticReportService creates a new instance of DiagnosticReportService
+// observationGetter looks up the observations that reports reference as results
+func NewDiagnosticReportService(diagnosticReportRepository repository.DiagnosticReportRepository, observationGetter ObservationGetter) *DiagnosticReportService {
+	return &DiagnosticReportService{
+		diagnosticReportRepository: diagnosticReportRepository,
+		diagnosticReportMapper:     models.NewDiagnosticReportMapper(),
+		observationGetter:          observationGetter,
+	}
+}
+
+// SetChangeRepository enables recording diagnostic report writes to the change log
+func (service *DiagnosticReportService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing diagnostic report write events to internal consumers
+func (service *DiagnosticReportService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *DiagnosticReportService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// ValidateResultReferences checks that every result of a report references a stored observation
+// Results that are not Observation/{id} references or name unknown observations are returned as a RejectionError
+func (service *DiagnosticReportService) ValidateResultReferences(ctx context.Context, fhirDiagnosticReport *fhir.DiagnosticReport) error {
+	var resultIssues []outcome.Issue
+	for index, result := range fhirDiagnosticReport.Result {
+		expression := fmt.Sprintf("DiagnosticReport.result[%d]", index)
+		var observationID string
+		isObservation := false
+		if result.Reference != nil {
+			observationID, isObservation = strings.CutPrefix(*result.Reference, "Observation/")
+		}
+		if !isObservation || observationID == "" {
+			resultIssues = append(resultIssues, outcome.Error(fhir.IssueTypeValue, "Result must be an Observation/{id} reference", expression))
+			continue
+		}
+
+		_, getError := service.observationGetter.GetObservationByID(ctx, observationID)
+		if errors.Is(getError, repository.ErrObservationNotFound) {
+			resultIssues = append(resultIssues, outcome.Error(fhir.IssueTypeNotFound, fmt.Sprintf("Observation/%s does not exist", observationID), expression))
+			continue
+		}
+		if getError != nil {
+			return fmt.Errorf("failed to look up result observation: %w", getError)
+		}
+	}
+
+	if len(resultIssues) > 0 {
+		return &outcome.RejectionError{Issues: resultIssues}
+	}
+	return nil
+}
+
+// CreateDiagnosticReport creates a new diagnostic report from a FHIR DiagnosticReport resource
+// Reports whose results do not reference stored observations are rejected
+func (service *DiagnosticReportService) CreateDiagnosticReport(ctx context.Context, fhirDiagnosticReport *fhir.DiagnosticReport) (*fhir.DiagnosticReport, error) {
+	if validationError := service.ValidateResultReferences(ctx, fhirDiagnosticReport); validationError != nil {
+		return nil, validationError
+	}
+
+	domainDiagnosticReport, mappingIssues := service.diagnosticReportMapper.FromFHIR(fhirDiagnosticReport)
+	if issuesError := handleMappingIssues(ctx, "DiagnosticReport", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	createdDiagnosticReport, createdFHIRDiagnosticReport, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(writeContext context.Context) (*models.DiagnosticReport, error) {
+		return service.diagnosticReportRepository.Create(writeContext, domainDiagnosticReport)
+	})
+	if createError != nil {
+		return nil, createError
+	}
+
+	reportIgnoredElements(ctx, "DiagnosticReport", fhirDiagnosticReport, createdFHIRDiagnosticReport, mappingIssues)
+
+	// MongoDB cannot join a multi-write transaction, so a rolled-back transaction removes the diagnostic report again
+	if scope := transactionScopeFrom(ctx); scope != nil {
+		scope.onRollback(func(undoContext context.Context) {
+			service.undoUnrecordedWrite(undoContext, createdDiagnosticReport.ID, models.ChangeOperationCreate)
+		})
+	}
+
+	return createdFHIRDiagnosticReport, nil
+}
+
+// GetDiagnosticReportByID retrieves a diagnostic report by ID, reporting ErrResourceNotFound for unknown diagnostic reports
+func (service *DiagnosticReportService) GetDiagnosticReportByID(ctx context.Context, diagnosticReportID string) (*fhir.DiagnosticReport, error) {
+	domainDiagnosticReport, getError := service.diagnosticReportRepository.GetByID(ctx, diagnosticReportID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(getError, repository.ErrDiagnosticReportNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+
+	return service.diagnosticReportMapper.ToFHIR(domainDiagnosticReport), nil
+}
+
+// SearchDiagnosticReports retrieves diagnostic reports matching the search criteria
+func (service *DiagnosticReportService) SearchDiagnosticReports(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) ([]*fhir.DiagnosticReport, error) {
+	domainDiagnosticReports, searchError := service.diagnosticReportRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirDiagnosticReports := make([]*fhir.DiagnosticReport, 0, len(domainDiagnosticReports))
+	for _, domainDiagnosticReport := range domainDiagnosticReports {
+		fhirDiagnosticReports = append(fhirDiagnosticReports, service.diagnosticReportMapper.ToFHIR(domainDiagnosticReport))
+	}
+
+	return fhirDiagnosticReports, nil
+}
+
+// CountDiagnosticReports returns how many diagnostic reports match the search across all pages, used as the searchset total
+func (service *DiagnosticReportService) CountDiagnosticReports(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) (int, error) {
+	return service.diagnosticReportRepository.Count(ctx, searchParams)
+}
+
+// UpdateDiagnosticReport updates an existing diagnostic report, reporting ErrResourceNotFound for unknown diagnostic reports
+// Reports whose results do not reference stored observations are rejected
+func (service *DiagnosticReportService) UpdateDiagnosticReport(ctx context.Context, diagnosticReportID string, fhirDiagnosticReport *fhir.DiagnosticReport) (*fhir.DiagnosticReport, error) {
+	if validationError := service.ValidateResultReferences(ctx, fhirDiagnosticReport); validationError != nil {
+		return nil, validationError
+	}
+
+	domainDiagnosticReport, mappingIssues := service.diagnosticReportMapper.FromFHIR(fhirDiagnosticReport)
+	if issuesError := handleMappingIssues(ctx, "DiagnosticReport", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	domainDiagnosticReport.ID = diagnosticReportID
+
+	_, updatedFHIRDiagnosticReport, updateError := service.commitWrite(ctx, diagnosticReportID, models.ChangeOperationUpdate, func(writeContext context.Context) (*models.DiagnosticReport, error) {
+		return service.diagnosticReportRepository.Update(writeContext, domainDiagnosticReport)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, repository.ErrDiagnosticReportNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	reportIgnoredElements(ctx, "DiagnosticReport", fhirDiagnosticReport, updatedFHIRDiagnosticReport, mappingIssues)
+	return updatedFHIRDiagnosticReport, nil
+}
+
+// DeleteDiagnosticReport removes a diagnostic report by ID, reporting ErrResourceNotFound for unknown diagnostic reports
+func (service *DiagnosticReportService) DeleteDiagnosticReport(ctx context.Context, diagnosticReportID string) error {
+	_, _, deleteError := service.commitWrite(ctx, diagnosticReportID, models.ChangeOperationDelete, func(writeContext context.Context) (*models.DiagnosticReport, error) {
+		return nil, service.diagnosticReportRepository.Delete(writeContext, diagnosticReportID)
+	})
+	if errors.Is(deleteError, ErrResourceDeleted) {
+		return ErrResourceDeleted
+	}
+	if errors.Is(deleteError, repository.ErrDiagnosticReportNotFound) {
+		return ErrResourceNotFound
+	}
+	return deleteError
+}
+
+// commitWrite runs write and records it in the change log, then publishes it to event consumers
+// As with observations, MongoDB cannot join the change log's Postgres transaction, so the change is recorded in
+// a transaction opened before the write and committed only after it succeeds; when the change cannot be
+// recorded a create is undone, while an update or delete stays applied and is logged for repair
+// write returns the stored diagnostic report, or nil for deletes, whose change carries no compartment
+func (service *DiagnosticReportService) commitWrite(ctx context.Context, diagnosticReportID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.DiagnosticReport, error)) (*models.DiagnosticReport, *fhir.DiagnosticReport, error) {
+	var writtenDiagnosticReport *models.DiagnosticReport
+	var writtenFHIRDiagnosticReport *fhir.DiagnosticReport
+	var version int
+	writeApplied := false
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenDiagnosticReport, writeError = write(ctx)
+		if writeError != nil {
+			return writeError
+		}
+		writeApplied = true
+
+		var snapshot interface{}
+		var compartmentPatientID string
+		if writtenDiagnosticReport != nil {
+			diagnosticReportID = writtenDiagnosticReport.ID
+			writtenFHIRDiagnosticReport = service.diagnosticReportMapper.ToFHIR(writtenDiagnosticReport)
+			snapshot = writtenFHIRDiagnosticReport
+			compartmentPatientID = writtenDiagnosticReport.PatientID
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "DiagnosticReport", diagnosticReportID, operation, snapshot, compartmentPatientID)
+		return recordError
+	})
+	if transactionError != nil {
+		if writeApplied {
+			service.undoUnrecordedWrite(ctx, diagnosticReportID, operation)
+		}
+		return nil, nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenDiagnosticReport != nil {
+		resource = writtenDiagnosticReport
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "DiagnosticReport", diagnosticReportID, operation, version, resource)
+	return writtenDiagnosticReport, writtenFHIRDiagnosticReport, nil
+}
+
+// undoUnrecordedWrite removes a created diagnostic report whose change could not be recorded
+// Updates and deletes cannot be undone without the previous version, so they are logged for repair
+func (service *DiagnosticReportService) undoUnrecordedWrite(ctx context.Context, diagnosticReportID string, operation models.ChangeOperation) {
+	if operation == models.ChangeOperationCreate {
+		if deleteError := service.diagnosticReportRepository.Delete(ctx, diagnosticReportID); deleteError == nil {
+			return
+		}
+	}
+
+	log.Error().
+		Str("diagnostic_report_id", diagnosticReportID).
+		Str("operation", string(operation)).
+		Msg("DiagnosticReport write is stored but missing from the change log")
+}
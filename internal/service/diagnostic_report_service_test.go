@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockDiagnosticReportRepository implements DiagnosticReportRepository interface for testing
+type MockDiagnosticReportRepository struct {
+	diagnosticReports map[string]*models.DiagnosticReport
+	deleted           map[string]bool
+}
+
+// NewMockDiagnosticReportRepository creates a new mock repository for testing
+func NewMockDiagnosticReportRepository() *MockDiagnosticReportRepository {
+	return &MockDiagnosticReportRepository{diagnosticReports: make(map[string]*models.DiagnosticReport), deleted: make(map[string]bool)}
+}
+
+// Create stores a diagnostic report under a generated ObjectID
+func (mock *MockDiagnosticReportRepository) Create(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error) {
+	diagnosticReport.ID = primitive.NewObjectID().Hex()
+	mock.diagnosticReports[diagnosticReport.ID] = diagnosticReport
+	return diagnosticReport, nil
+}
+
+// GetByID retrieves a stored diagnostic report
+func (mock *MockDiagnosticReportRepository) GetByID(ctx context.Context, diagnosticReportID string) (*models.DiagnosticReport, error) {
+	if mock.deleted[diagnosticReportID] {
+		return nil, deletedError(repository.ErrDiagnosticReportNotFound)
+	}
+	diagnosticReport, exists := mock.diagnosticReports[diagnosticReportID]
+	if !exists {
+		return nil, repository.ErrDiagnosticReportNotFound
+	}
+	return diagnosticReport, nil
+}
+
+// Search returns every stored diagnostic report
+func (mock *MockDiagnosticReportRepository) Search(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) ([]*models.DiagnosticReport, error) {
+	result := make([]*models.DiagnosticReport, 0, len(mock.diagnosticReports))
+	for _, diagnosticReport := range mock.diagnosticReports {
+		result = append(result, diagnosticReport)
+	}
+	return result, nil
+}
+
+// Count returns how many diagnostic reports are stored, as every search matches them all
+func (mock *MockDiagnosticReportRepository) Count(ctx context.Context, searchParams *models.DiagnosticReportSearchParams) (int, error) {
+	return len(mock.diagnosticReports), nil
+}
+
+// Update replaces a stored diagnostic report
+func (mock *MockDiagnosticReportRepository) Update(ctx context.Context, diagnosticReport *models.DiagnosticReport) (*models.DiagnosticReport, error) {
+	if mock.deleted[diagnosticReport.ID] {
+		return nil, deletedError(repository.ErrDiagnosticReportNotFound)
+	}
+	if _, exists := mock.diagnosticReports[diagnosticReport.ID]; !exists {
+		return nil, repository.ErrDiagnosticReportNotFound
+	}
+	mock.diagnosticReports[diagnosticReport.ID] = diagnosticReport
+	return diagnosticReport, nil
+}
+
+// Delete marks a stored diagnostic report deleted, as the repository does
+func (mock *MockDiagnosticReportRepository) Delete(ctx context.Context, diagnosticReportID string) error {
+	if mock.deleted[diagnosticReportID] {
+		return deletedError(repository.ErrDiagnosticReportNotFound)
+	}
+	if _, exists := mock.diagnosticReports[diagnosticReportID]; !exists {
+		return repository.ErrDiagnosticReportNotFound
+	}
+	delete(mock.diagnosticReports, diagnosticReportID)
+	mock.deleted[diagnosticReportID] = true
+	return nil
+}
+
+// newTestDiagnosticReportService creates a diagnostic report service whose result lookups read observationRepository
+func newTestDiagnosticReportService(diagnosticReportRepository *MockDiagnosticReportRepository, observationRepository *MockObservationRepository) *DiagnosticReportService {
+	return NewDiagnosticReportService(diagnosticReportRepository, NewObservationService(observationRepository))
+}
+
+// TestDiagnosticReportService_Lifecycle verifies creates and updates are recorded in the subject's compartment and deletes in none
+func TestDiagnosticReportService_Lifecycle(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	observationRepository := NewMockObservationRepository()
+	observationRepository.observations["obs-1"] = &models.Observation{ID: "obs-1", PatientID: "patient-1"}
+	diagnosticReportService := newTestDiagnosticReportService(NewMockDiagnosticReportRepository(), observationRepository)
+	diagnosticReportService.SetChangeRepository(changeRepository)
+	ctx := context.Background()
+
+	patientReference := "Patient/patient-1"
+	resultReference := "Observation/obs-1"
+	createdDiagnosticReport, createError := diagnosticReportService.CreateDiagnosticReport(ctx, &fhir.DiagnosticReport{
+		Status:  fhir.DiagnosticReportStatusPreliminary,
+		Subject: &fhir.Reference{Reference: &patientReference},
+		Result:  []fhir.Reference{{Reference: &resultReference}},
+	})
+	if createError != nil {
+		t.Fatalf("Expected no error creating the diagnostic report, got %v", createError)
+	}
+
+	diagnosticReportID := *createdDiagnosticReport.Id
+	if _, updateError := diagnosticReportService.UpdateDiagnosticReport(ctx, diagnosticReportID, &fhir.DiagnosticReport{
+		Status:  fhir.DiagnosticReportStatusFinal,
+		Subject: &fhir.Reference{Reference: &patientReference},
+		Result:  []fhir.Reference{{Reference: &resultReference}},
+	}); updateError != nil {
+		t.Fatalf("Expected no error updating the diagnostic report, got %v", updateError)
+	}
+	if deleteError := diagnosticReportService.DeleteDiagnosticReport(ctx, diagnosticReportID); deleteError != nil {
+		t.Fatalf("Expected no error deleting the diagnostic report, got %v", deleteError)
+	}
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	for index, expectedCompartment := range []string{"patient-1", "patient-1", ""} {
+		change := changeRepository.changes[index]
+		if change.ResourceType != "DiagnosticReport" || change.ResourceID != diagnosticReportID || change.CompartmentPatientID != expectedCompartment {
+			t.Errorf("Change %d: expected DiagnosticReport/%s in compartment %q, got %+v", index, diagnosticReportID, expectedCompartment, change)
+		}
+	}
+
+	if _, getError := diagnosticReportService.GetDiagnosticReportByID(ctx, diagnosticReportID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted reading a deleted diagnostic report, got %v", getError)
+	}
+	if deleteError := diagnosticReportService.DeleteDiagnosticReport(ctx, diagnosticReportID); !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+}
+
+// TestDiagnosticReportService_RejectsUnknownResults verifies results naming missing observations or other resource types are rejected before anything is stored
+func TestDiagnosticReportService_RejectsUnknownResults(t *testing.T) {
+	diagnosticReportRepository := NewMockDiagnosticReportRepository()
+	observationRepository := NewMockObservationRepository()
+	observationRepository.observations["obs-1"] = &models.Observation{ID: "obs-1"}
+	diagnosticReportService := newTestDiagnosticReportService(diagnosticReportRepository, observationRepository)
+
+	storedResult := "Observation/obs-1"
+	missingResult := "Observation/obs-missing"
+	wrongTypeResult := "Patient/patient-1"
+	_, createError := diagnosticReportService.CreateDiagnosticReport(context.Background(), &fhir.DiagnosticReport{
+		Result: []fhir.Reference{{Reference: &storedResult}, {Reference: &missingResult}, {Reference: &wrongTypeResult}},
+	})
+
+	var rejection *outcome.RejectionError
+	if !errors.As(createError, &rejection) || len(rejection.Issues) != 2 {
+		t.Fatalf("Expected a rejection with two issues, got %v", createError)
+	}
+	if rejection.Issues[0].Code != fhir.IssueTypeNotFound || rejection.Issues[0].Expression[0] != "DiagnosticReport.result[1]" {
+		t.Errorf("Expected the missing observation reported at result[1], got %+v", rejection.Issues[0])
+	}
+	if rejection.Issues[1].Expression[0] != "DiagnosticReport.result[2]" {
+		t.Errorf("Expected the patient reference reported at result[2], got %+v", rejection.Issues[1])
+	}
+	if len(diagnosticReportRepository.diagnosticReports) != 0 {
+		t.Errorf("Expected nothing stored for a rejected report, got %d", len(diagnosticReportRepository.diagnosticReports))
+	}
+}
+
+// TestDiagnosticReportService_ResultLookupFailure verifies a failing observation lookup is returned as an error rather than a rejection
+func TestDiagnosticReportService_ResultLookupFailure(t *testing.T) {
+	observationRepository := NewMockObservationRepository()
+	observationRepository.getByIDError = errors.New("mongo unavailable")
+	diagnosticReportService := newTestDiagnosticReportService(NewMockDiagnosticReportRepository(), observationRepository)
+
+	resultReference := "Observation/obs-1"
+	_, createError := diagnosticReportService.CreateDiagnosticReport(context.Background(), &fhir.DiagnosticReport{
+		Result: []fhir.Reference{{Reference: &resultReference}},
+	})
+
+	var rejection *outcome.RejectionError
+	if createError == nil || errors.As(createError, &rejection) {
+		t.Errorf("Expected the lookup failure as a plain error, got %v", createError)
+	}
+}
+
+// TestDiagnosticReportService_UnrecordedCreateIsUndone verifies a diagnostic report whose create cannot be recorded is removed again
+func TestDiagnosticReportService_UnrecordedCreateIsUndone(t *testing.T) {
+	diagnosticReportRepository := NewMockDiagnosticReportRepository()
+	diagnosticReportService := newTestDiagnosticReportService(diagnosticReportRepository, NewMockObservationRepository())
+	diagnosticReportService.SetChangeRepository(&MockChangeRepository{recordError: errors.New("change log unavailable")})
+
+	if _, createError := diagnosticReportService.CreateDiagnosticReport(context.Background(), &fhir.DiagnosticReport{}); createError == nil {
+		t.Fatal("Expected the create to fail when its change cannot be recorded")
+	}
+	if len(diagnosticReportRepository.diagnosticReports) != 0 {
+		t.Errorf("Expected the unrecorded diagnostic report to be removed, got %d stored", len(diagnosticReportRepository.diagnosticReports))
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/terminology"
+)
+
+// backfillDisplays fills in display text the client left out for codes the terminology knows
+// Displays the client sent are kept as they are. Categories are stored without their system, so their codes
+// are looked up in the FHIR observation-category system
+func backfillDisplays(codeTerminology *terminology.Terminology, observation *models.Observation) {
+	if codeTerminology == nil {
+		return
+	}
+
+	if observation.CodeDisplay == "" {
+		if display, known := codeTerminology.Display(observation.CodeSystem, observation.Code); known {
+			observation.CodeDisplay = display
+		}
+	}
+	for index := range observation.Components {
+		component := &observation.Components[index]
+		if component.CodeDisplay == "" {
+			if display, known := codeTerminology.Display(component.CodeSystem, component.Code); known {
+				component.CodeDisplay = display
+			}
+		}
+	}
+	if observation.CategoryDisplay == "" && observation.Category != "" {
+		if display, known := codeTerminology.Display(terminology.ObservationCategorySystem, observation.Category); known {
+			observation.CategoryDisplay = display
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/terminology"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// bloodPressureWithoutDisplays returns a blood pressure panel whose codings carry no display text
+// except the diastolic component, which keeps the client's own
+func bloodPressureWithoutDisplays() *fhir.Observation {
+	loinc := terminology.LOINCSystem
+	panelCode := "85354-9"
+	category := "vital-signs"
+	systolicCode := "8480-6"
+	diastolicCode := "8462-4"
+	diastolicDisplay := "Diastolic"
+	return &fhir.Observation{
+		Category: []fhir.CodeableConcept{{Coding: []fhir.Coding{{Code: &category}}}},
+		Code:     fhir.CodeableConcept{Coding: []fhir.Coding{{System: &loinc, Code: &panelCode}}},
+		Component: []fhir.ObservationComponent{
+			{Code: fhir.CodeableConcept{Coding: []fhir.Coding{{System: &loinc, Code: &systolicCode}}}},
+			{Code: fhir.CodeableConcept{Coding: []fhir.Coding{{System: &loinc, Code: &diastolicCode, Display: &diastolicDisplay}}}},
+		},
+	}
+}
+
+// TestObservationService_BackfillsDisplays verifies missing code, component, and category displays are filled in
+// and displays sent by the client are kept
+func TestObservationService_BackfillsDisplays(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	observationService.SetTerminology(terminology.Default())
+
+	created, createError := observationService.CreateObservation(context.Background(), bloodPressureWithoutDisplays())
+	if createError != nil {
+		t.Fatalf("Expected no error, got %v", createError)
+	}
+
+	stored := mockRepo.lastCreated
+	if stored.CodeDisplay != "Blood pressure panel with all children optional" {
+		t.Errorf("Expected the panel display to be filled in, got %q", stored.CodeDisplay)
+	}
+	if stored.Components[0].CodeDisplay != "Systolic blood pressure" || stored.Components[1].CodeDisplay != "Diastolic" {
+		t.Errorf("Expected the systolic display filled in and the client's diastolic display kept, got %+v", stored.Components)
+	}
+	if *created.Category[0].Coding[0].Display != "Vital Signs" {
+		t.Errorf("Expected the category display to be filled in, got %q", *created.Category[0].Coding[0].Display)
+	}
+}
+
+// TestObservationService_NoDisplayBackfillWithoutTerminology verifies displays are left empty when backfill is off
+func TestObservationService_NoDisplayBackfillWithoutTerminology(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+
+	created, createError := observationService.CreateObservation(context.Background(), bloodPressureWithoutDisplays())
+	if createError != nil {
+		t.Fatalf("Expected no error, got %v", createError)
+	}
+	if mockRepo.lastCreated.CodeDisplay != "" || *created.Category[0].Coding[0].Display != "vital-signs" {
+		t.Errorf("Expected no displays filled in, got %q and category %q", mockRepo.lastCreated.CodeDisplay, *created.Category[0].Coding[0].Display)
+	}
+}
@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// EncounterService handles business logic for Encounter operations
+type EncounterService struct {
+	encounterRepository repository.EncounterRepository
+	encounterMapper     *models.EncounterMapper
+	changeRepository    repository.ChangeRepository
+	eventPublisher      events.Publisher
+	strictMapping       bool
+}
+
+// NewEncounterService creates a new instance of EncounterService
+func NewEncounterService(encounterRepository repository.EncounterRepository) *EncounterService {
+	return &EncounterService{
+		encounterRepository: encounterRepository,
+		encounterMapper:     models.NewEncounterMapper(),
+	}
+}
+
+// SetChangeRepository enables recording encounter writes to the change log
+func (service *EncounterService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing encounter write events to internal consumers
+func (service *EncounterService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *EncounterService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// CreateEncounter creates a new encounter from a FHIR Encounter resource
+func (service *EncounterService) CreateEncounter(ctx context.Context, fhirEncounter *fhir.Encounter) (*fhir.Encounter, error) {
+	domainEncounter, mappingIssues := service.encounterMapper.FromFHIR(fhirEncounter)
+	if issuesError := handleMappingIssues(ctx, "Encounter", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	createdFHIREncounter, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(transactionContext context.Context) (*models.Encounter, error) {
+		return service.encounterRepository.Create(transactionContext, domainEncounter)
+	})
+	if createError != nil {
+		return nil, createError
+	}
+
+	reportIgnoredElements(ctx, "Encounter", fhirEncounter, createdFHIREncounter, mappingIssues)
+	return createdFHIREncounter, nil
+}
+
+// GetEncounterByID retrieves an encounter by ID, reporting ErrResourceNotFound for unknown encounters
+func (service *EncounterService) GetEncounterByID(ctx context.Context, encounterID string) (*fhir.Encounter, error) {
+	if _, parseError := uuid.Parse(encounterID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainEncounter, getError := service.encounterRepository.GetByID(ctx, encounterID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+
+	return service.encounterMapper.ToFHIR(domainEncounter), nil
+}
+
+// SearchEncounters retrieves encounters matching the search criteria
+// Patient IDs are stored as UUIDs, so any other patient ID matches no encounters
+func (service *EncounterService) SearchEncounters(ctx context.Context, searchParams *models.EncounterSearchParams) ([]*fhir.Encounter, error) {
+	if !searchablePatientID(searchParams.PatientID) {
+		return []*fhir.Encounter{}, nil
+	}
+
+	domainEncounters, searchError := service.encounterRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirEncounters := make([]*fhir.Encounter, len(domainEncounters))
+	for index, domainEncounter := range domainEncounters {
+		fhirEncounters[index] = service.encounterMapper.ToFHIR(domainEncounter)
+	}
+
+	return fhirEncounters, nil
+}
+
+// CountEncounters returns how many encounters match the search across all pages, used as the searchset total
+func (service *EncounterService) CountEncounters(ctx context.Context, searchParams *models.EncounterSearchParams) (int, error) {
+	if !searchablePatientID(searchParams.PatientID) {
+		return 0, nil
+	}
+	return service.encounterRepository.Count(ctx, searchParams)
+}
+
+// searchablePatientID reports whether a search's patient criterion can match a patient ID stored as a UUID
+func searchablePatientID(patientID string) bool {
+	if patientID == "" {
+		return true
+	}
+	_, parseError := uuid.Parse(patientID)
+	return parseError == nil
+}
+
+// UpdateEncounter updates an existing encounter, reporting ErrResourceNotFound for unknown encounters
+func (service *EncounterService) UpdateEncounter(ctx context.Context, encounterID string, fhirEncounter *fhir.Encounter) (*fhir.Encounter, error) {
+	if _, parseError := uuid.Parse(encounterID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainEncounter, mappingIssues := service.encounterMapper.FromFHIR(fhirEncounter)
+	if issuesError := handleMappingIssues(ctx, "Encounter", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	domainEncounter.ID = encounterID
+
+	updatedFHIREncounter, updateError := service.commitWrite(ctx, encounterID, models.ChangeOperationUpdate, func(transactionContext context.Context) (*models.Encounter, error) {
+		return service.encounterRepository.Update(transactionContext, domainEncounter)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	reportIgnoredElements(ctx, "Encounter", fhirEncounter, updatedFHIREncounter, mappingIssues)
+	return updatedFHIREncounter, nil
+}
+
+// DeleteEncounter removes an encounter by ID, reporting ErrResourceNotFound for unknown encounters
+// Observations recorded during the encounter keep their reference
+func (service *EncounterService) DeleteEncounter(ctx context.Context, encounterID string) error {
+	if _, parseError := uuid.Parse(encounterID); parseError != nil {
+		return ErrResourceNotFound
+	}
+
+	_, deleteError := service.commitWrite(ctx, encounterID, models.ChangeOperationDelete, func(transactionContext context.Context) (*models.Encounter, error) {
+		return nil, service.encounterRepository.Delete(transactionContext, encounterID)
+	})
+	if errors.Is(deleteError, ErrResourceDeleted) {
+		return ErrResourceDeleted
+	}
+	if errors.Is(deleteError, sql.ErrNoRows) {
+		return ErrResourceNotFound
+	}
+	return deleteError
+}
+
+// commitWrite runs write and records it in the change log in one transaction, then publishes it to event consumers
+// write returns the stored encounter, or nil for deletes; its FHIR form is returned and kept as the version's snapshot
+// Creates and updates are recorded in the subject patient's compartment; deletes, like observation deletes, carry none
+func (service *EncounterService) commitWrite(ctx context.Context, encounterID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.Encounter, error)) (*fhir.Encounter, error) {
+	var writtenEncounter *models.Encounter
+	var writtenFHIREncounter *fhir.Encounter
+	var version int
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenEncounter, writeError = write(transactionContext)
+		if writeError != nil {
+			return writeError
+		}
+
+		var snapshot interface{}
+		var compartmentPatientID string
+		if writtenEncounter != nil {
+			encounterID = writtenEncounter.ID
+			writtenFHIREncounter = service.encounterMapper.ToFHIR(writtenEncounter)
+			snapshot = writtenFHIREncounter
+			compartmentPatientID = writtenEncounter.PatientID
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "Encounter", encounterID, operation, snapshot, compartmentPatientID)
+		return recordError
+	})
+	if transactionError != nil {
+		return nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenEncounter != nil {
+		resource = writtenEncounter
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "Encounter", encounterID, operation, version, resource)
+	return writtenFHIREncounter, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockEncounterRepository implements EncounterRepository interface for testing
+type MockEncounterRepository struct {
+	encounters map[string]*models.Encounter
+	deleted    map[string]bool
+	searches   int
+}
+
+// NewMockEncounterRepository creates a new mock repository for testing
+func NewMockEncounterRepository() *MockEncounterRepository {
+	return &MockEncounterRepository{encounters: make(map[string]*models.Encounter), deleted: make(map[string]bool)}
+}
+
+// Create stores an encounter under a generated UUID
+func (mock *MockEncounterRepository) Create(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error) {
+	encounter.ID = uuid.NewString()
+	mock.encounters[encounter.ID] = encounter
+	return encounter, nil
+}
+
+// GetByID retrieves a stored encounter
+func (mock *MockEncounterRepository) GetByID(ctx context.Context, encounterID string) (*models.Encounter, error) {
+	if mock.deleted[encounterID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	encounter, exists := mock.encounters[encounterID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return encounter, nil
+}
+
+// Search returns every stored encounter and counts the call
+func (mock *MockEncounterRepository) Search(ctx context.Context, searchParams *models.EncounterSearchParams) ([]*models.Encounter, error) {
+	mock.searches++
+	result := make([]*models.Encounter, 0, len(mock.encounters))
+	for _, encounter := range mock.encounters {
+		result = append(result, encounter)
+	}
+	return result, nil
+}
+
+// Count returns how many encounters are stored, as every search matches them all
+func (mock *MockEncounterRepository) Count(ctx context.Context, searchParams *models.EncounterSearchParams) (int, error) {
+	return len(mock.encounters), nil
+}
+
+// Update replaces a stored encounter
+func (mock *MockEncounterRepository) Update(ctx context.Context, encounter *models.Encounter) (*models.Encounter, error) {
+	if mock.deleted[encounter.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.encounters[encounter.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.encounters[encounter.ID] = encounter
+	return encounter, nil
+}
+
+// Delete marks a stored encounter deleted, as the repository does
+func (mock *MockEncounterRepository) Delete(ctx context.Context, encounterID string) error {
+	if mock.deleted[encounterID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.encounters[encounterID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.encounters, encounterID)
+	mock.deleted[encounterID] = true
+	return nil
+}
+
+// TestEncounterService_Lifecycle verifies creates and updates are recorded in the subject's compartment and deletes in none
+func TestEncounterService_Lifecycle(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	encounterService := NewEncounterService(NewMockEncounterRepository())
+	encounterService.SetChangeRepository(changeRepository)
+	ctx := context.Background()
+
+	patientID := uuid.NewString()
+	patientReference := "Patient/" + patientID
+	createdEncounter, createError := encounterService.CreateEncounter(ctx, &fhir.Encounter{
+		Status:  fhir.EncounterStatusInProgress,
+		Subject: &fhir.Reference{Reference: &patientReference},
+	})
+	if createError != nil {
+		t.Fatalf("Expected no error creating the encounter, got %v", createError)
+	}
+
+	encounterID := *createdEncounter.Id
+	if _, updateError := encounterService.UpdateEncounter(ctx, encounterID, &fhir.Encounter{
+		Status:  fhir.EncounterStatusFinished,
+		Subject: &fhir.Reference{Reference: &patientReference},
+	}); updateError != nil {
+		t.Fatalf("Expected no error updating the encounter, got %v", updateError)
+	}
+	if deleteError := encounterService.DeleteEncounter(ctx, encounterID); deleteError != nil {
+		t.Fatalf("Expected no error deleting the encounter, got %v", deleteError)
+	}
+	if _, getError := encounterService.GetEncounterByID(ctx, encounterID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted reading the deleted encounter, got %v", getError)
+	}
+	if deleteError := encounterService.DeleteEncounter(ctx, encounterID); !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	for index, expectedCompartment := range []string{patientID, patientID, ""} {
+		change := changeRepository.changes[index]
+		if change.ResourceType != "Encounter" || change.ResourceID != encounterID || change.CompartmentPatientID != expectedCompartment {
+			t.Errorf("Change %d: expected Encounter/%s in compartment %q, got %+v", index, encounterID, expectedCompartment, change)
+		}
+	}
+}
+
+// TestEncounterService_SearchNonUUIDPatient verifies a patient ID that cannot be stored matches nothing without querying
+func TestEncounterService_SearchNonUUIDPatient(t *testing.T) {
+	encounterRepository := NewMockEncounterRepository()
+	encounterService := NewEncounterService(encounterRepository)
+	ctx := context.Background()
+	if _, createError := encounterService.CreateEncounter(ctx, &fhir.Encounter{Status: fhir.EncounterStatusPlanned}); createError != nil {
+		t.Fatalf("Expected no error creating the encounter, got %v", createError)
+	}
+
+	searchParams := &models.EncounterSearchParams{PatientID: "not-a-uuid", Limit: 10}
+	encounters, searchError := encounterService.SearchEncounters(ctx, searchParams)
+	if searchError != nil || len(encounters) != 0 {
+		t.Errorf("Expected no encounters, got %d (error %v)", len(encounters), searchError)
+	}
+	if total, _ := encounterService.CountEncounters(ctx, searchParams); total != 0 {
+		t.Errorf("Expected a total of 0, got %d", total)
+	}
+	if encounterRepository.searches != 0 {
+		t.Errorf("Expected the repository not to be searched, got %d searches", encounterRepository.searches)
+	}
+}
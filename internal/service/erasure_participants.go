@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// observationErasure withdraws the patient's observations from MongoDB
+type observationErasure struct {
+	observationService *ObservationService
+	erasureStore       repository.ObservationErasureStore
+}
+
+// NewObservationErasureParticipant creates the saga step for the patient's observations
+// Moves are recorded through observationService so sync consumers, event subscribers, and the ledger follow them
+func NewObservationErasureParticipant(observationService *ObservationService, erasureStore repository.ObservationErasureStore) ErasureParticipant {
+	return &observationErasure{
+		observationService: observationService,
+		erasureStore:       erasureStore,
+	}
+}
+
+// Name identifies the observation store
+func (participant *observationErasure) Name() string {
+	return "observations"
+}
+
+// Hold moves the observations aside and records each live one as deleted
+func (participant *observationErasure) Hold(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	heldObservations, holdError := participant.erasureStore.HoldForErasure(ctx, erasure.ID, erasure.PatientID)
+	if holdError != nil {
+		return 0, holdError
+	}
+	if recordError := participant.observationService.recordMovedObservations(ctx, liveObservations(heldObservations), models.ChangeOperationDelete); recordError != nil {
+		return 0, recordError
+	}
+	return int64(len(heldObservations)), nil
+}
+
+// Restore moves the observations back and records each live one as created again
+func (participant *observationErasure) Restore(ctx context.Context, erasure *models.PatientErasure) error {
+	restoredObservations, restoreError := participant.erasureStore.RestoreErasure(ctx, erasure.ID)
+	if restoreError != nil {
+		return restoreError
+	}
+	return participant.observationService.recordMovedObservations(ctx, liveObservations(restoredObservations), models.ChangeOperationCreate)
+}
+
+// liveObservations returns the observations that were not deleted
+// A deleted observation's delete is already recorded, so moving it records nothing
+func liveObservations(observations []*models.Observation) []*models.Observation {
+	live := make([]*models.Observation, 0, len(observations))
+	for _, observation := range observations {
+		if observation.DeletedAt == nil {
+			live = append(live, observation)
+		}
+	}
+	return live
+}
+
+// Purge deletes the held observations
+func (participant *observationErasure) Purge(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	return participant.erasureStore.PurgeErasure(ctx, erasure.ID)
+}
+
+// patientErasure withdraws the patient row and its daily rollups from Postgres
+type patientErasure struct {
+	patientService    *PatientService
+	erasureRepository repository.ErasureRepository
+}
+
+// NewPatientErasureParticipant creates the saga step for the patient and its rollups
+// The patient's delete and reinstatement are recorded through patientService like any other patient write
+func NewPatientErasureParticipant(patientService *PatientService, erasureRepository repository.ErasureRepository) ErasureParticipant {
+	return &patientErasure{
+		patientService:    patientService,
+		erasureRepository: erasureRepository,
+	}
+}
+
+// Name identifies the patient store
+func (participant *patientErasure) Name() string {
+	return "patient"
+}
+
+// Hold copies the patient and rollups aside and deletes them in one transaction
+func (participant *patientErasure) Hold(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	var heldRows int64
+	withdrawError := participant.patientService.withdrawForErasure(ctx, erasure.PatientID, func(transactionContext context.Context) error {
+		var holdError error
+		heldRows, holdError = participant.erasureRepository.HoldPatientRecords(transactionContext, erasure.ID, erasure.PatientID)
+		return holdError
+	})
+	if withdrawError != nil {
+		return 0, withdrawError
+	}
+	return heldRows, nil
+}
+
+// Restore puts the patient and rollups back in one transaction
+func (participant *patientErasure) Restore(ctx context.Context, erasure *models.PatientErasure) error {
+	return participant.patientService.reinstateAfterErasure(ctx, erasure.PatientID, func(transactionContext context.Context) error {
+		return participant.erasureRepository.RestorePatientRecords(transactionContext, erasure.ID)
+	})
+}
+
+// Purge deletes the held patient and rollup rows
+func (participant *patientErasure) Purge(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	return participant.erasureRepository.PurgePatientRecords(ctx, erasure.ID)
+}
+
+// historyErasure removes the patient's data from the change log's point-in-time snapshots
+type historyErasure struct {
+	erasureRepository repository.ErasureRepository
+}
+
+// NewHistoryErasureParticipant creates the saga step for the snapshots kept in the change log
+// Snapshots are only cleared at purge: until then history reads still need them if the erasure is cancelled
+func NewHistoryErasureParticipant(erasureRepository repository.ErasureRepository) ErasureParticipant {
+	return &historyErasure{
+		erasureRepository: erasureRepository,
+	}
+}
+
+// Name identifies the change log
+func (participant *historyErasure) Name() string {
+	return "history"
+}
+
+// Hold withdraws nothing; past versions stay readable until the purge so a cancelled erasure loses no history
+func (participant *historyErasure) Hold(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	return 0, nil
+}
+
+// Restore has nothing to put back
+func (participant *historyErasure) Restore(ctx context.Context, erasure *models.PatientErasure) error {
+	return nil
+}
+
+// Purge clears the snapshots of every change in the patient's compartment
+func (participant *historyErasure) Purge(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	return participant.erasureRepository.ClearCompartmentSnapshots(ctx, erasure.PatientID)
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// ErasureParticipant is one store a patient erasure withdraws records from, holds, and finally purges
+// Every method must be safe to repeat: a saga interrupted by a restart runs its current step again
+// Another store, such as a blob store for attachments, joins the saga by implementing this interface
+type ErasureParticipant interface {
+	// Name identifies the store in held and purged counts and in the deletion certificate
+	Name() string
+
+	// Hold withdraws the patient's records from circulation so they can still be restored, returning how many it withdrew
+	Hold(ctx context.Context, erasure *models.PatientErasure) (int64, error)
+
+	// Restore puts back whatever Hold withdrew; it does nothing when Hold withdrew nothing
+	Restore(ctx context.Context, erasure *models.PatientErasure) error
+
+	// Purge permanently deletes what Hold withdrew, returning how many records it deleted
+	Purge(ctx context.Context, erasure *models.PatientErasure) (int64, error)
+}
+
+// ErasurePolicy controls how long erased records are kept and how often the saga runner looks for work
+type ErasurePolicy struct {
+	// WaitingPeriod is how long withdrawn records are kept, and the erasure can be cancelled, before they are purged
+	WaitingPeriod time.Duration
+
+	// PollInterval is the pause between checks for erasures with work to do
+	PollInterval time.Duration
+}
+
+// DefaultErasurePolicy keeps withdrawn records for 30 days and checks for work every minute
+func DefaultErasurePolicy() ErasurePolicy {
+	return ErasurePolicy{
+		WaitingPeriod: 30 * 24 * time.Hour,
+		PollInterval:  time.Minute,
+	}
+}
+
+// ErasureService carries out patients' right to erasure as a saga across every store holding their records
+// A request first withdraws the records from each participant in order; if a step fails, or the request is
+// cancelled during the waiting period, the completed steps are compensated in reverse. Once the waiting
+// period has passed the records are purged and a deletion certificate is issued
+type ErasureService struct {
+	erasureRepository repository.ErasureRepository
+	patientRepository repository.PatientRepository
+	participants      []ErasureParticipant
+	deletionGuard     DeletionGuard
+	policy            ErasurePolicy
+	now               func() time.Time
+}
+
+// NewErasureService creates a new patient erasure service whose saga runs the participants in order
+func NewErasureService(erasureRepository repository.ErasureRepository, patientRepository repository.PatientRepository, policy ErasurePolicy, participants ...ErasureParticipant) *ErasureService {
+	return &ErasureService{
+		erasureRepository: erasureRepository,
+		patientRepository: patientRepository,
+		participants:      participants,
+		policy:            policy,
+		now:               time.Now,
+	}
+}
+
+// SetDeletionGuard refuses erasure of patients under legal hold
+func (service *ErasureService) SetDeletionGuard(deletionGuard DeletionGuard) {
+	service.deletionGuard = deletionGuard
+}
+
+// Request queues erasure of the patient; only the compliance role may do this
+func (service *ErasureService) Request(ctx context.Context, patientID string, reason string) (*models.PatientErasure, error) {
+	principal, roleError := requireErasureCompliance(ctx)
+	if roleError != nil {
+		return nil, roleError
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, apperrors.InvalidInput("reason", "is required")
+	}
+	// A deleted patient's row is kept, so it can still be erased
+	if _, getError := service.patientRepository.GetByID(ctx, patientID); getError != nil && !errors.Is(getError, ErrResourceDeleted) {
+		return nil, apperrors.NotFound("Patient", patientID)
+	}
+	if service.deletionGuard != nil {
+		if guardError := service.deletionGuard.CheckDeletable(ctx, patientID, "Patient", patientID); guardError != nil {
+			return nil, guardError
+		}
+	}
+
+	erasure, createError := service.erasureRepository.Create(ctx, &models.PatientErasure{
+		PatientID:   patientID,
+		Reason:      strings.TrimSpace(reason),
+		RequestedBy: principal.ID,
+	})
+	if errors.Is(createError, sql.ErrNoRows) {
+		return nil, apperrors.Conflict("PatientErasure", "patient "+patientID+" already has an erasure in progress")
+	}
+	if createError != nil {
+		return nil, createError
+	}
+
+	log.Info().
+		Int64("erasure_id", erasure.ID).
+		Str("patient_id", patientID).
+		Str("actor", principal.ID).
+		Str("reason", erasure.Reason).
+		Msg("Patient erasure requested")
+	return erasure, nil
+}
+
+// Get returns the erasure and its progress (compliance role only)
+func (service *ErasureService) Get(ctx context.Context, erasureID int64) (*models.PatientErasure, error) {
+	if _, roleError := requireErasureCompliance(ctx); roleError != nil {
+		return nil, roleError
+	}
+	return service.getErasure(ctx, erasureID)
+}
+
+// Cancel stops a pending or held erasure and queues restoring the records it withdrew; only the compliance role may do this
+func (service *ErasureService) Cancel(ctx context.Context, erasureID int64) (*models.PatientErasure, error) {
+	principal, roleError := requireErasureCompliance(ctx)
+	if roleError != nil {
+		return nil, roleError
+	}
+
+	erasure, getError := service.getErasure(ctx, erasureID)
+	if getError != nil {
+		return nil, getError
+	}
+	if erasure.Status != models.ErasureStatusPending && erasure.Status != models.ErasureStatusHeld {
+		return nil, apperrors.Conflict("PatientErasure", "erasure is already "+string(erasure.Status))
+	}
+
+	cancelError := service.erasureRepository.Cancel(ctx, erasureID, principal.ID)
+	if errors.Is(cancelError, sql.ErrNoRows) {
+		return nil, apperrors.Conflict("PatientErasure", "erasure changed state; try again")
+	}
+	if cancelError != nil {
+		return nil, cancelError
+	}
+
+	log.Info().
+		Int64("erasure_id", erasureID).
+		Str("patient_id", erasure.PatientID).
+		Str("actor", principal.ID).
+		Msg("Patient erasure cancelled")
+	return service.getErasure(ctx, erasureID)
+}
+
+// Certificate returns the deletion certificate of a purged erasure (compliance role only)
+func (service *ErasureService) Certificate(ctx context.Context, erasureID int64) (*models.DeletionCertificate, error) {
+	if _, roleError := requireErasureCompliance(ctx); roleError != nil {
+		return nil, roleError
+	}
+
+	erasure, getError := service.getErasure(ctx, erasureID)
+	if getError != nil {
+		return nil, getError
+	}
+	if erasure.Status != models.ErasureStatusPurged {
+		return nil, apperrors.Conflict("PatientErasure", "no certificate until the erasure is purged; it is "+string(erasure.Status))
+	}
+
+	return service.erasureRepository.GetCertificate(ctx, erasureID)
+}
+
+// getErasure reads the erasure, reporting a 404 when it does not exist
+func (service *ErasureService) getErasure(ctx context.Context, erasureID int64) (*models.PatientErasure, error) {
+	erasure, getError := service.erasureRepository.Get(ctx, erasureID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, apperrors.NotFound("PatientErasure", fmt.Sprint(erasureID))
+	}
+	return erasure, getError
+}
+
+// Run advances erasures with work to do, pausing PollInterval when there is none, until ctx is cancelled
+// Erasures interrupted by a restart continue where they stopped
+func (service *ErasureService) Run(ctx context.Context) {
+	for {
+		erasure, processError := service.ProcessNext(ctx)
+		if processError != nil && ctx.Err() == nil {
+			log.Error().Err(processError).Msg("Failed to process patient erasure")
+		}
+		if erasure != nil && processError == nil {
+			continue
+		}
+
+		timer := time.NewTimer(service.policy.PollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// ProcessNext advances the oldest erasure with work to do by one stage: withdrawing, compensating, or purging
+// It returns the erasure it worked on, or nil when none is due
+func (service *ErasureService) ProcessNext(ctx context.Context) (*models.PatientErasure, error) {
+	erasure, findError := service.erasureRepository.NextDue(ctx, service.now())
+	if errors.Is(findError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if findError != nil {
+		return nil, findError
+	}
+
+	switch erasure.Status {
+	case models.ErasureStatusPending:
+		return erasure, service.withdraw(ctx, erasure)
+	case models.ErasureStatusCompensating:
+		return erasure, service.compensate(ctx, erasure)
+	case models.ErasureStatusHeld, models.ErasureStatusPurging:
+		return erasure, service.purge(ctx, erasure)
+	}
+	return erasure, nil
+}
+
+// withdraw runs each participant's Hold in order, then starts the waiting period
+// The step that was in progress when the saga stopped is run again, since Hold can be repeated
+// A failed step switches the erasure to compensating and restores what was withdrawn
+func (service *ErasureService) withdraw(ctx context.Context, erasure *models.PatientErasure) error {
+	for stepIndex := max(erasure.StepsStarted-1, 0); stepIndex < len(service.participants); stepIndex++ {
+		participant := service.participants[stepIndex]
+
+		// The step is marked started before it runs so compensation restores it even if the saga stops mid-step
+		stepError := service.erasureRepository.StartStep(ctx, erasure.ID, stepIndex+1)
+		if errors.Is(stepError, sql.ErrNoRows) {
+			return nil
+		}
+		if stepError != nil {
+			return stepError
+		}
+		erasure.StepsStarted = max(erasure.StepsStarted, stepIndex+1)
+
+		heldCount, holdError := participant.Hold(ctx, erasure)
+		if holdError != nil {
+			return service.startCompensation(ctx, erasure, fmt.Sprintf("%s: %v", participant.Name(), holdError))
+		}
+		if recordError := service.erasureRepository.RecordHeld(ctx, erasure.ID, participant.Name(), heldCount); recordError != nil {
+			return recordError
+		}
+	}
+
+	purgeAfter := service.now().Add(service.policy.WaitingPeriod)
+	heldError := service.erasureRepository.MarkHeld(ctx, erasure.ID, purgeAfter)
+	if errors.Is(heldError, sql.ErrNoRows) {
+		return nil
+	}
+	if heldError != nil {
+		return heldError
+	}
+
+	log.Info().
+		Int64("erasure_id", erasure.ID).
+		Str("patient_id", erasure.PatientID).
+		Time("purge_after", purgeAfter).
+		Msg("Patient records withdrawn for erasure")
+	return nil
+}
+
+// startCompensation records the failed step and restores what the saga withdrew
+func (service *ErasureService) startCompensation(ctx context.Context, erasure *models.PatientErasure, failure string) error {
+	log.Error().
+		Int64("erasure_id", erasure.ID).
+		Str("patient_id", erasure.PatientID).
+		Str("failure", failure).
+		Msg("Patient erasure step failed; compensating")
+
+	compensationError := service.erasureRepository.StartCompensation(ctx, erasure.ID, failure)
+	if errors.Is(compensationError, sql.ErrNoRows) {
+		return nil
+	}
+	if compensationError != nil {
+		return compensationError
+	}
+	erasure.Status = models.ErasureStatusCompensating
+	erasure.Error = failure
+	return service.compensate(ctx, erasure)
+}
+
+// compensate runs Restore for every started step in reverse order, then ends the erasure as failed or cancelled
+// A Restore that fails leaves the erasure compensating so the next run tries again
+func (service *ErasureService) compensate(ctx context.Context, erasure *models.PatientErasure) error {
+	for stepIndex := min(erasure.StepsStarted, len(service.participants)) - 1; stepIndex >= 0; stepIndex-- {
+		participant := service.participants[stepIndex]
+		if restoreError := participant.Restore(ctx, erasure); restoreError != nil {
+			return fmt.Errorf("failed to restore %s for erasure %d: %w", participant.Name(), erasure.ID, restoreError)
+		}
+		if recordError := service.erasureRepository.RecordRestored(ctx, erasure.ID, stepIndex); recordError != nil {
+			return recordError
+		}
+	}
+
+	if finishError := service.erasureRepository.FinishCompensation(ctx, erasure.ID); finishError != nil {
+		return finishError
+	}
+
+	log.Info().
+		Int64("erasure_id", erasure.ID).
+		Str("patient_id", erasure.PatientID).
+		Bool("cancelled", erasure.CancelledBy != "").
+		Msg("Patient erasure compensated; records restored")
+	return nil
+}
+
+// purge permanently deletes the held records from every participant and issues the deletion certificate
+// Claiming the erasure first stops it from being cancelled once deletion has begun
+func (service *ErasureService) purge(ctx context.Context, erasure *models.PatientErasure) error {
+	if erasure.Status == models.ErasureStatusHeld {
+		claimError := service.erasureRepository.StartPurge(ctx, erasure.ID)
+		if errors.Is(claimError, sql.ErrNoRows) {
+			return nil
+		}
+		if claimError != nil {
+			return claimError
+		}
+	}
+
+	purgedCounts := make(map[string]int64, len(service.participants))
+	for _, participant := range service.participants {
+		purgedCount, purgeError := participant.Purge(ctx, erasure)
+		if purgeError != nil {
+			return fmt.Errorf("failed to purge %s for erasure %d: %w", participant.Name(), erasure.ID, purgeError)
+		}
+		purgedCounts[participant.Name()] = purgedCount
+	}
+
+	certificate, certificateError := DeletionCertificate(erasure, purgedCounts, service.now())
+	if certificateError != nil {
+		return certificateError
+	}
+	if markError := service.erasureRepository.MarkPurged(ctx, erasure.ID, purgedCounts, certificate); markError != nil {
+		return markError
+	}
+
+	log.Info().
+		Int64("erasure_id", erasure.ID).
+		Str("patient_id", erasure.PatientID).
+		Str("digest", certificate.Digest).
+		Msg("Patient records purged; deletion certificate issued")
+	return nil
+}
+
+// DeletionCertificate builds the certificate for an erasure purged at purgedAt, sealed with its SHA-256 digest
+// Recomputing the digest over the certificate with Digest emptied verifies it has not been altered
+func DeletionCertificate(erasure *models.PatientErasure, purgedCounts map[string]int64, purgedAt time.Time) (*models.DeletionCertificate, error) {
+	certificate := &models.DeletionCertificate{
+		ErasureID:     erasure.ID,
+		PatientID:     erasure.PatientID,
+		Reason:        erasure.Reason,
+		RequestedBy:   erasure.RequestedBy,
+		RequestedAt:   erasure.CreatedAt.UTC(),
+		PurgedAt:      purgedAt.UTC(),
+		PurgedRecords: purgedCounts,
+	}
+
+	digest, digestError := CertificateDigest(certificate)
+	if digestError != nil {
+		return nil, digestError
+	}
+	certificate.Digest = digest
+	return certificate, nil
+}
+
+// CertificateDigest returns the hex SHA-256 of the certificate's JSON encoding with Digest left empty
+func CertificateDigest(certificate *models.DeletionCertificate) (string, error) {
+	unsealed := *certificate
+	unsealed.Digest = ""
+	encodedCertificate, encodeError := json.Marshal(unsealed)
+	if encodeError != nil {
+		return "", encodeError
+	}
+	sum := sha256.Sum256(encodedCertificate)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// requireErasureCompliance returns the request's principal, or a 403 when it lacks the compliance role
+func requireErasureCompliance(ctx context.Context) (auth.Principal, error) {
+	principal := auth.FromContext(ctx)
+	if !principal.HasRole(auth.RoleCompliance) {
+		return principal, apperrors.Forbidden("Patient erasures can only be requested or read by the compliance role")
+	}
+	return principal, nil
+}
@@ -0,0 +1,530 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// memoryErasureRepository implements ErasureRepository in memory, enforcing the same state transitions
+type memoryErasureRepository struct {
+	erasures     map[int64]*models.PatientErasure
+	certificates map[int64]*models.DeletionCertificate
+}
+
+// newMemoryErasureRepository creates an empty erasure store
+func newMemoryErasureRepository() *memoryErasureRepository {
+	return &memoryErasureRepository{
+		erasures:     make(map[int64]*models.PatientErasure),
+		certificates: make(map[int64]*models.DeletionCertificate),
+	}
+}
+
+// Create stores a pending erasure unless the patient has one in progress
+func (repository *memoryErasureRepository) Create(ctx context.Context, erasure *models.PatientErasure) (*models.PatientErasure, error) {
+	for _, existing := range repository.erasures {
+		active := slices.Contains([]models.ErasureStatus{models.ErasureStatusPending, models.ErasureStatusHeld, models.ErasureStatusCompensating, models.ErasureStatusPurging}, existing.Status)
+		if existing.PatientID == erasure.PatientID && active {
+			return nil, sql.ErrNoRows
+		}
+	}
+	erasure.ID = int64(len(repository.erasures) + 1)
+	erasure.Status = models.ErasureStatusPending
+	erasure.HeldCounts = map[string]int64{}
+	erasure.CreatedAt = time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	repository.erasures[erasure.ID] = erasure
+	return repository.Get(ctx, erasure.ID)
+}
+
+// Get returns a copy of the erasure
+func (repository *memoryErasureRepository) Get(ctx context.Context, erasureID int64) (*models.PatientErasure, error) {
+	erasure, exists := repository.erasures[erasureID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	copied := *erasure
+	return &copied, nil
+}
+
+// NextDue returns the lowest-numbered erasure with work to do
+func (repository *memoryErasureRepository) NextDue(ctx context.Context, now time.Time) (*models.PatientErasure, error) {
+	for erasureID := int64(1); erasureID <= int64(len(repository.erasures)); erasureID++ {
+		erasure := repository.erasures[erasureID]
+		switch {
+		case erasure.Status == models.ErasureStatusPending, erasure.Status == models.ErasureStatusCompensating, erasure.Status == models.ErasureStatusPurging:
+			return repository.Get(ctx, erasureID)
+		case erasure.Status == models.ErasureStatusHeld && !erasure.PurgeAfter.After(now):
+			return repository.Get(ctx, erasureID)
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// transition applies change when the erasure is in one of the allowed states
+func (repository *memoryErasureRepository) transition(erasureID int64, change func(erasure *models.PatientErasure), from ...models.ErasureStatus) error {
+	erasure, exists := repository.erasures[erasureID]
+	if !exists || !slices.Contains(from, erasure.Status) {
+		return sql.ErrNoRows
+	}
+	change(erasure)
+	return nil
+}
+
+// StartStep raises the started step count
+func (repository *memoryErasureRepository) StartStep(ctx context.Context, erasureID int64, stepsStarted int) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.StepsStarted = max(erasure.StepsStarted, stepsStarted)
+	}, models.ErasureStatusPending)
+}
+
+// RecordHeld adds to the store's held count
+func (repository *memoryErasureRepository) RecordHeld(ctx context.Context, erasureID int64, store string, count int64) error {
+	repository.erasures[erasureID].HeldCounts[store] += count
+	return nil
+}
+
+// MarkHeld starts the waiting period
+func (repository *memoryErasureRepository) MarkHeld(ctx context.Context, erasureID int64, purgeAfter time.Time) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.Status = models.ErasureStatusHeld
+		erasure.PurgeAfter = &purgeAfter
+	}, models.ErasureStatusPending)
+}
+
+// StartCompensation records the failure
+func (repository *memoryErasureRepository) StartCompensation(ctx context.Context, erasureID int64, failure string) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.Status = models.ErasureStatusCompensating
+		erasure.Error = failure
+	}, models.ErasureStatusPending)
+}
+
+// Cancel records the cancelling actor
+func (repository *memoryErasureRepository) Cancel(ctx context.Context, erasureID int64, actor string) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.Status = models.ErasureStatusCompensating
+		erasure.CancelledBy = actor
+	}, models.ErasureStatusPending, models.ErasureStatusHeld)
+}
+
+// RecordRestored lowers the started step count
+func (repository *memoryErasureRepository) RecordRestored(ctx context.Context, erasureID int64, stepsStarted int) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.StepsStarted = stepsStarted
+	}, models.ErasureStatusCompensating)
+}
+
+// FinishCompensation ends the erasure as cancelled or failed
+func (repository *memoryErasureRepository) FinishCompensation(ctx context.Context, erasureID int64) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.Status = models.ErasureStatusFailed
+		if erasure.CancelledBy != "" {
+			erasure.Status = models.ErasureStatusCancelled
+		}
+		erasure.HeldCounts = map[string]int64{}
+		erasure.PurgeAfter = nil
+	}, models.ErasureStatusCompensating)
+}
+
+// StartPurge claims a held erasure
+func (repository *memoryErasureRepository) StartPurge(ctx context.Context, erasureID int64) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.Status = models.ErasureStatusPurging
+	}, models.ErasureStatusHeld)
+}
+
+// MarkPurged stores the counts and certificate
+func (repository *memoryErasureRepository) MarkPurged(ctx context.Context, erasureID int64, purgedCounts map[string]int64, certificate *models.DeletionCertificate) error {
+	return repository.transition(erasureID, func(erasure *models.PatientErasure) {
+		erasure.Status = models.ErasureStatusPurged
+		erasure.PurgedCounts = purgedCounts
+		erasure.PurgedAt = &certificate.PurgedAt
+		repository.certificates[erasureID] = certificate
+	}, models.ErasureStatusPurging)
+}
+
+// GetCertificate returns the stored certificate
+func (repository *memoryErasureRepository) GetCertificate(ctx context.Context, erasureID int64) (*models.DeletionCertificate, error) {
+	certificate, exists := repository.certificates[erasureID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return certificate, nil
+}
+
+// HoldPatientRecords holds nothing; the saga tests use scripted participants
+func (repository *memoryErasureRepository) HoldPatientRecords(ctx context.Context, erasureID int64, patientID string) (int64, error) {
+	return 0, nil
+}
+
+// RestorePatientRecords has nothing to restore
+func (repository *memoryErasureRepository) RestorePatientRecords(ctx context.Context, erasureID int64) error {
+	return sql.ErrNoRows
+}
+
+// PurgePatientRecords purges nothing
+func (repository *memoryErasureRepository) PurgePatientRecords(ctx context.Context, erasureID int64) (int64, error) {
+	return 0, nil
+}
+
+// ClearCompartmentSnapshots clears nothing
+func (repository *memoryErasureRepository) ClearCompartmentSnapshots(ctx context.Context, patientID string) (int64, error) {
+	return 0, nil
+}
+
+// scriptedParticipant is a saga step that logs its calls and can be made to fail its hold
+type scriptedParticipant struct {
+	name      string
+	records   int64
+	holdError error
+	calls     *[]string
+}
+
+// Name returns the scripted name
+func (participant *scriptedParticipant) Name() string {
+	return participant.name
+}
+
+// Hold logs the call and returns the scripted count or error
+func (participant *scriptedParticipant) Hold(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	*participant.calls = append(*participant.calls, "hold:"+participant.name)
+	if participant.holdError != nil {
+		return 0, participant.holdError
+	}
+	return participant.records, nil
+}
+
+// Restore logs the call
+func (participant *scriptedParticipant) Restore(ctx context.Context, erasure *models.PatientErasure) error {
+	*participant.calls = append(*participant.calls, "restore:"+participant.name)
+	return nil
+}
+
+// Purge logs the call and returns the scripted count
+func (participant *scriptedParticipant) Purge(ctx context.Context, erasure *models.PatientErasure) (int64, error) {
+	*participant.calls = append(*participant.calls, "purge:"+participant.name)
+	return participant.records, nil
+}
+
+// newTestErasureService creates an erasure service over one stored patient and the given participants,
+// with a clock the test can move
+func newTestErasureService(participants ...ErasureParticipant) (*ErasureService, *memoryErasureRepository, *time.Time) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1"}
+	erasureRepository := newMemoryErasureRepository()
+
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	erasureService := NewErasureService(erasureRepository, patientRepository, ErasurePolicy{WaitingPeriod: 72 * time.Hour, PollInterval: time.Millisecond}, participants...)
+	erasureService.now = func() time.Time { return now }
+	return erasureService, erasureRepository, &now
+}
+
+// TestErasureService_Request verifies who may request an erasure and which requests are refused
+func TestErasureService_Request(t *testing.T) {
+	erasureService, _, _ := newTestErasureService()
+	holdRepository := newMemoryLegalHoldRepository()
+	erasureService.SetDeletionGuard(NewLegalHoldService(holdRepository, NewMockPatientRepository()))
+	ctx := complianceContext()
+
+	clerkContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:clerk", Roles: []string{"clerk"}})
+	_, forbiddenError := erasureService.Request(clerkContext, "patient-1", "GDPR article 17 request")
+	expectStatus(t, forbiddenError, http.StatusForbidden)
+
+	_, reasonError := erasureService.Request(ctx, "patient-1", " ")
+	expectStatus(t, reasonError, http.StatusBadRequest)
+
+	_, missingError := erasureService.Request(ctx, "missing", "GDPR article 17 request")
+	expectStatus(t, missingError, http.StatusNotFound)
+
+	holdRepository.holds["patient-1"] = &models.LegalHold{PatientID: "patient-1", Reason: "Case 42"}
+	_, heldError := erasureService.Request(ctx, "patient-1", "GDPR article 17 request")
+	expectStatus(t, heldError, http.StatusConflict)
+	delete(holdRepository.holds, "patient-1")
+
+	erasure, requestError := erasureService.Request(ctx, "patient-1", " GDPR article 17 request ")
+	if requestError != nil {
+		t.Fatalf("Expected no error, got %v", requestError)
+	}
+	if erasure.Status != models.ErasureStatusPending || erasure.Reason != "GDPR article 17 request" || erasure.RequestedBy != "api-key:compliance" {
+		t.Errorf("Expected a pending erasure requested by compliance, got %+v", erasure)
+	}
+
+	_, duplicateError := erasureService.Request(ctx, "patient-1", "Second request")
+	expectStatus(t, duplicateError, http.StatusConflict)
+
+	// A deleted patient's row is kept, so it can still be erased
+	patientRepository := NewMockPatientRepository()
+	patientRepository.getByIDError = deletedError(sql.ErrNoRows)
+	deletedPatientService := NewErasureService(newMemoryErasureRepository(), patientRepository, ErasurePolicy{WaitingPeriod: time.Hour})
+	if _, deletedRequestError := deletedPatientService.Request(ctx, "patient-2", "GDPR article 17 request"); deletedRequestError != nil {
+		t.Errorf("Expected a deleted patient to be erasable, got %v", deletedRequestError)
+	}
+}
+
+// TestErasureService_WithdrawsThenPurgesAfterWaitingPeriod verifies the saga holds every store, waits, then purges and certifies
+func TestErasureService_WithdrawsThenPurgesAfterWaitingPeriod(t *testing.T) {
+	calls := []string{}
+	erasureService, erasureRepository, now := newTestErasureService(
+		&scriptedParticipant{name: "observations", records: 12, calls: &calls},
+		&scriptedParticipant{name: "patient", records: 3, calls: &calls},
+	)
+	ctx := complianceContext()
+	requested, _ := erasureService.Request(ctx, "patient-1", "GDPR article 17 request")
+
+	if _, processError := erasureService.ProcessNext(ctx); processError != nil {
+		t.Fatalf("Expected no error, got %v", processError)
+	}
+	held, _ := erasureService.Get(ctx, requested.ID)
+	if held.Status != models.ErasureStatusHeld || held.HeldCounts["observations"] != 12 || held.HeldCounts["patient"] != 3 {
+		t.Fatalf("Expected held with per-store counts, got %+v", held)
+	}
+	if !held.PurgeAfter.Equal(now.Add(72 * time.Hour)) {
+		t.Errorf("Expected purge after the waiting period, got %v", held.PurgeAfter)
+	}
+
+	_, earlyError := erasureService.Certificate(ctx, requested.ID)
+	expectStatus(t, earlyError, http.StatusConflict)
+	if due, _ := erasureService.ProcessNext(ctx); due != nil {
+		t.Fatalf("Expected nothing due during the waiting period, got %+v", due)
+	}
+
+	*now = now.Add(73 * time.Hour)
+	if _, processError := erasureService.ProcessNext(ctx); processError != nil {
+		t.Fatalf("Expected no error, got %v", processError)
+	}
+
+	expectedCalls := []string{"hold:observations", "hold:patient", "purge:observations", "purge:patient"}
+	if !slices.Equal(calls, expectedCalls) {
+		t.Errorf("Expected calls %v, got %v", expectedCalls, calls)
+	}
+
+	purged, _ := erasureService.Get(ctx, requested.ID)
+	if purged.Status != models.ErasureStatusPurged || purged.PurgedCounts["observations"] != 12 {
+		t.Errorf("Expected purged with counts, got %+v", purged)
+	}
+
+	certificate, certificateError := erasureService.Certificate(ctx, requested.ID)
+	if certificateError != nil {
+		t.Fatalf("Expected a certificate, got %v", certificateError)
+	}
+	if certificate.PatientID != "patient-1" || certificate.PurgedRecords["patient"] != 3 || !certificate.PurgedAt.Equal(*now) {
+		t.Errorf("Unexpected certificate %+v", certificate)
+	}
+	digest, _ := CertificateDigest(certificate)
+	if certificate.Digest == "" || digest != certificate.Digest {
+		t.Errorf("Expected the digest to verify, got %q and recomputed %q", certificate.Digest, digest)
+	}
+
+	tampered := *certificate
+	tampered.PurgedRecords = map[string]int64{"patient": 0}
+	if tamperedDigest, _ := CertificateDigest(&tampered); tamperedDigest == certificate.Digest {
+		t.Error("Expected an altered certificate to fail verification")
+	}
+	if len(erasureRepository.certificates) != 1 {
+		t.Errorf("Expected one stored certificate, got %d", len(erasureRepository.certificates))
+	}
+}
+
+// TestErasureService_CompensatesFailedStep verifies a failed step restores every started step in reverse
+func TestErasureService_CompensatesFailedStep(t *testing.T) {
+	calls := []string{}
+	erasureService, _, _ := newTestErasureService(
+		&scriptedParticipant{name: "observations", records: 12, calls: &calls},
+		&scriptedParticipant{name: "patient", holdError: errors.New("connection reset"), calls: &calls},
+		&scriptedParticipant{name: "history", calls: &calls},
+	)
+	ctx := complianceContext()
+	requested, _ := erasureService.Request(ctx, "patient-1", "GDPR article 17 request")
+
+	if _, processError := erasureService.ProcessNext(ctx); processError != nil {
+		t.Fatalf("Expected compensation to succeed, got %v", processError)
+	}
+
+	expectedCalls := []string{"hold:observations", "hold:patient", "restore:patient", "restore:observations"}
+	if !slices.Equal(calls, expectedCalls) {
+		t.Errorf("Expected calls %v, got %v", expectedCalls, calls)
+	}
+
+	failed, _ := erasureService.Get(ctx, requested.ID)
+	if failed.Status != models.ErasureStatusFailed || failed.Error != "patient: connection reset" || failed.StepsStarted != 0 {
+		t.Errorf("Expected a failed erasure with its error, got %+v", failed)
+	}
+
+	// A failed erasure no longer blocks a new request
+	if _, requestError := erasureService.Request(ctx, "patient-1", "Retry"); requestError != nil {
+		t.Errorf("Expected a new request to be accepted, got %v", requestError)
+	}
+}
+
+// TestErasureService_CancelDuringWaitingPeriod verifies cancelling restores the records and is refused once purged
+func TestErasureService_CancelDuringWaitingPeriod(t *testing.T) {
+	calls := []string{}
+	erasureService, _, now := newTestErasureService(
+		&scriptedParticipant{name: "observations", records: 2, calls: &calls},
+		&scriptedParticipant{name: "patient", records: 1, calls: &calls},
+	)
+	ctx := complianceContext()
+	requested, _ := erasureService.Request(ctx, "patient-1", "GDPR article 17 request")
+	erasureService.ProcessNext(ctx)
+
+	clerkContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:clerk", Roles: []string{"clerk"}})
+	_, forbiddenError := erasureService.Cancel(clerkContext, requested.ID)
+	expectStatus(t, forbiddenError, http.StatusForbidden)
+
+	cancelling, cancelError := erasureService.Cancel(ctx, requested.ID)
+	if cancelError != nil || cancelling.Status != models.ErasureStatusCompensating {
+		t.Fatalf("Expected compensating, got %+v (%v)", cancelling, cancelError)
+	}
+
+	*now = now.Add(100 * time.Hour)
+	erasureService.ProcessNext(ctx)
+	cancelled, _ := erasureService.Get(ctx, requested.ID)
+	if cancelled.Status != models.ErasureStatusCancelled || cancelled.CancelledBy != "api-key:compliance" {
+		t.Errorf("Expected cancelled by compliance, got %+v", cancelled)
+	}
+
+	expectedCalls := []string{"hold:observations", "hold:patient", "restore:patient", "restore:observations"}
+	if !slices.Equal(calls, expectedCalls) {
+		t.Errorf("Expected calls %v, got %v", expectedCalls, calls)
+	}
+
+	_, againError := erasureService.Cancel(ctx, requested.ID)
+	expectStatus(t, againError, http.StatusConflict)
+	_, missingError := erasureService.Get(ctx, 99)
+	expectStatus(t, missingError, http.StatusNotFound)
+}
+
+// memoryObservationErasureStore moves observations between in-memory live and held sets
+type memoryObservationErasureStore struct {
+	live []*models.Observation
+	held map[int64][]*models.Observation
+}
+
+// HoldForErasure moves the patient's live observations to the erasure
+func (store *memoryObservationErasureStore) HoldForErasure(ctx context.Context, erasureID int64, patientID string) ([]*models.Observation, error) {
+	moved := []*models.Observation{}
+	remaining := []*models.Observation{}
+	for _, observation := range store.live {
+		if observation.PatientID == patientID {
+			moved = append(moved, observation)
+		} else {
+			remaining = append(remaining, observation)
+		}
+	}
+	store.live = remaining
+	store.held[erasureID] = append(store.held[erasureID], moved...)
+	return moved, nil
+}
+
+// RestoreErasure moves the erasure's observations back
+func (store *memoryObservationErasureStore) RestoreErasure(ctx context.Context, erasureID int64) ([]*models.Observation, error) {
+	moved := store.held[erasureID]
+	store.live = append(store.live, moved...)
+	delete(store.held, erasureID)
+	return moved, nil
+}
+
+// PurgeErasure drops the erasure's observations
+func (store *memoryObservationErasureStore) PurgeErasure(ctx context.Context, erasureID int64) (int64, error) {
+	purged := int64(len(store.held[erasureID]))
+	delete(store.held, erasureID)
+	return purged, nil
+}
+
+// TestObservationErasureParticipant_RecordsMoves verifies held and restored observations reach the change log, events, and ledger
+func TestObservationErasureParticipant_RecordsMoves(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	publisher := &recordingPublisher{}
+	ledger := &recordingLedger{adjustments: map[string]int64{}}
+	observationService := NewObservationService(NewMockObservationRepository())
+	observationService.SetChangeRepository(changeRepository)
+	observationService.SetEventPublisher(publisher)
+	observationService.SetLedgerRepository(ledger)
+
+	// obs-4 was deleted before the erasure, so its delete is already recorded
+	deletedAt := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+	store := &memoryObservationErasureStore{
+		live: []*models.Observation{
+			{ID: "obs-1", PatientID: "patient-1"},
+			{ID: "obs-2", PatientID: "patient-1"},
+			{ID: "obs-3", PatientID: "patient-2"},
+			{ID: "obs-4", PatientID: "patient-1", DeletedAt: &deletedAt},
+		},
+		held: map[int64][]*models.Observation{},
+	}
+	participant := NewObservationErasureParticipant(observationService, store)
+	erasure := &models.PatientErasure{ID: 1, PatientID: "patient-1"}
+	ctx := context.Background()
+
+	heldCount, holdError := participant.Hold(ctx, erasure)
+	if holdError != nil || heldCount != 3 || len(store.live) != 1 {
+		t.Fatalf("Expected three observations held, got %d (%v)", heldCount, holdError)
+	}
+	if repeatCount, _ := participant.Hold(ctx, erasure); repeatCount != 0 {
+		t.Errorf("Expected a repeated hold to move nothing, got %d", repeatCount)
+	}
+	if len(changeRepository.changes) != 2 || changeRepository.changes[0].Operation != models.ChangeOperationDelete || ledger.adjustments["patient-1"] != -2 {
+		t.Fatalf("Expected two recorded deletes and a ledger of -2, got %d changes and %d", len(changeRepository.changes), ledger.adjustments["patient-1"])
+	}
+
+	if restoreError := participant.Restore(ctx, erasure); restoreError != nil {
+		t.Fatalf("Expected no error, got %v", restoreError)
+	}
+	restoredChange := changeRepository.changes[2]
+	if restoredChange.Operation != models.ChangeOperationCreate || restoredChange.Version != 2 || restoredChange.CompartmentPatientID != "patient-1" || len(restoredChange.Snapshot) == 0 {
+		t.Errorf("Expected a create with a snapshot after the delete, got %+v", restoredChange)
+	}
+	if ledger.adjustments["patient-1"] != 0 || len(publisher.published) != 4 || len(store.live) != 4 {
+		t.Errorf("Expected the ledger balanced and four events, got %d and %d", ledger.adjustments["patient-1"], len(publisher.published))
+	}
+}
+
+// countingErasureRepository counts the patient holds and restores it is asked for
+type countingErasureRepository struct {
+	*memoryErasureRepository
+	holds    int
+	restores int
+}
+
+// HoldPatientRecords counts the hold and reports the patient row held
+func (repository *countingErasureRepository) HoldPatientRecords(ctx context.Context, erasureID int64, patientID string) (int64, error) {
+	repository.holds++
+	return 1, nil
+}
+
+// RestorePatientRecords counts the restore
+func (repository *countingErasureRepository) RestorePatientRecords(ctx context.Context, erasureID int64) error {
+	repository.restores++
+	return nil
+}
+
+// TestPatientErasureParticipant_DeletedPatient verifies a patient deleted before its erasure is held and put back
+// without recording a second delete or a create
+func TestPatientErasureParticipant_DeletedPatient(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.getByIDError = deletedError(sql.ErrNoRows)
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(patientRepository)
+	patientService.SetChangeRepository(changeRepository)
+	erasureRepository := &countingErasureRepository{memoryErasureRepository: newMemoryErasureRepository()}
+	participant := NewPatientErasureParticipant(patientService, erasureRepository)
+	erasure := &models.PatientErasure{ID: 1, PatientID: "patient-1"}
+	ctx := context.Background()
+
+	if heldRows, holdError := participant.Hold(ctx, erasure); holdError != nil || heldRows != 1 || erasureRepository.holds != 1 {
+		t.Fatalf("Expected the deleted patient held once, got %d rows and %d holds (%v)", heldRows, erasureRepository.holds, holdError)
+	}
+	if restoreError := participant.Restore(ctx, erasure); restoreError != nil || erasureRepository.restores == 0 {
+		t.Fatalf("Expected the deleted patient put back, got %d restores (%v)", erasureRepository.restores, restoreError)
+	}
+	if len(changeRepository.changes) != 0 {
+		t.Errorf("Expected no recorded changes, got %+v", changeRepository.changes)
+	}
+}
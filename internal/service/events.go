@@ -0,0 +1,31 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+)
+
+// publishWriteEvent emits a resource event when a publisher is configured
+// Inside a TransactionScope the event is held until the transaction commits
+func publishWriteEvent(ctx context.Context, eventPublisher events.Publisher, resourceType string, resourceID string, operation models.ChangeOperation, version int, resource interface{}) {
+	if eventPublisher == nil {
+		return
+	}
+
+	event := events.Event{
+		Type:         events.TypeForOperation(operation),
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Version:      version,
+		TenantID:     tenant.FromContext(ctx),
+		Resource:     resource,
+	}
+	if scope := transactionScopeFrom(ctx); scope != nil {
+		scope.deferEvent(eventPublisher, event)
+		return
+	}
+	eventPublisher.Publish(ctx, event)
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// recordingPublisher captures published events
+type recordingPublisher struct {
+	published []events.Event
+}
+
+// Publish records the event
+func (publisher *recordingPublisher) Publish(ctx context.Context, event events.Event) {
+	publisher.published = append(publisher.published, event)
+}
+
+// TestPatientService_PublishesEvents verifies each write emits an event with version and tenant
+func TestPatientService_PublishesEvents(t *testing.T) {
+	publisher := &recordingPublisher{}
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(&MockChangeRepository{})
+	patientService.SetEventPublisher(publisher)
+
+	ctx := tenant.WithTenant(context.Background(), "acme")
+	createdPatient, _ := patientService.CreatePatient(ctx, &fhir.Patient{})
+	patientService.UpdatePatient(ctx, *createdPatient.Id, &fhir.Patient{})
+	patientService.DeletePatient(ctx, *createdPatient.Id)
+
+	expectedTypes := []events.EventType{events.EventResourceCreated, events.EventResourceUpdated, events.EventResourceDeleted}
+	if len(publisher.published) != len(expectedTypes) {
+		t.Fatalf("Expected %d events, got %d", len(expectedTypes), len(publisher.published))
+	}
+	for index, event := range publisher.published {
+		if event.Type != expectedTypes[index] || event.ResourceType != "Patient" || event.TenantID != "acme" {
+			t.Errorf("Unexpected event %d: %+v", index, event)
+		}
+		if event.Version != index+1 {
+			t.Errorf("Expected version %d, got %d", index+1, event.Version)
+		}
+	}
+	if publisher.published[0].Resource == nil || publisher.published[2].Resource != nil {
+		t.Error("Expected resource on create and none on delete")
+	}
+}
+
+// TestObservationService_PublishesEvents verifies observation writes emit events
+func TestObservationService_PublishesEvents(t *testing.T) {
+	publisher := &recordingPublisher{}
+	observationService := NewObservationService(NewMockObservationRepository())
+	observationService.SetEventPublisher(publisher)
+
+	observationService.CreateObservation(context.Background(), &fhir.Observation{})
+
+	if len(publisher.published) != 1 || publisher.published[0].ResourceType != "Observation" {
+		t.Fatalf("Expected one Observation event, got %+v", publisher.published)
+	}
+	if publisher.published[0].TenantID != tenant.DefaultTenantID || publisher.published[0].Version != 0 {
+		t.Errorf("Expected default tenant and no version without a change log, got %+v", publisher.published[0])
+	}
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/healthexport"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// healthExportLimit caps the observations read for one export; the app requests the rest with a later start
+const healthExportLimit = 1000
+
+// ObservationSearcher searches observations; ObservationService implements it
+type ObservationSearcher interface {
+	SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error)
+}
+
+// HealthExportService exports a patient's vital signs in Apple HealthKit and Google Fit formats for the companion app
+type HealthExportService struct {
+	patientService      *PatientService
+	observationSearcher ObservationSearcher
+}
+
+// NewHealthExportService creates a new health export service instance
+func NewHealthExportService(patientService *PatientService, observationSearcher ObservationSearcher) *HealthExportService {
+	return &HealthExportService{
+		patientService:      patientService,
+		observationSearcher: observationSearcher,
+	}
+}
+
+// Export converts the patient's observations effective at or after since (all of them when nil), oldest first
+// The export is truncated after healthExportLimit observations
+// The patient lookup error is returned unchanged when the patient cannot be read; a failed observation
+// search is returned as an internal AppError
+func (service *HealthExportService) Export(ctx context.Context, patientID string, format healthexport.Format, since *time.Time, exposeObservation func(*fhir.Observation)) (*healthexport.Export, error) {
+	if _, getError := service.patientService.GetPatientByID(ctx, patientID); getError != nil {
+		return nil, getError
+	}
+
+	observations, searchError := service.observationSearcher.SearchObservations(ctx, &models.ObservationSearchParams{
+		PatientID:       patientID,
+		DateGreaterThan: since,
+		SortBy:          "effective_date",
+		SortOrder:       "asc",
+		Limit:           healthExportLimit,
+	})
+	if searchError != nil {
+		return nil, apperrors.Internal("Failed to read observations to export", searchError)
+	}
+	for _, observation := range observations {
+		exposeObservation(observation)
+	}
+
+	export := healthexport.Build(format, observations)
+	export.Truncated = len(observations) == healthExportLimit
+	return export, nil
+}
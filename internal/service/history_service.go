@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// HistoryService answers point-in-time reads from the snapshots kept in the change log
+// Resources written before snapshots were recorded have no readable history
+type HistoryService struct {
+	snapshotRepository repository.SnapshotRepository
+}
+
+// NewHistoryService creates a new history service instance
+func NewHistoryService(snapshotRepository repository.SnapshotRepository) *HistoryService {
+	return &HistoryService{
+		snapshotRepository: snapshotRepository,
+	}
+}
+
+// ReadAsOf returns the resource as it was at the given time: a *fhir.Patient or *fhir.Observation
+// ErrResourceNotFound means it did not exist yet; ErrResourceDeleted means it had been deleted
+func (service *HistoryService) ReadAsOf(ctx context.Context, resourceType string, resourceID string, at time.Time) (interface{}, error) {
+	change, findError := service.snapshotRepository.FindAsOf(ctx, resourceType, resourceID, at)
+	return service.resourceFromChange(change, findError)
+}
+
+// ReadVersion returns the given version of the resource: a *fhir.Patient or *fhir.Observation
+func (service *HistoryService) ReadVersion(ctx context.Context, resourceType string, resourceID string, version int) (interface{}, error) {
+	change, findError := service.snapshotRepository.FindVersion(ctx, resourceType, resourceID, version)
+	return service.resourceFromChange(change, findError)
+}
+
+// PatientCompartmentAsOf returns the patient and the observations in the patient's compartment as they were at the given time
+// The patient comes first; the call fails like ReadAsOf when the patient did not exist at that time
+func (service *HistoryService) PatientCompartmentAsOf(ctx context.Context, patientID string, at time.Time) ([]interface{}, error) {
+	patient, readError := service.ReadAsOf(ctx, "Patient", patientID, at)
+	if readError != nil {
+		return nil, readError
+	}
+
+	changes, listError := service.snapshotRepository.ListCompartmentAsOf(ctx, patientID, at)
+	if listError != nil {
+		return nil, listError
+	}
+
+	resources := []interface{}{patient}
+	for _, change := range changes {
+		if change.ResourceType == "Patient" {
+			continue
+		}
+		resource, decodeError := decodeSnapshot(change)
+		if decodeError != nil {
+			return nil, decodeError
+		}
+		resources = append(resources, resource)
+	}
+
+	return resources, nil
+}
+
+// HistoryVersionLimit is the most versions a resource's history lists
+const HistoryVersionLimit = 100
+
+// HistoryVersion is one version in a resource's history
+type HistoryVersion struct {
+	// Change is the change log entry that produced the version
+	Change *models.ResourceChange
+
+	// Resource is the resource as of the version: a *fhir.Patient or *fhir.Observation, or nil for deletes and
+	// versions recorded before snapshots were kept
+	Resource interface{}
+}
+
+// ListVersions returns the resource's most recent versions, deletes included, newest first
+// ErrResourceNotFound means no change to the resource was ever recorded
+func (service *HistoryService) ListVersions(ctx context.Context, resourceType string, resourceID string) ([]HistoryVersion, error) {
+	changes, listError := service.snapshotRepository.ListVersions(ctx, resourceType, resourceID, HistoryVersionLimit)
+	if listError != nil {
+		return nil, listError
+	}
+	if len(changes) == 0 {
+		return nil, ErrResourceNotFound
+	}
+
+	versions := make([]HistoryVersion, 0, len(changes))
+	for _, change := range changes {
+		version := HistoryVersion{Change: change}
+		if change.Operation != models.ChangeOperationDelete && len(change.Snapshot) > 0 {
+			resource, decodeError := decodeSnapshot(change)
+			if decodeError != nil {
+				return nil, decodeError
+			}
+			version.Resource = resource
+		}
+		versions = append(versions, version)
+	}
+
+	return versions, nil
+}
+
+// resourceFromChange maps a change lookup to the resource it recorded
+func (service *HistoryService) resourceFromChange(change *models.ResourceChange, findError error) (interface{}, error) {
+	if errors.Is(findError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if findError != nil {
+		return nil, findError
+	}
+	if change.Operation == models.ChangeOperationDelete {
+		return nil, ErrResourceDeleted
+	}
+	if len(change.Snapshot) == 0 {
+		// Recorded before snapshots were kept
+		return nil, ErrResourceNotFound
+	}
+
+	return decodeSnapshot(change)
+}
+
+// decodeSnapshot decodes a change's snapshot and stamps meta.versionId and meta.lastUpdated from the change
+func decodeSnapshot(change *models.ResourceChange) (interface{}, error) {
+	versionID := strconv.Itoa(change.Version)
+	lastUpdated := change.ChangedAt.UTC().Format(time.RFC3339)
+
+	switch change.ResourceType {
+	case "Patient":
+		var patient fhir.Patient
+		if decodeError := json.Unmarshal(change.Snapshot, &patient); decodeError != nil {
+			return nil, fmt.Errorf("failed to decode Patient/%s version %d: %w", change.ResourceID, change.Version, decodeError)
+		}
+		patient.Meta = stampMeta(patient.Meta, versionID, lastUpdated)
+		return &patient, nil
+	case "Observation":
+		var observation fhir.Observation
+		if decodeError := json.Unmarshal(change.Snapshot, &observation); decodeError != nil {
+			return nil, fmt.Errorf("failed to decode Observation/%s version %d: %w", change.ResourceID, change.Version, decodeError)
+		}
+		observation.Meta = stampMeta(observation.Meta, versionID, lastUpdated)
+		return &observation, nil
+	default:
+		return nil, fmt.Errorf("no snapshot decoder for resource type %s", change.ResourceType)
+	}
+}
+
+// stampMeta sets the version and timestamp on a snapshot's meta, keeping any tags it carried
+func stampMeta(meta *fhir.Meta, versionID string, lastUpdated string) *fhir.Meta {
+	if meta == nil {
+		meta = &fhir.Meta{}
+	}
+	meta.VersionId = &versionID
+	meta.LastUpdated = &lastUpdated
+	return meta
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// FindVersion returns the change that produced the given version
+func (mock *MockChangeRepository) FindVersion(ctx context.Context, resourceType string, resourceID string, version int) (*models.ResourceChange, error) {
+	for _, change := range mock.changes {
+		if change.ResourceType == resourceType && change.ResourceID == resourceID && change.Version == version {
+			return change, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// FindAsOf returns the latest change recorded at or before the given time
+func (mock *MockChangeRepository) FindAsOf(ctx context.Context, resourceType string, resourceID string, at time.Time) (*models.ResourceChange, error) {
+	var latest *models.ResourceChange
+	for _, change := range mock.changes {
+		if change.ResourceType == resourceType && change.ResourceID == resourceID && !change.ChangedAt.After(at) {
+			latest = change
+		}
+	}
+	if latest == nil {
+		return nil, sql.ErrNoRows
+	}
+	return latest, nil
+}
+
+// ListVersions returns up to limit of the resource's changes, newest first
+func (mock *MockChangeRepository) ListVersions(ctx context.Context, resourceType string, resourceID string, limit int) ([]*models.ResourceChange, error) {
+	versions := []*models.ResourceChange{}
+	for index := len(mock.changes) - 1; index >= 0 && len(versions) < limit; index-- {
+		change := mock.changes[index]
+		if change.ResourceType == resourceType && change.ResourceID == resourceID {
+			versions = append(versions, change)
+		}
+	}
+	return versions, nil
+}
+
+// ListCompartmentAsOf returns the latest live change per resource in the patient's compartment at the given time
+func (mock *MockChangeRepository) ListCompartmentAsOf(ctx context.Context, patientID string, at time.Time) ([]*models.ResourceChange, error) {
+	latestByResource := map[string]*models.ResourceChange{}
+	order := []string{}
+	for _, change := range mock.changes {
+		if change.ChangedAt.After(at) {
+			continue
+		}
+		key := change.ResourceType + "/" + change.ResourceID
+		if _, seen := latestByResource[key]; !seen {
+			order = append(order, key)
+		}
+		latestByResource[key] = change
+	}
+
+	result := []*models.ResourceChange{}
+	for _, key := range order {
+		change := latestByResource[key]
+		if change.CompartmentPatientID == patientID && change.Operation != models.ChangeOperationDelete {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// TestHistoryService_ReadAsOf verifies reads before creation, between versions, and after deletion
+func TestHistoryService_ReadAsOf(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(changeRepository)
+	historyService := NewHistoryService(changeRepository)
+	ctx := context.Background()
+
+	family := "Smith"
+	createdPatient, _ := patientService.CreatePatient(ctx, &fhir.Patient{Name: []fhir.HumanName{{Family: &family}}})
+	patientID := *createdPatient.Id
+	renamedFamily := "Jones"
+	patientService.UpdatePatient(ctx, patientID, &fhir.Patient{Name: []fhir.HumanName{{Family: &renamedFamily}}})
+	patientService.DeletePatient(ctx, patientID)
+
+	if _, readError := historyService.ReadAsOf(ctx, "Patient", patientID, mockChangeEpoch); !errors.Is(readError, ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound before creation, got %v", readError)
+	}
+
+	resource, readError := historyService.ReadAsOf(ctx, "Patient", patientID, mockChangeEpoch.Add(90*time.Second))
+	if readError != nil {
+		t.Fatalf("Expected no error, got %v", readError)
+	}
+	firstVersion := resource.(*fhir.Patient)
+	if *firstVersion.Name[0].Family != "Smith" || *firstVersion.Meta.VersionId != "1" {
+		t.Errorf("Expected version 1 named Smith, got %s version %s", *firstVersion.Name[0].Family, *firstVersion.Meta.VersionId)
+	}
+	if *firstVersion.Meta.LastUpdated != "2024-05-01T12:01:00Z" {
+		t.Errorf("Expected lastUpdated from the change, got %s", *firstVersion.Meta.LastUpdated)
+	}
+
+	resource, _ = historyService.ReadVersion(ctx, "Patient", patientID, 2)
+	if secondVersion := resource.(*fhir.Patient); *secondVersion.Name[0].Family != "Jones" {
+		t.Errorf("Expected version 2 named Jones, got %s", *secondVersion.Name[0].Family)
+	}
+
+	if _, readError := historyService.ReadAsOf(ctx, "Patient", patientID, mockChangeEpoch.Add(time.Hour)); !errors.Is(readError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted after deletion, got %v", readError)
+	}
+}
+
+// TestHistoryService_ListVersions verifies versions are listed newest first with deletes as tombstones, and that
+// a resource without recorded changes has no versions
+func TestHistoryService_ListVersions(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(changeRepository)
+	historyService := NewHistoryService(changeRepository)
+	ctx := context.Background()
+
+	family := "Smith"
+	createdPatient, _ := patientService.CreatePatient(ctx, &fhir.Patient{Name: []fhir.HumanName{{Family: &family}}})
+	patientID := *createdPatient.Id
+	patientService.DeletePatient(ctx, patientID)
+
+	versions, listError := historyService.ListVersions(ctx, "Patient", patientID)
+	if listError != nil {
+		t.Fatalf("Expected no error, got %v", listError)
+	}
+	if len(versions) != 2 || versions[0].Change.Operation != models.ChangeOperationDelete || versions[0].Resource != nil {
+		t.Fatalf("Expected the delete first and without a resource, got %+v", versions)
+	}
+	if createdVersion := versions[1].Resource.(*fhir.Patient); *createdVersion.Name[0].Family != "Smith" || *createdVersion.Meta.VersionId != "1" {
+		t.Errorf("Expected version 1 named Smith, got %+v", createdVersion)
+	}
+
+	if _, listError := historyService.ListVersions(ctx, "Patient", "never-stored"); !errors.Is(listError, ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound without versions, got %v", listError)
+	}
+}
+
+// TestHistoryService_PatientCompartmentAsOf verifies observations join the compartment when they are recorded
+func TestHistoryService_PatientCompartmentAsOf(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(changeRepository)
+	observationService := NewObservationService(NewMockObservationRepository())
+	observationService.SetChangeRepository(changeRepository)
+	historyService := NewHistoryService(changeRepository)
+	ctx := context.Background()
+
+	createdPatient, _ := patientService.CreatePatient(ctx, &fhir.Patient{})
+	patientID := *createdPatient.Id
+	subject := "Patient/" + patientID
+	code := "8480-6"
+	observationService.CreateObservation(ctx, &fhir.Observation{
+		Status:  fhir.ObservationStatusFinal,
+		Code:    fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &code}}},
+		Subject: &fhir.Reference{Reference: &subject},
+	})
+
+	beforeObservation, _ := historyService.PatientCompartmentAsOf(ctx, patientID, mockChangeEpoch.Add(90*time.Second))
+	if len(beforeObservation) != 1 {
+		t.Errorf("Expected only the patient before the observation was recorded, got %d resources", len(beforeObservation))
+	}
+
+	afterObservation, compartmentError := historyService.PatientCompartmentAsOf(ctx, patientID, mockChangeEpoch.Add(150*time.Second))
+	if compartmentError != nil {
+		t.Fatalf("Expected no error, got %v", compartmentError)
+	}
+	if len(afterObservation) != 2 {
+		t.Fatalf("Expected patient and observation, got %d resources", len(afterObservation))
+	}
+	if _, isPatient := afterObservation[0].(*fhir.Patient); !isPatient {
+		t.Errorf("Expected the patient first, got %T", afterObservation[0])
+	}
+	if _, isObservation := afterObservation[1].(*fhir.Observation); !isObservation {
+		t.Errorf("Expected the observation second, got %T", afterObservation[1])
+	}
+}
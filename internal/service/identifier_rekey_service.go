@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// rekeyMappingHeader is the required first line of a mapping file
+var rekeyMappingHeader = []string{"old_system", "old_value", "new_system", "new_value"}
+
+// RekeyPolicy limits how fast re-keying jobs write to the patients table
+type RekeyPolicy struct {
+	// BatchSize is how many mappings, or rollbacks, are applied in one transaction
+	BatchSize int
+
+	// BatchInterval is the pause between batches
+	BatchInterval time.Duration
+}
+
+// DefaultRekeyPolicy applies 100 mappings per second
+func DefaultRekeyPolicy() RekeyPolicy {
+	return RekeyPolicy{
+		BatchSize:     100,
+		BatchInterval: time.Second,
+	}
+}
+
+// IdentifierRekeyService rewrites patient identifiers in bulk from a mapping file, for example when a hospital changes MRN systems
+// Jobs run in the background in rate-limited batches; every rewrite is audited and can be rolled back
+type IdentifierRekeyService struct {
+	rekeyRepository repository.IdentifierRekeyRepository
+	patientService  *PatientService
+	policy          RekeyPolicy
+}
+
+// NewIdentifierRekeyService creates a new identifier re-keying service instance
+// Rewritten patients are recorded through patientService so they get a new version and an update event
+func NewIdentifierRekeyService(rekeyRepository repository.IdentifierRekeyRepository, patientService *PatientService, policy RekeyPolicy) *IdentifierRekeyService {
+	return &IdentifierRekeyService{
+		rekeyRepository: rekeyRepository,
+		patientService:  patientService,
+		policy:          policy,
+	}
+}
+
+// StartJob validates the mapping file and queues a job to apply it; only the identity-admin role may do this
+func (service *IdentifierRekeyService) StartJob(ctx context.Context, mappingFile io.Reader, reason string) (*models.RekeyJob, error) {
+	principal, roleError := requireIdentityAdmin(ctx)
+	if roleError != nil {
+		return nil, roleError
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, apperrors.InvalidInput("reason", "is required")
+	}
+
+	mappings, parseError := ParseIdentifierMappings(mappingFile)
+	if parseError != nil {
+		return nil, parseError
+	}
+
+	job, createError := service.rekeyRepository.CreateJob(ctx, &models.RekeyJob{
+		Reason: strings.TrimSpace(reason),
+		Actor:  principal.ID,
+	}, mappings)
+	if createError != nil {
+		return nil, createError
+	}
+
+	log.Info().
+		Int64("job_id", job.ID).
+		Int("mappings", len(mappings)).
+		Str("actor", principal.ID).
+		Str("reason", job.Reason).
+		Msg("Identifier re-key job started")
+	return job, nil
+}
+
+// GetJob returns the job with its progress counts
+func (service *IdentifierRekeyService) GetJob(ctx context.Context, jobID int64) (*models.RekeyJob, error) {
+	job, getError := service.rekeyRepository.GetJob(ctx, jobID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, apperrors.NotFound("RekeyJob", fmt.Sprint(jobID))
+	}
+	return job, getError
+}
+
+// ListRecords returns the job's audit history, oldest first
+func (service *IdentifierRekeyService) ListRecords(ctx context.Context, jobID int64) (*models.RekeyJob, []*models.RekeyRecord, error) {
+	job, getError := service.GetJob(ctx, jobID)
+	if getError != nil {
+		return nil, nil, getError
+	}
+
+	records, listError := service.rekeyRepository.ListRecords(ctx, jobID)
+	if listError != nil {
+		return nil, nil, listError
+	}
+	return job, records, nil
+}
+
+// Rollback stops the job and queues restoring the old identifiers it wrote; only the identity-admin role may do this
+func (service *IdentifierRekeyService) Rollback(ctx context.Context, jobID int64) (*models.RekeyJob, error) {
+	principal, roleError := requireIdentityAdmin(ctx)
+	if roleError != nil {
+		return nil, roleError
+	}
+
+	job, getError := service.GetJob(ctx, jobID)
+	if getError != nil {
+		return nil, getError
+	}
+	if job.Status != models.RekeyJobStatusRunning && job.Status != models.RekeyJobStatusCompleted {
+		return nil, apperrors.Conflict("RekeyJob", "job is already "+string(job.Status))
+	}
+
+	rollbackError := service.rekeyRepository.StartRollback(ctx, jobID, principal.ID)
+	if errors.Is(rollbackError, sql.ErrNoRows) {
+		return nil, apperrors.Conflict("RekeyJob", "job changed state; try again")
+	}
+	if rollbackError != nil {
+		return nil, rollbackError
+	}
+
+	log.Info().
+		Int64("job_id", jobID).
+		Str("actor", principal.ID).
+		Msg("Identifier re-key rollback started")
+	return service.GetJob(ctx, jobID)
+}
+
+// Run processes active jobs one batch at a time, pausing BatchInterval between batches, until ctx is cancelled
+// Jobs interrupted by a restart continue where they stopped
+func (service *IdentifierRekeyService) Run(ctx context.Context) {
+	for {
+		if _, processError := service.ProcessNextBatch(ctx); processError != nil && ctx.Err() == nil {
+			log.Error().Err(processError).Msg("Failed to process identifier re-key batch")
+		}
+
+		timer := time.NewTimer(service.policy.BatchInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// ProcessNextBatch applies or rolls back one batch of the oldest active job
+// It returns the audit entries written, or none when no job is active
+func (service *IdentifierRekeyService) ProcessNextBatch(ctx context.Context) ([]*models.RekeyRecord, error) {
+	job, findError := service.rekeyRepository.NextActiveJob(ctx)
+	if errors.Is(findError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if findError != nil {
+		return nil, findError
+	}
+
+	var records []*models.RekeyRecord
+	var batchError error
+	if job.Status == models.RekeyJobStatusRollingBack {
+		records, batchError = service.rekeyRepository.RollbackBatch(ctx, job.ID, service.policy.BatchSize)
+	} else {
+		records, batchError = service.rekeyRepository.ApplyBatch(ctx, job.ID, service.policy.BatchSize)
+	}
+	if batchError != nil {
+		return nil, batchError
+	}
+
+	actionCounts := map[models.RekeyAction]int{}
+	for _, record := range records {
+		actionCounts[record.Action]++
+		if record.Action != models.RekeyActionRekeyed && record.Action != models.RekeyActionRolledBack {
+			continue
+		}
+		// The identifier is already committed; a failure here only leaves the change log and events behind
+		if recordError := service.patientService.RecordExternalUpdate(ctx, record.PatientID); recordError != nil {
+			log.Error().Err(recordError).Str("patient_id", record.PatientID).Msg("Failed to record re-keyed patient")
+		}
+	}
+
+	if len(records) > 0 {
+		log.Info().
+			Int64("job_id", job.ID).
+			Str("status", string(job.Status)).
+			Int("rekeyed", actionCounts[models.RekeyActionRekeyed]).
+			Int("skipped", actionCounts[models.RekeyActionSkipped]).
+			Int("rolled_back", actionCounts[models.RekeyActionRolledBack]).
+			Int("rollback_skipped", actionCounts[models.RekeyActionRollbackSkipped]).
+			Msg("Identifier re-key batch applied")
+	}
+	return records, nil
+}
+
+// ParseIdentifierMappings reads a CSV mapping file with the header old_system,old_value,new_system,new_value
+// Every old identifier and every new identifier may appear only once, mappings may not chain, and a mapping must change the identifier
+func ParseIdentifierMappings(mappingFile io.Reader) ([]models.IdentifierMapping, error) {
+	reader := csv.NewReader(mappingFile)
+	reader.FieldsPerRecord = len(rekeyMappingHeader)
+	reader.TrimLeadingSpace = true
+
+	header, headerError := reader.Read()
+	if errors.Is(headerError, io.EOF) {
+		return nil, apperrors.InvalidInput("mapping", "file is empty")
+	}
+	if headerError != nil {
+		return nil, apperrors.InvalidInput("mapping", headerError.Error())
+	}
+	for index, column := range rekeyMappingHeader {
+		if !strings.EqualFold(strings.TrimSpace(header[index]), column) {
+			return nil, apperrors.InvalidInput("mapping", "header must be "+strings.Join(rekeyMappingHeader, ","))
+		}
+	}
+
+	mappings := []models.IdentifierMapping{}
+	seenOld := map[string]int{}
+	seenNew := map[string]int{}
+	for {
+		fields, readError := reader.Read()
+		if errors.Is(readError, io.EOF) {
+			break
+		}
+		if readError != nil {
+			return nil, apperrors.InvalidInput("mapping", readError.Error())
+		}
+
+		line, _ := reader.FieldPos(0)
+		mapping := models.IdentifierMapping{
+			Line:      line,
+			OldSystem: strings.TrimSpace(fields[0]),
+			OldValue:  strings.TrimSpace(fields[1]),
+			NewSystem: strings.TrimSpace(fields[2]),
+			NewValue:  strings.TrimSpace(fields[3]),
+		}
+		if mapping.OldSystem == "" || mapping.OldValue == "" || mapping.NewSystem == "" || mapping.NewValue == "" {
+			return nil, apperrors.InvalidInput("mapping", fmt.Sprintf("line %d: every column is required", line))
+		}
+
+		oldKey := mapping.OldSystem + "|" + mapping.OldValue
+		newKey := mapping.NewSystem + "|" + mapping.NewValue
+		if oldKey == newKey {
+			return nil, apperrors.InvalidInput("mapping", fmt.Sprintf("line %d: the new identifier is the same as the old one", line))
+		}
+		if firstLine, duplicate := seenOld[oldKey]; duplicate {
+			return nil, apperrors.InvalidInput("mapping", fmt.Sprintf("line %d: old identifier %s is already mapped on line %d", line, oldKey, firstLine))
+		}
+		if firstLine, duplicate := seenNew[newKey]; duplicate {
+			return nil, apperrors.InvalidInput("mapping", fmt.Sprintf("line %d: new identifier %s is already used on line %d", line, newKey, firstLine))
+		}
+		if firstLine, chained := seenNew[oldKey]; chained {
+			return nil, apperrors.InvalidInput("mapping", fmt.Sprintf("line %d: old identifier %s is a new identifier on line %d; chained mappings are not supported", line, oldKey, firstLine))
+		}
+		if firstLine, chained := seenOld[newKey]; chained {
+			return nil, apperrors.InvalidInput("mapping", fmt.Sprintf("line %d: new identifier %s is an old identifier on line %d; chained mappings are not supported", line, newKey, firstLine))
+		}
+		seenOld[oldKey] = line
+		seenNew[newKey] = line
+
+		mappings = append(mappings, mapping)
+	}
+
+	if len(mappings) == 0 {
+		return nil, apperrors.InvalidInput("mapping", "at least one mapping is required")
+	}
+	return mappings, nil
+}
+
+// requireIdentityAdmin returns the request's principal, or a 403 when it lacks the identity-admin role
+func requireIdentityAdmin(ctx context.Context) (auth.Principal, error) {
+	principal := auth.FromContext(ctx)
+	if !principal.HasRole(auth.RoleIdentityAdmin) {
+		return principal, apperrors.Forbidden("Identifiers can only be re-keyed by the identity-admin role")
+	}
+	return principal, nil
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// memoryRekeyRepository implements IdentifierRekeyRepository in memory over a mock patient store
+type memoryRekeyRepository struct {
+	patients  map[string]*models.Patient
+	jobs      map[int64]*models.RekeyJob
+	mappings  map[int64][]models.IdentifierMapping
+	processed map[int64]int
+	records   []*models.RekeyRecord
+}
+
+// newMemoryRekeyRepository creates an empty job store that rewrites the given patients
+func newMemoryRekeyRepository(patients map[string]*models.Patient) *memoryRekeyRepository {
+	return &memoryRekeyRepository{
+		patients:  patients,
+		jobs:      make(map[int64]*models.RekeyJob),
+		mappings:  make(map[int64][]models.IdentifierMapping),
+		processed: make(map[int64]int),
+	}
+}
+
+// CreateJob stores a running job
+func (repository *memoryRekeyRepository) CreateJob(ctx context.Context, job *models.RekeyJob, mappings []models.IdentifierMapping) (*models.RekeyJob, error) {
+	job.ID = int64(len(repository.jobs) + 1)
+	job.Status = models.RekeyJobStatusRunning
+	repository.jobs[job.ID] = job
+	repository.mappings[job.ID] = mappings
+	return repository.GetJob(ctx, job.ID)
+}
+
+// GetJob returns the job with counts derived from its records
+func (repository *memoryRekeyRepository) GetJob(ctx context.Context, jobID int64) (*models.RekeyJob, error) {
+	job, exists := repository.jobs[jobID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	counted := *job
+	counted.Total = len(repository.mappings[jobID])
+	counted.Processed = repository.processed[jobID]
+	counted.Rekeyed, counted.Skipped, counted.RolledBack = 0, 0, 0
+	for _, record := range repository.records {
+		if record.JobID != jobID {
+			continue
+		}
+		switch record.Action {
+		case models.RekeyActionRekeyed:
+			counted.Rekeyed++
+		case models.RekeyActionSkipped:
+			counted.Skipped++
+		case models.RekeyActionRolledBack:
+			counted.RolledBack++
+		}
+	}
+	return &counted, nil
+}
+
+// NextActiveJob returns the lowest-numbered active job
+func (repository *memoryRekeyRepository) NextActiveJob(ctx context.Context) (*models.RekeyJob, error) {
+	for jobID := int64(1); jobID <= int64(len(repository.jobs)); jobID++ {
+		status := repository.jobs[jobID].Status
+		if status == models.RekeyJobStatusRunning || status == models.RekeyJobStatusRollingBack {
+			return repository.GetJob(ctx, jobID)
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// StartRollback switches a running or completed job to rolling back
+func (repository *memoryRekeyRepository) StartRollback(ctx context.Context, jobID int64, actor string) error {
+	job, exists := repository.jobs[jobID]
+	if !exists || (job.Status != models.RekeyJobStatusRunning && job.Status != models.RekeyJobStatusCompleted) {
+		return sql.ErrNoRows
+	}
+	job.Status = models.RekeyJobStatusRollingBack
+	job.RollbackActor = actor
+	return nil
+}
+
+// ApplyBatch applies the next mappings with the same skip rules as the PostgreSQL repository
+func (repository *memoryRekeyRepository) ApplyBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	job := repository.jobs[jobID]
+	remaining := repository.mappings[jobID][repository.processed[jobID]:]
+	if len(remaining) > limit {
+		remaining = remaining[:limit]
+	}
+
+	records := []*models.RekeyRecord{}
+	for _, mapping := range remaining {
+		record := &models.RekeyRecord{JobID: jobID, Action: models.RekeyActionSkipped, OldSystem: mapping.OldSystem, OldValue: mapping.OldValue,
+			NewSystem: mapping.NewSystem, NewValue: mapping.NewValue, Actor: job.Actor}
+		matches := repository.patientsWith(mapping.OldSystem, mapping.OldValue)
+		switch {
+		case len(matches) != 1:
+			record.Detail = "no single patient has the old identifier"
+		case len(repository.patientsWith(mapping.NewSystem, mapping.NewValue)) > 0:
+			record.PatientID = matches[0].ID
+			record.Detail = "the new identifier already belongs to another patient"
+		default:
+			record.PatientID = matches[0].ID
+			matches[0].IdentifierSystem, matches[0].IdentifierValue = mapping.NewSystem, mapping.NewValue
+			record.Action = models.RekeyActionRekeyed
+		}
+		records = append(records, repository.append(record))
+		repository.processed[jobID]++
+	}
+
+	if len(remaining) < limit {
+		job.Status = models.RekeyJobStatusCompleted
+	}
+	return records, nil
+}
+
+// RollbackBatch reverts the newest rewrites whose identifier is unchanged
+func (repository *memoryRekeyRepository) RollbackBatch(ctx context.Context, jobID int64, limit int) ([]*models.RekeyRecord, error) {
+	job := repository.jobs[jobID]
+	reverted := map[int64]bool{}
+	for _, record := range repository.records {
+		reverted[record.RevertsID] = true
+	}
+
+	rewrites := []*models.RekeyRecord{}
+	for index := len(repository.records) - 1; index >= 0 && len(rewrites) < limit; index-- {
+		record := repository.records[index]
+		if record.JobID == jobID && record.Action == models.RekeyActionRekeyed && !reverted[record.ID] {
+			rewrites = append(rewrites, record)
+		}
+	}
+
+	records := []*models.RekeyRecord{}
+	for _, rewrite := range rewrites {
+		record := *rewrite
+		record.RevertsID = rewrite.ID
+		record.Actor = job.RollbackActor
+		record.Action = models.RekeyActionRollbackSkipped
+		patient := repository.patients[rewrite.PatientID]
+		if patient != nil && patient.IdentifierSystem == rewrite.NewSystem && patient.IdentifierValue == rewrite.NewValue {
+			patient.IdentifierSystem, patient.IdentifierValue = rewrite.OldSystem, rewrite.OldValue
+			record.Action = models.RekeyActionRolledBack
+		}
+		records = append(records, repository.append(&record))
+	}
+
+	if len(rewrites) < limit {
+		job.Status = models.RekeyJobStatusRolledBack
+	}
+	return records, nil
+}
+
+// ListRecords returns the job's records
+func (repository *memoryRekeyRepository) ListRecords(ctx context.Context, jobID int64) ([]*models.RekeyRecord, error) {
+	records := []*models.RekeyRecord{}
+	for _, record := range repository.records {
+		if record.JobID == jobID {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// patientsWith returns the patients holding an identifier
+func (repository *memoryRekeyRepository) patientsWith(system string, value string) []*models.Patient {
+	matches := []*models.Patient{}
+	for _, patient := range repository.patients {
+		if patient.IdentifierSystem == system && patient.IdentifierValue == value {
+			matches = append(matches, patient)
+		}
+	}
+	return matches
+}
+
+// append stores a record with the next ID
+func (repository *memoryRekeyRepository) append(record *models.RekeyRecord) *models.RekeyRecord {
+	record.ID = int64(len(repository.records) + 1)
+	record.OccurredAt = time.Now()
+	repository.records = append(repository.records, record)
+	return record
+}
+
+// identityAdminContext returns a context for a caller with the identity-admin role
+func identityAdminContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:records", Roles: []string{auth.RoleIdentityAdmin}})
+}
+
+// TestParseIdentifierMappings verifies the mapping file format and its validation
+func TestParseIdentifierMappings(t *testing.T) {
+	mappings, parseError := ParseIdentifierMappings(strings.NewReader(
+		"old_system,old_value,new_system,new_value\nurn:old,A1,urn:new,B1\n urn:old , A2 ,urn:new,B2\n"))
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+	if len(mappings) != 2 || mappings[1].OldValue != "A2" || mappings[1].Line != 3 {
+		t.Errorf("Unexpected mappings %+v", mappings)
+	}
+
+	testCases := []struct {
+		name string
+		file string
+	}{
+		{"empty file", ""},
+		{"header only", "old_system,old_value,new_system,new_value\n"},
+		{"wrong header", "system,value,new_system,new_value\nurn:old,A1,urn:new,B1\n"},
+		{"missing column", "old_system,old_value,new_system,new_value\nurn:old,A1,urn:new\n"},
+		{"empty value", "old_system,old_value,new_system,new_value\nurn:old,,urn:new,B1\n"},
+		{"unchanged identifier", "old_system,old_value,new_system,new_value\nurn:old,A1,urn:old,A1\n"},
+		{"duplicate old identifier", "old_system,old_value,new_system,new_value\nurn:old,A1,urn:new,B1\nurn:old,A1,urn:new,B2\n"},
+		{"duplicate new identifier", "old_system,old_value,new_system,new_value\nurn:old,A1,urn:new,B1\nurn:old,A2,urn:new,B1\n"},
+		{"chained mapping", "old_system,old_value,new_system,new_value\nurn:old,A1,urn:old,A2\nurn:old,A2,urn:new,B2\n"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, parseError := ParseIdentifierMappings(strings.NewReader(testCase.file))
+			var appError *apperrors.AppError
+			if !errors.As(parseError, &appError) || appError.StatusCode != http.StatusBadRequest {
+				t.Errorf("Expected 400, got %v", parseError)
+			}
+		})
+	}
+}
+
+// TestIdentifierRekeyService_StartJob_RequiresIdentityAdmin verifies only the identity-admin role may start or roll back jobs
+func TestIdentifierRekeyService_StartJob_RequiresIdentityAdmin(t *testing.T) {
+	rekeyRepository := newMemoryRekeyRepository(map[string]*models.Patient{})
+	rekeyService := NewIdentifierRekeyService(rekeyRepository, NewPatientService(NewMockPatientRepository()), DefaultRekeyPolicy())
+	mappingFile := "old_system,old_value,new_system,new_value\nurn:old,A1,urn:new,B1\n"
+
+	_, startError := rekeyService.StartJob(context.Background(), strings.NewReader(mappingFile), "MRN migration")
+	var appError *apperrors.AppError
+	if !errors.As(startError, &appError) || appError.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403, got %v", startError)
+	}
+	if _, startError := rekeyService.StartJob(identityAdminContext(), strings.NewReader(mappingFile), " "); startError == nil {
+		t.Error("Expected a reason to be required")
+	}
+	if len(rekeyRepository.jobs) != 0 {
+		t.Error("Expected no job to be created")
+	}
+
+	job, _ := rekeyService.StartJob(identityAdminContext(), strings.NewReader(mappingFile), "MRN migration")
+	if _, rollbackError := rekeyService.Rollback(context.Background(), job.ID); !errors.As(rollbackError, &appError) || appError.StatusCode != http.StatusForbidden {
+		t.Errorf("Expected 403 for rollback, got %v", rollbackError)
+	}
+}
+
+// TestIdentifierRekeyService_ApplyAndRollback verifies batches rewrite identifiers, skip unsafe mappings, and roll back
+func TestIdentifierRekeyService_ApplyAndRollback(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	for _, patient := range []*models.Patient{
+		{ID: "patient-1", IdentifierSystem: "urn:old", IdentifierValue: "A1"},
+		{ID: "patient-2", IdentifierSystem: "urn:old", IdentifierValue: "A2"},
+		{ID: "patient-3", IdentifierSystem: "urn:old", IdentifierValue: "A3"},
+		{ID: "patient-4", IdentifierSystem: "urn:new", IdentifierValue: "B3"},
+	} {
+		patientRepository.patients[patient.ID] = patient
+	}
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(patientRepository)
+	patientService.SetChangeRepository(changeRepository)
+	rekeyRepository := newMemoryRekeyRepository(patientRepository.patients)
+	rekeyService := NewIdentifierRekeyService(rekeyRepository, patientService, RekeyPolicy{BatchSize: 2, BatchInterval: time.Millisecond})
+	ctx := identityAdminContext()
+
+	job, startError := rekeyService.StartJob(ctx, strings.NewReader("old_system,old_value,new_system,new_value\n"+
+		"urn:old,A1,urn:new,B1\nurn:old,A2,urn:new,B2\nurn:old,A3,urn:new,B3\nurn:old,A9,urn:new,B9\n"), "MRN migration")
+	if startError != nil {
+		t.Fatalf("Expected no error, got %v", startError)
+	}
+
+	firstBatch, _ := rekeyService.ProcessNextBatch(ctx)
+	if len(firstBatch) != 2 {
+		t.Fatalf("Expected a batch of 2, got %d", len(firstBatch))
+	}
+	rekeyService.ProcessNextBatch(ctx)
+	rekeyService.ProcessNextBatch(ctx)
+
+	job, _ = rekeyService.GetJob(ctx, job.ID)
+	if job.Status != models.RekeyJobStatusCompleted || job.Processed != 4 || job.Rekeyed != 2 || job.Skipped != 2 {
+		t.Fatalf("Unexpected job after applying %+v", job)
+	}
+	if patientRepository.patients["patient-1"].IdentifierValue != "B1" || patientRepository.patients["patient-3"].IdentifierValue != "A3" {
+		t.Errorf("Expected patient-1 re-keyed and patient-3 left alone")
+	}
+	if len(changeRepository.changes) != 2 || changeRepository.changes[0].Operation != models.ChangeOperationUpdate {
+		t.Errorf("Expected an update in the change log per re-keyed patient, got %d changes", len(changeRepository.changes))
+	}
+
+	// patient-2 changes again outside the job, so its rewrite cannot be undone
+	patientRepository.patients["patient-2"].IdentifierValue = "B2-corrected"
+	if _, rollbackError := rekeyService.Rollback(ctx, job.ID); rollbackError != nil {
+		t.Fatalf("Expected no error, got %v", rollbackError)
+	}
+	rekeyService.ProcessNextBatch(ctx)
+	rekeyService.ProcessNextBatch(ctx)
+
+	job, records, _ := rekeyService.ListRecords(ctx, job.ID)
+	if job.Status != models.RekeyJobStatusRolledBack || job.RolledBack != 1 || job.RollbackActor != "api-key:records" {
+		t.Fatalf("Unexpected job after rollback %+v", job)
+	}
+	if patientRepository.patients["patient-1"].IdentifierValue != "A1" || patientRepository.patients["patient-2"].IdentifierValue != "B2-corrected" {
+		t.Errorf("Expected patient-1 restored and patient-2 kept")
+	}
+	if lastRecord := records[len(records)-1]; lastRecord.Action != models.RekeyActionRolledBack || lastRecord.PatientID != "patient-1" {
+		t.Errorf("Expected the last entry to restore patient-1, got %+v", lastRecord)
+	}
+
+	var appError *apperrors.AppError
+	if _, rollbackError := rekeyService.Rollback(ctx, job.ID); !errors.As(rollbackError, &appError) || appError.StatusCode != http.StatusConflict {
+		t.Errorf("Expected 409 rolling back twice, got %v", rollbackError)
+	}
+	if _, getError := rekeyService.GetJob(ctx, 99); !errors.As(getError, &appError) || appError.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown job, got %v", getError)
+	}
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// ImmunizationService handles business logic for Immunization operations
+type ImmunizationService struct {
+	immunizationRepository repository.ImmunizationRepository
+	immunizationMapper     *models.ImmunizationMapper
+	changeRepository       repository.ChangeRepository
+	eventPublisher         events.Publisher
+	strictMapping          bool
+}
+
+// NewImmunizationService creates a new instance of ImmunizationService
+func NewImmunizationService(immunizationRepository repository.ImmunizationRepository) *ImmunizationService {
+	return &ImmunizationService{
+		immunizationRepository: immunizationRepository,
+		immunizationMapper:     models.NewImmunizationMapper(),
+	}
+}
+
+// SetChangeRepository enables recording immunization writes to the change log
+func (service *ImmunizationService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing immunization write events to internal consumers
+func (service *ImmunizationService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *ImmunizationService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// CreateImmunization creates a new immunization from a FHIR Immunization resource
+// Immunizations without a valid status are rejected
+func (service *ImmunizationService) CreateImmunization(ctx context.Context, fhirImmunization *fhir.Immunization) (*fhir.Immunization, error) {
+	if codeIssues := models.ImmunizationCodeIssues(fhirImmunization); len(codeIssues) > 0 {
+		return nil, &outcome.RejectionError{Issues: codeIssues}
+	}
+
+	domainImmunization, mappingIssues := service.immunizationMapper.FromFHIR(fhirImmunization)
+	if issuesError := handleMappingIssues(ctx, "Immunization", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	createdFHIRImmunization, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(transactionContext context.Context) (*models.Immunization, error) {
+		return service.immunizationRepository.Create(transactionContext, domainImmunization)
+	})
+	if createError != nil {
+		return nil, createError
+	}
+
+	reportIgnoredElements(ctx, "Immunization", fhirImmunization, createdFHIRImmunization, mappingIssues)
+	return createdFHIRImmunization, nil
+}
+
+// GetImmunizationByID retrieves an immunization by ID, reporting ErrResourceNotFound for unknown immunizations
+func (service *ImmunizationService) GetImmunizationByID(ctx context.Context, immunizationID string) (*fhir.Immunization, error) {
+	if _, parseError := uuid.Parse(immunizationID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainImmunization, getError := service.immunizationRepository.GetByID(ctx, immunizationID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+
+	return service.immunizationMapper.ToFHIR(domainImmunization), nil
+}
+
+// SearchImmunizations retrieves immunizations matching the search criteria
+// Patient IDs are stored as UUIDs, so any other patient ID matches no immunizations
+func (service *ImmunizationService) SearchImmunizations(ctx context.Context, searchParams *models.ImmunizationSearchParams) ([]*fhir.Immunization, error) {
+	if !searchablePatientID(searchParams.PatientID) {
+		return []*fhir.Immunization{}, nil
+	}
+
+	domainImmunizations, searchError := service.immunizationRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirImmunizations := make([]*fhir.Immunization, len(domainImmunizations))
+	for index, domainImmunization := range domainImmunizations {
+		fhirImmunizations[index] = service.immunizationMapper.ToFHIR(domainImmunization)
+	}
+
+	return fhirImmunizations, nil
+}
+
+// CountImmunizations returns how many immunizations match the search across all pages, used as the searchset total
+func (service *ImmunizationService) CountImmunizations(ctx context.Context, searchParams *models.ImmunizationSearchParams) (int, error) {
+	if !searchablePatientID(searchParams.PatientID) {
+		return 0, nil
+	}
+	return service.immunizationRepository.Count(ctx, searchParams)
+}
+
+// UpdateImmunization updates an existing immunization, reporting ErrResourceNotFound for unknown immunizations
+// Immunizations without a valid status are rejected
+func (service *ImmunizationService) UpdateImmunization(ctx context.Context, immunizationID string, fhirImmunization *fhir.Immunization) (*fhir.Immunization, error) {
+	if _, parseError := uuid.Parse(immunizationID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	if codeIssues := models.ImmunizationCodeIssues(fhirImmunization); len(codeIssues) > 0 {
+		return nil, &outcome.RejectionError{Issues: codeIssues}
+	}
+
+	domainImmunization, mappingIssues := service.immunizationMapper.FromFHIR(fhirImmunization)
+	if issuesError := handleMappingIssues(ctx, "Immunization", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	domainImmunization.ID = immunizationID
+
+	updatedFHIRImmunization, updateError := service.commitWrite(ctx, immunizationID, models.ChangeOperationUpdate, func(transactionContext context.Context) (*models.Immunization, error) {
+		return service.immunizationRepository.Update(transactionContext, domainImmunization)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	reportIgnoredElements(ctx, "Immunization", fhirImmunization, updatedFHIRImmunization, mappingIssues)
+	return updatedFHIRImmunization, nil
+}
+
+// DeleteImmunization removes an immunization by ID, reporting ErrResourceNotFound for unknown immunizations
+func (service *ImmunizationService) DeleteImmunization(ctx context.Context, immunizationID string) error {
+	if _, parseError := uuid.Parse(immunizationID); parseError != nil {
+		return ErrResourceNotFound
+	}
+
+	_, deleteError := service.commitWrite(ctx, immunizationID, models.ChangeOperationDelete, func(transactionContext context.Context) (*models.Immunization, error) {
+		return nil, service.immunizationRepository.Delete(transactionContext, immunizationID)
+	})
+	if errors.Is(deleteError, ErrResourceDeleted) {
+		return ErrResourceDeleted
+	}
+	if errors.Is(deleteError, sql.ErrNoRows) {
+		return ErrResourceNotFound
+	}
+	return deleteError
+}
+
+// commitWrite runs write and records it in the change log in one transaction, then publishes it to event consumers
+// write returns the stored immunization, or nil for deletes; its FHIR form is returned and kept as the version's snapshot
+// Creates and updates are recorded in the subject patient's compartment; deletes, like observation deletes, carry none
+func (service *ImmunizationService) commitWrite(ctx context.Context, immunizationID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.Immunization, error)) (*fhir.Immunization, error) {
+	var writtenImmunization *models.Immunization
+	var writtenFHIRImmunization *fhir.Immunization
+	var version int
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenImmunization, writeError = write(transactionContext)
+		if writeError != nil {
+			return writeError
+		}
+
+		var snapshot interface{}
+		var compartmentPatientID string
+		if writtenImmunization != nil {
+			immunizationID = writtenImmunization.ID
+			writtenFHIRImmunization = service.immunizationMapper.ToFHIR(writtenImmunization)
+			snapshot = writtenFHIRImmunization
+			compartmentPatientID = writtenImmunization.PatientID
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "Immunization", immunizationID, operation, snapshot, compartmentPatientID)
+		return recordError
+	})
+	if transactionError != nil {
+		return nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenImmunization != nil {
+		resource = writtenImmunization
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "Immunization", immunizationID, operation, version, resource)
+	return writtenFHIRImmunization, nil
+}
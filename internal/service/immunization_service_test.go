@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockImmunizationRepository implements ImmunizationRepository interface for testing
+type MockImmunizationRepository struct {
+	immunizations map[string]*models.Immunization
+	deleted       map[string]bool
+}
+
+// NewMockImmunizationRepository creates a new mock repository for testing
+func NewMockImmunizationRepository() *MockImmunizationRepository {
+	return &MockImmunizationRepository{immunizations: make(map[string]*models.Immunization), deleted: make(map[string]bool)}
+}
+
+// Create stores an immunization under a generated UUID
+func (mock *MockImmunizationRepository) Create(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error) {
+	immunization.ID = uuid.NewString()
+	mock.immunizations[immunization.ID] = immunization
+	return immunization, nil
+}
+
+// GetByID retrieves a stored immunization
+func (mock *MockImmunizationRepository) GetByID(ctx context.Context, immunizationID string) (*models.Immunization, error) {
+	if mock.deleted[immunizationID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	immunization, exists := mock.immunizations[immunizationID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return immunization, nil
+}
+
+// Search returns every stored immunization
+func (mock *MockImmunizationRepository) Search(ctx context.Context, searchParams *models.ImmunizationSearchParams) ([]*models.Immunization, error) {
+	result := make([]*models.Immunization, 0, len(mock.immunizations))
+	for _, immunization := range mock.immunizations {
+		result = append(result, immunization)
+	}
+	return result, nil
+}
+
+// Count returns how many immunizations are stored, as every search matches them all
+func (mock *MockImmunizationRepository) Count(ctx context.Context, searchParams *models.ImmunizationSearchParams) (int, error) {
+	return len(mock.immunizations), nil
+}
+
+// Update replaces a stored immunization
+func (mock *MockImmunizationRepository) Update(ctx context.Context, immunization *models.Immunization) (*models.Immunization, error) {
+	if mock.deleted[immunization.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.immunizations[immunization.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.immunizations[immunization.ID] = immunization
+	return immunization, nil
+}
+
+// Delete marks a stored immunization deleted, as the repository does
+func (mock *MockImmunizationRepository) Delete(ctx context.Context, immunizationID string) error {
+	if mock.deleted[immunizationID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.immunizations[immunizationID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.immunizations, immunizationID)
+	mock.deleted[immunizationID] = true
+	return nil
+}
+
+// TestImmunizationService_Lifecycle verifies creates and updates are recorded in the subject's compartment and deletes in none
+func TestImmunizationService_Lifecycle(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	immunizationService := NewImmunizationService(NewMockImmunizationRepository())
+	immunizationService.SetChangeRepository(changeRepository)
+	ctx := context.Background()
+
+	patientID := uuid.NewString()
+	patientReference := "Patient/" + patientID
+	createdImmunization, createError := immunizationService.CreateImmunization(ctx, &fhir.Immunization{
+		Status:  fhir.ImmunizationStatusCodesCompleted,
+		Patient: fhir.Reference{Reference: &patientReference},
+	})
+	if createError != nil {
+		t.Fatalf("Expected no error creating the immunization, got %v", createError)
+	}
+
+	immunizationID := *createdImmunization.Id
+	if _, updateError := immunizationService.UpdateImmunization(ctx, immunizationID, &fhir.Immunization{
+		Status:  fhir.ImmunizationStatusCodesEnteredInError,
+		Patient: fhir.Reference{Reference: &patientReference},
+	}); updateError != nil {
+		t.Fatalf("Expected no error updating the immunization, got %v", updateError)
+	}
+	if deleteError := immunizationService.DeleteImmunization(ctx, immunizationID); deleteError != nil {
+		t.Fatalf("Expected no error deleting the immunization, got %v", deleteError)
+	}
+	if _, getError := immunizationService.GetImmunizationByID(ctx, immunizationID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted reading the deleted immunization, got %v", getError)
+	}
+	if deleteError := immunizationService.DeleteImmunization(ctx, immunizationID); !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	for index, expectedCompartment := range []string{patientID, patientID, ""} {
+		change := changeRepository.changes[index]
+		if change.ResourceType != "Immunization" || change.ResourceID != immunizationID || change.CompartmentPatientID != expectedCompartment {
+			t.Errorf("Change %d: expected Immunization/%s in compartment %q, got %+v", index, immunizationID, expectedCompartment, change)
+		}
+	}
+}
+
+// TestImmunizationService_RejectsInvalidCodes verifies an immunization without a valid status is not stored
+func TestImmunizationService_RejectsInvalidCodes(t *testing.T) {
+	immunizationRepository := NewMockImmunizationRepository()
+	immunizationService := NewImmunizationService(immunizationRepository)
+
+	_, createError := immunizationService.CreateImmunization(context.Background(), &fhir.Immunization{Status: fhir.ImmunizationStatusCodesInProgress})
+	var rejection *outcome.RejectionError
+	if !errors.As(createError, &rejection) || len(rejection.Issues) != 1 {
+		t.Fatalf("Expected a rejection for the unknown status, got %v", createError)
+	}
+	if len(immunizationRepository.immunizations) != 0 {
+		t.Errorf("Expected nothing stored, got %d immunizations", len(immunizationRepository.immunizations))
+	}
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/referencerange"
+)
+
+// computeInterpretation gives an observation sent without an interpretation one computed from its reference
+// range: H above it, L below it, and N within it
+// Interpretations the client sent, or the plausibility check set, are kept as they are
+func computeInterpretation(interpreter *referencerange.Interpreter, observation *models.Observation) {
+	if interpreter == nil || observation.Interpretation != nil {
+		return
+	}
+	if computed, interpreted := interpreter.Interpret(observation); interpreted {
+		observation.Interpretation = computed
+	}
+}
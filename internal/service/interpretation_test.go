@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/plausibility"
+	"github.com/nathannewyen/fhir-health-interop/internal/referencerange"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestObservationService_Interpretation verifies interpretations are computed only for observations sent
+// without one, and plausibility flags take precedence
+func TestObservationService_Interpretation(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	observationService.SetPlausibilityChecker(plausibility.NewChecker(plausibility.DefaultRules()))
+	observationService.SetInterpreter(referencerange.NewInterpreter(referencerange.DefaultRanges()))
+
+	created, createError := observationService.CreateObservation(context.Background(), heartRateObservation("120"))
+	if createError != nil {
+		t.Fatalf("Expected the observation stored, got %v", createError)
+	}
+	if len(created.Interpretation) != 1 || *created.Interpretation[0].Coding[0].Code != referencerange.HighCode {
+		t.Errorf("Expected a fast heart rate interpreted as high, got %+v", created.Interpretation)
+	}
+
+	sentObservation := heartRateObservation("120")
+	sentCode := "A"
+	sentObservation.Interpretation = []fhir.CodeableConcept{{Coding: []fhir.Coding{{Code: &sentCode}}}}
+	observationService.CreateObservation(context.Background(), sentObservation)
+	if mockRepo.lastCreated.Interpretation.Code != "A" {
+		t.Errorf("Expected the client's interpretation kept, got %+v", mockRepo.lastCreated.Interpretation)
+	}
+
+	observationService.CreateObservation(context.Background(), heartRateObservation("950"))
+	if mockRepo.lastCreated.Interpretation.Code != plausibility.QuestionableCode {
+		t.Errorf("Expected an implausible value to stay questionable, got %+v", mockRepo.lastCreated.Interpretation)
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// ErrUnderLegalHold is wrapped by the error returned when a deletion is refused because of a legal hold
+var ErrUnderLegalHold = errors.New("record is under legal hold")
+
+// DeletionGuard refuses deletions that would destroy records under legal hold
+// Services and retention or purge jobs call it before removing anything tied to a patient
+type DeletionGuard interface {
+	// CheckDeletable returns an error wrapping ErrUnderLegalHold when the patient is held
+	CheckDeletable(ctx context.Context, patientID string, resourceType string, resourceID string) error
+}
+
+// LegalHoldStatus is a patient's hold state together with its audit history
+type LegalHoldStatus struct {
+	PatientID string                   `json:"patient_id"`
+	Held      bool                     `json:"held"`
+	Hold      *models.LegalHold        `json:"hold,omitempty"`
+	History   []*models.LegalHoldEvent `json:"history"`
+}
+
+// LegalHoldService places and releases legal holds and enforces them on deletion
+type LegalHoldService struct {
+	holdRepository    repository.LegalHoldRepository
+	patientRepository repository.PatientRepository
+}
+
+// NewLegalHoldService creates a new legal hold service instance
+func NewLegalHoldService(holdRepository repository.LegalHoldRepository, patientRepository repository.PatientRepository) *LegalHoldService {
+	return &LegalHoldService{
+		holdRepository:    holdRepository,
+		patientRepository: patientRepository,
+	}
+}
+
+// PlaceHold puts the patient under legal hold; only the compliance role may do this
+// Placing a hold on an already-held patient replaces its reason
+func (service *LegalHoldService) PlaceHold(ctx context.Context, patientID string, reason string) (*models.LegalHold, error) {
+	principal, roleError := requireCompliance(ctx)
+	if roleError != nil {
+		return nil, roleError
+	}
+	if strings.TrimSpace(reason) == "" {
+		return nil, apperrors.InvalidInput("reason", "is required")
+	}
+	if _, getError := service.patientRepository.GetByID(ctx, patientID); getError != nil {
+		return nil, apperrors.NotFound("Patient", patientID)
+	}
+
+	hold, placeError := service.holdRepository.Place(ctx, &models.LegalHold{
+		PatientID: patientID,
+		Reason:    strings.TrimSpace(reason),
+		PlacedBy:  principal.ID,
+	})
+	if placeError != nil {
+		return nil, placeError
+	}
+
+	log.Info().
+		Str("patient_id", patientID).
+		Str("actor", principal.ID).
+		Str("reason", hold.Reason).
+		Msg("Legal hold placed")
+	return hold, nil
+}
+
+// ReleaseHold lifts the patient's legal hold; only the compliance role may do this
+func (service *LegalHoldService) ReleaseHold(ctx context.Context, patientID string, reason string) error {
+	principal, roleError := requireCompliance(ctx)
+	if roleError != nil {
+		return roleError
+	}
+	if strings.TrimSpace(reason) == "" {
+		return apperrors.InvalidInput("reason", "is required")
+	}
+
+	releaseError := service.holdRepository.Release(ctx, patientID, principal.ID, strings.TrimSpace(reason))
+	if errors.Is(releaseError, sql.ErrNoRows) {
+		return apperrors.NotFound("LegalHold", patientID)
+	}
+	if releaseError != nil {
+		return releaseError
+	}
+
+	log.Info().
+		Str("patient_id", patientID).
+		Str("actor", principal.ID).
+		Str("reason", reason).
+		Msg("Legal hold released")
+	return nil
+}
+
+// GetStatus returns whether the patient is held and the full hold history (compliance role only)
+func (service *LegalHoldService) GetStatus(ctx context.Context, patientID string) (*LegalHoldStatus, error) {
+	if _, roleError := requireCompliance(ctx); roleError != nil {
+		return nil, roleError
+	}
+
+	status := &LegalHoldStatus{PatientID: patientID}
+
+	hold, getError := service.holdRepository.Get(ctx, patientID)
+	switch {
+	case getError == nil:
+		status.Held = true
+		status.Hold = hold
+	case !errors.Is(getError, sql.ErrNoRows):
+		return nil, getError
+	}
+
+	history, historyError := service.holdRepository.ListEvents(ctx, patientID)
+	if historyError != nil {
+		return nil, historyError
+	}
+	status.History = history
+
+	return status, nil
+}
+
+// CheckDeletable refuses deletion of a held patient or any resource belonging to one
+// Refusals are written to the audit history; lookup failures also refuse, since a hold cannot be ruled out
+func (service *LegalHoldService) CheckDeletable(ctx context.Context, patientID string, resourceType string, resourceID string) error {
+	if patientID == "" {
+		return nil
+	}
+
+	hold, getError := service.holdRepository.Get(ctx, patientID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil
+	}
+	if getError != nil {
+		return apperrors.Internal("Failed to check legal hold", getError)
+	}
+
+	actor := auth.FromContext(ctx).ID
+	auditError := service.holdRepository.RecordEvent(ctx, &models.LegalHoldEvent{
+		PatientID:    patientID,
+		Action:       models.LegalHoldActionDeletionBlocked,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Actor:        actor,
+		Reason:       hold.Reason,
+	})
+	if auditError != nil {
+		log.Error().Err(auditError).Str("patient_id", patientID).Msg("Failed to record blocked deletion")
+	}
+
+	log.Warn().
+		Str("patient_id", patientID).
+		Str("resource_type", resourceType).
+		Str("resource_id", resourceID).
+		Str("actor", actor).
+		Msg("Deletion blocked by legal hold")
+
+	holdError := apperrors.Conflict(resourceType, "patient "+patientID+" is under legal hold")
+	holdError.Err = ErrUnderLegalHold
+	return holdError
+}
+
+// requireCompliance returns the request's principal, or a 403 when it lacks the compliance role
+func requireCompliance(ctx context.Context) (auth.Principal, error) {
+	principal := auth.FromContext(ctx)
+	if !principal.HasRole(auth.RoleCompliance) {
+		return principal, apperrors.Forbidden("Legal holds can only be read or changed by the compliance role")
+	}
+	return principal, nil
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// memoryLegalHoldRepository implements LegalHoldRepository in memory for testing
+type memoryLegalHoldRepository struct {
+	holds  map[string]*models.LegalHold
+	events []*models.LegalHoldEvent
+}
+
+// newMemoryLegalHoldRepository creates an empty in-memory hold repository
+func newMemoryLegalHoldRepository() *memoryLegalHoldRepository {
+	return &memoryLegalHoldRepository{holds: make(map[string]*models.LegalHold)}
+}
+
+// Place stores the hold and records the placement
+func (repository *memoryLegalHoldRepository) Place(ctx context.Context, hold *models.LegalHold) (*models.LegalHold, error) {
+	hold.PlacedAt = time.Now()
+	repository.holds[hold.PatientID] = hold
+	repository.RecordEvent(ctx, &models.LegalHoldEvent{PatientID: hold.PatientID, Action: models.LegalHoldActionPlaced, Actor: hold.PlacedBy, Reason: hold.Reason})
+	return hold, nil
+}
+
+// Release removes the hold and records the release
+func (repository *memoryLegalHoldRepository) Release(ctx context.Context, patientID string, actor string, reason string) error {
+	if _, exists := repository.holds[patientID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(repository.holds, patientID)
+	return repository.RecordEvent(ctx, &models.LegalHoldEvent{PatientID: patientID, Action: models.LegalHoldActionReleased, Actor: actor, Reason: reason})
+}
+
+// Get returns the active hold
+func (repository *memoryLegalHoldRepository) Get(ctx context.Context, patientID string) (*models.LegalHold, error) {
+	hold, exists := repository.holds[patientID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return hold, nil
+}
+
+// RecordEvent appends to the history
+func (repository *memoryLegalHoldRepository) RecordEvent(ctx context.Context, event *models.LegalHoldEvent) error {
+	repository.events = append(repository.events, event)
+	return nil
+}
+
+// ListEvents returns the patient's history
+func (repository *memoryLegalHoldRepository) ListEvents(ctx context.Context, patientID string) ([]*models.LegalHoldEvent, error) {
+	events := []*models.LegalHoldEvent{}
+	for _, event := range repository.events {
+		if event.PatientID == patientID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// complianceContext returns a context for a caller with the compliance role
+func complianceContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:compliance", Roles: []string{auth.RoleCompliance}})
+}
+
+// newTestLegalHoldService creates a hold service with one stored patient
+func newTestLegalHoldService() (*LegalHoldService, *memoryLegalHoldRepository, *MockPatientRepository) {
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients["patient-1"] = &models.Patient{ID: "patient-1"}
+	holdRepository := newMemoryLegalHoldRepository()
+	return NewLegalHoldService(holdRepository, patientRepository), holdRepository, patientRepository
+}
+
+// TestLegalHoldService_RequiresComplianceRole verifies only compliance may read or change holds
+func TestLegalHoldService_RequiresComplianceRole(t *testing.T) {
+	holdService, holdRepository, _ := newTestLegalHoldService()
+	otherContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:clerk", Roles: []string{"clerk"}})
+
+	for _, ctx := range []context.Context{context.Background(), otherContext} {
+		_, placeError := holdService.PlaceHold(ctx, "patient-1", "Case 42")
+		var appError *apperrors.AppError
+		if !errors.As(placeError, &appError) || appError.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected 403, got %v", placeError)
+		}
+		if _, statusError := holdService.GetStatus(ctx, "patient-1"); !errors.As(statusError, &appError) || appError.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected 403 reading the hold, got %v", statusError)
+		}
+	}
+	if len(holdRepository.holds) != 0 {
+		t.Error("Expected no hold to be placed")
+	}
+}
+
+// TestLegalHoldService_PlaceAndRelease verifies the hold lifecycle and its audit history
+func TestLegalHoldService_PlaceAndRelease(t *testing.T) {
+	holdService, _, _ := newTestLegalHoldService()
+	ctx := complianceContext()
+
+	if _, placeError := holdService.PlaceHold(ctx, "patient-1", " "); placeError == nil {
+		t.Error("Expected a reason to be required")
+	}
+	if _, placeError := holdService.PlaceHold(ctx, "missing", "Case 42"); placeError == nil {
+		t.Error("Expected unknown patient to be rejected")
+	}
+
+	hold, placeError := holdService.PlaceHold(ctx, "patient-1", "Case 42")
+	if placeError != nil || hold.PlacedBy != "api-key:compliance" {
+		t.Fatalf("Expected hold placed by compliance, got %+v (%v)", hold, placeError)
+	}
+
+	status, _ := holdService.GetStatus(ctx, "patient-1")
+	if !status.Held || status.Hold.Reason != "Case 42" {
+		t.Errorf("Expected held status, got %+v", status)
+	}
+
+	if releaseError := holdService.ReleaseHold(ctx, "patient-1", "Case closed"); releaseError != nil {
+		t.Fatalf("Expected no error, got %v", releaseError)
+	}
+	if releaseError := holdService.ReleaseHold(ctx, "patient-1", "Case closed"); releaseError == nil {
+		t.Error("Expected releasing an unheld patient to fail")
+	}
+
+	status, _ = holdService.GetStatus(ctx, "patient-1")
+	if status.Held || len(status.History) != 2 {
+		t.Errorf("Expected released status with two history entries, got %+v", status)
+	}
+}
+
+// TestLegalHoldService_BlocksDeletion verifies held patients and their observations cannot be deleted
+func TestLegalHoldService_BlocksDeletion(t *testing.T) {
+	holdService, holdRepository, patientRepository := newTestLegalHoldService()
+	holdService.PlaceHold(complianceContext(), "patient-1", "Case 42")
+
+	patientService := NewPatientService(patientRepository)
+	patientService.SetDeletionGuard(holdService)
+	deleteError := patientService.DeletePatient(context.Background(), "patient-1")
+	if !errors.Is(deleteError, ErrUnderLegalHold) {
+		t.Errorf("Expected ErrUnderLegalHold, got %v", deleteError)
+	}
+	if patientRepository.lastDeletedID != "" {
+		t.Error("Expected the patient not to be deleted")
+	}
+
+	observationRepository := NewMockObservationRepository()
+	observationRepository.observations["obs-1"] = &models.Observation{ID: "obs-1", PatientID: "patient-1"}
+	observationRepository.observations["obs-2"] = &models.Observation{ID: "obs-2", PatientID: "patient-2"}
+	ledger := &recordingLedger{adjustments: map[string]int64{}}
+	observationService := NewObservationService(observationRepository)
+	observationService.SetDeletionGuard(holdService)
+	observationService.SetLedgerRepository(ledger)
+
+	if deleteError := observationService.DeleteObservation(context.Background(), "obs-1"); !errors.Is(deleteError, ErrUnderLegalHold) {
+		t.Errorf("Expected ErrUnderLegalHold for held patient's observation, got %v", deleteError)
+	}
+	if deleteError := observationService.DeleteObservation(context.Background(), "obs-2"); deleteError != nil {
+		t.Errorf("Expected unheld observation to be deleted, got %v", deleteError)
+	}
+	if observationRepository.getByIDCalls != 2 || ledger.adjustments["patient-2"] != -1 {
+		t.Errorf("Expected one read per delete and the ledger decremented, got %d reads and %v", observationRepository.getByIDCalls, ledger.adjustments)
+	}
+
+	blockedCount := 0
+	for _, event := range holdRepository.events {
+		if event.Action == models.LegalHoldActionDeletionBlocked {
+			blockedCount++
+		}
+	}
+	if blockedCount != 2 {
+		t.Errorf("Expected 2 blocked deletions audited, got %d", blockedCount)
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// handleMappingIssues logs data the mapper dropped and either reports it to the caller as warnings
+// or, in strict mode, rejects the write so nothing is stored with missing fields
+func handleMappingIssues(ctx context.Context, resourceType string, strict bool, issues []outcome.Issue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+
+	for _, issue := range issues {
+		log.Warn().
+			Str("resource_type", resourceType).
+			Strs("expression", issue.Expression).
+			Bool("strict", strict).
+			Msg(issue.Diagnostics)
+	}
+
+	if strict {
+		rejectedIssues := make([]outcome.Issue, len(issues))
+		for index, issue := range issues {
+			issue.Severity = fhir.IssueSeverityError
+			rejectedIssues[index] = issue
+		}
+		return &outcome.RejectionError{Issues: rejectedIssues}
+	}
+
+	outcome.Collect(ctx, issues...)
+	return nil
+}
+
+// reportIgnoredElements tells the caller which submitted elements the mappers do not support and did not store
+// These are informational until the mappers reach full fidelity, so strict mode does not reject them
+func reportIgnoredElements(ctx context.Context, resourceType string, submitted interface{}, stored interface{}, mappingIssues []outcome.Issue) {
+	ignoredIssues := models.IgnoredElementIssues(resourceType, submitted, stored, mappingIssues)
+	if len(ignoredIssues) == 0 {
+		return
+	}
+
+	ignoredPaths := make([]string, 0, len(ignoredIssues))
+	for _, issue := range ignoredIssues {
+		ignoredPaths = append(ignoredPaths, issue.Expression...)
+	}
+	log.Debug().Str("resource_type", resourceType).Strs("ignored", ignoredPaths).Msg("Write dropped unsupported elements")
+
+	outcome.Collect(ctx, ignoredIssues...)
+}
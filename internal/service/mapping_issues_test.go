@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestPatientService_CreatePatient_CollectsMappingWarnings verifies dropped data is stored with warnings
+func TestPatientService_CreatePatient_CollectsMappingWarnings(t *testing.T) {
+	mockRepository := NewMockPatientRepository()
+	patientService := NewPatientService(mockRepository)
+	birthDate := "not-a-date"
+
+	ctx, collector := outcome.WithCollector(context.Background())
+	_, createError := patientService.CreatePatient(ctx, &fhir.Patient{BirthDate: &birthDate})
+
+	if createError != nil {
+		t.Fatalf("Expected no error, got %v", createError)
+	}
+	if mockRepository.lastCreated == nil {
+		t.Fatal("Expected patient to be stored")
+	}
+	issues := collector.Issues()
+	if len(issues) != 1 || issues[0].Severity != fhir.IssueSeverityWarning {
+		t.Errorf("Expected one warning, got %+v", issues)
+	}
+}
+
+// TestPatientService_StrictMapping_RejectsWrite verifies strict mode rejects before storing
+func TestPatientService_StrictMapping_RejectsWrite(t *testing.T) {
+	mockRepository := NewMockPatientRepository()
+	patientService := NewPatientService(mockRepository)
+	patientService.SetStrictMapping(true)
+	birthDate := "not-a-date"
+
+	_, createError := patientService.CreatePatient(context.Background(), &fhir.Patient{BirthDate: &birthDate})
+	_, updateError := patientService.UpdatePatient(context.Background(), "patient-1", &fhir.Patient{BirthDate: &birthDate})
+
+	for _, writeError := range []error{createError, updateError} {
+		var rejection *outcome.RejectionError
+		if !errors.As(writeError, &rejection) {
+			t.Fatalf("Expected RejectionError, got %v", writeError)
+		}
+		if rejection.Issues[0].Severity != fhir.IssueSeverityError {
+			t.Errorf("Expected rejected issues to be errors, got %s", rejection.Issues[0].Severity)
+		}
+	}
+	if mockRepository.lastCreated != nil || mockRepository.lastUpdated != nil {
+		t.Error("Expected nothing to be stored in strict mode")
+	}
+}
+
+// TestObservationService_StrictMapping_RejectsWrite verifies strict mode applies to observations
+func TestObservationService_StrictMapping_RejectsWrite(t *testing.T) {
+	observationService := NewObservationService(NewMockObservationRepository())
+	effectiveDateTime := "yesterday"
+
+	ctx, collector := outcome.WithCollector(context.Background())
+	if _, createError := observationService.CreateObservation(ctx, &fhir.Observation{EffectiveDateTime: &effectiveDateTime}); createError != nil {
+		t.Fatalf("Expected lenient create to succeed, got %v", createError)
+	}
+	if len(collector.Issues()) != 1 {
+		t.Errorf("Expected one warning, got %+v", collector.Issues())
+	}
+
+	observationService.SetStrictMapping(true)
+	_, strictError := observationService.CreateObservation(context.Background(), &fhir.Observation{EffectiveDateTime: &effectiveDateTime})
+	var rejection *outcome.RejectionError
+	if !errors.As(strictError, &rejection) {
+		t.Errorf("Expected RejectionError in strict mode, got %v", strictError)
+	}
+}
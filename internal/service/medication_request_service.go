@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MedicationRequestService handles business logic for MedicationRequest operations
+type MedicationRequestService struct {
+	medicationRequestRepository repository.MedicationRequestRepository
+	medicationRequestMapper     *models.MedicationRequestMapper
+	changeRepository            repository.ChangeRepository
+	eventPublisher              events.Publisher
+	strictMapping               bool
+}
+
+// NewMedicationRequestService creates a new instance of MedicationRequestService
+func NewMedicationRequestService(medicationRequestRepository repository.MedicationRequestRepository) *MedicationRequestService {
+	return &MedicationRequestService{
+		medicationRequestRepository: medicationRequestRepository,
+		medicationRequestMapper:     models.NewMedicationRequestMapper(),
+	}
+}
+
+// SetChangeRepository enables recording medication request writes to the change log
+func (service *MedicationRequestService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing medication request write events to internal consumers
+func (service *MedicationRequestService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *MedicationRequestService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// CreateMedicationRequest creates a new medication request from a FHIR MedicationRequest resource
+// Requests without a valid status and intent are rejected
+func (service *MedicationRequestService) CreateMedicationRequest(ctx context.Context, fhirMedicationRequest *fhir.MedicationRequest) (*fhir.MedicationRequest, error) {
+	if codeIssues := models.MedicationRequestCodeIssues(fhirMedicationRequest); len(codeIssues) > 0 {
+		return nil, &outcome.RejectionError{Issues: codeIssues}
+	}
+
+	domainMedicationRequest, mappingIssues := service.medicationRequestMapper.FromFHIR(fhirMedicationRequest)
+	if issuesError := handleMappingIssues(ctx, "MedicationRequest", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	createdFHIRMedicationRequest, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(transactionContext context.Context) (*models.MedicationRequest, error) {
+		return service.medicationRequestRepository.Create(transactionContext, domainMedicationRequest)
+	})
+	if createError != nil {
+		return nil, createError
+	}
+
+	reportIgnoredElements(ctx, "MedicationRequest", fhirMedicationRequest, createdFHIRMedicationRequest, mappingIssues)
+	return createdFHIRMedicationRequest, nil
+}
+
+// GetMedicationRequestByID retrieves a medication request by ID, reporting ErrResourceNotFound for unknown medication requests
+func (service *MedicationRequestService) GetMedicationRequestByID(ctx context.Context, medicationRequestID string) (*fhir.MedicationRequest, error) {
+	if _, parseError := uuid.Parse(medicationRequestID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainMedicationRequest, getError := service.medicationRequestRepository.GetByID(ctx, medicationRequestID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+
+	return service.medicationRequestMapper.ToFHIR(domainMedicationRequest), nil
+}
+
+// SearchMedicationRequests retrieves medication requests matching the search criteria
+// Patient IDs are stored as UUIDs, so any other patient ID matches no medication requests
+func (service *MedicationRequestService) SearchMedicationRequests(ctx context.Context, searchParams *models.MedicationRequestSearchParams) ([]*fhir.MedicationRequest, error) {
+	if !searchablePatientID(searchParams.PatientID) {
+		return []*fhir.MedicationRequest{}, nil
+	}
+
+	domainMedicationRequests, searchError := service.medicationRequestRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirMedicationRequests := make([]*fhir.MedicationRequest, len(domainMedicationRequests))
+	for index, domainMedicationRequest := range domainMedicationRequests {
+		fhirMedicationRequests[index] = service.medicationRequestMapper.ToFHIR(domainMedicationRequest)
+	}
+
+	return fhirMedicationRequests, nil
+}
+
+// CountMedicationRequests returns how many medication requests match the search across all pages, used as the searchset total
+func (service *MedicationRequestService) CountMedicationRequests(ctx context.Context, searchParams *models.MedicationRequestSearchParams) (int, error) {
+	if !searchablePatientID(searchParams.PatientID) {
+		return 0, nil
+	}
+	return service.medicationRequestRepository.Count(ctx, searchParams)
+}
+
+// UpdateMedicationRequest updates an existing medication request, reporting ErrResourceNotFound for unknown medication requests
+// Requests without a valid status and intent are rejected
+func (service *MedicationRequestService) UpdateMedicationRequest(ctx context.Context, medicationRequestID string, fhirMedicationRequest *fhir.MedicationRequest) (*fhir.MedicationRequest, error) {
+	if _, parseError := uuid.Parse(medicationRequestID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	if codeIssues := models.MedicationRequestCodeIssues(fhirMedicationRequest); len(codeIssues) > 0 {
+		return nil, &outcome.RejectionError{Issues: codeIssues}
+	}
+
+	domainMedicationRequest, mappingIssues := service.medicationRequestMapper.FromFHIR(fhirMedicationRequest)
+	if issuesError := handleMappingIssues(ctx, "MedicationRequest", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	domainMedicationRequest.ID = medicationRequestID
+
+	updatedFHIRMedicationRequest, updateError := service.commitWrite(ctx, medicationRequestID, models.ChangeOperationUpdate, func(transactionContext context.Context) (*models.MedicationRequest, error) {
+		return service.medicationRequestRepository.Update(transactionContext, domainMedicationRequest)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	reportIgnoredElements(ctx, "MedicationRequest", fhirMedicationRequest, updatedFHIRMedicationRequest, mappingIssues)
+	return updatedFHIRMedicationRequest, nil
+}
+
+// DeleteMedicationRequest removes a medication request by ID, reporting ErrResourceNotFound for unknown medication requests
+func (service *MedicationRequestService) DeleteMedicationRequest(ctx context.Context, medicationRequestID string) error {
+	if _, parseError := uuid.Parse(medicationRequestID); parseError != nil {
+		return ErrResourceNotFound
+	}
+
+	_, deleteError := service.commitWrite(ctx, medicationRequestID, models.ChangeOperationDelete, func(transactionContext context.Context) (*models.MedicationRequest, error) {
+		return nil, service.medicationRequestRepository.Delete(transactionContext, medicationRequestID)
+	})
+	if errors.Is(deleteError, ErrResourceDeleted) {
+		return ErrResourceDeleted
+	}
+	if errors.Is(deleteError, sql.ErrNoRows) {
+		return ErrResourceNotFound
+	}
+	return deleteError
+}
+
+// commitWrite runs write and records it in the change log in one transaction, then publishes it to event consumers
+// write returns the stored medication request, or nil for deletes; its FHIR form is returned and kept as the version's snapshot
+// Creates and updates are recorded in the subject patient's compartment; deletes, like observation deletes, carry none
+func (service *MedicationRequestService) commitWrite(ctx context.Context, medicationRequestID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.MedicationRequest, error)) (*fhir.MedicationRequest, error) {
+	var writtenMedicationRequest *models.MedicationRequest
+	var writtenFHIRMedicationRequest *fhir.MedicationRequest
+	var version int
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenMedicationRequest, writeError = write(transactionContext)
+		if writeError != nil {
+			return writeError
+		}
+
+		var snapshot interface{}
+		var compartmentPatientID string
+		if writtenMedicationRequest != nil {
+			medicationRequestID = writtenMedicationRequest.ID
+			writtenFHIRMedicationRequest = service.medicationRequestMapper.ToFHIR(writtenMedicationRequest)
+			snapshot = writtenFHIRMedicationRequest
+			compartmentPatientID = writtenMedicationRequest.PatientID
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "MedicationRequest", medicationRequestID, operation, snapshot, compartmentPatientID)
+		return recordError
+	})
+	if transactionError != nil {
+		return nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenMedicationRequest != nil {
+		resource = writtenMedicationRequest
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "MedicationRequest", medicationRequestID, operation, version, resource)
+	return writtenFHIRMedicationRequest, nil
+}
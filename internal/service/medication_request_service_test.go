@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockMedicationRequestRepository implements MedicationRequestRepository interface for testing
+type MockMedicationRequestRepository struct {
+	medicationRequests map[string]*models.MedicationRequest
+	deleted            map[string]bool
+}
+
+// NewMockMedicationRequestRepository creates a new mock repository for testing
+func NewMockMedicationRequestRepository() *MockMedicationRequestRepository {
+	return &MockMedicationRequestRepository{medicationRequests: make(map[string]*models.MedicationRequest), deleted: make(map[string]bool)}
+}
+
+// Create stores a medication request under a generated UUID
+func (mock *MockMedicationRequestRepository) Create(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error) {
+	medicationRequest.ID = uuid.NewString()
+	mock.medicationRequests[medicationRequest.ID] = medicationRequest
+	return medicationRequest, nil
+}
+
+// GetByID retrieves a stored medication request
+func (mock *MockMedicationRequestRepository) GetByID(ctx context.Context, medicationRequestID string) (*models.MedicationRequest, error) {
+	if mock.deleted[medicationRequestID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	medicationRequest, exists := mock.medicationRequests[medicationRequestID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return medicationRequest, nil
+}
+
+// Search returns every stored medication request
+func (mock *MockMedicationRequestRepository) Search(ctx context.Context, searchParams *models.MedicationRequestSearchParams) ([]*models.MedicationRequest, error) {
+	result := make([]*models.MedicationRequest, 0, len(mock.medicationRequests))
+	for _, medicationRequest := range mock.medicationRequests {
+		result = append(result, medicationRequest)
+	}
+	return result, nil
+}
+
+// Count returns how many medication requests are stored, as every search matches them all
+func (mock *MockMedicationRequestRepository) Count(ctx context.Context, searchParams *models.MedicationRequestSearchParams) (int, error) {
+	return len(mock.medicationRequests), nil
+}
+
+// Update replaces a stored medication request
+func (mock *MockMedicationRequestRepository) Update(ctx context.Context, medicationRequest *models.MedicationRequest) (*models.MedicationRequest, error) {
+	if mock.deleted[medicationRequest.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.medicationRequests[medicationRequest.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.medicationRequests[medicationRequest.ID] = medicationRequest
+	return medicationRequest, nil
+}
+
+// Delete marks a stored medication request deleted, as the repository does
+func (mock *MockMedicationRequestRepository) Delete(ctx context.Context, medicationRequestID string) error {
+	if mock.deleted[medicationRequestID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.medicationRequests[medicationRequestID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.medicationRequests, medicationRequestID)
+	mock.deleted[medicationRequestID] = true
+	return nil
+}
+
+// TestMedicationRequestService_Lifecycle verifies creates and updates are recorded in the subject's compartment and deletes in none
+func TestMedicationRequestService_Lifecycle(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	medicationRequestService := NewMedicationRequestService(NewMockMedicationRequestRepository())
+	medicationRequestService.SetChangeRepository(changeRepository)
+	ctx := context.Background()
+
+	patientID := uuid.NewString()
+	patientReference := "Patient/" + patientID
+	createdRequest, createError := medicationRequestService.CreateMedicationRequest(ctx, &fhir.MedicationRequest{
+		Status:  "active",
+		Intent:  "order",
+		Subject: fhir.Reference{Reference: &patientReference},
+	})
+	if createError != nil {
+		t.Fatalf("Expected no error creating the medication request, got %v", createError)
+	}
+
+	medicationRequestID := *createdRequest.Id
+	if _, updateError := medicationRequestService.UpdateMedicationRequest(ctx, medicationRequestID, &fhir.MedicationRequest{
+		Status:  "stopped",
+		Intent:  "order",
+		Subject: fhir.Reference{Reference: &patientReference},
+	}); updateError != nil {
+		t.Fatalf("Expected no error updating the medication request, got %v", updateError)
+	}
+	if deleteError := medicationRequestService.DeleteMedicationRequest(ctx, medicationRequestID); deleteError != nil {
+		t.Fatalf("Expected no error deleting the medication request, got %v", deleteError)
+	}
+	if _, getError := medicationRequestService.GetMedicationRequestByID(ctx, medicationRequestID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted reading the deleted medication request, got %v", getError)
+	}
+	if deleteError := medicationRequestService.DeleteMedicationRequest(ctx, medicationRequestID); !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	for index, expectedCompartment := range []string{patientID, patientID, ""} {
+		change := changeRepository.changes[index]
+		if change.ResourceType != "MedicationRequest" || change.ResourceID != medicationRequestID || change.CompartmentPatientID != expectedCompartment {
+			t.Errorf("Change %d: expected MedicationRequest/%s in compartment %q, got %+v", index, medicationRequestID, expectedCompartment, change)
+		}
+	}
+}
+
+// TestMedicationRequestService_RejectsInvalidCodes verifies a request without a valid status and intent is not stored
+func TestMedicationRequestService_RejectsInvalidCodes(t *testing.T) {
+	medicationRequestRepository := NewMockMedicationRequestRepository()
+	medicationRequestService := NewMedicationRequestService(medicationRequestRepository)
+
+	_, createError := medicationRequestService.CreateMedicationRequest(context.Background(), &fhir.MedicationRequest{Status: "pending", Intent: "order"})
+	var rejection *outcome.RejectionError
+	if !errors.As(createError, &rejection) || len(rejection.Issues) != 1 {
+		t.Fatalf("Expected a rejection for the unknown status, got %v", createError)
+	}
+	if len(medicationRequestRepository.medicationRequests) != 0 {
+		t.Errorf("Expected nothing stored, got %d medication requests", len(medicationRequestRepository.medicationRequests))
+	}
+}
@@ -2,16 +2,48 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/dedup"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/journal"
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/plausibility"
+	"github.com/nathannewyen/fhir-health-interop/internal/referencerange"
 	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/nathannewyen/fhir-health-interop/internal/terminology"
+	"github.com/nathannewyen/fhir-health-interop/internal/tracing"
+	"github.com/nathannewyen/fhir-health-interop/internal/units"
+	"github.com/rs/zerolog/log"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
+// ErrVersionConflict is returned when a write names a version the resource is no longer at
+var ErrVersionConflict = errors.New("resource version conflict")
+
 // ObservationService handles business logic for Observation resources
 type ObservationService struct {
 	observationRepository repository.ObservationRepository
 	observationMapper     *models.ObservationMapper
+	changeRepository      repository.ChangeRepository
+	eventPublisher        events.Publisher
+	strictMapping         bool
+	ledgerRepository      repository.LedgerRepository
+	deletionGuard         DeletionGuard
+	plausibilityChecker   *plausibility.Checker
+	searchGroup           *dedup.Group
+	writeJournal          repository.WriteJournalRepository
+	terminology           *terminology.Terminology
+	codeValidation        *codeValidation
+	unitNormalization     *unitNormalization
+	interpreter           *referencerange.Interpreter
+	subjectPatients       repository.PatientRepository
 }
 
 // NewObservationService creates a new observation service instance
@@ -22,23 +54,169 @@ func NewObservationService(observationRepository repository.ObservationRepositor
 	}
 }
 
+// SetSearchGroup collapses identical concurrent observation searches into one repository call
+func (service *ObservationService) SetSearchGroup(searchGroup *dedup.Group) {
+	service.searchGroup = searchGroup
+}
+
+// SetChangeRepository enables recording observation writes to the change log
+func (service *ObservationService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing observation write events to internal consumers
+func (service *ObservationService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetLedgerRepository enables maintaining expected per-patient observation counts for reconciliation
+func (service *ObservationService) SetLedgerRepository(ledgerRepository repository.LedgerRepository) {
+	service.ledgerRepository = ledgerRepository
+}
+
+// SetDeletionGuard refuses deleting observations whose patient is under legal hold
+func (service *ObservationService) SetDeletionGuard(deletionGuard DeletionGuard) {
+	service.deletionGuard = deletionGuard
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *ObservationService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// SetPlausibilityChecker enables rejecting, flagging, or logging implausible vital-sign values on write
+func (service *ObservationService) SetPlausibilityChecker(checker *plausibility.Checker) {
+	service.plausibilityChecker = checker
+}
+
+// SetTerminology enables filling in code and category displays that writes leave out
+func (service *ObservationService) SetTerminology(codeTerminology *terminology.Terminology) {
+	service.terminology = codeTerminology
+}
+
+// SetCodeValidation enables checking observation and component codes with lookup on write; action is
+// terminology.ValidationReject to fail writes with invalid codes or terminology.ValidationWarn to store them
+// with a warning
+func (service *ObservationService) SetCodeValidation(lookup terminology.CodeSystemLookup, action string) {
+	service.codeValidation = &codeValidation{lookup: lookup, action: action}
+}
+
+// SetUnitNormalization enables checking and converting the units of observation values with converter on write
+// action is units.ValidationReject to fail writes with unknown units, units.ValidationWarn to store them with a
+// warning, or empty to leave units unchecked. With storeCanonical, each value's canonical value and unit are
+// stored next to it and value-quantity searches in a unit also compare canonical values
+func (service *ObservationService) SetUnitNormalization(converter *units.Converter, action string, storeCanonical bool) {
+	service.unitNormalization = &unitNormalization{converter: converter, action: action, storeCanonical: storeCanonical}
+}
+
+// SetInterpreter enables computing the interpretation of observations written without one from reference ranges
+func (service *ObservationService) SetInterpreter(interpreter *referencerange.Interpreter) {
+	service.interpreter = interpreter
+}
+
+// SetReferentialIntegrity rejects creates and updates whose subject patient does not exist in patientRepository
+func (service *ObservationService) SetReferentialIntegrity(patientRepository repository.PatientRepository) {
+	service.subjectPatients = patientRepository
+}
+
+// SetWriteJournal enables journaling each write before it is applied, so a write interrupted by a crash
+// before its change was recorded is completed or rolled back by RecoverOldest
+func (service *ObservationService) SetWriteJournal(writeJournal repository.WriteJournalRepository) {
+	service.writeJournal = writeJournal
+}
+
+// AddMappingHook registers a site-specific hook on the observation mapper
+func (service *ObservationService) AddMappingHook(hook models.ObservationMappingHook) {
+	service.observationMapper.AddHook(hook)
+}
+
+// ObservationToFHIR converts a stored observation the way reads return it, running the site mapping hooks
+// Event consumers use it to send the observations carried by write events
+func (service *ObservationService) ObservationToFHIR(observation *models.Observation) *fhir.Observation {
+	return service.observationMapper.ToFHIR(observation)
+}
+
 // CreateObservation creates a new observation from FHIR resource
 func (service *ObservationService) CreateObservation(ctx context.Context, fhirObservation *fhir.Observation) (*fhir.Observation, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.CreateObservation")
+	defer span.End()
+
 	// Convert FHIR to domain model
-	observation := service.observationMapper.FromFHIR(fhirObservation)
+	observation, mappingIssues := service.observationMapper.FromFHIR(fhirObservation)
+	if issuesError := handleMappingIssues(ctx, "Observation", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	if plausibilityError := applyPlausibility(ctx, service.plausibilityChecker, observation); plausibilityError != nil {
+		return nil, plausibilityError
+	}
+	if codeError := applyCodeValidation(ctx, service.codeValidation, observation); codeError != nil {
+		return nil, codeError
+	}
+	if unitError := applyUnitNormalization(ctx, service.unitNormalization, observation); unitError != nil {
+		return nil, unitError
+	}
+	computeInterpretation(service.interpreter, observation)
+	backfillDisplays(service.terminology, observation)
+	if subjectError := service.checkSubject(ctx, observation); subjectError != nil {
+		return nil, subjectError
+	}
 
-	// Create in repository
-	createdObservation, createError := service.observationRepository.Create(ctx, observation)
+	// The server assigns IDs; a journaled create needs its ID before the observation is stored
+	observation.ID = ""
+	if service.writeJournal != nil {
+		observation.ID = repository.NewObservationID()
+	}
+
+	// Create in repository and record the write
+	createdObservation, createdFHIRObservation, createError := service.commitWrite(ctx, observation.ID, "", models.ChangeOperationCreate, func(writeContext context.Context) (*models.Observation, error) {
+		return service.observationRepository.Create(writeContext, observation)
+	})
 	if createError != nil {
 		return nil, createError
 	}
 
-	// Convert back to FHIR
-	return service.observationMapper.ToFHIR(createdObservation), nil
+	reportIgnoredElements(ctx, "Observation", fhirObservation, createdFHIRObservation, mappingIssues)
+	service.adjustLedger(ctx, createdObservation.PatientID, 1)
+
+	// MongoDB cannot join a multi-write transaction, so a rolled-back transaction removes the observation again
+	if scope := transactionScopeFrom(ctx); scope != nil {
+		scope.onRollback(func(undoContext context.Context) {
+			service.undoUnrecordedWrite(undoContext, createdObservation.ID, models.ChangeOperationCreate)
+			service.adjustLedger(undoContext, createdObservation.PatientID, -1)
+		})
+	}
+
+	return createdFHIRObservation, nil
+}
+
+// checkSubject rejects the observation when referential integrity is on and its subject patient does not exist
+// Observations without a subject are left to validation
+func (service *ObservationService) checkSubject(ctx context.Context, observation *models.Observation) error {
+	if service.subjectPatients == nil || observation.PatientID == "" {
+		return nil
+	}
+
+	missingSubject := &outcome.RejectionError{Issues: []outcome.Issue{
+		outcome.Error(fhir.IssueTypeNotFound, "Observation.subject references a patient that does not exist", "Observation.subject"),
+	}}
+	if _, parseError := uuid.Parse(observation.PatientID); parseError != nil {
+		return missingSubject
+	}
+	_, getError := service.subjectPatients.GetByID(ctx, observation.PatientID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return missingSubject
+	}
+	if getError != nil {
+		return fmt.Errorf("failed to look up subject patient: %w", getError)
+	}
+	return nil
 }
 
 // GetObservationByID retrieves an observation by ID
 func (service *ObservationService) GetObservationByID(ctx context.Context, observationID string) (*fhir.Observation, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.GetObservationByID")
+	defer span.End()
+
 	// Get from repository
 	observation, getError := service.observationRepository.GetByID(ctx, observationID)
 	if getError != nil {
@@ -51,6 +229,9 @@ func (service *ObservationService) GetObservationByID(ctx context.Context, obser
 
 // GetObservationsByPatientID retrieves all observations for a patient
 func (service *ObservationService) GetObservationsByPatientID(ctx context.Context, patientID string, limit int, offset int) ([]*fhir.Observation, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.GetObservationsByPatientID")
+	defer span.End()
+
 	// Get from repository
 	observations, getError := service.observationRepository.GetByPatientID(ctx, patientID, limit, offset)
 	if getError != nil {
@@ -68,6 +249,9 @@ func (service *ObservationService) GetObservationsByPatientID(ctx context.Contex
 
 // GetAllObservations retrieves all observations with pagination
 func (service *ObservationService) GetAllObservations(ctx context.Context, limit int, offset int) ([]*fhir.Observation, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.GetAllObservations")
+	defer span.End()
+
 	// Get from repository
 	observations, getError := service.observationRepository.GetAll(ctx, limit, offset)
 	if getError != nil {
@@ -84,27 +268,139 @@ func (service *ObservationService) GetAllObservations(ctx context.Context, limit
 }
 
 // UpdateObservation updates an existing observation
-func (service *ObservationService) UpdateObservation(ctx context.Context, observationID string, fhirObservation *fhir.Observation) (*fhir.Observation, error) {
+// A non-zero expectedVersion makes the update conditional: it fails with ErrVersionConflict unless the
+// stored observation is still at that version, so concurrent editors cannot overwrite each other
+func (service *ObservationService) UpdateObservation(ctx context.Context, observationID string, fhirObservation *fhir.Observation, expectedVersion int) (*fhir.Observation, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.UpdateObservation")
+	defer span.End()
+
 	// Convert FHIR to domain model
-	observation := service.observationMapper.FromFHIR(fhirObservation)
+	observation, mappingIssues := service.observationMapper.FromFHIR(fhirObservation)
+	if issuesError := handleMappingIssues(ctx, "Observation", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	if plausibilityError := applyPlausibility(ctx, service.plausibilityChecker, observation); plausibilityError != nil {
+		return nil, plausibilityError
+	}
+	if codeError := applyCodeValidation(ctx, service.codeValidation, observation); codeError != nil {
+		return nil, codeError
+	}
+	if unitError := applyUnitNormalization(ctx, service.unitNormalization, observation); unitError != nil {
+		return nil, unitError
+	}
+	computeInterpretation(service.interpreter, observation)
+	backfillDisplays(service.terminology, observation)
+	if subjectError := service.checkSubject(ctx, observation); subjectError != nil {
+		return nil, subjectError
+	}
 	observation.ID = observationID
+	observation.Version = expectedVersion
+
+	// Remember the current patient so the ledger can follow a reassignment
+	previousPatientID := service.ledgerPatientID(ctx, observationID)
 
-	// Update in repository
-	updatedObservation, updateError := service.observationRepository.Update(ctx, observation)
+	// Update in repository and record the write
+	updatedObservation, updatedFHIRObservation, updateError := service.commitWrite(ctx, observationID, previousPatientID, models.ChangeOperationUpdate, func(writeContext context.Context) (*models.Observation, error) {
+		return service.observationRepository.Update(writeContext, observation)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, repository.ErrObservationNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	if errors.Is(updateError, repository.ErrObservationVersionConflict) {
+		return nil, ErrVersionConflict
+	}
 	if updateError != nil {
 		return nil, updateError
 	}
 
-	// Convert back to FHIR
-	return service.observationMapper.ToFHIR(updatedObservation), nil
+	reportIgnoredElements(ctx, "Observation", fhirObservation, updatedFHIRObservation, mappingIssues)
+	if previousPatientID != updatedObservation.PatientID {
+		service.adjustLedger(ctx, previousPatientID, -1)
+		service.adjustLedger(ctx, updatedObservation.PatientID, 1)
+	}
+
+	return updatedFHIRObservation, nil
+}
+
+// ReassignObservation moves an observation to another patient, such as the target of a patient merge, and
+// records the update like UpdateObservation
+// The update is conditional on the version read, so an edit made meanwhile fails it with ErrVersionConflict
+func (service *ObservationService) ReassignObservation(ctx context.Context, observationID string, patientID string) error {
+	ctx, span := tracing.Start(ctx, "ObservationService.ReassignObservation")
+	defer span.End()
+
+	observation, getError := service.observationRepository.GetByID(ctx, observationID)
+	if errors.Is(getError, repository.ErrObservationNotFound) {
+		return ErrResourceNotFound
+	}
+	if getError != nil {
+		return getError
+	}
+	previousPatientID := observation.PatientID
+	if previousPatientID == patientID {
+		return nil
+	}
+	observation.PatientID = patientID
+	observation.Version = observation.CurrentVersion()
+
+	_, _, updateError := service.commitWrite(ctx, observationID, previousPatientID, models.ChangeOperationUpdate, func(writeContext context.Context) (*models.Observation, error) {
+		return service.observationRepository.Update(writeContext, observation)
+	})
+	if errors.Is(updateError, repository.ErrObservationNotFound) {
+		return ErrResourceNotFound
+	}
+	if errors.Is(updateError, repository.ErrObservationVersionConflict) {
+		return ErrVersionConflict
+	}
+	if updateError != nil {
+		return updateError
+	}
+
+	service.adjustLedger(ctx, previousPatientID, -1)
+	service.adjustLedger(ctx, patientID, 1)
+	return nil
 }
 
 // SearchObservations retrieves observations matching the search criteria
 func (service *ObservationService) SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error) {
-	// Search in repository
-	observations, searchError := service.observationRepository.Search(ctx, searchParams)
+	fhirObservations, _, searchError := service.SearchObservationsPage(ctx, searchParams)
+	return fhirObservations, searchError
+}
+
+// SearchObservationsPage searches for observations like SearchObservations and, for a cursor-paged search
+// with more matches after this page, returns the cursor continuing it
+// One extra match is read to tell whether another page follows, so the last page has no cursor
+func (service *ObservationService) SearchObservationsPage(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, *models.PageCursor, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.SearchObservations")
+	defer span.End()
+	searchParams = canonicalSearchParams(service.unitNormalization, searchParams)
+
+	pageSize := searchParams.Limit
+	if searchParams.CursorPaging && pageSize > 0 {
+		lookaheadParams := *searchParams
+		lookaheadParams.Limit = pageSize + 1
+		searchParams = &lookaheadParams
+	}
+
+	// Search in repository, sharing the query with identical concurrent searches
+	observations, shared, searchError := dedup.Do(ctx, service.searchGroup, "Observation", searchParams, func(searchContext context.Context) ([]*models.Observation, error) {
+		return service.observationRepository.Search(searchContext, searchParams)
+	})
 	if searchError != nil {
-		return nil, searchError
+		return nil, nil, searchError
+	}
+	if shared {
+		observations = copyObservations(observations)
+	}
+
+	var nextCursor *models.PageCursor
+	if searchParams.CursorPaging && pageSize > 0 && len(observations) > pageSize {
+		observations = observations[:pageSize]
+		lastObservation := observations[pageSize-1]
+		nextCursor = &models.PageCursor{CreatedAt: lastObservation.CreatedAt, ID: lastObservation.ID}
 	}
 
 	// Convert to FHIR
@@ -113,10 +409,388 @@ func (service *ObservationService) SearchObservations(ctx context.Context, searc
 		fhirObservations = append(fhirObservations, service.observationMapper.ToFHIR(observation))
 	}
 
+	return fhirObservations, nextCursor, nil
+}
+
+// StreamObservations calls visit with each observation matching the search in FHIR format, reading them from
+// the database one at a time instead of holding every match; an error from visit stops the stream
+// Streams are not shared between identical searches, since each caller consumes its results at its own pace
+func (service *ObservationService) StreamObservations(ctx context.Context, searchParams *models.ObservationSearchParams, visit func(fhirObservation *fhir.Observation) error) error {
+	ctx, span := tracing.Start(ctx, "ObservationService.StreamObservations")
+	defer span.End()
+	searchParams = canonicalSearchParams(service.unitNormalization, searchParams)
+
+	return service.observationRepository.Stream(ctx, searchParams, func(observation *models.Observation) error {
+		return visit(service.observationMapper.ToFHIR(observation))
+	})
+}
+
+// CountObservations returns how many observations match the search across all pages, used as the searchset total
+func (service *ObservationService) CountObservations(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.CountObservations")
+	defer span.End()
+	searchParams = canonicalSearchParams(service.unitNormalization, searchParams)
+
+	return service.observationRepository.Count(ctx, searchParams)
+}
+
+// LastObservations returns the patient's most recent observations of each code, for Observation/$lastn
+func (service *ObservationService) LastObservations(ctx context.Context, lastNParams *models.ObservationLastNParams) ([]*fhir.Observation, error) {
+	ctx, span := tracing.Start(ctx, "ObservationService.LastObservations")
+	defer span.End()
+
+	observations, lastNError := service.observationRepository.LastN(ctx, lastNParams)
+	if lastNError != nil {
+		return nil, lastNError
+	}
+
+	fhirObservations := make([]*fhir.Observation, 0, len(observations))
+	for _, observation := range observations {
+		fhirObservations = append(fhirObservations, service.observationMapper.ToFHIR(observation))
+	}
+
 	return fhirObservations, nil
 }
 
 // DeleteObservation deletes an observation by ID
+// The stored observation is read once and its patient shared by the legal hold check and the ledger
 func (service *ObservationService) DeleteObservation(ctx context.Context, observationID string) error {
-	return service.observationRepository.Delete(ctx, observationID)
+	ctx, span := tracing.Start(ctx, "ObservationService.DeleteObservation")
+	defer span.End()
+
+	previousPatientID := ""
+	if service.ledgerRepository != nil || service.deletionGuard != nil {
+		previousPatientID = service.storedPatientID(ctx, observationID)
+	}
+	if service.deletionGuard != nil {
+		if guardError := service.deletionGuard.CheckDeletable(ctx, previousPatientID, "Observation", observationID); guardError != nil {
+			return guardError
+		}
+	}
+
+	_, _, deleteError := service.commitWrite(ctx, observationID, previousPatientID, models.ChangeOperationDelete, func(writeContext context.Context) (*models.Observation, error) {
+		return nil, service.observationRepository.Delete(writeContext, observationID)
+	})
+	if deleteError != nil {
+		return deleteError
+	}
+	service.adjustLedger(ctx, previousPatientID, -1)
+
+	return nil
+}
+
+// AddObservationTags merges tags into the observation's meta.tag and returns the resulting tags
+// Tags are workflow labels outside the resource content. As with FHIR $meta-add, changing them creates no
+// version, so nothing is written to the change log or published: a change log entry is a version whose
+// snapshot would differ only in meta. Consumers that react to tags search with _tag instead
+func (service *ObservationService) AddObservationTags(ctx context.Context, observationID string, tags models.Tags) (models.Tags, error) {
+	return service.updateObservationTags(ctx, observationID, func(currentTags models.Tags) models.Tags {
+		return currentTags.Add(tags...)
+	})
+}
+
+// RemoveObservationTags removes tags from the observation's meta.tag and returns the remaining tags
+func (service *ObservationService) RemoveObservationTags(ctx context.Context, observationID string, tags models.Tags) (models.Tags, error) {
+	return service.updateObservationTags(ctx, observationID, func(currentTags models.Tags) models.Tags {
+		return currentTags.Remove(tags...)
+	})
+}
+
+// updateObservationTags applies a tag change, reporting ErrResourceNotFound for unknown observations
+func (service *ObservationService) updateObservationTags(ctx context.Context, observationID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	updatedTags, updateError := service.observationRepository.UpdateTags(ctx, observationID, change)
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, repository.ErrObservationNotFound) {
+		return nil, ErrResourceNotFound
+	}
+	return updatedTags, updateError
+}
+
+// commitWrite runs write and records it in the change log, then publishes it to event consumers
+// MongoDB cannot join the change log's Postgres transaction, so the change is recorded in a transaction
+// opened before the write and committed only after it succeeds. When the change cannot be recorded the
+// error is returned; a create is undone, while an update or delete stays applied and is logged for repair
+// With a write journal the write is journaled first and the entry removed in the change's transaction, so a
+// crash at any point leaves the entry for RecoverOldest; previousPatientID is kept with it for the ledger
+func (service *ObservationService) commitWrite(ctx context.Context, observationID string, previousPatientID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.Observation, error)) (*models.Observation, *fhir.Observation, error) {
+	journalEntry, journalError := service.journalWrite(ctx, observationID, previousPatientID, operation)
+	if journalError != nil {
+		return nil, nil, journalError
+	}
+
+	var writtenObservation *models.Observation
+	var writtenFHIRObservation *fhir.Observation
+	var version int
+	writeApplied := false
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenObservation, writeError = write(ctx)
+		if writeError != nil {
+			return writeError
+		}
+		writeApplied = true
+
+		var snapshot interface{}
+		var compartmentPatientID string
+		if writtenObservation != nil {
+			observationID = writtenObservation.ID
+			writtenFHIRObservation = service.observationMapper.ToFHIR(writtenObservation)
+			snapshot = writtenFHIRObservation
+			compartmentPatientID = writtenObservation.PatientID
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "Observation", observationID, operation, snapshot, compartmentPatientID)
+		if recordError != nil {
+			return recordError
+		}
+		return service.completeJournalEntry(transactionContext, journalEntry)
+	})
+	if transactionError != nil {
+		if writeApplied {
+			service.undoUnrecordedWrite(ctx, observationID, operation)
+		} else {
+			service.discardJournalEntry(ctx, journalEntry)
+		}
+		return nil, nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenObservation != nil {
+		resource = writtenObservation
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "Observation", observationID, operation, version, resource)
+	return writtenObservation, writtenFHIRObservation, nil
+}
+
+// recordMovedObservations records observations moved out of or back into the store by a patient erasure as
+// deletes or creates in the change log, publishes them, and adjusts the ledger
+// The observations have already moved, so a failure to record them is returned for the caller to compensate
+func (service *ObservationService) recordMovedObservations(ctx context.Context, observations []*models.Observation, operation models.ChangeOperation) error {
+	versions := make([]int, len(observations))
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		for index, observation := range observations {
+			var snapshot interface{}
+			var compartmentPatientID string
+			if operation != models.ChangeOperationDelete {
+				snapshot = service.observationMapper.ToFHIR(observation)
+				compartmentPatientID = observation.PatientID
+			}
+
+			var recordError error
+			versions[index], recordError = recordChange(transactionContext, service.changeRepository, "Observation", observation.ID, operation, snapshot, compartmentPatientID)
+			if recordError != nil {
+				return recordError
+			}
+		}
+		return nil
+	})
+	if transactionError != nil {
+		return transactionError
+	}
+
+	ledgerDelta := int64(1)
+	if operation == models.ChangeOperationDelete {
+		ledgerDelta = -1
+	}
+	ledgerDeltas := map[string]int64{}
+	for index, observation := range observations {
+		var resource interface{}
+		if operation != models.ChangeOperationDelete {
+			resource = observation
+		}
+		publishWriteEvent(ctx, service.eventPublisher, "Observation", observation.ID, operation, versions[index], resource)
+		ledgerDeltas[observation.PatientID] += ledgerDelta
+	}
+	for patientID, delta := range ledgerDeltas {
+		service.adjustLedger(ctx, patientID, delta)
+	}
+	return nil
+}
+
+// undoUnrecordedWrite removes a created observation whose change could not be recorded
+// Updates and deletes cannot be undone without the previous version, so they are logged for repair;
+// with a write journal their entry stays and recovery records them
+func (service *ObservationService) undoUnrecordedWrite(ctx context.Context, observationID string, operation models.ChangeOperation) {
+	if operation == models.ChangeOperationCreate {
+		if deleteError := service.observationRepository.Delete(ctx, observationID); deleteError == nil {
+			return
+		}
+	}
+
+	log.Error().
+		Str("observation_id", observationID).
+		Str("operation", string(operation)).
+		Bool("journaled", service.writeJournal != nil).
+		Msg("Observation write is stored but missing from the change log")
+}
+
+// journalWrite durably records an accepted write before it is applied; nil without a write journal
+func (service *ObservationService) journalWrite(ctx context.Context, observationID string, previousPatientID string, operation models.ChangeOperation) (*models.WriteJournalEntry, error) {
+	if service.writeJournal == nil {
+		return nil, nil
+	}
+
+	journalEntry, appendError := service.writeJournal.Append(ctx, &models.WriteJournalEntry{
+		ResourceType:      "Observation",
+		ResourceID:        observationID,
+		Operation:         operation,
+		TenantID:          tenant.FromContext(ctx),
+		PreviousPatientID: previousPatientID,
+	})
+	if appendError != nil {
+		return nil, fmt.Errorf("failed to journal observation write: %w", appendError)
+	}
+	return journalEntry, nil
+}
+
+// completeJournalEntry removes a journaled write's entry in the transaction that records its change
+func (service *ObservationService) completeJournalEntry(ctx context.Context, journalEntry *models.WriteJournalEntry) error {
+	if journalEntry == nil {
+		return nil
+	}
+	return service.writeJournal.Complete(ctx, journalEntry.ID)
+}
+
+// discardJournalEntry removes the entry of a write that failed without being applied
+// An entry left behind is harmless: recovery finds the write was not applied and rolls it back
+func (service *ObservationService) discardJournalEntry(ctx context.Context, journalEntry *models.WriteJournalEntry) {
+	if journalEntry == nil {
+		return
+	}
+	if completeError := service.writeJournal.Complete(ctx, journalEntry.ID); completeError != nil {
+		log.Warn().Err(completeError).Int64("journal_entry_id", journalEntry.ID).Msg("Failed to discard write journal entry")
+	}
+}
+
+// RecoverOldest completes or rolls back the oldest observation write journaled before cutoff
+// The entry is claimed and removed in the transaction that records the change, so concurrent recoveries
+// never handle the same write. Whether the write was applied is read from the store: a created observation
+// exists, an updated one was updated after the write was journaled, and a deleted one is gone. An applied
+// write is recorded with the stored observation as its snapshot, published, and counted in the ledger;
+// one that was not applied has nothing to undo and its entry is discarded
+func (service *ObservationService) RecoverOldest(ctx context.Context, cutoff time.Time) (journal.Outcome, error) {
+	if service.writeJournal == nil {
+		return journal.OutcomeNone, nil
+	}
+
+	outcome := journal.OutcomeNone
+	var recoveredEntry *models.WriteJournalEntry
+	var storedObservation *models.Observation
+	var version int
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var claimError error
+		recoveredEntry, claimError = service.writeJournal.ClaimOldest(transactionContext, cutoff)
+		if claimError != nil || recoveredEntry == nil {
+			return claimError
+		}
+		transactionContext = tenant.WithTenant(transactionContext, recoveredEntry.TenantID)
+
+		var getError error
+		storedObservation, getError = service.observationRepository.GetByID(ctx, recoveredEntry.ResourceID)
+		if getError != nil && !errors.Is(getError, repository.ErrObservationNotFound) {
+			return getError
+		}
+
+		outcome = journal.OutcomeRolledBack
+		if journaledWriteApplied(recoveredEntry, storedObservation) {
+			outcome = journal.OutcomeCompleted
+
+			var snapshot interface{}
+			var compartmentPatientID string
+			if storedObservation != nil {
+				snapshot = service.observationMapper.ToFHIR(storedObservation)
+				compartmentPatientID = storedObservation.PatientID
+			}
+			var recordError error
+			version, recordError = recordChange(transactionContext, service.changeRepository, "Observation", recoveredEntry.ResourceID, recoveredEntry.Operation, snapshot, compartmentPatientID)
+			if recordError != nil {
+				return recordError
+			}
+		}
+		return service.writeJournal.Complete(transactionContext, recoveredEntry.ID)
+	})
+	if transactionError != nil {
+		return journal.OutcomeNone, transactionError
+	}
+	if outcome != journal.OutcomeCompleted {
+		return outcome, nil
+	}
+
+	recoveredContext := tenant.WithTenant(ctx, recoveredEntry.TenantID)
+	var resource interface{}
+	if recoveredEntry.Operation != models.ChangeOperationDelete {
+		resource = storedObservation
+	}
+	publishWriteEvent(recoveredContext, service.eventPublisher, "Observation", recoveredEntry.ResourceID, recoveredEntry.Operation, version, resource)
+
+	switch recoveredEntry.Operation {
+	case models.ChangeOperationCreate:
+		service.adjustLedger(recoveredContext, storedObservation.PatientID, 1)
+	case models.ChangeOperationDelete:
+		service.adjustLedger(recoveredContext, recoveredEntry.PreviousPatientID, -1)
+	case models.ChangeOperationUpdate:
+		if recoveredEntry.PreviousPatientID != storedObservation.PatientID {
+			service.adjustLedger(recoveredContext, recoveredEntry.PreviousPatientID, -1)
+			service.adjustLedger(recoveredContext, storedObservation.PatientID, 1)
+		}
+	}
+
+	log.Warn().
+		Str("observation_id", recoveredEntry.ResourceID).
+		Str("operation", string(recoveredEntry.Operation)).
+		Time("journaled_at", recoveredEntry.CreatedAt).
+		Msg("Completed observation write interrupted before its change was recorded")
+	return outcome, nil
+}
+
+// journaledWriteApplied reports whether the store shows the journaled write, given the observation stored
+// under its ID now (nil when there is none)
+// Writes stamp updated_at after they are journaled, so an update applied or superseded since shows a later one
+// MongoDB keeps milliseconds, so the journal time is compared at that precision
+func journaledWriteApplied(journalEntry *models.WriteJournalEntry, storedObservation *models.Observation) bool {
+	switch journalEntry.Operation {
+	case models.ChangeOperationCreate:
+		return storedObservation != nil
+	case models.ChangeOperationUpdate:
+		return storedObservation != nil && !storedObservation.UpdatedAt.Before(journalEntry.CreatedAt.Truncate(time.Millisecond))
+	default:
+		return storedObservation == nil
+	}
+}
+
+// ledgerPatientID returns the stored observation's patient when a ledger is configured
+func (service *ObservationService) ledgerPatientID(ctx context.Context, observationID string) string {
+	if service.ledgerRepository == nil {
+		return ""
+	}
+	return service.storedPatientID(ctx, observationID)
+}
+
+// storedPatientID returns the stored observation's patient
+// A missing observation yields no patient and is left for the write itself to report
+func (service *ObservationService) storedPatientID(ctx context.Context, observationID string) string {
+	existingObservation, getError := service.observationRepository.GetByID(ctx, observationID)
+	if getError != nil {
+		return ""
+	}
+	return existingObservation.PatientID
+}
+
+// adjustLedger applies a count change to the patient's ledger entry
+// Failures are logged rather than returned; reconciliation reports the resulting drift
+func (service *ObservationService) adjustLedger(ctx context.Context, patientID string, delta int64) {
+	if service.ledgerRepository == nil || patientID == "" {
+		return
+	}
+
+	if adjustError := service.ledgerRepository.Adjust(ctx, patientID, delta); adjustError != nil {
+		log.Warn().
+			Err(adjustError).
+			Str("patient_id", patientID).
+			Int64("delta", delta).
+			Msg("Failed to update observation ledger")
+	}
 }
@@ -3,23 +3,28 @@ package service
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
 // MockObservationRepository implements ObservationRepository for testing
 type MockObservationRepository struct {
-	observations  map[string]*models.Observation
-	createError   error
-	getByIDError  error
-	getAllError   error
+	observations      map[string]*models.Observation
+	createError       error
+	getByIDError      error
+	getAllError       error
 	getByPatientError error
-	updateError   error
-	deleteError   error
-	lastCreated   *models.Observation
+	updateError       error
+	deleteError       error
+	lastCreated       *models.Observation
+	getByIDCalls      int
 }
 
 func NewMockObservationRepository() *MockObservationRepository {
@@ -32,7 +37,9 @@ func (mock *MockObservationRepository) Create(ctx context.Context, observation *
 	if mock.createError != nil {
 		return nil, mock.createError
 	}
-	observation.ID = "generated-mongo-id-123"
+	if observation.ID == "" {
+		observation.ID = "generated-mongo-id-123"
+	}
 	observation.CreatedAt = time.Now()
 	observation.UpdatedAt = time.Now()
 	mock.observations[observation.ID] = observation
@@ -41,12 +48,13 @@ func (mock *MockObservationRepository) Create(ctx context.Context, observation *
 }
 
 func (mock *MockObservationRepository) GetByID(ctx context.Context, observationID string) (*models.Observation, error) {
+	mock.getByIDCalls++
 	if mock.getByIDError != nil {
 		return nil, mock.getByIDError
 	}
 	observation, exists := mock.observations[observationID]
 	if !exists {
-		return nil, errors.New("observation not found")
+		return nil, repository.ErrObservationNotFound
 	}
 	return observation, nil
 }
@@ -79,6 +87,14 @@ func (mock *MockObservationRepository) Update(ctx context.Context, observation *
 	if mock.updateError != nil {
 		return nil, mock.updateError
 	}
+	storedVersion := 1
+	if storedObservation, exists := mock.observations[observation.ID]; exists {
+		storedVersion = storedObservation.CurrentVersion()
+	}
+	if observation.Version != 0 && observation.Version != storedVersion {
+		return nil, repository.ErrObservationVersionConflict
+	}
+	observation.Version = storedVersion + 1
 	observation.UpdatedAt = time.Now()
 	mock.observations[observation.ID] = observation
 	return observation, nil
@@ -95,6 +111,26 @@ func (mock *MockObservationRepository) Search(ctx context.Context, searchParams
 	return result, nil
 }
 
+func (mock *MockObservationRepository) Count(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error) {
+	if mock.getAllError != nil {
+		return 0, mock.getAllError
+	}
+	return len(mock.observations), nil
+}
+
+func (mock *MockObservationRepository) Stream(ctx context.Context, searchParams *models.ObservationSearchParams, visit func(observation *models.Observation) error) error {
+	results, searchError := mock.Search(ctx, searchParams)
+	if searchError != nil {
+		return searchError
+	}
+	for _, result := range results {
+		if visitError := visit(result); visitError != nil {
+			return visitError
+		}
+	}
+	return nil
+}
+
 func (mock *MockObservationRepository) Delete(ctx context.Context, observationID string) error {
 	if mock.deleteError != nil {
 		return mock.deleteError
@@ -103,6 +139,44 @@ func (mock *MockObservationRepository) Delete(ctx context.Context, observationID
 	return nil
 }
 
+func (mock *MockObservationRepository) UpdateTags(ctx context.Context, observationID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	observation, exists := mock.observations[observationID]
+	if !exists {
+		return nil, repository.ErrObservationNotFound
+	}
+	observation.Tags = change(observation.Tags)
+	return observation.Tags, nil
+}
+
+// LastN returns the patient's newest Max observations of each code, ordered by code and then newest first
+func (mock *MockObservationRepository) LastN(ctx context.Context, lastNParams *models.ObservationLastNParams) ([]*models.Observation, error) {
+	if mock.getAllError != nil {
+		return nil, mock.getAllError
+	}
+	patientObservations := make([]*models.Observation, 0)
+	for _, observation := range mock.observations {
+		if observation.PatientID == lastNParams.PatientID {
+			patientObservations = append(patientObservations, observation)
+		}
+	}
+	sort.Slice(patientObservations, func(first int, second int) bool {
+		if patientObservations[first].Code != patientObservations[second].Code {
+			return patientObservations[first].Code < patientObservations[second].Code
+		}
+		return patientObservations[first].EffectiveDate.After(*patientObservations[second].EffectiveDate)
+	})
+
+	result := make([]*models.Observation, 0)
+	keptPerCode := make(map[string]int)
+	for _, observation := range patientObservations {
+		if keptPerCode[observation.Code] < lastNParams.Max {
+			keptPerCode[observation.Code]++
+			result = append(result, observation)
+		}
+	}
+	return result, nil
+}
+
 // TestObservationService_CreateObservation verifies observation creation
 func TestObservationService_CreateObservation(t *testing.T) {
 	mockRepo := NewMockObservationRepository()
@@ -294,7 +368,7 @@ func TestObservationService_UpdateObservation(t *testing.T) {
 		},
 	}
 
-	updatedObservation, updateError := observationService.UpdateObservation(context.Background(), "update-id", fhirObservation)
+	updatedObservation, updateError := observationService.UpdateObservation(context.Background(), "update-id", fhirObservation, 0)
 
 	if updateError != nil {
 		t.Fatalf("Expected no error, got %v", updateError)
@@ -318,7 +392,7 @@ func TestObservationService_UpdateObservation_Error(t *testing.T) {
 		},
 	}
 
-	updatedObservation, updateError := observationService.UpdateObservation(context.Background(), "test-id", fhirObservation)
+	updatedObservation, updateError := observationService.UpdateObservation(context.Background(), "test-id", fhirObservation, 0)
 
 	if updateError == nil {
 		t.Error("Expected error, got nil")
@@ -328,6 +402,25 @@ func TestObservationService_UpdateObservation_Error(t *testing.T) {
 	}
 }
 
+// TestObservationService_UpdateObservation_VersionConflict verifies an update naming a stale version is refused
+func TestObservationService_UpdateObservation_VersionConflict(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	mockRepo.observations["versioned-id"] = &models.Observation{ID: "versioned-id", Status: "preliminary", Version: 2}
+
+	if _, updateError := observationService.UpdateObservation(context.Background(), "versioned-id", &fhir.Observation{Status: fhir.ObservationStatusFinal}, 1); !errors.Is(updateError, ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict for a stale version, got %v", updateError)
+	}
+
+	updatedObservation, updateError := observationService.UpdateObservation(context.Background(), "versioned-id", &fhir.Observation{Status: fhir.ObservationStatusFinal}, 2)
+	if updateError != nil {
+		t.Fatalf("Expected the current version to be accepted, got %v", updateError)
+	}
+	if updatedObservation.Meta == nil || *updatedObservation.Meta.VersionId != "3" {
+		t.Errorf("Expected version 3 after the update, got %+v", updatedObservation.Meta)
+	}
+}
+
 // TestObservationService_DeleteObservation verifies deletion
 func TestObservationService_DeleteObservation(t *testing.T) {
 	mockRepo := NewMockObservationRepository()
@@ -418,6 +511,62 @@ func TestObservationService_SearchObservations(t *testing.T) {
 	}
 }
 
+// TestObservationService_SearchObservationsPage verifies a cursor-paged search returns a cursor only when
+// more matches follow the page
+func TestObservationService_SearchObservationsPage(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	createdAt := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)
+	for _, observationID := range []string{"page-obs-1", "page-obs-2", "page-obs-3"} {
+		mockRepo.observations[observationID] = &models.Observation{ID: observationID, Status: "final", CreatedAt: createdAt}
+	}
+
+	results, nextCursor, searchError := observationService.SearchObservationsPage(context.Background(), &models.ObservationSearchParams{Limit: 2, CursorPaging: true})
+	if searchError != nil {
+		t.Fatalf("Expected no error, got %v", searchError)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Expected a page of 2 observations, got %d", len(results))
+	}
+	if nextCursor == nil || nextCursor.ID != *results[1].Id || !nextCursor.CreatedAt.Equal(createdAt) {
+		t.Errorf("Expected a cursor after the page's last observation, got %+v", nextCursor)
+	}
+
+	lastResults, lastCursor, _ := observationService.SearchObservationsPage(context.Background(), &models.ObservationSearchParams{Limit: 3, CursorPaging: true})
+	if len(lastResults) != 3 || lastCursor != nil {
+		t.Errorf("Expected the last page without a cursor, got %d observations and %+v", len(lastResults), lastCursor)
+	}
+}
+
+// TestObservationService_StreamObservations verifies each observation is visited in FHIR format and an error
+// from the visitor stops the stream
+func TestObservationService_StreamObservations(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	for _, observationID := range []string{"stream-obs-1", "stream-obs-2", "stream-obs-3"} {
+		mockRepo.observations[observationID] = &models.Observation{ID: observationID, Status: "final"}
+	}
+
+	visitedIDs := []string{}
+	streamError := observationService.StreamObservations(context.Background(), &models.ObservationSearchParams{}, func(fhirObservation *fhir.Observation) error {
+		visitedIDs = append(visitedIDs, *fhirObservation.Id)
+		return nil
+	})
+	if streamError != nil || len(visitedIDs) != 3 {
+		t.Fatalf("Expected 3 observations visited, got %v (%v)", visitedIDs, streamError)
+	}
+
+	stopError := errors.New("client went away")
+	visits := 0
+	streamError = observationService.StreamObservations(context.Background(), &models.ObservationSearchParams{}, func(fhirObservation *fhir.Observation) error {
+		visits++
+		return stopError
+	})
+	if !errors.Is(streamError, stopError) || visits != 1 {
+		t.Errorf("Expected the stream to stop at the first error, got %d visits and %v", visits, streamError)
+	}
+}
+
 // TestObservationService_SearchObservations_Error tests search error handling
 func TestObservationService_SearchObservations_Error(t *testing.T) {
 	// Setup mock repository with error
@@ -443,6 +592,74 @@ func TestObservationService_SearchObservations_Error(t *testing.T) {
 	}
 }
 
+// TestObservationService_ReferentialIntegrity verifies writes naming a patient that does not exist are rejected
+// only when referential integrity is on
+func TestObservationService_ReferentialIntegrity(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	storedPatientID := "0b6b9a52-2f7e-4b43-9d1c-6d2f8d1f4a10"
+	patientRepository.patients[storedPatientID] = &models.Patient{ID: storedPatientID}
+	observationRepository := NewMockObservationRepository()
+	observationService := NewObservationService(observationRepository)
+
+	observationFor := func(patientID string) *fhir.Observation {
+		patientReference := "Patient/" + patientID
+		return &fhir.Observation{Status: fhir.ObservationStatusFinal, Subject: &fhir.Reference{Reference: &patientReference}}
+	}
+	if _, createError := observationService.CreateObservation(context.Background(), observationFor("unknown-patient")); createError != nil {
+		t.Fatalf("Expected unchecked subjects without referential integrity, got %v", createError)
+	}
+
+	observationService.SetReferentialIntegrity(patientRepository)
+	created, createError := observationService.CreateObservation(context.Background(), observationFor(storedPatientID))
+	if createError != nil {
+		t.Fatalf("Expected an observation of a stored patient to be created, got %v", createError)
+	}
+	for _, patientID := range []string{"unknown-patient", "5f0c3c1e-1d7a-4a8e-8f65-2c0a9b7e3d21"} {
+		var rejection *outcome.RejectionError
+		if _, createError := observationService.CreateObservation(context.Background(), observationFor(patientID)); !errors.As(createError, &rejection) {
+			t.Errorf("Patient/%s: expected a rejected create, got %v", patientID, createError)
+		}
+		if _, updateError := observationService.UpdateObservation(context.Background(), *created.Id, observationFor(patientID), 0); !errors.As(updateError, &rejection) {
+			t.Errorf("Patient/%s: expected a rejected update, got %v", patientID, updateError)
+		}
+	}
+	if observationRepository.observations[*created.Id].PatientID != storedPatientID {
+		t.Errorf("Expected the rejected updates to leave the observation's patient unchanged")
+	}
+}
+
+// TestObservationService_LastObservations verifies the newest observations of each code are returned as FHIR
+func TestObservationService_LastObservations(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+
+	for day := 1; day <= 3; day++ {
+		effectiveDate := time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC)
+		for _, code := range []string{"8480-6", "2093-3"} {
+			observationID := fmt.Sprintf("%s-day-%d", code, day)
+			mockRepo.observations[observationID] = &models.Observation{
+				ID:            observationID,
+				PatientID:     "patient-001",
+				Status:        "final",
+				Code:          code,
+				CodeSystem:    "http://loinc.org",
+				EffectiveDate: &effectiveDate,
+			}
+		}
+	}
+
+	results, lastNError := observationService.LastObservations(context.Background(), &models.ObservationLastNParams{PatientID: "patient-001", Max: 2})
+	if lastNError != nil {
+		t.Fatalf("Expected no error, got %v", lastNError)
+	}
+	if len(results) != 4 {
+		t.Fatalf("Expected 2 observations of each of 2 codes, got %d", len(results))
+	}
+	if *results[0].Id != "2093-3-day-3" || *results[1].Id != "2093-3-day-2" {
+		t.Errorf("Expected the newest cholesterol observations first, got %s and %s", *results[0].Id, *results[1].Id)
+	}
+}
+
 // TestNewObservationService verifies constructor
 func TestNewObservationService(t *testing.T) {
 	mockRepo := NewMockObservationRepository()
@@ -458,3 +675,42 @@ func TestNewObservationService(t *testing.T) {
 		t.Error("Expected mapper to be set")
 	}
 }
+
+// recordingLedger captures ledger adjustments
+type recordingLedger struct {
+	adjustments map[string]int64
+}
+
+// Adjust records the delta
+func (ledger *recordingLedger) Adjust(ctx context.Context, patientID string, delta int64) error {
+	ledger.adjustments[patientID] += delta
+	return nil
+}
+
+// ExpectedCounts returns the recorded adjustments
+func (ledger *recordingLedger) ExpectedCounts(ctx context.Context) (map[string]int64, error) {
+	return ledger.adjustments, nil
+}
+
+// TestObservationService_MaintainsLedger verifies creates, reassignments, and deletes adjust the ledger
+func TestObservationService_MaintainsLedger(t *testing.T) {
+	ledger := &recordingLedger{adjustments: map[string]int64{}}
+	observationService := NewObservationService(NewMockObservationRepository())
+	observationService.SetLedgerRepository(ledger)
+	ctx := context.Background()
+
+	firstPatient := "Patient/p1"
+	secondPatient := "Patient/p2"
+	created, _ := observationService.CreateObservation(ctx, &fhir.Observation{Subject: &fhir.Reference{Reference: &firstPatient}})
+	observationService.CreateObservation(ctx, &fhir.Observation{Subject: &fhir.Reference{Reference: &firstPatient}})
+	observationService.UpdateObservation(ctx, *created.Id, &fhir.Observation{Subject: &fhir.Reference{Reference: &secondPatient}}, 0)
+
+	if ledger.adjustments["p1"] != 1 || ledger.adjustments["p2"] != 1 {
+		t.Errorf("Expected one observation each after reassignment, got %v", ledger.adjustments)
+	}
+
+	observationService.DeleteObservation(ctx, *created.Id)
+	if ledger.adjustments["p2"] != 0 {
+		t.Errorf("Expected delete to decrement p2, got %v", ledger.adjustments)
+	}
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"sort"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/matching"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tracing"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// matchCandidateLimit caps each search that gathers candidates for scoring, so a common name or birth date
+// cannot make a match read the whole patients table
+const matchCandidateLimit = 200
+
+// PatientMatch is a stored patient that may be the patient a $match asked about
+type PatientMatch struct {
+	Patient *fhir.Patient
+
+	// Score is between 0 and 1; higher is more alike
+	Score float64
+
+	// Grade is the confidence the score gives
+	Grade matching.Grade
+}
+
+// PatientMatchService finds stored patients that may be the same person as a partial Patient resource
+// Candidates are gathered with indexed searches on the identifier, family name, and birth date, then scored
+// deterministically, so the same query against the same data always ranks the same way
+type PatientMatchService struct {
+	patientService *PatientService
+	config         matching.Config
+}
+
+// NewPatientMatchService creates a new patient match service scoring candidates with config
+func NewPatientMatchService(patientService *PatientService, config matching.Config) *PatientMatchService {
+	return &PatientMatchService{
+		patientService: patientService,
+		config:         config,
+	}
+}
+
+// Match returns at most count matches for fhirPatient, best first; ties are ordered by ID
+// onlyCertain keeps only certain matches. Patients merged into another are never returned, since the
+// patient they were merged into holds their record now
+func (service *PatientMatchService) Match(ctx context.Context, fhirPatient *fhir.Patient, count int, onlyCertain bool) ([]PatientMatch, error) {
+	ctx, span := tracing.Start(ctx, "PatientMatchService.Match")
+	defer span.End()
+
+	query, _ := service.patientService.patientMapper.FromFHIR(fhirPatient)
+	if query.IdentifierValue == "" && query.FamilyName == "" && query.GivenName == "" && query.BirthDate == nil {
+		return nil, apperrors.InvalidInput("resource", "must give an identifier, a name, or a birth date to match on")
+	}
+	query.IdentifierSystem = service.canonicalIdentifierSystem(query.IdentifierSystem)
+
+	candidates, candidatesError := service.gatherCandidates(ctx, query)
+	if candidatesError != nil {
+		return nil, candidatesError
+	}
+
+	matches := []PatientMatch{}
+	for _, candidate := range candidates {
+		comparable := *candidate
+		comparable.IdentifierSystem = service.canonicalIdentifierSystem(candidate.IdentifierSystem)
+		score := service.config.Score(query, &comparable)
+		grade, isMatch := service.config.Grade(score)
+		if !isMatch || (onlyCertain && grade != matching.GradeCertain) {
+			continue
+		}
+		matches = append(matches, PatientMatch{Patient: service.patientService.patientMapper.ToFHIR(candidate), Score: score, Grade: grade})
+	}
+
+	sort.Slice(matches, func(first int, second int) bool {
+		if matches[first].Score != matches[second].Score {
+			return matches[first].Score > matches[second].Score
+		}
+		return *matches[first].Patient.Id < *matches[second].Patient.Id
+	})
+	if len(matches) > count {
+		matches = matches[:count]
+	}
+	return matches, nil
+}
+
+// gatherCandidates collects the distinct unmerged patients sharing the query's identifier, family name, or
+// birth date; family names match misspellings too when fuzzy name search is on
+func (service *PatientMatchService) gatherCandidates(ctx context.Context, query *models.Patient) ([]*models.Patient, error) {
+	candidateSearches := []*models.PatientSearchParams{}
+	if query.IdentifierValue != "" {
+		identifierCriterion := &models.IdentifierCriterion{Value: query.IdentifierValue}
+		if query.IdentifierSystem != "" {
+			identifierCriterion.Systems = []string{query.IdentifierSystem}
+		}
+		candidateSearches = append(candidateSearches, &models.PatientSearchParams{Identifier: identifierCriterion})
+	}
+	if query.FamilyName != "" {
+		candidateSearches = append(candidateSearches, &models.PatientSearchParams{FamilyName: models.NameCriteria{{Values: []string{query.FamilyName}}}})
+	}
+	if query.BirthDate != nil {
+		candidateSearches = append(candidateSearches, &models.PatientSearchParams{BirthDate: query.BirthDate})
+	}
+
+	candidates := []*models.Patient{}
+	seenIDs := make(map[string]bool)
+	for _, searchParams := range candidateSearches {
+		searchParams.Limit = matchCandidateLimit
+		found, searchError := service.patientService.patientRepository.Search(ctx, service.patientService.normalizedSearchParams(searchParams))
+		if searchError != nil {
+			return nil, apperrors.Internal("Failed to search for match candidates", searchError)
+		}
+		for _, candidate := range found {
+			if seenIDs[candidate.ID] || candidate.ReplacedBy != "" {
+				continue
+			}
+			seenIDs[candidate.ID] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates, nil
+}
+
+// canonicalIdentifierSystem returns the site's canonical spelling of an identifier system, so aliases of the
+// same system compare equal when scoring
+func (service *PatientMatchService) canonicalIdentifierSystem(system string) string {
+	if system == "" || service.patientService.identifierSystems == nil {
+		return system
+	}
+	equivalentSystems := service.patientService.identifierSystems.EquivalentIdentifierSystems(system)
+	if len(equivalentSystems) == 0 {
+		return system
+	}
+	return equivalentSystems[0]
+}
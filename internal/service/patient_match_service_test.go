@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/matching"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// newTestMatchService returns a match service over a registered patient, a likely duplicate of them under an
+// identifier system alias, a namesake, and a duplicate that was already merged
+func newTestMatchService() *PatientMatchService {
+	birthDate := time.Date(1980, time.March, 4, 0, 0, 0, 0, time.UTC)
+	patientRepository := NewMockPatientRepository()
+	patientRepository.patients = map[string]*models.Patient{
+		"patient-a": {ID: "patient-a", IdentifierSystem: "http://hospital.example.org/mrn", IdentifierValue: "12345", FamilyName: "Smith", GivenName: "Jane", Gender: "female", BirthDate: &birthDate},
+		"patient-b": {ID: "patient-b", IdentifierSystem: "MRN", IdentifierValue: "12345", FamilyName: "Smyth", GivenName: "Jane", Gender: "female", BirthDate: &birthDate},
+		"patient-c": {ID: "patient-c", FamilyName: "Smith", GivenName: "Jane", Gender: "female"},
+		"patient-d": {ID: "patient-d", IdentifierSystem: "http://hospital.example.org/mrn", IdentifierValue: "12345", FamilyName: "Smith", GivenName: "Jane", Gender: "female", BirthDate: &birthDate, ReplacedBy: "patient-a"},
+	}
+	patientService := NewPatientService(patientRepository)
+	patientService.SetIdentifierSystemNormalizer(staticIdentifierSystems{"http://hospital.example.org/mrn", "MRN"})
+	return NewPatientMatchService(patientService, matching.DefaultConfig())
+}
+
+// matchQuery returns the partial Patient a registration desk would send for Jane Smith
+func matchQuery() *fhir.Patient {
+	system := "http://hospital.example.org/mrn"
+	value := "12345"
+	family := "Smith"
+	birthDate := "1980-03-04"
+	gender := fhir.AdministrativeGenderFemale
+	return &fhir.Patient{
+		Identifier: []fhir.Identifier{{System: &system, Value: &value}},
+		Name:       []fhir.HumanName{{Family: &family, Given: []string{"Jane"}}},
+		BirthDate:  &birthDate,
+		Gender:     &gender,
+	}
+}
+
+// TestPatientMatchService_Match verifies candidates are ranked by score with their grades, identifier aliases
+// count as the same system, and merged patients are left out
+func TestPatientMatchService_Match(t *testing.T) {
+	matchService := newTestMatchService()
+
+	matches, matchError := matchService.Match(context.Background(), matchQuery(), 10, false)
+	if matchError != nil {
+		t.Fatalf("Expected no error, got %v", matchError)
+	}
+	expectedMatches := []struct {
+		patientID string
+		grade     matching.Grade
+	}{
+		{patientID: "patient-a", grade: matching.GradeCertain},
+		{patientID: "patient-b", grade: matching.GradeCertain},
+		{patientID: "patient-c", grade: matching.GradePossible},
+	}
+	if len(matches) != len(expectedMatches) {
+		t.Fatalf("Expected %d matches, got %+v", len(expectedMatches), matches)
+	}
+	for index, expected := range expectedMatches {
+		if *matches[index].Patient.Id != expected.patientID || matches[index].Grade != expected.grade {
+			t.Errorf("Expected match %d to be %s (%s), got %s (%s, %v)", index, expected.patientID, expected.grade, *matches[index].Patient.Id, matches[index].Grade, matches[index].Score)
+		}
+	}
+	if matches[0].Score != 1 || matches[1].Score >= matches[0].Score {
+		t.Errorf("Expected the exact patient to score 1 above the misspelled one, got %v and %v", matches[0].Score, matches[1].Score)
+	}
+
+	certainMatches, _ := matchService.Match(context.Background(), matchQuery(), 1, true)
+	if len(certainMatches) != 1 || *certainMatches[0].Patient.Id != "patient-a" {
+		t.Errorf("Expected only the best certain match, got %+v", certainMatches)
+	}
+}
+
+// TestPatientMatchService_RequiresCriteria verifies a query with nothing to match on is refused
+func TestPatientMatchService_RequiresCriteria(t *testing.T) {
+	gender := fhir.AdministrativeGenderFemale
+	_, matchError := newTestMatchService().Match(context.Background(), &fhir.Patient{Gender: &gender}, 10, false)
+	var appError *apperrors.AppError
+	if !errors.As(matchError, &appError) || appError.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected a 400, got %v", matchError)
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// mergeBatchSize is how many of the source patient's observations are looked up at a time while merging
+const mergeBatchSize = 100
+
+// mergePath is the operation's path, recorded on the audit events of every merge
+const mergePath = "/fhir/Patient/$merge"
+
+// MergeObservations finds a merged patient's observations and moves them to the surviving patient
+// ObservationService implements it, so every moved observation gets a new version and an update event
+type MergeObservations interface {
+	SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error)
+	ReassignObservation(ctx context.Context, observationID string, patientID string) error
+}
+
+// MergeAuditRecorder stores the audit events of merges; AuditEventService implements it
+type MergeAuditRecorder interface {
+	Record(ctx context.Context, event *models.AuditEvent) error
+}
+
+// PatientMergeResult is the outcome of merging one patient into another
+type PatientMergeResult struct {
+	// Target is the surviving patient
+	Target *fhir.Patient
+
+	// ReassignedObservations counts the observations moved from the source patient to Target
+	ReassignedObservations int
+}
+
+// PatientMergeService merges duplicate patient records: the source patient's observations move to the
+// target, and the source is marked inactive with a replaced-by link to the target
+type PatientMergeService struct {
+	patientService *PatientService
+	observations   MergeObservations
+	auditRecorder  MergeAuditRecorder
+}
+
+// NewPatientMergeService creates a new patient merge service instance
+// The merged patient is updated through patientService, so it gets a new version and merge listeners hear of it
+func NewPatientMergeService(patientService *PatientService, observations MergeObservations) *PatientMergeService {
+	return &PatientMergeService{
+		patientService: patientService,
+		observations:   observations,
+	}
+}
+
+// SetAuditRecorder records an AuditEvent naming the source and one naming the target of every merge, so the
+// audit trail of either patient shows it
+func (service *PatientMergeService) SetAuditRecorder(auditRecorder MergeAuditRecorder) {
+	service.auditRecorder = auditRecorder
+}
+
+// Merge merges the source patient into the target; only the identity-admin role may do this
+// Observations are moved before the source is marked, so a merge that fails partway can simply be retried.
+// Retrying a completed merge moves any observation written to the source since and changes nothing else
+func (service *PatientMergeService) Merge(ctx context.Context, sourceID string, targetID string) (*PatientMergeResult, error) {
+	principal := auth.FromContext(ctx)
+	if !principal.HasRole(auth.RoleIdentityAdmin) {
+		return nil, apperrors.Forbidden("Patients can only be merged by the identity-admin role")
+	}
+	if sourceID == targetID {
+		return nil, apperrors.InvalidInput("target-patient", "must be a different patient than source-patient")
+	}
+
+	source, sourceError := service.loadPatient(ctx, sourceID)
+	if sourceError != nil {
+		return nil, sourceError
+	}
+	target, targetError := service.loadPatient(ctx, targetID)
+	if targetError != nil {
+		return nil, targetError
+	}
+	if target.ReplacedBy != "" {
+		return nil, apperrors.Conflict("Patient", "target-patient was itself merged into Patient/"+target.ReplacedBy)
+	}
+	if source.ReplacedBy != "" && source.ReplacedBy != target.ID {
+		return nil, apperrors.Conflict("Patient", "source-patient was already merged into Patient/"+source.ReplacedBy)
+	}
+
+	reassignedCount, reassignError := service.reassignObservations(ctx, source.ID, target.ID)
+	if reassignError != nil {
+		return nil, reassignError
+	}
+
+	if source.ReplacedBy != target.ID {
+		markError := service.patientService.markMerged(ctx, source, target)
+		if errors.Is(markError, ErrResourceNotFound) {
+			return nil, apperrors.NotFound("Patient", source.ID)
+		}
+		if markError != nil {
+			return nil, apperrors.Internal("Failed to mark the source patient merged", markError)
+		}
+		service.recordAudit(ctx, principal, source.ID)
+		service.recordAudit(ctx, principal, target.ID)
+	}
+
+	log.Info().
+		Str("source_patient_id", source.ID).
+		Str("target_patient_id", target.ID).
+		Int("reassigned_observations", reassignedCount).
+		Str("actor", principal.ID).
+		Msg("Patient merged")
+	return &PatientMergeResult{
+		Target:                 service.patientService.patientMapper.ToFHIR(target),
+		ReassignedObservations: reassignedCount,
+	}, nil
+}
+
+// loadPatient reads a patient taking part in a merge, reporting a 410 for deleted patients and a 404 for unknown ones
+func (service *PatientMergeService) loadPatient(ctx context.Context, patientID string) (*models.Patient, error) {
+	if _, parseError := uuid.Parse(patientID); parseError != nil {
+		return nil, apperrors.NotFound("Patient", patientID)
+	}
+	patient, getError := service.patientService.patientRepository.GetByID(ctx, patientID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, apperrors.Gone("Patient", patientID)
+	}
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, apperrors.NotFound("Patient", patientID)
+	}
+	if getError != nil {
+		return nil, apperrors.Internal("Failed to read the patient", getError)
+	}
+	return patient, nil
+}
+
+// reassignObservations moves the source patient's observations to the target in batches and returns how many moved
+// Moved observations no longer match the search, so each batch picks up where the last one left off
+func (service *PatientMergeService) reassignObservations(ctx context.Context, sourceID string, targetID string) (int, error) {
+	reassignedCount := 0
+	for {
+		observations, searchError := service.observations.SearchObservations(ctx, &models.ObservationSearchParams{PatientID: sourceID, Limit: mergeBatchSize})
+		if searchError != nil {
+			return reassignedCount, apperrors.Internal("Failed to find the source patient's observations", searchError)
+		}
+		if len(observations) == 0 {
+			return reassignedCount, nil
+		}
+
+		for _, observation := range observations {
+			reassignError := service.observations.ReassignObservation(ctx, *observation.Id, targetID)
+			// Deleted meanwhile, or edited meanwhile and found again by the next batch
+			if errors.Is(reassignError, ErrResourceNotFound) || errors.Is(reassignError, ErrVersionConflict) {
+				continue
+			}
+			if reassignError != nil {
+				return reassignedCount, apperrors.Internal("Failed to move the source patient's observations", reassignError)
+			}
+			reassignedCount++
+		}
+	}
+}
+
+// recordAudit records the merge as an update of one of the merged patients
+// A failure is logged, since the merge has already committed
+func (service *PatientMergeService) recordAudit(ctx context.Context, principal auth.Principal, patientID string) {
+	if service.auditRecorder == nil {
+		return
+	}
+
+	event := &models.AuditEvent{
+		Action:      models.AuditActionUpdate,
+		Interaction: "operation",
+		Outcome:     models.AuditOutcomeSuccess,
+		StatusCode:  http.StatusOK,
+		AgentID:     principal.ID,
+		AgentRoles:  principal.Roles,
+		TenantID:    tenant.FromContext(ctx),
+		EntityType:  "Patient",
+		EntityID:    patientID,
+		Method:      http.MethodPost,
+		Path:        mergePath,
+	}
+	if recordError := service.auditRecorder.Record(context.WithoutCancel(ctx), event); recordError != nil {
+		log.Error().
+			Err(recordError).
+			Str("audit", "audit_event_lost").
+			Str("principal", principal.ID).
+			Str("patient_id", patientID).
+			Msg("Failed to record patient merge audit event")
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Patients taking part in the merge tests
+const (
+	mergeSourceID = "5a1e6c2d-8b3f-4e7a-9c0d-1f2e3a4b5c6d"
+	mergeTargetID = "7b2f8d3e-9c4a-4f8b-8d1e-2a3b4c5d6e7f"
+	mergeOtherID  = "9c3a9e4f-0d5b-4a9c-8e2f-3b4c5d6e7f80"
+)
+
+// stubMergeObservations keeps observation IDs by patient and moves them when reassigned
+type stubMergeObservations struct {
+	stubPatientObservations
+	conflicts map[string]bool
+}
+
+// ReassignObservation moves the observation, failing once with ErrVersionConflict for observations in conflicts
+func (stub *stubMergeObservations) ReassignObservation(ctx context.Context, observationID string, patientID string) error {
+	if stub.conflicts[observationID] {
+		delete(stub.conflicts, observationID)
+		return ErrVersionConflict
+	}
+	stub.patientIDs[observationID] = patientID
+	return nil
+}
+
+// recordingMergeListener records the patient writes and merges it is told about
+type recordingMergeListener struct {
+	recordingWriteListener
+	mergedSources []*models.Patient
+}
+
+// PatientMerged records the merged source
+func (listener *recordingMergeListener) PatientMerged(ctx context.Context, source *models.Patient, target *models.Patient) error {
+	listener.mergedSources = append(listener.mergedSources, source)
+	return nil
+}
+
+// memoryAuditRecorder keeps recorded audit events in memory
+type memoryAuditRecorder struct {
+	events []*models.AuditEvent
+}
+
+// Record appends the event
+func (recorder *memoryAuditRecorder) Record(ctx context.Context, event *models.AuditEvent) error {
+	recorder.events = append(recorder.events, event)
+	return nil
+}
+
+// newTestMergeService returns a merge service over a source, a target, and another patient, with two of the
+// source's observations and one of the other patient's
+func newTestMergeService() (*PatientMergeService, *MockPatientRepository, *stubMergeObservations, *MockChangeRepository) {
+	patientRepository := NewMockPatientRepository()
+	for _, patientID := range []string{mergeSourceID, mergeTargetID, mergeOtherID} {
+		patientRepository.patients[patientID] = &models.Patient{ID: patientID, Active: true, FamilyName: "Smith"}
+	}
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(patientRepository)
+	patientService.SetChangeRepository(changeRepository)
+
+	observations := &stubMergeObservations{
+		stubPatientObservations: stubPatientObservations{patientIDs: map[string]string{
+			"obs-1": mergeSourceID,
+			"obs-2": mergeSourceID,
+			"obs-3": mergeOtherID,
+		}},
+		conflicts: map[string]bool{"obs-2": true},
+	}
+	return NewPatientMergeService(patientService, observations), patientRepository, observations, changeRepository
+}
+
+// TestPatientMergeService_Merge verifies the source's observations move to the target, the source becomes an
+// inactive replaced-by record in a new version, and the merge reaches merge listeners and the audit trail
+func TestPatientMergeService_Merge(t *testing.T) {
+	mergeService, patientRepository, observations, changeRepository := newTestMergeService()
+	listener := &recordingMergeListener{}
+	mergeService.patientService.AddWriteListener(listener)
+	auditRecorder := &memoryAuditRecorder{}
+	mergeService.SetAuditRecorder(auditRecorder)
+
+	mergeResult, mergeError := mergeService.Merge(identityAdminContext(), mergeSourceID, mergeTargetID)
+	if mergeError != nil {
+		t.Fatalf("Expected no error, got %v", mergeError)
+	}
+	if mergeResult.ReassignedObservations != 2 || *mergeResult.Target.Id != mergeTargetID {
+		t.Errorf("Expected both observations moved to the target, got %+v", mergeResult)
+	}
+	if observations.patientIDs["obs-1"] != mergeTargetID || observations.patientIDs["obs-2"] != mergeTargetID || observations.patientIDs["obs-3"] != mergeOtherID {
+		t.Errorf("Expected only the source's observations moved, got %v", observations.patientIDs)
+	}
+
+	mergedSource := patientRepository.patients[mergeSourceID]
+	if mergedSource.Active || mergedSource.ReplacedBy != mergeTargetID {
+		t.Errorf("Expected an inactive source replaced by the target, got %+v", mergedSource)
+	}
+	if len(changeRepository.changes) != 1 || changeRepository.changes[0].ResourceID != mergeSourceID || changeRepository.changes[0].Operation != models.ChangeOperationUpdate {
+		t.Errorf("Expected the source's update in the change log, got %+v", changeRepository.changes)
+	}
+	if len(listener.mergedSources) != 1 || len(listener.operations) != 0 {
+		t.Errorf("Expected one merge and no plain write, got %d merges and %v", len(listener.mergedSources), listener.operations)
+	}
+	if len(auditRecorder.events) != 2 || auditRecorder.events[0].EntityID != mergeSourceID || auditRecorder.events[1].EntityID != mergeTargetID {
+		t.Errorf("Expected audit events for the source and the target, got %+v", auditRecorder.events)
+	}
+
+	// A retry moves observations written to the source since and changes nothing else
+	observations.patientIDs["obs-4"] = mergeSourceID
+	retryResult, retryError := mergeService.Merge(identityAdminContext(), mergeSourceID, mergeTargetID)
+	if retryError != nil || retryResult.ReassignedObservations != 1 {
+		t.Fatalf("Expected the retry to move the late observation, got %+v, %v", retryResult, retryError)
+	}
+	if len(changeRepository.changes) != 1 || len(auditRecorder.events) != 2 {
+		t.Errorf("Expected no second version or audit events, got %d changes and %d events", len(changeRepository.changes), len(auditRecorder.events))
+	}
+}
+
+// TestPatientMergeService_Rejects verifies merges are refused without the role, into the same or an unknown
+// patient, and when either patient was merged elsewhere
+func TestPatientMergeService_Rejects(t *testing.T) {
+	testCases := []struct {
+		name               string
+		ctx                context.Context
+		sourceID           string
+		targetID           string
+		replacedBy         map[string]string
+		expectedStatusCode int
+	}{
+		{name: "no role", ctx: context.Background(), sourceID: mergeSourceID, targetID: mergeTargetID, expectedStatusCode: http.StatusForbidden},
+		{name: "same patient", ctx: identityAdminContext(), sourceID: mergeSourceID, targetID: mergeSourceID, expectedStatusCode: http.StatusBadRequest},
+		{name: "unknown target", ctx: identityAdminContext(), sourceID: mergeSourceID, targetID: "0d4b0f5a-1e6c-4b0d-9f3a-4c5d6e7f8091", expectedStatusCode: http.StatusNotFound},
+		{name: "malformed source", ctx: identityAdminContext(), sourceID: "not-a-uuid", targetID: mergeTargetID, expectedStatusCode: http.StatusNotFound},
+		{name: "merged target", ctx: identityAdminContext(), sourceID: mergeSourceID, targetID: mergeTargetID, replacedBy: map[string]string{mergeTargetID: mergeOtherID}, expectedStatusCode: http.StatusConflict},
+		{name: "source merged elsewhere", ctx: identityAdminContext(), sourceID: mergeSourceID, targetID: mergeTargetID, replacedBy: map[string]string{mergeSourceID: mergeOtherID}, expectedStatusCode: http.StatusConflict},
+	}
+	for _, testCase := range testCases {
+		mergeService, patientRepository, observations, _ := newTestMergeService()
+		for patientID, replacementID := range testCase.replacedBy {
+			patientRepository.patients[patientID].ReplacedBy = replacementID
+		}
+
+		_, mergeError := mergeService.Merge(testCase.ctx, testCase.sourceID, testCase.targetID)
+		var appError *apperrors.AppError
+		if !errors.As(mergeError, &appError) || appError.StatusCode != testCase.expectedStatusCode {
+			t.Errorf("%s: expected status %d, got %v", testCase.name, testCase.expectedStatusCode, mergeError)
+		}
+		if observations.patientIDs["obs-1"] != mergeSourceID {
+			t.Errorf("%s: expected no observation moved", testCase.name)
+		}
+	}
+}
+
+// TestPatientService_ReplacementLink verifies a replaced-by link must name another stored patient's ID
+func TestPatientService_ReplacementLink(t *testing.T) {
+	patientService := NewPatientService(NewMockPatientRepository())
+	invalidReferences := []string{"Patient/not-a-uuid", "Patient/" + mergeSourceID}
+	for _, reference := range invalidReferences {
+		fhirPatient := &fhir.Patient{Link: []fhir.PatientLink{{Other: fhir.Reference{Reference: &reference}, Type: fhir.LinkTypeReplacedBy}}}
+		if _, updateError := patientService.UpdatePatient(context.Background(), mergeSourceID, fhirPatient); updateError == nil {
+			t.Errorf("%s: expected the link to be rejected", reference)
+		}
+	}
+}
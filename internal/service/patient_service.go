@@ -2,16 +2,76 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/dedup"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
 	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/tracing"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
+// PatientWriteListener is told about each stored patient inside the write's transaction
+// Anything it queues through the same database commits or rolls back with the write; an error rolls the write back
+type PatientWriteListener interface {
+	PatientWritten(ctx context.Context, operation models.ChangeOperation, patient *models.Patient) error
+}
+
+// PatientMergeListener is a write listener that is also told when a patient is merged into another
+// It is called instead of PatientWritten for the merged patient's update, inside the same transaction
+type PatientMergeListener interface {
+	PatientMerged(ctx context.Context, source *models.Patient, target *models.Patient) error
+}
+
 // PatientService handles business logic for Patient operations
 type PatientService struct {
 	patientRepository repository.PatientRepository
 	patientMapper     *models.PatientMapper
+	changeRepository  repository.ChangeRepository
+	eventPublisher    events.Publisher
+	strictMapping     bool
+	deletionGuard     DeletionGuard
+	writeListeners    []PatientWriteListener
+	searchGroup       *dedup.Group
+	identifierSystems IdentifierSystemNormalizer
+	deletePolicy      PatientDeletePolicy
+	observations      PatientObservations
+}
+
+// PatientDeletePolicy decides what deleting a patient does to the observations that reference it
+type PatientDeletePolicy string
+
+const (
+	// PatientDeleteAllow deletes the patient and leaves its observations in place
+	PatientDeleteAllow PatientDeletePolicy = "allow"
+
+	// PatientDeleteBlock refuses deleting a patient while observations reference it
+	PatientDeleteBlock PatientDeletePolicy = "block"
+
+	// PatientDeleteCascade deletes the patient's observations before the patient
+	PatientDeleteCascade PatientDeletePolicy = "cascade"
+)
+
+// cascadeBatchSize is how many of a patient's observations are looked up at a time while cascading a delete
+const cascadeBatchSize = 100
+
+// PatientObservations counts, finds, and deletes a patient's observations for the patient delete policy
+// ObservationService implements it, so cascaded deletes are checked against legal holds and recorded like any other
+type PatientObservations interface {
+	CountObservations(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error)
+	SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error)
+	DeleteObservation(ctx context.Context, observationID string) error
+}
+
+// IdentifierSystemNormalizer maps an identifier system to the systems a site treats as the same
+type IdentifierSystemNormalizer interface {
+	// EquivalentIdentifierSystems returns the canonical system for a system followed by its aliases
+	EquivalentIdentifierSystems(system string) []string
 }
 
 // NewPatientService creates a new instance of PatientService
@@ -22,28 +82,90 @@ func NewPatientService(patientRepository repository.PatientRepository) *PatientS
 	}
 }
 
+// SetSearchGroup collapses identical concurrent patient searches into one repository call
+func (service *PatientService) SetSearchGroup(searchGroup *dedup.Group) {
+	service.searchGroup = searchGroup
+}
+
+// SetIdentifierSystemNormalizer makes identifier searches match every alias of the searched system
+func (service *PatientService) SetIdentifierSystemNormalizer(normalizer IdentifierSystemNormalizer) {
+	service.identifierSystems = normalizer
+}
+
+// SetChangeRepository enables recording patient writes to the change log
+func (service *PatientService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing patient write events to internal consumers
+func (service *PatientService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *PatientService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// SetDeletionGuard refuses deleting patients under legal hold
+func (service *PatientService) SetDeletionGuard(deletionGuard DeletionGuard) {
+	service.deletionGuard = deletionGuard
+}
+
+// SetDeletePolicy decides whether deleting a patient is refused or cascades while observations reference it
+// Without a policy, or with PatientDeleteAllow, the patient is deleted and its observations are left in place
+func (service *PatientService) SetDeletePolicy(policy PatientDeletePolicy, observations PatientObservations) {
+	service.deletePolicy = policy
+	service.observations = observations
+}
+
+// AddWriteListener registers a listener called for every patient create and update before it commits
+// Listeners that also implement PatientMergeListener are told about merges as merges
+func (service *PatientService) AddWriteListener(listener PatientWriteListener) {
+	service.writeListeners = append(service.writeListeners, listener)
+}
+
+// AddMappingHook registers a site-specific hook on the patient mapper
+func (service *PatientService) AddMappingHook(hook models.PatientMappingHook) {
+	service.patientMapper.AddHook(hook)
+}
+
 // CreatePatient creates a new patient from FHIR Patient resource
 func (service *PatientService) CreatePatient(ctx context.Context, fhirPatient *fhir.Patient) (*fhir.Patient, error) {
+	ctx, span := tracing.Start(ctx, "PatientService.CreatePatient")
+	defer span.End()
+
 	// Convert FHIR Patient to domain model
-	domainPatient := service.patientMapper.FromFHIR(fhirPatient)
+	domainPatient, mappingIssues := service.patientMapper.FromFHIR(fhirPatient)
+	if issuesError := handleMappingIssues(ctx, "Patient", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	if linkError := checkReplacementLink(domainPatient); linkError != nil {
+		return nil, linkError
+	}
 
 	// Set default active status if not provided
 	if fhirPatient.Active == nil {
 		domainPatient.Active = true
 	}
 
-	// Save to database
-	createdPatient, createError := service.patientRepository.Create(ctx, domainPatient)
+	// Save to database together with its change log entry
+	createdFHIRPatient, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(transactionContext context.Context) (*models.Patient, error) {
+		return service.patientRepository.Create(transactionContext, domainPatient)
+	})
 	if createError != nil {
 		return nil, createError
 	}
 
-	// Convert back to FHIR format and return
-	return service.patientMapper.ToFHIR(createdPatient), nil
+	reportIgnoredElements(ctx, "Patient", fhirPatient, createdFHIRPatient, mappingIssues)
+	return createdFHIRPatient, nil
 }
 
 // GetPatientByID retrieves a patient by ID and returns as FHIR Patient
 func (service *PatientService) GetPatientByID(ctx context.Context, patientID string) (*fhir.Patient, error) {
+	ctx, span := tracing.Start(ctx, "PatientService.GetPatientByID")
+	defer span.End()
+
 	// Get from database
 	domainPatient, getError := service.patientRepository.GetByID(ctx, patientID)
 	if getError != nil {
@@ -56,6 +178,9 @@ func (service *PatientService) GetPatientByID(ctx context.Context, patientID str
 
 // GetAllPatients retrieves all patients with pagination
 func (service *PatientService) GetAllPatients(ctx context.Context, limit int, offset int) ([]*fhir.Patient, error) {
+	ctx, span := tracing.Start(ctx, "PatientService.GetAllPatients")
+	defer span.End()
+
 	// Get from database
 	domainPatients, getError := service.patientRepository.GetAll(ctx, limit, offset)
 	if getError != nil {
@@ -73,26 +198,97 @@ func (service *PatientService) GetAllPatients(ctx context.Context, limit int, of
 
 // UpdatePatient updates an existing patient
 func (service *PatientService) UpdatePatient(ctx context.Context, patientID string, fhirPatient *fhir.Patient) (*fhir.Patient, error) {
+	ctx, span := tracing.Start(ctx, "PatientService.UpdatePatient")
+	defer span.End()
+
 	// Convert FHIR Patient to domain model
-	domainPatient := service.patientMapper.FromFHIR(fhirPatient)
+	domainPatient, mappingIssues := service.patientMapper.FromFHIR(fhirPatient)
+	if issuesError := handleMappingIssues(ctx, "Patient", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
 	domainPatient.ID = patientID
+	if linkError := checkReplacementLink(domainPatient); linkError != nil {
+		return nil, linkError
+	}
 
-	// Update in database
-	updatedPatient, updateError := service.patientRepository.Update(ctx, domainPatient)
+	// Update in database together with its change log entry
+	updatedFHIRPatient, updateError := service.commitWrite(ctx, patientID, models.ChangeOperationUpdate, func(transactionContext context.Context) (*models.Patient, error) {
+		return service.patientRepository.Update(transactionContext, domainPatient)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
 	if updateError != nil {
 		return nil, updateError
 	}
 
-	// Convert back to FHIR format and return
-	return service.patientMapper.ToFHIR(updatedPatient), nil
+	reportIgnoredElements(ctx, "Patient", fhirPatient, updatedFHIRPatient, mappingIssues)
+	return updatedFHIRPatient, nil
+}
+
+// checkReplacementLink rejects a replaced-by link that cannot name a stored patient
+func checkReplacementLink(patient *models.Patient) error {
+	if patient.ReplacedBy == "" {
+		return nil
+	}
+	if _, parseError := uuid.Parse(patient.ReplacedBy); parseError != nil {
+		return apperrors.InvalidInput("link", "a replaced-by link must reference a stored Patient")
+	}
+	if patient.ReplacedBy == patient.ID {
+		return apperrors.InvalidInput("link", "a patient cannot be replaced by itself")
+	}
+	return nil
+}
+
+// RecordExternalUpdate records a patient update written outside this service, such as a bulk identifier re-key,
+// so it gets a new version in the change log and is published to event consumers like any other update
+func (service *PatientService) RecordExternalUpdate(ctx context.Context, patientID string) error {
+	_, recordError := service.commitWrite(ctx, patientID, models.ChangeOperationUpdate, func(transactionContext context.Context) (*models.Patient, error) {
+		return service.patientRepository.GetByID(transactionContext, patientID)
+	})
+	return recordError
 }
 
 // SearchPatients retrieves patients matching the search criteria
 func (service *PatientService) SearchPatients(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, error) {
-	// Search in database
-	domainPatients, searchError := service.patientRepository.Search(ctx, searchParams)
+	fhirPatients, _, searchError := service.SearchPatientsPage(ctx, searchParams)
+	return fhirPatients, searchError
+}
+
+// SearchPatientsPage searches for patients like SearchPatients and, for a cursor-paged search with more
+// matches after this page, returns the cursor continuing it
+// One extra match is read to tell whether another page follows, so the last page has no cursor
+func (service *PatientService) SearchPatientsPage(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, *models.PageCursor, error) {
+	ctx, span := tracing.Start(ctx, "PatientService.SearchPatients")
+	defer span.End()
+
+	searchParams = service.normalizedSearchParams(searchParams)
+	pageSize := searchParams.Limit
+	if searchParams.CursorPaging && pageSize > 0 {
+		lookaheadParams := *searchParams
+		lookaheadParams.Limit = pageSize + 1
+		searchParams = &lookaheadParams
+	}
+
+	// Search in database, sharing the query with identical concurrent searches
+	domainPatients, shared, searchError := dedup.Do(ctx, service.searchGroup, "Patient", searchParams, func(searchContext context.Context) ([]*models.Patient, error) {
+		return service.patientRepository.Search(searchContext, searchParams)
+	})
 	if searchError != nil {
-		return nil, searchError
+		return nil, nil, searchError
+	}
+	if shared {
+		domainPatients = copyPatients(domainPatients)
+	}
+
+	var nextCursor *models.PageCursor
+	if searchParams.CursorPaging && pageSize > 0 && len(domainPatients) > pageSize {
+		domainPatients = domainPatients[:pageSize]
+		lastPatient := domainPatients[pageSize-1]
+		nextCursor = &models.PageCursor{CreatedAt: lastPatient.CreatedAt, ID: lastPatient.ID}
 	}
 
 	// Convert each patient to FHIR format
@@ -101,10 +297,242 @@ func (service *PatientService) SearchPatients(ctx context.Context, searchParams
 		fhirPatients[index] = service.patientMapper.ToFHIR(domainPatient)
 	}
 
-	return fhirPatients, nil
+	return fhirPatients, nextCursor, nil
+}
+
+// CountPatients returns how many patients match the search across all pages, used as the searchset total
+func (service *PatientService) CountPatients(ctx context.Context, searchParams *models.PatientSearchParams) (int, error) {
+	ctx, span := tracing.Start(ctx, "PatientService.CountPatients")
+	defer span.End()
+
+	return service.patientRepository.Count(ctx, service.normalizedSearchParams(searchParams))
+}
+
+// StreamPatients calls visit with each patient matching the search in FHIR format, reading them from the
+// database one at a time instead of holding every match; an error from visit stops the stream
+// Streams are not shared between identical searches, since each caller consumes its rows at its own pace
+func (service *PatientService) StreamPatients(ctx context.Context, searchParams *models.PatientSearchParams, visit func(fhirPatient *fhir.Patient) error) error {
+	ctx, span := tracing.Start(ctx, "PatientService.StreamPatients")
+	defer span.End()
+
+	searchParams = service.normalizedSearchParams(searchParams)
+	return service.patientRepository.Stream(ctx, searchParams, func(domainPatient *models.Patient) error {
+		return visit(service.patientMapper.ToFHIR(domainPatient))
+	})
+}
+
+// normalizedSearchParams searches under the canonical identifier system and its aliases, however the
+// client spelled it; searchParams is returned unchanged when there is nothing to expand
+func (service *PatientService) normalizedSearchParams(searchParams *models.PatientSearchParams) *models.PatientSearchParams {
+	if searchParams.Identifier == nil || service.identifierSystems == nil || len(searchParams.Identifier.Systems) != 1 || searchParams.Identifier.Systems[0] == "" {
+		return searchParams
+	}
+	normalizedParams := *searchParams
+	normalizedParams.Identifier = &models.IdentifierCriterion{
+		Systems: service.identifierSystems.EquivalentIdentifierSystems(searchParams.Identifier.Systems[0]),
+		Value:   searchParams.Identifier.Value,
+	}
+	return &normalizedParams
 }
 
 // DeletePatient removes a patient by ID
 func (service *PatientService) DeletePatient(ctx context.Context, patientID string) error {
-	return service.patientRepository.Delete(ctx, patientID)
+	ctx, span := tracing.Start(ctx, "PatientService.DeletePatient")
+	defer span.End()
+
+	if service.deletionGuard != nil {
+		if guardError := service.deletionGuard.CheckDeletable(ctx, patientID, "Patient", patientID); guardError != nil {
+			return guardError
+		}
+	}
+	if dependentsError := service.handleDependentObservations(ctx, patientID); dependentsError != nil {
+		return dependentsError
+	}
+
+	_, deleteError := service.commitWrite(ctx, patientID, models.ChangeOperationDelete, func(transactionContext context.Context) (*models.Patient, error) {
+		return nil, service.patientRepository.Delete(transactionContext, patientID)
+	})
+	return deleteError
+}
+
+// handleDependentObservations applies the delete policy to the patient's observations before the patient is deleted
+// Cascaded observations are deleted first, so a patient delete that fails afterwards can simply be retried
+func (service *PatientService) handleDependentObservations(ctx context.Context, patientID string) error {
+	switch service.deletePolicy {
+	case PatientDeleteBlock:
+		dependentCount, countError := service.observations.CountObservations(ctx, &models.ObservationSearchParams{PatientID: patientID})
+		if countError != nil {
+			return apperrors.Internal("Failed to count the patient's observations", countError)
+		}
+		if dependentCount > 0 {
+			return apperrors.Conflict("Patient", fmt.Sprintf("%d observations reference the patient; delete them first", dependentCount))
+		}
+	case PatientDeleteCascade:
+		for {
+			dependents, searchError := service.observations.SearchObservations(ctx, &models.ObservationSearchParams{PatientID: patientID, Limit: cascadeBatchSize})
+			if searchError != nil {
+				return apperrors.Internal("Failed to find the patient's observations", searchError)
+			}
+			if len(dependents) == 0 {
+				return nil
+			}
+			for _, dependent := range dependents {
+				deleteError := service.observations.DeleteObservation(ctx, *dependent.Id)
+				if errors.Is(deleteError, repository.ErrObservationNotFound) {
+					continue
+				}
+				if deleteError != nil {
+					return apperrors.Internal("Failed to delete the patient's observations", deleteError)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// withdrawForErasure runs hold, which copies the patient aside and removes its row, recording a delete like DeletePatient
+// hold runs in the delete's transaction; when it reports sql.ErrNoRows the patient was already withdrawn and nothing is recorded.
+// A patient that was deleted before is held without recording another delete
+func (service *PatientService) withdrawForErasure(ctx context.Context, patientID string, hold func(ctx context.Context) error) error {
+	_, withdrawError := service.commitWrite(ctx, patientID, models.ChangeOperationDelete, func(transactionContext context.Context) (*models.Patient, error) {
+		if _, getError := service.patientRepository.GetByID(transactionContext, patientID); getError != nil {
+			return nil, getError
+		}
+		return nil, hold(transactionContext)
+	})
+	if errors.Is(withdrawError, ErrResourceDeleted) {
+		withdrawError = hold(ctx)
+	}
+	if errors.Is(withdrawError, sql.ErrNoRows) {
+		return nil
+	}
+	return withdrawError
+}
+
+// reinstateAfterErasure records the patient put back by restore as a create, so sync and event consumers see it return
+// restore runs in the create's transaction; when it reports sql.ErrNoRows there was nothing to put back and nothing is recorded.
+// A patient that was deleted before the erasure comes back deleted, so it is put back without recording a create
+func (service *PatientService) reinstateAfterErasure(ctx context.Context, patientID string, restore func(ctx context.Context) error) error {
+	_, reinstateError := service.commitWrite(ctx, patientID, models.ChangeOperationCreate, func(transactionContext context.Context) (*models.Patient, error) {
+		if restoreError := restore(transactionContext); restoreError != nil {
+			return nil, restoreError
+		}
+		return service.patientRepository.GetByID(transactionContext, patientID)
+	})
+	if errors.Is(reinstateError, ErrResourceDeleted) {
+		reinstateError = restore(ctx)
+	}
+	if errors.Is(reinstateError, sql.ErrNoRows) {
+		return nil
+	}
+	return reinstateError
+}
+
+// AddPatientTags merges tags into the patient's meta.tag and returns the resulting tags
+// Tags are workflow labels outside the resource content. As with FHIR $meta-add, changing them creates no
+// version, so nothing is written to the change log or published: a change log entry is a version whose
+// snapshot would differ only in meta. Consumers that react to tags search with _tag instead
+func (service *PatientService) AddPatientTags(ctx context.Context, patientID string, tags models.Tags) (models.Tags, error) {
+	return service.updatePatientTags(ctx, patientID, func(currentTags models.Tags) models.Tags {
+		return currentTags.Add(tags...)
+	})
+}
+
+// RemovePatientTags removes tags from the patient's meta.tag and returns the remaining tags
+func (service *PatientService) RemovePatientTags(ctx context.Context, patientID string, tags models.Tags) (models.Tags, error) {
+	return service.updatePatientTags(ctx, patientID, func(currentTags models.Tags) models.Tags {
+		return currentTags.Remove(tags...)
+	})
+}
+
+// updatePatientTags applies a tag change, reporting ErrResourceNotFound for unknown patients
+func (service *PatientService) updatePatientTags(ctx context.Context, patientID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	if _, parseError := uuid.Parse(patientID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	updatedTags, updateError := service.patientRepository.UpdateTags(ctx, patientID, change)
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	return updatedTags, updateError
+}
+
+// markMerged records source as merged into target: inactive, and replaced by target
+// It is versioned and published like any other update, while merge listeners are told about the merge itself
+func (service *PatientService) markMerged(ctx context.Context, source *models.Patient, target *models.Patient) error {
+	mergedSource := *source
+	mergedSource.Active = false
+	mergedSource.ReplacedBy = target.ID
+
+	notifyMerge := func(transactionContext context.Context, listener PatientWriteListener, writtenPatient *models.Patient) error {
+		if mergeListener, isMergeListener := listener.(PatientMergeListener); isMergeListener {
+			return mergeListener.PatientMerged(transactionContext, writtenPatient, target)
+		}
+		return listener.PatientWritten(transactionContext, models.ChangeOperationUpdate, writtenPatient)
+	}
+	_, updateError := service.commitWriteNotifying(ctx, source.ID, models.ChangeOperationUpdate, func(transactionContext context.Context) (*models.Patient, error) {
+		return service.patientRepository.Update(transactionContext, &mergedSource)
+	}, notifyMerge)
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return ErrResourceNotFound
+	}
+	return updateError
+}
+
+// commitWrite runs write, records it in the change log, and notifies write listeners in one transaction,
+// then publishes it to event consumers
+// write returns the stored patient, or nil for deletes; the FHIR form of that patient is returned to the
+// caller and kept as the version's snapshot. The write is rolled back when its change cannot be recorded
+func (service *PatientService) commitWrite(ctx context.Context, patientID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.Patient, error)) (*fhir.Patient, error) {
+	return service.commitWriteNotifying(ctx, patientID, operation, write, func(transactionContext context.Context, listener PatientWriteListener, writtenPatient *models.Patient) error {
+		return listener.PatientWritten(transactionContext, operation, writtenPatient)
+	})
+}
+
+// commitWriteNotifying is commitWrite with the call that tells each write listener about the stored patient
+func (service *PatientService) commitWriteNotifying(ctx context.Context, patientID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.Patient, error), notify func(ctx context.Context, listener PatientWriteListener, writtenPatient *models.Patient) error) (*fhir.Patient, error) {
+	var writtenPatient *models.Patient
+	var writtenFHIRPatient *fhir.Patient
+	var version int
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenPatient, writeError = write(transactionContext)
+		if writeError != nil {
+			return writeError
+		}
+
+		var snapshot interface{}
+		if writtenPatient != nil {
+			patientID = writtenPatient.ID
+			writtenFHIRPatient = service.patientMapper.ToFHIR(writtenPatient)
+			snapshot = writtenFHIRPatient
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "Patient", patientID, operation, snapshot, patientID)
+		if recordError != nil || writtenPatient == nil {
+			return recordError
+		}
+
+		for _, listener := range service.writeListeners {
+			if listenerError := notify(transactionContext, listener, writtenPatient); listenerError != nil {
+				return listenerError
+			}
+		}
+		return nil
+	})
+	if transactionError != nil {
+		return nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenPatient != nil {
+		resource = writtenPatient
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "Patient", patientID, operation, version, resource)
+	return writtenFHIRPatient, nil
 }
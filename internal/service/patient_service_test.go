@@ -2,25 +2,32 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
 	"github.com/samply/golang-fhir-models/fhir-models/fhir"
 )
 
 // MockPatientRepository implements PatientRepository interface for testing
 type MockPatientRepository struct {
-	patients       map[string]*models.Patient
-	createError    error
-	getByIDError   error
-	getAllError    error
-	updateError    error
-	deleteError    error
-	lastCreated    *models.Patient
-	lastUpdated    *models.Patient
-	lastDeletedID  string
+	patients      map[string]*models.Patient
+	createError   error
+	getByIDError  error
+	getAllError   error
+	updateError   error
+	deleteError   error
+	lastCreated   *models.Patient
+	lastUpdated   *models.Patient
+	lastDeletedID string
+	lastSearch    *models.PatientSearchParams
 }
 
 // NewMockPatientRepository creates a new mock repository for testing
@@ -51,7 +58,7 @@ func (mock *MockPatientRepository) GetByID(ctx context.Context, patientID string
 	}
 	patient, exists := mock.patients[patientID]
 	if !exists {
-		return nil, errors.New("patient not found")
+		return nil, sql.ErrNoRows
 	}
 	return patient, nil
 }
@@ -81,6 +88,7 @@ func (mock *MockPatientRepository) Update(ctx context.Context, patient *models.P
 
 // Search retrieves patients matching search criteria
 func (mock *MockPatientRepository) Search(ctx context.Context, searchParams *models.PatientSearchParams) ([]*models.Patient, error) {
+	mock.lastSearch = searchParams
 	if mock.getAllError != nil {
 		return nil, mock.getAllError
 	}
@@ -91,6 +99,28 @@ func (mock *MockPatientRepository) Search(ctx context.Context, searchParams *mod
 	return result, nil
 }
 
+// Count returns how many patients are stored, as every search matches them all
+func (mock *MockPatientRepository) Count(ctx context.Context, searchParams *models.PatientSearchParams) (int, error) {
+	mock.lastSearch = searchParams
+	if mock.getAllError != nil {
+		return 0, mock.getAllError
+	}
+	return len(mock.patients), nil
+}
+
+func (mock *MockPatientRepository) Stream(ctx context.Context, searchParams *models.PatientSearchParams, visit func(patient *models.Patient) error) error {
+	results, searchError := mock.Search(ctx, searchParams)
+	if searchError != nil {
+		return searchError
+	}
+	for _, result := range results {
+		if visitError := visit(result); visitError != nil {
+			return visitError
+		}
+	}
+	return nil
+}
+
 // Delete removes a patient by ID
 func (mock *MockPatientRepository) Delete(ctx context.Context, patientID string) error {
 	if mock.deleteError != nil {
@@ -101,6 +131,21 @@ func (mock *MockPatientRepository) Delete(ctx context.Context, patientID string)
 	return nil
 }
 
+// UpdateTags applies a tag change to a stored patient
+func (mock *MockPatientRepository) UpdateTags(ctx context.Context, patientID string, change func(models.Tags) models.Tags) (models.Tags, error) {
+	patient, exists := mock.patients[patientID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	patient.Tags = change(patient.Tags)
+	return patient.Tags, nil
+}
+
+// deletedError is the error a repository returns for a resource it marked deleted, matching its notFound error
+func deletedError(notFound error) error {
+	return fmt.Errorf("%w: %w", notFound, ErrResourceDeleted)
+}
+
 // TestPatientService_CreatePatient verifies patient creation through service layer
 func TestPatientService_CreatePatient(t *testing.T) {
 	mockRepo := NewMockPatientRepository()
@@ -310,6 +355,32 @@ func TestPatientService_UpdatePatient(t *testing.T) {
 	}
 }
 
+// TestPatientService_UpdatePatient_NotFound verifies updating an unknown patient reports ErrResourceNotFound
+func TestPatientService_UpdatePatient_NotFound(t *testing.T) {
+	mockRepo := NewMockPatientRepository()
+	mockRepo.updateError = sql.ErrNoRows
+	patientService := NewPatientService(mockRepo)
+
+	familyName := "Smith"
+	_, updateError := patientService.UpdatePatient(context.Background(), "missing-uuid", &fhir.Patient{Name: []fhir.HumanName{{Family: &familyName}}})
+	if !errors.Is(updateError, ErrResourceNotFound) {
+		t.Errorf("Expected ErrResourceNotFound, got %v", updateError)
+	}
+}
+
+// TestPatientService_UpdatePatient_Deleted verifies updating a deleted patient reports ErrResourceDeleted
+func TestPatientService_UpdatePatient_Deleted(t *testing.T) {
+	mockRepo := NewMockPatientRepository()
+	mockRepo.updateError = deletedError(sql.ErrNoRows)
+	patientService := NewPatientService(mockRepo)
+
+	familyName := "Smith"
+	_, updateError := patientService.UpdatePatient(context.Background(), "deleted-uuid", &fhir.Patient{Name: []fhir.HumanName{{Family: &familyName}}})
+	if updateError != ErrResourceDeleted {
+		t.Errorf("Expected ErrResourceDeleted, got %v", updateError)
+	}
+}
+
 // TestPatientService_DeletePatient verifies patient deletion
 func TestPatientService_DeletePatient(t *testing.T) {
 	mockRepo := NewMockPatientRepository()
@@ -332,6 +403,72 @@ func TestPatientService_DeletePatient(t *testing.T) {
 	}
 }
 
+// stubPatientObservations keeps observation IDs by patient for the delete policy tests
+type stubPatientObservations struct {
+	patientIDs map[string]string
+}
+
+// CountObservations counts the searched patient's observations
+func (stub *stubPatientObservations) CountObservations(ctx context.Context, searchParams *models.ObservationSearchParams) (int, error) {
+	observations, _ := stub.SearchObservations(ctx, searchParams)
+	return len(observations), nil
+}
+
+// SearchObservations returns up to Limit of the searched patient's observations
+func (stub *stubPatientObservations) SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error) {
+	observations := []*fhir.Observation{}
+	for observationID, patientID := range stub.patientIDs {
+		if patientID == searchParams.PatientID && (searchParams.Limit == 0 || len(observations) < searchParams.Limit) {
+			observations = append(observations, &fhir.Observation{Id: &observationID})
+		}
+	}
+	return observations, nil
+}
+
+// DeleteObservation forgets the observation
+func (stub *stubPatientObservations) DeleteObservation(ctx context.Context, observationID string) error {
+	delete(stub.patientIDs, observationID)
+	return nil
+}
+
+// TestPatientService_DeletePolicy verifies referenced patients are kept under the block policy and deleted
+// together with their observations under the cascade policy
+func TestPatientService_DeletePolicy(t *testing.T) {
+	for _, policy := range []PatientDeletePolicy{PatientDeleteBlock, PatientDeleteCascade} {
+		mockRepo := NewMockPatientRepository()
+		mockRepo.patients["referenced-uuid"] = &models.Patient{ID: "referenced-uuid"}
+		observations := &stubPatientObservations{patientIDs: map[string]string{"obs-1": "referenced-uuid", "obs-2": "other-uuid"}}
+		for index := 0; index < cascadeBatchSize+5; index++ {
+			observations.patientIDs[fmt.Sprintf("obs-batch-%d", index)] = "referenced-uuid"
+		}
+		patientService := NewPatientService(mockRepo)
+		patientService.SetDeletePolicy(policy, observations)
+
+		deleteError := patientService.DeletePatient(context.Background(), "referenced-uuid")
+
+		switch policy {
+		case PatientDeleteBlock:
+			var appError *apperrors.AppError
+			if !errors.As(deleteError, &appError) || appError.StatusCode != http.StatusConflict {
+				t.Errorf("block: expected 409, got %v", deleteError)
+			}
+			if mockRepo.lastDeletedID != "" || len(observations.patientIDs) != cascadeBatchSize+7 {
+				t.Errorf("block: expected the patient and its observations to be kept")
+			}
+		case PatientDeleteCascade:
+			if deleteError != nil {
+				t.Fatalf("cascade: expected no error, got %v", deleteError)
+			}
+			if mockRepo.lastDeletedID != "referenced-uuid" {
+				t.Errorf("cascade: expected the patient to be deleted")
+			}
+			if len(observations.patientIDs) != 1 || observations.patientIDs["obs-2"] != "other-uuid" {
+				t.Errorf("cascade: expected only the other patient's observation to remain, got %v", observations.patientIDs)
+			}
+		}
+	}
+}
+
 // TestPatientService_SearchPatients tests search functionality
 func TestPatientService_SearchPatients(t *testing.T) {
 	// Setup mock repository
@@ -365,7 +502,7 @@ func TestPatientService_SearchPatients(t *testing.T) {
 
 	// Execute search
 	searchParams := &models.PatientSearchParams{
-		Name:   "Smith",
+		Name:   models.NameCriteria{{Values: []string{"Smith"}}},
 		Limit:  10,
 		Offset: 0,
 	}
@@ -421,3 +558,62 @@ func TestNewPatientService(t *testing.T) {
 		t.Error("Expected NewPatientService to return non-nil instance")
 	}
 }
+
+// upperCaseNameHook upper-cases stored family names
+type upperCaseNameHook struct {
+	models.BasePatientMappingHook
+}
+
+// PostFromFHIR upper-cases the family name
+func (upperCaseNameHook) PostFromFHIR(fhirPatient *fhir.Patient, patient *models.Patient) {
+	patient.FamilyName = strings.ToUpper(patient.FamilyName)
+}
+
+// TestPatientService_AddMappingHook verifies registered hooks apply to writes
+func TestPatientService_AddMappingHook(t *testing.T) {
+	mockRepo := NewMockPatientRepository()
+	service := NewPatientService(mockRepo)
+	service.AddMappingHook(upperCaseNameHook{})
+
+	familyName := "smith"
+	service.CreatePatient(context.Background(), &fhir.Patient{Name: []fhir.HumanName{{Family: &familyName}}})
+
+	if mockRepo.lastCreated == nil || mockRepo.lastCreated.FamilyName != "SMITH" {
+		t.Errorf("Expected hook to upper-case the stored family name, got %+v", mockRepo.lastCreated)
+	}
+}
+
+// staticIdentifierSystems treats every listed system as the same identifier system
+type staticIdentifierSystems []string
+
+// EquivalentIdentifierSystems returns every listed system when the system is one of them
+func (systems staticIdentifierSystems) EquivalentIdentifierSystems(system string) []string {
+	for _, listedSystem := range systems {
+		if listedSystem == system {
+			return systems
+		}
+	}
+	return []string{system}
+}
+
+// TestPatientService_SearchPatients_IdentifierAliases verifies identifier searches match every alias of the system
+func TestPatientService_SearchPatients_IdentifierAliases(t *testing.T) {
+	mockRepo := NewMockPatientRepository()
+	patientService := NewPatientService(mockRepo)
+	patientService.SetIdentifierSystemNormalizer(staticIdentifierSystems{"http://hospital.example.org/mrn", "MRN"})
+
+	searchParams := &models.PatientSearchParams{Identifier: &models.IdentifierCriterion{Systems: []string{"MRN"}, Value: "12345"}}
+	patientService.SearchPatients(context.Background(), searchParams)
+
+	if !reflect.DeepEqual(mockRepo.lastSearch.Identifier.Systems, []string{"http://hospital.example.org/mrn", "MRN"}) || mockRepo.lastSearch.Identifier.Value != "12345" {
+		t.Errorf("Expected the canonical system and its aliases searched, got %+v", mockRepo.lastSearch.Identifier)
+	}
+	if !reflect.DeepEqual(searchParams.Identifier.Systems, []string{"MRN"}) {
+		t.Errorf("Expected the caller's parameters unchanged, got %+v", searchParams.Identifier)
+	}
+
+	patientService.SearchPatients(context.Background(), &models.PatientSearchParams{Identifier: &models.IdentifierCriterion{Value: "12345"}})
+	if len(mockRepo.lastSearch.Identifier.Systems) != 0 {
+		t.Errorf("Expected a value-only search to match any system, got %+v", mockRepo.lastSearch.Identifier)
+	}
+}
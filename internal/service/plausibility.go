@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/plausibility"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// applyPlausibility acts on observation values outside their plausible ranges before the write
+// Rejected values fail the write; flagged ones mark the observation questionable and warn the caller;
+// every finding is logged so device glitches can be traced whichever action is configured
+func applyPlausibility(ctx context.Context, checker *plausibility.Checker, observation *models.Observation) error {
+	if checker == nil {
+		return nil
+	}
+
+	findings := checker.Check(observation)
+	if len(findings) == 0 {
+		return nil
+	}
+
+	var rejectedIssues []outcome.Issue
+	for _, finding := range findings {
+		log.Warn().
+			Str("patient_id", observation.PatientID).
+			Str("code", finding.Rule.Code).
+			Float64("value", finding.Value).
+			Str("action", finding.Action).
+			Msg(finding.Diagnostics())
+
+		switch finding.Action {
+		case plausibility.ActionReject:
+			rejectedIssues = append(rejectedIssues, outcome.Error(fhir.IssueTypeBusinessRule, finding.Diagnostics(), finding.Expression))
+		case plausibility.ActionFlag:
+			observation.Interpretation = plausibility.Questionable()
+			outcome.Collect(ctx, outcome.Warning(fhir.IssueTypeBusinessRule, finding.Diagnostics()+"; stored as questionable", finding.Expression))
+		}
+	}
+
+	if len(rejectedIssues) > 0 {
+		return &outcome.RejectionError{Issues: rejectedIssues}
+	}
+	return nil
+}
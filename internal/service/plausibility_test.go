@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/plausibility"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// heartRateObservation builds a FHIR heart rate observation with the given value
+func heartRateObservation(value string) *fhir.Observation {
+	code := "8867-4"
+	unit := "/min"
+	patientReference := "Patient/patient-123"
+	number := json.Number(value)
+	return &fhir.Observation{
+		Status:        fhir.ObservationStatusFinal,
+		Code:          fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &code}}},
+		Subject:       &fhir.Reference{Reference: &patientReference},
+		ValueQuantity: &fhir.Quantity{Value: &number, Unit: &unit},
+	}
+}
+
+// TestObservationService_Plausibility verifies implausible values are flagged or rejected as configured
+func TestObservationService_Plausibility(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	observationService.SetPlausibilityChecker(plausibility.NewChecker(plausibility.DefaultRules()))
+
+	ctx, collector := outcome.WithCollector(context.Background())
+	created, createError := observationService.CreateObservation(ctx, heartRateObservation("950"))
+	if createError != nil {
+		t.Fatalf("Expected a flagged observation to be stored, got %v", createError)
+	}
+	if len(created.Interpretation) != 1 || *created.Interpretation[0].Coding[0].Code != plausibility.QuestionableCode {
+		t.Errorf("Expected a questionable interpretation, got %+v", created.Interpretation)
+	}
+	if len(collector.Issues()) != 1 {
+		t.Errorf("Expected one warning for the caller, got %+v", collector.Issues())
+	}
+
+	if _, normalError := observationService.CreateObservation(context.Background(), heartRateObservation("72")); normalError != nil || mockRepo.lastCreated.Interpretation != nil {
+		t.Errorf("Expected a plausible value stored without interpretation, got %+v (%v)", mockRepo.lastCreated.Interpretation, normalError)
+	}
+
+	rules := plausibility.DefaultRules()
+	rules.DefaultAction = plausibility.ActionReject
+	observationService.SetPlausibilityChecker(plausibility.NewChecker(rules))
+	mockRepo.lastCreated = nil
+	_, rejectError := observationService.CreateObservation(context.Background(), heartRateObservation("950"))
+	var rejection *outcome.RejectionError
+	if !errors.As(rejectError, &rejection) || mockRepo.lastCreated != nil {
+		t.Errorf("Expected the write rejected before storing, got %v", rejectError)
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// PractitionerService handles business logic for Practitioner operations
+type PractitionerService struct {
+	practitionerRepository repository.PractitionerRepository
+	practitionerMapper     *models.PractitionerMapper
+	changeRepository       repository.ChangeRepository
+	eventPublisher         events.Publisher
+	strictMapping          bool
+}
+
+// NewPractitionerService creates a new instance of PractitionerService
+func NewPractitionerService(practitionerRepository repository.PractitionerRepository) *PractitionerService {
+	return &PractitionerService{
+		practitionerRepository: practitionerRepository,
+		practitionerMapper:     models.NewPractitionerMapper(),
+	}
+}
+
+// SetChangeRepository enables recording practitioner writes to the change log
+func (service *PractitionerService) SetChangeRepository(changeRepository repository.ChangeRepository) {
+	service.changeRepository = changeRepository
+}
+
+// SetEventPublisher enables publishing practitioner write events to internal consumers
+func (service *PractitionerService) SetEventPublisher(eventPublisher events.Publisher) {
+	service.eventPublisher = eventPublisher
+}
+
+// SetStrictMapping rejects writes whose data cannot be fully mapped instead of storing them with warnings
+func (service *PractitionerService) SetStrictMapping(strict bool) {
+	service.strictMapping = strict
+}
+
+// CreatePractitioner creates a new practitioner from a FHIR Practitioner resource
+func (service *PractitionerService) CreatePractitioner(ctx context.Context, fhirPractitioner *fhir.Practitioner) (*fhir.Practitioner, error) {
+	domainPractitioner, mappingIssues := service.practitionerMapper.FromFHIR(fhirPractitioner)
+	if issuesError := handleMappingIssues(ctx, "Practitioner", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	// Set default active status if not provided
+	if fhirPractitioner.Active == nil {
+		domainPractitioner.Active = true
+	}
+
+	createdFHIRPractitioner, createError := service.commitWrite(ctx, "", models.ChangeOperationCreate, func(transactionContext context.Context) (*models.Practitioner, error) {
+		return service.practitionerRepository.Create(transactionContext, domainPractitioner)
+	})
+	if createError != nil {
+		return nil, createError
+	}
+
+	reportIgnoredElements(ctx, "Practitioner", fhirPractitioner, createdFHIRPractitioner, mappingIssues)
+	return createdFHIRPractitioner, nil
+}
+
+// GetPractitionerByID retrieves a practitioner by ID, reporting ErrResourceNotFound for unknown practitioners
+func (service *PractitionerService) GetPractitionerByID(ctx context.Context, practitionerID string) (*fhir.Practitioner, error) {
+	if _, parseError := uuid.Parse(practitionerID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainPractitioner, getError := service.practitionerRepository.GetByID(ctx, practitionerID)
+	if errors.Is(getError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+
+	return service.practitionerMapper.ToFHIR(domainPractitioner), nil
+}
+
+// SearchPractitioners retrieves practitioners matching the search criteria
+func (service *PractitionerService) SearchPractitioners(ctx context.Context, searchParams *models.PractitionerSearchParams) ([]*fhir.Practitioner, error) {
+	domainPractitioners, searchError := service.practitionerRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirPractitioners := make([]*fhir.Practitioner, len(domainPractitioners))
+	for index, domainPractitioner := range domainPractitioners {
+		fhirPractitioners[index] = service.practitionerMapper.ToFHIR(domainPractitioner)
+	}
+
+	return fhirPractitioners, nil
+}
+
+// CountPractitioners returns how many practitioners match the search across all pages, used as the searchset total
+func (service *PractitionerService) CountPractitioners(ctx context.Context, searchParams *models.PractitionerSearchParams) (int, error) {
+	return service.practitionerRepository.Count(ctx, searchParams)
+}
+
+// UpdatePractitioner updates an existing practitioner, reporting ErrResourceNotFound for unknown practitioners
+func (service *PractitionerService) UpdatePractitioner(ctx context.Context, practitionerID string, fhirPractitioner *fhir.Practitioner) (*fhir.Practitioner, error) {
+	if _, parseError := uuid.Parse(practitionerID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainPractitioner, mappingIssues := service.practitionerMapper.FromFHIR(fhirPractitioner)
+	if issuesError := handleMappingIssues(ctx, "Practitioner", service.strictMapping, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+	domainPractitioner.ID = practitionerID
+
+	updatedFHIRPractitioner, updateError := service.commitWrite(ctx, practitionerID, models.ChangeOperationUpdate, func(transactionContext context.Context) (*models.Practitioner, error) {
+		return service.practitionerRepository.Update(transactionContext, domainPractitioner)
+	})
+	if errors.Is(updateError, ErrResourceDeleted) {
+		return nil, ErrResourceDeleted
+	}
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+
+	reportIgnoredElements(ctx, "Practitioner", fhirPractitioner, updatedFHIRPractitioner, mappingIssues)
+	return updatedFHIRPractitioner, nil
+}
+
+// DeletePractitioner removes a practitioner by ID, reporting ErrResourceNotFound for unknown practitioners
+// Observations that name the practitioner as performer keep their reference
+func (service *PractitionerService) DeletePractitioner(ctx context.Context, practitionerID string) error {
+	if _, parseError := uuid.Parse(practitionerID); parseError != nil {
+		return ErrResourceNotFound
+	}
+
+	_, deleteError := service.commitWrite(ctx, practitionerID, models.ChangeOperationDelete, func(transactionContext context.Context) (*models.Practitioner, error) {
+		return nil, service.practitionerRepository.Delete(transactionContext, practitionerID)
+	})
+	if errors.Is(deleteError, ErrResourceDeleted) {
+		return ErrResourceDeleted
+	}
+	if errors.Is(deleteError, sql.ErrNoRows) {
+		return ErrResourceNotFound
+	}
+	return deleteError
+}
+
+// commitWrite runs write and records it in the change log in one transaction, then publishes it to event consumers
+// write returns the stored practitioner, or nil for deletes; its FHIR form is returned and kept as the version's snapshot
+// Practitioners belong to no patient compartment, so their changes are recorded without one
+func (service *PractitionerService) commitWrite(ctx context.Context, practitionerID string, operation models.ChangeOperation, write func(ctx context.Context) (*models.Practitioner, error)) (*fhir.Practitioner, error) {
+	var writtenPractitioner *models.Practitioner
+	var writtenFHIRPractitioner *fhir.Practitioner
+	var version int
+	transactionError := inChangeTransaction(ctx, service.changeRepository, func(transactionContext context.Context) error {
+		var writeError error
+		writtenPractitioner, writeError = write(transactionContext)
+		if writeError != nil {
+			return writeError
+		}
+
+		var snapshot interface{}
+		if writtenPractitioner != nil {
+			practitionerID = writtenPractitioner.ID
+			writtenFHIRPractitioner = service.practitionerMapper.ToFHIR(writtenPractitioner)
+			snapshot = writtenFHIRPractitioner
+		}
+
+		var recordError error
+		version, recordError = recordChange(transactionContext, service.changeRepository, "Practitioner", practitionerID, operation, snapshot, "")
+		return recordError
+	})
+	if transactionError != nil {
+		return nil, transactionError
+	}
+
+	var resource interface{}
+	if writtenPractitioner != nil {
+		resource = writtenPractitioner
+	}
+	publishWriteEvent(ctx, service.eventPublisher, "Practitioner", practitionerID, operation, version, resource)
+	return writtenFHIRPractitioner, nil
+}
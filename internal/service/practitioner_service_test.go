@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockPractitionerRepository implements PractitionerRepository interface for testing
+type MockPractitionerRepository struct {
+	practitioners map[string]*models.Practitioner
+	deleted       map[string]bool
+}
+
+// NewMockPractitionerRepository creates a new mock repository for testing
+func NewMockPractitionerRepository() *MockPractitionerRepository {
+	return &MockPractitionerRepository{practitioners: make(map[string]*models.Practitioner), deleted: make(map[string]bool)}
+}
+
+// Create stores a practitioner under a generated UUID
+func (mock *MockPractitionerRepository) Create(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error) {
+	practitioner.ID = uuid.NewString()
+	mock.practitioners[practitioner.ID] = practitioner
+	return practitioner, nil
+}
+
+// GetByID retrieves a stored practitioner
+func (mock *MockPractitionerRepository) GetByID(ctx context.Context, practitionerID string) (*models.Practitioner, error) {
+	if mock.deleted[practitionerID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	practitioner, exists := mock.practitioners[practitionerID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return practitioner, nil
+}
+
+// Search returns every stored practitioner
+func (mock *MockPractitionerRepository) Search(ctx context.Context, searchParams *models.PractitionerSearchParams) ([]*models.Practitioner, error) {
+	result := make([]*models.Practitioner, 0, len(mock.practitioners))
+	for _, practitioner := range mock.practitioners {
+		result = append(result, practitioner)
+	}
+	return result, nil
+}
+
+// Count returns how many practitioners are stored, as every search matches them all
+func (mock *MockPractitionerRepository) Count(ctx context.Context, searchParams *models.PractitionerSearchParams) (int, error) {
+	return len(mock.practitioners), nil
+}
+
+// Update replaces a stored practitioner
+func (mock *MockPractitionerRepository) Update(ctx context.Context, practitioner *models.Practitioner) (*models.Practitioner, error) {
+	if mock.deleted[practitioner.ID] {
+		return nil, deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.practitioners[practitioner.ID]; !exists {
+		return nil, sql.ErrNoRows
+	}
+	mock.practitioners[practitioner.ID] = practitioner
+	return practitioner, nil
+}
+
+// Delete marks a stored practitioner deleted, as the repository does
+func (mock *MockPractitionerRepository) Delete(ctx context.Context, practitionerID string) error {
+	if mock.deleted[practitionerID] {
+		return deletedError(sql.ErrNoRows)
+	}
+	if _, exists := mock.practitioners[practitionerID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.practitioners, practitionerID)
+	mock.deleted[practitionerID] = true
+	return nil
+}
+
+// TestPractitionerService_Lifecycle verifies each write is recorded in the change log without a patient compartment
+func TestPractitionerService_Lifecycle(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	practitionerService := NewPractitionerService(NewMockPractitionerRepository())
+	practitionerService.SetChangeRepository(changeRepository)
+	ctx := context.Background()
+
+	familyName := "House"
+	createdPractitioner, createError := practitionerService.CreatePractitioner(ctx, &fhir.Practitioner{Name: []fhir.HumanName{{Family: &familyName}}})
+	if createError != nil {
+		t.Fatalf("Expected no error creating the practitioner, got %v", createError)
+	}
+	if createdPractitioner.Active == nil || !*createdPractitioner.Active {
+		t.Errorf("Expected practitioners to default to active")
+	}
+
+	practitionerID := *createdPractitioner.Id
+	updatedFamilyName := "Wilson"
+	if _, updateError := practitionerService.UpdatePractitioner(ctx, practitionerID, &fhir.Practitioner{Name: []fhir.HumanName{{Family: &updatedFamilyName}}}); updateError != nil {
+		t.Fatalf("Expected no error updating the practitioner, got %v", updateError)
+	}
+	if deleteError := practitionerService.DeletePractitioner(ctx, practitionerID); deleteError != nil {
+		t.Fatalf("Expected no error deleting the practitioner, got %v", deleteError)
+	}
+	if _, getError := practitionerService.GetPractitionerByID(ctx, practitionerID); !errors.Is(getError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted reading the deleted practitioner, got %v", getError)
+	}
+	if deleteError := practitionerService.DeletePractitioner(ctx, practitionerID); !errors.Is(deleteError, ErrResourceDeleted) {
+		t.Errorf("Expected ErrResourceDeleted deleting twice, got %v", deleteError)
+	}
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	for index, expectedOperation := range []models.ChangeOperation{models.ChangeOperationCreate, models.ChangeOperationUpdate, models.ChangeOperationDelete} {
+		change := changeRepository.changes[index]
+		if change.ResourceType != "Practitioner" || change.ResourceID != practitionerID || change.Operation != expectedOperation || change.CompartmentPatientID != "" {
+			t.Errorf("Change %d: expected Practitioner/%s %s outside any compartment, got %+v", index, practitionerID, expectedOperation, change)
+		}
+	}
+}
+
+// TestPractitionerService_NotFound verifies unknown and malformed IDs are reported as ErrResourceNotFound
+func TestPractitionerService_NotFound(t *testing.T) {
+	practitionerService := NewPractitionerService(NewMockPractitionerRepository())
+	ctx := context.Background()
+
+	for _, practitionerID := range []string{"not-a-uuid", "6f1c2a3e-8d4b-4c5a-9e7f-0a1b2c3d4e5f"} {
+		if _, getError := practitionerService.GetPractitionerByID(ctx, practitionerID); !errors.Is(getError, ErrResourceNotFound) {
+			t.Errorf("Expected ErrResourceNotFound reading %s, got %v", practitionerID, getError)
+		}
+		if _, updateError := practitionerService.UpdatePractitioner(ctx, practitionerID, &fhir.Practitioner{}); !errors.Is(updateError, ErrResourceNotFound) {
+			t.Errorf("Expected ErrResourceNotFound updating %s, got %v", practitionerID, updateError)
+		}
+		if deleteError := practitionerService.DeletePractitioner(ctx, practitionerID); !errors.Is(deleteError, ErrResourceNotFound) {
+			t.Errorf("Expected ErrResourceNotFound deleting %s, got %v", practitionerID, deleteError)
+		}
+	}
+}
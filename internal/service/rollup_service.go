@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// RollupService reads the daily observation rollups computed by the rollup job
+type RollupService struct {
+	rollupRepository repository.RollupRepository
+}
+
+// NewRollupService creates a new rollup service instance
+func NewRollupService(rollupRepository repository.RollupRepository) *RollupService {
+	return &RollupService{
+		rollupRepository: rollupRepository,
+	}
+}
+
+// DailyRollups returns a patient's daily aggregates for one code, oldest day first
+func (service *RollupService) DailyRollups(ctx context.Context, query models.RollupQuery) ([]*models.ObservationRollup, error) {
+	if query.PatientID == "" || query.Code == "" {
+		return nil, apperrors.ValidationError("patient and code are required")
+	}
+	if query.From != nil && query.To != nil && query.To.Before(*query.From) {
+		return nil, apperrors.InvalidInput("end", "must not be before start")
+	}
+
+	rollups, listError := service.rollupRepository.List(ctx, query)
+	if listError != nil {
+		return nil, apperrors.Internal("Failed to read observation rollups", listError)
+	}
+	return rollups, nil
+}
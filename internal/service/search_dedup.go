@@ -0,0 +1,35 @@
+package service
+
+import (
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// copyPatients copies search results shared with other callers
+// The mapper and handlers write through pointers into the domain values (e.g. exposing opaque IDs),
+// so each caller needs its own copies before converting them
+func copyPatients(patients []*models.Patient) []*models.Patient {
+	copies := make([]*models.Patient, len(patients))
+	for index, patient := range patients {
+		patientCopy := *patient
+		patientCopy.Tags = append(models.Tags(nil), patient.Tags...)
+		copies[index] = &patientCopy
+	}
+	return copies
+}
+
+// copyObservations copies search results shared with other callers, as copyPatients does for patients
+func copyObservations(observations []*models.Observation) []*models.Observation {
+	copies := make([]*models.Observation, len(observations))
+	for index, observation := range observations {
+		observationCopy := *observation
+		observationCopy.Components = append([]models.ObservationComponent(nil), observation.Components...)
+		observationCopy.ReferenceRanges = append([]models.ReferenceRange(nil), observation.ReferenceRanges...)
+		observationCopy.Tags = append(models.Tags(nil), observation.Tags...)
+		if observation.Interpretation != nil {
+			interpretationCopy := *observation.Interpretation
+			observationCopy.Interpretation = &interpretationCopy
+		}
+		copies[index] = &observationCopy
+	}
+	return copies
+}
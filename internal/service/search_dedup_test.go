@@ -0,0 +1,32 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestCopyObservations_DoNotAliasSharedResults verifies callers can modify copies without touching the shared results
+func TestCopyObservations_DoNotAliasSharedResults(t *testing.T) {
+	shared := []*models.Observation{{
+		ID:             "obs-1",
+		Components:     []models.ObservationComponent{{Code: "8480-6"}},
+		Tags:           models.Tags{{Code: "reviewed"}},
+		Interpretation: &models.Interpretation{Code: "H"},
+	}}
+
+	copies := copyObservations(shared)
+	copies[0].ID = "exposed-id"
+	copies[0].Components[0].Code = "changed"
+	copies[0].Tags[0].Code = "changed"
+	copies[0].Interpretation.Code = "changed"
+
+	if shared[0].ID != "obs-1" || shared[0].Components[0].Code != "8480-6" || shared[0].Tags[0].Code != "reviewed" || shared[0].Interpretation.Code != "H" {
+		t.Errorf("Expected the shared observation unchanged, got %+v", shared[0])
+	}
+
+	patients := copyPatients([]*models.Patient{{ID: "patient-1"}})
+	if patients[0].ID != "patient-1" {
+		t.Errorf("Expected the patient copied, got %+v", patients[0])
+	}
+}
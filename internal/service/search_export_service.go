@@ -0,0 +1,406 @@
+package service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SearchExportKeySize is the length in bytes of the key that seals export files and signs download links
+const SearchExportKeySize = 32
+
+// PatientSearcher searches patients; PatientService implements it
+type PatientSearcher interface {
+	SearchPatients(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, error)
+}
+
+// ExportResourceExposer rewrites the IDs in an exported resource to the ones clients of the context's tenant see
+type ExportResourceExposer func(ctx context.Context, resource interface{})
+
+// SearchExportPolicy bounds search exports and how long their files and links last
+type SearchExportPolicy struct {
+	// MaxResources caps the resources written to one export; the rest are left out and the export marked truncated
+	MaxResources int
+
+	// PageSize is the number of resources read per search while materializing
+	PageSize int
+
+	// Retention is how long a finished export can be downloaded before its file is deleted
+	Retention time.Duration
+
+	// LinkTTL is how long a signed download link stays valid
+	LinkTTL time.Duration
+
+	// PollInterval is how often the runner looks for pending exports when idle
+	PollInterval time.Duration
+
+	// StaleAfter is how long an export may stay running before another runner takes it over
+	StaleAfter time.Duration
+}
+
+// DefaultSearchExportPolicy returns the export limits used unless configured otherwise
+func DefaultSearchExportPolicy() SearchExportPolicy {
+	return SearchExportPolicy{
+		MaxResources: 100000,
+		PageSize:     500,
+		Retention:    24 * time.Hour,
+		LinkTTL:      15 * time.Minute,
+		PollInterval: 5 * time.Second,
+		StaleAfter:   30 * time.Minute,
+	}
+}
+
+// SearchExportService materializes search results into encrypted files served over signed, expiring links
+// Exports are requested by an authenticated caller and only shown to that caller; every download is audited
+type SearchExportService struct {
+	exportRepository    repository.SearchExportRepository
+	patientSearcher     PatientSearcher
+	observationSearcher ObservationSearcher
+	fileCipher          cipher.AEAD
+	signingKey          []byte
+	policy              SearchExportPolicy
+	exposeResource      ExportResourceExposer
+	now                 func() time.Time
+}
+
+// NewSearchExportService creates a search export service sealing files with key, which must be SearchExportKeySize bytes
+// The link signing key is derived from key, so one secret covers both
+func NewSearchExportService(exportRepository repository.SearchExportRepository, patientSearcher PatientSearcher, observationSearcher ObservationSearcher, key []byte, policy SearchExportPolicy) (*SearchExportService, error) {
+	if len(key) != SearchExportKeySize {
+		return nil, fmt.Errorf("search export key must be %d bytes, got %d", SearchExportKeySize, len(key))
+	}
+	block, blockError := aes.NewCipher(key)
+	if blockError != nil {
+		return nil, fmt.Errorf("failed to create export cipher: %w", blockError)
+	}
+	fileCipher, gcmError := cipher.NewGCM(block)
+	if gcmError != nil {
+		return nil, fmt.Errorf("failed to create export cipher: %w", gcmError)
+	}
+
+	keyDerivation := hmac.New(sha256.New, key)
+	keyDerivation.Write([]byte("search-export-download-links"))
+
+	return &SearchExportService{
+		exportRepository:    exportRepository,
+		patientSearcher:     patientSearcher,
+		observationSearcher: observationSearcher,
+		fileCipher:          fileCipher,
+		signingKey:          keyDerivation.Sum(nil),
+		policy:              policy,
+		exposeResource:      func(context.Context, interface{}) {},
+		now:                 time.Now,
+	}, nil
+}
+
+// SetExposer sets how stored IDs in exported resources are rewritten for the requesting tenant
+func (service *SearchExportService) SetExposer(exposeResource ExportResourceExposer) {
+	service.exposeResource = exposeResource
+}
+
+// Request queues an export of every resource matching query for the calling principal
+// searchQuery is query with exposed IDs already resolved to stored ones; it is what the runner searches with
+func (service *SearchExportService) Request(ctx context.Context, resourceType string, query string, searchQuery string, format string) (*models.SearchExport, error) {
+	principal := auth.FromContext(ctx)
+	if principal.ID == auth.AnonymousPrincipalID {
+		return nil, apperrors.Unauthorized("Search exports require an API key")
+	}
+	if format == "" {
+		format = models.SearchExportFormatNDJSON
+	}
+	if format != models.SearchExportFormatNDJSON && format != models.SearchExportFormatCSV {
+		return nil, apperrors.InvalidInput("format", "must be ndjson or csv")
+	}
+	if _, parseError := parseExportSearch(resourceType, searchQuery); parseError != nil {
+		return nil, parseError
+	}
+
+	export, createError := service.exportRepository.Create(ctx, &models.SearchExport{
+		ResourceType: resourceType,
+		Query:        query,
+		SearchQuery:  searchQuery,
+		Format:       format,
+		RequestedBy:  principal.ID,
+		TenantID:     tenant.VerifiedFromContext(ctx),
+	})
+	if createError != nil {
+		return nil, apperrors.Internal("Failed to queue search export", createError)
+	}
+
+	log.Info().Int64("export_id", export.ID).Str("principal", principal.ID).Str("resource_type", resourceType).Str("query", query).Msg("Search export requested")
+	return export, nil
+}
+
+// Get returns an export to the principal who requested it, with a fresh download link when it is ready
+// and the downloads made so far; other callers get a 404 so export IDs reveal nothing
+func (service *SearchExportService) Get(ctx context.Context, exportID int64) (*models.SearchExport, error) {
+	export, getError := service.requestedExport(ctx, exportID)
+	if getError != nil {
+		return nil, getError
+	}
+
+	if export.Status == models.SearchExportStatusReady {
+		linkExpiresAt := service.now().Add(service.policy.LinkTTL).Truncate(time.Second)
+		if export.ExpiresAt != nil && export.ExpiresAt.Before(linkExpiresAt) {
+			linkExpiresAt = export.ExpiresAt.Truncate(time.Second)
+		}
+		export.DownloadURL = fmt.Sprintf("/exports/%d/download?expires=%d&signature=%s", export.ID, linkExpiresAt.Unix(), service.sign(export.ID, linkExpiresAt.Unix()))
+		export.DownloadURLExpiresAt = &linkExpiresAt
+	}
+
+	downloads, listError := service.exportRepository.ListDownloads(ctx, exportID)
+	if listError != nil {
+		return nil, apperrors.Internal("Failed to read search export downloads", listError)
+	}
+	export.Downloads = downloads
+	return export, nil
+}
+
+// Download checks a signed link and returns the decrypted export file
+// The link is the credential, so it works without an API key; the download is recorded before the
+// file is returned and is refused if it cannot be recorded
+func (service *SearchExportService) Download(ctx context.Context, exportID int64, expires string, signature string, download models.SearchExportDownload) (*models.SearchExport, []byte, error) {
+	expiresUnix, parseError := strconv.ParseInt(expires, 10, 64)
+	if parseError != nil || !hmac.Equal([]byte(signature), []byte(service.sign(exportID, expiresUnix))) {
+		return nil, nil, apperrors.Forbidden("The download link is invalid")
+	}
+	if service.now().Unix() > expiresUnix {
+		return nil, nil, apperrors.Forbidden("The download link has expired; read the export again for a new one")
+	}
+
+	export, getError := service.exportRepository.Get(ctx, exportID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, nil, apperrors.NotFound("SearchExport", strconv.FormatInt(exportID, 10))
+	}
+	if getError != nil {
+		return nil, nil, apperrors.Internal("Failed to read search export", getError)
+	}
+	if export.Status == models.SearchExportStatusExpired {
+		return nil, nil, apperrors.Gone("SearchExport", strconv.FormatInt(exportID, 10))
+	}
+	if export.Status != models.SearchExportStatusReady {
+		return nil, nil, apperrors.Conflict("SearchExport", "export is "+string(export.Status))
+	}
+
+	sealed, contentError := service.exportRepository.Content(ctx, exportID)
+	if contentError != nil {
+		return nil, nil, apperrors.Internal("Failed to read search export file", contentError)
+	}
+	content, openError := service.open(exportID, sealed)
+	if openError != nil {
+		return nil, nil, apperrors.Internal("Failed to decrypt search export file", openError)
+	}
+
+	download.ExportID = exportID
+	download.DownloadedBy = auth.FromContext(ctx).ID
+	if recordError := service.exportRepository.RecordDownload(ctx, &download); recordError != nil {
+		return nil, nil, apperrors.Internal("Failed to record search export download", recordError)
+	}
+
+	log.Info().Int64("export_id", exportID).Str("principal", download.DownloadedBy).Str("requested_by", export.RequestedBy).Str("remote_address", download.RemoteAddress).Msg("Search export downloaded")
+	return export, content, nil
+}
+
+// Run materializes pending exports and expires old ones until ctx is cancelled
+func (service *SearchExportService) Run(ctx context.Context) {
+	for {
+		if expired, expireError := service.exportRepository.ExpireBefore(ctx, service.now()); expireError != nil {
+			log.Error().Err(expireError).Msg("Failed to expire search exports")
+		} else if expired > 0 {
+			log.Info().Int64("expired", expired).Msg("Deleted expired search export files")
+		}
+
+		processed, processError := service.ProcessNext(ctx)
+		if processError != nil {
+			log.Error().Err(processError).Msg("Failed to process search export")
+		}
+		if processed != nil {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(service.policy.PollInterval):
+		}
+	}
+}
+
+// ProcessNext claims the oldest pending export and materializes it, returning nil when none is waiting
+// A search or sealing failure marks the export failed; the returned error is reserved for storage failures
+func (service *SearchExportService) ProcessNext(ctx context.Context) (*models.SearchExport, error) {
+	export, claimError := service.exportRepository.ClaimNext(ctx, service.now().Add(-service.policy.StaleAfter))
+	if errors.Is(claimError, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if claimError != nil {
+		return nil, fmt.Errorf("failed to claim search export: %w", claimError)
+	}
+
+	content, resourceCount, truncated, materializeError := service.materialize(ctx, export)
+	if materializeError != nil {
+		log.Warn().Err(materializeError).Int64("export_id", export.ID).Msg("Search export failed")
+		export.Status = models.SearchExportStatusFailed
+		export.Error = materializeError.Error()
+		return export, service.exportRepository.Fail(ctx, export.ID, materializeError.Error())
+	}
+
+	sealed, sealError := service.seal(export.ID, content)
+	if sealError != nil {
+		return export, service.exportRepository.Fail(ctx, export.ID, "failed to encrypt export file")
+	}
+	expiresAt := service.now().Add(service.policy.Retention)
+	if completeError := service.exportRepository.Complete(ctx, export.ID, sealed, resourceCount, truncated, expiresAt); completeError != nil {
+		return export, fmt.Errorf("failed to store search export %d: %w", export.ID, completeError)
+	}
+
+	log.Info().Int64("export_id", export.ID).Int("resources", resourceCount).Bool("truncated", truncated).Msg("Search export ready")
+	export.Status = models.SearchExportStatusReady
+	export.ResourceCount = resourceCount
+	export.Truncated = truncated
+	export.ExpiresAt = &expiresAt
+	return export, nil
+}
+
+// requestedExport reads an export, hiding it from everyone but the principal who requested it
+func (service *SearchExportService) requestedExport(ctx context.Context, exportID int64) (*models.SearchExport, error) {
+	export, getError := service.exportRepository.Get(ctx, exportID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, apperrors.NotFound("SearchExport", strconv.FormatInt(exportID, 10))
+	}
+	if getError != nil {
+		return nil, apperrors.Internal("Failed to read search export", getError)
+	}
+	if export.RequestedBy != auth.FromContext(ctx).ID {
+		return nil, apperrors.NotFound("SearchExport", strconv.FormatInt(exportID, 10))
+	}
+	return export, nil
+}
+
+// materialize pages through the export's search and writes every result in the export's format
+// It runs as the export's tenant, so IDs are exposed as the requester saw them
+func (service *SearchExportService) materialize(ctx context.Context, export *models.SearchExport) ([]byte, int, bool, error) {
+	searchParams, parseError := parseExportSearch(export.ResourceType, export.SearchQuery)
+	if parseError != nil {
+		return nil, 0, false, parseError
+	}
+	tenantContext := tenant.WithVerifiedTenant(ctx, export.TenantID)
+	writer, writerError := newExportWriter(export.ResourceType, export.Format)
+	if writerError != nil {
+		return nil, 0, false, writerError
+	}
+
+	resourceCount := 0
+	for offset := 0; ; offset += service.policy.PageSize {
+		page, searchError := service.searchPage(tenantContext, searchParams, offset)
+		if searchError != nil {
+			return nil, 0, false, fmt.Errorf("search failed at offset %d: %w", offset, searchError)
+		}
+		for _, resource := range page {
+			if resourceCount == service.policy.MaxResources {
+				return writer.Bytes(), resourceCount, true, nil
+			}
+			service.exposeResource(tenantContext, resource)
+			if writeError := writer.Write(resource); writeError != nil {
+				return nil, 0, false, writeError
+			}
+			resourceCount++
+		}
+		if len(page) < service.policy.PageSize {
+			return writer.Bytes(), resourceCount, false, nil
+		}
+	}
+}
+
+// searchPage reads one page of the export's search starting at offset
+// Exports page by offset from the first match, so a _cursor in the export's query is ignored
+func (service *SearchExportService) searchPage(ctx context.Context, searchParams interface{}, offset int) ([]interface{}, error) {
+	var page []interface{}
+	switch params := searchParams.(type) {
+	case *models.PatientSearchParams:
+		pageParams := *params
+		pageParams.Limit = service.policy.PageSize
+		pageParams.Offset = offset
+		pageParams.CursorPaging = false
+		pageParams.After = nil
+		patients, searchError := service.patientSearcher.SearchPatients(ctx, &pageParams)
+		if searchError != nil {
+			return nil, searchError
+		}
+		for _, patient := range patients {
+			page = append(page, patient)
+		}
+	case *models.ObservationSearchParams:
+		pageParams := *params
+		pageParams.Limit = service.policy.PageSize
+		pageParams.Offset = offset
+		pageParams.CursorPaging = false
+		pageParams.After = nil
+		observations, searchError := service.observationSearcher.SearchObservations(ctx, &pageParams)
+		if searchError != nil {
+			return nil, searchError
+		}
+		for _, observation := range observations {
+			page = append(page, observation)
+		}
+	}
+	return page, nil
+}
+
+// parseExportSearch parses a search query with the same rules as the resource's search endpoint
+func parseExportSearch(resourceType string, searchQuery string) (interface{}, error) {
+	searchRequest := &http.Request{URL: &url.URL{RawQuery: searchQuery}}
+	switch resourceType {
+	case "Patient":
+		return utils.ParsePatientSearchParams(searchRequest)
+	case "Observation":
+		return utils.ParseObservationSearchParams(searchRequest)
+	default:
+		return nil, apperrors.InvalidInput("resourceType", "must be Patient or Observation")
+	}
+}
+
+// sign returns the hex HMAC binding a download link to its export and expiry
+func (service *SearchExportService) sign(exportID int64, expiresUnix int64) string {
+	mac := hmac.New(sha256.New, service.signingKey)
+	fmt.Fprintf(mac, "%d:%d", exportID, expiresUnix)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal encrypts an export file, prepending the nonce; the export ID is authenticated so files cannot be swapped between exports
+func (service *SearchExportService) seal(exportID int64, content []byte) ([]byte, error) {
+	nonce := make([]byte, service.fileCipher.NonceSize())
+	if _, randomError := rand.Read(nonce); randomError != nil {
+		return nil, randomError
+	}
+	return service.fileCipher.Seal(nonce, nonce, content, []byte(strconv.FormatInt(exportID, 10))), nil
+}
+
+// open decrypts a file sealed by seal for the same export
+func (service *SearchExportService) open(exportID int64, sealed []byte) ([]byte, error) {
+	nonceSize := service.fileCipher.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, errors.New("sealed export file is too short")
+	}
+	return service.fileCipher.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(strconv.FormatInt(exportID, 10)))
+}
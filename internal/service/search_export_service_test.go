@@ -0,0 +1,363 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/auth"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// memorySearchExportRepository implements SearchExportRepository in memory
+type memorySearchExportRepository struct {
+	exports   map[int64]*models.SearchExport
+	downloads []*models.SearchExportDownload
+}
+
+// newMemorySearchExportRepository creates an empty export store
+func newMemorySearchExportRepository() *memorySearchExportRepository {
+	return &memorySearchExportRepository{exports: make(map[int64]*models.SearchExport)}
+}
+
+// Create stores a pending export
+func (repository *memorySearchExportRepository) Create(ctx context.Context, export *models.SearchExport) (*models.SearchExport, error) {
+	export.ID = int64(len(repository.exports) + 1)
+	export.Status = models.SearchExportStatusPending
+	repository.exports[export.ID] = export
+	return repository.Get(ctx, export.ID)
+}
+
+// Get returns a copy of the export without its content
+func (repository *memorySearchExportRepository) Get(ctx context.Context, exportID int64) (*models.SearchExport, error) {
+	export, exists := repository.exports[exportID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	copied := *export
+	copied.Content = nil
+	return &copied, nil
+}
+
+// ClaimNext marks the lowest-numbered pending export running
+func (repository *memorySearchExportRepository) ClaimNext(ctx context.Context, staleBefore time.Time) (*models.SearchExport, error) {
+	for exportID := int64(1); exportID <= int64(len(repository.exports)); exportID++ {
+		if export := repository.exports[exportID]; export.Status == models.SearchExportStatusPending {
+			export.Status = models.SearchExportStatusRunning
+			return repository.Get(ctx, exportID)
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+// Complete stores the sealed file of a running export
+func (repository *memorySearchExportRepository) Complete(ctx context.Context, exportID int64, content []byte, resourceCount int, truncated bool, expiresAt time.Time) error {
+	export := repository.exports[exportID]
+	export.Status = models.SearchExportStatusReady
+	export.Content = content
+	export.ResourceCount = resourceCount
+	export.Truncated = truncated
+	export.ExpiresAt = &expiresAt
+	return nil
+}
+
+// Fail records why a running export stopped
+func (repository *memorySearchExportRepository) Fail(ctx context.Context, exportID int64, failure string) error {
+	repository.exports[exportID].Status = models.SearchExportStatusFailed
+	repository.exports[exportID].Error = failure
+	return nil
+}
+
+// Content returns the sealed file of a ready export
+func (repository *memorySearchExportRepository) Content(ctx context.Context, exportID int64) ([]byte, error) {
+	return repository.exports[exportID].Content, nil
+}
+
+// ExpireBefore deletes the files of ready exports past their expiry
+func (repository *memorySearchExportRepository) ExpireBefore(ctx context.Context, now time.Time) (int64, error) {
+	var expired int64
+	for _, export := range repository.exports {
+		if export.Status == models.SearchExportStatusReady && export.ExpiresAt.Before(now) {
+			export.Status = models.SearchExportStatusExpired
+			export.Content = nil
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// RecordDownload appends to the audit trail
+func (repository *memorySearchExportRepository) RecordDownload(ctx context.Context, download *models.SearchExportDownload) error {
+	repository.downloads = append(repository.downloads, download)
+	return nil
+}
+
+// ListDownloads returns the export's audit trail
+func (repository *memorySearchExportRepository) ListDownloads(ctx context.Context, exportID int64) ([]*models.SearchExportDownload, error) {
+	var downloads []*models.SearchExportDownload
+	for _, download := range repository.downloads {
+		if download.ExportID == exportID {
+			downloads = append(downloads, download)
+		}
+	}
+	return downloads, nil
+}
+
+// stubPatientSearcher pages through a fixed list of patients
+type stubPatientSearcher struct {
+	patients []*fhir.Patient
+	searches []*models.PatientSearchParams
+}
+
+// SearchPatients returns the page of patients the params ask for
+func (searcher *stubPatientSearcher) SearchPatients(ctx context.Context, searchParams *models.PatientSearchParams) ([]*fhir.Patient, error) {
+	searcher.searches = append(searcher.searches, searchParams)
+	start := min(searchParams.Offset, len(searcher.patients))
+	end := min(start+searchParams.Limit, len(searcher.patients))
+	return searcher.patients[start:end], nil
+}
+
+// stubObservationSearcher returns a fixed list of observations
+type stubObservationSearcher struct {
+	observations []*fhir.Observation
+}
+
+// SearchObservations returns the observations on the first page only
+func (searcher *stubObservationSearcher) SearchObservations(ctx context.Context, searchParams *models.ObservationSearchParams) ([]*fhir.Observation, error) {
+	if searchParams.Offset > 0 {
+		return nil, nil
+	}
+	return searcher.observations, nil
+}
+
+// testPatients returns count patients named after their position
+func testPatients(count int) []*fhir.Patient {
+	patients := make([]*fhir.Patient, count)
+	for index := range patients {
+		patientID := fmt.Sprintf("p-%d", index+1)
+		family := fmt.Sprintf("Family%d", index+1)
+		patients[index] = &fhir.Patient{Id: &patientID, Name: []fhir.HumanName{{Family: &family}}}
+	}
+	return patients
+}
+
+// exporterContext is a caller with an API key
+func exporterContext() context.Context {
+	return auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:analyst"})
+}
+
+// newTestSearchExportService creates an export service with small pages over in-memory stores
+func newTestSearchExportService(patientSearcher *stubPatientSearcher, observationSearcher *stubObservationSearcher) (*SearchExportService, *memorySearchExportRepository) {
+	exportRepository := newMemorySearchExportRepository()
+	policy := DefaultSearchExportPolicy()
+	policy.PageSize = 2
+	exportService, _ := NewSearchExportService(exportRepository, patientSearcher, observationSearcher, bytes.Repeat([]byte{7}, SearchExportKeySize), policy)
+	exportService.now = func() time.Time { return time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC) }
+	return exportService, exportRepository
+}
+
+// signedLinkParams extracts the expires and signature parameters from a download URL
+func signedLinkParams(t *testing.T, downloadURL string) (string, string) {
+	t.Helper()
+	parsedURL, parseError := url.Parse(downloadURL)
+	if parseError != nil {
+		t.Fatalf("Expected a valid download URL, got %q", downloadURL)
+	}
+	return parsedURL.Query().Get("expires"), parsedURL.Query().Get("signature")
+}
+
+// TestNewSearchExportService_KeySize verifies keys of the wrong length are rejected
+func TestNewSearchExportService_KeySize(t *testing.T) {
+	if _, keyError := NewSearchExportService(newMemorySearchExportRepository(), nil, nil, []byte("short"), DefaultSearchExportPolicy()); keyError == nil {
+		t.Error("Expected a short key to be rejected")
+	}
+}
+
+// TestSearchExportService_Request verifies exports need an API key, a known type and format, and a valid query
+func TestSearchExportService_Request(t *testing.T) {
+	exportService, _ := newTestSearchExportService(&stubPatientSearcher{}, &stubObservationSearcher{})
+
+	_, anonymousError := exportService.Request(context.Background(), "Patient", "", "", "")
+	expectStatus(t, anonymousError, http.StatusUnauthorized)
+
+	_, typeError := exportService.Request(exporterContext(), "Encounter", "", "", "")
+	expectStatus(t, typeError, http.StatusBadRequest)
+
+	_, formatError := exportService.Request(exporterContext(), "Patient", "", "", "xlsx")
+	expectStatus(t, formatError, http.StatusBadRequest)
+
+	export, requestError := exportService.Request(exporterContext(), "Patient", "gender=female", "gender=female", "")
+	if requestError != nil {
+		t.Fatalf("Expected no error, got %v", requestError)
+	}
+	if export.Status != models.SearchExportStatusPending || export.Format != models.SearchExportFormatNDJSON || export.RequestedBy != "api-key:analyst" {
+		t.Errorf("Expected a pending NDJSON export for the caller, got %+v", export)
+	}
+}
+
+// TestSearchExportService_ProcessNext verifies every page is written and the file is stored encrypted
+func TestSearchExportService_ProcessNext(t *testing.T) {
+	patientSearcher := &stubPatientSearcher{patients: testPatients(5)}
+	exportService, exportRepository := newTestSearchExportService(patientSearcher, &stubObservationSearcher{})
+	exportService.SetExposer(func(ctx context.Context, resource interface{}) {
+		patient := resource.(*fhir.Patient)
+		exposedID := "exposed-" + *patient.Id
+		patient.Id = &exposedID
+	})
+
+	export, _ := exportService.Request(exporterContext(), "Patient", "_count=1", "_count=1", "")
+	processed, processError := exportService.ProcessNext(context.Background())
+	if processError != nil {
+		t.Fatalf("Expected no error, got %v", processError)
+	}
+	if processed.ID != export.ID || processed.Status != models.SearchExportStatusReady || processed.ResourceCount != 5 || processed.Truncated {
+		t.Errorf("Expected all 5 patients exported, got %+v", processed)
+	}
+	if len(patientSearcher.searches) != 3 {
+		t.Errorf("Expected 3 pages of 2 regardless of _count, got %d searches", len(patientSearcher.searches))
+	}
+
+	stored := exportRepository.exports[export.ID].Content
+	if bytes.Contains(stored, []byte("Family1")) {
+		t.Error("Expected the stored file to be encrypted")
+	}
+
+	readyExport, _ := exportService.Get(exporterContext(), export.ID)
+	expires, signature := signedLinkParams(t, readyExport.DownloadURL)
+	_, content, downloadError := exportService.Download(context.Background(), export.ID, expires, signature, models.SearchExportDownload{RemoteAddress: "10.0.0.9"})
+	if downloadError != nil {
+		t.Fatalf("Expected no error, got %v", downloadError)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 5 || !strings.Contains(lines[0], `"id":"exposed-p-1"`) {
+		t.Errorf("Expected 5 NDJSON lines with exposed IDs, got %q", content)
+	}
+
+	if processed, _ := exportService.ProcessNext(context.Background()); processed != nil {
+		t.Errorf("Expected no export left to process, got %+v", processed)
+	}
+}
+
+// TestSearchExportService_Truncated verifies exports stop at MaxResources
+func TestSearchExportService_Truncated(t *testing.T) {
+	exportService, _ := newTestSearchExportService(&stubPatientSearcher{patients: testPatients(5)}, &stubObservationSearcher{})
+	exportService.policy.MaxResources = 3
+
+	exportService.Request(exporterContext(), "Patient", "", "", "")
+	processed, _ := exportService.ProcessNext(context.Background())
+	if processed.ResourceCount != 3 || !processed.Truncated {
+		t.Errorf("Expected 3 patients and a truncated export, got %+v", processed)
+	}
+}
+
+// TestSearchExportService_Get verifies exports are hidden from other callers and list their downloads
+func TestSearchExportService_Get(t *testing.T) {
+	exportService, _ := newTestSearchExportService(&stubPatientSearcher{patients: testPatients(1)}, &stubObservationSearcher{})
+	export, _ := exportService.Request(exporterContext(), "Patient", "", "", "")
+
+	pendingExport, _ := exportService.Get(exporterContext(), export.ID)
+	if pendingExport.DownloadURL != "" {
+		t.Errorf("Expected no download link before the export is ready, got %q", pendingExport.DownloadURL)
+	}
+
+	otherContext := auth.WithPrincipal(context.Background(), auth.Principal{ID: "api-key:other"})
+	_, otherError := exportService.Get(otherContext, export.ID)
+	expectStatus(t, otherError, http.StatusNotFound)
+
+	exportService.ProcessNext(context.Background())
+	readyExport, _ := exportService.Get(exporterContext(), export.ID)
+	expires, signature := signedLinkParams(t, readyExport.DownloadURL)
+	exportService.Download(otherContext, export.ID, expires, signature, models.SearchExportDownload{UserAgent: "curl/8"})
+
+	auditedExport, _ := exportService.Get(exporterContext(), export.ID)
+	if len(auditedExport.Downloads) != 1 || auditedExport.Downloads[0].DownloadedBy != "api-key:other" || auditedExport.Downloads[0].UserAgent != "curl/8" {
+		t.Errorf("Expected the download to be audited, got %+v", auditedExport.Downloads)
+	}
+}
+
+// TestSearchExportService_DownloadLinks verifies tampered, expired, and retired links are refused
+func TestSearchExportService_DownloadLinks(t *testing.T) {
+	exportService, exportRepository := newTestSearchExportService(&stubPatientSearcher{patients: testPatients(1)}, &stubObservationSearcher{})
+	export, _ := exportService.Request(exporterContext(), "Patient", "", "", "")
+	exportService.ProcessNext(context.Background())
+	readyExport, _ := exportService.Get(exporterContext(), export.ID)
+	expires, signature := signedLinkParams(t, readyExport.DownloadURL)
+
+	_, _, tamperedError := exportService.Download(context.Background(), export.ID, expires+"0", signature, models.SearchExportDownload{})
+	expectStatus(t, tamperedError, http.StatusForbidden)
+
+	_, _, otherExportError := exportService.Download(context.Background(), export.ID+1, expires, signature, models.SearchExportDownload{})
+	expectStatus(t, otherExportError, http.StatusForbidden)
+
+	startedAt := exportService.now()
+	exportService.now = func() time.Time { return startedAt.Add(time.Hour) }
+	_, _, expiredLinkError := exportService.Download(context.Background(), export.ID, expires, signature, models.SearchExportDownload{})
+	expectStatus(t, expiredLinkError, http.StatusForbidden)
+
+	exportService.now = func() time.Time { return startedAt.Add(48 * time.Hour) }
+	exportRepository.ExpireBefore(context.Background(), exportService.now())
+	retiredExport, _ := exportService.Get(exporterContext(), export.ID)
+	if retiredExport.Status != models.SearchExportStatusExpired || retiredExport.DownloadURL != "" {
+		t.Errorf("Expected an expired export without a link, got %+v", retiredExport)
+	}
+	if len(exportRepository.downloads) != 0 {
+		t.Errorf("Expected refused downloads not to be audited, got %d", len(exportRepository.downloads))
+	}
+}
+
+// TestSearchExportService_CSV verifies observations are flattened into CSV rows with formulas neutralized
+func TestSearchExportService_CSV(t *testing.T) {
+	observationID := "obs-1"
+	subject := "Patient/p-1"
+	code := "8867-4"
+	note := "=HYPERLINK(\"http://example.com\")"
+	observationSearcher := &stubObservationSearcher{observations: []*fhir.Observation{{
+		Id:          &observationID,
+		Subject:     &fhir.Reference{Reference: &subject},
+		Status:      fhir.ObservationStatusFinal,
+		Code:        fhir.CodeableConcept{Coding: []fhir.Coding{{Code: &code}}},
+		ValueString: &note,
+	}}}
+	exportService, _ := newTestSearchExportService(&stubPatientSearcher{}, observationSearcher)
+
+	export, _ := exportService.Request(exporterContext(), "Observation", "patient=p-1", "patient=p-1", models.SearchExportFormatCSV)
+	exportService.ProcessNext(context.Background())
+	readyExport, _ := exportService.Get(exporterContext(), export.ID)
+	expires, signature := signedLinkParams(t, readyExport.DownloadURL)
+	_, content, downloadError := exportService.Download(context.Background(), export.ID, expires, signature, models.SearchExportDownload{})
+	if downloadError != nil {
+		t.Fatalf("Expected no error, got %v", downloadError)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 || lines[0] != strings.Join(observationExportColumns, ",") {
+		t.Fatalf("Expected a header and one row, got %q", content)
+	}
+	if !strings.HasPrefix(lines[1], "obs-1,Patient/p-1,final,,,8867-4,,") || !strings.Contains(lines[1], `"'=HYPERLINK(`) {
+		t.Errorf("Expected the observation flattened with its formula neutralized, got %q", lines[1])
+	}
+}
+
+// TestNeutralizeFormula verifies text that spreadsheets would evaluate is quoted while numbers are kept
+func TestNeutralizeFormula(t *testing.T) {
+	testCases := map[string]string{
+		"=1+1":   "'=1+1",
+		"@SUM()": "'@SUM()",
+		"-5.2":   "-5.2",
+		"+cmd":   "'+cmd",
+		"normal": "normal",
+		"":       "",
+	}
+	for value, expected := range testCases {
+		if neutralized := neutralizeFormula(value); neutralized != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, value, neutralized)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// patientExportColumns are the CSV columns of a Patient export
+var patientExportColumns = []string{"id", "identifier_system", "identifier_value", "active", "family", "given", "gender", "birth_date"}
+
+// observationExportColumns are the CSV columns of an Observation export
+var observationExportColumns = []string{"id", "subject", "status", "category", "code_system", "code", "code_display", "value", "unit", "effective", "issued"}
+
+// exportWriter accumulates exported resources in one file format
+type exportWriter interface {
+	Write(resource interface{}) error
+	Bytes() []byte
+}
+
+// newExportWriter returns a writer for the export's format
+func newExportWriter(resourceType string, format string) (exportWriter, error) {
+	switch format {
+	case models.SearchExportFormatNDJSON:
+		return &ndjsonExportWriter{}, nil
+	case models.SearchExportFormatCSV:
+		writer := &csvExportWriter{resourceType: resourceType}
+		writer.csvWriter = csv.NewWriter(&writer.buffer)
+		columns := patientExportColumns
+		if resourceType == "Observation" {
+			columns = observationExportColumns
+		}
+		return writer, writer.csvWriter.Write(columns)
+	default:
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+}
+
+// ndjsonExportWriter writes one FHIR resource per line
+type ndjsonExportWriter struct {
+	buffer bytes.Buffer
+}
+
+// Write appends the resource as a line of JSON
+func (writer *ndjsonExportWriter) Write(resource interface{}) error {
+	resourceJSON, marshalError := json.Marshal(resource)
+	if marshalError != nil {
+		return fmt.Errorf("failed to encode exported resource: %w", marshalError)
+	}
+	writer.buffer.Write(resourceJSON)
+	writer.buffer.WriteByte('\n')
+	return nil
+}
+
+// Bytes returns the file written so far
+func (writer *ndjsonExportWriter) Bytes() []byte {
+	return writer.buffer.Bytes()
+}
+
+// csvExportWriter writes one row of flattened elements per resource
+type csvExportWriter struct {
+	resourceType string
+	buffer       bytes.Buffer
+	csvWriter    *csv.Writer
+}
+
+// Write appends the resource's row
+func (writer *csvExportWriter) Write(resource interface{}) error {
+	var row []string
+	switch typedResource := resource.(type) {
+	case *fhir.Patient:
+		row = patientExportRow(typedResource)
+	case *fhir.Observation:
+		row = observationExportRow(typedResource)
+	default:
+		return fmt.Errorf("cannot write %T to a CSV export", resource)
+	}
+	for index, value := range row {
+		row[index] = neutralizeFormula(value)
+	}
+	return writer.csvWriter.Write(row)
+}
+
+// Bytes flushes and returns the file written so far
+func (writer *csvExportWriter) Bytes() []byte {
+	writer.csvWriter.Flush()
+	return writer.buffer.Bytes()
+}
+
+// patientExportRow flattens a patient into patientExportColumns
+func patientExportRow(patient *fhir.Patient) []string {
+	row := make([]string, len(patientExportColumns))
+	row[0] = stringValue(patient.Id)
+	if len(patient.Identifier) > 0 {
+		row[1] = stringValue(patient.Identifier[0].System)
+		row[2] = stringValue(patient.Identifier[0].Value)
+	}
+	if patient.Active != nil {
+		row[3] = strconv.FormatBool(*patient.Active)
+	}
+	if len(patient.Name) > 0 {
+		row[4] = stringValue(patient.Name[0].Family)
+		row[5] = strings.Join(patient.Name[0].Given, " ")
+	}
+	if patient.Gender != nil {
+		row[6] = patient.Gender.Code()
+	}
+	row[7] = stringValue(patient.BirthDate)
+	return row
+}
+
+// observationExportRow flattens an observation into observationExportColumns
+func observationExportRow(observation *fhir.Observation) []string {
+	row := make([]string, len(observationExportColumns))
+	row[0] = stringValue(observation.Id)
+	if observation.Subject != nil {
+		row[1] = stringValue(observation.Subject.Reference)
+	}
+	row[2] = observation.Status.Code()
+	if len(observation.Category) > 0 && len(observation.Category[0].Coding) > 0 {
+		row[3] = stringValue(observation.Category[0].Coding[0].Code)
+	}
+	if len(observation.Code.Coding) > 0 {
+		row[4] = stringValue(observation.Code.Coding[0].System)
+		row[5] = stringValue(observation.Code.Coding[0].Code)
+		row[6] = stringValue(observation.Code.Coding[0].Display)
+	}
+	switch {
+	case observation.ValueQuantity != nil:
+		if observation.ValueQuantity.Value != nil {
+			row[7] = observation.ValueQuantity.Value.String()
+		}
+		row[8] = stringValue(observation.ValueQuantity.Unit)
+	case observation.ValueString != nil:
+		row[7] = *observation.ValueString
+	}
+	row[9] = stringValue(observation.EffectiveDateTime)
+	row[10] = stringValue(observation.Issued)
+	return row
+}
+
+// neutralizeFormula prefixes text a spreadsheet would run as a formula with a quote
+// Numbers keep their sign so negative values stay numeric
+func neutralizeFormula(value string) string {
+	if value == "" || !strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return value
+	}
+	if _, numberError := strconv.ParseFloat(value, 64); numberError == nil {
+		return value
+	}
+	return "'" + value
+}
+
+// stringValue dereferences an optional string, returning "" for nil
+func stringValue(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/nathannewyen/fhir-health-interop/internal/subscription"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// SubscriptionChangeListener is told when subscriptions are created, updated, or deleted
+// The subscription notifier uses it to reload its cached subscriptions
+type SubscriptionChangeListener interface {
+	SubscriptionsChanged()
+}
+
+// SubscriptionService handles business logic for Subscription operations
+// Subscriptions belong to the tenant of the API key that made them, since their channel headers often hold
+// credentials; other tenants cannot see them and their writes are not notified
+type SubscriptionService struct {
+	subscriptionRepository repository.SubscriptionRepository
+	subscriptionMapper     *models.SubscriptionMapper
+	changeListener         SubscriptionChangeListener
+}
+
+// NewSubscriptionService creates a new instance of SubscriptionService
+func NewSubscriptionService(subscriptionRepository repository.SubscriptionRepository) *SubscriptionService {
+	return &SubscriptionService{
+		subscriptionRepository: subscriptionRepository,
+		subscriptionMapper:     models.NewSubscriptionMapper(),
+	}
+}
+
+// SetChangeListener registers the listener told about every subscription write
+func (service *SubscriptionService) SetChangeListener(changeListener SubscriptionChangeListener) {
+	service.changeListener = changeListener
+}
+
+// CreateSubscription validates and stores a subscription; requested subscriptions are activated immediately
+func (service *SubscriptionService) CreateSubscription(ctx context.Context, fhirSubscription *fhir.Subscription) (*fhir.Subscription, error) {
+	domainSubscription, prepareError := service.prepareSubscription(ctx, fhirSubscription, nil)
+	if prepareError != nil {
+		return nil, prepareError
+	}
+	domainSubscription.TenantID = tenant.VerifiedFromContext(ctx)
+
+	createdSubscription, createError := service.subscriptionRepository.Create(ctx, domainSubscription)
+	if createError != nil {
+		return nil, createError
+	}
+	service.subscriptionsChanged()
+
+	createdFHIRSubscription := service.subscriptionMapper.ToFHIR(createdSubscription)
+	reportIgnoredElements(ctx, "Subscription", fhirSubscription, createdFHIRSubscription, nil)
+	return createdFHIRSubscription, nil
+}
+
+// GetSubscriptionByID retrieves one of the tenant's subscriptions, reporting ErrResourceNotFound for others
+func (service *SubscriptionService) GetSubscriptionByID(ctx context.Context, subscriptionID string) (*fhir.Subscription, error) {
+	domainSubscription, getError := service.tenantSubscription(ctx, subscriptionID)
+	if getError != nil {
+		return nil, getError
+	}
+	return service.subscriptionMapper.ToFHIR(domainSubscription), nil
+}
+
+// SearchSubscriptions retrieves the tenant's subscriptions matching the search criteria
+func (service *SubscriptionService) SearchSubscriptions(ctx context.Context, searchParams *models.SubscriptionSearchParams) ([]*fhir.Subscription, error) {
+	searchParams.TenantID = tenant.VerifiedFromContext(ctx)
+	domainSubscriptions, searchError := service.subscriptionRepository.Search(ctx, searchParams)
+	if searchError != nil {
+		return nil, searchError
+	}
+
+	fhirSubscriptions := make([]*fhir.Subscription, len(domainSubscriptions))
+	for index, domainSubscription := range domainSubscriptions {
+		fhirSubscriptions[index] = service.subscriptionMapper.ToFHIR(domainSubscription)
+	}
+	return fhirSubscriptions, nil
+}
+
+// CountSubscriptions returns how many of the tenant's subscriptions match the search across all pages
+func (service *SubscriptionService) CountSubscriptions(ctx context.Context, searchParams *models.SubscriptionSearchParams) (int, error) {
+	searchParams.TenantID = tenant.VerifiedFromContext(ctx)
+	return service.subscriptionRepository.Count(ctx, searchParams)
+}
+
+// UpdateSubscription replaces one of the tenant's subscriptions, reporting ErrResourceNotFound for others
+// Setting the status back to requested or active restarts a subscription stopped by an error
+func (service *SubscriptionService) UpdateSubscription(ctx context.Context, subscriptionID string, fhirSubscription *fhir.Subscription) (*fhir.Subscription, error) {
+	storedSubscription, getError := service.tenantSubscription(ctx, subscriptionID)
+	if getError != nil {
+		return nil, getError
+	}
+
+	domainSubscription, prepareError := service.prepareSubscription(ctx, fhirSubscription, storedSubscription)
+	if prepareError != nil {
+		return nil, prepareError
+	}
+	domainSubscription.ID = subscriptionID
+
+	updatedSubscription, updateError := service.subscriptionRepository.Update(ctx, domainSubscription)
+	if errors.Is(updateError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if updateError != nil {
+		return nil, updateError
+	}
+	service.subscriptionsChanged()
+
+	updatedFHIRSubscription := service.subscriptionMapper.ToFHIR(updatedSubscription)
+	reportIgnoredElements(ctx, "Subscription", fhirSubscription, updatedFHIRSubscription, nil)
+	return updatedFHIRSubscription, nil
+}
+
+// DeleteSubscription removes one of the tenant's subscriptions, reporting ErrResourceNotFound for others
+// Notifications already queued for it are dropped when they come up for delivery
+func (service *SubscriptionService) DeleteSubscription(ctx context.Context, subscriptionID string) error {
+	if _, getError := service.tenantSubscription(ctx, subscriptionID); getError != nil {
+		return getError
+	}
+
+	deleteError := service.subscriptionRepository.Delete(ctx, subscriptionID)
+	if errors.Is(deleteError, sql.ErrNoRows) {
+		return ErrResourceNotFound
+	}
+	if deleteError != nil {
+		return deleteError
+	}
+	service.subscriptionsChanged()
+	return nil
+}
+
+// tenantSubscription reads a subscription made by the request's tenant, reporting ErrResourceNotFound otherwise
+func (service *SubscriptionService) tenantSubscription(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	if _, parseError := uuid.Parse(subscriptionID); parseError != nil {
+		return nil, ErrResourceNotFound
+	}
+
+	domainSubscription, getError := service.subscriptionRepository.GetByID(ctx, subscriptionID)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return nil, ErrResourceNotFound
+	}
+	if getError != nil {
+		return nil, getError
+	}
+	if domainSubscription.TenantID != tenant.VerifiedFromContext(ctx) {
+		return nil, ErrResourceNotFound
+	}
+	return domainSubscription, nil
+}
+
+// prepareSubscription maps a submitted subscription and rejects what cannot be notified, every problem reported
+// at once; stored is the subscription being updated, or nil for creates
+func (service *SubscriptionService) prepareSubscription(ctx context.Context, fhirSubscription *fhir.Subscription, stored *models.Subscription) (*models.Subscription, error) {
+	domainSubscription, mappingIssues := service.subscriptionMapper.FromFHIR(fhirSubscription)
+	// An end that cannot be read would leave the subscription running forever, so mapping issues always reject
+	if issuesError := handleMappingIssues(ctx, "Subscription", true, mappingIssues); issuesError != nil {
+		return nil, issuesError
+	}
+
+	var issues []outcome.Issue
+	if _, criteriaError := subscription.ParseCriteria(domainSubscription.Criteria); criteriaError != nil {
+		issues = append(issues, outcome.Error(fhir.IssueTypeNotSupported, criteriaError.Error(), "Subscription.criteria"))
+	}
+	if domainSubscription.ChannelType != fhir.SubscriptionChannelTypeRestHook.Code() {
+		issues = append(issues, outcome.Error(fhir.IssueTypeNotSupported, "only rest-hook channels are supported, got "+domainSubscription.ChannelType, "Subscription.channel.type"))
+	}
+	if endpointError := subscription.ValidateEndpoint(domainSubscription.Endpoint); endpointError != nil {
+		issues = append(issues, outcome.Error(fhir.IssueTypeValue, endpointError.Error(), "Subscription.channel.endpoint"))
+	}
+	if payloadError := subscription.ValidatePayload(domainSubscription.Payload); payloadError != nil {
+		issues = append(issues, outcome.Error(fhir.IssueTypeNotSupported, payloadError.Error(), "Subscription.channel.payload"))
+	}
+	if _, headerError := subscription.ParseHeaders(domainSubscription.Headers); headerError != nil {
+		issues = append(issues, outcome.Error(fhir.IssueTypeValue, headerError.Error(), "Subscription.channel.header"))
+	}
+
+	// The server activates requested subscriptions; only it puts them in error, which clients may keep or clear
+	switch domainSubscription.Status {
+	case models.SubscriptionStatusRequested, models.SubscriptionStatusActive:
+		domainSubscription.Status = models.SubscriptionStatusActive
+	case models.SubscriptionStatusError:
+		if stored == nil || stored.Status != models.SubscriptionStatusError {
+			issues = append(issues, outcome.Error(fhir.IssueTypeValue, "status error is set by the server when notifications fail; use requested or off", "Subscription.status"))
+		} else {
+			domainSubscription.Error = stored.Error
+		}
+	}
+
+	if len(issues) > 0 {
+		return nil, &outcome.RejectionError{Issues: issues}
+	}
+	return domainSubscription, nil
+}
+
+// subscriptionsChanged tells the change listener about a subscription write
+func (service *SubscriptionService) subscriptionsChanged() {
+	if service.changeListener != nil {
+		service.changeListener.SubscriptionsChanged()
+	}
+}
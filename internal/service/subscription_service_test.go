@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/tenant"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockSubscriptionRepository implements SubscriptionRepository interface for testing
+type MockSubscriptionRepository struct {
+	subscriptions map[string]*models.Subscription
+}
+
+// NewMockSubscriptionRepository creates a new mock repository for testing
+func NewMockSubscriptionRepository() *MockSubscriptionRepository {
+	return &MockSubscriptionRepository{subscriptions: make(map[string]*models.Subscription)}
+}
+
+// Create stores a subscription under a generated UUID
+func (mock *MockSubscriptionRepository) Create(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	subscription.ID = uuid.NewString()
+	mock.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+// GetByID retrieves a stored subscription
+func (mock *MockSubscriptionRepository) GetByID(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	subscription, exists := mock.subscriptions[subscriptionID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return subscription, nil
+}
+
+// Search returns the tenant's stored subscriptions
+func (mock *MockSubscriptionRepository) Search(ctx context.Context, searchParams *models.SubscriptionSearchParams) ([]*models.Subscription, error) {
+	result := []*models.Subscription{}
+	for _, subscription := range mock.subscriptions {
+		if subscription.TenantID == searchParams.TenantID {
+			result = append(result, subscription)
+		}
+	}
+	return result, nil
+}
+
+// Count returns how many subscriptions the tenant has
+func (mock *MockSubscriptionRepository) Count(ctx context.Context, searchParams *models.SubscriptionSearchParams) (int, error) {
+	subscriptions, _ := mock.Search(ctx, searchParams)
+	return len(subscriptions), nil
+}
+
+// Update replaces a stored subscription, keeping its tenant
+func (mock *MockSubscriptionRepository) Update(ctx context.Context, subscription *models.Subscription) (*models.Subscription, error) {
+	stored, exists := mock.subscriptions[subscription.ID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	subscription.TenantID = stored.TenantID
+	mock.subscriptions[subscription.ID] = subscription
+	return subscription, nil
+}
+
+// Delete removes a stored subscription
+func (mock *MockSubscriptionRepository) Delete(ctx context.Context, subscriptionID string) error {
+	if _, exists := mock.subscriptions[subscriptionID]; !exists {
+		return sql.ErrNoRows
+	}
+	delete(mock.subscriptions, subscriptionID)
+	return nil
+}
+
+// ListActive returns the active stored subscriptions
+func (mock *MockSubscriptionRepository) ListActive(ctx context.Context) ([]*models.Subscription, error) {
+	active := []*models.Subscription{}
+	for _, subscription := range mock.subscriptions {
+		if subscription.Status == models.SubscriptionStatusActive {
+			active = append(active, subscription)
+		}
+	}
+	return active, nil
+}
+
+// RecordError puts a stored subscription in error
+func (mock *MockSubscriptionRepository) RecordError(ctx context.Context, subscriptionID string, message string) error {
+	subscription, exists := mock.subscriptions[subscriptionID]
+	if !exists {
+		return sql.ErrNoRows
+	}
+	subscription.Status = models.SubscriptionStatusError
+	subscription.Error = message
+	return nil
+}
+
+// countingChangeListener counts subscription changes
+type countingChangeListener struct {
+	changes int
+}
+
+// SubscriptionsChanged counts the change
+func (listener *countingChangeListener) SubscriptionsChanged() {
+	listener.changes++
+}
+
+// restHookSubscription builds a requested rest-hook subscription for the criteria
+func restHookSubscription(criteria string) *fhir.Subscription {
+	endpoint := "https://alerts.example.com/notify"
+	return &fhir.Subscription{
+		Status:   fhir.SubscriptionStatusRequested,
+		Reason:   "Blood pressure alerts",
+		Criteria: criteria,
+		Channel:  fhir.SubscriptionChannel{Type: fhir.SubscriptionChannelTypeRestHook, Endpoint: &endpoint},
+	}
+}
+
+// TestSubscriptionService_Lifecycle verifies subscriptions are activated, scoped to their tenant, and announced
+func TestSubscriptionService_Lifecycle(t *testing.T) {
+	subscriptionRepository := NewMockSubscriptionRepository()
+	subscriptionService := NewSubscriptionService(subscriptionRepository)
+	changeListener := &countingChangeListener{}
+	subscriptionService.SetChangeListener(changeListener)
+	clinicContext := tenant.WithVerifiedTenant(context.Background(), "clinic-a")
+
+	created, createError := subscriptionService.CreateSubscription(clinicContext, restHookSubscription("Observation?patient=123&code=8480-6"))
+	if createError != nil {
+		t.Fatalf("Expected the subscription created, got %v", createError)
+	}
+	if created.Status != fhir.SubscriptionStatusActive || subscriptionRepository.subscriptions[*created.Id].TenantID != "clinic-a" {
+		t.Errorf("Expected an active subscription of the tenant, got %+v", subscriptionRepository.subscriptions[*created.Id])
+	}
+
+	otherContext := tenant.WithVerifiedTenant(context.Background(), "clinic-b")
+	if _, getError := subscriptionService.GetSubscriptionByID(otherContext, *created.Id); !errors.Is(getError, ErrResourceNotFound) {
+		t.Errorf("Expected another tenant's subscription not found, got %v", getError)
+	}
+	if deleteError := subscriptionService.DeleteSubscription(otherContext, *created.Id); !errors.Is(deleteError, ErrResourceNotFound) {
+		t.Errorf("Expected another tenant unable to delete the subscription, got %v", deleteError)
+	}
+
+	subscriptionRepository.RecordError(context.Background(), *created.Id, "webhook responded 410")
+	stopped, _ := subscriptionService.GetSubscriptionByID(clinicContext, *created.Id)
+	if _, keepError := subscriptionService.UpdateSubscription(clinicContext, *created.Id, stopped); keepError != nil {
+		t.Errorf("Expected an errored subscription to keep its error status, got %v", keepError)
+	}
+	if subscriptionRepository.subscriptions[*created.Id].Error != "webhook responded 410" {
+		t.Errorf("Expected the error kept, got %+v", subscriptionRepository.subscriptions[*created.Id])
+	}
+
+	restarted, updateError := subscriptionService.UpdateSubscription(clinicContext, *created.Id, restHookSubscription("Observation?patient=123"))
+	if updateError != nil || restarted.Status != fhir.SubscriptionStatusActive || restarted.Error != nil {
+		t.Errorf("Expected the subscription restarted without its error, got %+v (%v)", restarted, updateError)
+	}
+
+	if deleteError := subscriptionService.DeleteSubscription(clinicContext, *created.Id); deleteError != nil {
+		t.Fatalf("Expected the subscription deleted, got %v", deleteError)
+	}
+	if changeListener.changes != 4 {
+		t.Errorf("Expected the create, both updates, and the delete announced, got %d changes", changeListener.changes)
+	}
+}
+
+// TestSubscriptionService_Validation verifies subscriptions that cannot be notified are rejected with every problem
+func TestSubscriptionService_Validation(t *testing.T) {
+	subscriptionService := NewSubscriptionService(NewMockSubscriptionRepository())
+
+	invalid := restHookSubscription("Encounter?patient=123")
+	invalid.Channel.Type = fhir.SubscriptionChannelTypeEmail
+	endpoint := "ftp://alerts.example.com"
+	payload := "application/fhir+xml"
+	invalid.Channel.Endpoint = &endpoint
+	invalid.Channel.Payload = &payload
+	invalid.Channel.Header = []string{"no separator"}
+	invalid.Status = fhir.SubscriptionStatusError
+
+	_, createError := subscriptionService.CreateSubscription(context.Background(), invalid)
+	var rejection *outcome.RejectionError
+	if !errors.As(createError, &rejection) || len(rejection.Issues) != 6 {
+		t.Fatalf("Expected six rejection issues, got %v", createError)
+	}
+
+	unreadableEnd := restHookSubscription("Observation")
+	end := "next tuesday"
+	unreadableEnd.End = &end
+	if _, endError := subscriptionService.CreateSubscription(context.Background(), unreadableEnd); !errors.As(endError, &rejection) {
+		t.Errorf("Expected an unreadable end rejected, got %v", endError)
+	}
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+	"github.com/rs/zerolog/log"
+)
+
+// cursorPrefix namespaces the opaque cursor so clients cannot mistake it for a raw sequence
+const cursorPrefix = "seq:"
+
+// ChangeFeedPage is one page of the differential sync feed
+type ChangeFeedPage struct {
+	// Changes recorded after the requested cursor, oldest first
+	Changes []*models.ResourceChange
+
+	// Cursor to pass as "since" on the next request
+	Cursor string
+
+	// True when more changes are available beyond this page
+	HasMore bool
+}
+
+// SyncService serves the differential sync change feed
+type SyncService struct {
+	changeRepository repository.ChangeRepository
+}
+
+// NewSyncService creates a new sync service instance
+func NewSyncService(changeRepository repository.ChangeRepository) *SyncService {
+	return &SyncService{
+		changeRepository: changeRepository,
+	}
+}
+
+// ChangesSince returns up to limit changes recorded after the given cursor
+// An empty cursor starts from the beginning of the change log
+func (service *SyncService) ChangesSince(ctx context.Context, cursor string, limit int) (*ChangeFeedPage, error) {
+	afterSequence, decodeError := DecodeChangeCursor(cursor)
+	if decodeError != nil {
+		return nil, decodeError
+	}
+
+	// Fetch one extra change to detect whether another page exists
+	changes, listError := service.changeRepository.ListSince(ctx, afterSequence, limit+1)
+	if listError != nil {
+		return nil, listError
+	}
+
+	hasMore := len(changes) > limit
+	if hasMore {
+		changes = changes[:limit]
+	}
+
+	// The new cursor is the last returned sequence, or the incoming one when nothing changed
+	lastSequence := afterSequence
+	if len(changes) > 0 {
+		lastSequence = changes[len(changes)-1].Sequence
+	}
+
+	return &ChangeFeedPage{
+		Changes: changes,
+		Cursor:  EncodeChangeCursor(lastSequence),
+		HasMore: hasMore,
+	}, nil
+}
+
+// EncodeChangeCursor converts a change log sequence into an opaque cursor
+func EncodeChangeCursor(sequence int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(cursorPrefix + strconv.FormatInt(sequence, 10)))
+}
+
+// DecodeChangeCursor converts an opaque cursor back into a change log sequence
+func DecodeChangeCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	decodedCursor, decodeError := base64.RawURLEncoding.DecodeString(cursor)
+	if decodeError != nil || len(decodedCursor) <= len(cursorPrefix) || string(decodedCursor[:len(cursorPrefix)]) != cursorPrefix {
+		return 0, apperrors.InvalidInput("since", "must be a cursor returned by a previous sync request")
+	}
+
+	sequence, parseError := strconv.ParseInt(string(decodedCursor[len(cursorPrefix):]), 10, 64)
+	if parseError != nil || sequence < 0 {
+		return 0, apperrors.InvalidInput("since", "must be a cursor returned by a previous sync request")
+	}
+
+	return sequence, nil
+}
+
+// inChangeTransaction runs work in a change log transaction when a change log is configured
+// Postgres writes made with the context passed to work commit or roll back with the recorded change
+func inChangeTransaction(ctx context.Context, changeRepository repository.ChangeRepository, work func(ctx context.Context) error) error {
+	if changeRepository == nil {
+		return work(ctx)
+	}
+	return changeRepository.InTransaction(ctx, work)
+}
+
+// recordChange appends a write to the change log when one is configured and returns the new version
+// snapshot is the FHIR resource after the write (nil for deletes) and is kept for point-in-time reads
+// Callers run it in the write's transaction and fail the write when it returns an error, so the
+// sync feed never misses a committed write
+func recordChange(ctx context.Context, changeRepository repository.ChangeRepository, resourceType string, resourceID string, operation models.ChangeOperation, snapshot interface{}, compartmentPatientID string) (int, error) {
+	if changeRepository == nil {
+		return 0, nil
+	}
+
+	change := &models.ResourceChange{
+		ResourceType:         resourceType,
+		ResourceID:           resourceID,
+		Operation:            operation,
+		CompartmentPatientID: compartmentPatientID,
+	}
+	if snapshot != nil {
+		encodedSnapshot, encodeError := json.Marshal(snapshot)
+		if encodeError != nil {
+			return 0, encodeError
+		}
+		change.Snapshot = encodedSnapshot
+	}
+
+	recordedChange, recordError := changeRepository.Record(ctx, change)
+	if recordError != nil {
+		log.Error().
+			Err(recordError).
+			Str("resource_type", resourceType).
+			Str("resource_id", resourceID).
+			Str("operation", string(operation)).
+			Msg("Failed to record resource change")
+		return 0, recordError
+	}
+
+	return recordedChange.Version, nil
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// mockChangeEpoch is the time before the first recorded change; change N is recorded N minutes later
+var mockChangeEpoch = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// MockChangeRepository implements ChangeRepository interface for testing
+type MockChangeRepository struct {
+	changes     []*models.ResourceChange
+	recordError error
+}
+
+// InTransaction runs work and discards the changes it recorded when it fails, like a rollback
+func (mock *MockChangeRepository) InTransaction(ctx context.Context, work func(ctx context.Context) error) error {
+	recordedBefore := len(mock.changes)
+	if workError := work(ctx); workError != nil {
+		mock.changes = mock.changes[:recordedBefore]
+		return workError
+	}
+	return nil
+}
+
+// Record appends a change, assigning sequence and per-resource version
+func (mock *MockChangeRepository) Record(ctx context.Context, change *models.ResourceChange) (*models.ResourceChange, error) {
+	if mock.recordError != nil {
+		return nil, mock.recordError
+	}
+	change.Sequence = int64(len(mock.changes) + 1)
+	change.ChangedAt = mockChangeEpoch.Add(time.Duration(change.Sequence) * time.Minute)
+	change.Version = 1
+	for _, existingChange := range mock.changes {
+		if existingChange.ResourceType == change.ResourceType && existingChange.ResourceID == change.ResourceID {
+			change.Version++
+		}
+	}
+	mock.changes = append(mock.changes, change)
+	return change, nil
+}
+
+// ListSince returns changes after the given sequence
+func (mock *MockChangeRepository) ListSince(ctx context.Context, afterSequence int64, limit int) ([]*models.ResourceChange, error) {
+	result := []*models.ResourceChange{}
+	for _, change := range mock.changes {
+		if change.Sequence > afterSequence && len(result) < limit {
+			result = append(result, change)
+		}
+	}
+	return result, nil
+}
+
+// TestSyncService_ChangesSince_Paging verifies cursors walk the feed without gaps or repeats
+func TestSyncService_ChangesSince_Paging(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	for _, resourceID := range []string{"a", "b", "c"} {
+		changeRepository.Record(context.Background(), &models.ResourceChange{
+			ResourceType: "Patient", ResourceID: resourceID, Operation: models.ChangeOperationCreate,
+		})
+	}
+	syncService := NewSyncService(changeRepository)
+
+	firstPage, firstError := syncService.ChangesSince(context.Background(), "", 2)
+	if firstError != nil {
+		t.Fatalf("Expected no error, got %v", firstError)
+	}
+	if len(firstPage.Changes) != 2 || !firstPage.HasMore {
+		t.Fatalf("Expected 2 changes with more available, got %d (has_more=%v)", len(firstPage.Changes), firstPage.HasMore)
+	}
+
+	secondPage, _ := syncService.ChangesSince(context.Background(), firstPage.Cursor, 2)
+	if len(secondPage.Changes) != 1 || secondPage.Changes[0].ResourceID != "c" || secondPage.HasMore {
+		t.Fatalf("Expected final page with resource c, got %+v", secondPage)
+	}
+
+	// Polling with the latest cursor returns nothing new and keeps the cursor stable
+	emptyPage, _ := syncService.ChangesSince(context.Background(), secondPage.Cursor, 2)
+	if len(emptyPage.Changes) != 0 || emptyPage.Cursor != secondPage.Cursor {
+		t.Errorf("Expected empty page with unchanged cursor, got %+v", emptyPage)
+	}
+}
+
+// TestSyncService_ChangesSince_InvalidCursor verifies malformed cursors are rejected
+func TestSyncService_ChangesSince_InvalidCursor(t *testing.T) {
+	syncService := NewSyncService(&MockChangeRepository{})
+
+	for _, cursor := range []string{"not-base64!", "MTIz", EncodeChangeCursor(-1)} {
+		if _, cursorError := syncService.ChangesSince(context.Background(), cursor, 10); cursorError == nil {
+			t.Errorf("Expected error for cursor %q", cursor)
+		}
+	}
+}
+
+// TestChangeCursor_RoundTrip verifies cursors decode to the encoded sequence
+func TestChangeCursor_RoundTrip(t *testing.T) {
+	sequence, decodeError := DecodeChangeCursor(EncodeChangeCursor(42))
+	if decodeError != nil || sequence != 42 {
+		t.Errorf("Expected 42, got %d (%v)", sequence, decodeError)
+	}
+}
+
+// TestPatientService_RecordsChanges verifies writes are appended to the change log
+func TestPatientService_RecordsChanges(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(changeRepository)
+
+	createdPatient, _ := patientService.CreatePatient(context.Background(), &fhir.Patient{})
+	patientService.UpdatePatient(context.Background(), *createdPatient.Id, &fhir.Patient{})
+	patientService.DeletePatient(context.Background(), *createdPatient.Id)
+
+	if len(changeRepository.changes) != 3 {
+		t.Fatalf("Expected 3 recorded changes, got %d", len(changeRepository.changes))
+	}
+	expectedOperations := []models.ChangeOperation{models.ChangeOperationCreate, models.ChangeOperationUpdate, models.ChangeOperationDelete}
+	for index, change := range changeRepository.changes {
+		if change.Operation != expectedOperations[index] || change.ResourceID != *createdPatient.Id {
+			t.Errorf("Unexpected change at %d: %+v", index, change)
+		}
+		if change.Version != index+1 {
+			t.Errorf("Expected version %d, got %d", index+1, change.Version)
+		}
+	}
+}
+
+// TestPatientService_RecordChangeFailure verifies a change log failure fails the write instead of being dropped
+func TestPatientService_RecordChangeFailure(t *testing.T) {
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(&MockChangeRepository{recordError: errors.New("log unavailable")})
+
+	if _, createError := patientService.CreatePatient(context.Background(), &fhir.Patient{}); createError == nil {
+		t.Error("Expected create to fail when its change cannot be recorded")
+	}
+}
+
+// recordingWriteListener records the patient writes it is told about and fails when listenError is set
+type recordingWriteListener struct {
+	operations  []models.ChangeOperation
+	listenError error
+}
+
+// PatientWritten records the operation
+func (listener *recordingWriteListener) PatientWritten(ctx context.Context, operation models.ChangeOperation, patient *models.Patient) error {
+	listener.operations = append(listener.operations, operation)
+	return listener.listenError
+}
+
+// TestPatientService_WriteListener verifies listeners see creates and updates inside the write,
+// and that a failing listener rolls the write back
+func TestPatientService_WriteListener(t *testing.T) {
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(changeRepository)
+	listener := &recordingWriteListener{}
+	patientService.AddWriteListener(listener)
+
+	createdPatient, createError := patientService.CreatePatient(context.Background(), &fhir.Patient{})
+	if createError != nil {
+		t.Fatalf("Expected no error, got %v", createError)
+	}
+	patientService.UpdatePatient(context.Background(), *createdPatient.Id, createdPatient)
+	patientService.DeletePatient(context.Background(), *createdPatient.Id)
+
+	if len(listener.operations) != 2 || listener.operations[0] != models.ChangeOperationCreate || listener.operations[1] != models.ChangeOperationUpdate {
+		t.Errorf("Expected a create and an update, got %v", listener.operations)
+	}
+
+	listener.listenError = errors.New("queue unavailable")
+	recordedBefore := len(changeRepository.changes)
+	if _, failedError := patientService.CreatePatient(context.Background(), &fhir.Patient{}); failedError == nil {
+		t.Error("Expected create to fail when a write listener fails")
+	}
+	if len(changeRepository.changes) != recordedBefore {
+		t.Errorf("Expected the failed create's change to be rolled back")
+	}
+}
+
+// TestPatientService_TagChangesAreNotVersioned verifies tag changes write no change log entry and reach no listener
+func TestPatientService_TagChangesAreNotVersioned(t *testing.T) {
+	patientRepository := NewMockPatientRepository()
+	patientID := "3f2b8c1e-4d5a-4b6c-9e7f-0a1b2c3d4e5f"
+	patientRepository.patients[patientID] = &models.Patient{ID: patientID}
+	changeRepository := &MockChangeRepository{}
+	patientService := NewPatientService(patientRepository)
+	patientService.SetChangeRepository(changeRepository)
+	listener := &recordingWriteListener{}
+	patientService.AddWriteListener(listener)
+
+	reviewTag := models.Tag{System: "http://example.org/workflow", Code: "needs-review"}
+	if _, addError := patientService.AddPatientTags(context.Background(), patientID, models.Tags{reviewTag}); addError != nil {
+		t.Fatalf("Expected no error, got %v", addError)
+	}
+	patientService.RemovePatientTags(context.Background(), patientID, models.Tags{reviewTag})
+
+	if len(changeRepository.changes) != 0 || len(listener.operations) != 0 {
+		t.Errorf("Expected no versions or listener calls, got %d changes and %v", len(changeRepository.changes), listener.operations)
+	}
+}
+
+// TestObservationService_RecordChangeFailure verifies an observation create whose change cannot be recorded is undone
+func TestObservationService_RecordChangeFailure(t *testing.T) {
+	observationRepository := NewMockObservationRepository()
+	observationService := NewObservationService(observationRepository)
+	observationService.SetChangeRepository(&MockChangeRepository{recordError: errors.New("log unavailable")})
+
+	if _, createError := observationService.CreateObservation(context.Background(), &fhir.Observation{}); createError == nil {
+		t.Error("Expected create to fail when its change cannot be recorded")
+	}
+	if len(observationRepository.observations) != 0 {
+		t.Errorf("Expected the unrecorded observation to be removed, found %d", len(observationRepository.observations))
+	}
+}
@@ -0,0 +1,15 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/repository"
+)
+
+// ErrResourceNotFound is returned by tag operations when the target resource does not exist
+var ErrResourceNotFound = errors.New("resource not found")
+
+// ErrResourceDeleted is returned for a resource that was deleted: by reads, updates, and deletes of it, and by
+// history reads of a version that is a delete
+// It is the repositories' error, so a repository error passed through unchanged also matches it
+var ErrResourceDeleted = repository.ErrResourceDeleted
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+)
+
+// transactionScopeContextKey is the context key for the TransactionScope of a multi-write transaction
+type transactionScopeContextKey struct{}
+
+// pendingEvent is an event held back until its transaction commits
+type pendingEvent struct {
+	publisher events.Publisher
+	event     events.Event
+}
+
+// TransactionScope holds what a transaction spanning several service writes must settle when it ends:
+// events that may only be published once it commits, and undos for MongoDB writes, which cannot join
+// its Postgres transaction and so must be reversed by hand when it rolls back
+type TransactionScope struct {
+	mutex  sync.Mutex
+	events []pendingEvent
+	undos  []func(ctx context.Context)
+}
+
+// WithTransactionScope returns a context whose writes defer their events and register their undos with the
+// returned scope; the caller must end it with Commit or Rollback
+func WithTransactionScope(ctx context.Context) (context.Context, *TransactionScope) {
+	scope := &TransactionScope{}
+	return context.WithValue(ctx, transactionScopeContextKey{}, scope), scope
+}
+
+// transactionScopeFrom returns the scope carried by ctx, or nil outside a multi-write transaction
+func transactionScopeFrom(ctx context.Context) *TransactionScope {
+	scope, _ := ctx.Value(transactionScopeContextKey{}).(*TransactionScope)
+	return scope
+}
+
+// deferEvent holds an event until the transaction commits
+func (scope *TransactionScope) deferEvent(publisher events.Publisher, event events.Event) {
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+	scope.events = append(scope.events, pendingEvent{publisher: publisher, event: event})
+}
+
+// onRollback registers an undo to run if the transaction rolls back
+func (scope *TransactionScope) onRollback(undo func(ctx context.Context)) {
+	scope.mutex.Lock()
+	defer scope.mutex.Unlock()
+	scope.undos = append(scope.undos, undo)
+}
+
+// Commit publishes the deferred events in the order the writes were made; call it after the transaction commits
+func (scope *TransactionScope) Commit(ctx context.Context) {
+	scope.mutex.Lock()
+	pendingEvents := scope.events
+	scope.events, scope.undos = nil, nil
+	scope.mutex.Unlock()
+
+	for _, pending := range pendingEvents {
+		pending.publisher.Publish(ctx, pending.event)
+	}
+}
+
+// Rollback discards the deferred events and runs the undos, newest first; call it after the transaction rolls back
+// ctx must not carry the rolled-back transaction
+func (scope *TransactionScope) Rollback(ctx context.Context) {
+	scope.mutex.Lock()
+	undos := scope.undos
+	scope.events, scope.undos = nil, nil
+	scope.mutex.Unlock()
+
+	for undoIndex := len(undos) - 1; undoIndex >= 0; undoIndex-- {
+		undos[undoIndex](ctx)
+	}
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// TestTransactionScope_Commit verifies events raised inside a scope are held until it commits
+func TestTransactionScope_Commit(t *testing.T) {
+	publisher := &recordingPublisher{}
+	patientService := NewPatientService(NewMockPatientRepository())
+	patientService.SetChangeRepository(&MockChangeRepository{})
+	patientService.SetEventPublisher(publisher)
+
+	scopeContext, scope := WithTransactionScope(context.Background())
+	patientService.CreatePatient(scopeContext, &fhir.Patient{})
+	patientService.CreatePatient(scopeContext, &fhir.Patient{})
+	if len(publisher.published) != 0 {
+		t.Fatalf("Expected no events before commit, got %d", len(publisher.published))
+	}
+
+	scope.Commit(context.Background())
+	if len(publisher.published) != 2 {
+		t.Errorf("Expected both events after commit, got %d", len(publisher.published))
+	}
+}
+
+// TestTransactionScope_Rollback verifies a rolled-back scope drops its events and removes the observations it created
+func TestTransactionScope_Rollback(t *testing.T) {
+	publisher := &recordingPublisher{}
+	observationRepository := NewMockObservationRepository()
+	observationService := NewObservationService(observationRepository)
+	observationService.SetEventPublisher(publisher)
+
+	scopeContext, scope := WithTransactionScope(context.Background())
+	createdObservation, createError := observationService.CreateObservation(scopeContext, &fhir.Observation{})
+	if createError != nil {
+		t.Fatalf("Expected the observation to be created, got %v", createError)
+	}
+
+	scope.Rollback(context.Background())
+	if len(publisher.published) != 0 {
+		t.Errorf("Expected no events after rollback, got %d", len(publisher.published))
+	}
+	if _, getError := observationRepository.GetByID(context.Background(), *createdObservation.Id); getError == nil {
+		t.Error("Expected the created observation to be removed on rollback")
+	}
+	scope.Commit(context.Background())
+	if len(publisher.published) != 0 {
+		t.Errorf("Expected a rolled-back scope to publish nothing, got %d", len(publisher.published))
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/units"
+	"github.com/rs/zerolog/log"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// unitNormalization validates the units of observation values and converts the values to canonical units
+type unitNormalization struct {
+	converter *units.Converter
+
+	// action is units.ValidationReject or units.ValidationWarn; empty leaves units unchecked
+	action string
+
+	// storeCanonical stores each value's canonical value and unit next to the value as sent
+	storeCanonical bool
+}
+
+// applyUnitNormalization checks the units of the observation's value and component values before the write
+// Values whose unit is unknown, or cannot express the observation's analyte, fail the write or warn the caller,
+// depending on the action. With storeCanonical, the other values get their canonical value and unit.
+// Values without a unit pass unchanged
+func applyUnitNormalization(ctx context.Context, normalization *unitNormalization, observation *models.Observation) error {
+	if normalization == nil {
+		return nil
+	}
+
+	var rejectedIssues []outcome.Issue
+	canonicalValue, canonicalUnit, issue := normalization.normalize(ctx, observation, observation.Code, observation.ValueQuantity, observation.ValueUnit, "Observation.valueQuantity.unit")
+	observation.CanonicalValue, observation.CanonicalUnit = canonicalValue, canonicalUnit
+	if issue != nil {
+		rejectedIssues = append(rejectedIssues, *issue)
+	}
+	for index := range observation.Components {
+		component := &observation.Components[index]
+		expression := fmt.Sprintf("Observation.component[%d].valueQuantity.unit", index)
+		canonicalValue, canonicalUnit, issue := normalization.normalize(ctx, observation, component.Code, component.ValueQuantity, component.ValueUnit, expression)
+		component.CanonicalValue, component.CanonicalUnit = canonicalValue, canonicalUnit
+		if issue != nil {
+			rejectedIssues = append(rejectedIssues, *issue)
+		}
+	}
+
+	if len(rejectedIssues) > 0 {
+		return &outcome.RejectionError{Issues: rejectedIssues}
+	}
+	return nil
+}
+
+// normalize returns the canonical value and unit of one value of the observation, when they are stored, and
+// the issue rejecting the write when its unit does not convert and the action is reject
+// With the warn action the issue is collected as a warning instead
+func (normalization *unitNormalization) normalize(ctx context.Context, observation *models.Observation, code string, value *float64, unit string, expression string) (*float64, string, *outcome.Issue) {
+	if value == nil || unit == "" {
+		return nil, "", nil
+	}
+
+	canonical, normalizeError := normalization.converter.Normalize(code, *value, unit)
+	if normalizeError == nil {
+		if !normalization.storeCanonical {
+			return nil, "", nil
+		}
+		return &canonical.Value, canonical.Unit, nil
+	}
+	if normalization.action == "" {
+		return nil, "", nil
+	}
+
+	diagnostics := fmt.Sprintf("Observation %s: %v", code, normalizeError)
+	log.Warn().
+		Str("patient_id", observation.PatientID).
+		Str("code", code).
+		Str("unit", unit).
+		Str("action", normalization.action).
+		Msg(diagnostics)
+	if normalization.action == units.ValidationReject {
+		issue := outcome.Error(fhir.IssueTypeCodeInvalid, diagnostics, expression)
+		return nil, "", &issue
+	}
+	outcome.Collect(ctx, outcome.Warning(fhir.IssueTypeCodeInvalid, diagnostics, expression))
+	return nil, "", nil
+}
+
+// canonicalSearchParams returns the search with each value-quantity criterion given in a known unit also
+// converted to its canonical unit, so it matches observations stored with canonical values in other units
+// The search's code picks the analyte when it names exactly one; otherwise the unit's own dimension is used,
+// so a search in mmol/L without a code does not find glucose sent in mg/dL. Searches are returned unchanged
+// when canonical values are not stored
+func canonicalSearchParams(normalization *unitNormalization, searchParams *models.ObservationSearchParams) *models.ObservationSearchParams {
+	if normalization == nil || !normalization.storeCanonical || len(searchParams.ValueQuantity) == 0 {
+		return searchParams
+	}
+
+	code := ""
+	if len(searchParams.Code) == 1 && len(searchParams.Code[0]) == 1 {
+		code = searchParams.Code[0][0]
+	}
+
+	converted := *searchParams
+	converted.ValueQuantity = make(models.QuantityCriteria, len(searchParams.ValueQuantity))
+	for groupIndex, quantityGroup := range searchParams.ValueQuantity {
+		convertedGroup := make([]models.QuantityCriterion, len(quantityGroup))
+		for index, criterion := range quantityGroup {
+			convertedGroup[index] = canonicalCriterion(normalization.converter, code, criterion)
+		}
+		converted.ValueQuantity[groupIndex] = convertedGroup
+	}
+	return &converted
+}
+
+// canonicalCriterion converts a criterion's values to the canonical unit of its unit; criteria without a unit
+// or in a unit that does not convert are returned unchanged
+// Every conversion is increasing, so the converted range bounds the same values as the original
+func canonicalCriterion(converter *units.Converter, code string, criterion models.QuantityCriterion) models.QuantityCriterion {
+	if criterion.Unit == "" {
+		return criterion
+	}
+	canonicalValue, valueError := converter.Normalize(code, criterion.Value, criterion.Unit)
+	if valueError != nil {
+		return criterion
+	}
+	canonicalLow, _ := converter.Normalize(code, criterion.Low, criterion.Unit)
+	canonicalHigh, _ := converter.Normalize(code, criterion.High, criterion.Unit)
+
+	criterion.CanonicalUnit = canonicalValue.Unit
+	criterion.CanonicalValue = canonicalValue.Value
+	criterion.CanonicalLow = canonicalLow.Value
+	criterion.CanonicalHigh = canonicalHigh.Value
+	return criterion
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/outcome"
+	"github.com/nathannewyen/fhir-health-interop/internal/units"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// glucoseObservation builds a FHIR blood glucose observation with the given value and unit
+func glucoseObservation(value string, unit string) *fhir.Observation {
+	fhirObservation := heartRateObservation(value)
+	code := "2339-0"
+	fhirObservation.Code.Coding[0].Code = &code
+	fhirObservation.ValueQuantity.Unit = &unit
+	return fhirObservation
+}
+
+// TestObservationService_UnitNormalization verifies canonical values are stored next to the values as sent
+func TestObservationService_UnitNormalization(t *testing.T) {
+	mockRepo := NewMockObservationRepository()
+	observationService := NewObservationService(mockRepo)
+	observationService.SetUnitNormalization(units.NewConverter(units.DefaultTable()), units.ValidationReject, true)
+
+	created, createError := observationService.CreateObservation(context.Background(), glucoseObservation("5.5", "mmol/L"))
+	if createError != nil {
+		t.Fatalf("Expected the observation stored, got %v", createError)
+	}
+	stored := mockRepo.lastCreated
+	if *stored.ValueQuantity != 5.5 || stored.ValueUnit != "mmol/L" {
+		t.Errorf("Expected the value stored as sent, got %v %s", *stored.ValueQuantity, stored.ValueUnit)
+	}
+	if stored.CanonicalValue == nil || math.Abs(*stored.CanonicalValue-99.088) > 0.001 || stored.CanonicalUnit != "mg/dL" {
+		t.Errorf("Expected a canonical value of 99.088 mg/dL, got %v %s", stored.CanonicalValue, stored.CanonicalUnit)
+	}
+	if *created.ValueQuantity.Unit != "mmol/L" {
+		t.Errorf("Expected the response to keep the unit as sent, got %s", *created.ValueQuantity.Unit)
+	}
+
+	_, rejectError := observationService.CreateObservation(context.Background(), glucoseObservation("99", "kg"))
+	var rejection *outcome.RejectionError
+	if !errors.As(rejectError, &rejection) || rejection.Issues[0].Code != fhir.IssueTypeCodeInvalid {
+		t.Errorf("Expected a unit that cannot express glucose rejected, got %v", rejectError)
+	}
+
+	observationService.SetUnitNormalization(units.NewConverter(units.DefaultTable()), units.ValidationWarn, false)
+	ctx, collector := outcome.WithCollector(context.Background())
+	if _, warnError := observationService.CreateObservation(ctx, glucoseObservation("99", "mg/deciliter")); warnError != nil || len(collector.Issues()) != 1 {
+		t.Errorf("Expected the unknown unit stored with a warning, got %v and %+v", warnError, collector.Issues())
+	}
+	if mockRepo.lastCreated.CanonicalValue != nil {
+		t.Errorf("Expected no canonical value stored when storing is off, got %v", *mockRepo.lastCreated.CanonicalValue)
+	}
+}
+
+// TestCanonicalSearchParams verifies value-quantity criteria in a unit are converted for the searched code
+func TestCanonicalSearchParams(t *testing.T) {
+	normalization := &unitNormalization{converter: units.NewConverter(units.DefaultTable()), storeCanonical: true}
+	searchParams := &models.ObservationSearchParams{
+		Code: models.ValueCriteria{{"2339-0"}},
+		ValueQuantity: models.QuantityCriteria{
+			{{Comparator: "ge", Value: 5.5, Unit: "mmol/L"}},
+			{{Comparator: "lt", Value: 200}},
+		},
+	}
+
+	converted := canonicalSearchParams(normalization, searchParams)
+	criterion := converted.ValueQuantity[0][0]
+	if criterion.CanonicalUnit != "mg/dL" || math.Abs(criterion.CanonicalValue-99.088) > 0.001 {
+		t.Errorf("Expected the criterion converted to mg/dL, got %+v", criterion)
+	}
+	if converted.ValueQuantity[1][0].CanonicalUnit != "" {
+		t.Errorf("Expected a criterion without a unit left unchanged, got %+v", converted.ValueQuantity[1][0])
+	}
+	if searchParams.ValueQuantity[0][0].CanonicalUnit != "" {
+		t.Error("Expected the caller's search left unchanged")
+	}
+
+	searchParams.Code = nil
+	if withoutCode := canonicalSearchParams(normalization, searchParams); withoutCode.ValueQuantity[0][0].CanonicalUnit != "mmol/L" {
+		t.Errorf("Expected a search without a code to use the unit's own dimension, got %+v", withoutCode.ValueQuantity[0][0])
+	}
+
+	normalization.storeCanonical = false
+	if unchanged := canonicalSearchParams(normalization, searchParams); unchanged != searchParams {
+		t.Error("Expected searches unchanged when canonical values are not stored")
+	}
+}
@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/journal"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// MockWriteJournalRepository implements WriteJournalRepository for testing
+type MockWriteJournalRepository struct {
+	entries  []*models.WriteJournalEntry
+	appended []*models.WriteJournalEntry
+	nextID   int64
+}
+
+// Append stores a copy of the entry stamped with the current time
+func (mock *MockWriteJournalRepository) Append(ctx context.Context, entry *models.WriteJournalEntry) (*models.WriteJournalEntry, error) {
+	mock.nextID++
+	storedEntry := *entry
+	storedEntry.ID = mock.nextID
+	storedEntry.CreatedAt = time.Now()
+	mock.entries = append(mock.entries, &storedEntry)
+	mock.appended = append(mock.appended, &storedEntry)
+	return &storedEntry, nil
+}
+
+// Complete removes the entry
+func (mock *MockWriteJournalRepository) Complete(ctx context.Context, id int64) error {
+	for index, entry := range mock.entries {
+		if entry.ID == id {
+			mock.entries = append(mock.entries[:index], mock.entries[index+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+// ClaimOldest returns the first entry created before cutoff
+func (mock *MockWriteJournalRepository) ClaimOldest(ctx context.Context, cutoff time.Time) (*models.WriteJournalEntry, error) {
+	for _, entry := range mock.entries {
+		if entry.CreatedAt.Before(cutoff) {
+			return entry, nil
+		}
+	}
+	return nil, nil
+}
+
+// newJournaledObservationService returns an observation service with a change log and write journal
+func newJournaledObservationService() (*ObservationService, *MockObservationRepository, *MockChangeRepository, *MockWriteJournalRepository) {
+	observationRepository := NewMockObservationRepository()
+	changeRepository := &MockChangeRepository{}
+	writeJournal := &MockWriteJournalRepository{}
+	observationService := NewObservationService(observationRepository)
+	observationService.SetChangeRepository(changeRepository)
+	observationService.SetWriteJournal(writeJournal)
+	return observationService, observationRepository, changeRepository, writeJournal
+}
+
+// TestObservationService_JournaledWrite verifies a write is journaled under its final ID and the entry removed once recorded
+func TestObservationService_JournaledWrite(t *testing.T) {
+	observationService, _, changeRepository, writeJournal := newJournaledObservationService()
+
+	createdObservation, createError := observationService.CreateObservation(context.Background(), &fhir.Observation{})
+	if createError != nil {
+		t.Fatalf("Expected no error, got %v", createError)
+	}
+
+	if len(writeJournal.appended) != 1 || writeJournal.appended[0].ResourceID != *createdObservation.Id {
+		t.Fatalf("Expected the create to be journaled under its ID, got %+v", writeJournal.appended)
+	}
+	if len(writeJournal.entries) != 0 || len(changeRepository.changes) != 1 {
+		t.Errorf("Expected the entry removed and the change recorded, got %d entries and %d changes", len(writeJournal.entries), len(changeRepository.changes))
+	}
+}
+
+// TestObservationService_RecoverOldest verifies interrupted writes are completed when applied and rolled back otherwise
+func TestObservationService_RecoverOldest(t *testing.T) {
+	journaledAt := time.Now().Add(-time.Hour)
+	testCases := []struct {
+		name            string
+		operation       models.ChangeOperation
+		stored          *models.Observation
+		expectedOutcome journal.Outcome
+	}{
+		{"create applied", models.ChangeOperationCreate, &models.Observation{ID: "obs-1", PatientID: "patient-1", UpdatedAt: journaledAt.Add(time.Second)}, journal.OutcomeCompleted},
+		{"create not applied", models.ChangeOperationCreate, nil, journal.OutcomeRolledBack},
+		{"update applied", models.ChangeOperationUpdate, &models.Observation{ID: "obs-1", PatientID: "patient-1", UpdatedAt: journaledAt.Add(time.Second)}, journal.OutcomeCompleted},
+		{"update not applied", models.ChangeOperationUpdate, &models.Observation{ID: "obs-1", PatientID: "patient-1", UpdatedAt: journaledAt.Add(-time.Minute)}, journal.OutcomeRolledBack},
+		{"delete applied", models.ChangeOperationDelete, nil, journal.OutcomeCompleted},
+		{"delete not applied", models.ChangeOperationDelete, &models.Observation{ID: "obs-1", PatientID: "patient-1"}, journal.OutcomeRolledBack},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			observationService, observationRepository, changeRepository, writeJournal := newJournaledObservationService()
+			if testCase.stored != nil {
+				observationRepository.observations["obs-1"] = testCase.stored
+			}
+			writeJournal.entries = []*models.WriteJournalEntry{
+				{ID: 1, ResourceType: "Observation", ResourceID: "obs-1", Operation: testCase.operation, CreatedAt: journaledAt},
+			}
+
+			outcome, recoverError := observationService.RecoverOldest(context.Background(), time.Now())
+			if recoverError != nil {
+				t.Fatalf("Expected no error, got %v", recoverError)
+			}
+			if outcome != testCase.expectedOutcome {
+				t.Errorf("Expected outcome %d, got %d", testCase.expectedOutcome, outcome)
+			}
+			if len(writeJournal.entries) != 0 {
+				t.Errorf("Expected the entry to be removed, %d left", len(writeJournal.entries))
+			}
+
+			expectedChanges := 0
+			if testCase.expectedOutcome == journal.OutcomeCompleted {
+				expectedChanges = 1
+			}
+			if len(changeRepository.changes) != expectedChanges {
+				t.Fatalf("Expected %d recorded changes, got %d", expectedChanges, len(changeRepository.changes))
+			}
+			if expectedChanges == 1 && changeRepository.changes[0].Operation != testCase.operation {
+				t.Errorf("Expected a %s change, got %s", testCase.operation, changeRepository.changes[0].Operation)
+			}
+		})
+	}
+}
+
+// TestObservationService_RecoverUnrecordedUpdate verifies an update that stayed applied when its change failed
+// is recorded by the next recovery, and that recent entries are left to the live write
+func TestObservationService_RecoverUnrecordedUpdate(t *testing.T) {
+	observationService, observationRepository, changeRepository, writeJournal := newJournaledObservationService()
+	observationRepository.observations["obs-1"] = &models.Observation{ID: "obs-1", PatientID: "patient-1"}
+
+	changeRepository.recordError = errors.New("log unavailable")
+	if _, updateError := observationService.UpdateObservation(context.Background(), "obs-1", &fhir.Observation{}, 0); updateError == nil {
+		t.Fatal("Expected the update to fail when its change cannot be recorded")
+	}
+	if len(writeJournal.entries) != 1 {
+		t.Fatalf("Expected the journal entry to stay for recovery, got %d", len(writeJournal.entries))
+	}
+	changeRepository.recordError = nil
+
+	if outcome, _ := observationService.RecoverOldest(context.Background(), writeJournal.entries[0].CreatedAt); outcome != journal.OutcomeNone {
+		t.Errorf("Expected an entry newer than the cutoff to be left alone, got outcome %d", outcome)
+	}
+
+	outcome, recoverError := observationService.RecoverOldest(context.Background(), time.Now().Add(time.Second))
+	if recoverError != nil || outcome != journal.OutcomeCompleted {
+		t.Fatalf("Expected the update to be completed, got %d (%v)", outcome, recoverError)
+	}
+	if len(changeRepository.changes) != 1 || changeRepository.changes[0].Operation != models.ChangeOperationUpdate {
+		t.Errorf("Expected the update to be recorded, got %+v", changeRepository.changes)
+	}
+}
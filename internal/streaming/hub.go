@@ -0,0 +1,312 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrSlowConsumer is reported when a subscriber's buffer overflowed under the disconnect policy
+var ErrSlowConsumer = errors.New("subscriber disconnected: buffer full (slow consumer)")
+
+// ErrIdleTimeout is reported when a subscriber stopped draining messages for too long
+var ErrIdleTimeout = errors.New("subscriber disconnected: idle timeout")
+
+// ErrSubscriptionClosed is reported when a subscription was closed by its owner or the hub
+var ErrSubscriptionClosed = errors.New("subscription closed")
+
+// OverflowPolicy decides what happens when a subscriber's buffer is full
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest discards the oldest buffered message to make room for the new one
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+
+	// OverflowDisconnect closes the subscription so the client can reconnect and resync
+	OverflowDisconnect OverflowPolicy = "disconnect"
+)
+
+// Config holds per-connection limits applied to every subscriber of a hub
+type Config struct {
+	// BufferSize is the maximum number of undelivered messages held per subscriber
+	BufferSize int
+
+	// IdleTimeout closes subscribers that have not drained messages for this long
+	IdleTimeout time.Duration
+
+	// OverflowPolicy decides how a full buffer is handled
+	OverflowPolicy OverflowPolicy
+}
+
+// DefaultConfig returns conservative limits suitable for dashboard connections
+func DefaultConfig() Config {
+	return Config{
+		BufferSize:     64,
+		IdleTimeout:    30 * time.Second,
+		OverflowPolicy: OverflowDisconnect,
+	}
+}
+
+// Stats reports hub-wide counters for monitoring slow consumers
+type Stats struct {
+	Subscribers     int   `json:"subscribers"`
+	Published       int64 `json:"published"`
+	Dropped         int64 `json:"dropped"`
+	SlowDisconnects int64 `json:"slow_disconnects"`
+	IdleDisconnects int64 `json:"idle_disconnects"`
+}
+
+// Hub fans out messages published on a topic to all subscribers of that topic
+// Publishing never blocks: each subscriber has a bounded buffer so memory stays bounded
+type Hub struct {
+	config Config
+
+	mutex       sync.Mutex
+	subscribers map[string]map[*Subscription]struct{}
+
+	published       atomic.Int64
+	dropped         atomic.Int64
+	slowDisconnects atomic.Int64
+	idleDisconnects atomic.Int64
+}
+
+// NewHub creates a hub enforcing the given per-connection limits
+func NewHub(config Config) *Hub {
+	// Fall back to defaults for unset limits so a zero Config is still safe
+	defaults := DefaultConfig()
+	if config.BufferSize <= 0 {
+		config.BufferSize = defaults.BufferSize
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaults.IdleTimeout
+	}
+	if config.OverflowPolicy == "" {
+		config.OverflowPolicy = defaults.OverflowPolicy
+	}
+
+	return &Hub{
+		config:      config,
+		subscribers: make(map[string]map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber on a topic
+func (hub *Hub) Subscribe(topic string) *Subscription {
+	subscription := &Subscription{
+		hub:      hub,
+		topic:    topic,
+		messages: make(chan []byte, hub.config.BufferSize),
+		done:     make(chan struct{}),
+	}
+	subscription.touch(time.Now())
+
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	if hub.subscribers[topic] == nil {
+		hub.subscribers[topic] = make(map[*Subscription]struct{})
+	}
+	hub.subscribers[topic][subscription] = struct{}{}
+
+	return subscription
+}
+
+// Publish delivers a message to every subscriber of a topic without blocking
+func (hub *Hub) Publish(topic string, message []byte) {
+	hub.published.Add(1)
+
+	// Copy the subscriber set so slow deliveries never hold the hub lock
+	hub.mutex.Lock()
+	recipients := make([]*Subscription, 0, len(hub.subscribers[topic]))
+	for subscription := range hub.subscribers[topic] {
+		recipients = append(recipients, subscription)
+	}
+	hub.mutex.Unlock()
+
+	for _, subscription := range recipients {
+		hub.deliver(subscription, message)
+	}
+}
+
+// Stats returns a snapshot of hub counters
+func (hub *Hub) Stats() Stats {
+	hub.mutex.Lock()
+	subscriberCount := 0
+	for _, topicSubscribers := range hub.subscribers {
+		subscriberCount += len(topicSubscribers)
+	}
+	hub.mutex.Unlock()
+
+	return Stats{
+		Subscribers:     subscriberCount,
+		Published:       hub.published.Load(),
+		Dropped:         hub.dropped.Load(),
+		SlowDisconnects: hub.slowDisconnects.Load(),
+		IdleDisconnects: hub.idleDisconnects.Load(),
+	}
+}
+
+// Run periodically closes idle subscribers until the context is cancelled
+func (hub *Hub) Run(ctx context.Context) {
+	// Check several times per timeout window so idle consumers are closed promptly
+	ticker := time.NewTicker(hub.config.IdleTimeout / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			hub.reapIdle(now)
+		}
+	}
+}
+
+// deliver enqueues a message for one subscriber, applying the overflow policy when full
+func (hub *Hub) deliver(subscription *Subscription, message []byte) {
+	select {
+	case subscription.messages <- message:
+		return
+	case <-subscription.done:
+		return
+	default:
+	}
+
+	// Buffer is full: the consumer is not keeping up
+	if hub.config.OverflowPolicy == OverflowDisconnect {
+		hub.slowDisconnects.Add(1)
+		subscription.closeWithReason(ErrSlowConsumer)
+		return
+	}
+
+	// Drop the oldest buffered message and retry once; a concurrent reader may have made room already
+	select {
+	case <-subscription.messages:
+		hub.dropped.Add(1)
+	default:
+	}
+	select {
+	case subscription.messages <- message:
+	default:
+		hub.dropped.Add(1)
+	}
+}
+
+// reapIdle closes subscribers that have buffered messages but stopped draining them
+func (hub *Hub) reapIdle(now time.Time) {
+	hub.mutex.Lock()
+	candidates := make([]*Subscription, 0)
+	for _, topicSubscribers := range hub.subscribers {
+		for subscription := range topicSubscribers {
+			candidates = append(candidates, subscription)
+		}
+	}
+	hub.mutex.Unlock()
+
+	for _, subscription := range candidates {
+		// A consumer blocked waiting for new messages is healthy, not idle
+		if subscription.waiting.Load() {
+			continue
+		}
+		lastActivity := time.Unix(0, subscription.lastActivity.Load())
+		if now.Sub(lastActivity) > hub.config.IdleTimeout {
+			hub.idleDisconnects.Add(1)
+			subscription.closeWithReason(ErrIdleTimeout)
+		}
+	}
+}
+
+// remove unregisters a subscription from its topic
+func (hub *Hub) remove(subscription *Subscription) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	topicSubscribers := hub.subscribers[subscription.topic]
+	delete(topicSubscribers, subscription)
+	if len(topicSubscribers) == 0 {
+		delete(hub.subscribers, subscription.topic)
+	}
+}
+
+// Subscription is a single consumer's bounded view of a topic
+type Subscription struct {
+	hub      *Hub
+	topic    string
+	messages chan []byte
+	done     chan struct{}
+
+	closeOnce   sync.Once
+	closeReason error
+
+	// lastActivity is the unix-nano time the consumer last drained a message
+	lastActivity atomic.Int64
+
+	// waiting is true while the consumer is blocked in Next waiting for a message
+	waiting atomic.Bool
+}
+
+// Topic returns the topic this subscription listens to
+func (subscription *Subscription) Topic() string {
+	return subscription.topic
+}
+
+// Next blocks until a message is available, the subscription closes, or the context ends
+func (subscription *Subscription) Next(ctx context.Context) ([]byte, error) {
+	subscription.waiting.Store(true)
+	defer func() {
+		subscription.waiting.Store(false)
+		subscription.touch(time.Now())
+	}()
+
+	// Deliver already-buffered messages before reporting closure
+	select {
+	case message := <-subscription.messages:
+		return message, nil
+	default:
+	}
+
+	select {
+	case message := <-subscription.messages:
+		return message, nil
+	case <-subscription.done:
+		return nil, subscription.Err()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// Done returns a channel closed when the subscription ends
+func (subscription *Subscription) Done() <-chan struct{} {
+	return subscription.done
+}
+
+// Err returns why the subscription was closed, or nil while it is open
+func (subscription *Subscription) Err() error {
+	select {
+	case <-subscription.done:
+		return subscription.closeReason
+	default:
+		return nil
+	}
+}
+
+// Close ends the subscription and releases its buffer
+func (subscription *Subscription) Close() {
+	subscription.closeWithReason(ErrSubscriptionClosed)
+}
+
+// closeWithReason closes the subscription exactly once and records why
+func (subscription *Subscription) closeWithReason(reason error) {
+	subscription.closeOnce.Do(func() {
+		subscription.closeReason = reason
+		close(subscription.done)
+		subscription.hub.remove(subscription)
+	})
+}
+
+// touch records consumer activity
+func (subscription *Subscription) touch(now time.Time) {
+	subscription.lastActivity.Store(now.UnixNano())
+}
@@ -0,0 +1,137 @@
+package streaming
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestHub_PublishDelivers verifies subscribers receive messages for their topic only
+func TestHub_PublishDelivers(t *testing.T) {
+	hub := NewHub(DefaultConfig())
+	subscription := hub.Subscribe("patient-1")
+	otherSubscription := hub.Subscribe("patient-2")
+
+	hub.Publish("patient-1", []byte("hello"))
+
+	message, nextError := subscription.Next(context.Background())
+	if nextError != nil {
+		t.Fatalf("Expected message, got error %v", nextError)
+	}
+	if string(message) != "hello" {
+		t.Errorf("Expected hello, got %s", message)
+	}
+
+	// The other topic's subscriber should have nothing buffered
+	timeoutContext, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, nextError := otherSubscription.Next(timeoutContext); !errors.Is(nextError, context.DeadlineExceeded) {
+		t.Errorf("Expected deadline exceeded, got %v", nextError)
+	}
+}
+
+// TestHub_DisconnectSlowConsumer verifies a full buffer disconnects the subscriber
+func TestHub_DisconnectSlowConsumer(t *testing.T) {
+	hub := NewHub(Config{BufferSize: 2, OverflowPolicy: OverflowDisconnect})
+	subscription := hub.Subscribe("topic")
+
+	// Publish more messages than the buffer can hold without consuming any
+	for index := 0; index < 3; index++ {
+		hub.Publish("topic", []byte("message"))
+	}
+
+	select {
+	case <-subscription.Done():
+	default:
+		t.Fatal("Expected slow subscription to be closed")
+	}
+	if !errors.Is(subscription.Err(), ErrSlowConsumer) {
+		t.Errorf("Expected ErrSlowConsumer, got %v", subscription.Err())
+	}
+
+	stats := hub.Stats()
+	if stats.SlowDisconnects != 1 {
+		t.Errorf("Expected 1 slow disconnect, got %d", stats.SlowDisconnects)
+	}
+	if stats.Subscribers != 0 {
+		t.Errorf("Expected subscriber to be removed, got %d", stats.Subscribers)
+	}
+}
+
+// TestHub_DropOldest verifies the drop-oldest policy keeps the newest messages
+func TestHub_DropOldest(t *testing.T) {
+	hub := NewHub(Config{BufferSize: 2, OverflowPolicy: OverflowDropOldest})
+	subscription := hub.Subscribe("topic")
+
+	hub.Publish("topic", []byte("1"))
+	hub.Publish("topic", []byte("2"))
+	hub.Publish("topic", []byte("3"))
+
+	firstMessage, _ := subscription.Next(context.Background())
+	secondMessage, _ := subscription.Next(context.Background())
+	if string(firstMessage) != "2" || string(secondMessage) != "3" {
+		t.Errorf("Expected messages 2 and 3, got %s and %s", firstMessage, secondMessage)
+	}
+	if hub.Stats().Dropped != 1 {
+		t.Errorf("Expected 1 dropped message, got %d", hub.Stats().Dropped)
+	}
+	if subscription.Err() != nil {
+		t.Errorf("Expected subscription to stay open, got %v", subscription.Err())
+	}
+}
+
+// TestHub_ReapIdle verifies a consumer that stopped draining is closed
+func TestHub_ReapIdle(t *testing.T) {
+	hub := NewHub(Config{BufferSize: 4, IdleTimeout: time.Second})
+	idleSubscription := hub.Subscribe("topic")
+
+	// A consumer blocked in Next is waiting for data and must not be reaped
+	waitingSubscription := hub.Subscribe("other")
+	waitingContext, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go waitingSubscription.Next(waitingContext)
+	for !waitingSubscription.waiting.Load() {
+		time.Sleep(time.Millisecond)
+	}
+
+	hub.reapIdle(time.Now().Add(2 * time.Second))
+
+	if !errors.Is(idleSubscription.Err(), ErrIdleTimeout) {
+		t.Errorf("Expected idle subscription to be closed, got %v", idleSubscription.Err())
+	}
+	if waitingSubscription.Err() != nil {
+		t.Errorf("Expected waiting subscription to stay open, got %v", waitingSubscription.Err())
+	}
+}
+
+// TestSubscription_NextDrainsBeforeClose verifies buffered messages are delivered before closure
+func TestSubscription_NextDrainsBeforeClose(t *testing.T) {
+	hub := NewHub(DefaultConfig())
+	subscription := hub.Subscribe("topic")
+
+	hub.Publish("topic", []byte("last"))
+	subscription.Close()
+
+	message, nextError := subscription.Next(context.Background())
+	if nextError != nil || string(message) != "last" {
+		t.Errorf("Expected buffered message, got %s (%v)", message, nextError)
+	}
+
+	_, nextError = subscription.Next(context.Background())
+	if !errors.Is(nextError, ErrSubscriptionClosed) {
+		t.Errorf("Expected ErrSubscriptionClosed, got %v", nextError)
+	}
+}
+
+// TestNewHub_Defaults verifies unset limits fall back to defaults
+func TestNewHub_Defaults(t *testing.T) {
+	hub := NewHub(Config{})
+
+	if hub.config.BufferSize != DefaultConfig().BufferSize {
+		t.Errorf("Expected default buffer size, got %d", hub.config.BufferSize)
+	}
+	if hub.config.OverflowPolicy != OverflowDisconnect {
+		t.Errorf("Expected disconnect policy, got %s", hub.config.OverflowPolicy)
+	}
+}
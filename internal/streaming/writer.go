@@ -0,0 +1,67 @@
+package streaming
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// defaultFlushThreshold is the number of buffered bytes after which the writer flushes
+const defaultFlushThreshold = 32 * 1024
+
+// Writer streams newline-delimited records to an HTTP response with backpressure
+// Each write carries a deadline, so a client that stops reading makes the producer fail fast
+// instead of letting the server buffer unbounded output on its behalf
+type Writer struct {
+	responseWriter http.ResponseWriter
+	controller     *http.ResponseController
+	writeTimeout   time.Duration
+	flushThreshold int
+	bufferedBytes  int
+}
+
+// NewWriter creates a streaming writer with a per-write timeout
+func NewWriter(responseWriter http.ResponseWriter, writeTimeout time.Duration) *Writer {
+	return &Writer{
+		responseWriter: responseWriter,
+		controller:     http.NewResponseController(responseWriter),
+		writeTimeout:   writeTimeout,
+		flushThreshold: defaultFlushThreshold,
+	}
+}
+
+// WriteRecord writes one record followed by a newline, flushing once enough bytes are buffered
+func (writer *Writer) WriteRecord(record []byte) error {
+	// Bound how long this write may block on a slow client
+	if writer.writeTimeout > 0 {
+		deadlineError := writer.controller.SetWriteDeadline(time.Now().Add(writer.writeTimeout))
+		if deadlineError != nil && !errors.Is(deadlineError, http.ErrNotSupported) {
+			return deadlineError
+		}
+	}
+
+	if _, writeError := writer.responseWriter.Write(record); writeError != nil {
+		return writeError
+	}
+	if _, writeError := writer.responseWriter.Write([]byte("\n")); writeError != nil {
+		return writeError
+	}
+
+	// Flush periodically so the client sees progress and server-side buffers stay small
+	writer.bufferedBytes += len(record) + 1
+	if writer.bufferedBytes >= writer.flushThreshold {
+		return writer.Flush()
+	}
+
+	return nil
+}
+
+// Flush pushes buffered bytes to the client
+func (writer *Writer) Flush() error {
+	writer.bufferedBytes = 0
+	flushError := writer.controller.Flush()
+	if flushError != nil && !errors.Is(flushError, http.ErrNotSupported) {
+		return flushError
+	}
+	return nil
+}
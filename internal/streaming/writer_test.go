@@ -0,0 +1,61 @@
+package streaming
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// failingResponseWriter simulates a client connection that has gone away
+type failingResponseWriter struct {
+	*httptest.ResponseRecorder
+}
+
+// Write always fails like a timed-out connection would
+func (writer *failingResponseWriter) Write(data []byte) (int, error) {
+	return 0, errors.New("i/o timeout")
+}
+
+// TestWriter_WriteRecord verifies records are newline delimited
+func TestWriter_WriteRecord(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := NewWriter(recorder, time.Second)
+
+	writer.WriteRecord([]byte(`{"id":"1"}`))
+	writer.WriteRecord([]byte(`{"id":"2"}`))
+	writer.Flush()
+
+	expected := "{\"id\":\"1\"}\n{\"id\":\"2\"}\n"
+	if recorder.Body.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, recorder.Body.String())
+	}
+	if !recorder.Flushed {
+		t.Error("Expected response to be flushed")
+	}
+}
+
+// TestWriter_FlushThreshold verifies large output is flushed incrementally
+func TestWriter_FlushThreshold(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	writer := NewWriter(recorder, 0)
+	writer.flushThreshold = 8
+
+	writer.WriteRecord([]byte("0123456789"))
+
+	if !recorder.Flushed {
+		t.Error("Expected writer to flush after exceeding threshold")
+	}
+	if writer.bufferedBytes != 0 {
+		t.Errorf("Expected buffered bytes reset, got %d", writer.bufferedBytes)
+	}
+}
+
+// TestWriter_WriteError verifies write failures are surfaced so producers stop
+func TestWriter_WriteError(t *testing.T) {
+	writer := NewWriter(&failingResponseWriter{httptest.NewRecorder()}, time.Second)
+
+	if writeError := writer.WriteRecord([]byte("record")); writeError == nil {
+		t.Error("Expected write error to be returned")
+	}
+}
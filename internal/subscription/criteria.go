@@ -0,0 +1,265 @@
+package subscription
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/nathannewyen/fhir-health-interop/internal/utils"
+)
+
+// criteriaParameters lists the search parameters a criteria may use for each resource type that can be
+// subscribed to; paging, sorting, and include parameters mean nothing for a single written resource
+var criteriaParameters = map[string][]string{
+	"Patient": {
+		"name", "name:contains", "name:exact",
+		"family", "family:contains", "family:exact",
+		"given", "given:contains", "given:exact",
+		"gender", "birthdate", "active", "identifier", "_tag",
+	},
+	"Observation": {"patient", "encounter", "code", "category", "status", "date", "value-quantity", "_tag"},
+}
+
+// Criteria is a parsed subscription criteria: a resource type and the search its written resources must match
+type Criteria struct {
+	ResourceType string
+
+	// Patient or Observation holds the parsed search for the criteria's resource type
+	Patient     *models.PatientSearchParams
+	Observation *models.ObservationSearchParams
+}
+
+// ParseCriteria parses a criteria such as "Observation?patient=123&code=8480-6" with the search parsers, so a
+// subscription matches the written resources the same search would return
+func ParseCriteria(rawCriteria string) (*Criteria, error) {
+	resourceType, rawQuery, _ := strings.Cut(rawCriteria, "?")
+	supportedParameters, supported := criteriaParameters[resourceType]
+	if !supported {
+		return nil, fmt.Errorf("criteria must start with one of %s, got %q", strings.Join(subscribableTypes(), ", "), resourceType)
+	}
+
+	query, queryError := url.ParseQuery(rawQuery)
+	if queryError != nil {
+		return nil, fmt.Errorf("criteria query is malformed: %w", queryError)
+	}
+	for parameter := range query {
+		if !slices.Contains(supportedParameters, parameter) {
+			return nil, fmt.Errorf("criteria parameter %q is not supported for %s; supported parameters are %s", parameter, resourceType, strings.Join(supportedParameters, ", "))
+		}
+	}
+
+	criteria := &Criteria{ResourceType: resourceType}
+	searchRequest := &http.Request{URL: &url.URL{RawQuery: rawQuery}}
+	var parseError error
+	switch resourceType {
+	case "Patient":
+		criteria.Patient, parseError = utils.ParsePatientSearchParams(searchRequest)
+	case "Observation":
+		criteria.Observation, parseError = utils.ParseObservationSearchParams(searchRequest)
+	}
+	if parseError != nil {
+		return nil, fmt.Errorf("criteria search is invalid: %w", parseError)
+	}
+	return criteria, nil
+}
+
+// subscribableTypes returns the resource types a criteria can name, sorted
+func subscribableTypes() []string {
+	resourceTypes := make([]string, 0, len(criteriaParameters))
+	for resourceType := range criteriaParameters {
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+	sort.Strings(resourceTypes)
+	return resourceTypes
+}
+
+// Matches reports whether a written resource of resourceType matches the criteria
+// Resources are the domain models the services publish; anything else never matches
+func (criteria *Criteria) Matches(resourceType string, resource interface{}) bool {
+	if resourceType != criteria.ResourceType {
+		return false
+	}
+	switch written := resource.(type) {
+	case *models.Patient:
+		return criteria.Patient != nil && matchesPatient(criteria.Patient, written)
+	case *models.Observation:
+		return criteria.Observation != nil && matchesObservation(criteria.Observation, written)
+	default:
+		return false
+	}
+}
+
+// matchesPatient applies the filters of the patient repository's search to one patient
+func matchesPatient(searchParams *models.PatientSearchParams, patient *models.Patient) bool {
+	for _, criterion := range searchParams.Name {
+		if !matchesName(criterion, patient.GivenName, patient.FamilyName) {
+			return false
+		}
+	}
+	for _, criterion := range searchParams.FamilyName {
+		if !matchesName(criterion, patient.FamilyName) {
+			return false
+		}
+	}
+	for _, criterion := range searchParams.GivenName {
+		if !matchesName(criterion, patient.GivenName) {
+			return false
+		}
+	}
+	if !matchesValues(searchParams.Gender, patient.Gender) {
+		return false
+	}
+
+	// Birth dates are compared as dates, whatever time of day they were parsed with
+	if searchParams.BirthDate != nil || searchParams.BirthDateGreaterThan != nil || searchParams.BirthDateLessThan != nil {
+		if patient.BirthDate == nil {
+			return false
+		}
+		birthDate := calendarDate(*patient.BirthDate)
+		if searchParams.BirthDate != nil && !birthDate.Equal(calendarDate(*searchParams.BirthDate)) {
+			return false
+		}
+		if searchParams.BirthDateGreaterThan != nil && birthDate.Before(calendarDate(*searchParams.BirthDateGreaterThan)) {
+			return false
+		}
+		if searchParams.BirthDateLessThan != nil && birthDate.After(calendarDate(*searchParams.BirthDateLessThan)) {
+			return false
+		}
+	}
+
+	if searchParams.Active != nil && patient.Active != *searchParams.Active {
+		return false
+	}
+	if identifier := searchParams.Identifier; identifier != nil {
+		if len(identifier.Systems) > 0 && !slices.Contains(identifier.Systems, patient.IdentifierSystem) {
+			return false
+		}
+		if identifier.Value != "" && patient.IdentifierValue != identifier.Value {
+			return false
+		}
+	}
+	return matchesTags(searchParams.Tags, patient.Tags)
+}
+
+// matchesObservation applies the filters of the observation repository's search to one observation
+func matchesObservation(searchParams *models.ObservationSearchParams, observation *models.Observation) bool {
+	if searchParams.PatientID != "" && observation.PatientID != searchParams.PatientID {
+		return false
+	}
+	if searchParams.EncounterID != "" && observation.EncounterID != searchParams.EncounterID {
+		return false
+	}
+	if !matchesValues(searchParams.Code, observation.Code) || !matchesValues(searchParams.Category, observation.Category) || !matchesValues(searchParams.Status, observation.Status) {
+		return false
+	}
+
+	if searchParams.DateGreaterThan != nil || searchParams.DateLessThan != nil {
+		if observation.EffectiveDate == nil {
+			return false
+		}
+		if searchParams.DateGreaterThan != nil && observation.EffectiveDate.Before(*searchParams.DateGreaterThan) {
+			return false
+		}
+		if searchParams.DateLessThan != nil && observation.EffectiveDate.After(*searchParams.DateLessThan) {
+			return false
+		}
+	}
+
+	for _, quantityGroup := range searchParams.ValueQuantity {
+		if !slices.ContainsFunc(quantityGroup, func(criterion models.QuantityCriterion) bool {
+			return matchesQuantity(criterion, observation)
+		}) {
+			return false
+		}
+	}
+	return matchesTags(searchParams.Tags, observation.Tags)
+}
+
+// matchesName reports whether any value of the criterion matches any of the names: exactly for :exact,
+// otherwise as a part of the name ignoring case
+func matchesName(criterion models.NameCriterion, names ...string) bool {
+	for _, value := range criterion.Values {
+		for _, name := range names {
+			if criterion.Match == models.NameMatchExact && name == value {
+				return true
+			}
+			if criterion.Match != models.NameMatchExact && strings.Contains(strings.ToLower(name), strings.ToLower(value)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchesValues reports whether the value is in every group of the criteria
+func matchesValues(criteria models.ValueCriteria, value string) bool {
+	for _, valueGroup := range criteria {
+		if !slices.Contains(valueGroup, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesQuantity compares the observation's value by a value-quantity criterion; a criterion with a unit
+// matches only values recorded in that unit
+func matchesQuantity(criterion models.QuantityCriterion, observation *models.Observation) bool {
+	if observation.ValueQuantity == nil {
+		return false
+	}
+	if criterion.Unit != "" && observation.ValueUnit != criterion.Unit {
+		return false
+	}
+
+	value := *observation.ValueQuantity
+	switch criterion.Comparator {
+	case "gt":
+		return value > criterion.Value
+	case "lt":
+		return value < criterion.Value
+	case "ge":
+		return value >= criterion.Value
+	case "le":
+		return value <= criterion.Value
+	case "ne":
+		return value < criterion.Low || value >= criterion.High
+	default:
+		return value >= criterion.Low && value < criterion.High
+	}
+}
+
+// matchesTags reports whether every group of the criteria has a criterion matching one of the tags
+func matchesTags(criteria models.TagCriteria, tags models.Tags) bool {
+	for _, tagGroup := range criteria {
+		if !slices.ContainsFunc(tagGroup, func(criterion models.TagCriterion) bool {
+			return slices.ContainsFunc(tags, func(tag models.Tag) bool {
+				return matchesTag(criterion, tag)
+			})
+		}) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesTag reports whether a tag matches a _tag criterion the way the repositories' tag filters do
+func matchesTag(criterion models.TagCriterion, tag models.Tag) bool {
+	if criterion.System != "" && tag.System != criterion.System {
+		return false
+	}
+	if criterion.System == "" && !criterion.AnySystem && tag.System != "" {
+		return false
+	}
+	return criterion.Code == "" || tag.Code == criterion.Code
+}
+
+// calendarDate truncates a time to its UTC date
+func calendarDate(moment time.Time) time.Time {
+	year, month, day := moment.UTC().Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
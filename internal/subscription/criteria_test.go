@@ -0,0 +1,111 @@
+package subscription
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// floatPointer returns a pointer to value
+func floatPointer(value float64) *float64 {
+	return &value
+}
+
+// TestParseCriteria_Invalid verifies criteria the notifier cannot match are refused
+func TestParseCriteria_Invalid(t *testing.T) {
+	invalidCriteria := []string{
+		"",
+		"Encounter?patient=123",
+		"Observation?patient=123&_count=5",
+		"Observation?subject=Patient/123",
+		"Patient?name:missing=true",
+		"Observation?value-quantity=abc",
+		"Observation?code=%zz",
+	}
+	for _, rawCriteria := range invalidCriteria {
+		if _, parseError := ParseCriteria(rawCriteria); parseError == nil {
+			t.Errorf("Expected %q to be refused", rawCriteria)
+		}
+	}
+}
+
+// TestCriteria_Matches_Observation verifies observations are matched the way an Observation search filters them
+func TestCriteria_Matches_Observation(t *testing.T) {
+	effectiveDate := time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC)
+	observation := &models.Observation{
+		PatientID:     "123",
+		Code:          "8480-6",
+		Category:      "vital-signs",
+		Status:        "final",
+		ValueQuantity: floatPointer(150),
+		ValueUnit:     "mm[Hg]",
+		EffectiveDate: &effectiveDate,
+		Tags:          models.Tags{{System: "http://example.org/workflow", Code: "reviewed"}},
+	}
+
+	testCases := []struct {
+		criteria string
+		matches  bool
+	}{
+		{criteria: "Observation", matches: true},
+		{criteria: "Observation?patient=123&code=8480-6", matches: true},
+		{criteria: "Observation?patient=Patient/123&code=8462-4,8480-6", matches: true},
+		{criteria: "Observation?patient=456&code=8480-6", matches: false},
+		{criteria: "Observation?code=8480-6&code=8462-4", matches: false},
+		{criteria: "Observation?value-quantity=gt140|http://unitsofmeasure.org|mm[Hg]", matches: true},
+		{criteria: "Observation?value-quantity=gt140|http://unitsofmeasure.org|mmHg", matches: false},
+		{criteria: "Observation?value-quantity=ge100&value-quantity=lt140", matches: false},
+		{criteria: "Observation?date=ge2026-03-01&date=le2026-03-31", matches: true},
+		{criteria: "Observation?date=ge2026-04-01", matches: false},
+		{criteria: "Observation?_tag=reviewed", matches: true},
+		{criteria: "Observation?_tag=|reviewed", matches: false},
+		{criteria: "Patient?gender=male", matches: false},
+	}
+	for _, testCase := range testCases {
+		criteria, parseError := ParseCriteria(testCase.criteria)
+		if parseError != nil {
+			t.Fatalf("%s: expected the criteria to parse, got %v", testCase.criteria, parseError)
+		}
+		if matches := criteria.Matches("Observation", observation); matches != testCase.matches {
+			t.Errorf("%s: expected match %v, got %v", testCase.criteria, testCase.matches, matches)
+		}
+	}
+}
+
+// TestCriteria_Matches_Patient verifies patients are matched the way a Patient search filters them
+func TestCriteria_Matches_Patient(t *testing.T) {
+	birthDate := time.Date(1980, 5, 17, 0, 0, 0, 0, time.UTC)
+	patient := &models.Patient{
+		FamilyName:       "Smith",
+		GivenName:        "Jane",
+		Gender:           "female",
+		BirthDate:        &birthDate,
+		Active:           true,
+		IdentifierSystem: "http://hospital.example.org/mrn",
+		IdentifierValue:  "MRN-1",
+	}
+
+	testCases := []struct {
+		criteria string
+		matches  bool
+	}{
+		{criteria: "Patient?name=smi", matches: true},
+		{criteria: "Patient?family:exact=smith", matches: false},
+		{criteria: "Patient?given:exact=Jane&gender=female", matches: true},
+		{criteria: "Patient?birthdate=1980-05-17", matches: true},
+		{criteria: "Patient?birthdate=ge1990-01-01", matches: false},
+		{criteria: "Patient?active=false", matches: false},
+		{criteria: "Patient?identifier=http://hospital.example.org/mrn|MRN-1", matches: true},
+		{criteria: "Patient?identifier=|MRN-1", matches: false},
+	}
+	for _, testCase := range testCases {
+		criteria, parseError := ParseCriteria(testCase.criteria)
+		if parseError != nil {
+			t.Fatalf("%s: expected the criteria to parse, got %v", testCase.criteria, parseError)
+		}
+		if matches := criteria.Matches("Patient", patient); matches != testCase.matches {
+			t.Errorf("%s: expected match %v, got %v", testCase.criteria, testCase.matches, matches)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// KindSubscription is the delivery kind of subscription notifications; their destination is the subscription ID
+const KindSubscription = "subscription"
+
+// defaultRefreshInterval bounds how long subscriptions made on other instances go unnotified
+const defaultRefreshInterval = 30 * time.Second
+
+// Store is the subscription storage the notifier and sender read
+type Store interface {
+	// ListActive returns the active subscriptions that have not ended
+	ListActive(ctx context.Context) ([]*models.Subscription, error)
+
+	// GetByID returns a subscription, or sql.ErrNoRows when it does not exist
+	GetByID(ctx context.Context, subscriptionID string) (*models.Subscription, error)
+
+	// RecordError stops a subscription with the error status and the failure that stopped it
+	RecordError(ctx context.Context, subscriptionID string, message string) error
+}
+
+// Enqueuer queues notifications for delivery; *delivery.Queue satisfies it
+type Enqueuer interface {
+	Enqueue(ctx context.Context, kind string, destination string, contentType string, payload []byte) (*models.Delivery, error)
+}
+
+// activeSubscription is a cached active subscription with its parsed criteria
+type activeSubscription struct {
+	subscription *models.Subscription
+	criteria     *Criteria
+}
+
+// Notifier matches resource events against active subscriptions and queues a notification for each match
+// Active subscriptions are cached, reloaded when they change on this instance and at least every refresh interval
+type Notifier struct {
+	store           Store
+	queue           Enqueuer
+	refreshInterval time.Duration
+	now             func() time.Time
+
+	mutex    sync.Mutex
+	active   []activeSubscription
+	loadedAt time.Time
+}
+
+// NewNotifier creates a notifier that reads subscriptions from store and queues notifications on queue
+func NewNotifier(store Store, queue Enqueuer) *Notifier {
+	return &Notifier{
+		store:           store,
+		queue:           queue,
+		refreshInterval: defaultRefreshInterval,
+		now:             time.Now,
+	}
+}
+
+// SubscriptionsChanged drops the cached subscriptions, so the next event reloads them
+func (notifier *Notifier) SubscriptionsChanged() {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+	notifier.loadedAt = time.Time{}
+}
+
+// Handle is the event bus handler; creates and updates are notified, deletes are not
+// Each match is queued rather than sent, so a slow or failing endpoint never blocks the bus
+func (notifier *Notifier) Handle(ctx context.Context, event events.Event) error {
+	if event.Type == events.EventResourceDeleted || event.Resource == nil {
+		return nil
+	}
+	if _, subscribable := criteriaParameters[event.ResourceType]; !subscribable {
+		return nil
+	}
+
+	active, loadError := notifier.activeSubscriptions(ctx)
+	if loadError != nil {
+		return loadError
+	}
+
+	now := notifier.now()
+	for _, candidate := range active {
+		subscription := candidate.subscription
+		if subscription.TenantID != event.TenantID || (subscription.End != nil && !now.Before(*subscription.End)) {
+			continue
+		}
+		if !candidate.criteria.Matches(event.ResourceType, event.Resource) {
+			continue
+		}
+
+		payload, encodeError := notificationPayload(subscription, event)
+		if encodeError != nil {
+			return encodeError
+		}
+		if _, enqueueError := notifier.queue.Enqueue(ctx, KindSubscription, subscription.ID, subscription.Payload, payload); enqueueError != nil {
+			return enqueueError
+		}
+	}
+	return nil
+}
+
+// activeSubscriptions returns the cached active subscriptions, reloading them when stale
+// Subscriptions whose stored criteria no longer parse are logged and skipped
+func (notifier *Notifier) activeSubscriptions(ctx context.Context) ([]activeSubscription, error) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	now := notifier.now()
+	if !notifier.loadedAt.IsZero() && now.Sub(notifier.loadedAt) < notifier.refreshInterval {
+		return notifier.active, nil
+	}
+
+	subscriptions, listError := notifier.store.ListActive(ctx)
+	if listError != nil {
+		return nil, fmt.Errorf("failed to load active subscriptions: %w", listError)
+	}
+	active := make([]activeSubscription, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		criteria, parseError := ParseCriteria(subscription.Criteria)
+		if parseError != nil {
+			log.Warn().Err(parseError).Str("subscription_id", subscription.ID).Msg("Skipping subscription with invalid criteria")
+			continue
+		}
+		active = append(active, activeSubscription{subscription: subscription, criteria: criteria})
+	}
+
+	notifier.active = active
+	notifier.loadedAt = now
+	return active, nil
+}
+
+// notificationPayload returns the body of a notification: nothing when the subscription asked for no payload,
+// otherwise the written resource in FHIR JSON
+func notificationPayload(subscription *models.Subscription, event events.Event) ([]byte, error) {
+	if subscription.Payload == "" {
+		return nil, nil
+	}
+
+	var fhirResource interface{}
+	switch written := event.Resource.(type) {
+	case *models.Patient:
+		fhirResource = models.NewPatientMapper().ToFHIR(written)
+	case *models.Observation:
+		fhirResource = models.NewObservationMapper().ToFHIR(written)
+	default:
+		return nil, fmt.Errorf("cannot notify %s resources of type %T", event.ResourceType, event.Resource)
+	}
+	return json.Marshal(fhirResource)
+}
@@ -0,0 +1,175 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/events"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// fakeStore is an in-memory subscription store that counts how often active subscriptions are loaded
+type fakeStore struct {
+	subscriptions map[string]*models.Subscription
+	loads         int
+}
+
+// ListActive returns the active subscriptions
+func (store *fakeStore) ListActive(ctx context.Context) ([]*models.Subscription, error) {
+	store.loads++
+	active := []*models.Subscription{}
+	for _, subscription := range store.subscriptions {
+		if subscription.Status == models.SubscriptionStatusActive {
+			active = append(active, subscription)
+		}
+	}
+	return active, nil
+}
+
+// GetByID returns a stored subscription
+func (store *fakeStore) GetByID(ctx context.Context, subscriptionID string) (*models.Subscription, error) {
+	subscription, exists := store.subscriptions[subscriptionID]
+	if !exists {
+		return nil, sql.ErrNoRows
+	}
+	return subscription, nil
+}
+
+// RecordError puts a stored subscription in error
+func (store *fakeStore) RecordError(ctx context.Context, subscriptionID string, message string) error {
+	store.subscriptions[subscriptionID].Status = models.SubscriptionStatusError
+	store.subscriptions[subscriptionID].Error = message
+	return nil
+}
+
+// recordingQueue records enqueued notifications instead of delivering them
+type recordingQueue struct {
+	deliveries []*models.Delivery
+}
+
+// Enqueue records the notification
+func (queue *recordingQueue) Enqueue(ctx context.Context, kind string, destination string, contentType string, payload []byte) (*models.Delivery, error) {
+	queued := &models.Delivery{Kind: kind, Destination: destination, ContentType: contentType, Payload: payload}
+	queue.deliveries = append(queue.deliveries, queued)
+	return queued, nil
+}
+
+// TestNotifier_Handle verifies matching writes of the subscription's tenant are queued with the requested payload
+func TestNotifier_Handle(t *testing.T) {
+	ended := time.Now().Add(-time.Hour)
+	store := &fakeStore{subscriptions: map[string]*models.Subscription{
+		"with-payload": {ID: "with-payload", Status: models.SubscriptionStatusActive, Criteria: "Observation?patient=123&code=8480-6", Payload: "application/fhir+json", TenantID: "clinic-a"},
+		"no-payload":   {ID: "no-payload", Status: models.SubscriptionStatusActive, Criteria: "Observation?patient=123", TenantID: "clinic-a"},
+		"other-tenant": {ID: "other-tenant", Status: models.SubscriptionStatusActive, Criteria: "Observation?patient=123", TenantID: "clinic-b"},
+		"ended":        {ID: "ended", Status: models.SubscriptionStatusActive, Criteria: "Observation", TenantID: "clinic-a", End: &ended},
+		"off":          {ID: "off", Status: models.SubscriptionStatusOff, Criteria: "Observation", TenantID: "clinic-a"},
+	}}
+	queue := &recordingQueue{}
+	notifier := NewNotifier(store, queue)
+
+	observation := &models.Observation{ID: "obs-1", PatientID: "123", Code: "8480-6", Status: "final", ValueQuantity: floatPointer(150), ValueUnit: "mm[Hg]"}
+	event := events.Event{Type: events.EventResourceCreated, ResourceType: "Observation", ResourceID: "obs-1", TenantID: "clinic-a", Resource: observation}
+	if handleError := notifier.Handle(context.Background(), event); handleError != nil {
+		t.Fatalf("Expected the event handled, got %v", handleError)
+	}
+
+	queued := map[string]*models.Delivery{}
+	for _, notification := range queue.deliveries {
+		queued[notification.Destination] = notification
+	}
+	if len(queue.deliveries) != 2 || queued["with-payload"] == nil || queued["no-payload"] == nil {
+		t.Fatalf("Expected notifications for the two matching subscriptions, got %+v", queue.deliveries)
+	}
+	var notified map[string]interface{}
+	json.Unmarshal(queued["with-payload"].Payload, &notified)
+	if queued["with-payload"].Kind != KindSubscription || queued["with-payload"].ContentType != "application/fhir+json" || notified["resourceType"] != "Observation" || notified["id"] != "obs-1" {
+		t.Errorf("Expected the observation sent as FHIR JSON, got %+v with %s", queued["with-payload"], queued["with-payload"].Payload)
+	}
+	if queued["no-payload"].Payload != nil || queued["no-payload"].ContentType != "" {
+		t.Errorf("Expected no body for a subscription without a payload, got %+v", queued["no-payload"])
+	}
+
+	deleteEvent := event
+	deleteEvent.Type = events.EventResourceDeleted
+	deleteEvent.Resource = nil
+	notifier.Handle(context.Background(), deleteEvent)
+	if len(queue.deliveries) != 2 {
+		t.Errorf("Expected deletes not notified, got %d notifications", len(queue.deliveries))
+	}
+	if store.loads != 1 {
+		t.Errorf("Expected active subscriptions loaded once and cached, got %d loads", store.loads)
+	}
+
+	notifier.SubscriptionsChanged()
+	notifier.Handle(context.Background(), event)
+	if store.loads != 2 {
+		t.Errorf("Expected a subscription change to reload the cache, got %d loads", store.loads)
+	}
+}
+
+// TestSender_Send verifies notifications are posted with the subscription's headers and refused ones stop it
+func TestSender_Send(t *testing.T) {
+	var receivedAuthorization, receivedBody string
+	responseStatus := http.StatusOK
+	endpoint := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedAuthorization = r.Header.Get("Authorization")
+		body, _ := io.ReadAll(r.Body)
+		receivedBody = string(body)
+		w.WriteHeader(responseStatus)
+	}))
+	defer endpoint.Close()
+
+	store := &fakeStore{subscriptions: map[string]*models.Subscription{
+		"sub-1": {ID: "sub-1", Status: models.SubscriptionStatusActive, Endpoint: endpoint.URL, Headers: []string{"Authorization: Bearer secret"}},
+	}}
+	sender := NewSender(store)
+	notification := &models.Delivery{ID: 1, Kind: KindSubscription, Destination: "sub-1", ContentType: "application/fhir+json", Payload: []byte(`{"resourceType":"Observation"}`)}
+
+	if sendError := sender.Send(context.Background(), notification); sendError != nil {
+		t.Fatalf("Expected the notification delivered, got %v", sendError)
+	}
+	if receivedAuthorization != "Bearer secret" || receivedBody != `{"resourceType":"Observation"}` {
+		t.Errorf("Expected the subscription's header and the payload, got %q and %q", receivedAuthorization, receivedBody)
+	}
+
+	responseStatus = http.StatusServiceUnavailable
+	if sendError := sender.Send(context.Background(), notification); sendError == nil || delivery.IsPermanent(sendError) {
+		t.Errorf("Expected a 503 retried, got %v", sendError)
+	}
+
+	responseStatus = http.StatusGone
+	if sendError := sender.Send(context.Background(), notification); !delivery.IsPermanent(sendError) {
+		t.Errorf("Expected a 410 to fail permanently, got %v", sendError)
+	}
+	if store.subscriptions["sub-1"].Status != models.SubscriptionStatusError || store.subscriptions["sub-1"].Error == "" {
+		t.Errorf("Expected the subscription stopped with the error, got %+v", store.subscriptions["sub-1"])
+	}
+
+	if sendError := sender.Send(context.Background(), notification); !delivery.IsPermanent(sendError) {
+		t.Errorf("Expected notifications of a stopped subscription dropped, got %v", sendError)
+	}
+	notification.Destination = "deleted"
+	if sendError := sender.Send(context.Background(), notification); !delivery.IsPermanent(sendError) {
+		t.Errorf("Expected notifications of a deleted subscription dropped, got %v", sendError)
+	}
+}
+
+// TestParseHeaders verifies channel headers must be given as "Name: value"
+func TestParseHeaders(t *testing.T) {
+	header, parseError := ParseHeaders([]string{"Authorization: Bearer a:b", "X-Source:  ehr "})
+	if parseError != nil || header.Get("Authorization") != "Bearer a:b" || header.Get("X-Source") != "ehr" {
+		t.Errorf("Expected both headers parsed, got %v (%v)", header, parseError)
+	}
+	for _, invalidHeader := range []string{"no separator", ": value", "Bad Name: value"} {
+		if _, invalidError := ParseHeaders([]string{invalidHeader}); invalidError == nil {
+			t.Errorf("Expected %q to be refused", invalidHeader)
+		}
+	}
+}
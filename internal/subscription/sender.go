@@ -0,0 +1,104 @@
+package subscription
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/delivery"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+	"github.com/rs/zerolog/log"
+)
+
+// PayloadTypes lists the notification payloads supported: none, or the resource as FHIR JSON
+var PayloadTypes = []string{"", "application/fhir+json", "application/json"}
+
+// Sender posts queued notifications to their subscription's endpoint with the subscription's headers
+// A subscription whose endpoint refuses a notification outright is stopped with the error status
+type Sender struct {
+	store      Store
+	httpSender *delivery.HTTPSender
+}
+
+// NewSender creates a sender that reads each notification's subscription from store
+func NewSender(store Store) *Sender {
+	return &Sender{store: store, httpSender: delivery.NewHTTPSender()}
+}
+
+// Send delivers one notification; notifications for subscriptions that were deleted or stopped are dropped
+func (sender *Sender) Send(ctx context.Context, notification *models.Delivery) error {
+	subscription, getError := sender.store.GetByID(ctx, notification.Destination)
+	if errors.Is(getError, sql.ErrNoRows) {
+		return delivery.Permanent(fmt.Errorf("subscription %s no longer exists", notification.Destination))
+	}
+	if getError != nil {
+		return getError
+	}
+	if subscription.Status != models.SubscriptionStatusActive {
+		return delivery.Permanent(fmt.Errorf("subscription %s is %s", subscription.ID, subscription.Status))
+	}
+
+	header, headerError := ParseHeaders(subscription.Headers)
+	if headerError != nil {
+		return delivery.Permanent(headerError)
+	}
+
+	sendError := sender.httpSender.Post(ctx, notification, subscription.Endpoint, header)
+	if delivery.IsPermanent(sendError) {
+		if recordError := sender.store.RecordError(ctx, subscription.ID, sendError.Error()); recordError != nil {
+			log.Error().Err(recordError).Str("subscription_id", subscription.ID).Msg("Failed to record subscription error")
+		}
+	}
+	return sendError
+}
+
+// ValidateEndpoint checks that a rest-hook endpoint is an absolute http or https URL
+func ValidateEndpoint(endpoint string) error {
+	parsedURL, parseError := url.Parse(endpoint)
+	if parseError != nil || (parsedURL.Scheme != "http" && parsedURL.Scheme != "https") || parsedURL.Host == "" {
+		return fmt.Errorf("endpoint %q must be an absolute http or https URL", endpoint)
+	}
+	return nil
+}
+
+// ValidatePayload checks that a notification payload MIME type is supported
+func ValidatePayload(payload string) error {
+	if !slices.Contains(PayloadTypes, payload) {
+		return fmt.Errorf("payload %q is not supported; leave it out or use %s", payload, strings.Join(PayloadTypes[1:], " or "))
+	}
+	return nil
+}
+
+// ParseHeaders parses channel headers given as "Name: value", such as "Authorization: Bearer secret"
+func ParseHeaders(headers []string) (http.Header, error) {
+	header := http.Header{}
+	for _, rawHeader := range headers {
+		name, value, found := strings.Cut(rawHeader, ":")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !found || !validHeaderName(name) || strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header %q must be given as \"Name: value\"", rawHeader)
+		}
+		header.Add(name, value)
+	}
+	return header, nil
+}
+
+// validHeaderName reports whether name is a non-empty HTTP token, the characters allowed in a header name
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, character := range name {
+		isAlphanumeric := (character >= 'a' && character <= 'z') || (character >= 'A' && character <= 'Z') || (character >= '0' && character <= '9')
+		if !isAlphanumeric && !strings.ContainsRune("!#$%&'*+-.^_`|~", character) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	apperrors "github.com/nathannewyen/fhir-health-interop/internal/errors"
+	"github.com/nathannewyen/fhir-health-interop/internal/middleware"
+)
+
+const (
+	// DefaultTenantID is used when a request does not identify a tenant
+	DefaultTenantID = "default"
+
+	// HeaderTenantID names the tenant directly
+	HeaderTenantID = "X-Tenant-ID"
+
+	// HeaderAPIKey carries an API key that maps to a tenant
+	HeaderAPIKey = "X-API-Key"
+)
+
+// contextKey is a custom type for tenant context keys to avoid collisions
+type contextKey string
+
+// tenantIDKey is the context key for the resolved tenant ID
+const tenantIDKey contextKey = "tenant_id"
+
+// verifiedKey marks a tenant that was resolved from an API key rather than a client-set header
+const verifiedKey contextKey = "tenant_verified"
+
+// WithTenant returns a context carrying the tenant ID
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+// WithVerifiedTenant returns a context carrying a tenant ID that was resolved from an API key
+func WithVerifiedTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(WithTenant(ctx, tenantID), verifiedKey, true)
+}
+
+// FromContext returns the tenant ID for the request, or DefaultTenantID when none was resolved
+func FromContext(ctx context.Context) string {
+	if tenantID, ok := ctx.Value(tenantIDKey).(string); ok && tenantID != "" {
+		return tenantID
+	}
+	return DefaultTenantID
+}
+
+// VerifiedFromContext returns the tenant the request's API key maps to, or DefaultTenantID when the
+// tenant came from X-Tenant-ID or was not given
+// Use it wherever the client must not choose its own tenant, such as quotas and signing keys
+func VerifiedFromContext(ctx context.Context) string {
+	if verified, ok := ctx.Value(verifiedKey).(bool); ok && verified {
+		return FromContext(ctx)
+	}
+	return DefaultTenantID
+}
+
+// Resolver identifies the tenant for each request from its API key or tenant header
+type Resolver struct {
+	// apiKeys maps API keys to tenant IDs
+	apiKeys map[string]string
+}
+
+// NewResolver creates a resolver with the given API key to tenant mapping
+func NewResolver(apiKeys map[string]string) *Resolver {
+	return &Resolver{
+		apiKeys: apiKeys,
+	}
+}
+
+// Middleware stores the tenant in the request context
+// A known API key wins; an unknown API key is rejected; otherwise X-Tenant-ID is used
+func (resolver *Resolver) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get(HeaderAPIKey); apiKey != "" {
+			mappedTenantID, known := resolver.apiKeys[apiKey]
+			if !known {
+				middleware.WriteError(w, r, apperrors.Unauthorized("Unknown API key"))
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(WithVerifiedTenant(r.Context(), mappedTenantID)))
+			return
+		}
+
+		tenantID := DefaultTenantID
+		if headerTenantID := strings.TrimSpace(r.Header.Get(HeaderTenantID)); headerTenantID != "" {
+			tenantID = headerTenantID
+		}
+
+		next.ServeHTTP(w, r.WithContext(WithTenant(r.Context(), tenantID)))
+	})
+}
+
+// ParseAPIKeys parses a "key:tenant,key:tenant" list into an API key mapping
+func ParseAPIKeys(rawKeys string) (map[string]string, error) {
+	apiKeys := make(map[string]string)
+	for _, pair := range strings.Split(rawKeys, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		apiKey, tenantID, found := strings.Cut(pair, ":")
+		if !found || strings.TrimSpace(apiKey) == "" || strings.TrimSpace(tenantID) == "" {
+			return nil, fmt.Errorf("invalid API key entry %q: expected key:tenant", pair)
+		}
+		apiKeys[strings.TrimSpace(apiKey)] = strings.TrimSpace(tenantID)
+	}
+	return apiKeys, nil
+}
@@ -0,0 +1,100 @@
+package tenant
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// captureTenant returns a handler that records the resolved tenant ID
+func captureTenant(resolvedTenantID *string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*resolvedTenantID = FromContext(r.Context())
+	})
+}
+
+// TestResolver_Middleware verifies tenant resolution order
+func TestResolver_Middleware(t *testing.T) {
+	resolver := NewResolver(map[string]string{"key-1": "acme"})
+
+	testCases := []struct {
+		name           string
+		headers        map[string]string
+		expectedTenant string
+	}{
+		{"no headers", nil, DefaultTenantID},
+		{"tenant header", map[string]string{HeaderTenantID: "globex"}, "globex"},
+		{"api key", map[string]string{HeaderAPIKey: "key-1"}, "acme"},
+		{"api key wins over header", map[string]string{HeaderAPIKey: "key-1", HeaderTenantID: "globex"}, "acme"},
+	}
+
+	for _, testCase := range testCases {
+		var resolvedTenantID string
+		request := httptest.NewRequest(http.MethodGet, "/fhir/Patient", nil)
+		for name, value := range testCase.headers {
+			request.Header.Set(name, value)
+		}
+
+		resolver.Middleware(captureTenant(&resolvedTenantID)).ServeHTTP(httptest.NewRecorder(), request)
+
+		if resolvedTenantID != testCase.expectedTenant {
+			t.Errorf("%s: expected tenant %s, got %s", testCase.name, testCase.expectedTenant, resolvedTenantID)
+		}
+	}
+}
+
+// TestVerifiedFromContext verifies only API key tenants are trusted
+func TestVerifiedFromContext(t *testing.T) {
+	resolver := NewResolver(map[string]string{"key-1": "acme"})
+	var verifiedTenantID string
+	captureVerified := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		verifiedTenantID = VerifiedFromContext(r.Context())
+	})
+
+	headerRequest := httptest.NewRequest(http.MethodGet, "/fhir/Patient", nil)
+	headerRequest.Header.Set(HeaderTenantID, "globex")
+	resolver.Middleware(captureVerified).ServeHTTP(httptest.NewRecorder(), headerRequest)
+	if verifiedTenantID != DefaultTenantID {
+		t.Errorf("Expected a header tenant not to be trusted, got %s", verifiedTenantID)
+	}
+
+	keyRequest := httptest.NewRequest(http.MethodGet, "/fhir/Patient", nil)
+	keyRequest.Header.Set(HeaderAPIKey, "key-1")
+	resolver.Middleware(captureVerified).ServeHTTP(httptest.NewRecorder(), keyRequest)
+	if verifiedTenantID != "acme" {
+		t.Errorf("Expected the API key tenant, got %s", verifiedTenantID)
+	}
+}
+
+// TestResolver_Middleware_UnknownAPIKey verifies unknown keys are rejected
+func TestResolver_Middleware_UnknownAPIKey(t *testing.T) {
+	resolver := NewResolver(map[string]string{"key-1": "acme"})
+	var resolvedTenantID string
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient", nil)
+	request.Header.Set(HeaderAPIKey, "stolen")
+	recorder := httptest.NewRecorder()
+	resolver.Middleware(captureTenant(&resolvedTenantID)).ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusUnauthorized {
+		t.Errorf("Expected status 401, got %d", recorder.Code)
+	}
+	if resolvedTenantID != "" {
+		t.Error("Expected the handler not to run")
+	}
+}
+
+// TestParseAPIKeys verifies key lists are parsed and malformed entries rejected
+func TestParseAPIKeys(t *testing.T) {
+	apiKeys, parseError := ParseAPIKeys(" key-1:acme , key-2:globex,")
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+	if len(apiKeys) != 2 || apiKeys["key-2"] != "globex" {
+		t.Errorf("Unexpected keys: %v", apiKeys)
+	}
+
+	if _, invalidError := ParseAPIKeys("key-only"); invalidError == nil {
+		t.Error("Expected error for entry without tenant")
+	}
+}
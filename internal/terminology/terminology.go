@@ -0,0 +1,97 @@
+package terminology
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Code systems with built-in displays
+const (
+	// LOINCSystem codes observations
+	LOINCSystem = "http://loinc.org"
+
+	// ObservationCategorySystem codes observation categories
+	ObservationCategorySystem = "http://terminology.hl7.org/CodeSystem/observation-category"
+)
+
+// defaultConceptsJSON holds the built-in displays for common LOINC codes and every observation category
+//
+//go:embed concepts.json
+var defaultConceptsJSON []byte
+
+// Concept is the display text of one code
+type Concept struct {
+	System  string `json:"system"`
+	Code    string `json:"code"`
+	Display string `json:"display"`
+}
+
+// conceptFile is the layout of the embedded concepts and of site terminology files
+type conceptFile struct {
+	Concepts []Concept `json:"concepts"`
+}
+
+// Terminology looks up display text for codes and whether codes exist
+type Terminology struct {
+	// displays maps system and code, joined by "|", to the display
+	displays map[string]string
+
+	// systems holds every code system with concepts
+	systems map[string]bool
+}
+
+// Default returns the built-in terminology
+func Default() *Terminology {
+	concepts := conceptFile{}
+	if decodeError := json.Unmarshal(defaultConceptsJSON, &concepts); decodeError != nil {
+		panic(fmt.Sprintf("embedded terminology concepts are invalid: %v", decodeError))
+	}
+
+	terminology := &Terminology{displays: make(map[string]string, len(concepts.Concepts)), systems: make(map[string]bool)}
+	terminology.add(concepts.Concepts)
+	return terminology
+}
+
+// Load reads site concepts from a JSON file over the built-in ones
+// A site concept replaces the built-in display for the same system and code
+func Load(path string) (*Terminology, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read terminology: %w", readError)
+	}
+
+	siteConcepts := conceptFile{}
+	if decodeError := json.Unmarshal(fileBytes, &siteConcepts); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse terminology: %w", decodeError)
+	}
+	for index, concept := range siteConcepts.Concepts {
+		if concept.System == "" || concept.Code == "" || concept.Display == "" {
+			return nil, fmt.Errorf("terminology concepts[%d] must set system, code, and display", index)
+		}
+	}
+
+	terminology := Default()
+	terminology.add(siteConcepts.Concepts)
+	return terminology, nil
+}
+
+// Display returns the display text of a code, and false when the code is unknown
+func (terminology *Terminology) Display(system string, code string) (string, bool) {
+	display, known := terminology.displays[system+"|"+code]
+	return display, known
+}
+
+// Len returns the number of codes with a display
+func (terminology *Terminology) Len() int {
+	return len(terminology.displays)
+}
+
+// add indexes concepts, replacing displays already known for the same code
+func (terminology *Terminology) add(concepts []Concept) {
+	for _, concept := range concepts {
+		terminology.displays[concept.System+"|"+concept.Code] = concept.Display
+		terminology.systems[concept.System] = true
+	}
+}
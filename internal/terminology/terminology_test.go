@@ -0,0 +1,52 @@
+package terminology
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestDefault_Display verifies built-in LOINC and category displays are found and unknown codes are not
+func TestDefault_Display(t *testing.T) {
+	terminology := Default()
+
+	if display, known := terminology.Display(LOINCSystem, "8867-4"); !known || display != "Heart rate" {
+		t.Errorf("Expected Heart rate, got %q (%v)", display, known)
+	}
+	if display, known := terminology.Display(ObservationCategorySystem, "vital-signs"); !known || display != "Vital Signs" {
+		t.Errorf("Expected Vital Signs, got %q (%v)", display, known)
+	}
+	if _, known := terminology.Display("http://snomed.info/sct", "8867-4"); known {
+		t.Error("Expected a code to be looked up in its own system only")
+	}
+}
+
+// TestLoad verifies site concepts are added over the built-in ones and incomplete concepts are rejected
+func TestLoad(t *testing.T) {
+	directory := t.TempDir()
+	sitePath := filepath.Join(directory, "terminology.json")
+	os.WriteFile(sitePath, []byte(`{"concepts": [
+		{"system": "http://loinc.org", "code": "8867-4", "display": "Pulse"},
+		{"system": "http://example.org/local", "code": "GLU-POC", "display": "Point-of-care glucose"}
+	]}`), 0o600)
+
+	terminology, loadError := Load(sitePath)
+	if loadError != nil {
+		t.Fatalf("Expected no error, got %v", loadError)
+	}
+	if display, _ := terminology.Display(LOINCSystem, "8867-4"); display != "Pulse" {
+		t.Errorf("Expected the site display to replace the built-in one, got %q", display)
+	}
+	if display, _ := terminology.Display("http://example.org/local", "GLU-POC"); display != "Point-of-care glucose" {
+		t.Errorf("Expected the site concept to be added, got %q", display)
+	}
+	if display, _ := terminology.Display(LOINCSystem, "9279-1"); display != "Respiratory rate" {
+		t.Errorf("Expected other built-in displays to be kept, got %q", display)
+	}
+
+	invalidPath := filepath.Join(directory, "invalid.json")
+	os.WriteFile(invalidPath, []byte(`{"concepts": [{"system": "http://loinc.org", "code": "1-8"}]}`), 0o600)
+	if _, invalidError := Load(invalidPath); invalidError == nil {
+		t.Error("Expected a concept without a display to be rejected")
+	}
+}
@@ -0,0 +1,212 @@
+package terminology
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Actions taken on observation codes a lookup finds invalid
+const (
+	// ValidationReject fails the write so the invalid code is never stored
+	ValidationReject = "reject"
+
+	// ValidationWarn stores the observation and warns the caller about the code
+	ValidationWarn = "warn"
+)
+
+// Validity is the result of looking up a code in its code system
+type Validity int
+
+const (
+	// ValidityUnchecked means the lookup cannot tell whether the code exists, usually because it does not know
+	// the code system
+	ValidityUnchecked Validity = iota
+
+	// ValidityValid means the code exists in the code system
+	ValidityValid
+
+	// ValidityInvalid means the code does not exist in the code system
+	ValidityInvalid
+)
+
+// CodeSystemLookup tells whether codes exist in their code systems
+type CodeSystemLookup interface {
+	// LookupCode returns whether code exists in system; errors mean the lookup could not be made
+	LookupCode(ctx context.Context, system string, code string) (Validity, error)
+}
+
+// LookupCode checks a code against the built-in and site concepts
+// A listed code is valid. A LOINC code that is not listed is invalid when its check digit is wrong and unchecked
+// otherwise, since the built-in LOINC codes are only a subset. Site code systems are listed in full, so their
+// unlisted codes are invalid. Codes of systems without concepts are unchecked
+func (terminology *Terminology) LookupCode(ctx context.Context, system string, code string) (Validity, error) {
+	if _, known := terminology.displays[system+"|"+code]; known {
+		return ValidityValid, nil
+	}
+	if system == LOINCSystem {
+		if !ValidLOINCCheckDigit(code) {
+			return ValidityInvalid, nil
+		}
+		return ValidityUnchecked, nil
+	}
+	if terminology.systems[system] {
+		return ValidityInvalid, nil
+	}
+	return ValidityUnchecked, nil
+}
+
+// ValidLOINCCheckDigit reports whether a code has the form of a LOINC code, digits, a hyphen, and a check
+// digit, and its check digit is the mod 10 digit LOINC computes from the digits before it
+func ValidLOINCCheckDigit(code string) bool {
+	digits, checkDigit, hasHyphen := strings.Cut(code, "-")
+	if !hasHyphen || digits == "" || len(digits) > 7 || len(checkDigit) != 1 || !allDigits(digits) || !allDigits(checkDigit) {
+		return false
+	}
+
+	sum := 0
+	for position := 0; position < len(digits); position++ {
+		digit := int(digits[len(digits)-1-position] - '0')
+		// Every other digit is doubled, starting with the rightmost
+		if position%2 == 0 {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+	}
+	return int(checkDigit[0]-'0') == (10-sum%10)%10
+}
+
+// allDigits reports whether text is made of ASCII digits only
+func allDigits(text string) bool {
+	for _, character := range text {
+		if character < '0' || character > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// fallbackLookup asks one lookup and, for codes it cannot check, another
+type fallbackLookup struct {
+	primary   CodeSystemLookup
+	secondary CodeSystemLookup
+}
+
+// Fallback returns a lookup that answers from primary and asks secondary only about codes primary leaves
+// unchecked, so codes primary knows never wait on secondary
+func Fallback(primary CodeSystemLookup, secondary CodeSystemLookup) CodeSystemLookup {
+	return &fallbackLookup{primary: primary, secondary: secondary}
+}
+
+// LookupCode asks primary, then secondary when primary cannot tell
+func (lookup *fallbackLookup) LookupCode(ctx context.Context, system string, code string) (Validity, error) {
+	validity, lookupError := lookup.primary.LookupCode(ctx, system, code)
+	if lookupError != nil || validity != ValidityUnchecked {
+		return validity, lookupError
+	}
+	return lookup.secondary.LookupCode(ctx, system, code)
+}
+
+// serverCacheLimit is how many looked-up codes a ServerLookup remembers before starting over
+const serverCacheLimit = 10000
+
+// ServerLookup validates codes with a FHIR terminology server's CodeSystem/$validate-code operation
+// Answers are cached, since codes repeat across observations and do not change meaning; failed lookups are not
+// It is safe for concurrent use by multiple request goroutines
+type ServerLookup struct {
+	baseURL    string
+	httpClient *http.Client
+
+	// mutex guards answers
+	mutex sync.Mutex
+
+	// answers maps system and code, joined by "|", to the server's answer
+	answers map[string]Validity
+}
+
+// NewServerLookup creates a lookup against the terminology server at baseURL, such as
+// https://tx.fhir.org/r4, giving up on a request after timeout
+func NewServerLookup(baseURL string, timeout time.Duration) (*ServerLookup, error) {
+	if !strings.HasPrefix(baseURL, "https://") && !strings.HasPrefix(baseURL, "http://") {
+		return nil, fmt.Errorf("terminology server URL %q must be an http or https URL", baseURL)
+	}
+	return &ServerLookup{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: timeout},
+		answers:    make(map[string]Validity),
+	}, nil
+}
+
+// LookupCode asks the server whether code exists in system
+// A 404 means the server does not know the code system, so the code is unchecked
+func (lookup *ServerLookup) LookupCode(ctx context.Context, system string, code string) (Validity, error) {
+	answerKey := system + "|" + code
+	lookup.mutex.Lock()
+	validity, answered := lookup.answers[answerKey]
+	lookup.mutex.Unlock()
+	if answered {
+		return validity, nil
+	}
+
+	query := url.Values{"url": {system}, "code": {code}}
+	request, requestError := http.NewRequestWithContext(ctx, http.MethodGet, lookup.baseURL+"/CodeSystem/$validate-code?"+query.Encode(), nil)
+	if requestError != nil {
+		return ValidityUnchecked, fmt.Errorf("failed to build terminology request: %w", requestError)
+	}
+	request.Header.Set("Accept", "application/fhir+json")
+
+	response, sendError := lookup.httpClient.Do(request)
+	if sendError != nil {
+		return ValidityUnchecked, fmt.Errorf("terminology server unreachable: %w", sendError)
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+		var decodeError error
+		validity, decodeError = decodeValidateCodeResult(response)
+		if decodeError != nil {
+			return ValidityUnchecked, decodeError
+		}
+	case http.StatusNotFound:
+		validity = ValidityUnchecked
+	default:
+		return ValidityUnchecked, fmt.Errorf("terminology server answered %d", response.StatusCode)
+	}
+
+	lookup.mutex.Lock()
+	if len(lookup.answers) >= serverCacheLimit {
+		lookup.answers = make(map[string]Validity)
+	}
+	lookup.answers[answerKey] = validity
+	lookup.mutex.Unlock()
+	return validity, nil
+}
+
+// decodeValidateCodeResult reads the result parameter of a $validate-code response
+func decodeValidateCodeResult(response *http.Response) (Validity, error) {
+	var parameters fhir.Parameters
+	if decodeError := json.NewDecoder(response.Body).Decode(&parameters); decodeError != nil {
+		return ValidityUnchecked, fmt.Errorf("invalid terminology server response: %w", decodeError)
+	}
+	for _, parameter := range parameters.Parameter {
+		if parameter.Name != "result" || parameter.ValueBoolean == nil {
+			continue
+		}
+		if *parameter.ValueBoolean {
+			return ValidityValid, nil
+		}
+		return ValidityInvalid, nil
+	}
+	return ValidityUnchecked, fmt.Errorf("terminology server response has no result")
+}
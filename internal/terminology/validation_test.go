@@ -0,0 +1,104 @@
+package terminology
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestValidLOINCCheckDigit verifies LOINC check digits are computed mod 10 and malformed codes are refused
+func TestValidLOINCCheckDigit(t *testing.T) {
+	terminology := Default()
+	for key := range terminology.displays {
+		if system, code, _ := strings.Cut(key, "|"); system == LOINCSystem && !ValidLOINCCheckDigit(code) {
+			t.Errorf("Expected built-in LOINC code %s to have a valid check digit", code)
+		}
+	}
+	for _, invalidCode := range []string{"8867-5", "8867", "8867-", "-4", "88a7-4", "8867-44"} {
+		if ValidLOINCCheckDigit(invalidCode) {
+			t.Errorf("Expected %q to be refused", invalidCode)
+		}
+	}
+}
+
+// TestTerminology_LookupCode verifies listed codes are valid, unlisted LOINC codes are checked by their check
+// digit, and codes of systems without concepts are unchecked
+func TestTerminology_LookupCode(t *testing.T) {
+	terminology := Default()
+	terminology.add([]Concept{{System: "http://hospital.example.org/codes", Code: "GLU-POC", Display: "Point-of-care glucose"}})
+
+	testCases := []struct {
+		system   string
+		code     string
+		expected Validity
+	}{
+		{system: LOINCSystem, code: "8867-4", expected: ValidityValid},
+		{system: LOINCSystem, code: "8867-5", expected: ValidityInvalid},
+		{system: LOINCSystem, code: "1751-7", expected: ValidityUnchecked},
+		{system: "http://hospital.example.org/codes", code: "GLU-POC", expected: ValidityValid},
+		{system: "http://hospital.example.org/codes", code: "GLU-LAB", expected: ValidityInvalid},
+		{system: "http://snomed.info/sct", code: "364075005", expected: ValidityUnchecked},
+	}
+	for _, testCase := range testCases {
+		if validity, _ := terminology.LookupCode(context.Background(), testCase.system, testCase.code); validity != testCase.expected {
+			t.Errorf("%s|%s: expected %d, got %d", testCase.system, testCase.code, testCase.expected, validity)
+		}
+	}
+}
+
+// TestServerLookup verifies $validate-code answers are read and cached, and the server is asked only about
+// codes the built-in concepts cannot check
+func TestServerLookup(t *testing.T) {
+	var requests int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&requests, 1)
+		if r.URL.Path != "/CodeSystem/$validate-code" {
+			t.Errorf("Unexpected path %s", r.URL.Path)
+		}
+		switch r.URL.Query().Get("code") {
+		case "1751-7":
+			w.Write([]byte(`{"resourceType":"Parameters","parameter":[{"name":"result","valueBoolean":true}]}`))
+		case "1234-4":
+			w.Write([]byte(`{"resourceType":"Parameters","parameter":[{"name":"result","valueBoolean":false},{"name":"message","valueString":"Unknown code"}]}`))
+		case "unknown-system":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	serverLookup, _ := NewServerLookup(server.URL+"/", time.Second)
+	lookup := Fallback(Default(), serverLookup)
+	testCases := []struct {
+		system   string
+		code     string
+		expected Validity
+	}{
+		{system: LOINCSystem, code: "8867-4", expected: ValidityValid},
+		{system: LOINCSystem, code: "1751-7", expected: ValidityValid},
+		{system: LOINCSystem, code: "1751-7", expected: ValidityValid},
+		{system: LOINCSystem, code: "1234-4", expected: ValidityInvalid},
+		{system: "http://example.org/local", code: "unknown-system", expected: ValidityUnchecked},
+	}
+	for _, testCase := range testCases {
+		validity, lookupError := lookup.LookupCode(context.Background(), testCase.system, testCase.code)
+		if lookupError != nil || validity != testCase.expected {
+			t.Errorf("%s|%s: expected %d, got %d (%v)", testCase.system, testCase.code, testCase.expected, validity, lookupError)
+		}
+	}
+	if requests != 3 {
+		t.Errorf("Expected the built-in code and the cached answer not to reach the server, got %d requests", requests)
+	}
+
+	if _, failedError := lookup.LookupCode(context.Background(), LOINCSystem, "2000-8"); failedError == nil {
+		t.Error("Expected a server error to fail the lookup")
+	}
+	if _, urlError := NewServerLookup("tx.fhir.org", time.Second); urlError == nil {
+		t.Error("Expected a URL without a scheme to be refused")
+	}
+}
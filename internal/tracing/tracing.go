@@ -0,0 +1,160 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.43.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer used for the server's own spans
+const instrumentationName = "github.com/nathannewyen/fhir-health-interop"
+
+// defaultServiceName is reported as service.name when OTEL_SERVICE_NAME is not set
+const defaultServiceName = "fhir-health-interop"
+
+// Protocols accepted in OTEL_EXPORTER_OTLP_PROTOCOL (or OTEL_EXPORTER_OTLP_TRACES_PROTOCOL)
+const (
+	ProtocolHTTP = "http/protobuf"
+	ProtocolGRPC = "grpc"
+)
+
+// Config selects where spans are exported
+// The exporters read the remaining standard OTEL_EXPORTER_OTLP_* variables themselves: headers, timeout,
+// compression, certificates, and insecure
+type Config struct {
+	// Endpoint is the OTLP collector address; spans are not exported when it is empty
+	Endpoint string
+
+	// Protocol is ProtocolHTTP (the default) or ProtocolGRPC
+	Protocol string
+
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+}
+
+// ConfigFromEnvironment reads the standard OpenTelemetry variables, preferring the trace-specific ones
+func ConfigFromEnvironment() Config {
+	config := Config{
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+		Protocol:    os.Getenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+	}
+	if config.Endpoint == "" {
+		config.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	if config.Protocol == "" {
+		config.Protocol = os.Getenv("OTEL_EXPORTER_OTLP_PROTOCOL")
+	}
+	if config.Protocol == "" {
+		config.Protocol = ProtocolHTTP
+	}
+	if config.ServiceName == "" {
+		config.ServiceName = defaultServiceName
+	}
+	return config
+}
+
+// Setup installs the W3C trace context propagator and, when an endpoint is configured, a tracer provider
+// batching spans to the OTLP collector
+// Without an endpoint spans are not recorded, but incoming trace context still flows to outgoing calls.
+// The returned function flushes buffered spans and must be called on shutdown
+func Setup(ctx context.Context, config Config) (func(ctx context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if config.Endpoint == "" {
+		return func(ctx context.Context) error { return nil }, nil
+	}
+
+	var exporter sdktrace.SpanExporter
+	var exporterError error
+	switch config.Protocol {
+	case ProtocolHTTP:
+		exporter, exporterError = otlptracehttp.New(ctx)
+	case ProtocolGRPC:
+		exporter, exporterError = otlptracegrpc.New(ctx)
+	default:
+		return nil, fmt.Errorf("unsupported OTLP protocol %q; use %s or %s", config.Protocol, ProtocolHTTP, ProtocolGRPC)
+	}
+	if exporterError != nil {
+		return nil, fmt.Errorf("failed to create the OTLP trace exporter: %w", exporterError)
+	}
+
+	serviceResource, resourceError := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(config.ServiceName)))
+	if resourceError != nil {
+		return nil, fmt.Errorf("failed to describe the service for tracing: %w", resourceError)
+	}
+
+	// The sampler follows OTEL_TRACES_SAMPLER; by default every trace is sampled unless the caller's traceparent says otherwise
+	tracerProvider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(serviceResource),
+	)
+	otel.SetTracerProvider(tracerProvider)
+	return tracerProvider.Shutdown, nil
+}
+
+// Start starts an internal span as a child of the span in ctx, such as a service call
+// Spans are dropped cheaply when tracing is not set up
+func Start(ctx context.Context, spanName string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, spanName, trace.WithAttributes(attributes...))
+}
+
+// Inject writes the trace context of ctx into outgoing request headers as traceparent and tracestate
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Middleware continues the trace named by an incoming traceparent header, or starts one, with a server span
+// per request named by its chi route pattern
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		parentContext := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		spanContext, span := otel.Tracer(instrumentationName).Start(parentContext, r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method), semconv.URLPath(r.URL.Path)),
+		)
+		defer span.End()
+
+		recorder := &statusRecorder{ResponseWriter: w, statusCode: http.StatusOK}
+		next.ServeHTTP(recorder, r.WithContext(spanContext))
+
+		// The route pattern is only known once chi has routed the request
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+			span.SetName(r.Method + " " + routeContext.RoutePattern())
+			span.SetAttributes(semconv.HTTPRoute(routeContext.RoutePattern()))
+		}
+		span.SetAttributes(semconv.HTTPResponseStatusCode(recorder.statusCode))
+		if recorder.statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.statusCode))
+		}
+	})
+}
+
+// statusRecorder captures the status code written by the wrapped handler
+type statusRecorder struct {
+	http.ResponseWriter
+	statusCode int
+}
+
+// WriteHeader captures the status code before writing
+func (recorder *statusRecorder) WriteHeader(statusCode int) {
+	recorder.statusCode = statusCode
+	recorder.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController
+func (recorder *statusRecorder) Unwrap() http.ResponseWriter {
+	return recorder.ResponseWriter
+}
@@ -0,0 +1,100 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// incomingTraceParent is the W3C traceparent an upstream integration engine sends
+const incomingTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// recordSpans installs a tracer provider keeping finished spans in memory for the test
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+	Setup(context.Background(), Config{})
+	return recorder
+}
+
+// TestMiddleware_ContinuesIncomingTrace verifies the server span joins the caller's trace, is named by route,
+// and parents the spans started while handling the request
+func TestMiddleware_ContinuesIncomingTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/fhir/Patient/{id}", func(w http.ResponseWriter, r *http.Request) {
+		_, span := Start(r.Context(), "PatientService.GetPatientByID")
+		span.End()
+	})
+	router.Post("/fhir/Observation", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient/p-1", nil)
+	request.Header.Set("traceparent", incomingTraceParent)
+	router.ServeHTTP(httptest.NewRecorder(), request)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/fhir/Observation", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 3 {
+		t.Fatalf("Expected a service span and two server spans, got %d", len(spans))
+	}
+	serviceSpan, serverSpan, failedSpan := spans[0], spans[1], spans[2]
+	if serverSpan.Name() != "GET /fhir/Patient/{id}" || serverSpan.SpanKind() != trace.SpanKindServer {
+		t.Errorf("Expected a server span named by route, got %q (%v)", serverSpan.Name(), serverSpan.SpanKind())
+	}
+	if serverSpan.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" || serverSpan.Parent().SpanID().String() != "00f067aa0ba902b7" {
+		t.Errorf("Expected the server span to continue the incoming trace, got trace %s parent %s",
+			serverSpan.SpanContext().TraceID(), serverSpan.Parent().SpanID())
+	}
+	if serviceSpan.Parent().SpanID() != serverSpan.SpanContext().SpanID() {
+		t.Error("Expected the service span to be a child of the server span")
+	}
+	if failedSpan.Name() != "POST /fhir/Observation" || failedSpan.Status().Code != codes.Error || failedSpan.Parent().IsValid() {
+		t.Errorf("Expected a new root span marked as an error for the 500, got %q %v", failedSpan.Name(), failedSpan.Status())
+	}
+}
+
+// TestInject verifies outgoing requests carry the current trace context
+func TestInject(t *testing.T) {
+	recordSpans(t)
+	spanContext, span := Start(context.Background(), "delivery.Send")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(spanContext, header)
+	expectedTraceParent := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+	if header.Get("traceparent") != expectedTraceParent {
+		t.Errorf("Expected traceparent %s, got %q", expectedTraceParent, header.Get("traceparent"))
+	}
+}
+
+// TestConfigFromEnvironment verifies trace-specific variables win and the defaults apply when nothing is set
+func TestConfigFromEnvironment(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://collector:4318")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_PROTOCOL", "grpc")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_PROTOCOL", "http/protobuf")
+	t.Setenv("OTEL_SERVICE_NAME", "")
+
+	config := ConfigFromEnvironment()
+	if config.Endpoint != "http://collector:4318" || config.Protocol != ProtocolHTTP || config.ServiceName != defaultServiceName {
+		t.Errorf("Unexpected config %+v", config)
+	}
+
+	if _, setupError := Setup(context.Background(), Config{Endpoint: "collector:4317", Protocol: "udp"}); setupError == nil {
+		t.Error("Expected an unsupported protocol to be rejected")
+	}
+}
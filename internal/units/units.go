@@ -0,0 +1,261 @@
+package units
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Actions taken on observation units the converter does not accept
+const (
+	// ValidationReject fails the write so the unit is never stored
+	ValidationReject = "reject"
+
+	// ValidationWarn stores the observation and warns the caller about the unit
+	ValidationWarn = "warn"
+)
+
+// defaultTableJSON holds the built-in UCUM units and analytes
+//
+//go:embed units.json
+var defaultTableJSON []byte
+
+// Unit is one UCUM unit of a dimension
+type Unit struct {
+	// Code is the UCUM code of the unit (e.g. "mg/dL")
+	Code string `json:"code"`
+
+	// Aliases are other spellings of Code that clients send (e.g. "mmHg" for "mm[Hg]")
+	Aliases []string `json:"aliases"`
+
+	// Factor and Offset convert a value in this unit to the dimension's canonical unit: value*Factor + Offset
+	Factor float64 `json:"factor"`
+	Offset float64 `json:"offset"`
+}
+
+// Dimension is a kind of quantity whose units convert into one another, such as mass concentration
+type Dimension struct {
+	// Name identifies the dimension in analytes (e.g. "mass-concentration")
+	Name string `json:"name"`
+
+	// Canonical is the code of the unit values of this dimension are normalized to
+	Canonical string `json:"canonical"`
+
+	// Units are the units of the dimension, including the canonical one
+	Units []Unit `json:"units"`
+}
+
+// Conversion converts an analyte's values from another dimension, such as glucose from mmol/L to mg/dL
+type Conversion struct {
+	// From is the name of the dimension converted from
+	From string `json:"from"`
+
+	// Factor multiplies a value in From's canonical unit to give it in the analyte's canonical unit
+	Factor float64 `json:"factor"`
+}
+
+// Analyte ties an observation code to the dimension its values are normalized to
+type Analyte struct {
+	// Code is the observation or component code (e.g. LOINC "2339-0")
+	Code string `json:"code"`
+
+	// Display names the analyte in diagnostics
+	Display string `json:"display"`
+
+	// Dimension is the name of the dimension the analyte's values are normalized to
+	Dimension string `json:"dimension"`
+
+	// Conversions list the other dimensions the analyte may be sent in
+	Conversions []Conversion `json:"conversions"`
+}
+
+// Table configures the known units and analytes
+type Table struct {
+	Dimensions []Dimension `json:"dimensions"`
+	Analytes   []Analyte   `json:"analytes"`
+}
+
+// DefaultTable returns the built-in units and analytes
+func DefaultTable() *Table {
+	table := &Table{}
+	if decodeError := json.Unmarshal(defaultTableJSON, table); decodeError != nil {
+		panic(fmt.Sprintf("embedded unit table is invalid: %v", decodeError))
+	}
+	return table
+}
+
+// LoadTable reads site units from a JSON file and merges them over the built-in ones
+// A site dimension replaces the built-in dimension of the same name and a site analyte the built-in analyte
+// of the same code; other built-in entries are kept
+func LoadTable(path string) (*Table, error) {
+	fileBytes, readError := os.ReadFile(path)
+	if readError != nil {
+		return nil, fmt.Errorf("failed to read unit table: %w", readError)
+	}
+
+	siteTable := &Table{}
+	if decodeError := json.Unmarshal(fileBytes, siteTable); decodeError != nil {
+		return nil, fmt.Errorf("failed to parse unit table: %w", decodeError)
+	}
+
+	table := DefaultTable().Merge(siteTable)
+	if validateError := table.Validate(); validateError != nil {
+		return nil, validateError
+	}
+
+	return table, nil
+}
+
+// Merge returns the table with the site's dimensions and analytes replacing those of the same name and code
+func (table *Table) Merge(siteTable *Table) *Table {
+	merged := &Table{}
+
+	siteDimensions := make(map[string]bool, len(siteTable.Dimensions))
+	for _, dimension := range siteTable.Dimensions {
+		siteDimensions[dimension.Name] = true
+	}
+	for _, dimension := range table.Dimensions {
+		if !siteDimensions[dimension.Name] {
+			merged.Dimensions = append(merged.Dimensions, dimension)
+		}
+	}
+	merged.Dimensions = append(merged.Dimensions, siteTable.Dimensions...)
+
+	siteAnalytes := make(map[string]bool, len(siteTable.Analytes))
+	for _, analyte := range siteTable.Analytes {
+		siteAnalytes[analyte.Code] = true
+	}
+	for _, analyte := range table.Analytes {
+		if !siteAnalytes[analyte.Code] {
+			merged.Analytes = append(merged.Analytes, analyte)
+		}
+	}
+	merged.Analytes = append(merged.Analytes, siteTable.Analytes...)
+
+	return merged
+}
+
+// Validate checks the table for units and analytes the converter cannot apply
+func (table *Table) Validate() error {
+	dimensionNames := make(map[string]bool, len(table.Dimensions))
+	spellings := make(map[string]string)
+	for index, dimension := range table.Dimensions {
+		if dimension.Name == "" {
+			return fmt.Errorf("unit dimensions[%d] must set name", index)
+		}
+		if dimensionNames[dimension.Name] {
+			return fmt.Errorf("unit dimension %s is listed twice", dimension.Name)
+		}
+		dimensionNames[dimension.Name] = true
+
+		hasCanonical := false
+		for _, unit := range dimension.Units {
+			if unit.Code == "" || unit.Factor <= 0 {
+				return fmt.Errorf("units of dimension %s must set code and a positive factor", dimension.Name)
+			}
+			if unit.Code == dimension.Canonical {
+				if unit.Factor != 1 || unit.Offset != 0 {
+					return fmt.Errorf("canonical unit %s of dimension %s must have factor 1 and no offset", unit.Code, dimension.Name)
+				}
+				hasCanonical = true
+			}
+			for _, spelling := range append([]string{unit.Code}, unit.Aliases...) {
+				if owner, taken := spellings[spelling]; taken {
+					return fmt.Errorf("unit %q of dimension %s is already a unit of dimension %s", spelling, dimension.Name, owner)
+				}
+				spellings[spelling] = dimension.Name
+			}
+		}
+		if !hasCanonical {
+			return fmt.Errorf("unit dimension %s must list its canonical unit %q", dimension.Name, dimension.Canonical)
+		}
+	}
+
+	for index, analyte := range table.Analytes {
+		if analyte.Code == "" {
+			return fmt.Errorf("unit analytes[%d] must set code", index)
+		}
+		if !dimensionNames[analyte.Dimension] {
+			return fmt.Errorf("unit analyte %s has unknown dimension %q", analyte.Code, analyte.Dimension)
+		}
+		for _, conversion := range analyte.Conversions {
+			if !dimensionNames[conversion.From] || conversion.Factor <= 0 {
+				return fmt.Errorf("unit analyte %s must convert from a known dimension with a positive factor, got %q", analyte.Code, conversion.From)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Quantity is a value with its unit
+type Quantity struct {
+	Value float64
+	Unit  string
+}
+
+// knownUnit is a unit with the dimension it belongs to
+type knownUnit struct {
+	unit      Unit
+	dimension *Dimension
+}
+
+// Converter validates units and normalizes values to canonical units
+// It is read-only after construction, so it is safe for concurrent use
+type Converter struct {
+	// units maps every code and alias to its unit
+	units map[string]knownUnit
+
+	// analytes maps observation codes to their analytes
+	analytes map[string]Analyte
+
+	// canonicalUnits maps dimension names to their canonical units
+	canonicalUnits map[string]string
+}
+
+// NewConverter builds a converter from a validated table
+func NewConverter(table *Table) *Converter {
+	converter := &Converter{
+		units:          make(map[string]knownUnit),
+		analytes:       make(map[string]Analyte, len(table.Analytes)),
+		canonicalUnits: make(map[string]string, len(table.Dimensions)),
+	}
+	for dimensionIndex := range table.Dimensions {
+		dimension := &table.Dimensions[dimensionIndex]
+		converter.canonicalUnits[dimension.Name] = dimension.Canonical
+		for _, unit := range dimension.Units {
+			converter.units[unit.Code] = knownUnit{unit: unit, dimension: dimension}
+			for _, alias := range unit.Aliases {
+				converter.units[alias] = knownUnit{unit: unit, dimension: dimension}
+			}
+		}
+	}
+	for _, analyte := range table.Analytes {
+		converter.analytes[analyte.Code] = analyte
+	}
+	return converter
+}
+
+// Normalize converts a value of the observation code to its canonical unit
+// Values of an analyte are converted to the canonical unit of the analyte's dimension, through one of its
+// conversions when sent in another dimension; values of other codes to the canonical unit of their own
+// dimension. Unknown units, and units an analyte cannot be expressed in, are errors
+func (converter *Converter) Normalize(code string, value float64, unit string) (Quantity, error) {
+	known, isKnown := converter.units[unit]
+	if !isKnown {
+		return Quantity{}, fmt.Errorf("unit %q is not a known UCUM unit", unit)
+	}
+	canonicalValue := value*known.unit.Factor + known.unit.Offset
+
+	analyte, isAnalyte := converter.analytes[code]
+	if !isAnalyte || analyte.Dimension == known.dimension.Name {
+		return Quantity{Value: canonicalValue, Unit: known.dimension.Canonical}, nil
+	}
+	for _, conversion := range analyte.Conversions {
+		if conversion.From == known.dimension.Name {
+			return Quantity{Value: canonicalValue * conversion.Factor, Unit: converter.canonicalUnits[analyte.Dimension]}, nil
+		}
+	}
+	return Quantity{}, fmt.Errorf("unit %q cannot express %s", unit, analyte.Display)
+}
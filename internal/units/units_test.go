@@ -0,0 +1,117 @@
+package units
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestDefaultTable_Validate verifies the built-in units and analytes are consistent
+func TestDefaultTable_Validate(t *testing.T) {
+	if validateError := DefaultTable().Validate(); validateError != nil {
+		t.Fatalf("Expected the built-in unit table to be valid, got %v", validateError)
+	}
+}
+
+// TestConverter_Normalize verifies values are converted to the canonical unit of their analyte or dimension
+func TestConverter_Normalize(t *testing.T) {
+	converter := NewConverter(DefaultTable())
+	testCases := []struct {
+		code          string
+		value         float64
+		unit          string
+		expectedValue float64
+		expectedUnit  string
+	}{
+		{code: "2339-0", value: 100, unit: "mg/dL", expectedValue: 100, expectedUnit: "mg/dL"},
+		{code: "2339-0", value: 5.5, unit: "mmol/L", expectedValue: 99.088, expectedUnit: "mg/dL"},
+		{code: "2339-0", value: 1, unit: "g/L", expectedValue: 100, expectedUnit: "mg/dL"},
+		{code: "2160-0", value: 88.4, unit: "umol/L", expectedValue: 1, expectedUnit: "mg/dL"},
+		{code: "29463-7", value: 154, unit: "[lb_av]", expectedValue: 69.853, expectedUnit: "kg"},
+		{code: "8310-5", value: 98.6, unit: "[degF]", expectedValue: 37, expectedUnit: "Cel"},
+		{code: "8480-6", value: 120, unit: "mmHg", expectedValue: 120, expectedUnit: "mm[Hg]"},
+		{code: "14749-6", value: 5.5, unit: "mmol/L", expectedValue: 5.5, expectedUnit: "mmol/L"},
+	}
+	for _, testCase := range testCases {
+		canonical, normalizeError := converter.Normalize(testCase.code, testCase.value, testCase.unit)
+		if normalizeError != nil {
+			t.Errorf("%s %v %s: expected no error, got %v", testCase.code, testCase.value, testCase.unit, normalizeError)
+			continue
+		}
+		if canonical.Unit != testCase.expectedUnit || math.Abs(canonical.Value-testCase.expectedValue) > 0.001 {
+			t.Errorf("%s %v %s: expected %v %s, got %v %s", testCase.code, testCase.value, testCase.unit, testCase.expectedValue, testCase.expectedUnit, canonical.Value, canonical.Unit)
+		}
+	}
+}
+
+// TestConverter_Normalize_Invalid verifies unknown units and units an analyte cannot be expressed in are refused
+func TestConverter_Normalize_Invalid(t *testing.T) {
+	converter := NewConverter(DefaultTable())
+	invalidCases := []struct {
+		code string
+		unit string
+	}{
+		{code: "2339-0", unit: "mg/deciliter"},
+		{code: "2339-0", unit: "kg"},
+		{code: "718-7", unit: "mmol/L"},
+		{code: "8867-4", unit: "mm[Hg]"},
+	}
+	for _, invalidCase := range invalidCases {
+		if _, normalizeError := converter.Normalize(invalidCase.code, 1, invalidCase.unit); normalizeError == nil {
+			t.Errorf("%s in %s: expected an error", invalidCase.code, invalidCase.unit)
+		}
+	}
+}
+
+// TestLoadTable verifies site dimensions and analytes replace built-in ones and invalid tables are refused
+func TestLoadTable(t *testing.T) {
+	directory := t.TempDir()
+	sitePath := filepath.Join(directory, "units.json")
+	siteTable := `{
+		"dimensions": [{"name": "mass", "canonical": "g", "units": [{"code": "g", "factor": 1}, {"code": "kg", "factor": 1000}]}],
+		"analytes": [{"code": "GLU-POC", "display": "Point-of-care glucose", "dimension": "mass-concentration", "conversions": [{"from": "substance-concentration", "factor": 18}]}]
+	}`
+	os.WriteFile(sitePath, []byte(siteTable), 0o600)
+
+	table, loadError := LoadTable(sitePath)
+	if loadError != nil {
+		t.Fatalf("Expected the site table to load, got %v", loadError)
+	}
+	converter := NewConverter(table)
+	if weight, _ := converter.Normalize("29463-7", 70, "kg"); weight.Unit != "g" || weight.Value != 70000 {
+		t.Errorf("Expected the site mass dimension to normalize to grams, got %+v", weight)
+	}
+	if glucose, _ := converter.Normalize("GLU-POC", 5, "mmol/L"); glucose.Unit != "mg/dL" || glucose.Value != 90 {
+		t.Errorf("Expected the site analyte to convert with its factor, got %+v", glucose)
+	}
+	if _, missingError := converter.Normalize("29463-7", 1, "[lb_av]"); missingError == nil {
+		t.Error("Expected units left out of the replaced dimension to be unknown")
+	}
+
+	invalidTables := []string{
+		`{"dimensions": [{"name": "volume", "canonical": "L", "units": [{"code": "mL", "factor": 0.001}]}]}`,
+		`{"dimensions": [{"name": "volume", "canonical": "L", "units": [{"code": "L", "factor": 1}, {"code": "mg/dL", "factor": 2}]}]}`,
+		`{"analytes": [{"code": "1751-7", "dimension": "volume"}]}`,
+		`not json`,
+	}
+	for _, invalidTable := range invalidTables {
+		os.WriteFile(sitePath, []byte(invalidTable), 0o600)
+		if _, invalidError := LoadTable(sitePath); invalidError == nil {
+			t.Errorf("Expected %s to be refused", strings.Join(strings.Fields(invalidTable), " "))
+		}
+	}
+}
+
+// TestLoadTable_ExampleFile verifies the shipped example table loads and its analytes convert
+func TestLoadTable_ExampleFile(t *testing.T) {
+	table, loadError := LoadTable(filepath.Join("..", "..", "config", "units.example.json"))
+	if loadError != nil {
+		t.Fatalf("Expected example table to load, got %v", loadError)
+	}
+	glucose, normalizeError := NewConverter(table).Normalize("2345-7", 100, "mg/dL")
+	if normalizeError != nil || glucose.Unit != "mmol/L" || math.Abs(glucose.Value-5.55) > 0.001 {
+		t.Errorf("Expected serum glucose normalized to 5.55 mmol/L, got %+v (%v)", glucose, normalizeError)
+	}
+}
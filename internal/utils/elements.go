@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+)
+
+// ParseElementsParameter returns the comma-separated _elements values, or nil when the parameter is absent
+func ParseElementsParameter(request *http.Request) []string {
+	rawValues, present := request.URL.Query()["_elements"]
+	if !present {
+		return nil
+	}
+
+	elements := []string{}
+	for _, rawValue := range rawValues {
+		for _, element := range strings.Split(rawValue, ",") {
+			if element = strings.TrimSpace(element); element != "" {
+				elements = append(elements, element)
+			}
+		}
+	}
+	return elements
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// pageCursorPrefix versions the page cursor format, so a future format can tell old cursors apart
+const pageCursorPrefix = "c1|"
+
+// errInvalidPageCursor is returned for a _cursor this server did not issue
+var errInvalidPageCursor = errors.New("_cursor must be taken from the next link of a previous page")
+
+// EncodePageCursor returns the opaque _cursor value continuing a search after the given match
+func EncodePageCursor(cursor *models.PageCursor) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(pageCursorPrefix + cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID))
+}
+
+// decodePageCursor parses a _cursor value made by EncodePageCursor
+func decodePageCursor(rawCursor string) (*models.PageCursor, error) {
+	decodedCursor, decodeError := base64.RawURLEncoding.DecodeString(rawCursor)
+	if decodeError != nil || !strings.HasPrefix(string(decodedCursor), pageCursorPrefix) {
+		return nil, errInvalidPageCursor
+	}
+
+	rawCreatedAt, cursorID, found := strings.Cut(strings.TrimPrefix(string(decodedCursor), pageCursorPrefix), "|")
+	if !found || cursorID == "" {
+		return nil, errInvalidPageCursor
+	}
+	createdAt, parseError := time.Parse(time.RFC3339Nano, rawCreatedAt)
+	if parseError != nil {
+		return nil, errInvalidPageCursor
+	}
+	return &models.PageCursor{CreatedAt: createdAt, ID: cursorID}, nil
+}
+
+// parsePageCursor decides whether a search pages with cursors and decodes its _cursor
+// Searches sorted by created_at without _offset page with cursors; _cursor cannot be combined with _offset
+// or another sort order
+func parsePageCursor(queryParams url.Values, sortBy string) (bool, *models.PageCursor, error) {
+	rawCursor := queryParams.Get("_cursor")
+	if rawCursor == "" {
+		return models.SortedByCreation(sortBy) && !queryParams.Has("_offset"), nil, nil
+	}
+	if queryParams.Has("_offset") {
+		return false, nil, errors.New("_cursor and _offset cannot be combined")
+	}
+	if !models.SortedByCreation(sortBy) {
+		return false, nil, errors.New("_cursor can only continue searches sorted by created_at")
+	}
+
+	after, decodeError := decodePageCursor(rawCursor)
+	if decodeError != nil {
+		return false, nil, decodeError
+	}
+	return true, after, nil
+}
+
+// isPatientID reports whether id is a UUID, the form patient IDs take
+func isPatientID(id string) bool {
+	_, parseError := uuid.Parse(id)
+	return parseError == nil
+}
+
+// isObservationID reports whether id is a MongoDB ObjectID in hex, the form observation IDs take
+func isObservationID(id string) bool {
+	if len(id) != 24 {
+		return false
+	}
+	_, decodeError := hex.DecodeString(id)
+	return decodeError == nil
+}
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
+)
+
+// TestPageCursor_RoundTrip verifies an encoded cursor parses back to the same match
+func TestPageCursor_RoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 4, 5, 6, 7, 123456000, time.UTC)
+	cursor := EncodePageCursor(&models.PageCursor{CreatedAt: createdAt, ID: "0c7d3a5e-3b55-4a63-9f1c-2a9e7d6b8f10"})
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?family=Smith&_cursor="+cursor, nil)
+	searchParams, parseError := ParsePatientSearchParams(request)
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+	if !searchParams.CursorPaging || searchParams.After == nil {
+		t.Fatalf("Expected a cursor-paged search continuing after the cursor, got %+v", searchParams)
+	}
+	if !searchParams.After.CreatedAt.Equal(createdAt) || searchParams.After.ID != "0c7d3a5e-3b55-4a63-9f1c-2a9e7d6b8f10" {
+		t.Errorf("Expected the cursor's match back, got %+v", searchParams.After)
+	}
+}
+
+// TestParsePageCursor_Mode verifies searches sorted by created_at page with cursors unless they give _offset
+func TestParsePageCursor_Mode(t *testing.T) {
+	testCases := []struct {
+		query                string
+		expectedCursorPaging bool
+	}{
+		{query: "", expectedCursorPaging: true},
+		{query: "?_sort=-created_at", expectedCursorPaging: true},
+		{query: "?_offset=20", expectedCursorPaging: false},
+		{query: "?_sort=family", expectedCursorPaging: false},
+	}
+	for _, testCase := range testCases {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/Patient"+testCase.query, nil)
+		searchParams, parseError := ParsePatientSearchParams(request)
+		if parseError != nil {
+			t.Fatalf("%q: expected no error, got %v", testCase.query, parseError)
+		}
+		if searchParams.CursorPaging != testCase.expectedCursorPaging {
+			t.Errorf("%q: expected cursor paging %v, got %v", testCase.query, testCase.expectedCursorPaging, searchParams.CursorPaging)
+		}
+	}
+}
+
+// TestParsePageCursor_Invalid verifies cursors that were not issued, or cannot continue the search, are rejected
+func TestParsePageCursor_Invalid(t *testing.T) {
+	patientCursor := EncodePageCursor(&models.PageCursor{CreatedAt: time.Now(), ID: "0c7d3a5e-3b55-4a63-9f1c-2a9e7d6b8f10"})
+	observationCursor := EncodePageCursor(&models.PageCursor{CreatedAt: time.Now(), ID: "65f1c0a2b3c4d5e6f7a8b9c0"})
+
+	invalidTargets := []string{
+		"/fhir/Patient?_cursor=not-a-cursor",
+		"/fhir/Patient?_cursor=" + patientCursor + "&_offset=10",
+		"/fhir/Patient?_cursor=" + patientCursor + "&_sort=family",
+		"/fhir/Patient?_cursor=" + observationCursor,
+		"/fhir/Observation?_cursor=" + patientCursor,
+		"/fhir/Observation?_cursor=" + observationCursor + "&_sort=-date",
+	}
+	for _, target := range invalidTargets {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		var parseError error
+		if request.URL.Path == "/fhir/Patient" {
+			_, parseError = ParsePatientSearchParams(request)
+		} else {
+			_, parseError = ParseObservationSearchParams(request)
+		}
+		if parseError == nil {
+			t.Errorf("%s: expected an error", target)
+		}
+	}
+
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?_cursor="+observationCursor, nil)
+	if _, parseError := ParseObservationSearchParams(request); parseError != nil {
+		t.Errorf("Expected an observation cursor to be accepted, got %v", parseError)
+	}
+}
@@ -1,7 +1,11 @@
 package utils
 
 import (
+	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -9,6 +13,45 @@ import (
 	"github.com/nathannewyen/fhir-health-interop/internal/models"
 )
 
+// PatientSearchParameters lists the query parameters understood by ParsePatientSearchParams
+var PatientSearchParameters = []string{"name", "family", "given", "gender", "birthdate", "active", "identifier", "_tag", "_revinclude", "_elements", "_sort", "_count", "_offset", "_cursor", "_stream"}
+
+// ObservationSearchParameters lists the query parameters understood by ParseObservationSearchParams
+var ObservationSearchParameters = []string{"patient", "encounter", "code", "category", "status", "date", "value-quantity", "_tag", "_include", "_elements", "_sort", "_count", "_offset", "_cursor", "_stream"}
+
+// PractitionerSearchParameters lists the query parameters understood by ParsePractitionerSearchParams
+var PractitionerSearchParameters = []string{"name", "family", "given", "active", "identifier", "specialty", "_elements", "_count", "_offset"}
+
+// EncounterSearchParameters lists the query parameters understood by ParseEncounterSearchParams
+var EncounterSearchParameters = []string{"patient", "status", "date", "_elements", "_count", "_offset"}
+
+// ConditionSearchParameters lists the query parameters understood by ParseConditionSearchParams
+var ConditionSearchParameters = []string{"patient", "code", "clinical-status", "onset-date", "_elements", "_count", "_offset"}
+
+// MedicationRequestSearchParameters lists the query parameters understood by ParseMedicationRequestSearchParams
+var MedicationRequestSearchParameters = []string{"patient", "status", "intent", "authoredon", "_elements", "_count", "_offset"}
+
+// AllergyIntoleranceSearchParameters lists the query parameters understood by ParseAllergyIntoleranceSearchParams
+var AllergyIntoleranceSearchParameters = []string{"patient", "clinical-status", "category", "criticality", "_elements", "_count", "_offset"}
+
+// DiagnosticReportSearchParameters lists the query parameters understood by ParseDiagnosticReportSearchParams
+var DiagnosticReportSearchParameters = []string{"patient", "category", "code", "date", "_elements", "_count", "_offset"}
+
+// ImmunizationSearchParameters lists the query parameters understood by ParseImmunizationSearchParams
+var ImmunizationSearchParameters = []string{"patient", "vaccine-code", "date", "status", "lot-number", "_elements", "_count", "_offset"}
+
+// AuditEventSearchParameters lists the query parameters understood by ParseAuditEventSearchParams
+var AuditEventSearchParameters = []string{"agent", "entity", "entity-type", "action", "outcome", "date", "_elements", "_count", "_offset"}
+
+// SubscriptionSearchParameters lists the query parameters understood by ParseSubscriptionSearchParams
+var SubscriptionSearchParameters = []string{"status", "_elements", "_count", "_offset"}
+
+// PatientRevIncludes lists the _revinclude values Patient searches understand
+var PatientRevIncludes = []string{"Observation:patient", "Observation:subject"}
+
+// ObservationIncludes lists the _include values Observation searches understand
+var ObservationIncludes = []string{"Observation:subject", "Observation:patient"}
+
 // ParsePatientSearchParams extracts and validates patient search parameters from HTTP request
 func ParsePatientSearchParams(request *http.Request) (*models.PatientSearchParams, error) {
 	queryParams := request.URL.Query()
@@ -18,38 +61,26 @@ func ParsePatientSearchParams(request *http.Request) (*models.PatientSearchParam
 		Offset: 0,   // Default offset
 	}
 
-	// Parse name parameter
-	if name := queryParams.Get("name"); name != "" {
-		searchParams.Name = name
-	}
+	// Parse name, family, given, and gender parameters; repeated parameters must all match and
+	// comma-separated values within one parameter match any
+	searchParams.Name = parseNameCriteria(queryParams, "name")
+	searchParams.FamilyName = parseNameCriteria(queryParams, "family")
+	searchParams.GivenName = parseNameCriteria(queryParams, "given")
+	searchParams.Gender = parseValueCriteria(queryParams["gender"])
 
-	// Parse family name parameter
-	if familyName := queryParams.Get("family"); familyName != "" {
-		searchParams.FamilyName = familyName
-	}
-
-	// Parse given name parameter
-	if givenName := queryParams.Get("given"); givenName != "" {
-		searchParams.GivenName = givenName
-	}
-
-	// Parse gender parameter
-	if gender := queryParams.Get("gender"); gender != "" {
-		searchParams.Gender = gender
-	}
-
-	// Parse birthdate parameter with prefixes
-	if birthdate := queryParams.Get("birthdate"); birthdate != "" {
+	// Parse birthdate parameters with prefixes; a range is given as two parameters, birthdate=ge...&birthdate=le...
+	for _, birthdate := range queryParams["birthdate"] {
 		parsedDate, prefix := parseDateWithPrefix(birthdate)
-		if parsedDate != nil {
-			switch prefix {
-			case "ge", "gt":
-				searchParams.BirthDateGreaterThan = parsedDate
-			case "le", "lt":
-				searchParams.BirthDateLessThan = parsedDate
-			case "eq", "":
-				searchParams.BirthDate = parsedDate
-			}
+		if parsedDate == nil {
+			continue
+		}
+		switch prefix {
+		case "ge", "gt":
+			searchParams.BirthDateGreaterThan = laterDate(searchParams.BirthDateGreaterThan, parsedDate)
+		case "le", "lt":
+			searchParams.BirthDateLessThan = earlierDate(searchParams.BirthDateLessThan, parsedDate)
+		case "eq", "":
+			searchParams.BirthDate = parsedDate
 		}
 	}
 
@@ -60,6 +91,14 @@ func ParsePatientSearchParams(request *http.Request) (*models.PatientSearchParam
 		}
 	}
 
+	// Parse identifier parameter
+	if identifier := queryParams.Get("identifier"); identifier != "" {
+		searchParams.Identifier = parseIdentifierCriterion(identifier)
+	}
+
+	// Parse _tag parameters
+	searchParams.Tags = parseTagCriteria(queryParams["_tag"])
+
 	// Parse sort parameter
 	if sortBy := queryParams.Get("_sort"); sortBy != "" {
 		// Handle descending sort (prefix with -)
@@ -89,43 +128,391 @@ func ParsePatientSearchParams(request *http.Request) (*models.PatientSearchParam
 		}
 	}
 
+	// Parse _cursor, which continues a search sorted by created_at after the previous page
+	cursorPaging, after, cursorError := parsePageCursor(queryParams, searchParams.SortBy)
+	if cursorError != nil {
+		return nil, cursorError
+	}
+	if after != nil && !isPatientID(after.ID) {
+		return nil, errInvalidPageCursor
+	}
+	searchParams.CursorPaging = cursorPaging
+	searchParams.After = after
+
 	return searchParams, nil
 }
 
-// ParseObservationSearchParams extracts and validates observation search parameters from HTTP request
-func ParseObservationSearchParams(request *http.Request) (*models.ObservationSearchParams, error) {
+// parseNameCriteria parses a name parameter with no modifier, :contains, and :exact, in that order
+func parseNameCriteria(queryParams url.Values, parameterName string) models.NameCriteria {
+	var criteria models.NameCriteria
+	for _, match := range []models.NameMatch{models.NameMatchDefault, models.NameMatchContains, models.NameMatchExact} {
+		queryKey := parameterName
+		if match != models.NameMatchDefault {
+			queryKey += ":" + string(match)
+		}
+		for _, valueGroup := range parseValueCriteria(queryParams[queryKey]) {
+			criteria = append(criteria, models.NameCriterion{Values: valueGroup, Match: match})
+		}
+	}
+	return criteria
+}
+
+// parseIdentifierCriterion parses an identifier token: "system|value", "value" (any system),
+// "|value" (no system), or "system|" (any value); nil when neither part is given
+func parseIdentifierCriterion(identifier string) *models.IdentifierCriterion {
+	separatorIndex := strings.Index(identifier, "|")
+	if separatorIndex < 0 {
+		return &models.IdentifierCriterion{Value: identifier}
+	}
+
+	system := identifier[:separatorIndex]
+	value := identifier[separatorIndex+1:]
+	if system == "" && value == "" {
+		return nil
+	}
+	return &models.IdentifierCriterion{Systems: []string{system}, Value: value}
+}
+
+// ParsePractitionerSearchParams extracts and validates practitioner search parameters from HTTP request
+func ParsePractitionerSearchParams(request *http.Request) (*models.PractitionerSearchParams, error) {
 	queryParams := request.URL.Query()
 
-	searchParams := &models.ObservationSearchParams{
-		Limit:  10,  // Default limit
-		Offset: 0,   // Default offset
+	searchParams := &models.PractitionerSearchParams{
+		Limit:  10,
+		Offset: 0,
 	}
 
-	// Parse patient parameter
+	// Parse name parameters
+	searchParams.Name = queryParams.Get("name")
+	searchParams.FamilyName = queryParams.Get("family")
+	searchParams.GivenName = queryParams.Get("given")
+
+	// Parse active parameter
+	if active := queryParams.Get("active"); active != "" {
+		if activeBool, parseError := strconv.ParseBool(active); parseError == nil {
+			searchParams.Active = &activeBool
+		}
+	}
+
+	// Parse identifier parameter
+	if identifier := queryParams.Get("identifier"); identifier != "" {
+		searchParams.Identifier = parseIdentifierCriterion(identifier)
+	}
+
+	// Parse specialty parameter
+	if specialty := queryParams.Get("specialty"); specialty != "" {
+		searchParams.Specialty = parseCodingCriterion(specialty)
+	}
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// parseCodingCriterion parses a token matched against one coding: "system|code", "code" (any system),
+// "|code" (no system), or "system|" (any code); nil when neither part is given
+func parseCodingCriterion(token string) *models.CodingCriterion {
+	separatorIndex := strings.Index(token, "|")
+	if separatorIndex < 0 {
+		return &models.CodingCriterion{Code: token, AnySystem: true}
+	}
+
+	system := token[:separatorIndex]
+	code := token[separatorIndex+1:]
+	if system == "" && code == "" {
+		return nil
+	}
+	return &models.CodingCriterion{System: system, Code: code}
+}
+
+// ParseEncounterSearchParams extracts and validates encounter search parameters from HTTP request
+func ParseEncounterSearchParams(request *http.Request) (*models.EncounterSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.EncounterSearchParams{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	// Parse patient parameter, accepting "patient=123" and "patient=Patient/123"
 	if patientID := queryParams.Get("patient"); patientID != "" {
-		// Handle both "patient=123" and "patient=Patient/123" formats
-		if strings.HasPrefix(patientID, "Patient/") {
-			searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
-		} else {
-			searchParams.PatientID = patientID
+		searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
+	}
+
+	// Parse status parameter
+	searchParams.Status = queryParams.Get("status")
+
+	// Parse date parameter with prefixes
+	if date := queryParams.Get("date"); date != "" {
+		parsedDate, prefix := parseDateWithPrefix(date)
+		if parsedDate != nil {
+			switch prefix {
+			case "ge", "gt":
+				searchParams.DateGreaterThan = parsedDate
+			case "le", "lt":
+				searchParams.DateLessThan = parsedDate
+			}
+		}
+	}
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
 		}
 	}
 
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseConditionSearchParams extracts and validates condition search parameters from HTTP request
+func ParseConditionSearchParams(request *http.Request) (*models.ConditionSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.ConditionSearchParams{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	// Parse patient parameter, accepting "patient=123" and "patient=Patient/123"
+	if patientID := queryParams.Get("patient"); patientID != "" {
+		searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
+	}
+
 	// Parse code parameter
 	if code := queryParams.Get("code"); code != "" {
-		searchParams.Code = code
+		searchParams.Code = parseCodingCriterion(code)
+	}
+
+	// Parse clinical-status parameter
+	searchParams.ClinicalStatus = queryParams.Get("clinical-status")
+
+	// Parse onset-date parameter with prefixes
+	if onsetDate := queryParams.Get("onset-date"); onsetDate != "" {
+		parsedDate, prefix := parseDateWithPrefix(onsetDate)
+		if parsedDate != nil {
+			switch prefix {
+			case "ge", "gt":
+				searchParams.OnsetDateGreaterThan = parsedDate
+			case "le", "lt":
+				searchParams.OnsetDateLessThan = parsedDate
+			}
+		}
+	}
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseMedicationRequestSearchParams extracts and validates medication request search parameters from HTTP request
+func ParseMedicationRequestSearchParams(request *http.Request) (*models.MedicationRequestSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.MedicationRequestSearchParams{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	// Parse patient parameter, accepting "patient=123" and "patient=Patient/123"
+	if patientID := queryParams.Get("patient"); patientID != "" {
+		searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
+	}
+
+	// Parse status and intent parameters
+	searchParams.Status = queryParams.Get("status")
+	searchParams.Intent = queryParams.Get("intent")
+
+	// Parse authoredon parameter with prefixes
+	if authoredOn := queryParams.Get("authoredon"); authoredOn != "" {
+		parsedDate, prefix := parseDateWithPrefix(authoredOn)
+		if parsedDate != nil {
+			switch prefix {
+			case "ge", "gt":
+				searchParams.AuthoredOnGreaterThan = parsedDate
+			case "le", "lt":
+				searchParams.AuthoredOnLessThan = parsedDate
+			}
+		}
+	}
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseAllergyIntoleranceSearchParams extracts and validates allergy intolerance search parameters from HTTP request
+func ParseAllergyIntoleranceSearchParams(request *http.Request) (*models.AllergyIntoleranceSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.AllergyIntoleranceSearchParams{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	// Parse patient parameter, accepting "patient=123" and "patient=Patient/123"
+	if patientID := queryParams.Get("patient"); patientID != "" {
+		searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
+	}
+
+	// Parse clinical status, category, and criticality parameters
+	searchParams.ClinicalStatus = queryParams.Get("clinical-status")
+	searchParams.Category = queryParams.Get("category")
+	searchParams.Criticality = queryParams.Get("criticality")
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseDiagnosticReportSearchParams extracts and validates diagnostic report search parameters from HTTP request
+func ParseDiagnosticReportSearchParams(request *http.Request) (*models.DiagnosticReportSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.DiagnosticReportSearchParams{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	// Parse patient parameter, accepting "patient=123" and "patient=Patient/123"
+	if patientID := queryParams.Get("patient"); patientID != "" {
+		searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
 	}
 
 	// Parse category parameter
-	if category := queryParams.Get("category"); category != "" {
-		searchParams.Category = category
+	searchParams.Category = queryParams.Get("category")
+
+	// Parse code parameter
+	if code := queryParams.Get("code"); code != "" {
+		searchParams.Code = parseCodingCriterion(code)
 	}
 
-	// Parse status parameter
-	if status := queryParams.Get("status"); status != "" {
-		searchParams.Status = status
+	// Parse date parameter with prefixes
+	if date := queryParams.Get("date"); date != "" {
+		parsedDate, prefix := parseDateWithPrefix(date)
+		if parsedDate != nil {
+			switch prefix {
+			case "ge", "gt":
+				searchParams.DateGreaterThan = parsedDate
+			case "le", "lt":
+				searchParams.DateLessThan = parsedDate
+			}
+		}
+	}
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseImmunizationSearchParams extracts and validates immunization search parameters from HTTP request
+func ParseImmunizationSearchParams(request *http.Request) (*models.ImmunizationSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.ImmunizationSearchParams{
+		Limit:  10,
+		Offset: 0,
 	}
 
+	// Parse patient parameter, accepting "patient=123" and "patient=Patient/123"
+	if patientID := queryParams.Get("patient"); patientID != "" {
+		searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
+	}
+
+	// Parse vaccine-code parameter
+	if vaccineCode := queryParams.Get("vaccine-code"); vaccineCode != "" {
+		searchParams.VaccineCode = parseCodingCriterion(vaccineCode)
+	}
+
+	// Parse status and lot-number parameters
+	searchParams.Status = queryParams.Get("status")
+	searchParams.LotNumber = queryParams.Get("lot-number")
+
 	// Parse date parameter with prefixes
 	if date := queryParams.Get("date"); date != "" {
 		parsedDate, prefix := parseDateWithPrefix(date)
@@ -139,6 +526,171 @@ func ParseObservationSearchParams(request *http.Request) (*models.ObservationSea
 		}
 	}
 
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseAuditEventSearchParams extracts and validates audit event search parameters from HTTP request
+func ParseAuditEventSearchParams(request *http.Request) (*models.AuditEventSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.AuditEventSearchParams{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	// Parse agent, action, outcome, and entity type parameters
+	searchParams.AgentID = queryParams.Get("agent")
+	searchParams.Action = queryParams.Get("action")
+	searchParams.Outcome = queryParams.Get("outcome")
+	searchParams.EntityType = queryParams.Get("entity-type")
+
+	// Parse entity parameter, accepting "entity=Type/id" and a bare "entity=id"
+	if entity := queryParams.Get("entity"); entity != "" {
+		if entityType, entityID, typed := strings.Cut(entity, "/"); typed {
+			searchParams.EntityType = entityType
+			searchParams.EntityID = entityID
+		} else {
+			searchParams.EntityID = entity
+		}
+	}
+
+	// Parse date parameters with prefixes; a range is given as two parameters, date=ge...&date=le...
+	for _, date := range queryParams["date"] {
+		parsedDate, prefix := parseDateWithPrefix(date)
+		if parsedDate == nil {
+			continue
+		}
+		switch prefix {
+		case "ge", "gt":
+			searchParams.DateGreaterThan = parsedDate
+		case "le", "lt":
+			searchParams.DateLessThan = parsedDate
+		}
+	}
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseSubscriptionSearchParams extracts subscription search parameters from HTTP request
+func ParseSubscriptionSearchParams(request *http.Request) (*models.SubscriptionSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.SubscriptionSearchParams{
+		Limit:  10,
+		Offset: 0,
+	}
+
+	// Parse status parameter
+	searchParams.Status = queryParams.Get("status")
+
+	// Parse limit parameter
+	if limit := queryParams.Get("_count"); limit != "" {
+		if limitInt, parseError := strconv.Atoi(limit); parseError == nil && limitInt > 0 {
+			if limitInt > 100 {
+				limitInt = 100 // Maximum limit
+			}
+			searchParams.Limit = limitInt
+		}
+	}
+
+	// Parse offset parameter
+	if offset := queryParams.Get("_offset"); offset != "" {
+		if offsetInt, parseError := strconv.Atoi(offset); parseError == nil && offsetInt >= 0 {
+			searchParams.Offset = offsetInt
+		}
+	}
+
+	return searchParams, nil
+}
+
+// ParseObservationSearchParams extracts and validates observation search parameters from HTTP request
+func ParseObservationSearchParams(request *http.Request) (*models.ObservationSearchParams, error) {
+	queryParams := request.URL.Query()
+
+	searchParams := &models.ObservationSearchParams{
+		Limit:  10,  // Default limit
+		Offset: 0,   // Default offset
+	}
+
+	// Parse patient parameter
+	if patientID := queryParams.Get("patient"); patientID != "" {
+		// Handle both "patient=123" and "patient=Patient/123" formats
+		if strings.HasPrefix(patientID, "Patient/") {
+			searchParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
+		} else {
+			searchParams.PatientID = patientID
+		}
+	}
+
+	// Parse encounter parameter, accepting "encounter=123" and "encounter=Encounter/123"
+	if encounterID := queryParams.Get("encounter"); encounterID != "" {
+		searchParams.EncounterID = strings.TrimPrefix(encounterID, "Encounter/")
+	}
+
+	// Parse code, category, and status parameters; repeated parameters must all match and
+	// comma-separated values within one parameter match any
+	searchParams.Code = parseValueCriteria(queryParams["code"])
+	searchParams.Category = parseValueCriteria(queryParams["category"])
+	searchParams.Status = parseValueCriteria(queryParams["status"])
+
+	// Parse date parameters with prefixes; a range is given as two parameters, date=ge...&date=le...
+	for _, date := range queryParams["date"] {
+		parsedDate, prefix := parseDateWithPrefix(date)
+		if parsedDate == nil {
+			continue
+		}
+		switch prefix {
+		case "ge", "gt":
+			searchParams.DateGreaterThan = laterDate(searchParams.DateGreaterThan, parsedDate)
+		case "le", "lt":
+			searchParams.DateLessThan = earlierDate(searchParams.DateLessThan, parsedDate)
+		}
+	}
+
+	// Parse value-quantity parameters; repeats must all match, so value-quantity=ge5&value-quantity=lt10 is a range
+	valueQuantity, quantityError := parseQuantityCriteria(queryParams["value-quantity"])
+	if quantityError != nil {
+		return nil, quantityError
+	}
+	searchParams.ValueQuantity = valueQuantity
+
+	// Parse _tag parameters
+	searchParams.Tags = parseTagCriteria(queryParams["_tag"])
+
 	// Parse sort parameter
 	if sortBy := queryParams.Get("_sort"); sortBy != "" {
 		// Handle descending sort (prefix with -)
@@ -168,9 +720,219 @@ func ParseObservationSearchParams(request *http.Request) (*models.ObservationSea
 		}
 	}
 
+	// Parse _cursor, which continues a search sorted by created_at after the previous page
+	cursorPaging, after, cursorError := parsePageCursor(queryParams, searchParams.SortBy)
+	if cursorError != nil {
+		return nil, cursorError
+	}
+	if after != nil && !isObservationID(after.ID) {
+		return nil, errInvalidPageCursor
+	}
+	searchParams.CursorPaging = cursorPaging
+	searchParams.After = after
+
 	return searchParams, nil
 }
 
+// parseValueCriteria parses the values of a repeatable search parameter
+// Repeated parameters must all match; comma-separated values within one parameter match any
+func parseValueCriteria(parameterValues []string) models.ValueCriteria {
+	var criteria models.ValueCriteria
+	for _, parameterValue := range parameterValues {
+		var valueGroup []string
+		for _, value := range strings.Split(parameterValue, ",") {
+			if value != "" {
+				valueGroup = append(valueGroup, value)
+			}
+		}
+		if len(valueGroup) > 0 {
+			criteria = append(criteria, valueGroup)
+		}
+	}
+	return criteria
+}
+
+// quantityComparators lists the value-quantity prefixes understood by parseQuantityCriterion
+var quantityComparators = []string{"eq", "ne", "gt", "lt", "ge", "le", "ap"}
+
+// approximateFraction is how far either side of the value ap matches, as a fraction of the value
+const approximateFraction = 0.1
+
+// parseQuantityCriteria parses value-quantity values
+// Repeated parameters must all match; comma-separated values within one parameter match any
+func parseQuantityCriteria(parameterValues []string) (models.QuantityCriteria, error) {
+	var criteria models.QuantityCriteria
+	for _, parameterValue := range parameterValues {
+		var quantityGroup []models.QuantityCriterion
+		for _, token := range strings.Split(parameterValue, ",") {
+			if token == "" {
+				continue
+			}
+			criterion, parseError := parseQuantityCriterion(token)
+			if parseError != nil {
+				return nil, parseError
+			}
+			quantityGroup = append(quantityGroup, criterion)
+		}
+		if len(quantityGroup) > 0 {
+			criteria = append(criteria, quantityGroup)
+		}
+	}
+	return criteria, nil
+}
+
+// parseQuantityCriterion parses "[comparator]number", optionally followed by "|system|unit"
+// Only the unit is stored with an observation, so the system is accepted but not matched
+func parseQuantityCriterion(token string) (models.QuantityCriterion, error) {
+	criterion := models.QuantityCriterion{Comparator: "eq"}
+
+	number, unitPart, hasUnit := strings.Cut(token, "|")
+	if hasUnit {
+		_, unit, hasSystem := strings.Cut(unitPart, "|")
+		if !hasSystem {
+			return criterion, fmt.Errorf("value-quantity %q must be number|system|unit", token)
+		}
+		criterion.Unit = unit
+	}
+
+	if len(number) > 2 && slices.Contains(quantityComparators, number[:2]) {
+		criterion.Comparator = number[:2]
+		number = number[2:]
+	}
+	value, parseError := strconv.ParseFloat(number, 64)
+	if parseError != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return criterion, fmt.Errorf("value-quantity %q must be a number with an optional eq, ne, gt, lt, ge, le, or ap prefix", token)
+	}
+	criterion.Value = value
+
+	halfPrecision := implicitHalfPrecision(number)
+	criterion.Low = value - halfPrecision
+	criterion.High = value + halfPrecision
+	if criterion.Comparator == "ap" {
+		// ap is never narrower than eq, so ap0 still matches values that round to zero
+		approximateSpread := max(math.Abs(value)*approximateFraction, halfPrecision)
+		criterion.Comparator = "eq"
+		criterion.Low = value - approximateSpread
+		criterion.High = value + approximateSpread
+	}
+	return criterion, nil
+}
+
+// implicitHalfPrecision returns half the last significant place of a search number: 0.05 for 5.4 and
+// 0.5 for 140, so an equality search matches every value that rounds to it
+func implicitHalfPrecision(number string) float64 {
+	mantissa, exponent, _ := strings.Cut(strings.ToLower(number), "e")
+	exponentValue, _ := strconv.Atoi(exponent)
+	decimals := 0
+	if _, fraction, hasFraction := strings.Cut(mantissa, "."); hasFraction {
+		decimals = len(fraction)
+	}
+	return 0.5 * math.Pow(10, float64(exponentValue-decimals))
+}
+
+// laterDate returns the later of a lower bound already parsed and a new one, since both must hold
+func laterDate(current *time.Time, parsed *time.Time) *time.Time {
+	if current != nil && current.After(*parsed) {
+		return current
+	}
+	return parsed
+}
+
+// earlierDate returns the earlier of an upper bound already parsed and a new one, since both must hold
+func earlierDate(current *time.Time, parsed *time.Time) *time.Time {
+	if current != nil && current.Before(*parsed) {
+		return current
+	}
+	return parsed
+}
+
+// ParseObservationLastNParams extracts the parameters of Observation/$lastn from HTTP request
+// patient (or subject), code, and category may be repeated or comma-separated; max defaults to 1 observation per code
+func ParseObservationLastNParams(request *http.Request) (*models.ObservationLastNParams, error) {
+	queryParams := request.URL.Query()
+
+	lastNParams := &models.ObservationLastNParams{
+		Max: 1, // Default observations per code
+	}
+
+	// Parse patient parameter, accepting "patient=123", "patient=Patient/123", and the subject alias
+	patientID := queryParams.Get("patient")
+	if patientID == "" {
+		patientID = queryParams.Get("subject")
+	}
+	lastNParams.PatientID = strings.TrimPrefix(patientID, "Patient/")
+
+	// Parse code parameters; any of the codes matches
+	for _, codeValue := range queryParams["code"] {
+		for _, code := range strings.Split(codeValue, ",") {
+			if code != "" {
+				lastNParams.Codes = append(lastNParams.Codes, *parseCodingCriterion(code))
+			}
+		}
+	}
+
+	// Parse category parameters; any of the categories matches
+	for _, categoryValue := range queryParams["category"] {
+		for _, category := range strings.Split(categoryValue, ",") {
+			if category != "" {
+				lastNParams.Categories = append(lastNParams.Categories, category)
+			}
+		}
+	}
+
+	// Parse date parameters with prefixes; a range is given as two parameters, date=ge...&date=le...
+	for _, date := range queryParams["date"] {
+		parsedDate, prefix := parseDateWithPrefix(date)
+		if parsedDate == nil {
+			return nil, fmt.Errorf("invalid date %q", date)
+		}
+		switch prefix {
+		case "ge", "gt":
+			lastNParams.DateGreaterThan = parsedDate
+		case "le", "lt":
+			lastNParams.DateLessThan = parsedDate
+		}
+	}
+
+	// Parse max parameter
+	if maxValue := queryParams.Get("max"); maxValue != "" {
+		maxInt, parseError := strconv.Atoi(maxValue)
+		if parseError != nil || maxInt < 1 {
+			return nil, fmt.Errorf("max must be a positive integer, got %q", maxValue)
+		}
+		if maxInt > 100 {
+			maxInt = 100 // Maximum observations per code
+		}
+		lastNParams.Max = maxInt
+	}
+
+	return lastNParams, nil
+}
+
+// parseTagCriteria parses _tag token values: "system|code", "code" (any system), "|code" (no system),
+// or "system|" (any code)
+// Repeated parameters must all match; comma-separated values within one parameter match any
+func parseTagCriteria(tagValues []string) models.TagCriteria {
+	var criteria models.TagCriteria
+	for _, tagValue := range tagValues {
+		var tagGroup []models.TagCriterion
+		for _, token := range strings.Split(tagValue, ",") {
+			criterion := models.TagCriterion{Code: token, AnySystem: true}
+			if separatorIndex := strings.Index(token, "|"); separatorIndex >= 0 {
+				criterion = models.TagCriterion{System: token[:separatorIndex], Code: token[separatorIndex+1:]}
+			}
+			if criterion.System == "" && criterion.Code == "" {
+				continue
+			}
+			tagGroup = append(tagGroup, criterion)
+		}
+		if len(tagGroup) > 0 {
+			criteria = append(criteria, tagGroup)
+		}
+	}
+	return criteria
+}
+
 // parseDateWithPrefix extracts date prefix (ge, le, etc.) and parses the date
 func parseDateWithPrefix(dateString string) (*time.Time, string) {
 	prefix := ""
@@ -1,10 +1,15 @@
 package utils
 
 import (
+	"math"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
 	"testing"
 	"time"
+
+	"github.com/nathannewyen/fhir-health-interop/internal/models"
 )
 
 // TestParsePatientSearchParams_Name tests parsing the name parameter
@@ -17,8 +22,8 @@ func TestParsePatientSearchParams_Name(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", parseError)
 	}
 
-	if searchParams.Name != "Smith" {
-		t.Errorf("Expected name 'Smith', got '%s'", searchParams.Name)
+	if !reflect.DeepEqual(searchParams.Name, models.NameCriteria{{Values: []string{"Smith"}}}) {
+		t.Errorf("Expected name 'Smith', got %v", searchParams.Name)
 	}
 }
 
@@ -32,12 +37,12 @@ func TestParsePatientSearchParams_FamilyAndGiven(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", parseError)
 	}
 
-	if searchParams.FamilyName != "Doe" {
-		t.Errorf("Expected family name 'Doe', got '%s'", searchParams.FamilyName)
+	if !reflect.DeepEqual(searchParams.FamilyName, models.NameCriteria{{Values: []string{"Doe"}}}) {
+		t.Errorf("Expected family name 'Doe', got %v", searchParams.FamilyName)
 	}
 
-	if searchParams.GivenName != "John" {
-		t.Errorf("Expected given name 'John', got '%s'", searchParams.GivenName)
+	if !reflect.DeepEqual(searchParams.GivenName, models.NameCriteria{{Values: []string{"John"}}}) {
+		t.Errorf("Expected given name 'John', got %v", searchParams.GivenName)
 	}
 }
 
@@ -51,8 +56,8 @@ func TestParsePatientSearchParams_Gender(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", parseError)
 	}
 
-	if searchParams.Gender != "male" {
-		t.Errorf("Expected gender 'male', got '%s'", searchParams.Gender)
+	if !reflect.DeepEqual(searchParams.Gender, models.ValueCriteria{{"male"}}) {
+		t.Errorf("Expected gender 'male', got %v", searchParams.Gender)
 	}
 }
 
@@ -247,8 +252,8 @@ func TestParseObservationSearchParams_Code(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", parseError)
 	}
 
-	if searchParams.Code != "8480-6" {
-		t.Errorf("Expected code '8480-6', got '%s'", searchParams.Code)
+	if !reflect.DeepEqual(searchParams.Code, models.ValueCriteria{{"8480-6"}}) {
+		t.Errorf("Expected code '8480-6', got %v", searchParams.Code)
 	}
 }
 
@@ -262,8 +267,8 @@ func TestParseObservationSearchParams_Status(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", parseError)
 	}
 
-	if searchParams.Status != "final" {
-		t.Errorf("Expected status 'final', got '%s'", searchParams.Status)
+	if !reflect.DeepEqual(searchParams.Status, models.ValueCriteria{{"final"}}) {
+		t.Errorf("Expected status 'final', got %v", searchParams.Status)
 	}
 }
 
@@ -277,8 +282,8 @@ func TestParseObservationSearchParams_Category(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", parseError)
 	}
 
-	if searchParams.Category != "vital-signs" {
-		t.Errorf("Expected category 'vital-signs', got '%s'", searchParams.Category)
+	if !reflect.DeepEqual(searchParams.Category, models.ValueCriteria{{"vital-signs"}}) {
+		t.Errorf("Expected category 'vital-signs', got %v", searchParams.Category)
 	}
 }
 
@@ -316,15 +321,373 @@ func TestParseObservationSearchParams_MultipleFilters(t *testing.T) {
 		t.Errorf("Expected patient ID '123', got '%s'", searchParams.PatientID)
 	}
 
-	if searchParams.Code != "8480-6" {
-		t.Errorf("Expected code '8480-6', got '%s'", searchParams.Code)
+	if !reflect.DeepEqual(searchParams.Code, models.ValueCriteria{{"8480-6"}}) {
+		t.Errorf("Expected code '8480-6', got %v", searchParams.Code)
 	}
 
-	if searchParams.Status != "final" {
-		t.Errorf("Expected status 'final', got '%s'", searchParams.Status)
+	if !reflect.DeepEqual(searchParams.Status, models.ValueCriteria{{"final"}}) {
+		t.Errorf("Expected status 'final', got %v", searchParams.Status)
 	}
 
 	if searchParams.Limit != 25 {
 		t.Errorf("Expected limit 25, got %d", searchParams.Limit)
 	}
 }
+
+// TestParsePatientSearchParams_Tag tests parsing _tag tokens, with repeats ANDed and commas ORed
+func TestParsePatientSearchParams_Tag(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?_tag=http://example.org/workflow|needs-review&_tag=imported,http://example.org/source|,|local", nil)
+
+	searchParams, parseError := ParsePatientSearchParams(request)
+
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+
+	expectedCriteria := models.TagCriteria{
+		{{System: "http://example.org/workflow", Code: "needs-review"}},
+		{{Code: "imported", AnySystem: true}, {System: "http://example.org/source"}, {Code: "local"}},
+	}
+	if !reflect.DeepEqual(searchParams.Tags, expectedCriteria) {
+		t.Errorf("Expected tags %+v, got %+v", expectedCriteria, searchParams.Tags)
+	}
+}
+
+// TestParseObservationSearchParams_Tag tests that empty _tag values are ignored
+func TestParseObservationSearchParams_Tag(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?_tag=&_tag=needs-review", nil)
+
+	searchParams, parseError := ParseObservationSearchParams(request)
+
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+
+	if len(searchParams.Tags) != 1 || searchParams.Tags[0][0].Code != "needs-review" {
+		t.Errorf("Expected one needs-review tag group, got %+v", searchParams.Tags)
+	}
+}
+
+// TestParsePatientSearchParams_Identifier tests the identifier token forms
+func TestParsePatientSearchParams_Identifier(t *testing.T) {
+	testCases := map[string]*models.IdentifierCriterion{
+		"MRN|12345": {Systems: []string{"MRN"}, Value: "12345"},
+		"12345":     {Value: "12345"},
+		"|12345":    {Systems: []string{""}, Value: "12345"},
+		"MRN|":      {Systems: []string{"MRN"}},
+		"|":         nil,
+	}
+
+	for identifier, expectedCriterion := range testCases {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?identifier="+url.QueryEscape(identifier), nil)
+		searchParams, _ := ParsePatientSearchParams(request)
+		if !reflect.DeepEqual(searchParams.Identifier, expectedCriterion) {
+			t.Errorf("identifier=%s: expected %+v, got %+v", identifier, expectedCriterion, searchParams.Identifier)
+		}
+	}
+}
+
+// TestParsePractitionerSearchParams_Specialty verifies specialty tokens with and without a system
+func TestParsePractitionerSearchParams_Specialty(t *testing.T) {
+	testCases := map[string]*models.CodingCriterion{
+		"http://nucc.org/provider-taxonomy|207RI0200X": {System: "http://nucc.org/provider-taxonomy", Code: "207RI0200X"},
+		"207RI0200X":                         {Code: "207RI0200X", AnySystem: true},
+		"|207RI0200X":                        {Code: "207RI0200X"},
+		"http://nucc.org/provider-taxonomy|": {System: "http://nucc.org/provider-taxonomy"},
+		"|":                                  nil,
+	}
+
+	for specialty, expectedCriterion := range testCases {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/Practitioner?specialty="+url.QueryEscape(specialty), nil)
+		searchParams, _ := ParsePractitionerSearchParams(request)
+		if !reflect.DeepEqual(searchParams.Specialty, expectedCriterion) {
+			t.Errorf("specialty=%s: expected %+v, got %+v", specialty, expectedCriterion, searchParams.Specialty)
+		}
+	}
+}
+
+// TestParsePractitionerSearchParams_NameAndIdentifier verifies name and identifier filters and the page size
+func TestParsePractitionerSearchParams_NameAndIdentifier(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Practitioner?family=House&identifier=http://hl7.org/fhir/sid/us-npi|1234567893&_count=500", nil)
+	searchParams, _ := ParsePractitionerSearchParams(request)
+
+	if searchParams.FamilyName != "House" || searchParams.Name != "" {
+		t.Errorf("Expected family House only, got %+v", searchParams)
+	}
+	expectedIdentifier := &models.IdentifierCriterion{Systems: []string{"http://hl7.org/fhir/sid/us-npi"}, Value: "1234567893"}
+	if !reflect.DeepEqual(searchParams.Identifier, expectedIdentifier) {
+		t.Errorf("Expected %+v, got %+v", expectedIdentifier, searchParams.Identifier)
+	}
+	if searchParams.Limit != 100 {
+		t.Errorf("Expected _count capped at 100, got %d", searchParams.Limit)
+	}
+}
+
+// TestParseEncounterSearchParams verifies the patient reference, status, and date prefix are read
+func TestParseEncounterSearchParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Encounter?patient=Patient/patient-1&status=finished&date=ge2024-03-01", nil)
+	searchParams, _ := ParseEncounterSearchParams(request)
+
+	if searchParams.PatientID != "patient-1" || searchParams.Status != "finished" {
+		t.Errorf("Expected patient-1 with status finished, got %+v", searchParams)
+	}
+	if searchParams.DateGreaterThan == nil || searchParams.DateGreaterThan.Format("2006-01-02") != "2024-03-01" || searchParams.DateLessThan != nil {
+		t.Errorf("Expected only a lower date bound of 2024-03-01, got %v to %v", searchParams.DateGreaterThan, searchParams.DateLessThan)
+	}
+	if searchParams.Limit != 10 {
+		t.Errorf("Expected the default page size, got %d", searchParams.Limit)
+	}
+}
+
+// TestParseObservationSearchParams_Encounter verifies both encounter reference forms are read
+func TestParseObservationSearchParams_Encounter(t *testing.T) {
+	for _, encounter := range []string{"encounter-1", "Encounter/encounter-1"} {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?encounter="+url.QueryEscape(encounter), nil)
+		searchParams, _ := ParseObservationSearchParams(request)
+		if searchParams.EncounterID != "encounter-1" {
+			t.Errorf("encounter=%s: expected encounter-1, got %q", encounter, searchParams.EncounterID)
+		}
+	}
+}
+
+// TestParseConditionSearchParams verifies the patient reference, code token, clinical status, and onset prefix are read
+func TestParseConditionSearchParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Condition?patient=Patient/patient-1&code="+url.QueryEscape("http://snomed.info/sct|44054006")+"&clinical-status=active&onset-date=le2020-01-01", nil)
+	searchParams, _ := ParseConditionSearchParams(request)
+
+	if searchParams.PatientID != "patient-1" || searchParams.ClinicalStatus != "active" {
+		t.Errorf("Expected active conditions of patient-1, got %+v", searchParams)
+	}
+	expectedCode := &models.CodingCriterion{System: "http://snomed.info/sct", Code: "44054006"}
+	if !reflect.DeepEqual(searchParams.Code, expectedCode) {
+		t.Errorf("Expected %+v, got %+v", expectedCode, searchParams.Code)
+	}
+	if searchParams.OnsetDateLessThan == nil || searchParams.OnsetDateLessThan.Format("2006-01-02") != "2020-01-01" || searchParams.OnsetDateGreaterThan != nil {
+		t.Errorf("Expected only an upper onset bound of 2020-01-01, got %v to %v", searchParams.OnsetDateGreaterThan, searchParams.OnsetDateLessThan)
+	}
+}
+
+// TestParseMedicationRequestSearchParams verifies patient, status, intent, and authoredon are parsed
+func TestParseMedicationRequestSearchParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/MedicationRequest?patient=Patient/patient-1&status=active&intent=order&authoredon=ge2024-01-01&_count=500", nil)
+	searchParams, _ := ParseMedicationRequestSearchParams(request)
+
+	if searchParams.PatientID != "patient-1" || searchParams.Status != "active" || searchParams.Intent != "order" {
+		t.Errorf("Expected active orders of patient-1, got %+v", searchParams)
+	}
+	if searchParams.AuthoredOnGreaterThan == nil || searchParams.AuthoredOnGreaterThan.Format("2006-01-02") != "2024-01-01" || searchParams.AuthoredOnLessThan != nil {
+		t.Errorf("Expected only a lower authoredon bound of 2024-01-01, got %v to %v", searchParams.AuthoredOnGreaterThan, searchParams.AuthoredOnLessThan)
+	}
+	if searchParams.Limit != 100 {
+		t.Errorf("Expected _count capped at 100, got %d", searchParams.Limit)
+	}
+}
+
+// TestParseAllergyIntoleranceSearchParams verifies patient, clinical-status, category, and criticality are parsed
+func TestParseAllergyIntoleranceSearchParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/AllergyIntolerance?patient=Patient/patient-1&clinical-status=active&category=medication&criticality=high&_offset=20", nil)
+	searchParams, _ := ParseAllergyIntoleranceSearchParams(request)
+
+	if searchParams.PatientID != "patient-1" || searchParams.ClinicalStatus != "active" {
+		t.Errorf("Expected active allergies of patient-1, got %+v", searchParams)
+	}
+	if searchParams.Category != "medication" || searchParams.Criticality != "high" || searchParams.Offset != 20 {
+		t.Errorf("Expected high-criticality medication allergies from offset 20, got %+v", searchParams)
+	}
+}
+
+// TestParseDiagnosticReportSearchParams verifies patient, category, code, and date are parsed
+func TestParseDiagnosticReportSearchParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/DiagnosticReport?patient=Patient/patient-1&category=LAB&code=http://loinc.org|58410-2&date=le2024-06-30", nil)
+	searchParams, _ := ParseDiagnosticReportSearchParams(request)
+
+	if searchParams.PatientID != "patient-1" || searchParams.Category != "LAB" {
+		t.Errorf("Expected lab reports of patient-1, got %+v", searchParams)
+	}
+	if searchParams.Code == nil || searchParams.Code.System != "http://loinc.org" || searchParams.Code.Code != "58410-2" {
+		t.Errorf("Expected the LOINC code criterion, got %+v", searchParams.Code)
+	}
+	if searchParams.DateLessThan == nil || searchParams.DateGreaterThan != nil {
+		t.Errorf("Expected only an upper date bound, got %+v", searchParams)
+	}
+}
+
+// TestParseImmunizationSearchParams verifies patient, vaccine-code, status, lot-number, and date are parsed
+func TestParseImmunizationSearchParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Immunization?patient=patient-1&vaccine-code=http://hl7.org/fhir/sid/cvx|208&status=completed&lot-number=EW0182&date=ge2021-01-01", nil)
+	searchParams, _ := ParseImmunizationSearchParams(request)
+
+	if searchParams.PatientID != "patient-1" || searchParams.Status != "completed" || searchParams.LotNumber != "EW0182" {
+		t.Errorf("Expected completed immunizations of patient-1 from lot EW0182, got %+v", searchParams)
+	}
+	if searchParams.VaccineCode == nil || searchParams.VaccineCode.System != "http://hl7.org/fhir/sid/cvx" || searchParams.VaccineCode.Code != "208" {
+		t.Errorf("Expected the CVX vaccine code criterion, got %+v", searchParams.VaccineCode)
+	}
+	if searchParams.DateGreaterThan == nil || searchParams.DateLessThan != nil {
+		t.Errorf("Expected only a lower date bound, got %+v", searchParams)
+	}
+}
+
+// TestParseAuditEventSearchParams verifies agent, entity, action, outcome, and a date range are parsed
+func TestParseAuditEventSearchParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/AuditEvent?agent=oauth:growth-chart&entity=Patient/p-1&action=R&outcome=4&date=ge2024-01-01&date=le2024-02-01", nil)
+	searchParams, _ := ParseAuditEventSearchParams(request)
+
+	if searchParams.AgentID != "oauth:growth-chart" || searchParams.EntityType != "Patient" || searchParams.EntityID != "p-1" {
+		t.Errorf("Expected the client's accesses to patient p-1, got %+v", searchParams)
+	}
+	if searchParams.Action != "R" || searchParams.Outcome != "4" {
+		t.Errorf("Expected failed reads, got %+v", searchParams)
+	}
+	if searchParams.DateGreaterThan == nil || searchParams.DateLessThan == nil {
+		t.Errorf("Expected both date bounds, got %+v", searchParams)
+	}
+}
+
+// TestParseObservationLastNParams verifies the subject, repeated and comma-separated codes, categories, and max are parsed
+func TestParseObservationLastNParams(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation/$lastn?subject=Patient/p-1&code="+url.QueryEscape("http://loinc.org|8480-6,8462-4")+"&code=2093-3&category=vital-signs&max=3", nil)
+	lastNParams, parseError := ParseObservationLastNParams(request)
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+
+	if lastNParams.PatientID != "p-1" || lastNParams.Max != 3 || !reflect.DeepEqual(lastNParams.Categories, []string{"vital-signs"}) {
+		t.Errorf("Expected the 3 newest vital signs of p-1, got %+v", lastNParams)
+	}
+	expectedCodes := []models.CodingCriterion{
+		{System: "http://loinc.org", Code: "8480-6"},
+		{Code: "8462-4", AnySystem: true},
+		{Code: "2093-3", AnySystem: true},
+	}
+	if !reflect.DeepEqual(lastNParams.Codes, expectedCodes) {
+		t.Errorf("Expected %+v, got %+v", expectedCodes, lastNParams.Codes)
+	}
+}
+
+// TestParseObservationLastNParams_Max verifies max defaults to 1 and must be a positive integer
+func TestParseObservationLastNParams_Max(t *testing.T) {
+	lastNParams, _ := ParseObservationLastNParams(httptest.NewRequest(http.MethodGet, "/fhir/Observation/$lastn?patient=p-1", nil))
+	if lastNParams.Max != 1 {
+		t.Errorf("Expected max to default to 1, got %d", lastNParams.Max)
+	}
+
+	for _, maxValue := range []string{"0", "-2", "many"} {
+		if _, parseError := ParseObservationLastNParams(httptest.NewRequest(http.MethodGet, "/fhir/Observation/$lastn?patient=p-1&max="+maxValue, nil)); parseError == nil {
+			t.Errorf("max=%s: expected an error", maxValue)
+		}
+	}
+}
+
+// TestParseObservationSearchParams_MultipleValues verifies comma-separated values form one group and repeated parameters separate groups
+func TestParseObservationSearchParams_MultipleValues(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?status=final,amended&code=8480-6&code=8462-4,&date=ge2024-01-01&date=ge2024-03-01&date=le2024-06-30", nil)
+
+	searchParams, parseError := ParseObservationSearchParams(request)
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+
+	if !reflect.DeepEqual(searchParams.Status, models.ValueCriteria{{"final", "amended"}}) {
+		t.Errorf("Expected one status group of final or amended, got %v", searchParams.Status)
+	}
+	if !reflect.DeepEqual(searchParams.Code, models.ValueCriteria{{"8480-6"}, {"8462-4"}}) {
+		t.Errorf("Expected two code groups, got %v", searchParams.Code)
+	}
+	if searchParams.Category != nil {
+		t.Errorf("Expected no category criteria, got %v", searchParams.Category)
+	}
+
+	// Both lower bounds must hold, so the later one applies
+	expectedStart := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	if searchParams.DateGreaterThan == nil || !searchParams.DateGreaterThan.Equal(expectedStart) {
+		t.Errorf("Expected date >= %v, got %v", expectedStart, searchParams.DateGreaterThan)
+	}
+	expectedEnd := time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC)
+	if searchParams.DateLessThan == nil || !searchParams.DateLessThan.Equal(expectedEnd) {
+		t.Errorf("Expected date <= %v, got %v", expectedEnd, searchParams.DateLessThan)
+	}
+}
+
+// TestParsePatientSearchParams_MultipleValues verifies repeated and comma-separated patient parameters
+func TestParsePatientSearchParams_MultipleValues(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?gender=male,female&family=Smith&family=Jones&birthdate=le2000-01-01&birthdate=le1990-01-01", nil)
+
+	searchParams, _ := ParsePatientSearchParams(request)
+
+	if !reflect.DeepEqual(searchParams.Gender, models.ValueCriteria{{"male", "female"}}) {
+		t.Errorf("Expected one gender group, got %v", searchParams.Gender)
+	}
+	if !reflect.DeepEqual(searchParams.FamilyName, models.NameCriteria{{Values: []string{"Smith"}}, {Values: []string{"Jones"}}}) {
+		t.Errorf("Expected two family groups, got %v", searchParams.FamilyName)
+	}
+	expectedEnd := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	if searchParams.BirthDateLessThan == nil || !searchParams.BirthDateLessThan.Equal(expectedEnd) {
+		t.Errorf("Expected birthdate <= %v, got %v", expectedEnd, searchParams.BirthDateLessThan)
+	}
+}
+
+// TestParseObservationSearchParams_ValueQuantity verifies comparators, implicit precision, and units
+func TestParseObservationSearchParams_ValueQuantity(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?value-quantity=gt140&value-quantity=le5.4|http://unitsofmeasure.org|mmol/L,5.4||mmol/L,ap100", nil)
+
+	searchParams, parseError := ParseObservationSearchParams(request)
+	if parseError != nil {
+		t.Fatalf("Expected no error, got %v", parseError)
+	}
+	if len(searchParams.ValueQuantity) != 2 || len(searchParams.ValueQuantity[1]) != 3 {
+		t.Fatalf("Expected a group of one and a group of three, got %+v", searchParams.ValueQuantity)
+	}
+
+	greaterThan := searchParams.ValueQuantity[0][0]
+	if greaterThan.Comparator != "gt" || greaterThan.Value != 140 || greaterThan.Unit != "" {
+		t.Errorf("Expected gt 140 in any unit, got %+v", greaterThan)
+	}
+	lessOrEqual := searchParams.ValueQuantity[1][0]
+	if lessOrEqual.Comparator != "le" || lessOrEqual.Value != 5.4 || lessOrEqual.Unit != "mmol/L" {
+		t.Errorf("Expected le 5.4 mmol/L, got %+v", lessOrEqual)
+	}
+
+	// A value given to one decimal place matches everything that rounds to it
+	equal := searchParams.ValueQuantity[1][1]
+	if equal.Comparator != "eq" || math.Abs(equal.Low-5.35) > 1e-9 || math.Abs(equal.High-5.45) > 1e-9 || equal.Unit != "mmol/L" {
+		t.Errorf("Expected eq 5.35 to 5.45 mmol/L, got %+v", equal)
+	}
+	approximate := searchParams.ValueQuantity[1][2]
+	if approximate.Comparator != "eq" || approximate.Low != 90 || approximate.High != 110 {
+		t.Errorf("Expected ap100 to match 90 to 110, got %+v", approximate)
+	}
+}
+
+// TestParseObservationSearchParams_InvalidValueQuantity verifies malformed values are rejected
+func TestParseObservationSearchParams_InvalidValueQuantity(t *testing.T) {
+	for _, valueQuantity := range []string{"high", "sa5", "5|mmol/L", "NaN"} {
+		request := httptest.NewRequest(http.MethodGet, "/fhir/Observation?value-quantity="+url.QueryEscape(valueQuantity), nil)
+		if _, parseError := ParseObservationSearchParams(request); parseError == nil {
+			t.Errorf("value-quantity=%s: expected an error", valueQuantity)
+		}
+	}
+}
+
+// TestParsePatientSearchParams_NameModifiers verifies :contains and :exact are kept with their values
+func TestParsePatientSearchParams_NameModifiers(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/fhir/Patient?name:exact=Li,Lee&name=smi&name:contains=ith&given:exact=Jane&family:missing=true", nil)
+
+	searchParams, _ := ParsePatientSearchParams(request)
+
+	expectedName := models.NameCriteria{
+		{Values: []string{"smi"}},
+		{Values: []string{"ith"}, Match: models.NameMatchContains},
+		{Values: []string{"Li", "Lee"}, Match: models.NameMatchExact},
+	}
+	if !reflect.DeepEqual(searchParams.Name, expectedName) {
+		t.Errorf("Expected %+v, got %+v", expectedName, searchParams.Name)
+	}
+	expectedGiven := models.NameCriteria{{Values: []string{"Jane"}, Match: models.NameMatchExact}}
+	if !reflect.DeepEqual(searchParams.GivenName, expectedGiven) {
+		t.Errorf("Expected %+v, got %+v", expectedGiven, searchParams.GivenName)
+	}
+	if searchParams.FamilyName != nil {
+		t.Errorf("Expected unsupported modifiers to be ignored, got %+v", searchParams.FamilyName)
+	}
+}
@@ -0,0 +1,109 @@
+package warmup
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StepFunc performs one warm-up action
+type StepFunc func(ctx context.Context) error
+
+// step is a named warm-up action
+type step struct {
+	name string
+	run  StepFunc
+}
+
+// StepResult records how one warm-up step went
+type StepResult struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Status is the warm-up progress reported by the readiness endpoint
+type Status struct {
+	Ready      bool         `json:"ready"`
+	StartedAt  *time.Time   `json:"started_at,omitempty"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+	Steps      []StepResult `json:"steps"`
+}
+
+// Warmer runs warm-up steps once at startup and reports readiness when they finish
+// A failed step is logged and recorded but does not hold readiness back: warm-up only
+// removes cold-start latency, and connectivity is already verified before the server starts
+type Warmer struct {
+	steps []step
+
+	mutex  sync.Mutex
+	status Status
+}
+
+// NewWarmer creates a warmer with no steps
+func NewWarmer() *Warmer {
+	return &Warmer{
+		status: Status{Steps: []StepResult{}},
+	}
+}
+
+// Add registers a step; steps run in the order they were added
+func (warmer *Warmer) Add(name string, run StepFunc) {
+	warmer.steps = append(warmer.steps, step{name: name, run: run})
+}
+
+// Run executes every step, bounded by timeout overall, then marks the warmer ready
+func (warmer *Warmer) Run(ctx context.Context, timeout time.Duration) Status {
+	warmupContext, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	startedAt := time.Now()
+	warmer.mutex.Lock()
+	warmer.status.StartedAt = &startedAt
+	warmer.mutex.Unlock()
+
+	for _, warmupStep := range warmer.steps {
+		stepStart := time.Now()
+		stepError := warmupStep.run(warmupContext)
+		result := StepResult{Name: warmupStep.name, Duration: time.Since(stepStart)}
+
+		if stepError != nil {
+			result.Error = stepError.Error()
+			log.Warn().Err(stepError).Str("step", warmupStep.name).Dur("duration", result.Duration).Msg("Warm-up step failed")
+		} else {
+			log.Info().Str("step", warmupStep.name).Dur("duration", result.Duration).Msg("Warm-up step finished")
+		}
+
+		warmer.mutex.Lock()
+		warmer.status.Steps = append(warmer.status.Steps, result)
+		warmer.mutex.Unlock()
+	}
+
+	finishedAt := time.Now()
+	warmer.mutex.Lock()
+	warmer.status.FinishedAt = &finishedAt
+	warmer.status.Ready = true
+	warmer.mutex.Unlock()
+
+	log.Info().Dur("duration", finishedAt.Sub(startedAt)).Msg("Warm-up complete, ready for traffic")
+	return warmer.Status()
+}
+
+// Ready reports whether warm-up has finished
+func (warmer *Warmer) Ready() bool {
+	warmer.mutex.Lock()
+	defer warmer.mutex.Unlock()
+	return warmer.status.Ready
+}
+
+// Status returns a copy of the current warm-up progress
+func (warmer *Warmer) Status() Status {
+	warmer.mutex.Lock()
+	defer warmer.mutex.Unlock()
+
+	statusCopy := warmer.status
+	statusCopy.Steps = append([]StepResult{}, warmer.status.Steps...)
+	return statusCopy
+}
@@ -0,0 +1,55 @@
+package warmup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestWarmer_RunsStepsInOrder verifies steps run in order and readiness flips afterwards
+func TestWarmer_RunsStepsInOrder(t *testing.T) {
+	warmer := NewWarmer()
+	order := []string{}
+	warmer.Add("first", func(ctx context.Context) error {
+		if warmer.Ready() {
+			t.Error("Expected not ready while steps run")
+		}
+		order = append(order, "first")
+		return nil
+	})
+	warmer.Add("second", func(ctx context.Context) error {
+		order = append(order, "second")
+		return errors.New("pool exhausted")
+	})
+
+	status := warmer.Run(context.Background(), time.Second)
+
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("Unexpected step order: %v", order)
+	}
+	if !status.Ready || !warmer.Ready() {
+		t.Error("Expected ready after warm-up even when a step fails")
+	}
+	if status.Steps[0].Error != "" || status.Steps[1].Error != "pool exhausted" {
+		t.Errorf("Unexpected step results: %+v", status.Steps)
+	}
+	if status.StartedAt == nil || status.FinishedAt == nil {
+		t.Error("Expected start and finish times")
+	}
+}
+
+// TestWarmer_Timeout verifies steps receive a context bounded by the timeout
+func TestWarmer_Timeout(t *testing.T) {
+	warmer := NewWarmer()
+	warmer.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	status := warmer.Run(context.Background(), 10*time.Millisecond)
+
+	if !status.Ready || status.Steps[0].Error != context.DeadlineExceeded.Error() {
+		t.Errorf("Expected timed-out step, got %+v", status.Steps)
+	}
+}
@@ -0,0 +1,288 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is appended to the client's key to compute Sec-WebSocket-Accept (RFC 6455 section 1.3)
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes of the frames a server must understand (RFC 6455 section 5.2)
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+// Close codes sent when the server ends a connection (RFC 6455 section 7.4.1)
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+)
+
+// maxControlPayload is the largest payload a ping, pong, or close frame may carry
+const maxControlPayload = 125
+
+// ErrNotWebSocket is returned when a request is not a WebSocket upgrade
+var ErrNotWebSocket = errors.New("request is not a WebSocket upgrade")
+
+// ErrMessageTooBig is returned when a client message exceeds the connection's read limit
+var ErrMessageTooBig = errors.New("websocket message exceeds the read limit")
+
+// CloseError is returned by ReadMessage when the client closed the connection
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+// Error describes the close code and reason the client sent
+func (closeError *CloseError) Error() string {
+	return fmt.Sprintf("websocket closed by client: %d %s", closeError.Code, closeError.Reason)
+}
+
+// IsUpgradeRequest reports whether the request asks to switch to the WebSocket protocol
+func IsUpgradeRequest(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") && headerContainsToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrade completes the opening handshake and takes over the request's connection
+// Requests that are not valid upgrades return ErrNotWebSocket before anything is written, so callers can
+// answer them with a normal error response
+func Upgrade(w http.ResponseWriter, r *http.Request, readLimit int64) (*Conn, error) {
+	clientKey := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !IsUpgradeRequest(r) || r.Header.Get("Sec-WebSocket-Version") != "13" || clientKey == "" {
+		return nil, ErrNotWebSocket
+	}
+
+	netConn, readWriter, hijackError := http.NewResponseController(w).Hijack()
+	if hijackError != nil {
+		return nil, hijackError
+	}
+	// The server's read and write deadlines no longer apply once the connection is ours
+	netConn.SetDeadline(time.Time{})
+
+	handshake := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(clientKey) + "\r\n\r\n"
+	if _, writeError := readWriter.WriteString(handshake); writeError != nil {
+		netConn.Close()
+		return nil, writeError
+	}
+	if flushError := readWriter.Flush(); flushError != nil {
+		netConn.Close()
+		return nil, flushError
+	}
+
+	return &Conn{netConn: netConn, reader: readWriter.Reader, readLimit: readLimit}, nil
+}
+
+// Conn is the server side of a WebSocket connection
+// One goroutine may read while any number write; writes are serialized
+type Conn struct {
+	netConn   net.Conn
+	reader    *bufio.Reader
+	readLimit int64
+
+	writeMutex sync.Mutex
+	closeOnce  sync.Once
+}
+
+// ReadMessage returns the next text or binary message, answering pings and reassembling fragments on the way
+// It returns a *CloseError once the client closes the connection
+func (conn *Conn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+
+	for {
+		final, opcode, payload, readError := conn.readFrame()
+		if readError != nil {
+			if errors.Is(readError, ErrMessageTooBig) {
+				conn.CloseWithReason(CloseMessageTooBig, "message too big")
+			}
+			return 0, nil, readError
+		}
+
+		switch opcode {
+		case opPing:
+			if pongError := conn.writeFrame(opPong, payload, time.Now().Add(10*time.Second)); pongError != nil {
+				return 0, nil, pongError
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			closeError := &CloseError{Code: CloseNormal}
+			if len(payload) >= 2 {
+				closeError.Code = int(binary.BigEndian.Uint16(payload))
+				closeError.Reason = string(payload[2:])
+			}
+			conn.CloseWithReason(CloseNormal, "")
+			return 0, nil, closeError
+		case opText, opBinary:
+			if messageType != 0 {
+				conn.CloseWithReason(CloseProtocolError, "expected a continuation frame")
+				return 0, nil, errors.New("websocket message started before the previous one finished")
+			}
+			messageType = int(opcode)
+		case opContinuation:
+			if messageType == 0 {
+				conn.CloseWithReason(CloseProtocolError, "unexpected continuation frame")
+				return 0, nil, errors.New("websocket continuation frame without a message")
+			}
+		default:
+			conn.CloseWithReason(CloseProtocolError, "unknown opcode")
+			return 0, nil, fmt.Errorf("websocket frame with unknown opcode %d", opcode)
+		}
+
+		if conn.readLimit > 0 && int64(len(message)+len(payload)) > conn.readLimit {
+			conn.CloseWithReason(CloseMessageTooBig, "message too big")
+			return 0, nil, ErrMessageTooBig
+		}
+		message = append(message, payload...)
+		if final {
+			return messageType, message, nil
+		}
+	}
+}
+
+// WriteText sends one text message, failing if the client does not take it before the deadline
+func (conn *Conn) WriteText(message []byte, deadline time.Time) error {
+	return conn.writeFrame(opText, message, deadline)
+}
+
+// CloseWithReason sends a close frame with the code and reason, then closes the connection
+// Only the first call has any effect
+func (conn *Conn) CloseWithReason(code int, reason string) {
+	conn.closeOnce.Do(func() {
+		payload := make([]byte, 2, 2+len(reason))
+		binary.BigEndian.PutUint16(payload, uint16(code))
+		if len(reason) > maxControlPayload-2 {
+			reason = reason[:maxControlPayload-2]
+		}
+		payload = append(payload, reason...)
+		conn.writeFrame(opClose, payload, time.Now().Add(time.Second))
+		conn.netConn.Close()
+	})
+}
+
+// Close closes the connection with a normal close frame
+func (conn *Conn) Close() {
+	conn.CloseWithReason(CloseNormal, "")
+}
+
+// readFrame reads one frame and unmasks its payload; clients must mask every frame (RFC 6455 section 5.1)
+func (conn *Conn) readFrame() (bool, byte, []byte, error) {
+	var header [2]byte
+	if _, readError := io.ReadFull(conn.reader, header[:]); readError != nil {
+		return false, 0, nil, readError
+	}
+	final := header[0]&0x80 != 0
+	opcode := header[0] & 0x0F
+	if header[0]&0x70 != 0 {
+		conn.CloseWithReason(CloseProtocolError, "reserved bits set")
+		return false, 0, nil, errors.New("websocket frame uses reserved bits")
+	}
+	if header[1]&0x80 == 0 {
+		conn.CloseWithReason(CloseProtocolError, "client frames must be masked")
+		return false, 0, nil, errors.New("websocket client frame is not masked")
+	}
+
+	payloadLength := uint64(header[1] & 0x7F)
+	switch payloadLength {
+	case 126:
+		var extended [2]byte
+		if _, readError := io.ReadFull(conn.reader, extended[:]); readError != nil {
+			return false, 0, nil, readError
+		}
+		payloadLength = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		if _, readError := io.ReadFull(conn.reader, extended[:]); readError != nil {
+			return false, 0, nil, readError
+		}
+		payloadLength = binary.BigEndian.Uint64(extended[:])
+	}
+
+	isControl := opcode&0x8 != 0
+	if isControl && (!final || payloadLength > maxControlPayload) {
+		conn.CloseWithReason(CloseProtocolError, "invalid control frame")
+		return false, 0, nil, errors.New("websocket control frame is fragmented or too long")
+	}
+	// Refuse oversized frames before allocating their payload
+	if conn.readLimit > 0 && payloadLength > uint64(conn.readLimit) {
+		return false, 0, nil, ErrMessageTooBig
+	}
+
+	var maskKey [4]byte
+	if _, readError := io.ReadFull(conn.reader, maskKey[:]); readError != nil {
+		return false, 0, nil, readError
+	}
+	payload := make([]byte, payloadLength)
+	if _, readError := io.ReadFull(conn.reader, payload); readError != nil {
+		return false, 0, nil, readError
+	}
+	for index := range payload {
+		payload[index] ^= maskKey[index%4]
+	}
+	return final, opcode, payload, nil
+}
+
+// writeFrame sends one unmasked, unfragmented frame
+func (conn *Conn) writeFrame(opcode byte, payload []byte, deadline time.Time) error {
+	frame := make([]byte, 0, len(payload)+10)
+	frame = append(frame, 0x80|opcode)
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+	frame = append(frame, payload...)
+
+	conn.writeMutex.Lock()
+	defer conn.writeMutex.Unlock()
+	conn.netConn.SetWriteDeadline(deadline)
+	_, writeError := conn.netConn.Write(frame)
+	return writeError
+}
+
+// acceptKey computes the Sec-WebSocket-Accept value for a client's Sec-WebSocket-Key
+func acceptKey(clientKey string) string {
+	digest := sha1.Sum([]byte(clientKey + acceptGUID))
+	return base64.StdEncoding.EncodeToString(digest[:])
+}
+
+// headerContainsToken reports whether a comma-separated header lists the token, ignoring case
+func headerContainsToken(header http.Header, name string, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, element := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(element), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
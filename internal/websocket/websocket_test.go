@@ -0,0 +1,134 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testClient is the client side of a connection, masking its frames as browsers do
+type testClient struct {
+	netConn net.Conn
+	reader  *bufio.Reader
+}
+
+// dialTestServer opens a WebSocket connection to a test server
+func dialTestServer(t *testing.T, server *httptest.Server) *testClient {
+	netConn, dialError := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if dialError != nil {
+		t.Fatalf("Failed to dial the test server: %v", dialError)
+	}
+	handshake := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	netConn.Write([]byte(handshake))
+
+	reader := bufio.NewReader(netConn)
+	response, readError := http.ReadResponse(reader, nil)
+	if readError != nil || response.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("Expected 101 Switching Protocols, got %v (%v)", response, readError)
+	}
+	// The accept value for this key is given in RFC 6455 section 1.3
+	if response.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("Unexpected Sec-WebSocket-Accept %q", response.Header.Get("Sec-WebSocket-Accept"))
+	}
+	return &testClient{netConn: netConn, reader: reader}
+}
+
+// writeFrame sends one masked frame
+func (client *testClient) writeFrame(final bool, opcode byte, payload []byte) {
+	firstByte := opcode
+	if final {
+		firstByte |= 0x80
+	}
+	frame := []byte{firstByte, 0x80 | byte(len(payload))}
+	maskKey := []byte{1, 2, 3, 4}
+	frame = append(frame, maskKey...)
+	for index, payloadByte := range payload {
+		frame = append(frame, payloadByte^maskKey[index%4])
+	}
+	client.netConn.Write(frame)
+}
+
+// readFrame reads one unmasked server frame
+func (client *testClient) readFrame(t *testing.T) (byte, []byte) {
+	client.netConn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var header [2]byte
+	if _, readError := io.ReadFull(client.reader, header[:]); readError != nil {
+		t.Fatalf("Failed to read a frame: %v", readError)
+	}
+	payloadLength := int(header[1] & 0x7F)
+	if payloadLength == 126 {
+		var extended [2]byte
+		io.ReadFull(client.reader, extended[:])
+		payloadLength = int(binary.BigEndian.Uint16(extended[:]))
+	}
+	payload := make([]byte, payloadLength)
+	io.ReadFull(client.reader, payload)
+	return header[0] & 0x0F, payload
+}
+
+// TestConn_Echo verifies fragmented messages are reassembled, pings answered, and close frames returned
+func TestConn_Echo(t *testing.T) {
+	readErrors := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, upgradeError := Upgrade(w, r, 16)
+		if upgradeError != nil {
+			readErrors <- upgradeError
+			return
+		}
+		for {
+			_, message, readError := conn.ReadMessage()
+			if readError != nil {
+				readErrors <- readError
+				return
+			}
+			conn.WriteText(message, time.Now().Add(time.Second))
+		}
+	}))
+	defer server.Close()
+
+	client := dialTestServer(t, server)
+	client.writeFrame(false, opText, []byte("hel"))
+	client.writeFrame(true, opPing, []byte("are you there"))
+	client.writeFrame(true, opContinuation, []byte("lo"))
+
+	if opcode, payload := client.readFrame(t); opcode != opPong || string(payload) != "are you there" {
+		t.Errorf("Expected the ping answered first, got opcode %d %q", opcode, payload)
+	}
+	if opcode, payload := client.readFrame(t); opcode != opText || string(payload) != "hello" {
+		t.Errorf("Expected the fragments echoed as one message, got opcode %d %q", opcode, payload)
+	}
+
+	client.writeFrame(true, opText, []byte("this message is over the limit"))
+	if opcode, payload := client.readFrame(t); opcode != opClose || binary.BigEndian.Uint16(payload) != CloseMessageTooBig {
+		t.Errorf("Expected an oversized message to close the connection with 1009, got opcode %d %v", opcode, payload)
+	}
+	if readError := <-readErrors; !errors.Is(readError, ErrMessageTooBig) {
+		t.Errorf("Expected ErrMessageTooBig, got %v", readError)
+	}
+
+	second := dialTestServer(t, server)
+	second.writeFrame(true, opClose, []byte{0x03, 0xE8})
+	if opcode, _ := second.readFrame(t); opcode != opClose {
+		t.Errorf("Expected the close answered, got opcode %d", opcode)
+	}
+	var closeError *CloseError
+	if readError := <-readErrors; !errors.As(readError, &closeError) || closeError.Code != CloseNormal {
+		t.Errorf("Expected a normal close reported, got %v", readError)
+	}
+}
+
+// TestUpgrade_NotWebSocket verifies plain requests are refused before the connection is taken over
+func TestUpgrade_NotWebSocket(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/ws", nil)
+	if _, upgradeError := Upgrade(httptest.NewRecorder(), request, 0); !errors.Is(upgradeError, ErrNotWebSocket) {
+		t.Errorf("Expected ErrNotWebSocket, got %v", upgradeError)
+	}
+}
@@ -0,0 +1,2 @@
+-- Rollback migration: Drop resource_changes table
+DROP TABLE IF EXISTS resource_changes;
@@ -0,0 +1,24 @@
+-- Migration: Create resource_changes table
+-- Append-only change log of every write, used by the differential sync API
+
+CREATE TABLE IF NOT EXISTS resource_changes (
+    -- Monotonic position in the change log, used as the sync watermark
+    sequence BIGSERIAL PRIMARY KEY,
+
+    -- Changed resource (type + logical ID)
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(64) NOT NULL,
+
+    -- Version of the resource after the change (1 for creates)
+    version INTEGER NOT NULL,
+
+    -- Kind of write: create, update, delete
+    operation VARCHAR(16) NOT NULL,
+
+    changed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    -- Each resource version is recorded exactly once
+    UNIQUE (resource_type, resource_id, version)
+);
+
+COMMENT ON TABLE resource_changes IS 'Append-only change log backing the differential sync API';
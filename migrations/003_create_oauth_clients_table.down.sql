@@ -0,0 +1,2 @@
+-- Rollback migration: Drop oauth_clients table
+DROP TABLE IF EXISTS oauth_clients;
@@ -0,0 +1,39 @@
+-- Migration: Create oauth_clients table
+-- Partner applications registered through the self-service registration endpoint
+
+CREATE TABLE IF NOT EXISTS oauth_clients (
+    -- Issued client identifier
+    client_id UUID PRIMARY KEY,
+
+    client_name VARCHAR(255) NOT NULL,
+
+    -- public, confidential, or backend
+    client_type VARCHAR(20) NOT NULL,
+
+    -- none, client_secret_basic, or private_key_jwt
+    token_endpoint_auth_method VARCHAR(50) NOT NULL,
+
+    grant_types TEXT[] NOT NULL DEFAULT '{}',
+    redirect_uris TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Public keys for private_key_jwt authentication (either a URL or an inline set)
+    jwks_uri TEXT,
+    jwks JSONB,
+
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+
+    -- SHA-256 hash of the client secret; the secret itself is only returned once
+    secret_hash VARCHAR(64),
+
+    -- Approval workflow: pending, approved, rejected
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    review_note TEXT,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    reviewed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Index for the admin review queue
+CREATE INDEX idx_oauth_clients_status ON oauth_clients(status, created_at);
+
+COMMENT ON TABLE oauth_clients IS 'OAuth2 clients registered by partner applications, pending admin approval';
@@ -0,0 +1,2 @@
+-- Rollback migration: Drop observation_ledger table
+DROP TABLE IF EXISTS observation_ledger;
@@ -0,0 +1,15 @@
+-- Migration: Create observation_ledger table
+-- Expected observation count per patient, maintained on every observation write
+-- and compared against MongoDB by the integrity reconciliation job
+
+CREATE TABLE IF NOT EXISTS observation_ledger (
+    -- Patient the observations belong to (not a foreign key: orphans must be representable)
+    patient_id VARCHAR(64) PRIMARY KEY,
+
+    -- Number of observations the API has stored for the patient
+    observation_count BIGINT NOT NULL DEFAULT 0,
+
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE observation_ledger IS 'Expected per-patient observation counts for Postgres/Mongo reconciliation';
@@ -0,0 +1,3 @@
+-- Rollback migration: Drop legal hold tables
+DROP TABLE IF EXISTS legal_hold_audit;
+DROP TABLE IF EXISTS patient_legal_holds;
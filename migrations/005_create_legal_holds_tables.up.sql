@@ -0,0 +1,41 @@
+-- Migration: Create legal hold tables
+-- Active holds block deletion of the patient and its related resources;
+-- every placement, release, and blocked deletion is recorded in the audit table
+
+CREATE TABLE IF NOT EXISTS patient_legal_holds (
+    -- Held patient; the foreign key also stops the row being deleted underneath an active hold
+    patient_id UUID PRIMARY KEY REFERENCES patients(id),
+
+    -- Why the hold was placed (case or matter reference)
+    reason TEXT NOT NULL,
+
+    -- Principal that placed the hold
+    placed_by VARCHAR(255) NOT NULL,
+
+    placed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS legal_hold_audit (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Not a foreign key: the history must outlive the hold and the patient
+    patient_id VARCHAR(64) NOT NULL,
+
+    -- placed, released, or deletion_blocked
+    action VARCHAR(32) NOT NULL,
+
+    -- Resource affected by the action (the patient itself or a related resource)
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(64) NOT NULL,
+
+    actor VARCHAR(255) NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+
+    occurred_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for a patient's hold history
+CREATE INDEX idx_legal_hold_audit_patient ON legal_hold_audit(patient_id, occurred_at);
+
+COMMENT ON TABLE patient_legal_holds IS 'Active litigation and investigation holds on patient records';
+COMMENT ON TABLE legal_hold_audit IS 'Append-only history of legal hold changes and blocked deletions';
@@ -0,0 +1,2 @@
+-- Rollback: Drop outbound delivery queue
+DROP TABLE IF EXISTS delivery_queue;
@@ -0,0 +1,33 @@
+-- Migration: Create outbound delivery queue
+-- Outbound webhook and ADT deliveries wait here until sent or retried;
+-- deliveries that exhaust their attempts stay as dead letters until retried or discarded
+
+CREATE TABLE IF NOT EXISTS delivery_queue (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Delivery channel (webhook, mllp) selecting the sender
+    kind VARCHAR(32) NOT NULL,
+
+    -- Endpoint, topic, or subscription the payload is sent to; circuit breaking is per destination
+    destination TEXT NOT NULL,
+
+    payload BYTEA NOT NULL,
+    content_type VARCHAR(128) NOT NULL DEFAULT 'application/json',
+
+    -- pending or dead
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+
+    -- Earliest time the next attempt may run; claimed deliveries are leased by moving it forward
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for claiming due deliveries
+CREATE INDEX IF NOT EXISTS idx_delivery_queue_due ON delivery_queue(status, next_attempt_at);
+
+COMMENT ON TABLE delivery_queue IS 'Outbound deliveries awaiting retry and dead letters';
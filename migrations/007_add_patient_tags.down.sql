@@ -0,0 +1,3 @@
+-- Rollback: Remove workflow tags from patients
+DROP INDEX IF EXISTS idx_patients_tags;
+ALTER TABLE patients DROP COLUMN IF EXISTS tags;
@@ -0,0 +1,9 @@
+-- Migration: Add workflow tags to patients
+-- Tags are meta.tag codings managed through $meta-add and $meta-delete and searched with _tag
+
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS tags JSONB NOT NULL DEFAULT '[]';
+
+-- Index for _tag containment searches
+CREATE INDEX IF NOT EXISTS idx_patients_tags ON patients USING GIN (tags jsonb_path_ops);
+
+COMMENT ON COLUMN patients.tags IS 'meta.tag codings as [{"system", "code", "display"}]';
@@ -0,0 +1,5 @@
+-- Rollback: Remove resource snapshots from the change log
+DROP INDEX IF EXISTS idx_resource_changes_compartment;
+DROP INDEX IF EXISTS idx_resource_changes_resource_time;
+ALTER TABLE resource_changes DROP COLUMN IF EXISTS compartment_patient_id;
+ALTER TABLE resource_changes DROP COLUMN IF EXISTS snapshot;
@@ -0,0 +1,13 @@
+-- Migration: Store resource snapshots in the change log
+-- Each change keeps the resource as it was after the write so reads can be answered as of a past time or version
+
+ALTER TABLE resource_changes ADD COLUMN IF NOT EXISTS snapshot JSONB;
+
+-- Patient whose compartment the resource belonged to after the change (the patient itself, or an observation's subject)
+ALTER TABLE resource_changes ADD COLUMN IF NOT EXISTS compartment_patient_id VARCHAR(64);
+
+-- Indexes for point-in-time lookups of one resource and of a patient's compartment
+CREATE INDEX IF NOT EXISTS idx_resource_changes_resource_time ON resource_changes(resource_type, resource_id, changed_at);
+CREATE INDEX IF NOT EXISTS idx_resource_changes_compartment ON resource_changes(compartment_patient_id);
+
+COMMENT ON COLUMN resource_changes.snapshot IS 'FHIR JSON of the resource after the change; NULL for deletes and changes recorded before snapshots';
@@ -0,0 +1,4 @@
+-- Rollback: Drop identifier re-keying tables
+DROP TABLE IF EXISTS identifier_rekey_audit;
+DROP TABLE IF EXISTS identifier_rekey_mappings;
+DROP TABLE IF EXISTS identifier_rekey_jobs;
@@ -0,0 +1,71 @@
+-- Migration: Create identifier re-keying tables
+-- A re-key job rewrites patient identifiers from an uploaded old → new mapping in batches;
+-- every rewrite, skip, and rollback is recorded in the audit table, which also drives rollback
+
+CREATE TABLE IF NOT EXISTS identifier_rekey_jobs (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- running, completed, rolling_back, or rolled_back
+    status VARCHAR(16) NOT NULL DEFAULT 'running',
+
+    -- Why the identifiers are changing (for example the MRN system migration)
+    reason TEXT NOT NULL,
+
+    -- Principal that started the job; rollbacks are attributed to the principal that requested them
+    actor VARCHAR(255) NOT NULL,
+    rollback_actor VARCHAR(255) NOT NULL DEFAULT '',
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS identifier_rekey_mappings (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES identifier_rekey_jobs(id) ON DELETE CASCADE,
+
+    -- Line in the uploaded mapping file, so skips can be traced back to it
+    line INTEGER NOT NULL,
+
+    old_system VARCHAR(255) NOT NULL,
+    old_value VARCHAR(255) NOT NULL,
+    new_system VARCHAR(255) NOT NULL,
+    new_value VARCHAR(255) NOT NULL,
+
+    -- Set once the mapping has been applied or skipped
+    processed_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Index for claiming a job's next unprocessed batch in file order
+CREATE INDEX idx_identifier_rekey_mappings_pending ON identifier_rekey_mappings(job_id, line) WHERE processed_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS identifier_rekey_audit (
+    id BIGSERIAL PRIMARY KEY,
+    job_id BIGINT NOT NULL REFERENCES identifier_rekey_jobs(id) ON DELETE CASCADE,
+
+    -- Not a foreign key: the history must outlive the patient; empty when no patient matched
+    patient_id VARCHAR(64) NOT NULL DEFAULT '',
+
+    -- rekeyed, skipped, rolled_back, or rollback_skipped
+    action VARCHAR(32) NOT NULL,
+
+    old_system VARCHAR(255) NOT NULL,
+    old_value VARCHAR(255) NOT NULL,
+    new_system VARCHAR(255) NOT NULL,
+    new_value VARCHAR(255) NOT NULL,
+
+    -- Why a mapping or rollback was skipped
+    detail TEXT NOT NULL DEFAULT '',
+
+    -- The rekeyed entry a rollback entry reverts
+    reverts_id BIGINT REFERENCES identifier_rekey_audit(id),
+
+    actor VARCHAR(255) NOT NULL,
+    occurred_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for a job's history and for finding entries not yet rolled back
+CREATE INDEX idx_identifier_rekey_audit_job ON identifier_rekey_audit(job_id, id);
+CREATE INDEX idx_identifier_rekey_audit_reverts ON identifier_rekey_audit(reverts_id);
+
+COMMENT ON TABLE identifier_rekey_jobs IS 'Bulk patient identifier re-keying jobs';
+COMMENT ON TABLE identifier_rekey_audit IS 'Append-only history of identifier rewrites and their rollbacks';
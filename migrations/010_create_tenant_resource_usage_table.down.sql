@@ -0,0 +1,2 @@
+-- Rollback: Drop tenant_resource_usage table
+DROP TABLE IF EXISTS tenant_resource_usage;
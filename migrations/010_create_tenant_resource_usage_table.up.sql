@@ -0,0 +1,28 @@
+-- Migration: Create tenant_resource_usage table
+-- Quota usage per stored resource, so tenant counters survive restarts, count resources
+-- stored before quotas were enabled, and are released when a resource is deleted
+
+CREATE TABLE IF NOT EXISTS tenant_resource_usage (
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+
+    -- Tenant the resource counts against, taken from the writer's API key
+    tenant_id VARCHAR(255) NOT NULL,
+
+    -- Size of the last stored payload; updates replace it, deletes remove the row
+    payload_bytes BIGINT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (resource_type, resource_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_resource_usage_tenant ON tenant_resource_usage (tenant_id);
+
+-- Patients stored before quotas existed count against the default tenant
+INSERT INTO tenant_resource_usage (resource_type, resource_id, tenant_id, payload_bytes, created_at)
+SELECT 'Patient', patients.id::text, 'default', pg_column_size(patients.*), patients.created_at
+FROM patients
+ON CONFLICT (resource_type, resource_id) DO NOTHING;
+
+COMMENT ON TABLE tenant_resource_usage IS 'Per-resource quota usage backing tenant quota counters';
@@ -0,0 +1,2 @@
+-- Rollback migration: Drop observation_daily_rollups table
+DROP TABLE IF EXISTS observation_daily_rollups;
@@ -0,0 +1,32 @@
+-- Migration: Create observation_daily_rollups table
+-- Daily per-patient, per-code aggregates of numeric observation values, computed from MongoDB
+-- by the rollup job so longitudinal charts read one row per day instead of every observation
+
+CREATE TABLE IF NOT EXISTS observation_daily_rollups (
+    -- Patient the observations belong to (not a foreign key, like observation_ledger)
+    patient_id VARCHAR(64) NOT NULL,
+
+    code VARCHAR(255) NOT NULL,
+    code_system VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Values in different units are never mixed into one aggregate
+    unit VARCHAR(64) NOT NULL DEFAULT '',
+
+    -- UTC day of the observations' effective date
+    day DATE NOT NULL,
+
+    observation_count BIGINT NOT NULL,
+    min_value DOUBLE PRECISION NOT NULL,
+    max_value DOUBLE PRECISION NOT NULL,
+    sum_value DOUBLE PRECISION NOT NULL,
+
+    computed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    -- Patient, code, and day lead so chart queries over a date range read one index range
+    PRIMARY KEY (patient_id, code, day, code_system, unit)
+);
+
+-- Lets the rollup job replace a day range across all patients
+CREATE INDEX IF NOT EXISTS idx_observation_daily_rollups_day ON observation_daily_rollups (day);
+
+COMMENT ON TABLE observation_daily_rollups IS 'Daily per-patient, per-code observation aggregates for longitudinal charts';
@@ -0,0 +1,3 @@
+-- Rollback: Drop patient erasure tables
+DROP TABLE IF EXISTS patient_erasure_holds;
+DROP TABLE IF EXISTS patient_erasures;
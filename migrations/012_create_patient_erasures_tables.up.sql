@@ -0,0 +1,59 @@
+-- Migration: Create patient erasure tables
+-- A right-to-erasure request withdraws a patient's records from every store as a saga, keeps them
+-- for a waiting period so the request can be cancelled, then purges them and issues a deletion certificate
+
+CREATE TABLE IF NOT EXISTS patient_erasures (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Not a foreign key: the erasure and its certificate must outlive the patient
+    patient_id VARCHAR(64) NOT NULL,
+
+    -- pending, held, compensating, purging, purged, failed, or cancelled
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+
+    -- Why the patient asked to be erased, and who requested or cancelled it
+    reason TEXT NOT NULL,
+    requested_by VARCHAR(255) NOT NULL,
+    cancelled_by VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Number of saga steps started; compensation restores them in reverse
+    steps_started INTEGER NOT NULL DEFAULT 0,
+
+    -- Why a step failed and the saga was compensated
+    error TEXT NOT NULL DEFAULT '',
+
+    -- Records withdrawn and purged per store, e.g. {"observations": 12, "patient": 3}
+    held_counts JSONB NOT NULL DEFAULT '{}',
+    purged_counts JSONB NOT NULL DEFAULT '{}',
+
+    -- Set when every store has been withdrawn; the purge runs once this has passed
+    purge_after TIMESTAMP WITH TIME ZONE,
+
+    -- Deletion certificate issued when the purge completes
+    certificate JSONB,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    purged_at TIMESTAMP WITH TIME ZONE
+);
+
+-- A patient can have only one erasure in progress
+CREATE UNIQUE INDEX IF NOT EXISTS idx_patient_erasures_active ON patient_erasures(patient_id)
+    WHERE status IN ('pending', 'held', 'compensating', 'purging');
+
+-- Index for the saga runner finding erasures with work to do
+CREATE INDEX IF NOT EXISTS idx_patient_erasures_status ON patient_erasures(status, purge_after);
+
+-- Postgres rows withdrawn by an erasure, kept until they are purged or restored
+CREATE TABLE IF NOT EXISTS patient_erasure_holds (
+    erasure_id BIGINT PRIMARY KEY REFERENCES patient_erasures(id) ON DELETE CASCADE,
+
+    -- The patients row and its observation_daily_rollups rows as JSON
+    patient JSONB NOT NULL,
+    rollups JSONB NOT NULL DEFAULT '[]',
+
+    held_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE patient_erasures IS 'Right-to-erasure requests and their deletion certificates';
+COMMENT ON TABLE patient_erasure_holds IS 'Patient rows withdrawn by a pending erasure, restorable until purged';
@@ -0,0 +1,2 @@
+-- Rollback: Drop write journal
+DROP TABLE IF EXISTS write_journal;
@@ -0,0 +1,28 @@
+-- Migration: Create write journal
+-- Observation writes span MongoDB and the Postgres change log, so a crash between the two leaves a write
+-- applied but unrecorded. Each accepted write is journaled here before it is applied, and the entry is
+-- removed in the transaction that records the change; entries left by a crash are recovered at startup
+
+CREATE TABLE IF NOT EXISTS write_journal (
+    id BIGSERIAL PRIMARY KEY,
+
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(64) NOT NULL,
+
+    -- create, update, or delete
+    operation VARCHAR(16) NOT NULL,
+
+    -- Tenant the write was made for, so recovered writes are published to the right consumers
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Patient the resource belonged to before the write, for the observation count ledger
+    previous_patient_id VARCHAR(64) NOT NULL DEFAULT '',
+
+    -- Set by the server before the write is applied; a stored resource updated since then received the write
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+-- Index for recovering the oldest stale entries first
+CREATE INDEX IF NOT EXISTS idx_write_journal_created_at ON write_journal(created_at);
+
+COMMENT ON TABLE write_journal IS 'Accepted writes not yet recorded in the change log';
@@ -0,0 +1,3 @@
+-- Rollback: Drop search export tables
+DROP TABLE IF EXISTS search_export_downloads;
+DROP TABLE IF EXISTS search_exports;
@@ -0,0 +1,58 @@
+-- Migration: Create search export tables
+-- A search export materializes every result of a search into an encrypted NDJSON or CSV file that its
+-- requester downloads through an expiring signed link; each download is audited
+
+CREATE TABLE IF NOT EXISTS search_exports (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Patient or Observation
+    resource_type VARCHAR(64) NOT NULL,
+
+    -- Search criteria as the client sent them, and with exposed IDs translated to stored ones
+    query TEXT NOT NULL DEFAULT '',
+    search_query TEXT NOT NULL DEFAULT '',
+
+    -- ndjson or csv
+    format VARCHAR(16) NOT NULL,
+
+    -- pending, running, ready, failed, or expired
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+
+    -- API key fingerprint of the requester, the only caller who may see or link to the export
+    requested_by VARCHAR(255) NOT NULL,
+
+    -- Verified tenant the search ran for, so exported IDs are exposed as the requester sees them
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+
+    resource_count INTEGER NOT NULL DEFAULT 0,
+    truncated BOOLEAN NOT NULL DEFAULT FALSE,
+    error TEXT NOT NULL DEFAULT '',
+
+    -- AES-256-GCM sealed file, with its nonce prepended; cleared when the export expires
+    content BYTEA,
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Index for claiming queued exports and expiring finished ones
+CREATE INDEX IF NOT EXISTS idx_search_exports_status ON search_exports(status, updated_at);
+
+CREATE TABLE IF NOT EXISTS search_export_downloads (
+    id BIGSERIAL PRIMARY KEY,
+    export_id BIGINT NOT NULL REFERENCES search_exports(id) ON DELETE CASCADE,
+
+    -- API key fingerprint of the caller, or anonymous when the signed link was used without a key
+    downloaded_by VARCHAR(255) NOT NULL,
+    remote_address VARCHAR(255) NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+
+    downloaded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_search_export_downloads_export ON search_export_downloads(export_id);
+
+COMMENT ON TABLE search_exports IS 'Asynchronous search result exports and their encrypted files';
+COMMENT ON TABLE search_export_downloads IS 'Audit trail of search export downloads';
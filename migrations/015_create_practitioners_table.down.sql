@@ -0,0 +1,2 @@
+-- Rollback migration: Drop practitioners table
+DROP TABLE IF EXISTS practitioners;
@@ -0,0 +1,41 @@
+-- Migration: Create practitioners table for FHIR Practitioner resources
+-- Practitioners are the providers observations reference as their performer
+
+CREATE TABLE IF NOT EXISTS practitioners (
+    -- Primary key using UUID, like patients
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- FHIR identifier system and value (e.g., NPI)
+    identifier_system VARCHAR(255),
+    identifier_value VARCHAR(255),
+
+    -- Practitioner active status
+    active BOOLEAN DEFAULT true,
+
+    -- Name fields (supporting single name for simplicity)
+    family_name VARCHAR(255) NOT NULL,
+    given_name VARCHAR(255) NOT NULL,
+
+    -- Administrative gender (male, female, other, unknown)
+    gender VARCHAR(20),
+
+    -- Specialty from the first qualification code (e.g., a NUCC taxonomy code)
+    specialty_system VARCHAR(255),
+    specialty_code VARCHAR(64),
+    specialty_display VARCHAR(255),
+
+    -- Audit fields for tracking changes
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for faster lookups by identifier
+CREATE INDEX idx_practitioners_identifier ON practitioners(identifier_system, identifier_value);
+
+-- Index for name searches
+CREATE INDEX idx_practitioners_name ON practitioners(family_name, given_name);
+
+-- Index for specialty searches
+CREATE INDEX idx_practitioners_specialty ON practitioners(specialty_code);
+
+COMMENT ON TABLE practitioners IS 'Stores FHIR R4 Practitioner resources referenced by observations';
@@ -0,0 +1,2 @@
+-- Rollback migration: Drop encounters table
+DROP TABLE IF EXISTS encounters;
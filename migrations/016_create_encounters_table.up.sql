@@ -0,0 +1,40 @@
+-- Migration: Create encounters table for FHIR Encounter resources
+-- Encounters are the patient visits observations can be grouped under
+
+CREATE TABLE IF NOT EXISTS encounters (
+    -- Primary key using UUID, like patients
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Encounter status (planned, arrived, in-progress, finished, cancelled, ...)
+    status VARCHAR(20) NOT NULL,
+
+    -- Encounter class coding (e.g., AMB or IMP from the v3 ActCode system)
+    class_system VARCHAR(255),
+    class_code VARCHAR(64),
+    class_display VARCHAR(255),
+
+    -- Subject patient; not a foreign key so erasing a patient is not blocked by its visits
+    patient_id UUID,
+
+    -- Practitioners taking part in the encounter, in participant order
+    participant_practitioner_ids UUID[] NOT NULL DEFAULT '{}',
+
+    -- Visit period; an encounter still in progress has no end
+    period_start TIMESTAMP WITH TIME ZONE,
+    period_end TIMESTAMP WITH TIME ZONE,
+
+    -- Audit fields for tracking changes
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for a patient's encounters, newest visit first
+CREATE INDEX idx_encounters_patient ON encounters(patient_id, period_start DESC);
+
+-- Index for date range searches
+CREATE INDEX idx_encounters_period ON encounters(period_start, period_end);
+
+-- Index for status searches
+CREATE INDEX idx_encounters_status ON encounters(status);
+
+COMMENT ON TABLE encounters IS 'Stores FHIR R4 Encounter resources linking patients, practitioners, and observations';
@@ -0,0 +1,2 @@
+-- Rollback migration: Drop medication_requests table
+DROP TABLE IF EXISTS medication_requests;
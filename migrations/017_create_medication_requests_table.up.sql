@@ -0,0 +1,44 @@
+-- Migration: Create medication_requests table for FHIR MedicationRequest resources
+-- Medication requests are the prescriptions exchanged with e-prescribing systems
+
+CREATE TABLE IF NOT EXISTS medication_requests (
+    -- Primary key using UUID, like patients
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Request status (active, on-hold, cancelled, completed, ...) and intent (proposal, plan, order, ...)
+    status VARCHAR(20) NOT NULL,
+    intent VARCHAR(20) NOT NULL,
+
+    -- Requested medication coding (e.g., an RxNorm code)
+    medication_system VARCHAR(255),
+    medication_code VARCHAR(64),
+    medication_display VARCHAR(255),
+
+    -- Subject patient; not a foreign key so erasing a patient is not blocked by its prescriptions
+    patient_id UUID,
+
+    -- Encounter the request was written in and the practitioner who wrote it
+    encounter_id UUID,
+    requester_practitioner_id UUID,
+
+    -- When the request was written
+    authored_on TIMESTAMP WITH TIME ZONE,
+
+    -- Free-text dosage instructions (e.g., "1 tablet by mouth twice daily")
+    dosage_instruction_text TEXT,
+
+    -- Audit fields for tracking changes
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for a patient's prescriptions, newest first
+CREATE INDEX idx_medication_requests_patient ON medication_requests(patient_id, authored_on DESC);
+
+-- Index for authored date range searches
+CREATE INDEX idx_medication_requests_authored_on ON medication_requests(authored_on);
+
+-- Index for status and intent searches
+CREATE INDEX idx_medication_requests_status ON medication_requests(status, intent);
+
+COMMENT ON TABLE medication_requests IS 'Stores FHIR R4 MedicationRequest resources for e-prescribing';
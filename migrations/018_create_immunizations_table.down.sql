@@ -0,0 +1,2 @@
+-- Rollback migration: Drop immunizations table
+DROP TABLE IF EXISTS immunizations;
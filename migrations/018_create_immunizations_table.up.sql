@@ -0,0 +1,43 @@
+-- Migration: Create immunizations table for FHIR Immunization resources
+-- Immunizations are the vaccination records exchanged with immunization registries
+
+CREATE TABLE IF NOT EXISTS immunizations (
+    -- Primary key using UUID, like patients
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Immunization status (completed, entered-in-error, not-done)
+    status VARCHAR(20) NOT NULL,
+
+    -- Administered vaccine coding (e.g., a CVX code)
+    vaccine_system VARCHAR(255),
+    vaccine_code VARCHAR(64),
+    vaccine_display VARCHAR(255),
+
+    -- Vaccinated patient; not a foreign key so erasing a patient is not blocked by its immunizations
+    patient_id UUID,
+
+    -- When the vaccine was given, as submitted and as a timestamp for date searches
+    occurrence_date_time VARCHAR(35),
+    occurred_at TIMESTAMP WITH TIME ZONE,
+
+    -- Vaccine lot number, used to trace recalls
+    lot_number VARCHAR(100),
+
+    -- Audit fields for tracking changes
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for a patient's immunization history, most recent first
+CREATE INDEX idx_immunizations_patient ON immunizations(patient_id, occurred_at DESC);
+
+-- Index for occurrence date range searches
+CREATE INDEX idx_immunizations_occurred_at ON immunizations(occurred_at);
+
+-- Index for vaccine code searches
+CREATE INDEX idx_immunizations_vaccine_code ON immunizations(vaccine_code);
+
+-- Index for lot number searches during recalls
+CREATE INDEX idx_immunizations_lot_number ON immunizations(lot_number);
+
+COMMENT ON TABLE immunizations IS 'Stores FHIR R4 Immunization resources for immunization registry exchange';
@@ -0,0 +1,3 @@
+-- Rollback: Drop bulk export tables
+DROP TABLE IF EXISTS bulk_export_files;
+DROP TABLE IF EXISTS bulk_exports;
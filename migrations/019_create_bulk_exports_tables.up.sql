@@ -0,0 +1,47 @@
+-- Migration: Create bulk export tables
+-- A bulk export is a FHIR Bulk Data $export job; workers write one NDJSON file per resource type to the
+-- bulk export directory, and the rows below track the job and the files it produced
+
+CREATE TABLE IF NOT EXISTS bulk_exports (
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Kick-off request URL, echoed in the completion manifest
+    request TEXT NOT NULL,
+
+    -- Resource types to export, in file order
+    types TEXT[] NOT NULL,
+
+    -- Only resources changed after this time are exported, when set
+    since TIMESTAMP WITH TIME ZONE,
+
+    -- pending, running, complete, failed, cancelled, or expired
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+
+    -- API key fingerprint of the requester, the only caller who may see the export or download its files
+    requested_by VARCHAR(255) NOT NULL,
+
+    -- Verified tenant the export reads for
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+
+    progress TEXT NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Index for claiming queued exports and expiring finished ones
+CREATE INDEX IF NOT EXISTS idx_bulk_exports_status ON bulk_exports(status, updated_at);
+
+CREATE TABLE IF NOT EXISTS bulk_export_files (
+    export_id BIGINT NOT NULL REFERENCES bulk_exports(id) ON DELETE CASCADE,
+    resource_type VARCHAR(64) NOT NULL,
+    resource_count INTEGER NOT NULL,
+
+    PRIMARY KEY (export_id, resource_type)
+);
+
+COMMENT ON TABLE bulk_exports IS 'FHIR Bulk Data $export jobs';
+COMMENT ON TABLE bulk_export_files IS 'NDJSON files written by completed bulk exports, one per resource type';
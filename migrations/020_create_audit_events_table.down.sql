@@ -0,0 +1,2 @@
+-- Rollback: Drop audit events table
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,42 @@
+-- Migration: Create audit events table
+-- Every create, read, update, delete, and search of clinical data is recorded here and served as FHIR
+-- AuditEvent resources
+
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- C, R, U, D, or E, and the FHIR RESTful interaction (read, search-type, operation, ...)
+    action CHAR(1) NOT NULL,
+    interaction VARCHAR(32) NOT NULL,
+
+    -- 0 (success), 4 (minor failure), or 8 (serious failure), and the HTTP status behind it
+    outcome VARCHAR(2) NOT NULL,
+    status_code INTEGER NOT NULL,
+
+    -- Principal that made the request and the roles it held; never an API key itself
+    agent_id VARCHAR(255) NOT NULL,
+    agent_roles TEXT[] NOT NULL DEFAULT '{}',
+
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+    client_address VARCHAR(64) NOT NULL DEFAULT '',
+
+    -- Not foreign keys: the trail must outlive the resources it describes
+    entity_type VARCHAR(64) NOT NULL,
+    entity_id VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Raw query string of searches
+    query TEXT NOT NULL DEFAULT '',
+
+    method VARCHAR(16) NOT NULL,
+    path TEXT NOT NULL,
+    request_id VARCHAR(64) NOT NULL DEFAULT '',
+
+    recorded_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for the AuditEvent search parameters
+CREATE INDEX IF NOT EXISTS idx_audit_events_recorded ON audit_events(recorded_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_entity ON audit_events(entity_id, recorded_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_agent ON audit_events(agent_id, recorded_at);
+
+COMMENT ON TABLE audit_events IS 'Append-only trail of access to clinical data, served as FHIR AuditEvent resources';
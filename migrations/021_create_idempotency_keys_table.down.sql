@@ -0,0 +1,2 @@
+-- Rollback: Drop idempotency keys table
+DROP TABLE IF EXISTS idempotency_keys;
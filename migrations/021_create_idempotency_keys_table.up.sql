@@ -0,0 +1,32 @@
+-- Migration: Create idempotency keys table
+-- POST requests sent with an Idempotency-Key header are recorded here with their response, so a retried
+-- request is answered with the original response instead of creating a duplicate resource
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    -- Principal and tenant the key belongs to, and the key the client chose
+    client_id VARCHAR(512) NOT NULL,
+    idempotency_key VARCHAR(255) NOT NULL,
+
+    method VARCHAR(16) NOT NULL,
+    path TEXT NOT NULL,
+
+    -- SHA-256 of the request body, hex encoded
+    request_hash CHAR(64) NOT NULL,
+
+    -- False while the first request is in progress
+    completed BOOLEAN NOT NULL DEFAULT FALSE,
+
+    status_code INTEGER NOT NULL DEFAULT 0,
+    response_headers JSONB NOT NULL DEFAULT '{}',
+    response_body BYTEA,
+
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+
+    PRIMARY KEY (client_id, idempotency_key)
+);
+
+-- Index for purging expired keys
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires ON idempotency_keys(expires_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Responses to POST requests replayed to retries sent with the same Idempotency-Key';
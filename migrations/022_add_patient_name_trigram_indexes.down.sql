@@ -0,0 +1,4 @@
+-- Rollback: Remove the patient name trigram indexes
+-- The pg_trgm extension is left installed, since other objects may depend on it
+DROP INDEX IF EXISTS idx_patients_given_name_trgm;
+DROP INDEX IF EXISTS idx_patients_family_name_trgm;
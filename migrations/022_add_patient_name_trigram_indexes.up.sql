@@ -0,0 +1,8 @@
+-- Migration: Index patient names for substring and fuzzy search
+-- pg_trgm lets LIKE '%name%' and similarity matches use a GIN index instead of scanning every patient
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Searches lower-case the names, so the indexes cover LOWER(...) to match the queries
+CREATE INDEX IF NOT EXISTS idx_patients_family_name_trgm ON patients USING GIN (LOWER(family_name) gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS idx_patients_given_name_trgm ON patients USING GIN (LOWER(given_name) gin_trgm_ops);
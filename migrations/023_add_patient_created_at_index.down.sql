@@ -0,0 +1,2 @@
+-- Rollback: Remove the patient creation time index
+DROP INDEX IF EXISTS idx_patients_created_at_id;
@@ -0,0 +1,5 @@
+-- Migration: Index patients by creation time for cursor-paged searches
+-- Searches sorted by created_at continue after (created_at, id) of the previous page's last patient,
+-- which this index answers without reading the skipped rows; it is scanned backwards for newest-first pages
+
+CREATE INDEX IF NOT EXISTS idx_patients_created_at_id ON patients (created_at, id);
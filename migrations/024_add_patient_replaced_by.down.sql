@@ -0,0 +1,2 @@
+-- Rollback: Remove the merged-into patient reference
+ALTER TABLE patients DROP COLUMN IF EXISTS replaced_by;
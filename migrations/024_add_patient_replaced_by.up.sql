@@ -0,0 +1,7 @@
+-- Migration: Record which patient a merged duplicate was merged into
+-- $merge marks the source patient inactive and points it at the surviving target, served as a replaced-by link
+
+-- Not a foreign key, so erasing the target patient is not blocked by the duplicates merged into it
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS replaced_by UUID;
+
+COMMENT ON COLUMN patients.replaced_by IS 'Patient this duplicate was merged into, served as Patient.link of type replaced-by';
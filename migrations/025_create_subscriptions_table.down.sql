@@ -0,0 +1,2 @@
+-- Rollback migration: Drop subscriptions table
+DROP TABLE IF EXISTS subscriptions;
@@ -0,0 +1,41 @@
+-- Migration: Create subscriptions table for FHIR Subscription resources
+-- Clients register a search criteria and a rest-hook endpoint that matching creates and updates are POSTed to
+
+CREATE TABLE IF NOT EXISTS subscriptions (
+    -- Primary key using UUID, like patients
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- requested, active, error, or off
+    status VARCHAR(20) NOT NULL,
+
+    reason TEXT NOT NULL DEFAULT '',
+
+    -- Search whose matches are notified, e.g. Observation?patient=123&code=8480-6
+    criteria TEXT NOT NULL,
+
+    -- rest-hook channel: endpoint URL, payload MIME type (empty sends no body), and "Name: value" headers
+    endpoint TEXT NOT NULL,
+    payload VARCHAR(100) NOT NULL DEFAULT '',
+    headers TEXT[] NOT NULL DEFAULT '{}',
+
+    -- When notifications stop (NULL means never)
+    end_time TIMESTAMP WITH TIME ZONE,
+
+    -- Failure that stopped the subscription with the error status
+    error TEXT,
+
+    -- Tenant whose writes are notified
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Audit fields for tracking changes
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Index for the notifier, which reads every active subscription
+CREATE INDEX idx_subscriptions_status ON subscriptions(status);
+
+-- Index for listing a tenant's subscriptions
+CREATE INDEX idx_subscriptions_tenant ON subscriptions(tenant_id, created_at);
+
+COMMENT ON TABLE subscriptions IS 'Stores FHIR R4 Subscription resources notified over rest-hook channels';
@@ -0,0 +1,2 @@
+-- Rollback migration: Drop event outbox table
+DROP TABLE IF EXISTS event_outbox;
@@ -0,0 +1,23 @@
+-- Migration: Create event outbox table
+-- Resource change events are written here in the transaction that records the change, then relayed
+-- to Kafka in order and deleted, so every committed write is published at least once
+
+CREATE TABLE IF NOT EXISTS event_outbox (
+    -- Allocated under the change log lock, so IDs follow commit order
+    id BIGSERIAL PRIMARY KEY,
+
+    -- Kafka topic and message key; the key is the resource ID so a resource's events stay in one partition
+    topic VARCHAR(255) NOT NULL,
+    event_key VARCHAR(255) NOT NULL,
+
+    -- JSON-encoded resource event
+    payload BYTEA NOT NULL,
+
+    -- Failed relay attempts of this event and the latest failure
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+COMMENT ON TABLE event_outbox IS 'Resource change events awaiting publication to Kafka';
@@ -0,0 +1,2 @@
+-- Rollback migration: Drop async requests table
+DROP TABLE IF EXISTS async_requests;
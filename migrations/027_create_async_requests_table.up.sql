@@ -0,0 +1,48 @@
+-- Migration: Create async requests table
+-- Searches and operations sent with Prefer: respond-async are stored here, run by a background worker,
+-- and their response kept until the client collects it from the status URL or it expires
+
+CREATE TABLE IF NOT EXISTS async_requests (
+    -- Primary key using UUID, so status URLs cannot be guessed
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- The request as received: method, path with query, the headers it is replayed with, and its body
+    method VARCHAR(16) NOT NULL,
+    request_url TEXT NOT NULL,
+    request_headers JSONB NOT NULL DEFAULT '{}',
+    request_body BYTEA,
+
+    -- Caller the request runs as: principal, roles, and tenant (verified when resolved from an API key)
+    principal_id VARCHAR(255) NOT NULL,
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+    tenant_verified BOOLEAN NOT NULL DEFAULT FALSE,
+
+    -- pending, running, complete, failed, or cancelled
+    status VARCHAR(20) NOT NULL,
+
+    -- Times a worker claimed the request; more than one means a worker stopped while running it
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+
+    -- Response the request was answered with once complete
+    response_status INTEGER NOT NULL DEFAULT 0,
+    response_headers JSONB NOT NULL DEFAULT '{}',
+    response_body BYTEA,
+
+    -- updated_at is refreshed while a worker runs the request, so a stopped worker's request is reclaimed
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+
+    -- When a finished request and its response are deleted
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Index for workers claiming the oldest pending request
+CREATE INDEX IF NOT EXISTS idx_async_requests_status ON async_requests(status, created_at);
+
+-- Index for deleting expired requests
+CREATE INDEX IF NOT EXISTS idx_async_requests_expires ON async_requests(expires_at);
+
+COMMENT ON TABLE async_requests IS 'Requests sent with Prefer: respond-async and their responses';
@@ -0,0 +1,69 @@
+-- Rollback migration: Drop background jobs table and restore the async requests table
+CREATE TABLE IF NOT EXISTS async_requests (
+    -- Primary key using UUID, so status URLs cannot be guessed
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- The request as received: method, path with query, the headers it is replayed with, and its body
+    method VARCHAR(16) NOT NULL,
+    request_url TEXT NOT NULL,
+    request_headers JSONB NOT NULL DEFAULT '{}',
+    request_body BYTEA,
+
+    -- Caller the request runs as: principal, roles, and tenant (verified when resolved from an API key)
+    principal_id VARCHAR(255) NOT NULL,
+    roles TEXT[] NOT NULL DEFAULT '{}',
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+    tenant_verified BOOLEAN NOT NULL DEFAULT FALSE,
+
+    -- pending, running, complete, failed, or cancelled
+    status VARCHAR(20) NOT NULL,
+
+    -- Times a worker claimed the request; more than one means a worker stopped while running it
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+
+    -- Response the request was answered with once complete
+    response_status INTEGER NOT NULL DEFAULT 0,
+    response_headers JSONB NOT NULL DEFAULT '{}',
+    response_body BYTEA,
+
+    -- updated_at is refreshed while a worker runs the request, so a stopped worker's request is reclaimed
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+
+    -- When a finished request and its response are deleted
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Index for workers claiming the oldest pending request
+CREATE INDEX IF NOT EXISTS idx_async_requests_status ON async_requests(status, created_at);
+
+-- Index for deleting expired requests
+CREATE INDEX IF NOT EXISTS idx_async_requests_expires ON async_requests(expires_at);
+
+COMMENT ON TABLE async_requests IS 'Requests sent with Prefer: respond-async and their responses';
+
+-- Queued async-request jobs go back to the old table as pending requests; other jobs are lost
+INSERT INTO async_requests (id, method, request_url, request_headers, request_body, principal_id, roles, tenant_id,
+                            tenant_verified, status, created_at, updated_at)
+SELECT id,
+       payload_json->>'method',
+       payload_json->>'url',
+       COALESCE(payload_json->'headers', '{}'::jsonb),
+       decode(payload_json->>'body', 'base64'),
+       principal_id,
+       ARRAY(SELECT jsonb_array_elements_text(COALESCE(payload_json->'roles', '[]'::jsonb))),
+       tenant_id,
+       COALESCE((payload_json->>'tenant_verified')::boolean, FALSE),
+       'pending',
+       created_at,
+       updated_at
+FROM (
+    SELECT id, principal_id, tenant_id, created_at, updated_at, convert_from(payload, 'UTF8')::jsonb AS payload_json
+    FROM jobs
+    WHERE kind = 'async-request' AND status = 'queued'
+) AS queued_requests
+ON CONFLICT (id) DO NOTHING;
+
+DROP TABLE IF EXISTS jobs;
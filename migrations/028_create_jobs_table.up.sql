@@ -0,0 +1,108 @@
+-- Migration: Create background jobs table
+-- Jobs wait here for the worker pool, are retried with backoff when an attempt fails, and are kept with
+-- their outcome until they expire. Requests sent with Prefer: respond-async now run as jobs, replacing
+-- the async_requests table, whose requests are carried over
+
+CREATE TABLE IF NOT EXISTS jobs (
+    -- Primary key using UUID, so status URLs built from it cannot be guessed
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+
+    -- Job type selecting the handler, e.g. async-request
+    kind VARCHAR(64) NOT NULL,
+
+    -- Handler input and output; cleared when a job is cancelled
+    payload BYTEA,
+    result BYTEA,
+
+    -- queued, running, succeeded, failed, or cancelled
+    status VARCHAR(16) NOT NULL DEFAULT 'queued',
+    progress TEXT NOT NULL DEFAULT '',
+
+    -- Times a worker started the job, and the most it may be started before the job fails
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL,
+    last_error TEXT NOT NULL DEFAULT '',
+
+    -- Caller who queued the job
+    principal_id VARCHAR(255) NOT NULL DEFAULT '',
+    tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+
+    -- Earliest time a queued job may start; retries move it forward by the backoff
+    run_after TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    -- updated_at is refreshed while a worker runs the job, so a stopped worker's job is reclaimed
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+
+    -- When a finished job and its result are deleted
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Index for workers claiming the next due job
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(status, run_after);
+
+-- Index for listing recent jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_created ON jobs(created_at DESC);
+
+-- Index for deleting expired jobs
+CREATE INDEX IF NOT EXISTS idx_jobs_expires ON jobs(expires_at);
+
+COMMENT ON TABLE jobs IS 'Background jobs with their progress, attempts, and outcome';
+
+-- Async requests in the old table become async-request jobs under the same ID, so their status URLs keep
+-- working. The payload and result are the JSON the async runner stores, with bodies base64-encoded.
+-- Pending requests are queued; a running search is queued to run again, while a running operation fails,
+-- as the runner would after a restart, since it may already have changed data. Cancelled requests are dropped
+DO $$
+BEGIN
+    IF to_regclass('async_requests') IS NOT NULL THEN
+        INSERT INTO jobs (id, kind, payload, result, status, attempts, max_attempts, last_error, principal_id, tenant_id,
+                          created_at, updated_at, finished_at, expires_at)
+        SELECT id,
+               'async-request',
+               convert_to(json_build_object(
+                   'method', method,
+                   'url', request_url,
+                   'headers', request_headers,
+                   'body', translate(encode(request_body, 'base64'), E'\n', ''),
+                   'roles', roles,
+                   'tenant_verified', tenant_verified
+               )::text, 'UTF8'),
+               CASE WHEN status = 'complete' THEN convert_to(json_build_object(
+                   'status', response_status,
+                   'headers', response_headers,
+                   'body', translate(encode(response_body, 'base64'), E'\n', '')
+               )::text, 'UTF8') END,
+               CASE
+                   WHEN status = 'complete' THEN 'succeeded'
+                   WHEN status = 'failed' OR (status = 'running' AND method <> 'GET') THEN 'failed'
+                   ELSE 'queued'
+               END,
+               CASE WHEN status IN ('pending', 'running') AND method = 'GET' THEN 0 ELSE attempts END,
+               CASE WHEN method = 'GET' THEN 3 ELSE 1 END,
+               CASE
+                   WHEN status = 'running' AND method <> 'GET' THEN 'the server was upgraded while the operation was running; resubmit it'
+                   ELSE error
+               END,
+               principal_id,
+               tenant_id,
+               created_at,
+               updated_at,
+               CASE
+                   WHEN status = 'running' AND method <> 'GET' THEN CURRENT_TIMESTAMP
+                   WHEN status IN ('complete', 'failed') THEN completed_at
+               END,
+               CASE
+                   WHEN status = 'running' AND method <> 'GET' THEN CURRENT_TIMESTAMP + INTERVAL '24 hours'
+                   WHEN status IN ('complete', 'failed') THEN expires_at
+               END
+        FROM async_requests
+        WHERE status <> 'cancelled'
+        ON CONFLICT (id) DO NOTHING;
+    END IF;
+END
+$$;
+
+DROP TABLE IF EXISTS async_requests;
@@ -0,0 +1,6 @@
+-- Rollback: Stop running bulk exports as background jobs
+-- Unfinished bulk-export jobs are removed; their pending and running exports are claimed by the old workers
+DELETE FROM jobs WHERE kind = 'bulk-export' AND status IN ('queued', 'running');
+
+DROP INDEX IF EXISTS idx_bulk_exports_job_id;
+ALTER TABLE bulk_exports DROP COLUMN IF EXISTS job_id;
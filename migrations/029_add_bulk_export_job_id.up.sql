@@ -0,0 +1,20 @@
+-- Migration: Run bulk exports as background jobs
+-- Each export is written by a bulk-export job in the jobs table, which claims, retries, and cancels it like
+-- any other job; the export row keeps the status, files, and expiry the Bulk Data endpoints serve
+
+-- Not a foreign key, so finished jobs expire from the jobs table while their exports stay downloadable
+ALTER TABLE bulk_exports ADD COLUMN IF NOT EXISTS job_id UUID;
+
+-- Index for finding the export a job writes
+CREATE UNIQUE INDEX IF NOT EXISTS idx_bulk_exports_job_id ON bulk_exports(job_id);
+
+-- Exports still waiting for the old bulk export workers are queued as jobs, so they are not lost
+UPDATE bulk_exports SET job_id = gen_random_uuid() WHERE status IN ('pending', 'running') AND job_id IS NULL;
+
+INSERT INTO jobs (id, kind, status, max_attempts, principal_id, tenant_id)
+SELECT job_id, 'bulk-export', 'queued', 5, requested_by, tenant_id
+FROM bulk_exports
+WHERE status IN ('pending', 'running')
+ON CONFLICT (id) DO NOTHING;
+
+COMMENT ON COLUMN bulk_exports.job_id IS 'bulk-export job writing the export''s files';
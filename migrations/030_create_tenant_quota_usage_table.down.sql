@@ -0,0 +1,2 @@
+-- Rollback: Drop tenant_quota_usage table
+DROP TABLE IF EXISTS tenant_quota_usage;
@@ -0,0 +1,30 @@
+-- Migration: Create tenant_quota_usage table
+-- One row of quota counters per tenant, checked and updated with a single conditional UPDATE so every
+-- server instance enforces the same limits. Seeded from the per-resource usage of migration 010
+
+CREATE TABLE IF NOT EXISTS tenant_quota_usage (
+    tenant_id VARCHAR(255) PRIMARY KEY,
+
+    -- Stored patients, and the summed payload size of every stored resource
+    patients BIGINT NOT NULL DEFAULT 0,
+    storage_bytes BIGINT NOT NULL DEFAULT 0,
+
+    -- Observations created on observation_day (UTC); a write on a later day starts the count again
+    observations_today BIGINT NOT NULL DEFAULT 0,
+    observation_day DATE NOT NULL DEFAULT (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')::date,
+
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO tenant_quota_usage (tenant_id, patients, storage_bytes, observations_today, observation_day)
+SELECT tenant_id,
+       COUNT(*) FILTER (WHERE resource_type = 'Patient'),
+       COALESCE(SUM(payload_bytes), 0),
+       COUNT(*) FILTER (WHERE resource_type = 'Observation'
+                        AND (created_at AT TIME ZONE 'UTC')::date = (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')::date),
+       (CURRENT_TIMESTAMP AT TIME ZONE 'UTC')::date
+FROM tenant_resource_usage
+GROUP BY tenant_id
+ON CONFLICT (tenant_id) DO NOTHING;
+
+COMMENT ON TABLE tenant_quota_usage IS 'Per-tenant quota counters enforced atomically across server instances';
@@ -0,0 +1,2 @@
+-- Rollback: Drop edit_locks table
+DROP TABLE IF EXISTS edit_locks;
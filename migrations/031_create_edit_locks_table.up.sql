@@ -0,0 +1,17 @@
+-- Migration: Create edit_locks table
+-- Advisory edit locks, kept in PostgreSQL so every server instance sees the same holder. A row whose
+-- expires_at has passed is no lock at all and is deleted by the next acquisition
+
+CREATE TABLE IF NOT EXISTS edit_locks (
+    resource_type VARCHAR(64) NOT NULL,
+    resource_id VARCHAR(255) NOT NULL,
+    owner VARCHAR(255) NOT NULL,
+    acquired_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (resource_type, resource_id)
+);
+
+-- Acquiring a lock deletes every expired row
+CREATE INDEX IF NOT EXISTS idx_edit_locks_expires_at ON edit_locks (expires_at);
+
+COMMENT ON TABLE edit_locks IS 'Advisory edit locks shared by every server instance';
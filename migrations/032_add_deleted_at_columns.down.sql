@@ -0,0 +1,13 @@
+-- Rollback: Drop deleted_at from every resource table
+-- Rows marked deleted are removed first, as they were before soft delete
+DELETE FROM patients WHERE deleted_at IS NOT NULL;
+DELETE FROM practitioners WHERE deleted_at IS NOT NULL;
+DELETE FROM encounters WHERE deleted_at IS NOT NULL;
+DELETE FROM immunizations WHERE deleted_at IS NOT NULL;
+DELETE FROM medication_requests WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE patients DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE practitioners DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE encounters DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE immunizations DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE medication_requests DROP COLUMN IF EXISTS deleted_at;
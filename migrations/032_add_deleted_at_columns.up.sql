@@ -0,0 +1,16 @@
+-- Migration: Add deleted_at to every resource table
+-- A delete marks the row deleted instead of removing it, so reads of a deleted resource answer 410 Gone while
+-- resources that never existed answer 404. Deleted rows are left out of every read and search. MongoDB
+-- collections need no change: a document without deleted_at is live
+
+ALTER TABLE patients ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE practitioners ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE encounters ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE immunizations ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE medication_requests ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN patients.deleted_at IS 'When the patient was deleted; NULL while it is live';
+COMMENT ON COLUMN practitioners.deleted_at IS 'When the practitioner was deleted; NULL while it is live';
+COMMENT ON COLUMN encounters.deleted_at IS 'When the encounter was deleted; NULL while it is live';
+COMMENT ON COLUMN immunizations.deleted_at IS 'When the immunization was deleted; NULL while it is live';
+COMMENT ON COLUMN medication_requests.deleted_at IS 'When the medication request was deleted; NULL while it is live';
@@ -0,0 +1,8 @@
+package migrations
+
+import "embed"
+
+// Files holds every NNN_name.up.sql and NNN_name.down.sql migration, so the server and cmd/migrate apply the same files
+//
+//go:embed *.sql
+var Files embed.FS
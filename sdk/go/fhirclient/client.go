@@ -0,0 +1,1173 @@
+// Code generated by cmd/sdkgen from the server's CapabilityStatement. DO NOT EDIT.
+
+package fhirclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/samply/golang-fhir-models/fhir-models/fhir"
+)
+
+// Client calls the FHIR API of one server
+type Client struct {
+	// BaseURL is the server address, e.g. https://fhir.example.com
+	BaseURL string
+
+	// HTTPClient sends the requests
+	HTTPClient *http.Client
+
+	// Header is sent with every request, e.g. X-API-Key or X-Tenant-ID
+	Header http.Header
+}
+
+// NewClient creates a client for the server at baseURL
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+		Header:     http.Header{},
+	}
+}
+
+// Error is returned for responses outside 2xx; Outcome is set when the server sent an OperationOutcome
+type Error struct {
+	StatusCode int
+	Outcome    *fhir.OperationOutcome
+	Body       []byte
+}
+
+// Error summarizes the status and the first OperationOutcome issue
+func (responseError *Error) Error() string {
+	if responseError.Outcome != nil && len(responseError.Outcome.Issue) > 0 && responseError.Outcome.Issue[0].Diagnostics != nil {
+		return fmt.Sprintf("fhir server returned %d: %s", responseError.StatusCode, *responseError.Outcome.Issue[0].Diagnostics)
+	}
+	return fmt.Sprintf("fhir server returned %d", responseError.StatusCode)
+}
+
+// do sends one request with body encoded as FHIR JSON, decoding a 2xx response into result when it is not nil
+func (client *Client) do(ctx context.Context, method string, path string, query url.Values, body any, result any) error {
+	requestURL := client.BaseURL + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var requestBody io.Reader
+	if body != nil {
+		encodedBody, encodeError := json.Marshal(body)
+		if encodeError != nil {
+			return fmt.Errorf("failed to encode request: %w", encodeError)
+		}
+		requestBody = bytes.NewReader(encodedBody)
+	}
+
+	request, requestError := http.NewRequestWithContext(ctx, method, requestURL, requestBody)
+	if requestError != nil {
+		return requestError
+	}
+	for headerName, headerValues := range client.Header {
+		request.Header[headerName] = headerValues
+	}
+	request.Header.Set("Accept", "application/fhir+json")
+	if body != nil {
+		request.Header.Set("Content-Type", "application/fhir+json")
+	}
+
+	response, responseError := client.HTTPClient.Do(request)
+	if responseError != nil {
+		return responseError
+	}
+	defer response.Body.Close()
+
+	responseBody, readError := io.ReadAll(response.Body)
+	if readError != nil {
+		return fmt.Errorf("failed to read response: %w", readError)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		serverError := &Error{StatusCode: response.StatusCode, Body: responseBody}
+		var operationOutcome fhir.OperationOutcome
+		if json.Unmarshal(responseBody, &operationOutcome) == nil && len(operationOutcome.Issue) > 0 {
+			serverError.Outcome = &operationOutcome
+		}
+		return serverError
+	}
+
+	if result == nil || len(responseBody) == 0 {
+		return nil
+	}
+	if decodeError := json.Unmarshal(responseBody, result); decodeError != nil {
+		return fmt.Errorf("failed to decode response: %w", decodeError)
+	}
+	return nil
+}
+
+// searchMatches runs a search and decodes the match entries of the searchset Bundle it returns
+func searchMatches[Resource any](ctx context.Context, client *Client, path string, query url.Values) ([]Resource, error) {
+	var searchset fhir.Bundle
+	if searchError := client.do(ctx, http.MethodGet, path, query, nil, &searchset); searchError != nil {
+		return nil, searchError
+	}
+	resources := make([]Resource, 0, len(searchset.Entry))
+	for _, entry := range searchset.Entry {
+		if entry.Search != nil && entry.Search.Mode != nil && *entry.Search.Mode != fhir.SearchEntryModeMatch {
+			continue
+		}
+		var resource Resource
+		if decodeError := json.Unmarshal(entry.Resource, &resource); decodeError != nil {
+			return nil, fmt.Errorf("failed to decode search entry: %w", decodeError)
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// PatientSearch holds the Patient search parameters; zero values are left out
+type PatientSearch struct {
+	// Name is name: Any part of the given or family name; Patient also accepts :contains and :exact
+	Name string
+	// Family is family: Family name; Patient also accepts :contains and :exact
+	Family string
+	// Given is given: Given name; Patient also accepts :contains and :exact
+	Given string
+	// Gender is gender: Administrative gender
+	Gender string
+	// Birthdate is birthdate: Birth date, optionally prefixed with ge, gt, le, or lt
+	Birthdate string
+	// Active is active: Whether the record is active (true or false)
+	Active string
+	// Identifier is identifier: Identifier as system|value, value, |value, or system|; Patient searches also match site aliases of the system
+	Identifier string
+	// Tag is _tag: meta.tag as code, system|code, |code, or system|; repeats must all match
+	Tag []string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Sort is _sort: Sort field, prefixed with - for descending
+	Sort string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+	// Cursor is _cursor: Continuation token from a previous page's next link; cannot be combined with _offset
+	Cursor string
+	// Stream is _stream: true to receive every match, up to 100000, in one JSON Bundle written as the matches are read
+	Stream string
+}
+
+// values encodes the search as query parameters
+func (search PatientSearch) values() url.Values {
+	query := url.Values{}
+	if search.Name != "" {
+		query.Set("name", search.Name)
+	}
+	if search.Family != "" {
+		query.Set("family", search.Family)
+	}
+	if search.Given != "" {
+		query.Set("given", search.Given)
+	}
+	if search.Gender != "" {
+		query.Set("gender", search.Gender)
+	}
+	if search.Birthdate != "" {
+		query.Set("birthdate", search.Birthdate)
+	}
+	if search.Active != "" {
+		query.Set("active", search.Active)
+	}
+	if search.Identifier != "" {
+		query.Set("identifier", search.Identifier)
+	}
+	for _, value := range search.Tag {
+		query.Add("_tag", value)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Sort != "" {
+		query.Set("_sort", search.Sort)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	if search.Cursor != "" {
+		query.Set("_cursor", search.Cursor)
+	}
+	if search.Stream != "" {
+		query.Set("_stream", search.Stream)
+	}
+	return query
+}
+
+// SearchPatient returns the Patient resources matching search
+func (client *Client) SearchPatient(ctx context.Context, search PatientSearch) ([]fhir.Patient, error) {
+	return searchMatches[fhir.Patient](ctx, client, "/fhir/Patient", search.values())
+}
+
+// CreatePatient creates a Patient and returns it as stored
+func (client *Client) CreatePatient(ctx context.Context, resource *fhir.Patient) (*fhir.Patient, error) {
+	var created fhir.Patient
+	if createError := client.do(ctx, http.MethodPost, "/fhir/Patient", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadPatient returns the Patient with the given ID
+func (client *Client) ReadPatient(ctx context.Context, id string) (*fhir.Patient, error) {
+	var resource fhir.Patient
+	if readError := client.do(ctx, http.MethodGet, "/fhir/Patient/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdatePatient replaces the Patient with the given ID and returns it as stored
+func (client *Client) UpdatePatient(ctx context.Context, id string, resource *fhir.Patient) (*fhir.Patient, error) {
+	var updated fhir.Patient
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/Patient/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeletePatient deletes the Patient with the given ID
+func (client *Client) DeletePatient(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/Patient/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// PatientEverything invokes $everything: The patient and its compartment as a searchset Bundle, filtered by _type and _since
+func (client *Client) PatientEverything(ctx context.Context, id string, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Patient/"+url.PathEscape(id)+"/$everything", parameters, nil, &result)
+}
+
+// PatientSnapshot invokes $snapshot: The patient compartment as it was at _at
+func (client *Client) PatientSnapshot(ctx context.Context, id string, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Patient/"+url.PathEscape(id)+"/$snapshot", parameters, nil, &result)
+}
+
+// PatientHealthExport invokes $health-export: The patient's vital signs as Apple HealthKit or Google Fit JSON
+func (client *Client) PatientHealthExport(ctx context.Context, id string, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Patient/"+url.PathEscape(id)+"/$health-export", parameters, nil, &result)
+}
+
+// PatientAvatar invokes $avatar: A generated identicon PNG for the patient
+func (client *Client) PatientAvatar(ctx context.Context, id string, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Patient/"+url.PathEscape(id)+"/$avatar", parameters, nil, &result)
+}
+
+// PatientExport invokes $export: Asynchronous Bulk Data export of every patient and their compartments as NDJSON files
+func (client *Client) PatientExport(ctx context.Context, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Patient/$export", parameters, nil, &result)
+}
+
+// PatientMerge invokes $merge: Merge a duplicate source-patient into a target-patient: its observations move to the target and it becomes inactive, replaced by the target
+func (client *Client) PatientMerge(ctx context.Context, body any) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodPost, "/fhir/Patient/$merge", nil, body, &result)
+}
+
+// PatientMatch invokes $match: Stored patients that may be the same person as a partial Patient, ranked by score with a match grade
+func (client *Client) PatientMatch(ctx context.Context, body any) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodPost, "/fhir/Patient/$match", nil, body, &result)
+}
+
+// PatientMeta invokes $meta: The resource's meta (tags)
+func (client *Client) PatientMeta(ctx context.Context, id string, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Patient/"+url.PathEscape(id)+"/$meta", parameters, nil, &result)
+}
+
+// PatientMetaAdd invokes $meta-add: Add meta.tag values without creating a new version
+func (client *Client) PatientMetaAdd(ctx context.Context, id string, body any) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodPost, "/fhir/Patient/"+url.PathEscape(id)+"/$meta-add", nil, body, &result)
+}
+
+// PatientMetaDelete invokes $meta-delete: Remove meta.tag values without creating a new version
+func (client *Client) PatientMetaDelete(ctx context.Context, id string, body any) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodPost, "/fhir/Patient/"+url.PathEscape(id)+"/$meta-delete", nil, body, &result)
+}
+
+// ObservationSearch holds the Observation search parameters; zero values are left out
+type ObservationSearch struct {
+	// Patient is patient: Subject patient ID or Patient/{id} reference
+	Patient string
+	// Encounter is encounter: Encounter ID or Encounter/{id} reference
+	Encounter string
+	// Code is code: Observation code; Condition or DiagnosticReport code as code, system|code, |code, or system|
+	Code string
+	// Category is category: Observation, AllergyIntolerance, or DiagnosticReport category
+	Category string
+	// Status is status: Observation, Encounter, MedicationRequest, Immunization, or Subscription status
+	Status string
+	// Date is date: Observation or DiagnosticReport effective date, Encounter period, Immunization occurrence, or AuditEvent recording time, prefixed with ge, gt, le, or lt
+	Date string
+	// ValueQuantity is value-quantity: Observation value as [prefix]number or [prefix]number|system|unit with prefix eq, ne, gt, lt, ge, le, or ap; the unit is matched but not the system
+	ValueQuantity string
+	// Tag is _tag: meta.tag as code, system|code, |code, or system|; repeats must all match
+	Tag []string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Sort is _sort: Sort field, prefixed with - for descending
+	Sort string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+	// Cursor is _cursor: Continuation token from a previous page's next link; cannot be combined with _offset
+	Cursor string
+	// Stream is _stream: true to receive every match, up to 100000, in one JSON Bundle written as the matches are read
+	Stream string
+}
+
+// values encodes the search as query parameters
+func (search ObservationSearch) values() url.Values {
+	query := url.Values{}
+	if search.Patient != "" {
+		query.Set("patient", search.Patient)
+	}
+	if search.Encounter != "" {
+		query.Set("encounter", search.Encounter)
+	}
+	if search.Code != "" {
+		query.Set("code", search.Code)
+	}
+	if search.Category != "" {
+		query.Set("category", search.Category)
+	}
+	if search.Status != "" {
+		query.Set("status", search.Status)
+	}
+	if search.Date != "" {
+		query.Set("date", search.Date)
+	}
+	if search.ValueQuantity != "" {
+		query.Set("value-quantity", search.ValueQuantity)
+	}
+	for _, value := range search.Tag {
+		query.Add("_tag", value)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Sort != "" {
+		query.Set("_sort", search.Sort)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	if search.Cursor != "" {
+		query.Set("_cursor", search.Cursor)
+	}
+	if search.Stream != "" {
+		query.Set("_stream", search.Stream)
+	}
+	return query
+}
+
+// SearchObservation returns the Observation resources matching search
+func (client *Client) SearchObservation(ctx context.Context, search ObservationSearch) ([]fhir.Observation, error) {
+	return searchMatches[fhir.Observation](ctx, client, "/fhir/Observation", search.values())
+}
+
+// CreateObservation creates a Observation and returns it as stored
+func (client *Client) CreateObservation(ctx context.Context, resource *fhir.Observation) (*fhir.Observation, error) {
+	var created fhir.Observation
+	if createError := client.do(ctx, http.MethodPost, "/fhir/Observation", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadObservation returns the Observation with the given ID
+func (client *Client) ReadObservation(ctx context.Context, id string) (*fhir.Observation, error) {
+	var resource fhir.Observation
+	if readError := client.do(ctx, http.MethodGet, "/fhir/Observation/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateObservation replaces the Observation with the given ID and returns it as stored
+func (client *Client) UpdateObservation(ctx context.Context, id string, resource *fhir.Observation) (*fhir.Observation, error) {
+	var updated fhir.Observation
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/Observation/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteObservation deletes the Observation with the given ID
+func (client *Client) DeleteObservation(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/Observation/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ObservationDailyRollup invokes $daily-rollup: Daily count, min, max, and average for a patient and code
+func (client *Client) ObservationDailyRollup(ctx context.Context, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Observation/$daily-rollup", parameters, nil, &result)
+}
+
+// ObservationLastn invokes $lastn: The patient's max most recent observations of each code, filtered by code, category, and date
+func (client *Client) ObservationLastn(ctx context.Context, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Observation/$lastn", parameters, nil, &result)
+}
+
+// ObservationMeta invokes $meta: The resource's meta (tags)
+func (client *Client) ObservationMeta(ctx context.Context, id string, parameters url.Values) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodGet, "/fhir/Observation/"+url.PathEscape(id)+"/$meta", parameters, nil, &result)
+}
+
+// ObservationMetaAdd invokes $meta-add: Add meta.tag values without creating a new version
+func (client *Client) ObservationMetaAdd(ctx context.Context, id string, body any) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodPost, "/fhir/Observation/"+url.PathEscape(id)+"/$meta-add", nil, body, &result)
+}
+
+// ObservationMetaDelete invokes $meta-delete: Remove meta.tag values without creating a new version
+func (client *Client) ObservationMetaDelete(ctx context.Context, id string, body any) (json.RawMessage, error) {
+	var result json.RawMessage
+	return result, client.do(ctx, http.MethodPost, "/fhir/Observation/"+url.PathEscape(id)+"/$meta-delete", nil, body, &result)
+}
+
+// PractitionerSearch holds the Practitioner search parameters; zero values are left out
+type PractitionerSearch struct {
+	// Name is name: Any part of the given or family name; Patient also accepts :contains and :exact
+	Name string
+	// Family is family: Family name; Patient also accepts :contains and :exact
+	Family string
+	// Given is given: Given name; Patient also accepts :contains and :exact
+	Given string
+	// Active is active: Whether the record is active (true or false)
+	Active string
+	// Identifier is identifier: Identifier as system|value, value, |value, or system|; Patient searches also match site aliases of the system
+	Identifier string
+	// Specialty is specialty: Practitioner qualification code as code, system|code, |code, or system|
+	Specialty string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search PractitionerSearch) values() url.Values {
+	query := url.Values{}
+	if search.Name != "" {
+		query.Set("name", search.Name)
+	}
+	if search.Family != "" {
+		query.Set("family", search.Family)
+	}
+	if search.Given != "" {
+		query.Set("given", search.Given)
+	}
+	if search.Active != "" {
+		query.Set("active", search.Active)
+	}
+	if search.Identifier != "" {
+		query.Set("identifier", search.Identifier)
+	}
+	if search.Specialty != "" {
+		query.Set("specialty", search.Specialty)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchPractitioner returns the Practitioner resources matching search
+func (client *Client) SearchPractitioner(ctx context.Context, search PractitionerSearch) ([]fhir.Practitioner, error) {
+	return searchMatches[fhir.Practitioner](ctx, client, "/fhir/Practitioner", search.values())
+}
+
+// CreatePractitioner creates a Practitioner and returns it as stored
+func (client *Client) CreatePractitioner(ctx context.Context, resource *fhir.Practitioner) (*fhir.Practitioner, error) {
+	var created fhir.Practitioner
+	if createError := client.do(ctx, http.MethodPost, "/fhir/Practitioner", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadPractitioner returns the Practitioner with the given ID
+func (client *Client) ReadPractitioner(ctx context.Context, id string) (*fhir.Practitioner, error) {
+	var resource fhir.Practitioner
+	if readError := client.do(ctx, http.MethodGet, "/fhir/Practitioner/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdatePractitioner replaces the Practitioner with the given ID and returns it as stored
+func (client *Client) UpdatePractitioner(ctx context.Context, id string, resource *fhir.Practitioner) (*fhir.Practitioner, error) {
+	var updated fhir.Practitioner
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/Practitioner/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeletePractitioner deletes the Practitioner with the given ID
+func (client *Client) DeletePractitioner(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/Practitioner/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// EncounterSearch holds the Encounter search parameters; zero values are left out
+type EncounterSearch struct {
+	// Patient is patient: Subject patient ID or Patient/{id} reference
+	Patient string
+	// Status is status: Observation, Encounter, MedicationRequest, Immunization, or Subscription status
+	Status string
+	// Date is date: Observation or DiagnosticReport effective date, Encounter period, Immunization occurrence, or AuditEvent recording time, prefixed with ge, gt, le, or lt
+	Date string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search EncounterSearch) values() url.Values {
+	query := url.Values{}
+	if search.Patient != "" {
+		query.Set("patient", search.Patient)
+	}
+	if search.Status != "" {
+		query.Set("status", search.Status)
+	}
+	if search.Date != "" {
+		query.Set("date", search.Date)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchEncounter returns the Encounter resources matching search
+func (client *Client) SearchEncounter(ctx context.Context, search EncounterSearch) ([]fhir.Encounter, error) {
+	return searchMatches[fhir.Encounter](ctx, client, "/fhir/Encounter", search.values())
+}
+
+// CreateEncounter creates a Encounter and returns it as stored
+func (client *Client) CreateEncounter(ctx context.Context, resource *fhir.Encounter) (*fhir.Encounter, error) {
+	var created fhir.Encounter
+	if createError := client.do(ctx, http.MethodPost, "/fhir/Encounter", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadEncounter returns the Encounter with the given ID
+func (client *Client) ReadEncounter(ctx context.Context, id string) (*fhir.Encounter, error) {
+	var resource fhir.Encounter
+	if readError := client.do(ctx, http.MethodGet, "/fhir/Encounter/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateEncounter replaces the Encounter with the given ID and returns it as stored
+func (client *Client) UpdateEncounter(ctx context.Context, id string, resource *fhir.Encounter) (*fhir.Encounter, error) {
+	var updated fhir.Encounter
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/Encounter/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteEncounter deletes the Encounter with the given ID
+func (client *Client) DeleteEncounter(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/Encounter/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ConditionSearch holds the Condition search parameters; zero values are left out
+type ConditionSearch struct {
+	// Patient is patient: Subject patient ID or Patient/{id} reference
+	Patient string
+	// Code is code: Observation code; Condition or DiagnosticReport code as code, system|code, |code, or system|
+	Code string
+	// ClinicalStatus is clinical-status: Condition or AllergyIntolerance clinical status (active, inactive, resolved; Condition also recurrence, relapse, remission)
+	ClinicalStatus string
+	// OnsetDate is onset-date: Condition onset date prefixed with ge, gt, le, or lt
+	OnsetDate string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search ConditionSearch) values() url.Values {
+	query := url.Values{}
+	if search.Patient != "" {
+		query.Set("patient", search.Patient)
+	}
+	if search.Code != "" {
+		query.Set("code", search.Code)
+	}
+	if search.ClinicalStatus != "" {
+		query.Set("clinical-status", search.ClinicalStatus)
+	}
+	if search.OnsetDate != "" {
+		query.Set("onset-date", search.OnsetDate)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchCondition returns the Condition resources matching search
+func (client *Client) SearchCondition(ctx context.Context, search ConditionSearch) ([]fhir.Condition, error) {
+	return searchMatches[fhir.Condition](ctx, client, "/fhir/Condition", search.values())
+}
+
+// CreateCondition creates a Condition and returns it as stored
+func (client *Client) CreateCondition(ctx context.Context, resource *fhir.Condition) (*fhir.Condition, error) {
+	var created fhir.Condition
+	if createError := client.do(ctx, http.MethodPost, "/fhir/Condition", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadCondition returns the Condition with the given ID
+func (client *Client) ReadCondition(ctx context.Context, id string) (*fhir.Condition, error) {
+	var resource fhir.Condition
+	if readError := client.do(ctx, http.MethodGet, "/fhir/Condition/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateCondition replaces the Condition with the given ID and returns it as stored
+func (client *Client) UpdateCondition(ctx context.Context, id string, resource *fhir.Condition) (*fhir.Condition, error) {
+	var updated fhir.Condition
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/Condition/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteCondition deletes the Condition with the given ID
+func (client *Client) DeleteCondition(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/Condition/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// MedicationRequestSearch holds the MedicationRequest search parameters; zero values are left out
+type MedicationRequestSearch struct {
+	// Patient is patient: Subject patient ID or Patient/{id} reference
+	Patient string
+	// Status is status: Observation, Encounter, MedicationRequest, Immunization, or Subscription status
+	Status string
+	// Intent is intent: MedicationRequest intent (proposal, plan, order, ...)
+	Intent string
+	// Authoredon is authoredon: MedicationRequest authored date prefixed with ge, gt, le, or lt
+	Authoredon string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search MedicationRequestSearch) values() url.Values {
+	query := url.Values{}
+	if search.Patient != "" {
+		query.Set("patient", search.Patient)
+	}
+	if search.Status != "" {
+		query.Set("status", search.Status)
+	}
+	if search.Intent != "" {
+		query.Set("intent", search.Intent)
+	}
+	if search.Authoredon != "" {
+		query.Set("authoredon", search.Authoredon)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchMedicationRequest returns the MedicationRequest resources matching search
+func (client *Client) SearchMedicationRequest(ctx context.Context, search MedicationRequestSearch) ([]fhir.MedicationRequest, error) {
+	return searchMatches[fhir.MedicationRequest](ctx, client, "/fhir/MedicationRequest", search.values())
+}
+
+// CreateMedicationRequest creates a MedicationRequest and returns it as stored
+func (client *Client) CreateMedicationRequest(ctx context.Context, resource *fhir.MedicationRequest) (*fhir.MedicationRequest, error) {
+	var created fhir.MedicationRequest
+	if createError := client.do(ctx, http.MethodPost, "/fhir/MedicationRequest", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadMedicationRequest returns the MedicationRequest with the given ID
+func (client *Client) ReadMedicationRequest(ctx context.Context, id string) (*fhir.MedicationRequest, error) {
+	var resource fhir.MedicationRequest
+	if readError := client.do(ctx, http.MethodGet, "/fhir/MedicationRequest/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateMedicationRequest replaces the MedicationRequest with the given ID and returns it as stored
+func (client *Client) UpdateMedicationRequest(ctx context.Context, id string, resource *fhir.MedicationRequest) (*fhir.MedicationRequest, error) {
+	var updated fhir.MedicationRequest
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/MedicationRequest/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteMedicationRequest deletes the MedicationRequest with the given ID
+func (client *Client) DeleteMedicationRequest(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/MedicationRequest/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// AllergyIntoleranceSearch holds the AllergyIntolerance search parameters; zero values are left out
+type AllergyIntoleranceSearch struct {
+	// Patient is patient: Subject patient ID or Patient/{id} reference
+	Patient string
+	// ClinicalStatus is clinical-status: Condition or AllergyIntolerance clinical status (active, inactive, resolved; Condition also recurrence, relapse, remission)
+	ClinicalStatus string
+	// Category is category: Observation, AllergyIntolerance, or DiagnosticReport category
+	Category string
+	// Criticality is criticality: AllergyIntolerance criticality (low, high, unable-to-assess)
+	Criticality string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search AllergyIntoleranceSearch) values() url.Values {
+	query := url.Values{}
+	if search.Patient != "" {
+		query.Set("patient", search.Patient)
+	}
+	if search.ClinicalStatus != "" {
+		query.Set("clinical-status", search.ClinicalStatus)
+	}
+	if search.Category != "" {
+		query.Set("category", search.Category)
+	}
+	if search.Criticality != "" {
+		query.Set("criticality", search.Criticality)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchAllergyIntolerance returns the AllergyIntolerance resources matching search
+func (client *Client) SearchAllergyIntolerance(ctx context.Context, search AllergyIntoleranceSearch) ([]fhir.AllergyIntolerance, error) {
+	return searchMatches[fhir.AllergyIntolerance](ctx, client, "/fhir/AllergyIntolerance", search.values())
+}
+
+// CreateAllergyIntolerance creates a AllergyIntolerance and returns it as stored
+func (client *Client) CreateAllergyIntolerance(ctx context.Context, resource *fhir.AllergyIntolerance) (*fhir.AllergyIntolerance, error) {
+	var created fhir.AllergyIntolerance
+	if createError := client.do(ctx, http.MethodPost, "/fhir/AllergyIntolerance", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadAllergyIntolerance returns the AllergyIntolerance with the given ID
+func (client *Client) ReadAllergyIntolerance(ctx context.Context, id string) (*fhir.AllergyIntolerance, error) {
+	var resource fhir.AllergyIntolerance
+	if readError := client.do(ctx, http.MethodGet, "/fhir/AllergyIntolerance/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateAllergyIntolerance replaces the AllergyIntolerance with the given ID and returns it as stored
+func (client *Client) UpdateAllergyIntolerance(ctx context.Context, id string, resource *fhir.AllergyIntolerance) (*fhir.AllergyIntolerance, error) {
+	var updated fhir.AllergyIntolerance
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/AllergyIntolerance/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteAllergyIntolerance deletes the AllergyIntolerance with the given ID
+func (client *Client) DeleteAllergyIntolerance(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/AllergyIntolerance/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// DiagnosticReportSearch holds the DiagnosticReport search parameters; zero values are left out
+type DiagnosticReportSearch struct {
+	// Patient is patient: Subject patient ID or Patient/{id} reference
+	Patient string
+	// Category is category: Observation, AllergyIntolerance, or DiagnosticReport category
+	Category string
+	// Code is code: Observation code; Condition or DiagnosticReport code as code, system|code, |code, or system|
+	Code string
+	// Date is date: Observation or DiagnosticReport effective date, Encounter period, Immunization occurrence, or AuditEvent recording time, prefixed with ge, gt, le, or lt
+	Date string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search DiagnosticReportSearch) values() url.Values {
+	query := url.Values{}
+	if search.Patient != "" {
+		query.Set("patient", search.Patient)
+	}
+	if search.Category != "" {
+		query.Set("category", search.Category)
+	}
+	if search.Code != "" {
+		query.Set("code", search.Code)
+	}
+	if search.Date != "" {
+		query.Set("date", search.Date)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchDiagnosticReport returns the DiagnosticReport resources matching search
+func (client *Client) SearchDiagnosticReport(ctx context.Context, search DiagnosticReportSearch) ([]fhir.DiagnosticReport, error) {
+	return searchMatches[fhir.DiagnosticReport](ctx, client, "/fhir/DiagnosticReport", search.values())
+}
+
+// CreateDiagnosticReport creates a DiagnosticReport and returns it as stored
+func (client *Client) CreateDiagnosticReport(ctx context.Context, resource *fhir.DiagnosticReport) (*fhir.DiagnosticReport, error) {
+	var created fhir.DiagnosticReport
+	if createError := client.do(ctx, http.MethodPost, "/fhir/DiagnosticReport", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadDiagnosticReport returns the DiagnosticReport with the given ID
+func (client *Client) ReadDiagnosticReport(ctx context.Context, id string) (*fhir.DiagnosticReport, error) {
+	var resource fhir.DiagnosticReport
+	if readError := client.do(ctx, http.MethodGet, "/fhir/DiagnosticReport/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateDiagnosticReport replaces the DiagnosticReport with the given ID and returns it as stored
+func (client *Client) UpdateDiagnosticReport(ctx context.Context, id string, resource *fhir.DiagnosticReport) (*fhir.DiagnosticReport, error) {
+	var updated fhir.DiagnosticReport
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/DiagnosticReport/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteDiagnosticReport deletes the DiagnosticReport with the given ID
+func (client *Client) DeleteDiagnosticReport(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/DiagnosticReport/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// ImmunizationSearch holds the Immunization search parameters; zero values are left out
+type ImmunizationSearch struct {
+	// Patient is patient: Subject patient ID or Patient/{id} reference
+	Patient string
+	// VaccineCode is vaccine-code: Immunization vaccine code as code, system|code, |code, or system|
+	VaccineCode string
+	// Date is date: Observation or DiagnosticReport effective date, Encounter period, Immunization occurrence, or AuditEvent recording time, prefixed with ge, gt, le, or lt
+	Date string
+	// Status is status: Observation, Encounter, MedicationRequest, Immunization, or Subscription status
+	Status string
+	// LotNumber is lot-number: Immunization vaccine lot number, matched exactly
+	LotNumber string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search ImmunizationSearch) values() url.Values {
+	query := url.Values{}
+	if search.Patient != "" {
+		query.Set("patient", search.Patient)
+	}
+	if search.VaccineCode != "" {
+		query.Set("vaccine-code", search.VaccineCode)
+	}
+	if search.Date != "" {
+		query.Set("date", search.Date)
+	}
+	if search.Status != "" {
+		query.Set("status", search.Status)
+	}
+	if search.LotNumber != "" {
+		query.Set("lot-number", search.LotNumber)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchImmunization returns the Immunization resources matching search
+func (client *Client) SearchImmunization(ctx context.Context, search ImmunizationSearch) ([]fhir.Immunization, error) {
+	return searchMatches[fhir.Immunization](ctx, client, "/fhir/Immunization", search.values())
+}
+
+// CreateImmunization creates a Immunization and returns it as stored
+func (client *Client) CreateImmunization(ctx context.Context, resource *fhir.Immunization) (*fhir.Immunization, error) {
+	var created fhir.Immunization
+	if createError := client.do(ctx, http.MethodPost, "/fhir/Immunization", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadImmunization returns the Immunization with the given ID
+func (client *Client) ReadImmunization(ctx context.Context, id string) (*fhir.Immunization, error) {
+	var resource fhir.Immunization
+	if readError := client.do(ctx, http.MethodGet, "/fhir/Immunization/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateImmunization replaces the Immunization with the given ID and returns it as stored
+func (client *Client) UpdateImmunization(ctx context.Context, id string, resource *fhir.Immunization) (*fhir.Immunization, error) {
+	var updated fhir.Immunization
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/Immunization/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteImmunization deletes the Immunization with the given ID
+func (client *Client) DeleteImmunization(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/Immunization/"+url.PathEscape(id), nil, nil, nil)
+}
+
+// AuditEventSearch holds the AuditEvent search parameters; zero values are left out
+type AuditEventSearch struct {
+	// Agent is agent: AuditEvent requesting principal, such as oauth:{client_id}
+	Agent string
+	// Entity is entity: AuditEvent accessed resource as {type}/{id} or a bare ID
+	Entity string
+	// EntityType is entity-type: AuditEvent accessed resource type
+	EntityType string
+	// Action is action: AuditEvent action (C, R, U, D, or E)
+	Action string
+	// Outcome is outcome: AuditEvent outcome (0 success, 4 refused or rejected, 8 server failure)
+	Outcome string
+	// Date is date: Observation or DiagnosticReport effective date, Encounter period, Immunization occurrence, or AuditEvent recording time, prefixed with ge, gt, le, or lt
+	Date string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search AuditEventSearch) values() url.Values {
+	query := url.Values{}
+	if search.Agent != "" {
+		query.Set("agent", search.Agent)
+	}
+	if search.Entity != "" {
+		query.Set("entity", search.Entity)
+	}
+	if search.EntityType != "" {
+		query.Set("entity-type", search.EntityType)
+	}
+	if search.Action != "" {
+		query.Set("action", search.Action)
+	}
+	if search.Outcome != "" {
+		query.Set("outcome", search.Outcome)
+	}
+	if search.Date != "" {
+		query.Set("date", search.Date)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchAuditEvent returns the AuditEvent resources matching search
+func (client *Client) SearchAuditEvent(ctx context.Context, search AuditEventSearch) ([]fhir.AuditEvent, error) {
+	return searchMatches[fhir.AuditEvent](ctx, client, "/fhir/AuditEvent", search.values())
+}
+
+// ReadAuditEvent returns the AuditEvent with the given ID
+func (client *Client) ReadAuditEvent(ctx context.Context, id string) (*fhir.AuditEvent, error) {
+	var resource fhir.AuditEvent
+	if readError := client.do(ctx, http.MethodGet, "/fhir/AuditEvent/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// SubscriptionSearch holds the Subscription search parameters; zero values are left out
+type SubscriptionSearch struct {
+	// Status is status: Observation, Encounter, MedicationRequest, Immunization, or Subscription status
+	Status string
+	// Elements is _elements: Elements to return; the rest are left out
+	Elements []string
+	// Count is _count: Page size (at most 100)
+	Count int
+	// Offset is _offset: Number of matches to skip
+	Offset int
+}
+
+// values encodes the search as query parameters
+func (search SubscriptionSearch) values() url.Values {
+	query := url.Values{}
+	if search.Status != "" {
+		query.Set("status", search.Status)
+	}
+	for _, value := range search.Elements {
+		query.Add("_elements", value)
+	}
+	if search.Count > 0 {
+		query.Set("_count", strconv.Itoa(search.Count))
+	}
+	if search.Offset > 0 {
+		query.Set("_offset", strconv.Itoa(search.Offset))
+	}
+	return query
+}
+
+// SearchSubscription returns the Subscription resources matching search
+func (client *Client) SearchSubscription(ctx context.Context, search SubscriptionSearch) ([]fhir.Subscription, error) {
+	return searchMatches[fhir.Subscription](ctx, client, "/fhir/Subscription", search.values())
+}
+
+// CreateSubscription creates a Subscription and returns it as stored
+func (client *Client) CreateSubscription(ctx context.Context, resource *fhir.Subscription) (*fhir.Subscription, error) {
+	var created fhir.Subscription
+	if createError := client.do(ctx, http.MethodPost, "/fhir/Subscription", nil, resource, &created); createError != nil {
+		return nil, createError
+	}
+	return &created, nil
+}
+
+// ReadSubscription returns the Subscription with the given ID
+func (client *Client) ReadSubscription(ctx context.Context, id string) (*fhir.Subscription, error) {
+	var resource fhir.Subscription
+	if readError := client.do(ctx, http.MethodGet, "/fhir/Subscription/"+url.PathEscape(id), nil, nil, &resource); readError != nil {
+		return nil, readError
+	}
+	return &resource, nil
+}
+
+// UpdateSubscription replaces the Subscription with the given ID and returns it as stored
+func (client *Client) UpdateSubscription(ctx context.Context, id string, resource *fhir.Subscription) (*fhir.Subscription, error) {
+	var updated fhir.Subscription
+	if updateError := client.do(ctx, http.MethodPut, "/fhir/Subscription/"+url.PathEscape(id), nil, resource, &updated); updateError != nil {
+		return nil, updateError
+	}
+	return &updated, nil
+}
+
+// DeleteSubscription deletes the Subscription with the given ID
+func (client *Client) DeleteSubscription(ctx context.Context, id string) error {
+	return client.do(ctx, http.MethodDelete, "/fhir/Subscription/"+url.PathEscape(id), nil, nil, nil)
+}
@@ -0,0 +1,7 @@
+// Package fhirclient is a thin Go client for the fhir-health-interop API, generated from the same
+// resource definitions as the server's CapabilityStatement
+// Regenerate after changing internal/capability: go generate ./sdk/...
+package fhirclient
+
+//go:generate go run ../../../cmd/sdkgen -lang go -out client.go
+//go:generate go run ../../../cmd/sdkgen -lang typescript -out ../../typescript/client.ts